@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"os"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/backup"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// RunBackup 导出数据库及设置至备份文件
+func RunBackup(path string, thumbnails bool) {
+	f, err := os.Create(path)
+	if err != nil {
+		util.Log().Error("无法创建备份文件, %s", err)
+		return
+	}
+	defer f.Close()
+
+	manifest, err := backup.Export(f, backup.Options{Thumbnails: thumbnails})
+	if err != nil {
+		util.Log().Error("备份失败: %s", err)
+		return
+	}
+
+	util.Log().Info("已备份 %d 个数据表、%d 个缩略图至 %s", len(manifest.Tables), manifest.Thumbnails, path)
+}
+
+// RunRestore 从备份文件恢复数据库及设置，force 为 true 时覆盖已有数据
+func RunRestore(path string, force bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		util.Log().Error("无法打开备份文件, %s", err)
+		return
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil {
+		util.Log().Error("无法读取备份文件, %s", err)
+		return
+	}
+
+	manifest, err := backup.Restore(f, stat.Size(), backup.RestoreOptions{Force: force})
+	if err != nil {
+		util.Log().Error("恢复失败: %s", err)
+		return
+	}
+
+	util.Log().Info("已从 %s 恢复 %s 版本创建的备份，请重新启动 Cloudreve", path, manifest.Version)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/gin-gonic/gin"
 	"io/fs"
 )
@@ -29,6 +30,12 @@ func Init(path string, statics fs.FS) {
 		mode    string
 		factory func()
 	}{
+		{
+			"both",
+			func() {
+				tracing.Init()
+			},
+		},
 		{
 			"both",
 			func() {
@@ -53,6 +60,13 @@ func Init(path string, statics fs.FS) {
 				task.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				task.Use(task.HookOnSuccess, task.NotifySucceeded)
+				task.Use(task.HookOnFailure, task.NotifyFailed)
+			},
+		},
 		{
 			"master",
 			func() {
@@ -77,6 +91,12 @@ func Init(path string, statics fs.FS) {
 				crontab.Init()
 			},
 		},
+		{
+			"master",
+			func() {
+				initSettingReload()
+			},
+		},
 		{
 			"master",
 			func() {
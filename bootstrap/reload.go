@@ -0,0 +1,27 @@
+package bootstrap
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/crontab"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// initSettingReload 注册设置项变更后需要重新初始化的组件，使管理员修改设置后无需重启
+func initSettingReload() {
+	reload := func(name string, init func()) model.SettingChangeHandler {
+		return func([]string) {
+			util.Log().Info("设置项变更，重新初始化%s", name)
+			init()
+		}
+	}
+
+	model.OnSettingChange(reload("邮件队列", email.Init),
+		"fromName", "fromAdress", "smtpHost", "smtpPort", "replyTo", "smtpUser", "smtpPass", "smtpEncryption", "mail_keepalive",
+		"mail_driver", "mail_api_key", "mail_api_secret", "mail_api_domain", "mail_api_region", "mail_rate_limit", "mail_max_retries")
+	model.OnSettingChange(reload("定时任务", crontab.Reload), "cron_*")
+	model.OnSettingChange(reload("任务队列", task.ApplySettings), "max_worker_num", "maintenance_enabled")
+	model.OnSettingChange(reload("签名密钥", auth.Init), "secret_key")
+}
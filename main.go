@@ -17,16 +17,27 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/bootstrap"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/dlna"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ftp"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sftp"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/routers"
 
 	"github.com/mholt/archiver/v4"
+	"google.golang.org/grpc"
 )
 
 var (
 	isEject    bool
 	confPath   string
 	scriptName string
+
+	backupPath       string
+	backupThumbnails bool
+	restorePath      string
+	restoreForce     bool
 )
 
 //go:embed assets.zip
@@ -38,6 +49,10 @@ func init() {
 	flag.StringVar(&confPath, "c", util.RelativePath("conf.ini"), "配置文件路径")
 	flag.BoolVar(&isEject, "eject", false, "导出内置静态资源")
 	flag.StringVar(&scriptName, "database-script", "", "运行内置数据库助手脚本")
+	flag.StringVar(&backupPath, "backup", "", "导出数据库及设置至备份文件")
+	flag.BoolVar(&backupThumbnails, "backup-thumbnails", false, "备份时包含本机存储策略下的缩略图")
+	flag.StringVar(&restorePath, "restore", "", "从备份文件恢复数据库及设置")
+	flag.BoolVar(&restoreForce, "restore-force", false, "恢复时覆盖已有数据")
 	flag.Parse()
 
 	staticFS = archiver.ArchiveFS{
@@ -63,9 +78,97 @@ func main() {
 		return
 	}
 
+	if backupPath != "" {
+		// 导出备份
+		bootstrap.RunBackup(backupPath, backupThumbnails)
+		return
+	}
+
+	if restorePath != "" {
+		// 从备份恢复
+		bootstrap.RunRestore(restorePath, restoreForce)
+		return
+	}
+
 	api := routers.InitRouter()
 	server := &http.Server{Handler: api}
 
+	// 如果启用了 S3 兼容接口
+	var s3Server *http.Server
+	if conf.S3Config.Listen != "" && conf.SystemConfig.Mode == "master" {
+		s3Server = &http.Server{Addr: conf.S3Config.Listen, Handler: routers.InitS3Router()}
+		go func() {
+			util.Log().Info("S3 兼容接口开始监听 %s", conf.S3Config.Listen)
+			if err := s3Server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				util.Log().Error("无法监听[%s]，%s", conf.S3Config.Listen, err)
+			}
+		}()
+	}
+
+	// 如果启用了 FTP 服务
+	var ftpServer *ftp.Server
+	if conf.FTPConfig.Listen != "" && conf.SystemConfig.Mode == "master" {
+		var err error
+		if ftpServer, err = ftp.NewCloudreveServer(); err != nil {
+			util.Log().Error("无法启动 FTP 服务，%s", err)
+		} else {
+			go func() {
+				util.Log().Info("FTP 服务开始监听 %s", conf.FTPConfig.Listen)
+				if err := ftpServer.ListenAndServe(); err != nil && err != ftp.ErrServerClosed {
+					util.Log().Error("无法监听[%s]，%s", conf.FTPConfig.Listen, err)
+				}
+			}()
+		}
+	}
+
+	// 如果启用了 SFTP 服务
+	var sftpServer *sftp.Server
+	if conf.SFTPConfig.Listen != "" && conf.SystemConfig.Mode == "master" {
+		var err error
+		if sftpServer, err = sftp.NewCloudreveServer(); err != nil {
+			util.Log().Error("无法启动 SFTP 服务，%s", err)
+		} else {
+			go func() {
+				util.Log().Info("SFTP 服务开始监听 %s", conf.SFTPConfig.Listen)
+				if err := sftpServer.ListenAndServe(); err != nil && err != sftp.ErrServerClosed {
+					util.Log().Error("无法监听[%s]，%s", conf.SFTPConfig.Listen, err)
+				}
+			}()
+		}
+	}
+
+	// 如果启用了 DLNA 媒体服务
+	var dlnaServer *dlna.Server
+	if conf.DLNAConfig.Listen != "" && conf.SystemConfig.Mode == "master" {
+		dlnaServer = dlna.NewCloudreveServer()
+		go func() {
+			util.Log().Info("DLNA 媒体服务开始监听 %s", conf.DLNAConfig.Listen)
+			if err := dlnaServer.ListenAndServe(); err != nil && err != dlna.ErrServerClosed {
+				util.Log().Error("无法启动 DLNA 媒体服务[%s]，%s", conf.DLNAConfig.Listen, err)
+			}
+		}()
+	}
+
+	// 如果启用了 gRPC 接口
+	var rpcServer *grpc.Server
+	if conf.RPCConfig.Listen != "" && conf.SystemConfig.Mode == "master" {
+		var err error
+		if rpcServer, err = routers.InitRPCServer(); err != nil {
+			util.Log().Error("无法启动 gRPC 接口，%s", err)
+		} else {
+			go func() {
+				util.Log().Info("gRPC 接口开始监听 %s", conf.RPCConfig.Listen)
+				listener, err := net.Listen("tcp", conf.RPCConfig.Listen)
+				if err == nil {
+					err = rpcServer.Serve(listener)
+				}
+				if err != nil && err != grpc.ErrServerStopped {
+					util.Log().Error("无法监听[%s]，%s", conf.RPCConfig.Listen, err)
+				}
+			}()
+		}
+	}
+
 	// 收到信号后关闭服务器
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
@@ -79,10 +182,50 @@ func main() {
 			defer cancel()
 		}
 
+		if s3Server != nil {
+			if err := s3Server.Shutdown(ctx); err != nil {
+				util.Log().Error("关闭 S3 server 错误, %s", err)
+			}
+		}
+
+		if ftpServer != nil {
+			if err := ftpServer.Shutdown(); err != nil {
+				util.Log().Error("关闭 FTP server 错误, %s", err)
+			}
+		}
+
+		if sftpServer != nil {
+			if err := sftpServer.Shutdown(); err != nil {
+				util.Log().Error("关闭 SFTP server 错误, %s", err)
+			}
+		}
+
+		if dlnaServer != nil {
+			if err := dlnaServer.Shutdown(); err != nil {
+				util.Log().Error("关闭 DLNA server 错误, %s", err)
+			}
+		}
+
+		if rpcServer != nil {
+			rpcServer.Stop()
+		}
+
+		// 写入尚未汇总的流量统计
+		if conf.SystemConfig.Mode == "master" {
+			if err := stats.Flush(); err != nil {
+				util.Log().Warning("无法写入流量统计, %s", err)
+			}
+		}
+
 		err := server.Shutdown(ctx)
 		if err != nil {
 			util.Log().Error("关闭 server 错误, %s", err)
 		}
+
+		// 导出尚未发送的链路追踪数据
+		if err := tracing.Shutdown(ctx); err != nil {
+			util.Log().Warning("无法导出链路追踪数据, %s", err)
+		}
 	}()
 
 	// 如果启用了SSL
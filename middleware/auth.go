@@ -9,6 +9,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/oss"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/upyun"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/qiniu/go-sdk/v7/auth/qbox"
 	"io/ioutil"
@@ -18,6 +19,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
 )
@@ -50,12 +52,46 @@ func SignRequired(authInstance auth.Auth) gin.HandlerFunc {
 // CurrentUser 获取登录用户
 func CurrentUser() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// 使用个人访问令牌时不读取会话
+		if raw := bearerToken(c); raw != "" {
+			if res := useAPIToken(c, raw); res.Code != 0 {
+				c.JSON(200, res)
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		session := sessions.Default(c)
 		uid := session.Get("user_id")
 		if uid != nil {
-			user, err := model.GetActiveUserByID(uid)
-			if err == nil {
-				c.Set("user", &user)
+			if user := sessionUser(c, uid); user != nil {
+				c.Set("user", user)
+				if user.Impersonator == 0 {
+					stats.MarkActive(user.ID)
+				}
+
+				// 子账户只能访问限定目录内的对象
+				if user.SubAccount != nil && !subAccountAllowed(c, user) {
+					c.JSON(200, serializer.Err(serializer.CodeSubAccountRestricted, "This operation is not allowed for sub-accounts", nil))
+					c.Abort()
+					return
+				}
+
+				// 管理员模拟用户时限制可访问的接口，并记录修改请求
+				if user.Impersonator != 0 {
+					if isCredentialRoute(c.FullPath()) {
+						c.JSON(200, serializer.Err(serializer.CodeImpersonationRestricted, "This operation is not allowed while impersonating", nil))
+						c.Abort()
+						return
+					}
+
+					if c.Request.Method != "GET" && c.Request.Method != "HEAD" {
+						model.RecordAudit(c, user.ID, model.AuditImpersonateRequest, model.AuditTarget("user", user.ID),
+							c.Request.Method+" "+c.Request.URL.Path)
+					}
+				}
 			}
 		}
 		c.Next()
@@ -66,7 +102,14 @@ func CurrentUser() gin.HandlerFunc {
 func AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if user, _ := c.Get("user"); user != nil {
-			if _, ok := user.(*model.User); ok {
+			if u, ok := user.(*model.User); ok {
+				// 用户组限制了可访问的IP范围
+				if !u.Group.AllowIP(c.ClientIP()) {
+					c.JSON(200, serializer.Err(serializer.CodeIPNotAllowed, "Access from your IP address is not allowed", nil))
+					c.Abort()
+					return
+				}
+
 				c.Next()
 				return
 			}
@@ -77,6 +120,76 @@ func AuthRequired() gin.HandlerFunc {
 	}
 }
 
+// twoFactorEnrollRoutes 用户组要求开启二步验证时，未开启的用户仍可访问的接口
+var twoFactorEnrollRoutes = map[string]bool{
+	"/api/v3/user/me":              true,
+	"/api/v3/user/session":         true,
+	"/api/v3/user/setting":         true,
+	"/api/v3/user/setting/2fa":     true,
+	"/api/v3/user/authn":           true,
+	"/api/v3/user/authn/finish":    true,
+	"/api/v3/user/setting/:option": true,
+}
+
+// TwoFactorEnforced 用户组要求开启二步验证时，拒绝未开启的用户访问开启二步验证以外的接口
+func TwoFactorEnforced() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*model.User)
+		if !user.Group.OptionsSerialized.Require2FA || user.RequireSecondFactor() || user.Impersonator != 0 {
+			c.Next()
+			return
+		}
+
+		if twoFactorEnrollRoutes[c.FullPath()] {
+			option := c.Param("option")
+			if option == "" || option == "2fa" || option == "authn_2fa" {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(200, serializer.Err(serializer.CodeTwoFactorRequired, "Two-factor authentication is required by your user group", nil))
+		c.Abort()
+	}
+}
+
+// passwordChangeRoutes 密码过期后仍可访问的接口
+var passwordChangeRoutes = map[string]bool{
+	"/api/v3/user/me":              true,
+	"/api/v3/user/session":         true,
+	"/api/v3/user/setting":         true,
+	"/api/v3/user/setting/:option": true,
+}
+
+// PasswordRotation 密码超过有效期时，拒绝用户访问修改密码以外的接口
+func PasswordRotation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*model.User)
+		if user.Impersonator != 0 || user.SubAccount != nil || !password.NewPolicy().Expired(user) {
+			c.Next()
+			return
+		}
+
+		if passwordChangeRoutes[c.FullPath()] {
+			option := c.Param("option")
+			if option == "" || option == "password" {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(200, serializer.Err(serializer.CodePasswordExpired, "Your password has expired, please change it", nil))
+		c.Abort()
+	}
+}
+
+// webdavReadMethods 只读 WebDAV 账户可使用的请求方法
+var webdavReadMethods = map[string]bool{
+	"GET":      true,
+	"HEAD":     true,
+	"PROPFIND": true,
+}
+
 // WebDAVAuth 验证WebDAV登录及权限
 func WebDAVAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -101,21 +214,29 @@ func WebDAVAuth() gin.HandlerFunc {
 			return
 		}
 
-		// 密码正确？
+		// 密码正确且未过期？
 		webdav, err := model.GetWebdavByPassword(password, expectedUser.ID)
-		if err != nil {
+		if err != nil || webdav.Expired() {
 			c.Status(http.StatusUnauthorized)
 			c.Abort()
 			return
 		}
 
-		// 用户组已启用WebDAV？
-		if !expectedUser.Group.WebDAVEnabled {
+		// 用户组已启用WebDAV？是否允许从当前IP访问？
+		if !expectedUser.Group.WebDAVEnabled || !expectedUser.Group.AllowIP(c.ClientIP()) {
 			c.Status(http.StatusForbidden)
 			c.Abort()
 			return
 		}
 
+		// 只读账户仅允许读取
+		if webdav.Readonly && !webdavReadMethods[c.Request.Method] {
+			c.Status(http.StatusForbidden)
+			c.Abort()
+			return
+		}
+
+		webdav.Touch(c.ClientIP())
 		c.Set("user", &expectedUser)
 		c.Set("webdav", webdav)
 		c.Next()
@@ -284,6 +405,38 @@ func OneDriveCallbackAuth() gin.HandlerFunc {
 	}
 }
 
+// adminReadRoutes 使用 POST 方法的只读管理接口，不记录审计事件
+var adminReadRoutes = map[string]bool{
+	"/api/v3/admin/audit/list":      true,
+	"/api/v3/admin/audit/search":    true,
+	"/api/v3/admin/policy/list":     true,
+	"/api/v3/admin/group/list":      true,
+	"/api/v3/admin/user/list":       true,
+	"/api/v3/admin/file/list":       true,
+	"/api/v3/admin/share/list":      true,
+	"/api/v3/admin/share/search":    true,
+	"/api/v3/admin/share/lock_logs": true,
+	"/api/v3/admin/share/folder":    true,
+	"/api/v3/admin/download/list":   true,
+	"/api/v3/admin/task/list":       true,
+	"/api/v3/admin/oauth/list":      true,
+	"/api/v3/admin/invitation/list": true,
+	"/api/v3/admin/node/list":       true,
+}
+
+// AuditAdminRequest 记录管理员发起的修改请求
+func AuditAdminRequest() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != "GET" && c.Request.Method != "HEAD" && !adminReadRoutes[c.FullPath()] {
+			if user, ok := c.Get("user"); ok {
+				model.RecordAudit(c, user.(*model.User).ID, model.AuditAdminRequest, "",
+					c.Request.Method+" "+c.Request.URL.Path)
+			}
+		}
+		c.Next()
+	}
+}
+
 // IsAdmin 必须为管理员用户组
 func IsAdmin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -1,12 +1,20 @@
 package middleware
 
 import (
+	"regexp"
+	"time"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
 )
 
+// requestIDPattern 可沿用的请求 ID 格式
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
 // HashID 将给定对象的HashID转换为真实ID
 func HashID(IDType int) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -45,3 +53,24 @@ func CacheControl() gin.HandlerFunc {
 		c.Header("Cache-Control", "private, no-cache")
 	}
 }
+
+// RequestID 为请求分配 ID 并写入请求上下文及响应头，反向代理传入合法的 X-Request-ID 时沿用。
+// 请求结束后以 http 模块记录访问日志
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader("X-Request-ID")
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.Must(uuid.NewV4()).String()
+		}
+
+		c.Set("request_id", id)
+		c.Header("X-Request-ID", id)
+		c.Request = c.Request.WithContext(util.WithRequestID(c.Request.Context(), id))
+
+		start := time.Now()
+		c.Next()
+
+		util.Log().Module("http").WithRequestID(id).Debug("%s %s %d %s",
+			c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"github.com/cloudreve/Cloudreve/v3/bootstrap"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/unfurl"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"io/ioutil"
@@ -39,7 +40,7 @@ func FrontendFileHandler() gin.HandlerFunc {
 		path := c.Request.URL.Path
 
 		// API 跳过
-		if strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/custom") || strings.HasPrefix(path, "/dav") || path == "/manifest.json" {
+		if strings.HasPrefix(path, "/api") || strings.HasPrefix(path, "/custom") || strings.HasPrefix(path, "/dav") || path == "/manifest.json" || path == "/healthz" {
 			c.Next()
 			return
 		}
@@ -56,6 +57,13 @@ func FrontendFileHandler() gin.HandlerFunc {
 				"{pwa_small_icon}": options["pwa_small_icon"],
 			}, fileContent)
 
+			// 分享页面插入预览卡片信息
+			if key, ok := unfurl.ShareKey(path); ok && unfurl.Enabled() {
+				if card := unfurl.ForShare(c, key); card != nil {
+					finalHTML = strings.Replace(finalHTML, "</head>", card.MetaTags()+"</head>", 1)
+				}
+			}
+
 			c.Header("Content-Type", "text/html")
 			c.String(200, finalHTML)
 			c.Abort()
@@ -123,7 +123,7 @@ func TestFrontendFileHandler(t *testing.T) {
 
 	// API 相关跳过
 	{
-		for _, reqPath := range []string{"/api/user", "/manifest.json", "/dav/path"} {
+		for _, reqPath := range []string{"/api/user", "/manifest.json", "/dav/path", "/healthz"} {
 			file, _ := util.CreatNestedFile("tests/index.html")
 			defer file.Close()
 			testStatic := &StaticMock{}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// IdempotencyKeyHeader 客户端提供幂等键的请求头
+	IdempotencyKeyHeader   = "Idempotency-Key"
+	idempotencyCachePrefix = "idempotency_"
+	idempotencyPending     = "pending"
+)
+
+// idempotencyLock 保证检查与占用幂等键的原子性
+var idempotencyLock sync.Mutex
+
+// responseRecorder 记录写出的响应内容
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotent 对携带幂等键的任务创建请求去重，
+// 同一用户重复提交相同幂等键时直接返回首次成功请求的结果
+func Idempotent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		if len(key) > 255 {
+			c.JSON(200, serializer.ParamErr("Idempotency key is too long", nil))
+			c.Abort()
+			return
+		}
+
+		var uid uint
+		if user, ok := c.Get("user"); ok {
+			if u, ok := user.(*model.User); ok {
+				uid = u.ID
+			}
+		}
+		cacheKey := fmt.Sprintf("%d_%s_%s", uid, c.FullPath(), key)
+
+		idempotencyLock.Lock()
+		if res, ok := cache.Get(idempotencyCachePrefix + cacheKey); ok {
+			idempotencyLock.Unlock()
+			if body, ok := res.(string); ok && body != idempotencyPending {
+				c.Data(200, "application/json; charset=utf-8", []byte(body))
+			} else {
+				c.JSON(200, serializer.Err(serializer.CodeConflict, "A request with the same idempotency key is in progress", nil))
+			}
+			c.Abort()
+			return
+		}
+
+		ttl := model.GetIntSetting("idempotency_key_ttl", 86400)
+		cache.Set(idempotencyCachePrefix+cacheKey, idempotencyPending, ttl)
+		idempotencyLock.Unlock()
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// 只记录成功的结果，失败的请求可以使用相同幂等键重试
+		var res serializer.Response
+		if err := json.Unmarshal(recorder.body.Bytes(), &res); err == nil && res.Code == 0 {
+			cache.Set(idempotencyCachePrefix+cacheKey, recorder.body.String(), ttl)
+			return
+		}
+
+		cache.Deletes([]string{cacheKey}, idempotencyCachePrefix)
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdempotent(t *testing.T) {
+	a := assert.New(t)
+	cache.Set("setting_idempotency_key_ttl", "60", 0)
+
+	called := 0
+	code := 0
+	r := gin.New()
+	r.POST("/task", Idempotent(), func(c *gin.Context) {
+		called++
+		c.JSON(200, serializer.Response{Code: code, Data: called})
+	})
+	request := func(key string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/task", nil)
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// 未提供幂等键
+	{
+		request("")
+		request("")
+		a.Equal(2, called)
+	}
+
+	// 重复提交返回首次结果
+	{
+		first := request("key1")
+		second := request("key1")
+		a.Equal(3, called)
+		a.Equal(first.Body.String(), second.Body.String())
+	}
+
+	// 失败的请求可以重试
+	{
+		code = serializer.CodeParamErr
+		request("key2")
+		code = 0
+		request("key2")
+		a.Equal(5, called)
+	}
+
+	// 相同幂等键的请求处理中
+	{
+		cache.Set(idempotencyCachePrefix+"0_/task_key3", idempotencyPending, 0)
+		res := request("key3")
+		a.Equal(5, called)
+		a.Contains(res.Body.String(), "409")
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// sessionUser 读取会话中的登录用户。管理员模拟其他用户时返回被模拟的用户，
+// 模拟已过期、管理员失去权限或被模拟用户不可用时结束模拟
+func sessionUser(c *gin.Context, uid interface{}) *model.User {
+	user, err := model.GetActiveUserByID(uid)
+	if err != nil || !trackSession(c, &user) {
+		return nil
+	}
+
+	// 子账户以母账户的身份操作限定的目录
+	if user.IsSubAccount() {
+		parent, err := model.GetActiveUserByID(user.ParentID)
+		if err != nil {
+			return nil
+		}
+
+		parent.SubAccount = &user
+		return &parent
+	}
+
+	targetID, ok := util.GetSession(c, "impersonate_id").(uint)
+	if !ok {
+		return &user
+	}
+
+	until, _ := util.GetSession(c, "impersonate_until").(int64)
+	target, err := model.GetActiveUserByID(targetID)
+	if err != nil || !user.IsAdmin() || time.Now().Unix() > until {
+		util.DeleteSession(c, "impersonate_id")
+		util.DeleteSession(c, "impersonate_until")
+		model.RecordAudit(c, user.ID, model.AuditImpersonateStop, model.AuditTarget("user", targetID), "expired")
+		return &user
+	}
+
+	target.Impersonator = user.ID
+	return &target
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionUser(t *testing.T) {
+	asserts := assert.New(t)
+	sessionFunc := Session("233")
+
+	newContext := func(until int64) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		sessionFunc(c)
+		util.SetSession(c, map[string]interface{}{
+			"user_id":           1,
+			"session_key":       "valid",
+			"impersonate_id":    uint(2),
+			"impersonate_until": until,
+		})
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(1, "{}"))
+		mock.ExpectQuery("SELECT(.+)user_sessions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "ip", "last_seen"}).AddRow(1, 1, "", time.Now()))
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(2, "{}"))
+		return c
+	}
+
+	// 模拟中
+	{
+		c := newContext(time.Now().Add(time.Hour).Unix())
+		user := sessionUser(c, 1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(user)
+		asserts.EqualValues(2, user.ID)
+		asserts.EqualValues(1, user.Impersonator)
+	}
+
+	// 模拟已过期
+	{
+		c := newContext(time.Now().Add(-time.Hour).Unix())
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		user := sessionUser(c, 1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(user)
+		asserts.EqualValues(1, user.ID)
+		asserts.EqualValues(0, user.Impersonator)
+		asserts.Nil(util.GetSession(c, "impersonate_id"))
+	}
+}
+
+func TestSessionUser_SubAccount(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/test", nil)
+	Session("233")(c)
+	util.SetSession(c, map[string]interface{}{
+		"user_id":     3,
+		"session_key": "valid",
+	})
+
+	mock.ExpectQuery("SELECT(.+)users(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "options", "parent_id", "folder"}).AddRow(3, "{}", 1, "/family"))
+	mock.ExpectQuery("SELECT(.+)user_sessions(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "ip", "last_seen"}).AddRow(1, 3, "", time.Now()))
+	mock.ExpectQuery("SELECT(.+)users(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "options"}).AddRow(1, "{}"))
+
+	user := sessionUser(c, 3)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NotNil(user)
+	asserts.EqualValues(1, user.ID)
+	asserts.NotNil(user.SubAccount)
+	asserts.EqualValues(3, user.SubAccount.ID)
+	asserts.Equal("/family", user.SubAccount.Folder)
+}
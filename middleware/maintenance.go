@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// inMaintenance 站点是否处于维护模式，管理员不受限制
+func inMaintenance(c *gin.Context) bool {
+	if !model.IsTrueVal(model.GetSettingByName("maintenance_enabled")) {
+		return false
+	}
+
+	if user, ok := c.Get("user"); ok {
+		if user, ok := user.(*model.User); ok && user.IsAdmin() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Maintenance 维护模式下拒绝发起新的上传或任务，已在进行的分片上传不受影响。
+// 指定 methods 时仅拦截对应方法的请求
+func Maintenance(methods ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(methods) > 0 && !containsMethod(methods, c.Request.Method) {
+			c.Next()
+			return
+		}
+
+		if inMaintenance(c) {
+			c.Header("Retry-After", "600")
+			c.JSON(http.StatusServiceUnavailable, serializer.Err(
+				serializer.CodeMaintenance,
+				model.GetSettingByName("maintenance_message"),
+				nil,
+			))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// S3Maintenance 维护模式下拒绝 S3 接口发起新的上传。携带 uploadId 的
+// 分片上传请求用于完成已在进行的上传，及针对存储桶的批量删除，予以放行
+func S3Maintenance() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, resuming := c.GetQuery("uploadId")
+		if !resuming && c.Param("key") != "/" && inMaintenance(c) {
+			c.Header("Retry-After", "600")
+			s3gateway.WriteError(c, s3gateway.ErrServiceUnavailable)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func containsMethod(methods []string, method string) bool {
+	for _, m := range methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenance(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_maintenance_message", "maintaining", 0)
+	TestFunc := Maintenance()
+
+	// 未开启
+	{
+		cache.Set("setting_maintenance_enabled", "0", 0)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("PUT", "/test", nil)
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	cache.Set("setting_maintenance_enabled", "1", 0)
+
+	// 普通用户被拒绝
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("PUT", "/test", nil)
+		c.Set("user", &model.User{GroupID: 2})
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusServiceUnavailable, rec.Code)
+		asserts.Contains(rec.Body.String(), "maintaining")
+	}
+
+	// 管理员不受限制
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("PUT", "/test", nil)
+		c.Set("user", &model.User{GroupID: 1})
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 仅拦截指定方法
+	{
+		TestFunc = Maintenance("PUT")
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("PROPFIND", "/test", nil)
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+
+		c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("PUT", "/test", nil)
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
+func TestS3Maintenance(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_maintenance_enabled", "1", 0)
+	TestFunc := S3Maintenance()
+
+	// 新的上传被拒绝
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("PUT", "/bucket/key", nil)
+		c.Params = gin.Params{{Key: "key", Value: "/key"}}
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusServiceUnavailable, rec.Code)
+		asserts.Contains(rec.Body.String(), "ServiceUnavailable")
+	}
+
+	// 已在进行的分片上传放行
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("PUT", "/bucket/key?partNumber=1&uploadId=1", nil)
+		c.Params = gin.Params{{Key: "key", Value: "/key"}}
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	cache.Set("setting_maintenance_enabled", "0", 0)
+}
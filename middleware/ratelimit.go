@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// 限流的接口类别，对应站点设置 rate_limit_<类别> 及用户组设置中的键
+const (
+	RateLimitAuth          = "auth"
+	RateLimitUpload        = "upload"
+	RateLimitShareDownload = "share_download"
+	RateLimitAPI           = "api"
+)
+
+// rateLimitWindow 限流计数的时间窗口，单位为秒
+const rateLimitWindow = 60
+
+// clientSubject 返回匿名请求的计数对象。ClientIP 仅采信受信任的反向代理转发的地址，
+// 无法解析时使用连接的对端地址；IPv6 地址按 /64 网段计数，以免通过更换地址绕过限制
+func clientSubject(c *gin.Context) string {
+	ip := net.ParseIP(c.ClientIP())
+	if ip == nil {
+		ip, _ = c.RemoteIP()
+	}
+	if ip == nil {
+		return "ip_unknown"
+	}
+
+	if ip.To4() == nil {
+		ip = ip.Mask(net.CIDRMask(64, 128))
+	}
+	return "ip_" + ip.String()
+}
+
+// RateLimit 限制给定类别接口每分钟的请求数。已登录用户按用户计数，
+// 上限优先取自用户组设置；匿名请求按 IP 计数，使用站点默认值
+func RateLimit(category string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options := model.GetSettingByNames("rate_limit_enabled", "rate_limit_"+category)
+		if !model.IsTrueVal(options["rate_limit_enabled"]) {
+			c.Next()
+			return
+		}
+
+		limit, _ := strconv.Atoi(options["rate_limit_"+category])
+		subject := clientSubject(c)
+		if user, ok := c.Get("user"); ok {
+			if user, ok := user.(*model.User); ok && user.ID > 0 {
+				subject = "user_" + strconv.FormatUint(uint64(user.ID), 10)
+				if groupLimit := user.Group.OptionsSerialized.RateLimits[category]; groupLimit != 0 {
+					limit = groupLimit
+				}
+			}
+		}
+
+		if limit <= 0 {
+			c.Next()
+			return
+		}
+
+		// 固定窗口计数，计数器随窗口过期
+		now := time.Now().Unix()
+		window := now / rateLimitWindow
+		reset := (window+1)*rateLimitWindow - now
+		count, err := cache.Incr(fmt.Sprintf("rate_limit_%s_%s_%d", category, subject, window), rateLimitWindow)
+		if err != nil {
+			util.Log().Warning("无法记录请求频率, %s", err)
+			c.Next()
+			return
+		}
+
+		remaining := int64(limit) - count
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+		c.Header("X-RateLimit-Remaining", strconv.FormatInt(remaining, 10))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+
+		if count > int64(limit) {
+			c.Header("Retry-After", strconv.FormatInt(reset, 10))
+			c.JSON(http.StatusTooManyRequests, serializer.Err(serializer.CodeRateLimited, "Too many requests, please try again later", nil))
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
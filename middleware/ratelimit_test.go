@@ -0,0 +1,123 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_rate_limit_test", "2", 0)
+	TestFunc := RateLimit("test")
+
+	// 未开启
+	{
+		cache.Set("setting_rate_limit_enabled", "0", 0)
+		for i := 0; i < 3; i++ {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			TestFunc(c)
+			asserts.False(c.IsAborted())
+		}
+	}
+
+	cache.Set("setting_rate_limit_enabled", "1", 0)
+
+	// 匿名用户按 IP 计数
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.RemoteAddr = "192.168.1.1:1234"
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+		asserts.Equal("2", c.Writer.Header().Get("X-RateLimit-Limit"))
+		asserts.Equal("1", c.Writer.Header().Get("X-RateLimit-Remaining"))
+
+		c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.RemoteAddr = "192.168.1.1:1234"
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+
+		rec := httptest.NewRecorder()
+		c, _ = gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.RemoteAddr = "192.168.1.1:1234"
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusTooManyRequests, rec.Code)
+		asserts.NotEmpty(rec.Header().Get("Retry-After"))
+
+		// 其他 IP 不受影响
+		c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.RemoteAddr = "192.168.1.2:1234"
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 用户组不限制
+	{
+		user := &model.User{}
+		user.ID = 1
+		user.Group.OptionsSerialized.RateLimits = map[string]int{"test": -1}
+		for i := 0; i < 3; i++ {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request, _ = http.NewRequest("GET", "/test", nil)
+			c.Set("user", user)
+			TestFunc(c)
+			asserts.False(c.IsAborted())
+		}
+	}
+
+	// 用户组自定义上限
+	{
+		user := &model.User{}
+		user.ID = 2
+		user.Group.OptionsSerialized.RateLimits = map[string]int{"test": 1}
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Set("user", user)
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+		asserts.Equal("1", c.Writer.Header().Get("X-RateLimit-Limit"))
+
+		c, _ = gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Set("user", user)
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
+func TestRateLimit_ClientSubject(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_rate_limit_enabled", "1", 0)
+	cache.Set("setting_rate_limit_forged", "1", 0)
+	TestFunc := RateLimit("forged")
+
+	request := func(remoteAddr, forwarded string) *gin.Context {
+		c, r := gin.CreateTestContext(httptest.NewRecorder())
+		r.SetTrustedProxies(nil)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.RemoteAddr = remoteAddr
+		c.Request.Header.Set("X-Forwarded-For", forwarded)
+		TestFunc(c)
+		return c
+	}
+
+	// 不受信任的对端伪造请求头不能绕过限制
+	asserts.False(request("192.168.2.1:1234", "1.1.1.1").IsAborted())
+	asserts.True(request("192.168.2.1:1234", "2.2.2.2").IsAborted())
+
+	// 同一 /64 网段的 IPv6 地址共用计数
+	asserts.False(request("[2001:db8::1]:1234", "").IsAborted())
+	asserts.True(request("[2001:db8::2]:1234", "").IsAborted())
+	asserts.False(request("[2001:db8:0:1::1]:1234", "").IsAborted())
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// S3Auth 校验 S3 兼容接口请求的签名，并设置访问密钥所属用户
+func S3Auth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		fail := func(err error) {
+			s3gateway.WriteError(c, err)
+			c.Abort()
+		}
+
+		sig, err := s3gateway.Parse(c.Request)
+		if err != nil {
+			fail(err)
+			return
+		}
+
+		key, err := model.GetS3AccessKey(sig.AccessKey)
+		if err != nil {
+			fail(s3gateway.ErrInvalidAccessKeyID)
+			return
+		}
+
+		if err := sig.Verify(c.Request, key.Secret); err != nil {
+			fail(err)
+			return
+		}
+
+		// 用户组允许使用 S3 接口？是否允许从当前IP访问？
+		user, err := model.GetActiveUserByID(key.UserID)
+		if err != nil || !user.Group.OptionsSerialized.S3Gateway || !user.Group.AllowIP(c.ClientIP()) {
+			fail(s3gateway.ErrAccessDenied)
+			return
+		}
+
+		// 只读密钥仅允许读取
+		if key.Readonly && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			fail(s3gateway.ErrAccessDenied)
+			return
+		}
+
+		key.Touch(c.ClientIP())
+		c.Set("user", &user)
+		c.Set("s3_key", key)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3Auth(t *testing.T) {
+	asserts := assert.New(t)
+	AuthFunc := S3Auth()
+	now := time.Now().UTC().Format("20060102T150405Z")
+	signed := func() *http.Request {
+		r, _ := http.NewRequest("GET", "/bucket/key", nil)
+		r.Header.Set("X-Amz-Date", now)
+		r.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+		r.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential=CRKEY/"+now[:8]+"/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=abc")
+		return r
+	}
+
+	// 未携带签名
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/bucket/key", nil)
+		AuthFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusForbidden, rec.Code)
+		asserts.Contains(rec.Body.String(), "<Code>AccessDenied</Code>")
+	}
+
+	// 访问密钥不存在
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = signed()
+		mock.ExpectQuery("SELECT(.+)s3_access_keys(.+)").WithArgs("CRKEY").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		AuthFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), "<Code>InvalidAccessKeyId</Code>")
+	}
+
+	// 签名错误
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = signed()
+		mock.ExpectQuery("SELECT(.+)s3_access_keys(.+)").WithArgs("CRKEY").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "access_key", "secret"}).AddRow(1, 1, "CRKEY", "secret"))
+		AuthFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+		asserts.Contains(rec.Body.String(), "<Code>SignatureDoesNotMatch</Code>")
+	}
+}
@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+)
+
+// corsCache 按当前跨域设置构建的中间件，设置变更后重新构建
+var corsCache struct {
+	sync.Mutex
+	key     string
+	handler gin.HandlerFunc
+}
+
+// CORS 按站点设置处理跨域请求，未设置允许的来源时沿用配置文件中的跨域配置
+func CORS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if handler := currentCORSHandler(); handler != nil {
+			handler(c)
+			if c.IsAborted() {
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func currentCORSHandler() gin.HandlerFunc {
+	options := model.GetSettingByNames("cors_allow_origins", "cors_allow_credentials")
+	config := cors.Config{
+		AllowMethods:     conf.CORSConfig.AllowMethods,
+		AllowHeaders:     conf.CORSConfig.AllowHeaders,
+		ExposeHeaders:    conf.CORSConfig.ExposeHeaders,
+		AllowCredentials: model.IsTrueVal(options["cors_allow_credentials"]),
+	}
+
+	origins, err := util.ParseOrigins(options["cors_allow_origins"])
+	if err != nil {
+		util.Log().Warning("跨域来源设置无效，%s", err)
+	}
+
+	if len(origins) > 0 {
+		config.AllowOrigins = origins
+	} else if conf.CORSConfig.AllowOrigins[0] != "UNSET" {
+		config.AllowOrigins = conf.CORSConfig.AllowOrigins
+		config.AllowCredentials = conf.CORSConfig.AllowCredentials
+	} else {
+		return nil
+	}
+
+	key := strings.Join(config.AllowOrigins, ",") + "|" + strconv.FormatBool(config.AllowCredentials)
+	corsCache.Lock()
+	defer corsCache.Unlock()
+	if corsCache.key == key {
+		return corsCache.handler
+	}
+
+	corsCache.key = key
+	corsCache.handler = nil
+	if err := config.Validate(); err != nil {
+		util.Log().Warning("跨域配置无效，%s", err)
+		return nil
+	}
+
+	corsCache.handler = cors.New(config)
+	return corsCache.handler
+}
+
+// SecurityHeaders 按站点设置添加 CSP、HSTS 等安全响应头，
+// 以便在不重新编译的情况下允许其他站点嵌入预览页面
+func SecurityHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		options := model.GetSettingByNames(
+			"security_csp",
+			"security_frame_ancestors",
+			"security_hsts_max_age",
+			"security_hsts_subdomains",
+		)
+
+		frameAncestors := strings.TrimSpace(options["security_frame_ancestors"])
+		if csp := buildCSP(options["security_csp"], frameAncestors); csp != "" {
+			c.Header("Content-Security-Policy", csp)
+		}
+
+		// 仅能以 X-Frame-Options 表达的取值同时设置，兼容旧浏览器
+		switch frameAncestors {
+		case "'none'":
+			c.Header("X-Frame-Options", "DENY")
+		case "'self'":
+			c.Header("X-Frame-Options", "SAMEORIGIN")
+		}
+
+		// HSTS 仅对 HTTPS 请求生效
+		maxAge, _ := strconv.Atoi(options["security_hsts_max_age"])
+		if maxAge > 0 && (c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https") {
+			hsts := "max-age=" + strconv.Itoa(maxAge)
+			if model.IsTrueVal(options["security_hsts_subdomains"]) {
+				hsts += "; includeSubDomains"
+			}
+			c.Header("Strict-Transport-Security", hsts)
+		}
+
+		c.Next()
+	}
+}
+
+// buildCSP 合并 CSP 设置与 frame-ancestors 设置，CSP 中已指定 frame-ancestors 时以其为准
+func buildCSP(csp, frameAncestors string) string {
+	csp = strings.TrimRight(strings.TrimSpace(csp), ";")
+	if frameAncestors == "" || strings.Contains(csp, "frame-ancestors") {
+		return csp
+	}
+
+	if csp == "" {
+		return "frame-ancestors " + frameAncestors
+	}
+	return csp + "; frame-ancestors " + frameAncestors
+}
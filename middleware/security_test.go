@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_cors_allow_credentials", "0", 0)
+	TestFunc := CORS()
+
+	// 未设置允许的来源
+	{
+		cache.Set("setting_cors_allow_origins", "", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.Header.Set("Origin", "https://a.example.com")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+		asserts.Empty(rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	cache.Set("setting_cors_allow_origins", "https://a.example.com", 0)
+
+	// 允许的来源
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.Header.Set("Origin", "https://a.example.com")
+		TestFunc(c)
+		asserts.False(c.IsAborted())
+		asserts.Equal("https://a.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// 不允许的来源
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.Header.Set("Origin", "https://b.example.com")
+		TestFunc(c)
+		asserts.True(c.IsAborted())
+		asserts.Equal(http.StatusForbidden, rec.Code)
+	}
+
+	cache.Set("setting_cors_allow_origins", "", 0)
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	asserts := assert.New(t)
+	TestFunc := SecurityHeaders()
+	cache.Set("setting_security_csp", "default-src 'self';", 0)
+	cache.Set("setting_security_frame_ancestors", "'self'", 0)
+	cache.Set("setting_security_hsts_max_age", "31536000", 0)
+	cache.Set("setting_security_hsts_subdomains", "1", 0)
+
+	// HTTP 请求不设置 HSTS
+	{
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		TestFunc(c)
+		asserts.Equal("default-src 'self'; frame-ancestors 'self'", rec.Header().Get("Content-Security-Policy"))
+		asserts.Equal("SAMEORIGIN", rec.Header().Get("X-Frame-Options"))
+		asserts.Empty(rec.Header().Get("Strict-Transport-Security"))
+	}
+
+	// 反向代理后的 HTTPS 请求
+	{
+		cache.Set("setting_security_frame_ancestors", "'self' https://*.example.com", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		c.Request.Header.Set("X-Forwarded-Proto", "https")
+		TestFunc(c)
+		asserts.Equal("default-src 'self'; frame-ancestors 'self' https://*.example.com", rec.Header().Get("Content-Security-Policy"))
+		asserts.Empty(rec.Header().Get("X-Frame-Options"))
+		asserts.Equal("max-age=31536000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+	}
+
+	// 未设置时不添加响应头
+	{
+		cache.Set("setting_security_csp", "", 0)
+		cache.Set("setting_security_frame_ancestors", "", 0)
+		cache.Set("setting_security_hsts_max_age", "0", 0)
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		TestFunc(c)
+		asserts.Empty(rec.Header().Get("Content-Security-Policy"))
+		asserts.Empty(rec.Header().Get("X-Frame-Options"))
+	}
+}
+
+func TestBuildCSP(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("", buildCSP("", ""))
+	asserts.Equal("frame-ancestors 'none'", buildCSP("", "'none'"))
+	asserts.Equal("frame-ancestors https://a.com", buildCSP("frame-ancestors https://a.com", "'none'"))
+}
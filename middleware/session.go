@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -8,6 +10,7 @@ import (
 	"github.com/gin-contrib/sessions/memstore"
 	"github.com/gin-contrib/sessions/redis"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // Store session存储
@@ -18,7 +21,7 @@ func Session(secret string) gin.HandlerFunc {
 	// Redis设置不为空，且非测试模式时使用Redis
 	if conf.RedisConfig.Server != "" && gin.Mode() != gin.TestMode {
 		var err error
-		Store, err = redis.NewStoreWithDB(10, conf.RedisConfig.Network, conf.RedisConfig.Server, conf.RedisConfig.Password, conf.RedisConfig.DB, []byte(secret))
+		Store, err = redis.NewStoreWithPool(cache.NewRedisPool(10, cache.RedisOptionsFromConfig()), []byte(secret))
 		if err != nil {
 			util.Log().Panic("无法连接到 Redis：%s", err)
 		}
@@ -34,6 +37,40 @@ func Session(secret string) gin.HandlerFunc {
 	return sessions.Sessions("cloudreve-session", Store)
 }
 
+// trackSession 记录登录会话的设备及访问信息，会话已被注销时清空会话并返回 false
+func trackSession(c *gin.Context, user *model.User) bool {
+	if key, ok := util.GetSession(c, "session_key").(string); ok && key != "" {
+		record, err := model.GetUserSession(key)
+		if err == gorm.ErrRecordNotFound {
+			util.ClearSession(c)
+			return false
+		}
+
+		if err != nil {
+			return true
+		}
+
+		if record.UserID == user.ID {
+			record.Touch(c.ClientIP())
+			return true
+		}
+	}
+
+	// 新登录的会话
+	record := &model.UserSession{
+		UserID:    user.ID,
+		UserAgent: c.Request.UserAgent(),
+		IP:        c.ClientIP(),
+	}
+	if err := record.Create(); err != nil {
+		util.Log().Warning("无法记录登录会话：%s", err)
+		return true
+	}
+
+	util.SetSession(c, map[string]interface{}{"session_key": record.SessionKey})
+	return true
+}
+
 // CSRFInit 初始化CSRF标记
 func CSRFInit() gin.HandlerFunc {
 	return func(c *gin.Context) {
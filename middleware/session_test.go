@@ -4,7 +4,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -71,3 +74,48 @@ func TestCSRFCheck(t *testing.T) {
 		asserts.True(c.IsAborted())
 	}
 }
+
+func TestTrackSession(t *testing.T) {
+	asserts := assert.New(t)
+	sessionFunc := Session("233")
+	user := &model.User{}
+	user.ID = 1
+
+	// 新登录的会话
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		sessionFunc(c)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.True(trackSession(c, user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEmpty(util.GetSession(c, "session_key"))
+	}
+
+	// 会话已被注销
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		sessionFunc(c)
+		util.SetSession(c, map[string]interface{}{"user_id": 1, "session_key": "revoked"})
+		mock.ExpectQuery("SELECT(.+)user_sessions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.False(trackSession(c, user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(util.GetSession(c, "user_id"))
+	}
+
+	// 有效会话
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request, _ = http.NewRequest("GET", "/test", nil)
+		sessionFunc(c)
+		util.SetSession(c, map[string]interface{}{"user_id": 1, "session_key": "valid"})
+		mock.ExpectQuery("SELECT(.+)user_sessions(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "ip", "last_seen"}).AddRow(1, 1, "", time.Now()))
+		asserts.True(trackSession(c, user))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("valid", util.GetSession(c, "session_key"))
+	}
+}
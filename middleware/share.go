@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -45,18 +47,66 @@ func ShareAvailable() gin.HandlerFunc {
 
 		share := model.GetShareByHashID(c.Param("id"))
 
-		if share == nil || !share.IsAvailable() {
+		if share == nil || !share.IsAvailable() || !share.IsAccessibleBy(user) {
 			c.JSON(200, serializer.Err(serializer.CodeNotFound, "分享不存在或已失效", nil))
 			c.Abort()
 			return
 		}
 
+		// 流量用尽后仅创建者可继续访问
+		if share.TrafficExhausted() && share.UserID != user.ID {
+			c.JSON(200, serializer.Err(serializer.CodeShareTrafficExhausted, "分享流量已用尽", nil))
+			c.Abort()
+			return
+		}
+
+		// 访问来源限制，创建者不受限制
+		if share.UserID != user.ID && !share.IsAccessibleFrom(c) {
+			c.JSON(200, serializer.Err(serializer.CodeShareAccessRestricted, "您所在的网络或地区无法访问此分享", nil))
+			c.Abort()
+			return
+		}
+
 		c.Set("user", user)
 		c.Set("share", share)
 		c.Next()
 	}
 }
 
+// FederatedShareAvailable 根据联邦分享令牌检查分享是否可用，其他实例的用户以匿名身份访问分享
+func FederatedShareAvailable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !model.IsTrueVal(model.GetSettingByName("federation_enabled")) {
+			c.JSON(200, serializer.Err(serializer.CodeFederationNotAllowed, "联邦分享未启用", nil))
+			c.Abort()
+			return
+		}
+
+		share := model.GetShareByFederationToken(c.Param("token"))
+		if share == nil || share.Internal || !share.IsAvailable() {
+			c.JSON(200, serializer.Err(serializer.CodeNotFound, "分享不存在或已失效", nil))
+			c.Abort()
+			return
+		}
+
+		if !share.IsAccessibleFrom(c) {
+			c.JSON(200, serializer.Err(serializer.CodeShareAccessRestricted, "您所在的网络或地区无法访问此分享", nil))
+			c.Abort()
+			return
+		}
+
+		if share.TrafficExhausted() {
+			c.JSON(200, serializer.Err(serializer.CodeShareTrafficExhausted, "分享流量已用尽", nil))
+			c.Abort()
+			return
+		}
+
+		c.Set("user", model.NewAnonymousUser())
+		c.Set("share", share)
+		c.Next()
+	}
+}
+
 // ShareCanPreview 检查分享是否可被预览
 func ShareCanPreview() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -74,8 +124,53 @@ func ShareCanPreview() gin.HandlerFunc {
 	}
 }
 
+// ShareCanDownload 检查分享是否允许下载
+func ShareCanDownload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if share, ok := c.Get("share"); ok {
+			if !share.(*model.Share).DownloadDisabled {
+				c.Next()
+				return
+			}
+			c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "此分享仅允许预览",
+				nil))
+			c.Abort()
+			return
+		}
+		c.Abort()
+	}
+}
+
+// ShareCanReshare 检查当前用户是否可以转发分享，创建者及拥有转发角色的接收者可以转发
+func ShareCanReshare() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		shareCtx, shareOk := c.Get("share")
+		userCtx, userOk := c.Get("user")
+		if shareOk && userOk {
+			if shareCtx.(*model.Share).Can(userCtx.(*model.User), model.ShareRoleReshare) {
+				c.Next()
+				return
+			}
+			c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr, "您无权转发此分享", nil))
+			c.Abort()
+			return
+		}
+		c.Abort()
+	}
+}
+
 // CheckShareUnlocked 检查分享是否已解锁
 func CheckShareUnlocked() gin.HandlerFunc {
+	return checkShareUnlocked(false)
+}
+
+// CheckSharePreviewUnlocked 检查分享是否已解锁，或请求携带了有效的预览令牌，
+// 仅用于预览相关的路由
+func CheckSharePreviewUnlocked() gin.HandlerFunc {
+	return checkShareUnlocked(true)
+}
+
+func checkShareUnlocked(allowToken bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		if shareCtx, ok := c.Get("share"); ok {
 			share := shareCtx.(*model.Share)
@@ -83,6 +178,9 @@ func CheckShareUnlocked() gin.HandlerFunc {
 			if share.Password != "" {
 				sessionKey := fmt.Sprintf("share_unlock_%d", share.ID)
 				unlocked := util.GetSession(c, sessionKey) != nil
+				if !unlocked && allowToken && c.Query("token") != "" {
+					unlocked = auth.CheckSharePreview(auth.General, share, c.Query("token"), c.Query("path")) == nil
+				}
 				if !unlocked {
 					c.JSON(200, serializer.Err(serializer.CodeNoPermissionErr,
 						"无权访问此分享", nil))
@@ -124,6 +222,14 @@ func BeforeShareDownload() gin.HandlerFunc {
 					return
 				}
 
+				// 记录访问事件，目录分享下记录所下载文件的路径
+				fileName := c.Query("path")
+				if fileName == "" {
+					fileName = share.SourceName
+				}
+				share.RecordAccess(c, model.ShareAccessDownload, fileName)
+				notify.ShareAccessed(c, share, model.ShareAccessDownload, fileName)
+
 				c.Next()
 				return
 			}
@@ -6,6 +6,8 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -49,6 +51,47 @@ func TestShareAvailable(t *testing.T) {
 		asserts.NotNil(c.Get("user"))
 		asserts.NotNil(c.Get("share"))
 	}
+
+	// 流量已用尽
+	{
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WillReturnRows(
+				sqlmock.NewRows(
+					[]string{"id", "remain_downloads", "source_id", "user_id", "traffic_limit", "traffic_used"}).
+					AddRow(1, 1, 2, 1, 10, 10),
+			)
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		c, _ := gin.CreateTestContext(rec)
+		c.Params = []gin.Param{
+			{"id", "x9T4"},
+		}
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+	}
+
+	// 访问来源受限
+	{
+		cache.Set("setting_share_allowed_ips", "", 0)
+		cache.Set("setting_share_allowed_countries", "", 0)
+		mock.ExpectQuery("SELECT(.+)groups(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WillReturnRows(
+				sqlmock.NewRows(
+					[]string{"id", "remain_downloads", "source_id", "user_id", "allowed_ips"}).
+					AddRow(1, 1, 2, 1, "10.0.0.0/8"),
+			)
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		c.Params = []gin.Param{
+			{"id", "x9T4"},
+		}
+		testFunc(c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(c.IsAborted())
+	}
 }
 
 func TestShareCanPreview(t *testing.T) {
@@ -80,6 +123,66 @@ func TestShareCanPreview(t *testing.T) {
 	}
 }
 
+func TestShareCanDownload(t *testing.T) {
+	asserts := assert.New(t)
+	rec := httptest.NewRecorder()
+	testFunc := ShareCanDownload()
+
+	// 无分享上下文
+	{
+		c, _ := gin.CreateTestContext(rec)
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 可以下载
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{})
+		testFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 禁止下载
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{DownloadDisabled: true})
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
+func TestShareCanReshare(t *testing.T) {
+	asserts := assert.New(t)
+	rec := httptest.NewRecorder()
+	testFunc := ShareCanReshare()
+
+	// 无分享上下文
+	{
+		c, _ := gin.CreateTestContext(rec)
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 创建者
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{UserID: 1, Internal: true})
+		c.Set("user", &model.User{Model: gorm.Model{ID: 1}})
+		testFunc(c)
+		asserts.False(c.IsAborted())
+	}
+
+	// 公开分享的访客
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Set("share", &model.Share{UserID: 1})
+		c.Set("user", &model.User{Model: gorm.Model{ID: 2}})
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
 func TestCheckShareUnlocked(t *testing.T) {
 	asserts := assert.New(t)
 	rec := httptest.NewRecorder()
@@ -102,6 +205,55 @@ func TestCheckShareUnlocked(t *testing.T) {
 
 }
 
+func TestCheckSharePreviewUnlocked(t *testing.T) {
+	asserts := assert.New(t)
+	rec := httptest.NewRecorder()
+	testFunc := CheckSharePreviewUnlocked()
+	sessionFunc := Session("233")
+	auth.General = auth.HMACAuth{SecretKey: []byte("test")}
+	share := &model.Share{Model: gorm.Model{ID: 1}, Password: "123"}
+
+	// 未解锁且无令牌
+	{
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/?path=/a.jpg", nil)
+		sessionFunc(c)
+		c.Set("share", share)
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 令牌有效
+	{
+		token := auth.SignSharePreview(auth.General, share, "/", 60)
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/?path=/a.jpg&token="+token, nil)
+		sessionFunc(c)
+		c.Set("share", share)
+		testFunc(c)
+		asserts.False(c.IsAborted())
+
+		// 普通路由不接受令牌
+		c, _ = gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/?path=/a.jpg&token="+token, nil)
+		sessionFunc(c)
+		c.Set("share", share)
+		CheckShareUnlocked()(c)
+		asserts.True(c.IsAborted())
+	}
+
+	// 令牌作用范围不匹配
+	{
+		token := auth.SignSharePreview(auth.General, share, "/gallery", 60)
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/?path=/a.jpg&token="+token, nil)
+		sessionFunc(c)
+		c.Set("share", share)
+		testFunc(c)
+		asserts.True(c.IsAborted())
+	}
+}
+
 func TestBeforeShareDownload(t *testing.T) {
 	asserts := assert.New(t)
 	rec := httptest.NewRecorder()
@@ -133,6 +285,7 @@ func TestBeforeShareDownload(t *testing.T) {
 	// 可以下载
 	{
 		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest("GET", "/", nil)
 		c.Set("share", &model.Share{})
 		c.Set("user", &model.User{
 			Model: gorm.Model{ID: 1},
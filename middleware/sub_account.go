@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+)
+
+// subAccountRoutes 子账户在限定目录的接口之外还可访问的接口
+var subAccountRoutes = map[string]bool{
+	"/api/v3/user/session": true,
+}
+
+// subAccountAllowed 检查子账户能否访问当前接口，子账户的权限与限定目录的个人访问令牌相同
+func subAccountAllowed(c *gin.Context, user *model.User) bool {
+	route := c.FullPath()
+	if isCredentialRoute(route) {
+		return false
+	}
+
+	return subAccountRoutes[route] || folderRouteAllowed(c, user.SubAccount, user)
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubAccountAllowed(t *testing.T) {
+	asserts := assert.New(t)
+	user := &model.User{SubAccount: &model.User{ParentID: 1, Folder: "/family"}}
+	check := func(method, route, target string) bool {
+		var allowed bool
+		r := gin.New()
+		r.Handle(method, route, func(c *gin.Context) {
+			allowed = subAccountAllowed(c, user)
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+		return allowed
+	}
+
+	// 限定目录内按路径操作的接口
+	asserts.True(check("GET", "/api/v3/directory/*path", "/api/v3/directory/photos"))
+	asserts.True(check("PUT", "/api/v3/file/upload", "/api/v3/file/upload"))
+	asserts.True(check("GET", "/api/v3/user/me", "/api/v3/user/me"))
+
+	// 退出登录
+	asserts.True(check("DELETE", "/api/v3/user/session", "/api/v3/user/session"))
+
+	// 管理类及按 ID 操作的接口
+	asserts.False(check("DELETE", "/api/v3/object", "/api/v3/object"))
+	asserts.False(check("GET", "/api/v3/user/setting", "/api/v3/user/setting"))
+	asserts.False(check("POST", "/api/v3/user/subaccount", "/api/v3/user/subaccount"))
+	asserts.False(check("GET", "/api/v3/admin/summary", "/api/v3/admin/summary"))
+	asserts.False(check("GET", "/api/v3/file/preview/:id", "/api/v3/file/preview/invalid"))
+}
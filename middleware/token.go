@@ -0,0 +1,156 @@
+package middleware
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/gin-gonic/gin"
+)
+
+// credentialRoutes 管理站点及账户凭证的接口前缀，个人访问令牌及模拟用户的管理员均无法访问
+var credentialRoutes = []string{
+	"/api/v3/admin",
+	"/api/v3/user/setting",
+	"/api/v3/user/authn",
+	"/api/v3/user/token",
+	"/api/v3/user/devices",
+	"/api/v3/user/subaccount",
+	"/api/v3/webdav",
+	"/api/v3/s3",
+	"/api/v3/sftp",
+	"/api/v3/oauth",
+}
+
+// apiTokenUploadRoutes 上传令牌可访问的接口，列目录用于获取上传所需的存储策略
+var apiTokenUploadRoutes = map[string]bool{
+	"/api/v3/user/me":                       true,
+	"/api/v3/directory/*path":               true,
+	"/api/v3/file/upload":                   true,
+	"/api/v3/file/upload/:sessionId/:index": true,
+	"/api/v3/file/upload/:sessionId":        true,
+}
+
+// apiTokenReadRoutes 只读令牌可访问的非 GET 接口
+var apiTokenReadRoutes = map[string]bool{
+	"/api/v3/file/download/:id": true,
+	"/api/v3/graphql":           true,
+}
+
+// apiTokenFolderRoutes 限定目录的令牌可访问的接口，均按路径定位对象，
+// 文件系统会以限定的目录为根目录解析路径
+var apiTokenFolderRoutes = map[string]bool{
+	"/api/v3/user/me":                       true,
+	"/api/v3/user/storage":                  true,
+	"/api/v3/directory":                     true,
+	"/api/v3/directory/*path":               true,
+	"/api/v3/file/create":                   true,
+	"/api/v3/file/upload":                   true,
+	"/api/v3/file/upload/:sessionId/:index": true,
+	"/api/v3/file/upload/:sessionId":        true,
+}
+
+// apiTokenFolderFileRoutes 限定目录的令牌可访问的按文件 ID 定位的接口，需检查文件所在目录
+var apiTokenFolderFileRoutes = map[string]bool{
+	"/api/v3/file/download/:id": true,
+	"/api/v3/file/preview/:id":  true,
+	"/api/v3/file/content/:id":  true,
+	"/api/v3/file/thumb/:id":    true,
+}
+
+// isCredentialRoute 是否为管理站点及账户凭证的接口
+func isCredentialRoute(route string) bool {
+	for _, prefix := range credentialRoutes {
+		if strings.HasPrefix(route, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bearerToken 读取请求头中的 Bearer 凭证
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// useAPIToken 使用个人访问令牌认证用户，并检查令牌能否访问当前接口
+func useAPIToken(c *gin.Context, raw string) serializer.Response {
+	token, err := model.GetAPIToken(raw)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, "API token is invalid or expired", nil)
+	}
+
+	user, err := model.GetActiveUserByID(token.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, "API token is invalid or expired", nil)
+	}
+
+	if !apiTokenAllowed(c, token, &user) {
+		return serializer.Err(serializer.CodeAPITokenScope, "API token is not allowed to perform this operation", nil)
+	}
+
+	token.Touch(c.ClientIP())
+	stats.MarkActive(user.ID)
+	c.Set("user", &user)
+	c.Set(filesystem.APITokenCtx, token)
+	return serializer.Response{}
+}
+
+// apiTokenAllowed 检查令牌的权限范围及限定目录是否允许访问当前接口
+func apiTokenAllowed(c *gin.Context, token *model.APIToken, user *model.User) bool {
+	route := c.FullPath()
+	if isCredentialRoute(route) {
+		return false
+	}
+
+	switch token.Scope {
+	case model.APITokenScopeRead:
+		if c.Request.Method != "GET" && c.Request.Method != "HEAD" && !apiTokenReadRoutes[route] {
+			return false
+		}
+	case model.APITokenScopeUpload:
+		if !apiTokenUploadRoutes[route] {
+			return false
+		}
+	case model.APITokenScopeFull:
+	default:
+		return false
+	}
+
+	if token.Folder == "" {
+		return true
+	}
+
+	return folderRouteAllowed(c, token, user)
+}
+
+// folderScope 限定了可访问目录的凭证
+type folderScope interface {
+	CoversFile(file *model.File) bool
+}
+
+// folderRouteAllowed 检查限定了目录的凭证能否访问当前接口，user 为文件所有者
+func folderRouteAllowed(c *gin.Context, scope folderScope, user *model.User) bool {
+	route := c.FullPath()
+	if apiTokenFolderRoutes[route] {
+		return true
+	}
+
+	if apiTokenFolderFileRoutes[route] {
+		id, err := hashid.DecodeHashID(c.Param("id"), hashid.FileID)
+		if err != nil {
+			return false
+		}
+		files, err := model.GetFilesByIDs([]uint{id}, user.ID)
+		return err == nil && len(files) == 1 && scope.CoversFile(&files[0])
+	}
+
+	return false
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBearerToken(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request, _ = http.NewRequest("GET", "/test", nil)
+	asserts.Equal("", bearerToken(c))
+
+	c.Request.Header.Set("Authorization", "Basic 123")
+	asserts.Equal("", bearerToken(c))
+
+	c.Request.Header.Set("Authorization", "bearer cr_123 ")
+	asserts.Equal("cr_123", bearerToken(c))
+}
+
+func TestAPITokenAllowed(t *testing.T) {
+	asserts := assert.New(t)
+	check := func(token *model.APIToken, method, route, target string) bool {
+		var allowed bool
+		r := gin.New()
+		r.Handle(method, route, func(c *gin.Context) {
+			allowed = apiTokenAllowed(c, token, &model.User{})
+		})
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, target, nil))
+		return allowed
+	}
+
+	// 管理类接口
+	full := &model.APIToken{Scope: model.APITokenScopeFull}
+	asserts.True(check(full, "DELETE", "/api/v3/object", "/api/v3/object"))
+	asserts.False(check(full, "GET", "/api/v3/user/setting", "/api/v3/user/setting"))
+	asserts.False(check(full, "POST", "/api/v3/user/token", "/api/v3/user/token"))
+	asserts.False(check(full, "GET", "/api/v3/admin/summary", "/api/v3/admin/summary"))
+
+	// 只读
+	read := &model.APIToken{Scope: model.APITokenScopeRead}
+	asserts.True(check(read, "GET", "/api/v3/directory/*path", "/api/v3/directory/foo"))
+	asserts.True(check(read, "PUT", "/api/v3/file/download/:id", "/api/v3/file/download/1"))
+	asserts.True(check(read, "POST", "/api/v3/graphql", "/api/v3/graphql"))
+	asserts.True(check(read, "GET", "/api/v3/sync/changes", "/api/v3/sync/changes"))
+	asserts.False(check(read, "PUT", "/api/v3/sync/file", "/api/v3/sync/file"))
+	asserts.False(check(read, "DELETE", "/api/v3/object", "/api/v3/object"))
+
+	// 仅上传
+	upload := &model.APIToken{Scope: model.APITokenScopeUpload}
+	asserts.True(check(upload, "PUT", "/api/v3/file/upload", "/api/v3/file/upload"))
+	asserts.True(check(upload, "POST", "/api/v3/file/upload/:sessionId/:index", "/api/v3/file/upload/1/0"))
+	asserts.False(check(upload, "GET", "/api/v3/file/preview/:id", "/api/v3/file/preview/1"))
+
+	// 未知范围
+	asserts.False(check(&model.APIToken{Scope: "unknown"}, "GET", "/api/v3/user/me", "/api/v3/user/me"))
+
+	// 限定目录
+	folder := &model.APIToken{Scope: model.APITokenScopeFull, Folder: "/scripts"}
+	asserts.True(check(folder, "PUT", "/api/v3/directory", "/api/v3/directory"))
+	asserts.False(check(folder, "DELETE", "/api/v3/object", "/api/v3/object"))
+	asserts.False(check(folder, "POST", "/api/v3/graphql", "/api/v3/graphql"))
+	asserts.False(check(folder, "GET", "/api/v3/sync/changes", "/api/v3/sync/changes"))
+	asserts.False(check(folder, "GET", "/api/v3/file/preview/:id", "/api/v3/file/preview/invalid"))
+}
@@ -0,0 +1,141 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 公告级别
+const (
+	AnnouncementInfo     = "info"
+	AnnouncementWarning  = "warning"
+	AnnouncementCritical = "critical"
+)
+
+// Announcement 站点公告
+type Announcement struct {
+	gorm.Model
+	Title       string     `json:"title"`
+	Content     string     `gorm:"type:text" json:"content"`
+	Level       string     `gorm:"size:16" json:"level"`
+	Groups      string     `json:"-"`            // 目标用户组ID列表，为空时面向全部用户
+	Email       bool       `json:"email"`        // 发布时是否同时发送邮件
+	AuthorID    uint       `json:"author_id"`    // 创建公告的管理员
+	PublishedAt *time.Time `json:"published_at"` // 发布时间，未发布时为空
+	ExpiresAt   *time.Time `json:"expires_at"`   // 过期时间，过期后不再展示，为空时不过期
+
+	// 数据库忽略字段
+	GroupList []uint `gorm:"-" json:"groups"`
+}
+
+// AfterFind 找到公告后的钩子，处理目标用户组列表
+func (announcement *Announcement) AfterFind() (err error) {
+	if announcement.Groups != "" {
+		err = json.Unmarshal([]byte(announcement.Groups), &announcement.GroupList)
+	}
+	return err
+}
+
+// BeforeSave 保存公告前的钩子，序列化目标用户组列表
+func (announcement *Announcement) BeforeSave() (err error) {
+	groups, err := json.Marshal(&announcement.GroupList)
+	announcement.Groups = string(groups)
+	return err
+}
+
+// Save 创建或保存公告
+func (announcement *Announcement) Save() error {
+	return DB.Save(announcement).Error
+}
+
+// GetAnnouncementByID 用ID获取公告
+func GetAnnouncementByID(id uint) (Announcement, error) {
+	var announcement Announcement
+	result := DB.First(&announcement, id)
+	return announcement, result.Error
+}
+
+// ListAnnouncements 分页列出全部公告
+func ListAnnouncements(page, pageSize int) ([]Announcement, int) {
+	var (
+		announcements []Announcement
+		total         int
+	)
+
+	DB.Model(&Announcement{}).Count(&total)
+	DB.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&announcements)
+	return announcements, total
+}
+
+// ListActiveAnnouncements 列出已发布且未过期，并面向给定用户组的公告
+func ListActiveAnnouncements(groupID uint) ([]Announcement, error) {
+	var announcements []Announcement
+	err := DB.Where("published_at is not NULL and (expires_at is NULL or expires_at > ?)", time.Now()).
+		Order("published_at desc").Find(&announcements).Error
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Announcement, 0, len(announcements))
+	for _, announcement := range announcements {
+		if announcement.IsVisibleTo(groupID) {
+			res = append(res, announcement)
+		}
+	}
+	return res, nil
+}
+
+// DeleteAnnouncement 删除公告，已投递的站内信不受影响
+func DeleteAnnouncement(id uint) error {
+	return DB.Where("id = ?", id).Delete(&Announcement{}).Error
+}
+
+// IsVisibleTo 公告是否面向给定用户组
+func (announcement *Announcement) IsVisibleTo(groupID uint) bool {
+	if len(announcement.GroupList) == 0 {
+		return true
+	}
+	for _, id := range announcement.GroupList {
+		if id == groupID {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish 发布公告
+func (announcement *Announcement) Publish() error {
+	now := time.Now()
+	announcement.PublishedAt = &now
+	return DB.Model(announcement).UpdateColumn("published_at", now).Error
+}
+
+// EachRecipient 分批遍历公告面向的已激活用户，fn 返回错误时停止遍历
+func (announcement *Announcement) EachRecipient(batch int, fn func([]User) error) error {
+	var lastID uint
+	for {
+		dbChain := DB.Where("id > ? and status = ?", lastID, Active)
+		if len(announcement.GroupList) > 0 {
+			dbChain = dbChain.Where("group_id in (?)", announcement.GroupList)
+		}
+
+		var users []User
+		if err := dbChain.Order("id").Limit(batch).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		if err := fn(users); err != nil {
+			return err
+		}
+
+		lastID = users[len(users)-1].ID
+		if len(users) < batch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnnouncement_AfterFind(t *testing.T) {
+	asserts := assert.New(t)
+
+	announcement := &Announcement{Groups: "[1,2]"}
+	asserts.NoError(announcement.AfterFind())
+	asserts.Equal([]uint{1, 2}, announcement.GroupList)
+
+	announcement = &Announcement{Groups: "invalid"}
+	asserts.Error(announcement.AfterFind())
+}
+
+func TestAnnouncement_BeforeSave(t *testing.T) {
+	asserts := assert.New(t)
+
+	announcement := &Announcement{GroupList: []uint{1, 2}}
+	asserts.NoError(announcement.BeforeSave())
+	asserts.Equal("[1,2]", announcement.Groups)
+}
+
+func TestAnnouncement_IsVisibleTo(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.True((&Announcement{}).IsVisibleTo(3))
+	asserts.True((&Announcement{GroupList: []uint{1, 2}}).IsVisibleTo(2))
+	asserts.False((&Announcement{GroupList: []uint{1, 2}}).IsVisibleTo(3))
+}
+
+func TestListActiveAnnouncements(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功，过滤其他用户组的公告
+	{
+		mock.ExpectQuery("SELECT(.+)announcements(.+)published_at is not NULL(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "groups"}).AddRow(1, "").AddRow(2, "[1]").AddRow(3, "[2,3]"))
+		res, err := ListActiveAnnouncements(2)
+		asserts.NoError(err)
+		asserts.Len(res, 2)
+		asserts.EqualValues(1, res[0].ID)
+		asserts.EqualValues(3, res[1].ID)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectQuery("SELECT(.+)announcements(.+)").WillReturnError(errors.New("error"))
+		_, err := ListActiveAnnouncements(2)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestAnnouncement_Publish(t *testing.T) {
+	asserts := assert.New(t)
+	announcement := &Announcement{}
+	announcement.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)announcements(.+)published_at(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(announcement.Publish())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NotNil(announcement.PublishedAt)
+	asserts.WithinDuration(time.Now(), *announcement.PublishedAt, time.Second)
+}
+
+func TestAnnouncement_EachRecipient(t *testing.T) {
+	asserts := assert.New(t)
+	announcement := &Announcement{}
+
+	// 分批遍历全部用户
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(0, Active).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WithArgs(2, Active).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		visited := 0
+		asserts.NoError(announcement.EachRecipient(2, func(users []User) error {
+			visited += len(users)
+			return nil
+		}))
+		asserts.Equal(3, visited)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// fn 返回错误时停止
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		asserts.Error(announcement.EachRecipient(2, func(users []User) error {
+			return errors.New("error")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 个人访问令牌的权限范围
+const (
+	// APITokenScopeRead 只读，仅允许 GET 请求
+	APITokenScopeRead = "read"
+	// APITokenScopeUpload 仅允许上传文件
+	APITokenScopeUpload = "upload"
+	// APITokenScopeFull 与登录会话权限相同
+	APITokenScopeFull = "full"
+
+	// APITokenPrefix 令牌前缀，便于识别及与其他凭证区分
+	APITokenPrefix = "cr_"
+	// oauthRefreshPrefix 第三方应用刷新令牌前缀，无法直接作为访问令牌使用
+	oauthRefreshPrefix = "crr_"
+)
+
+// APIToken 用户个人访问令牌
+type APIToken struct {
+	gorm.Model
+	Name       string     // 令牌名称
+	UserID     uint       `gorm:"index"`                 // 用户ID
+	Token      string     `gorm:"unique_index" json:"-"` // 令牌的 SHA256 摘要
+	Scope      string     // 权限范围
+	Folder     string     `gorm:"type:text"`      // 限定访问的目录，为空时不限制
+	ClientID   uint       `gorm:"index"`          // 签发令牌的第三方应用ID，为 0 时为用户自行创建
+	Refresh    string     `gorm:"index" json:"-"` // 刷新令牌的 SHA256 摘要
+	ExpiredAt  *time.Time // 过期时间，为空时永不过期
+	LastUsedAt *time.Time // 最后使用时间
+	LastIP     string     // 最后使用的 IP
+}
+
+// hashAPIToken 计算令牌摘要，数据库中只保存摘要
+func hashAPIToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// newSecret 生成带前缀的随机凭证
+func newSecret(prefix string) (string, error) {
+	b := make([]byte, 30)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Create 生成令牌并创建记录，返回的明文令牌只在创建时可见
+func (token *APIToken) Create() (string, error) {
+	raw, err := newSecret(APITokenPrefix)
+	if err != nil {
+		return "", err
+	}
+	token.Token = hashAPIToken(raw)
+
+	if err := DB.Create(token).Error; err != nil {
+		return "", err
+	}
+	return raw, nil
+}
+
+// IssueOAuthToken 为第三方应用签发访问令牌及刷新令牌，令牌已存在时轮换两者
+func (token *APIToken) IssueOAuthToken(ttl time.Duration) (string, string, error) {
+	access, err := newSecret(APITokenPrefix)
+	if err != nil {
+		return "", "", err
+	}
+	refresh, err := newSecret(oauthRefreshPrefix)
+	if err != nil {
+		return "", "", err
+	}
+
+	expires := time.Now().Add(ttl)
+	token.Token = hashAPIToken(access)
+	token.Refresh = hashAPIToken(refresh)
+	token.ExpiredAt = &expires
+	if err := DB.Save(token).Error; err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
+
+// Expired 令牌是否已过期
+func (token *APIToken) Expired() bool {
+	return token.ExpiredAt != nil && token.ExpiredAt.Before(time.Now())
+}
+
+// Touch 记录令牌的使用时间及 IP，五分钟内重复使用且 IP 不变时不更新
+func (token *APIToken) Touch(ip string) {
+	now := time.Now()
+	if token.LastUsedAt != nil && now.Sub(*token.LastUsedAt) < 5*time.Minute && token.LastIP == ip {
+		return
+	}
+	token.LastUsedAt = &now
+	token.LastIP = ip
+	DB.Model(token).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_ip": ip})
+}
+
+// GetAPIToken 根据明文令牌查找未过期的令牌
+func GetAPIToken(raw string) (*APIToken, error) {
+	if !strings.HasPrefix(raw, APITokenPrefix) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	token := &APIToken{}
+	if err := DB.Where("token = ?", hashAPIToken(raw)).First(token).Error; err != nil {
+		return nil, err
+	}
+	if token.Expired() {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return token, nil
+}
+
+// GetOAuthTokenByRefresh 根据刷新令牌查找第三方应用签发的令牌，刷新令牌不随访问令牌过期
+func GetOAuthTokenByRefresh(raw string, clientID uint) (*APIToken, error) {
+	if !strings.HasPrefix(raw, oauthRefreshPrefix) {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	token := &APIToken{}
+	err := DB.Where("refresh = ? and client_id = ?", hashAPIToken(raw), clientID).First(token).Error
+	return token, err
+}
+
+// GetOAuthToken 根据访问令牌或刷新令牌查找第三方应用签发的令牌
+func GetOAuthToken(raw string, clientID uint) (*APIToken, error) {
+	token := &APIToken{}
+	hash := hashAPIToken(raw)
+	err := DB.Where("(token = ? or refresh = ?) and client_id = ?", hash, hash, clientID).First(token).Error
+	return token, err
+}
+
+// ListAPITokens 列出用户的所有令牌
+func ListAPITokens(uid uint) []APIToken {
+	var tokens []APIToken
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&tokens)
+	return tokens
+}
+
+// DeleteAPITokenByID 根据令牌ID和UID删除令牌
+func DeleteAPITokenByID(id, uid uint) {
+	DB.Where("user_id = ? and id = ?", uid, id).Delete(&APIToken{})
+}
+
+// CoversPath 路径是否位于令牌限定的目录内
+func (token *APIToken) CoversPath(p string) bool {
+	return folderCoversPath(token.Folder, p)
+}
+
+// CoversFile 文件是否位于令牌限定的目录内
+func (token *APIToken) CoversFile(file *File) bool {
+	return folderCoversFile(token.Folder, file)
+}
+
+// folderCoversPath 路径是否位于给定目录内，目录为空时不限制
+func folderCoversPath(folder, p string) bool {
+	if folder == "" {
+		return true
+	}
+
+	root := path.Clean("/" + folder)
+	p = path.Clean("/" + p)
+	return root == "/" || p == root || strings.HasPrefix(p, root+"/")
+}
+
+// folderCoversFile 文件是否位于给定目录内，目录为空时不限制
+func folderCoversFile(folder string, file *File) bool {
+	if folder == "" {
+		return true
+	}
+
+	folders, err := GetFoldersByIDs([]uint{file.FolderID}, file.UserID)
+	if err != nil || len(folders) == 0 {
+		return false
+	}
+	if err := folders[0].TraceRoot(); err != nil {
+		return false
+	}
+	return folderCoversPath(folder, path.Join(folders[0].Position, folders[0].Name))
+}
@@ -0,0 +1,151 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIToken_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		token := APIToken{UserID: 1, Scope: APITokenScopeRead}
+		raw, err := token.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(strings.HasPrefix(raw, APITokenPrefix))
+		asserts.Equal(hashAPIToken(raw), token.Token)
+		asserts.NotEqual(raw, token.Token)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		token := APIToken{}
+		raw, err := token.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Empty(raw)
+	}
+}
+
+func TestGetAPIToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 前缀不符
+	{
+		_, err := GetAPIToken("123")
+		asserts.Equal(gorm.ErrRecordNotFound, err)
+	}
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(hashAPIToken("cr_123")).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetAPIToken("cr_123")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 已过期
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "expired_at"}).AddRow(1, time.Now().Add(-time.Hour)))
+		_, err := GetAPIToken("cr_123")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(gorm.ErrRecordNotFound, err)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expired_at"}).AddRow(1, 2, time.Now().Add(time.Hour)))
+		token, err := GetAPIToken("cr_123")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, token.UserID)
+	}
+}
+
+func TestAPIToken_Touch(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 首次使用
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	token := APIToken{}
+	token.ID = 1
+	token.Touch("127.0.0.1")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NotNil(token.LastUsedAt)
+
+	// 短时间内再次使用不更新
+	token.Touch("127.0.0.1")
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// IP 变化
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	token.Touch("127.0.0.2")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("127.0.0.2", token.LastIP)
+}
+
+func TestAPIToken_CoversPath(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.True((&APIToken{}).CoversPath("/any"))
+	asserts.True((&APIToken{Folder: "/"}).CoversPath("/any"))
+
+	token := &APIToken{Folder: "/scripts/"}
+	asserts.True(token.CoversPath("/scripts"))
+	asserts.True(token.CoversPath("/scripts/a/b"))
+	asserts.False(token.CoversPath("/scripts2"))
+	asserts.False(token.CoversPath("/scripts/../other"))
+	asserts.False(token.CoversPath("/"))
+}
+
+func TestAPIToken_CoversFile(t *testing.T) {
+	asserts := assert.New(t)
+	token := &APIToken{Folder: "/scripts"}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.False(token.CoversFile(&File{FolderID: 2, UserID: 1}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 位于限定目录下
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(2, "scripts", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+		asserts.True(token.CoversFile(&File{FolderID: 2, UserID: 1}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 位于其他目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "other", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+		asserts.False(token.CoversFile(&File{FolderID: 3, UserID: 1}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,258 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// 审计事件类型
+const (
+	// AuditShareCreate 创建分享
+	AuditShareCreate = "share_create"
+	// AuditShareRevoke 撤销分享
+	AuditShareRevoke = "share_revoke"
+	// AuditImpersonateStart 管理员开始模拟用户
+	AuditImpersonateStart = "impersonate_start"
+	// AuditImpersonateStop 管理员结束模拟用户
+	AuditImpersonateStop = "impersonate_stop"
+	// AuditImpersonateRequest 管理员模拟用户期间发起的修改请求
+	AuditImpersonateRequest = "impersonate_request"
+	// AuditAccountDeletionRequest 用户申请注销账户
+	AuditAccountDeletionRequest = "account_deletion_request"
+	// AuditAccountDeletionCancel 用户撤销注销申请
+	AuditAccountDeletionCancel = "account_deletion_cancel"
+	// AuditAccountPurge 冷静期结束后清除账户
+	AuditAccountPurge = "account_purge"
+	// AuditGroupUpgrade 管理员临时调整用户组
+	AuditGroupUpgrade = "group_upgrade"
+	// AuditGroupRevert 临时用户组到期后恢复
+	AuditGroupRevert = "group_revert"
+	// AuditGuestCreate 管理员创建访客账户
+	AuditGuestCreate = "guest_create"
+	// AuditGuestRenew 管理员为访客账户续期
+	AuditGuestRenew = "guest_renew"
+	// AuditGuestExpire 访客账户到期后停用
+	AuditGuestExpire = "guest_expire"
+	// AuditEmailChange 用户验证新邮箱后更改账户邮箱
+	AuditEmailChange = "email_change"
+	// AuditUserImport 管理员批量导入用户
+	AuditUserImport = "user_import"
+	// AuditAccountMerge 管理员将账户合并至其他账户
+	AuditAccountMerge = "account_merge"
+	// AuditLogin 用户登录成功
+	AuditLogin = "login"
+	// AuditLoginFailed 用户登录失败
+	AuditLoginFailed = "login_failed"
+	// AuditUserPermission 管理员更改用户的用户组或状态
+	AuditUserPermission = "user_permission"
+	// AuditUserDelete 管理员删除用户
+	AuditUserDelete = "user_delete"
+	// AuditGroupChange 管理员创建或修改用户组
+	AuditGroupChange = "group_change"
+	// AuditGroupDelete 管理员删除用户组
+	AuditGroupDelete = "group_delete"
+	// AuditObjectDelete 删除文件或目录
+	AuditObjectDelete = "object_delete"
+	// AuditAdminRequest 管理员发起的修改请求
+	AuditAdminRequest = "admin_request"
+	// AuditLogExport 管理员导出审计记录
+	AuditLogExport = "audit_export"
+	// AuditVirusDetected 上传的文件检出病毒
+	AuditVirusDetected = "virus_detected"
+	// AuditModeration 文件未通过内容审核，或管理员复核审核结果
+	AuditModeration = "moderation"
+	// AuditBlockedHash 上传或下载的文件内容命中禁止列表
+	AuditBlockedHash = "blocked_hash"
+	// AuditAnnouncement 管理员发布或删除公告
+	AuditAnnouncement = "announcement"
+	// AuditMaintenance 管理员开启或关闭维护模式
+	AuditMaintenance = "maintenance"
+	// AuditBackup 管理员导出备份或从备份恢复
+	AuditBackup = "backup"
+)
+
+// auditDetailMaxObjects 删除对象的审计记录中最多列出的对象名称数量
+const auditDetailMaxObjects = 20
+
+// ErrAuditLogAppendOnly 审计记录只能追加，不能修改或删除
+var ErrAuditLogAppendOnly = errors.New("audit logs are append-only")
+
+// AuditLog 审计记录
+type AuditLog struct {
+	ID             uint      `gorm:"primary_key" json:"id"`
+	CreatedAt      time.Time `gorm:"index:created_at" json:"time"`
+	UserID         uint      `gorm:"index:user_id" json:"user_id"` // 操作者，0 表示系统
+	Action         string    `gorm:"index:action" json:"action"`
+	Target         string    `gorm:"index:target" json:"target"` // 操作对象，格式为 类型:ID
+	Detail         string    `gorm:"type:text" json:"detail"`
+	IP             string    `json:"ip"`
+	ImpersonatorID uint      `gorm:"index:impersonator_id" json:"impersonator_id,omitempty"` // 管理员模拟用户期间的操作，记录管理员ID
+}
+
+// AuditLogFilter 审计记录筛选条件，零值字段不参与筛选
+type AuditLogFilter struct {
+	UserID     uint      // 操作者
+	Actions    []string  // 事件类型
+	Target     string    // 操作对象，完整匹配
+	TargetType string    // 操作对象类型，如 user、share
+	IP         string    // 来源 IP
+	Keywords   string    // 在详情中搜索
+	Start      time.Time // 起始时间（含）
+	End        time.Time // 截止时间（不含）
+}
+
+// BeforeUpdate 禁止修改审计记录
+func (log *AuditLog) BeforeUpdate() error {
+	return ErrAuditLogAppendOnly
+}
+
+// BeforeDelete 禁止删除审计记录
+func (log *AuditLog) BeforeDelete() error {
+	return ErrAuditLogAppendOnly
+}
+
+// Create 创建审计记录
+func (log *AuditLog) Create() error {
+	if err := DB.Create(log).Error; err != nil {
+		util.Log().Warning("无法插入审计记录, %s", err)
+		return err
+	}
+	return nil
+}
+
+// AuditTarget 返回审计记录中表示给定对象的字符串
+func AuditTarget(kind string, id uint) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+// RecordAudit 记录一条审计事件，c 为空时表示系统发起的操作
+func RecordAudit(c *gin.Context, uid uint, action, target, detail string) {
+	log := &AuditLog{
+		UserID: uid,
+		Action: action,
+		Target: target,
+		Detail: detail,
+	}
+	if c != nil {
+		log.IP = c.ClientIP()
+		if user, ok := c.Get("user"); ok {
+			if user, ok := user.(*User); ok {
+				log.ImpersonatorID = user.Impersonator
+			}
+		}
+	}
+	log.Create()
+}
+
+// RecordObjectDelete 记录一次删除文件及目录的操作，详情中列出被删除的对象名称
+func RecordObjectDelete(c *gin.Context, uid uint, folders []Folder, files []File) {
+	if len(folders)+len(files) == 0 {
+		return
+	}
+
+	target := ""
+	if len(folders) == 1 && len(files) == 0 {
+		target = AuditTarget("folder", folders[0].ID)
+	} else if len(folders) == 0 && len(files) == 1 {
+		target = AuditTarget("file", files[0].ID)
+	}
+
+	names := make([]string, 0, auditDetailMaxObjects)
+	for i := 0; i < len(folders) && len(names) < auditDetailMaxObjects; i++ {
+		names = append(names, folders[i].Name+"/")
+	}
+	for i := 0; i < len(files) && len(names) < auditDetailMaxObjects; i++ {
+		names = append(names, files[i].Name)
+	}
+
+	detail := strings.Join(names, ", ")
+	if more := len(folders) + len(files) - len(names); more > 0 {
+		detail += fmt.Sprintf(" and %d more", more)
+	}
+	RecordAudit(c, uid, AuditObjectDelete, target, detail)
+}
+
+// RecordShareRevoke 为被撤销的每个分享记录审计事件，reason 为撤销原因
+func RecordShareRevoke(c *gin.Context, uid uint, shares []Share, reason string) {
+	for _, share := range shares {
+		RecordAudit(c, uid, AuditShareRevoke, AuditTarget("share", share.ID), reason+": "+share.SourceName)
+	}
+}
+
+// apply 将筛选条件应用于查询
+func (filter *AuditLogFilter) apply(db *gorm.DB) *gorm.DB {
+	if filter.UserID > 0 {
+		db = db.Where("user_id = ?", filter.UserID)
+	}
+	if len(filter.Actions) > 0 {
+		db = db.Where("action in (?)", filter.Actions)
+	}
+	if filter.Target != "" {
+		db = db.Where("target = ?", filter.Target)
+	}
+	if filter.TargetType != "" {
+		db = db.Where("target like ?", filter.TargetType+":%")
+	}
+	if filter.IP != "" {
+		db = db.Where("ip = ?", filter.IP)
+	}
+	if filter.Keywords != "" {
+		db = db.Where("detail "+likeOperator(DB)+" ?", "%"+filter.Keywords+"%")
+	}
+	if !filter.Start.IsZero() {
+		db = db.Where("created_at >= ?", filter.Start)
+	}
+	if !filter.End.IsZero() {
+		db = db.Where("created_at < ?", filter.End)
+	}
+	return db
+}
+
+// ListAuditLogs 分页列出符合条件的审计记录，按时间倒序排列
+func ListAuditLogs(filter *AuditLogFilter, page, pageSize int) ([]AuditLog, int) {
+	var (
+		logs  []AuditLog
+		total int
+	)
+
+	dbChain := filter.apply(DB.Model(&AuditLog{}))
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&logs)
+	return logs, total
+}
+
+// EachAuditLog 按时间顺序分批遍历符合条件的审计记录，最多遍历 limit 条，fn 返回错误时停止遍历
+func EachAuditLog(filter *AuditLogFilter, batch, limit int, fn func([]AuditLog) error) error {
+	var lastID uint
+	for visited := 0; visited < limit; {
+		size := batch
+		if limit-visited < size {
+			size = limit - visited
+		}
+
+		var logs []AuditLog
+		if err := filter.apply(DB.Where("id > ?", lastID)).Order("id").Limit(size).Find(&logs).Error; err != nil {
+			return err
+		}
+		if len(logs) == 0 {
+			return nil
+		}
+
+		if err := fn(logs); err != nil {
+			return err
+		}
+
+		visited += len(logs)
+		lastID = logs[len(logs)-1].ID
+		if len(logs) < size {
+			return nil
+		}
+	}
+	return nil
+}
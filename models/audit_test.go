@@ -0,0 +1,213 @@
+package model
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditLog_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		log := &AuditLog{UserID: 1, Action: AuditShareCreate, Target: "share:1"}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(log.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, log.ID)
+	}
+
+	// 失败
+	{
+		log := &AuditLog{UserID: 1, Action: AuditShareCreate, Target: "share:1"}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(log.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestRecordShareRevoke(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	shares := []Share{{SourceName: "a.txt"}, {SourceName: "b.txt"}}
+	shares[0].ID, shares[1].ID = 1, 2
+	for _, share := range shares {
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(3), AuditShareRevoke, AuditTarget("share", share.ID),
+				"revoked by admin: "+share.SourceName, "10.0.0.1", uint(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	RecordShareRevoke(c, 3, shares, "revoked by admin")
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRecordAudit_Impersonator(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+	c.Set("user", &User{Impersonator: 1})
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+		WithArgs(sqlmock.AnyArg(), uint(2), AuditImpersonateRequest, "user:2", "POST /", "10.0.0.1", uint(1)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	RecordAudit(c, 2, AuditImpersonateRequest, AuditTarget("user", 2), "POST /")
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestAuditLog_AppendOnly(t *testing.T) {
+	asserts := assert.New(t)
+	log := &AuditLog{ID: 1}
+
+	// 修改
+	{
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		asserts.Equal(ErrAuditLogAppendOnly, DB.Model(log).Update("detail", "changed").Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除
+	{
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+		asserts.Equal(ErrAuditLogAppendOnly, DB.Delete(log).Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestRecordObjectDelete(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/", nil)
+	c.Request.RemoteAddr = "10.0.0.1:1234"
+
+	// 无对象
+	RecordObjectDelete(c, 1, nil, nil)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 单个文件
+	{
+		files := []File{{Name: "a.txt"}}
+		files[0].ID = 2
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(1), AuditObjectDelete, "file:2", "a.txt", "10.0.0.1", uint(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		RecordObjectDelete(c, 1, nil, files)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 超出列出数量
+	{
+		folders := []Folder{{Name: "dir"}}
+		files := make([]File, auditDetailMaxObjects+1)
+		for i := range files {
+			files[i].Name = "f"
+		}
+		detail := "dir/" + strings.Repeat(", f", auditDetailMaxObjects-1) + " and 2 more"
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(1), AuditObjectDelete, "", detail, "10.0.0.1", uint(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		RecordObjectDelete(c, 1, folders, files)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListAuditLogs(t *testing.T) {
+	asserts := assert.New(t)
+	filter := &AuditLogFilter{
+		UserID:     1,
+		Actions:    []string{AuditLogin, AuditLoginFailed},
+		TargetType: "user",
+		Start:      time.Unix(100, 0),
+	}
+
+	mock.ExpectQuery("SELECT count(.+)user_id = (.+)action in (.+)target like (.+)created_at >= (.+)").
+		WithArgs(1, AuditLogin, AuditLoginFailed, "user:%", time.Unix(100, 0)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT(.+)audit_logs(.+)ORDER BY id desc(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	logs, total := ListAuditLogs(filter, 1, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(2, total)
+	asserts.Len(logs, 2)
+}
+
+func TestEachAuditLog(t *testing.T) {
+	asserts := assert.New(t)
+	filter := &AuditLogFilter{IP: "10.0.0.1"}
+
+	// 分批遍历至无更多记录
+	{
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)").WithArgs(0, "10.0.0.1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)").WithArgs(2, "10.0.0.1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		var visited []uint
+		err := EachAuditLog(filter, 2, 10, func(logs []AuditLog) error {
+			for _, log := range logs {
+				visited = append(visited, log.ID)
+			}
+			return nil
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal([]uint{1, 2, 3}, visited)
+	}
+
+	// 达到数量上限
+	{
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)LIMIT 2").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)LIMIT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		count := 0
+		err := EachAuditLog(filter, 2, 3, func(logs []AuditLog) error {
+			count += len(logs)
+			return nil
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(3, count)
+	}
+
+	// 回调出错
+	{
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		err := EachAuditLog(filter, 2, 10, func(logs []AuditLog) error {
+			return errors.New("error")
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 查询出错
+	{
+		mock.ExpectQuery("SELECT(.+)audit_logs(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(EachAuditLog(filter, 2, 10, func(logs []AuditLog) error { return nil }))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,201 @@
+package model
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 文件元数据中记录内容摘要的键
+const (
+	HashMD5MetaKey    = "hash_md5"
+	HashSHA1MetaKey   = "hash_sha1"
+	HashSHA256MetaKey = "hash_sha256"
+)
+
+// blockedHashBatchSize 批量添加禁止摘要时每次查询及写入的数量
+const blockedHashBatchSize = 500
+
+// BlockedHash 禁止上传及分享的文件内容摘要
+type BlockedHash struct {
+	gorm.Model
+	Hash   string `gorm:"size:64;unique_index:idx_blocked_hash" json:"hash"` // 小写十六进制，按长度区分 MD5、SHA1 及 SHA256
+	Source string `json:"source"`                                            // 来源，手动添加时为 manual，导入时为列表地址
+	Note   string `json:"note"`
+}
+
+// NormalizeContentHash 将摘要转换为小写十六进制，不是 MD5、SHA1 或 SHA256 摘要时返回 false
+func NormalizeContentHash(hash string) (string, bool) {
+	hash = strings.ToLower(strings.TrimSpace(hash))
+	switch len(hash) {
+	case 32, 40, 64:
+	default:
+		return "", false
+	}
+
+	if _, err := hex.DecodeString(hash); err != nil {
+		return "", false
+	}
+	return hash, true
+}
+
+// ParseBlockedHashList 从外部列表中解析摘要，每行取第一列，
+// 支持以空白或逗号分隔的列表及 md5sum/sha256sum 的输出格式，忽略 # 开头的注释及无法识别的行
+func ParseBlockedHashList(r io.Reader) ([]string, error) {
+	var (
+		hashes []string
+		seen   = make(map[string]bool)
+	)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ';' || r == ' ' || r == '\t'
+		})
+		if len(fields) == 0 {
+			continue
+		}
+		if hash, ok := NormalizeContentHash(strings.Trim(fields[0], `"'`)); ok && !seen[hash] {
+			seen[hash] = true
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes, scanner.Err()
+}
+
+// AddBlockedHashes 添加禁止的摘要，已存在的摘要会被跳过，返回新添加的数量
+func AddBlockedHashes(hashes []string, source, note string) (int, error) {
+	added := 0
+	for start := 0; start < len(hashes); start += blockedHashBatchSize {
+		end := start + blockedHashBatchSize
+		if end > len(hashes) {
+			end = len(hashes)
+		}
+		batch := hashes[start:end]
+
+		var existed []string
+		if err := DB.Model(&BlockedHash{}).Where("hash in (?)", batch).Pluck("hash", &existed).Error; err != nil {
+			return added, err
+		}
+
+		skip := make(map[string]bool, len(existed))
+		for _, hash := range existed {
+			skip[hash] = true
+		}
+
+		tx := DB.Begin()
+		for _, hash := range batch {
+			if skip[hash] {
+				continue
+			}
+			if err := tx.Create(&BlockedHash{Hash: hash, Source: source, Note: note}).Error; err != nil {
+				tx.Rollback()
+				return added, err
+			}
+			skip[hash] = true
+			added++
+		}
+		if err := tx.Commit().Error; err != nil {
+			return added, err
+		}
+	}
+
+	return added, nil
+}
+
+// MatchBlockedHash 查找命中禁止列表的摘要，未命中时返回 nil
+func MatchBlockedHash(hashes []string) (*BlockedHash, error) {
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+
+	var blocked BlockedHash
+	result := DB.Where("hash in (?)", hashes).First(&blocked)
+	if result.RecordNotFound() {
+		return nil, nil
+	}
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return &blocked, nil
+}
+
+// ListBlockedHashes 分页列出禁止的摘要，keywords 不为空时按摘要前缀或备注搜索
+func ListBlockedHashes(keywords string, page, pageSize int) ([]BlockedHash, int) {
+	var (
+		hashes []BlockedHash
+		total  int
+	)
+
+	dbChain := DB.Model(&BlockedHash{})
+	if keywords != "" {
+		dbChain = dbChain.Where("hash like ? or note "+likeOperator(DB)+" ?", strings.ToLower(keywords)+"%", "%"+keywords+"%")
+	}
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&hashes)
+	return hashes, total
+}
+
+// DeleteBlockedHashes 删除禁止的摘要，删除后可重新添加
+func DeleteBlockedHashes(ids []uint) error {
+	return DB.Unscoped().Where("id in (?)", ids).Delete(&BlockedHash{}).Error
+}
+
+// contentHashKeys 记录内容摘要的元数据键，按摘要强度由弱到强排列
+var contentHashKeys = []string{HashMD5MetaKey, HashSHA1MetaKey, HashSHA256MetaKey}
+
+// ContentHashes 返回上传时记录在元数据中的内容摘要
+func (file *File) ContentHashes() []string {
+	var hashes []string
+	for _, key := range contentHashKeys {
+		if hash := file.MetadataSerialized[key]; hash != "" {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes
+}
+
+// ContentChecksum 返回记录的最强内容摘要，未记录时返回空字符串
+func (file *File) ContentChecksum() string {
+	hashes := file.ContentHashes()
+	if len(hashes) == 0 {
+		return ""
+	}
+	return hashes[len(hashes)-1]
+}
+
+// clearContentHashes 文件内容改变后移除元数据中已失效的内容摘要
+func (file *File) clearContentHashes(tx *gorm.DB) error {
+	if len(file.ContentHashes()) == 0 {
+		return nil
+	}
+
+	for _, key := range contentHashKeys {
+		delete(file.MetadataSerialized, key)
+	}
+	metaValue, err := json.Marshal(&file.MetadataSerialized)
+	if err != nil {
+		return err
+	}
+
+	file.Metadata = string(metaValue)
+	return tx.Model(file).Set("gorm:association_autoupdate", false).UpdateColumn("metadata", file.Metadata).Error
+}
+
+// MatchBlockedFile 站点启用禁止列表时，检查文件记录的内容摘要是否命中，未命中时返回 nil
+func MatchBlockedFile(file *File) (*BlockedHash, error) {
+	if !IsTrueVal(GetSettingByName("hash_blocklist_enabled")) {
+		return nil, nil
+	}
+	return MatchBlockedHash(file.ContentHashes())
+}
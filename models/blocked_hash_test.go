@@ -0,0 +1,148 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testMD5    = "9a0364b9e99bb480dd25e1f0284c8555"
+	testSHA256 = "ed7002b439e9ac845f22357d822bac1444730fbdb6016d3ec9432297b9ec9f73"
+)
+
+func TestNormalizeContentHash(t *testing.T) {
+	asserts := assert.New(t)
+
+	hash, ok := NormalizeContentHash(" " + strings.ToUpper(testMD5) + "\n")
+	asserts.True(ok)
+	asserts.Equal(testMD5, hash)
+
+	_, ok = NormalizeContentHash(testSHA256)
+	asserts.True(ok)
+
+	_, ok = NormalizeContentHash("abc")
+	asserts.False(ok)
+	_, ok = NormalizeContentHash(strings.Repeat("z", 32))
+	asserts.False(ok)
+}
+
+func TestParseBlockedHashList(t *testing.T) {
+	asserts := assert.New(t)
+
+	hashes, err := ParseBlockedHashList(strings.NewReader(`# comment
+` + testMD5 + `  file.jpg
+"` + strings.ToUpper(testSHA256) + `",known bad
+
+,,,
+not a hash
+` + testMD5 + "\tduplicated\n"))
+	asserts.NoError(err)
+	asserts.Equal([]string{testMD5, testSHA256}, hashes)
+}
+
+func TestAddBlockedHashes(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功，跳过已存在的摘要
+	{
+		mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"hash"}).AddRow(testMD5))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)blocked_hashes(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		added, err := AddBlockedHashes([]string{testMD5, testSHA256}, "manual", "")
+		asserts.NoError(err)
+		asserts.Equal(1, added)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WillReturnRows(sqlmock.NewRows([]string{"hash"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)blocked_hashes(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		_, err := AddBlockedHashes([]string{testMD5}, "manual", "")
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestMatchBlockedHash(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无摘要
+	res, err := MatchBlockedHash(nil)
+	asserts.NoError(err)
+	asserts.Nil(res)
+
+	// 未命中
+	mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	res, err = MatchBlockedHash([]string{testMD5})
+	asserts.NoError(err)
+	asserts.Nil(res)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 命中
+	mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(1, testMD5))
+	res, err = MatchBlockedHash([]string{testMD5})
+	asserts.NoError(err)
+	asserts.Equal(testMD5, res.Hash)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 查询出错
+	mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WillReturnError(errors.New("error"))
+	_, err = MatchBlockedHash([]string{testMD5})
+	asserts.Error(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestMatchBlockedFile(t *testing.T) {
+	asserts := assert.New(t)
+	file := &File{MetadataSerialized: map[string]string{HashMD5MetaKey: testMD5, HashSHA256MetaKey: testSHA256}}
+	asserts.Equal([]string{testMD5, testSHA256}, file.ContentHashes())
+
+	// 未开启
+	cache.Set("setting_hash_blocklist_enabled", "0", 0)
+	res, err := MatchBlockedFile(file)
+	asserts.NoError(err)
+	asserts.Nil(res)
+
+	// 开启
+	cache.Set("setting_hash_blocklist_enabled", "1", 0)
+	mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WithArgs(testMD5, testSHA256).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(1, testSHA256))
+	res, err = MatchBlockedFile(file)
+	asserts.NoError(err)
+	asserts.Equal(testSHA256, res.Hash)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListBlockedHashes(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)blocked_hashes(.+)").WithArgs("9a03%", "%9A03%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WithArgs("9a03%", "%9A03%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(1, testMD5))
+	hashes, total := ListBlockedHashes("9A03", 1, 10)
+	asserts.Equal(1, total)
+	asserts.Len(hashes, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestDeleteBlockedHashes(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)blocked_hashes(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteBlockedHashes([]uint{1, 2}))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
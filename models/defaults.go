@@ -11,11 +11,16 @@ var defaultSettings = []Setting{
 	{Name: "siteName", Value: `Cloudreve`, Type: "basic"},
 	{Name: "register_enabled", Value: `1`, Type: "register"},
 	{Name: "default_group", Value: `2`, Type: "register"},
+	{Name: "register_invite", Value: `0`, Type: "register"},
+	{Name: "register_phone", Value: `0`, Type: "register"},
+	{Name: "email_domain_allow", Value: ``, Type: "register"},
+	{Name: "email_domain_deny", Value: ``, Type: "register"},
 	{Name: "siteKeywords", Value: `网盘，网盘`, Type: "basic"},
 	{Name: "siteDes", Value: `Cloudreve`, Type: "basic"},
 	{Name: "siteTitle", Value: `平步云端`, Type: "basic"},
 	{Name: "siteScript", Value: ``, Type: "basic"},
 	{Name: "siteID", Value: uuid.Must(uuid.NewV4()).String(), Type: "basic"},
+	{Name: "cloudflare_proxy", Value: `0`, Type: "basic"},
 	{Name: "fromName", Value: `Cloudreve`, Type: "mail"},
 	{Name: "mail_keepalive", Value: `30`, Type: "mail"},
 	{Name: "fromAdress", Value: `no-reply@acg.blue`, Type: "mail"},
@@ -25,6 +30,23 @@ var defaultSettings = []Setting{
 	{Name: "smtpUser", Value: `no-reply@acg.blue`, Type: "mail"},
 	{Name: "smtpPass", Value: ``, Type: "mail"},
 	{Name: "smtpEncryption", Value: `0`, Type: "mail"},
+	{Name: "mail_driver", Value: `smtp`, Type: "mail"},
+	{Name: "mail_api_key", Value: ``, Type: "mail"},
+	{Name: "mail_api_secret", Value: ``, Type: "mail"},
+	{Name: "mail_api_domain", Value: ``, Type: "mail"},
+	{Name: "mail_api_region", Value: ``, Type: "mail"},
+	{Name: "mail_rate_limit", Value: `0`, Type: "mail"},
+	{Name: "mail_max_retries", Value: `5`, Type: "mail"},
+	{Name: "mail_webhook_token", Value: ``, Type: "mail"},
+	{Name: "sms_provider", Value: ``, Type: "sms"},
+	{Name: "sms_access_key", Value: ``, Type: "sms"},
+	{Name: "sms_secret_key", Value: ``, Type: "sms"},
+	{Name: "sms_sign_name", Value: ``, Type: "sms"},
+	{Name: "sms_app_id", Value: ``, Type: "sms"},
+	{Name: "sms_region", Value: ``, Type: "sms"},
+	{Name: "sms_from", Value: ``, Type: "sms"},
+	{Name: "sms_verify_template", Value: ``, Type: "sms"},
+	{Name: "sms_alert_template", Value: ``, Type: "sms"},
 	{Name: "maxEditSize", Value: `4194304`, Type: "file_edit"},
 	{Name: "archive_timeout", Value: `600`, Type: "timeout"},
 	{Name: "download_timeout", Value: `600`, Type: "timeout"},
@@ -38,10 +60,20 @@ var defaultSettings = []Setting{
 	{Name: "slave_transfer_timeout", Value: `172800`, Type: "timeout"},
 	{Name: "onedrive_monitor_timeout", Value: `600`, Type: "timeout"},
 	{Name: "share_download_session_timeout", Value: `2073600`, Type: "timeout"},
+	{Name: "share_preview_token_timeout", Value: `1800`, Type: "timeout"},
 	{Name: "onedrive_callback_check", Value: `20`, Type: "timeout"},
 	{Name: "folder_props_timeout", Value: `300`, Type: "timeout"},
+	{Name: "folder_list_timeout", Value: `600`, Type: "timeout"},
 	{Name: "chunk_retries", Value: `5`, Type: "retry"},
 	{Name: "onedrive_source_timeout", Value: `1800`, Type: "timeout"},
+	{Name: "compress_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "decompress_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "transfer_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "import_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "takeout_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "storage_audit_task_timeout", Value: `0`, Type: "timeout"},
+	{Name: "takeout_archive_timeout", Value: `259200`, Type: "timeout"},
+	{Name: "metalink_timeout", Value: `86400`, Type: "timeout"},
 	{Name: "reset_after_upload_failed", Value: `0`, Type: "upload"},
 	{Name: "use_temp_chunk_buffer", Value: `1`, Type: "upload"},
 	{Name: "login_captcha", Value: `0`, Type: "login"},
@@ -55,6 +87,16 @@ solid #e9e9e9;"bgcolor="#fff"><tr style="font-family: 'Helvetica Neue',Helvetica
 14px; margin: 0;"><td class="alert alert-warning"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 16px; vertical-align: top; color: #fff; font-weight: 500; text-align: center; border-radius: 3px 3px 0 0; background-color: #009688; margin: 0; padding: 20px;"align="center"bgcolor="#FF9F00"valign="top">激活{siteTitle}账户</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-wrap"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 20px;"valign="top"><table width="100%"cellpadding="0"cellspacing="0"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica
 Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">亲爱的<strong style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;">{userName}</strong>：</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">感谢您注册{siteTitle},请点击下方按钮完成账户激活。</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top"><a href="{activationUrl}"class="btn-primary"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; color: #FFF; text-decoration: none; line-height: 2em; font-weight: bold; text-align: center; cursor: pointer; display: inline-block; border-radius: 5px; text-transform: capitalize; background-color: #009688; margin: 0; border-color: #009688; border-style: solid; border-width: 10px 20px;">激活账户</a></td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">感谢您选择{siteTitle}。</td></tr></table></td></tr></table><div class="footer"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; width: 100%; clear: both; color: #999; margin: 0; padding: 20px;"><table width="100%"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="aligncenter content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 12px; vertical-align: top; color: #999; text-align: center; margin: 0; padding: 0 0 20px;"align="center"valign="top">此邮件由系统自动发送，请不要直接回复。</td></tr></table></div></div></td><td style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0;"valign="top"></td></tr></table></body></html>`, Type: "mail_template"},
 	{Name: "forget_captcha", Value: `0`, Type: "login"},
+	{Name: "2fa_remember_days", Value: `30`, Type: "login"},
+	{Name: "account_deletion_grace_days", Value: `7`, Type: "login"},
+	{Name: "login_alert", Value: `0`, Type: "login"},
+	{Name: "login_alert_rules", Value: `new_device,new_country`, Type: "login"},
+	{Name: "login_alert_confirm", Value: `0`, Type: "login"},
+	{Name: "password_min_length", Value: `4`, Type: "login"},
+	{Name: "password_classes", Value: ``, Type: "login"},
+	{Name: "password_breach_check", Value: `0`, Type: "login"},
+	{Name: "password_max_age_days", Value: `0`, Type: "login"},
+	{Name: "password_history", Value: `0`, Type: "login"},
 	{Name: "mail_reset_pwd_template", Value: `<!DOCTYPE html PUBLIC"-//W3C//DTD XHTML 1.0 Transitional//EN""http://www.w3.org/TR/xhtml1/DTD/xhtml1-transitional.dtd"><html xmlns="http://www.w3.org/1999/xhtml"style="font-family: 'Helvetica Neue', Helvetica, Arial, sans-serif; box-sizing: border-box;
 font-size: 14px; margin: 0;"><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>重设密码</title><style type="text/css">img{max-width:100%}body{-webkit-font-smoothing:antialiased;-webkit-text-size-adjust:none;width:100%!important;height:100%;line-height:1.6em}body{background-color:#f6f6f6}@media only screen and(max-width:640px){body{padding:0!important}h1{font-weight:800!important;margin:20px 0 5px!important}h2{font-weight:800!important;margin:20px 0 5px!important}h3{font-weight:800!important;margin:20px 0 5px!important}h4{font-weight:800!important;margin:20px 0 5px!important}h1{font-size:22px!important}h2{font-size:18px!important}h3{font-size:16px!important}.container{padding:0!important;width:100%!important}.content{padding:0!important}.content-wrap{padding:10px!important}.invoice{width:100%!important}}</style></head><body itemscope itemtype="http://schema.org/EmailMessage"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing:
 border-box; font-size: 14px; -webkit-font-smoothing: antialiased; -webkit-text-size-adjust: none; width: 100% !important; height: 100%; line-height: 1.6em; background-color: #f6f6f6; margin: 0;"bgcolor="#f6f6f6"><table class="body-wrap"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; width: 100%; background-color: #f6f6f6; margin: 0;"bgcolor="#f6f6f6"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif;
@@ -62,13 +104,95 @@ box-sizing: border-box; font-size: 14px; margin: 0;"><td style="font-family: 'He
 solid #e9e9e9;"bgcolor="#fff"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size:
 14px; margin: 0;"><td class="alert alert-warning"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 16px; vertical-align: top; color: #fff; font-weight: 500; text-align: center; border-radius: 3px 3px 0 0; background-color: #2196F3; margin: 0; padding: 20px;"align="center"bgcolor="#FF9F00"valign="top">重设{siteTitle}密码</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-wrap"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 20px;"valign="top"><table width="100%"cellpadding="0"cellspacing="0"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica
 Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">亲爱的<strong style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;">{userName}</strong>：</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">请点击下方按钮完成密码重设。如果非你本人操作，请忽略此邮件。</td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top"><a href="{resetUrl}"class="btn-primary"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; color: #FFF; text-decoration: none; line-height: 2em; font-weight: bold; text-align: center; cursor: pointer; display: inline-block; border-radius: 5px; text-transform: capitalize; background-color: #2196F3; margin: 0; border-color: #2196F3; border-style: solid; border-width: 10px 20px;">重设密码</a></td></tr><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0; padding: 0 0 20px;"valign="top">感谢您选择{siteTitle}。</td></tr></table></td></tr></table><div class="footer"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; width: 100%; clear: both; color: #999; margin: 0; padding: 20px;"><table width="100%"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><tr style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; margin: 0;"><td class="aligncenter content-block"style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 12px; vertical-align: top; color: #999; text-align: center; margin: 0; padding: 0 0 20px;"align="center"valign="top">此邮件由系统自动发送，请不要直接回复。</td></tr></table></div></div></td><td style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; vertical-align: top; margin: 0;"valign="top"></td></tr></table></body></html>`, Type: "mail_template"},
+	{Name: "mail_share_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>文件分享</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p><strong>{userName}</strong> 向您分享了 <strong>{fileName}</strong></p><p style="white-space: pre-wrap; color: #666;">{message}</p><p><a href="{shareUrl}"style="display: inline-block; background-color: #348eda; color: #fff; text-decoration: none; padding: 8px 16px; border-radius: 3px;">查看分享</a></p><p style="color: #999; font-size: 12px;">如果按钮无法点击，请复制以下链接到浏览器中打开：{shareUrl}</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_share_password_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>分享密码</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p><strong>{userName}</strong> 向您分享的 <strong>{fileName}</strong> 已加密，访问密码为：</p><p style="font-size: 20px; font-weight: bold; letter-spacing: 2px;">{password}</p><p style="color: #999; font-size: 12px; margin-top: 20px;">分享链接已通过另一封邮件单独发送。此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_login_alert_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>新设备登录提醒</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>您的账户刚刚在{reason}登录。</p><p style="color: #666;">时间：{time}<br/>IP：{ip}<br/>国家/地区：{country}<br/>设备：{device}</p><p>如果这不是您本人的操作，请立即修改密码并在设置中注销其他登录设备。</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_login_confirm_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>确认登录</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>您的账户正在{reason}登录，请在登录页面输入以下验证码完成登录：</p><p style="font-size: 20px; font-weight: bold; letter-spacing: 2px;">{code}</p><p style="color: #666;">时间：{time}<br/>IP：{ip}<br/>国家/地区：{country}<br/>设备：{device}</p><p>如果这不是您本人的操作，请立即修改密码。</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_group_overuse_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>用户组到期提醒</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>您的临时用户组「{expiredGroup}」已到期，账户已恢复为「{currentGroup}」。</p><p>您当前已使用 <strong>{used}</strong> 存储空间，超出了「{currentGroup}」的容量上限 <strong>{capacity}</strong>。现有文件不会被删除，但在清理文件使用量低于上限之前，将无法上传新文件。</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_email_change_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>验证新邮箱</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>您正在将账户邮箱更改为 <strong>{email}</strong>，请在设置页面输入以下验证码完成更改：</p><p style="font-size: 20px; font-weight: bold; letter-spacing: 2px;">{code}</p><p>验证码 10 分钟内有效。如果这不是您本人的操作，请忽略此邮件。</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_account_invite_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>账户已开通</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>管理员已为您开通账户 <strong>{email}</strong>，请点击下方按钮设置登录密码：</p><p><a href="{inviteUrl}"style="display: inline-block; background-color: #348eda; color: #fff; text-decoration: none; padding: 8px 16px; border-radius: 3px;">设置密码</a></p><p style="color: #999; font-size: 12px;">链接 7 天内有效。如果按钮无法点击，请复制以下链接到浏览器中打开：{inviteUrl}</p><p style="color: #999; font-size: 12px; margin-top: 20px;">此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_notification_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>通知</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p><strong>{title}</strong></p><p style="white-space: pre-wrap; color: #666;">{content}</p><p style="color: #999; font-size: 12px; margin-top: 20px;">您可以在设置页面中更改通知方式。此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_notification_digest_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>通知汇总</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>以下是您最近收到的 {count} 条通知：</p><ul style="padding-left: 20px;">{notifications}</ul><p style="color: #999; font-size: 12px; margin-top: 20px;">您可以在设置页面中更改通知方式。此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
+	{Name: "mail_usage_report_template", Value: `<!DOCTYPE html><html><head><meta name="viewport"content="width=device-width"/><meta http-equiv="Content-Type"content="text/html; charset=UTF-8"/><title>站点{period}</title></head><body style="font-family: 'Helvetica Neue',Helvetica,Arial,sans-serif; font-size: 14px; line-height: 1.6em; background-color: #f6f6f6; margin: 0; padding: 20px;"><div style="max-width: 600px; margin: 0 auto; background-color: #fff; border: 1px solid #e9e9e9; border-radius: 3px; padding: 20px;"><h2 style="font-size: 18px; margin: 0 0 20px;">{siteTitle}</h2><p>亲爱的<strong>{userName}</strong>：</p><p>以下是站点 {from} 至 {to} 的用量{period}：</p><table style="width: 100%; border-collapse: collapse;"><tr><td style="padding: 5px 0; color: #666;">新注册用户</td><td style="text-align: right;">{newUsers}</td></tr><tr><td style="padding: 5px 0; color: #666;">用户总数</td><td style="text-align: right;">{totalUsers}</td></tr><tr><td style="padding: 5px 0; color: #666;">单日最多活跃用户</td><td style="text-align: right;">{activeUsers}</td></tr><tr><td style="padding: 5px 0; color: #666;">上传流量</td><td style="text-align: right;">{uploadTraffic}</td></tr><tr><td style="padding: 5px 0; color: #666;">下载流量</td><td style="text-align: right;">{downloadTraffic}</td></tr><tr><td style="padding: 5px 0; color: #666;">存储用量</td><td style="text-align: right;">{storage}（{storageGrowth}）</td></tr></table><p><strong>下载最多的分享</strong></p><ol style="padding-left: 20px;">{topDownloads}</ol><p><strong>失败的任务</strong></p><ul style="padding-left: 20px;">{failedTasks}</ul><p style="color: #999; font-size: 12px; margin-top: 20px;">您可以在站点设置中更改或关闭用量报告。此邮件由 <a href="{siteUrl}"style="color: #999;">{siteTitle}</a> 系统自动发送，请不要直接回复。</p></div></body></html>`, Type: "mail_template"},
 	{Name: "db_version_" + conf.RequiredDBVersion, Value: `installed`, Type: "version"},
 	{Name: "hot_share_num", Value: `10`, Type: "share"},
+	{Name: "share_anonymous_upload", Value: `0`, Type: "share"},
+	{Name: "share_password_max_attempts", Value: `5`, Type: "share"},
+	{Name: "share_password_lock_duration", Value: `900`, Type: "share"},
+	{Name: "share_password_max_share_attempts", Value: `50`, Type: "share"},
+	{Name: "share_access_log", Value: `1`, Type: "share"},
+	{Name: "share_access_log_retention_days", Value: `30`, Type: "share"},
+	{Name: "notify_quota_threshold", Value: `90`, Type: "notification"},
+	{Name: "notify_webhook", Value: `1`, Type: "notification"},
+	{Name: "notify_webhook_timeout", Value: `10`, Type: "notification"},
+	{Name: "notify_share_interval", Value: `3600`, Type: "notification"},
+	{Name: "notify_retention_days", Value: `30`, Type: "notification"},
+	{Name: "share_revoke_on_move", Value: `1`, Type: "share"},
+	{Name: "share_allowed_ips", Value: ``, Type: "share"},
+	{Name: "share_allowed_countries", Value: ``, Type: "share"},
+	{Name: "federation_enabled", Value: `0`, Type: "share"},
+	{Name: "federation_trusted_hosts", Value: ``, Type: "share"},
+	{Name: "share_unfurl", Value: `1`, Type: "share"},
 	{Name: "gravatar_server", Value: `https://www.gravatar.com/`, Type: "avatar"},
 	{Name: "defaultTheme", Value: `#3f51b5`, Type: "basic"},
 	{Name: "themes", Value: `{"#3f51b5":{"palette":{"primary":{"main":"#3f51b5"},"secondary":{"main":"#f50057"}}},"#2196f3":{"palette":{"primary":{"main":"#2196f3"},"secondary":{"main":"#FFC107"}}},"#673AB7":{"palette":{"primary":{"main":"#673AB7"},"secondary":{"main":"#2196F3"}}},"#E91E63":{"palette":{"primary":{"main":"#E91E63"},"secondary":{"main":"#42A5F5","contrastText":"#fff"}}},"#FF5722":{"palette":{"primary":{"main":"#FF5722"},"secondary":{"main":"#3F51B5"}}},"#FFC107":{"palette":{"primary":{"main":"#FFC107"},"secondary":{"main":"#26C6DA"}}},"#8BC34A":{"palette":{"primary":{"main":"#8BC34A","contrastText":"#fff"},"secondary":{"main":"#FF8A65","contrastText":"#fff"}}},"#009688":{"palette":{"primary":{"main":"#009688"},"secondary":{"main":"#4DD0E1","contrastText":"#fff"}}},"#607D8B":{"palette":{"primary":{"main":"#607D8B"},"secondary":{"main":"#F06292"}}},"#795548":{"palette":{"primary":{"main":"#795548"},"secondary":{"main":"#4CAF50","contrastText":"#fff"}}}}`, Type: "basic"},
 	{Name: "max_worker_num", Value: `10`, Type: "task"},
 	{Name: "max_parallel_transfer", Value: `4`, Type: "task"},
+	{Name: "task_io_limit", Value: `0`, Type: "task"},
+	{Name: "task_retention_days", Value: `30`, Type: "task"},
+	{Name: "task_retention_count", Value: `200`, Type: "task"},
+	{Name: "file_change_retention_days", Value: `30`, Type: "sync"},
+	{Name: "idempotency_key_ttl", Value: `86400`, Type: "task"},
+	{Name: "rate_limit_enabled", Value: `1`, Type: "rate_limit"},
+	{Name: "rate_limit_auth", Value: `20`, Type: "rate_limit"},
+	{Name: "rate_limit_upload", Value: `600`, Type: "rate_limit"},
+	{Name: "rate_limit_share_download", Value: `60`, Type: "rate_limit"},
+	{Name: "rate_limit_api", Value: `1200`, Type: "rate_limit"},
+	{Name: "statistics_top_users", Value: `10`, Type: "statistics"},
+	{Name: "usage_report_period", Value: ``, Type: "statistics"},
+	{Name: "capacity_warning_percent", Value: `10`, Type: "capacity"},
+	{Name: "capacity_critical_percent", Value: `5`, Type: "capacity"},
+	{Name: "capacity_suspend_upload", Value: `0`, Type: "capacity"},
+	{Name: "health_check_ttl", Value: `30`, Type: "health"},
+	{Name: "health_check_timeout", Value: `10`, Type: "health"},
+	{Name: "health_check_token", Value: ``, Type: "health"},
+	{Name: "clamav_enabled", Value: `0`, Type: "clamav"},
+	{Name: "clamav_address", Value: `tcp://127.0.0.1:3310`, Type: "clamav"},
+	{Name: "clamav_timeout", Value: `300`, Type: "clamav"},
+	{Name: "clamav_max_size", Value: `104857600`, Type: "clamav"},
+	{Name: "clamav_action", Value: `quarantine`, Type: "clamav"},
+	{Name: "moderation_enabled", Value: `0`, Type: "moderation"},
+	{Name: "moderation_provider", Value: `nsfw`, Type: "moderation"},
+	{Name: "moderation_on_upload", Value: `1`, Type: "moderation"},
+	{Name: "moderation_on_share", Value: `1`, Type: "moderation"},
+	{Name: "moderation_auto_block", Value: `1`, Type: "moderation"},
+	{Name: "moderation_image_exts", Value: `jpg,jpeg,png,gif,webp,bmp`, Type: "moderation"},
+	{Name: "moderation_video_exts", Value: `mp4,mov,avi,mkv,flv,webm`, Type: "moderation"},
+	{Name: "moderation_batch_size", Value: `20`, Type: "moderation"},
+	{Name: "moderation_max_attempts", Value: `3`, Type: "moderation"},
+	{Name: "moderation_timeout", Value: `600`, Type: "moderation"},
+	{Name: "moderation_url_ttl", Value: `3600`, Type: "moderation"},
+	{Name: "moderation_aliyun_access_key", Value: ``, Type: "moderation"},
+	{Name: "moderation_aliyun_secret_key", Value: ``, Type: "moderation"},
+	{Name: "moderation_aliyun_region", Value: `cn-shanghai`, Type: "moderation"},
+	{Name: "moderation_aliyun_scenes", Value: `porn,terrorism`, Type: "moderation"},
+	{Name: "moderation_tencent_secret_id", Value: ``, Type: "moderation"},
+	{Name: "moderation_tencent_secret_key", Value: ``, Type: "moderation"},
+	{Name: "moderation_tencent_bucket", Value: ``, Type: "moderation"},
+	{Name: "moderation_tencent_region", Value: `ap-shanghai`, Type: "moderation"},
+	{Name: "moderation_nsfw_endpoint", Value: `http://127.0.0.1:5000/`, Type: "moderation"},
+	{Name: "moderation_nsfw_block_score", Value: `90`, Type: "moderation"},
+	{Name: "moderation_nsfw_review_score", Value: `60`, Type: "moderation"},
+	{Name: "hash_blocklist_enabled", Value: `0`, Type: "blocklist"},
+	{Name: "hash_blocklist_max_size", Value: `1073741824`, Type: "blocklist"},
+	{Name: "maintenance_enabled", Value: `0`, Type: "maintenance"},
+	{Name: "maintenance_message", Value: `站点正在维护升级，暂时无法上传文件或创建新任务，请稍后再试。`, Type: "maintenance"},
+	{Name: "cors_allow_origins", Value: ``, Type: "security"},
+	{Name: "cors_allow_credentials", Value: `0`, Type: "security"},
+	{Name: "security_csp", Value: ``, Type: "security"},
+	{Name: "security_frame_ancestors", Value: ``, Type: "security"},
+	{Name: "security_hsts_max_age", Value: `0`, Type: "security"},
+	{Name: "security_hsts_subdomains", Value: `0`, Type: "security"},
 	{Name: "secret_key", Value: util.RandStringRunes(256), Type: "auth"},
 	{Name: "temp_path", Value: "temp", Type: "path"},
 	{Name: "avatar_path", Value: "avatar", Type: "path"},
@@ -80,7 +204,50 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "share_view_method", Value: "list", Type: "view"},
 	{Name: "cron_garbage_collect", Value: "@hourly", Type: "cron"},
 	{Name: "cron_recycle_upload_session", Value: "@every 1h30m", Type: "cron"},
+	{Name: "cron_purge_task_history", Value: "@daily", Type: "cron"},
+	{Name: "cron_collect_expired_share", Value: "@every 10m", Type: "cron"},
+	{Name: "cron_ldap_sync", Value: "@hourly", Type: "cron"},
+	{Name: "cron_storage_audit", Value: "@weekly", Type: "cron"},
+	{Name: "cron_notification_digest", Value: "@hourly", Type: "cron"},
+	{Name: "cron_aggregate_statistics", Value: "@every 15m", Type: "cron"},
+	{Name: "cron_moderation", Value: "@every 1m", Type: "cron"},
+	{Name: "cron_usage_report", Value: "0 8 * * *", Type: "cron"},
+	{Name: "cron_capacity_check", Value: "@every 30m", Type: "cron"},
+	{Name: "cron_repair_folder_stats", Value: "@daily", Type: "cron"},
+	{Name: "ldap_enabled", Value: "0", Type: "ldap"},
+	{Name: "ldap_url", Value: "", Type: "ldap"},
+	{Name: "ldap_bind_dn", Value: "", Type: "ldap"},
+	{Name: "ldap_bind_password", Value: "", Type: "ldap"},
+	{Name: "ldap_base_dn", Value: "", Type: "ldap"},
+	{Name: "ldap_user_filter", Value: "(uid={username})", Type: "ldap"},
+	{Name: "ldap_email_attr", Value: "mail", Type: "ldap"},
+	{Name: "ldap_nick_attr", Value: "displayName", Type: "ldap"},
+	{Name: "ldap_group_attr", Value: "memberOf", Type: "ldap"},
+	{Name: "ldap_group_map", Value: "{}", Type: "ldap"},
+	{Name: "ldap_timeout", Value: "10", Type: "ldap"},
+	{Name: "ldap_sync", Value: "0", Type: "ldap"},
+	{Name: "oidc_enabled", Value: "0", Type: "oidc"},
+	{Name: "oidc_issuer", Value: "", Type: "oidc"},
+	{Name: "oidc_client_id", Value: "", Type: "oidc"},
+	{Name: "oidc_client_secret", Value: "", Type: "oidc"},
+	{Name: "oidc_scopes", Value: "openid profile email", Type: "oidc"},
+	{Name: "oidc_group_claim", Value: "groups", Type: "oidc"},
+	{Name: "oidc_group_map", Value: "{}", Type: "oidc"},
+	{Name: "oidc_register", Value: "1", Type: "oidc"},
+	{Name: "saml_enabled", Value: "0", Type: "saml"},
+	{Name: "saml_idp_entity_id", Value: "", Type: "saml"},
+	{Name: "saml_idp_sso_url", Value: "", Type: "saml"},
+	{Name: "saml_idp_slo_url", Value: "", Type: "saml"},
+	{Name: "saml_idp_certificate", Value: "", Type: "saml"},
+	{Name: "saml_sp_entity_id", Value: "", Type: "saml"},
+	{Name: "saml_email_attr", Value: "email", Type: "saml"},
+	{Name: "saml_nick_attr", Value: "displayName", Type: "saml"},
+	{Name: "saml_group_attr", Value: "groups", Type: "saml"},
+	{Name: "saml_group_map", Value: "{}", Type: "saml"},
+	{Name: "saml_register", Value: "1", Type: "saml"},
 	{Name: "authn_enabled", Value: "0", Type: "authn"},
+	{Name: "oauth_enabled", Value: "0", Type: "oauth"},
+	{Name: "oauth_token_ttl", Value: "3600", Type: "oauth"},
 	{Name: "captcha_type", Value: "normal", Type: "captcha"},
 	{Name: "captcha_height", Value: "60", Type: "captcha"},
 	{Name: "captcha_width", Value: "240", Type: "captcha"},
@@ -103,6 +270,8 @@ Neue',Helvetica,Arial,sans-serif; box-sizing: border-box; font-size: 14px; verti
 	{Name: "thumb_height", Value: "300", Type: "thumb"},
 	{Name: "thumb_file_suffix", Value: "._thumb", Type: "thumb"},
 	{Name: "thumb_max_task_count", Value: "-1", Type: "thumb"},
+	{Name: "thumb_queue_size", Value: "256", Type: "thumb"},
+	{Name: "thumb_storage_policy", Value: "0", Type: "thumb"},
 	{Name: "thumb_encode_method", Value: "jpg", Type: "thumb"},
 	{Name: "thumb_gc_after_gen", Value: "0", Type: "thumb"},
 	{Name: "thumb_encode_quality", Value: "85", Type: "thumb"},
@@ -0,0 +1,66 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// likeOperator 返回不区分大小写的模糊匹配运算符。MySQL、SQLite 的 LIKE 默认不区分大小写，
+// PostgreSQL 需使用 ILIKE 才能得到一致的搜索结果
+func likeOperator(db *gorm.DB) string {
+	if db.Dialect().GetName() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// insertIgnore 插入一条记录，与 keys 列上的唯一索引冲突时忽略，返回是否写入了新记录。
+// 冲突时不产生错误，可在事务中使用，PostgreSQL 的事务在语句出错后无法继续执行
+func insertIgnore(db *gorm.DB, value interface{}, keys []string, fields map[string]interface{}) (bool, error) {
+	dialect := db.Dialect()
+	table := dialect.Quote(db.NewScope(value).TableName())
+
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		quoted[i] = dialect.Quote(column)
+		args[i] = fields[column]
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(columns)), ",")
+
+	var sql string
+	switch dialect.GetName() {
+	case "mysql":
+		sql = fmt.Sprintf("INSERT IGNORE INTO %s (%s) VALUES (%s)", table, strings.Join(quoted, ","), placeholders)
+	case "sqlite3":
+		sql = fmt.Sprintf("INSERT OR IGNORE INTO %s (%s) VALUES (%s)", table, strings.Join(quoted, ","), placeholders)
+	case "postgres":
+		conflicts := make([]string, len(keys))
+		for i, key := range keys {
+			conflicts[i] = dialect.Quote(key)
+		}
+		sql = fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO NOTHING", table,
+			strings.Join(quoted, ","), placeholders, strings.Join(conflicts, ","))
+	default:
+		// 其他数据库没有忽略冲突的插入语法，仅在记录不存在时插入
+		conditions := make([]string, len(keys))
+		for i, key := range keys {
+			conditions[i] = dialect.Quote(key) + " = ?"
+			args = append(args, fields[key])
+		}
+		sql = fmt.Sprintf("INSERT INTO %s (%s) SELECT %s WHERE NOT EXISTS (SELECT 1 FROM %s WHERE %s)", table,
+			strings.Join(quoted, ","), placeholders, table, strings.Join(conditions, " AND "))
+	}
+
+	result := db.Exec(sql, args...)
+	return result.RowsAffected > 0, result.Error
+}
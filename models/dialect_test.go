@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// usePostgres 在测试中使用 PostgreSQL 方言生成 SQL
+func usePostgres(t *testing.T) {
+	pg, err := gorm.Open("postgres", mockDB.DB())
+	if err != nil {
+		t.Fatal(err)
+	}
+	DB = pg
+	t.Cleanup(func() {
+		DB = mockDB
+	})
+}
+
+func TestLikeOperator(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("LIKE", likeOperator(DB))
+
+	usePostgres(t)
+	asserts.Equal("ILIKE", likeOperator(DB))
+}
+
+func TestInsertIgnore(t *testing.T) {
+	asserts := assert.New(t)
+	fields := map[string]interface{}{"name": "siteName", "value": "Cloudreve"}
+
+	// MySQL
+	{
+		mock.ExpectExec("INSERT IGNORE INTO `settings` \\(`name`,`value`\\) VALUES \\(\\?,\\?\\)").
+			WithArgs("siteName", "Cloudreve").WillReturnResult(sqlmock.NewResult(1, 1))
+		inserted, err := insertIgnore(DB, &Setting{}, []string{"name"}, fields)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(inserted)
+	}
+
+	// SQL Server
+	{
+		mssql, _ := gorm.Open("mssql", mockDB.DB())
+		mock.ExpectExec("INSERT INTO \\[settings\\] \\(\\[name\\],\\[value\\]\\) SELECT (.+) WHERE NOT EXISTS \\(SELECT 1 FROM \\[settings\\] WHERE \\[name\\] = (.+)\\)").
+			WithArgs("siteName", "Cloudreve", "siteName").WillReturnResult(sqlmock.NewResult(0, 0))
+		inserted, err := insertIgnore(mssql, &Setting{}, []string{"name"}, fields)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(inserted)
+	}
+
+	usePostgres(t)
+
+	// PostgreSQL，记录已存在
+	{
+		mock.ExpectExec("INSERT INTO \"settings\" \\(\"name\",\"value\"\\) VALUES \\(\\$1,\\$2\\) ON CONFLICT \\(\"name\"\\) DO NOTHING").
+			WithArgs("siteName", "Cloudreve").WillReturnResult(sqlmock.NewResult(0, 0))
+		inserted, err := insertIgnore(DB, &Setting{}, []string{"name"}, fields)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(inserted)
+	}
+
+	// PostgreSQL，出错
+	{
+		mock.ExpectExec("INSERT INTO(.+)ON CONFLICT(.+)").WillReturnError(errors.New("error"))
+		inserted, err := insertIgnore(DB, &Setting{}, []string{"name"}, fields)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.False(inserted)
+	}
+}
+
+func TestPostgres_GetOrCreateStatistic(t *testing.T) {
+	asserts := assert.New(t)
+	usePostgres(t)
+
+	mock.ExpectQuery("SELECT (.+) FROM \"statistics\" WHERE (.+)date = \\$1(.+)").WithArgs("2022-01-02").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec("INSERT INTO \"statistics\" (.+) ON CONFLICT \\(\"date\"\\) DO NOTHING").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT (.+) FROM \"statistics\" WHERE (.+)date = \\$1(.+)").WithArgs("2022-01-02").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date"}).AddRow(3, "2022-01-02"))
+	stat, err := GetOrCreateStatistic("2022-01-02")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, stat.ID)
+}
+
+func TestPostgres_Search(t *testing.T) {
+	asserts := assert.New(t)
+	usePostgres(t)
+
+	// 文件搜索不区分大小写
+	{
+		mock.ExpectQuery("SELECT (.+) FROM \"files\" WHERE (.+)user_id = \\$1(.+)name ILIKE \\$2 or name ILIKE \\$3").
+			WithArgs(1, "%Report%", "%.PDF").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "report.pdf"))
+		files, err := GetFilesByKeywords(1, nil, "%Report%", "%.PDF")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(files, 1)
+	}
+
+	// 分页查询
+	{
+		mock.ExpectQuery("SELECT count(.+) FROM \"blocked_hashes\" WHERE (.+)note ILIKE \\$2").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+		mock.ExpectQuery("SELECT (.+) FROM \"blocked_hashes\" WHERE (.+)note ILIKE \\$2(.+) ORDER BY id desc LIMIT 10 OFFSET 10").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(2, "abc").AddRow(1, "abd"))
+		hashes, total := ListBlockedHashes("ab", 2, 10)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(12, total)
+		asserts.Len(hashes, 2)
+	}
+}
@@ -30,8 +30,10 @@ type Download struct {
 	User *User `gorm:"PRELOAD:false,association_autoupdate:false"`
 
 	// 数据库忽略字段
-	StatusInfo rpc.StatusInfo `gorm:"-"`
-	Task       *Task          `gorm:"-"`
+	StatusInfo  rpc.StatusInfo `gorm:"-"`
+	Task        *Task          `gorm:"-"`
+	RequestID   string         `gorm:"-" json:"-"` // 创建任务的请求 ID，用于关联日志
+	TraceParent string         `gorm:"-" json:"-"` // 创建任务的请求所在链路，用于延续链路追踪
 }
 
 // AfterFind 找到下载任务后的钩子，处理Status结构
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// DownloadToken 一次性下载链接凭证
+type DownloadToken struct {
+	gorm.Model
+	Token      string     `gorm:"unique_index:download_token"`
+	UserID     uint       // 创建者UID
+	FileID     uint       // 对应文件ID
+	Expires    *time.Time // 过期时间，空值表示无过期时间
+	ConsumedAt *time.Time // 被使用的时间，空值表示尚未使用
+}
+
+// Create 创建一次性下载凭证
+func (token *DownloadToken) Create() error {
+	if err := DB.Create(token).Error; err != nil {
+		util.Log().Warning("无法插入下载凭证记录, %s", err)
+		return err
+	}
+	return nil
+}
+
+// GetDownloadToken 根据凭证字符串查找尚未使用且未过期的一次性下载凭证
+func GetDownloadToken(token string) (*DownloadToken, error) {
+	var res DownloadToken
+	result := DB.Where("token = ? and consumed_at is NULL and (expires is NULL or expires > ?)", token, time.Now()).
+		First(&res)
+	return &res, result.Error
+}
+
+// Claim 原子地占用此凭证，返回是否占用成功，并发请求中只有一个能够成功
+func (token *DownloadToken) Claim() (bool, error) {
+	now := time.Now()
+	result := DB.Model(&DownloadToken{}).
+		Where("id = ? and consumed_at is NULL", token.ID).
+		UpdateColumn("consumed_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return false, nil
+	}
+
+	token.ConsumedAt = &now
+	return true, nil
+}
+
+// Release 下载失败时释放凭证，使其可以再次使用
+func (token *DownloadToken) Release() error {
+	token.ConsumedAt = nil
+	return DB.Model(token).UpdateColumn("consumed_at", gorm.Expr("NULL")).Error
+}
+
+// DeleteStaleDownloadTokens 删除已使用或已过期的一次性下载凭证
+func DeleteStaleDownloadTokens() error {
+	return DB.Unscoped().Where("consumed_at is not NULL or expires < ?", time.Now()).
+		Delete(&DownloadToken{}).Error
+}
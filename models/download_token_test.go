@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDownloadToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)download_tokens(.+)consumed_at is NULL(.+)").
+			WithArgs("token", sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "file_id"}).AddRow(1, 2))
+		res, err := GetDownloadToken("token")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, res.FileID)
+	}
+
+	// 不存在
+	{
+		mock.ExpectQuery("SELECT(.+)download_tokens(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := GetDownloadToken("token")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestDownloadToken_Claim(t *testing.T) {
+	asserts := assert.New(t)
+	token := DownloadToken{Model: gorm.Model{ID: 1}}
+
+	// 占用成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)consumed_at is NULL(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		claimed, err := token.Claim()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(claimed)
+		asserts.NotNil(token.ConsumedAt)
+	}
+
+	// 已被占用
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		claimed, err := token.Claim()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(claimed)
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		claimed, err := token.Claim()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.False(claimed)
+	}
+}
+
+func TestDownloadToken_Release(t *testing.T) {
+	asserts := assert.New(t)
+	token := DownloadToken{Model: gorm.Model{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)consumed_at(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(token.Release())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Nil(token.ConsumedAt)
+}
+
+func TestDeleteStaleDownloadTokens(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)download_tokens(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteStaleDownloadTokens())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// EmailDomainAllowed 检查邮箱域名是否符合注册及更改邮箱时的域名限制。
+// 禁止列表优先；允许列表不为空时，域名须在允许列表中。列表中的域名同时匹配其子域名
+func EmailDomainAllowed(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+
+	options := GetSettingByNames("email_domain_allow", "email_domain_deny")
+	if matchEmailDomain(domain, util.SplitList(options["email_domain_deny"])) {
+		return false
+	}
+
+	allow := util.SplitList(options["email_domain_allow"])
+	return len(allow) == 0 || matchEmailDomain(domain, allow)
+}
+
+// matchEmailDomain 域名是否为列表中的域名或其子域名
+func matchEmailDomain(domain string, patterns []string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(pattern, "*."), "@"))
+		if pattern != "" && (domain == pattern || strings.HasSuffix(domain, "."+pattern)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEmailDomainAllowed(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未设置限制
+	{
+		cache.Set("setting_email_domain_allow", "", 0)
+		cache.Set("setting_email_domain_deny", "", 0)
+		asserts.True(EmailDomainAllowed("a@example.com"))
+		asserts.False(EmailDomainAllowed("invalid"))
+	}
+
+	// 禁止列表
+	{
+		cache.Set("setting_email_domain_deny", "mailinator.com, *.temp.org", 0)
+		asserts.False(EmailDomainAllowed("a@Mailinator.com"))
+		asserts.False(EmailDomainAllowed("a@x.mailinator.com"))
+		asserts.False(EmailDomainAllowed("a@x.temp.org"))
+		asserts.True(EmailDomainAllowed("a@notmailinator.com"))
+	}
+
+	// 允许列表，禁止列表优先
+	{
+		cache.Set("setting_email_domain_allow", "example.com\n@corp.net", 0)
+		cache.Set("setting_email_domain_deny", "guest.example.com", 0)
+		asserts.True(EmailDomainAllowed("a@example.com"))
+		asserts.True(EmailDomainAllowed("a@dev.corp.net"))
+		asserts.False(EmailDomainAllowed("a@guest.example.com"))
+		asserts.False(EmailDomainAllowed("a@other.com"))
+	}
+
+	cache.Set("setting_email_domain_allow", "", 0)
+	cache.Set("setting_email_domain_deny", "", 0)
+}
@@ -0,0 +1,105 @@
+package model
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// FederatedShare 提供给其他实例用户挂载的联邦分享
+type FederatedShare struct {
+	gorm.Model
+	ShareID  uint   `gorm:"index:share_id"`
+	Token    string `gorm:"type:char(32);unique_index:federation_token"`
+	Instance string // 接收方实例地址
+	Email    string // 接收方用户邮箱
+}
+
+// RemoteShare 其他实例提供给本实例用户的联邦分享
+type RemoteShare struct {
+	gorm.Model
+	UserID   uint   `gorm:"index:user_id"`
+	Provider string // 分享方实例地址
+	Token    string `gorm:"type:char(32)"`
+	Name     string
+	Owner    string
+	IsDir    bool
+	Accepted bool
+}
+
+// NewFederatedShare 为接收方新建联邦分享记录，令牌可免密访问分享，使用 crypto/rand 生成
+func NewFederatedShare(shareID uint, instance, email string) *FederatedShare {
+	return &FederatedShare{
+		ShareID:  shareID,
+		Token:    util.RandSecureString(32),
+		Instance: instance,
+		Email:    email,
+	}
+}
+
+// Create 创建联邦分享记录
+func (federated *FederatedShare) Create() (uint, error) {
+	if err := DB.Create(federated).Error; err != nil {
+		return 0, err
+	}
+	return federated.ID, nil
+}
+
+// Delete 删除联邦分享记录
+func (federated *FederatedShare) Delete() error {
+	return DB.Unscoped().Delete(federated).Error
+}
+
+// GetFederatedShareByToken 根据令牌查找联邦分享记录
+func GetFederatedShareByToken(token string) (*FederatedShare, error) {
+	var federated FederatedShare
+	result := DB.Where("token = ?", token).First(&federated)
+	return &federated, result.Error
+}
+
+// GetShareByFederationToken 根据联邦分享令牌查找对应的分享
+func GetShareByFederationToken(token string) *Share {
+	federated, err := GetFederatedShareByToken(token)
+	if err != nil {
+		return nil
+	}
+
+	var share Share
+	if err := DB.First(&share, federated.ShareID).Error; err != nil {
+		return nil
+	}
+
+	return &share
+}
+
+// Create 创建远程分享记录
+func (remote *RemoteShare) Create() (uint, error) {
+	if err := DB.Create(remote).Error; err != nil {
+		return 0, err
+	}
+	return remote.ID, nil
+}
+
+// Accept 接受远程分享
+func (remote *RemoteShare) Accept() error {
+	remote.Accepted = true
+	return DB.Model(remote).Update("accepted", true).Error
+}
+
+// Delete 删除远程分享
+func (remote *RemoteShare) Delete() error {
+	return DB.Delete(remote).Error
+}
+
+// GetRemoteShare 根据ID和用户ID查找远程分享
+func GetRemoteShare(id, uid uint) (*RemoteShare, error) {
+	var remote RemoteShare
+	result := DB.Where("id = ? AND user_id = ?", id, uid).First(&remote)
+	return &remote, result.Error
+}
+
+// ListRemoteShares 列出用户收到的全部远程分享
+func ListRemoteShares(uid uint) []RemoteShare {
+	var remotes []RemoteShare
+	DB.Where("user_id = ?", uid).Order("id desc").Find(&remotes)
+	return remotes
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFederatedShare(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 令牌不能由 math/rand 的种子推算
+	rand.Seed(1)
+	predicted := util.RandStringRunes(32)
+	rand.Seed(1)
+	federated := NewFederatedShare(1, "https://example.com", "a@example.com")
+	asserts.Len(federated.Token, 32)
+	asserts.NotEqual(predicted, federated.Token)
+	asserts.NotEqual(federated.Token, NewFederatedShare(1, "https://example.com", "a@example.com").Token)
+	asserts.EqualValues(1, federated.ShareID)
+	asserts.Equal("https://example.com", federated.Instance)
+	asserts.Equal("a@example.com", federated.Email)
+}
+
+func TestGetShareByFederationToken(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 令牌不存在
+	{
+		mock.ExpectQuery("SELECT(.+)federated_shares(.+)").
+			WithArgs("token").
+			WillReturnError(errors.New("not found"))
+		asserts.Nil(GetShareByFederationToken("token"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 分享已被删除
+	{
+		mock.ExpectQuery("SELECT(.+)federated_shares(.+)").
+			WithArgs("token").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "share_id"}).AddRow(1, 2))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.Nil(GetShareByFederationToken("token"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)federated_shares(.+)").
+			WithArgs("token").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "share_id"}).AddRow(1, 2))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+		share := GetShareByFederationToken("token")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(share)
+		asserts.EqualValues(2, share.ID)
+	}
+}
+
+func TestRemoteShare_Accept(t *testing.T) {
+	asserts := assert.New(t)
+	remote := &RemoteShare{}
+	remote.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)remote_shares(.+)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(remote.Accept())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.True(remote.Accepted)
+}
+
+func TestGetRemoteShare(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)remote_shares(.+)").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token"}).AddRow(1, 2, "token"))
+	remote, err := GetRemoteShare(1, 2)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal("token", remote.Token)
+}
@@ -1,9 +1,12 @@
 package model
 
 import (
+	"crypto/sha1"
 	"encoding/gob"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"path"
 	"time"
 
@@ -55,7 +58,22 @@ func (file *File) Create() error {
 		return err
 	}
 
-	return tx.Commit().Error
+	touched, err := updateFolderStats(tx, map[uint]folderStat{file.FolderID: {Size: int64(file.Size), Files: 1}})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	// 上传中的占位文件在上传完成后才视为新建
+	if file.UploadSessionID == nil {
+		recordFileChanges(file.UserID, false, []uint{file.ID}, FileChangeCreated)
+	}
+	InvalidateFolderList(touched...)
+	return nil
 }
 
 // AfterFind 找到文件后的钩子
@@ -127,7 +145,7 @@ func GetFilesByKeywords(uid uint, parents []uint, keywords ...interface{}) ([]Fi
 
 	// 生成查询条件
 	for i := 0; i < len(keywords); i++ {
-		conditions += "name like ?"
+		conditions += "name " + likeOperator(DB) + " ?"
 		if i != len(keywords)-1 {
 			conditions += " or "
 		}
@@ -227,6 +245,8 @@ func DeleteFiles(files []*File, uid uint) error {
 	user := &User{}
 	user.ID = uid
 	var size uint64
+	ids := make([]uint, 0, len(files))
+	deltas := make(map[uint]folderStat)
 	for _, file := range files {
 		if file.UserID != uid {
 			tx.Rollback()
@@ -245,6 +265,8 @@ func DeleteFiles(files []*File, uid uint) error {
 		}
 
 		size += file.Size
+		ids = append(ids, file.ID)
+		deltas[file.FolderID] = deltas[file.FolderID].add(folderStat{Size: -int64(file.Size), Files: -1})
 	}
 
 	if err := user.ChangeStorage(tx, "-", size); err != nil {
@@ -252,7 +274,19 @@ func DeleteFiles(files []*File, uid uint) error {
 		return err
 	}
 
-	return tx.Commit().Error
+	touched, err := updateFolderStats(tx, deltas)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	recordFileChanges(uid, false, ids, FileChangeDeleted)
+	InvalidateFolderList(touched...)
+	return nil
 }
 
 // GetFilesByParentIDs 根据父目录ID查找文件
@@ -262,6 +296,22 @@ func GetFilesByParentIDs(ids []uint, uid uint) ([]File, error) {
 	return files, result.Error
 }
 
+// GetUserUsedStorage 按文件记录统计用户实际使用的容量
+func GetUserUsedStorage(uid uint) (uint64, error) {
+	var res struct {
+		Total uint64
+	}
+	err := DB.Model(&File{}).Select("coalesce(sum(size), 0) as total").Where("user_id = ?", uid).Scan(&res).Error
+	return res.Total, err
+}
+
+// GetFilesBySourceNames 查找给定存储策略下引用了给定物理对象的文件，不区分所有者
+func GetFilesBySourceNames(policyID uint, sources []string) ([]File, error) {
+	var files []File
+	err := DB.Where("policy_id = ? and source_name in (?)", policyID, sources).Find(&files).Error
+	return files, err
+}
+
 // GetFilesByUploadSession 查找上传会话对应的文件
 func GetFilesByUploadSession(sessionID string, uid uint) (*File, error) {
 	file := File{}
@@ -271,12 +321,23 @@ func GetFilesByUploadSession(sessionID string, uid uint) (*File, error) {
 
 // Rename 重命名文件
 func (file *File) Rename(new string) error {
-	return DB.Model(&file).UpdateColumn("name", new).Error
+	if err := DB.Model(&file).UpdateColumn("name", new).Error; err != nil {
+		return err
+	}
+
+	recordFileChanges(file.UserID, false, []uint{file.ID}, FileChangeMoved)
+	InvalidateFolderList(file.FolderID)
+	return nil
 }
 
 // UpdatePicInfo 更新文件的图像信息
 func (file *File) UpdatePicInfo(value string) error {
-	return DB.Model(&file).Set("gorm:association_autoupdate", false).UpdateColumns(File{PicInfo: value}).Error
+	if err := DB.Model(&file).Set("gorm:association_autoupdate", false).UpdateColumns(File{PicInfo: value}).Error; err != nil {
+		return err
+	}
+
+	InvalidateFolderList(file.FolderID)
+	return nil
 }
 
 // UpdateSize 更新文件的大小信息
@@ -307,8 +368,77 @@ func (file *File) UpdateSize(value uint64) error {
 		return err
 	}
 
+	if err := file.clearContentHashes(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	touched, err := updateFolderStats(tx, map[uint]folderStat{file.FolderID: {Size: int64(value) - int64(file.Size)}})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	file.Size = value
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if file.UploadSessionID == nil {
+		recordFileChanges(file.UserID, false, []uint{file.ID}, FileChangeModified)
+	}
+	InvalidateFolderList(touched...)
+	return nil
+}
+
+// ChangeSize 按增量更新文件大小，并同步更新用户已用容量及所在目录的统计。
+// 增量直接累加至数据库中的记录，并发更新同一文件时不会相互覆盖
+func (file *File) ChangeSize(operator string, delta uint64) error {
+	if delta == 0 {
+		return nil
+	}
+
+	tx := DB.Begin()
+	if err := tx.Model(&file).
+		Set("gorm:association_autoupdate", false).
+		Update("size", gorm.Expr("size "+operator+" ?", delta)).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	user := User{}
+	user.ID = file.UserID
+	if err := user.ChangeStorage(tx, operator, delta); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := file.clearContentHashes(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	stat := folderStat{Size: int64(delta)}
+	if operator == "-" {
+		stat = stat.neg()
+	}
+	touched, err := updateFolderStats(tx, map[uint]folderStat{file.FolderID: stat})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	if operator == "-" {
+		file.Size -= delta
+	} else {
+		file.Size += delta
+	}
+	InvalidateFolderList(touched...)
+	return nil
 }
 
 // UpdateSourceName 更新文件的源文件名
@@ -316,17 +446,57 @@ func (file *File) UpdateSourceName(value string) error {
 	return DB.Model(&file).Set("gorm:association_autoupdate", false).Update("source_name", value).Error
 }
 
+// QuarantineMetaKey 文件被隔离时在元数据中记录检出的病毒名称
+const QuarantineMetaKey = "quarantine"
+
+// 缩略图保存在独立的存储策略中时，在元数据中记录其存储策略 ID 及路径
+const (
+	ThumbPolicyMetaKey = "thumb_policy"
+	ThumbPathMetaKey   = "thumb_path"
+)
+
+// IsQuarantined 文件是否因检出病毒被隔离
+func (file *File) IsQuarantined() bool {
+	_, ok := file.MetadataSerialized[QuarantineMetaKey]
+	return ok
+}
+
+// UpdateMetadata 合并并保存文件元数据
+func (file *File) UpdateMetadata(data map[string]string) error {
+	if file.MetadataSerialized == nil {
+		file.MetadataSerialized = make(map[string]string, len(data))
+	}
+
+	for k, v := range data {
+		file.MetadataSerialized[k] = v
+	}
+
+	metaValue, err := json.Marshal(&file.MetadataSerialized)
+	if err != nil {
+		return err
+	}
+
+	file.Metadata = string(metaValue)
+	return DB.Model(file).Set("gorm:association_autoupdate", false).UpdateColumn("metadata", file.Metadata).Error
+}
+
 func (file *File) PopChunkToFile(lastModified *time.Time, picInfo string) error {
 	file.UploadSessionID = nil
 	if lastModified != nil {
 		file.UpdatedAt = *lastModified
 	}
 
-	return DB.Model(file).UpdateColumns(map[string]interface{}{
+	if err := DB.Model(file).UpdateColumns(map[string]interface{}{
 		"upload_session_id": file.UploadSessionID,
 		"updated_at":        file.UpdatedAt,
 		"pic_info":          picInfo,
-	}).Error
+	}).Error; err != nil {
+		return err
+	}
+
+	recordFileChanges(file.UserID, false, []uint{file.ID}, FileChangeModified)
+	InvalidateFolderList(file.FolderID)
+	return nil
 }
 
 // CanCopy 返回文件是否可被复制
@@ -334,6 +504,22 @@ func (file *File) CanCopy() bool {
 	return file.UploadSessionID == nil
 }
 
+// Hash 返回标识文件内容版本的摘要，文件内容被覆盖后摘要随之改变。
+// 修改时间仅精确到秒，以兼容不保存更高精度的数据库
+func (file *File) Hash() string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s/%d/%d", file.SourceName, file.Size, file.UpdatedAt.Unix())))
+	return hex.EncodeToString(sum[:])
+}
+
+// ETag 生成用于下载时条件请求及断点续传的实体标签。记录了内容摘要时以摘要生成强实体标签，
+// 否则以文件版本摘要生成弱实体标签，弱实体标签不能用于 If-Range 断点续传
+func (file *File) ETag() string {
+	if checksum := file.ContentChecksum(); checksum != "" {
+		return `"` + checksum + `"`
+	}
+	return `W/"` + file.Hash() + `"`
+}
+
 /*
 	实现 webdav.FileInfo 接口
 */
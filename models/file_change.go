@@ -0,0 +1,84 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 文件变更类型
+const (
+	FileChangeCreated = iota
+	FileChangeModified
+	FileChangeMoved
+	FileChangeDeleted
+)
+
+// fileChangeBatchSize 单条语句最多写入的变更记录数，避免超出数据库的参数数量限制
+const fileChangeBatchSize = 100
+
+// FileChange 文件及目录的变更记录，供同步客户端增量拉取
+type FileChange struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index:created_at"`
+	UserID    uint      `gorm:"index:user_id"`
+	IsDir     bool
+	ObjectID  uint
+	Action    int
+}
+
+// recordFileChanges 记录一批对象的变更，失败时仅记录日志，不影响已完成的文件操作
+func recordFileChanges(uid uint, isDir bool, ids []uint, action int) {
+	if len(ids) == 0 {
+		return
+	}
+
+	// gorm 不支持批量插入，此处直接拼接多行插入语句
+	table := DB.NewScope(&FileChange{}).QuotedTableName()
+	now := time.Now()
+	for start := 0; start < len(ids); start += fileChangeBatchSize {
+		end := start + fileChangeBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*5)
+		for _, id := range ids[start:end] {
+			values = append(values, "(?,?,?,?,?)")
+			args = append(args, now, uid, isDir, id, action)
+		}
+
+		if err := DB.Exec(
+			"INSERT INTO "+table+" (created_at,user_id,is_dir,object_id,action) VALUES "+strings.Join(values, ","),
+			args...,
+		).Error; err != nil {
+			util.Log().Warning("无法记录文件变更, %s", err)
+			return
+		}
+	}
+}
+
+// ListFileChanges 按顺序列出用户在 cursor 之后的至多 limit 条变更记录
+func ListFileChanges(uid, cursor uint, limit int) ([]FileChange, error) {
+	var changes []FileChange
+	err := DB.Where("user_id = ? and id > ?", uid, cursor).Order("id asc").Limit(limit).Find(&changes).Error
+	return changes, err
+}
+
+// GetLatestFileChangeID 获取用户最新一条变更记录的 ID，无记录时返回 0
+func GetLatestFileChangeID(uid uint) (uint, error) {
+	var change FileChange
+	result := DB.Select("id").Where("user_id = ?", uid).Order("id desc").Limit(1).Find(&change)
+	if result.RecordNotFound() {
+		return 0, nil
+	}
+	return change.ID, result.Error
+}
+
+// DeleteFileChangesBefore 删除给定时间之前的变更记录
+func DeleteFileChangesBefore(before time.Time) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&FileChange{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,95 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFileChanges(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无对象
+	{
+		recordFileChanges(1, false, []uint{}, FileChangeCreated)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 分批写入
+	{
+		ids := make([]uint, fileChangeBatchSize+1)
+		for i := range ids {
+			ids[i] = uint(i + 1)
+		}
+		mock.ExpectExec("INSERT INTO(.+)file_changes(.+)").WillReturnResult(sqlmock.NewResult(1, fileChangeBatchSize))
+		mock.ExpectExec("INSERT INTO(.+)file_changes(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(1), true, uint(fileChangeBatchSize+1), FileChangeDeleted).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		recordFileChanges(1, true, ids, FileChangeDeleted)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		mock.ExpectExec("INSERT INTO(.+)file_changes(.+)").WillReturnError(errors.New("error"))
+		recordFileChanges(1, false, []uint{1}, FileChangeModified)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListFileChanges(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)file_changes(.+)").
+		WithArgs(1, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "object_id", "action"}).AddRow(6, 2, FileChangeMoved).AddRow(8, 3, FileChangeDeleted))
+	changes, err := ListFileChanges(1, 5, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(changes, 2)
+	asserts.EqualValues(8, changes[1].ID)
+	asserts.Equal(FileChangeDeleted, changes[1].Action)
+}
+
+func TestGetLatestFileChangeID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无记录
+	{
+		mock.ExpectQuery("SELECT(.+)file_changes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		id, err := GetLatestFileChangeID(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(0, id)
+	}
+
+	// 出错
+	{
+		mock.ExpectQuery("SELECT(.+)file_changes(.+)").WillReturnError(errors.New("error"))
+		_, err := GetLatestFileChangeID(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)file_changes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+		id, err := GetLatestFileChangeID(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(12, id)
+	}
+}
+
+func TestDeleteFileChangesBefore(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	deleted, err := DeleteFileChangesBefore(time.Now())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, deleted)
+}
@@ -0,0 +1,121 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrFileLocked 资源已被其他客户端锁定
+var ErrFileLocked = errors.New("resource is locked")
+
+// FileLock WebDAV 客户端创建的文件锁。锁保存在数据库中，由各个节点共享，
+// 文件系统写入或删除前统一检查，对所有前端生效。Root 为资源在所有者目录树中的完整路径
+type FileLock struct {
+	ID        uint `gorm:"primary_key"`
+	CreatedAt time.Time
+	Token     string `gorm:"size:64;unique_index"`
+	UserID    uint   `gorm:"index"`
+	Root      string `gorm:"type:text"`
+	ZeroDepth bool
+	OwnerXML  string `gorm:"type:text"`
+	ExpiresAt time.Time
+}
+
+// isSubPath child 是否位于 parent 目录下
+func isSubPath(parent, child string) bool {
+	if parent == "/" {
+		return child != "/"
+	}
+	return strings.HasPrefix(child, parent+"/")
+}
+
+// Covers 锁是否作用于 p
+func (lock *FileLock) Covers(p string) bool {
+	return lock.Root == p || !lock.ZeroDepth && isSubPath(lock.Root, p)
+}
+
+// conflicts 在 root 处新建锁是否与此锁冲突，zeroDepth 为假时新锁同时作用于 root 下的资源
+func (lock *FileLock) conflicts(root string, zeroDepth bool) bool {
+	return lock.Covers(root) || !zeroDepth && isSubPath(root, lock.Root)
+}
+
+// GetFileLocks 获取用户未过期的锁
+func GetFileLocks(uid uint) ([]FileLock, error) {
+	var locks []FileLock
+	err := DB.Where("user_id = ? and expires_at > ?", uid, time.Now()).Find(&locks).Error
+	return locks, err
+}
+
+// GetFileLockByToken 根据 token 获取用户未过期的锁
+func GetFileLockByToken(uid uint, token string) (*FileLock, error) {
+	var lock FileLock
+	err := DB.Where("user_id = ? and token = ? and expires_at > ?", uid, token, time.Now()).First(&lock).Error
+	return &lock, err
+}
+
+// CreateFileLock 创建锁，与用户已有的锁冲突时返回 ErrFileLocked。先写入锁再检查先于它创建的锁，
+// 多个节点并发锁定同一资源时只有先写入的一方成功
+func CreateFileLock(lock *FileLock) error {
+	// 顺带清理已过期的锁
+	if err := DB.Where("user_id = ? and expires_at <= ?", lock.UserID, time.Now()).Delete(&FileLock{}).Error; err != nil {
+		return err
+	}
+
+	if err := DB.Create(lock).Error; err != nil {
+		return err
+	}
+
+	locks, err := GetFileLocks(lock.UserID)
+	if err != nil {
+		lock.Delete()
+		return err
+	}
+
+	for _, existing := range locks {
+		if existing.ID < lock.ID && existing.conflicts(lock.Root, lock.ZeroDepth) {
+			lock.Delete()
+			return ErrFileLocked
+		}
+	}
+
+	return nil
+}
+
+// CheckFileLocks 检查写入 paths 时是否会修改被锁定的资源，paths 为目录时其下的资源被锁定同样视为冲突
+func CheckFileLocks(uid uint, paths ...string) error {
+	locks, err := GetFileLocks(uid)
+	if err != nil {
+		return err
+	}
+
+	return FileLocksConflict(locks, paths...)
+}
+
+// FileLocksConflict 检查写入 paths 时是否会修改 locks 锁定的资源
+func FileLocksConflict(locks []FileLock, paths ...string) error {
+	for _, lock := range locks {
+		for _, p := range paths {
+			if lock.conflicts(p, false) {
+				return ErrFileLocked
+			}
+		}
+	}
+
+	return nil
+}
+
+// Refresh 延长锁的有效期
+func (lock *FileLock) Refresh(expires time.Time) error {
+	if err := DB.Model(&FileLock{}).Where("id = ?", lock.ID).Update("expires_at", expires).Error; err != nil {
+		return err
+	}
+
+	lock.ExpiresAt = expires
+	return nil
+}
+
+// Delete 删除锁
+func (lock *FileLock) Delete() error {
+	return DB.Where("id = ?", lock.ID).Delete(&FileLock{}).Error
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var fileLockRows = []string{"id", "token", "user_id", "root", "zero_depth", "expires_at"}
+
+func TestFileLock_Covers(t *testing.T) {
+	asserts := assert.New(t)
+
+	infinite := &FileLock{Root: "/a"}
+	asserts.True(infinite.Covers("/a"))
+	asserts.True(infinite.Covers("/a/b/c.txt"))
+	asserts.False(infinite.Covers("/ab"))
+	asserts.False(infinite.Covers("/"))
+
+	zero := &FileLock{Root: "/a", ZeroDepth: true}
+	asserts.True(zero.Covers("/a"))
+	asserts.False(zero.Covers("/a/b"))
+
+	root := &FileLock{Root: "/"}
+	asserts.True(root.Covers("/a"))
+
+	// 新锁作用于已锁定资源的父目录
+	asserts.True(zero.conflicts("/", false))
+	asserts.False(zero.conflicts("/", true))
+}
+
+func TestCreateFileLock(t *testing.T) {
+	asserts := assert.New(t)
+	expires := time.Now().Add(time.Hour)
+
+	// 成功
+	{
+		lock := &FileLock{Token: "token", UserID: 1, Root: "/a/b.txt", ZeroDepth: true, ExpiresAt: expires}
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_locks(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(fileLockRows).
+				AddRow(1, "other", 1, "/a/c.txt", true, expires).
+				AddRow(2, "token", 1, "/a/b.txt", true, expires))
+		asserts.NoError(CreateFileLock(lock))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, lock.ID)
+	}
+
+	// 与先创建的锁冲突
+	{
+		lock := &FileLock{Token: "token", UserID: 1, Root: "/a/b.txt", ZeroDepth: true, ExpiresAt: expires}
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_locks(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(fileLockRows).
+				AddRow(1, "other", 1, "/a", false, expires).
+				AddRow(2, "token", 1, "/a/b.txt", true, expires))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_locks(.+)").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.Equal(ErrFileLocked, CreateFileLock(lock))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 后创建的锁不影响先创建的锁
+	{
+		lock := &FileLock{Token: "token", UserID: 1, Root: "/a", ExpiresAt: expires}
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_locks(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(fileLockRows).
+				AddRow(2, "token", 1, "/a", false, expires).
+				AddRow(3, "other", 1, "/a/b.txt", true, expires))
+		asserts.NoError(CreateFileLock(lock))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		lock := &FileLock{Token: "token", UserID: 1, Root: "/a", ExpiresAt: expires}
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)file_locks(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(CreateFileLock(lock))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCheckFileLocks(t *testing.T) {
+	asserts := assert.New(t)
+	expires := time.Now().Add(time.Hour)
+
+	// 未锁定
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(fileLockRows).AddRow(1, "token", 1, "/a/b.txt", true, expires))
+		asserts.NoError(CheckFileLocks(1, "/a/c.txt", "/b"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目录下的资源被锁定
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(fileLockRows).AddRow(1, "token", 1, "/a/b.txt", true, expires))
+		asserts.Equal(ErrFileLocked, CheckFileLocks(1, "/b", "/a"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(CheckFileLocks(1, "/a"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFileLock_Refresh(t *testing.T) {
+	asserts := assert.New(t)
+	lock := &FileLock{ID: 1}
+	expires := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)file_locks(.+)").WithArgs(expires, 1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(lock.Refresh(expires))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(expires, lock.ExpiresAt)
+
+	// 失败时不修改
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)file_locks(.+)").WillReturnError(errors.New("error"))
+	mock.ExpectRollback()
+	asserts.Error(lock.Refresh(time.Now()))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(expires, lock.ExpiresAt)
+}
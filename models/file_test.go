@@ -410,6 +410,28 @@ func TestGetFilesByParentIDs(t *testing.T) {
 	asserts.Len(files, 3)
 }
 
+func TestGetUserUsedStorage(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT coalesce(.+)files(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(10))
+	total, err := GetUserUsedStorage(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(10, total)
+}
+
+func TestGetFilesBySourceNames(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a", "b").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "a"))
+	files, err := GetFilesBySourceNames(1, []string{"a", "b"})
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(files, 1)
+}
+
 func TestGetFilesByUploadSession(t *testing.T) {
 	a := assert.New(t)
 
@@ -456,6 +478,69 @@ func TestFile_Updates(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
 	}
+
+	// UpdateMetadata
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)metadata(.+)").WithArgs(`{"k":"v"}`, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		err := file.UpdateMetadata(map[string]string{"k": "v"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("v", file.MetadataSerialized["k"])
+	}
+}
+
+func TestFile_ChangeSize(t *testing.T) {
+	a := assert.New(t)
+
+	// 增加成功
+	{
+		file := File{Size: 10}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)size(.+)+(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)storage(.+)+(.+)").WithArgs(uint64(4), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		a.NoError(file.ChangeSize("+", 4))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(14, file.Size)
+	}
+
+	// 减少成功
+	{
+		file := File{Size: 10}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)size(.+)-(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)storage(.+)-(.+)").WithArgs(uint64(4), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		a.NoError(file.ChangeSize("-", 4))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(6, file.Size)
+	}
+
+	// 无增量
+	{
+		file := File{Size: 10}
+		a.NoError(file.ChangeSize("+", 0))
+		a.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 文件更新失败
+	{
+		file := File{Size: 10}
+		file.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+
+		a.Error(file.ChangeSize("+", 4))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(10, file.Size)
+	}
 }
 
 func TestFile_UpdateSize(t *testing.T) {
@@ -527,6 +612,60 @@ func TestFile_CanCopy(t *testing.T) {
 	a.False(file.CanCopy())
 }
 
+func TestFile_IsQuarantined(t *testing.T) {
+	a := assert.New(t)
+	file := File{}
+	a.False(file.IsQuarantined())
+	file.MetadataSerialized = map[string]string{QuarantineMetaKey: "Eicar-Test-Signature"}
+	a.True(file.IsQuarantined())
+}
+
+func TestFile_Hash(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	file := File{SourceName: "1_a.txt", Size: 10}
+	file.UpdatedAt = now
+
+	hash := file.Hash()
+	a.Len(hash, 40)
+
+	// 同一秒内的修改时间视为相同
+	file.UpdatedAt = now.Truncate(time.Second)
+	a.Equal(hash, file.Hash())
+
+	// 内容变更
+	file.Size = 11
+	a.NotEqual(hash, file.Hash())
+}
+
+func TestFile_ETag(t *testing.T) {
+	a := assert.New(t)
+
+	// 未记录内容摘要时为弱实体标签
+	file := File{SourceName: "1_a.txt", Size: 10}
+	a.Equal(`W/"`+file.Hash()+`"`, file.ETag())
+
+	// 以最强的内容摘要生成强实体标签
+	file.MetadataSerialized = map[string]string{HashMD5MetaKey: "md5", HashSHA256MetaKey: "sha256"}
+	a.Equal(`"sha256"`, file.ETag())
+}
+
+func TestFile_UpdateSize_ClearHashes(t *testing.T) {
+	a := assert.New(t)
+	file := File{Model: gorm.Model{ID: 1}, Size: 10, MetadataSerialized: map[string]string{HashSHA256MetaKey: "sha256", "other": "value"}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)files(.+)").WithArgs(11, sqlmock.AnyArg(), 1, 10).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE(.+)storage(.+)+(.+)").WithArgs(uint64(1), sqlmock.AnyArg()).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE(.+)files(.+)metadata(.+)").WithArgs(`{"other":"value"}`, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	a.NoError(file.UpdateSize(11))
+	a.NoError(mock.ExpectationsWereMet())
+	a.Empty(file.ContentChecksum())
+	a.Equal("value", file.MetadataSerialized["other"])
+}
+
 func TestFile_FileInfoInterface(t *testing.T) {
 	asserts := assert.New(t)
 	file := File{
@@ -17,18 +17,34 @@ type Folder struct {
 	ParentID *uint  `gorm:"index:parent_id;unique_index:idx_only_one_name"`
 	OwnerID  uint   `gorm:"index:owner_id"`
 
+	// 累计统计，包括所有递归子目录，随文件及目录操作增量更新
+	Size      uint64
+	FileNum   uint64
+	FolderNum uint64
+
 	// 数据库忽略字段
 	Position string `gorm:"-"`
 }
 
 // Create 创建目录
 func (folder *Folder) Create() (uint, error) {
-	if err := DB.FirstOrCreate(folder, *folder).Error; err != nil {
+	result := DB.FirstOrCreate(folder, *folder)
+	if err := result.Error; err != nil {
 		folder.Model = gorm.Model{}
 		err2 := DB.First(folder, *folder).Error
 		return folder.ID, err2
 	}
 
+	recordFileChanges(folder.OwnerID, true, []uint{folder.ID}, FileChangeCreated)
+
+	// 目录已存在时不影响上级目录的统计
+	if result.RowsAffected > 0 && folder.ParentID != nil {
+		touched, err := updateFolderStats(DB, map[uint]folderStat{*folder.ParentID: {Folders: 1}})
+		if err != nil {
+			util.Log().Warning("无法更新目录统计, %s", err)
+		}
+		InvalidateFolderList(touched...)
+	}
 	return folder.ID, nil
 }
 
@@ -127,10 +143,15 @@ func GetRecursiveChildFolder(dirs []uint, uid uint, includeSelf bool) ([]Folder,
 	return folders, err
 }
 
-// DeleteFolderByIDs 根据给定ID批量删除目录记录
-func DeleteFolderByIDs(ids []uint) error {
-	result := DB.Where("id in (?)", ids).Unscoped().Delete(&Folder{})
-	return result.Error
+// DeleteFolderByIDs 根据给定ID批量删除用户的目录记录
+func DeleteFolderByIDs(ids []uint, uid uint) error {
+	result := DB.Where("id in (?) and owner_id = ?", ids, uid).Unscoped().Delete(&Folder{})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	recordFileChanges(uid, true, ids, FileChangeDeleted)
+	return nil
 }
 
 // GetFoldersByIDs 根据ID和用户查找所有目录
@@ -158,7 +179,19 @@ func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy b
 			return 0, err
 		}
 
-		// 复制文件记录
+		// 复制文件记录，已复制的文件在返回前记录变更并计入目标目录的统计
+		copiedIDs := make([]uint, 0, len(originFiles))
+		defer func() {
+			recordFileChanges(dstFolder.OwnerID, false, copiedIDs, FileChangeCreated)
+			touched, err := updateFolderStats(DB, map[uint]folderStat{
+				dstFolder.ID: {Size: int64(copiedSize), Files: int64(len(copiedIDs))},
+			})
+			if err != nil {
+				util.Log().Warning("无法更新目录统计, %s", err)
+			}
+			InvalidateFolderList(touched...)
+		}()
+
 		for _, oldFile := range originFiles {
 			if !oldFile.CanCopy() {
 				util.Log().Warning("无法复制正在上传中的文件 [%s]， 跳过...", oldFile.Name)
@@ -174,11 +207,29 @@ func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy b
 			}
 
 			copiedSize += oldFile.Size
+			copiedIDs = append(copiedIDs, oldFile.ID)
 		}
 
 	} else {
+		tx := DB.Begin()
+
+		// 统计被移动文件的大小及数量
+		var moved struct {
+			Size uint64
+			Num  uint64
+		}
+		if err := tx.Model(&File{}).Select("coalesce(sum(size), 0) as size, count(*) as num").Where(
+			"id in (?) and user_id = ? and folder_id = ?",
+			files,
+			folder.OwnerID,
+			folder.ID,
+		).Scan(&moved).Error; err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
 		// 更改顶级要移动文件的父目录指向
-		err := DB.Model(File{}).Where(
+		err := tx.Model(File{}).Where(
 			"id in (?) and user_id = ? and folder_id = ?",
 			files,
 			folder.OwnerID,
@@ -189,9 +240,23 @@ func (folder *Folder) MoveOrCopyFileTo(files []uint, dstFolder *Folder, isCopy b
 			}).
 			Error
 		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		delta := folderStat{Size: int64(moved.Size), Files: int64(moved.Num)}
+		touched, err := updateFolderStats(tx, map[uint]folderStat{folder.ID: delta.neg(), dstFolder.ID: delta})
+		if err != nil {
+			tx.Rollback()
+			return 0, err
+		}
+
+		if err := tx.Commit().Error; err != nil {
 			return 0, err
 		}
 
+		recordFileChanges(folder.OwnerID, false, files, FileChangeMoved)
+		InvalidateFolderList(touched...)
 	}
 
 	return copiedSize, nil
@@ -213,6 +278,22 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 		subFolderIDs[key] = value.ID
 	}
 
+	// 新建的目录及文件在返回前记录变更，复制中途出错时已复制的部分同样需要记录
+	var newFolderIDs, newFileIDs []uint
+	defer func() {
+		recordFileChanges(dstFolder.OwnerID, true, newFolderIDs, FileChangeCreated)
+		recordFileChanges(dstFolder.OwnerID, false, newFileIDs, FileChangeCreated)
+
+		// 复制的目录沿用原目录的统计，仅需累加至目标目录
+		touched, err := updateFolderStats(DB, map[uint]folderStat{
+			dstFolder.ID: {Size: int64(size), Files: int64(len(newFileIDs)), Folders: int64(len(newFolderIDs))},
+		})
+		if err != nil {
+			util.Log().Warning("无法更新目录统计, %s", err)
+		}
+		InvalidateFolderList(touched...)
+	}()
+
 	// 复制子目录
 	var newIDCache = make(map[uint]uint)
 	for _, folder := range subFolders {
@@ -238,6 +319,7 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 		}
 		// 记录新的ID以便其子目录使用
 		newIDCache[oldID] = folder.ID
+		newFolderIDs = append(newFolderIDs, folder.ID)
 
 	}
 
@@ -266,6 +348,7 @@ func (folder *Folder) CopyFolderTo(folderID uint, dstFolder *Folder) (size uint6
 		}
 
 		size += oldFile.Size
+		newFileIDs = append(newFileIDs, oldFile.ID)
 	}
 
 	return size, nil
@@ -282,8 +365,25 @@ func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
 		return errors.New("cannot move a folder into itself")
 	}
 
+	tx := DB.Begin()
+
+	// 统计被移动目录的累计大小及子项数量
+	var moved struct {
+		Size      uint64
+		FileNum   uint64
+		FolderNum uint64
+		Num       uint64
+	}
+	if err := tx.Model(&Folder{}).
+		Select("coalesce(sum(size), 0) as size, coalesce(sum(file_num), 0) as file_num, coalesce(sum(folder_num), 0) as folder_num, count(*) as num").
+		Where("id in (?) and owner_id = ? and parent_id = ?", dirs, folder.OwnerID, folder.ID).
+		Scan(&moved).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	// 更改顶级要移动目录的父目录指向
-	err := DB.Model(Folder{}).Where(
+	err := tx.Model(Folder{}).Where(
 		"id in (?) and owner_id = ? and parent_id = ?",
 		dirs,
 		folder.OwnerID,
@@ -291,14 +391,39 @@ func (folder *Folder) MoveFolderTo(dirs []uint, dstFolder *Folder) error {
 	).Update(map[string]interface{}{
 		"parent_id": dstFolder.ID,
 	}).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 
-	return err
+	delta := folderStat{Size: int64(moved.Size), Files: int64(moved.FileNum), Folders: int64(moved.FolderNum + moved.Num)}
+	touched, err := updateFolderStats(tx, map[uint]folderStat{folder.ID: delta.neg(), dstFolder.ID: delta})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	recordFileChanges(folder.OwnerID, true, dirs, FileChangeMoved)
+	InvalidateFolderList(touched...)
+	return nil
 
 }
 
 // Rename 重命名目录
 func (folder *Folder) Rename(new string) error {
-	return DB.Model(&folder).UpdateColumn("name", new).Error
+	if err := DB.Model(&folder).UpdateColumn("name", new).Error; err != nil {
+		return err
+	}
+
+	recordFileChanges(folder.OwnerID, true, []uint{folder.ID}, FileChangeMoved)
+	if folder.ParentID != nil {
+		InvalidateFolderList(*folder.ParentID)
+	}
+	return nil
 }
 
 /*
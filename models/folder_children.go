@@ -0,0 +1,243 @@
+package model
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 目录子项的排序字段
+const (
+	ChildOrderName = "name"
+	ChildOrderSize = "size"
+	ChildOrderDate = "updated_at"
+)
+
+// 目录子项的类型筛选
+const (
+	ChildTypeDir  = "dir"
+	ChildTypeFile = "file"
+)
+
+// ErrInvalidChildrenCursor 无法解析的分页游标
+var ErrInvalidChildrenCursor = errors.New("invalid cursor")
+
+// ChildrenQuery 分页列取目录子项的条件
+type ChildrenQuery struct {
+	// OrderBy 排序字段，子目录按其累计大小排序
+	OrderBy string
+	Desc    bool
+	// Type 仅列出子目录或文件，为空时均列出
+	Type    string
+	Keyword string
+	Limit   int
+	// Cursor 上一页返回的游标，为空时从第一项开始
+	Cursor *ChildrenCursor
+}
+
+// ChildrenCursor 目录子项的分页游标，指向上一页的最后一项。子目录排在文件之前，
+// IsFile 为真时表示已列取至文件部分，ID 为 0 时从该部分的第一项开始
+type ChildrenCursor struct {
+	OrderBy string
+	Desc    bool
+	IsFile  bool
+	ID      uint
+	// Value 最后一项排序字段的值
+	Value string
+}
+
+// String 将游标编码为字符串
+func (cursor *ChildrenCursor) String() string {
+	kind, direction := ChildTypeDir, "asc"
+	if cursor.IsFile {
+		kind = ChildTypeFile
+	}
+	if cursor.Desc {
+		direction = "desc"
+	}
+
+	raw := fmt.Sprintf("%s.%s.%s.%d.%s", kind, cursor.OrderBy, direction, cursor.ID, cursor.Value)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseChildrenCursor 解析游标字符串
+func ParseChildrenCursor(s string) (*ChildrenCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidChildrenCursor
+	}
+
+	parts := strings.SplitN(string(raw), ".", 5)
+	if len(parts) != 5 {
+		return nil, ErrInvalidChildrenCursor
+	}
+
+	id, err := strconv.ParseUint(parts[3], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidChildrenCursor
+	}
+
+	cursor := &ChildrenCursor{
+		OrderBy: parts[1],
+		Desc:    parts[2] == "desc",
+		IsFile:  parts[0] == ChildTypeFile,
+		ID:      uint(id),
+		Value:   parts[4],
+	}
+	if _, err := childOrderArg(childOrderColumn(cursor.OrderBy), cursor.Value, cursor.ID); err != nil {
+		return nil, err
+	}
+
+	return cursor, nil
+}
+
+// childOrderColumn 获取排序字段对应的数据库字段
+func childOrderColumn(orderBy string) string {
+	switch orderBy {
+	case ChildOrderSize:
+		return ChildOrderSize
+	case ChildOrderDate:
+		return ChildOrderDate
+	default:
+		return ChildOrderName
+	}
+}
+
+// childOrderArg 将游标中排序字段的值转换为查询参数
+func childOrderArg(column, value string, id uint) (interface{}, error) {
+	if id == 0 {
+		return nil, nil
+	}
+
+	switch column {
+	case ChildOrderSize:
+		size, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidChildrenCursor
+		}
+		return size, nil
+	case ChildOrderDate:
+		date, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, ErrInvalidChildrenCursor
+		}
+		return date, nil
+	default:
+		return value, nil
+	}
+}
+
+// childOrderValue 获取排序字段的值，用于生成游标
+func childOrderValue(column, name string, size uint64, date time.Time) string {
+	switch column {
+	case ChildOrderSize:
+		return strconv.FormatUint(size, 10)
+	case ChildOrderDate:
+		return date.Format(time.RFC3339Nano)
+	default:
+		return name
+	}
+}
+
+// scope 为查询附加筛选、游标及排序条件
+func (query *ChildrenQuery) scope(tx *gorm.DB, isFile bool) (*gorm.DB, error) {
+	column := childOrderColumn(query.OrderBy)
+	if query.Keyword != "" {
+		tx = tx.Where("name like ?", "%"+query.Keyword+"%")
+	}
+
+	op, direction := ">", "asc"
+	if query.Desc {
+		op, direction = "<", "desc"
+	}
+
+	// 从游标之后开始
+	if cursor := query.Cursor; cursor != nil && cursor.IsFile == isFile && cursor.ID > 0 {
+		value, err := childOrderArg(column, cursor.Value, cursor.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		tx = tx.Where(
+			fmt.Sprintf("%s %s ? or (%s = ? and id %s ?)", column, op, column, op),
+			value, value, cursor.ID,
+		)
+	}
+
+	return tx.Order(fmt.Sprintf("%s %s, id %s", column, direction, direction)), nil
+}
+
+// ListChildren 按排序及筛选条件在数据库中分页列出子目录及文件，子目录排在文件之前。
+// 返回下一页的游标，已无更多子项时为 nil
+func (folder *Folder) ListChildren(query *ChildrenQuery) ([]Folder, []File, *ChildrenCursor, error) {
+	var (
+		folders []Folder
+		files   []File
+	)
+
+	position := path.Join(folder.Position, folder.Name)
+	next := &ChildrenCursor{OrderBy: query.OrderBy, Desc: query.Desc}
+
+	// 子目录
+	if query.Type != ChildTypeFile && (query.Cursor == nil || !query.Cursor.IsFile) {
+		tx, err := query.scope(DB.Where("parent_id = ?", folder.ID), false)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if err := tx.Limit(query.Limit + 1).Find(&folders).Error; err != nil {
+			return nil, nil, nil, err
+		}
+
+		for i := 0; i < len(folders); i++ {
+			folders[i].Position = position
+		}
+
+		if len(folders) > query.Limit {
+			folders = folders[:query.Limit]
+			last := folders[len(folders)-1]
+			next.ID = last.ID
+			next.Value = childOrderValue(childOrderColumn(query.OrderBy), last.Name, last.Size, last.UpdatedAt)
+			return folders, nil, next, nil
+		}
+	}
+
+	if query.Type == ChildTypeDir {
+		return folders, nil, nil, nil
+	}
+
+	// 子目录未占满本页时，继续列出文件，多取一项用于判断是否还有下一页
+	limit := query.Limit - len(folders)
+	tx, err := query.scope(DB.Where("folder_id = ? and upload_session_id is null", folder.ID), true)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if err := tx.Limit(limit + 1).Find(&files).Error; err != nil {
+		return nil, nil, nil, err
+	}
+
+	for i := 0; i < len(files); i++ {
+		files[i].Position = position
+	}
+
+	if len(files) <= limit {
+		return folders, files, nil, nil
+	}
+
+	files = files[:limit]
+	next.IsFile = true
+	if limit > 0 {
+		last := files[len(files)-1]
+		next.ID = last.ID
+		next.Value = childOrderValue(childOrderColumn(query.OrderBy), last.Name, last.Size, last.UpdatedAt)
+	}
+
+	return folders, files, next, nil
+}
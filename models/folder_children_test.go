@@ -0,0 +1,145 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChildrenCursor(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 编解码
+	{
+		cursor := &ChildrenCursor{OrderBy: ChildOrderName, Desc: true, IsFile: true, ID: 5, Value: "a.b.txt"}
+		res, err := ParseChildrenCursor(cursor.String())
+		asserts.NoError(err)
+		asserts.Equal(cursor, res)
+	}
+
+	// 文件部分的起始位置
+	{
+		cursor := &ChildrenCursor{OrderBy: ChildOrderSize, IsFile: true}
+		res, err := ParseChildrenCursor(cursor.String())
+		asserts.NoError(err)
+		asserts.Equal(cursor, res)
+	}
+
+	// 无法解析
+	{
+		_, err := ParseChildrenCursor("???")
+		asserts.Equal(ErrInvalidChildrenCursor, err)
+		_, err = ParseChildrenCursor((&ChildrenCursor{OrderBy: ChildOrderSize, IsFile: true, ID: 1, Value: "name"}).String())
+		asserts.Equal(ErrInvalidChildrenCursor, err)
+		_, err = ParseChildrenCursor((&ChildrenCursor{OrderBy: ChildOrderDate, ID: 1, Value: "1"}).String())
+		asserts.Equal(ErrInvalidChildrenCursor, err)
+	}
+}
+
+func TestFolder_ListChildren(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{Model: gorm.Model{ID: 1}, Name: "/"}
+
+	// 子目录占满本页
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)ORDER BY name asc, id asc LIMIT 3").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a").AddRow(3, "b").AddRow(4, "c"))
+		folders, files, next, err := folder.ListChildren(&ChildrenQuery{OrderBy: ChildOrderName, Limit: 2})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 2)
+		asserts.Equal("/", folders[0].Position)
+		asserts.Empty(files)
+		asserts.Equal(&ChildrenCursor{OrderBy: ChildOrderName, ID: 3, Value: "b"}, next)
+	}
+
+	// 子目录按累计大小排序
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)ORDER BY size desc, id desc LIMIT 2").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "size"}).AddRow(2, "a", 30).AddRow(3, "b", 20))
+		folders, _, next, err := folder.ListChildren(&ChildrenQuery{OrderBy: ChildOrderSize, Desc: true, Limit: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.Equal(&ChildrenCursor{OrderBy: ChildOrderSize, Desc: true, ID: 2, Value: "30"}, next)
+	}
+
+	// 从游标之后继续，子目录未占满本页时列出文件
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)name > (.+)id > (.+)ORDER BY name asc, id asc LIMIT 3").
+			WithArgs(1, "b", "b", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "c"))
+		mock.ExpectQuery("SELECT(.+)files(.+)upload_session_id is null(.+)ORDER BY name asc, id asc LIMIT 2").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "1.txt").AddRow(6, "2.txt"))
+		folders, files, next, err := folder.ListChildren(&ChildrenQuery{
+			OrderBy: ChildOrderName,
+			Limit:   2,
+			Cursor:  &ChildrenCursor{OrderBy: ChildOrderName, ID: 3, Value: "b"},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.Len(files, 1)
+		asserts.Equal(&ChildrenCursor{OrderBy: ChildOrderName, IsFile: true, ID: 5, Value: "1.txt"}, next)
+	}
+
+	// 子目录恰好占满本页，下一页从文件开始
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a"))
+		mock.ExpectQuery("SELECT(.+)files(.+)LIMIT 1").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "1.txt"))
+		folders, files, next, err := folder.ListChildren(&ChildrenQuery{OrderBy: ChildOrderSize, Limit: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.Empty(files)
+		asserts.Equal(&ChildrenCursor{OrderBy: ChildOrderSize, IsFile: true}, next)
+	}
+
+	// 按大小倒序列出文件的最后一页，附带筛选条件
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)name like (.+)size < (.+)id < (.+)ORDER BY size desc, id desc LIMIT 3").
+			WithArgs(1, "%txt%", 100, 100, 5).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "size"}).AddRow(7, "3.txt", 10))
+		folders, files, next, err := folder.ListChildren(&ChildrenQuery{
+			OrderBy: ChildOrderSize,
+			Desc:    true,
+			Keyword: "txt",
+			Limit:   2,
+			Cursor:  &ChildrenCursor{OrderBy: ChildOrderSize, Desc: true, IsFile: true, ID: 5, Value: "100"},
+		})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Empty(folders)
+		asserts.Len(files, 1)
+		asserts.Nil(next)
+	}
+
+	// 仅列出子目录，按修改日期排序
+	{
+		date := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+		mock.ExpectQuery("SELECT(.+)folders(.+)ORDER BY updated_at asc, id asc LIMIT 2").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "updated_at"}).AddRow(2, "a", date).AddRow(3, "b", date))
+		folders, files, next, err := folder.ListChildren(&ChildrenQuery{OrderBy: ChildOrderDate, Type: ChildTypeDir, Limit: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.Empty(files)
+		asserts.Equal(date.Format(time.RFC3339Nano), next.Value)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(gorm.ErrInvalidSQL)
+		_, _, _, err := folder.ListChildren(&ChildrenQuery{OrderBy: ChildOrderName, Type: ChildTypeFile, Limit: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"strconv"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// folderListVersionPrefix 目录列表缓存版本号的键前缀
+const folderListVersionPrefix = "folder_list_ver_"
+
+// FolderListVersion 获取目录列表缓存的版本号，不存在时生成新的版本号。
+// 列表缓存以版本号区分，目录内容变更后版本号被清除，旧版本的缓存随之失效
+func FolderListVersion(id uint, ttl int) string {
+	key := folderListVersionPrefix + strconv.FormatUint(uint64(id), 10)
+	if version, ok := cache.Get(key); ok {
+		if res, ok := version.(string); ok {
+			return res
+		}
+	}
+
+	version := util.RandStringRunes(8)
+	if err := cache.Set(key, version, ttl); err != nil {
+		util.Log().Warning("无法保存目录列表缓存版本号, %s", err)
+	}
+
+	return version
+}
+
+// InvalidateFolderList 使给定目录的列表缓存失效
+func InvalidateFolderList(ids ...uint) {
+	if len(ids) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		keys = append(keys, strconv.FormatUint(uint64(id), 10))
+	}
+
+	if err := cache.Deletes(keys, folderListVersionPrefix); err != nil {
+		util.Log().Warning("无法清除目录列表缓存, %s", err)
+	}
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderListVersion(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Deletes([]string{"1", "2"}, folderListVersionPrefix)
+
+	// 首次获取时生成
+	version := FolderListVersion(1, 0)
+	asserts.NotEmpty(version)
+	asserts.Equal(version, FolderListVersion(1, 0))
+	asserts.NotEqual(version, FolderListVersion(2, 0))
+
+	// 失效后重新生成
+	InvalidateFolderList()
+	asserts.Equal(version, FolderListVersion(1, 0))
+	InvalidateFolderList(1, 2)
+	asserts.NotEqual(version, FolderListVersion(1, 0))
+}
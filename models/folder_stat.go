@@ -0,0 +1,280 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// folderStat 目录统计的增量，包括累计大小、文件数及子目录数
+type folderStat struct {
+	Size    int64
+	Files   int64
+	Folders int64
+}
+
+// add 合并增量
+func (stat folderStat) add(other folderStat) folderStat {
+	return folderStat{
+		Size:    stat.Size + other.Size,
+		Files:   stat.Files + other.Files,
+		Folders: stat.Folders + other.Folders,
+	}
+}
+
+// neg 取反向增量
+func (stat folderStat) neg() folderStat {
+	return folderStat{Size: -stat.Size, Files: -stat.Files, Folders: -stat.Folders}
+}
+
+// subtree 以目录自身为根的子树统计，用于整体移入或移出上级目录
+func (folder *Folder) subtree() folderStat {
+	return folderStat{
+		Size:    int64(folder.Size),
+		Files:   int64(folder.FileNum),
+		Folders: int64(folder.FolderNum) + 1,
+	}
+}
+
+// statExpr 构建统计字段的更新表达式，扣减时不低于 0
+func statExpr(column string, delta int64) interface{} {
+	if delta >= 0 {
+		return gorm.Expr(column+" + ?", delta)
+	}
+
+	return gorm.Expr(fmt.Sprintf("CASE WHEN %s > ? THEN %s - ? ELSE 0 END", column, column), -delta, -delta)
+}
+
+// folderParents 逐层查询给定目录及其所有上级目录的父目录，返回目录 ID 至父目录 ID 的映射，
+// 根目录的父目录 ID 为 0，不存在的目录不在结果中
+func folderParents(tx *gorm.DB, ids []uint) (map[uint]uint, error) {
+	parents := make(map[uint]uint)
+	pending := ids
+
+	// 最大递归65535次
+	for i := 0; i < 65535 && len(pending) > 0; i++ {
+		var folders []Folder
+		if err := tx.Select("id, parent_id").Where("id in (?)", pending).Find(&folders).Error; err != nil {
+			return nil, err
+		}
+
+		for _, folder := range folders {
+			parents[folder.ID] = 0
+			if folder.ParentID != nil {
+				parents[folder.ID] = *folder.ParentID
+			}
+		}
+
+		// 下一层待查询的父目录
+		next := make(map[uint]bool)
+		for _, folder := range folders {
+			if folder.ParentID != nil {
+				if _, ok := parents[*folder.ParentID]; !ok {
+					next[*folder.ParentID] = true
+				}
+			}
+		}
+
+		pending = make([]uint, 0, len(next))
+		for id := range next {
+			pending = append(pending, id)
+		}
+		sort.Slice(pending, func(i, j int) bool { return pending[i] < pending[j] })
+	}
+
+	return parents, nil
+}
+
+// updateFolderStats 将增量累加至给定目录及其所有上级目录，增量相同的目录合并为一条更新语句。
+// 返回统计发生变化的目录，调用方应在事务提交后使这些目录的列表缓存失效
+func updateFolderStats(tx *gorm.DB, deltas map[uint]folderStat) ([]uint, error) {
+	ids := make([]uint, 0, len(deltas))
+	for id, delta := range deltas {
+		if id > 0 && delta != (folderStat{}) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	parents, err := folderParents(tx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// 汇总每个目录的增量，共同的上级目录中相反的增量相互抵消
+	acc := make(map[uint]folderStat)
+	for _, id := range ids {
+		for cur, i := id, 0; cur > 0 && i < 65535; i++ {
+			parent, ok := parents[cur]
+			if !ok {
+				break
+			}
+
+			acc[cur] = acc[cur].add(deltas[id])
+			cur = parent
+		}
+	}
+
+	touched := make([]uint, 0, len(acc))
+	for id, delta := range acc {
+		if delta != (folderStat{}) {
+			touched = append(touched, id)
+		}
+	}
+
+	// 按 ID 顺序更新，减少并发事务间的死锁
+	sort.Slice(touched, func(i, j int) bool { return touched[i] < touched[j] })
+	groups := make(map[folderStat][]uint)
+	for _, id := range touched {
+		groups[acc[id]] = append(groups[acc[id]], id)
+	}
+
+	updated := make(map[folderStat]bool, len(groups))
+	for _, id := range touched {
+		delta := acc[id]
+		if updated[delta] {
+			continue
+		}
+		updated[delta] = true
+
+		columns := make(map[string]interface{}, 3)
+		if delta.Size != 0 {
+			columns["size"] = statExpr("size", delta.Size)
+		}
+		if delta.Files != 0 {
+			columns["file_num"] = statExpr("file_num", delta.Files)
+		}
+		if delta.Folders != 0 {
+			columns["folder_num"] = statExpr("folder_num", delta.Folders)
+		}
+
+		if err := tx.Model(&Folder{}).Where("id in (?)", groups[delta]).UpdateColumns(columns).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return touched, nil
+}
+
+// ReleaseFolderStats 从被删除目录的上级目录中扣除这些目录。folders 为一次删除的全部目录，
+// 其中的文件已在删除文件记录时扣除
+func ReleaseFolderStats(folders []Folder) {
+	deleted := make(map[uint]bool, len(folders))
+	for _, folder := range folders {
+		deleted[folder.ID] = true
+	}
+
+	// 仅处理顶层目录，其余目录随之一并扣除
+	deltas := make(map[uint]folderStat)
+	for i := range folders {
+		if parent := folders[i].ParentID; parent != nil && !deleted[*parent] {
+			deltas[*parent] = deltas[*parent].add(folderStat{Folders: -folders[i].subtree().Folders})
+		}
+	}
+
+	touched, err := updateFolderStats(DB, deltas)
+	if err != nil {
+		util.Log().Warning("无法更新目录统计, %s", err)
+	}
+	InvalidateFolderList(touched...)
+}
+
+// RepairFolderStats 按文件及目录记录重新统计用户所有目录的累计大小及子项数量，
+// 修正与记录不一致的目录，返回修正的目录数
+func RepairFolderStats(uid uint) (int, error) {
+	var folders []Folder
+	if err := DB.Where("owner_id = ?", uid).Find(&folders).Error; err != nil {
+		return 0, err
+	}
+
+	// 各目录下直属文件的大小及数量
+	var direct []struct {
+		FolderID uint
+		Size     uint64
+		Num      uint64
+	}
+	if err := DB.Model(&File{}).Select("folder_id, coalesce(sum(size), 0) as size, count(*) as num").
+		Where("user_id = ?", uid).Group("folder_id").Scan(&direct).Error; err != nil {
+		return 0, err
+	}
+
+	parents := make(map[uint]uint, len(folders))
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			parents[folder.ID] = *folder.ParentID
+		} else {
+			parents[folder.ID] = 0
+		}
+	}
+
+	// 将直属文件及每个目录自身累加至其所有上级目录
+	expected := make(map[uint]folderStat, len(folders))
+	propagate := func(id uint, delta folderStat) {
+		for cur, i := id, 0; cur > 0 && i < 65535; i++ {
+			parent, ok := parents[cur]
+			if !ok {
+				return
+			}
+
+			expected[cur] = expected[cur].add(delta)
+			cur = parent
+		}
+	}
+	for _, row := range direct {
+		propagate(row.FolderID, folderStat{Size: int64(row.Size), Files: int64(row.Num)})
+	}
+	for _, folder := range folders {
+		if folder.ParentID != nil {
+			propagate(*folder.ParentID, folderStat{Folders: 1})
+		}
+	}
+
+	// 修正的目录及其父目录的列表均已变更
+	repaired := make([]uint, 0)
+	for _, folder := range folders {
+		stat := expected[folder.ID]
+		if stat == (folderStat{Size: int64(folder.Size), Files: int64(folder.FileNum), Folders: int64(folder.FolderNum)}) {
+			continue
+		}
+
+		if err := DB.Model(&Folder{}).Where("id = ?", folder.ID).UpdateColumns(map[string]interface{}{
+			"size":       stat.Size,
+			"file_num":   stat.Files,
+			"folder_num": stat.Folders,
+		}).Error; err != nil {
+			return len(repaired), err
+		}
+		repaired = append(repaired, folder.ID)
+	}
+
+	stale := make([]uint, 0, len(repaired)*2)
+	for _, id := range repaired {
+		stale = append(stale, id, parents[id])
+	}
+	InvalidateFolderList(stale...)
+	return len(repaired), nil
+}
+
+// RepairAllFolderStats 重新统计所有用户的目录，返回修正的目录数
+func RepairAllFolderStats() (int, error) {
+	var uids []uint
+	if err := DB.Model(&User{}).Pluck("id", &uids).Error; err != nil {
+		return 0, err
+	}
+
+	total := 0
+	for _, uid := range uids {
+		repaired, err := RepairFolderStats(uid)
+		total += repaired
+		if err != nil {
+			return total, err
+		}
+	}
+
+	return total, nil
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateFolderStats(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无增量
+	{
+		touched, err := updateFolderStats(DB, map[uint]folderStat{0: {Files: 1}, 1: {}})
+		asserts.NoError(err)
+		asserts.Empty(touched)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 累加至所有上级目录
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(3, 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)file_num(.+)size").
+			WithArgs(1, 10, 1, 2, 3).
+			WillReturnResult(sqlmock.NewResult(1, 3))
+		mock.ExpectCommit()
+		tx := DB.Begin()
+		touched, err := updateFolderStats(tx, map[uint]folderStat{3: {Size: 10, Files: 1}})
+		tx.Commit()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal([]uint{1, 2, 3}, touched)
+	}
+
+	// 共同上级目录中的增量相互抵消
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1).AddRow(3, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)CASE WHEN(.+)").
+			WithArgs(1, 1, 10, 10, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(1, 10, 3).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		tx := DB.Begin()
+		touched, err := updateFolderStats(tx, map[uint]folderStat{
+			2: {Size: -10, Files: -1},
+			3: {Size: 10, Files: 1},
+		})
+		tx.Commit()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal([]uint{2, 3}, touched)
+	}
+
+	// 查询上级目录失败
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		_, err := updateFolderStats(DB, map[uint]folderStat{3: {Folders: 1}})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestReleaseFolderStats(t *testing.T) {
+	asserts := assert.New(t)
+	parent := uint(1)
+	child := uint(2)
+
+	// 仅从顶层目录的上级目录中扣除
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)folder_num(.+)CASE WHEN(.+)").
+		WithArgs(2, 2, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	ReleaseFolderStats([]Folder{
+		{Model: gorm.Model{ID: 2}, ParentID: &parent, FolderNum: 1},
+		{Model: gorm.Model{ID: 3}, ParentID: &child},
+	})
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestRepairFolderStats(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 修正统计不一致的目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size", "file_num", "folder_num"}).
+				AddRow(1, nil, 10, 1, 1).
+				AddRow(2, 1, 0, 0, 0))
+		mock.ExpectQuery("SELECT(.+)files(.+)GROUP BY folder_id").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"folder_id", "size", "num"}).AddRow(2, 10, 1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(1, 0, 10, 2).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		repaired, err := RepairFolderStats(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(1, repaired)
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		_, err := RepairFolderStats(1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
@@ -160,7 +160,7 @@ func TestDeleteFolderByIDs(t *testing.T) {
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
-		err := DeleteFolderByIDs([]uint{1, 2, 3})
+		err := DeleteFolderByIDs([]uint{1, 2, 3}, 1)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 	}
@@ -170,7 +170,7 @@ func TestDeleteFolderByIDs(t *testing.T) {
 		mock.ExpectExec("DELETE(.+)").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
-		err := DeleteFolderByIDs([]uint{1, 2, 3})
+		err := DeleteFolderByIDs([]uint{1, 2, 3}, 1)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
 	}
@@ -237,6 +237,15 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
+		// 更新目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)file_num(.+)size").
+			WithArgs(2, 30, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		storage, err := folder.MoveOrCopyFileTo(
 			[]uint{1, 2, 3},
 			&dstFolder,
@@ -286,6 +295,15 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
+		// 已复制的文件计入目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(1, 10, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		storage, err := folder.MoveOrCopyFileTo(
 			[]uint{1, 2},
 			&dstFolder,
@@ -299,9 +317,21 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 	// 移动文件 成功
 	{
 		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)sum(.+)files(.+)").
+			WithArgs(1, 2, 1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"size", "num"}).AddRow(30, 2))
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 1).
 			WillReturnResult(sqlmock.NewResult(1, 2))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)CASE WHEN(.+)").
+			WithArgs(2, 2, 30, 30, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(2, 30, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		storage, err := folder.MoveOrCopyFileTo(
 			[]uint{1, 2},
@@ -316,6 +346,8 @@ func TestFolder_MoveOrCopyFileTo(t *testing.T) {
 	// 移动文件 出错
 	{
 		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)sum(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"size", "num"}).AddRow(30, 2))
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 1).
 			WillReturnError(errors.New("error"))
@@ -386,6 +418,16 @@ func TestFolder_CopyFolderTo(t *testing.T) {
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(6, 1))
 		mock.ExpectCommit()
 
+		// 更新目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(2, 3, 30, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
 		size, err := parFolder.CopyFolderTo(2, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
@@ -416,6 +458,16 @@ func TestFolder_CopyFolderTo(t *testing.T) {
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(5, 1))
 		mock.ExpectCommit()
 
+		// 已复制的部分计入目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(1, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
 		size, err := parFolder.CopyFolderTo(2, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
@@ -446,6 +498,16 @@ func TestFolder_CopyFolderTo(t *testing.T) {
 			WithArgs(1, 2, 3, 4).
 			WillReturnError(errors.New("error"))
 
+		// 已复制的部分计入目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(3, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
 		size, err := parFolder.CopyFolderTo(2, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
@@ -488,6 +550,16 @@ func TestFolder_CopyFolderTo(t *testing.T) {
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
 
+		// 已复制的部分计入目标目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(10, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(1, 3, 10, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
 		size, err := parFolder.CopyFolderTo(2, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
@@ -513,15 +585,40 @@ func TestFolder_MoveOrCopyFolderTo_Move(t *testing.T) {
 	// 成功
 	{
 		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)sum(.+)folders(.+)").
+			WithArgs(1, 2, 1, 9).
+			WillReturnRows(sqlmock.NewRows([]string{"size", "file_num", "folder_num", "num"}).AddRow(30, 2, 1, 2))
 		mock.ExpectExec("UPDATE(.+)").
 			WithArgs(10, sqlmock.AnyArg(), 1, 2, 1, 9).
 			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(9, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(9, nil).AddRow(10, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)CASE WHEN(.+)").
+			WithArgs(2, 2, 3, 3, 30, 30, 9).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)folders(.+)").
+			WithArgs(2, 3, 30, 10).
+			WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		err := parFolder.MoveFolderTo([]uint{1, 2}, &dstFolder)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NoError(err)
 	}
 
+	// 更新统计失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT(.+)sum(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"size", "file_num", "folder_num", "num"}).AddRow(30, 2, 1, 2))
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		err := parFolder.MoveFolderTo([]uint{1, 2}, &dstFolder)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
 	// 移动自己到自己内部，失败
 	{
 		err := parFolder.MoveFolderTo([]uint{10, 2}, &dstFolder)
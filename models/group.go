@@ -2,6 +2,9 @@ package model
 
 import (
 	"encoding/json"
+	"net"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
 )
 
@@ -33,6 +36,23 @@ type GroupOption struct {
 	Aria2Options    map[string]interface{} `json:"aria2_options,omitempty"` // 离线下载用户组配置
 	SourceBatchSize int                    `json:"source_batch,omitempty"`
 	Aria2BatchSize  int                    `json:"aria2_batch,omitempty"`
+	MaxParallelTask int                    `json:"max_parallel_task,omitempty"` // 单用户同时执行的后台任务数，0 为不限制
+	ShareSlug       bool                   `json:"share_slug,omitempty"`        // 自定义分享链接
+	ShareTraffic    uint64                 `json:"share_traffic,omitempty"`     // 单个分享的下载流量上限，0 为不限制
+	Require2FA      bool                   `json:"require_2fa,omitempty"`       // 强制成员开启二步验证后才能使用
+	AllowedIPs      string                 `json:"allowed_ips,omitempty"`       // 成员可登录的 CIDR 网段，以逗号分隔，为空表示不限制
+	DeniedIPs       string                 `json:"denied_ips,omitempty"`        // 禁止成员登录的 CIDR 网段，以逗号分隔
+	Invite          bool                   `json:"invite,omitempty"`            // 允许成员创建注册邀请码
+	InviteLimit     int                    `json:"invite_limit,omitempty"`      // 成员同时持有的可用邀请码数量，0 为不限制
+	MaxFileSize     uint64                 `json:"max_file_size,omitempty"`     // 单文件大小上限，与存储策略的限制同时生效，0 为不限制
+	DeniedExts      string                 `json:"denied_exts,omitempty"`       // 禁止上传的扩展名，以逗号分隔
+	DailyUpload     uint64                 `json:"daily_upload,omitempty"`      // 每日上传流量上限，0 为不限制
+	SubAccounts     int                    `json:"sub_accounts,omitempty"`      // 成员可创建的子账户数量，0 为不允许创建
+	S3Gateway       bool                   `json:"s3_gateway,omitempty"`        // 允许通过 S3 兼容接口访问文件
+	FTP             bool                   `json:"ftp,omitempty"`               // 允许通过 FTP 访问文件
+	SFTP            bool                   `json:"sftp,omitempty"`              // 允许通过 SFTP 访问文件
+	DLNA            bool                   `json:"dlna,omitempty"`              // 允许在局域网中以 DLNA 发布媒体目录
+	RateLimits      map[string]int         `json:"rate_limits,omitempty"`       // 各类接口每分钟的请求数上限，未设置时使用站点默认值，-1 为不限制
 }
 
 // GetGroupByID 用ID获取用户组
@@ -79,3 +99,31 @@ func (group *Group) SerializePolicyList() (err error) {
 	group.Options = string(optionsValue)
 	return err
 }
+
+// AllowIP 返回用户组成员是否可从给定IP访问，限制格式无效时拒绝访问
+func (group *Group) AllowIP(addr string) bool {
+	if group.OptionsSerialized.AllowedIPs == "" && group.OptionsSerialized.DeniedIPs == "" {
+		return true
+	}
+
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	denied, err := util.ParseIPRanges(group.OptionsSerialized.DeniedIPs)
+	if err != nil {
+		util.Log().Warning("用户组 [%s] 的IP黑名单格式无效, %s", group.Name, err)
+		return false
+	}
+	if util.IPInRanges(ip, denied) {
+		return false
+	}
+
+	allowed, err := util.ParseIPRanges(group.OptionsSerialized.AllowedIPs)
+	if err != nil {
+		util.Log().Warning("用户组 [%s] 的IP白名单格式无效, %s", group.Name, err)
+		return false
+	}
+	return len(allowed) == 0 || util.IPInRanges(ip, allowed)
+}
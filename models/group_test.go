@@ -75,3 +75,32 @@ func TestGroup_BeforeSave(t *testing.T) {
 	}
 
 }
+
+func TestGroup_AllowIP(t *testing.T) {
+	asserts := assert.New(t)
+	group := Group{}
+
+	// 未设置限制
+	asserts.True(group.AllowIP("1.2.3.4"))
+
+	// 白名单
+	group.OptionsSerialized.AllowedIPs = "10.0.0.0/8, 192.168.1.1"
+	asserts.True(group.AllowIP("10.1.2.3"))
+	asserts.True(group.AllowIP("192.168.1.1"))
+	asserts.False(group.AllowIP("192.168.1.2"))
+	asserts.False(group.AllowIP("invalid"))
+
+	// 黑名单优先
+	group.OptionsSerialized.DeniedIPs = "10.0.0.0/24"
+	asserts.False(group.AllowIP("10.0.0.1"))
+	asserts.True(group.AllowIP("10.0.1.1"))
+
+	// 仅黑名单
+	group.OptionsSerialized.AllowedIPs = ""
+	asserts.True(group.AllowIP("1.2.3.4"))
+	asserts.False(group.AllowIP("10.0.0.1"))
+
+	// 格式无效
+	group.OptionsSerialized.DeniedIPs = "10.0.0.0/33"
+	asserts.False(group.AllowIP("1.2.3.4"))
+}
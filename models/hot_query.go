@@ -0,0 +1,259 @@
+package model
+
+import (
+	"database/sql"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/jinzhu/gorm"
+)
+
+// maxPathJoins 解析路径时单条查询最多连接的目录层级数
+const maxPathJoins = 8
+
+// 热点查询读取的字段，通过迁移新增的字段在旧记录中可能为 NULL
+var (
+	hotFolderColumns = []string{"id", "created_at", "updated_at", "name", "parent_id", "owner_id",
+		"COALESCE(%s.size, 0)", "COALESCE(%s.file_num, 0)", "COALESCE(%s.folder_num, 0)"}
+	hotFileColumns = []string{"id", "created_at", "updated_at", "name", "COALESCE(%s.source_name, '')", "user_id",
+		"size", "COALESCE(%s.pic_info, '')", "folder_id", "policy_id", "upload_session_id", "COALESCE(%s.metadata, '')"}
+)
+
+// stmtKey 预处理语句缓存的键，数据库连接重建后重新准备
+type stmtKey struct {
+	db    gorm.SQLCommon
+	query string
+}
+
+// stmtCache 热点查询的预处理语句缓存
+var stmtCache sync.Map
+
+// selectColumns 生成带表别名的查询字段
+func selectColumns(alias string, columns []string) string {
+	res := make([]string, len(columns))
+	for i, column := range columns {
+		if strings.Contains(column, "%s") {
+			res[i] = strings.ReplaceAll(column, "%s", alias)
+		} else {
+			res[i] = alias + "." + column
+		}
+	}
+	return strings.Join(res, ", ")
+}
+
+// rebind 将查询中的 ? 占位符替换为当前数据库方言的占位符。gorm 的 BindVar 在
+// PostgreSQL 以外的方言中返回内部占位标记，手写 SQL 不经过 gorm 替换，因此仅 PostgreSQL 需要改写
+func rebind(dialect gorm.Dialect, query string) string {
+	if dialect.GetName() != "postgres" {
+		return query
+	}
+
+	var (
+		builder strings.Builder
+		i       int
+	)
+	for _, c := range query {
+		if c == '?' {
+			i++
+			builder.WriteString(dialect.BindVar(i))
+			continue
+		}
+		builder.WriteRune(c)
+	}
+	return builder.String()
+}
+
+// preparedStmt 获取已缓存的预处理语句，不存在时准备新的语句
+func preparedStmt(db gorm.SQLCommon, query string) (*sql.Stmt, error) {
+	key := stmtKey{db: db, query: query}
+	if stmt, ok := stmtCache.Load(key); ok {
+		return stmt.(*sql.Stmt), nil
+	}
+
+	stmt, err := db.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+
+	// 并发准备了相同的语句时保留先存入的一个
+	if actual, loaded := stmtCache.LoadOrStore(key, stmt); loaded {
+		stmt.Close()
+		return actual.(*sql.Stmt), nil
+	}
+	return stmt, nil
+}
+
+// hotQuery 执行手写 SQL 查询，query 使用 ? 作为占位符，开启 PrepareStmt 时复用预处理语句
+func hotQuery(query string, args ...interface{}) (*sql.Rows, error) {
+	query = rebind(DB.Dialect(), query)
+	db := DB.CommonDB()
+	if !conf.DatabaseConfig.PrepareStmt {
+		return db.Query(query, args...)
+	}
+
+	stmt, err := preparedStmt(db, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.Query(args...)
+}
+
+// scanFolder 按 hotFolderColumns 的顺序扫描目录
+func scanFolder(rows *sql.Rows) (Folder, error) {
+	var (
+		folder   Folder
+		parentID sql.NullInt64
+	)
+	err := rows.Scan(&folder.ID, &folder.CreatedAt, &folder.UpdatedAt, &folder.Name, &parentID, &folder.OwnerID,
+		&folder.Size, &folder.FileNum, &folder.FolderNum)
+	if parentID.Valid {
+		id := uint(parentID.Int64)
+		folder.ParentID = &id
+	}
+	return folder, err
+}
+
+// scanFile 按 hotFileColumns 的顺序扫描文件
+func scanFile(rows *sql.Rows) (File, error) {
+	var (
+		file      File
+		sessionID sql.NullString
+	)
+	err := rows.Scan(&file.ID, &file.CreatedAt, &file.UpdatedAt, &file.Name, &file.SourceName, &file.UserID,
+		&file.Size, &file.PicInfo, &file.FolderID, &file.PolicyID, &sessionID, &file.Metadata)
+	if sessionID.Valid {
+		file.UploadSessionID = &sessionID.String
+	}
+	return file, err
+}
+
+// GetChildren 获取目录下的全部子目录及文件。目录列表在每次浏览、访问分享时都会执行，
+// 开启 FastQuery 时使用手写 SQL 只查询所需字段并直接扫描，绕过 ORM 的反射开销
+func (folder *Folder) GetChildren() ([]Folder, []File, error) {
+	if !conf.DatabaseConfig.FastQuery {
+		folders, err := folder.GetChildFolder()
+		if err != nil {
+			return nil, nil, err
+		}
+		files, err := folder.GetChildFiles()
+		return folders, files, err
+	}
+
+	position := path.Join(folder.Position, folder.Name)
+	folders := make([]Folder, 0)
+	rows, err := hotQuery(fmt.Sprintf(
+		"SELECT %s FROM %s f WHERE f.parent_id = ? AND f.deleted_at IS NULL",
+		selectColumns("f", hotFolderColumns), DB.NewScope(&Folder{}).QuotedTableName(),
+	), folder.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		child, err := scanFolder(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		child.Position = position
+		folders = append(folders, child)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	files := make([]File, 0)
+	fileRows, err := hotQuery(fmt.Sprintf(
+		"SELECT %s FROM %s f WHERE f.folder_id = ? AND f.deleted_at IS NULL",
+		selectColumns("f", hotFileColumns), DB.NewScope(&File{}).QuotedTableName(),
+	), folder.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fileRows.Close()
+
+	for fileRows.Next() {
+		child, err := scanFile(fileRows)
+		if err != nil {
+			return nil, nil, err
+		}
+		child.Position = position
+		files = append(files, child)
+	}
+
+	return folders, files, fileRows.Err()
+}
+
+// GetChildByPath 从当前目录起逐级查找名为 names 的子目录，返回最后一级目录。
+// 开启 FastQuery 时将多个层级连接为一条查询，否则逐级调用 GetChild
+func (folder *Folder) GetChildByPath(names []string) (*Folder, error) {
+	current := folder
+	for len(names) > 0 {
+		var err error
+		if !conf.DatabaseConfig.FastQuery {
+			current, err = current.GetChild(names[0])
+			names = names[1:]
+		} else {
+			n := len(names)
+			if n > maxPathJoins {
+				n = maxPathJoins
+			}
+			current, err = current.resolvePath(names[:n])
+			names = names[n:]
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return current, nil
+}
+
+// resolvePath 使用一条连接查询解析至多 maxPathJoins 级子目录
+func (folder *Folder) resolvePath(names []string) (*Folder, error) {
+	table := DB.NewScope(&Folder{}).QuotedTableName()
+	last := fmt.Sprintf("f%d", len(names))
+
+	var query strings.Builder
+	fmt.Fprintf(&query, "SELECT %s FROM %s f0", selectColumns(last, hotFolderColumns), table)
+	args := make([]interface{}, 0, len(names)+1)
+	for i, name := range names {
+		fmt.Fprintf(&query, " INNER JOIN %s f%d ON f%d.parent_id = f%d.id AND f%d.owner_id = f%d.owner_id AND f%d.name = ? AND f%d.deleted_at IS NULL",
+			table, i+1, i+1, i, i+1, i, i+1, i+1)
+		args = append(args, name)
+	}
+	query.WriteString(" WHERE f0.id = ?")
+	args = append(args, folder.ID)
+
+	rows, err := hotQuery(query.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	res, err := scanFolder(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与逐级 GetChild 相同，路径为上一级目录的完整路径
+	res.Position = path.Join(append([]string{folder.Position, folder.Name}, names[:len(names)-1]...)...)
+	return &res, nil
+}
+
+// addHotQueryIndexes 为热点查询添加复合索引，列取子项时可直接按名称顺序读取索引
+func addHotQueryIndexes() {
+	DB.Model(&Folder{}).AddIndex("idx_folder_children", "parent_id", "name")
+	DB.Model(&File{}).AddIndex("idx_file_children", "folder_id", "name")
+}
@@ -0,0 +1,155 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+// useFastQuery 在测试中开启手写 SQL 查询
+func useFastQuery(t *testing.T, prepare bool) {
+	old := *conf.DatabaseConfig
+	conf.DatabaseConfig.FastQuery = true
+	conf.DatabaseConfig.PrepareStmt = prepare
+	t.Cleanup(func() {
+		*conf.DatabaseConfig = old
+	})
+}
+
+var (
+	hotFolderRows = []string{"id", "created_at", "updated_at", "name", "parent_id", "owner_id", "size", "file_num", "folder_num"}
+	hotFileRows   = []string{"id", "created_at", "updated_at", "name", "source_name", "user_id", "size", "pic_info", "folder_id",
+		"policy_id", "upload_session_id", "metadata"}
+)
+
+func TestRebind(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("a = ? AND b = ?", rebind(DB.Dialect(), "a = ? AND b = ?"))
+
+	usePostgres(t)
+	asserts.Equal("a = $1 AND b = $2", rebind(DB.Dialect(), "a = ? AND b = ?"))
+}
+
+func TestFolder_GetChildren(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{Model: gorm.Model{ID: 1}, Name: "/"}
+	now := time.Now()
+
+	// 使用 ORM 查询
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "sub"))
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "1.txt"))
+		folders, files, err := folder.GetChildren()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.Len(files, 1)
+	}
+
+	useFastQuery(t, false)
+
+	// 手写 SQL
+	{
+		mock.ExpectQuery("SELECT f.id, (.+), COALESCE\\(f.size, 0\\)(.+) FROM `folders` f WHERE f.parent_id = \\? AND f.deleted_at IS NULL").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows).AddRow(2, now, now, "sub", 1, 1, 10, 1, 0))
+		mock.ExpectQuery("SELECT f.id, (.+) FROM `files` f WHERE f.folder_id = \\? AND f.deleted_at IS NULL").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFileRows).
+				AddRow(3, now, now, "1.txt", "1.txt", 1, 5, "", 1, 1, nil, "").
+				AddRow(4, now, now, "2.txt", "2.txt", 1, 0, "", 1, 1, "session", ""))
+		folders, files, err := folder.GetChildren()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(folders, 1)
+		asserts.EqualValues(1, *folders[0].ParentID)
+		asserts.EqualValues(10, folders[0].Size)
+		asserts.Equal("/", folders[0].Position)
+		asserts.Len(files, 2)
+		asserts.Nil(files[0].UploadSessionID)
+		asserts.Equal("session", *files[1].UploadSessionID)
+		asserts.Equal("/", files[1].Position)
+	}
+
+	// 预处理语句复用
+	{
+		conf.DatabaseConfig.PrepareStmt = true
+		mock.ExpectPrepare("SELECT(.+)`folders` f(.+)").
+			ExpectQuery().WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows))
+		mock.ExpectPrepare("SELECT(.+)`files` f(.+)").
+			ExpectQuery().WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFileRows))
+		_, _, err := folder.GetChildren()
+		asserts.NoError(err)
+
+		mock.ExpectQuery("SELECT(.+)`folders` f(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows))
+		mock.ExpectQuery("SELECT(.+)`files` f(.+)").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows(hotFileRows))
+		folders, files, err := folder.GetChildren()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Empty(folders)
+		asserts.Empty(files)
+	}
+}
+
+func TestFolder_GetChildByPath(t *testing.T) {
+	asserts := assert.New(t)
+	folder := &Folder{Model: gorm.Model{ID: 1}, Name: "/"}
+	now := time.Now()
+
+	// 逐级查询
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 0, "a").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 0, "b").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "b"))
+		res, err := folder.GetChildByPath([]string{"a", "b"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(3, res.ID)
+		asserts.Equal("/a", res.Position)
+	}
+
+	// 空路径
+	{
+		res, err := folder.GetChildByPath(nil)
+		asserts.NoError(err)
+		asserts.Equal(folder, res)
+	}
+
+	useFastQuery(t, false)
+
+	// 连接查询，超过单条查询的层级数时分多次查询
+	{
+		names := []string{"1", "2", "3", "4", "5", "6", "7", "8", "9"}
+		mock.ExpectQuery("SELECT f8.id, (.+) FROM `folders` f0 INNER JOIN `folders` f1 ON f1.parent_id = f0.id AND f1.owner_id = f0.owner_id AND f1.name = \\? (.+) WHERE f0.id = \\?").
+			WithArgs("1", "2", "3", "4", "5", "6", "7", "8", 1).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows).AddRow(9, now, now, "8", 8, 1, 0, 0, 0))
+		mock.ExpectQuery("SELECT f1.id, (.+) FROM `folders` f0 INNER JOIN `folders` f1 (.+) WHERE f0.id = \\?").
+			WithArgs("9", 9).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows).AddRow(10, now, now, "9", 9, 1, 0, 0, 0))
+		res, err := folder.GetChildByPath(names)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(10, res.ID)
+		asserts.Equal("/1/2/3/4/5/6/7/8", res.Position)
+	}
+
+	// 目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs("a", 1).
+			WillReturnRows(sqlmock.NewRows(hotFolderRows))
+		_, err := folder.GetChildByPath([]string{"a"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(gorm.ErrRecordNotFound, err)
+	}
+}
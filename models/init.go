@@ -2,9 +2,12 @@ package model
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
@@ -36,12 +39,7 @@ func Init() {
 			// 未指定数据库或者明确指定为 sqlite 时，使用 SQLite3 数据库
 			db, err = gorm.Open("sqlite3", util.RelativePath(conf.DatabaseConfig.DBFile))
 		case "postgres":
-			db, err = gorm.Open(conf.DatabaseConfig.Type, fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable",
-				conf.DatabaseConfig.Host,
-				conf.DatabaseConfig.User,
-				conf.DatabaseConfig.Password,
-				conf.DatabaseConfig.Name,
-				conf.DatabaseConfig.Port))
+			db, err = gorm.Open(conf.DatabaseConfig.Type, postgresDSN())
 		case "mysql", "mssql":
 			db, err = gorm.Open(conf.DatabaseConfig.Type, fmt.Sprintf("%s:%s@(%s:%d)/%s?charset=%s&parseTime=True&loc=Local",
 				conf.DatabaseConfig.User,
@@ -73,18 +71,63 @@ func Init() {
 	}
 
 	//设置连接池
-	db.DB().SetMaxIdleConns(50)
-	if conf.DatabaseConfig.Type == "sqlite" || conf.DatabaseConfig.Type == "sqlite3" || conf.DatabaseConfig.Type == "UNSET" {
-		db.DB().SetMaxOpenConns(1)
-	} else {
-		db.DB().SetMaxOpenConns(100)
-	}
+	configurePool(db)
 
-	//超时
-	db.DB().SetConnMaxLifetime(time.Second * 30)
+	// 启用链路追踪时记录数据库操作
+	if tracing.Enabled() {
+		tracing.RegisterCallbacks(db)
+	}
 
 	DB = db
 
 	//执行迁移
 	migration()
 }
+
+// configurePool 按配置设置连接池，未配置的项使用默认值，SQLite 只允许一个连接
+func configurePool(db *gorm.DB) {
+	maxOpen, maxIdle, lifetime := conf.DatabaseConfig.MaxOpenConns, conf.DatabaseConfig.MaxIdleConns, conf.DatabaseConfig.ConnMaxLifetime
+	if conf.DatabaseConfig.Type == "sqlite" || conf.DatabaseConfig.Type == "sqlite3" || conf.DatabaseConfig.Type == "UNSET" {
+		maxOpen = 1
+	} else if maxOpen == 0 {
+		maxOpen = 100
+	}
+	if maxIdle == 0 {
+		maxIdle = 50
+	}
+	if lifetime == 0 {
+		lifetime = 30
+	}
+
+	db.DB().SetMaxOpenConns(maxOpen)
+	db.DB().SetMaxIdleConns(maxIdle)
+
+	//超时
+	db.DB().SetConnMaxLifetime(time.Duration(lifetime) * time.Second)
+	db.DB().SetConnMaxIdleTime(time.Duration(conf.DatabaseConfig.ConnMaxIdleTime) * time.Second)
+}
+
+// postgresDSN 生成 PostgreSQL 连接字符串，参数值按 libpq 规则转义
+func postgresDSN() string {
+	sslMode := conf.DatabaseConfig.SSLMode
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+
+	params := [][2]string{
+		{"host", conf.DatabaseConfig.Host},
+		{"user", conf.DatabaseConfig.User},
+		{"password", conf.DatabaseConfig.Password},
+		{"dbname", conf.DatabaseConfig.Name},
+		{"port", strconv.Itoa(conf.DatabaseConfig.Port)},
+		{"sslmode", sslMode},
+	}
+
+	escaper := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+	dsn := make([]string, 0, len(params))
+	for _, param := range params {
+		dsn = append(dsn, fmt.Sprintf("%s='%s'", param[0], escaper.Replace(param[1])))
+	}
+
+	return strings.Join(dsn, " ")
+}
@@ -0,0 +1,29 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	asserts := assert.New(t)
+	old := *conf.DatabaseConfig
+	defer func() {
+		*conf.DatabaseConfig = old
+	}()
+
+	conf.DatabaseConfig.Host = "127.0.0.1"
+	conf.DatabaseConfig.User = "cloudreve"
+	conf.DatabaseConfig.Password = `p@ss word'\`
+	conf.DatabaseConfig.Name = "cloudreve"
+	conf.DatabaseConfig.Port = 5432
+
+	// 默认不使用 SSL
+	conf.DatabaseConfig.SSLMode = ""
+	asserts.Equal(`host='127.0.0.1' user='cloudreve' password='p@ss word\'\\' dbname='cloudreve' port='5432' sslmode='disable'`, postgresDSN())
+
+	conf.DatabaseConfig.SSLMode = "verify-full"
+	asserts.Contains(postgresDSN(), "sslmode='verify-full'")
+}
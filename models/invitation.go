@@ -0,0 +1,92 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// ErrInvitationUnavailable 邀请码不存在、已过期或已达到使用次数上限
+var ErrInvitationUnavailable = errors.New("invitation code is invalid or has expired")
+
+// Invitation 注册邀请码
+type Invitation struct {
+	gorm.Model
+	Code      string     `gorm:"unique_index"` // 邀请码
+	CreatorID uint       `gorm:"index"`        // 创建者ID
+	GroupID   uint       // 受邀用户加入的用户组，为 0 时使用默认用户组
+	MaxUses   int        // 可使用次数，为 0 时不限制
+	Uses      int        // 已使用次数
+	ExpiredAt *time.Time // 过期时间，为空时永不过期
+}
+
+// Create 生成邀请码并创建记录
+func (invitation *Invitation) Create() error {
+	invitation.Code = util.RandSecureString(16)
+	return DB.Create(invitation).Error
+}
+
+// Available 邀请码是否仍可使用
+func (invitation *Invitation) Available() bool {
+	if invitation.ExpiredAt != nil && time.Now().After(*invitation.ExpiredAt) {
+		return false
+	}
+	return invitation.MaxUses == 0 || invitation.Uses < invitation.MaxUses
+}
+
+// Use 占用一次邀请码使用次数，并发使用时不会超过上限
+func (invitation *Invitation) Use() error {
+	if !invitation.Available() {
+		return ErrInvitationUnavailable
+	}
+
+	res := DB.Model(invitation).Where("max_uses = 0 OR uses < max_uses").
+		UpdateColumn("uses", gorm.Expr("uses + ?", 1))
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrInvitationUnavailable
+	}
+
+	invitation.Uses++
+	return nil
+}
+
+// Release 归还一次使用次数，用于注册失败时
+func (invitation *Invitation) Release() {
+	if invitation.Uses > 0 {
+		invitation.Uses--
+	}
+	DB.Model(invitation).Where("uses > 0").UpdateColumn("uses", gorm.Expr("uses - ?", 1))
+}
+
+// GetInvitationByCode 根据邀请码查找记录
+func GetInvitationByCode(code string) (*Invitation, error) {
+	invitation := &Invitation{}
+	err := DB.Where("code = ?", code).First(invitation).Error
+	return invitation, err
+}
+
+// ListInvitations 列出用户创建的邀请码
+func ListInvitations(uid uint) []Invitation {
+	var invitations []Invitation
+	DB.Where("creator_id = ?", uid).Order("created_at desc").Find(&invitations)
+	return invitations
+}
+
+// CountAvailableInvitations 统计用户创建的仍可使用的邀请码数量
+func CountAvailableInvitations(uid uint) int {
+	total := 0
+	DB.Model(&Invitation{}).
+		Where("creator_id = ? and (max_uses = 0 or uses < max_uses) and (expired_at is NULL or expired_at > ?)", uid, time.Now()).
+		Count(&total)
+	return total
+}
+
+// DeleteInvitationByID 根据ID和创建者删除邀请码
+func DeleteInvitationByID(id, uid uint) {
+	DB.Where("creator_id = ? and id = ?", uid, id).Delete(&Invitation{})
+}
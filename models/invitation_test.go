@@ -0,0 +1,92 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvitation_Create(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	invitation := Invitation{CreatorID: 1}
+	asserts.NoError(invitation.Create())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(invitation.Code, 16)
+}
+
+func TestInvitation_Available(t *testing.T) {
+	asserts := assert.New(t)
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+
+	asserts.True((&Invitation{}).Available())
+	asserts.True((&Invitation{MaxUses: 2, Uses: 1, ExpiredAt: &future}).Available())
+	asserts.False((&Invitation{MaxUses: 2, Uses: 2}).Available())
+	asserts.False((&Invitation{ExpiredAt: &past}).Available())
+}
+
+func TestInvitation_Use(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		invitation := Invitation{MaxUses: 1}
+		invitation.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)uses(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(invitation.Use())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(1, invitation.Uses)
+
+		// 已用完
+		asserts.Equal(ErrInvitationUnavailable, invitation.Use())
+	}
+
+	// 并发使用导致次数用完
+	{
+		invitation := Invitation{MaxUses: 1}
+		invitation.ID = 1
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)uses(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		asserts.Equal(ErrInvitationUnavailable, invitation.Use())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(0, invitation.Uses)
+	}
+}
+
+func TestGetInvitationByCode(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)invitations(.+)").WithArgs("code").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 3))
+	invitation, err := GetInvitationByCode("code")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, invitation.GroupID)
+}
+
+func TestCountAvailableInvitations(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT count(.+)invitations(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	asserts.Equal(2, CountAvailableInvitations(1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestInvitation_Release(t *testing.T) {
+	asserts := assert.New(t)
+	invitation := Invitation{Uses: 1}
+	invitation.ID = 1
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)uses(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	invitation.Release()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(0, invitation.Uses)
+}
@@ -0,0 +1,77 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LoginDevice 用户登录过的设备及来源国家/地区，用于识别异常登录
+type LoginDevice struct {
+	gorm.Model
+	UserID      uint      `gorm:"index"`         // 用户ID
+	Fingerprint string    `gorm:"size:64;index"` // 设备指纹
+	Country     string    `gorm:"size:8"`        // 国家/地区代码，无法识别时为空
+	UserAgent   string    `gorm:"type:text"`     // 登录设备的 User-Agent
+	IP          string    // 最后登录 IP
+	LastSeen    time.Time // 最后登录时间
+}
+
+// LoginAnomaly 异常登录的检测结果
+type LoginAnomaly struct {
+	NewDevice  bool // 从未使用过的设备
+	NewCountry bool // 从未登录过的国家/地区
+}
+
+// Any 是否存在任意异常
+func (anomaly LoginAnomaly) Any() bool {
+	return anomaly.NewDevice || anomaly.NewCountry
+}
+
+// LoginFingerprint 根据 User-Agent 计算设备指纹
+func LoginFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(userAgent))
+	return hex.EncodeToString(sum[:])
+}
+
+// DetectLoginAnomaly 与用户已登录过的设备比对，首次登录的用户不视为异常
+func DetectLoginAnomaly(uid uint, fingerprint, country string) (LoginAnomaly, error) {
+	var devices []LoginDevice
+	if err := DB.Where("user_id = ?", uid).Find(&devices).Error; err != nil {
+		return LoginAnomaly{}, err
+	}
+
+	if len(devices) == 0 {
+		return LoginAnomaly{}, nil
+	}
+
+	anomaly := LoginAnomaly{NewDevice: true, NewCountry: country != ""}
+	for _, device := range devices {
+		if device.Fingerprint == fingerprint {
+			anomaly.NewDevice = false
+		}
+		if device.Country == country {
+			anomaly.NewCountry = false
+		}
+	}
+
+	return anomaly, nil
+}
+
+// RecordLoginDevice 记录一次登录的设备及来源，同一设备在同一国家/地区的登录只保留一条记录
+func RecordLoginDevice(uid uint, userAgent, country, ip string) error {
+	device := &LoginDevice{}
+	fingerprint := LoginFingerprint(userAgent)
+	err := DB.Where(map[string]interface{}{"user_id": uid, "fingerprint": fingerprint, "country": country}).
+		Attrs(LoginDevice{UserAgent: userAgent}).
+		FirstOrInit(device).Error
+	if err != nil {
+		return err
+	}
+
+	device.IP = ip
+	device.LastSeen = time.Now()
+	return DB.Save(device).Error
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginFingerprint(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Len(LoginFingerprint("Mozilla/5.0"), 64)
+	asserts.Equal(LoginFingerprint("Mozilla/5.0"), LoginFingerprint("Mozilla/5.0"))
+	asserts.NotEqual(LoginFingerprint("Mozilla/5.0"), LoginFingerprint("curl/7.0"))
+}
+
+func TestDetectLoginAnomaly(t *testing.T) {
+	asserts := assert.New(t)
+	fingerprint := LoginFingerprint("Mozilla/5.0")
+	columns := []string{"id", "user_id", "fingerprint", "country"}
+
+	// 首次登录
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").WillReturnRows(sqlmock.NewRows(columns))
+		anomaly, err := DetectLoginAnomaly(1, fingerprint, "CN")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(anomaly.Any())
+	}
+
+	// 已知设备及国家/地区
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, fingerprint, "CN"))
+		anomaly, err := DetectLoginAnomaly(1, fingerprint, "CN")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(anomaly.Any())
+	}
+
+	// 新设备、新国家/地区
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, "other", "CN"))
+		anomaly, err := DetectLoginAnomaly(1, fingerprint, "US")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(anomaly.NewDevice)
+		asserts.True(anomaly.NewCountry)
+	}
+
+	// 无法识别国家/地区时不视为新国家/地区
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(1, 1, fingerprint, "CN"))
+		anomaly, err := DetectLoginAnomaly(1, fingerprint, "")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.False(anomaly.Any())
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").WillReturnError(errors.New("error"))
+		_, err := DetectLoginAnomaly(1, fingerprint, "CN")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
+func TestRecordLoginDevice(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 新设备
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)login_devices(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(RecordLoginDevice(1, "Mozilla/5.0", "CN", "1.1.1.1"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已有记录
+	{
+		mock.ExpectQuery("SELECT(.+)login_devices(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "last_seen"}).AddRow(1, time.Now()))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)login_devices(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(RecordLoginDevice(1, "Mozilla/5.0", "CN", "1.1.1.2"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -11,8 +11,19 @@ import (
 	"github.com/jinzhu/gorm"
 	"sort"
 	"strings"
+	"time"
 )
 
+// Models 返回全部数据模型，用于数据库迁移及备份
+func Models() []interface{} {
+	return []interface{}{&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
+		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{}, &ShareLockLog{},
+		&ShareAccessLog{}, &ShareRecipient{}, &DownloadToken{}, &ShareBundleItem{},
+		&ShareMailLog{}, &FederatedShare{}, &RemoteShare{}, &AuditLog{}, &APIToken{},
+		&OAuthClient{}, &UserSession{}, &LoginDevice{}, &Invitation{}, &Notification{}, &S3AccessKey{}, &SSHKey{},
+		&FileChange{}, &WebdavProp{}, &Statistic{}, &Moderation{}, &BlockedHash{}, &Announcement{}, &FileLock{}}
+}
+
 // 是否需要迁移
 func needMigration() bool {
 	var setting Setting
@@ -40,8 +51,10 @@ func migration() {
 		DB = DB.Set("gorm:table_options", "ENGINE=InnoDB")
 	}
 
-	DB.AutoMigrate(&User{}, &Setting{}, &Group{}, &Policy{}, &Folder{}, &File{}, &Share{},
-		&Task{}, &Download{}, &Tag{}, &Webdav{}, &Node{})
+	DB.AutoMigrate(Models()...)
+
+	// 添加热点查询使用的复合索引
+	addHotQueryIndexes()
 
 	// 创建初始存储策略
 	addDefaultPolicy()
@@ -88,8 +101,18 @@ func addDefaultPolicy() {
 }
 
 func addDefaultSettings() {
+	now := time.Now()
 	for _, value := range defaultSettings {
-		DB.Where(Setting{Name: value.Name}).Create(&value)
+		// 已存在的设置项保持不变
+		if _, err := insertIgnore(DB, &Setting{}, []string{"name"}, map[string]interface{}{
+			"created_at": now,
+			"updated_at": now,
+			"type":       value.Type,
+			"name":       value.Name,
+			"value":      value.Value,
+		}); err != nil {
+			util.Log().Warning("无法添加初始设置 %q, %s", value.Name, err)
+		}
 	}
 }
 
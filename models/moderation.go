@@ -0,0 +1,196 @@
+package model
+
+import (
+	"path"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 内容审核状态
+const (
+	// ModerationPending 等待审核
+	ModerationPending = "pending"
+	// ModerationPass 审核通过
+	ModerationPass = "pass"
+	// ModerationReview 疑似违规，等待人工复核
+	ModerationReview = "review"
+	// ModerationBlock 违规
+	ModerationBlock = "block"
+	// ModerationError 多次重试后仍无法完成审核
+	ModerationError = "error"
+)
+
+// 需要审核的媒体类型
+const (
+	ModerationImage = "image"
+	ModerationVideo = "video"
+)
+
+// Moderation 内容审核记录
+type Moderation struct {
+	gorm.Model
+	FileID     uint    `gorm:"index:file_id" json:"file_id"`
+	UserID     uint    `gorm:"index:user_id" json:"user_id"`
+	ShareID    uint    `json:"share_id"` // 因创建分享提交审核时对应的分享，上传时提交为 0
+	FileName   string  `json:"file_name"`
+	Kind       string  `gorm:"size:16" json:"kind"`
+	Status     string  `gorm:"size:16;index:status" json:"status"`
+	Provider   string  `gorm:"size:16" json:"provider"`
+	Label      string  `json:"label"`                  // 审核服务给出的违规类型
+	Score      float64 `json:"score"`                  // 审核服务给出的置信度，0-100
+	Error      string  `gorm:"type:text" json:"error"` // 最近一次审核失败的原因
+	Attempts   int     `json:"attempts"`
+	ReviewerID uint    `json:"reviewer_id"` // 人工复核的管理员，0 表示尚未复核
+}
+
+// ModerationMediaKind 根据文件扩展名判断是否为需要审核的媒体文件，不需要审核时返回空字符串
+func ModerationMediaKind(name string) string {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+	if ext == "" {
+		return ""
+	}
+
+	options := GetSettingByNames("moderation_image_exts", "moderation_video_exts")
+	for _, kind := range []string{ModerationImage, ModerationVideo} {
+		for _, allowed := range strings.Split(options["moderation_"+kind+"_exts"], ",") {
+			if strings.TrimSpace(allowed) == ext {
+				return kind
+			}
+		}
+	}
+
+	return ""
+}
+
+// IsModerationEnabled 是否在给定时机提交内容审核，trigger 为 upload 或 share
+func IsModerationEnabled(trigger string) bool {
+	options := GetSettingByNames("moderation_enabled", "moderation_on_"+trigger)
+	return IsTrueVal(options["moderation_enabled"]) && IsTrueVal(options["moderation_on_"+trigger])
+}
+
+// SubmitModeration 提交文件审核。上传的文件内容总是重新审核；因创建分享提交时，
+// 文件已在审核中或已通过则不重复提交，已有违规结论时直接将其应用到文件的分享上
+func SubmitModeration(file *File, shareID uint) error {
+	kind := ModerationMediaKind(file.Name)
+	if kind == "" {
+		return nil
+	}
+
+	var existed Moderation
+	if shareID > 0 && DB.Where("file_id = ? and status <> ?", file.ID, ModerationError).
+		Order("id desc").First(&existed).Error == nil {
+		if existed.Status == ModerationReview || existed.Status == ModerationBlock {
+			return UpdateSharesModerationByFile(file.ID, existed.ShareStatus())
+		}
+		return nil
+	}
+
+	return DB.Create(&Moderation{
+		FileID:   file.ID,
+		UserID:   file.UserID,
+		ShareID:  shareID,
+		FileName: file.Name,
+		Kind:     kind,
+		Status:   ModerationPending,
+	}).Error
+}
+
+// ListPendingModerations 按提交顺序列出等待审核的记录
+func ListPendingModerations(limit int) ([]Moderation, error) {
+	var moderations []Moderation
+	err := DB.Where("status = ?", ModerationPending).Order("id").Limit(limit).Find(&moderations).Error
+	return moderations, err
+}
+
+// ListModerations 分页列出审核记录，status 为空时列出全部
+func ListModerations(status string, page, pageSize int) ([]Moderation, int) {
+	var (
+		moderations []Moderation
+		total       int
+	)
+
+	dbChain := DB.Model(&Moderation{})
+	if status != "" {
+		dbChain = dbChain.Where("status = ?", status)
+	}
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&moderations)
+	return moderations, total
+}
+
+// GetModerationByID 用ID获取审核记录
+func GetModerationByID(id uint) (Moderation, error) {
+	var moderation Moderation
+	result := DB.First(&moderation, id)
+	return moderation, result.Error
+}
+
+// Finish 保存审核结论
+func (moderation *Moderation) Finish(provider, status, label string, score float64) error {
+	moderation.Provider = provider
+	moderation.Status = status
+	moderation.Label = label
+	moderation.Score = score
+	moderation.Error = ""
+	return DB.Model(moderation).Updates(map[string]interface{}{
+		"provider": provider,
+		"status":   status,
+		"label":    label,
+		"score":    score,
+		"error":    "",
+	}).Error
+}
+
+// Fail 记录一次审核失败，重试次数用尽后不再审核
+func (moderation *Moderation) Fail(reason string, maxAttempts int) error {
+	moderation.Attempts++
+	moderation.Error = reason
+	if moderation.Attempts >= maxAttempts {
+		moderation.Status = ModerationError
+	}
+
+	return DB.Model(moderation).Updates(map[string]interface{}{
+		"attempts": moderation.Attempts,
+		"error":    reason,
+		"status":   moderation.Status,
+	}).Error
+}
+
+// Resolve 管理员人工复核，status 为 ModerationPass 或 ModerationBlock，结论同时应用到文件的分享上
+func (moderation *Moderation) Resolve(status string, reviewer uint) error {
+	moderation.Status = status
+	moderation.ReviewerID = reviewer
+	if err := DB.Model(moderation).Updates(map[string]interface{}{
+		"status":      status,
+		"reviewer_id": reviewer,
+	}).Error; err != nil {
+		return err
+	}
+
+	return UpdateSharesModerationByFile(moderation.FileID, moderation.ShareStatus())
+}
+
+// ShareStatus 审核结论对应的分享状态，违规时是否自动屏蔽分享由 moderation_auto_block 设置决定
+func (moderation *Moderation) ShareStatus() string {
+	switch moderation.Status {
+	case ModerationBlock:
+		if moderation.ReviewerID > 0 || IsTrueVal(GetSettingByName("moderation_auto_block")) {
+			return ModerationBlock
+		}
+		return ModerationReview
+	case ModerationReview:
+		return ModerationReview
+	default:
+		return ""
+	}
+}
+
+// UpdateSharesModerationByFile 更新直接分享此文件，或在合集中包含此文件的分享的审核状态
+func UpdateSharesModerationByFile(fileID uint, status string) error {
+	bundles := DB.Model(&ShareBundleItem{}).Select("share_id").
+		Where("is_dir = ? and source_id = ?", false, fileID).QueryExpr()
+	return DB.Model(&Share{}).
+		Where("(is_dir = ? and bundle = ? and source_id = ?) or id in (?)", false, false, fileID, bundles).
+		UpdateColumn("moderation_status", status).Error
+}
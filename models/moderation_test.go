@@ -0,0 +1,195 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModerationMediaKind(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_moderation_image_exts", "jpg,png", 0)
+	cache.Set("setting_moderation_video_exts", "mp4, mkv", 0)
+
+	asserts.Equal(ModerationImage, ModerationMediaKind("a.JPG"))
+	asserts.Equal(ModerationVideo, ModerationMediaKind("dir/b.mkv"))
+	asserts.Equal("", ModerationMediaKind("c.txt"))
+	asserts.Equal("", ModerationMediaKind("jpg"))
+}
+
+func TestIsModerationEnabled(t *testing.T) {
+	asserts := assert.New(t)
+
+	cache.Set("setting_moderation_enabled", "0", 0)
+	cache.Set("setting_moderation_on_upload", "1", 0)
+	asserts.False(IsModerationEnabled("upload"))
+
+	cache.Set("setting_moderation_enabled", "1", 0)
+	asserts.True(IsModerationEnabled("upload"))
+
+	cache.Set("setting_moderation_on_share", "0", 0)
+	asserts.False(IsModerationEnabled("share"))
+}
+
+func TestSubmitModeration(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_moderation_image_exts", "jpg,png", 0)
+	cache.Set("setting_moderation_video_exts", "mp4", 0)
+	cache.Set("setting_moderation_auto_block", "1", 0)
+	file := &File{Name: "a.jpg", UserID: 1}
+	file.ID = 2
+
+	// 非媒体文件
+	{
+		asserts.NoError(SubmitModeration(&File{Name: "a.txt"}, 0))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 上传时提交
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)moderations(.+)").
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), uint(2), uint(1), uint(0), "a.jpg",
+				ModerationImage, ModerationPending, "", "", float64(0), "", 0, uint(0)).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SubmitModeration(file, 0))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 创建分享时提交，无已有记录
+	{
+		mock.ExpectQuery("SELECT(.+)moderations(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)moderations(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SubmitModeration(file, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 创建分享时提交，已通过审核
+	{
+		mock.ExpectQuery("SELECT(.+)moderations(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, ModerationPass))
+		asserts.NoError(SubmitModeration(file, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 创建分享时提交，已有违规结论
+	{
+		mock.ExpectQuery("SELECT(.+)moderations(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "status"}).AddRow(1, ModerationBlock))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)moderation_status(.+)").
+			WithArgs(ModerationBlock, false, false, uint(2), false, uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(SubmitModeration(file, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListModerations(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)moderations(.+)").WithArgs(ModerationPending).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	res, err := ListPendingModerations(10)
+	asserts.NoError(err)
+	asserts.Len(res, 2)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	mock.ExpectQuery("SELECT count(.+)moderations(.+)").WithArgs(ModerationBlock).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT(.+)moderations(.+)").WithArgs(ModerationBlock).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	list, total := ListModerations(ModerationBlock, 1, 10)
+	asserts.Len(list, 1)
+	asserts.Equal(1, total)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestModeration_Finish(t *testing.T) {
+	asserts := assert.New(t)
+	moderation := &Moderation{Error: "timeout"}
+	moderation.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)moderations(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(moderation.Finish("nsfw", ModerationReview, "porn", 75))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(ModerationReview, moderation.Status)
+	asserts.Equal("nsfw", moderation.Provider)
+	asserts.Empty(moderation.Error)
+}
+
+func TestModeration_Fail(t *testing.T) {
+	asserts := assert.New(t)
+	moderation := &Moderation{Status: ModerationPending}
+	moderation.ID = 1
+
+	// 仍可重试
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)moderations(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	asserts.NoError(moderation.Fail("timeout", 2))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(ModerationPending, moderation.Status)
+	asserts.Equal(1, moderation.Attempts)
+
+	// 重试次数用尽
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)moderations(.+)").WillReturnError(errors.New("error"))
+	mock.ExpectRollback()
+	asserts.Error(moderation.Fail("timeout", 2))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(ModerationError, moderation.Status)
+}
+
+func TestModeration_Resolve(t *testing.T) {
+	asserts := assert.New(t)
+	moderation := &Moderation{FileID: 2, Status: ModerationReview}
+	moderation.ID = 1
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)moderations(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").
+			WithArgs("", false, false, uint(2), false, uint(2)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(moderation.Resolve(ModerationPass, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(3, moderation.ReviewerID)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)moderations(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(moderation.Resolve(ModerationBlock, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestModeration_ShareStatus(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.Equal("", (&Moderation{Status: ModerationPass}).ShareStatus())
+	asserts.Equal(ModerationReview, (&Moderation{Status: ModerationReview}).ShareStatus())
+
+	cache.Set("setting_moderation_auto_block", "1", 0)
+	asserts.Equal(ModerationBlock, (&Moderation{Status: ModerationBlock}).ShareStatus())
+
+	cache.Set("setting_moderation_auto_block", "0", 0)
+	asserts.Equal(ModerationReview, (&Moderation{Status: ModerationBlock}).ShareStatus())
+	asserts.Equal(ModerationBlock, (&Moderation{Status: ModerationBlock, ReviewerID: 1}).ShareStatus())
+}
@@ -0,0 +1,191 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// 通知事件类型
+const (
+	// NotifyShareAccessed 分享被访问或下载
+	NotifyShareAccessed = "share_accessed"
+	// NotifyTaskFinished 任务执行完成或失败
+	NotifyTaskFinished = "task_finished"
+	// NotifyQuotaWarning 已用容量超过提醒阈值
+	NotifyQuotaWarning = "quota_warning"
+	// NotifyLoginAlert 异常登录提醒
+	NotifyLoginAlert = "login_alert"
+	// NotifyVirusDetected 上传的文件检出病毒
+	NotifyVirusDetected = "virus_detected"
+	// NotifyModeration 文件未通过内容审核
+	NotifyModeration = "moderation"
+	// NotifyAnnouncement 站点公告
+	NotifyAnnouncement = "announcement"
+	// NotifyCapacityAlert 存储策略剩余容量不足，仅发送给管理员
+	NotifyCapacityAlert = "capacity_alert"
+)
+
+// 通知渠道
+const (
+	// NotifyChannelEmail 邮件
+	NotifyChannelEmail = "email"
+	// NotifyChannelInbox 站内信
+	NotifyChannelInbox = "inbox"
+	// NotifyChannelWebhook 用户设置的 Webhook
+	NotifyChannelWebhook = "webhook"
+	// NotifyChannelSMS 短信，仅用于重要事件
+	NotifyChannelSMS = "sms"
+)
+
+var (
+	// NotifyEvents 全部通知事件类型
+	NotifyEvents = []string{NotifyShareAccessed, NotifyTaskFinished, NotifyQuotaWarning, NotifyLoginAlert, NotifyVirusDetected, NotifyModeration, NotifyAnnouncement, NotifyCapacityAlert}
+	// NotifyChannels 全部通知渠道
+	NotifyChannels = []string{NotifyChannelEmail, NotifyChannelInbox, NotifyChannelWebhook, NotifyChannelSMS}
+	// NotifySMSEvents 可以通过短信通知的重要事件
+	NotifySMSEvents = []string{NotifyLoginAlert, NotifyVirusDetected, NotifyQuotaWarning, NotifyCapacityAlert}
+)
+
+// defaultNotifyRoutes 用户未设置时各事件使用的通知渠道
+var defaultNotifyRoutes = map[string][]string{
+	NotifyShareAccessed: {NotifyChannelInbox},
+	NotifyTaskFinished:  {NotifyChannelInbox},
+	NotifyQuotaWarning:  {NotifyChannelInbox, NotifyChannelEmail},
+	NotifyLoginAlert:    {NotifyChannelEmail},
+	NotifyVirusDetected: {NotifyChannelInbox, NotifyChannelEmail},
+	NotifyModeration:    {NotifyChannelInbox},
+	NotifyAnnouncement:  {NotifyChannelInbox, NotifyChannelEmail},
+	NotifyCapacityAlert: {NotifyChannelInbox, NotifyChannelEmail},
+}
+
+// NotificationPreference 用户的通知偏好
+type NotificationPreference struct {
+	Routes        map[string][]string `json:"routes,omitempty"`         // 各事件类型的通知渠道，未设置的事件使用默认渠道
+	Digest        []string            `json:"digest,omitempty"`         // 邮件通知改为定期汇总发送的事件类型
+	Webhook       string              `json:"webhook,omitempty"`        // Webhook 地址
+	WebhookSecret string              `json:"webhook_secret,omitempty"` // Webhook 请求签名密钥
+}
+
+// Channels 获取事件的通知渠道
+func (pref *NotificationPreference) Channels(event string) []string {
+	if pref != nil {
+		if channels, ok := pref.Routes[event]; ok {
+			return channels
+		}
+	}
+	return defaultNotifyRoutes[event]
+}
+
+// IsDigest 事件的邮件通知是否汇总发送
+func (pref *NotificationPreference) IsDigest(event string) bool {
+	if pref == nil {
+		return false
+	}
+	for _, e := range pref.Digest {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification 通知记录，包括站内信及等待汇总发送邮件的通知
+type Notification struct {
+	gorm.Model
+	UserID  uint   `gorm:"index"`
+	Event   string `gorm:"size:32"`
+	Title   string
+	Content string     `gorm:"type:text"`
+	Inbox   bool       // 是否在站内信中展示
+	Pending bool       `gorm:"index"` // 是否等待汇总邮件发送
+	ReadAt  *time.Time // 站内信阅读时间，未读时为空
+}
+
+// Create 创建通知记录
+func (notification *Notification) Create() error {
+	return DB.Create(notification).Error
+}
+
+// ListNotifications 分页列出用户的站内信
+func ListNotifications(uid uint, unread bool, page, pageSize int) ([]Notification, int) {
+	var (
+		notifications []Notification
+		total         int
+	)
+
+	dbChain := DB.Model(&Notification{}).Where("user_id = ? and inbox = ?", uid, true)
+	if unread {
+		dbChain = dbChain.Where("read_at is NULL")
+	}
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&notifications)
+	return notifications, total
+}
+
+// CountUnreadNotifications 统计用户的未读站内信数量
+func CountUnreadNotifications(uid uint) int {
+	var total int
+	DB.Model(&Notification{}).Where("user_id = ? and inbox = ? and read_at is NULL", uid, true).Count(&total)
+	return total
+}
+
+// MarkNotificationsRead 将用户的站内信标为已读，ids 为空时标记全部
+func MarkNotificationsRead(uid uint, ids []uint) error {
+	dbChain := DB.Model(&Notification{}).Where("user_id = ? and inbox = ? and read_at is NULL", uid, true)
+	if len(ids) > 0 {
+		dbChain = dbChain.Where("id in (?)", ids)
+	}
+	return dbChain.UpdateColumn("read_at", time.Now()).Error
+}
+
+// DeleteNotifications 从用户的站内信中删除通知，仍在等待汇总邮件发送的通知只从站内信中移除
+func DeleteNotifications(uid uint, ids []uint) error {
+	tx := DB.Begin()
+	if err := tx.Model(&Notification{}).Where("user_id = ? and id in (?) and pending = ?", uid, ids, true).
+		UpdateColumn("inbox", false).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Unscoped().Where("user_id = ? and id in (?) and pending = ?", uid, ids, false).
+		Delete(&Notification{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// ListPendingNotifications 列出全部等待汇总邮件发送的通知，按用户排列
+func ListPendingNotifications() ([]Notification, error) {
+	var notifications []Notification
+	err := DB.Where("pending = ?", true).Order("user_id, id").Find(&notifications).Error
+	return notifications, err
+}
+
+// FinishNotificationDigest 汇总邮件发送完成后，删除不在站内信中展示的通知，其余通知取消待发送标记
+func FinishNotificationDigest(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	tx := DB.Begin()
+	if err := tx.Unscoped().Where("id in (?) and inbox = ?", ids, false).Delete(&Notification{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Model(&Notification{}).Where("id in (?)", ids).UpdateColumn("pending", false).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteReadNotificationsBefore 删除给定时间之前已读的站内信
+func DeleteReadNotificationsBefore(before time.Time) (int64, error) {
+	result := DB.Unscoped().Where("read_at < ? and pending = ?", before, false).Delete(&Notification{})
+	return result.RowsAffected, result.Error
+}
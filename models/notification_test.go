@@ -0,0 +1,126 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotificationPreference_Channels(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未设置时使用默认渠道
+	var pref *NotificationPreference
+	asserts.Equal([]string{NotifyChannelEmail}, pref.Channels(NotifyLoginAlert))
+	asserts.False(pref.IsDigest(NotifyLoginAlert))
+
+	pref = &NotificationPreference{
+		Routes: map[string][]string{
+			NotifyLoginAlert:    {NotifyChannelWebhook},
+			NotifyShareAccessed: {},
+		},
+		Digest: []string{NotifyShareAccessed},
+	}
+	asserts.Equal([]string{NotifyChannelWebhook}, pref.Channels(NotifyLoginAlert))
+	asserts.Empty(pref.Channels(NotifyShareAccessed))
+	asserts.Equal([]string{NotifyChannelInbox}, pref.Channels(NotifyTaskFinished))
+	asserts.True(pref.IsDigest(NotifyShareAccessed))
+	asserts.False(pref.IsDigest(NotifyTaskFinished))
+}
+
+func TestListNotifications(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT count(.+)read_at is NULL(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT(.+)notifications(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "title"}).AddRow(2, "b").AddRow(1, "a"))
+	res, total := ListNotifications(1, true, 1, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(2, total)
+	asserts.Len(res, 2)
+	asserts.Equal("b", res[0].Title)
+}
+
+func TestMarkNotificationsRead(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 标记全部
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)read_at(.+)").WithArgs(sqlmock.AnyArg(), 1, true).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		asserts.NoError(MarkNotificationsRead(1, nil))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 标记指定通知
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)id in(.+)").WithArgs(sqlmock.AnyArg(), 1, true, 3, 4).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		asserts.NoError(MarkNotificationsRead(1, []uint{3, 4}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteNotifications(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)inbox(.+)").WithArgs(false, 1, 3, true).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE(.+)").WithArgs(1, 3, false).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteNotifications(1, []uint{3}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)inbox(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(DeleteNotifications(1, []uint{3}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFinishNotificationDigest(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无需操作
+	asserts.NoError(FinishNotificationDigest(nil))
+
+	// 删除不在站内信中展示的通知，其余取消待发送标记
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WithArgs(1, 2, false).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)pending(.+)").WithArgs(false, 1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(FinishNotificationDigest([]uint{1, 2}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(FinishNotificationDigest([]uint{1, 2}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteReadNotificationsBefore(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	deleted, err := DeleteReadNotificationsBefore(time.Now())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, deleted)
+}
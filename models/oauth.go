@@ -0,0 +1,96 @@
+package model
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// OAuthClient 通过 OAuth2 授权访问用户文件的第三方应用
+type OAuthClient struct {
+	gorm.Model
+	Name         string // 应用名称
+	ClientID     string `gorm:"unique_index"` // 客户端ID
+	Secret       string `json:"-"`            // 客户端密钥的 SHA256 摘要
+	Public       bool   // 是否为无法保存密钥的公开客户端，公开客户端必须使用 PKCE
+	Homepage     string // 应用主页
+	RedirectURIs string `gorm:"type:text"` // 允许的回调地址，每行一个
+}
+
+// Create 创建应用并生成客户端ID及密钥，返回的明文密钥只在创建时可见
+func (client *OAuthClient) Create() (string, error) {
+	client.ClientID = util.RandStringRunes(32)
+	secret, err := client.newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := DB.Create(client).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ResetSecret 重新生成客户端密钥
+func (client *OAuthClient) ResetSecret() (string, error) {
+	secret, err := client.newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	if err := DB.Model(client).Update("secret", client.Secret).Error; err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+func (client *OAuthClient) newSecret() (string, error) {
+	secret, err := newSecret("")
+	if err != nil {
+		return "", err
+	}
+	client.Secret = hashAPIToken(secret)
+	return secret, nil
+}
+
+// CheckSecret 校验客户端密钥
+func (client *OAuthClient) CheckSecret(secret string) bool {
+	if client.Secret == "" || secret == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(client.Secret), []byte(hashAPIToken(secret))) == 1
+}
+
+// AllowRedirect 回调地址是否已登记，须完全匹配
+func (client *OAuthClient) AllowRedirect(uri string) bool {
+	for _, allowed := range strings.Split(client.RedirectURIs, "\n") {
+		if allowed = strings.TrimSpace(allowed); allowed != "" && allowed == uri {
+			return true
+		}
+	}
+	return false
+}
+
+// GetOAuthClient 根据客户端ID查找应用
+func GetOAuthClient(clientID string) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	err := DB.Where("client_id = ?", clientID).First(client).Error
+	return client, err
+}
+
+// GetOAuthClientByID 根据ID查找应用
+func GetOAuthClientByID(id uint) (*OAuthClient, error) {
+	client := &OAuthClient{}
+	err := DB.First(client, id).Error
+	return client, err
+}
+
+// DeleteOAuthClient 删除应用及其签发的全部令牌
+func DeleteOAuthClient(id uint) error {
+	if err := DB.Where("client_id = ?", id).Delete(&APIToken{}).Error; err != nil {
+		return err
+	}
+	return DB.Delete(&OAuthClient{}, id).Error
+}
@@ -0,0 +1,118 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOAuthClient_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		client := OAuthClient{Name: "app"}
+		secret, err := client.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(client.ClientID, 32)
+		asserts.True(client.CheckSecret(secret))
+		asserts.False(client.CheckSecret(secret + "1"))
+		asserts.False(client.CheckSecret(""))
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		client := OAuthClient{}
+		secret, err := client.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Empty(secret)
+	}
+}
+
+func TestOAuthClient_ResetSecret(t *testing.T) {
+	asserts := assert.New(t)
+	client := OAuthClient{}
+	client.ID = 1
+	client.Secret = hashAPIToken("old")
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	secret, err := client.ResetSecret()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.True(client.CheckSecret(secret))
+	asserts.False(client.CheckSecret("old"))
+}
+
+func TestOAuthClient_AllowRedirect(t *testing.T) {
+	asserts := assert.New(t)
+	client := OAuthClient{RedirectURIs: "https://app.example.com/callback\r\n\nhttp://localhost:8080/cb "}
+
+	asserts.True(client.AllowRedirect("https://app.example.com/callback"))
+	asserts.True(client.AllowRedirect("http://localhost:8080/cb"))
+	asserts.False(client.AllowRedirect("https://app.example.com/callback/evil"))
+	asserts.False(client.AllowRedirect("https://app.example.com"))
+	asserts.False(client.AllowRedirect(""))
+}
+
+func TestAPIToken_IssueOAuthToken(t *testing.T) {
+	asserts := assert.New(t)
+	token := APIToken{UserID: 1, ClientID: 2, Scope: APITokenScopeRead}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	access, refresh, err := token.IssueOAuthToken(time.Hour)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal(hashAPIToken(access), token.Token)
+	asserts.Equal(hashAPIToken(refresh), token.Refresh)
+	asserts.False(token.Expired())
+
+	// 刷新令牌无法作为访问令牌使用
+	_, err = GetAPIToken(refresh)
+	asserts.Equal(gorm.ErrRecordNotFound, err)
+
+	// 轮换
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	newAccess, newRefresh, err := token.IssueOAuthToken(time.Hour)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.NotEqual(access, newAccess)
+	asserts.NotEqual(refresh, newRefresh)
+}
+
+func TestGetOAuthTokenByRefresh(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 前缀不符
+	{
+		_, err := GetOAuthTokenByRefresh("cr_123", 1)
+		asserts.Equal(gorm.ErrRecordNotFound, err)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(hashAPIToken("crr_123"), 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "client_id"}).AddRow(3, 1))
+		token, err := GetOAuthTokenByRefresh("crr_123", 1)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(3, token.ID)
+	}
+}
@@ -65,8 +65,22 @@ type PolicyOption struct {
 	TPSLimit float64 `json:"tps_limit,omitempty"`
 	// 每秒 API 请求爆发上限
 	TPSLimitBurst int `json:"tps_limit_burst,omitempty"`
+	// 上传的文件检出病毒后的处理方式，为空时使用站点设置
+	VirusAction string `json:"virus_action,omitempty"`
+	// 允许客户端同时上传的分片数，0 或 1 时按顺序上传
+	ChunkConcurrency int `json:"chunk_concurrency,omitempty"`
 }
 
+// 检出病毒后的处理方式
+const (
+	// VirusActionQuarantine 隔离文件，文件保留但无法下载或预览
+	VirusActionQuarantine = "quarantine"
+	// VirusActionReject 删除文件
+	VirusActionReject = "reject"
+	// VirusActionNone 不扫描此存储策略下的文件
+	VirusActionNone = "none"
+)
+
 // thumbSuffix 支持缩略图处理的文件扩展名
 var thumbSuffix = map[string][]string{
 	"local":    {},
@@ -103,6 +117,13 @@ func GetPolicyByID(ID interface{}) (Policy, error) {
 	return policy, result.Error
 }
 
+// GetPolicies 列出所有存储策略
+func GetPolicies() ([]Policy, error) {
+	var policies []Policy
+	result := DB.Find(&policies)
+	return policies, result.Error
+}
+
 // AfterFind 找到存储策略后的钩子
 func (policy *Policy) AfterFind() (err error) {
 	// 解析存储策略设置到OptionsSerialized
@@ -210,6 +231,21 @@ func (policy *Policy) IsThumbGenerateNeeded() bool {
 	return policy.Type == "local"
 }
 
+// GetVirusAction 返回此策略下的文件检出病毒后的处理方式
+func (policy *Policy) GetVirusAction() string {
+	action := policy.OptionsSerialized.VirusAction
+	if action == "" {
+		action = GetSettingByName("clamav_action")
+	}
+
+	switch action {
+	case VirusActionReject, VirusActionNone:
+		return action
+	default:
+		return VirusActionQuarantine
+	}
+}
+
 // IsUploadPlaceholderWithSize 返回此策略创建上传会话时是否需要预留空间
 func (policy *Policy) IsUploadPlaceholderWithSize() bool {
 	if policy.Type == "remote" {
@@ -223,6 +259,20 @@ func (policy *Policy) IsUploadPlaceholderWithSize() bool {
 	return false
 }
 
+// MaxChunkConcurrency 返回此策略允许客户端同时上传的分片数。本机及从机策略按偏移写入分片，
+// S3 及 OSS 的各分片对应独立的分块，OneDrive 等其余策略的上传会话只接受按顺序上传的分片
+func (policy *Policy) MaxChunkConcurrency() int {
+	if policy.OptionsSerialized.ChunkSize == 0 || policy.OptionsSerialized.ChunkConcurrency < 1 {
+		return 1
+	}
+
+	if util.ContainsString([]string{"local", "remote", "s3", "oss"}, policy.Type) {
+		return policy.OptionsSerialized.ChunkConcurrency
+	}
+
+	return 1
+}
+
 // CanStructureBeListed 返回存储策略是否能被前台列物理目录
 func (policy *Policy) CanStructureBeListed() bool {
 	return policy.Type != "local" && policy.Type != "remote"
@@ -246,3 +296,22 @@ func (policy *Policy) UpdateAccessKeyAndClearCache(s string) error {
 func (policy *Policy) ClearCache() {
 	cache.Deletes([]string{strconv.FormatUint(uint64(policy.ID), 10)}, "policy_")
 }
+
+// policyUploadSuspendedPrefix 因容量不足暂停接收新上传的存储策略的缓存前缀
+const policyUploadSuspendedPrefix = "policy_upload_suspended_"
+
+// SetPolicyUploadSuspended 设置存储策略是否因容量不足暂停接收新上传
+func SetPolicyUploadSuspended(id uint, suspended bool) {
+	key := strconv.FormatUint(uint64(id), 10)
+	if suspended {
+		cache.Set(policyUploadSuspendedPrefix+key, true, 0)
+		return
+	}
+	cache.Deletes([]string{key}, policyUploadSuspendedPrefix)
+}
+
+// IsPolicyUploadSuspended 返回存储策略是否因容量不足暂停接收新上传
+func IsPolicyUploadSuspended(id uint) bool {
+	_, ok := cache.Get(policyUploadSuspendedPrefix + strconv.FormatUint(uint64(id), 10))
+	return ok
+}
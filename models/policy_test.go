@@ -45,6 +45,20 @@ func TestGetPolicyByID(t *testing.T) {
 
 }
 
+func TestGetPolicies(t *testing.T) {
+	asserts := assert.New(t)
+
+	rows := sqlmock.NewRows([]string{"id", "name", "options"}).
+		AddRow(1, "默认存储策略", "{\"od_redirect\":\"123\"}").
+		AddRow(2, "备用存储策略", "")
+	mock.ExpectQuery("^SELECT(.+)").WillReturnRows(rows)
+	policies, err := GetPolicies()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(policies, 2)
+	asserts.Equal("123", policies[0].OptionsSerialized.OdRedirect)
+}
+
 func TestPolicy_BeforeSave(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -277,3 +291,52 @@ func TestPolicy_UpdateAccessKeyAndClearCache(t *testing.T) {
 	_, ok := cache.Get("policy_1331")
 	a.False(ok)
 }
+
+func TestPolicy_GetVirusAction(t *testing.T) {
+	a := assert.New(t)
+	policy := Policy{}
+
+	// 使用站点设置
+	cache.Set("setting_clamav_action", VirusActionReject, 0)
+	a.Equal(VirusActionReject, policy.GetVirusAction())
+
+	// 无法识别的设置按隔离处理
+	cache.Set("setting_clamav_action", "unknown", 0)
+	a.Equal(VirusActionQuarantine, policy.GetVirusAction())
+
+	// 存储策略覆盖站点设置
+	policy.OptionsSerialized.VirusAction = VirusActionNone
+	a.Equal(VirusActionNone, policy.GetVirusAction())
+}
+
+func TestPolicy_MaxChunkConcurrency(t *testing.T) {
+	a := assert.New(t)
+	policy := Policy{Type: "local"}
+
+	// 未开启分片上传
+	policy.OptionsSerialized.ChunkConcurrency = 4
+	a.Equal(1, policy.MaxChunkConcurrency())
+
+	// 本机策略
+	policy.OptionsSerialized.ChunkSize = 10
+	a.Equal(4, policy.MaxChunkConcurrency())
+
+	// 只接受按顺序上传分片的策略
+	policy.Type = "onedrive"
+	a.Equal(1, policy.MaxChunkConcurrency())
+
+	// 未设置
+	policy.Type = "s3"
+	policy.OptionsSerialized.ChunkConcurrency = 0
+	a.Equal(1, policy.MaxChunkConcurrency())
+}
+
+func TestPolicyUploadSuspended(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.False(IsPolicyUploadSuspended(10))
+	SetPolicyUploadSuspended(10, true)
+	asserts.True(IsPolicyUploadSuspended(10))
+	SetPolicyUploadSuspended(10, false)
+	asserts.False(IsPolicyUploadSuspended(10))
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"math/big"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// s3AccessKeyCharset 访问密钥ID使用的字符，与 AWS 一致只包含大写字母及数字
+const s3AccessKeyCharset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// S3AccessKey S3 兼容接口的访问密钥，每个密钥对应一个存储桶
+type S3AccessKey struct {
+	gorm.Model
+	Name       string     // 密钥名称
+	UserID     uint       `gorm:"index"`        // 用户ID
+	AccessKey  string     `gorm:"unique_index"` // 访问密钥ID
+	Secret     string     `json:"-"`            // 签名密钥，校验 SigV4 签名需要明文
+	Bucket     string     // 存储桶名称
+	Root       string     `gorm:"type:text"` // 存储桶对应的目录
+	Readonly   bool       // 只读，不允许修改文件
+	LastUsedAt *time.Time // 最后使用时间
+	LastIP     string     // 最后使用的 IP
+}
+
+// newS3AccessKeyID 生成以 CR 开头的 20 位访问密钥ID
+func newS3AccessKeyID() (string, error) {
+	id := make([]byte, 20)
+	id[0], id[1] = 'C', 'R'
+	max := big.NewInt(int64(len(s3AccessKeyCharset)))
+	for i := 2; i < len(id); i++ {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		id[i] = s3AccessKeyCharset[n.Int64()]
+	}
+	return string(id), nil
+}
+
+// Create 生成访问密钥并创建记录，返回的签名密钥只在创建时可见
+func (key *S3AccessKey) Create() (string, error) {
+	id, err := newS3AccessKeyID()
+	if err != nil {
+		return "", err
+	}
+
+	secret := make([]byte, 30)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+
+	key.AccessKey = id
+	key.Secret = base64.RawURLEncoding.EncodeToString(secret)
+	if err := DB.Create(key).Error; err != nil {
+		return "", err
+	}
+	return key.Secret, nil
+}
+
+// Touch 记录密钥的使用时间及 IP，五分钟内重复使用且 IP 不变时不更新
+func (key *S3AccessKey) Touch(ip string) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < 5*time.Minute && key.LastIP == ip {
+		return
+	}
+	key.LastUsedAt = &now
+	key.LastIP = ip
+	DB.Model(key).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_ip": ip})
+}
+
+// GetS3AccessKey 根据访问密钥ID查找密钥
+func GetS3AccessKey(accessKey string) (*S3AccessKey, error) {
+	key := &S3AccessKey{}
+	err := DB.Where("access_key = ?", accessKey).First(key).Error
+	return key, err
+}
+
+// ListS3AccessKeys 列出用户的所有访问密钥
+func ListS3AccessKeys(uid uint) []S3AccessKey {
+	var keys []S3AccessKey
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&keys)
+	return keys
+}
+
+// DeleteS3AccessKeyByID 根据密钥ID和UID删除访问密钥
+func DeleteS3AccessKeyByID(id, uid uint) {
+	DB.Where("user_id = ? and id = ?", uid, id).Delete(&S3AccessKey{})
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS3AccessKey_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)s3_access_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		key := &S3AccessKey{UserID: 1, Bucket: "backup", Root: "/"}
+		secret, err := key.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Regexp(regexp.MustCompile("^CR[A-Z0-9]{18}$"), key.AccessKey)
+		asserts.Len(secret, 40)
+		asserts.Equal(secret, key.Secret)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		key := &S3AccessKey{}
+		secret, err := key.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Empty(secret)
+	}
+}
+
+func TestS3AccessKey_Touch(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 首次使用
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)s3_access_keys(.+)").WithArgs("1.1.1.1", sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		key := &S3AccessKey{}
+		key.ID = 1
+		key.Touch("1.1.1.1")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(key.LastUsedAt)
+		asserts.Equal("1.1.1.1", key.LastIP)
+
+		// 短时间内重复使用
+		key.Touch("1.1.1.1")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// IP 变化
+	{
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)s3_access_keys(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		key := &S3AccessKey{LastUsedAt: &now, LastIP: "1.1.1.1"}
+		key.ID = 1
+		key.Touch("2.2.2.2")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("2.2.2.2", key.LastIP)
+	}
+}
+
+func TestGetS3AccessKey(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)s3_access_keys(.+)").WithArgs("CRKEY").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "access_key", "bucket"}).AddRow(1, "CRKEY", "backup"))
+	key, err := GetS3AccessKey("CRKEY")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal("backup", key.Bucket)
+}
+
+func TestListS3AccessKeys(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)s3_access_keys(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	res := ListS3AccessKeys(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestDeleteS3AccessKeyByID(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)s3_access_keys(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	DeleteS3AccessKeyByID(1, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
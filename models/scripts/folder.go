@@ -0,0 +1,20 @@
+package scripts
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+type FolderStatsCalibration int
+
+// Run 运行脚本校准所有目录的累计大小及子项数量
+func (script FolderStatsCalibration) Run(ctx context.Context) {
+	repaired, err := model.RepairAllFolderStats()
+	if err != nil {
+		util.Log().Warning("无法校准目录统计, %s", err)
+	}
+
+	util.Log().Info("已校准 %d 个目录的统计", repaired)
+}
@@ -0,0 +1,30 @@
+package scripts
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolderStatsCalibration_Run(t *testing.T) {
+	asserts := assert.New(t)
+	script := FolderStatsCalibration(0)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "size", "file_num", "folder_num"}).AddRow(1, nil, 0, 0, 0))
+	mock.ExpectQuery("SELECT(.+)files(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"folder_id", "size", "num"}).AddRow(1, 10, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)").
+		WithArgs(1, 0, 10, 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	script.Run(context.Background())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -1,10 +1,14 @@
 package model
 
 import (
+	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
 )
 
@@ -108,3 +112,69 @@ func GetIntSetting(key string, defaultVal int) int {
 	}
 	return res
 }
+
+// SettingChangeHandler 设置项变更后的回调，参数为发生变更且已订阅的设置项
+type SettingChangeHandler func(changed []string)
+
+type settingSubscriber struct {
+	keys    []string
+	handler SettingChangeHandler
+}
+
+var (
+	settingSubscribersLock sync.RWMutex
+	settingSubscribers     []settingSubscriber
+
+	// 依次处理设置变更通知，避免组件被并发重新初始化
+	settingNotifyLock sync.Mutex
+)
+
+// OnSettingChange 订阅设置项变更，以便启动时读取设置的组件在设置变更后重新初始化。
+// keys 中以 * 结尾的项按前缀匹配
+func OnSettingChange(handler SettingChangeHandler, keys ...string) {
+	settingSubscribersLock.Lock()
+	defer settingSubscribersLock.Unlock()
+	settingSubscribers = append(settingSubscribers, settingSubscriber{keys: keys, handler: handler})
+}
+
+// NotifySettingChange 清除设置项缓存，并通知订阅了这些设置项的组件
+func NotifySettingChange(names ...string) {
+	_ = cache.Deletes(names, "setting_")
+
+	settingSubscribersLock.RLock()
+	subscribers := settingSubscribers
+	settingSubscribersLock.RUnlock()
+
+	settingNotifyLock.Lock()
+	defer settingNotifyLock.Unlock()
+	for _, subscriber := range subscribers {
+		if changed := subscriber.match(names); len(changed) > 0 {
+			subscriber.call(changed)
+		}
+	}
+}
+
+// match 返回已订阅的设置项
+func (subscriber *settingSubscriber) match(names []string) []string {
+	var matched []string
+	for _, name := range names {
+		for _, key := range subscriber.keys {
+			if name == key || (strings.HasSuffix(key, "*") && strings.HasPrefix(name, strings.TrimSuffix(key, "*"))) {
+				matched = append(matched, name)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// call 调用回调，回调出错不影响其他组件
+func (subscriber *settingSubscriber) call(changed []string) {
+	defer func() {
+		if err := recover(); err != nil {
+			util.Log().Warning("设置项 %s 变更后无法重新初始化组件，%s", strings.Join(changed, ","), fmt.Sprint(err))
+		}
+	}()
+
+	subscriber.handler(changed)
+}
@@ -194,3 +194,30 @@ func TestGetIntSetting(t *testing.T) {
 	}
 
 }
+
+func TestNotifySettingChange(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_test_notify_a", "1", 0)
+
+	var received [][]string
+	OnSettingChange(func(changed []string) {
+		received = append(received, changed)
+	}, "test_notify_a", "test_notify_prefix_*")
+	OnSettingChange(func(changed []string) {
+		panic("error")
+	}, "test_notify_a")
+
+	// 未订阅的设置项
+	NotifySettingChange("test_notify_b")
+	asserts.Empty(received)
+
+	// 精确及前缀匹配，回调出错不影响其他订阅
+	asserts.NotPanics(func() {
+		NotifySettingChange("test_notify_a", "test_notify_prefix_1", "test_notify_b")
+	})
+	asserts.Equal([][]string{{"test_notify_a", "test_notify_prefix_1"}}, received)
+
+	// 清除缓存
+	_, ok := cache.Get("setting_test_notify_a")
+	asserts.False(ok)
+}
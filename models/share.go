@@ -1,8 +1,10 @@
 package model
 
 import (
+	"crypto/subtle"
 	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
 
@@ -13,19 +15,48 @@ import (
 	"github.com/jinzhu/gorm"
 )
 
+// 分享失效后的处理方式
+const (
+	// ShareExpireKeep 仅使分享失效
+	ShareExpireKeep = iota
+	// ShareExpireUnshare 删除分享记录
+	ShareExpireUnshare
+	// ShareExpireDeleteSource 删除分享记录及源文件
+	ShareExpireDeleteSource
+)
+
 // Share 分享模型
 type Share struct {
 	gorm.Model
-	Password        string     // 分享密码，空值为非加密分享
-	IsDir           bool       // 原始资源是否为目录
-	UserID          uint       // 创建用户ID
-	SourceID        uint       // 原始资源ID
-	Views           int        // 浏览数
-	Downloads       int        // 下载数
-	RemainDownloads int        // 剩余下载配额，负值标识无限制
-	Expires         *time.Time // 过期时间，空值表示无过期时间
-	PreviewEnabled  bool       // 是否允许直接预览
-	SourceName      string     `gorm:"index:source"` // 用于搜索的字段
+	Password         string     // 分享密码，空值为非加密分享
+	IsDir            bool       // 原始资源是否为目录
+	UserID           uint       // 创建用户ID
+	SourceID         uint       // 原始资源ID
+	Views            int        // 浏览数
+	Downloads        int        // 下载数
+	RemainDownloads  int        // 剩余下载配额，负值标识无限制
+	Expires          *time.Time // 过期时间，空值表示无过期时间
+	PreviewEnabled   bool       // 是否允许直接预览
+	DownloadDisabled bool       // 是否禁止下载，仅允许预览
+	SourceName       string     `gorm:"index:source"` // 用于搜索的字段
+	AllowUpload      bool       // 是否允许访客上传文件，仅目录分享有效
+	UploadSizeLimit  uint64     // 访客上传总大小限制，0 表示无限制
+	UploadCountLimit int        // 访客上传文件数量限制，0 表示无限制
+	UploadedSize     uint64     // 访客已上传总大小
+	UploadedCount    int        // 访客已上传文件数量
+	ExpireAction     int        // 下载次数用尽或过期后的处理方式
+	Slug             *string    `gorm:"unique_index:share_slug"` // 自定义分享链接，空值表示使用随机Key
+	Internal         bool       // 是否为仅指定用户/用户组可见的内部分享
+	TrafficLimit     uint64     // 下载流量上限，0 表示无限制
+	TrafficUsed      uint64     // 已使用的下载流量
+	Watermark        bool       // 是否为预览图像添加水印
+	WatermarkText    string     // 自定义水印文字，为空时使用访客IP与访问时间
+	Bundle           bool       // 是否为包含多个文件/目录的合集分享
+	Secret           string     `gorm:"type:varchar(16)"` // 附加在分享标识后的随机密钥，防止遍历，为空时仅凭HashID访问
+	Description      string     `gorm:"type:text"`        // 分享者填写的描述，展示在分享页面
+	AllowedIPs       string     `gorm:"type:text"`        // 允许访问的 CIDR 网段，以逗号分隔，为空表示不限制
+	AllowedCountries string     // 允许访问的国家/地区代码，以逗号分隔，为空表示不限制
+	ModerationStatus string     `gorm:"size:16"` // 内容审核状态，为空表示正常，review 为待复核，block 为已屏蔽
 
 	// 数据库忽略字段
 	User   User   `gorm:"PRELOAD:false,association_autoupdate:false"`
@@ -33,6 +64,22 @@ type Share struct {
 	Folder Folder `gorm:"PRELOAD:false,association_autoupdate:false"`
 }
 
+var (
+	// ErrShareSlugInvalid 自定义链接格式不正确
+	ErrShareSlugInvalid = errors.New("slug must be 3-64 characters of letters, digits, '-' or '_'")
+	// ErrShareSlugReserved 自定义链接为保留字
+	ErrShareSlugReserved = errors.New("slug is reserved")
+
+	shareSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{2,63}$`)
+
+	// reservedShareSlugs 不可用作自定义链接的保留字
+	reservedShareSlugs = map[string]bool{
+		"admin": true, "api": true, "dav": true, "home": true, "list": true, "login": true,
+		"search": true, "setting": true, "share": true, "static": true, "info": true,
+		"download": true, "preview": true, "upload": true,
+	}
+)
+
 // Create 创建分享
 func (share *Share) Create() (uint, error) {
 	if err := DB.Create(share).Error; err != nil {
@@ -42,35 +89,104 @@ func (share *Share) Create() (uint, error) {
 	return share.ID, nil
 }
 
-// GetShareByHashID 根据HashID查找分享
+// GetShareByHashID 根据分享标识查找分享，标识由HashID及可选的随机密钥组成，
+// 无法解码时按自定义链接查找
 func GetShareByHashID(hashID string) *Share {
+	var (
+		share  Share
+		result *gorm.DB
+	)
+
+	// 分离随机密钥
+	secret := ""
+	if i := strings.IndexByte(hashID, '.'); i >= 0 {
+		hashID, secret = hashID[:i], hashID[i+1:]
+	}
+
 	id, err := hashid.DecodeHashID(hashID, hashid.ShareID)
 	if err != nil {
-		return nil
+		if secret != "" {
+			return nil
+		}
+		result = DB.Where("slug = ?", strings.ToLower(hashID)).First(&share)
+	} else {
+		result = DB.First(&share, id)
 	}
-	var share Share
-	result := DB.First(&share, id)
+
 	if result.Error != nil {
 		return nil
 	}
 
+	// 带有密钥的分享须提供正确的密钥，自定义链接除外
+	if err == nil && subtle.ConstantTimeCompare([]byte(share.Secret), []byte(secret)) != 1 {
+		return nil
+	}
+
 	return &share
 }
 
+// ValidateShareSlug 检查自定义链接是否合法，返回规范化后的链接
+func ValidateShareSlug(slug string) (string, error) {
+	slug = strings.ToLower(slug)
+	if !shareSlugPattern.MatchString(slug) {
+		return "", ErrShareSlugInvalid
+	}
+
+	// 保留字以及可被解码为分享ID的字符串均不可用
+	if _, err := hashid.DecodeHashID(slug, hashid.ShareID); err == nil || reservedShareSlugs[slug] {
+		return "", ErrShareSlugReserved
+	}
+
+	return slug, nil
+}
+
+// IsShareSlugExisted 返回自定义链接是否已被使用
+func IsShareSlugExisted(slug string) bool {
+	var count int
+	DB.Model(&Share{}).Where("slug = ?", slug).Count(&count)
+	return count > 0
+}
+
+// HashID 返回分享的标识，带有随机密钥时以 . 分隔附加在HashID之后
+func (share *Share) HashID() string {
+	key := hashid.HashID(share.ID, hashid.ShareID)
+	if share.Secret != "" {
+		key += "." + share.Secret
+	}
+	return key
+}
+
+// Key 返回分享链接中使用的标识
+func (share *Share) Key() string {
+	if share.Slug != nil {
+		return *share.Slug
+	}
+	return share.HashID()
+}
+
 // IsAvailable 返回此分享是否可用（是否过期）
 func (share *Share) IsAvailable() bool {
 	if share.RemainDownloads == 0 {
 		return false
 	}
+	if share.ModerationStatus == ModerationBlock {
+		return false
+	}
 	if share.Expires != nil && time.Now().After(*share.Expires) {
 		return false
 	}
 
-	// 检查创建者状态
-	if share.Creator().Status != Active {
+	// 检查创建者状态，申请注销的用户分享一并失效
+	if creator := share.Creator(); creator.Status != Active || creator.DeleteAfter != nil {
 		return false
 	}
 
+	// 合集分享中至少要有一个源对象存在
+	if share.Bundle {
+		files, folders := share.BundleSources()
+		return len(files)+len(folders) > 0
+	}
+
 	// 检查源对象是否存在
 	var sourceID uint
 	if share.IsDir {
@@ -135,9 +251,87 @@ func (share *Share) CanBeDownloadBy(user *User) error {
 		}
 		return errors.New("您当前的用户组无权下载")
 	}
+
+	// 内部分享由接收者的角色决定
+	if share.Internal && !share.Can(user, ShareRoleDownload) {
+		return errors.New("您无权下载此分享")
+	}
+	return nil
+}
+
+// CanBeMountedBy 返回此分享能否被给定用户挂载为只读的 WebDAV 目录，
+// 设有下载次数、流量、水印或访问来源限制的分享无法通过 WebDAV 计量，不可挂载
+func (share *Share) CanBeMountedBy(user *User) error {
+	if !share.IsDir || share.Bundle {
+		return errors.New("只能挂载目录分享")
+	}
+	if !share.IsAvailable() {
+		return errors.New("分享已失效")
+	}
+
+	// 挂载自己的分享不受限制
+	if share.UserID == user.ID {
+		return nil
+	}
+
+	if share.Password != "" {
+		return errors.New("无法挂载加密分享")
+	}
+	if share.DownloadDisabled || share.Watermark || share.RemainDownloads >= 0 || share.TrafficLimit > 0 ||
+		share.AllowedIPs != "" || share.AllowedCountries != "" {
+		return errors.New("此分享设有访问限制，无法挂载")
+	}
+	return share.CanBeDownloadBy(user)
+}
+
+// CanBeUploadedBy 返回此分享是否可以被给定用户上传文件
+func (share *Share) CanBeUploadedBy(user *User) error {
+	if !share.IsDir {
+		return errors.New("此分享不允许上传文件")
+	}
+
+	// 内部分享由接收者的角色决定
+	if share.Internal {
+		if share.Can(user, ShareRoleUpload) {
+			return nil
+		}
+		return errors.New("您无权向此分享上传文件")
+	}
+
+	if !share.AllowUpload {
+		return errors.New("此分享不允许上传文件")
+	}
+
+	if user.IsAnonymous() && !IsTrueVal(GetSettingByName("share_anonymous_upload")) {
+		return errors.New("未登录用户无法上传")
+	}
+
 	return nil
 }
 
+// CheckUploadQuota 检查访客上传给定大小的文件后是否超出分享限制
+func (share *Share) CheckUploadQuota(size uint64) bool {
+	if share.UploadCountLimit > 0 && share.UploadedCount+1 > share.UploadCountLimit {
+		return false
+	}
+
+	if share.UploadSizeLimit > 0 && share.UploadedSize+size > share.UploadSizeLimit {
+		return false
+	}
+
+	return true
+}
+
+// Uploaded 增加访客上传的文件数量与大小
+func (share *Share) Uploaded(size uint64) error {
+	share.UploadedCount++
+	share.UploadedSize += size
+	return DB.Model(share).UpdateColumns(map[string]interface{}{
+		"uploaded_count": gorm.Expr("uploaded_count + ?", 1),
+		"uploaded_size":  gorm.Expr("uploaded_size + ?", size),
+	}).Error
+}
+
 // WasDownloadedBy 返回分享是否已被用户下载过
 func (share *Share) WasDownloadedBy(user *User, c *gin.Context) (exist bool) {
 	if user.IsAnonymous() {
@@ -163,6 +357,22 @@ func (share *Share) DownloadBy(user *User, c *gin.Context) error {
 	return nil
 }
 
+// TrafficExhausted 返回分享的下载流量是否已用尽
+func (share *Share) TrafficExhausted() bool {
+	return share.TrafficLimit > 0 && share.TrafficUsed >= share.TrafficLimit
+}
+
+// CheckTraffic 返回下载给定大小的文件后是否仍在流量上限内
+func (share *Share) CheckTraffic(size uint64) bool {
+	return share.TrafficLimit == 0 || share.TrafficUsed+size <= share.TrafficLimit
+}
+
+// ConsumeTraffic 增加已使用的下载流量
+func (share *Share) ConsumeTraffic(size uint64) error {
+	share.TrafficUsed += size
+	return DB.Model(share).UpdateColumn("traffic_used", gorm.Expr("traffic_used + ?", size)).Error
+}
+
 // Viewed 增加访问次数
 func (share *Share) Viewed() {
 	share.Views++
@@ -196,6 +406,27 @@ func DeleteShareBySourceIDs(sources []uint, isDir bool) error {
 	return DB.Where("source_id in (?) and is_dir = ?", sources, isDir).Delete(&Share{}).Error
 }
 
+// GetExpiredShares 获取已失效且需要自动处理的分享，
+// 下载次数用尽的分享需在最后一次下载后经过 grace 秒才会被返回，以免中断正在进行的下载
+func GetExpiredShares(grace int) ([]Share, error) {
+	var shares []Share
+	now := time.Now()
+	result := DB.Where("expire_action <> ?", ShareExpireKeep).
+		Where("(remain_downloads = 0 and updated_at < ?) or (expires is not NULL and expires < ?)",
+			now.Add(-time.Duration(grace)*time.Second), now).
+		Find(&shares)
+	return shares, result.Error
+}
+
+// ListSharesBySources 列出用户以给定目录、文件自身为源的分享
+func ListSharesBySources(uid uint, dirs, files []uint) ([]Share, error) {
+	var shares []Share
+	err := DB.Where("user_id = ?", uid).
+		Where("(is_dir = ? and source_id in (?)) or (is_dir = ? and source_id in (?))", true, dirs, false, files).
+		Order("id desc").Find(&shares).Error
+	return shares, err
+}
+
 // ListShares 列出UID下的分享
 func ListShares(uid uint, page, pageSize int, order string, publicOnly bool) ([]Share, int) {
 	var (
@@ -205,7 +436,7 @@ func ListShares(uid uint, page, pageSize int, order string, publicOnly bool) ([]
 	dbChain := DB
 	dbChain = dbChain.Where("user_id = ?", uid)
 	if publicOnly {
-		dbChain = dbChain.Where("password = ?", "")
+		dbChain = dbChain.Where("password = ? and internal = ?", "", false)
 	}
 
 	// 计算总数用于分页
@@ -235,7 +466,7 @@ func SearchShares(page, pageSize int, order, keywords string) ([]Share, int) {
 	}
 
 	dbChain := DB
-	dbChain = dbChain.Where("password = ? and remain_downloads <> 0 and (expires is NULL or expires > ?) and source_name like ?", "", time.Now(), "%"+strings.Join(availableList, "%")+"%")
+	dbChain = dbChain.Where("password = ? and internal = ? and remain_downloads <> 0 and (expires is NULL or expires > ?) and source_name "+likeOperator(DB)+" ?", "", false, time.Now(), "%"+strings.Join(availableList, "%")+"%")
 
 	// 计算总数用于分页
 	dbChain.Model(&Share{}).Count(&total)
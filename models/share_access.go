@@ -0,0 +1,143 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// 分享访问事件类型
+const (
+	ShareAccessView = iota
+	ShareAccessDownload
+)
+
+// ShareAccessLog 分享访问记录
+type ShareAccessLog struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	CreatedAt time.Time `gorm:"index:created_at" json:"time"`
+	ShareID   uint      `gorm:"index:share_id" json:"-"`
+	Type      int       `json:"type"`
+	IP        string    `json:"ip"`
+	Country   string    `json:"country"`
+	Referrer  string    `gorm:"type:text" json:"referrer"`
+	FileName  string    `gorm:"type:text" json:"file"`
+}
+
+// ShareAccessStat 分享访问统计条目
+type ShareAccessStat struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// Create 创建访问记录
+func (log *ShareAccessLog) Create() error {
+	if err := DB.Create(log).Error; err != nil {
+		util.Log().Warning("无法插入分享访问记录, %s", err)
+		return err
+	}
+	return nil
+}
+
+// fromTrustedProxy 请求是否由受信任的反向代理转发，经 Unix socket 监听时对端均为本机的反向代理
+func fromTrustedProxy(c *gin.Context) bool {
+	if conf.UnixConfig.Listen != "" {
+		return true
+	}
+	_, trusted := c.RemoteIP()
+	return trusted
+}
+
+// ClientCountry 返回 Cloudflare 提供的访客国家/地区代码，无法识别时为空。
+// 请求头可由客户端任意设置，仅在设定了站点位于 Cloudflare 之后，且请求由受信任的反向代理转发时信任
+func ClientCountry(c *gin.Context) string {
+	if !IsTrueVal(GetSettingByName("cloudflare_proxy")) || !fromTrustedProxy(c) {
+		return ""
+	}
+	return strings.ToUpper(strings.TrimSpace(c.GetHeader("CF-IPCountry")))
+}
+
+// RecordAccess 记录一次分享访问事件
+func (share *Share) RecordAccess(c *gin.Context, accessType int, fileName string) {
+	if !IsTrueVal(GetSettingByName("share_access_log")) {
+		return
+	}
+
+	log := &ShareAccessLog{
+		ShareID:  share.ID,
+		Type:     accessType,
+		IP:       c.ClientIP(),
+		Country:  ClientCountry(c),
+		Referrer: c.Request.Referer(),
+		FileName: fileName,
+	}
+	log.Create()
+}
+
+// ShareDownloads 分享的下载次数
+type ShareDownloads struct {
+	ID        uint   `json:"id"`
+	Name      string `json:"name"`
+	Downloads int    `json:"downloads"`
+}
+
+// ListShareAccessLogs 分页列出分享的访问记录
+func ListShareAccessLogs(shareID uint, page, pageSize int) ([]ShareAccessLog, int) {
+	var (
+		logs  []ShareAccessLog
+		total int
+	)
+
+	dbChain := DB.Model(&ShareAccessLog{}).Where("share_id = ?", shareID)
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&logs)
+	return logs, total
+}
+
+// CountShareAccess 按事件类型统计分享的访问次数
+func CountShareAccess(shareID uint) map[int]int {
+	var rows []struct {
+		Type  int
+		Count int
+	}
+	DB.Model(&ShareAccessLog{}).Select("type, count(*) as count").
+		Where("share_id = ?", shareID).Group("type").Scan(&rows)
+
+	res := make(map[int]int, len(rows))
+	for _, row := range rows {
+		res[row.Type] = row.Count
+	}
+	return res
+}
+
+// TopShareAccess 按给定字段统计访问次数最多的前 limit 项，field 只能为 referrer 或 country
+func TopShareAccess(shareID uint, field string, limit int) []ShareAccessStat {
+	var res []ShareAccessStat
+	DB.Model(&ShareAccessLog{}).Select(field+" as name, count(*) as count").
+		Where("share_id = ? and "+field+" <> ?", shareID, "").
+		Group(field).Order("count desc").Limit(limit).Scan(&res)
+	return res
+}
+
+// DeleteShareAccessLogsBefore 删除给定时间之前的访问记录
+func DeleteShareAccessLogsBefore(before time.Time) (int64, error) {
+	result := DB.Where("created_at < ?", before).Delete(&ShareAccessLog{})
+	return result.RowsAffected, result.Error
+}
+
+// TopDownloadedShares 根据访问记录列出 [from, to) 期间下载次数最多的 limit 个分享
+func TopDownloadedShares(from, to time.Time, limit int) ([]ShareDownloads, error) {
+	var res []ShareDownloads
+	err := DB.Table(DB.NewScope(&ShareAccessLog{}).TableName()+" l").
+		Select("l.share_id as id, s.source_name as name, count(l.id) as downloads").
+		Joins("inner join "+DB.NewScope(&Share{}).TableName()+" s on s.id = l.share_id").
+		Where("l.type = ? and l.created_at >= ? and l.created_at < ?", ShareAccessDownload, from, to).
+		Group("l.share_id, s.source_name").
+		Order("downloads desc").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
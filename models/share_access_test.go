@@ -0,0 +1,130 @@
+package model
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_RecordAccess(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.Header.Set("Referer", "https://example.com/")
+
+	// 未开启
+	{
+		cache.Set("setting_share_access_log", "0", 0)
+		share.RecordAccess(c, ShareAccessView, "")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 开启
+	{
+		cache.Set("setting_share_access_log", "1", 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(1), ShareAccessDownload, sqlmock.AnyArg(), "", "https://example.com/", "a.txt").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		share.RecordAccess(c, ShareAccessDownload, "a.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCountShareAccess(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)GROUP BY(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).AddRow(ShareAccessView, 3).AddRow(ShareAccessDownload, 1))
+	res := CountShareAccess(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(3, res[ShareAccessView])
+	asserts.Equal(1, res[ShareAccessDownload])
+}
+
+func TestTopShareAccess(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT referrer as name(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "count"}).AddRow("https://example.com/", 2))
+	res := TopShareAccess(1, "referrer", 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 1)
+	asserts.Equal(2, res[0].Count)
+}
+
+func TestTopDownloadedShares(t *testing.T) {
+	asserts := assert.New(t)
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 7)
+
+	mock.ExpectQuery("SELECT(.+)share_access_logs l inner join shares s(.+)GROUP BY l.share_id, s.source_name(.+)LIMIT 5").
+		WithArgs(ShareAccessDownload, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "downloads"}).AddRow(1, "a.txt", 4))
+	res, err := TopDownloadedShares(from, to, 5)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal([]ShareDownloads{{ID: 1, Name: "a.txt", Downloads: 4}}, res)
+}
+
+func TestDeleteShareAccessLogsBefore(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	deleted, err := DeleteShareAccessLogsBefore(time.Now())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, deleted)
+}
+
+func TestShare_IsAccessibleFrom(t *testing.T) {
+	asserts := assert.New(t)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "10.1.2.3:1234"
+	cache.Set("setting_share_allowed_ips", "", 0)
+	cache.Set("setting_share_allowed_countries", "", 0)
+	cache.Set("setting_cloudflare_proxy", "1", 0)
+	defer cache.Deletes([]string{"cloudflare_proxy"}, "setting_")
+
+	// 无限制
+	asserts.True((&Share{}).IsAccessibleFrom(c))
+
+	// IP 限制
+	asserts.True((&Share{AllowedIPs: "192.168.0.0/16,10.0.0.0/8"}).IsAccessibleFrom(c))
+	asserts.False((&Share{AllowedIPs: "192.168.0.0/16"}).IsAccessibleFrom(c))
+	asserts.False((&Share{AllowedIPs: "invalid"}).IsAccessibleFrom(c))
+
+	// 国家/地区限制，无法识别时拒绝
+	asserts.False((&Share{AllowedCountries: "CN"}).IsAccessibleFrom(c))
+	c.Request.Header.Set("CF-IPCountry", "cn")
+	asserts.True((&Share{AllowedCountries: "cn, jp"}).IsAccessibleFrom(c))
+	asserts.False((&Share{AllowedCountries: "US"}).IsAccessibleFrom(c))
+
+	// 未设定位于 Cloudflare 之后时不信任请求头
+	cache.Set("setting_cloudflare_proxy", "0", 0)
+	asserts.Equal("", ClientCountry(c))
+	asserts.False((&Share{AllowedCountries: "CN"}).IsAccessibleFrom(c))
+	cache.Set("setting_cloudflare_proxy", "1", 0)
+
+	// 全局限制
+	cache.Set("setting_share_allowed_countries", "US", 0)
+	asserts.False((&Share{}).IsAccessibleFrom(c))
+	cache.Set("setting_share_allowed_countries", "", 0)
+	// 不受信任的对端伪造请求头
+	c, r := gin.CreateTestContext(httptest.NewRecorder())
+	r.SetTrustedProxies(nil)
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	c.Request.RemoteAddr = "203.0.113.1:1234"
+	c.Request.Header.Set("X-Forwarded-For", "10.1.2.3")
+	c.Request.Header.Set("CF-IPCountry", "cn")
+	asserts.False((&Share{AllowedIPs: "10.0.0.0/8"}).IsAccessibleFrom(c))
+	asserts.False((&Share{AllowedCountries: "CN"}).IsAccessibleFrom(c))
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"path"
+	"strings"
+)
+
+// ShareBundleItem 合集分享中包含的文件或目录
+type ShareBundleItem struct {
+	ID       uint `gorm:"primary_key"`
+	ShareID  uint `gorm:"index:share_id"`
+	IsDir    bool
+	SourceID uint
+}
+
+// SetBundleItems 设置合集分享包含的文件和目录
+func (share *Share) SetBundleItems(files, folders []uint) error {
+	tx := DB.Begin()
+	if err := tx.Where("share_id = ?", share.ID).Delete(&ShareBundleItem{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	items := make([]ShareBundleItem, 0, len(files)+len(folders))
+	for _, id := range folders {
+		items = append(items, ShareBundleItem{ShareID: share.ID, IsDir: true, SourceID: id})
+	}
+	for _, id := range files {
+		items = append(items, ShareBundleItem{ShareID: share.ID, SourceID: id})
+	}
+
+	for i := range items {
+		if err := tx.Create(&items[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// BundleSources 获取合集分享中仍存在的文件和目录
+func (share *Share) BundleSources() ([]File, []Folder) {
+	var items []ShareBundleItem
+	DB.Where("share_id = ?", share.ID).Find(&items)
+
+	fileIDs := make([]uint, 0, len(items))
+	folderIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		if item.IsDir {
+			folderIDs = append(folderIDs, item.SourceID)
+		} else {
+			fileIDs = append(fileIDs, item.SourceID)
+		}
+	}
+
+	var (
+		files   []File
+		folders []Folder
+	)
+	if len(fileIDs) > 0 {
+		files, _ = GetFilesByIDs(fileIDs, share.UserID)
+	}
+	if len(folderIDs) > 0 {
+		folders, _ = GetFoldersByIDs(folderIDs, share.UserID)
+	}
+	return files, folders
+}
+
+// ResolveBundlePath 解析合集分享中的路径，路径为顶层文件时返回该文件；
+// 否则返回路径所在的顶层目录及目录下的相对路径。均未找到时返回 nil
+func (share *Share) ResolveBundlePath(fullPath string) (*Folder, string, *File) {
+	fullPath = path.Clean("/" + fullPath)
+	segments := strings.SplitN(strings.TrimPrefix(fullPath, "/"), "/", 2)
+	files, folders := share.BundleSources()
+
+	if len(segments) == 1 {
+		for i := range files {
+			if files[i].Name == segments[0] {
+				return nil, "", &files[i]
+			}
+		}
+	}
+
+	for i := range folders {
+		if folders[i].Name == segments[0] {
+			rel := "/"
+			if len(segments) == 2 {
+				rel += segments[1]
+			}
+			return &folders[i], rel, nil
+		}
+	}
+
+	return nil, "", nil
+}
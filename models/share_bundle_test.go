@@ -0,0 +1,108 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_SetBundleItems(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}, Bundle: true}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)share_bundle_items(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT(.+)").WithArgs(1, true, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT(.+)").WithArgs(1, false, 2).WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	asserts.NoError(share.SetBundleItems([]uint{2}, []uint{3}))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestShare_ResolveBundlePath(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}, UserID: 1, Bundle: true}
+	expectSources := func() {
+		mock.ExpectQuery("SELECT(.+)share_bundle_items(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir", "source_id"}).
+				AddRow(1, true, 3).AddRow(2, false, 2))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "a.txt"))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "docs"))
+	}
+
+	// 顶层文件
+	{
+		expectSources()
+		folder, _, file := share.ResolveBundlePath("/a.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(folder)
+		asserts.EqualValues(2, file.ID)
+	}
+
+	// 顶层目录
+	{
+		expectSources()
+		folder, rel, file := share.ResolveBundlePath("/docs")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(file)
+		asserts.EqualValues(3, folder.ID)
+		asserts.Equal("/", rel)
+	}
+
+	// 目录下的路径
+	{
+		expectSources()
+		folder, rel, file := share.ResolveBundlePath("/docs/sub/b.txt")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(file)
+		asserts.EqualValues(3, folder.ID)
+		asserts.Equal("/sub/b.txt", rel)
+	}
+
+	// 不存在
+	{
+		expectSources()
+		folder, _, file := share.ResolveBundlePath("/a.txt/b")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(file)
+		asserts.Nil(folder)
+	}
+}
+
+func TestShare_IsAvailable_Bundle(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{
+		Model:           gorm.Model{ID: 1},
+		UserID:          1,
+		RemainDownloads: -1,
+		Bundle:          true,
+		User:            User{Model: gorm.Model{ID: 1}, Status: Active},
+	}
+
+	// 源对象均已删除
+	{
+		mock.ExpectQuery("SELECT(.+)share_bundle_items(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir", "source_id"}).AddRow(1, false, 2))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.False(share.IsAvailable())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 至少一个源对象存在
+	{
+		mock.ExpectQuery("SELECT(.+)share_bundle_items(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir", "source_id"}).
+				AddRow(1, false, 2).AddRow(2, true, 3))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+		asserts.True(share.IsAvailable())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
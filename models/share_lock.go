@@ -0,0 +1,130 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+)
+
+// ShareLockLog 分享密码尝试次数过多被锁定的记录
+type ShareLockLog struct {
+	gorm.Model
+	ShareID uint `gorm:"index:share_id"`
+	// 为空时表示整个分享被锁定
+	IP          string
+	Attempts    int
+	LockedUntil time.Time
+}
+
+// Create 创建锁定记录
+func (log *ShareLockLog) Create() error {
+	if err := DB.Create(log).Error; err != nil {
+		util.Log().Warning("无法插入分享锁定记录, %s", err)
+		return err
+	}
+	return nil
+}
+
+func shareAttemptKey(shareID uint, ip string) string {
+	return fmt.Sprintf("share_attempt_%d_%s", shareID, ip)
+}
+
+func shareLockKey(shareID uint, ip string) string {
+	return fmt.Sprintf("share_lock_%d_%s", shareID, ip)
+}
+
+func shareFailedKey(shareID uint) string {
+	return fmt.Sprintf("share_failed_%d", shareID)
+}
+
+func shareLockedKey(shareID uint) string {
+	return fmt.Sprintf("share_locked_%d", shareID)
+}
+
+// IsPasswordLocked 返回给定 IP 是否因密码尝试次数过多而被暂时禁止解锁此分享，
+// 所有 IP 的错误次数之和过多时整个分享被锁定
+func (share *Share) IsPasswordLocked(ip string) bool {
+	if _, locked := cache.Get(shareLockedKey(share.ID)); locked {
+		return true
+	}
+	_, locked := cache.Get(shareLockKey(share.ID, ip))
+	return locked
+}
+
+// getAttempts 返回计数 key 已记录的错误次数
+func getAttempts(key string) int {
+	if count, ok := cache.Get(key); ok {
+		if n, ok := count.(int); ok {
+			return n
+		}
+	}
+	return 0
+}
+
+// PasswordFailed 记录一次密码错误，超过阈值时锁定给定 IP 并返回 true
+func (share *Share) PasswordFailed(ip string) bool {
+	maxAttempts := GetIntSetting("share_password_max_attempts", 5)
+	if maxAttempts <= 0 {
+		return false
+	}
+
+	lockDuration := GetIntSetting("share_password_lock_duration", 900)
+	if share.shareFailed(lockDuration) {
+		return true
+	}
+
+	attempts := getAttempts(shareAttemptKey(share.ID, ip)) + 1
+	if attempts < maxAttempts {
+		cache.Set(shareAttemptKey(share.ID, ip), attempts, lockDuration)
+		return false
+	}
+
+	// 达到阈值，锁定此 IP
+	cache.Deletes([]string{fmt.Sprintf("%d_%s", share.ID, ip)}, "share_attempt_")
+	cache.Set(shareLockKey(share.ID, ip), true, lockDuration)
+	util.Log().Warning("IP [%s] 尝试分享 [%d] 的密码次数过多，已锁定 %d 秒", ip, share.ID, lockDuration)
+
+	log := &ShareLockLog{
+		ShareID:     share.ID,
+		IP:          ip,
+		Attempts:    attempts,
+		LockedUntil: time.Now().Add(time.Duration(lockDuration) * time.Second),
+	}
+	log.Create()
+	return true
+}
+
+// shareFailed 累计分享在所有 IP 下的错误次数，超过阈值时锁定整个分享并返回 true，
+// 以免通过更换 IP 绕过单个 IP 的限制
+func (share *Share) shareFailed(lockDuration int) bool {
+	maxAttempts := GetIntSetting("share_password_max_share_attempts", 50)
+	if maxAttempts <= 0 {
+		return false
+	}
+
+	attempts := getAttempts(shareFailedKey(share.ID)) + 1
+	if attempts < maxAttempts {
+		cache.Set(shareFailedKey(share.ID), attempts, lockDuration)
+		return false
+	}
+
+	cache.Deletes([]string{fmt.Sprintf("%d", share.ID)}, "share_failed_")
+	cache.Set(shareLockedKey(share.ID), true, lockDuration)
+	util.Log().Warning("分享 [%d] 的密码错误次数过多，已锁定 %d 秒", share.ID, lockDuration)
+
+	log := &ShareLockLog{
+		ShareID:     share.ID,
+		Attempts:    attempts,
+		LockedUntil: time.Now().Add(time.Duration(lockDuration) * time.Second),
+	}
+	log.Create()
+	return true
+}
+
+// PasswordSucceed 密码正确后清除给定 IP 的错误计数，分享的累计错误次数不清除
+func (share *Share) PasswordSucceed(ip string) {
+	cache.Deletes([]string{fmt.Sprintf("%d_%s", share.ID, ip)}, "share_attempt_")
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_PasswordFailed(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}}
+	cache.Set("setting_share_password_max_attempts", "3", 0)
+	cache.Set("setting_share_password_lock_duration", "60", 0)
+	cache.Set("setting_share_password_max_share_attempts", "100", 0)
+
+	// 未达到阈值
+	asserts.False(share.PasswordFailed("1.1.1.1"))
+	asserts.False(share.PasswordFailed("1.1.1.1"))
+	asserts.False(share.IsPasswordLocked("1.1.1.1"))
+
+	// 达到阈值，锁定
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.True(share.PasswordFailed("1.1.1.1"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.True(share.IsPasswordLocked("1.1.1.1"))
+
+	// 其他 IP 不受影响
+	asserts.False(share.IsPasswordLocked("2.2.2.2"))
+
+	// 成功后清除计数
+	asserts.False(share.PasswordFailed("2.2.2.2"))
+	share.PasswordSucceed("2.2.2.2")
+	_, ok := cache.Get("share_attempt_1_2.2.2.2")
+	asserts.False(ok)
+
+	// 关闭保护
+	cache.Set("setting_share_password_max_attempts", "0", 0)
+	asserts.False(share.PasswordFailed("3.3.3.3"))
+}
+
+func TestShare_PasswordFailed_Share(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 2}}
+	cache.Set("setting_share_password_max_attempts", "3", 0)
+	cache.Set("setting_share_password_lock_duration", "60", 0)
+	cache.Set("setting_share_password_max_share_attempts", "4", 0)
+
+	// 更换 IP 尝试
+	asserts.False(share.PasswordFailed("1.1.1.1"))
+	asserts.False(share.PasswordFailed("2.2.2.2"))
+	asserts.False(share.PasswordFailed("3.3.3.3"))
+	share.PasswordSucceed("3.3.3.3")
+	asserts.False(share.IsPasswordLocked("4.4.4.4"))
+
+	// 累计达到阈值，锁定整个分享
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.True(share.PasswordFailed("4.4.4.4"))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.True(share.IsPasswordLocked("5.5.5.5"))
+
+	// 其他分享不受影响
+	other := Share{Model: gorm.Model{ID: 3}}
+	asserts.False(other.IsPasswordLocked("5.5.5.5"))
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ShareMailLog 分享邮件发送记录
+type ShareMailLog struct {
+	ID        uint      `gorm:"primary_key" json:"-"`
+	CreatedAt time.Time `json:"time"`
+	ShareID   uint      `gorm:"index:share_id" json:"-"`
+	Email     string    `json:"email"`
+	Success   bool      `json:"success"`
+}
+
+// Create 创建邮件发送记录
+func (log *ShareMailLog) Create() error {
+	if err := DB.Create(log).Error; err != nil {
+		util.Log().Warning("无法插入分享邮件发送记录, %s", err)
+		return err
+	}
+	return nil
+}
+
+// ListShareMailLogs 分页列出分享的邮件发送记录
+func ListShareMailLogs(shareID uint, page, pageSize int) ([]ShareMailLog, int) {
+	var (
+		logs  []ShareMailLog
+		total int
+	)
+
+	dbChain := DB.Model(&ShareMailLog{}).Where("share_id = ?", shareID)
+	dbChain.Count(&total)
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order("id desc").Find(&logs)
+	return logs, total
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareMailLog_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		log := &ShareMailLog{ShareID: 1, Email: "a@example.com", Success: true}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").
+			WithArgs(sqlmock.AnyArg(), uint(1), "a@example.com", true).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(log.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, log.ID)
+	}
+
+	// 失败
+	{
+		log := &ShareMailLog{ShareID: 1, Email: "a@example.com"}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(log.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestListShareMailLogs(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)share_mail_logs(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT(.+)share_mail_logs(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "success"}).
+			AddRow(2, "b@example.com", false).
+			AddRow(1, "a@example.com", true))
+	logs, total := ListShareMailLogs(1, 1, 10)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(2, total)
+	asserts.Len(logs, 2)
+	asserts.Equal("b@example.com", logs[0].Email)
+	asserts.False(logs[0].Success)
+}
@@ -0,0 +1,175 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ShareRecipient 内部分享的接收者，UserID 与 GroupID 二选一
+type ShareRecipient struct {
+	gorm.Model
+	ShareID  uint `gorm:"index:share_id"`
+	UserID   uint `gorm:"index:recipient_user"`
+	GroupID  uint `gorm:"index:recipient_group"`
+	Writable bool // 接收者是否可向分享目录上传文件，仅对未设置 Roles 的旧记录有效
+	Roles    int  // 接收者的角色权限，由 ShareRole* 组合而成
+}
+
+// 内部分享接收者的角色权限
+const (
+	// ShareRoleView 浏览分享内容
+	ShareRoleView = 1 << iota
+	// ShareRoleDownload 下载文件
+	ShareRoleDownload
+	// ShareRoleUpload 向分享目录上传文件
+	ShareRoleUpload
+	// ShareRoleEdit 重命名对象、创建目录
+	ShareRoleEdit
+	// ShareRoleDelete 删除对象
+	ShareRoleDelete
+	// ShareRoleReshare 将分享转发给他人
+	ShareRoleReshare
+
+	// ShareRoleAll 全部权限
+	ShareRoleAll = ShareRoleView | ShareRoleDownload | ShareRoleUpload | ShareRoleEdit | ShareRoleDelete | ShareRoleReshare
+)
+
+// shareRoleNames 角色名称，顺序与权限位一致
+var shareRoleNames = []string{"view", "download", "upload", "edit", "delete", "reshare"}
+
+// ParseShareRoles 将角色名称转换为权限，浏览权限总是包含在内
+func ParseShareRoles(names []string) (int, error) {
+	roles := ShareRoleView
+	for _, name := range names {
+		found := false
+		for i, roleName := range shareRoleNames {
+			if name == roleName {
+				roles |= 1 << i
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown share role %q", name)
+		}
+	}
+
+	return roles, nil
+}
+
+// ShareRoleNames 返回权限包含的角色名称
+func ShareRoleNames(roles int) []string {
+	names := make([]string, 0, len(shareRoleNames))
+	for i, name := range shareRoleNames {
+		if roles&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Permissions 返回接收者的角色权限，未设置 Roles 的旧记录可浏览、下载，
+// 并由 Writable 决定能否上传
+func (recipient *ShareRecipient) Permissions() int {
+	if recipient.Roles != 0 {
+		return recipient.Roles | ShareRoleView
+	}
+
+	roles := ShareRoleView | ShareRoleDownload
+	if recipient.Writable {
+		roles |= ShareRoleUpload
+	}
+	return roles
+}
+
+// SetRecipients 设置内部分享的接收者
+func (share *Share) SetRecipients(recipients []ShareRecipient) error {
+	tx := DB.Begin()
+	if err := tx.Where("share_id = ?", share.ID).Delete(&ShareRecipient{}).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for i := range recipients {
+		recipients[i].ShareID = share.ID
+		if err := tx.Create(&recipients[i]).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// RecipientOf 返回给定用户在内部分享中对应的接收者记录，用户记录优先于用户组记录，
+// 不是接收者时返回 nil
+func (share *Share) RecipientOf(user *User) *ShareRecipient {
+	if user.IsAnonymous() {
+		return nil
+	}
+
+	var recipients []ShareRecipient
+	DB.Where("share_id = ? and (user_id = ? or group_id = ?)", share.ID, user.ID, user.GroupID).
+		Order("user_id desc").Find(&recipients)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	return &recipients[0]
+}
+
+// PermissionsOf 返回给定用户对分享拥有的权限。创建者拥有全部权限；
+// 公开分享的访客可浏览、下载，允许上传时可上传；内部分享由接收者的角色决定
+func (share *Share) PermissionsOf(user *User) int {
+	if share.UserID == user.ID {
+		return ShareRoleAll
+	}
+
+	if !share.Internal {
+		roles := ShareRoleView | ShareRoleDownload
+		if share.IsDir && share.AllowUpload {
+			roles |= ShareRoleUpload
+		}
+		return roles
+	}
+
+	if recipient := share.RecipientOf(user); recipient != nil {
+		return recipient.Permissions()
+	}
+
+	return 0
+}
+
+// Can 返回给定用户是否拥有分享的指定权限
+func (share *Share) Can(user *User, role int) bool {
+	return share.PermissionsOf(user)&role == role
+}
+
+// IsAccessibleBy 返回给定用户是否可以访问此分享，公开分享对所有人可见
+func (share *Share) IsAccessibleBy(user *User) bool {
+	if !share.Internal || share.UserID == user.ID {
+		return true
+	}
+
+	return share.RecipientOf(user) != nil
+}
+
+// ListReceivedShares 列出分享给指定用户及其用户组的内部分享
+func ListReceivedShares(user *User, page, pageSize int, order string) ([]Share, int) {
+	var (
+		shares []Share
+		total  int
+	)
+
+	recipients := DB.Model(&ShareRecipient{}).Select("share_id").
+		Where("user_id = ? or group_id = ?", user.ID, user.GroupID).QueryExpr()
+	dbChain := DB.Where("internal = ? and id in (?)", true, recipients)
+
+	// 计算总数用于分页
+	dbChain.Model(&Share{}).Count(&total)
+
+	// 查询记录
+	dbChain.Limit(pageSize).Offset((page - 1) * pageSize).Order(order).Find(&shares)
+	return shares, total
+}
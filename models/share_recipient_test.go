@@ -0,0 +1,158 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShare_SetRecipients(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)share_recipients(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+	recipients := []ShareRecipient{{UserID: 2}, {GroupID: 1, Writable: true}}
+	asserts.NoError(share.SetRecipients(recipients))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, recipients[1].ShareID)
+}
+
+func TestShare_IsAccessibleBy(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 2}, GroupID: 2}
+
+	// 公开分享
+	{
+		share := Share{}
+		asserts.True(share.IsAccessibleBy(NewAnonymousUser()))
+	}
+
+	// 内部分享，匿名用户
+	{
+		share := Share{Internal: true, UserID: 1}
+		asserts.False(share.IsAccessibleBy(&User{}))
+	}
+
+	// 内部分享，创建者
+	{
+		share := Share{Internal: true, UserID: 2}
+		asserts.True(share.IsAccessibleBy(user))
+	}
+
+	// 内部分享，接收者
+	{
+		share := Share{Model: gorm.Model{ID: 1}, Internal: true, UserID: 1}
+		mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+			WithArgs(1, 2, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+		asserts.True(share.IsAccessibleBy(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 内部分享，非接收者
+	{
+		share := Share{Model: gorm.Model{ID: 1}, Internal: true, UserID: 1}
+		mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.False(share.IsAccessibleBy(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestShare_CanBeUploadedBy_Internal(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 2}, GroupID: 2}
+	share := Share{Model: gorm.Model{ID: 1}, Internal: true, IsDir: true, UserID: 1}
+
+	// 只读接收者
+	mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "group_id", "writable"}).AddRow(1, 2, false))
+	asserts.Error(share.CanBeUploadedBy(user))
+
+	// 可写接收者
+	mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "writable"}).AddRow(1, 2, true))
+	asserts.NoError(share.CanBeUploadedBy(user))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListReceivedShares(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 2}, GroupID: 2}
+
+	mock.ExpectQuery("SELECT(.+)share_recipients(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT(.+)share_recipients(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "internal"}).AddRow(1, true))
+
+	res, total := ListReceivedShares(user, 1, 10, "id desc")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 1)
+	asserts.Equal(1, total)
+}
+
+func TestParseShareRoles(t *testing.T) {
+	asserts := assert.New(t)
+
+	roles, err := ParseShareRoles([]string{"download", "delete"})
+	asserts.NoError(err)
+	asserts.Equal(ShareRoleView|ShareRoleDownload|ShareRoleDelete, roles)
+	asserts.Equal([]string{"view", "download", "delete"}, ShareRoleNames(roles))
+
+	_, err = ParseShareRoles([]string{"admin"})
+	asserts.Error(err)
+}
+
+func TestShareRecipient_Permissions(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 旧记录
+	asserts.Equal(ShareRoleView|ShareRoleDownload, (&ShareRecipient{}).Permissions())
+	asserts.Equal(ShareRoleView|ShareRoleDownload|ShareRoleUpload, (&ShareRecipient{Writable: true}).Permissions())
+
+	// 设置了角色，总是可浏览
+	asserts.Equal(ShareRoleView|ShareRoleEdit, (&ShareRecipient{Roles: ShareRoleEdit}).Permissions())
+}
+
+func TestShare_PermissionsOf(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 2}, GroupID: 2}
+
+	// 创建者
+	{
+		share := Share{Internal: true, UserID: 2}
+		asserts.Equal(ShareRoleAll, share.PermissionsOf(user))
+	}
+
+	// 公开分享
+	{
+		share := Share{UserID: 1, IsDir: true, AllowUpload: true}
+		asserts.Equal(ShareRoleView|ShareRoleDownload|ShareRoleUpload, share.PermissionsOf(user))
+		asserts.False(share.Can(user, ShareRoleDelete))
+	}
+
+	// 内部分享，接收者
+	{
+		share := Share{Model: gorm.Model{ID: 1}, Internal: true, UserID: 1}
+		mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "roles"}).AddRow(1, 2, ShareRoleView|ShareRoleDelete))
+		asserts.True(share.Can(user, ShareRoleDelete))
+		mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "roles"}).AddRow(1, 2, ShareRoleView|ShareRoleDelete))
+		asserts.False(share.Can(user, ShareRoleDownload))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 内部分享，非接收者
+	{
+		share := Share{Model: gorm.Model{ID: 1}, Internal: true, UserID: 1}
+		mock.ExpectQuery("SELECT(.+)share_recipients(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		asserts.Equal(0, share.PermissionsOf(user))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,43 @@
+package model
+
+import (
+	"net"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// IsAccessibleFrom 检查访客来源是否满足分享自身及全局设定的IP、国家/地区限制，
+// 设定了国家/地区限制时，无法识别国家/地区的访客不可访问。ClientIP 仅采信受信任的
+// 反向代理转发的地址，不受信任的对端伪造的请求头会被忽略
+func (share *Share) IsAccessibleFrom(c *gin.Context) bool {
+	ip := net.ParseIP(c.ClientIP())
+	country := ClientCountry(c)
+	settings := GetSettingByNames("share_allowed_ips", "share_allowed_countries")
+
+	return matchAccessRule(ip, country, share.AllowedIPs, share.AllowedCountries) &&
+		matchAccessRule(ip, country, settings["share_allowed_ips"], settings["share_allowed_countries"])
+}
+
+// matchAccessRule 检查IP及国家/地区是否满足给定限制，限制格式无效时拒绝访问
+func matchAccessRule(ip net.IP, country, ips, countries string) bool {
+	if strings.TrimSpace(ips) != "" {
+		ranges, err := util.ParseIPRanges(ips)
+		if err != nil {
+			util.Log().Warning("无效的分享访问IP限制, %s", err)
+			return false
+		}
+		if ip == nil || !util.IPInRanges(ip, ranges) {
+			return false
+		}
+	}
+
+	if allowed := util.SplitList(strings.ToUpper(countries)); len(allowed) > 0 {
+		if country == "" || !util.ContainsString(allowed, country) {
+			return false
+		}
+	}
+
+	return true
+}
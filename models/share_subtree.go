@@ -0,0 +1,107 @@
+package model
+
+import (
+	"github.com/jinzhu/gorm"
+)
+
+// AncestorIDs 返回目录自身及其所有上级目录的ID
+func (folder *Folder) AncestorIDs() []uint {
+	ids := []uint{folder.ID}
+	parentID := folder.ParentID
+
+	// 最大递归65535次
+	for i := 0; parentID != nil && i < 65535; i++ {
+		var parent Folder
+		if err := DB.Where("id = ? AND owner_id = ?", *parentID, folder.OwnerID).First(&parent).Error; err != nil {
+			break
+		}
+		ids = append(ids, parent.ID)
+		parentID = parent.ParentID
+	}
+
+	return ids
+}
+
+// sharesUnder 构建查询用户给定目录子树（含目录本身）及文件相关分享的条件，
+// 包含以这些对象为源的分享及包含这些对象的合集分享
+func sharesUnder(uid uint, dirs, files []uint) (*gorm.DB, error) {
+	folderIDs := []uint{}
+	if len(dirs) > 0 {
+		folders, err := GetRecursiveChildFolder(dirs, uid, true)
+		if err != nil {
+			return nil, err
+		}
+		for _, folder := range folders {
+			folderIDs = append(folderIDs, folder.ID)
+		}
+	}
+
+	childFiles := DB.Model(&File{}).Select("id").
+		Where("user_id = ? and folder_id in (?)", uid, folderIDs).QueryExpr()
+	sourceCond := "(is_dir = ? and source_id in (?)) or (is_dir = ? and (source_id in (?) or source_id in (?)))"
+	bundles := DB.Model(&ShareBundleItem{}).Select("share_id").
+		Where(sourceCond, true, folderIDs, false, childFiles, files).QueryExpr()
+
+	return DB.Model(&Share{}).Where("user_id = ?", uid).
+		Where(sourceCond+" or id in (?)", true, folderIDs, false, childFiles, files, bundles), nil
+}
+
+// ListSharesUnder 列出用户给定目录子树（含目录本身）及文件下所有对象的分享
+func ListSharesUnder(uid uint, dirs, files []uint) ([]Share, error) {
+	var shares []Share
+	dbChain, err := sharesUnder(uid, dirs, files)
+	if err != nil {
+		return shares, err
+	}
+
+	err = dbChain.Order("id desc").Find(&shares).Error
+	return shares, err
+}
+
+// RevokeSharesUnder 撤销用户给定目录子树（含目录本身）及文件下所有对象的分享，
+// 返回被撤销的分享
+func RevokeSharesUnder(uid uint, dirs, files []uint) ([]Share, error) {
+	shares, err := ListSharesUnder(uid, dirs, files)
+	if err != nil || len(shares) == 0 {
+		return shares, err
+	}
+
+	ids := make([]uint, 0, len(shares))
+	for _, share := range shares {
+		ids = append(ids, share.ID)
+	}
+
+	return shares, DB.Where("id in (?)", ids).Delete(&Share{}).Error
+}
+
+// RevokeSharesMovedOut 对象从 src 目录移动至 dst 目录后，如果离开了用户某个已分享目录的子树，
+// 则撤销这些对象及其子对象自身的分享，返回被撤销的分享
+func RevokeSharesMovedOut(src, dst *Folder, dirs, files []uint) ([]Share, error) {
+	if src.ID == dst.ID {
+		return nil, nil
+	}
+
+	// 找出移出的上级目录
+	kept := make(map[uint]bool)
+	for _, id := range dst.AncestorIDs() {
+		kept[id] = true
+	}
+	left := make([]uint, 0)
+	for _, id := range src.AncestorIDs() {
+		if !kept[id] {
+			left = append(left, id)
+		}
+	}
+	if len(left) == 0 {
+		return nil, nil
+	}
+
+	// 移出的目录中是否有已分享的
+	var count int
+	if err := DB.Model(&Share{}).Where("user_id = ? and is_dir = ? and source_id in (?)", src.OwnerID, true, left).
+		Count(&count).Error; err != nil || count == 0 {
+		return nil, err
+	}
+
+	return RevokeSharesUnder(src.OwnerID, dirs, files)
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFolder_AncestorIDs(t *testing.T) {
+	asserts := assert.New(t)
+	parentID := uint(2)
+	folder := Folder{Model: gorm.Model{ID: 3}, ParentID: &parentID, OwnerID: 1}
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	asserts.Equal([]uint{3, 2, 1}, folder.AncestorIDs())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListSharesUnder(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)shares(.+)share_bundle_items(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir"}).AddRow(2, true).AddRow(1, false))
+	shares, err := ListSharesUnder(1, []uint{2}, nil)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(shares, 2)
+}
+
+func TestRevokeSharesMovedOut(t *testing.T) {
+	asserts := assert.New(t)
+	rootID, sharedID := uint(1), uint(2)
+	root := &Folder{Model: gorm.Model{ID: 1}, OwnerID: 1}
+	shared := &Folder{Model: gorm.Model{ID: 2}, ParentID: &rootID, OwnerID: 1}
+	inner := &Folder{Model: gorm.Model{ID: 3}, ParentID: &sharedID, OwnerID: 1}
+
+	// 目录未变化
+	{
+		revoked, err := RevokeSharesMovedOut(shared, shared, nil, []uint{1})
+		asserts.NoError(err)
+		asserts.Empty(revoked)
+	}
+
+	// 移入子目录，未离开任何目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		revoked, err := RevokeSharesMovedOut(shared, inner, nil, []uint{1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Empty(revoked)
+	}
+
+	// 离开的目录未被分享
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WithArgs(1, true, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		revoked, err := RevokeSharesMovedOut(shared, root, nil, []uint{1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Empty(revoked)
+	}
+
+	// 移出已分享的目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WithArgs(1, true, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		revoked, err := RevokeSharesMovedOut(shared, root, nil, []uint{1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(revoked, 2)
+	}
+}
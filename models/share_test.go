@@ -3,12 +3,14 @@ package model
 import (
 	"errors"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -63,12 +65,95 @@ func TestGetShareByHashID(t *testing.T) {
 		asserts.Nil(res)
 	}
 
-	// ID解码失败
+	// ID解码失败，按自定义链接查找
 	{
-		res := GetShareByHashID("empty")
+		mock.ExpectQuery("SELECT(.+)slug(.+)").
+			WithArgs("empty").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		res := GetShareByHashID("Empty")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(res)
+	}
+
+	// 自定义链接查找成功
+	{
+		mock.ExpectQuery("SELECT(.+)slug(.+)").
+			WithArgs("q3-report").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "slug"}).AddRow(1, "q3-report"))
+		res := GetShareByHashID("q3-report")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(res)
+		asserts.Equal("q3-report", res.Key())
+	}
+
+	// 带有密钥的分享
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}).AddRow(12, "abcdefgh12345678"))
+		res := GetShareByHashID("x9T4.abcdefgh12345678")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(res)
+		asserts.Equal("x9T4.abcdefgh12345678", res.Key())
+	}
+
+	// 密钥错误
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}).AddRow(1, "abcdefgh12345678"))
+		res := GetShareByHashID("x9T4.abcdefgh00000000")
+		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(res)
 	}
 
+	// 缺少密钥
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "secret"}).AddRow(1, "abcdefgh12345678"))
+		res := GetShareByHashID("x9T4")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(res)
+	}
+
+	// 自定义链接不接受密钥
+	{
+		res := GetShareByHashID("q3-report.abcdefgh12345678")
+		asserts.Nil(res)
+	}
+}
+
+func TestValidateShareSlug(t *testing.T) {
+	asserts := assert.New(t)
+	conf.SystemConfig.HashIDSalt = ""
+
+	// 成功
+	{
+		slug, err := ValidateShareSlug("Q3-Report")
+		asserts.NoError(err)
+		asserts.Equal("q3-report", slug)
+	}
+
+	// 格式错误
+	for _, slug := range []string{"ab", "-abc", "a b c", "中文链接", strings.Repeat("a", 65)} {
+		_, err := ValidateShareSlug(slug)
+		asserts.Equal(ErrShareSlugInvalid, err, slug)
+	}
+
+	// 保留字
+	{
+		_, err := ValidateShareSlug("admin")
+		asserts.Equal(ErrShareSlugReserved, err)
+	}
+}
+
+func TestShare_Key(t *testing.T) {
+	asserts := assert.New(t)
+	conf.SystemConfig.HashIDSalt = ""
+	share := Share{Model: gorm.Model{ID: 1}}
+	asserts.Equal(hashid.HashID(1, hashid.ShareID), share.Key())
+
+	slug := "q3-report"
+	share.Slug = &slug
+	asserts.Equal(slug, share.Key())
 }
 
 func TestShare_IsAvailable(t *testing.T) {
@@ -80,6 +165,15 @@ func TestShare_IsAvailable(t *testing.T) {
 		asserts.False(share.IsAvailable())
 	}
 
+	// 内容审核违规
+	{
+		share := Share{
+			RemainDownloads:  -1,
+			ModerationStatus: ModerationBlock,
+		}
+		asserts.False(share.IsAvailable())
+	}
+
 	// 时效过期
 	{
 		expires := time.Unix(10, 10)
@@ -126,6 +220,19 @@ func TestShare_IsAvailable(t *testing.T) {
 		}
 		asserts.False(share.IsAvailable())
 	}
+
+	// 用户已申请注销
+	{
+		deleteAfter := time.Now().Add(time.Hour)
+		share := Share{
+			RemainDownloads: -1,
+			SourceID:        2,
+			IsDir:           true,
+			User:            User{DeleteAfter: &deleteAfter},
+		}
+		share.User.ID = 1
+		asserts.False(share.IsAvailable())
+	}
 }
 
 func TestShare_GetCreator(t *testing.T) {
@@ -159,6 +266,56 @@ func TestShare_Source(t *testing.T) {
 	}
 }
 
+func TestShare_CanBeMountedBy(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 1}}
+	user.Group.OptionsSerialized.ShareDownload = true
+	newShare := func() *Share {
+		share := &Share{IsDir: true, UserID: 2, SourceID: 3, RemainDownloads: -1}
+		share.User = User{Model: gorm.Model{ID: 2}, Status: Active}
+		share.Folder.ID = 3
+		return share
+	}
+
+	// 成功
+	asserts.NoError(newShare().CanBeMountedBy(user))
+
+	// 文件分享
+	{
+		share := newShare()
+		share.IsDir = false
+		asserts.Error(share.CanBeMountedBy(user))
+	}
+
+	// 加密分享
+	{
+		share := newShare()
+		share.Password = "123"
+		asserts.Error(share.CanBeMountedBy(user))
+	}
+
+	// 限制下载次数
+	{
+		share := newShare()
+		share.RemainDownloads = 3
+		asserts.Error(share.CanBeMountedBy(user))
+	}
+
+	// 挂载自己的加密分享
+	{
+		share := newShare()
+		share.Password = "123"
+		share.UserID = 1
+		asserts.NoError(share.CanBeMountedBy(user))
+	}
+
+	// 用户组无权下载
+	{
+		user := &User{Model: gorm.Model{ID: 1}}
+		asserts.Error(newShare().CanBeMountedBy(user))
+	}
+}
+
 func TestShare_CanBeDownloadBy(t *testing.T) {
 	asserts := assert.New(t)
 	share := Share{}
@@ -202,6 +359,103 @@ func TestShare_CanBeDownloadBy(t *testing.T) {
 	}
 }
 
+func TestShare_CanBeUploadedBy(t *testing.T) {
+	asserts := assert.New(t)
+	user := &User{Model: gorm.Model{ID: 1}}
+	anonymous := NewAnonymousUser()
+
+	// 文件分享
+	{
+		share := Share{AllowUpload: true}
+		asserts.Error(share.CanBeUploadedBy(user))
+	}
+
+	// 未开启上传
+	{
+		share := Share{IsDir: true}
+		asserts.Error(share.CanBeUploadedBy(user))
+	}
+
+	// 未登录用户，未开启匿名上传
+	{
+		cache.Set("setting_share_anonymous_upload", "0", 0)
+		share := Share{IsDir: true, AllowUpload: true}
+		asserts.Error(share.CanBeUploadedBy(anonymous))
+		asserts.NoError(share.CanBeUploadedBy(user))
+	}
+
+	// 未登录用户，开启匿名上传
+	{
+		cache.Set("setting_share_anonymous_upload", "1", 0)
+		share := Share{IsDir: true, AllowUpload: true}
+		asserts.NoError(share.CanBeUploadedBy(anonymous))
+	}
+}
+
+func TestShare_CheckUploadQuota(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无限制
+	{
+		share := Share{UploadedCount: 100, UploadedSize: 100}
+		asserts.True(share.CheckUploadQuota(1024))
+	}
+
+	// 超出数量限制
+	{
+		share := Share{UploadCountLimit: 2, UploadedCount: 2}
+		asserts.False(share.CheckUploadQuota(1))
+	}
+
+	// 超出大小限制
+	{
+		share := Share{UploadSizeLimit: 10, UploadedSize: 5}
+		asserts.True(share.CheckUploadQuota(5))
+		asserts.False(share.CheckUploadQuota(6))
+	}
+}
+
+func TestShare_Uploaded(t *testing.T) {
+	asserts := assert.New(t)
+	share := Share{Model: gorm.Model{ID: 1}}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(share.Uploaded(10))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, share.UploadedCount)
+	asserts.EqualValues(10, share.UploadedSize)
+}
+
+func TestShare_Traffic(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无限制
+	{
+		share := Share{TrafficUsed: 1 << 40}
+		asserts.False(share.TrafficExhausted())
+		asserts.True(share.CheckTraffic(1 << 40))
+	}
+
+	// 有限制
+	{
+		share := Share{Model: gorm.Model{ID: 1}, TrafficLimit: 10, TrafficUsed: 4}
+		asserts.False(share.TrafficExhausted())
+		asserts.True(share.CheckTraffic(6))
+		asserts.False(share.CheckTraffic(7))
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)traffic_used(.+)").
+			WithArgs(6, 1).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(share.ConsumeTraffic(6))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(share.TrafficExhausted())
+	}
+}
+
 func TestShare_WasDownloadedBy(t *testing.T) {
 	asserts := assert.New(t)
 	share := Share{
@@ -295,6 +549,20 @@ func TestShare_UpdateAndDelete(t *testing.T) {
 
 }
 
+func TestGetExpiredShares(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)expire_action(.+)").
+		WithArgs(ShareExpireKeep, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "expire_action"}).AddRow(1, ShareExpireDeleteSource))
+
+	res, err := GetExpiredShares(600)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(res, 1)
+	asserts.Equal(ShareExpireDeleteSource, res[0].ExpireAction)
+}
+
 func TestListShares(t *testing.T) {
 	asserts := assert.New(t)
 
@@ -307,12 +575,35 @@ func TestListShares(t *testing.T) {
 	asserts.Equal(2, total)
 }
 
+func TestListSharesBySources(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").
+			WithArgs(1, true, 2, false, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir", "source_id"}).AddRow(2, true, 2).AddRow(1, false, 3))
+		res, err := ListSharesBySources(1, []uint{2}, []uint{3})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(res, 2)
+	}
+
+	// 失败
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnError(errors.New("error"))
+		_, err := ListSharesBySources(1, nil, []uint{3})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+}
+
 func TestSearchShares(t *testing.T) {
 	asserts := assert.New(t)
 
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectQuery("SELECT(.+)").
-		WithArgs("", sqlmock.AnyArg(), "%1%2%").
+		WithArgs("", false, sqlmock.AnyArg(), "%1%2%").
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	res, total := SearchShares(1, 10, "id", "1 2")
 	asserts.NoError(mock.ExpectationsWereMet())
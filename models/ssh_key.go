@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// SSHKey 用于 SFTP 公钥登录的 SSH 公钥
+type SSHKey struct {
+	gorm.Model
+	Name        string     // 公钥名称
+	UserID      uint       `gorm:"index"`        // 用户ID
+	PublicKey   string     `gorm:"type:text"`    // authorized_keys 格式的公钥
+	Fingerprint string     `gorm:"unique_index"` // SHA256 指纹，同一公钥只能属于一个用户
+	LastUsedAt  *time.Time // 最后使用时间
+	LastIP      string     // 最后使用的 IP
+}
+
+// Create 创建公钥记录
+func (key *SSHKey) Create() (uint, error) {
+	if err := DB.Create(key).Error; err != nil {
+		return 0, err
+	}
+	return key.ID, nil
+}
+
+// Touch 记录公钥的使用时间及 IP，五分钟内重复使用且 IP 不变时不更新
+func (key *SSHKey) Touch(ip string) {
+	now := time.Now()
+	if key.LastUsedAt != nil && now.Sub(*key.LastUsedAt) < 5*time.Minute && key.LastIP == ip {
+		return
+	}
+	key.LastUsedAt = &now
+	key.LastIP = ip
+	DB.Model(key).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_ip": ip})
+}
+
+// GetSSHKeyByFingerprint 根据指纹查找公钥
+func GetSSHKeyByFingerprint(fingerprint string) (*SSHKey, error) {
+	key := &SSHKey{}
+	err := DB.Where("fingerprint = ?", fingerprint).First(key).Error
+	return key, err
+}
+
+// ListSSHKeys 列出用户的所有公钥
+func ListSSHKeys(uid uint) []SSHKey {
+	var keys []SSHKey
+	DB.Where("user_id = ?", uid).Order("created_at desc").Find(&keys)
+	return keys
+}
+
+// DeleteSSHKeyByID 根据公钥ID和UID删除公钥
+func DeleteSSHKeyByID(id, uid uint) {
+	DB.Where("user_id = ? and id = ?", uid, id).Delete(&SSHKey{})
+}
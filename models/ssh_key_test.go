@@ -0,0 +1,98 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSSHKey_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)ssh_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		key := &SSHKey{UserID: 1, Fingerprint: "SHA256:abc"}
+		id, err := key.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, id)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		key := &SSHKey{}
+		id, err := key.Create()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.EqualValues(0, id)
+	}
+}
+
+func TestSSHKey_Touch(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 首次使用
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)ssh_keys(.+)").WithArgs("1.1.1.1", sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		key := &SSHKey{}
+		key.ID = 1
+		key.Touch("1.1.1.1")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(key.LastUsedAt)
+
+		// 短时间内重复使用
+		key.Touch("1.1.1.1")
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// IP 变化
+	{
+		now := time.Now()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)ssh_keys(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		key := &SSHKey{LastUsedAt: &now, LastIP: "1.1.1.1"}
+		key.ID = 1
+		key.Touch("2.2.2.2")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("2.2.2.2", key.LastIP)
+	}
+}
+
+func TestGetSSHKeyByFingerprint(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)ssh_keys(.+)").WithArgs("SHA256:abc").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "fingerprint"}).AddRow(1, 2, "SHA256:abc"))
+	key, err := GetSSHKeyByFingerprint("SHA256:abc")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, key.UserID)
+}
+
+func TestListSSHKeys(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)ssh_keys(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	res := ListSSHKeys(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(res, 2)
+}
+
+func TestDeleteSSHKeyByID(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)ssh_keys(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	DeleteSSHKeyByID(1, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,234 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// StatisticDateFormat 统计日期格式
+const StatisticDateFormat = "2006-01-02"
+
+// Statistic 站点每日统计，由定时任务汇总写入
+type Statistic struct {
+	gorm.Model
+	Date            string `gorm:"size:10;unique_index:statistic_date"` // 统计日期
+	UploadTraffic   uint64 // 当日上传流量
+	DownloadTraffic uint64 // 当日下载流量
+	ActiveUsers     int    // 当日活跃用户数
+	Storage         string `gorm:"type:text"` // 最近一次汇总的存储用量
+
+	// 数据库忽略字段
+	StorageSerialized StorageUsage `gorm:"-"`
+}
+
+// StorageUsage 存储用量汇总
+type StorageUsage struct {
+	Policies []PolicyUsage `json:"policies"`
+	Groups   []GroupUsage  `json:"groups"`
+	TopUsers []UserUsage   `json:"top_users"`
+	// UpdatedAt 汇总时间
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PolicyUsage 存储策略的用量
+type PolicyUsage struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Files int    `json:"files"`
+	Size  uint64 `json:"size"`
+}
+
+// GroupUsage 用户组的用量
+type GroupUsage struct {
+	ID    uint   `json:"id"`
+	Name  string `json:"name"`
+	Users int    `json:"users"`
+	Size  uint64 `json:"size"`
+}
+
+// UserUsage 用户的用量
+type UserUsage struct {
+	ID    uint   `json:"id"`
+	Email string `json:"email"`
+	Nick  string `json:"nick"`
+	Size  uint64 `json:"size"`
+}
+
+// UsageReport 一个周期内的站点用量报告，统计范围为 [From, To)
+type UsageReport struct {
+	Period          string // 报告周期，weekly 或 monthly
+	From            time.Time
+	To              time.Time
+	NewUsers        int    // 新注册用户数
+	TotalUsers      int    // 周期结束时的用户总数
+	UploadTraffic   uint64 // 周期内上传流量
+	DownloadTraffic uint64 // 周期内下载流量
+	PeakActiveUsers int    // 周期内单日最多活跃用户数
+	StorageBefore   uint64 // 周期开始前的存储用量
+	StorageAfter    uint64 // 周期结束时的存储用量
+	TopDownloads    []ShareDownloads
+	FailedTasks     []TaskCount
+}
+
+// TotalSize 所有存储策略的用量之和
+func (usage *StorageUsage) TotalSize() uint64 {
+	var total uint64
+	for _, policy := range usage.Policies {
+		total += policy.Size
+	}
+	return total
+}
+
+// TotalUsers 所有用户组的用户数之和
+func (usage *StorageUsage) TotalUsers() int {
+	total := 0
+	for _, group := range usage.Groups {
+		total += group.Users
+	}
+	return total
+}
+
+// AfterFind 找到统计记录后的钩子
+func (stat *Statistic) AfterFind() (err error) {
+	// 解析存储用量到 StorageSerialized
+	if stat.Storage != "" {
+		err = json.Unmarshal([]byte(stat.Storage), &stat.StorageSerialized)
+	}
+
+	return err
+}
+
+// GetOrCreateStatistic 获取给定日期的统计记录，不存在时创建。多个进程同时创建时，
+// 依赖唯一索引忽略重复的插入
+func GetOrCreateStatistic(date string) (*Statistic, error) {
+	var stat Statistic
+	result := DB.Where("date = ?", date).First(&stat)
+	if !result.RecordNotFound() {
+		return &stat, result.Error
+	}
+
+	now := time.Now()
+	if _, err := insertIgnore(DB, &Statistic{}, []string{"date"}, map[string]interface{}{
+		"created_at": now,
+		"updated_at": now,
+		"date":       date,
+	}); err != nil {
+		return &stat, err
+	}
+
+	result = DB.Where("date = ?", date).First(&stat)
+	return &stat, result.Error
+}
+
+// ListStatistics 按日期顺序列出 [from, to] 范围内的统计记录
+func ListStatistics(from, to string) ([]Statistic, error) {
+	var stats []Statistic
+	result := DB.Where("date >= ? and date <= ?", from, to).Order("date asc").Find(&stats)
+	return stats, result.Error
+}
+
+// GetLatestStorageUsage 获取最近一次汇总的存储用量，尚未汇总时返回空值
+func GetLatestStorageUsage() (StorageUsage, error) {
+	var stat Statistic
+	result := DB.Where("storage <> ?", "").Order("date desc").Limit(1).Find(&stat)
+	if result.RecordNotFound() {
+		return StorageUsage{}, nil
+	}
+	return stat.StorageSerialized, result.Error
+}
+
+// GetStorageUsageAt 获取 date 当日及之前最近一次汇总的存储用量，不存在时返回空值
+func GetStorageUsageAt(date string) (StorageUsage, error) {
+	var stat Statistic
+	result := DB.Where("date <= ? and storage <> ?", date, "").Order("date desc").Limit(1).Find(&stat)
+	if result.RecordNotFound() {
+		return StorageUsage{}, nil
+	}
+	return stat.StorageSerialized, result.Error
+}
+
+// AddTraffic 累加统计记录的上传、下载流量
+func (stat *Statistic) AddTraffic(upload, download uint64) error {
+	if err := DB.Model(stat).UpdateColumns(map[string]interface{}{
+		"upload_traffic":   gorm.Expr("upload_traffic + ?", upload),
+		"download_traffic": gorm.Expr("download_traffic + ?", download),
+	}).Error; err != nil {
+		return err
+	}
+
+	stat.UploadTraffic += upload
+	stat.DownloadTraffic += download
+	return nil
+}
+
+// UpdateActiveUsers 更新统计记录的活跃用户数
+func (stat *Statistic) UpdateActiveUsers(count int) error {
+	stat.ActiveUsers = count
+	return DB.Model(stat).UpdateColumn("active_users", count).Error
+}
+
+// UpdateStorage 更新统计记录的存储用量
+func (stat *Statistic) UpdateStorage(usage StorageUsage) error {
+	storage, err := json.Marshal(usage)
+	if err != nil {
+		return err
+	}
+
+	stat.Storage = string(storage)
+	stat.StorageSerialized = usage
+	return DB.Model(stat).UpdateColumn("storage", stat.Storage).Error
+}
+
+// AggregatePolicyUsage 按存储策略汇总文件数量及大小
+func AggregatePolicyUsage() ([]PolicyUsage, error) {
+	var res []PolicyUsage
+	err := DB.Table(DB.NewScope(&Policy{}).TableName() + " p").
+		Select("p.id, p.name, count(f.id) as files, coalesce(sum(f.size), 0) as size").
+		Joins("left join " + DB.NewScope(&File{}).TableName() + " f on f.policy_id = p.id and f.deleted_at is null").
+		Where("p.deleted_at is null").
+		Group("p.id, p.name").
+		Order("size desc").
+		Scan(&res).Error
+	return res, err
+}
+
+// AggregateGroupUsage 按用户组汇总用户数量及已用容量
+func AggregateGroupUsage() ([]GroupUsage, error) {
+	var res []GroupUsage
+	err := DB.Table(DB.NewScope(&Group{}).TableName() + " g").
+		Select("g.id, g.name, count(u.id) as users, coalesce(sum(u.storage), 0) as size").
+		Joins("left join " + DB.NewScope(&User{}).TableName() + " u on u.group_id = g.id and u.deleted_at is null").
+		Where("g.deleted_at is null").
+		Group("g.id, g.name").
+		Order("size desc").
+		Scan(&res).Error
+	return res, err
+}
+
+// TopUsersByStorage 列出已用容量最多的 limit 个用户
+func TopUsersByStorage(limit int) ([]UserUsage, error) {
+	var res []UserUsage
+	err := DB.Model(&User{}).
+		Select("id, email, nick, storage as size").
+		Order("storage desc").
+		Limit(limit).
+		Scan(&res).Error
+	return res, err
+}
+
+// MarkUsersActive 将给定用户的最近活跃日期设为 date
+func MarkUsersActive(ids []uint, date string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return DB.Model(&User{}).Where("id in (?)", ids).UpdateColumn("active_date", date).Error
+}
+
+// CountActiveUsers 统计最近活跃日期为 date 的用户数
+func CountActiveUsers(date string) (int, error) {
+	count := 0
+	err := DB.Model(&User{}).Where("active_date = ?", date).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetOrCreateStatistic(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 已存在
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-01-01").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date", "upload_traffic"}).AddRow(1, "2022-01-01", 10))
+		stat, err := GetOrCreateStatistic("2022-01-01")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, stat.ID)
+		asserts.EqualValues(10, stat.UploadTraffic)
+	}
+
+	// 不存在，创建
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectExec("INSERT IGNORE INTO(.+)statistics(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-01-02").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "date"}).AddRow(2, "2022-01-02"))
+		stat, err := GetOrCreateStatistic("2022-01-02")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, stat.ID)
+		asserts.Equal("2022-01-02", stat.Date)
+	}
+}
+
+func TestStatistic_Update(t *testing.T) {
+	asserts := assert.New(t)
+	stat := &Statistic{UploadTraffic: 1}
+	stat.ID = 1
+
+	// 累加流量
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)statistics(.+)download_traffic(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(stat.AddTraffic(2, 3))
+	asserts.EqualValues(3, stat.UploadTraffic)
+	asserts.EqualValues(3, stat.DownloadTraffic)
+
+	// 活跃用户
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)statistics(.+)active_users(.+)").WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(stat.UpdateActiveUsers(5))
+	asserts.Equal(5, stat.ActiveUsers)
+
+	// 存储用量
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)statistics(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(stat.UpdateStorage(StorageUsage{Policies: []PolicyUsage{{ID: 1, Size: 10}}}))
+	asserts.Contains(stat.Storage, `"size":10`)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestGetLatestStorageUsage(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 尚未汇总
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		usage, err := GetLatestStorageUsage()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Empty(usage.Policies)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).
+			AddRow(1, `{"policies":[{"id":1,"name":"default","files":2,"size":10}],"top_users":[{"id":1,"size":10}]}`))
+		usage, err := GetLatestStorageUsage()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(usage.Policies, 1)
+		asserts.Equal("default", usage.Policies[0].Name)
+		asserts.Equal(2, usage.Policies[0].Files)
+		asserts.EqualValues(10, usage.TopUsers[0].Size)
+	}
+}
+
+func TestGetStorageUsageAt(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 尚未汇总
+	mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-01-01", "").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	usage, err := GetStorageUsageAt("2022-01-01")
+	asserts.NoError(err)
+	asserts.Empty(usage.Policies)
+
+	mock.ExpectQuery("SELECT(.+)statistics(.+)date <= (.+)ORDER BY date desc").WithArgs("2022-01-07", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).
+			AddRow(1, `{"policies":[{"id":1,"size":10},{"id":2,"size":5}],"groups":[{"id":1,"users":2},{"id":2,"users":3}]}`))
+	usage, err = GetStorageUsageAt("2022-01-07")
+	asserts.NoError(err)
+	asserts.EqualValues(15, usage.TotalSize())
+	asserts.Equal(5, usage.TotalUsers())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListStatistics(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-01-01", "2022-01-31").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "date"}).AddRow(1, "2022-01-01").AddRow(2, "2022-01-03"))
+	stats, err := ListStatistics("2022-01-01", "2022-01-31")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(stats, 2)
+}
+
+func TestAggregateUsage(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)policies p left join files f(.+)GROUP BY p.id, p.name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "files", "size"}).AddRow(1, "default", 2, 10))
+	policies, err := AggregatePolicyUsage()
+	asserts.NoError(err)
+	asserts.Equal([]PolicyUsage{{ID: 1, Name: "default", Files: 2, Size: 10}}, policies)
+
+	mock.ExpectQuery("SELECT(.+)groups g left join users u(.+)GROUP BY g.id, g.name").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "users", "size"}).AddRow(1, "admin", 1, 10))
+	groups, err := AggregateGroupUsage()
+	asserts.NoError(err)
+	asserts.Equal([]GroupUsage{{ID: 1, Name: "admin", Users: 1, Size: 10}}, groups)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)ORDER BY storage desc LIMIT 5").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "nick", "size"}).AddRow(1, "a@cloudreve.org", "a", 10))
+	users, err := TopUsersByStorage(5)
+	asserts.NoError(err)
+	asserts.Equal([]UserUsage{{ID: 1, Email: "a@cloudreve.org", Nick: "a", Size: 10}}, users)
+
+	mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
+	_, err = TopUsersByStorage(5)
+	asserts.Error(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestActiveUsers(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无用户
+	asserts.NoError(MarkUsersActive(nil, "2022-01-01"))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)users(.+)active_date(.+)").WithArgs("2022-01-01", 1, 2).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	asserts.NoError(MarkUsersActive([]uint{1, 2}, "2022-01-01"))
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)").WithArgs("2022-01-01").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	count, err := CountActiveUsers("2022-01-01")
+	asserts.NoError(err)
+	asserts.Equal(2, count)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
@@ -0,0 +1,51 @@
+package model
+
+// ListSubAccounts 列出用户创建的子账户
+func ListSubAccounts(parentID uint) []User {
+	var users []User
+	DB.Where("parent_id = ?", parentID).Order("created_at desc").Find(&users)
+	return users
+}
+
+// CountSubAccounts 统计用户创建的子账户数量
+func CountSubAccounts(parentID uint) int {
+	total := 0
+	DB.Model(&User{}).Where("parent_id = ?", parentID).Count(&total)
+	return total
+}
+
+// GetSubAccount 根据ID和母账户ID查找子账户
+func GetSubAccount(id, parentID uint) (User, error) {
+	var user User
+	err := DB.Where("parent_id = ? and id = ?", parentID, id).First(&user).Error
+	return user, err
+}
+
+// DeleteSubAccount 删除子账户及其登录会话
+func DeleteSubAccount(id, parentID uint) error {
+	res := DB.Unscoped().Where("parent_id = ? and id = ?", parentID, id).Delete(&User{})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+
+	// 子账户不拥有文件，一并删除其创建时生成的空根目录
+	if err := DB.Where("owner_id = ?", id).Delete(&Folder{}).Error; err != nil {
+		return err
+	}
+	return DB.Where("user_id = ?", id).Delete(&UserSession{}).Error
+}
+
+// IsSubAccount 是否为子账户
+func (user *User) IsSubAccount() bool {
+	return user.ParentID != 0
+}
+
+// CoversPath 路径是否位于子账户可访问的目录内
+func (user *User) CoversPath(p string) bool {
+	return folderCoversPath(user.Folder, p)
+}
+
+// CoversFile 文件是否位于子账户可访问的目录内
+func (user *User) CoversFile(file *File) bool {
+	return folderCoversFile(user.Folder, file)
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountSubAccounts(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT count(.+)users(.+)parent_id(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	asserts.Equal(2, CountSubAccounts(1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestGetSubAccount(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)users(.+)parent_id(.+)").WithArgs(1, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id", "folder"}).AddRow(3, 1, "/family"))
+	user, err := GetSubAccount(3, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.True(user.IsSubAccount())
+	asserts.True(user.CoversPath("/family/photos"))
+	asserts.False(user.CoversPath("/work"))
+}
+
+func TestDeleteSubAccount(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)users(.+)").WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)user_sessions(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteSubAccount(3, 1))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 不属于当前用户
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)users(.+)").WithArgs(2, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		asserts.NoError(DeleteSubAccount(3, 2))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
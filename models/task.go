@@ -1,6 +1,8 @@
 package model
 
 import (
+	"time"
+
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
 )
@@ -12,8 +14,13 @@ type Task struct {
 	Type     int    // 任务类型
 	UserID   uint   // 发起者UID，0表示为系统发起
 	Progress int    // 进度
-	Error    string `gorm:"type:text"` // 错误信息
-	Props    string `gorm:"type:text"` // 任务属性
+	Error    string `gorm:"type:text"`          // 错误信息
+	Props    string `gorm:"type:text"`          // 任务属性
+	Report   string `gorm:"type:text" json:"-"` // 逐项处理结果报告
+
+	// 数据库忽略字段
+	RequestID   string `gorm:"-" json:"-"` // 创建任务的请求 ID，用于关联日志
+	TraceParent string `gorm:"-" json:"-"` // 创建任务的请求所在链路，用于延续链路追踪
 }
 
 // Create 创建任务记录
@@ -35,11 +42,21 @@ func (task *Task) SetProgress(progress int) error {
 	return DB.Model(task).Select("progress").Updates(map[string]interface{}{"progress": progress}).Error
 }
 
+// SetProps 更新任务属性
+func (task *Task) SetProps(props string) error {
+	return DB.Model(task).Select("props").Updates(map[string]interface{}{"props": props}).Error
+}
+
 // SetError 设定错误信息
 func (task *Task) SetError(err string) error {
 	return DB.Model(task).Select("error").Updates(map[string]interface{}{"error": err}).Error
 }
 
+// SetReport 保存逐项处理结果报告
+func (task *Task) SetReport(report string) error {
+	return DB.Model(task).Select("report").Updates(map[string]interface{}{"report": report}).Error
+}
+
 // GetTasksByStatus 根据状态检索任务
 func GetTasksByStatus(status ...int) []Task {
 	var tasks []Task
@@ -54,14 +71,40 @@ func GetTasksByID(id interface{}) (*Task, error) {
 	return task, result.Error
 }
 
-// ListTasks 列出用户所属的任务
-func ListTasks(uid uint, page, pageSize int, order string) ([]Task, int) {
+// CountUserTasks 统计用户给定类型及状态的任务数量
+func CountUserTasks(uid uint, taskType int, status ...int) int {
+	total := 0
+	DB.Model(&Task{}).Where("user_id = ? AND type = ? AND status in (?)", uid, taskType, status).Count(&total)
+	return total
+}
+
+// TaskCount 某类任务的数量
+type TaskCount struct {
+	Type  int
+	Name  string `gorm:"-"`
+	Count int
+}
+
+// CountTasksByType 按类型统计 [from, to) 期间更新为给定状态的任务数量
+func CountTasksByType(from, to time.Time, status ...int) ([]TaskCount, error) {
+	var res []TaskCount
+	err := DB.Model(&Task{}).Select("type, count(*) as count").
+		Where("updated_at >= ? AND updated_at < ? AND status in (?)", from, to, status).
+		Group("type").Order("count desc").Scan(&res).Error
+	return res, err
+}
+
+// ListTasks 列出用户所属的任务，conditions 为附加的筛选条件
+func ListTasks(uid uint, page, pageSize int, order string, conditions map[string]interface{}) ([]Task, int) {
 	var (
 		tasks []Task
 		total int
 	)
 	dbChain := DB
 	dbChain = dbChain.Where("user_id = ?", uid)
+	for k, v := range conditions {
+		dbChain = dbChain.Where(k+" = ?", v)
+	}
 
 	// 计算总数用于分页
 	dbChain.Model(&Task{}).Count(&total)
@@ -71,3 +114,41 @@ func ListTasks(uid uint, page, pageSize int, order string) ([]Task, int) {
 
 	return tasks, total
 }
+
+// DeleteTasksBefore 彻底删除给定时间前结束的任务记录
+func DeleteTasksBefore(before time.Time, status ...int) (int64, error) {
+	res := DB.Unscoped().Where("updated_at < ? AND status in (?)", before, status).Delete(&Task{})
+	return res.RowsAffected, res.Error
+}
+
+// DeleteTasksExceeding 彻底删除每个用户超出保留数量 keep 的较早的已结束任务记录
+func DeleteTasksExceeding(keep int, status ...int) (int64, error) {
+	var users []struct {
+		UserID uint
+		Total  int
+	}
+	if err := DB.Model(&Task{}).Select("user_id, count(id) as total").
+		Where("status in (?)", status).Group("user_id").
+		Having("count(id) > ?", keep).Scan(&users).Error; err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, user := range users {
+		// 找到需要保留的最早一条记录
+		var boundary Task
+		if err := DB.Select("id").Where("user_id = ? AND status in (?)", user.UserID, status).
+			Order("id desc").Offset(keep - 1).Limit(1).Find(&boundary).Error; err != nil {
+			return deleted, err
+		}
+
+		res := DB.Unscoped().Where("user_id = ? AND status in (?) AND id < ?", user.UserID, status, boundary.ID).
+			Delete(&Task{})
+		if res.Error != nil {
+			return deleted, res.Error
+		}
+		deleted += res.RowsAffected
+	}
+
+	return deleted, nil
+}
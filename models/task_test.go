@@ -6,6 +6,7 @@ import (
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestTask_Create(t *testing.T) {
@@ -47,6 +48,18 @@ func TestTask_SetError(t *testing.T) {
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestTask_SetProps(t *testing.T) {
+	asserts := assert.New(t)
+	task := Task{
+		Model: gorm.Model{ID: 1},
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(task.SetProps("{}"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestTask_SetStatus(t *testing.T) {
 	asserts := assert.New(t)
 	task := Task{
@@ -86,10 +99,57 @@ func TestListTasks(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
 
-	res, total := ListTasks(1, 1, 10, "")
+	res, total := ListTasks(1, 1, 10, "", nil)
 	asserts.NoError(mock.ExpectationsWereMet())
 	asserts.EqualValues(5, total)
 	asserts.Len(res, 1)
+
+	// 附加筛选条件
+	mock.ExpectQuery("SELECT(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT(.+)").WithArgs(1, 2).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+	res, total = ListTasks(1, 1, 10, "", map[string]interface{}{"status": 2})
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(1, total)
+	asserts.Len(res, 1)
+}
+
+func TestDeleteTasksBefore(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	deleted, err := DeleteTasksBefore(time.Now(), 2, 4)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(3, deleted)
+}
+
+func TestDeleteTasksExceeding(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
+		_, err := DeleteTasksExceeding(10, 2, 4)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id", "total"}).AddRow(1, 12))
+		mock.ExpectQuery("SELECT(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WithArgs(1, 2, 4, 20).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+		deleted, err := DeleteTasksExceeding(10, 2, 4)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(2, deleted)
+	}
 }
 
 func TestGetTasksByStatus(t *testing.T) {
@@ -102,3 +162,25 @@ func TestGetTasksByStatus(t *testing.T) {
 	a.NoError(mock.ExpectationsWereMet())
 	a.Len(res, 1)
 }
+
+func TestCountUserTasks(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)").WithArgs(1, 4, 0, 1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	asserts.Equal(1, CountUserTasks(1, 4, 0, 1))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestCountTasksByType(t *testing.T) {
+	asserts := assert.New(t)
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 1, 0)
+
+	mock.ExpectQuery("SELECT type, count(.+)tasks(.+)GROUP BY type").
+		WithArgs(from, to, 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).AddRow(2, 3).AddRow(0, 1))
+	res, err := CountTasksByType(from, to, 2, 5)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal([]TaskCount{{Type: 2, Count: 3}, {Type: 0, Count: 1}}, res)
+}
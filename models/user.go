@@ -7,6 +7,7 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"strings"
+	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
@@ -24,20 +25,56 @@ const (
 	OveruseBaned
 )
 
+const (
+	// EmailDeliverable 邮箱可正常投递
+	EmailDeliverable = iota
+	// EmailSoftBounced 邮件暂时无法投递，如收件箱已满
+	EmailSoftBounced
+	// EmailBounced 邮箱不存在或永久拒收，不再向其发送邮件
+	EmailBounced
+	// EmailComplained 收件人将邮件标记为垃圾邮件，不再向其发送邮件
+	EmailComplained
+)
+
 // User 用户模型
 type User struct {
 	// 表字段
 	gorm.Model
-	Email     string `gorm:"type:varchar(100);unique_index"`
-	Nick      string `gorm:"size:50"`
-	Password  string `json:"-"`
-	Status    int
-	GroupID   uint
-	Storage   uint64
-	TwoFactor string
-	Avatar    string
-	Options   string `json:"-" gorm:"size:4294967295"`
-	Authn     string `gorm:"size:4294967295"`
+	Email       string `gorm:"type:varchar(100);unique_index"`
+	Nick        string `gorm:"size:50"`
+	Password    string `json:"-"`
+	Status      int
+	GroupID     uint
+	Storage     uint64
+	TwoFactor   string
+	Recovery    string `gorm:"type:text" json:"-"` // 二步验证恢复码的摘要
+	Avatar      string
+	Options     string     `json:"-" gorm:"size:4294967295"`
+	Authn       string     `gorm:"size:4294967295"`
+	LDAPUser    string     `gorm:"column:ldap_user;index:ldap_user" json:"-"`       // LDAP 用户名，为空表示本地用户
+	OIDC        string     `gorm:"column:oidc_subject;index:oidc_subject" json:"-"` // 关联的 OIDC 用户标识
+	SAML        string     `gorm:"column:saml_subject;index:saml_subject" json:"-"` // 关联的 SAML 用户标识
+	DeleteAfter *time.Time `json:"delete_after,omitempty"`                          // 申请注销后计划清除账户的时间，为空表示未申请注销
+
+	PasswordChangedAt *time.Time `json:"-"`                  // 最后一次修改密码的时间，为空时以注册时间计
+	PasswordHistory   string     `gorm:"type:text" json:"-"` // 近期使用过的密码摘要，每行一个
+
+	PreviousGroupID uint       `json:"-"`                       // 临时调整用户组前的用户组，到期后恢复
+	GroupExpires    *time.Time `json:"group_expires,omitempty"` // 临时用户组的到期时间，为空表示长期有效
+
+	UploadTraffic     uint64 `json:"-"`                                  // 当日已上传流量
+	UploadTrafficDate string `gorm:"size:10" json:"-"`                   // 上传流量的统计日期
+	ActiveDate        string `gorm:"size:10;index:active_date" json:"-"` // 最近活跃的日期
+
+	ParentID uint   `gorm:"index" json:"-"`     // 子账户所属的母账户ID，为 0 表示普通账户
+	Folder   string `gorm:"type:text" json:"-"` // 子账户可访问的母账户目录
+
+	Quota         uint64     `json:"quota,omitempty"`           // 单独设置的容量上限，为 0 时使用用户组的容量
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`      // 访客账户的到期时间，为空表示长期有效
+	PurgeOnExpiry bool       `json:"purge_on_expiry,omitempty"` // 访客账户到期后是否删除其全部文件
+
+	EmailStatus int    `json:"email_status"`                         // 邮箱的投递状态，由邮件服务商推送的退信及投诉通知更新
+	Phone       string `gorm:"size:32;index" json:"phone,omitempty"` // 已验证的手机号码，E.164 格式
 
 	// 关联模型
 	Group  Group  `gorm:"save_associations:false:false"`
@@ -45,6 +82,8 @@ type User struct {
 
 	// 数据库忽略字段
 	OptionsSerialized UserOption `gorm:"-"`
+	Impersonator      uint       `gorm:"-" json:"-"` // 正在模拟此用户的管理员ID
+	SubAccount        *User      `gorm:"-" json:"-"` // 正在以此用户身份操作的子账户
 }
 
 func init() {
@@ -53,8 +92,11 @@ func init() {
 
 // UserOption 用户个性化配置字段
 type UserOption struct {
-	ProfileOff     bool   `json:"profile_off,omitempty"`
-	PreferredTheme string `json:"preferred_theme,omitempty"`
+	ProfileOff     bool                    `json:"profile_off,omitempty"`
+	PreferredTheme string                  `json:"preferred_theme,omitempty"`
+	AuthnTwoFactor bool                    `json:"authn_2fa,omitempty"`    // 是否使用验证器作为二步验证
+	Notification   *NotificationPreference `json:"notification,omitempty"` // 通知偏好，未设置时使用默认渠道
+	DLNAFolder     uint                    `json:"dlna_folder,omitempty"`  // 以 DLNA 发布的媒体目录 ID，为 0 时不发布
 }
 
 // Root 获取用户的根目录
@@ -109,17 +151,51 @@ func (user *User) IncreaseStorageWithoutCheck(size uint64) {
 
 }
 
+// MaxStorage 获取用户的容量上限，单独设置的容量优先于用户组容量
+func (user *User) MaxStorage() uint64 {
+	if user.Quota > 0 {
+		return user.Quota
+	}
+	return user.Group.MaxStorage
+}
+
+// SetStorage 将用户已用容量设为给定值
+func (user *User) SetStorage(size uint64) error {
+	if err := DB.Model(user).UpdateColumn("storage", size).Error; err != nil {
+		return err
+	}
+	user.Storage = size
+	return nil
+}
+
+// ListUsersStorageMismatch 列出已用容量与文件记录统计结果不一致的用户ID
+func ListUsersStorageMismatch() ([]uint, error) {
+	var ids []uint
+	err := DB.Model(&User{}).
+		Joins("left join (select user_id, sum(size) as total from files where deleted_at is NULL group by user_id) f on f.user_id = users.id").
+		Where("users.storage <> coalesce(f.total, 0)").
+		Pluck("users.id", &ids).Error
+	return ids, err
+}
+
 // GetRemainingCapacity 获取剩余配额
 func (user *User) GetRemainingCapacity() uint64 {
-	total := user.Group.MaxStorage
+	total := user.MaxStorage()
 	if total <= user.Storage {
 		return 0
 	}
 	return total - user.Storage
 }
 
-// GetPolicyID 获取用户当前的存储策略ID
+// GetPolicyID 获取用户当前的存储策略ID，跳过因容量不足暂停接收新上传的存储策略，
+// 全部暂停时仍返回首个存储策略
 func (user *User) GetPolicyID(prefer uint) uint {
+	for _, id := range user.Group.PolicyList {
+		if !IsPolicyUploadSuspended(id) {
+			return id
+		}
+	}
+
 	if len(user.Group.PolicyList) > 0 {
 		return user.Group.PolicyList[0]
 	}
@@ -147,6 +223,190 @@ func GetActiveUserByOpenID(openid string) (User, error) {
 	return user, result.Error
 }
 
+// ListDLNAPublishers 列出设置了 DLNA 媒体目录的可登录用户
+func ListDLNAPublishers() ([]User, error) {
+	var users []User
+	result := DB.Set("gorm:auto_preload", true).
+		Where("status = ? and options like ?", Active, `%"dlna_folder":%`).Find(&users)
+	return users, result.Error
+}
+
+// ListUsersPendingDeletion 列出注销冷静期已结束、待清除的用户
+func ListUsersPendingDeletion() ([]User, error) {
+	var users []User
+	result := DB.Where("delete_after is not NULL and delete_after < ?", time.Now()).Find(&users)
+	return users, result.Error
+}
+
+// ScheduleDeletion 申请注销账户，冷静期结束后清除账户
+func (user *User) ScheduleDeletion(grace time.Duration) error {
+	deleteAfter := time.Now().Add(grace)
+
+	// 不经由 user 更新，更新失败时 user 保持原状
+	if err := DB.Model(&User{}).Where("id = ?", user.ID).UpdateColumn("delete_after", deleteAfter).Error; err != nil {
+		return err
+	}
+
+	user.DeleteAfter = &deleteAfter
+	return nil
+}
+
+// CancelDeletion 撤销注销申请
+func (user *User) CancelDeletion() error {
+	if err := DB.Model(user).UpdateColumn("delete_after", gorm.Expr("NULL")).Error; err != nil {
+		return err
+	}
+
+	user.DeleteAfter = nil
+	return nil
+}
+
+// Purge 删除用户及其全部关联记录，调用前需先删除用户的文件
+func (user *User) Purge() error {
+	for _, related := range []interface{}{
+		&Download{}, &Task{}, &Tag{}, &Webdav{}, &Share{}, &APIToken{}, &UserSession{}, &LoginDevice{},
+		&S3AccessKey{}, &SSHKey{}, &FileChange{},
+	} {
+		if err := DB.Where("user_id = ?", user.ID).Delete(related).Error; err != nil {
+			return err
+		}
+	}
+
+	// 用户创建的邀请码一并删除
+	if err := DB.Where("creator_id = ?", user.ID).Delete(&Invitation{}).Error; err != nil {
+		return err
+	}
+
+	// 子账户一并删除
+	if err := DB.Unscoped().Where("parent_id = ?", user.ID).Delete(&User{}).Error; err != nil {
+		return err
+	}
+
+	return DB.Unscoped().Delete(user).Error
+}
+
+// UpgradeGroup 将用户临时调整到给定用户组，到期后恢复为原用户组
+func (user *User) UpgradeGroup(groupID uint, until time.Time) error {
+	// 延长或更换临时用户组时，仍恢复为最初的用户组
+	previous := user.GroupID
+	if user.GroupExpires != nil {
+		previous = user.PreviousGroupID
+	}
+
+	if err := DB.Model(user).UpdateColumns(map[string]interface{}{
+		"group_id":          groupID,
+		"previous_group_id": previous,
+		"group_expires":     until,
+	}).Error; err != nil {
+		return err
+	}
+
+	user.GroupID = groupID
+	user.PreviousGroupID = previous
+	user.GroupExpires = &until
+	return nil
+}
+
+// RevertGroup 临时用户组到期后恢复为原用户组，并重新读取用户组信息
+func (user *User) RevertGroup() error {
+	// 更新时 user 中的字段会被改写为新值，须提前读取原用户组
+	previous := user.PreviousGroupID
+	if err := DB.Model(user).UpdateColumns(map[string]interface{}{
+		"group_id":          previous,
+		"previous_group_id": 0,
+		"group_expires":     gorm.Expr("NULL"),
+	}).Error; err != nil {
+		return err
+	}
+
+	user.GroupID = previous
+	user.PreviousGroupID = 0
+	user.GroupExpires = nil
+
+	group, err := GetGroupByID(user.GroupID)
+	if err != nil {
+		return err
+	}
+	user.Group = group
+	return nil
+}
+
+// ListUsersGroupExpired 列出临时用户组已到期的用户
+func ListUsersGroupExpired() ([]User, error) {
+	var users []User
+	err := DB.Where("group_expires is not NULL and group_expires <= ?", time.Now()).Find(&users).Error
+	return users, err
+}
+
+// ListGuestsExpired 列出已到期但仍可登录的访客账户
+func ListGuestsExpired() ([]User, error) {
+	var users []User
+	err := DB.Where("expires_at is not NULL and expires_at <= ? and status = ?", time.Now(), Active).Find(&users).Error
+	return users, err
+}
+
+// ExpireGuest 停用已到期的访客账户：禁止登录，撤销其全部分享，并注销会话、API 令牌及 WebDAV 账号。
+// 返回被撤销的分享，用于记录审计事件
+func (user *User) ExpireGuest() ([]Share, error) {
+	var shares []Share
+	if err := DB.Where("user_id = ?", user.ID).Find(&shares).Error; err != nil {
+		return nil, err
+	}
+
+	// 不经由 user 更新，事务回滚时 user 保持原状
+	tx := DB.Begin()
+	if err := tx.Model(&User{}).Where("id = ?", user.ID).UpdateColumn("status", Baned).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for _, related := range []interface{}{&Share{}, &APIToken{}, &UserSession{}, &Webdav{}, &S3AccessKey{}, &SSHKey{}} {
+		if err := tx.Where("user_id = ?", user.ID).Delete(related).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	user.Status = Baned
+	return shares, nil
+}
+
+// UploadedToday 返回用户当日已上传的流量
+func (user *User) UploadedToday() uint64 {
+	if user.UploadTrafficDate != time.Now().Format("2006-01-02") {
+		return 0
+	}
+	return user.UploadTraffic
+}
+
+// AddUploadTraffic 累加用户当日上传流量，跨日后重新统计
+func (user *User) AddUploadTraffic(size uint64) error {
+	today := time.Now().Format("2006-01-02")
+	uploaded := user.UploadedToday()
+
+	// 按列名顺序更新，upload_traffic 须先于日期更新以读取原统计日期，
+	// 更新后 user 中的日期已被改写，须提前读取今日已上传的流量
+	if err := DB.Model(user).UpdateColumns(map[string]interface{}{
+		"upload_traffic":      gorm.Expr("CASE WHEN upload_traffic_date = ? THEN upload_traffic + ? ELSE ? END", today, size, size),
+		"upload_traffic_date": today,
+	}).Error; err != nil {
+		return err
+	}
+
+	user.UploadTraffic = uploaded + size
+	user.UploadTrafficDate = today
+	return nil
+}
+
+// ListUserRecords 按创建顺序列出属于用户的全部记录，records 为目标模型的切片指针
+func ListUserRecords(uid uint, records interface{}) error {
+	return DB.Where("user_id = ?", uid).Order("id").Find(records).Error
+}
+
 // GetUserByEmail 用Email获取用户
 func GetUserByEmail(email string) (User, error) {
 	var user User
@@ -161,6 +421,89 @@ func GetActiveUserByEmail(email string) (User, error) {
 	return user, result.Error
 }
 
+// SetEmailStatus 根据邮件服务商的投递反馈更新邮箱的投递状态，返回更新的用户数。
+// 软退信不覆盖永久退信及投诉，投递成功仅清除软退信
+func SetEmailStatus(address string, status int) (int64, error) {
+	db := DB.Model(&User{}).Where("email = ?", address)
+	switch status {
+	case EmailDeliverable:
+		db = db.Where("email_status = ?", EmailSoftBounced)
+	case EmailSoftBounced:
+		db = db.Where("email_status = ?", EmailDeliverable)
+	}
+
+	result := db.UpdateColumn("email_status", status)
+	return result.RowsAffected, result.Error
+}
+
+// IsEmailSuppressed 邮箱是否因永久退信或投诉而不再接收邮件
+func IsEmailSuppressed(address string) bool {
+	count := 0
+	DB.Model(&User{}).Where("email = ? and email_status in (?)", address, []int{EmailBounced, EmailComplained}).Count(&count)
+	return count > 0
+}
+
+// IsPhoneUsed 手机号码是否已被其他用户绑定
+func IsPhoneUsed(phone string, exceptUID uint) bool {
+	count := 0
+	DB.Model(&User{}).Where("phone = ? and id <> ?", phone, exceptUID).Count(&count)
+	return count > 0
+}
+
+// CountUsersCreated 统计 [from, to) 期间注册的用户数
+func CountUsersCreated(from, to time.Time) (int, error) {
+	count := 0
+	err := DB.Model(&User{}).Where("created_at >= ? and created_at < ?", from, to).Count(&count).Error
+	return count, err
+}
+
+// ListAdmins 列出状态正常的管理员用户组用户
+func ListAdmins() ([]User, error) {
+	var users []User
+	result := DB.Where("group_id = ? and status = ?", 1, Active).Find(&users)
+	return users, result.Error
+}
+
+// GetUserByLDAP 用 LDAP 用户名获取用户
+func GetUserByLDAP(username string) (User, error) {
+	var user User
+	result := DB.Set("gorm:auto_preload", true).Where("ldap_user = ?", username).First(&user)
+	return user, result.Error
+}
+
+// ListLDAPUsers 列出所有通过 LDAP 登录创建的用户
+func ListLDAPUsers() ([]User, error) {
+	var users []User
+	result := DB.Where("ldap_user <> ?", "").Find(&users)
+	return users, result.Error
+}
+
+// GetUserByOIDC 用关联的 OIDC 用户标识获取用户
+func GetUserByOIDC(subject string) (User, error) {
+	var user User
+	result := DB.Set("gorm:auto_preload", true).Where("oidc_subject = ?", subject).First(&user)
+	return user, result.Error
+}
+
+// LinkOIDC 将用户与 OIDC 用户标识关联
+func (user *User) LinkOIDC(subject string) error {
+	user.OIDC = subject
+	return DB.Model(user).Update("oidc_subject", subject).Error
+}
+
+// GetUserBySAML 用关联的 SAML 用户标识获取用户
+func GetUserBySAML(nameID string) (User, error) {
+	var user User
+	result := DB.Set("gorm:auto_preload", true).Where("saml_subject = ?", nameID).First(&user)
+	return user, result.Error
+}
+
+// LinkSAML 将用户与 SAML 用户标识关联
+func (user *User) LinkSAML(nameID string) error {
+	user.SAML = nameID
+	return DB.Model(user).Update("saml_subject", nameID).Error
+}
+
 // NewUser 返回一个新的空 User
 func NewUser() User {
 	options := UserOption{}
@@ -198,7 +541,7 @@ func (user *User) AfterFind() (err error) {
 	return err
 }
 
-//SerializeOptions 将序列后的Option写入到数据库字段
+// SerializeOptions 将序列后的Option写入到数据库字段
 func (user *User) SerializeOptions() (err error) {
 	optionsValue, err := json.Marshal(&user.OptionsSerialized)
 	user.Options = string(optionsValue)
@@ -207,9 +550,14 @@ func (user *User) SerializeOptions() (err error) {
 
 // CheckPassword 根据明文校验密码
 func (user *User) CheckPassword(password string) (bool, error) {
+	return verifyPassword(user.Password, password)
+}
+
+// verifyPassword 校验明文密码与存储的密码摘要是否一致
+func verifyPassword(stored, password string) (bool, error) {
 
 	// 根据存储密码拆分为 Salt 和 Digest
-	passwordStore := strings.Split(user.Password, ":")
+	passwordStore := strings.Split(stored, ":")
 	if len(passwordStore) != 2 && len(passwordStore) != 3 {
 		return false, errors.New("Unknown password type")
 	}
@@ -258,6 +606,61 @@ func (user *User) SetPassword(password string) error {
 	return nil
 }
 
+// UsedPassword 返回明文密码是否与当前密码或最近 history 个历史密码相同
+func (user *User) UsedPassword(password string, history int) bool {
+	if history <= 0 {
+		return false
+	}
+
+	stores := append([]string{user.Password}, user.passwordHistory()...)
+	if len(stores) > history {
+		stores = stores[:history]
+	}
+
+	for _, stored := range stores {
+		if ok, _ := verifyPassword(stored, password); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ChangePassword 修改密码，并将原密码摘要保留在最多 history 条的历史记录中
+func (user *User) ChangePassword(password string, history int) error {
+	previous := append([]string{user.Password}, user.passwordHistory()...)
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+
+	// 当前密码本身占用一条历史记录
+	if keep := history - 1; keep > 0 {
+		if len(previous) > keep {
+			previous = previous[:keep]
+		}
+		user.PasswordHistory = strings.Join(previous, "\n")
+	} else {
+		user.PasswordHistory = ""
+	}
+
+	now := time.Now()
+	user.PasswordChangedAt = &now
+	return DB.Model(user).Updates(map[string]interface{}{
+		"password":            user.Password,
+		"password_history":    user.PasswordHistory,
+		"password_changed_at": now,
+	}).Error
+}
+
+func (user *User) passwordHistory() []string {
+	var res []string
+	for _, stored := range strings.Split(user.PasswordHistory, "\n") {
+		if stored != "" {
+			res = append(res, stored)
+		}
+	}
+	return res
+}
+
 // NewAnonymousUser 返回一个匿名用户
 func NewAnonymousUser() *User {
 	user := User{}
@@ -271,6 +674,11 @@ func (user *User) IsAnonymous() bool {
 	return user.ID == 0
 }
 
+// IsAdmin 是否为管理员
+func (user *User) IsAdmin() bool {
+	return user.GroupID == 1 || user.ID == 1
+}
+
 // SetStatus 设定用户状态
 func (user *User) SetStatus(status int) {
 	DB.Model(&user).Update("status", status)
@@ -0,0 +1,123 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"strings"
+)
+
+const (
+	// RecoveryCodeCount 每次生成的二步验证恢复码数量
+	RecoveryCodeCount = 10
+	recoveryCodeChars = "23456789abcdefghjkmnpqrstuvwxyz"
+)
+
+func hashRecoveryCode(code string) string {
+	sum := sha256.Sum256([]byte(normalizeRecoveryCode(code)))
+	return hex.EncodeToString(sum[:])
+}
+
+// normalizeRecoveryCode 忽略大小写、空白及分隔符
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code))
+}
+
+// newRecoveryCode 生成形如 xxxxx-xxxxx 的恢复码
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	max := big.NewInt(int64(len(recoveryCodeChars)))
+	for i := range b {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = recoveryCodeChars[n.Int64()]
+	}
+	return string(b[:5]) + "-" + string(b[5:]), nil
+}
+
+// recoveryCodeHashes 返回尚未使用的恢复码摘要
+func (user *User) recoveryCodeHashes() []string {
+	var hashes []string
+	if user.Recovery != "" {
+		json.Unmarshal([]byte(user.Recovery), &hashes)
+	}
+	return hashes
+}
+
+func (user *User) saveRecoveryCodeHashes(hashes []string) error {
+	res, err := json.Marshal(hashes)
+	if err != nil {
+		return err
+	}
+	if len(hashes) == 0 {
+		res = nil
+	}
+
+	user.Recovery = string(res)
+	return DB.Model(user).Update("recovery", user.Recovery).Error
+}
+
+// GenerateRecoveryCodes 生成新的二步验证恢复码，原有恢复码全部失效。
+// 数据库中只保存摘要，明文仅在生成时返回一次
+func (user *User) GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes[i] = code
+		hashes[i] = hashRecoveryCode(code)
+	}
+
+	if err := user.saveRecoveryCodeHashes(hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ClearRecoveryCodes 清除所有恢复码
+func (user *User) ClearRecoveryCodes() error {
+	return user.saveRecoveryCodeHashes(nil)
+}
+
+// RecoveryCodesLeft 返回剩余可用的恢复码数量
+func (user *User) RecoveryCodesLeft() int {
+	return len(user.recoveryCodeHashes())
+}
+
+// UseRecoveryCode 使用恢复码完成二步验证，恢复码有效时将其作废并返回 true
+func (user *User) UseRecoveryCode(code string) bool {
+	if normalizeRecoveryCode(code) == "" {
+		return false
+	}
+
+	expected := hashRecoveryCode(code)
+	hashes := user.recoveryCodeHashes()
+	for i, hash := range hashes {
+		if subtle.ConstantTimeCompare([]byte(hash), []byte(expected)) == 1 {
+			remain := ""
+			if rest := append(hashes[:i:i], hashes[i+1:]...); len(rest) > 0 {
+				res, _ := json.Marshal(rest)
+				remain = string(res)
+			}
+
+			// 仅在恢复码未被并发使用时作废，避免同一恢复码被使用两次
+			result := DB.Model(&User{}).Where("id = ? AND recovery = ?", user.ID, user.Recovery).Update("recovery", remain)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return false
+			}
+			user.Recovery = remain
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUser_GenerateRecoveryCodes(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Model: gorm.Model{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	codes, err := user.GenerateRecoveryCodes()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(codes, RecoveryCodeCount)
+	asserts.Equal(RecoveryCodeCount, user.RecoveryCodesLeft())
+	asserts.NotContains(user.Recovery, codes[0])
+}
+
+func TestUser_UseRecoveryCode(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Model: gorm.Model{ID: 1}}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	codes, err := user.GenerateRecoveryCodes()
+	asserts.NoError(err)
+
+	// 无效恢复码
+	{
+		asserts.False(user.UseRecoveryCode(""))
+		asserts.False(user.UseRecoveryCode("00000-00000"))
+	}
+
+	// 被并发使用
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		asserts.False(user.UseRecoveryCode(codes[0]))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(RecoveryCodeCount, user.RecoveryCodesLeft())
+	}
+
+	// 成功，忽略大小写及分隔符
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.True(user.UseRecoveryCode(" " + strings.ToUpper(strings.Replace(codes[0], "-", "", 1))))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(RecoveryCodeCount-1, user.RecoveryCodesLeft())
+	}
+
+	// 已使用过的恢复码
+	{
+		asserts.False(user.UseRecoveryCode(codes[0]))
+	}
+}
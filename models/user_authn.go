@@ -1,14 +1,17 @@
 package model
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"time"
 
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/jinzhu/gorm"
 )
 
 /*
@@ -42,7 +45,35 @@ func (user User) WebAuthnIcon() string {
 
 // WebAuthnCredentials 获得已注册的验证器凭证
 func (user User) WebAuthnCredentials() []webauthn.Credential {
-	var res []webauthn.Credential
+	authenticators := user.AuthnCredentials()
+	res := make([]webauthn.Credential, 0, len(authenticators))
+	for _, authenticator := range authenticators {
+		res = append(res, authenticator.Credential)
+	}
+	return res
+}
+
+// AuthnCredential 已注册的验证器及其管理信息
+type AuthnCredential struct {
+	webauthn.Credential
+	Name       string     `json:",omitempty"`
+	Passkey    bool       `json:",omitempty"` // 是否为可发现凭证，可用于无用户名登录
+	CreatedAt  *time.Time `json:",omitempty"`
+	LastUsedAt *time.Time `json:",omitempty"`
+}
+
+// AuthnCredentialID 返回验证器凭证ID的字符串形式
+func AuthnCredentialID(id []byte) string {
+	return base64.StdEncoding.EncodeToString(id)
+}
+
+// AuthnCredentials 获得已注册的验证器及其管理信息
+func (user User) AuthnCredentials() []AuthnCredential {
+	var res []AuthnCredential
+	if user.Authn == "" {
+		return res
+	}
+
 	err := json.Unmarshal([]byte(user.Authn), &res)
 	if err != nil {
 		fmt.Println(err)
@@ -50,30 +81,84 @@ func (user User) WebAuthnCredentials() []webauthn.Credential {
 	return res
 }
 
-// RegisterAuthn 添加新的验证器
-func (user *User) RegisterAuthn(credential *webauthn.Credential) error {
-	exists := user.WebAuthnCredentials()
-	exists = append(exists, *credential)
-	res, err := json.Marshal(exists)
+func (user *User) saveAuthn(authenticators []AuthnCredential) error {
+	res, err := json.Marshal(authenticators)
 	if err != nil {
 		return err
 	}
 
-	return DB.Model(user).Update("authn", string(res)).Error
+	user.Authn = string(res)
+	return DB.Model(user).Update("authn", user.Authn).Error
+}
+
+// RegisterAuthn 添加新的验证器
+func (user *User) RegisterAuthn(credential *webauthn.Credential) error {
+	return user.AddAuthn(AuthnCredential{Credential: *credential})
+}
+
+// AddAuthn 添加新的验证器，并记录添加时间
+func (user *User) AddAuthn(authenticator AuthnCredential) error {
+	now := time.Now()
+	authenticator.CreatedAt = &now
+	return user.saveAuthn(append(user.AuthnCredentials(), authenticator))
+}
+
+// RenameAuthn 重命名验证器
+func (user *User) RenameAuthn(id, name string) error {
+	authenticators := user.AuthnCredentials()
+	for i := range authenticators {
+		if AuthnCredentialID(authenticators[i].ID) == id {
+			authenticators[i].Name = name
+			return user.saveAuthn(authenticators)
+		}
+	}
+
+	return gorm.ErrRecordNotFound
+}
+
+// UpdateAuthnUsage 验证器完成一次验证后，更新其签名计数及最后使用时间
+func (user *User) UpdateAuthnUsage(credential *webauthn.Credential) error {
+	authenticators := user.AuthnCredentials()
+	for i := range authenticators {
+		if bytes.Equal(authenticators[i].ID, credential.ID) {
+			now := time.Now()
+			authenticators[i].Authenticator = credential.Authenticator
+			authenticators[i].LastUsedAt = &now
+			return user.saveAuthn(authenticators)
+		}
+	}
+
+	return gorm.ErrRecordNotFound
 }
 
 // RemoveAuthn 删除验证器
 func (user *User) RemoveAuthn(id string) {
-	exists := user.WebAuthnCredentials()
+	exists := user.AuthnCredentials()
 	for i := 0; i < len(exists); i++ {
-		idEncoded := base64.StdEncoding.EncodeToString(exists[i].ID)
-		if idEncoded == id {
+		if AuthnCredentialID(exists[i].ID) == id {
 			exists[len(exists)-1], exists[i] = exists[i], exists[len(exists)-1]
 			exists = exists[:len(exists)-1]
 			break
 		}
 	}
 
-	res, _ := json.Marshal(exists)
-	DB.Model(user).Update("authn", string(res))
+	user.saveAuthn(exists)
+}
+
+// RequireSecondFactor 返回登录时是否需要二步验证
+func (user *User) RequireSecondFactor() bool {
+	return user.TwoFactor != "" || (user.OptionsSerialized.AuthnTwoFactor && user.CanUseAuthn())
+}
+
+// CanUseAuthn 返回用户是否可以使用已注册的验证器登录
+func (user *User) CanUseAuthn() bool {
+	return IsTrueVal(GetSettingByName("authn_enabled")) && len(user.WebAuthnCredentials()) > 0
+}
+
+// GetActiveUserByWebAuthnID 用验证器返回的用户句柄获取激活的用户
+func GetActiveUserByWebAuthnID(handle []byte) (User, error) {
+	if len(handle) != 8 {
+		return User{}, gorm.ErrRecordNotFound
+	}
+	return GetActiveUserByID(uint(binary.LittleEndian.Uint64(handle)))
 }
@@ -2,6 +2,7 @@ package model
 
 import (
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
@@ -98,3 +99,98 @@ func TestUser_RemoveAuthn(t *testing.T) {
 		asserts.NoError(mock.ExpectationsWereMet())
 	}
 }
+
+func TestUser_RenameAuthn(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{
+		Model: gorm.Model{ID: 1},
+		Authn: `[{"ID":"MTIz","PublicKey":"+4sg1vYcjg8=","AttestationType":"packed","Authenticator":{"AAGUID":"+lg=","SignCount":0,"CloneWarning":false}}]`,
+	}
+
+	// 验证器不存在
+	{
+		asserts.Equal(gorm.ErrRecordNotFound, user.RenameAuthn("not_exist", "name"))
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.RenameAuthn("MTIz", "YubiKey"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal("YubiKey", user.AuthnCredentials()[0].Name)
+	}
+}
+
+func TestUser_UpdateAuthnUsage(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{
+		Model: gorm.Model{ID: 1},
+		Authn: `[{"ID":"MTIz","PublicKey":"+4sg1vYcjg8=","AttestationType":"packed","Authenticator":{"AAGUID":"+lg=","SignCount":0,"CloneWarning":false}}]`,
+	}
+
+	credential := webauthn.Credential{ID: []byte("123")}
+	credential.Authenticator.SignCount = 5
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(user.UpdateAuthnUsage(&credential))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	authenticators := user.AuthnCredentials()
+	asserts.EqualValues(5, authenticators[0].Authenticator.SignCount)
+	asserts.NotNil(authenticators[0].LastUsedAt)
+}
+
+func TestUser_RequireSecondFactor(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_authn_enabled", "1", 0)
+	user := User{
+		Model: gorm.Model{ID: 1},
+	}
+
+	asserts.False(user.RequireSecondFactor())
+
+	user.TwoFactor = "secret"
+	asserts.True(user.RequireSecondFactor())
+
+	// 使用验证器作为二步验证，但未注册验证器
+	user.TwoFactor = ""
+	user.OptionsSerialized.AuthnTwoFactor = true
+	asserts.False(user.RequireSecondFactor())
+
+	user.Authn = `[{"ID":"MTIz","PublicKey":"+4sg1vYcjg8=","AttestationType":"packed","Authenticator":{"AAGUID":"+lg=","SignCount":0,"CloneWarning":false}}]`
+	asserts.True(user.RequireSecondFactor())
+
+	// 全局关闭验证器
+	cache.Set("setting_authn_enabled", "0", 0)
+	asserts.False(user.RequireSecondFactor())
+}
+
+func TestGetActiveUserByWebAuthnID(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 句柄长度错误
+	{
+		_, err := GetActiveUserByWebAuthnID([]byte("1"))
+		asserts.Error(err)
+	}
+
+	// 成功
+	{
+		cache.Deletes([]string{"1"}, "policy_")
+		mock.ExpectQuery("^SELECT (.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "group_id"}).AddRow(1, 1))
+		mock.ExpectQuery("^SELECT (.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "policies"}).AddRow(1, "[1]"))
+		mock.ExpectQuery("^SELECT (.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		user, err := GetActiveUserByWebAuthnID(User{Model: gorm.Model{ID: 1}}.WebAuthnID())
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(1, user.ID)
+	}
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// ErrMergeFolderExisted 合并目标目录下已存在同名目录
+var ErrMergeFolderExisted = errors.New("a folder with the same name already exists")
+
+// MergeUser 将 source 账户合并至 target，所有操作在同一事务中完成：
+// source 的根目录以 name 为名移入 target 的 parent 目录下，文件、分享、任务及离线下载转移给 target，
+// 已用容量累加至 target；source 账户被停用，注销其会话、令牌及 WebDAV 账号，并获得新的空根目录
+func MergeUser(source, target *User, parent *Folder, name string) error {
+	root, err := source.Root()
+	if err != nil {
+		return err
+	}
+
+	tx := DB.Begin()
+	if err := mergeUser(tx, source, target, root, parent, name); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// 原根目录整体计入目标目录的统计
+	touched, err := updateFolderStats(tx, map[uint]folderStat{parent.ID: root.subtree()})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	InvalidateFolderList(touched...)
+	target.Storage += source.Storage
+	source.Storage = 0
+	source.Status = Baned
+	return nil
+}
+
+func mergeUser(tx *gorm.DB, source, target *User, root, parent *Folder, name string) error {
+	var existed int
+	if err := tx.Model(&Folder{}).Where("parent_id = ? and name = ?", parent.ID, name).Count(&existed).Error; err != nil {
+		return err
+	}
+	if existed > 0 {
+		return ErrMergeFolderExisted
+	}
+
+	// 转移目录，并将原根目录移入目标目录
+	if err := tx.Model(&Folder{}).Where("owner_id = ?", source.ID).UpdateColumn("owner_id", target.ID).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(root).UpdateColumns(map[string]interface{}{
+		"name":      name,
+		"parent_id": parent.ID,
+	}).Error; err != nil {
+		return err
+	}
+
+	// 转移文件、分享、任务及离线下载
+	for _, related := range []interface{}{&File{}, &Share{}, &Task{}, &Download{}} {
+		if err := tx.Model(related).Where("user_id = ?", source.ID).UpdateColumn("user_id", target.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	// 合并已用容量
+	if err := tx.Model(target).UpdateColumn("storage", gorm.Expr("storage + ?", source.Storage)).Error; err != nil {
+		return err
+	}
+	// 不经由 source 更新，其已用容量在事务提交后才累加至 target
+	if err := tx.Model(&User{}).Where("id = ?", source.ID).UpdateColumns(map[string]interface{}{
+		"status":  Baned,
+		"storage": 0,
+	}).Error; err != nil {
+		return err
+	}
+
+	// 停用原账户的全部登录方式
+	for _, related := range []interface{}{&APIToken{}, &UserSession{}, &Webdav{}, &S3AccessKey{}, &SSHKey{}} {
+		if err := tx.Where("user_id = ?", source.ID).Delete(related).Error; err != nil {
+			return err
+		}
+	}
+
+	return tx.Create(&Folder{Name: "/", OwnerID: source.ID}).Error
+}
@@ -0,0 +1,81 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeUser(t *testing.T) {
+	asserts := assert.New(t)
+	source := &User{Storage: 10}
+	source.ID = 2
+	target := &User{Storage: 5}
+	target.ID = 3
+	parent := &Folder{}
+	parent.ID = 30
+
+	// 根目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(MergeUser(source, target, parent, "merged"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目标目录下存在同名目录
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT count(.+)folders(.+)").WithArgs(30, "merged").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+		mock.ExpectRollback()
+		asserts.Equal(ErrMergeFolderExisted, MergeUser(source, target, parent, "merged"))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 转移文件失败
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT count(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)folders(.+)owner_id").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 5))
+		mock.ExpectExec("UPDATE(.+)folders(.+)name(.+)parent_id").WithArgs("merged", 30, 20).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(MergeUser(source, target, parent, "merged"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(10, source.Storage)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(20))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT count(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectExec("UPDATE(.+)folders(.+)owner_id").WillReturnResult(sqlmock.NewResult(1, 5))
+		mock.ExpectExec("UPDATE(.+)folders(.+)name(.+)parent_id").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)files(.+)user_id").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 3))
+		mock.ExpectExec("UPDATE(.+)shares(.+)user_id").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)tasks(.+)user_id").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)downloads(.+)user_id").WithArgs(3, 2).WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)users(.+)storage").WithArgs(10, 3).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)status(.+)storage").WithArgs(Baned, 0, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)api_tokens(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)user_sessions(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)webdavs(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)s3_access_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)ssh_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("INSERT(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(40, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(30).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(30, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)folder_num").WithArgs(1, 30).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(MergeUser(source, target, parent, "merged"))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(15, target.Storage)
+		asserts.EqualValues(0, source.Storage)
+		asserts.Equal(Baned, source.Status)
+	}
+}
@@ -0,0 +1,74 @@
+package model
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// userSessionTTL 登录会话闲置多久后视为失效，与会话 Cookie 有效期一致
+const userSessionTTL = 7 * 24 * time.Hour
+
+// UserSession 用户的登录会话，用于列出登录设备及远程注销
+type UserSession struct {
+	gorm.Model
+	SessionKey string    `gorm:"unique_index" json:"-"` // 会话标识，保存在会话中
+	UserID     uint      `gorm:"index"`                 // 用户ID
+	UserAgent  string    `gorm:"type:text"`             // 登录设备的 User-Agent
+	IP         string    // 最后访问 IP
+	LastSeen   time.Time // 最后访问时间
+}
+
+// Create 创建会话记录
+func (session *UserSession) Create() error {
+	key, err := newSecret("")
+	if err != nil {
+		return err
+	}
+
+	session.SessionKey = key
+	session.LastSeen = time.Now()
+	return DB.Create(session).Error
+}
+
+// Touch 记录会话的访问时间及 IP，五分钟内重复访问且 IP 不变时不更新
+func (session *UserSession) Touch(ip string) {
+	now := time.Now()
+	if now.Sub(session.LastSeen) < 5*time.Minute && session.IP == ip {
+		return
+	}
+
+	session.LastSeen = now
+	session.IP = ip
+	DB.Model(session).UpdateColumns(map[string]interface{}{"last_seen": now, "ip": ip})
+}
+
+// GetUserSession 根据会话标识查找未失效的会话
+func GetUserSession(key string) (*UserSession, error) {
+	session := &UserSession{}
+	err := DB.Where("session_key = ? and last_seen > ?", key, time.Now().Add(-userSessionTTL)).First(session).Error
+	return session, err
+}
+
+// ListUserSessions 列出用户未失效的会话
+func ListUserSessions(uid uint) []UserSession {
+	var sessions []UserSession
+	DB.Where("user_id = ? and last_seen > ?", uid, time.Now().Add(-userSessionTTL)).
+		Order("last_seen desc").Find(&sessions)
+	return sessions
+}
+
+// DeleteUserSessionByID 根据会话ID和UID注销会话
+func DeleteUserSessionByID(id, uid uint) {
+	DB.Where("user_id = ? and id = ?", uid, id).Delete(&UserSession{})
+}
+
+// DeleteUserSessionByKey 根据会话标识注销会话
+func DeleteUserSessionByKey(key string) {
+	DB.Where("session_key = ?", key).Delete(&UserSession{})
+}
+
+// DeleteOtherUserSessions 注销用户除指定会话外的全部会话
+func DeleteOtherUserSessions(uid uint, key string) {
+	DB.Where("user_id = ? and session_key <> ?", uid, key).Delete(&UserSession{})
+}
@@ -0,0 +1,80 @@
+package model
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSession_Create(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		session := UserSession{UserID: 1}
+		asserts.NoError(session.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotEmpty(session.SessionKey)
+		asserts.False(session.LastSeen.IsZero())
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		session := UserSession{UserID: 1}
+		asserts.Error(session.Create())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestUserSession_Touch(t *testing.T) {
+	asserts := assert.New(t)
+	session := UserSession{IP: "127.0.0.1", LastSeen: time.Now()}
+	session.ID = 1
+
+	// 短时间内同一 IP 不更新
+	session.Touch("127.0.0.1")
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// IP 变化
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	session.Touch("127.0.0.2")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal("127.0.0.2", session.IP)
+}
+
+func TestGetUserSession(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)user_sessions(.+)").WithArgs("key", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 2))
+	session, err := GetUserSession("key")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.EqualValues(2, session.UserID)
+
+	mock.ExpectQuery("SELECT(.+)user_sessions(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	_, err = GetUserSession("key")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Error(err)
+}
+
+func TestDeleteOtherUserSessions(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)user_sessions(.+)").WithArgs(sqlmock.AnyArg(), 1, "current").
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectCommit()
+	DeleteOtherUserSessions(1, "current")
+	asserts.NoError(mock.ExpectationsWereMet())
+}
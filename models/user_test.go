@@ -3,6 +3,7 @@ package model
 import (
 	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
@@ -219,6 +220,17 @@ func TestUser_GetPolicyID(t *testing.T) {
 
 	newUser.Group.PolicyList = []uint{}
 	asserts.EqualValues(0, newUser.GetPolicyID(0))
+
+	// 跳过暂停接收新上传的存储策略
+	newUser.Group.PolicyList = []uint{1, 2}
+	SetPolicyUploadSuspended(1, true)
+	asserts.EqualValues(2, newUser.GetPolicyID(0))
+
+	// 全部暂停时使用首个存储策略
+	SetPolicyUploadSuspended(2, true)
+	asserts.EqualValues(1, newUser.GetPolicyID(0))
+	SetPolicyUploadSuspended(1, false)
+	SetPolicyUploadSuspended(2, false)
 }
 
 func TestUser_GetRemainingCapacity(t *testing.T) {
@@ -240,6 +252,10 @@ func TestUser_GetRemainingCapacity(t *testing.T) {
 	newUser.Group.MaxStorage = 100
 	newUser.Storage = 200
 	asserts.Equal(uint64(0), newUser.GetRemainingCapacity())
+
+	// 单独设置的容量优先
+	newUser.Quota = 300
+	asserts.Equal(uint64(100), newUser.GetRemainingCapacity())
 }
 
 func TestUser_DeductionCapacity(t *testing.T) {
@@ -414,6 +430,119 @@ func TestUser_IsAnonymous(t *testing.T) {
 	asserts.False(user.IsAnonymous())
 }
 
+func TestUser_IsAdmin(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	user.ID = 2
+	asserts.False(user.IsAdmin())
+	user.GroupID = 1
+	asserts.True(user.IsAdmin())
+	user.GroupID = 2
+	user.ID = 1
+	asserts.True(user.IsAdmin())
+}
+
+func TestUser_ScheduleDeletion(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	user.ID = 2
+
+	// 申请注销
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)delete_after(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.ScheduleDeletion(24 * time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(user.DeleteAfter)
+		asserts.True(user.DeleteAfter.After(time.Now()))
+	}
+
+	// 撤销注销
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)delete_after(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.CancelDeletion())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(user.DeleteAfter)
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(user.ScheduleDeletion(time.Hour))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(user.DeleteAfter)
+	}
+}
+
+func TestListUsersPendingDeletion(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)delete_after(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	users, err := ListUsersPendingDeletion()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 2)
+}
+
+func TestListDLNAPublishers(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Deletes([]string{"1"}, "policy_")
+	mock.ExpectQuery("SELECT(.+)users(.+)options(.+)").
+		WithArgs(Active, `%"dlna_folder":%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "options", "group_id"}).AddRow(2, `{"dlna_folder":5}`, 1))
+	mock.ExpectQuery("SELECT(.+)groups(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policies"}).AddRow(1, "管理员", "[1]"))
+	mock.ExpectQuery("SELECT(.+)policies(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "默认存储策略"))
+	users, err := ListDLNAPublishers()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 1)
+	asserts.EqualValues(5, users[0].OptionsSerialized.DLNAFolder)
+	asserts.EqualValues(1, users[0].Group.ID)
+}
+
+func TestListUserRecords(t *testing.T) {
+	asserts := assert.New(t)
+	var tags []Tag
+	mock.ExpectQuery("SELECT(.+)tags(.+)").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 1).AddRow(2, 1))
+	asserts.NoError(ListUserRecords(1, &tags))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Len(tags, 2)
+}
+
+func TestUser_Purge(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	user.ID = 2
+
+	for i := 0; i < 10; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("(.+)").WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+	}
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)file_changes(.+)").WithArgs(2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("(.+)").WithArgs(sqlmock.AnyArg(), 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)parent_id(.+)").WithArgs(2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)users(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(user.Purge())
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestUser_SetStatus(t *testing.T) {
 	asserts := assert.New(t)
 	user := User{}
@@ -436,3 +565,295 @@ func TestUser_UpdateOptions(t *testing.T) {
 	asserts.NoError(user.UpdateOptions())
 	asserts.NoError(mock.ExpectationsWereMet())
 }
+
+func TestUser_ChangePassword(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	user.ID = 2
+	asserts.NoError(user.SetPassword("first"))
+
+	// 保留两条历史记录
+	for _, pw := range []string{"second", "third", "fourth"} {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)password(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.ChangePassword(pw, 3))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	asserts.NotNil(user.PasswordChangedAt)
+	asserts.Len(user.passwordHistory(), 2)
+	ok, _ := user.CheckPassword("fourth")
+	asserts.True(ok)
+
+	// 不记录历史
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)password(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.ChangePassword("fifth", 0))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Empty(user.PasswordHistory)
+	}
+}
+
+func TestUser_UsedPassword(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{}
+	asserts.NoError(user.SetPassword("second"))
+	old := User{}
+	asserts.NoError(old.SetPassword("first"))
+	user.PasswordHistory = old.Password
+
+	asserts.False(user.UsedPassword("second", 0))
+	asserts.True(user.UsedPassword("second", 1))
+	asserts.False(user.UsedPassword("first", 1))
+	asserts.True(user.UsedPassword("first", 2))
+	asserts.False(user.UsedPassword("third", 2))
+}
+
+func TestUser_UpgradeGroup(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{GroupID: 2}
+	user.ID = 2
+	until := time.Now().Add(24 * time.Hour)
+
+	// 首次调整
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)group_expires(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.UpgradeGroup(4, until))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(4, user.GroupID)
+		asserts.EqualValues(2, user.PreviousGroupID)
+	}
+
+	// 更换临时用户组时保留最初的用户组
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)group_expires(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.UpgradeGroup(5, until.Add(time.Hour)))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(5, user.GroupID)
+		asserts.EqualValues(2, user.PreviousGroupID)
+	}
+
+	// 到期恢复
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)group_expires(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "User"))
+		asserts.NoError(user.RevertGroup())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.EqualValues(2, user.GroupID)
+		asserts.Equal("User", user.Group.Name)
+		asserts.Nil(user.GroupExpires)
+		asserts.EqualValues(0, user.PreviousGroupID)
+	}
+}
+
+func TestListUsersGroupExpired(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)group_expires(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	users, err := ListUsersGroupExpired()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 2)
+}
+
+func TestUser_SetStorage(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Storage: 10}
+	user.ID = 1
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)storage(.+)").WithArgs(5, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(user.SetStorage(5))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(5, user.Storage)
+}
+
+func TestListUsersStorageMismatch(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT users.id FROM(.+)left join(.+)coalesce(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(3))
+	ids, err := ListUsersStorageMismatch()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal([]uint{2, 3}, ids)
+}
+
+func TestListGuestsExpired(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)expires_at(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	users, err := ListGuestsExpired()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 1)
+}
+
+func TestUser_ExpireGuest(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{Status: Active}
+	user.ID = 2
+
+	// 列取分享失败
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnError(errors.New("error"))
+		_, err := user.ExpireGuest()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Equal(Active, user.Status)
+	}
+
+	// 撤销分享失败
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)status(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		_, err := user.ExpireGuest()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Equal(Active, user.Status)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)status(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)shares(.+)").WillReturnResult(sqlmock.NewResult(1, 2))
+		mock.ExpectExec("UPDATE(.+)api_tokens(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)user_sessions(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)webdavs(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)s3_access_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectExec("UPDATE(.+)ssh_keys(.+)").WillReturnResult(sqlmock.NewResult(1, 0))
+		mock.ExpectCommit()
+		shares, err := user.ExpireGuest()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(shares, 2)
+		asserts.Equal(Baned, user.Status)
+	}
+}
+
+func TestUser_AddUploadTraffic(t *testing.T) {
+	asserts := assert.New(t)
+	user := User{UploadTraffic: 10, UploadTrafficDate: "2000-01-01"}
+	user.ID = 1
+	asserts.EqualValues(0, user.UploadedToday())
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)upload_traffic(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(user.AddUploadTraffic(5))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+	asserts.EqualValues(10, user.UploadedToday())
+}
+
+func TestSetEmailStatus(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 永久退信
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)email_status(.+)").
+			WithArgs(EmailBounced, "a@example.com").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		count, err := SetEmailStatus("a@example.com", EmailBounced)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(1, count)
+	}
+
+	// 软退信不覆盖其他状态
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)email_status(.+)").
+			WithArgs(EmailSoftBounced, "a@example.com", EmailDeliverable).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		count, err := SetEmailStatus("a@example.com", EmailSoftBounced)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.EqualValues(0, count)
+	}
+
+	// 投递成功清除软退信
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)email_status(.+)").
+			WithArgs(EmailDeliverable, "a@example.com", EmailSoftBounced).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		_, err := SetEmailStatus("a@example.com", EmailDeliverable)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+	}
+}
+
+func TestIsEmailSuppressed(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)email_status in(.+)").
+		WithArgs("a@example.com", EmailBounced, EmailComplained).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	asserts.True(IsEmailSuppressed("a@example.com"))
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	asserts.False(IsEmailSuppressed("b@example.com"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestIsPhoneUsed(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)phone = (.+)").
+		WithArgs("+8613800138000", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	asserts.True(IsPhoneUsed("+8613800138000", 1))
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	asserts.False(IsPhoneUsed("+8613800138001", 0))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestCountUsersCreated(t *testing.T) {
+	asserts := assert.New(t)
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 7)
+
+	mock.ExpectQuery("SELECT count(.+)users(.+)created_at >= (.+)").
+		WithArgs(from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	count, err := CountUsersCreated(from, to)
+	asserts.NoError(err)
+	asserts.Equal(3, count)
+
+	mock.ExpectQuery("SELECT count(.+)").WillReturnError(errors.New("error"))
+	_, err = CountUsersCreated(from, to)
+	asserts.Error(err)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestListAdmins(t *testing.T) {
+	asserts := assert.New(t)
+
+	mock.ExpectQuery("SELECT(.+)users(.+)group_id = (.+)").
+		WithArgs(1, Active).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "admin@cloudreve.org"))
+	users, err := ListAdmins()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(users, 1)
+	asserts.Equal("admin@cloudreve.org", users[0].Email)
+}
@@ -1,16 +1,23 @@
 package model
 
 import (
+	"time"
+
 	"github.com/jinzhu/gorm"
 )
 
 // Webdav 应用账户
 type Webdav struct {
 	gorm.Model
-	Name     string // 应用名称
-	Password string `gorm:"unique_index:password_only_on"` // 应用密码
-	UserID   uint   `gorm:"unique_index:password_only_on"` // 用户ID
-	Root     string `gorm:"type:text"`                     // 根目录
+	Name       string     // 应用名称
+	Password   string     `gorm:"unique_index:password_only_on"` // 应用密码
+	UserID     uint       `gorm:"unique_index:password_only_on"` // 用户ID
+	Root       string     `gorm:"type:text"`                     // 根目录，挂载分享时为分享目录下的路径
+	ShareID    uint       // 挂载的分享ID，为 0 时挂载用户自己的文件
+	Readonly   bool       // 只读，不允许修改文件
+	ExpiredAt  *time.Time // 过期时间，为空时永不过期
+	LastUsedAt *time.Time // 最后使用时间
+	LastIP     string     // 最后使用的 IP
 }
 
 // Create 创建账户
@@ -21,6 +28,34 @@ func (webdav *Webdav) Create() (uint, error) {
 	return webdav.ID, nil
 }
 
+// Expired 账户是否已过期
+func (webdav *Webdav) Expired() bool {
+	return webdav.ExpiredAt != nil && webdav.ExpiredAt.Before(time.Now())
+}
+
+// MountedShare 返回账户挂载的分享，分享已失效或不再允许用户挂载时返回错误
+func (webdav *Webdav) MountedShare(user *User) (*Share, error) {
+	share := &Share{}
+	if err := DB.First(share, webdav.ShareID).Error; err != nil {
+		return nil, err
+	}
+	if err := share.CanBeMountedBy(user); err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// Touch 记录账户的使用时间及 IP，五分钟内重复使用且 IP 不变时不更新
+func (webdav *Webdav) Touch(ip string) {
+	now := time.Now()
+	if webdav.LastUsedAt != nil && now.Sub(*webdav.LastUsedAt) < 5*time.Minute && webdav.LastIP == ip {
+		return
+	}
+	webdav.LastUsedAt = &now
+	webdav.LastIP = ip
+	DB.Model(webdav).UpdateColumns(map[string]interface{}{"last_used_at": now, "last_ip": ip})
+}
+
 // GetWebdavByPassword 根据密码和用户查找Webdav应用
 func GetWebdavByPassword(password string, uid uint) (*Webdav, error) {
 	webdav := &Webdav{}
@@ -0,0 +1,71 @@
+package model
+
+// WebDAV 自定义属性名称的最大长度
+const WebdavPropNameMaxLength = 255
+
+// WebdavProp WebDAV 客户端为文件或目录设置的自定义属性（dead property），
+// Value 保存属性值的 XML 原文
+type WebdavProp struct {
+	ID       uint   `gorm:"primary_key"`
+	IsDir    bool   `gorm:"index:webdav_prop_object"`
+	ObjectID uint   `gorm:"index:webdav_prop_object"`
+	Space    string `gorm:"size:255"`
+	Name     string `gorm:"size:255"`
+	Lang     string
+	Value    string `gorm:"type:text"`
+}
+
+// WebdavPropPatch 对自定义属性的一次修改，Remove 为真时删除属性，否则设置属性
+type WebdavPropPatch struct {
+	Remove bool
+	Prop   WebdavProp
+}
+
+// GetWebdavProps 获取对象的自定义属性
+func GetWebdavProps(isDir bool, objectID uint) ([]WebdavProp, error) {
+	var props []WebdavProp
+	err := DB.Where("is_dir = ? and object_id = ?", isDir, objectID).Find(&props).Error
+	return props, err
+}
+
+// GetWebdavPropsByParent 获取目录下所有子目录及文件的自定义属性
+func GetWebdavPropsByParent(folderID uint) ([]WebdavProp, error) {
+	var props []WebdavProp
+	childFolders := DB.Model(&Folder{}).Select("id").Where("parent_id = ?", folderID).QueryExpr()
+	childFiles := DB.Model(&File{}).Select("id").Where("folder_id = ?", folderID).QueryExpr()
+	err := DB.Where("(is_dir = ? and object_id in (?)) or (is_dir = ? and object_id in (?))",
+		true, childFolders, false, childFiles).Find(&props).Error
+	return props, err
+}
+
+// PatchWebdavProps 按顺序修改对象的自定义属性，全部修改在同一事务中完成
+func PatchWebdavProps(isDir bool, objectID uint, patches []WebdavPropPatch) error {
+	tx := DB.Begin()
+	for _, patch := range patches {
+		if err := tx.Where("is_dir = ? and object_id = ? and space = ? and name = ?",
+			isDir, objectID, patch.Prop.Space, patch.Prop.Name).Delete(&WebdavProp{}).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+
+		if patch.Remove {
+			continue
+		}
+
+		prop := patch.Prop
+		prop.ID = 0
+		prop.IsDir = isDir
+		prop.ObjectID = objectID
+		if err := tx.Create(&prop).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	return tx.Commit().Error
+}
+
+// DeleteWebdavPropsByObjects 删除已删除对象的自定义属性
+func DeleteWebdavPropsByObjects(ids []uint, isDir bool) error {
+	return DB.Where("object_id in (?) and is_dir = ?", ids, isDir).Delete(&WebdavProp{}).Error
+}
@@ -0,0 +1,87 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetWebdavProps(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)webdav_props(.+)").
+		WithArgs(false, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "space", "name", "value"}).AddRow(1, "urn:test", "color", "red"))
+	props, err := GetWebdavProps(false, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(props, 1)
+	asserts.Equal("red", props[0].Value)
+}
+
+func TestGetWebdavPropsByParent(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectQuery("SELECT(.+)webdav_props(.+)folders(.+)files(.+)").
+		WithArgs(true, 1, false, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir", "object_id"}).AddRow(1, true, 2).AddRow(2, false, 3))
+	props, err := GetWebdavPropsByParent(1)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(props, 2)
+}
+
+func TestPatchWebdavProps(t *testing.T) {
+	asserts := assert.New(t)
+	patches := []WebdavPropPatch{
+		{Prop: WebdavProp{Space: "urn:test", Name: "color", Value: "red"}},
+		{Remove: true, Prop: WebdavProp{Space: "urn:test", Name: "size"}},
+	}
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").
+			WithArgs(true, 1, "urn:test", "color").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT(.+)webdav_props(.+)").
+			WithArgs(true, 1, "urn:test", "color", "", "red").
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").
+			WithArgs(true, 1, "urn:test", "size").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		asserts.NoError(PatchWebdavProps(true, 1, patches))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 写入失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("INSERT(.+)webdav_props(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(PatchWebdavProps(true, 1, patches))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 删除失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(PatchWebdavProps(false, 1, patches))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestDeleteWebdavPropsByObjects(t *testing.T) {
+	asserts := assert.New(t)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE(.+)webdav_props(.+)").
+		WithArgs(1, 2, true).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	asserts.NoError(DeleteWebdavPropsByObjects([]uint{1, 2}, true))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
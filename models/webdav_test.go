@@ -5,6 +5,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestWebdav_Create(t *testing.T) {
@@ -58,3 +59,34 @@ func TestDeleteWebDAVAccountByID(t *testing.T) {
 	DeleteWebDAVAccountByID(1, 1)
 	asserts.NoError(mock.ExpectationsWereMet())
 }
+
+func TestWebdav_Expired(t *testing.T) {
+	asserts := assert.New(t)
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	asserts.False((&Webdav{}).Expired())
+	asserts.False((&Webdav{ExpiredAt: &future}).Expired())
+	asserts.True((&Webdav{ExpiredAt: &past}).Expired())
+}
+
+func TestWebdav_MountedShare(t *testing.T) {
+	asserts := assert.New(t)
+	account := &Webdav{ShareID: 3}
+
+	// 分享不存在
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		share, err := account.MountedShare(&User{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(share)
+	}
+
+	// 不再允许挂载
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "is_dir"}).AddRow(3, false))
+		share, err := account.MountedShare(&User{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(share)
+	}
+}
@@ -16,7 +16,10 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Monitor 离线下载状态监控
@@ -49,6 +52,20 @@ func NewMonitor(task *model.Download, pool cluster.Pool, mqClient mq.MQ) {
 	}
 }
 
+// logger 监控使用的 Logger，附加创建任务的请求 ID
+func (monitor *Monitor) logger() *util.Logger {
+	return util.Log().Module("aria2").WithRequestID(monitor.Task.RequestID)
+}
+
+// context 返回附加了创建任务的请求 ID 及所在链路的上下文
+func (monitor *Monitor) context() context.Context {
+	ctx := tracing.Extract(context.Background(), monitor.Task.TraceParent)
+	if monitor.Task.RequestID != "" {
+		ctx = util.WithRequestID(ctx, monitor.Task.RequestID)
+	}
+	return ctx
+}
+
 // Loop 开启监控循环
 func (monitor *Monitor) Loop(mqClient mq.MQ) {
 	defer mqClient.Unsubscribe(monitor.Task.GID, monitor.notifier)
@@ -77,11 +94,11 @@ func (monitor *Monitor) Update() bool {
 
 	if err != nil {
 		monitor.retried++
-		util.Log().Warning("无法获取下载任务[%s]的状态，%s", monitor.Task.GID, err)
+		monitor.logger().Warning("无法获取下载任务[%s]的状态，%s", monitor.Task.GID, err)
 
 		// 十次重试后认定为任务失败
 		if monitor.retried > MAX_RETRY {
-			util.Log().Warning("无法获取下载任务[%s]的状态，超过最大重试次数限制，%s", monitor.Task.GID, err)
+			monitor.logger().Warning("无法获取下载任务[%s]的状态，超过最大重试次数限制，%s", monitor.Task.GID, err)
 			monitor.setErrorStatus(err)
 			monitor.RemoveTempFolder()
 			return true
@@ -93,7 +110,7 @@ func (monitor *Monitor) Update() bool {
 
 	// 磁力链下载需要跟随
 	if len(status.FollowedBy) > 0 {
-		util.Log().Debug("离线下载[%s]重定向至[%s]", monitor.Task.GID, status.FollowedBy[0])
+		monitor.logger().Debug("离线下载[%s]重定向至[%s]", monitor.Task.GID, status.FollowedBy[0])
 		monitor.Task.GID = status.FollowedBy[0]
 		monitor.Task.Save()
 		return false
@@ -101,13 +118,13 @@ func (monitor *Monitor) Update() bool {
 
 	// 更新任务信息
 	if err := monitor.UpdateTaskInfo(status); err != nil {
-		util.Log().Warning("无法更新下载任务[%s]的任务信息[%s]，", monitor.Task.GID, err)
+		monitor.logger().Warning("无法更新下载任务[%s]的任务信息[%s]，", monitor.Task.GID, err)
 		monitor.setErrorStatus(err)
 		monitor.RemoveTempFolder()
 		return true
 	}
 
-	util.Log().Debug("离线下载[%s]更新状态[%s]", status.Gid, status.Status)
+	monitor.logger().Debug("离线下载[%s]更新状态[%s]", status.Gid, status.Status)
 
 	switch status.Status {
 	case "complete":
@@ -122,7 +139,7 @@ func (monitor *Monitor) Update() bool {
 		monitor.RemoveTempFolder()
 		return true
 	default:
-		util.Log().Warning("下载任务[%s]返回未知状态信息[%s]，", monitor.Task.GID, status.Status)
+		monitor.logger().Warning("下载任务[%s]返回未知状态信息[%s]，", monitor.Task.GID, status.Status)
 		return true
 	}
 }
@@ -235,6 +252,11 @@ func (monitor *Monitor) RemoveTempFolder() {
 
 // Complete 完成下载，返回是否中断监控
 func (monitor *Monitor) Complete(pool task.Pool) bool {
+	ctx, span := tracing.Start(monitor.context(), "aria2.complete",
+		trace.WithAttributes(attribute.String("aria2.gid", monitor.Task.GID)),
+	)
+	defer span.End()
+
 	// 创建中转任务
 	file := make([]string, 0, len(monitor.Task.StatusInfo.Files))
 	sizes := make(map[string]uint64, len(monitor.Task.StatusInfo.Files))
@@ -263,7 +285,7 @@ func (monitor *Monitor) Complete(pool task.Pool) bool {
 	}
 
 	// 提交中转任务
-	pool.Submit(job)
+	pool.Submit(task.Trace(job, ctx))
 
 	// 更新任务ID
 	monitor.Task.TaskID = job.Model().ID
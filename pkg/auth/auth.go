@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -25,8 +26,23 @@ var (
 
 const CrHeaderPrefix = "X-Cr-"
 
-// General 通用的认证接口
-var General Auth
+// General 通用的认证接口，会被中间件等长期持有，签名密钥变更时仅替换其内部的鉴权器
+var General Auth = general
+
+var general = &reloadableAuth{}
+
+// reloadableAuth 可在运行时并发安全地替换的鉴权器
+type reloadableAuth struct {
+	current atomic.Value
+}
+
+func (a *reloadableAuth) Sign(body string, expires int64) string {
+	return a.current.Load().(Auth).Sign(body, expires)
+}
+
+func (a *reloadableAuth) Check(body string, sign string) error {
+	return a.current.Load().(Auth).Check(body, sign)
+}
 
 // Auth 鉴权认证
 type Auth interface {
@@ -139,7 +155,7 @@ func Init() {
 			util.Log().Panic("未指定 SlaveSecret，请前往配置文件中指定")
 		}
 	}
-	General = HMACAuth{
+	general.current.Store(HMACAuth{
 		SecretKey: []byte(secretKey),
-	}
+	})
 }
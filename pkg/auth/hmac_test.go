@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -85,6 +86,15 @@ func TestInit(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "value"}).AddRow(1, "12312312312312"))
 	Init()
 	asserts.NoError(mock.ExpectationsWereMet())
+	sign := general.Sign("content", 0)
+	asserts.NoError(general.Check("content", sign))
+
+	// 重新初始化后已持有的鉴权器使用新的密钥
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "value"}).AddRow(1, "45645645645645"))
+	cache.Deletes([]string{"secret_key"}, "setting_")
+	Init()
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Error(general.Check("content", sign))
 
 	// slave模式
 	conf.SystemConfig.Mode = "slave"
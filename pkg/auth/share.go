@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// SignSharePreview 为分享签发 ttl 秒后失效的预览令牌，令牌仅对 scope 目录及其子目录下的对象有效，
+// 分享密码变更后已签发的令牌随之失效
+func SignSharePreview(instance Auth, share *model.Share, scope string, ttl int64) string {
+	scope = path.Clean("/" + scope)
+	sign := instance.Sign(sharePreviewBody(share, scope), time.Now().Unix()+ttl)
+	return base64.RawURLEncoding.EncodeToString([]byte(scope)) + "." + sign
+}
+
+// CheckSharePreview 检查预览令牌对分享中 target 路径下的对象是否有效
+func CheckSharePreview(instance Auth, share *model.Share, token, target string) error {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return ErrAuthFailed
+	}
+
+	rawScope, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return ErrAuthFailed.WithError(err)
+	}
+
+	scope := string(rawScope)
+	if err := instance.Check(sharePreviewBody(share, scope), parts[1]); err != nil {
+		return err
+	}
+
+	// 目标路径需位于令牌作用范围内
+	if scope != "/" {
+		target = path.Clean("/" + target)
+		if target != scope && !strings.HasPrefix(target, scope+"/") {
+			return ErrAuthFailed
+		}
+	}
+
+	return nil
+}
+
+func sharePreviewBody(share *model.Share, scope string) string {
+	return fmt.Sprintf("share-preview:%d:%s:%s", share.ID, share.Password, scope)
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignSharePreview(t *testing.T) {
+	asserts := assert.New(t)
+	instance := HMACAuth{SecretKey: []byte(util.RandStringRunes(256))}
+	share := &model.Share{Model: gorm.Model{ID: 1}, Password: "123"}
+
+	// 作用于整个分享
+	{
+		token := SignSharePreview(instance, share, "", 60)
+		asserts.NoError(CheckSharePreview(instance, share, token, "/a/b.jpg"))
+		asserts.NoError(CheckSharePreview(instance, share, token, ""))
+	}
+
+	// 作用于子目录
+	{
+		token := SignSharePreview(instance, share, "/gallery/", 60)
+		asserts.NoError(CheckSharePreview(instance, share, token, "/gallery/1.jpg"))
+		asserts.NoError(CheckSharePreview(instance, share, token, "/gallery/sub/../2.jpg"))
+		asserts.Error(CheckSharePreview(instance, share, token, "/gallery2/1.jpg"))
+		asserts.Error(CheckSharePreview(instance, share, token, "/gallery/../secret.txt"))
+		asserts.Error(CheckSharePreview(instance, share, token, ""))
+	}
+
+	// 其他分享或密码已变更
+	{
+		token := SignSharePreview(instance, share, "/", 60)
+		asserts.Error(CheckSharePreview(instance, &model.Share{Model: gorm.Model{ID: 2}, Password: "123"}, token, "/"))
+		asserts.Error(CheckSharePreview(instance, &model.Share{Model: gorm.Model{ID: 1}, Password: "456"}, token, "/"))
+	}
+
+	// 格式错误或已过期
+	{
+		asserts.Error(CheckSharePreview(instance, share, "invalid", "/"))
+		asserts.Error(CheckSharePreview(instance, share, "!!.sign:0", "/"))
+		token := SignSharePreview(instance, share, "/", -int64(time.Hour.Seconds()))
+		asserts.Error(CheckSharePreview(instance, share, token, "/"))
+	}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// SignTwoFactorRemember 为完成二步验证的设备签发 ttl 秒后失效的免验证令牌，
+// 用户关闭或重新开启二步验证后已签发的令牌随之失效
+func SignTwoFactorRemember(instance Auth, user *model.User, ttl int64) string {
+	return instance.Sign(twoFactorRememberBody(user), time.Now().Unix()+ttl)
+}
+
+// CheckTwoFactorRemember 检查设备的免验证令牌对用户是否有效
+func CheckTwoFactorRemember(instance Auth, user *model.User, token string) error {
+	return instance.Check(twoFactorRememberBody(user), token)
+}
+
+func twoFactorRememberBody(user *model.User) string {
+	sum := sha256.Sum256([]byte(user.TwoFactor))
+	return fmt.Sprintf("2fa-remember:%d:%s", user.ID, hex.EncodeToString(sum[:]))
+}
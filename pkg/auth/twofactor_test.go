@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignTwoFactorRemember(t *testing.T) {
+	asserts := assert.New(t)
+	instance := HMACAuth{SecretKey: []byte(util.RandStringRunes(256))}
+	user := &model.User{Model: gorm.Model{ID: 1}, TwoFactor: "secret"}
+
+	// 有效
+	{
+		token := SignTwoFactorRemember(instance, user, 60)
+		asserts.NoError(CheckTwoFactorRemember(instance, user, token))
+	}
+
+	// 其他用户或二步验证密钥已变更
+	{
+		token := SignTwoFactorRemember(instance, user, 60)
+		asserts.Error(CheckTwoFactorRemember(instance, &model.User{Model: gorm.Model{ID: 2}, TwoFactor: "secret"}, token))
+		asserts.Error(CheckTwoFactorRemember(instance, &model.User{Model: gorm.Model{ID: 1}, TwoFactor: "other"}, token))
+	}
+
+	// 已过期
+	{
+		token := SignTwoFactorRemember(instance, user, -1)
+		asserts.Error(CheckTwoFactorRemember(instance, user, token))
+	}
+}
@@ -0,0 +1,264 @@
+package backup
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// FormatVersion 备份文件格式版本，格式不兼容时递增
+const FormatVersion = 1
+
+const (
+	manifestName = "manifest.json"
+	tablePrefix  = "tables/"
+	thumbPrefix  = "thumbs/"
+	timeKey      = "$t"
+
+	// 导出缩略图时每批查询的文件数
+	thumbBatchSize = 500
+)
+
+var (
+	// ErrInvalidArchive 备份文件缺少清单或格式不正确
+	ErrInvalidArchive = errors.New("invalid backup archive")
+	// ErrIncompatibleVersion 备份文件来自更新的版本，无法恢复
+	ErrIncompatibleVersion = errors.New("backup is created by a newer version of Cloudreve")
+	// ErrInstanceNotEmpty 目标站点已有数据
+	ErrInstanceNotEmpty = errors.New("target instance is not empty")
+)
+
+// Manifest 备份清单
+type Manifest struct {
+	// 备份文件格式版本
+	Format int `json:"format"`
+	// 创建备份的程序版本及数据库版本
+	Version   string    `json:"version"`
+	DBVersion string    `json:"db_version"`
+	DBType    string    `json:"db_type"`
+	CreatedAt time.Time `json:"created_at"`
+	// 各数据表的记录数
+	Tables map[string]int `json:"tables"`
+	// 包含的缩略图数量
+	Thumbnails int `json:"thumbnails"`
+}
+
+// Options 备份选项
+type Options struct {
+	// 是否包含本机存储策略下的缩略图
+	Thumbnails bool
+}
+
+// Export 导出全部数据表及站点设置至 zip 格式的备份文件，记录按数据库原始列值导出，
+// 不受模型序列化规则影响
+func Export(w io.Writer, opts Options) (*Manifest, error) {
+	manifest := &Manifest{
+		Format:    FormatVersion,
+		Version:   conf.BackendVersion,
+		DBVersion: conf.RequiredDBVersion,
+		DBType:    model.DB.Dialect().GetName(),
+		CreatedAt: time.Now(),
+		Tables:    make(map[string]int),
+	}
+
+	archive := zip.NewWriter(w)
+	for _, table := range tables() {
+		entry, err := archive.Create(tablePrefix + table + ".jsonl")
+		if err != nil {
+			return nil, err
+		}
+
+		count, err := exportTable(entry, table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export table %q: %w", table, err)
+		}
+		manifest.Tables[table] = count
+	}
+
+	if opts.Thumbnails {
+		count, err := exportThumbnails(archive)
+		if err != nil {
+			return nil, fmt.Errorf("failed to export thumbnails: %w", err)
+		}
+		manifest.Thumbnails = count
+	}
+
+	entry, err := archive.Create(manifestName)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.NewEncoder(entry).Encode(manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, archive.Close()
+}
+
+// tables 返回全部数据表名
+func tables() []string {
+	models := model.Models()
+	names := make([]string, 0, len(models))
+	for _, m := range models {
+		names = append(names, model.DB.NewScope(m).TableName())
+	}
+	return names
+}
+
+// exportTable 导出数据表，首行为列名，其后每行为一条记录
+func exportTable(w io.Writer, table string) (int, error) {
+	rows, err := model.DB.Raw("SELECT * FROM " + model.DB.Dialect().Quote(table)).Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	buf := bufio.NewWriter(w)
+	encoder := json.NewEncoder(buf)
+	if err := encoder.Encode(columns); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return count, err
+		}
+
+		cells := make([]interface{}, len(values))
+		for i, v := range values {
+			cells[i] = encodeValue(v)
+		}
+		if err := encoder.Encode(cells); err != nil {
+			return count, err
+		}
+		count++
+	}
+
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+
+	return count, buf.Flush()
+}
+
+// exportThumbnails 导出本机存储策略下文件的缩略图
+func exportThumbnails(archive *zip.Writer) (int, error) {
+	count := 0
+	err := eachThumbnail(func(name string) error {
+		ok, err := exportFile(archive, thumbEntryName(name), util.RelativePath(name))
+		if ok {
+			count++
+		}
+		return err
+	})
+
+	return count, err
+}
+
+// eachThumbnail 遍历本机存储策略下带有缩略图的文件，fn 的参数为缩略图相对于程序目录的路径
+func eachThumbnail(fn func(name string) error) error {
+	var policyIDs []uint
+	if err := model.DB.Model(&model.Policy{}).Where("type = ?", "local").Pluck("id", &policyIDs).Error; err != nil {
+		return err
+	}
+	if len(policyIDs) == 0 {
+		return nil
+	}
+
+	suffix := model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
+	var lastID uint
+	for {
+		var files []model.File
+		if err := model.DB.Where("id > ? and policy_id in (?) and pic_info <> ?", lastID, policyIDs, "").
+			Order("id").Limit(thumbBatchSize).Find(&files).Error; err != nil {
+			return err
+		}
+
+		for _, file := range files {
+			if err := fn(file.SourceName + suffix); err != nil {
+				return err
+			}
+		}
+
+		if len(files) < thumbBatchSize {
+			return nil
+		}
+		lastID = files[len(files)-1].ID
+	}
+}
+
+// thumbEntryName 缩略图在备份文件中的路径
+func thumbEntryName(name string) string {
+	return thumbPrefix + path.Clean("/" + filepath.ToSlash(name))[1:]
+}
+
+// exportFile 将本地文件写入备份，文件不存在时跳过
+func exportFile(archive *zip.Writer, name, src string) (bool, error) {
+	file, err := os.Open(src)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer file.Close()
+
+	entry, err := archive.Create(name)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = io.Copy(entry, file)
+	return err == nil, err
+}
+
+// encodeValue 将数据库驱动返回的列值转换为可序列化的值，时间类型单独标记以便恢复
+func encodeValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case []byte:
+		return string(value)
+	case time.Time:
+		return map[string]string{timeKey: value.Format(time.RFC3339Nano)}
+	}
+	return v
+}
+
+// decodeValue 还原 encodeValue 转换的列值
+func decodeValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i, nil
+		}
+		return value.Float64()
+	case map[string]interface{}:
+		s, ok := value[timeKey].(string)
+		if !ok {
+			return nil, ErrInvalidArchive
+		}
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	return v, nil
+}
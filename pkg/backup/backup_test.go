@@ -0,0 +1,149 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestEncodeDecodeValue(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now().Truncate(time.Microsecond)
+
+	row := []interface{}{int64(1), []byte("name"), now, nil, 1.5, true}
+	encoded := make([]interface{}, len(row))
+	for i, v := range row {
+		encoded[i] = encodeValue(v)
+	}
+
+	raw, err := json.Marshal(encoded)
+	asserts.NoError(err)
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var cells []interface{}
+	asserts.NoError(decoder.Decode(&cells))
+
+	expected := []interface{}{int64(1), "name", now, nil, 1.5, true}
+	for i, cell := range cells {
+		value, err := decodeValue(cell)
+		asserts.NoError(err)
+		if ts, ok := value.(time.Time); ok {
+			asserts.True(now.Equal(ts))
+			continue
+		}
+		asserts.Equal(expected[i], value)
+	}
+
+	// 无效的时间标记
+	_, err = decodeValue(map[string]interface{}{"else": "1"})
+	asserts.Equal(ErrInvalidArchive, err)
+}
+
+func TestExportTable(t *testing.T) {
+	asserts := assert.New(t)
+	buf := &bytes.Buffer{}
+
+	mock.ExpectQuery("SELECT (.+)").WillReturnRows(
+		sqlmock.NewRows([]string{"id", "name", "value"}).
+			AddRow(1, "siteName", "Cloudreve").
+			AddRow(2, "siteURL", nil),
+	)
+	count, err := exportTable(buf, "settings")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Equal(2, count)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	asserts.Len(lines, 3)
+	asserts.Equal(`["id","name","value"]`, lines[0])
+	asserts.Equal(`[1,"siteName","Cloudreve"]`, lines[1])
+	asserts.Equal(`[2,"siteURL",null]`, lines[2])
+}
+
+func TestThumbEntryName(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("thumbs/uploads/1/a.jpg._thumb", thumbEntryName("uploads/1/a.jpg._thumb"))
+	asserts.Equal("thumbs/data/a.jpg._thumb", thumbEntryName("/data/a.jpg._thumb"))
+	asserts.Equal("thumbs/a.jpg._thumb", thumbEntryName("../../a.jpg._thumb"))
+}
+
+func TestCheckCompatible(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.NoError(CheckCompatible(&Manifest{Format: FormatVersion, DBVersion: conf.RequiredDBVersion}))
+	asserts.NoError(CheckCompatible(&Manifest{Format: FormatVersion, DBVersion: "3.0.0"}))
+	asserts.Equal(ErrIncompatibleVersion, CheckCompatible(&Manifest{Format: FormatVersion, DBVersion: "99.0.0"}))
+	asserts.Equal(ErrIncompatibleVersion, CheckCompatible(&Manifest{Format: FormatVersion + 1, DBVersion: "3.0.0"}))
+	asserts.Equal(ErrInvalidArchive, CheckCompatible(&Manifest{Format: FormatVersion, DBVersion: "invalid"}))
+}
+
+func TestRestore(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不是 zip 文件
+	{
+		data := []byte("not a zip")
+		_, err := Restore(bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		asserts.Equal(ErrInvalidArchive, err)
+	}
+
+	newArchive := func(manifest *Manifest) []byte {
+		buf := &bytes.Buffer{}
+		archive := zip.NewWriter(buf)
+		if manifest != nil {
+			entry, _ := archive.Create(manifestName)
+			json.NewEncoder(entry).Encode(manifest)
+		}
+		archive.Close()
+		return buf.Bytes()
+	}
+
+	// 缺少清单
+	{
+		data := newArchive(nil)
+		_, err := Restore(bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		asserts.Equal(ErrInvalidArchive, err)
+	}
+
+	// 来自更新的版本
+	{
+		data := newArchive(&Manifest{Format: FormatVersion, DBVersion: "99.0.0"})
+		_, err := Restore(bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		asserts.Equal(ErrIncompatibleVersion, err)
+	}
+
+	// 目标站点已有数据
+	{
+		data := newArchive(&Manifest{Format: FormatVersion, DBVersion: conf.RequiredDBVersion})
+		mock.ExpectQuery("SELECT count(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT count(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(10))
+		_, err := Restore(bytes.NewReader(data), int64(len(data)), RestoreOptions{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrInstanceNotEmpty, err)
+	}
+}
@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/hashicorp/go-version"
+	"github.com/jinzhu/gorm"
+)
+
+// RestoreOptions 恢复选项
+type RestoreOptions struct {
+	// 目标站点已有数据时仍然恢复，已有数据将被覆盖
+	Force bool
+}
+
+// Restore 从备份文件恢复数据表及缩略图。备份来自较旧的数据库版本时，
+// 下次启动将按当前版本执行数据库迁移及升级脚本
+func Restore(r io.ReaderAt, size int64, opts RestoreOptions) (*Manifest, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+
+	manifest, err := readManifest(entries[manifestName])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := CheckCompatible(manifest); err != nil {
+		return nil, err
+	}
+
+	if !opts.Force && !isFresh() {
+		return nil, ErrInstanceNotEmpty
+	}
+
+	known := make(map[string]bool)
+	tx := model.DB.Begin()
+	for _, table := range tables() {
+		known[table] = true
+		entry, ok := entries[tablePrefix+table+".jsonl"]
+		if !ok {
+			// 由更新的版本引入的数据表，保留初始数据
+			continue
+		}
+
+		if err := restoreTable(tx, table, entry); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to restore table %q: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	for table := range manifest.Tables {
+		if !known[table] {
+			util.Log().Warning("备份中的数据表 %q 在当前版本中已不存在，已跳过", table)
+		}
+	}
+
+	resetSequences()
+	clearCache()
+
+	if manifest.Thumbnails > 0 {
+		if err := restoreThumbnails(entries); err != nil {
+			return manifest, fmt.Errorf("failed to restore thumbnails: %w", err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// CheckCompatible 检查备份能否恢复至当前版本，仅支持恢复由相同或更旧的数据库版本创建的备份
+func CheckCompatible(manifest *Manifest) error {
+	if manifest.Format > FormatVersion {
+		return ErrIncompatibleVersion
+	}
+
+	backupVersion, err := version.NewVersion(manifest.DBVersion)
+	if err != nil {
+		return ErrInvalidArchive
+	}
+
+	currentVersion, err := version.NewVersion(conf.RequiredDBVersion)
+	if err != nil {
+		return err
+	}
+
+	if backupVersion.GreaterThan(currentVersion) {
+		return ErrIncompatibleVersion
+	}
+
+	return nil
+}
+
+func readManifest(entry *zip.File) (*Manifest, error) {
+	if entry == nil {
+		return nil, ErrInvalidArchive
+	}
+
+	f, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var manifest Manifest
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil || manifest.Format == 0 {
+		return nil, ErrInvalidArchive
+	}
+
+	return &manifest, nil
+}
+
+// isFresh 目标站点是否为尚未使用的新站点，仅有初始管理员且没有文件
+func isFresh() bool {
+	var users, files int
+	model.DB.Model(&model.User{}).Count(&users)
+	model.DB.Model(&model.File{}).Count(&files)
+	return users <= 1 && files == 0
+}
+
+// restoreTable 清空数据表后写入备份中的记录，当前版本中已不存在的列被忽略
+func restoreTable(tx *gorm.DB, table string, entry *zip.File) error {
+	f, err := entry.Open()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.UseNumber()
+
+	var columns []string
+	if err := decoder.Decode(&columns); err != nil {
+		return ErrInvalidArchive
+	}
+
+	dialect := tx.Dialect()
+	keep := make([]int, 0, len(columns))
+	quoted := make([]string, 0, len(columns))
+	for i, column := range columns {
+		if dialect.HasColumn(table, column) {
+			keep = append(keep, i)
+			quoted = append(quoted, dialect.Quote(column))
+		} else {
+			util.Log().Warning("数据表 %q 的列 %q 在当前版本中已不存在，已跳过", table, column)
+		}
+	}
+
+	if err := tx.Exec("DELETE FROM " + dialect.Quote(table)).Error; err != nil {
+		return err
+	}
+
+	if len(keep) == 0 {
+		return nil
+	}
+
+	// SQL Server 需允许显式写入自增主键
+	if dialect.GetName() == "mssql" {
+		tx.Exec("SET IDENTITY_INSERT " + dialect.Quote(table) + " ON")
+		defer tx.Exec("SET IDENTITY_INSERT " + dialect.Quote(table) + " OFF")
+	}
+
+	stmt := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", dialect.Quote(table),
+		strings.Join(quoted, ","), strings.TrimSuffix(strings.Repeat("?,", len(keep)), ","))
+	for decoder.More() {
+		var cells []interface{}
+		if err := decoder.Decode(&cells); err != nil || len(cells) != len(columns) {
+			return ErrInvalidArchive
+		}
+
+		args := make([]interface{}, len(keep))
+		for j, i := range keep {
+			if args[j], err = decodeValue(cells[i]); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Exec(stmt, args...).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resetSequences 写入显式主键后，PostgreSQL 需将自增序列调整至当前最大值
+func resetSequences() {
+	dialect := model.DB.Dialect()
+	if dialect.GetName() != "postgres" {
+		return
+	}
+
+	for _, table := range tables() {
+		if !dialect.HasColumn(table, "id") {
+			continue
+		}
+
+		if err := model.DB.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 1)) FROM %s",
+			table, dialect.Quote(table))).Error; err != nil {
+			util.Log().Warning("无法重置数据表 %q 的自增序列，%s", table, err)
+		}
+	}
+}
+
+// clearCache 清除恢复前缓存的设置项等数据，并重新初始化依赖设置的组件
+func clearCache() {
+	if instance, ok := cache.Store.(*cache.RedisStore); ok {
+		instance.DeleteAll()
+	}
+
+	var names []string
+	model.DB.Model(&model.Setting{}).Pluck("name", &names)
+	model.NotifySettingChange(names...)
+}
+
+// restoreThumbnails 按恢复后的文件记录写回缩略图，写入路径由文件记录决定，不使用备份中的路径
+func restoreThumbnails(entries map[string]*zip.File) error {
+	return eachThumbnail(func(name string) error {
+		entry, ok := entries[thumbEntryName(name)]
+		if !ok {
+			return nil
+		}
+
+		src, err := entry.Open()
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+
+		out, err := util.CreatNestedFile(util.RelativePath(name))
+		if err != nil {
+			return err
+		}
+		defer out.Close()
+
+		_, err = io.Copy(out, src)
+		return err
+	})
+}
@@ -12,13 +12,7 @@ var Store Driver = NewMemoStore()
 // Init 初始化缓存
 func Init(isSlave bool) {
 	if conf.RedisConfig.Server != "" && gin.Mode() != gin.TestMode {
-		Store = NewRedisStore(
-			10,
-			conf.RedisConfig.Network,
-			conf.RedisConfig.Server,
-			conf.RedisConfig.Password,
-			conf.RedisConfig.DB,
-		)
+		Store = NewRedisStoreWithPool(NewRedisPool(10, RedisOptionsFromConfig()))
 	}
 
 	if isSlave {
@@ -45,6 +39,13 @@ type Driver interface {
 
 	// 删除值
 	Delete(keys []string, prefix string) error
+
+	// 计数器加一并返回新值，计数器不存在时新建，ttl为新建计数器的过期时间，单位为秒
+	Incr(key string, ttl int) (int64, error)
+
+	// 设置位图中 offset 处的位并返回该位原先的值及设置后的位图，位图按 Redis 的位序排列，
+	// 位图不存在时新建，ttl为位图的过期时间，单位为秒
+	SetBit(key string, offset int, value bool, ttl int) (bool, []byte, error)
 }
 
 // Set 设置缓存值
@@ -57,6 +58,16 @@ func Get(key string) (interface{}, bool) {
 	return Store.Get(key)
 }
 
+// Incr 计数器加一并返回新值
+func Incr(key string, ttl int) (int64, error) {
+	return Store.Incr(key, ttl)
+}
+
+// SetBit 设置位图中的一位
+func SetBit(key string, offset int, value bool, ttl int) (bool, []byte, error) {
+	return Store.SetBit(key, offset, value, ttl)
+}
+
 // Deletes 删除值
 func Deletes(keys []string, prefix string) error {
 	return Store.Delete(keys, prefix)
@@ -10,6 +10,9 @@ import (
 // MemoStore 内存存储驱动
 type MemoStore struct {
 	Store *sync.Map
+
+	// atomicLock 保证计数器及位图读取与写入的原子性
+	atomicLock sync.Mutex
 }
 
 // item 存储的对象
@@ -110,3 +113,52 @@ func (store *MemoStore) Delete(keys []string, prefix string) error {
 	}
 	return nil
 }
+
+// Incr 计数器加一，已过期或非计数器的值将被重置
+func (store *MemoStore) Incr(key string, ttl int) (int64, error) {
+	store.atomicLock.Lock()
+	defer store.atomicLock.Unlock()
+
+	if raw, ok := store.Store.Load(key); ok {
+		item, ok := raw.(itemWithTTL)
+		if ok && (item.expires <= 0 || item.expires >= time.Now().Unix()) {
+			if count, ok := item.value.(int64); ok {
+				item.value = count + 1
+				store.Store.Store(key, item)
+				return count + 1, nil
+			}
+		}
+	}
+
+	store.Store.Store(key, newItem(int64(1), ttl))
+	return 1, nil
+}
+
+// SetBit 设置位图中的一位，已过期或非位图的值将被重置
+func (store *MemoStore) SetBit(key string, offset int, value bool, ttl int) (bool, []byte, error) {
+	store.atomicLock.Lock()
+	defer store.atomicLock.Unlock()
+
+	var bits []byte
+	if raw, ok := store.Store.Load(key); ok {
+		item, ok := raw.(itemWithTTL)
+		if ok && (item.expires <= 0 || item.expires >= time.Now().Unix()) {
+			bits, _ = item.value.([]byte)
+		}
+	}
+
+	if len(bits) <= offset/8 {
+		bits = append(bits, make([]byte, offset/8+1-len(bits))...)
+	}
+
+	mask := byte(0x80) >> uint(offset%8)
+	prev := bits[offset/8]&mask != 0
+	if value {
+		bits[offset/8] |= mask
+	} else {
+		bits[offset/8] &^= mask
+	}
+
+	store.Store.Store(key, newItem(bits, ttl))
+	return prev, append([]byte(nil), bits...), nil
+}
@@ -145,3 +145,58 @@ func TestMemoStore_GarbageCollect(t *testing.T) {
 	_, ok := store.Get("test")
 	asserts.False(ok)
 }
+
+func TestMemoStore_Incr(t *testing.T) {
+	asserts := assert.New(t)
+	store := NewMemoStore()
+
+	// 新建、累加
+	for i := 1; i <= 3; i++ {
+		count, err := store.Incr("test", 10)
+		asserts.NoError(err)
+		asserts.EqualValues(i, count)
+	}
+	value, ok := store.Get("test")
+	asserts.True(ok)
+	asserts.EqualValues(3, value)
+
+	// 非计数器的值被重置
+	store.Set("test", "value", 0)
+	count, _ := store.Incr("test", 10)
+	asserts.EqualValues(1, count)
+
+	// 已过期的计数器被重置
+	store.Store.Store("test", itemWithTTL{value: int64(5), expires: time.Now().Unix() - 1})
+	count, _ = store.Incr("test", 10)
+	asserts.EqualValues(1, count)
+}
+
+func TestMemoStore_SetBit(t *testing.T) {
+	asserts := assert.New(t)
+	store := NewMemoStore()
+
+	// 新建位图并按需扩展
+	prev, bits, err := store.SetBit("test", 9, true, 10)
+	asserts.NoError(err)
+	asserts.False(prev)
+	asserts.Equal([]byte{0x00, 0x40}, bits)
+
+	prev, bits, _ = store.SetBit("test", 0, true, 10)
+	asserts.False(prev)
+	asserts.Equal([]byte{0x80, 0x40}, bits)
+
+	// 重复设置返回原先的值
+	prev, _, _ = store.SetBit("test", 9, true, 10)
+	asserts.True(prev)
+
+	// 清除
+	prev, bits, _ = store.SetBit("test", 9, false, 10)
+	asserts.True(prev)
+	asserts.Equal([]byte{0x80, 0x00}, bits)
+
+	// 已过期的位图被重置
+	store.Store.Store("test", itemWithTTL{value: []byte{0xff}, expires: time.Now().Unix() - 1})
+	prev, bits, _ = store.SetBit("test", 1, true, 10)
+	asserts.False(prev)
+	asserts.Equal([]byte{0x40}, bits)
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"encoding/gob"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gomodule/redigo/redis"
 )
@@ -43,36 +45,119 @@ func deserializer(value []byte) (interface{}, error) {
 	return res.Value, nil
 }
 
-// NewRedisStore 创建新的redis存储
-func NewRedisStore(size int, network, address, password, database string) *RedisStore {
-	return &RedisStore{
-		pool: &redis.Pool{
+// RedisOptions Redis 连接选项
+type RedisOptions struct {
+	Network string
+	// 单机模式下为服务器地址，哨兵及集群模式下为节点地址列表
+	Addrs    []string
+	Password string
+	DB       string
+	// 哨兵模式下主节点的名称
+	MasterName       string
+	SentinelPassword string
+	// 是否为集群模式，集群模式下只能使用 0 号数据库
+	Cluster bool
+}
+
+// dialRedis 创建 Redis 连接，测试时可替换
+var dialRedis = redis.Dial
+
+// RedisOptionsFromConfig 从配置文件读取 Redis 连接选项
+func RedisOptionsFromConfig() RedisOptions {
+	addrs := make([]string, 0)
+	for _, addr := range strings.Split(conf.RedisConfig.Server, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+
+	return RedisOptions{
+		Network:          conf.RedisConfig.Network,
+		Addrs:            addrs,
+		Password:         conf.RedisConfig.Password,
+		DB:               conf.RedisConfig.DB,
+		MasterName:       conf.RedisConfig.MasterName,
+		SentinelPassword: conf.RedisConfig.SentinelPassword,
+		Cluster:          conf.RedisConfig.Cluster,
+	}
+}
+
+// NewRedisPool 根据选项创建 Redis 连接池。哨兵模式下每次建立连接时向哨兵查询主节点地址，
+// 主从切换后旧连接在取出时被丢弃；集群模式下连接按键所在的哈希槽路由命令
+func NewRedisPool(size int, opts RedisOptions) *redis.Pool {
+	if opts.Cluster {
+		cluster := newRedisCluster(size, opts)
+		return &redis.Pool{
 			MaxIdle:     size,
 			IdleTimeout: 240 * time.Second,
-			TestOnBorrow: func(c redis.Conn, t time.Time) error {
-				_, err := c.Do("PING")
-				return err
-			},
 			Dial: func() (redis.Conn, error) {
-				db, err := strconv.Atoi(database)
-				if err != nil {
-					return nil, err
-				}
+				return &clusterConn{cluster: cluster}, nil
+			},
+		}
+	}
 
-				c, err := redis.Dial(
-					network,
-					address,
-					redis.DialDatabase(db),
-					redis.DialPassword(password),
-				)
+	pool := &redis.Pool{
+		MaxIdle:     size,
+		IdleTimeout: 240 * time.Second,
+		TestOnBorrow: func(c redis.Conn, t time.Time) error {
+			_, err := c.Do("PING")
+			return err
+		},
+		Dial: func() (redis.Conn, error) {
+			db, err := strconv.Atoi(opts.DB)
+			if err != nil {
+				return nil, err
+			}
+
+			address := ""
+			if len(opts.Addrs) > 0 {
+				address = opts.Addrs[0]
+			}
+
+			if opts.MasterName != "" {
+				address, err = sentinelMasterAddr(opts)
 				if err != nil {
-					util.Log().Warning("无法创建Redis连接：%s", err)
+					util.Log().Warning("无法获取Redis主节点地址：%s", err)
 					return nil, err
 				}
-				return c, nil
-			},
+			}
+
+			c, err := dialRedis(
+				opts.Network,
+				address,
+				redis.DialDatabase(db),
+				redis.DialPassword(opts.Password),
+			)
+			if err != nil {
+				util.Log().Warning("无法创建Redis连接：%s", err)
+				return nil, err
+			}
+			return c, nil
 		},
 	}
+
+	if opts.MasterName != "" {
+		pool.TestOnBorrow = func(c redis.Conn, t time.Time) error {
+			return checkMasterRole(c)
+		}
+	}
+
+	return pool
+}
+
+// NewRedisStore 创建新的redis存储
+func NewRedisStore(size int, network, address, password, database string) *RedisStore {
+	return NewRedisStoreWithPool(NewRedisPool(size, RedisOptions{
+		Network:  network,
+		Addrs:    []string{address},
+		Password: password,
+		DB:       database,
+	}))
+}
+
+// NewRedisStoreWithPool 使用给定的连接池创建redis存储
+func NewRedisStoreWithPool(pool *redis.Pool) *RedisStore {
+	return &RedisStore{pool: pool}
 }
 
 // Set 存储值
@@ -215,3 +300,59 @@ func (store *RedisStore) DeleteAll() error {
 
 	return err
 }
+
+// setBitScript 设置位并刷新过期时间，返回该位原先的值及设置后的位图
+const setBitScript = `local prev = redis.call('SETBIT', KEYS[1], ARGV[1], ARGV[2])
+if tonumber(ARGV[3]) > 0 then redis.call('EXPIRE', KEYS[1], ARGV[3]) end
+return {prev, redis.call('GET', KEYS[1])}`
+
+// Incr 计数器加一，新建计数器时设置过期时间
+func (store *RedisStore) Incr(key string, ttl int) (int64, error) {
+	rc := store.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return 0, rc.Err()
+	}
+
+	count, err := redis.Int64(rc.Do("INCR", key))
+	if err != nil {
+		return 0, err
+	}
+
+	if count == 1 && ttl > 0 {
+		if _, err := rc.Do("EXPIRE", key, ttl); err != nil {
+			return count, err
+		}
+	}
+
+	return count, nil
+}
+
+// SetBit 使用脚本设置位图中的一位，设置与读取位图在服务端原子执行
+func (store *RedisStore) SetBit(key string, offset int, value bool, ttl int) (bool, []byte, error) {
+	rc := store.pool.Get()
+	defer rc.Close()
+	if rc.Err() != nil {
+		return false, nil, rc.Err()
+	}
+
+	bit := 0
+	if value {
+		bit = 1
+	}
+
+	res, err := redis.Values(rc.Do("EVAL", setBitScript, 1, key, offset, bit, ttl))
+	if err != nil {
+		return false, nil, err
+	}
+
+	var (
+		prev int64
+		bits []byte
+	)
+	if _, err := redis.Scan(res, &prev, &bits); err != nil {
+		return false, nil, err
+	}
+
+	return prev == 1, bits, nil
+}
@@ -0,0 +1,397 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gomodule/redigo/redis"
+)
+
+const (
+	// Redis 集群的哈希槽数量
+	clusterSlots = 16384
+	// 遇到重定向或节点故障时的最大尝试次数
+	clusterMaxAttempts = 5
+	// 节点故障时重试的间隔
+	clusterRetryDelay = 100 * time.Millisecond
+)
+
+var (
+	// ErrClusterPipeline 集群模式下不支持流水线命令
+	ErrClusterPipeline = errors.New("pipelining is not supported in redis cluster mode")
+	// ErrNoClusterNode 未配置集群节点
+	ErrNoClusterNode = errors.New("no redis cluster node configured")
+)
+
+// redisCluster Redis 集群客户端，按键所在的哈希槽将命令路由至对应的主节点
+type redisCluster struct {
+	opts RedisOptions
+	size int
+
+	mu    sync.RWMutex
+	slots []string
+	pools map[string]*redis.Pool
+
+	// 同一时间只进行一次槽位加载
+	refreshLock sync.Mutex
+}
+
+func newRedisCluster(size int, opts RedisOptions) *redisCluster {
+	if opts.DB != "" && opts.DB != "0" {
+		util.Log().Warning("Redis 集群模式仅支持 0 号数据库，已忽略数据库设置 %q", opts.DB)
+	}
+
+	return &redisCluster{
+		opts:  opts,
+		size:  size,
+		slots: make([]string, clusterSlots),
+		pools: make(map[string]*redis.Pool),
+	}
+}
+
+// pool 获取节点的连接池，不存在时创建
+func (c *redisCluster) pool(addr string) *redis.Pool {
+	c.mu.RLock()
+	p, ok := c.pools[addr]
+	c.mu.RUnlock()
+	if ok {
+		return p
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if p, ok := c.pools[addr]; ok {
+		return p
+	}
+
+	p = &redis.Pool{
+		MaxIdle:     c.size,
+		IdleTimeout: 240 * time.Second,
+		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
+			_, err := conn.Do("PING")
+			return err
+		},
+		Dial: func() (redis.Conn, error) {
+			conn, err := dialRedis(c.opts.Network, addr, redis.DialPassword(c.opts.Password))
+			if err != nil {
+				util.Log().Warning("无法创建Redis集群节点 %q 的连接：%s", addr, err)
+			}
+			return conn, err
+		},
+	}
+	c.pools[addr] = p
+	return p
+}
+
+// nodeFor 返回哈希槽所在的主节点地址，槽位尚未加载时先加载槽位
+func (c *redisCluster) nodeFor(slot int) string {
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+	if addr != "" {
+		return addr
+	}
+
+	c.refresh()
+	c.mu.RLock()
+	addr = c.slots[slot]
+	c.mu.RUnlock()
+	if addr == "" && len(c.opts.Addrs) > 0 {
+		// 由节点返回的重定向确定实际位置
+		return c.opts.Addrs[0]
+	}
+	return addr
+}
+
+// masters 返回全部主节点地址
+func (c *redisCluster) masters() []string {
+	c.mu.RLock()
+	loaded := c.slots[0] != ""
+	c.mu.RUnlock()
+	if !loaded {
+		c.refresh()
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	seen := make(map[string]bool)
+	masters := make([]string, 0)
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			masters = append(masters, addr)
+		}
+	}
+
+	if len(masters) == 0 && len(c.opts.Addrs) > 0 {
+		masters = append(masters, c.opts.Addrs[0])
+	}
+	return masters
+}
+
+// refresh 依次从配置的节点及已知的节点加载哈希槽分布
+func (c *redisCluster) refresh() error {
+	c.refreshLock.Lock()
+	defer c.refreshLock.Unlock()
+
+	candidates := append([]string{}, c.opts.Addrs...)
+	c.mu.RLock()
+	for addr := range c.pools {
+		candidates = append(candidates, addr)
+	}
+	c.mu.RUnlock()
+
+	lastErr := ErrNoClusterNode
+	tried := make(map[string]bool)
+	for _, addr := range candidates {
+		if tried[addr] {
+			continue
+		}
+		tried[addr] = true
+
+		slots, err := c.loadSlots(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+		return nil
+	}
+
+	util.Log().Warning("无法加载Redis集群的哈希槽分布：%s", lastErr)
+	return lastErr
+}
+
+// loadSlots 通过 CLUSTER SLOTS 命令获取哈希槽分布
+func (c *redisCluster) loadSlots(addr string) ([]string, error) {
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+
+	ranges, err := redis.Values(conn.Do("CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	slots := make([]string, clusterSlots)
+	for _, raw := range ranges {
+		entry, err := redis.Values(raw, nil)
+		if err != nil || len(entry) < 3 {
+			return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply from %q", addr)
+		}
+
+		start, err1 := redis.Int(entry[0], nil)
+		end, err2 := redis.Int(entry[1], nil)
+		master, err3 := redis.Values(entry[2], nil)
+		if err1 != nil || err2 != nil || err3 != nil || len(master) < 2 ||
+			start < 0 || end >= clusterSlots || start > end {
+			return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply from %q", addr)
+		}
+
+		host, _ := redis.String(master[0], nil)
+		port, err := redis.Int(master[1], nil)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected CLUSTER SLOTS reply from %q", addr)
+		}
+		if host == "" {
+			// 节点未声明地址时，与响应的节点相同
+			host, _, _ = net.SplitHostPort(addr)
+		}
+
+		node := net.JoinHostPort(host, strconv.Itoa(port))
+		for slot := start; slot <= end; slot++ {
+			slots[slot] = node
+		}
+	}
+
+	return slots, nil
+}
+
+// doOn 在指定节点上执行命令
+func (c *redisCluster) doOn(addr string, asking bool, cmd string, args ...interface{}) (interface{}, error) {
+	conn := c.pool(addr).Get()
+	defer conn.Close()
+
+	if asking {
+		if _, err := conn.Do("ASKING"); err != nil {
+			return nil, err
+		}
+	}
+
+	return conn.Do(cmd, args...)
+}
+
+// do 在键所在的节点上执行命令，按 MOVED、ASK 重定向，节点故障时重新加载槽位后重试
+func (c *redisCluster) do(key string, cmd string, args ...interface{}) (interface{}, error) {
+	slot := keySlot(key)
+	addr := c.nodeFor(slot)
+	asking := false
+
+	var lastErr error
+	for attempt := 0; attempt < clusterMaxAttempts; attempt++ {
+		reply, err := c.doOn(addr, asking, cmd, args...)
+		if err == nil {
+			return reply, nil
+		}
+
+		lastErr = err
+		asking = false
+		if redisErr, ok := err.(redis.Error); ok {
+			fields := strings.Fields(string(redisErr))
+			switch {
+			case len(fields) == 3 && fields[0] == "MOVED":
+				addr = fields[2]
+				c.mu.Lock()
+				c.slots[slot] = addr
+				c.mu.Unlock()
+			case len(fields) == 3 && fields[0] == "ASK":
+				addr = fields[2]
+				asking = true
+			case len(fields) > 0 && (fields[0] == "TRYAGAIN" || fields[0] == "CLUSTERDOWN"):
+				time.Sleep(clusterRetryDelay)
+				c.refresh()
+				addr = c.nodeFor(slot)
+			default:
+				// 命令本身出错
+				return nil, err
+			}
+			continue
+		}
+
+		// 连接出错，节点可能已下线，重新加载槽位以获取故障转移后的主节点
+		time.Sleep(clusterRetryDelay)
+		c.refresh()
+		addr = c.nodeFor(slot)
+	}
+
+	return nil, lastErr
+}
+
+// clusterConn 集群连接，按键路由单键命令及单键脚本，MGET、MSET、DEL 按键拆分执行
+type clusterConn struct {
+	cluster *redisCluster
+}
+
+func (c *clusterConn) Close() error {
+	return nil
+}
+
+func (c *clusterConn) Err() error {
+	return nil
+}
+
+func (c *clusterConn) Send(string, ...interface{}) error {
+	return ErrClusterPipeline
+}
+
+func (c *clusterConn) Flush() error {
+	return ErrClusterPipeline
+}
+
+func (c *clusterConn) Receive() (interface{}, error) {
+	return nil, ErrClusterPipeline
+}
+
+func (c *clusterConn) Do(cmd string, args ...interface{}) (interface{}, error) {
+	switch strings.ToUpper(cmd) {
+	case "":
+		// 连接池归还连接时发送的空命令
+		return nil, nil
+	case "PING":
+		masters := c.cluster.masters()
+		if len(masters) == 0 {
+			return nil, ErrNoClusterNode
+		}
+		return c.cluster.doOn(masters[0], false, cmd, args...)
+	case "FLUSHDB", "FLUSHALL":
+		for _, addr := range c.cluster.masters() {
+			if _, err := c.cluster.doOn(addr, false, cmd, args...); err != nil {
+				return nil, err
+			}
+		}
+		return "OK", nil
+	case "MGET":
+		replies := make([]interface{}, len(args))
+		for i, arg := range args {
+			reply, err := c.cluster.do(clusterKey(arg), "GET", arg)
+			if err != nil {
+				return nil, err
+			}
+			replies[i] = reply
+		}
+		return replies, nil
+	case "MSET":
+		if len(args)%2 != 0 {
+			return nil, errors.New("wrong number of arguments for MSET")
+		}
+		for i := 0; i < len(args); i += 2 {
+			if _, err := c.cluster.do(clusterKey(args[i]), "SET", args[i], args[i+1]); err != nil {
+				return nil, err
+			}
+		}
+		return "OK", nil
+	case "DEL":
+		var deleted int64
+		for _, arg := range args {
+			n, err := redis.Int64(c.cluster.do(clusterKey(arg), "DEL", arg))
+			if err != nil {
+				return nil, err
+			}
+			deleted += n
+		}
+		return deleted, nil
+	case "EVAL", "EVALSHA":
+		// 脚本按第一个键路由
+		if len(args) < 3 {
+			return nil, fmt.Errorf("command %q without keys is not supported in redis cluster mode", cmd)
+		}
+		return c.cluster.do(clusterKey(args[2]), cmd, args...)
+	}
+
+	if len(args) == 0 {
+		return nil, fmt.Errorf("command %q is not supported in redis cluster mode", cmd)
+	}
+	return c.cluster.do(clusterKey(args[0]), cmd, args...)
+}
+
+// clusterKey 将命令参数转换为键名
+func clusterKey(arg interface{}) string {
+	if b, ok := arg.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(arg)
+}
+
+// keySlot 计算键所在的哈希槽，键中含有 {hashtag} 时仅计算花括号中的部分
+func keySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % clusterSlots)
+}
+
+// crc16 CRC16-CCITT (XMODEM) 校验，与 Redis 集群的键分布算法一致
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,98 @@
+package cache
+
+import (
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeySlot(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.EqualValues(0x31C3, crc16("123456789"))
+	asserts.Equal(12182, keySlot("foo"))
+	asserts.Equal(keySlot("{user1000}.following"), keySlot("{user1000}.followers"))
+	asserts.Equal(keySlot("user1000"), keySlot("{user1000}.following"))
+	// 空的 hashtag 按完整键名计算
+	asserts.NotEqual(keySlot(""), keySlot("foo{}{bar}"))
+}
+
+func TestRedisCluster(t *testing.T) {
+	asserts := assert.New(t)
+	nodes := map[string]*redigomock.Conn{
+		"127.0.0.1:7000": redigomock.NewConn(),
+		"127.0.0.1:7001": redigomock.NewConn(),
+	}
+	dialRedis = func(network, address string, options ...redis.DialOption) (redis.Conn, error) {
+		return nodes[address], nil
+	}
+	defer func() { dialRedis = redis.Dial }()
+
+	for _, node := range nodes {
+		node.Command("PING").Expect("PONG")
+		node.Command("CLUSTER", "SLOTS").Expect([]interface{}{
+			[]interface{}{int64(0), int64(8191), []interface{}{[]byte("127.0.0.1"), int64(7000)}},
+			[]interface{}{int64(8192), int64(16383), []interface{}{[]byte(""), int64(7001)}},
+		})
+	}
+
+	store := NewRedisStoreWithPool(NewRedisPool(10, RedisOptions{
+		Network: "tcp",
+		Addrs:   []string{"127.0.0.1:7000"},
+		Cluster: true,
+	}))
+
+	// 按哈希槽路由，foo 位于 12182 号槽
+	{
+		cmd := nodes["127.0.0.1:7001"].Command("SETEX", "foo", 10, redigomock.NewAnyData()).Expect("OK")
+		asserts.NoError(store.Set("foo", "bar", 10))
+		asserts.Equal(1, nodes["127.0.0.1:7001"].Stats(cmd))
+	}
+
+	// 哈希槽迁移后按 MOVED 重定向
+	{
+		value, _ := serializer("bar")
+		nodes["127.0.0.1:7001"].Command("GET", "foo").ExpectError(redis.Error("MOVED 12182 127.0.0.1:7000"))
+		nodes["127.0.0.1:7000"].Command("GET", "foo").Expect(value)
+		res, ok := store.Get("foo")
+		asserts.True(ok)
+		asserts.Equal("bar", res)
+	}
+
+	// 多键命令按键拆分
+	{
+		cmd1 := nodes["127.0.0.1:7000"].Command("DEL", "foo").Expect(int64(1))
+		cmd2 := nodes["127.0.0.1:7000"].Command("DEL", "bar").Expect(int64(1))
+		asserts.NoError(store.Delete([]string{"foo", "bar"}, ""))
+		asserts.Equal(1, nodes["127.0.0.1:7000"].Stats(cmd1))
+		asserts.Equal(1, nodes["127.0.0.1:7000"].Stats(cmd2))
+	}
+
+	// 清空全部主节点
+	{
+		cmd1 := nodes["127.0.0.1:7000"].Command("FLUSHDB").Expect("OK")
+		cmd2 := nodes["127.0.0.1:7001"].Command("FLUSHDB").Expect("OK")
+		asserts.NoError(store.DeleteAll())
+		asserts.Equal(1, nodes["127.0.0.1:7000"].Stats(cmd1))
+		asserts.Equal(1, nodes["127.0.0.1:7001"].Stats(cmd2))
+	}
+
+	// 脚本按第一个键路由
+	{
+		cmd := nodes["127.0.0.1:7001"].Command("EVAL", setBitScript, 1, "foo", 3, 1, 10).
+			Expect([]interface{}{int64(0), []byte{0x10}})
+		prev, bits, err := store.SetBit("foo", 3, true, 10)
+		asserts.NoError(err)
+		asserts.False(prev)
+		asserts.Equal([]byte{0x10}, bits)
+		asserts.Equal(1, nodes["127.0.0.1:7001"].Stats(cmd))
+	}
+
+	// 不支持流水线命令
+	{
+		conn := store.pool.Get()
+		asserts.Equal(ErrClusterPipeline, conn.Send("GET", "foo"))
+		conn.Close()
+	}
+}
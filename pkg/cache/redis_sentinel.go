@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// 连接哨兵节点的超时时间
+const sentinelTimeout = 3 * time.Second
+
+// ErrNotMaster 连接的节点不是主节点，通常发生在主从切换之后
+var ErrNotMaster = errors.New("redis node is not master")
+
+// sentinelMasterAddr 依次询问哨兵节点，返回主节点的当前地址
+func sentinelMasterAddr(opts RedisOptions) (string, error) {
+	if len(opts.Addrs) == 0 {
+		return "", errors.New("no sentinel address configured")
+	}
+
+	var lastErr error
+	for _, addr := range opts.Addrs {
+		master, err := queryMasterAddr(opts, addr)
+		if err == nil {
+			return master, nil
+		}
+		lastErr = err
+	}
+
+	return "", lastErr
+}
+
+func queryMasterAddr(opts RedisOptions, sentinel string) (string, error) {
+	c, err := dialRedis(
+		opts.Network,
+		sentinel,
+		redis.DialPassword(opts.SentinelPassword),
+		redis.DialConnectTimeout(sentinelTimeout),
+		redis.DialReadTimeout(sentinelTimeout),
+		redis.DialWriteTimeout(sentinelTimeout),
+	)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	res, err := redis.Strings(c.Do("SENTINEL", "get-master-addr-by-name", opts.MasterName))
+	if err == redis.ErrNil {
+		return "", fmt.Errorf("sentinel %q does not monitor master %q", sentinel, opts.MasterName)
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(res) != 2 {
+		return "", fmt.Errorf("unexpected reply from sentinel %q", sentinel)
+	}
+
+	return net.JoinHostPort(res[0], res[1]), nil
+}
+
+// checkMasterRole 确认连接的节点仍为主节点
+func checkMasterRole(c redis.Conn) error {
+	res, err := redis.Values(c.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+
+	if len(res) == 0 {
+		return ErrNotMaster
+	}
+
+	if role, _ := redis.String(res[0], nil); role != "master" {
+		return ErrNotMaster
+	}
+
+	return nil
+}
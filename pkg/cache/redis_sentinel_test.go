@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rafaeljusto/redigomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSentinelMasterAddr(t *testing.T) {
+	asserts := assert.New(t)
+	sentinel := redigomock.NewConn()
+	dialRedis = func(network, address string, options ...redis.DialOption) (redis.Conn, error) {
+		if address == "10.0.0.1:26379" {
+			return nil, errors.New("connection refused")
+		}
+		return sentinel, nil
+	}
+	defer func() { dialRedis = redis.Dial }()
+
+	opts := RedisOptions{
+		Network:    "tcp",
+		Addrs:      []string{"10.0.0.1:26379", "10.0.0.2:26379"},
+		MasterName: "mymaster",
+	}
+
+	// 第一个哨兵不可用时询问下一个
+	{
+		sentinel.Clear()
+		sentinel.Command("SENTINEL", "get-master-addr-by-name", "mymaster").
+			ExpectSlice([]byte("10.0.0.3"), []byte("6379"))
+		addr, err := sentinelMasterAddr(opts)
+		asserts.NoError(err)
+		asserts.Equal("10.0.0.3:6379", addr)
+	}
+
+	// 哨兵未监控此主节点
+	{
+		sentinel.Clear()
+		sentinel.Command("SENTINEL", "get-master-addr-by-name", "mymaster").Expect(nil)
+		_, err := sentinelMasterAddr(opts)
+		asserts.Error(err)
+	}
+
+	// 未配置哨兵
+	{
+		_, err := sentinelMasterAddr(RedisOptions{MasterName: "mymaster"})
+		asserts.Error(err)
+	}
+}
+
+func TestCheckMasterRole(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+
+	conn.Command("ROLE").ExpectSlice([]byte("master"), int64(0), []interface{}{})
+	asserts.NoError(checkMasterRole(conn))
+
+	conn.Clear()
+	conn.Command("ROLE").ExpectSlice([]byte("slave"), []byte("10.0.0.3"), int64(6379), []byte("connected"), int64(0))
+	asserts.Equal(ErrNotMaster, checkMasterRole(conn))
+
+	conn.Clear()
+	conn.Command("ROLE").ExpectError(errors.New("error"))
+	asserts.Error(checkMasterRole(conn))
+}
+
+func TestNewRedisPool_Sentinel(t *testing.T) {
+	asserts := assert.New(t)
+	sentinel := redigomock.NewConn()
+	master := redigomock.NewConn()
+	dialRedis = func(network, address string, options ...redis.DialOption) (redis.Conn, error) {
+		if address == "10.0.0.3:6379" {
+			return master, nil
+		}
+		return sentinel, nil
+	}
+	defer func() { dialRedis = redis.Dial }()
+
+	sentinel.Command("SENTINEL", "get-master-addr-by-name", "mymaster").
+		ExpectSlice([]byte("10.0.0.3"), []byte("6379"))
+	pool := NewRedisPool(10, RedisOptions{
+		Network:    "tcp",
+		Addrs:      []string{"10.0.0.1:26379"},
+		DB:         "0",
+		MasterName: "mymaster",
+	})
+
+	conn, err := pool.Dial()
+	asserts.NoError(err)
+	asserts.Equal(master, conn)
+}
@@ -322,3 +322,100 @@ func TestRedisStore_Delete(t *testing.T) {
 		asserts.Error(err)
 	}
 }
+
+func TestRedisStore_Incr(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	store := &RedisStore{pool: pool}
+
+	// 新建计数器，设置过期时间
+	{
+		cmd := conn.Command("INCR", "test").Expect(int64(1))
+		expire := conn.Command("EXPIRE", "test", 10).Expect(int64(1))
+		count, err := store.Incr("test", 10)
+		asserts.NoError(err)
+		asserts.EqualValues(1, count)
+		asserts.Equal(1, conn.Stats(cmd))
+		asserts.Equal(1, conn.Stats(expire))
+	}
+
+	// 累加
+	{
+		conn.Clear()
+		conn.Command("INCR", "test").Expect(int64(2))
+		count, err := store.Incr("test", 10)
+		asserts.NoError(err)
+		asserts.EqualValues(2, count)
+	}
+
+	// 命令执行失败
+	{
+		conn.Clear()
+		conn.Command("INCR", "test").ExpectError(errors.New("error"))
+		_, err := store.Incr("test", 10)
+		asserts.Error(err)
+	}
+
+	// 连接失败
+	{
+		store.pool = &redis.Pool{
+			Dial:    func() (redis.Conn, error) { return nil, errors.New("error") },
+			MaxIdle: 10,
+		}
+		_, err := store.Incr("test", 10)
+		asserts.Error(err)
+	}
+}
+
+func TestRedisStore_SetBit(t *testing.T) {
+	asserts := assert.New(t)
+	conn := redigomock.NewConn()
+	pool := &redis.Pool{
+		Dial:    func() (redis.Conn, error) { return conn, nil },
+		MaxIdle: 10,
+	}
+	store := &RedisStore{pool: pool}
+
+	// 设置新位
+	{
+		cmd := conn.Command("EVAL", setBitScript, 1, "test", 9, 1, 10).
+			Expect([]interface{}{int64(0), []byte{0x00, 0x40}})
+		prev, bits, err := store.SetBit("test", 9, true, 10)
+		asserts.NoError(err)
+		asserts.False(prev)
+		asserts.Equal([]byte{0x00, 0x40}, bits)
+		asserts.Equal(1, conn.Stats(cmd))
+	}
+
+	// 清除已设置的位
+	{
+		conn.Clear()
+		conn.Command("EVAL", setBitScript, 1, "test", 9, 0, 0).
+			Expect([]interface{}{int64(1), []byte{0x00, 0x00}})
+		prev, _, err := store.SetBit("test", 9, false, 0)
+		asserts.NoError(err)
+		asserts.True(prev)
+	}
+
+	// 命令执行失败
+	{
+		conn.Clear()
+		conn.Command("EVAL", setBitScript, 1, "test", 9, 1, 10).ExpectError(errors.New("error"))
+		_, _, err := store.SetBit("test", 9, true, 10)
+		asserts.Error(err)
+	}
+
+	// 连接失败
+	{
+		store.pool = &redis.Pool{
+			Dial:    func() (redis.Conn, error) { return nil, errors.New("error") },
+			MaxIdle: 10,
+		}
+		_, _, err := store.SetBit("test", 9, true, 10)
+		asserts.Error(err)
+	}
+}
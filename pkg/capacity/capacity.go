@@ -0,0 +1,125 @@
+package capacity
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 容量告警级别
+const (
+	// LevelNormal 剩余容量充足
+	LevelNormal = iota
+	// LevelWarning 剩余容量低于提醒阈值
+	LevelWarning
+	// LevelCritical 剩余容量低于严重阈值
+	LevelCritical
+)
+
+// levelCachePrefix 存储策略上次检查的告警级别的缓存前缀
+const levelCachePrefix = "capacity_level_"
+
+// queryTimeout 查询单个存储策略容量的超时时间
+const queryTimeout = 30 * time.Second
+
+// ErrNotSupported 存储策略不支持查询容量
+var ErrNotSupported = errors.New("storage policy does not support capacity query")
+
+// LevelOf 根据剩余容量占总容量的百分比计算告警级别，阈值为 0 时不告警
+func LevelOf(c *driver.Capacity, warning, critical int) int {
+	if c.Total == 0 {
+		return LevelNormal
+	}
+
+	if critical > 0 && c.Free*100 < c.Total*uint64(critical) {
+		return LevelCritical
+	}
+
+	if warning > 0 && c.Free*100 < c.Total*uint64(warning) {
+		return LevelWarning
+	}
+
+	return LevelNormal
+}
+
+// Query 查询存储策略所在磁盘、从机或云存储账户的容量
+func Query(ctx context.Context, policy *model.Policy) (*driver.Capacity, error) {
+	fs := &filesystem.FileSystem{Policy: policy}
+	if err := fs.DispatchHandler(); err != nil {
+		return nil, err
+	}
+
+	reporter, ok := fs.Handler.(driver.CapacityReporter)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+	return reporter.Capacity(ctx)
+}
+
+// Check 检查全部支持查询容量的存储策略，告警级别升高时通知管理员，并按设置暂停或恢复向剩余容量
+// 严重不足的存储策略路由新上传
+func Check(ctx context.Context) error {
+	policies, err := model.GetPolicies()
+	if err != nil {
+		return err
+	}
+
+	options := model.GetSettingByNames("capacity_warning_percent", "capacity_critical_percent", "capacity_suspend_upload")
+	warning, _ := strconv.Atoi(options["capacity_warning_percent"])
+	critical, _ := strconv.Atoi(options["capacity_critical_percent"])
+	suspend := model.IsTrueVal(options["capacity_suspend_upload"])
+
+	for i := range policies {
+		policy := &policies[i]
+		c, err := Query(ctx, policy)
+		if err == ErrNotSupported {
+			continue
+		}
+
+		if err != nil {
+			util.Log().Warning("无法查询存储策略 [%s] 的容量, %s", policy.Name, err)
+			continue
+		}
+
+		update(policy, c, LevelOf(c, warning, critical), suspend)
+	}
+
+	return nil
+}
+
+// update 记录存储策略的告警级别，级别升高时通知管理员
+func update(policy *model.Policy, c *driver.Capacity, level int, suspend bool) {
+	key := strconv.FormatUint(uint64(policy.ID), 10)
+	last := LevelNormal
+	if v, ok := cache.Get(levelCachePrefix + key); ok {
+		last = v.(int)
+	}
+	cache.Set(levelCachePrefix+key, level, 0)
+
+	suspended := suspend && level == LevelCritical
+	if suspended != model.IsPolicyUploadSuspended(policy.ID) {
+		model.SetPolicyUploadSuspended(policy.ID, suspended)
+		if suspended {
+			util.Log().Warning("存储策略 [%s] 剩余容量严重不足，已暂停路由新上传", policy.Name)
+		} else {
+			util.Log().Info("存储策略 [%s] 已恢复路由新上传", policy.Name)
+		}
+	}
+
+	if level > last {
+		notify.CapacityAlert(policy.Name, c.Free, c.Total, level == LevelCritical, suspended)
+	} else if level == LevelNormal && last != LevelNormal {
+		util.Log().Info("存储策略 [%s] 剩余容量已恢复，剩余 %s", policy.Name, util.FormatSize(c.Free))
+	}
+}
@@ -0,0 +1,86 @@
+package capacity
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestLevelOf(t *testing.T) {
+	asserts := assert.New(t)
+
+	testCases := []struct {
+		free, total uint64
+		level       int
+	}{
+		{50, 100, LevelNormal},
+		{10, 100, LevelNormal},
+		{9, 100, LevelWarning},
+		{4, 100, LevelCritical},
+		{0, 0, LevelNormal},
+	}
+
+	for _, tc := range testCases {
+		asserts.Equal(tc.level, LevelOf(&driver.Capacity{Free: tc.free, Total: tc.total}, 10, 5), tc)
+	}
+
+	// 阈值为 0 时不告警
+	asserts.Equal(LevelNormal, LevelOf(&driver.Capacity{Free: 0, Total: 100}, 0, 0))
+}
+
+func TestUpdate(t *testing.T) {
+	asserts := assert.New(t)
+	policy := &model.Policy{Model: gorm.Model{ID: 10}, Name: "default"}
+	c := &driver.Capacity{Free: 1, Total: 100}
+	defer model.SetPolicyUploadSuspended(10, false)
+
+	// 级别升高时通知管理员并暂停路由新上传
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		update(policy, c, LevelCritical, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(model.IsPolicyUploadSuspended(10))
+	}
+
+	// 级别未变化时不重复通知
+	{
+		update(policy, c, LevelCritical, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(model.IsPolicyUploadSuspended(10))
+	}
+
+	// 级别降低时恢复路由
+	{
+		update(policy, c, LevelWarning, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(model.IsPolicyUploadSuspended(10))
+	}
+
+	// 未开启暂停路由
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		update(policy, c, LevelCritical, false)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(model.IsPolicyUploadSuspended(10))
+	}
+}
@@ -0,0 +1,172 @@
+package clamav
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// chunkSize INSTREAM 命令每次发送的数据块大小
+const chunkSize = 64 << 10
+
+var (
+	// ErrUnsupportedAddress 无法识别的 clamd 地址
+	ErrUnsupportedAddress = errors.New("unsupported clamd address, use tcp://host:port or unix:///path/to/clamd.sock")
+	// ErrSizeLimitExceeded 数据流超过 clamd 的 StreamMaxLength 限制
+	ErrSizeLimitExceeded = errors.New("stream size exceeds clamd StreamMaxLength")
+	// ErrUnexpectedResponse clamd 返回了无法识别的结果
+	ErrUnexpectedResponse = errors.New("unexpected response from clamd")
+)
+
+// Result 扫描结果
+type Result struct {
+	Infected  bool   // 是否检出病毒
+	Signature string // 检出的病毒名称
+}
+
+// Client clamd 客户端
+type Client struct {
+	Network string // tcp 或 unix
+	Address string // 主机及端口，或 Unix Socket 路径
+	Timeout time.Duration
+}
+
+// NewClient 根据地址创建客户端，地址格式为 tcp://host:port 或 unix:///path/to/clamd.sock
+func NewClient(address string, timeout time.Duration) (*Client, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &Client{Network: u.Scheme, Timeout: timeout}
+	switch u.Scheme {
+	case "tcp":
+		client.Address = u.Host
+	case "unix":
+		client.Address = u.Path
+	}
+
+	if client.Address == "" {
+		return nil, ErrUnsupportedAddress
+	}
+	return client, nil
+}
+
+// dial 建立连接，并按超时时间及 ctx 设定读写期限
+func (client *Client) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: client.Timeout}
+	conn, err := dialer.DialContext(ctx, client.Network, client.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if client.Timeout > 0 && (!ok || time.Now().Add(client.Timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(client.Timeout), true
+	}
+	if ok {
+		conn.SetDeadline(deadline)
+	}
+
+	return conn, nil
+}
+
+// command 发送以 \0 结尾的命令并读取响应
+func (client *Client) command(ctx context.Context, cmd string, body func(w io.Writer) error) (string, error) {
+	conn, err := client.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("z" + cmd + "\x00")); err != nil {
+		return "", err
+	}
+
+	var bodyErr error
+	if body != nil {
+		bodyErr = body(conn)
+	}
+
+	// clamd 在数据超过限制时会先返回错误再关闭连接，写入失败时仍尝试读取响应
+	res, err := bufio.NewReader(conn).ReadString(0)
+	if res == "" && bodyErr != nil {
+		return "", bodyErr
+	}
+	if err != nil && !(err == io.EOF && res != "") {
+		return "", err
+	}
+
+	return strings.TrimSpace(strings.TrimRight(res, "\x00")), nil
+}
+
+// Ping 检查 clamd 是否可用
+func (client *Client) Ping(ctx context.Context) error {
+	res, err := client.command(ctx, "PING", nil)
+	if err != nil {
+		return err
+	}
+
+	if res != "PONG" {
+		return fmt.Errorf("%w: %s", ErrUnexpectedResponse, res)
+	}
+	return nil
+}
+
+// Scan 以 INSTREAM 命令扫描数据流
+func (client *Client) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	res, err := client.command(ctx, "INSTREAM", func(w io.Writer) error {
+		buf := make([]byte, chunkSize+4)
+		for {
+			n, err := io.ReadFull(r, buf[4:])
+			if n > 0 {
+				binary.BigEndian.PutUint32(buf[:4], uint32(n))
+				if _, werr := w.Write(buf[:n+4]); werr != nil {
+					return werr
+				}
+			}
+
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		// 以长度为 0 的数据块结束
+		_, err := w.Write([]byte{0, 0, 0, 0})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseResult(res)
+}
+
+// parseResult 解析扫描结果，格式为 stream: OK、stream: <病毒名称> FOUND 或 <错误信息> ERROR
+func parseResult(res string) (*Result, error) {
+	switch {
+	case res == "stream: OK":
+		return &Result{}, nil
+	case strings.HasPrefix(res, "stream: ") && strings.HasSuffix(res, " FOUND"):
+		return &Result{
+			Infected:  true,
+			Signature: strings.TrimSuffix(strings.TrimPrefix(res, "stream: "), " FOUND"),
+		}, nil
+	case strings.HasPrefix(res, "INSTREAM size limit exceeded"):
+		return nil, ErrSizeLimitExceeded
+	case strings.HasSuffix(res, " ERROR"):
+		return nil, errors.New(strings.TrimSuffix(res, " ERROR"))
+	}
+
+	return nil, fmt.Errorf("%w: %s", ErrUnexpectedResponse, res)
+}
@@ -0,0 +1,121 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClamd 模拟的 clamd 服务，检出内容中包含 EICAR 的数据流
+func fakeClamd(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, _ := r.ReadString(0)
+				switch cmd {
+				case "zPING\x00":
+					conn.Write([]byte("PONG\x00"))
+				case "zINSTREAM\x00":
+					var data bytes.Buffer
+					for {
+						var size uint32
+						if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+							break
+						}
+						io.CopyN(&data, r, int64(size))
+					}
+					if strings.Contains(data.String(), "EICAR") {
+						conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+					} else {
+						conn.Write([]byte("stream: OK\x00"))
+					}
+				default:
+					conn.Write([]byte("UNKNOWN COMMAND\x00"))
+				}
+			}(conn)
+		}
+	}()
+
+	return "tcp://" + listener.Addr().String()
+}
+
+func TestNewClient(t *testing.T) {
+	asserts := assert.New(t)
+
+	client, err := NewClient("tcp://127.0.0.1:3310", time.Second)
+	asserts.NoError(err)
+	asserts.Equal("tcp", client.Network)
+	asserts.Equal("127.0.0.1:3310", client.Address)
+
+	client, err = NewClient("unix:///var/run/clamav/clamd.ctl", time.Second)
+	asserts.NoError(err)
+	asserts.Equal("unix", client.Network)
+	asserts.Equal("/var/run/clamav/clamd.ctl", client.Address)
+
+	_, err = NewClient("127.0.0.1:3310", time.Second)
+	asserts.Error(err)
+	_, err = NewClient("http://127.0.0.1:3310", time.Second)
+	asserts.Equal(ErrUnsupportedAddress, err)
+}
+
+func TestClient_Scan(t *testing.T) {
+	asserts := assert.New(t)
+	client, err := NewClient(fakeClamd(t), time.Second)
+	asserts.NoError(err)
+
+	asserts.NoError(client.Ping(context.Background()))
+
+	// 未检出
+	res, err := client.Scan(context.Background(), strings.NewReader(strings.Repeat("a", chunkSize+10)))
+	asserts.NoError(err)
+	asserts.False(res.Infected)
+
+	// 检出
+	res, err = client.Scan(context.Background(), strings.NewReader(strings.Repeat("a", chunkSize)+"EICAR"))
+	asserts.NoError(err)
+	asserts.True(res.Infected)
+	asserts.Equal("Eicar-Test-Signature", res.Signature)
+
+	// 空数据流
+	res, err = client.Scan(context.Background(), strings.NewReader(""))
+	asserts.NoError(err)
+	asserts.False(res.Infected)
+
+	// 无法连接
+	client.Address = "127.0.0.1:1"
+	_, err = client.Scan(context.Background(), strings.NewReader(""))
+	asserts.Error(err)
+}
+
+func TestParseResult(t *testing.T) {
+	asserts := assert.New(t)
+
+	_, err := parseResult("INSTREAM size limit exceeded. ERROR")
+	asserts.Equal(ErrSizeLimitExceeded, err)
+
+	_, err = parseResult("Can't allocate memory ERROR")
+	asserts.EqualError(err, "Can't allocate memory")
+
+	_, err = parseResult("UNKNOWN COMMAND")
+	asserts.ErrorIs(err, ErrUnexpectedResponse)
+}
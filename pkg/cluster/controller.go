@@ -10,8 +10,10 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/jinzhu/gorm"
+	"google.golang.org/grpc"
 	"net/url"
 	"sync"
 )
@@ -42,6 +44,8 @@ type Controller interface {
 type slaveController struct {
 	masters map[string]MasterInfo
 	lock    sync.RWMutex
+	// dialOptions 连接主机 gRPC 接口时附加的选项
+	dialOptions []grpc.DialOption
 }
 
 // info of master node
@@ -52,7 +56,10 @@ type MasterInfo struct {
 	// used to invoke aria2 rpc calls
 	Instance Node
 	Client   request.Client
+	// RPC 主机开放 gRPC 接口时用于调用 NodeService，否则为 nil
+	RPC rpcv1.NodeServiceClient
 
+	rpcConn    *grpc.ClientConn
 	jobTracker map[string]bool
 }
 
@@ -73,26 +80,37 @@ func (c *slaveController) HandleHeartBeat(req *serializer.NodePingReq) (serializ
 	origin, ok := c.masters[req.SiteID]
 
 	if (ok && req.IsUpdate) || !ok {
-		if ok {
-			origin.Instance.Kill()
-		}
-
 		masterUrl, err := url.Parse(req.SiteURL)
 		if err != nil {
 			return serializer.NodePingResp{}, err
 		}
 
-		c.masters[req.SiteID] = MasterInfo{
+		masterAuth := auth.HMACAuth{SecretKey: []byte(req.Node.MasterKey)}
+		var rpcConn *grpc.ClientConn
+		if req.RPCEndpoint != "" {
+			rpcConn, err = dialMaster(req.RPCEndpoint, req.RPCTLS, req.Node.ID, masterAuth, int64(req.CredentialTTL), c.dialOptions...)
+			if err != nil {
+				return serializer.NodePingResp{}, err
+			}
+		}
+
+		if ok {
+			origin.Instance.Kill()
+			if origin.rpcConn != nil {
+				origin.rpcConn.Close()
+			}
+		}
+
+		info := MasterInfo{
 			ID:  req.SiteID,
 			URL: masterUrl,
 			TTL: req.CredentialTTL,
 			Client: request.NewClient(
 				request.WithEndpoint(masterUrl.String()),
 				request.WithSlaveMeta(fmt.Sprintf("%d", req.Node.ID)),
-				request.WithCredential(auth.HMACAuth{
-					SecretKey: []byte(req.Node.MasterKey),
-				}, int64(req.CredentialTTL)),
+				request.WithCredential(masterAuth, int64(req.CredentialTTL)),
 			),
+			rpcConn:    rpcConn,
 			jobTracker: make(map[string]bool),
 			Instance: NewNodeFromDBModel(&model.Node{
 				Model:                  gorm.Model{ID: req.Node.ID},
@@ -102,6 +120,11 @@ func (c *slaveController) HandleHeartBeat(req *serializer.NodePingReq) (serializ
 				Aria2OptionsSerialized: req.Node.Aria2OptionsSerialized,
 			}),
 		}
+		if rpcConn != nil {
+			info.RPC = rpcv1.NewNodeServiceClient(rpcConn)
+		}
+
+		c.masters[req.SiteID] = info
 	}
 
 	return serializer.NodePingResp{}, nil
@@ -124,6 +147,12 @@ func (c *slaveController) SendNotification(id, subject string, msg mq.Message) e
 	if node, ok := c.masters[id]; ok {
 		c.lock.RUnlock()
 
+		if node.RPC != nil {
+			if handled, err := node.sendRPCNotification(subject, msg); handled {
+				return err
+			}
+		}
+
 		body := bytes.Buffer{}
 		enc := gob.NewEncoder(&body)
 		if err := enc.Encode(&msg); err != nil {
@@ -188,6 +217,18 @@ func (c *slaveController) GetOneDriveToken(id string, policyID uint) (string, er
 	if node, ok := c.masters[id]; ok {
 		c.lock.RUnlock()
 
+		if node.RPC != nil {
+			ctx, cancel := node.rpcContext()
+			defer cancel()
+
+			credential, err := node.RPC.GetOneDriveCredential(ctx, &rpcv1.GetOneDriveCredentialRequest{PolicyId: uint64(policyID)})
+			if err != nil {
+				return "", err
+			}
+
+			return credential.AccessToken, nil
+		}
+
 		res, err := node.Client.Request(
 			"GET",
 			fmt.Sprintf("/api/v3/slave/credential/onedrive/%d", policyID),
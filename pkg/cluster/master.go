@@ -9,8 +9,11 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -209,7 +212,9 @@ func (r *rpcService) CreateTask(task *model.Download, groupOptions map[string]in
 		options[k] = v
 	}
 
+	_, span := traceAria2(task, "addUri")
 	gid, err := r.Caller.AddURI(task.Source, options)
+	tracing.End(span, err)
 	if err != nil || gid == "" {
 		return "", err
 	}
@@ -218,6 +223,7 @@ func (r *rpcService) CreateTask(task *model.Download, groupOptions map[string]in
 }
 
 func (r *rpcService) Status(task *model.Download) (rpc.StatusInfo, error) {
+	_, span := traceAria2(task, "tellStatus")
 	res, err := r.Caller.TellStatus(task.GID)
 	if err != nil {
 		// 失败后重试
@@ -225,13 +231,16 @@ func (r *rpcService) Status(task *model.Download) (rpc.StatusInfo, error) {
 		time.Sleep(r.retryDuration)
 		res, err = r.Caller.TellStatus(task.GID)
 	}
+	tracing.End(span, err)
 
 	return res, err
 }
 
 func (r *rpcService) Cancel(task *model.Download) error {
 	// 取消下载任务
+	_, span := traceAria2(task, "remove")
 	_, err := r.Caller.Remove(task.GID)
+	tracing.End(span, err)
 	if err != nil {
 		util.Log().Warning("无法取消离线下载任务[%s], %s", task.GID, err)
 	}
@@ -244,7 +253,9 @@ func (r *rpcService) Select(task *model.Download, files []int) error {
 	for i := 0; i < len(files); i++ {
 		selected[i] = strconv.Itoa(files[i])
 	}
+	_, span := traceAria2(task, "changeOption")
 	_, err := r.Caller.ChangeOption(task.GID, map[string]interface{}{"select-file": strings.Join(selected, ",")})
+	tracing.End(span, err)
 	return err
 }
 
@@ -270,3 +281,12 @@ func (s *rpcService) DeleteTempFile(task *model.Download) error {
 
 	return nil
 }
+
+// traceAria2 为离线下载任务的 aria2 调用记录 Span，延续创建任务时请求所在的链路
+func traceAria2(task *model.Download, method string) (context.Context, trace.Span) {
+	ctx := tracing.Extract(context.Background(), task.TraceParent)
+	return tracing.Start(ctx, "aria2."+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("aria2.gid", task.GID)),
+	)
+}
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+)
+
+// NodeRPCIDMetadata 从机调用主机 NodeService 时节点 ID 所在的元数据
+const NodeRPCIDMetadata = "x-cr-node-id"
+
+// downloadEventTypes 离线下载任务状态对应的 NodeService 事件类型
+var downloadEventTypes = map[string]rpcv1.DownloadEventType{
+	strconv.Itoa(common.Downloading): rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_START,
+	strconv.Itoa(common.Paused):      rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_PAUSE,
+	strconv.Itoa(common.Canceled):    rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_STOP,
+	strconv.Itoa(common.Complete):    rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_COMPLETE,
+	strconv.Itoa(common.Error):       rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_ERROR,
+}
+
+// NodeRPCSignContent 从机调用主机 NodeService 时待签名的内容
+func NodeRPCSignContent(method string, nodeID uint) string {
+	return serializer.NewRequestSignString(method, NodeRPCIDMetadata+"="+strconv.FormatUint(uint64(nodeID), 10), "")
+}
+
+// SignNodeRPC 为从机节点的调用生成签名元数据，ttl 为签名有效秒数
+func SignNodeRPC(ctx context.Context, instance auth.Auth, nodeID uint, method string, ttl int64) context.Context {
+	sign := instance.Sign(NodeRPCSignContent(method, nodeID), time.Now().Unix()+ttl)
+	return metadata.AppendToOutgoingContext(ctx,
+		NodeRPCIDMetadata, strconv.FormatUint(uint64(nodeID), 10),
+		"authorization", "Bearer "+sign,
+	)
+}
+
+// dialMaster 连接主机的 gRPC 接口，每次调用均以节点通信密钥签名
+func dialMaster(endpoint string, useTLS bool, nodeID uint, instance auth.Auth, ttl int64, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUnaryInterceptor(func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			return invoker(SignNodeRPC(ctx, instance, nodeID, method, ttl), method, req, reply, cc, opts...)
+		}),
+		grpc.WithStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(SignNodeRPC(ctx, instance, nodeID, method, ttl), desc, cc, method, opts...)
+		}),
+	}, opts...)
+
+	return grpc.Dial(endpoint, opts...)
+}
+
+// rpcContext 调用主机 NodeService 使用的上下文，调用须在签名有效期内完成
+func (info *MasterInfo) rpcContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), time.Duration(info.TTL)*time.Second)
+}
+
+// sendRPCNotification 通过 NodeService 发送消息通知，消息没有对应的接口时返回 false，由调用方改用 HTTP 发送
+func (info *MasterInfo) sendRPCNotification(subject string, msg mq.Message) (bool, error) {
+	ctx, cancel := info.rpcContext()
+	defer cancel()
+
+	switch content := msg.Content.(type) {
+	case []rpc.Event:
+		eventType, ok := downloadEventTypes[msg.Event]
+		if !ok {
+			return false, nil
+		}
+
+		// 消息已按 GID 分发至 subject，只上报 subject 对应的任务
+		stream, err := info.RPC.ReportDownloadEvents(ctx)
+		if err != nil {
+			return true, err
+		}
+		// 主机提前结束调用时 Send 返回 io.EOF，实际错误由 CloseAndRecv 返回
+		if err := stream.Send(&rpcv1.DownloadEvent{Gid: subject, Type: eventType}); err != nil && err != io.EOF {
+			return true, err
+		}
+		_, err = stream.CloseAndRecv()
+		return true, err
+	case serializer.SlaveTransferResult:
+		_, err := info.RPC.ReportTransferResult(ctx, &rpcv1.TransferResult{
+			Hash:    subject,
+			Success: msg.Event == serializer.SlaveTransferSuccess,
+			Error:   content.Error,
+		})
+		return true, err
+	}
+
+	return false, nil
+}
@@ -0,0 +1,193 @@
+package cluster
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeNodeService 校验签名并记录从机调用的主机 NodeService
+type fakeNodeService struct {
+	rpcv1.UnimplementedNodeServiceServer
+	key    string
+	events []*rpcv1.DownloadEvent
+	result *rpcv1.TransferResult
+}
+
+func (s *fakeNodeService) check(ctx context.Context, method string) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	nodeID, _ := strconv.ParseUint(md.Get(NodeRPCIDMetadata)[0], 10, 64)
+	sign := strings.TrimPrefix(md.Get("authorization")[0], "Bearer ")
+	if err := (auth.HMACAuth{SecretKey: []byte(s.key)}).Check(NodeRPCSignContent(method, uint(nodeID)), sign); err != nil {
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+	return nil
+}
+
+func (s *fakeNodeService) ReportDownloadEvents(stream rpcv1.NodeService_ReportDownloadEventsServer) error {
+	if err := s.check(stream.Context(), "/cloudreve.v1.NodeService/ReportDownloadEvents"); err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&rpcv1.ReportDownloadEventsResponse{Accepted: uint64(len(s.events))})
+		}
+		if err != nil {
+			return err
+		}
+		s.events = append(s.events, event)
+	}
+}
+
+func (s *fakeNodeService) ReportTransferResult(ctx context.Context, req *rpcv1.TransferResult) (*rpcv1.ReportTransferResultResponse, error) {
+	if err := s.check(ctx, "/cloudreve.v1.NodeService/ReportTransferResult"); err != nil {
+		return nil, err
+	}
+	s.result = req
+	return &rpcv1.ReportTransferResultResponse{}, nil
+}
+
+func (s *fakeNodeService) GetOneDriveCredential(ctx context.Context, req *rpcv1.GetOneDriveCredentialRequest) (*rpcv1.OneDriveCredential, error) {
+	if err := s.check(ctx, "/cloudreve.v1.NodeService/GetOneDriveCredential"); err != nil {
+		return nil, err
+	}
+	return &rpcv1.OneDriveCredential{AccessToken: "token" + strconv.FormatUint(req.PolicyId, 10)}, nil
+}
+
+// newRPCController 创建通过 bufconn 连接 fakeNodeService 的从机控制器，主机 ID 为 1
+func newRPCController(t *testing.T, service *fakeNodeService, masterKey string) *slaveController {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	rpcv1.RegisterNodeServiceServer(server, service)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	c := &slaveController{
+		masters: make(map[string]MasterInfo),
+		dialOptions: []grpc.DialOption{
+			grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+				return listener.DialContext(ctx)
+			}),
+		},
+	}
+	_, err := c.HandleHeartBeat(&serializer.NodePingReq{
+		SiteID:        "1",
+		SiteURL:       "http://127.0.0.1",
+		CredentialTTL: 60,
+		RPCEndpoint:   "bufnet",
+		Node:          &model.Node{MasterKey: masterKey},
+	})
+	assert.NoError(t, err)
+	return c
+}
+
+func TestSlaveController_HandleHeartBeat_RPC(t *testing.T) {
+	a := assert.New(t)
+	c := newRPCController(t, &fakeNodeService{}, "key")
+	a.NotNil(c.masters["1"].RPC)
+	origin := c.masters["1"].rpcConn
+
+	// fresh heart beat without rpc endpoint
+	{
+		_, err := c.HandleHeartBeat(&serializer.NodePingReq{
+			SiteID:   "1",
+			IsUpdate: true,
+			Node:     &model.Node{},
+		})
+		a.NoError(err)
+		a.Nil(c.masters["1"].RPC)
+		a.Error(origin.Invoke(context.Background(), "/cloudreve.v1.NodeService/Ping", &rpcv1.PingRequest{}, &rpcv1.PingResponse{}))
+	}
+}
+
+func TestSlaveController_SendNotification_RPC(t *testing.T) {
+	a := assert.New(t)
+	service := &fakeNodeService{key: "key"}
+	c := newRPCController(t, service, "key")
+
+	// aria2 event
+	{
+		a.NoError(c.SendNotification("1", "gid1", mq.Message{
+			TriggeredBy: "gid1",
+			Event:       strconv.Itoa(common.Complete),
+			Content:     []rpc.Event{{Gid: "gid1"}, {Gid: "gid2"}},
+		}))
+		a.Len(service.events, 1)
+		a.Equal("gid1", service.events[0].Gid)
+		a.Equal(rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_COMPLETE, service.events[0].Type)
+	}
+
+	// transfer result
+	{
+		a.NoError(c.SendNotification("1", "hash", mq.Message{
+			TriggeredBy: "1",
+			Event:       serializer.SlaveTransferFailed,
+			Content:     serializer.SlaveTransferResult{Error: "error"},
+		}))
+		a.Equal("hash", service.result.Hash)
+		a.False(service.result.Success)
+		a.Equal("error", service.result.Error)
+	}
+
+	// message without rpc method, fallback to http
+	{
+		mockRequest := &requestMock{}
+		mockRequest.On("Request", "PUT", "/api/v3/slave/notification/gid1", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader("{\"code\":0}")),
+			},
+		})
+		info := c.masters["1"]
+		info.Client = mockRequest
+		c.masters["1"] = info
+		a.NoError(c.SendNotification("1", "gid1", mq.Message{
+			Event:   strconv.Itoa(common.Ready),
+			Content: []rpc.Event{{Gid: "gid1"}},
+		}))
+		mockRequest.AssertExpectations(t)
+		a.Len(service.events, 1)
+	}
+}
+
+func TestSlaveController_GetOneDriveToken_RPC(t *testing.T) {
+	a := assert.New(t)
+
+	// success
+	{
+		c := newRPCController(t, &fakeNodeService{key: "key"}, "key")
+		token, err := c.GetOneDriveToken("1", 2)
+		a.NoError(err)
+		a.Equal("token2", token)
+	}
+
+	// invalid sign
+	{
+		c := newRPCController(t, &fakeNodeService{key: "key"}, "other")
+		token, err := c.GetOneDriveToken("1", 2)
+		a.Equal(codes.Unauthenticated, status.Code(err))
+		a.Empty(token)
+	}
+}
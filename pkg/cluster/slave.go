@@ -2,6 +2,7 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -12,7 +13,9 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"net/url"
 	"strings"
@@ -250,13 +253,21 @@ func (node *SlaveNode) DBModel() *model.Node {
 
 // getHeartbeatContent gets serializer.NodePingReq used to send heartbeat to slave
 func (node *SlaveNode) getHeartbeatContent(isUpdate bool) *serializer.NodePingReq {
-	return &serializer.NodePingReq{
+	req := &serializer.NodePingReq{
 		SiteURL:       model.GetSiteURL().String(),
 		IsUpdate:      isUpdate,
 		SiteID:        model.GetSettingByName("siteID"),
 		Node:          node.Model,
 		CredentialTTL: model.GetIntSetting("slave_api_timeout", 60),
 	}
+
+	// 主机开放 gRPC 接口时，从机改用 NodeService 调用主机
+	if conf.RPCConfig.Listen != "" && conf.RPCConfig.Endpoint != "" {
+		req.RPCEndpoint = conf.RPCConfig.Endpoint
+		req.RPCTLS = conf.RPCConfig.CertPath != ""
+	}
+
+	return req
 }
 
 func (node *SlaveNode) changeStatus(isActive bool) {
@@ -279,7 +290,14 @@ func (s *slaveCaller) Init() error {
 }
 
 // SendAria2Call send remote aria2 call to slave node
-func (s *slaveCaller) SendAria2Call(body *serializer.SlaveAria2Call, scope string) (*serializer.Response, error) {
+func (s *slaveCaller) SendAria2Call(body *serializer.SlaveAria2Call, scope string) (res *serializer.Response, err error) {
+	ctx := context.Background()
+	if body.Task != nil {
+		var span trace.Span
+		ctx, span = traceAria2(body.Task, scope)
+		defer func() { tracing.End(span, err) }()
+	}
+
 	reqReader, err := getAria2RequestBody(body)
 	if err != nil {
 		return nil, err
@@ -289,6 +307,7 @@ func (s *slaveCaller) SendAria2Call(body *serializer.SlaveAria2Call, scope strin
 		"POST",
 		"aria2/"+scope,
 		reqReader,
+		request.WithContext(ctx),
 	).CheckHTTPResponse(200).DecodeResponse()
 }
 
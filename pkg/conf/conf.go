@@ -1,6 +1,8 @@
 package conf
 
 import (
+	"time"
+
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/go-ini/ini"
 	"github.com/go-playground/validator/v10"
@@ -17,6 +19,17 @@ type database struct {
 	DBFile      string
 	Port        int
 	Charset     string
+	// PostgreSQL 连接的 SSL 模式
+	SSLMode string `validate:"omitempty,eq=disable|eq=allow|eq=prefer|eq=require|eq=verify-ca|eq=verify-full"`
+	// 连接池设置，连接时长单位为秒，为 0 时使用默认值，ConnMaxIdleTime 为 0 时不限制
+	MaxOpenConns    int `validate:"gte=0"`
+	MaxIdleConns    int `validate:"gte=0"`
+	ConnMaxLifetime int `validate:"gte=0"`
+	ConnMaxIdleTime int `validate:"gte=0"`
+	// 目录列表及路径解析使用手写 SQL 查询
+	FastQuery bool
+	// 手写 SQL 查询使用预处理语句，经由事务模式的连接池代理连接时应关闭
+	PrepareStmt bool
 }
 
 // system 系统通用配置
@@ -27,6 +40,9 @@ type system struct {
 	SessionSecret string
 	HashIDSalt    string
 	GracePeriod   int `validate:"gte=0"`
+	// 信任的反向代理地址或 CIDR，仅来自这些地址的请求会使用 X-Forwarded-For 等请求头中的客户端 IP，
+	// 留空时不信任任何代理
+	TrustedProxies []string
 }
 
 type ssl struct {
@@ -40,6 +56,51 @@ type unix struct {
 	ProxyHeader string `validate:"required_with=Listen"`
 }
 
+// s3 S3 兼容接口配置
+type s3 struct {
+	Listen string
+}
+
+// ftp FTP 服务配置
+type ftp struct {
+	Listen       string
+	PublicHost   string
+	PassivePorts string
+	CertPath     string
+	KeyPath      string `validate:"required_with=CertPath"`
+	ForceTLS     bool
+}
+
+// sftp SFTP 服务配置
+type sftp struct {
+	Listen      string
+	HostKeyPath string
+}
+
+// dlna DLNA 媒体服务配置
+type dlna struct {
+	Listen       string
+	FriendlyName string
+	Interfaces   string
+}
+
+// rpc gRPC 接口配置
+type rpc struct {
+	Listen   string
+	Endpoint string
+	CertPath string
+	KeyPath  string `validate:"required_with=CertPath"`
+}
+
+// tracing OpenTelemetry 链路追踪配置
+type tracing struct {
+	Endpoint    string
+	URLPath     string
+	Insecure    bool
+	ServiceName string
+	SampleRatio float64 `validate:"gte=0,lte=1"`
+}
+
 // slave 作为slave存储端配置
 type slave struct {
 	Secret          string `validate:"omitempty,gte=64"`
@@ -47,12 +108,28 @@ type slave struct {
 	SignatureTTL    int    `validate:"omitempty,gte=1"`
 }
 
+// log 日志配置
+type log struct {
+	Level      string `validate:"omitempty,eq=debug|eq=info|eq=warning|eq=error"`
+	Format     string `validate:"eq=text|eq=json"`
+	File       string
+	MaxSize    int `validate:"gte=0"`
+	MaxBackups int `validate:"gte=0"`
+	MaxAge     int `validate:"gte=0"`
+	Modules    string
+}
+
 // redis 配置
 type redis struct {
 	Network  string
 	Server   string
 	Password string
 	DB       string
+	// 哨兵模式下主节点的名称，此时 Server 为逗号分隔的哨兵地址
+	MasterName       string
+	SentinelPassword string
+	// 集群模式，此时 Server 为逗号分隔的集群节点地址
+	Cluster bool
 }
 
 // 跨域配置
@@ -111,6 +188,13 @@ func Init(path string) {
 		"Redis":      RedisConfig,
 		"CORS":       CORSConfig,
 		"Slave":      SlaveConfig,
+		"S3":         S3Config,
+		"FTP":        FTPConfig,
+		"SFTP":       SFTPConfig,
+		"DLNA":       DLNAConfig,
+		"RPC":        RPCConfig,
+		"Tracing":    TracingConfig,
+		"Log":        LogConfig,
 	}
 	for sectionName, sectionStruct := range sections {
 		err = mapSection(sectionName, sectionStruct)
@@ -124,13 +208,38 @@ func Init(path string) {
 		OptionOverwrite[key.Name()] = key.Value()
 	}
 
-	// 重设log等级
-	if !SystemConfig.Debug {
-		util.Level = util.LevelInformational
-		util.GloablLogger = nil
-		util.Log()
+	// 重设log等级及输出
+	if err := initLogger(); err != nil {
+		util.Log().Panic("日志配置无效: %s", err)
+	}
+
+}
+
+// initLogger 按日志配置设定输出格式、目标及各模块的日志等级，未指定等级时沿用调试模式的设定
+func initLogger() error {
+	options := util.LogOptions{
+		Level:   LogConfig.Level,
+		Format:  LogConfig.Format,
+		Modules: LogConfig.Modules,
+	}
+
+	if options.Level == "" {
+		options.Level = "info"
+		if SystemConfig.Debug {
+			options.Level = "debug"
+		}
+	}
+
+	if LogConfig.File != "" {
+		options.Output = &util.RotateWriter{
+			Filename:   util.RelativePath(LogConfig.File),
+			MaxSize:    int64(LogConfig.MaxSize) << 20,
+			MaxBackups: LogConfig.MaxBackups,
+			MaxAge:     time.Duration(LogConfig.MaxAge) * 24 * time.Hour,
+		}
 	}
 
+	return util.ConfigureLogger(options)
 }
 
 // mapSection 将配置文件的 Section 映射到结构体上
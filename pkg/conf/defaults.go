@@ -14,6 +14,7 @@ var DatabaseConfig = &database{
 	Charset: "utf8",
 	DBFile:  "cloudreve.db",
 	Port:    3306,
+	SSLMode: "disable",
 }
 
 // SystemConfig 系统公用配置
@@ -27,7 +28,7 @@ var SystemConfig = &system{
 var CORSConfig = &cors{
 	AllowOrigins:     []string{"UNSET"},
 	AllowMethods:     []string{"PUT", "POST", "GET", "OPTIONS"},
-	AllowHeaders:     []string{"Cookie", "X-Cr-Policy", "Authorization", "Content-Length", "Content-Type", "X-Cr-Path", "X-Cr-FileName"},
+	AllowHeaders:     []string{"Cookie", "X-Cr-Policy", "Authorization", "Content-Length", "Content-Type", "X-Cr-Path", "X-Cr-FileName", "Idempotency-Key"},
 	AllowCredentials: false,
 	ExposeHeaders:    nil,
 }
@@ -49,4 +50,62 @@ var UnixConfig = &unix{
 	ProxyHeader: "X-Forwarded-For",
 }
 
+// S3Config S3 兼容接口配置，监听地址为空时不启用
+var S3Config = &s3{
+	Listen: "",
+}
+
+// FTPConfig FTP 服务配置，监听地址为空时不启用。被动模式端口范围形如 30000-30100，
+// 为空时随机选择；设置证书后支持 FTPS，ForceTLS 时拒绝明文登录
+var FTPConfig = &ftp{
+	Listen:       "",
+	PublicHost:   "",
+	PassivePorts: "",
+	CertPath:     "",
+	KeyPath:      "",
+	ForceTLS:     false,
+}
+
+// SFTPConfig SFTP 服务配置，监听地址为空时不启用。主机密钥不存在时自动生成
+var SFTPConfig = &sftp{
+	Listen:      "",
+	HostKeyPath: "sftp_host_key",
+}
+
+// DLNAConfig DLNA 媒体服务配置，监听地址为空时不启用。Interfaces 为逗号分隔的网络接口名，
+// 为空时在所有支持多播的接口上发布
+var DLNAConfig = &dlna{
+	Listen:       "",
+	FriendlyName: "Cloudreve",
+	Interfaces:   "",
+}
+
+// LogConfig 日志配置，File 为空时输出至标准输出。MaxSize 为单个日志文件的大小上限 (MB)，
+// MaxAge 为轮转文件的保留天数，Modules 为逗号分隔的 模块=等级，如 ftp=debug,aria2=warning
+var LogConfig = &log{
+	Format:     "text",
+	MaxSize:    100,
+	MaxBackups: 10,
+	MaxAge:     30,
+}
+
+// RPCConfig gRPC 接口配置，监听地址为空时不启用，设置证书后使用 TLS。Endpoint 为从机连接
+// gRPC 接口使用的地址，如 master.example.com:9090，为空时从机仍通过 HTTP 调用主机
+var RPCConfig = &rpc{
+	Listen:   "",
+	Endpoint: "",
+	CertPath: "",
+	KeyPath:  "",
+}
+
+// TracingConfig 链路追踪配置，Endpoint 为 OTLP/HTTP 接收端地址，如 localhost:4318，为空时不启用。
+// Jaeger 1.35 及以上版本可直接接收 OTLP；SampleRatio 为新链路的采样比例
+var TracingConfig = &tracing{
+	Endpoint:    "",
+	URLPath:     "/v1/traces",
+	Insecure:    false,
+	ServiceName: "cloudreve",
+	SampleRatio: 1,
+}
+
 var OptionOverwrite = map[string]interface{}{}
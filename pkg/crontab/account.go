@@ -0,0 +1,142 @@
+package crontab
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// purgeDeletedAccounts 清除注销冷静期已结束的账户及其全部文件
+func purgeDeletedAccounts() {
+	users, err := model.ListUsersPendingDeletion()
+	if err != nil {
+		util.Log().Warning("无法列取待注销的用户, %s", err)
+		return
+	}
+
+	for i := range users {
+		if err := purgeAccount(&users[i]); err != nil {
+			util.Log().Warning("无法清除已注销的用户 [%d], %s", users[i].ID, err)
+			continue
+		}
+
+		model.RecordAudit(nil, 0, model.AuditAccountPurge, model.AuditTarget("user", users[i].ID), users[i].Email)
+		util.Log().Info("已清除注销冷静期结束的用户 [%s]", users[i].Email)
+	}
+}
+
+// purgeAccount 删除用户的全部文件及账户
+func purgeAccount(user *model.User) error {
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	root, err := user.Root()
+	if err != nil {
+		return err
+	}
+
+	if err := fs.Delete(context.Background(), []uint{root.ID}, []uint{}, false); err != nil {
+		return err
+	}
+
+	return user.Purge()
+}
+
+// revertExpiredGroups 将临时用户组已到期的用户恢复为原用户组
+func revertExpiredGroups() {
+	users, err := model.ListUsersGroupExpired()
+	if err != nil {
+		util.Log().Warning("无法列取临时用户组已到期的用户, %s", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		expired, _ := model.GetGroupByID(user.GroupID)
+		if err := user.RevertGroup(); err != nil {
+			util.Log().Warning("无法恢复用户 [%d] 的用户组, %s", user.ID, err)
+			continue
+		}
+
+		model.RecordAudit(nil, 0, model.AuditGroupRevert, model.AuditTarget("user", user.ID), expired.Name)
+		util.Log().Info("用户 [%s] 的临时用户组已到期，已恢复为 [%s]", user.Email, user.Group.Name)
+
+		// 容量超出原用户组上限时不删除文件，仅提醒用户清理，清理前无法上传新文件
+		if user.Storage > user.MaxStorage() {
+			title, body := email.NewGroupOveruseEmail(user.Nick, expired.Name, user.Group.Name, user.Storage, user.MaxStorage())
+			if err := email.Send(user.Email, title, body); err != nil {
+				util.Log().Warning("无法发送用户组到期提醒邮件, %s", err)
+			}
+		}
+	}
+}
+
+// expireGuestAccounts 停用已到期的访客账户，撤销其分享，并按设置删除其全部文件
+func expireGuestAccounts() {
+	users, err := model.ListGuestsExpired()
+	if err != nil {
+		util.Log().Warning("无法列取已到期的访客账户, %s", err)
+		return
+	}
+
+	for i := range users {
+		user := &users[i]
+		shares, err := user.ExpireGuest()
+		if err != nil {
+			util.Log().Warning("无法停用已到期的访客账户 [%d], %s", user.ID, err)
+			continue
+		}
+
+		model.RecordShareRevoke(nil, 0, shares, "guest expired")
+		model.RecordAudit(nil, 0, model.AuditGuestExpire, model.AuditTarget("user", user.ID), user.Email)
+		util.Log().Info("访客账户 [%s] 已到期，已停用", user.Email)
+
+		if user.PurgeOnExpiry {
+			if err := purgeFiles(user); err != nil {
+				util.Log().Warning("无法删除已到期访客账户 [%d] 的文件, %s", user.ID, err)
+			}
+		}
+	}
+}
+
+// purgeFiles 删除用户根目录下的全部文件，保留账户及根目录
+func purgeFiles(user *model.User) error {
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	root, err := user.Root()
+	if err != nil {
+		return err
+	}
+
+	folders, err := root.GetChildFolder()
+	if err != nil {
+		return err
+	}
+
+	files, err := root.GetChildFiles()
+	if err != nil {
+		return err
+	}
+
+	dirs := make([]uint, 0, len(folders))
+	for _, folder := range folders {
+		dirs = append(dirs, folder.ID)
+	}
+
+	ids := make([]uint, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+
+	return fs.Delete(context.Background(), dirs, ids, false)
+}
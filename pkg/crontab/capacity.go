@@ -0,0 +1,18 @@
+package crontab
+
+import (
+	"context"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/capacity"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// checkCapacity 检查存储策略的剩余容量
+func checkCapacity() {
+	if err := capacity.Check(context.Background()); err != nil {
+		util.Log().Warning("无法检查存储策略容量, %s", err)
+		return
+	}
+
+	util.Log().Info("定时任务 [cron_capacity_check] 执行完毕")
+}
@@ -2,6 +2,7 @@ package crontab
 
 import (
 	"context"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +11,8 @@ import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -17,6 +20,35 @@ func garbageCollect() {
 	// 清理打包下载产生的临时文件
 	collectArchiveFile()
 
+	// 清理过期的个人数据导出压缩包
+	collectTakeoutArchive()
+
+	// 清理过期的 S3 分片上传临时文件
+	collectS3Multipart()
+
+	// 清理过期的分享访问记录
+	collectShareAccessLog()
+
+	// 清理过期的已读站内信
+	collectNotifications()
+
+	// 清理过期的文件变更记录
+	collectFileChanges()
+
+	// 清理已使用的一次性下载凭证
+	if err := model.DeleteStaleDownloadTokens(); err != nil {
+		util.Log().Warning("无法清理一次性下载凭证, %s", err)
+	}
+
+	// 清除注销冷静期已结束的账户
+	purgeDeletedAccounts()
+
+	// 恢复临时用户组已到期的用户
+	revertExpiredGroups()
+
+	// 停用已到期的访客账户
+	expireGuestAccounts()
+
 	// 清理过期的内置内存缓存
 	if store, ok := cache.Store.(*cache.MemoStore); ok {
 		collectCache(store)
@@ -51,6 +83,44 @@ func collectArchiveFile() {
 
 }
 
+func collectTakeoutArchive() {
+	expires := model.GetIntSetting("takeout_archive_timeout", 259200)
+	root := task.TakeoutArchiveRoot()
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() &&
+			strings.HasPrefix(filepath.Base(path), "takeout_") &&
+			time.Now().Sub(info.ModTime()).Seconds() > float64(expires) {
+			util.Log().Debug("删除过期的个人数据导出压缩包 [%s]", path)
+			if err := os.Remove(path); err != nil {
+				util.Log().Debug("导出压缩包 [%s] 删除失败 , %s", path, err)
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		util.Log().Debug("[定时任务] 无法列取个人数据导出目录")
+	}
+}
+
+func collectS3Multipart() {
+	root := s3gateway.MultipartRoot()
+	entries, err := ioutil.ReadDir(root)
+	if err != nil {
+		return
+	}
+
+	// 每个分片上传对应一个目录，上传分片时目录的修改时间会更新
+	for _, entry := range entries {
+		if entry.IsDir() && time.Since(entry.ModTime()) > s3gateway.MultipartTTL {
+			util.Log().Debug("删除过期的 S3 分片上传 [%s]", entry.Name())
+			if err := os.RemoveAll(filepath.Join(root, entry.Name())); err != nil {
+				util.Log().Debug("分片上传 [%s] 删除失败 , %s", entry.Name(), err)
+			}
+		}
+	}
+}
+
 func collectCache(store *cache.MemoStore) {
 	util.Log().Debug("清理内存缓存")
 	store.GarbageCollect()
@@ -0,0 +1,20 @@
+package crontab
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// repairFolderStats 校准目录的累计大小及子项数量
+func repairFolderStats() {
+	repaired, err := model.RepairAllFolderStats()
+	if err != nil {
+		util.Log().Warning("无法校准目录统计, %s", err)
+		return
+	}
+
+	if repaired > 0 {
+		util.Log().Info("已校准 %d 个目录的统计", repaired)
+	}
+	util.Log().Info("定时任务 [cron_repair_folder_stats] 执行完毕")
+}
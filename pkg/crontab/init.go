@@ -1,31 +1,58 @@
 package crontab
 
 import (
+	"sync"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/robfig/cron/v3"
 )
 
-// Cron 定时任务
-var Cron *cron.Cron
+var (
+	// Cron 定时任务
+	Cron *cron.Cron
+	// cronLock 保护 Cron 的停止与替换，设置变更触发的 Reload 可能并发执行
+	cronLock sync.Mutex
+)
 
 // Reload 重新启动定时任务
 func Reload() {
+	cronLock.Lock()
+	defer cronLock.Unlock()
+
 	if Cron != nil {
 		Cron.Stop()
 	}
-	Init()
+	start()
 }
 
 // Init 初始化定时任务
 func Init() {
+	cronLock.Lock()
+	defer cronLock.Unlock()
+
+	start()
+}
+
+// start 按当前设置创建并启动定时任务，调用方需持有 cronLock
+func start() {
 	util.Log().Info("初始化定时任务...")
 	// 读取cron日程设置
 	options := model.GetSettingByNames(
 		"cron_garbage_collect",
 		"cron_recycle_upload_session",
+		"cron_purge_task_history",
+		"cron_collect_expired_share",
+		"cron_ldap_sync",
+		"cron_storage_audit",
+		"cron_notification_digest",
+		"cron_aggregate_statistics",
+		"cron_moderation",
+		"cron_usage_report",
+		"cron_capacity_check",
+		"cron_repair_folder_stats",
 	)
-	Cron := cron.New()
+	Cron = cron.New()
 	for k, v := range options {
 		var handler func()
 		switch k {
@@ -33,6 +60,26 @@ func Init() {
 			handler = garbageCollect
 		case "cron_recycle_upload_session":
 			handler = uploadSessionCollect
+		case "cron_purge_task_history":
+			handler = purgeTaskHistory
+		case "cron_collect_expired_share":
+			handler = collectExpiredShare
+		case "cron_ldap_sync":
+			handler = syncLDAPUsers
+		case "cron_storage_audit":
+			handler = auditStorage
+		case "cron_notification_digest":
+			handler = sendNotificationDigest
+		case "cron_aggregate_statistics":
+			handler = aggregateStatistics
+		case "cron_moderation":
+			handler = processModeration
+		case "cron_usage_report":
+			handler = sendUsageReport
+		case "cron_capacity_check":
+			handler = checkCapacity
+		case "cron_repair_folder_stats":
+			handler = repairFolderStats
 		default:
 			util.Log().Warning("未知定时任务类型 [%s]，跳过", k)
 			continue
@@ -0,0 +1,20 @@
+package crontab
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+func syncLDAPUsers() {
+	if !ldap.Enabled() || !model.IsTrueVal(model.GetSettingByName("ldap_sync")) {
+		return
+	}
+
+	if err := ldap.NewConfig().SyncUsers(); err != nil {
+		util.Log().Warning("无法同步 LDAP 用户, %s", err)
+		return
+	}
+
+	util.Log().Info("定时任务 [cron_ldap_sync] 执行完毕")
+}
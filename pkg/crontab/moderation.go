@@ -0,0 +1,16 @@
+package crontab
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/moderation"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// processModeration 审核等待中的图片及视频
+func processModeration() {
+	if err := moderation.ProcessPending(); err != nil {
+		util.Log().Warning("无法审核文件, %s", err)
+		return
+	}
+
+	util.Log().Info("定时任务 [cron_moderation] 执行完毕")
+}
@@ -0,0 +1,30 @@
+package crontab
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// sendNotificationDigest 汇总发送等待中的邮件通知
+func sendNotificationDigest() {
+	notify.SendDigests()
+	util.Log().Info("定时任务 [cron_notification_digest] 执行完毕")
+}
+
+// collectNotifications 清理超过保留期限的已读站内信
+func collectNotifications() {
+	days := model.GetIntSetting("notify_retention_days", 30)
+	if days <= 0 {
+		return
+	}
+
+	deleted, err := model.DeleteReadNotificationsBefore(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		util.Log().Warning("无法清理过期的站内信, %s", err)
+	} else if deleted > 0 {
+		util.Log().Info("已清理 %d 条超过 %d 天的已读站内信", deleted, days)
+	}
+}
@@ -0,0 +1,77 @@
+package crontab
+
+import (
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// failedTaskStatus 用量报告中视为失败的任务状态
+var failedTaskStatus = []int{task.Error, task.TimedOut}
+
+// taskTypeNames 用量报告中的任务类型名称
+var taskTypeNames = map[int]string{
+	task.CompressTaskType:     "压缩",
+	task.DecompressTaskType:   "解压缩",
+	task.TransferTaskType:     "中转",
+	task.ImportTaskType:       "导入",
+	task.TakeoutTaskType:      "导出个人数据",
+	task.StorageAuditTaskType: "存储一致性检查",
+}
+
+// sendUsageReport 在周期开始的当天向管理员发送上一周期的用量报告
+func sendUsageReport() {
+	period := model.GetSettingByName("usage_report_period")
+	if period == "" {
+		return
+	}
+
+	now := time.Now()
+	from, to, err := stats.ReportRange(period, now)
+	if err != nil {
+		util.Log().Warning("无法发送用量报告, %s", err)
+		return
+	}
+
+	// 仅在周期开始的当天发送，定时任务在当天多次执行时只发送一次
+	if now.Sub(to) >= 24*time.Hour {
+		return
+	}
+
+	key := fmt.Sprintf("usage_report_%s_%s", period, from.Format(model.StatisticDateFormat))
+	if _, ok := cache.Get(key); ok {
+		return
+	}
+
+	report, err := stats.BuildReport(period, from, to, failedTaskStatus...)
+	if err != nil {
+		util.Log().Warning("无法生成用量报告, %s", err)
+		return
+	}
+
+	for i := range report.FailedTasks {
+		report.FailedTasks[i].Name = taskTypeNames[report.FailedTasks[i].Type]
+	}
+
+	admins, err := model.ListAdmins()
+	if err != nil {
+		util.Log().Warning("无法列取管理员, %s", err)
+		return
+	}
+
+	cache.Set(key, true, 2*24*3600)
+	for _, admin := range admins {
+		title, body := email.NewUsageReportEmail(admin.Nick, report)
+		if err := email.Send(admin.Email, title, body); err != nil {
+			util.Log().Warning("无法发送用量报告至 [%s], %s", admin.Email, err)
+		}
+	}
+
+	util.Log().Info("定时任务 [cron_usage_report] 执行完毕，已向 %d 个管理员发送用量报告", len(admins))
+}
@@ -0,0 +1,67 @@
+package crontab
+
+import (
+	"context"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+func collectExpiredShare() {
+	shares, err := model.GetExpiredShares(model.GetIntSetting("download_timeout", 600))
+	if err != nil {
+		util.Log().Warning("无法列取已失效的分享, %s", err)
+		return
+	}
+
+	for i := range shares {
+		if shares[i].ExpireAction == model.ShareExpireDeleteSource {
+			if err := deleteShareSource(&shares[i]); err != nil {
+				util.Log().Warning("无法删除分享 [%d] 的源文件, %s", shares[i].ID, err)
+				continue
+			}
+		}
+
+		if err := shares[i].Delete(); err != nil {
+			util.Log().Warning("无法删除已失效的分享 [%d], %s", shares[i].ID, err)
+		}
+	}
+
+	util.Log().Info("定时任务 [cron_collect_expired_share] 执行完毕")
+}
+
+func collectShareAccessLog() {
+	days := model.GetIntSetting("share_access_log_retention_days", 30)
+	if days <= 0 {
+		return
+	}
+
+	deleted, err := model.DeleteShareAccessLogsBefore(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		util.Log().Warning("无法清理过期的分享访问记录, %s", err)
+	} else if deleted > 0 {
+		util.Log().Info("已清理 %d 条超过 %d 天的分享访问记录", deleted, days)
+	}
+}
+
+// deleteShareSource 删除分享的源文件或目录
+func deleteShareSource(share *model.Share) error {
+	user, err := model.GetUserByID(share.UserID)
+	if err != nil {
+		// 用户已不存在，源文件会随用户一同删除
+		return nil
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if share.IsDir {
+		return fs.Delete(context.Background(), []uint{share.SourceID}, []uint{}, false)
+	}
+	return fs.Delete(context.Background(), []uint{}, []uint{share.SourceID}, false)
+}
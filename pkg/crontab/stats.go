@@ -0,0 +1,16 @@
+package crontab
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// aggregateStatistics 写入累计的流量及活跃用户，并汇总存储用量
+func aggregateStatistics() {
+	if err := stats.Aggregate(); err != nil {
+		util.Log().Warning("无法汇总站点统计, %s", err)
+		return
+	}
+
+	util.Log().Info("定时任务 [cron_aggregate_statistics] 执行完毕")
+}
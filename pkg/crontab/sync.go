@@ -0,0 +1,24 @@
+package crontab
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// collectFileChanges 清理超过保留期限的文件变更记录，
+// 游标早于保留期限的同步客户端需重新进行全量比对
+func collectFileChanges() {
+	days := model.GetIntSetting("file_change_retention_days", 30)
+	if days <= 0 {
+		return
+	}
+
+	deleted, err := model.DeleteFileChangesBefore(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		util.Log().Warning("无法清理过期的文件变更记录, %s", err)
+	} else if deleted > 0 {
+		util.Log().Info("已清理 %d 条超过 %d 天的文件变更记录", deleted, days)
+	}
+}
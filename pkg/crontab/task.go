@@ -0,0 +1,62 @@
+package crontab
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// finishedTaskStatus 已结束、可被清理的任务状态
+var finishedTaskStatus = []int{task.Error, task.Canceled, task.Complete, task.TimedOut}
+
+func purgeTaskHistory() {
+	// 按保留天数清理
+	if days := model.GetIntSetting("task_retention_days", 0); days > 0 {
+		before := time.Now().AddDate(0, 0, -days)
+		deleted, err := model.DeleteTasksBefore(before, finishedTaskStatus...)
+		if err != nil {
+			util.Log().Warning("无法清理过期任务记录, %s", err)
+		} else if deleted > 0 {
+			util.Log().Info("已清理 %d 条超过 %d 天的任务记录", deleted, days)
+		}
+	}
+
+	// 按每用户保留条数清理
+	if keep := model.GetIntSetting("task_retention_count", 0); keep > 0 {
+		deleted, err := model.DeleteTasksExceeding(keep, finishedTaskStatus...)
+		if err != nil {
+			util.Log().Warning("无法清理超出数量的任务记录, %s", err)
+		} else if deleted > 0 {
+			util.Log().Info("已清理 %d 条超出保留数量的任务记录", deleted)
+		}
+	}
+
+	util.Log().Info("定时任务 [cron_purge_task_history] 执行完毕")
+}
+
+// auditStorage 为已用容量与文件记录不一致的用户创建存储一致性检查任务，只生成报告不自动修复
+func auditStorage() {
+	ids, err := model.ListUsersStorageMismatch()
+	if err != nil {
+		util.Log().Warning("无法列取已用容量不一致的用户, %s", err)
+		return
+	}
+
+	for _, uid := range ids {
+		// 跳过已有未完成检查任务的用户
+		if model.CountUserTasks(uid, task.StorageAuditTaskType, task.Queued, task.Processing) > 0 {
+			continue
+		}
+
+		job, err := task.NewStorageAuditTask(uid, false)
+		if err != nil {
+			util.Log().Warning("无法为用户 [%d] 创建存储一致性检查任务, %s", uid, err)
+			continue
+		}
+		task.TaskPoll.Submit(job)
+	}
+
+	util.Log().Info("定时任务 [cron_storage_audit] 执行完毕，%d 个用户的已用容量不一致", len(ids))
+}
@@ -0,0 +1,293 @@
+package dlna
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/gofrs/uuid"
+)
+
+// NewCloudreveServer 按照配置文件新建 DLNA 媒体服务，设备 UUID 由站点 ID 生成
+func NewCloudreveServer() *Server {
+	var interfaces []string
+	for _, name := range strings.Split(conf.DLNAConfig.Interfaces, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			interfaces = append(interfaces, name)
+		}
+	}
+
+	return NewServer(&Settings{
+		Listen:          conf.DLNAConfig.Listen,
+		FriendlyName:    conf.DLNAConfig.FriendlyName,
+		UUID:            uuid.NewV5(uuid.NamespaceURL, model.GetSettingByName("siteID")+"/dlna").String(),
+		Interfaces:      interfaces,
+		ModelNumber:     conf.BackendVersion,
+		PresentationURL: model.GetSiteURL().String(),
+	}, &cloudreveLibrary{})
+}
+
+// cloudreveLibrary 以用户发布的媒体目录为内容的媒体库，根容器下为各用户发布的目录。
+// 容器 ID 形如 d{用户ID}.{目录ID}，文件 ID 形如 f{用户ID}.{文件ID}
+type cloudreveLibrary struct{}
+
+// parseID 解析对象 ID
+func parseID(id string) (isDir bool, uid, objectID uint, ok bool) {
+	if len(id) < 2 || (id[0] != 'd' && id[0] != 'f') {
+		return false, 0, 0, false
+	}
+
+	parts := strings.SplitN(id[1:], ".", 2)
+	if len(parts) != 2 {
+		return false, 0, 0, false
+	}
+
+	user, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return false, 0, 0, false
+	}
+	object, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return false, 0, 0, false
+	}
+
+	return id[0] == 'd', uint(user), uint(object), true
+}
+
+// folderObjectID 目录的对象 ID
+func folderObjectID(uid, id uint) string {
+	return fmt.Sprintf("d%d.%d", uid, id)
+}
+
+// fileObjectID 文件的对象 ID
+func fileObjectID(uid, id uint) string {
+	return fmt.Sprintf("f%d.%d", uid, id)
+}
+
+// publisher 获取发布了媒体目录的用户，用户组须允许使用 DLNA
+func publisher(uid uint) (*model.User, error) {
+	user, err := model.GetActiveUserByID(uid)
+	if err != nil || !user.Group.OptionsSerialized.DLNA || user.OptionsSerialized.DLNAFolder == 0 {
+		return nil, ErrNotExist
+	}
+	return &user, nil
+}
+
+// publishedFolder 获取目录，目录须位于用户发布的媒体目录中
+func publishedFolder(user *model.User, id uint) (*model.Folder, error) {
+	folders, err := model.GetFoldersByIDs([]uint{id}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return nil, ErrNotExist
+	}
+
+	// 向上查找发布的目录
+	current := folders[0]
+	for current.ID != user.OptionsSerialized.DLNAFolder {
+		if current.ParentID == nil {
+			return nil, ErrNotExist
+		}
+
+		parents, err := model.GetFoldersByIDs([]uint{*current.ParentID}, user.ID)
+		if err != nil || len(parents) == 0 {
+			return nil, ErrNotExist
+		}
+		current = parents[0]
+	}
+
+	return &folders[0], nil
+}
+
+// publishedFile 获取文件，文件须位于用户发布的媒体目录中
+func publishedFile(user *model.User, id uint) (*model.File, error) {
+	files, err := model.GetFilesByIDs([]uint{id}, user.ID)
+	if err != nil || len(files) == 0 || files[0].UploadSessionID != nil {
+		return nil, ErrNotExist
+	}
+
+	if _, err := publishedFolder(user, files[0].FolderID); err != nil {
+		return nil, err
+	}
+	return &files[0], nil
+}
+
+// folderObject 将目录转换为容器，发布的目录位于根容器下，以用户昵称区分
+func folderObject(user *model.User, folder *model.Folder) Object {
+	obj := Object{
+		ID:      folderObjectID(user.ID, folder.ID),
+		Title:   folder.Name,
+		IsDir:   true,
+		ModTime: folder.UpdatedAt,
+	}
+
+	if folder.ID == user.OptionsSerialized.DLNAFolder {
+		obj.ParentID = rootID
+		obj.Title = fmt.Sprintf("%s (%s)", folder.Name, user.Nick)
+		if folder.ParentID == nil {
+			obj.Title = user.Nick
+		}
+	} else if folder.ParentID != nil {
+		obj.ParentID = folderObjectID(user.ID, *folder.ParentID)
+	}
+
+	return obj
+}
+
+// fileObject 将文件转换为对象
+func fileObject(user *model.User, file *model.File) Object {
+	return Object{
+		ID:       fileObjectID(user.ID, file.ID),
+		ParentID: folderObjectID(user.ID, file.FolderID),
+		Title:    file.Name,
+		Size:     file.Size,
+		ModTime:  file.UpdatedAt,
+		ETag:     file.ETag(),
+	}
+}
+
+// Get 获取发布的目录或文件
+func (l *cloudreveLibrary) Get(id string) (*Object, error) {
+	isDir, uid, objectID, ok := parseID(id)
+	if !ok {
+		return nil, ErrNotExist
+	}
+
+	user, err := publisher(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj Object
+	if isDir {
+		folder, err := publishedFolder(user, objectID)
+		if err != nil {
+			return nil, err
+		}
+		obj = folderObject(user, folder)
+	} else {
+		file, err := publishedFile(user, objectID)
+		if err != nil {
+			return nil, err
+		}
+		obj = fileObject(user, file)
+	}
+
+	return &obj, nil
+}
+
+// List 列出容器下的对象，根容器下为所有用户发布的目录
+func (l *cloudreveLibrary) List(id string) ([]Object, error) {
+	if id == rootID {
+		return l.listPublished()
+	}
+
+	isDir, uid, objectID, ok := parseID(id)
+	if !ok || !isDir {
+		return nil, ErrNotExist
+	}
+
+	user, err := publisher(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := publishedFolder(user, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := folder.GetChildFolder()
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Object, 0, len(folders)+len(files))
+	for i := range folders {
+		res = append(res, folderObject(user, &folders[i]))
+	}
+	for i := range files {
+		// 跳过上传中的占位文件
+		if files[i].UploadSessionID == nil {
+			res = append(res, fileObject(user, &files[i]))
+		}
+	}
+
+	return res, nil
+}
+
+// listPublished 列出所有用户发布的目录
+func (l *cloudreveLibrary) listPublished() ([]Object, error) {
+	users, err := model.ListDLNAPublishers()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Object, 0, len(users))
+	for i := range users {
+		user := &users[i]
+		if !user.Group.OptionsSerialized.DLNA || user.OptionsSerialized.DLNAFolder == 0 {
+			continue
+		}
+
+		folders, err := model.GetFoldersByIDs([]uint{user.OptionsSerialized.DLNAFolder}, user.ID)
+		if err != nil || len(folders) == 0 {
+			continue
+		}
+		res = append(res, folderObject(user, &folders[0]))
+	}
+
+	return res, nil
+}
+
+// fileReader 读取完成后回收文件系统
+type fileReader struct {
+	io.ReadSeekCloser
+	fs *filesystem.FileSystem
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadSeekCloser.Close()
+	r.fs.Recycle()
+	return err
+}
+
+// Open 以与网页下载相同的方式打开文件，适用用户组的限速
+func (l *cloudreveLibrary) Open(ctx context.Context, id string) (io.ReadSeekCloser, error) {
+	isDir, uid, objectID, ok := parseID(id)
+	if !ok || isDir {
+		return nil, ErrNotExist
+	}
+
+	user, err := publisher(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := publishedFile(user, objectID)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(ctx, 0)
+	if err != nil {
+		fs.Recycle()
+		return nil, err
+	}
+
+	return &fileReader{ReadSeekCloser: rs, fs: fs}, nil
+}
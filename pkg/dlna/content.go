@@ -0,0 +1,297 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// flagsAV 音视频的 DLNA 标志：流式传输、后台传输、允许连接暂停、DLNA 1.5
+	flagsAV = "01700000000000000000000000000000"
+	// flagsImage 图片的 DLNA 标志：交互式传输、后台传输、允许连接暂停、DLNA 1.5
+	flagsImage = "00f00000000000000000000000000000"
+)
+
+// mediaType 媒体文件的类型
+type mediaType struct {
+	mime  string
+	class string
+}
+
+// mediaTypes 按扩展名识别的媒体文件，其他文件不在媒体库中列出
+var mediaTypes = map[string]*mediaType{
+	".mp4":  {"video/mp4", "object.item.videoItem"},
+	".m4v":  {"video/mp4", "object.item.videoItem"},
+	".mkv":  {"video/x-matroska", "object.item.videoItem"},
+	".avi":  {"video/x-msvideo", "object.item.videoItem"},
+	".mov":  {"video/quicktime", "object.item.videoItem"},
+	".wmv":  {"video/x-ms-wmv", "object.item.videoItem"},
+	".flv":  {"video/x-flv", "object.item.videoItem"},
+	".webm": {"video/webm", "object.item.videoItem"},
+	".mpg":  {"video/mpeg", "object.item.videoItem"},
+	".mpeg": {"video/mpeg", "object.item.videoItem"},
+	".ts":   {"video/mp2t", "object.item.videoItem"},
+	".m2ts": {"video/mp2t", "object.item.videoItem"},
+	".3gp":  {"video/3gpp", "object.item.videoItem"},
+	".mp3":  {"audio/mpeg", "object.item.audioItem.musicTrack"},
+	".flac": {"audio/flac", "object.item.audioItem.musicTrack"},
+	".wav":  {"audio/wav", "object.item.audioItem.musicTrack"},
+	".m4a":  {"audio/mp4", "object.item.audioItem.musicTrack"},
+	".aac":  {"audio/aac", "object.item.audioItem.musicTrack"},
+	".ogg":  {"audio/ogg", "object.item.audioItem.musicTrack"},
+	".wma":  {"audio/x-ms-wma", "object.item.audioItem.musicTrack"},
+	".ape":  {"audio/x-ape", "object.item.audioItem.musicTrack"},
+	".jpg":  {"image/jpeg", "object.item.imageItem.photo"},
+	".jpeg": {"image/jpeg", "object.item.imageItem.photo"},
+	".png":  {"image/png", "object.item.imageItem.photo"},
+	".gif":  {"image/gif", "object.item.imageItem.photo"},
+	".bmp":  {"image/bmp", "object.item.imageItem.photo"},
+	".webp": {"image/webp", "object.item.imageItem.photo"},
+}
+
+// mediaTypeOf 获取文件的媒体类型，非媒体文件返回 nil
+func mediaTypeOf(name string) *mediaType {
+	return mediaTypes[strings.ToLower(path.Ext(name))]
+}
+
+// image 是否为图片
+func (m *mediaType) image() bool {
+	return strings.HasPrefix(m.mime, "image/")
+}
+
+// transferMode 文件流的传输模式
+func (m *mediaType) transferMode() string {
+	if m.image() {
+		return "Interactive"
+	}
+	return "Streaming"
+}
+
+// features 文件流的 DLNA 特性，支持按字节范围定位
+func (m *mediaType) features() string {
+	flags := flagsAV
+	if m.image() {
+		flags = flagsImage
+	}
+	return "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=" + flags
+}
+
+// sourceProtocolInfo 支持提供的所有媒体格式
+func sourceProtocolInfo() string {
+	seen := make(map[string]bool)
+	infos := make([]string, 0, len(mediaTypes))
+	for _, m := range mediaTypes {
+		if !seen[m.mime] {
+			seen[m.mime] = true
+			infos = append(infos, "http-get:*:"+m.mime+":*")
+		}
+	}
+	sort.Strings(infos)
+	return strings.Join(infos, ",")
+}
+
+// didlRes 文件的资源地址
+type didlRes struct {
+	ProtocolInfo string `xml:"protocolInfo,attr"`
+	Size         uint64 `xml:"size,attr"`
+	URL          string `xml:",chardata"`
+}
+
+// didlObject DIDL-Lite 中的容器或文件
+type didlObject struct {
+	XMLName    xml.Name
+	ID         string   `xml:"id,attr"`
+	ParentID   string   `xml:"parentID,attr"`
+	Restricted string   `xml:"restricted,attr"`
+	Searchable string   `xml:"searchable,attr,omitempty"`
+	Title      string   `xml:"dc:title"`
+	Class      string   `xml:"upnp:class"`
+	Date       string   `xml:"dc:date,omitempty"`
+	Res        *didlRes `xml:"res,omitempty"`
+}
+
+// didlLite Browse 动作返回的对象列表
+type didlLite struct {
+	XMLName   xml.Name `xml:"DIDL-Lite"`
+	XMLNS     string   `xml:"xmlns,attr"`
+	XMLNSDC   string   `xml:"xmlns:dc,attr"`
+	XMLNSUPnP string   `xml:"xmlns:upnp,attr"`
+	XMLNSDLNA string   `xml:"xmlns:dlna,attr"`
+	Objects   []didlObject
+}
+
+// didl 生成对象列表的 DIDL-Lite 文档，base 为文件流地址的前缀
+func didl(base string, objects []Object) (string, error) {
+	doc := didlLite{
+		XMLNS:     "urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/",
+		XMLNSDC:   "http://purl.org/dc/elements/1.1/",
+		XMLNSUPnP: "urn:schemas-upnp-org:metadata-1-0/upnp/",
+		XMLNSDLNA: "urn:schemas-dlna-org:metadata-1-0/",
+		Objects:   make([]didlObject, 0, len(objects)),
+	}
+
+	for _, obj := range objects {
+		item := didlObject{
+			ID:         obj.ID,
+			ParentID:   obj.ParentID,
+			Restricted: "1",
+			Title:      obj.Title,
+		}
+
+		if obj.IsDir {
+			item.XMLName.Local = "container"
+			item.Searchable = "0"
+			item.Class = "object.container.storageFolder"
+		} else {
+			item.XMLName.Local = "item"
+			item.Class = "object.item"
+			item.Date = obj.ModTime.Format("2006-01-02T15:04:05")
+			if media := mediaTypeOf(obj.Title); media != nil {
+				item.Class = media.class
+				item.Res = &didlRes{
+					ProtocolInfo: "http-get:*:" + media.mime + ":" + media.features(),
+					Size:         obj.Size,
+					URL:          base + "/dlna/res/" + url.PathEscape(obj.ID) + "/" + url.PathEscape(obj.Title),
+				}
+			}
+		}
+
+		doc.Objects = append(doc.Objects, item)
+	}
+
+	res, err := xml.Marshal(doc)
+	return string(res), err
+}
+
+// children 列出容器下的子容器及媒体文件，子容器在前，按名称排序
+func (s *Server) children(id string) ([]Object, error) {
+	objects, err := s.library.List(id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]Object, 0, len(objects))
+	for _, obj := range objects {
+		if obj.IsDir || mediaTypeOf(obj.Title) != nil {
+			res = append(res, obj)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].IsDir != res[j].IsDir {
+			return res[i].IsDir
+		}
+		return strings.ToLower(res[i].Title) < strings.ToLower(res[j].Title)
+	})
+	return res, nil
+}
+
+// browse 处理 ContentDirectory 的 Browse 动作
+func (s *Server) browse(r *http.Request, args map[string]string) ([]soapArg, error) {
+	start, err := strconv.ParseUint(args["StartingIndex"], 10, 32)
+	if err != nil {
+		return nil, errInvalidArgs
+	}
+	count, err := strconv.ParseUint(args["RequestedCount"], 10, 32)
+	if err != nil {
+		return nil, errInvalidArgs
+	}
+
+	obj, err := s.get(args["ObjectID"])
+	if err == ErrNotExist {
+		return nil, errNoSuchObject
+	} else if err != nil {
+		return nil, err
+	}
+
+	var (
+		objects []Object
+		total   int
+	)
+	switch args["BrowseFlag"] {
+	case "BrowseMetadata":
+		objects = []Object{*obj}
+		total = 1
+	case "BrowseDirectChildren":
+		if !obj.IsDir {
+			return nil, errNoSuchContainer
+		}
+
+		if objects, err = s.children(obj.ID); err != nil {
+			return nil, err
+		}
+
+		// 分页
+		total = len(objects)
+		if start > uint64(total) {
+			start = uint64(total)
+		}
+		objects = objects[start:]
+		if count > 0 && count < uint64(len(objects)) {
+			objects = objects[:count]
+		}
+	default:
+		return nil, errInvalidArgs
+	}
+
+	result, err := didl("http://"+r.Host, objects)
+	if err != nil {
+		return nil, err
+	}
+
+	return []soapArg{
+		{"Result", result},
+		{"NumberReturned", strconv.Itoa(len(objects))},
+		{"TotalMatches", strconv.Itoa(total)},
+		{"UpdateID", systemUpdateID},
+	}, nil
+}
+
+// serveContent 发送媒体文件，p 形如 {id}/{文件名}，文件名仅供客户端识别格式
+func (s *Server) serveContent(w http.ResponseWriter, r *http.Request, p string) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.SplitN(p, "/", 2)[0]
+	obj, err := s.get(id)
+	if err != nil {
+		if err == ErrNotExist {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	media := mediaTypeOf(obj.Title)
+	if obj.IsDir || media == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	rs, err := s.library.Open(r.Context(), id)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	defer rs.Close()
+
+	header := w.Header()
+	header.Set("Content-Type", media.mime)
+	header.Set("transferMode.dlna.org", media.transferMode())
+	if r.Header.Get("getcontentFeatures.dlna.org") == "1" {
+		header.Set("contentFeatures.dlna.org", media.features())
+	}
+	if obj.ETag != "" {
+		header.Set("ETag", obj.ETag)
+	}
+
+	http.ServeContent(w, r, obj.Title, obj.ModTime, rs)
+}
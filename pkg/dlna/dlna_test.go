@@ -0,0 +1,486 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// nopCloser 为 bytes.Reader 添加 Close
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// memLibrary 内存中的媒体库
+type memLibrary struct {
+	objects  map[string]*Object
+	children map[string][]string
+	content  map[string]string
+}
+
+func newMemLibrary() *memLibrary {
+	l := &memLibrary{
+		objects:  make(map[string]*Object),
+		children: make(map[string][]string),
+		content:  make(map[string]string),
+	}
+	l.add(Object{ID: "d1.1", ParentID: rootID, Title: "Movies (admin)", IsDir: true})
+	l.add(Object{ID: "d1.2", ParentID: "d1.1", Title: "Series", IsDir: true})
+	l.add(Object{ID: "f1.1", ParentID: "d1.1", Title: "b.mkv", ETag: `"etag"`}, "0123456789")
+	l.add(Object{ID: "f1.2", ParentID: "d1.1", Title: "A.mp4"}, "video")
+	l.add(Object{ID: "f1.3", ParentID: "d1.1", Title: "notes.txt"}, "text")
+	l.add(Object{ID: "f1.4", ParentID: "d1.1", Title: "cover.JPG"}, "image")
+	return l
+}
+
+func (l *memLibrary) add(obj Object, content ...string) {
+	obj.ModTime = time.Date(2021, 5, 1, 8, 30, 0, 0, time.UTC)
+	if len(content) > 0 {
+		obj.Size = uint64(len(content[0]))
+		l.content[obj.ID] = content[0]
+	}
+	l.objects[obj.ID] = &obj
+	l.children[obj.ParentID] = append(l.children[obj.ParentID], obj.ID)
+}
+
+func (l *memLibrary) Get(id string) (*Object, error) {
+	if obj, ok := l.objects[id]; ok {
+		return obj, nil
+	}
+	return nil, ErrNotExist
+}
+
+func (l *memLibrary) List(id string) ([]Object, error) {
+	if id == "d1.2" {
+		return nil, errors.New("error")
+	}
+
+	res := make([]Object, 0, len(l.children[id]))
+	for _, child := range l.children[id] {
+		res = append(res, *l.objects[child])
+	}
+	return res, nil
+}
+
+func (l *memLibrary) Open(ctx context.Context, id string) (io.ReadSeekCloser, error) {
+	content, ok := l.content[id]
+	if !ok {
+		return nil, ErrNotExist
+	}
+	return nopCloser{bytes.NewReader([]byte(content))}, nil
+}
+
+func newTestServer() *Server {
+	return NewServer(&Settings{
+		FriendlyName: "Cloudreve & Co",
+		UUID:         "c1e2f3a4-0000-4000-8000-000000000000",
+		ModelNumber:  "3.5.3",
+	}, newMemLibrary())
+}
+
+// browseResult Browse 动作的响应
+type browseResult struct {
+	Body struct {
+		Response struct {
+			Result         string
+			NumberReturned int
+			TotalMatches   int
+		} `xml:"BrowseResponse"`
+		Fault struct {
+			Detail struct {
+				UPnPError struct {
+					ErrorCode int `xml:"errorCode"`
+				}
+			} `xml:"detail"`
+		}
+	}
+}
+
+// didlResult 解析 DIDL-Lite
+type didlResult struct {
+	Containers []struct {
+		ID       string `xml:"id,attr"`
+		ParentID string `xml:"parentID,attr"`
+		Title    string `xml:"title"`
+	} `xml:"container"`
+	Items []struct {
+		ID    string `xml:"id,attr"`
+		Title string `xml:"title"`
+		Class string `xml:"class"`
+		Res   struct {
+			ProtocolInfo string `xml:"protocolInfo,attr"`
+			Size         uint64 `xml:"size,attr"`
+			URL          string `xml:",chardata"`
+		} `xml:"res"`
+	} `xml:"item"`
+}
+
+func browse(t *testing.T, s *Server, id, flag string, start, count int) (int, browseResult, didlResult) {
+	body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+		`<u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">` +
+		`<ObjectID>` + id + `</ObjectID><BrowseFlag>` + flag + `</BrowseFlag><Filter>*</Filter>` +
+		`<StartingIndex>` + strconv.Itoa(start) + `</StartingIndex><RequestedCount>` + strconv.Itoa(count) + `</RequestedCount>` +
+		`<SortCriteria></SortCriteria></u:Browse></s:Body></s:Envelope>`
+	req := httptest.NewRequest("POST", "http://192.168.1.2:8200/dlna/ctl/ContentDirectory", strings.NewReader(body))
+	req.Header.Set("SOAPACTION", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+
+	var res browseResult
+	assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &res))
+	var doc didlResult
+	if res.Body.Response.Result != "" {
+		assert.NoError(t, xml.Unmarshal([]byte(res.Body.Response.Result), &doc))
+	}
+	return w.Code, res, doc
+}
+
+// readerOf 读取 SSDP 消息
+func readerOf(msg []byte) *bufio.Reader {
+	return bufio.NewReader(bytes.NewReader(msg))
+}
+
+func TestServer_Description(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+
+	// 设备描述
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/rootDesc.xml", nil))
+		a.Equal(200, w.Code)
+		a.Contains(w.Body.String(), "<friendlyName>Cloudreve &amp; Co</friendlyName>")
+		a.Contains(w.Body.String(), "<UDN>uuid:c1e2f3a4-0000-4000-8000-000000000000</UDN>")
+		a.Contains(w.Body.String(), "<controlURL>/dlna/ctl/ContentDirectory</controlURL>")
+		a.Contains(w.Header().Get("Server"), "Cloudreve/3.5.3")
+		a.NoError(xml.Unmarshal(w.Body.Bytes(), new(struct{})))
+	}
+
+	// 服务描述
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/ContentDirectory.xml", nil))
+		a.Equal(200, w.Code)
+		a.Contains(w.Body.String(), "<name>Browse</name>")
+		a.Contains(w.Body.String(), "<allowedValue>BrowseDirectChildren</allowedValue>")
+		a.NoError(xml.Unmarshal(w.Body.Bytes(), new(struct{})))
+	}
+
+	// 服务不存在
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/AVTransport.xml", nil))
+		a.Equal(404, w.Code)
+	}
+}
+
+func TestServer_Browse(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+
+	// 根容器
+	{
+		code, res, doc := browse(t, s, "0", "BrowseMetadata", 0, 0)
+		a.Equal(200, code)
+		a.Equal(1, res.Body.Response.NumberReturned)
+		a.Len(doc.Containers, 1)
+		a.Equal("-1", doc.Containers[0].ParentID)
+		a.Equal("Cloudreve & Co", doc.Containers[0].Title)
+	}
+
+	// 根容器下的目录
+	{
+		code, res, doc := browse(t, s, "0", "BrowseDirectChildren", 0, 0)
+		a.Equal(200, code)
+		a.Equal(1, res.Body.Response.TotalMatches)
+		a.Len(doc.Containers, 1)
+		a.Equal("d1.1", doc.Containers[0].ID)
+	}
+
+	// 列出目录，跳过非媒体文件，目录在前并按名称排序
+	{
+		code, res, doc := browse(t, s, "d1.1", "BrowseDirectChildren", 0, 0)
+		a.Equal(200, code)
+		a.Equal(4, res.Body.Response.TotalMatches)
+		a.Equal(4, res.Body.Response.NumberReturned)
+		a.Len(doc.Containers, 1)
+		a.Len(doc.Items, 3)
+		a.Equal("A.mp4", doc.Items[0].Title)
+		a.Equal("b.mkv", doc.Items[1].Title)
+		a.Equal("cover.JPG", doc.Items[2].Title)
+		a.Equal("object.item.videoItem", doc.Items[1].Class)
+		a.Equal("object.item.imageItem.photo", doc.Items[2].Class)
+		a.EqualValues(10, doc.Items[1].Res.Size)
+		a.Equal("http://192.168.1.2:8200/dlna/res/f1.1/b.mkv", doc.Items[1].Res.URL)
+		a.True(strings.HasPrefix(doc.Items[1].Res.ProtocolInfo, "http-get:*:video/x-matroska:DLNA.ORG_OP=01;"))
+	}
+
+	// 分页
+	{
+		code, res, doc := browse(t, s, "d1.1", "BrowseDirectChildren", 1, 2)
+		a.Equal(200, code)
+		a.Equal(4, res.Body.Response.TotalMatches)
+		a.Equal(2, res.Body.Response.NumberReturned)
+		a.Len(doc.Containers, 0)
+		a.Len(doc.Items, 2)
+		a.Equal("A.mp4", doc.Items[0].Title)
+	}
+
+	// 起始位置超出范围
+	{
+		code, res, _ := browse(t, s, "d1.1", "BrowseDirectChildren", 9, 0)
+		a.Equal(200, code)
+		a.Equal(4, res.Body.Response.TotalMatches)
+		a.Equal(0, res.Body.Response.NumberReturned)
+	}
+
+	// 对象不存在
+	{
+		code, res, _ := browse(t, s, "d1.9", "BrowseMetadata", 0, 0)
+		a.Equal(500, code)
+		a.Equal(701, res.Body.Fault.Detail.UPnPError.ErrorCode)
+	}
+
+	// 文件不是容器
+	{
+		code, res, _ := browse(t, s, "f1.1", "BrowseDirectChildren", 0, 0)
+		a.Equal(500, code)
+		a.Equal(710, res.Body.Fault.Detail.UPnPError.ErrorCode)
+	}
+
+	// 无效的参数
+	{
+		code, res, _ := browse(t, s, "d1.1", "BrowseAll", 0, 0)
+		a.Equal(500, code)
+		a.Equal(402, res.Body.Fault.Detail.UPnPError.ErrorCode)
+	}
+
+	// 列出失败
+	{
+		code, res, _ := browse(t, s, "d1.2", "BrowseDirectChildren", 0, 0)
+		a.Equal(500, code)
+		a.Equal(501, res.Body.Fault.Detail.UPnPError.ErrorCode)
+	}
+}
+
+func TestServer_Control(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+	control := func(service, action, args string) *httptest.ResponseRecorder {
+		body := `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body>` +
+			`<u:` + action + ` xmlns:u="urn:schemas-upnp-org:service:` + service + `:1">` + args + `</u:` + action + `>` +
+			`</s:Body></s:Envelope>`
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/dlna/ctl/"+service, strings.NewReader(body)))
+		return w
+	}
+
+	// 支持的格式
+	{
+		w := control("ConnectionManager", "GetProtocolInfo", "")
+		a.Equal(200, w.Code)
+		a.Contains(w.Body.String(), "http-get:*:video/mp4:*")
+		a.Contains(w.Body.String(), "<u:GetProtocolInfoResponse")
+	}
+
+	// 连接信息
+	{
+		w := control("ConnectionManager", "GetCurrentConnectionInfo", "<ConnectionID>0</ConnectionID>")
+		a.Equal(200, w.Code)
+		a.Contains(w.Body.String(), "<Direction>Output</Direction>")
+
+		w = control("ConnectionManager", "GetCurrentConnectionInfo", "<ConnectionID>1</ConnectionID>")
+		a.Equal(500, w.Code)
+		a.Contains(w.Body.String(), "<errorCode>706</errorCode>")
+	}
+
+	// 未知的动作
+	{
+		w := control("ContentDirectory", "Search", "")
+		a.Equal(500, w.Code)
+		a.Contains(w.Body.String(), "<errorCode>401</errorCode>")
+	}
+
+	// 无效的请求
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/dlna/ctl/ContentDirectory", strings.NewReader("<")))
+		a.Equal(500, w.Code)
+
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/ctl/ContentDirectory", nil))
+		a.Equal(405, w.Code)
+	}
+}
+
+func TestServer_Event(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("SUBSCRIBE", "/dlna/evt/ContentDirectory", nil))
+	a.Equal(200, w.Code)
+	a.True(strings.HasPrefix(w.Header().Get("SID"), "uuid:"))
+	a.Equal(subscriptionTimeout, w.Header().Get("TIMEOUT"))
+
+	// 续订
+	req := httptest.NewRequest("SUBSCRIBE", "/dlna/evt/ContentDirectory", nil)
+	req.Header.Set("SID", "uuid:1")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	a.Equal("uuid:1", w.Header().Get("SID"))
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("UNSUBSCRIBE", "/dlna/evt/ContentDirectory", nil))
+	a.Equal(200, w.Code)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("SUBSCRIBE", "/dlna/evt/AVTransport", nil))
+	a.Equal(404, w.Code)
+}
+
+func TestServer_Content(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+
+	// 分段请求
+	{
+		req := httptest.NewRequest("GET", "/dlna/res/f1.1/b.mkv", nil)
+		req.Header.Set("Range", "bytes=4-")
+		req.Header.Set("getcontentFeatures.dlna.org", "1")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		a.Equal(http.StatusPartialContent, w.Code)
+		a.Equal("456789", w.Body.String())
+		a.Equal("video/x-matroska", w.Header().Get("Content-Type"))
+		a.Equal(`"etag"`, w.Header().Get("ETag"))
+		a.Equal("Streaming", w.Header().Get("transferMode.dlna.org"))
+		a.Contains(w.Header().Get("contentFeatures.dlna.org"), "DLNA.ORG_OP=01")
+	}
+
+	// 实体标签不匹配时返回完整内容
+	{
+		req := httptest.NewRequest("GET", "/dlna/res/f1.1", nil)
+		req.Header.Set("Range", "bytes=4-")
+		req.Header.Set("If-Range", `"changed"`)
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		a.Equal(http.StatusOK, w.Code)
+		a.Equal("0123456789", w.Body.String())
+		a.Empty(w.Header().Get("contentFeatures.dlna.org"))
+	}
+
+	// 图片
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/res/f1.4/cover.JPG", nil))
+		a.Equal(http.StatusOK, w.Code)
+		a.Equal("Interactive", w.Header().Get("transferMode.dlna.org"))
+	}
+
+	// 非媒体文件、目录及不存在的对象
+	for _, id := range []string{"f1.3", "d1.1", "f1.9"} {
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("GET", "/dlna/res/"+id, nil))
+		a.Equal(http.StatusNotFound, w.Code, id)
+	}
+
+	// 不支持的方法
+	{
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest("POST", "/dlna/res/f1.1", nil))
+		a.Equal(http.StatusMethodNotAllowed, w.Code)
+	}
+}
+
+func TestSSDPServer_Messages(t *testing.T) {
+	a := assert.New(t)
+	s := newSSDPServer(&Settings{UUID: "uuid-1", ModelNumber: "3.5.3"}, 8200)
+	ip := net.IPv4(192, 168, 1, 2)
+
+	a.Len(s.searchTargets("ssdp:all"), 3+len(services))
+	a.Equal([]string{deviceType}, s.searchTargets(deviceType))
+	a.Empty(s.searchTargets("urn:schemas-upnp-org:device:MediaRenderer:1"))
+
+	a.Equal("uuid:uuid-1", s.usn("uuid:uuid-1"))
+	a.Equal("uuid:uuid-1::upnp:rootdevice", s.usn("upnp:rootdevice"))
+
+	// 搜索响应
+	resp, err := http.ReadResponse(readerOf(s.searchResponse("upnp:rootdevice", ip)), nil)
+	a.NoError(err)
+	a.Equal(200, resp.StatusCode)
+	a.Equal("http://192.168.1.2:8200/dlna/rootDesc.xml", resp.Header.Get("LOCATION"))
+	a.Equal("upnp:rootdevice", resp.Header.Get("ST"))
+	a.Equal("max-age=1800", resp.Header.Get("CACHE-CONTROL"))
+
+	// 上线通告
+	req, err := http.ReadRequest(readerOf(s.notifyMessage(deviceType, "ssdp:alive", ip)))
+	a.NoError(err)
+	a.Equal("NOTIFY", req.Method)
+	a.Equal("239.255.255.250:1900", req.Host)
+	a.Equal("http://192.168.1.2:8200/dlna/rootDesc.xml", req.Header.Get("LOCATION"))
+	a.Equal("uuid:uuid-1::"+deviceType, req.Header.Get("USN"))
+
+	// 下线通告
+	req, err = http.ReadRequest(readerOf(s.notifyMessage(deviceType, "ssdp:byebye", ip)))
+	a.NoError(err)
+	a.Equal("ssdp:byebye", req.Header.Get("NTS"))
+	a.Empty(req.Header.Get("LOCATION"))
+
+	// 未启动时停止
+	a.NotPanics(s.stop)
+}
+
+func TestParseID(t *testing.T) {
+	a := assert.New(t)
+
+	isDir, uid, id, ok := parseID("d1.20")
+	a.True(ok)
+	a.True(isDir)
+	a.EqualValues(1, uid)
+	a.EqualValues(20, id)
+
+	isDir, uid, id, ok = parseID(fileObjectID(3, 4))
+	a.True(ok)
+	a.False(isDir)
+	a.EqualValues(3, uid)
+	a.EqualValues(4, id)
+
+	for _, invalid := range []string{"", "0", "d", "d1", "x1.2", "d1.a", "f-1.2"} {
+		_, _, _, ok = parseID(invalid)
+		a.False(ok, invalid)
+	}
+}
+
+func TestMediaTypeOf(t *testing.T) {
+	a := assert.New(t)
+	a.Equal("video/mp4", mediaTypeOf("a.MP4").mime)
+	a.Equal("audio/flac", mediaTypeOf("song.flac").mime)
+	a.Nil(mediaTypeOf("readme"))
+	a.Nil(mediaTypeOf("a.txt"))
+	a.Equal(flagsImage, strings.TrimPrefix(mediaTypeOf("a.png").features(), "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS="))
+}
+
+func TestNewServer_Shutdown(t *testing.T) {
+	a := assert.New(t)
+	s := newTestServer()
+	a.NoError(s.Shutdown())
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	a.NoError(err)
+	a.Equal(ErrServerClosed, s.Serve(l))
+}
@@ -0,0 +1,157 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rootID 根容器的 ID
+const rootID = "0"
+
+// ErrServerClosed 服务已关闭
+var ErrServerClosed = errors.New("dlna: server closed")
+
+// ErrNotExist 对象不存在或未发布
+var ErrNotExist = errors.New("dlna: object not exist")
+
+// Object 媒体库中的容器或文件
+type Object struct {
+	ID       string
+	ParentID string
+	Title    string
+	IsDir    bool
+	Size     uint64
+	ModTime  time.Time
+	// ETag 文件的实体标签，用于条件请求及断点续传
+	ETag string
+}
+
+// Library 提供媒体库内容的驱动，根容器的 ID 为 "0"，由服务处理
+type Library interface {
+	// Get 获取对象，不存在或未发布时返回 ErrNotExist
+	Get(id string) (*Object, error)
+	// List 列出容器下的对象
+	List(id string) ([]Object, error)
+	// Open 打开文件用于读取
+	Open(ctx context.Context, id string) (io.ReadSeekCloser, error)
+}
+
+// Settings 服务配置
+type Settings struct {
+	// Listen HTTP 服务的监听地址
+	Listen string
+	// FriendlyName 在客户端中显示的名称
+	FriendlyName string
+	// UUID 设备的唯一标识，应在重启后保持不变
+	UUID string
+	// Interfaces 发布服务的网络接口名，为空时使用所有支持多播的接口
+	Interfaces []string
+	// ModelNumber 设备的型号
+	ModelNumber string
+	// PresentationURL 设备的管理页面
+	PresentationURL string
+}
+
+// Server DLNA 媒体服务，通过 SSDP 在局域网中发布，以 HTTP 提供设备描述、控制及文件流
+type Server struct {
+	settings *Settings
+	library  Library
+
+	mu       sync.Mutex
+	http     *http.Server
+	ssdp     *ssdpServer
+	listener net.Listener
+	closed   bool
+}
+
+// NewServer 新建 DLNA 媒体服务
+func NewServer(settings *Settings, library Library) *Server {
+	return &Server{
+		settings: settings,
+		library:  library,
+	}
+}
+
+// ListenAndServe 监听 Settings.Listen 并开始发布服务，Shutdown 后返回 ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.settings.Listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 在 l 上提供 HTTP 服务，并在 l 的端口上通过 SSDP 发布
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+
+	s.listener = l
+	s.http = &http.Server{Handler: s}
+	s.ssdp = newSSDPServer(s.settings, l.Addr().(*net.TCPAddr).Port)
+	s.mu.Unlock()
+
+	if err := s.ssdp.start(); err != nil {
+		l.Close()
+		return err
+	}
+
+	err := s.http.Serve(l)
+	if err == http.ErrServerClosed {
+		return ErrServerClosed
+	}
+	return err
+}
+
+// Shutdown 通知客户端服务下线并关闭服务
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+
+	if s.ssdp != nil {
+		s.ssdp.stop()
+	}
+
+	if s.http != nil {
+		return s.http.Close()
+	}
+	return nil
+}
+
+// ServeHTTP 处理设备描述、控制、事件订阅及文件流请求
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Server", serverHeader(s.settings))
+
+	p := strings.TrimPrefix(r.URL.Path, "/dlna/")
+	switch {
+	case p == descriptionPath:
+		s.serveDescription(w, r)
+	case strings.HasPrefix(p, "ctl/"):
+		s.serveControl(w, r, strings.TrimPrefix(p, "ctl/"))
+	case strings.HasPrefix(p, "evt/"):
+		s.serveEvent(w, r, strings.TrimPrefix(p, "evt/"))
+	case strings.HasPrefix(p, "res/"):
+		s.serveContent(w, r, strings.TrimPrefix(p, "res/"))
+	default:
+		s.serveSCPD(w, r, strings.TrimSuffix(p, ".xml"))
+	}
+}
+
+// get 获取对象，根容器由服务生成
+func (s *Server) get(id string) (*Object, error) {
+	if id == rootID {
+		return &Object{ID: rootID, ParentID: "-1", Title: s.settings.FriendlyName, IsDir: true}, nil
+	}
+	return s.library.Get(id)
+}
@@ -0,0 +1,350 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+const (
+	// ssdpMaxAge 通告的有效期，单位为秒
+	ssdpMaxAge = 1800
+	// ssdpNotifyInterval 重复通告的间隔，应小于有效期的一半
+	ssdpNotifyInterval = 10 * time.Minute
+	// ssdpMaxDelay 响应搜索前的最大随机延迟
+	ssdpMaxDelay = 3 * time.Second
+)
+
+// ssdpGroup SSDP 多播地址
+var ssdpGroup = &net.UDPAddr{IP: net.IPv4(239, 255, 255, 250), Port: 1900}
+
+// ssdpServer 在局域网中通告服务并响应搜索
+type ssdpServer struct {
+	settings *Settings
+	port     int
+
+	interfaces []net.Interface
+	conn       *ipv4.PacketConn // 接收多播的搜索请求
+	sender     *ipv4.PacketConn // 发送通告及响应
+
+	sendMu  sync.Mutex
+	closing chan struct{}
+	wg      sync.WaitGroup
+}
+
+func newSSDPServer(settings *Settings, port int) *ssdpServer {
+	return &ssdpServer{
+		settings: settings,
+		port:     port,
+		closing:  make(chan struct{}),
+	}
+}
+
+// multicastInterfaces 获取发布服务的网络接口，names 为空时使用所有支持多播的接口
+func multicastInterfaces(names []string) ([]net.Interface, error) {
+	all, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]net.Interface, 0, len(all))
+	for _, ifi := range all {
+		if ifi.Flags&net.FlagUp == 0 || ifi.Flags&net.FlagMulticast == 0 || ifi.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+
+		if len(names) > 0 {
+			selected := false
+			for _, name := range names {
+				selected = selected || name == ifi.Name
+			}
+			if !selected {
+				continue
+			}
+		}
+
+		if interfaceIP(&ifi, nil) != nil {
+			res = append(res, ifi)
+		}
+	}
+
+	if len(res) == 0 {
+		return nil, fmt.Errorf("dlna: no multicast interface available")
+	}
+	return res, nil
+}
+
+// interfaceIP 获取接口的 IPv4 地址，优先选择与 remote 位于同一网段的地址
+func interfaceIP(ifi *net.Interface, remote net.IP) net.IP {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return nil
+	}
+
+	var first net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.To4() == nil {
+			continue
+		}
+		if remote != nil && ipNet.Contains(remote) {
+			return ipNet.IP.To4()
+		}
+		if first == nil {
+			first = ipNet.IP.To4()
+		}
+	}
+	return first
+}
+
+// start 加入多播组并开始通告
+func (s *ssdpServer) start() error {
+	interfaces, err := multicastInterfaces(s.settings.Interfaces)
+	if err != nil {
+		return err
+	}
+	s.interfaces = interfaces
+
+	conn, err := net.ListenMulticastUDP("udp4", &interfaces[0], ssdpGroup)
+	if err != nil {
+		return err
+	}
+	s.conn = ipv4.NewPacketConn(conn)
+	for i := 1; i < len(interfaces); i++ {
+		s.conn.JoinGroup(&interfaces[i], ssdpGroup)
+	}
+	// 部分平台不支持控制消息，此时按来源地址确定接口
+	s.conn.SetControlMessage(ipv4.FlagInterface, true)
+
+	sender, err := net.ListenPacket("udp4", ":0")
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	s.sender = ipv4.NewPacketConn(sender)
+	s.sender.SetMulticastTTL(2)
+
+	s.wg.Add(2)
+	go s.serve()
+	go s.announce()
+	return nil
+}
+
+// stop 通知服务下线并停止
+func (s *ssdpServer) stop() {
+	select {
+	case <-s.closing:
+		return
+	default:
+	}
+
+	close(s.closing)
+	if s.conn == nil {
+		return
+	}
+	s.conn.Close()
+	s.wg.Wait()
+
+	s.notifyAll("ssdp:byebye")
+	s.sender.Close()
+}
+
+// targets 通告及搜索的目标：根设备、设备 UUID、设备类型及各服务类型
+func (s *ssdpServer) targets() []string {
+	res := []string{"upnp:rootdevice", "uuid:" + s.settings.UUID, deviceType}
+	for _, svc := range services {
+		res = append(res, svc.typ)
+	}
+	return res
+}
+
+// usn 目标的唯一服务名
+func (s *ssdpServer) usn(target string) string {
+	if target == "uuid:"+s.settings.UUID {
+		return target
+	}
+	return "uuid:" + s.settings.UUID + "::" + target
+}
+
+// location 设备描述的地址
+func (s *ssdpServer) location(ip net.IP) string {
+	return fmt.Sprintf("http://%s/dlna/%s", net.JoinHostPort(ip.String(), strconv.Itoa(s.port)), descriptionPath)
+}
+
+// notifyMessage 生成多播通告，nts 为 ssdp:alive 或 ssdp:byebye
+func (s *ssdpServer) notifyMessage(target, nts string, ip net.IP) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("NOTIFY * HTTP/1.1\r\n")
+	fmt.Fprintf(&buf, "HOST: %s\r\n", ssdpGroup)
+	if nts == "ssdp:alive" {
+		fmt.Fprintf(&buf, "CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge)
+		fmt.Fprintf(&buf, "LOCATION: %s\r\n", s.location(ip))
+		fmt.Fprintf(&buf, "SERVER: %s\r\n", serverHeader(s.settings))
+	}
+	fmt.Fprintf(&buf, "NT: %s\r\n", target)
+	fmt.Fprintf(&buf, "NTS: %s\r\n", nts)
+	fmt.Fprintf(&buf, "USN: %s\r\n\r\n", s.usn(target))
+	return buf.Bytes()
+}
+
+// searchResponse 生成搜索的单播响应
+func (s *ssdpServer) searchResponse(target string, ip net.IP) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("HTTP/1.1 200 OK\r\n")
+	fmt.Fprintf(&buf, "CACHE-CONTROL: max-age=%d\r\n", ssdpMaxAge)
+	fmt.Fprintf(&buf, "DATE: %s\r\n", time.Now().UTC().Format(http.TimeFormat))
+	buf.WriteString("EXT:\r\n")
+	fmt.Fprintf(&buf, "LOCATION: %s\r\n", s.location(ip))
+	fmt.Fprintf(&buf, "SERVER: %s\r\n", serverHeader(s.settings))
+	fmt.Fprintf(&buf, "ST: %s\r\n", target)
+	fmt.Fprintf(&buf, "USN: %s\r\n", s.usn(target))
+	buf.WriteString("Content-Length: 0\r\n\r\n")
+	return buf.Bytes()
+}
+
+// searchTargets 搜索请求匹配的目标
+func (s *ssdpServer) searchTargets(st string) []string {
+	if st == "ssdp:all" {
+		return s.targets()
+	}
+	for _, target := range s.targets() {
+		if target == st {
+			return []string{target}
+		}
+	}
+	return nil
+}
+
+// notifyAll 在所有接口上发送通告
+func (s *ssdpServer) notifyAll(nts string) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for i := range s.interfaces {
+		ip := interfaceIP(&s.interfaces[i], nil)
+		if ip == nil || s.sender.SetMulticastInterface(&s.interfaces[i]) != nil {
+			continue
+		}
+
+		for _, target := range s.targets() {
+			s.sender.WriteTo(s.notifyMessage(target, nts, ip), nil, ssdpGroup)
+		}
+	}
+}
+
+// announce 定期发送上线通告
+func (s *ssdpServer) announce() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(ssdpNotifyInterval)
+	defer ticker.Stop()
+
+	s.notifyAll("ssdp:alive")
+	for {
+		select {
+		case <-s.closing:
+			return
+		case <-ticker.C:
+			s.notifyAll("ssdp:alive")
+		}
+	}
+}
+
+// serve 接收并响应搜索请求
+func (s *ssdpServer) serve() {
+	defer s.wg.Done()
+
+	buf := make([]byte, 2048)
+	for {
+		n, cm, src, err := s.conn.ReadFrom(buf)
+		if err != nil {
+			select {
+			case <-s.closing:
+				return
+			case <-time.After(100 * time.Millisecond):
+				continue
+			}
+		}
+
+		req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(buf[:n])))
+		if err != nil || req.Method != "M-SEARCH" || req.Header.Get("MAN") != `"ssdp:discover"` {
+			continue
+		}
+
+		remote, ok := src.(*net.UDPAddr)
+		if !ok {
+			continue
+		}
+
+		targets := s.searchTargets(req.Header.Get("ST"))
+		ip := s.localIP(cm, remote.IP)
+		if len(targets) == 0 || ip == nil {
+			continue
+		}
+
+		mx, _ := strconv.Atoi(req.Header.Get("MX"))
+		s.wg.Add(1)
+		go s.reply(remote, targets, ip, mx)
+	}
+}
+
+// localIP 确定接收请求的接口的地址，用于生成设备描述的地址
+func (s *ssdpServer) localIP(cm *ipv4.ControlMessage, remote net.IP) net.IP {
+	for i := range s.interfaces {
+		ifi := &s.interfaces[i]
+		if cm != nil && cm.IfIndex != 0 {
+			if ifi.Index == cm.IfIndex {
+				return interfaceIP(ifi, remote)
+			}
+		} else if sameSubnet(ifi, remote) {
+			return interfaceIP(ifi, remote)
+		}
+	}
+	return nil
+}
+
+// sameSubnet 接口是否与 remote 位于同一网段
+func sameSubnet(ifi *net.Interface, remote net.IP) bool {
+	addrs, err := ifi.Addrs()
+	if err != nil {
+		return false
+	}
+
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.Contains(remote) {
+			return true
+		}
+	}
+	return false
+}
+
+// reply 按 MX 随机延迟后响应搜索
+func (s *ssdpServer) reply(remote *net.UDPAddr, targets []string, ip net.IP, mx int) {
+	defer s.wg.Done()
+
+	delay := ssdpMaxDelay
+	if mx >= 0 && time.Duration(mx)*time.Second < delay {
+		delay = time.Duration(mx) * time.Second
+	}
+	if delay > 0 {
+		select {
+		case <-s.closing:
+			return
+		case <-time.After(time.Duration(rand.Int63n(int64(delay)))):
+		}
+	}
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	for _, target := range targets {
+		s.sender.WriteTo(s.searchResponse(target, ip), nil, remote)
+	}
+}
@@ -0,0 +1,472 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// descriptionPath 设备描述的路径
+	descriptionPath = "rootDesc.xml"
+	// deviceType 设备类型
+	deviceType = "urn:schemas-upnp-org:device:MediaServer:1"
+	// systemUpdateID 内容目录的版本，媒体库不跟踪变更，始终返回同一版本
+	systemUpdateID = "1"
+	// subscriptionTimeout 事件订阅的有效期
+	subscriptionTimeout = "Second-1800"
+)
+
+// upnpError UPnP 控制请求的错误
+type upnpError struct {
+	code int
+	desc string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("UPnP error %d: %s", e.code, e.desc)
+}
+
+var (
+	errInvalidAction   = &upnpError{401, "Invalid Action"}
+	errInvalidArgs     = &upnpError{402, "Invalid Args"}
+	errActionFailed    = &upnpError{501, "Action Failed"}
+	errNoSuchObject    = &upnpError{701, "No such object"}
+	errNoSuchContainer = &upnpError{710, "No such container"}
+)
+
+// scpdArg 动作的参数
+type scpdArg struct {
+	name     string
+	out      bool
+	variable string
+}
+
+// scpdAction 服务提供的动作
+type scpdAction struct {
+	name    string
+	args    []scpdArg
+	handler func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error)
+}
+
+// scpdVariable 服务的状态变量
+type scpdVariable struct {
+	name     string
+	dataType string
+	events   bool
+	allowed  []string
+}
+
+// service 设备提供的服务
+type service struct {
+	name      string
+	typ       string
+	id        string
+	actions   []scpdAction
+	variables []scpdVariable
+}
+
+// services 设备提供的服务，Windows Media Player 等客户端要求提供 X_MS_MediaReceiverRegistrar
+var services = []*service{
+	{
+		name: "ContentDirectory",
+		typ:  "urn:schemas-upnp-org:service:ContentDirectory:1",
+		id:   "urn:upnp-org:serviceId:ContentDirectory",
+		actions: []scpdAction{
+			{
+				name: "GetSearchCapabilities",
+				args: []scpdArg{{"SearchCaps", true, "SearchCapabilities"}},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"SearchCaps", ""}}, nil
+				},
+			},
+			{
+				name: "GetSortCapabilities",
+				args: []scpdArg{{"SortCaps", true, "SortCapabilities"}},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"SortCaps", ""}}, nil
+				},
+			},
+			{
+				name: "GetSystemUpdateID",
+				args: []scpdArg{{"Id", true, "SystemUpdateID"}},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"Id", systemUpdateID}}, nil
+				},
+			},
+			{
+				name: "Browse",
+				args: []scpdArg{
+					{"ObjectID", false, "A_ARG_TYPE_ObjectID"},
+					{"BrowseFlag", false, "A_ARG_TYPE_BrowseFlag"},
+					{"Filter", false, "A_ARG_TYPE_Filter"},
+					{"StartingIndex", false, "A_ARG_TYPE_Index"},
+					{"RequestedCount", false, "A_ARG_TYPE_Count"},
+					{"SortCriteria", false, "A_ARG_TYPE_SortCriteria"},
+					{"Result", true, "A_ARG_TYPE_Result"},
+					{"NumberReturned", true, "A_ARG_TYPE_Count"},
+					{"TotalMatches", true, "A_ARG_TYPE_Count"},
+					{"UpdateID", true, "A_ARG_TYPE_UpdateID"},
+				},
+				handler: (*Server).browse,
+			},
+		},
+		variables: []scpdVariable{
+			{name: "SearchCapabilities", dataType: "string"},
+			{name: "SortCapabilities", dataType: "string"},
+			{name: "SystemUpdateID", dataType: "ui4", events: true},
+			{name: "A_ARG_TYPE_ObjectID", dataType: "string"},
+			{name: "A_ARG_TYPE_BrowseFlag", dataType: "string", allowed: []string{"BrowseMetadata", "BrowseDirectChildren"}},
+			{name: "A_ARG_TYPE_Filter", dataType: "string"},
+			{name: "A_ARG_TYPE_Index", dataType: "ui4"},
+			{name: "A_ARG_TYPE_Count", dataType: "ui4"},
+			{name: "A_ARG_TYPE_SortCriteria", dataType: "string"},
+			{name: "A_ARG_TYPE_Result", dataType: "string"},
+			{name: "A_ARG_TYPE_UpdateID", dataType: "ui4"},
+		},
+	},
+	{
+		name: "ConnectionManager",
+		typ:  "urn:schemas-upnp-org:service:ConnectionManager:1",
+		id:   "urn:upnp-org:serviceId:ConnectionManager",
+		actions: []scpdAction{
+			{
+				name: "GetProtocolInfo",
+				args: []scpdArg{
+					{"Source", true, "SourceProtocolInfo"},
+					{"Sink", true, "SinkProtocolInfo"},
+				},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"Source", sourceProtocolInfo()}, {"Sink", ""}}, nil
+				},
+			},
+			{
+				name: "GetCurrentConnectionIDs",
+				args: []scpdArg{{"ConnectionIDs", true, "CurrentConnectionIDs"}},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"ConnectionIDs", "0"}}, nil
+				},
+			},
+			{
+				name: "GetCurrentConnectionInfo",
+				args: []scpdArg{
+					{"ConnectionID", false, "A_ARG_TYPE_ConnectionID"},
+					{"RcsID", true, "A_ARG_TYPE_RcsID"},
+					{"AVTransportID", true, "A_ARG_TYPE_AVTransportID"},
+					{"ProtocolInfo", true, "A_ARG_TYPE_ProtocolInfo"},
+					{"PeerConnectionManager", true, "A_ARG_TYPE_ConnectionManager"},
+					{"PeerConnectionID", true, "A_ARG_TYPE_ConnectionID"},
+					{"Direction", true, "A_ARG_TYPE_Direction"},
+					{"Status", true, "A_ARG_TYPE_ConnectionStatus"},
+				},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					if args["ConnectionID"] != "0" {
+						return nil, &upnpError{706, "Invalid connection reference"}
+					}
+					return []soapArg{
+						{"RcsID", "-1"},
+						{"AVTransportID", "-1"},
+						{"ProtocolInfo", ""},
+						{"PeerConnectionManager", ""},
+						{"PeerConnectionID", "-1"},
+						{"Direction", "Output"},
+						{"Status", "OK"},
+					}, nil
+				},
+			},
+		},
+		variables: []scpdVariable{
+			{name: "SourceProtocolInfo", dataType: "string", events: true},
+			{name: "SinkProtocolInfo", dataType: "string", events: true},
+			{name: "CurrentConnectionIDs", dataType: "string", events: true},
+			{name: "A_ARG_TYPE_ConnectionStatus", dataType: "string", allowed: []string{
+				"OK", "ContentFormatMismatch", "InsufficientBandwidth", "UnreliableChannel", "Unknown",
+			}},
+			{name: "A_ARG_TYPE_ConnectionManager", dataType: "string"},
+			{name: "A_ARG_TYPE_Direction", dataType: "string", allowed: []string{"Input", "Output"}},
+			{name: "A_ARG_TYPE_ProtocolInfo", dataType: "string"},
+			{name: "A_ARG_TYPE_ConnectionID", dataType: "i4"},
+			{name: "A_ARG_TYPE_AVTransportID", dataType: "i4"},
+			{name: "A_ARG_TYPE_RcsID", dataType: "i4"},
+		},
+	},
+	{
+		name: "X_MS_MediaReceiverRegistrar",
+		typ:  "urn:microsoft.com:service:X_MS_MediaReceiverRegistrar:1",
+		id:   "urn:microsoft.com:serviceId:X_MS_MediaReceiverRegistrar",
+		actions: []scpdAction{
+			{
+				name:    "IsAuthorized",
+				args:    []scpdArg{{"DeviceID", false, "A_ARG_TYPE_DeviceID"}, {"Result", true, "A_ARG_TYPE_Result"}},
+				handler: registrarAllowed,
+			},
+			{
+				name:    "IsValidated",
+				args:    []scpdArg{{"DeviceID", false, "A_ARG_TYPE_DeviceID"}, {"Result", true, "A_ARG_TYPE_Result"}},
+				handler: registrarAllowed,
+			},
+			{
+				name: "RegisterDevice",
+				args: []scpdArg{
+					{"RegistrationReqMsg", false, "A_ARG_TYPE_RegistrationReqMsg"},
+					{"RegistrationRespMsg", true, "A_ARG_TYPE_RegistrationRespMsg"},
+				},
+				handler: func(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+					return []soapArg{{"RegistrationRespMsg", ""}}, nil
+				},
+			},
+		},
+		variables: []scpdVariable{
+			{name: "A_ARG_TYPE_DeviceID", dataType: "string"},
+			{name: "A_ARG_TYPE_Result", dataType: "int"},
+			{name: "A_ARG_TYPE_RegistrationReqMsg", dataType: "bin.base64"},
+			{name: "A_ARG_TYPE_RegistrationRespMsg", dataType: "bin.base64"},
+			{name: "AuthorizationGrantedUpdateID", dataType: "ui4", events: true},
+			{name: "AuthorizationDeniedUpdateID", dataType: "ui4", events: true},
+			{name: "ValidationSucceededUpdateID", dataType: "ui4", events: true},
+			{name: "ValidationRevokedUpdateID", dataType: "ui4", events: true},
+		},
+	},
+}
+
+// registrarAllowed 局域网内的所有客户端均视为已授权
+func registrarAllowed(s *Server, r *http.Request, args map[string]string) ([]soapArg, error) {
+	return []soapArg{{"Result", "1"}}, nil
+}
+
+// findService 按名称查找服务
+func findService(name string) *service {
+	for _, svc := range services {
+		if svc.name == name {
+			return svc
+		}
+	}
+	return nil
+}
+
+// serverHeader SSDP 及 HTTP 响应中的 Server 头
+func serverHeader(settings *Settings) string {
+	return fmt.Sprintf("%s/1.0 UPnP/1.0 Cloudreve/%s", runtime.GOOS, settings.ModelNumber)
+}
+
+// escape 转义 XML 文本
+func escape(s string) string {
+	var buf bytes.Buffer
+	xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// writeXML 以 XML 格式响应
+func writeXML(w http.ResponseWriter, status int, content string) {
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+	w.WriteHeader(status)
+	w.Write([]byte(content))
+}
+
+// serveDescription 响应设备描述
+func (s *Server) serveDescription(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<root xmlns="urn:schemas-upnp-org:device-1-0" xmlns:dlna="urn:schemas-dlna-org:device-1-0">`)
+	buf.WriteString(`<specVersion><major>1</major><minor>0</minor></specVersion><device>`)
+	fmt.Fprintf(&buf, "<deviceType>%s</deviceType>", deviceType)
+	fmt.Fprintf(&buf, "<friendlyName>%s</friendlyName>", escape(s.settings.FriendlyName))
+	buf.WriteString("<manufacturer>Cloudreve</manufacturer><manufacturerURL>https://cloudreve.org</manufacturerURL>")
+	buf.WriteString("<modelName>Cloudreve</modelName>")
+	fmt.Fprintf(&buf, "<modelNumber>%s</modelNumber>", escape(s.settings.ModelNumber))
+	fmt.Fprintf(&buf, "<UDN>uuid:%s</UDN>", s.settings.UUID)
+	buf.WriteString("<dlna:X_DLNADOC>DMS-1.50</dlna:X_DLNADOC><serviceList>")
+	for _, svc := range services {
+		fmt.Fprintf(&buf,
+			"<service><serviceType>%s</serviceType><serviceId>%s</serviceId><SCPDURL>/dlna/%s.xml</SCPDURL>"+
+				"<controlURL>/dlna/ctl/%s</controlURL><eventSubURL>/dlna/evt/%s</eventSubURL></service>",
+			svc.typ, svc.id, svc.name, svc.name, svc.name,
+		)
+	}
+	buf.WriteString("</serviceList>")
+	if s.settings.PresentationURL != "" {
+		fmt.Fprintf(&buf, "<presentationURL>%s</presentationURL>", escape(s.settings.PresentationURL))
+	}
+	buf.WriteString("</device></root>")
+
+	writeXML(w, http.StatusOK, buf.String())
+}
+
+// serveSCPD 响应服务描述
+func (s *Server) serveSCPD(w http.ResponseWriter, r *http.Request, name string) {
+	svc := findService(name)
+	if svc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<scpd xmlns="urn:schemas-upnp-org:service-1-0">`)
+	buf.WriteString(`<specVersion><major>1</major><minor>0</minor></specVersion><actionList>`)
+	for _, action := range svc.actions {
+		fmt.Fprintf(&buf, "<action><name>%s</name><argumentList>", action.name)
+		for _, arg := range action.args {
+			direction := "in"
+			if arg.out {
+				direction = "out"
+			}
+			fmt.Fprintf(&buf,
+				"<argument><name>%s</name><direction>%s</direction><relatedStateVariable>%s</relatedStateVariable></argument>",
+				arg.name, direction, arg.variable,
+			)
+		}
+		buf.WriteString("</argumentList></action>")
+	}
+	buf.WriteString("</actionList><serviceStateTable>")
+	for _, variable := range svc.variables {
+		events := "no"
+		if variable.events {
+			events = "yes"
+		}
+		fmt.Fprintf(&buf, `<stateVariable sendEvents="%s"><name>%s</name><dataType>%s</dataType>`,
+			events, variable.name, variable.dataType)
+		if len(variable.allowed) > 0 {
+			buf.WriteString("<allowedValueList>")
+			for _, value := range variable.allowed {
+				fmt.Fprintf(&buf, "<allowedValue>%s</allowedValue>", value)
+			}
+			buf.WriteString("</allowedValueList>")
+		}
+		buf.WriteString("</stateVariable>")
+	}
+	buf.WriteString("</serviceStateTable></scpd>")
+
+	writeXML(w, http.StatusOK, buf.String())
+}
+
+// soapArg 控制响应中的参数
+type soapArg struct {
+	name  string
+	value string
+}
+
+// soapEnvelope 控制请求
+type soapEnvelope struct {
+	Body struct {
+		Action struct {
+			XMLName xml.Name
+			Args    []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:",any"`
+	} `xml:"Body"`
+}
+
+// serveControl 处理 SOAP 控制请求
+func (s *Server) serveControl(w http.ResponseWriter, r *http.Request, name string) {
+	svc := findService(name)
+	if svc == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	var envelope soapEnvelope
+	if err := xml.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&envelope); err != nil {
+		writeSOAPFault(w, errInvalidAction)
+		return
+	}
+
+	args := make(map[string]string, len(envelope.Body.Action.Args))
+	for _, arg := range envelope.Body.Action.Args {
+		args[arg.XMLName.Local] = arg.Value
+	}
+
+	name = envelope.Body.Action.XMLName.Local
+	for _, action := range svc.actions {
+		if action.name != name {
+			continue
+		}
+
+		res, err := action.handler(s, r, args)
+		if err != nil {
+			upnpErr, ok := err.(*upnpError)
+			if !ok {
+				upnpErr = errActionFailed
+			}
+			writeSOAPFault(w, upnpErr)
+			return
+		}
+
+		writeSOAPResponse(w, svc.typ, name, res)
+		return
+	}
+
+	writeSOAPFault(w, errInvalidAction)
+}
+
+// writeSOAPResponse 响应控制请求的结果
+func writeSOAPResponse(w http.ResponseWriter, serviceType, action string, args []soapArg) {
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	buf.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&buf, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "<%s>%s</%s>", arg.name, escape(arg.value), arg.name)
+	}
+	fmt.Fprintf(&buf, "</u:%sResponse></s:Body></s:Envelope>", action)
+
+	w.Header().Set("EXT", "")
+	writeXML(w, http.StatusOK, buf.String())
+}
+
+// writeSOAPFault 响应控制请求的错误
+func writeSOAPFault(w http.ResponseWriter, err *upnpError) {
+	content := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>`+
+		`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`+
+		`<s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail>`+
+		`<UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError>`+
+		`</detail></s:Fault></s:Body></s:Envelope>`, err.code, escape(err.desc))
+	writeXML(w, http.StatusInternalServerError, content)
+}
+
+// serveEvent 处理事件订阅。媒体库不跟踪变更，接受订阅但不发送事件
+func (s *Server) serveEvent(w http.ResponseWriter, r *http.Request, name string) {
+	if findService(name) == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "SUBSCRIBE":
+		sid := r.Header.Get("SID")
+		if sid == "" {
+			sid = "uuid:" + uuid.Must(uuid.NewV4()).String()
+		}
+		w.Header().Set("SID", sid)
+		w.Header().Set("TIMEOUT", subscriptionTimeout)
+		w.Header().Set("Content-Length", "0")
+		w.WriteHeader(http.StatusOK)
+	case "UNSUBSCRIBE":
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
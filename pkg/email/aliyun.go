@@ -0,0 +1,138 @@
+package email
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Aliyun 通过阿里云邮件推送（DirectMail）API 发送邮件
+type Aliyun struct {
+	Config   APIConfig
+	endpoint string
+	client   request.Client
+}
+
+type aliyunError struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+func newAliyun(config APIConfig) (Sender, error) {
+	if config.Key == "" || config.Secret == "" {
+		return nil, errors.New("未设置阿里云 AccessKey")
+	}
+
+	// 杭州区域使用默认地址，其他区域使用带区域的地址
+	endpoint := "https://dm.aliyuncs.com/"
+	if config.Region != "" && config.Region != "cn-hangzhou" {
+		endpoint = fmt.Sprintf("https://dm.%s.aliyuncs.com/", config.Region)
+	}
+
+	return &Aliyun{Config: config, endpoint: endpoint, client: request.NewClient()}, nil
+}
+
+// Deliver 投递邮件
+func (a *Aliyun) Deliver(ctx context.Context, msg *Message) error {
+	return a.call(ctx, "SingleSendMail", map[string]string{
+		"AccountName":    a.Config.Address,
+		"AddressType":    "1",
+		"ReplyToAddress": "false",
+		"FromAlias":      a.Config.Name,
+		"ToAddress":      msg.To,
+		"Subject":        msg.Subject,
+		"HtmlBody":       msg.Body,
+	})
+}
+
+// Ping 检查 AccessKey 是否有效
+func (a *Aliyun) Ping(ctx context.Context) error {
+	return a.call(ctx, "DescAccountSummary", nil)
+}
+
+// call 调用 DirectMail RPC 接口
+func (a *Aliyun) call(ctx context.Context, action string, params map[string]string) error {
+	values := url.Values{
+		"Action":           {action},
+		"Format":           {"JSON"},
+		"Version":          {"2015-11-23"},
+		"AccessKeyId":      {a.Config.Key},
+		"SignatureMethod":  {"HMAC-SHA1"},
+		"SignatureVersion": {"1.0"},
+		"SignatureNonce":   {util.RandStringRunes(32)},
+		"Timestamp":        {time.Now().UTC().Format("2006-01-02T15:04:05Z")},
+	}
+	if a.Config.Region != "" {
+		values.Set("RegionId", a.Config.Region)
+	}
+	for k, v := range params {
+		values.Set(k, v)
+	}
+	values.Set("Signature", aliyunSign("POST", values, a.Config.Secret))
+
+	resp := a.client.Request(
+		"POST",
+		a.endpoint,
+		strings.NewReader(values.Encode()),
+		request.WithContext(ctx),
+		request.WithHeader(http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}),
+	)
+	if resp.Err != nil {
+		return temporary(resp.Err)
+	}
+	defer resp.Response.Body.Close()
+
+	if resp.Response.StatusCode >= 200 && resp.Response.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Response.Body, 1024))
+	var apiErr aliyunError
+	json.Unmarshal(body, &apiErr)
+	err := fmt.Errorf("服务商返回错误 %d: %s", resp.Response.StatusCode, body)
+	if resp.Response.StatusCode >= 500 || strings.HasPrefix(apiErr.Code, "Throttling") {
+		return temporary(err)
+	}
+	return permanent(err)
+}
+
+// aliyunSign 计算 RPC 接口的签名
+func aliyunSign(method string, values url.Values, secret string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, aliyunEncode(k)+"="+aliyunEncode(values.Get(k)))
+	}
+
+	stringToSign := method + "&" + aliyunEncode("/") + "&" + aliyunEncode(strings.Join(pairs, "&"))
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunEncode 按阿里云要求进行 URL 编码
+func aliyunEncode(s string) string {
+	s = url.QueryEscape(s)
+	s = strings.ReplaceAll(s, "+", "%20")
+	s = strings.ReplaceAll(s, "*", "%2A")
+	return strings.ReplaceAll(s, "%7E", "~")
+}
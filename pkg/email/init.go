@@ -32,7 +32,34 @@ func Init() {
 		"smtpUser",
 		"smtpPass",
 		"smtpEncryption",
+		"mail_driver",
+		"mail_api_key",
+		"mail_api_secret",
+		"mail_api_domain",
+		"mail_api_region",
 	)
+
+	// 使用邮件服务商 API 发送
+	if driver := options["mail_driver"]; driver != "" && driver != "smtp" {
+		client, err := NewAPIClient(driver, APIConfig{
+			Name:    options["fromName"],
+			Address: options["fromAdress"],
+			ReplyTo: options["replyTo"],
+			Key:     options["mail_api_key"],
+			Secret:  options["mail_api_secret"],
+			Domain:  options["mail_api_domain"],
+			Region:  options["mail_api_region"],
+		}, float64(model.GetIntSetting("mail_rate_limit", 0)), model.GetIntSetting("mail_max_retries", 5))
+		if err != nil {
+			util.Log().Warning("无法初始化邮件发送服务 %q, %s", driver, err)
+			Client = nil
+			return
+		}
+
+		Client = client
+		return
+	}
+
 	port := model.GetIntSetting("smtpPort", 25)
 	keepAlive := model.GetIntSetting("mail_keepalive", 30)
 
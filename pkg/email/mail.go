@@ -3,6 +3,8 @@ package email
 import (
 	"errors"
 	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
 )
 
 // Driver 邮件发送驱动
@@ -11,6 +13,8 @@ type Driver interface {
 	Close()
 	// Send 发送邮件
 	Send(to, title, body string) error
+	// Ping 测试与发信服务器的连接
+	Ping() error
 }
 
 var (
@@ -18,6 +22,8 @@ var (
 	ErrChanNotOpen = errors.New("邮件队列未开启")
 	// ErrNoActiveDriver 无可用邮件发送服务
 	ErrNoActiveDriver = errors.New("无可用邮件发送服务")
+	// ErrRecipientSuppressed 收件人曾永久退信或投诉
+	ErrRecipientSuppressed = errors.New("收件人邮箱已被停止投递")
 )
 
 // Send 发送邮件
@@ -27,6 +33,11 @@ func Send(to, title, body string) error {
 		return nil
 	}
 
+	// 不再向退信或投诉的邮箱发送，以免影响发信信誉
+	if model.IsEmailSuppressed(to) {
+		return ErrRecipientSuppressed
+	}
+
 	Lock.RLock()
 	defer Lock.RUnlock()
 
@@ -36,3 +47,15 @@ func Send(to, title, body string) error {
 
 	return Client.Send(to, title, body)
 }
+
+// Ping 测试当前邮件发送服务是否可用
+func Ping() error {
+	Lock.RLock()
+	defer Lock.RUnlock()
+
+	if Client == nil {
+		return ErrNoActiveDriver
+	}
+
+	return Client.Ping()
+}
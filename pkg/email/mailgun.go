@@ -0,0 +1,74 @@
+package email
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+// Mailgun 通过 Mailgun Messages API 发送邮件
+type Mailgun struct {
+	Config   APIConfig
+	endpoint string
+	client   request.Client
+}
+
+func newMailgun(config APIConfig) (Sender, error) {
+	if config.Key == "" || config.Domain == "" {
+		return nil, errors.New("未设置 Mailgun API 密钥或发信域名")
+	}
+
+	// 欧洲区域的域名使用独立的 API 地址
+	endpoint := "https://api.mailgun.net"
+	if strings.EqualFold(config.Region, "eu") {
+		endpoint = "https://api.eu.mailgun.net"
+	}
+
+	return &Mailgun{Config: config, endpoint: endpoint, client: request.NewClient()}, nil
+}
+
+func (m *Mailgun) header() http.Header {
+	credential := base64.StdEncoding.EncodeToString([]byte("api:" + m.Config.Key))
+	return http.Header{
+		"Authorization": {"Basic " + credential},
+	}
+}
+
+// Deliver 投递邮件
+func (m *Mailgun) Deliver(ctx context.Context, msg *Message) error {
+	form := url.Values{
+		"from":    {m.Config.from()},
+		"to":      {msg.To},
+		"subject": {msg.Subject},
+		"html":    {msg.Body},
+	}
+	if m.Config.ReplyTo != "" {
+		form.Set("h:Reply-To", m.Config.ReplyTo)
+	}
+
+	header := m.header()
+	header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return checkResponse(m.client.Request(
+		"POST",
+		m.endpoint+"/v3/"+url.PathEscape(m.Config.Domain)+"/messages",
+		strings.NewReader(form.Encode()),
+		request.WithContext(ctx),
+		request.WithHeader(header),
+	))
+}
+
+// Ping 检查 API 密钥及发信域名是否有效
+func (m *Mailgun) Ping(ctx context.Context) error {
+	return checkResponse(m.client.Request(
+		"GET",
+		m.endpoint+"/v3/domains/"+url.PathEscape(m.Config.Domain),
+		nil,
+		request.WithContext(ctx),
+		request.WithHeader(m.header()),
+	))
+}
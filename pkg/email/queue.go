@@ -0,0 +1,259 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/mail"
+	"sort"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// 首次重试的等待时间，之后每次翻倍
+	retryBaseDelay = 10 * time.Second
+	// 重试等待时间的上限
+	retryMaxDelay = 10 * time.Minute
+	// 单次投递请求的超时时间
+	deliverTimeout = 30 * time.Second
+)
+
+// Message 待发送的邮件
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+
+	attempts int
+}
+
+// Sender 通过邮件服务商的 API 投递邮件
+type Sender interface {
+	// Deliver 投递一封邮件
+	Deliver(ctx context.Context, msg *Message) error
+	// Ping 检查 API 凭证是否有效
+	Ping(ctx context.Context) error
+}
+
+// DeliveryError 投递失败的原因，Temporary 为真时稍后重试
+type DeliveryError struct {
+	Temporary bool
+	Err       error
+}
+
+func (e *DeliveryError) Error() string {
+	return e.Err.Error()
+}
+
+func temporary(err error) error {
+	return &DeliveryError{Temporary: true, Err: err}
+}
+
+func permanent(err error) error {
+	return &DeliveryError{Temporary: false, Err: err}
+}
+
+// isTemporary 投递错误是否可以重试，网络错误等未分类的错误视为可重试
+func isTemporary(err error) bool {
+	var deliveryErr *DeliveryError
+	if errors.As(err, &deliveryErr) {
+		return deliveryErr.Temporary
+	}
+	return true
+}
+
+// APIConfig 邮件服务商 API 的发送配置
+type APIConfig struct {
+	Name    string // 发送者名
+	Address string // 发送者地址
+	ReplyTo string // 回复地址
+	Key     string // API 密钥或 AccessKey ID
+	Secret  string // AccessKey Secret，仅 SES 和阿里云使用
+	Domain  string // 发信域名，仅 Mailgun 使用
+	Region  string // 服务所在区域
+}
+
+// from 格式化发送者
+func (config APIConfig) from() string {
+	return (&mail.Address{Name: config.Name, Address: config.Address}).String()
+}
+
+type provider struct {
+	// 未设置发送速率时的默认值，单位为封每秒
+	tps float64
+	new func(config APIConfig) (Sender, error)
+}
+
+// providers 支持的邮件服务商
+var providers = map[string]provider{
+	"sendgrid": {tps: 10, new: newSendGrid},
+	"mailgun":  {tps: 10, new: newMailgun},
+	"ses":      {tps: 1, new: newSES},
+	"aliyun":   {tps: 5, new: newAliyun},
+}
+
+// IsSupportedDriver 是否为支持的邮件服务商
+func IsSupportedDriver(name string) bool {
+	_, ok := providers[name]
+	return ok
+}
+
+// SupportedDrivers 返回支持的邮件服务商名称
+func SupportedDrivers() []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// APIClient 通过邮件服务商 API 发送邮件的队列，按服务商限制发送速率，
+// 暂时性失败的邮件按指数退避重新加入队列
+type APIClient struct {
+	Provider string
+
+	sender     Sender
+	tps        float64
+	maxRetries int
+	limiter    request.TPSLimiter
+
+	ch     chan *Message
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewAPIClient 新建邮件服务商 API 发送队列，tps 为 0 时使用服务商的默认速率
+func NewAPIClient(name string, config APIConfig, tps float64, maxRetries int) (*APIClient, error) {
+	p, ok := providers[name]
+	if !ok {
+		return nil, fmt.Errorf("不支持的邮件发送服务 %q", name)
+	}
+
+	sender, err := p.new(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if tps <= 0 {
+		tps = p.tps
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	client := &APIClient{
+		Provider:   name,
+		sender:     sender,
+		tps:        tps,
+		maxRetries: maxRetries,
+		limiter:    request.NewTPSLimiter(),
+		ch:         make(chan *Message, 30),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+
+	go client.run()
+	return client, nil
+}
+
+// Send 将邮件加入发送队列
+func (client *APIClient) Send(to, title, body string) error {
+	return client.enqueue(&Message{To: to, Subject: title, Body: body})
+}
+
+func (client *APIClient) enqueue(msg *Message) error {
+	select {
+	case <-client.ctx.Done():
+		return ErrChanNotOpen
+	case client.ch <- msg:
+		return nil
+	}
+}
+
+// Close 关闭发送队列，尚在等待重试的邮件将被丢弃
+func (client *APIClient) Close() {
+	client.cancel()
+}
+
+// Ping 检查服务商 API 是否可用
+func (client *APIClient) Ping() error {
+	ctx, cancel := context.WithTimeout(client.ctx, deliverTimeout)
+	defer cancel()
+	return client.sender.Ping(ctx)
+}
+
+func (client *APIClient) run() {
+	for {
+		select {
+		case <-client.ctx.Done():
+			util.Log().Debug("邮件队列关闭")
+			return
+		case msg := <-client.ch:
+			client.deliver(msg)
+		}
+	}
+}
+
+func (client *APIClient) deliver(msg *Message) {
+	client.limiter.Limit(client.ctx, client.Provider, client.tps, 1)
+	if client.ctx.Err() != nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(client.ctx, deliverTimeout)
+	err := client.sender.Deliver(ctx, msg)
+	cancel()
+	if err == nil {
+		util.Log().Debug("邮件已发送")
+		return
+	}
+
+	msg.attempts++
+	if !isTemporary(err) || msg.attempts > client.maxRetries {
+		util.Log().Warning("邮件发送失败, %s", err)
+		return
+	}
+
+	delay := retryDelay(msg.attempts)
+	util.Log().Warning("邮件发送失败, %s，将在 %s 后第 %d 次重试", err, delay, msg.attempts)
+	time.AfterFunc(delay, func() {
+		client.enqueue(msg)
+	})
+}
+
+// retryDelay 第 n 次重试前的等待时间
+func retryDelay(n int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < n && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// checkResponse 检查服务商 API 的响应，限流及服务端错误可重试
+func checkResponse(resp *request.Response) error {
+	if resp.Err != nil {
+		return temporary(resp.Err)
+	}
+	defer resp.Response.Body.Close()
+
+	status := resp.Response.StatusCode
+	if status >= 200 && status < 300 {
+		return nil
+	}
+
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Response.Body, 1024))
+	err := fmt.Errorf("服务商返回错误 %d: %s", status, body)
+	if status == http.StatusTooManyRequests || status >= 500 {
+		return temporary(err)
+	}
+	return permanent(err)
+}
@@ -0,0 +1,93 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+)
+
+const sendGridEndpoint = "https://api.sendgrid.com"
+
+// SendGrid 通过 SendGrid Web API v3 发送邮件
+type SendGrid struct {
+	Config APIConfig
+	client request.Client
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridMail struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newSendGrid(config APIConfig) (Sender, error) {
+	if config.Key == "" {
+		return nil, errors.New("未设置 SendGrid API 密钥")
+	}
+
+	return &SendGrid{Config: config, client: request.NewClient()}, nil
+}
+
+func (s *SendGrid) header() http.Header {
+	return http.Header{
+		"Authorization": {"Bearer " + s.Config.Key},
+		"Content-Type":  {"application/json"},
+	}
+}
+
+// Deliver 投递邮件
+func (s *SendGrid) Deliver(ctx context.Context, msg *Message) error {
+	payload := sendGridMail{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: s.Config.Address, Name: s.Config.Name},
+		Subject:          msg.Subject,
+		Content:          []sendGridContent{{Type: "text/html", Value: msg.Body}},
+	}
+	if s.Config.ReplyTo != "" {
+		payload.ReplyTo = &sendGridAddress{Email: s.Config.ReplyTo, Name: s.Config.Name}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return permanent(err)
+	}
+
+	return checkResponse(s.client.Request(
+		"POST",
+		sendGridEndpoint+"/v3/mail/send",
+		bytes.NewReader(body),
+		request.WithContext(ctx),
+		request.WithHeader(s.header()),
+	))
+}
+
+// Ping 检查 API 密钥是否有效
+func (s *SendGrid) Ping(ctx context.Context) error {
+	return checkResponse(s.client.Request(
+		"GET",
+		sendGridEndpoint+"/v3/scopes",
+		nil,
+		request.WithContext(ctx),
+		request.WithHeader(s.header()),
+	))
+}
@@ -0,0 +1,78 @@
+package email
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ses"
+)
+
+// SES 通过 Amazon SES API 发送邮件
+type SES struct {
+	Config APIConfig
+	svc    *ses.SES
+}
+
+func newSES(config APIConfig) (Sender, error) {
+	if config.Key == "" || config.Secret == "" || config.Region == "" {
+		return nil, errors.New("未设置 Amazon SES 的 AccessKey 或区域")
+	}
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: credentials.NewStaticCredentials(config.Key, config.Secret, ""),
+		Region:      &config.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SES{Config: config, svc: ses.New(sess)}, nil
+}
+
+// Deliver 投递邮件
+func (s *SES) Deliver(ctx context.Context, msg *Message) error {
+	input := &ses.SendEmailInput{
+		Source: aws.String(s.Config.from()),
+		Destination: &ses.Destination{
+			ToAddresses: []*string{aws.String(msg.To)},
+		},
+		Message: &ses.Message{
+			Subject: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.Subject)},
+			Body: &ses.Body{
+				Html: &ses.Content{Charset: aws.String("UTF-8"), Data: aws.String(msg.Body)},
+			},
+		},
+	}
+	if s.Config.ReplyTo != "" {
+		input.ReplyToAddresses = []*string{aws.String(s.Config.ReplyTo)}
+	}
+
+	_, err := s.svc.SendEmailWithContext(ctx, input)
+	return sesError(err)
+}
+
+// Ping 检查凭证是否有效
+func (s *SES) Ping(ctx context.Context) error {
+	_, err := s.svc.GetSendQuotaWithContext(ctx, &ses.GetSendQuotaInput{})
+	return sesError(err)
+}
+
+// sesError 区分可重试的错误，限流及服务端错误可重试
+func sesError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	if reqErr, ok := err.(awserr.RequestFailure); ok {
+		if reqErr.StatusCode() == 429 || reqErr.StatusCode() >= 500 || reqErr.Code() == "Throttling" {
+			return temporary(err)
+		}
+		return permanent(err)
+	}
+
+	return temporary(err)
+}
@@ -62,6 +62,29 @@ func (client *SMTP) Close() {
 	}
 }
 
+// Ping 连接并登录 SMTP 服务器后断开，用于检查发信服务是否可用
+func (client *SMTP) Ping() error {
+	s, err := client.dialer().Dial()
+	if err != nil {
+		return err
+	}
+
+	return s.Close()
+}
+
+// dialer 根据配置创建 SMTP 连接器
+func (client *SMTP) dialer() *mail.Dialer {
+	d := mail.NewDialer(client.Config.Host, client.Config.Port, client.Config.User, client.Config.Password)
+	d.Timeout = time.Duration(client.Config.Keepalive+5) * time.Second
+	// 是否启用 SSL
+	d.SSL = false
+	if client.Config.Encryption {
+		d.SSL = true
+	}
+	d.StartTLSPolicy = mail.OpportunisticStartTLS
+	return d
+}
+
 // Init 初始化发送队列
 func (client *SMTP) Init() {
 	go func() {
@@ -74,15 +97,8 @@ func (client *SMTP) Init() {
 			}
 		}()
 
-		d := mail.NewDialer(client.Config.Host, client.Config.Port, client.Config.User, client.Config.Password)
-		d.Timeout = time.Duration(client.Config.Keepalive+5) * time.Second
+		d := client.dialer()
 		client.chOpen = true
-		// 是否启用 SSL
-		d.SSL = false
-		if client.Config.Encryption {
-			d.SSL = true
-		}
-		d.StartTLSPolicy = mail.OpportunisticStartTLS
 
 		var s mail.SendCloser
 		var err error
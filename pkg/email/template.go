@@ -2,8 +2,12 @@ package email
 
 import (
 	"fmt"
+	"html"
+	"strconv"
+	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -34,3 +38,213 @@ func NewResetEmail(userName, resetURL string) (string, string) {
 	return fmt.Sprintf("【%s】密码重置", options["siteName"]),
 		util.Replace(replace, options["mail_reset_pwd_template"])
 }
+
+// NewShareEmail 新建分享链接邮件，附言会被转义
+func NewShareEmail(userName, fileName, shareURL, message string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_share_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{fileName}":     html.EscapeString(fileName),
+		"{shareUrl}":     shareURL,
+		"{message}":      html.EscapeString(message),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】%s 向您分享了 %s", options["siteName"], userName, fileName),
+		util.Replace(replace, options["mail_share_template"])
+}
+
+// NewSharePasswordEmail 新建分享密码邮件，与分享链接分开发送
+func NewSharePasswordEmail(userName, fileName, password string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_share_password_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{fileName}":     html.EscapeString(fileName),
+		"{password}":     html.EscapeString(password),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】分享密码", options["siteName"]),
+		util.Replace(replace, options["mail_share_password_template"])
+}
+
+// LoginInfo 登录提醒邮件中展示的登录信息
+type LoginInfo struct {
+	Reason  string // 触发提醒的原因，如“新设备上”
+	IP      string
+	Country string
+	Device  string
+	Time    string
+}
+
+func (info *LoginInfo) replacements(options map[string]string, userName string) map[string]string {
+	country := info.Country
+	if country == "" {
+		country = "未知"
+	}
+	return map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{reason}":       html.EscapeString(info.Reason),
+		"{ip}":           html.EscapeString(info.IP),
+		"{country}":      html.EscapeString(country),
+		"{device}":       html.EscapeString(info.Device),
+		"{time}":         info.Time,
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+}
+
+// NewLoginAlertEmail 新建异常登录提醒邮件
+func NewLoginAlertEmail(userName string, info *LoginInfo) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_login_alert_template")
+	return fmt.Sprintf("【%s】新设备登录提醒", options["siteName"]),
+		util.Replace(info.replacements(options, userName), options["mail_login_alert_template"])
+}
+
+// NewLoginConfirmEmail 新建异常登录确认邮件，包含完成登录所需的验证码
+func NewLoginConfirmEmail(userName, code string, info *LoginInfo) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_login_confirm_template")
+	replace := info.replacements(options, userName)
+	replace["{code}"] = code
+	return fmt.Sprintf("【%s】确认登录", options["siteName"]),
+		util.Replace(replace, options["mail_login_confirm_template"])
+}
+
+// NewGroupOveruseEmail 新建临时用户组到期后容量超额的提醒邮件
+func NewGroupOveruseEmail(userName, expiredGroup, currentGroup string, used, capacity uint64) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_group_overuse_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{expiredGroup}": html.EscapeString(expiredGroup),
+		"{currentGroup}": html.EscapeString(currentGroup),
+		"{used}":         util.FormatSize(used),
+		"{capacity}":     util.FormatSize(capacity),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】用户组到期提醒", options["siteName"]),
+		util.Replace(replace, options["mail_group_overuse_template"])
+}
+
+// NewEmailChangeEmail 新建更改邮箱时发往新邮箱的验证邮件
+func NewEmailChangeEmail(userName, newEmail, code string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_email_change_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{email}":        html.EscapeString(newEmail),
+		"{code}":         code,
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】验证新邮箱", options["siteName"]),
+		util.Replace(replace, options["mail_email_change_template"])
+}
+
+// NewAccountInviteEmail 新建管理员开通账户后邀请用户设置密码的邮件
+func NewAccountInviteEmail(userName, address, inviteURL string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_account_invite_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{email}":        html.EscapeString(address),
+		"{inviteUrl}":    inviteURL,
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】账户已开通", options["siteName"]),
+		util.Replace(replace, options["mail_account_invite_template"])
+}
+
+// NewNotificationEmail 新建单条通知邮件，通知内容为纯文本
+func NewNotificationEmail(userName, title, content string) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_notification_template")
+	replace := map[string]string{
+		"{siteTitle}":    options["siteName"],
+		"{userName}":     html.EscapeString(userName),
+		"{title}":        html.EscapeString(title),
+		"{content}":      html.EscapeString(content),
+		"{siteUrl}":      options["siteURL"],
+		"{siteSecTitle}": options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】%s", options["siteName"], title),
+		util.Replace(replace, options["mail_notification_template"])
+}
+
+// NewNotificationDigestEmail 新建汇总多条通知的邮件
+func NewNotificationDigestEmail(userName string, notifications []model.Notification) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_notification_digest_template")
+	var items strings.Builder
+	for _, n := range notifications {
+		fmt.Fprintf(&items, `<li style="margin-bottom: 10px;"><strong>%s</strong> <span style="color: #999;">%s</span><br/><span style="white-space: pre-wrap; color: #666;">%s</span></li>`,
+			html.EscapeString(n.Title), n.CreatedAt.Format("2006-01-02 15:04"), html.EscapeString(n.Content))
+	}
+
+	replace := map[string]string{
+		"{siteTitle}":     options["siteName"],
+		"{userName}":      html.EscapeString(userName),
+		"{count}":         strconv.Itoa(len(notifications)),
+		"{notifications}": items.String(),
+		"{siteUrl}":       options["siteURL"],
+		"{siteSecTitle}":  options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】您有 %d 条新通知", options["siteName"], len(notifications)),
+		util.Replace(replace, options["mail_notification_digest_template"])
+}
+
+// NewUsageReportEmail 新建发送给管理员的站点用量报告邮件
+func NewUsageReportEmail(userName string, report *model.UsageReport) (string, string) {
+	options := model.GetSettingByNames("siteName", "siteURL", "siteTitle", "mail_usage_report_template")
+	period := "周报"
+	if report.Period == stats.ReportMonthly {
+		period = "月报"
+	}
+
+	growth := "+" + util.FormatSize(report.StorageAfter-report.StorageBefore)
+	if report.StorageAfter < report.StorageBefore {
+		growth = "-" + util.FormatSize(report.StorageBefore-report.StorageAfter)
+	}
+
+	var downloads strings.Builder
+	for _, share := range report.TopDownloads {
+		fmt.Fprintf(&downloads, `<li>%s <span style="color: #999;">%d 次</span></li>`, html.EscapeString(share.Name), share.Downloads)
+	}
+	if len(report.TopDownloads) == 0 {
+		downloads.WriteString("<li>无</li>")
+	}
+
+	var tasks strings.Builder
+	for _, task := range report.FailedTasks {
+		fmt.Fprintf(&tasks, `<li>%s <span style="color: #999;">%d 个</span></li>`, html.EscapeString(task.Name), task.Count)
+	}
+	if len(report.FailedTasks) == 0 {
+		tasks.WriteString("<li>无</li>")
+	}
+
+	from := report.From.Format("2006-01-02")
+	to := report.To.AddDate(0, 0, -1).Format("2006-01-02")
+	replace := map[string]string{
+		"{siteTitle}":       options["siteName"],
+		"{userName}":        html.EscapeString(userName),
+		"{period}":          period,
+		"{from}":            from,
+		"{to}":              to,
+		"{newUsers}":        strconv.Itoa(report.NewUsers),
+		"{totalUsers}":      strconv.Itoa(report.TotalUsers),
+		"{activeUsers}":     strconv.Itoa(report.PeakActiveUsers),
+		"{uploadTraffic}":   util.FormatSize(report.UploadTraffic),
+		"{downloadTraffic}": util.FormatSize(report.DownloadTraffic),
+		"{storage}":         util.FormatSize(report.StorageAfter),
+		"{storageGrowth}":   growth,
+		"{topDownloads}":    downloads.String(),
+		"{failedTasks}":     tasks.String(),
+		"{siteUrl}":         options["siteURL"],
+		"{siteSecTitle}":    options["siteTitle"],
+	}
+	return fmt.Sprintf("【%s】站点%s %s ~ %s", options["siteName"], period, from, to),
+		util.Replace(replace, options["mail_usage_report_template"])
+}
@@ -0,0 +1,220 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+var (
+	// ErrUnsupportedWebhook 服务商不支持推送投递通知
+	ErrUnsupportedWebhook = errors.New("此邮件服务商不支持投递通知")
+	// ErrInvalidWebhook 无法解析的投递通知
+	ErrInvalidWebhook = errors.New("无法解析的投递通知")
+)
+
+// emailEvent 投递通知中与邮箱状态相关的事件
+type emailEvent struct {
+	Address string
+	Status  int
+}
+
+// HandleWebhook 处理邮件服务商推送的投递通知，根据退信、投诉及投递成功事件更新用户的邮箱状态，
+// 返回处理的事件数
+func HandleWebhook(provider string, body []byte) (int, error) {
+	var (
+		events []emailEvent
+		err    error
+	)
+
+	switch provider {
+	case "sendgrid":
+		events, err = parseSendGridEvents(body)
+	case "mailgun":
+		events, err = parseMailgunEvents(body)
+	case "ses":
+		events, err = parseSESEvents(body)
+	default:
+		return 0, ErrUnsupportedWebhook
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	for _, event := range events {
+		if _, err := model.SetEmailStatus(event.Address, event.Status); err != nil {
+			util.Log().Warning("无法更新邮箱 %s 的投递状态, %s", event.Address, err)
+			return 0, err
+		}
+	}
+
+	return len(events), nil
+}
+
+// parseSendGridEvents 解析 SendGrid Event Webhook 推送的事件
+func parseSendGridEvents(body []byte) ([]emailEvent, error) {
+	var payload []struct {
+		Email  string `json:"email"`
+		Event  string `json:"event"`
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, ErrInvalidWebhook
+	}
+
+	events := make([]emailEvent, 0, len(payload))
+	for _, item := range payload {
+		status := -1
+		switch item.Event {
+		case "bounce":
+			status = model.EmailBounced
+			if item.Type == "blocked" {
+				// 被收件服务器暂时拦截
+				status = model.EmailSoftBounced
+			}
+		case "dropped":
+			// 因此前的退信或投诉被 SendGrid 拦截
+			if strings.Contains(item.Reason, "Bounced Address") {
+				status = model.EmailBounced
+			} else if strings.Contains(item.Reason, "Spam Reporting") {
+				status = model.EmailComplained
+			}
+		case "spamreport":
+			status = model.EmailComplained
+		case "delivered":
+			status = model.EmailDeliverable
+		}
+
+		if status >= 0 && item.Email != "" {
+			events = append(events, emailEvent{Address: item.Email, Status: status})
+		}
+	}
+
+	return events, nil
+}
+
+// parseMailgunEvents 解析 Mailgun Webhook 推送的事件
+func parseMailgunEvents(body []byte) ([]emailEvent, error) {
+	var payload struct {
+		EventData *struct {
+			Event     string `json:"event"`
+			Severity  string `json:"severity"`
+			Recipient string `json:"recipient"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.EventData == nil {
+		return nil, ErrInvalidWebhook
+	}
+
+	data := payload.EventData
+	status := -1
+	switch data.Event {
+	case "failed":
+		status = model.EmailSoftBounced
+		if data.Severity == "permanent" {
+			status = model.EmailBounced
+		}
+	case "complained":
+		status = model.EmailComplained
+	case "delivered":
+		status = model.EmailDeliverable
+	}
+
+	if status < 0 || data.Recipient == "" {
+		return nil, nil
+	}
+	return []emailEvent{{Address: data.Recipient, Status: status}}, nil
+}
+
+// parseSESEvents 解析 Amazon SES 经由 SNS 推送的通知，订阅确认请求会被自动确认
+func parseSESEvents(body []byte) ([]emailEvent, error) {
+	var envelope struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, ErrInvalidWebhook
+	}
+
+	message := body
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		return nil, confirmSNSSubscription(envelope.SubscribeURL)
+	case "Notification":
+		message = []byte(envelope.Message)
+	}
+
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Bounce           struct {
+			BounceType        string `json:"bounceType"`
+			BouncedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplainedRecipients []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+		Delivery struct {
+			Recipients []string `json:"recipients"`
+		} `json:"delivery"`
+	}
+	if err := json.Unmarshal(message, &notification); err != nil {
+		return nil, ErrInvalidWebhook
+	}
+
+	kind := notification.NotificationType
+	if kind == "" {
+		kind = notification.EventType
+	}
+
+	events := make([]emailEvent, 0)
+	switch kind {
+	case "Bounce":
+		status := model.EmailSoftBounced
+		if notification.Bounce.BounceType == "Permanent" {
+			status = model.EmailBounced
+		}
+		for _, r := range notification.Bounce.BouncedRecipients {
+			events = append(events, emailEvent{Address: r.EmailAddress, Status: status})
+		}
+	case "Complaint":
+		for _, r := range notification.Complaint.ComplainedRecipients {
+			events = append(events, emailEvent{Address: r.EmailAddress, Status: model.EmailComplained})
+		}
+	case "Delivery":
+		for _, r := range notification.Delivery.Recipients {
+			events = append(events, emailEvent{Address: r, Status: model.EmailDeliverable})
+		}
+	}
+
+	return events, nil
+}
+
+// confirmSNSSubscription 访问确认地址以完成 SNS 订阅，仅允许 AWS 的 SNS 地址
+func confirmSNSSubscription(target string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "https" ||
+		!strings.HasPrefix(u.Hostname(), "sns.") || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+		return ErrInvalidWebhook
+	}
+
+	resp := request.NewClient().Request("GET", u.String(), nil)
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	util.Log().Info("已确认 Amazon SNS 订阅")
+	return nil
+}
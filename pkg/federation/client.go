@@ -0,0 +1,206 @@
+package federation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+const (
+	basePath       = "/api/v3/federation/"
+	requestTimeout = time.Duration(30) * time.Second
+)
+
+var (
+	// ErrInvalidInstance 实例地址无效
+	ErrInvalidInstance = errors.New("invalid instance url")
+)
+
+// Client 与其他 Cloudreve 实例交互联邦分享的客户端
+type Client interface {
+	// Offer 向接收方实例提供联邦分享
+	Offer(ctx context.Context, offer *serializer.FederationOffer) error
+	// Verify 向分享方实例确认联邦分享令牌有效，返回分享方记录的分享信息
+	Verify(ctx context.Context, token string) (*serializer.FederationOffer, error)
+	// List 列出分享方实例上联邦分享目录下的对象
+	List(ctx context.Context, token, path string) (*serializer.ObjectList, error)
+	// Download 获取分享方实例上联邦分享文件的下载地址
+	Download(ctx context.Context, token, path string) (string, error)
+}
+
+// NewClient 创建与给定实例交互的客户端
+func NewClient(instance string) (Client, error) {
+	instanceURL, err := ParseInstance(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	base, _ := url.Parse(basePath)
+	return &client{
+		instance: instanceURL,
+		httpClient: request.NewClient(
+			request.WithEndpoint(instanceURL.ResolveReference(base).String()),
+			request.WithTimeout(requestTimeout),
+		),
+	}, nil
+}
+
+// ParseInstance 解析实例地址，只接受 http(s) 协议的地址
+func ParseInstance(instance string) (*url.URL, error) {
+	instanceURL, err := url.Parse(instance)
+	if err != nil {
+		return nil, err
+	}
+
+	if (instanceURL.Scheme != "http" && instanceURL.Scheme != "https") || instanceURL.Host == "" {
+		return nil, ErrInvalidInstance
+	}
+
+	return instanceURL, nil
+}
+
+// IsTrustedInstance 返回给定实例是否在受信任列表中，列表为空时不信任任何实例
+func IsTrustedInstance(instance string) bool {
+	instanceURL, err := ParseInstance(instance)
+	if err != nil {
+		return false
+	}
+
+	trusted := strings.TrimSpace(model.GetSettingByName("federation_trusted_hosts"))
+	if trusted == "" {
+		return false
+	}
+
+	for _, host := range strings.Split(trusted, ",") {
+		if strings.EqualFold(strings.TrimSpace(host), instanceURL.Host) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type client struct {
+	instance   *url.URL
+	httpClient request.Client
+}
+
+func (c *client) Offer(ctx context.Context, offer *serializer.FederationOffer) error {
+	body, err := json.Marshal(offer)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Request(
+		"POST",
+		"shares",
+		strings.NewReader(string(body)),
+		request.WithContext(ctx),
+		request.WithContentLength(int64(len(body))),
+		request.WithHeader(map[string][]string{"Content-Type": {"application/json"}}),
+	).CheckHTTPResponse(200).DecodeResponse()
+	if err != nil {
+		return err
+	}
+
+	if resp.Code != 0 {
+		return serializer.NewErrorFromResponse(resp)
+	}
+
+	return nil
+}
+
+func (c *client) Verify(ctx context.Context, token string) (*serializer.FederationOffer, error) {
+	resp, err := c.httpClient.Request(
+		"GET",
+		"shares/"+url.PathEscape(token),
+		nil,
+		request.WithContext(ctx),
+	).CheckHTTPResponse(200).DecodeResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != 0 {
+		return nil, serializer.NewErrorFromResponse(resp)
+	}
+
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var offer serializer.FederationOffer
+	if err := json.Unmarshal(data, &offer); err != nil {
+		return nil, err
+	}
+
+	return &offer, nil
+}
+
+func (c *client) List(ctx context.Context, token, path string) (*serializer.ObjectList, error) {
+	target := &url.URL{Path: fmt.Sprintf("shares/%s/list%s", token, path)}
+	resp, err := c.httpClient.Request(
+		"GET",
+		target.String(),
+		nil,
+		request.WithContext(ctx),
+	).CheckHTTPResponse(200).DecodeResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != 0 {
+		return nil, serializer.NewErrorFromResponse(resp)
+	}
+
+	// 响应数据已被解析为通用类型，重新编码后解析为列目录结果
+	data, err := json.Marshal(resp.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	var list serializer.ObjectList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+
+	return &list, nil
+}
+
+func (c *client) Download(ctx context.Context, token, path string) (string, error) {
+	resp, err := c.httpClient.Request(
+		"PUT",
+		fmt.Sprintf("shares/%s/download?path=%s", url.PathEscape(token), url.QueryEscape(path)),
+		nil,
+		request.WithContext(ctx),
+	).CheckHTTPResponse(200).DecodeResponse()
+	if err != nil {
+		return "", err
+	}
+
+	if resp.Code != 0 {
+		return "", serializer.NewErrorFromResponse(resp)
+	}
+
+	source, ok := resp.Data.(string)
+	if !ok {
+		return "", errors.New("unexpected download url")
+	}
+
+	// 相对地址以分享方实例地址为基准
+	sourceURL, err := url.Parse(source)
+	if err != nil {
+		return "", err
+	}
+
+	return c.instance.ResolveReference(sourceURL).String(), nil
+}
@@ -0,0 +1,233 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mocks/requestmock"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/stretchr/testify/assert"
+	testMock "github.com/stretchr/testify/mock"
+)
+
+func TestNewClient(t *testing.T) {
+	a := assert.New(t)
+
+	// 无法解析实例地址
+	{
+		c, err := NewClient(string([]byte{0x7f}))
+		a.Error(err)
+		a.Nil(c)
+	}
+
+	// 不支持的协议
+	{
+		c, err := NewClient("ftp://cloudreve.org")
+		a.ErrorIs(err, ErrInvalidInstance)
+		a.Nil(c)
+	}
+
+	// 成功
+	{
+		c, err := NewClient("https://cloudreve.org")
+		a.NoError(err)
+		a.NotNil(c)
+	}
+}
+
+func TestIsTrustedInstance(t *testing.T) {
+	a := assert.New(t)
+
+	cache.Set("setting_federation_trusted_hosts", "", 0)
+	a.False(IsTrustedInstance("https://a.example.com"))
+	a.False(IsTrustedInstance("a.example.com"))
+
+	cache.Set("setting_federation_trusted_hosts", "a.example.com, B.example.com:8080", 0)
+	a.True(IsTrustedInstance("https://a.example.com/"))
+	a.True(IsTrustedInstance("http://b.example.com:8080"))
+	a.False(IsTrustedInstance("https://b.example.com"))
+	a.False(IsTrustedInstance("https://c.example.com"))
+}
+
+func TestClient_Offer(t *testing.T) {
+	a := assert.New(t)
+	c, _ := NewClient("https://cloudreve.org")
+
+	// 请求失败
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "POST", "shares", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Err: errors.New("error"),
+		})
+		a.Error(c.Offer(context.Background(), &serializer.FederationOffer{}))
+		clientMock.AssertExpectations(t)
+	}
+
+	// 对方返回错误
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "POST", "shares", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":40066,"msg":"error"}`)),
+			},
+		})
+		err := c.Offer(context.Background(), &serializer.FederationOffer{})
+		a.Error(err)
+		a.Equal(serializer.CodeFederationNotAllowed, err.(serializer.AppError).Code)
+		clientMock.AssertExpectations(t)
+	}
+
+	// 成功
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "POST", "shares", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0}`)),
+			},
+		})
+		a.NoError(c.Offer(context.Background(), &serializer.FederationOffer{}))
+		clientMock.AssertExpectations(t)
+	}
+}
+
+func TestClient_Verify(t *testing.T) {
+	a := assert.New(t)
+	c, _ := NewClient("https://cloudreve.org")
+
+	// 对方返回错误
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "GET", "shares/token", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":404,"msg":"error"}`)),
+			},
+		})
+		res, err := c.Verify(context.Background(), "token")
+		a.Error(err)
+		a.Nil(res)
+		clientMock.AssertExpectations(t)
+	}
+
+	// 成功
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "GET", "shares/token", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0,"data":{"token":"token","email":"a@example.com","name":"a.txt","is_dir":true}}`)),
+			},
+		})
+		res, err := c.Verify(context.Background(), "token")
+		a.NoError(err)
+		a.Equal("a@example.com", res.Email)
+		a.Equal("a.txt", res.Name)
+		a.True(res.IsDir)
+		clientMock.AssertExpectations(t)
+	}
+}
+
+func TestClient_List(t *testing.T) {
+	a := assert.New(t)
+	c, _ := NewClient("https://cloudreve.org")
+
+	// 对方返回错误
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "GET", "shares/token/list/a%20b", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":404,"msg":"error"}`)),
+			},
+		})
+		res, err := c.List(context.Background(), "token", "/a b")
+		a.Error(err)
+		a.Nil(res)
+		clientMock.AssertExpectations(t)
+	}
+
+	// 成功
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "GET", "shares/token/list/", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0,"data":{"objects":[{"name":"a.txt","type":"file","size":10}]}}`)),
+			},
+		})
+		res, err := c.List(context.Background(), "token", "/")
+		a.NoError(err)
+		a.Len(res.Objects, 1)
+		a.Equal("a.txt", res.Objects[0].Name)
+		a.EqualValues(10, res.Objects[0].Size)
+		clientMock.AssertExpectations(t)
+	}
+}
+
+func TestClient_Download(t *testing.T) {
+	a := assert.New(t)
+	c, _ := NewClient("https://cloudreve.org/sub/")
+
+	// 对方返回错误
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "PUT", "shares/token/download?path=%2Fa.txt", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":403,"msg":"error"}`)),
+			},
+		})
+		res, err := c.Download(context.Background(), "token", "/a.txt")
+		a.Error(err)
+		a.Empty(res)
+		clientMock.AssertExpectations(t)
+	}
+
+	// 相对地址
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "PUT", "shares/token/download?path=", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0,"data":"/api/v3/file/download/key"}`)),
+			},
+		})
+		res, err := c.Download(context.Background(), "token", "")
+		a.NoError(err)
+		a.Equal("https://cloudreve.org/api/v3/file/download/key", res)
+		clientMock.AssertExpectations(t)
+	}
+
+	// 绝对地址
+	{
+		clientMock := requestmock.RequestMock{}
+		c.(*client).httpClient = &clientMock
+		clientMock.On("Request", "PUT", "shares/token/download?path=", testMock.Anything, testMock.Anything).Return(&request.Response{
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0,"data":"https://oss.example.com/a.txt?sign=1"}`)),
+			},
+		})
+		res, err := c.Download(context.Background(), "token", "")
+		a.NoError(err)
+		a.Equal("https://oss.example.com/a.txt?sign=1", res)
+		clientMock.AssertExpectations(t)
+	}
+}
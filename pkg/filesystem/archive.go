@@ -1,13 +1,14 @@
 package filesystem
 
 import (
-	"archive/zip"
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -24,8 +25,16 @@ import (
    ===============
 */
 
-// Compress 创建给定目录和文件的压缩文件
+// compressListPageSize 压缩目录时每次列取的子项数量
+var compressListPageSize = 1000
+
+// Compress 创建给定目录和文件的 zip 压缩文件
 func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs, fileIDs []uint, isArchive bool) error {
+	return fs.CompressWithOption(ctx, writer, folderIDs, fileIDs, &ArchiveOption{IsArchive: isArchive})
+}
+
+// CompressWithOption 按照给定的格式、密码等选项创建压缩文件
+func (fs *FileSystem) CompressWithOption(ctx context.Context, writer io.Writer, folderIDs, fileIDs []uint, opt *ArchiveOption) error {
 	// 查找待压缩目录
 	folders, err := model.GetFoldersByIDs(folderIDs, fs.User.ID)
 	if err != nil && len(folderIDs) != 0 {
@@ -71,36 +80,73 @@ func (fs *FileSystem) Compress(ctx context.Context, writer io.Writer, folderIDs,
 	}
 
 	// 创建压缩文件Writer
-	zipWriter := zip.NewWriter(writer)
-	defer zipWriter.Close()
+	archive, err := newArchiveWriter(writer, opt)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
 
 	ctx = reqContext
 
 	// 压缩各个目录及文件
 	for i := 0; i < len(folders); i++ {
-		select {
-		case <-reqContext.Done():
-			// 取消压缩请求
-			return ErrClientCanceled
-		default:
-			fs.doCompress(reqContext, nil, &folders[i], zipWriter, isArchive)
+		if err := fs.doCompress(reqContext, nil, &folders[i], archive); err != nil {
+			return err
 		}
-
 	}
 	for i := 0; i < len(files); i++ {
-		select {
-		case <-reqContext.Done():
-			// 取消压缩请求
-			return ErrClientCanceled
-		default:
-			fs.doCompress(reqContext, &files[i], nil, zipWriter, isArchive)
+		if err := fs.doCompress(reqContext, &files[i], nil, archive); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
-func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *model.Folder, zipWriter *zip.Writer, isArchive bool) {
+// Takeout 将用户的全部文件写入压缩包的 files 目录，并附加给定的元数据文件
+func (fs *FileSystem) Takeout(ctx context.Context, writer io.Writer, metadata map[string][]byte) error {
+	root, err := fs.User.Root()
+	if err != nil {
+		return ErrObjectNotExist
+	}
+	root.Position = ""
+	root.Name = "files"
+
+	archive, err := newArchiveWriter(writer, &ArchiveOption{})
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	// 写入元数据文件
+	names := make([]string, 0, len(metadata))
+	for name := range metadata {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := archive.WriteFile(name, time.Now(), uint64(len(metadata[name])), bytes.NewReader(metadata[name])); err != nil {
+			return err
+		}
+	}
+
+	if err := fs.doCompress(ctx, nil, root, archive); err != nil {
+		return err
+	}
+
+	return ctx.Err()
+}
+
+// doCompress 将文件或目录写入压缩包，文件内容直接从存储策略流式写入，
+// 目录按页列取子项，避免大目录的全部子项同时驻留内存
+func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *model.Folder, archive archiveWriter) error {
+	select {
+	case <-ctx.Done():
+		// 取消压缩请求
+		return ErrClientCanceled
+	default:
+	}
+
 	// 如果对象是文件
 	if file != nil {
 		// 切换上传策略
@@ -108,7 +154,7 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 		err := fs.DispatchHandler()
 		if err != nil {
 			util.Log().Warning("无法压缩文件%s，%s", file.Name, err)
-			return
+			return nil
 		}
 
 		// 获取文件内容
@@ -118,78 +164,93 @@ func (fs *FileSystem) doCompress(ctx context.Context, file *model.File, folder *
 		)
 		if err != nil {
 			util.Log().Debug("Open%s，%s", file.Name, err)
-			return
+			return nil
 		}
 		if closer, ok := fileToZip.(io.Closer); ok {
 			defer closer.Close()
 		}
 
-		// 创建压缩文件头
-		header := &zip.FileHeader{
-			Name:               filepath.FromSlash(path.Join(file.Position, file.Name)),
-			Modified:           file.UpdatedAt,
-			UncompressedSize64: file.Size,
+		// 写入压缩文件
+		name := filepath.FromSlash(path.Join(file.Position, file.Name))
+		if err := archive.WriteFile(name, file.UpdatedAt, file.Size, LimitReadCloser(ctx, fileToZip)); err != nil {
+			util.Log().Debug("无法压缩文件%s，%s", file.Name, err)
 		}
+		return nil
+	}
 
-		// 指定是压缩还是归档
-		if isArchive {
-			header.Method = zip.Store
-		} else {
-			header.Method = zip.Deflate
-		}
+	if folder == nil {
+		return nil
+	}
 
-		writer, err := zipWriter.CreateHeader(header)
+	// 对象是目录，逐页获取子文件及子目录
+	query := &model.ChildrenQuery{Limit: compressListPageSize}
+	for {
+		subFolders, subFiles, next, err := folder.ListChildren(query)
 		if err != nil {
-			return
+			util.Log().Warning("无法列取目录%s，%s", folder.Name, err)
+			return nil
 		}
 
-		_, err = io.Copy(writer, fileToZip)
-	} else if folder != nil {
-		// 对象是目录
-		// 获取子文件
-		subFiles, err := folder.GetChildFiles()
-		if err == nil && len(subFiles) > 0 {
-			for i := 0; i < len(subFiles); i++ {
-				fs.doCompress(ctx, &subFiles[i], nil, zipWriter, isArchive)
+		for i := 0; i < len(subFiles); i++ {
+			if err := fs.doCompress(ctx, &subFiles[i], nil, archive); err != nil {
+				return err
 			}
-
 		}
-		// 获取子目录，继续递归遍历
-		subFolders, err := folder.GetChildFolder()
-		if err == nil && len(subFolders) > 0 {
-			for i := 0; i < len(subFolders); i++ {
-				fs.doCompress(ctx, nil, &subFolders[i], zipWriter, isArchive)
+
+		// 继续递归遍历子目录
+		for i := 0; i < len(subFolders); i++ {
+			if err := fs.doCompress(ctx, nil, &subFolders[i], archive); err != nil {
+				return err
 			}
 		}
+
+		if next == nil {
+			return nil
+		}
+		query.Cursor = next
 	}
 }
 
-// Decompress 解压缩给定压缩文件到dst目录
-func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string) error {
+// ArchiveEntry 压缩包内的条目
+type ArchiveEntry struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	IsDir    bool      `json:"is_dir"`
+	Modified time.Time `json:"modified"`
+}
+
+// archiveSource 已打开的待解压文件
+type archiveSource struct {
+	extractor archiver.Extractor
+	reader    io.Reader
+	isZip     bool
+	closers   []func()
+}
+
+// Close 关闭文件流并删除临时文件
+func (source *archiveSource) Close() {
+	for i := len(source.closers) - 1; i >= 0; i-- {
+		source.closers[i]()
+	}
+}
+
+// openArchive 获取并识别压缩文件，zip 格式会先下载到临时目录
+func (fs *FileSystem) openArchive(ctx context.Context, src, encoding string) (*archiveSource, error) {
 	err := fs.ResetFileIfNotExist(ctx, src)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	tempZipFilePath := ""
-	defer func() {
-		// 结束时删除临时压缩文件
-		if tempZipFilePath != "" {
-			if err := os.Remove(tempZipFilePath); err != nil {
-				util.Log().Warning("无法删除临时压缩文件 %s , %s", tempZipFilePath, err)
-			}
-		}
-	}()
+	source := &archiveSource{}
 
 	// 下载压缩文件到临时目录
 	fileStream, err := fs.Handler.Get(ctx, fs.FileTarget[0].SourceName)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	source.closers = append(source.closers, func() { fileStream.Close() })
 
-	defer fileStream.Close()
-
-	tempZipFilePath = filepath.Join(
+	tempZipFilePath := filepath.Join(
 		util.RelativePath(model.GetSettingByName("temp_path")),
 		"decompress",
 		fmt.Sprintf("archive_%d.zip", time.Now().UnixNano()),
@@ -198,47 +259,121 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 	zipFile, err := util.CreatNestedFile(tempZipFilePath)
 	if err != nil {
 		util.Log().Warning("无法创建临时压缩文件 %s , %s", tempZipFilePath, err)
-		tempZipFilePath = ""
-		return err
+		source.Close()
+		return nil, err
 	}
-	defer zipFile.Close()
+	source.closers = append(source.closers, func() {
+		// 结束时删除临时压缩文件
+		zipFile.Close()
+		if err := os.Remove(tempZipFilePath); err != nil {
+			util.Log().Warning("无法删除临时压缩文件 %s , %s", tempZipFilePath, err)
+		}
+	})
 
 	// 下载前先判断是否是可解压的格式
-	format, readStream, err := archiver.Identify(fs.FileTarget[0].SourceName, fileStream)
+	format, readStream, err := archiver.Identify(fs.FileTarget[0].SourceName, LimitReadCloser(ctx, fileStream))
 	if err != nil {
 		util.Log().Warning("无法识别文件格式 %s , %s", fs.FileTarget[0].SourceName, err)
-		return err
+		source.Close()
+		return nil, err
 	}
 
 	extractor, ok := format.(archiver.Extractor)
 	if !ok {
-		return fmt.Errorf("file not an extractor %s", fs.FileTarget[0].SourceName)
+		source.Close()
+		return nil, fmt.Errorf("file not an extractor %s", fs.FileTarget[0].SourceName)
 	}
 
 	// 只有zip格式可以多个文件同时上传
-	var isZip bool
 	switch extractor.(type) {
 	case archiver.Zip:
 		extractor = archiver.Zip{TextEncoding: encoding}
-		isZip = true
+		source.isZip = true
 	}
 
 	// 除了zip必须下载到本地，其余的可以边下载边解压
-	reader := readStream
-	if isZip {
+	source.reader = readStream
+	if source.isZip {
 		_, err = io.Copy(zipFile, readStream)
 		if err != nil {
 			util.Log().Warning("无法写入临时压缩文件 %s , %s", tempZipFilePath, err)
-			return err
+			source.Close()
+			return nil, err
 		}
 
 		fileStream.Close()
 
 		// 设置文件偏移量
 		zipFile.Seek(0, io.SeekStart)
-		reader = zipFile
+		source.reader = zipFile
+	}
+
+	source.extractor = extractor
+	return source, nil
+}
+
+// ListArchive 列出压缩文件内的条目，不进行解压
+func (fs *FileSystem) ListArchive(ctx context.Context, src, encoding string) ([]ArchiveEntry, error) {
+	source, err := fs.openArchive(ctx, src, encoding)
+	if err != nil {
+		return nil, err
+	}
+	defer source.Close()
+
+	entries := make([]ArchiveEntry, 0)
+	err = source.extractor.Extract(ctx, source.reader, nil, func(ctx context.Context, f archiver.File) error {
+		entries = append(entries, ArchiveEntry{
+			Name:     util.FormSlash(f.NameInArchive),
+			Size:     f.FileInfo.Size(),
+			IsDir:    f.FileInfo.IsDir(),
+			Modified: f.FileInfo.ModTime(),
+		})
+		return nil
+	})
+
+	return entries, err
+}
+
+// matchArchiveEntry 判断压缩包内路径是否被给定的条目或通配符选中，
+// 未指定任何条目时选中全部
+func matchArchiveEntry(name string, patterns []string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	name = strings.Trim(name, "/")
+	for _, pattern := range patterns {
+		pattern = strings.Trim(util.FormSlash(pattern), "/")
+		if pattern == "" {
+			continue
+		}
+
+		// 精确匹配或位于选中的目录下
+		if name == pattern || strings.HasPrefix(name, pattern+"/") {
+			return true
+		}
+
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
 	}
 
+	return false
+}
+
+// Decompress 解压缩给定压缩文件到dst目录，
+// patterns 非空时只解压其中指定的条目或匹配通配符的条目
+func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string, patterns []string) error {
+	source, err := fs.openArchive(ctx, src, encoding)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	extractor := source.extractor
+	reader := source.reader
+	isZip := source.isZip
+
 	// 重设存储策略
 	fs.Policy = &fs.User.Policy
 	err = fs.DispatchHandler()
@@ -288,6 +423,11 @@ func (fs *FileSystem) Decompress(ctx context.Context, src, dst, encoding string)
 			return nil
 		}
 
+		// 是否选中此条目
+		if !matchArchiveEntry(rawPath, patterns) {
+			return nil
+		}
+
 		// 如果是目录
 		if f.FileInfo.IsDir() {
 			fs.CreateDirectory(ctx, savePath)
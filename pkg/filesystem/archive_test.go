@@ -1,17 +1,23 @@
 package filesystem
 
 import (
+	"archive/tar"
+	"archive/zip"
 	"bytes"
+	"compress/flate"
+	"compress/gzip"
 	"context"
 	"errors"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	testMock "github.com/stretchr/testify/mock"
+	"hash/crc32"
 	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -43,14 +49,18 @@ func TestFileSystem_Compress(t *testing.T) {
 					AddRow(1, "1.txt", "tests/file1.txt", 1),
 			)
 		asserts.NoError(cache.Set("setting_temp_path", "tests", -1))
+		// 查找子目录
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "sub"))
 		// 查找父目录子文件
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id"}))
-		// 查找子目录
+		// 查找子目录的子目录
 		mock.ExpectQuery("SELECT(.+)folders(.+)").
-			WithArgs(1).
-			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "sub"))
+			WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
 		// 查找子目录子文件
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(2).
@@ -64,9 +74,52 @@ func TestFileSystem_Compress(t *testing.T) {
 
 		err := fs.Compress(ctx, w, []uint{1}, []uint{1}, true)
 		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotEmpty(w.Len())
 	}
 
+	// 分页列取目录子项
+	{
+		compressListPageSize = 1
+		defer func() { compressListPageSize = 1000 }()
+
+		// 查找压缩父目录
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "parent"))
+		// 无顶级待压缩文件
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		// 第一页：无子目录，多取的一项表示还有下一页
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id"}).
+					AddRow(1, "1.txt", Path("tests/file1.txt"), 1).
+					AddRow(2, "2.txt", Path("tests/file2.txt"), 1),
+			)
+		// 第二页：从游标之后继续
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, "1.txt", "1.txt", 1).
+			WillReturnRows(
+				sqlmock.NewRows([]string{"id", "name", "source_name", "policy_id"}).
+					AddRow(2, "2.txt", Path("tests/file2.txt"), 1),
+			)
+		asserts.NoError(cache.Set("policy_1", model.Policy{Type: "local"}, -1))
+		w := &bytes.Buffer{}
+
+		err := fs.Compress(ctx, w, []uint{1}, []uint{}, false)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+
+		reader, err := zip.NewReader(bytes.NewReader(w.Bytes()), int64(w.Len()))
+		asserts.NoError(err)
+		asserts.Len(reader.File, 2)
+	}
+
 	// 上下文取消
 	{
 		ctx, cancel := context.WithCancel(context.Background())
@@ -176,7 +229,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		// 查找压缩文件，未找到
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
-		err := fs.Decompress(ctx, "/1.zip", "/", "")
+		err := fs.Decompress(ctx, "/1.zip", "/", "", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 	}
@@ -188,7 +241,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Get", testMock.Anything, "1.zip").Return(MockRSC{}, errors.New("error"))
 		fs.Handler = testHandler
-		err := fs.Decompress(ctx, "/1.zip", "/", "")
+		err := fs.Decompress(ctx, "/1.zip", "/", "", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 		asserts.EqualError(err, "error")
@@ -202,7 +255,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Get", testMock.Anything, "1.zip").Return(MockRSC{}, nil)
 		fs.Handler = testHandler
-		err := fs.Decompress(ctx, "/1.zip", "/", "")
+		err := fs.Decompress(ctx, "/1.zip", "/", "", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 	}
@@ -215,7 +268,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Get", testMock.Anything, "1.zip").Return(MockNopRSC("1"), nil)
 		fs.Handler = testHandler
-		err := fs.Decompress(ctx, "/1.zip", "/", "")
+		err := fs.Decompress(ctx, "/1.zip", "/", "", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 		asserts.Contains(err.Error(), "read error")
@@ -229,7 +282,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Get", testMock.Anything, "1.zip").Return(MockRSC{rs: strings.NewReader("read")}, nil)
 		fs.Handler = testHandler
-		err := fs.Decompress(ctx, "/1.zip", "/", "")
+		err := fs.Decompress(ctx, "/1.zip", "/", "", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Error(err)
 		asserts.True(util.IsEmpty(util.RelativePath("tests/decompress")))
@@ -246,7 +299,7 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler.On("Get", testMock.Anything, "1.zip").Return(zipFile, nil)
 		fs.Handler = testHandler
 
-		fs.Decompress(ctx, "/1.zip", "/", "")
+		fs.Decompress(ctx, "/1.zip", "/", "", nil)
 
 		zipFile.Close()
 
@@ -254,3 +307,126 @@ func TestFileSystem_Decompress(t *testing.T) {
 		testHandler.AssertExpectations(t)
 	}
 }
+
+func TestNewArchiveWriter(t *testing.T) {
+	a := assert.New(t)
+	modified := time.Date(2022, 5, 1, 10, 20, 30, 0, time.UTC)
+
+	// 不支持的格式
+	{
+		_, err := newArchiveWriter(&bytes.Buffer{}, &ArchiveOption{Format: "7z"})
+		a.Equal(ErrUnsupportedArchiveFormat, err)
+	}
+
+	// tar.gz 不支持密码
+	{
+		_, err := newArchiveWriter(&bytes.Buffer{}, &ArchiveOption{Format: ArchiveFormatTarGz, Password: "123"})
+		a.Equal(ErrArchivePasswordNotSupported, err)
+	}
+
+	// tar.gz
+	{
+		buf := &bytes.Buffer{}
+		w, err := newArchiveWriter(buf, &ArchiveOption{Format: ArchiveFormatTarGz})
+		a.NoError(err)
+		a.NoError(w.WriteFile("sub/1.txt", modified, 5, strings.NewReader("hello")))
+		a.NoError(w.Close())
+
+		gzipReader, err := gzip.NewReader(buf)
+		a.NoError(err)
+		tarReader := tar.NewReader(gzipReader)
+		header, err := tarReader.Next()
+		a.NoError(err)
+		a.Equal("sub/1.txt", header.Name)
+		content, _ := io.ReadAll(tarReader)
+		a.Equal("hello", string(content))
+	}
+
+	// tar.gz 文件流短于记录的大小
+	{
+		w, err := newArchiveWriter(&bytes.Buffer{}, &ArchiveOption{Format: ArchiveFormatTarGz})
+		a.NoError(err)
+		a.Equal(io.ErrUnexpectedEOF, w.WriteFile("1.txt", modified, 10, strings.NewReader("hello")))
+	}
+
+	// 带密码的 zip
+	{
+		buf := &bytes.Buffer{}
+		w, err := newArchiveWriter(buf, &ArchiveOption{Password: "secret"})
+		a.NoError(err)
+		a.NoError(w.WriteFile("1.txt", modified, 5, strings.NewReader("hello")))
+		a.NoError(w.Close())
+
+		reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		a.NoError(err)
+		a.Len(reader.File, 1)
+		file := reader.File[0]
+		a.EqualValues(1, file.Flags&0x1)
+		a.EqualValues(5, file.UncompressedSize64)
+
+		raw, err := file.OpenRaw()
+		a.NoError(err)
+		encrypted, _ := io.ReadAll(raw)
+
+		// 使用相同口令解密
+		decrypter := newZipCryptoWriter(nil, "secret")
+		plain := make([]byte, len(encrypted))
+		for i, c := range encrypted {
+			plain[i] = c ^ decrypter.streamByte()
+			decrypter.updateKeys(plain[i])
+		}
+		a.Equal(byte(file.ModifiedTime>>8), plain[11])
+
+		content, err := io.ReadAll(flate.NewReader(bytes.NewReader(plain[12:])))
+		a.NoError(err)
+		a.Equal("hello", string(content))
+		a.Equal(crc32.ChecksumIEEE(content), file.CRC32)
+	}
+}
+
+func TestMatchArchiveEntry(t *testing.T) {
+	a := assert.New(t)
+	a.True(matchArchiveEntry("a/b.txt", nil))
+	a.True(matchArchiveEntry("a/b.txt", []string{"a/b.txt"}))
+	a.True(matchArchiveEntry("a/b.txt", []string{"/a/"}))
+	a.True(matchArchiveEntry("a/", []string{"a"}))
+	a.True(matchArchiveEntry("a/b.txt", []string{"*.jpg", "a/*.txt"}))
+	a.False(matchArchiveEntry("a/b.txt", []string{"*.txt"}))
+	a.False(matchArchiveEntry("ab/c.txt", []string{"a"}))
+	a.False(matchArchiveEntry("a/b.txt", []string{""}))
+}
+
+func TestFileSystem_ListArchive(t *testing.T) {
+	a := assert.New(t)
+	ctx := context.Background()
+	fs := FileSystem{
+		User: &model.User{Model: gorm.Model{ID: 1}},
+	}
+	a.NoError(cache.Set("setting_temp_path", "tests", -1))
+
+	// 无法下载压缩文件
+	{
+		fs.FileTarget = []model.File{{SourceName: "1.zip", Policy: model.Policy{Type: "mock"}}}
+		fs.FileTarget[0].Policy.ID = 1
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "1.zip").Return(MockRSC{}, errors.New("error"))
+		fs.Handler = testHandler
+		_, err := fs.ListArchive(ctx, "/1.zip", "")
+		a.EqualError(err, "error")
+	}
+
+	// 成功
+	{
+		zipFile, _ := os.Open(Path("tests/test.zip"))
+		defer zipFile.Close()
+		fs.FileTarget = []model.File{{SourceName: "1.zip", Policy: model.Policy{Type: "mock"}}}
+		fs.FileTarget[0].Policy.ID = 1
+		testHandler := new(FileHeaderMock)
+		testHandler.On("Get", testMock.Anything, "1.zip").Return(zipFile, nil)
+		fs.Handler = testHandler
+		entries, err := fs.ListArchive(ctx, "/1.zip", "")
+		a.NoError(err)
+		a.NotEmpty(entries)
+		a.True(util.IsEmpty(util.RelativePath("tests/decompress")))
+	}
+}
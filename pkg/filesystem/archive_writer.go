@@ -0,0 +1,302 @@
+package filesystem
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/rand"
+	"errors"
+	"hash/crc32"
+	"io"
+	"sync"
+	"time"
+)
+
+// 支持的压缩格式
+const (
+	ArchiveFormatZip   = "zip"
+	ArchiveFormatTarGz = "tar.gz"
+)
+
+// archiveCopyBufferSize 写入压缩文件时复制文件流使用的缓冲区大小
+const archiveCopyBufferSize = 256 * 1024
+
+// zip64Version 使用 zip64 扩展所需的解压软件版本
+const zip64Version = 45
+
+// archiveBufferPool 写入压缩文件时复制文件流使用的缓冲区池，
+// 无论文件多大，每个写入中的条目只占用一个固定大小的缓冲区
+var archiveBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, archiveCopyBufferSize)
+		return &buf
+	},
+}
+
+var (
+	// ErrUnsupportedArchiveFormat 不支持的压缩格式
+	ErrUnsupportedArchiveFormat = errors.New("unsupported archive format")
+	// ErrArchivePasswordNotSupported 压缩格式不支持设置密码
+	ErrArchivePasswordNotSupported = errors.New("archive format does not support password")
+)
+
+// ArchiveOption 压缩选项
+type ArchiveOption struct {
+	// 压缩格式，为空时使用 zip
+	Format string
+	// 压缩包密码，仅 zip 格式可用
+	Password string
+	// 是否仅归档而不压缩
+	IsArchive bool
+}
+
+// ArchiveExt 返回压缩格式对应的扩展名
+func ArchiveExt(format string) string {
+	if format == "" {
+		format = ArchiveFormatZip
+	}
+	return "." + format
+}
+
+// archiveWriter 压缩文件写入器
+type archiveWriter interface {
+	// WriteFile 写入一个文件
+	WriteFile(name string, modified time.Time, size uint64, reader io.Reader) error
+	// Close 结束写入
+	Close() error
+}
+
+// newArchiveWriter 根据压缩选项创建写入器
+func newArchiveWriter(writer io.Writer, opt *ArchiveOption) (archiveWriter, error) {
+	switch opt.Format {
+	case "", ArchiveFormatZip:
+		return &zipArchiveWriter{
+			writer:    zip.NewWriter(writer),
+			password:  opt.Password,
+			isArchive: opt.IsArchive,
+		}, nil
+	case ArchiveFormatTarGz:
+		if opt.Password != "" {
+			return nil, ErrArchivePasswordNotSupported
+		}
+
+		level := gzip.DefaultCompression
+		if opt.IsArchive {
+			level = gzip.NoCompression
+		}
+		gzipWriter, _ := gzip.NewWriterLevel(writer, level)
+		return &tarGzArchiveWriter{
+			gzip: gzipWriter,
+			tar:  tar.NewWriter(gzipWriter),
+		}, nil
+	}
+
+	return nil, ErrUnsupportedArchiveFormat
+}
+
+// zipArchiveWriter zip 格式写入器
+type zipArchiveWriter struct {
+	writer    *zip.Writer
+	password  string
+	isArchive bool
+	// 加密条目共用的压缩器，避免每个条目重新分配
+	deflater *flate.Writer
+}
+
+func (w *zipArchiveWriter) WriteFile(name string, modified time.Time, size uint64, reader io.Reader) error {
+	header := &zip.FileHeader{
+		Name:               name,
+		Modified:           modified,
+		UncompressedSize64: size,
+	}
+
+	// 指定是压缩还是归档
+	if w.isArchive {
+		header.Method = zip.Store
+	} else {
+		header.Method = zip.Deflate
+	}
+
+	if w.password != "" {
+		return w.writeEncrypted(header, reader)
+	}
+
+	writer, err := w.writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = copyArchiveEntry(writer, reader)
+	return err
+}
+
+// writeEncrypted 使用传统 PKWARE 加密写入文件
+func (w *zipArchiveWriter) writeEncrypted(header *zip.FileHeader, reader io.Reader) error {
+	// 加密 + 数据描述符，CRC 与大小在写入完成后补全
+	header.Flags |= 0x1 | 0x8
+	raw, err := w.writer.CreateRaw(header)
+	if err != nil {
+		return err
+	}
+
+	encrypted := &countWriter{writer: newZipCryptoWriter(raw, w.password)}
+
+	// 加密头，启用数据描述符时最后一字节为修改时间的高位
+	encryptHeader := make([]byte, 12)
+	if _, err := rand.Read(encryptHeader[:11]); err != nil {
+		return err
+	}
+	encryptHeader[11] = byte(header.ModifiedTime >> 8)
+	if _, err := encrypted.Write(encryptHeader); err != nil {
+		return err
+	}
+
+	var (
+		dst      io.Writer = encrypted
+		deflater *flate.Writer
+	)
+	if header.Method == zip.Deflate {
+		if w.deflater == nil {
+			w.deflater, _ = flate.NewWriter(encrypted, flate.DefaultCompression)
+		} else {
+			w.deflater.Reset(encrypted)
+		}
+		deflater = w.deflater
+		dst = deflater
+	}
+
+	checksum := crc32.NewIEEE()
+	size, err := copyArchiveEntry(io.MultiWriter(dst, checksum), reader)
+	if err != nil {
+		return err
+	}
+
+	if deflater != nil {
+		if err := deflater.Close(); err != nil {
+			return err
+		}
+	}
+
+	header.CRC32 = checksum.Sum32()
+	header.UncompressedSize64 = uint64(size)
+	header.CompressedSize64 = encrypted.count
+	header.UncompressedSize = uint32(min64(header.UncompressedSize64, 0xffffffff))
+	header.CompressedSize = uint32(min64(header.CompressedSize64, 0xffffffff))
+
+	// 超过 4GB 的条目在数据描述符及中央目录中使用 zip64 扩展记录大小
+	if header.UncompressedSize64 >= 0xffffffff || header.CompressedSize64 >= 0xffffffff {
+		header.ReaderVersion = zip64Version
+	}
+	return nil
+}
+
+func (w *zipArchiveWriter) Close() error {
+	return w.writer.Close()
+}
+
+// tarGzArchiveWriter tar.gz 格式写入器
+type tarGzArchiveWriter struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func (w *tarGzArchiveWriter) WriteFile(name string, modified time.Time, size uint64, reader io.Reader) error {
+	err := w.tar.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     int64(size),
+		Mode:     0644,
+		ModTime:  modified,
+	})
+	if err != nil {
+		return err
+	}
+
+	n, err := copyArchiveEntry(w.tar, io.LimitReader(reader, int64(size)))
+	if err == nil && n < int64(size) {
+		err = io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+func (w *tarGzArchiveWriter) Close() error {
+	if err := w.tar.Close(); err != nil {
+		w.gzip.Close()
+		return err
+	}
+	return w.gzip.Close()
+}
+
+// copyArchiveEntry 使用缓冲区池中的缓冲区将文件流写入压缩文件条目
+func copyArchiveEntry(dst io.Writer, src io.Reader) (int64, error) {
+	buf := archiveBufferPool.Get().(*[]byte)
+	defer archiveBufferPool.Put(buf)
+
+	// 隐藏 WriterTo 及 ReaderFrom，使复制始终使用给定的缓冲区
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
+}
+
+// countWriter 记录写入字节数
+type countWriter struct {
+	writer io.Writer
+	count  uint64
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.count += uint64(n)
+	return n, err
+}
+
+// zipCryptoWriter 传统 PKWARE 加密写入器
+type zipCryptoWriter struct {
+	writer io.Writer
+	keys   [3]uint32
+	buf    []byte
+}
+
+func newZipCryptoWriter(writer io.Writer, password string) *zipCryptoWriter {
+	w := &zipCryptoWriter{
+		writer: writer,
+		keys:   [3]uint32{0x12345678, 0x23456789, 0x34567890},
+	}
+	for i := 0; i < len(password); i++ {
+		w.updateKeys(password[i])
+	}
+	return w
+}
+
+func (w *zipCryptoWriter) updateKeys(b byte) {
+	w.keys[0] = crc32Update(w.keys[0], b)
+	w.keys[1] = (w.keys[1]+(w.keys[0]&0xff))*134775813 + 1
+	w.keys[2] = crc32Update(w.keys[2], byte(w.keys[1]>>24))
+}
+
+func (w *zipCryptoWriter) streamByte() byte {
+	temp := uint16(w.keys[2] | 2)
+	return byte((uint32(temp) * uint32(temp^1)) >> 8)
+}
+
+func (w *zipCryptoWriter) Write(p []byte) (int, error) {
+	if cap(w.buf) < len(p) {
+		w.buf = make([]byte, len(p))
+	}
+	buf := w.buf[:len(p)]
+	for i, b := range p {
+		buf[i] = b ^ w.streamByte()
+		w.updateKeys(b)
+	}
+	return w.writer.Write(buf)
+}
+
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ (crc >> 8)
+}
+
+func min64(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     禁止列表相关
+   ================
+*/
+
+// HookCheckBlockedHash 计算上传文件的内容摘要，命中禁止列表时删除文件并拒绝上传，
+// 否则将摘要记录到文件元数据中，供分享下载时检查
+func HookCheckBlockedHash(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	if !model.IsTrueVal(model.GetSettingByName("hash_blocklist_enabled")) {
+		return nil
+	}
+
+	fileInfo := fileHeader.Info()
+	file := fileInfo.Model.(*model.File)
+	if maxSize := model.GetIntSetting("hash_blocklist_max_size", 0); maxSize > 0 && file.Size > uint64(maxSize) {
+		util.Log().Debug("文件 [%s] 超过摘要计算大小限制，跳过禁止列表检查", file.Name)
+		return nil
+	}
+
+	hashes, err := contentHashes(ctx, fs, fileInfo.SavePath)
+	if err != nil {
+		util.Log().Warning("无法计算文件 [%s] 的内容摘要, %s", file.Name, err)
+		return nil
+	}
+
+	blocked, err := model.MatchBlockedHash([]string{
+		hashes[model.HashMD5MetaKey],
+		hashes[model.HashSHA1MetaKey],
+		hashes[model.HashSHA256MetaKey],
+	})
+	if err != nil {
+		util.Log().Warning("无法检查禁止列表, %s", err)
+	}
+
+	if blocked == nil {
+		return file.UpdateMetadata(hashes)
+	}
+
+	util.Log().Warning("用户 [%s] 上传的文件 [%s] 命中禁止列表 %s", fs.User.Email, file.Name, blocked.Hash)
+	model.RecordAudit(nil, fs.User.ID, model.AuditBlockedHash, model.AuditTarget("file", file.ID),
+		fmt.Sprintf("upload rejected: %s/%s (%s)", fs.User.Email, file.Name, blocked.Hash))
+
+	// 覆盖已有文件时，由 AfterValidateFailed 钩子清空文件内容
+	if _, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+		return ErrFileBlocked
+	}
+
+	// 新文件连同记录一并删除，文件系统会在请求结束后回收，使用新的文件系统删除
+	deleteFs, err := NewFileSystem(fs.User)
+	if err != nil {
+		return err
+	}
+	defer deleteFs.Recycle()
+
+	deleteFs.SetTargetFile(&[]model.File{*file})
+	if err := deleteFs.Delete(ctx, []uint{}, []uint{file.ID}, true); err != nil {
+		util.Log().Warning("无法删除命中禁止列表的文件 [%s], %s", file.Name, err)
+	}
+
+	return ErrFileBlocked
+}
+
+// contentHashes 读取文件内容，一次计算 MD5、SHA1 及 SHA256 摘要
+func contentHashes(ctx context.Context, fs *FileSystem, source string) (map[string]string, error) {
+	rs, err := fs.Handler.Get(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	md5Hash, sha1Hash, sha256Hash := md5.New(), sha1.New(), sha256.New()
+	if _, err := io.Copy(io.MultiWriter(md5Hash, sha1Hash, sha256Hash), rs); err != nil {
+		return nil, err
+	}
+
+	return map[string]string{
+		model.HashMD5MetaKey:    hex.EncodeToString(md5Hash.Sum(nil)),
+		model.HashSHA1MetaKey:   hex.EncodeToString(sha1Hash.Sum(nil)),
+		model.HashSHA256MetaKey: hex.EncodeToString(sha256Hash.Sum(nil)),
+	}, nil
+}
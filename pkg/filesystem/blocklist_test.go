@@ -0,0 +1,87 @@
+package filesystem
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookCheckBlockedHash(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Email: "a@example.com"}, Handler: local.Driver{}}
+
+	physical, err := os.Create(util.RelativePath("TestHookCheckBlockedHash.txt"))
+	asserts.NoError(err)
+	physical.WriteString("content")
+	physical.Close()
+	defer os.Remove(util.RelativePath("TestHookCheckBlockedHash.txt"))
+	sum := sha256.Sum256([]byte("content"))
+
+	newHeader := func(size uint64) *fsctx.FileStream {
+		file := &model.File{Name: "a.txt", Size: size}
+		file.ID = 1
+		return &fsctx.FileStream{Model: file, SavePath: "TestHookCheckBlockedHash.txt"}
+	}
+
+	// 未开启
+	{
+		cache.Set("setting_hash_blocklist_enabled", "0", 0)
+		asserts.NoError(HookCheckBlockedHash(context.Background(), fs, newHeader(7)))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	cache.Set("setting_hash_blocklist_enabled", "1", 0)
+
+	// 超过大小限制，跳过
+	{
+		cache.Set("setting_hash_blocklist_max_size", "1", 0)
+		asserts.NoError(HookCheckBlockedHash(context.Background(), fs, newHeader(7)))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	cache.Set("setting_hash_blocklist_max_size", "0", 0)
+
+	// 无法读取文件，跳过
+	{
+		header := newHeader(7)
+		header.SavePath = "not_exist.txt"
+		asserts.NoError(HookCheckBlockedHash(context.Background(), fs, header))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未命中，记录摘要
+	{
+		header := newHeader(7)
+		mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)metadata(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(HookCheckBlockedHash(context.Background(), fs, header))
+		asserts.NoError(mock.ExpectationsWereMet())
+		file := header.Model.(*model.File)
+		asserts.Equal(hex.EncodeToString(sum[:]), file.MetadataSerialized[model.HashSHA256MetaKey])
+		asserts.Len(file.ContentHashes(), 3)
+	}
+
+	// 覆盖已有文件时命中
+	{
+		ctx := context.WithValue(context.Background(), fsctx.FileModelCtx, model.File{})
+		mock.ExpectQuery("SELECT(.+)blocked_hashes(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "hash"}).AddRow(1, hex.EncodeToString(sum[:])))
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.Equal(ErrFileBlocked, HookCheckBlockedHash(ctx, fs, newHeader(7)))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -40,3 +40,15 @@ type Handler interface {
 	// recursive - 是否递归列出
 	List(ctx context.Context, path string, recursive bool) ([]response.Object, error)
 }
+
+// Capacity 存储端的容量，单位为字节
+type Capacity struct {
+	Total uint64 `json:"total"`
+	Free  uint64 `json:"free"`
+}
+
+// CapacityReporter 可查询存储端容量的存储策略适配器
+type CapacityReporter interface {
+	// Capacity 获取存储策略所在磁盘或云存储账户的总容量及剩余容量
+	Capacity(ctx context.Context) (*Capacity, error)
+}
@@ -0,0 +1,37 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Capacity 获取存储目录所在磁盘的总容量及剩余容量
+func (handler Driver) Capacity(ctx context.Context) (*driver.Capacity, error) {
+	return DiskCapacity(StorageRoot(handler.Policy.DirNameRule))
+}
+
+// StorageRoot 返回存储路径规则中不含变量的部分对应的物理目录，
+// 目录尚未创建时向上查找已存在的目录
+func StorageRoot(dirNameRule string) string {
+	if i := strings.Index(dirNameRule, "{"); i >= 0 {
+		dirNameRule = dirNameRule[:i]
+	}
+
+	root := util.RelativePath(filepath.FromSlash(dirNameRule))
+	for {
+		if _, err := os.Stat(root); err == nil {
+			return root
+		}
+
+		parent := filepath.Dir(root)
+		if parent == root {
+			return root
+		}
+		root = parent
+	}
+}
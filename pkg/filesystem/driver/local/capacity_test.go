@@ -0,0 +1,39 @@
+package local
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageRoot(t *testing.T) {
+	asserts := assert.New(t)
+	base := util.RelativePath("")
+
+	// 目录不存在时向上查找
+	asserts.Equal(base, StorageRoot("uploads/{uid}/{path}"))
+	asserts.Equal(base, StorageRoot("{uid}/{path}"))
+
+	// 目录已存在
+	asserts.NoError(os.MkdirAll(util.RelativePath("TestStorageRoot"), Perm))
+	defer os.RemoveAll(util.RelativePath("TestStorageRoot"))
+	asserts.Equal(filepath.Join(base, "TestStorageRoot"), StorageRoot("TestStorageRoot/{uid}"))
+
+	// 绝对路径
+	asserts.Equal(os.TempDir(), StorageRoot(filepath.ToSlash(os.TempDir())))
+}
+
+func TestHandler_Capacity(t *testing.T) {
+	asserts := assert.New(t)
+	handler := Driver{Policy: &model.Policy{DirNameRule: "uploads/{uid}/{path}"}}
+
+	res, err := handler.Capacity(context.Background())
+	asserts.NoError(err)
+	asserts.NotZero(res.Total)
+	asserts.True(res.Free <= res.Total)
+}
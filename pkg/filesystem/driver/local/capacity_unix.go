@@ -0,0 +1,22 @@
+// +build !windows
+
+package local
+
+import (
+	"syscall"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+)
+
+// DiskCapacity 获取给定目录所在磁盘的总容量及非特权用户可用的剩余容量
+func DiskCapacity(path string) (*driver.Capacity, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return nil, err
+	}
+
+	return &driver.Capacity{
+		Total: uint64(stat.Blocks) * uint64(stat.Bsize),
+		Free:  uint64(stat.Bavail) * uint64(stat.Bsize),
+	}, nil
+}
@@ -0,0 +1,33 @@
+// +build windows
+
+package local
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+)
+
+var procGetDiskFreeSpaceEx = syscall.NewLazyDLL("kernel32.dll").NewProc("GetDiskFreeSpaceExW")
+
+// DiskCapacity 获取给定目录所在磁盘的总容量及当前用户可用的剩余容量
+func DiskCapacity(path string) (*driver.Capacity, error) {
+	pathPtr, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var free, total, totalFree uint64
+	ret, _, err := procGetDiskFreeSpaceEx.Call(
+		uintptr(unsafe.Pointer(pathPtr)),
+		uintptr(unsafe.Pointer(&free)),
+		uintptr(unsafe.Pointer(&total)),
+		uintptr(unsafe.Pointer(&totalFree)),
+	)
+	if ret == 0 {
+		return nil, err
+	}
+
+	return &driver.Capacity{Total: total, Free: free}, nil
+}
@@ -117,7 +117,7 @@ func (handler Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
 	openMode := os.O_CREATE | os.O_RDWR
 	if fileInfo.Mode&fsctx.Append == fsctx.Append {
 		openMode |= os.O_APPEND
-	} else {
+	} else if fileInfo.Mode&fsctx.Offset != fsctx.Offset {
 		openMode |= os.O_TRUNC
 	}
 
@@ -152,6 +152,14 @@ func (handler Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
 		}
 	}
 
+	// 并行上传的分片写入各自的位置，其余分片可能已先行写入
+	if fileInfo.Mode&fsctx.Offset == fsctx.Offset {
+		if _, err := out.Seek(int64(fileInfo.AppendStart), io.SeekStart); err != nil {
+			util.Log().Warning("无法定位分片写入位置，%s", err)
+			return err
+		}
+	}
+
 	// 写入文件内容
 	_, err = io.Copy(out, file)
 	return err
@@ -71,6 +71,35 @@ func TestHandler_Put(t *testing.T) {
 	}
 }
 
+func TestHandler_PutOffset(t *testing.T) {
+	asserts := assert.New(t)
+	handler := Driver{}
+	dst := util.RelativePath("TestHandler_PutOffset.txt")
+	defer os.Remove(dst)
+
+	// 分片乱序写入
+	chunks := []struct {
+		start   uint64
+		content string
+	}{
+		{3, "456"},
+		{6, "78"},
+		{0, "123"},
+	}
+	for _, chunk := range chunks {
+		asserts.NoError(handler.Put(context.Background(), &fsctx.FileStream{
+			AppendStart: chunk.start,
+			Mode:        fsctx.Offset | fsctx.Overwrite,
+			SavePath:    "TestHandler_PutOffset.txt",
+			File:        io.NopCloser(strings.NewReader(chunk.content)),
+		}))
+	}
+
+	content, err := os.ReadFile(dst)
+	asserts.NoError(err)
+	asserts.Equal("12345678", string(content))
+}
+
 func TestDriver_TruncateFailed(t *testing.T) {
 	a := assert.New(t)
 	h := Driver{}
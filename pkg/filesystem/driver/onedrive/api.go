@@ -142,6 +142,25 @@ func (client *Client) Meta(ctx context.Context, id string, path string) (*FileIn
 
 }
 
+// GetDriveQuota 获取驱动器的容量信息
+func (client *Client) GetDriveQuota(ctx context.Context) (*DriveQuota, error) {
+	res, err := client.requestWithStr(ctx, "GET", client.getRequestURL("")+"?$select=id,quota", "", 200)
+	if err != nil {
+		return nil, err
+	}
+
+	var drive Drive
+	if decodeErr := json.Unmarshal([]byte(res), &drive); decodeErr != nil {
+		return nil, decodeErr
+	}
+
+	if drive.Quota == nil {
+		return nil, ErrQuotaUnavailable
+	}
+
+	return drive.Quota, nil
+}
+
 // CreateUploadSession 创建分片上传会话
 func (client *Client) CreateUploadSession(ctx context.Context, dst string, opts ...Option) (string, error) {
 	options := newDefaultOption()
@@ -249,6 +249,62 @@ func TestClient_GetSiteIDByURL(t *testing.T) {
 	}
 }
 
+func TestClient_GetDriveQuota(t *testing.T) {
+	asserts := assert.New(t)
+	client, _ := NewClient(&model.Policy{})
+	client.Credential.AccessToken = "AccessToken"
+	client.Credential.ExpiresIn = time.Now().Add(time.Duration(100) * time.Hour).Unix()
+	client.Endpoints.EndpointURL = "https://graph.microsoft.com/v1.0"
+
+	// 未返回容量信息
+	{
+		clientMock := ClientMock{}
+		clientMock.On(
+			"Request",
+			"GET",
+			testMock.Anything,
+			testMock.Anything,
+			testMock.Anything,
+		).Return(&request.Response{
+			Err: nil,
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"id":"123"}`)),
+			},
+		})
+		client.Request = clientMock
+		res, err := client.GetDriveQuota(context.Background())
+		clientMock.AssertExpectations(t)
+		asserts.Equal(ErrQuotaUnavailable, err)
+		asserts.Nil(res)
+	}
+
+	// 返回正常
+	{
+		clientMock := ClientMock{}
+		clientMock.On(
+			"Request",
+			"GET",
+			"https://graph.microsoft.com/v1.0/me/drive?$select=id,quota",
+			testMock.Anything,
+			testMock.Anything,
+		).Return(&request.Response{
+			Err: nil,
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"id":"123","quota":{"total":100,"used":30,"remaining":70,"state":"normal"}}`)),
+			},
+		})
+		client.Request = clientMock
+		handler := Driver{Client: client}
+		res, err := handler.Capacity(context.Background())
+		clientMock.AssertExpectations(t)
+		asserts.NoError(err)
+		asserts.EqualValues(100, res.Total)
+		asserts.EqualValues(70, res.Free)
+	}
+}
+
 func TestClient_Meta(t *testing.T) {
 	asserts := assert.New(t)
 	client, _ := NewClient(&model.Policy{})
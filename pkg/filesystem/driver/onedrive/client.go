@@ -17,6 +17,8 @@ var (
 	ErrDeleteFile = errors.New("无法删除文件")
 	// ErrClientCanceled 客户端取消操作
 	ErrClientCanceled = errors.New("客户端取消操作")
+	// ErrQuotaUnavailable 驱动器未返回容量信息
+	ErrQuotaUnavailable = errors.New("驱动器未返回容量信息")
 )
 
 // Client OneDrive客户端
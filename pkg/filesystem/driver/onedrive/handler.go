@@ -88,33 +88,25 @@ func (handler Driver) List(ctx context.Context, base string, recursive bool) ([]
 
 // Get 获取文件
 func (handler Driver) Get(ctx context.Context, path string) (response.RSCloser, error) {
-	// 获取文件源地址
-	downloadURL, err := handler.Source(
-		ctx,
-		path,
-		url.URL{},
-		60,
-		false,
-		0,
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取文件数据流
-	resp, err := handler.HTTPClient.Request(
-		"GET",
-		downloadURL,
-		nil,
+	resp, err := request.GetSourceRSCloser(
+		handler.HTTPClient,
+		func() (string, error) {
+			return handler.Source(
+				ctx,
+				path,
+				url.URL{},
+				60,
+				false,
+				0,
+			)
+		},
 		request.WithContext(ctx),
-		request.WithTimeout(time.Duration(0)),
-	).CheckHTTPResponse(200).GetRSCloser()
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	resp.SetFirstFakeChunk()
-
 	// 尝试自主获取文件大小
 	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
 		resp.SetContentLength(int64(file.Size))
@@ -250,3 +242,13 @@ func (handler Driver) Token(ctx context.Context, ttl int64, uploadSession *seria
 func (handler Driver) CancelToken(ctx context.Context, uploadSession *serializer.UploadSession) error {
 	return handler.Client.DeleteUploadSession(ctx, uploadSession.UploadURL)
 }
+
+// Capacity 获取驱动器的总容量及剩余容量
+func (handler Driver) Capacity(ctx context.Context) (*driver.Capacity, error) {
+	quota, err := handler.Client.GetDriveQuota(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &driver.Capacity{Total: quota.Total, Free: quota.Remaining}, nil
+}
@@ -92,6 +92,20 @@ type ThumbResponse struct {
 	URL   string                   `json:"url"`
 }
 
+// Drive 驱动器信息
+type Drive struct {
+	ID    string      `json:"id"`
+	Quota *DriveQuota `json:"quota"`
+}
+
+// DriveQuota 驱动器容量
+type DriveQuota struct {
+	Total     uint64 `json:"total"`
+	Used      uint64 `json:"used"`
+	Remaining uint64 `json:"remaining"`
+	State     string `json:"state"`
+}
+
 // ListResponse 列取子项目响应
 type ListResponse struct {
 	Value   []FileInfo `json:"value"`
@@ -192,33 +192,25 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 	// 尽可能使用私有 Endpoint
 	ctx = context.WithValue(ctx, fsctx.ForceUsePublicEndpointCtx, false)
 
-	// 获取文件源地址
-	downloadURL, err := handler.Source(
-		ctx,
-		path,
-		url.URL{},
-		int64(model.GetIntSetting("preview_timeout", 60)),
-		false,
-		0,
-	)
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取文件数据流
-	resp, err := handler.HTTPClient.Request(
-		"GET",
-		downloadURL,
-		nil,
+	resp, err := request.GetSourceRSCloser(
+		handler.HTTPClient,
+		func() (string, error) {
+			return handler.Source(
+				ctx,
+				path,
+				url.URL{},
+				int64(model.GetIntSetting("preview_timeout", 60)),
+				false,
+				0,
+			)
+		},
 		request.WithContext(ctx),
-		request.WithTimeout(time.Duration(0)),
-	).CheckHTTPResponse(200).GetRSCloser()
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	resp.SetFirstFakeChunk()
-
 	// 尝试自主获取文件大小
 	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
 		resp.SetContentLength(int64(file.Size))
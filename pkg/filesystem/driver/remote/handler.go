@@ -9,10 +9,10 @@ import (
 	"net/url"
 	"path"
 	"strings"
-	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
@@ -86,6 +86,46 @@ func (handler *Driver) List(ctx context.Context, path string, recursive bool) ([
 	return res, nil
 }
 
+// Capacity 获取从机存储目录所在磁盘的总容量及剩余容量
+func (handler *Driver) Capacity(ctx context.Context) (*driver.Capacity, error) {
+	reqBodyEncoded, err := json.Marshal(serializer.CapacityRequest{
+		DirNameRule: handler.Policy.DirNameRule,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bodyReader := strings.NewReader(string(reqBodyEncoded))
+	signTTL := model.GetIntSetting("slave_api_timeout", 60)
+	resp, err := handler.Client.Request(
+		"POST",
+		handler.getAPIUrl("capacity"),
+		bodyReader,
+		request.WithContext(ctx),
+		request.WithCredential(handler.AuthInstance, int64(signTTL)),
+		request.WithMasterMeta(),
+	).CheckHTTPResponse(200).DecodeResponse()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Code != 0 {
+		return nil, errors.New(resp.Error)
+	}
+
+	var res driver.Capacity
+	resStr, ok := resp.Data.(string)
+	if !ok {
+		return nil, errors.New("未知的返回结果格式")
+	}
+
+	if err := json.Unmarshal([]byte(resStr), &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
 // getAPIUrl 获取接口请求地址
 func (handler *Driver) getAPIUrl(scope string, routes ...string) string {
 	serverURL, err := url.Parse(handler.Policy.Server)
@@ -101,6 +141,8 @@ func (handler *Driver) getAPIUrl(scope string, routes ...string) string {
 		controller, _ = url.Parse("/api/v3/slave/thumb")
 	case "list":
 		controller, _ = url.Parse("/api/v3/slave/list")
+	case "capacity":
+		controller, _ = url.Parse("/api/v3/slave/capacity")
 	default:
 		controller = serverURL
 	}
@@ -120,27 +162,19 @@ func (handler *Driver) Get(ctx context.Context, path string) (response.RSCloser,
 		speedLimit = user.Group.SpeedLimit
 	}
 
-	// 获取文件源地址
-	downloadURL, err := handler.Source(ctx, path, url.URL{}, 0, true, speedLimit)
-	if err != nil {
-		return nil, err
-	}
-
 	// 获取文件数据流
-	resp, err := handler.Client.Request(
-		"GET",
-		downloadURL,
-		nil,
+	resp, err := request.GetSourceRSCloser(
+		handler.Client,
+		func() (string, error) {
+			return handler.Source(ctx, path, url.URL{}, 0, true, speedLimit)
+		},
 		request.WithContext(ctx),
-		request.WithTimeout(time.Duration(0)),
 		request.WithMasterMeta(),
-	).CheckHTTPResponse(200).GetRSCloser()
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	resp.SetFirstFakeChunk()
-
 	// 尝试获取文件大小
 	if file, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
 		resp.SetContentLength(int64(file.Size))
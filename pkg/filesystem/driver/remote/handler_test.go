@@ -209,6 +209,67 @@ func TestHandler_Delete(t *testing.T) {
 	}
 }
 
+func TestDriver_Capacity(t *testing.T) {
+	asserts := assert.New(t)
+	handler := Driver{
+		Policy: &model.Policy{
+			SecretKey:   "test",
+			Server:      "http://test.com",
+			DirNameRule: "uploads/{uid}",
+		},
+		AuthInstance: auth.HMACAuth{},
+	}
+	ctx := context.Background()
+	cache.Set("setting_slave_api_timeout", "60", 0)
+
+	// 成功
+	{
+		clientMock := ClientMock{}
+		clientMock.On(
+			"Request",
+			"POST",
+			"http://test.com/api/v3/slave/capacity",
+			testMock.Anything,
+			testMock.Anything,
+		).Return(&request.Response{
+			Err: nil,
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":0,"data":"{\"total\":100,\"free\":30}"}`)),
+			},
+		})
+		handler.Client = clientMock
+		res, err := handler.Capacity(ctx)
+		clientMock.AssertExpectations(t)
+		asserts.NoError(err)
+		asserts.EqualValues(100, res.Total)
+		asserts.EqualValues(30, res.Free)
+	}
+
+	// 从机返回错误
+	{
+		clientMock := ClientMock{}
+		clientMock.On(
+			"Request",
+			"POST",
+			"http://test.com/api/v3/slave/capacity",
+			testMock.Anything,
+			testMock.Anything,
+		).Return(&request.Response{
+			Err: nil,
+			Response: &http.Response{
+				StatusCode: 200,
+				Body:       ioutil.NopCloser(strings.NewReader(`{"code":203}`)),
+			},
+		})
+		handler.Client = clientMock
+		res, err := handler.Capacity(ctx)
+		clientMock.AssertExpectations(t)
+		asserts.Error(err)
+		asserts.Nil(res)
+	}
+}
+
 func TestDriver_List(t *testing.T) {
 	asserts := assert.New(t)
 	handler := Driver{
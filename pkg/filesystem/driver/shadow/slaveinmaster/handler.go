@@ -53,10 +53,12 @@ func (d *Driver) Put(ctx context.Context, file fsctx.FileHeader) error {
 	defer file.Close()
 
 	fileInfo := file.Info()
+	ioLimit, _ := ctx.Value(fsctx.IOLimitCtx).(int64)
 	req := serializer.SlaveTransferReq{
-		Src:    fileInfo.Src,
-		Dst:    fileInfo.SavePath,
-		Policy: d.policy,
+		Src:     fileInfo.Src,
+		Dst:     fileInfo.SavePath,
+		Policy:  d.policy,
+		IOLimit: ioLimit,
 	}
 
 	body, err := json.Marshal(req)
@@ -11,6 +11,7 @@ var (
 	ErrFileSizeTooBig           = serializer.NewError(serializer.CodeFileTooLarge, "", nil)
 	ErrFileExtensionNotAllowed  = serializer.NewError(serializer.CodeFileTypeNotAllowed, "", nil)
 	ErrInsufficientCapacity     = serializer.NewError(serializer.CodeInsufficientCapacity, "", nil)
+	ErrUploadTrafficExceeded    = serializer.NewError(serializer.CodeUploadTrafficExceeded, "Daily upload traffic limit exceeded", nil)
 	ErrIllegalObjectName        = serializer.NewError(serializer.CodeIllegalObjectName, "", nil)
 	ErrClientCanceled           = errors.New("Client canceled operation")
 	ErrRootProtected            = serializer.NewError(serializer.CodeRootProtected, "", nil)
@@ -23,4 +24,10 @@ var (
 	ErrDBListObjects            = serializer.NewError(serializer.CodeDBError, "Failed to list object records", nil)
 	ErrDBDeleteObjects          = serializer.NewError(serializer.CodeDBError, "Failed to delete object records", nil)
 	ErrOneObjectOnly            = serializer.ParamErr("You can only copy one object at the same time", nil)
+	ErrSharePermissionDenied    = serializer.NewError(serializer.CodeNoPermissionErr, "Share role does not allow this operation", nil)
+	ErrFileQuarantined          = serializer.NewError(serializer.CodeFileQuarantined, "File is quarantined because a virus was detected", nil)
+	ErrFileBlocked              = serializer.NewError(serializer.CodeFileBlocked, "File content is not allowed", nil)
+	ErrPolicyCapacityExhausted  = serializer.NewError(serializer.CodePolicyCapacityExhausted, "Storage policy is running out of space", nil)
+	ErrLocked                   = serializer.NewError(serializer.CodeLocked, "Resource is locked by a WebDAV client", nil)
+	ErrThumbQueueFull           = serializer.NewError(serializer.CodeRateLimited, "Too many thumbnails are being generated, please try again later", nil)
 )
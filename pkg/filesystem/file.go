@@ -10,9 +10,13 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/juju/ratelimit"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 /* ============
@@ -30,6 +34,24 @@ func (r lrs) Read(p []byte) (int, error) {
 	return r.r.Read(p)
 }
 
+// 统计下载流量的ReaderSeeker
+type trafficRSC struct {
+	response.RSCloser
+}
+
+func (r trafficRSC) Read(p []byte) (int, error) {
+	n, err := r.RSCloser.Read(p)
+	stats.AddDownload(uint64(n))
+	return n, err
+}
+
+// SendFile 底层为本地文件时交由 dst 直接发送，同样计入下载流量
+func (r trafficRSC) SendFile(dst io.ReaderFrom, n int64) (int64, error) {
+	written, err := response.SendFile(dst, r.RSCloser, n)
+	stats.AddDownload(uint64(written))
+	return written, err
+}
+
 // withSpeedLimit 给原有的ReadSeeker加上限速
 func (fs *FileSystem) withSpeedLimit(rs response.RSCloser) response.RSCloser {
 	// 如果用户组有速度限制，就返回限制流速的ReaderSeeker
@@ -78,6 +100,8 @@ func (fs *FileSystem) AddFile(ctx context.Context, parent *model.Folder, file fs
 	}
 
 	fs.User.Storage += newFile.Size
+	stats.AddUpload(newFile.Size)
+	notify.QuotaWarning(fs.User, fs.User.Storage-newFile.Size)
 	return &newFile, nil
 }
 
@@ -146,8 +170,8 @@ func (fs *FileSystem) GetDownloadContent(ctx context.Context, id uint) (response
 		return nil, err
 	}
 
-	// 返回限速处理后的文件流
-	return fs.withSpeedLimit(rs), nil
+	// 返回限速处理后的文件流，读取的字节计入下载流量
+	return fs.withSpeedLimit(trafficRSC{rs}), nil
 
 }
 
@@ -160,7 +184,9 @@ func (fs *FileSystem) GetContent(ctx context.Context, id uint) (response.RSClose
 	ctx = context.WithValue(ctx, fsctx.FileModelCtx, fs.FileTarget[0])
 
 	// 获取文件流
-	rs, err := fs.Handler.Get(ctx, fs.FileTarget[0].SourceName)
+	getCtx, span := fs.traceDriver(ctx, "get")
+	rs, err := fs.Handler.Get(getCtx, fs.FileTarget[0].SourceName)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, ErrIO.WithError(err)
 	}
@@ -210,8 +236,14 @@ func (fs *FileSystem) deleteGroupedFile(ctx context.Context, files map[uint][]*m
 		}
 
 		// 执行删除
-		failedFile, _ := fs.Handler.Delete(ctx, sourceNamesAll)
+		deleteCtx, span := fs.traceDriver(ctx, "delete")
+		span.SetAttributes(attribute.Int("file.count", len(sourceNamesAll)))
+		failedFile, err := fs.Handler.Delete(deleteCtx, sourceNamesAll)
+		tracing.End(span, err)
 		failed[policyID] = failedFile
+
+		// 删除保存在独立存储策略中的缩略图
+		deleteStoredThumbs(ctx, toBeDeletedFiles)
 	}
 
 	return failed
@@ -255,6 +287,11 @@ func (fs *FileSystem) GetDownloadURL(ctx context.Context, id uint, timeout strin
 		return "", err
 	}
 
+	// 本机存储的文件经由 GetDownloadContent 中转，其余存储策略按文件大小计入下载流量
+	if fs.Policy.Type != "local" {
+		stats.AddDownload(fileTarget.Size)
+	}
+
 	return source, nil
 }
 
@@ -296,7 +333,9 @@ func (fs *FileSystem) SignURL(ctx context.Context, file *model.File, ttl int64,
 	// 签名最终URL
 	// 生成外链地址
 	siteURL := model.GetSiteURL()
-	source, err := fs.Handler.Source(ctx, fs.FileTarget[0].SourceName, *siteURL, ttl, isDownload, fs.User.Group.SpeedLimit)
+	sourceCtx, span := fs.traceDriver(ctx, "source")
+	source, err := fs.Handler.Source(sourceCtx, fs.FileTarget[0].SourceName, *siteURL, ttl, isDownload, fs.User.Group.SpeedLimit)
+	tracing.End(span, err)
 	if err != nil {
 		return "", serializer.NewError(serializer.CodeNotSet, "无法获取外链", err)
 	}
@@ -337,6 +376,11 @@ func (fs *FileSystem) resetFileIDIfNotExist(ctx context.Context, id uint) error
 		}
 	}
 
+	// 已隔离的文件不可读取
+	if fs.FileTarget[0].IsQuarantined() {
+		return ErrFileQuarantined
+	}
+
 	// 将当前存储策略重设为文件使用的
 	return fs.resetPolicyToFirstFile(ctx)
 }
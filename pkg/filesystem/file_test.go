@@ -11,6 +11,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/jinzhu/gorm"
@@ -51,6 +52,10 @@ func TestFileSystem_AddFile(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	// 更新所在目录统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	mock.ExpectExec("UPDATE(.+)folders(.+)file_num(.+)size").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	f, err := fs.AddFile(context.Background(), &folder, &file)
@@ -171,10 +176,12 @@ func TestFileSystem_GetDownloadContent(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policy_id", "source_name"}).AddRow(1, "TestFileSystem_GetDownloadContent.txt", 599, "TestFileSystem_GetDownloadContent.txt"))
 	mock.ExpectQuery("SELECT(.+)poli(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(1, "local"))
 
-	// 无限速
+	// 无限速，可使用 sendfile 发送
 	cache.Deletes([]string{"599"}, "policy_")
-	_, err = fs.GetDownloadContent(ctx, 1)
+	rs, err := fs.GetDownloadContent(ctx, 1)
 	asserts.NoError(err)
+	asserts.Implements((*response.SendFiler)(nil), rs)
+	rs.Close()
 	asserts.NoError(mock.ExpectationsWereMet())
 	fs.CleanTargets()
 
@@ -184,8 +191,11 @@ func TestFileSystem_GetDownloadContent(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)poli(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "type"}).AddRow(1, "local"))
 
 	fs.User.Group.SpeedLimit = 1
-	_, err = fs.GetDownloadContent(ctx, 1)
+	rs, err = fs.GetDownloadContent(ctx, 1)
 	asserts.NoError(err)
+	_, ok := rs.(response.SendFiler)
+	asserts.False(ok)
+	rs.Close()
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
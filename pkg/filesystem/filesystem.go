@@ -1,6 +1,7 @@
 package filesystem
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -19,8 +20,11 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/upyun"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/gin-gonic/gin"
 	cossdk "github.com/tencentyun/cos-go-sdk-v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"net/url"
 	"sync"
@@ -57,9 +61,6 @@ type FileSystem struct {
 	   文件系统处理适配器
 	*/
 	Handler driver.Handler
-
-	// 回收锁
-	recycleLock sync.Mutex
 }
 
 // getEmptyFS 从pool中获取新的FileSystem
@@ -70,7 +71,6 @@ func getEmptyFS() *FileSystem {
 
 // Recycle 回收FileSystem资源
 func (fs *FileSystem) Recycle() {
-	fs.recycleLock.Lock()
 	fs.reset()
 	FSPool.Put(fs)
 }
@@ -84,7 +84,6 @@ func (fs *FileSystem) reset() {
 	fs.Handler = nil
 	fs.Root = nil
 	fs.Lock = sync.Mutex{}
-	fs.recycleLock = sync.Mutex{}
 }
 
 // NewFileSystem 初始化一个文件系统
@@ -183,6 +182,19 @@ func (fs *FileSystem) DispatchHandler() error {
 	return nil
 }
 
+// traceDriver 开始记录调用存储策略适配器的 Span
+func (fs *FileSystem) traceDriver(ctx context.Context, op string) (context.Context, trace.Span) {
+	attrs := []attribute.KeyValue{attribute.String("driver.op", op)}
+	if fs.Policy != nil {
+		attrs = append(attrs,
+			attribute.Int64("policy.id", int64(fs.Policy.ID)),
+			attribute.String("policy.type", fs.Policy.Type),
+		)
+	}
+
+	return tracing.Start(ctx, "driver."+op, trace.WithAttributes(attrs...))
+}
+
 // NewFileSystemFromContext 从gin.Context创建文件系统
 func NewFileSystemFromContext(c *gin.Context) (*FileSystem, error) {
 	user, exist := c.Get("user")
@@ -190,7 +202,79 @@ func NewFileSystemFromContext(c *gin.Context) (*FileSystem, error) {
 		return NewAnonymousFileSystem()
 	}
 	fs, err := NewFileSystem(user.(*model.User))
-	return fs, err
+	if err != nil {
+		return fs, err
+	}
+
+	// 个人访问令牌限定了目录或为子账户时，重定根目录
+	folder := ""
+	if token, ok := c.Get(APITokenCtx); ok {
+		folder = token.(*model.APIToken).Folder
+	}
+	if sub := user.(*model.User).SubAccount; sub != nil {
+		folder = sub.Folder
+	}
+
+	if folder != "" {
+		if err := fs.chroot(folder); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+// NewFileSystemForWebDAV 按照 WebDAV 账户初始化文件系统。挂载分享时使用分享者的文件系统，
+// 以分享目录为根，子账户以限定目录为根；之后再以账户设定的目录为根，目录不存在时返回 ErrPathNotExist
+func NewFileSystemForWebDAV(user *model.User, account *model.Webdav) (*FileSystem, error) {
+	var (
+		fs  *FileSystem
+		err error
+	)
+
+	if account.ShareID != 0 {
+		share, err := account.MountedShare(user)
+		if err != nil {
+			return nil, ErrSharePermissionDenied
+		}
+
+		if fs, err = NewFileSystem(share.Creator()); err != nil {
+			return nil, err
+		}
+		fs.Root = share.SourceFolder()
+		fs.Root.Position = ""
+		fs.Root.Name = "/"
+	} else {
+		if fs, err = NewFileSystem(user); err != nil {
+			return nil, err
+		}
+		if sub := user.SubAccount; sub != nil {
+			if err := fs.chroot(sub.Folder); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if account.Root != "/" {
+		if err := fs.chroot(account.Root); err != nil {
+			return nil, err
+		}
+	}
+
+	return fs, nil
+}
+
+// chroot 以给定目录为根目录，目录不存在时回收文件系统并返回 ErrPathNotExist
+func (fs *FileSystem) chroot(folder string) error {
+	exist, root := fs.IsPathExist(folder)
+	if !exist {
+		fs.Recycle()
+		return ErrPathNotExist
+	}
+	root.Position = ""
+	root.Name = "/"
+	fs.Root = root
+	return nil
 }
 
 // NewFileSystemFromCallback 从gin.Context创建回调用文件系统
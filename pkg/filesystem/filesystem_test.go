@@ -13,6 +13,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/remote"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 
 	"testing"
@@ -61,6 +62,51 @@ func TestNewFileSystemFromContext(t *testing.T) {
 	asserts.Error(err)
 }
 
+func TestNewFileSystemForWebDAV(t *testing.T) {
+	asserts := assert.New(t)
+	user := &model.User{
+		Model:  gorm.Model{ID: 1},
+		Policy: model.Policy{Type: "local"},
+	}
+
+	// 以用户根目录为根
+	{
+		fs, err := NewFileSystemForWebDAV(user, &model.Webdav{Root: "/"})
+		asserts.NoError(err)
+		asserts.Nil(fs.Root)
+	}
+
+	// 重定根目录
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").WithArgs(1, 1, "docs").WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "name"}).AddRow(2, 1, "docs"))
+		fs, err := NewFileSystemForWebDAV(user, &model.Webdav{Root: "/docs"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal(uint(2), fs.Root.ID)
+		asserts.Equal("/", fs.Root.Name)
+	}
+
+	// 根目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)").WithArgs(1, 1, "docs").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		fs, err := NewFileSystemForWebDAV(user, &model.Webdav{Root: "/docs"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrPathNotExist, err)
+		asserts.Nil(fs)
+	}
+
+	// 挂载的分享已失效
+	{
+		mock.ExpectQuery("SELECT(.+)shares(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		fs, err := NewFileSystemForWebDAV(user, &model.Webdav{Root: "/", ShareID: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrSharePermissionDenied, err)
+		asserts.Nil(fs)
+	}
+}
+
 func TestDispatchHandler(t *testing.T) {
 	asserts := assert.New(t)
 	fs := &FileSystem{
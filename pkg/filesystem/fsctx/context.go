@@ -35,4 +35,12 @@ const (
 	CancelFuncCtx
 	// 文件在从机节点中的路径
 	SlaveSrcPath
+	// ShareRoleCtx 分享接收者的角色权限，设置后文件系统操作需具备对应权限
+	ShareRoleCtx
+	// ChunkConcurrencyCtx 客户端请求同时上传的分片数
+	ChunkConcurrencyCtx
+	// IOLimitCtx 后台任务读写文件的速率上限，字节/秒
+	IOLimitCtx
+	// IgnoreLocksCtx 跳过 WebDAV 锁检查，用于已确认持有锁的 WebDAV 请求及管理员、系统发起的操作
+	IgnoreLocksCtx
 )
@@ -13,6 +13,8 @@ const (
 	// Append 只适用于本地策略
 	Append WriteMode = 0x00002
 	Nop    WriteMode = 0x00004
+	// Offset 从 AppendStart 处写入，保留文件其余部分，用于并行上传分片，只适用于本地策略
+	Offset WriteMode = 0x00008
 )
 
 type UploadTaskInfo struct {
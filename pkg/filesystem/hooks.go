@@ -95,6 +95,35 @@ func HookValidateCapacity(ctx context.Context, fs *FileSystem, file fsctx.FileHe
 	return nil
 }
 
+// HookValidatePolicyCapacity 验证存储策略是否因容量不足暂停接收新上传
+func HookValidatePolicyCapacity(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+	if fs.Policy != nil && model.IsPolicyUploadSuspended(fs.Policy.ID) {
+		return ErrPolicyCapacityExhausted
+	}
+	return nil
+}
+
+// HookValidateUploadTraffic 验证用户组每日上传流量限制
+func HookValidateUploadTraffic(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+	limit := fs.User.Group.OptionsSerialized.DailyUpload
+	if limit > 0 && fs.User.UploadedToday()+file.Info().Size > limit {
+		return ErrUploadTrafficExceeded
+	}
+	return nil
+}
+
+// HookRecordUploadTraffic 用户组限制了每日上传流量时，记录本次上传的流量
+func HookRecordUploadTraffic(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+	if fs.User.Group.OptionsSerialized.DailyUpload == 0 {
+		return nil
+	}
+
+	if err := fs.User.AddUploadTraffic(file.Info().Size); err != nil {
+		util.Log().Warning("无法记录用户 [%d] 的上传流量，%s", fs.User.ID, err)
+	}
+	return nil
+}
+
 // HookValidateCapacityDiff 根据原有文件和新文件的大小验证用户容量
 func HookValidateCapacityDiff(ctx context.Context, fs *FileSystem, newFile fsctx.FileHeader) error {
 	originFile := ctx.Value(fsctx.FileModelCtx).(model.File)
@@ -233,15 +262,29 @@ func GenericAfterUpload(ctx context.Context, fs *FileSystem, fileHeader fsctx.Fi
 
 // HookGenerateThumb 生成缩略图
 func HookGenerateThumb(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
-	// 异步尝试生成缩略图
 	fileMode := fileHeader.Info().Model.(*model.File)
 	if fs.Policy.IsThumbGenerateNeeded() {
-		fs.recycleLock.Lock()
-		go func() {
-			defer fs.recycleLock.Unlock()
-			_, _ = fs.Handler.Delete(ctx, []string{fileMode.SourceName + model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")})
-			fs.GenerateThumbnail(ctx, fileMode)
-		}()
+		_, _ = fs.Handler.Delete(ctx, []string{fileMode.SourceName + model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")})
+
+		// 交由缩略图任务池异步生成，队列已满时在首次获取缩略图时再生成
+		if IsInExtensionList(HandledExtension, fileMode.Name) {
+			if _, err := fs.submitThumbnail(fileMode); err != nil {
+				util.Log().Debug("无法为 [%s] 生成缩略图：%s", fileMode.SourceName, err)
+			}
+		}
+	}
+	return nil
+}
+
+// HookSubmitModeration 上传完成后提交媒体文件的内容审核
+func HookSubmitModeration(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	if !model.IsModerationEnabled("upload") {
+		return nil
+	}
+
+	file := fileHeader.Info().Model.(*model.File)
+	if err := model.SubmitModeration(file, 0); err != nil {
+		util.Log().Warning("无法提交文件 [%s] 的内容审核, %s", file.Name, err)
 	}
 	return nil
 }
@@ -296,6 +339,7 @@ func HookPopPlaceholderToFile(picInfo string) Hook {
 func HookDeleteUploadSession(id string) Hook {
 	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
 		cache.Deletes([]string{id}, UploadSessionCachePrefix)
+		cache.Deletes([]string{id}, UploadChunksCachePrefix)
 		return nil
 	}
 }
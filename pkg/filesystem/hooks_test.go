@@ -15,6 +15,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/jinzhu/gorm"
@@ -116,6 +117,7 @@ func TestGenericAfterUpload(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)files").
 		WithArgs(1, "我的文件").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 1
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("我的文件", 1, 1).
@@ -124,6 +126,10 @@ func TestGenericAfterUpload(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	// 更新所在目录统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1).AddRow(1, nil))
+	mock.ExpectExec("UPDATE(.+)folders(.+)file_num").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	err := GenericAfterUpload(ctx, &fs, file)
@@ -137,6 +143,7 @@ func TestGenericAfterUpload(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)files").
 		WithArgs(1, "我的文件").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 1
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("我的文件", 1, 1).
@@ -155,6 +162,7 @@ func TestGenericAfterUpload(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)files").
 		WithArgs(1, "我的文件").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 1
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("我的文件", 1, 1).
@@ -173,6 +181,7 @@ func TestGenericAfterUpload(t *testing.T) {
 	mock.ExpectQuery("SELECT(.+)files").
 		WithArgs(1, "我的文件").
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 1
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("我的文件", 1, 1).
@@ -281,6 +290,62 @@ func TestHookValidateCapacity(t *testing.T) {
 	}
 }
 
+func TestHookValidatePolicyCapacity(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{Policy: &model.Policy{Model: gorm.Model{ID: 10}}}
+	ctx := context.Background()
+	file := &fsctx.FileStream{Size: 1}
+
+	asserts.NoError(HookValidatePolicyCapacity(ctx, fs, file))
+
+	model.SetPolicyUploadSuspended(10, true)
+	defer model.SetPolicyUploadSuspended(10, false)
+	asserts.Equal(ErrPolicyCapacityExhausted, HookValidatePolicyCapacity(ctx, fs, file))
+}
+
+func TestHookValidateUploadTraffic(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{
+		Model:             gorm.Model{ID: 1},
+		UploadTraffic:     5,
+		UploadTrafficDate: time.Now().Format("2006-01-02"),
+	}}
+	ctx := context.Background()
+	file := &fsctx.FileStream{Size: 6}
+
+	// 未限制
+	asserts.NoError(HookValidateUploadTraffic(ctx, fs, file))
+
+	fs.User.Group.OptionsSerialized.DailyUpload = 10
+	asserts.Equal(ErrUploadTrafficExceeded, HookValidateUploadTraffic(ctx, fs, file))
+	file.Size = 5
+	asserts.NoError(HookValidateUploadTraffic(ctx, fs, file))
+
+	// 跨日后重新统计
+	fs.User.UploadTrafficDate = "2000-01-01"
+	file.Size = 10
+	asserts.NoError(HookValidateUploadTraffic(ctx, fs, file))
+}
+
+func TestHookRecordUploadTraffic(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	ctx := context.Background()
+	file := &fsctx.FileStream{Size: 6}
+
+	// 未限制时不记录
+	asserts.NoError(HookRecordUploadTraffic(ctx, fs, file))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	fs.User.Group.OptionsSerialized.DailyUpload = 10
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)upload_traffic(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	asserts.NoError(HookRecordUploadTraffic(ctx, fs, file))
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.EqualValues(6, fs.User.UploadedToday())
+}
+
 func TestHookValidateCapacityDiff(t *testing.T) {
 	a := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -3,15 +3,15 @@ package filesystem
 import (
 	"context"
 	"fmt"
-	"sync"
-
 	"runtime"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -33,15 +33,30 @@ func (fs *FileSystem) GetThumb(ctx context.Context, id uint) (*response.ContentR
 		}, ErrObjectNotExist
 	}
 
+	// 缩略图保存在独立的存储策略中时，重定向至其签名地址
+	if policyID, thumbPath := storedThumb(&fs.FileTarget[0]); policyID > 0 {
+		return getStoredThumb(ctx, policyID, thumbPath)
+	}
+
 	w, h := fs.GenerateThumbnailSize(0, 0)
 	ctx = context.WithValue(ctx, fsctx.ThumbSizeCtx, [2]uint{w, h})
 	ctx = context.WithValue(ctx, fsctx.FileModelCtx, fs.FileTarget[0])
-	res, err := fs.Handler.Thumb(ctx, fs.FileTarget[0].SourceName)
+	thumbCtx, span := fs.traceDriver(ctx, "thumb")
+	res, err := fs.Handler.Thumb(thumbCtx, fs.FileTarget[0].SourceName)
+	tracing.End(span, err)
+
+	// 本地存储策略出错时在后台重新生成缩略图，生成完成前返回占位图
+	if err != nil && fs.Policy.Type == "local" && IsInExtensionList(HandledExtension, fs.FileTarget[0].Name) {
+		file := fs.FileTarget[0]
+		if _, err := fs.submitThumbnail(&file); err != nil {
+			return nil, err
+		}
 
-	// 本地存储策略出错时重新生成缩略图
-	if err != nil && fs.Policy.Type == "local" {
-		fs.GenerateThumbnail(ctx, &fs.FileTarget[0])
-		res, err = fs.Handler.Thumb(ctx, fs.FileTarget[0].SourceName)
+		return &response.ContentResponse{
+			Redirect:    false,
+			Content:     placeholderThumb(),
+			Placeholder: true,
+		}, nil
 	}
 
 	if err == nil && conf.SystemConfig.Mode == "master" {
@@ -51,40 +66,8 @@ func (fs *FileSystem) GetThumb(ctx context.Context, id uint) (*response.ContentR
 	return res, err
 }
 
-// thumbPool 要使用的任务池
-var thumbPool *Pool
-var once sync.Once
-
-// Pool 带有最大配额的任务池
-type Pool struct {
-	// 容量
-	worker chan int
-}
-
-// Init 初始化任务池
-func getThumbWorker() *Pool {
-	once.Do(func() {
-		maxWorker := model.GetIntSetting("thumb_max_task_count", -1)
-		if maxWorker <= 0 {
-			maxWorker = runtime.GOMAXPROCS(0)
-		}
-		thumbPool = &Pool{
-			worker: make(chan int, maxWorker),
-		}
-		util.Log().Debug("初始化Thumb任务队列，WorkerNum = %d", maxWorker)
-	})
-	return thumbPool
-}
-func (pool *Pool) addWorker() {
-	pool.worker <- 1
-	util.Log().Debug("Thumb任务队列，addWorker")
-}
-func (pool *Pool) releaseWorker() {
-	util.Log().Debug("Thumb任务队列，releaseWorker")
-	<-pool.worker
-}
-
-// GenerateThumbnail 尝试为本地策略文件生成缩略图并获取图像原始大小
+// GenerateThumbnail 尝试为本地策略文件生成缩略图并获取图像原始大小，
+// 生成任务由缩略图任务池执行，本方法等待其完成，队列已满时放弃生成
 // TODO 失败时，如果之前还有图像信息，则清除
 func (fs *FileSystem) GenerateThumbnail(ctx context.Context, file *model.File) {
 	// 判断是否可以生成缩略图
@@ -92,32 +75,65 @@ func (fs *FileSystem) GenerateThumbnail(ctx context.Context, file *model.File) {
 		return
 	}
 
+	done, err := fs.submitThumbnail(file)
+	if err != nil {
+		util.Log().Warning("无法为 [%s] 生成缩略图：%s", file.SourceName, err)
+		return
+	}
+
+	<-done
+}
+
+// submitThumbnail 将生成缩略图的任务加入缩略图任务池，返回任务完成时关闭的通道。
+// 任务在后台执行，不会使用请求结束后被回收的 FileSystem
+func (fs *FileSystem) submitThumbnail(file *model.File) (<-chan struct{}, error) {
+	handler := fs.Handler
+	return getThumbPool().Submit(file.SourceName, func() error {
+		return generateThumbnail(handler, file)
+	})
+}
+
+// generateThumbnail 读取文件数据，生成并保存缩略图，记录图像原始大小
+func generateThumbnail(handler driver.Handler, file *model.File) error {
 	// 新建上下文
-	newCtx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	// 获取文件数据
-	source, err := fs.Handler.Get(newCtx, file.SourceName)
+	source, err := handler.Get(ctx, file.SourceName)
 	if err != nil {
-		return
+		return err
 	}
 	defer source.Close()
-	getThumbWorker().addWorker()
-	defer getThumbWorker().releaseWorker()
 
 	image, err := thumb.NewThumbFromFile(source, file.Name)
 	if err != nil {
-		util.Log().Warning("生成缩略图时无法解析 [%s] 图像数据：%s", file.SourceName, err)
-		return
+		return fmt.Errorf("无法解析图像数据：%w", err)
 	}
 
 	// 获取原始图像尺寸
 	w, h := image.GetSize()
 
 	// 生成缩略图
-	image.GetThumb(fs.GenerateThumbnailSize(w, h))
-	// 保存到文件
-	err = image.Save(util.RelativePath(file.SourceName + model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")))
+	image.GetThumb(thumbnailSize())
+
+	// 主机上已有记录的文件可将缩略图保存至独立的存储策略，否则保存到原文件所在的本机磁盘
+	var thumbPolicy *model.Policy
+	if file.Model.ID > 0 && conf.SystemConfig.Mode == "master" {
+		thumbPolicy = thumbStoragePolicy()
+	}
+
+	thumbHandler := handler
+	thumbFile := file.SourceName + model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
+	if thumbPolicy != nil {
+		thumbFile = thumbStoragePath(file.SourceName)
+		if thumbHandler, err = newPolicyHandler(thumbPolicy); err != nil {
+			return fmt.Errorf("无法初始化缩略图存储策略：%w", err)
+		}
+		err = putThumb(ctx, thumbHandler, thumbFile, image)
+	} else {
+		err = image.Save(util.RelativePath(thumbFile))
+	}
 	image = nil
 	if model.IsTrueVal(model.GetSettingByName("thumb_gc_after_gen")) {
 		util.Log().Debug("GenerateThumbnail runtime.GC")
@@ -125,24 +141,34 @@ func (fs *FileSystem) GenerateThumbnail(ctx context.Context, file *model.File) {
 	}
 
 	if err != nil {
-		util.Log().Warning("无法保存缩略图：%s", err)
-		return
+		return fmt.Errorf("无法保存缩略图：%w", err)
 	}
 
 	// 更新文件的图像信息
 	if file.Model.ID > 0 {
 		err = file.UpdatePicInfo(fmt.Sprintf("%d,%d", w, h))
+		if err == nil {
+			err = updateStoredThumb(file, thumbPolicy, thumbFile)
+		}
 	} else {
 		file.PicInfo = fmt.Sprintf("%d,%d", w, h)
 	}
 
 	// 失败时删除缩略图文件
 	if err != nil {
-		_, _ = fs.Handler.Delete(newCtx, []string{file.SourceName + model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")})
+		_, _ = thumbHandler.Delete(ctx, []string{thumbFile})
+		return err
 	}
+
+	return nil
 }
 
 // GenerateThumbnailSize 获取要生成的缩略图的尺寸
 func (fs *FileSystem) GenerateThumbnailSize(w, h int) (uint, uint) {
+	return thumbnailSize()
+}
+
+// thumbnailSize 读取设置中缩略图的尺寸
+func thumbnailSize() (uint, uint) {
 	return uint(model.GetIntSetting("thumb_width", 400)), uint(model.GetIntSetting("thumb_width", 300))
 }
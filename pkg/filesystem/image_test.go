@@ -5,6 +5,7 @@ import (
 	"errors"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	testMock "github.com/stretchr/testify/mock"
@@ -39,15 +40,29 @@ func TestFileSystem_GetThumb(t *testing.T) {
 		asserts.NoError(err)
 		asserts.EqualValues(50, res.MaxAge)
 	}
-}
 
-func TestFileSystem_ThumbWorker(t *testing.T) {
-	asserts := assert.New(t)
+	// 本地策略缩略图不存在，后台生成并返回占位图
+	{
+		conf.SystemConfig.Mode = "slave"
+		defer func() { conf.SystemConfig.Mode = "master" }()
+		testHandller3 := new(FileHeaderMock)
+		testHandller3.On("Thumb", testMock.Anything, "1.png").Return((*response.ContentResponse)(nil), errors.New("error"))
+		testHandller3.On("Get", testMock.Anything, "1.png").Return(request.NopRSCloser{}, errors.New("error"))
+		fs.CleanTargets()
+		fs.SetTargetFile(&[]model.File{{Name: "1.png", SourceName: "1.png", PicInfo: "1,1"}})
+		fs.Policy = &model.Policy{Type: "local"}
+		fs.Handler = testHandller3
+		res, err := fs.GetThumb(context.Background(), 1)
+		asserts.NoError(err)
+		asserts.True(res.Placeholder)
+		asserts.NotNil(res.Content)
 
-	asserts.NotPanics(func() {
-		getThumbWorker().addWorker()
-		getThumbWorker().releaseWorker()
-	})
+		// 等待后台任务完成
+		done, err := getThumbPool().Submit("1.png", func() error { return nil })
+		asserts.NoError(err)
+		<-done
+		testHandller3.AssertExpectations(t)
+	}
 }
 
 func TestFileSystem_GenerateThumbnail(t *testing.T) {
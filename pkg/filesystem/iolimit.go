@@ -0,0 +1,74 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/juju/ratelimit"
+)
+
+/* ================
+	 后台任务限速
+   ================
+*/
+
+// ioBucket 所有后台任务共用的令牌桶，限速设置变更后重新创建
+var ioBucket struct {
+	sync.Mutex
+	rate   int64
+	bucket *ratelimit.Bucket
+}
+
+// backgroundIOBucket 返回给定速率下所有后台任务共用的令牌桶，速率不大于 0 时不限速
+func backgroundIOBucket(rate int64) *ratelimit.Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	ioBucket.Lock()
+	defer ioBucket.Unlock()
+	if ioBucket.bucket == nil || ioBucket.rate != rate {
+		ioBucket.rate = rate
+		ioBucket.bucket = ratelimit.NewBucketWithRate(float64(rate), rate)
+	}
+
+	return ioBucket.bucket
+}
+
+// WithIOLimit 返回附加了读写速率上限（字节/秒）的上下文，文件系统在此上下文中
+// 读取的文件流由所有后台任务共同分摊此速率
+func WithIOLimit(ctx context.Context, rate int64) context.Context {
+	if rate <= 0 {
+		return ctx
+	}
+
+	return context.WithValue(ctx, fsctx.IOLimitCtx, rate)
+}
+
+// IOLimit 返回上下文中的读写速率上限，0 表示不限速
+func IOLimit(ctx context.Context) int64 {
+	rate, _ := ctx.Value(fsctx.IOLimitCtx).(int64)
+	return rate
+}
+
+// 限速后的ReadCloser
+type limitedReadCloser struct {
+	io.Closer
+	r io.Reader
+}
+
+func (r limitedReadCloser) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+// LimitReadCloser 按上下文中的读写速率上限给文件流加上限速
+func LimitReadCloser(ctx context.Context, rc io.ReadCloser) io.ReadCloser {
+	bucket := backgroundIOBucket(IOLimit(ctx))
+	if bucket == nil || rc == nil {
+		return rc
+	}
+
+	return limitedReadCloser{rc, ratelimit.Reader(rc, bucket)}
+}
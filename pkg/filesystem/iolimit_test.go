@@ -0,0 +1,51 @@
+package filesystem
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackgroundIOBucket(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不限速
+	asserts.Nil(backgroundIOBucket(0))
+
+	// 相同速率共用令牌桶
+	bucket := backgroundIOBucket(1024)
+	asserts.NotNil(bucket)
+	asserts.True(bucket == backgroundIOBucket(1024))
+
+	// 速率变更后重新创建
+	asserts.False(bucket == backgroundIOBucket(2048))
+	asserts.InDelta(2048, backgroundIOBucket(2048).Rate(), 0.01)
+}
+
+func TestLimitReadCloser(t *testing.T) {
+	asserts := assert.New(t)
+	rc := io.NopCloser(strings.NewReader("content"))
+
+	// 未设定速率上限
+	{
+		ctx := WithIOLimit(context.Background(), 0)
+		asserts.EqualValues(0, IOLimit(ctx))
+		asserts.Equal(rc, LimitReadCloser(ctx, rc))
+		asserts.Nil(LimitReadCloser(ctx, nil))
+	}
+
+	// 设定速率上限
+	{
+		ctx := WithIOLimit(context.Background(), 1024)
+		asserts.EqualValues(1024, IOLimit(ctx))
+		limited := LimitReadCloser(ctx, rc)
+		asserts.NotEqual(rc, limited)
+		content, err := io.ReadAll(limited)
+		asserts.NoError(err)
+		asserts.Equal("content", string(content))
+		asserts.NoError(limited.Close())
+	}
+}
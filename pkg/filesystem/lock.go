@@ -0,0 +1,111 @@
+package filesystem
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+)
+
+/* =================
+	 WebDAV 锁检查
+   =================
+*/
+
+// checkLocks 写入或删除前检查涉及的资源是否已被 WebDAV 客户端锁定，被锁定时返回 ErrLocked。
+// resolve 返回涉及资源在所有者目录树中的完整路径，用户没有未过期的锁时不会调用
+func (fs *FileSystem) checkLocks(ctx context.Context, resolve func() ([]string, error)) error {
+	if ignore, ok := ctx.Value(fsctx.IgnoreLocksCtx).(bool); ok && ignore {
+		return nil
+	}
+
+	locks, err := model.GetFileLocks(fs.User.ID)
+	if err != nil {
+		return ErrDBListObjects.WithError(err)
+	}
+	if len(locks) == 0 {
+		return nil
+	}
+
+	paths, err := resolve()
+	if err != nil {
+		return err
+	}
+
+	if model.FileLocksConflict(locks, paths...) != nil {
+		return ErrLocked
+	}
+	return nil
+}
+
+// fullPaths 返回相对文件系统根目录的路径在所有者目录树中的完整路径
+func (fs *FileSystem) fullPaths(paths ...string) ([]string, error) {
+	res := make([]string, len(paths))
+	for i, p := range paths {
+		full, err := fs.FullPath(p)
+		if err != nil {
+			return nil, err
+		}
+		res[i] = full
+	}
+	return res, nil
+}
+
+// objectPaths 返回目录及文件在所有者目录树中的完整路径
+func (fs *FileSystem) objectPaths(dirs, files []uint) ([]string, error) {
+	res := make([]string, 0, len(dirs)+len(files))
+
+	if len(dirs) > 0 {
+		folders, err := model.GetFoldersByIDs(dirs, fs.User.ID)
+		if err != nil {
+			return nil, ErrDBListObjects.WithError(err)
+		}
+		for _, folder := range folders {
+			if err := folder.TraceRoot(); err != nil {
+				return nil, ErrObjectNotExist.WithError(err)
+			}
+			res = append(res, path.Join(folder.Position, folder.Name))
+		}
+	}
+
+	if len(files) > 0 {
+		fileObjects, err := model.GetFilesByIDs(files, fs.User.ID)
+		if err != nil {
+			return nil, ErrDBListObjects.WithError(err)
+		}
+
+		parents := make(map[uint]string)
+		for _, file := range fileObjects {
+			parent, ok := parents[file.FolderID]
+			if !ok {
+				folders, err := model.GetFoldersByIDs([]uint{file.FolderID}, fs.User.ID)
+				if err != nil || len(folders) == 0 {
+					return nil, ErrObjectNotExist.WithError(err)
+				}
+				if err := folders[0].TraceRoot(); err != nil {
+					return nil, ErrObjectNotExist.WithError(err)
+				}
+				parent = path.Join(folders[0].Position, folders[0].Name)
+				parents[file.FolderID] = parent
+			}
+			res = append(res, path.Join(parent, file.Name))
+		}
+	}
+
+	return res, nil
+}
+
+// movedPaths 返回对象移动至 dst 目录下后的完整路径，dst 为相对文件系统根目录的路径
+func (fs *FileSystem) movedPaths(paths []string, dst string) ([]string, error) {
+	dstPath, err := fs.FullPath(dst)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, len(paths))
+	for i, p := range paths {
+		res[i] = path.Join(dstPath, path.Base(p))
+	}
+	return res, nil
+}
@@ -0,0 +1,83 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileSystem_CheckLocks(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+	ctx := context.Background()
+	lockRows := []string{"id", "root", "zero_depth"}
+
+	// 未锁定
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(lockRows).AddRow(1, "/a/b.txt", true))
+		asserts.NoError(fs.checkLocks(ctx, func() ([]string, error) {
+			return fs.fullPaths("/a/c.txt", "/b")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 目录下的资源被锁定
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows(lockRows).AddRow(1, "/a/b.txt", true))
+		asserts.Equal(ErrLocked, fs.checkLocks(ctx, func() ([]string, error) {
+			return fs.fullPaths("/c", "/a")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 没有锁时不解析路径
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows(lockRows))
+		asserts.NoError(fs.checkLocks(ctx, func() ([]string, error) {
+			return nil, errors.New("error")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 文件被锁定
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").
+			WillReturnRows(sqlmock.NewRows(lockRows).AddRow(1, "/a/b.txt", true))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(2, "b.txt", 3))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "a", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(1, "/", nil, 1))
+		asserts.Equal(ErrLocked, fs.checkLocks(ctx, func() ([]string, error) {
+			return fs.objectPaths(nil, []uint{2})
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 查询失败
+	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(fs.checkLocks(ctx, func() ([]string, error) {
+			return fs.fullPaths("/a")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 已确认持有锁的请求不检查
+	{
+		ignoreCtx := context.WithValue(ctx, fsctx.IgnoreLocksCtx, true)
+		asserts.NoError(fs.checkLocks(ignoreCtx, func() ([]string, error) {
+			return fs.fullPaths("/a/b.txt")
+		}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
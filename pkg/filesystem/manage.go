@@ -2,14 +2,18 @@ package filesystem
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"path"
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -20,15 +24,41 @@ import (
 
 // Rename 重命名对象
 func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string) (err error) {
+	// 检查分享接收者的编辑权限
+	if err := checkShareRole(ctx, model.ShareRoleEdit); err != nil {
+		return err
+	}
+
 	// 验证新名字
 	if !fs.ValidateLegalName(ctx, new) || (len(file) > 0 && !fs.ValidateExtension(ctx, new)) {
 		return ErrIllegalObjectName
 	}
 
+	if len(dir) == 0 && len(file) == 0 {
+		return ErrPathNotExist
+	}
+
+	// 对象及其新路径均不能被锁定
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		var paths []string
+		var err error
+		if len(file) > 0 {
+			paths, err = fs.objectPaths(nil, file[:1])
+		} else if len(dir) > 0 {
+			paths, err = fs.objectPaths(dir[:1], nil)
+		}
+		for _, p := range paths {
+			paths = append(paths, path.Join(path.Dir(p), new))
+		}
+		return paths, err
+	}); err != nil {
+		return err
+	}
+
 	// 如果源对象是文件
 	if len(file) > 0 {
 		fileObject, err := model.GetFilesByIDs([]uint{file[0]}, fs.User.ID)
-		if err != nil || len(fileObject) == 0 {
+		if err != nil || len(fileObject) == 0 || !isUnderLimitParent(ctx, &fileObject[0].FolderID) {
 			return ErrPathNotExist
 		}
 
@@ -41,7 +71,7 @@ func (fs *FileSystem) Rename(ctx context.Context, dir, file []uint, new string)
 
 	if len(dir) > 0 {
 		folderObject, err := model.GetFoldersByIDs([]uint{dir[0]}, fs.User.ID)
-		if err != nil || len(folderObject) == 0 {
+		if err != nil || len(folderObject) == 0 || !isUnderLimitParent(ctx, folderObject[0].ParentID) {
 			return ErrPathNotExist
 		}
 
@@ -66,6 +96,17 @@ func (fs *FileSystem) Copy(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrPathNotExist
 	}
 
+	// 复制的目标路径不能被锁定
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		paths, err := fs.objectPaths(dirs, files)
+		if err != nil {
+			return nil, err
+		}
+		return fs.movedPaths(paths, dst)
+	}); err != nil {
+		return err
+	}
+
 	// 记录复制的文件的总容量
 	var newUsedStorage uint64
 
@@ -103,6 +144,18 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrPathNotExist
 	}
 
+	// 对象及其新路径均不能被锁定
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		paths, err := fs.objectPaths(dirs, files)
+		if err != nil {
+			return nil, err
+		}
+		moved, err := fs.movedPaths(paths, dst)
+		return append(paths, moved...), err
+	}); err != nil {
+		return err
+	}
+
 	// 处理目录及子文件移动
 	err := srcFolder.MoveFolderTo(dirs, dstFolder)
 	if err != nil {
@@ -115,13 +168,33 @@ func (fs *FileSystem) Move(ctx context.Context, dirs, files []uint, src, dst str
 		return ErrFileExisted.WithError(err)
 	}
 
-	// 移动文件
+	// 对象移出已分享的目录后，其自身的分享随之失效
+	if model.IsTrueVal(model.GetSettingByName("share_revoke_on_move")) {
+		revoked, err := model.RevokeSharesMovedOut(srcFolder, dstFolder, dirs, files)
+		if err != nil {
+			util.Log().Warning("无法撤销移出分享目录的对象的分享, %s", err)
+		} else {
+			model.RecordShareRevoke(nil, fs.User.ID, revoked, "moved out of shared folder")
+		}
+	}
 
 	return err
 }
 
 // Delete 递归删除对象, force 为 true 时强制删除文件记录，忽略物理删除是否成功
 func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool) error {
+	// 检查分享接收者的删除权限
+	if err := checkShareRole(ctx, model.ShareRoleDelete); err != nil {
+		return err
+	}
+
+	// 被锁定的对象及包含被锁定对象的目录不能删除
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		return fs.objectPaths(dirs, files)
+	}); err != nil {
+		return err
+	}
+
 	// 已删除的文件ID
 	var deletedFiles = make([]*model.File, 0, len(fs.FileTarget))
 	// 删除失败的文件的父目录ID
@@ -179,7 +252,7 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 		return ErrDBDeleteObjects.WithError(err)
 	}
 
-	// 删除文件记录对应的分享记录
+	// 删除文件记录对应的分享记录及 WebDAV 属性
 	// TODO 先取消分享再删除文件
 	deletedFileIDs := make([]uint, len(deletedFiles))
 	for k, file := range deletedFiles {
@@ -187,6 +260,7 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 	}
 
 	model.DeleteShareBySourceIDs(deletedFileIDs, false)
+	model.DeleteWebdavPropsByObjects(deletedFileIDs, false)
 
 	// 如果文件全部删除成功，继续删除目录
 	if len(deletedFiles) == len(allFiles) {
@@ -194,13 +268,17 @@ func (fs *FileSystem) Delete(ctx context.Context, dirs, files []uint, force bool
 		for _, value := range fs.DirTarget {
 			allFolderIDs = append(allFolderIDs, value.ID)
 		}
-		err = model.DeleteFolderByIDs(allFolderIDs)
+		err = model.DeleteFolderByIDs(allFolderIDs, fs.User.ID)
 		if err != nil {
 			return ErrDBDeleteObjects.WithError(err)
 		}
 
-		// 删除目录记录对应的分享记录
+		// 从上级目录的统计中扣除被删除的目录
+		model.ReleaseFolderStats(fs.DirTarget)
+
+		// 删除目录记录对应的分享记录及 WebDAV 属性
 		model.DeleteShareBySourceIDs(allFolderIDs, true)
+		model.DeleteWebdavPropsByObjects(allFolderIDs, true)
 	}
 
 	if notDeleted := len(fs.FileTarget) - len(deletedFiles); notDeleted > 0 {
@@ -222,6 +300,13 @@ func (fs *FileSystem) ListDeleteDirs(ctx context.Context, ids []uint) error {
 		return ErrDBListObjects.WithError(err)
 	}
 
+	// 如果上下文限制了父目录，则待删除的目录须位于其下
+	for _, folder := range folders {
+		if util.ContainsUint(ids, folder.ID) && !isUnderLimitParent(ctx, folder.ParentID) {
+			return ErrObjectNotExist
+		}
+	}
+
 	// 忽略根目录
 	for i := 0; i < len(folders); i++ {
 		if folders[i].ParentID == nil {
@@ -248,6 +333,13 @@ func (fs *FileSystem) ListDeleteFiles(ctx context.Context, ids []uint) error {
 	if err != nil {
 		return ErrDBListObjects.WithError(err)
 	}
+
+	// 如果上下文限制了父目录，则待删除的文件须位于其下
+	for _, file := range files {
+		if !isUnderLimitParent(ctx, &file.FolderID) {
+			return ErrObjectNotExist
+		}
+	}
 	fs.SetTargetFile(&files)
 	return nil
 }
@@ -265,16 +357,73 @@ func (fs *FileSystem) List(ctx context.Context, dirPath string, pathProcessor fu
 	fs.SetTargetDir(&[]model.Folder{*folder})
 
 	var parentPath = path.Join(folder.Position, folder.Name)
-	var childFolders []model.Folder
-	var childFiles []model.File
 
-	// 获取子目录
-	childFolders, _ = folder.GetChildFolder()
+	// 尝试从缓存中读取列表
+	ttl := model.GetIntSetting("folder_list_timeout", 0)
+	var cacheKey string
+	if ttl > 0 {
+		cacheKey = folderListCacheKey(ctx, folder.ID, ttl, parentPath, pathProcessor)
+		if objects, ok := cache.Get(cacheKey); ok {
+			if res, ok := objects.([]serializer.Object); ok {
+				return res, nil
+			}
+		}
+	}
+
+	// 获取子目录及子文件
+	childFolders, childFiles, err := folder.GetChildren()
+	if err != nil {
+		return nil, ErrDBListObjects.WithError(err)
+	}
+
+	objects := fs.listObjects(ctx, parentPath, childFiles, childFolders, pathProcessor)
+	if ttl > 0 {
+		if err := cache.Set(cacheKey, objects, ttl); err != nil {
+			util.Log().Warning("无法缓存目录列表, %s", err)
+		}
+	}
+
+	return objects, nil
+}
 
-	// 获取子文件
-	childFiles, _ = folder.GetChildFiles()
+// folderListCacheKey 获取目录列表的缓存键。同一目录在不同视图（路径处理钩子、分享 Key）
+// 下的列表分别缓存，并共享目录的缓存版本号，目录内容变更时一并失效
+func folderListCacheKey(ctx context.Context, folderID uint, ttl int, parentPath string, pathProcessor func(string) string) string {
+	view := parentPath
+	if pathProcessor != nil {
+		view = pathProcessor(parentPath)
+	}
 
-	return fs.listObjects(ctx, parentPath, childFiles, childFolders, pathProcessor), nil
+	if key, ok := ctx.Value(fsctx.ShareKeyCtx).(string); ok {
+		view += "\n" + key
+	}
+
+	sum := sha1.Sum([]byte(view))
+	return fmt.Sprintf("folder_list_%d_%s_%s", folderID, model.FolderListVersion(folderID, ttl), hex.EncodeToString(sum[:]))
+}
+
+// ListPage 按排序及筛选条件分页列出路径下的内容，返回下一页的游标，已无更多内容时为 nil
+func (fs *FileSystem) ListPage(ctx context.Context, dirPath string, query *model.ChildrenQuery) ([]serializer.Object, *model.ChildrenCursor, error) {
+	// 获取父目录
+	isExist, folder := fs.IsPathExist(dirPath)
+	if !isExist {
+		return nil, nil, ErrPathNotExist
+	}
+	fs.SetTargetDir(&[]model.Folder{*folder})
+
+	childFolders, childFiles, next, err := folder.ListChildren(query)
+	if err != nil {
+		return nil, nil, ErrDBListObjects.WithError(err)
+	}
+
+	parentPath := path.Join(folder.Position, folder.Name)
+	return fs.listObjects(ctx, parentPath, childFiles, childFolders, nil), next, nil
+}
+
+// ListItems 将给定的文件和目录作为 parent 下的对象列出，
+// 用于展示不在同一目录下的对象，如合集分享
+func (fs *FileSystem) ListItems(ctx context.Context, parent string, files []model.File, folders []model.Folder) []serializer.Object {
+	return fs.listObjects(ctx, parent, files, folders, nil)
 }
 
 // ListPhysical 列出存储策略中的外部目录
@@ -290,7 +439,9 @@ func (fs *FileSystem) ListPhysical(ctx context.Context, dirPath string) ([]seria
 	}
 
 	// 列取路径
-	objects, err := fs.Handler.List(ctx, dirPath, false)
+	listCtx, span := fs.traceDriver(ctx, "list")
+	objects, err := fs.Handler.List(listCtx, dirPath, false)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -338,7 +489,7 @@ func (fs *FileSystem) listObjects(ctx context.Context, parent string, files []mo
 			Name:       subFolder.Name,
 			Path:       processedPath,
 			Pic:        "",
-			Size:       0,
+			Size:       subFolder.Size,
 			Type:       "dir",
 			Date:       subFolder.UpdatedAt,
 			CreateDate: subFolder.CreatedAt,
@@ -383,6 +534,11 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, ErrRootProtected
 	}
 
+	// 检查分享接收者的编辑权限
+	if err := checkShareRole(ctx, model.ShareRoleEdit); err != nil {
+		return nil, err
+	}
+
 	if fullPath == "/" {
 		if fs.Root != nil {
 			return fs.Root, nil
@@ -418,6 +574,12 @@ func (fs *FileSystem) CreateDirectory(ctx context.Context, fullPath string) (*mo
 		return nil, ErrFileExisted
 	}
 
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		return fs.fullPaths(path.Join(base, dir))
+	}); err != nil {
+		return nil, err
+	}
+
 	// 创建目录
 	newFolder := model.Folder{
 		Name:     dir,
@@ -441,6 +603,19 @@ func (fs *FileSystem) SaveTo(ctx context.Context, path string) error {
 		return ErrPathNotExist
 	}
 
+	// 转存的目标路径不能被锁定
+	if err := fs.checkLocks(ctx, func() ([]string, error) {
+		name := ""
+		if len(fs.DirTarget) > 0 {
+			name = fs.DirTarget[0].Name
+		} else if len(fs.FileTarget) > 0 {
+			name = fs.FileTarget[0].Name
+		}
+		return fs.movedPaths([]string{name}, path)
+	}); err != nil {
+		return err
+	}
+
 	var (
 		totalSize uint64
 		err       error
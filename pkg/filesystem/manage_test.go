@@ -187,6 +187,112 @@ func TestFileSystem_List(t *testing.T) {
 	asserts.NoError(mock.ExpectationsWereMet())
 }
 
+func TestFileSystem_ListPage(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{
+		Model: gorm.Model{
+			ID: 1,
+		},
+	}}
+	ctx := context.Background()
+	query := &model.ChildrenQuery{OrderBy: model.ChildOrderName, Limit: 1}
+
+	// 成功，仍有下一页
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1, 1, "folder").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(5, "folder", 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "sub_folder1").AddRow(7, "sub_folder2"))
+	objects, next, err := fs.ListPage(ctx, "/folder", query)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.Equal("/folder", objects[0].Path)
+	asserts.NotNil(next)
+
+	// 列取失败
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1, 1, "folder").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(5, "folder", 1))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").WillReturnError(errors.New("error"))
+	_, _, err = fs.ListPage(ctx, "/folder", query)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Error(err)
+
+	// 目录不存在
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+	mock.ExpectQuery("SELECT(.+)").
+		WithArgs(1, 1, "folder").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}))
+	_, _, err = fs.ListPage(ctx, "/folder", query)
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(ErrPathNotExist, err)
+}
+
+func TestFileSystem_List_Cache(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{
+		Model: gorm.Model{
+			ID: 1,
+		},
+	}}
+	ctx := context.Background()
+	cache.Set("setting_folder_list_timeout", "600", 0)
+	defer cache.Deletes([]string{"folder_list_timeout"}, "setting_")
+	model.InvalidateFolderList(9)
+
+	expectPath := func() {
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(1, "/", 1))
+		mock.ExpectQuery("SELECT(.+)").
+			WithArgs(1, 1, "cached").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "owner_id"}).AddRow(9, "cached", 1))
+	}
+
+	// 未命中缓存
+	expectPath()
+	mock.ExpectQuery("SELECT(.+)folder(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "sub_folder1"))
+	mock.ExpectQuery("SELECT(.+)file(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policy_id"}))
+	objects, err := fs.List(ctx, "/cached", nil)
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 命中缓存
+	expectPath()
+	objects, err = fs.List(ctx, "/cached", nil)
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 不同视图分别缓存
+	expectPath()
+	mock.ExpectQuery("SELECT(.+)folder(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "sub_folder1"))
+	mock.ExpectQuery("SELECT(.+)file(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policy_id"}))
+	objects, err = fs.List(context.WithValue(ctx, fsctx.ShareKeyCtx, "share"), "/cached", nil)
+	asserts.NoError(err)
+	asserts.Len(objects, 1)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 目录内容变更后失效
+	model.InvalidateFolderList(9)
+	expectPath()
+	mock.ExpectQuery("SELECT(.+)folder(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(6, "sub_folder1").AddRow(7, "sub_folder2"))
+	mock.ExpectQuery("SELECT(.+)file(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "policy_id"}))
+	objects, err = fs.List(ctx, "/cached", nil)
+	asserts.NoError(err)
+	asserts.Len(objects, 2)
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
 func TestFileSystem_CreateDirectory(t *testing.T) {
 	asserts := assert.New(t)
 	fs := &FileSystem{User: &model.User{
@@ -225,6 +331,7 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
 
 	mock.ExpectQuery("SELECT(.+)files").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// ab
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("ab", 2, 1).
@@ -244,12 +351,20 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
 
 	mock.ExpectQuery("SELECT(.+)files").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("ab", 2, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}))
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
+	// 更新上级目录统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)folder_num").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 	_, err = fs.CreateDirectory(ctx, "/ad/ab")
 	asserts.NoError(err)
 	asserts.NoError(mock.ExpectationsWereMet())
@@ -268,6 +383,7 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
 	mock.ExpectQuery("SELECT(.+)files").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 创建ad
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("ad", 1, 1).
@@ -275,7 +391,15 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 	mock.ExpectCommit()
+	// 更新上级目录统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)folder_num").WithArgs(1, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 	mock.ExpectQuery("SELECT(.+)files").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 创建ab
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("ab", 2, 1).
@@ -283,6 +407,13 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
+	// 更新上级目录统计
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)folders(.+)folder_num").WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
 	_, err = fs.CreateDirectory(ctx, "/ad/ab")
 	asserts.NoError(err)
 	asserts.NoError(mock.ExpectationsWereMet())
@@ -301,6 +432,7 @@ func TestFileSystem_CreateDirectory(t *testing.T) {
 		WithArgs(1).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
 	mock.ExpectQuery("SELECT(.+)files").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	// 创建ad
 	mock.ExpectQuery("SELECT(.+)").
 		WithArgs("ad", 1, 1).
@@ -458,6 +590,7 @@ func TestFileSystem_Delete(t *testing.T) {
 	//全部未成功，强制
 	{
 		fs.CleanTargets()
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").
 			WillReturnRows(
 				sqlmock.NewRows([]string{"id", "parent_id"}).
@@ -489,6 +622,11 @@ func TestFileSystem_Delete(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
+		// 删除对应 WebDAV 属性
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 		// 删除目录
 		mock.ExpectBegin()
 		mock.ExpectExec("DELETE(.+)").
@@ -499,6 +637,11 @@ func TestFileSystem_Delete(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
+		// 删除对应 WebDAV 属性
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 
 		fs.FileTarget = []model.File{}
 		fs.DirTarget = []model.Folder{}
@@ -513,6 +656,7 @@ func TestFileSystem_Delete(t *testing.T) {
 		file.Close()
 		file2.Close()
 		asserts.NoError(err)
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").
 			WillReturnRows(
 				sqlmock.NewRows([]string{"id", "parent_id"}).
@@ -544,6 +688,11 @@ func TestFileSystem_Delete(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
+		// 删除对应 WebDAV 属性
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 		// 删除目录
 		mock.ExpectBegin()
 		mock.ExpectExec("DELETE(.+)").
@@ -554,6 +703,11 @@ func TestFileSystem_Delete(t *testing.T) {
 		mock.ExpectExec("UPDATE(.+)shares").
 			WillReturnResult(sqlmock.NewResult(0, 3))
 		mock.ExpectCommit()
+		// 删除对应 WebDAV 属性
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)webdav_props").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
 
 		fs.FileTarget = []model.File{}
 		fs.DirTarget = []model.Folder{}
@@ -606,6 +760,7 @@ func TestFileSystem_Copy(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1, 1, "src").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
 		err := fs.Copy(ctx, []uint{1}, []uint{}, "/src", "/dst")
 		asserts.Error(err)
@@ -654,6 +809,7 @@ func TestFileSystem_Move(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1, 1, "src").
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(2, 1))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		err := fs.Move(ctx, []uint{1}, []uint{}, "/src", "/dst")
 		asserts.Error(err)
 		asserts.NoError(mock.ExpectationsWereMet())
@@ -673,6 +829,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名文件 成功
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old.text"))
@@ -688,6 +845,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名文件 不存在
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
@@ -699,6 +857,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名文件 失败
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)files(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old.text"))
@@ -715,6 +874,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名目录 成功
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)folders(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old"))
@@ -730,6 +890,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名目录 不存在
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)folders(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
@@ -741,6 +902,7 @@ func TestFileSystem_Rename(t *testing.T) {
 
 	// 重命名目录 失败
 	{
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)folders(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(10, "old"))
@@ -787,6 +949,7 @@ func TestFileSystem_Rename(t *testing.T) {
 	// 新名字是目录，不应该检测扩展名
 	{
 		fs.Policy.OptionsSerialized.FileType = []string{"txt"}
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)folders(.+)").
 			WithArgs(10, 1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
@@ -812,6 +975,7 @@ func TestFileSystem_SaveTo(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
 		fs.SetTargetFile(&[]model.File{{Name: "test.txt"}})
 		err := fs.SaveTo(ctx, "/")
@@ -824,6 +988,7 @@ func TestFileSystem_SaveTo(t *testing.T) {
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
 		fs.SetTargetDir(&[]model.Folder{{Name: "folder"}})
 		err := fs.SaveTo(ctx, "/")
@@ -29,26 +29,23 @@ func (fs *FileSystem) IsPathExist(path string) (bool, *model.Folder) {
 		currentFolder = fs.Root
 	}
 
-	for _, folderName := range pathList {
-		var err error
-
-		// 根目录
-		if folderName == "/" {
-			if currentFolder != nil {
-				continue
-			}
+	// 根目录
+	if pathList[0] == "/" {
+		pathList = pathList[1:]
+		if currentFolder == nil {
+			var err error
 			currentFolder, err = fs.User.Root()
 			if err != nil {
 				return false, nil
 			}
-		} else {
-			currentFolder, err = currentFolder.GetChild(folderName)
-			if err != nil {
-				return false, nil
-			}
 		}
 	}
 
+	currentFolder, err := currentFolder.GetChildByPath(pathList)
+	if err != nil {
+		return false, nil
+	}
+
 	return true, currentFolder
 }
 
@@ -73,3 +70,23 @@ func (fs *FileSystem) IsChildFileExist(folder *model.Folder, name string) (bool,
 	file, err := folder.GetChildFile(name)
 	return err == nil, file
 }
+
+// FullPath 返回 p 在文件系统所有者目录树中的完整路径。设定了根目录时向上查找根目录的实际位置，
+// 通过分享挂载、子账户等不同方式访问同一资源时得到相同的路径
+func (fs *FileSystem) FullPath(p string) (string, error) {
+	if fs.Root == nil || fs.Root.ParentID == nil {
+		return path.Join("/", p), nil
+	}
+
+	// 设定根目录时其位置及名称已被改写，重新查询
+	folders, err := model.GetFoldersByIDs([]uint{fs.Root.ID}, fs.Root.OwnerID)
+	if err != nil || len(folders) == 0 {
+		return "", ErrPathNotExist
+	}
+	root := folders[0]
+	if err := root.TraceRoot(); err != nil {
+		return "", err
+	}
+
+	return path.Join(root.Position, root.Name, p), nil
+}
@@ -170,3 +170,40 @@ func TestFileSystem_IsChildFileExist(t *testing.T) {
 	asserts.True(exist)
 	asserts.Equal("/123", childFile.Position)
 }
+
+func TestFileSystem_FullPath(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}}
+
+	// 未设定根目录
+	{
+		res, err := fs.FullPath("/a/b")
+		asserts.NoError(err)
+		asserts.Equal("/a/b", res)
+	}
+
+	// 根目录为子目录
+	{
+		parentID := uint(2)
+		fs.Root = &model.Folder{Model: gorm.Model{ID: 3}, Name: "/", ParentID: &parentID, OwnerID: 1}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "sub", 2, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(2, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(2, "dir", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(1, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(1, "/", nil, 1))
+		res, err := fs.FullPath("/a")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Equal("/dir/sub/a", res)
+	}
+
+	// 根目录不存在
+	{
+		mock.ExpectQuery("SELECT(.+)folders(.+)").WithArgs(3, 1).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		_, err := fs.FullPath("/a")
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(ErrPathNotExist, err)
+	}
+}
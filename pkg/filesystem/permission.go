@@ -0,0 +1,27 @@
+package filesystem
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+)
+
+// checkShareRole 如果上下文中设定了分享接收者的角色权限，检查其是否包含 role
+func checkShareRole(ctx context.Context, role int) error {
+	if roles, ok := ctx.Value(fsctx.ShareRoleCtx).(int); ok && roles&role != role {
+		return ErrSharePermissionDenied
+	}
+
+	return nil
+}
+
+// isUnderLimitParent 返回父目录ID为 parentID 的对象是否直接位于上下文限制的父目录下，
+// 未限制父目录时总是返回 true
+func isUnderLimitParent(ctx context.Context, parentID *uint) bool {
+	if parent, ok := ctx.Value(fsctx.LimitParentCtx).(*model.Folder); ok {
+		return parentID != nil && *parentID == parent.ID
+	}
+
+	return true
+}
@@ -0,0 +1,79 @@
+package filesystem
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckShareRole(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 未设定角色
+	asserts.NoError(checkShareRole(context.Background(), model.ShareRoleDelete))
+
+	// 具备权限
+	ctx := context.WithValue(context.Background(), fsctx.ShareRoleCtx, model.ShareRoleView|model.ShareRoleEdit)
+	asserts.NoError(checkShareRole(ctx, model.ShareRoleEdit))
+
+	// 缺少权限
+	asserts.Equal(ErrSharePermissionDenied, checkShareRole(ctx, model.ShareRoleDelete))
+}
+
+func TestIsUnderLimitParent(t *testing.T) {
+	asserts := assert.New(t)
+	parentID, otherID := uint(1), uint(2)
+
+	// 未限制父目录
+	asserts.True(isUnderLimitParent(context.Background(), nil))
+
+	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, &model.Folder{Model: gorm.Model{ID: 1}})
+	asserts.True(isUnderLimitParent(ctx, &parentID))
+	asserts.False(isUnderLimitParent(ctx, &otherID))
+	asserts.False(isUnderLimitParent(ctx, nil))
+}
+
+func TestFileSystem_ShareRoleDenied(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}, Policy: &model.Policy{}}
+	ctx := context.WithValue(context.Background(), fsctx.ShareRoleCtx, model.ShareRoleView|model.ShareRoleDownload)
+
+	asserts.Equal(ErrSharePermissionDenied, fs.Rename(ctx, nil, []uint{10}, "new.txt"))
+	asserts.Equal(ErrSharePermissionDenied, fs.Delete(ctx, nil, []uint{10}, false))
+	_, err := fs.CreateDirectory(ctx, "/new")
+	asserts.Equal(ErrSharePermissionDenied, err)
+
+	cache.Set("setting_reset_after_upload_failed", "0", 0)
+	file := &fsctx.FileStream{File: ioutil.NopCloser(strings.NewReader("content"))}
+	asserts.Equal(ErrSharePermissionDenied, fs.Upload(ctx, file))
+}
+
+func TestFileSystem_RenameLimitParent(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{Model: gorm.Model{ID: 1}}, Policy: &model.Policy{}}
+	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, &model.Folder{Model: gorm.Model{ID: 1}})
+
+	// 文件不在限制的父目录下
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)files(.+)").
+		WithArgs(10, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(10, "old.txt", 2))
+	asserts.Equal(ErrPathNotExist, fs.Rename(ctx, nil, []uint{10}, "new.txt"))
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	// 目录不在限制的父目录下
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT(.+)folders(.+)").
+		WithArgs(10, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).AddRow(10, "old", 2))
+	asserts.Equal(ErrPathNotExist, fs.Rename(ctx, []uint{10}, nil, "new"))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
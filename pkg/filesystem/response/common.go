@@ -13,6 +13,8 @@ type ContentResponse struct {
 	Content  RSCloser
 	URL      string
 	MaxAge   int
+	// Placeholder 缩略图尚在生成，Content 为占位图，不应被客户端缓存
+	Placeholder bool
 }
 
 // RSCloser 存储策略适配器返回的文件流，有些策略需要带有Closer
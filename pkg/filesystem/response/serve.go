@@ -0,0 +1,111 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// copyBufferSize 无法使用 sendfile 时复制文件流使用的缓冲区大小
+const copyBufferSize = 32 * 1024
+
+// errNotFile 文件流的底层不是本地文件，无法使用 sendfile 发送
+var errNotFile = errors.New("underlying stream is not a local file")
+
+// bufferPool 复制文件流使用的缓冲区池，避免每次下载都分配新的缓冲区
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// SendFiler 包装了本地文件的文件流，可将文件内容直接交由 dst 发送，
+// 以便在 Linux 等系统上通过 sendfile 零拷贝发送
+type SendFiler interface {
+	// SendFile 将文件流当前位置起的至多 n 字节写入 dst
+	SendFile(dst io.ReaderFrom, n int64) (int64, error)
+}
+
+// SendFile 将 src 当前位置起的至多 n 字节写入 dst，src 为本地文件或实现了 SendFiler 时
+// 由 dst 直接读取文件，否则不进行写入并返回错误
+func SendFile(dst io.ReaderFrom, src io.Reader, n int64) (int64, error) {
+	switch f := src.(type) {
+	case *os.File:
+		return dst.ReadFrom(io.LimitReader(f, n))
+	case SendFiler:
+		return f.SendFile(dst, n)
+	}
+
+	return 0, errNotFile
+}
+
+// ServeContent 与 http.ServeContent 相同，content 底层为本地文件时通过 sendfile 发送，
+// 其余情况使用缓冲区池中的缓冲区复制
+func ServeContent(w http.ResponseWriter, r *http.Request, name string, modtime time.Time, content io.ReadSeeker) {
+	http.ServeContent(&contentWriter{w}, r, name, modtime, content)
+}
+
+// contentWriter 为 http.ServeContent 提供 io.ReaderFrom，接管文件内容的发送
+type contentWriter struct {
+	http.ResponseWriter
+}
+
+// ReadFrom 发送文件内容，http.ServeContent 发送单个区间时 src 为 *io.LimitedReader
+func (w *contentWriter) ReadFrom(src io.Reader) (int64, error) {
+	if limited, ok := src.(*io.LimitedReader); ok {
+		if dst := rawWriter(w.ResponseWriter); dst != nil {
+			n, err := SendFile(dst, limited.R, limited.N)
+			if err != errNotFile {
+				limited.N -= n
+				return n, err
+			}
+		}
+	}
+
+	buf := bufferPool.Get().(*[]byte)
+	defer bufferPool.Put(buf)
+	return io.CopyBuffer(writerOnly{w.ResponseWriter}, src, *buf)
+}
+
+// writerOnly 隐藏 Writer 的 io.ReaderFrom，使 io.CopyBuffer 使用给定的缓冲区
+type writerOnly struct {
+	io.Writer
+}
+
+// ginWriterType gin 默认的 ResponseWriter 类型名称
+const ginWriterType = "*gin.responseWriter"
+
+// rawWriter 获取可直接写入连接的 io.ReaderFrom，无法获取时返回 nil。
+// gin 的 ResponseWriter 未实现 io.ReaderFrom，也未提供获取被包装的 http.ResponseWriter 的方法，
+// 因此通过反射获取。w 被 gzip 等中间件包装时需经其处理，不可绕过
+func rawWriter(w http.ResponseWriter) io.ReaderFrom {
+	if rf, ok := w.(io.ReaderFrom); ok {
+		return rf
+	}
+
+	gw, ok := w.(gin.ResponseWriter)
+	if !ok || reflect.TypeOf(w).String() != ginWriterType {
+		return nil
+	}
+
+	field := reflect.ValueOf(w).Elem().FieldByName("ResponseWriter")
+	if !field.IsValid() || !field.CanInterface() {
+		return nil
+	}
+
+	rf, ok := field.Interface().(io.ReaderFrom)
+	if !ok {
+		return nil
+	}
+
+	// 绕过 gin 写入前，先由 gin 写出响应头
+	gw.WriteHeaderNow()
+	return rf
+}
@@ -0,0 +1,154 @@
+package response
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendFile(t *testing.T) {
+	asserts := assert.New(t)
+	path := filepath.Join(t.TempDir(), "file")
+	asserts.NoError(ioutil.WriteFile(path, []byte("0123456789"), 0644))
+
+	// 本地文件
+	{
+		file, err := os.Open(path)
+		asserts.NoError(err)
+		defer file.Close()
+		file.Seek(2, 0)
+
+		dst := &bytes.Buffer{}
+		n, err := SendFile(dst, file, 5)
+		asserts.NoError(err)
+		asserts.EqualValues(5, n)
+		asserts.Equal("23456", dst.String())
+	}
+
+	// 非本地文件
+	{
+		dst := &bytes.Buffer{}
+		n, err := SendFile(dst, strings.NewReader("0123456789"), 5)
+		asserts.Equal(errNotFile, err)
+		asserts.EqualValues(0, n)
+		asserts.Empty(dst.String())
+	}
+}
+
+func TestServeContent(t *testing.T) {
+	asserts := assert.New(t)
+	path := filepath.Join(t.TempDir(), "file.txt")
+	asserts.NoError(ioutil.WriteFile(path, []byte("0123456789"), 0644))
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/file", func(c *gin.Context) {
+		file, _ := os.Open(path)
+		defer file.Close()
+		ServeContent(c.Writer, c.Request, "file.txt", time.Now(), file)
+	})
+	r.GET("/reader", func(c *gin.Context) {
+		ServeContent(c.Writer, c.Request, "file.txt", time.Now(), strings.NewReader("0123456789"))
+	})
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	get := func(target, rangeHeader string) (int, string) {
+		req, _ := http.NewRequest("GET", server.URL+target, nil)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		asserts.NoError(err)
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+
+	for _, target := range []string{"/file", "/reader"} {
+		// 完整内容
+		status, body := get(target, "")
+		asserts.Equal(http.StatusOK, status)
+		asserts.Equal("0123456789", body)
+
+		// 单个区间
+		status, body = get(target, "bytes=3-5")
+		asserts.Equal(http.StatusPartialContent, status)
+		asserts.Equal("345", body)
+
+		// 多个区间
+		status, body = get(target, "bytes=0-1,8-9")
+		asserts.Equal(http.StatusPartialContent, status)
+		asserts.Contains(body, "01")
+		asserts.Contains(body, "89")
+	}
+}
+
+func TestRawWriter(t *testing.T) {
+	asserts := assert.New(t)
+
+	// httptest.ResponseRecorder 不支持 io.ReaderFrom
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		asserts.Nil(rawWriter(c.Writer))
+	}
+
+	// 被其他中间件包装
+	{
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		asserts.Nil(rawWriter(struct{ gin.ResponseWriter }{c.Writer}))
+	}
+
+	// 实际的连接
+	{
+		var found bool
+		r := gin.New()
+		r.GET("/", func(c *gin.Context) {
+			c.Status(http.StatusNoContent)
+			found = rawWriter(c.Writer) != nil
+		})
+		server := httptest.NewServer(r)
+		defer server.Close()
+
+		resp, err := http.Get(server.URL)
+		asserts.NoError(err)
+		resp.Body.Close()
+		asserts.True(found)
+		asserts.Equal(http.StatusNoContent, resp.StatusCode)
+	}
+}
+
+func TestServeContent_IfRange(t *testing.T) {
+	asserts := assert.New(t)
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/:etag", func(c *gin.Context) {
+		c.Header("ETag", map[string]string{"strong": `"abc"`, "weak": `W/"abc"`}[c.Param("etag")])
+		ServeContent(c.Writer, c.Request, "file.txt", time.Time{}, strings.NewReader("0123456789"))
+	})
+
+	get := func(target, ifRange string) int {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Range", "bytes=3-5")
+		req.Header.Set("If-Range", ifRange)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// 强实体标签相符时返回区间
+	asserts.Equal(http.StatusPartialContent, get("/strong", `"abc"`))
+
+	// 弱实体标签不能用于断点续传，返回完整内容
+	asserts.Equal(http.StatusOK, get("/weak", `W/"abc"`))
+}
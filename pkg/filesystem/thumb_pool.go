@@ -0,0 +1,186 @@
+package filesystem
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// defaultThumbQueueSize 未设置时缩略图生成队列的长度
+const defaultThumbQueueSize = 256
+
+// ThumbPoolStatus 缩略图任务池的运行状态
+type ThumbPoolStatus struct {
+	// Workers 生成缩略图的 worker 数量
+	Workers int `json:"workers"`
+	// Capacity 队列长度
+	Capacity int `json:"capacity"`
+	// Queued 排队中的任务数
+	Queued int `json:"queued"`
+	// Running 生成中的任务数
+	Running int64 `json:"running"`
+	// Completed 启动以来成功生成的数量
+	Completed uint64 `json:"completed"`
+	// Failed 启动以来生成失败的数量
+	Failed uint64 `json:"failed"`
+	// Rejected 启动以来因队列已满被拒绝的任务数
+	Rejected uint64 `json:"rejected"`
+}
+
+// thumbJob 缩略图生成任务
+type thumbJob struct {
+	key  string
+	run  func() error
+	done chan struct{}
+}
+
+// ThumbPool 缩略图任务池，由固定数量的 worker 依次生成队列中的缩略图，
+// 避免请求中同步生成缩略图导致响应缓慢。队列已满时拒绝新任务
+type ThumbPool struct {
+	workers int
+	queue   chan *thumbJob
+
+	// 排队及生成中的任务，用于合并同一文件的重复任务
+	pending map[string]*thumbJob
+	lock    sync.Mutex
+
+	running   int64
+	completed uint64
+	failed    uint64
+	rejected  uint64
+}
+
+var (
+	thumbPool     *ThumbPool
+	thumbPoolOnce sync.Once
+)
+
+// getThumbPool 获取缩略图任务池，首次使用时按设置初始化
+func getThumbPool() *ThumbPool {
+	thumbPoolOnce.Do(func() {
+		workers := model.GetIntSetting("thumb_max_task_count", -1)
+		if workers <= 0 {
+			workers = runtime.GOMAXPROCS(0)
+		}
+
+		size := model.GetIntSetting("thumb_queue_size", defaultThumbQueueSize)
+		if size <= 0 {
+			size = defaultThumbQueueSize
+		}
+
+		thumbPool = NewThumbPool(workers, size)
+		util.Log().Debug("初始化缩略图任务池，Worker 数量 = %d，队列长度 = %d", workers, size)
+	})
+	return thumbPool
+}
+
+// NewThumbPool 新建缩略图任务池并启动 worker
+func NewThumbPool(workers, size int) *ThumbPool {
+	pool := &ThumbPool{
+		workers: workers,
+		queue:   make(chan *thumbJob, size),
+		pending: make(map[string]*thumbJob),
+	}
+
+	for i := 0; i < workers; i++ {
+		go pool.work()
+	}
+
+	return pool
+}
+
+// Submit 将生成任务加入队列，返回任务完成时关闭的通道。key 相同的任务完成前
+// 不会重复加入，直接返回已有任务的通道。队列已满时返回 ErrThumbQueueFull
+func (pool *ThumbPool) Submit(key string, run func() error) (<-chan struct{}, error) {
+	pool.lock.Lock()
+	defer pool.lock.Unlock()
+
+	if job, ok := pool.pending[key]; ok {
+		return job.done, nil
+	}
+
+	job := &thumbJob{key: key, run: run, done: make(chan struct{})}
+	select {
+	case pool.queue <- job:
+		pool.pending[key] = job
+		return job.done, nil
+	default:
+		atomic.AddUint64(&pool.rejected, 1)
+		return nil, ErrThumbQueueFull
+	}
+}
+
+// Status 获取任务池的运行状态
+func (pool *ThumbPool) Status() ThumbPoolStatus {
+	return ThumbPoolStatus{
+		Workers:   pool.workers,
+		Capacity:  cap(pool.queue),
+		Queued:    len(pool.queue),
+		Running:   atomic.LoadInt64(&pool.running),
+		Completed: atomic.LoadUint64(&pool.completed),
+		Failed:    atomic.LoadUint64(&pool.failed),
+		Rejected:  atomic.LoadUint64(&pool.rejected),
+	}
+}
+
+// work 依次执行队列中的任务
+func (pool *ThumbPool) work() {
+	for job := range pool.queue {
+		atomic.AddInt64(&pool.running, 1)
+		err := job.run()
+		atomic.AddInt64(&pool.running, -1)
+
+		if err != nil {
+			atomic.AddUint64(&pool.failed, 1)
+			util.Log().Warning("无法为 [%s] 生成缩略图：%s", job.key, err)
+		} else {
+			atomic.AddUint64(&pool.completed, 1)
+		}
+
+		pool.lock.Lock()
+		delete(pool.pending, job.key)
+		pool.lock.Unlock()
+		close(job.done)
+	}
+}
+
+// GetThumbPoolStatus 获取缩略图任务池的运行状态
+func GetThumbPoolStatus() ThumbPoolStatus {
+	return getThumbPool().Status()
+}
+
+var (
+	placeholder     []byte
+	placeholderOnce sync.Once
+)
+
+// placeholderRSC 占位图的文件流
+type placeholderRSC struct {
+	*bytes.Reader
+}
+
+func (placeholderRSC) Close() error {
+	return nil
+}
+
+// placeholderThumb 缩略图生成完成前返回的占位图，为单个透明像素的 PNG 图像
+func placeholderThumb() response.RSCloser {
+	placeholderOnce.Do(func() {
+		img := image.NewNRGBA(image.Rect(0, 0, 1, 1))
+		img.Set(0, 0, color.Transparent)
+
+		buf := &bytes.Buffer{}
+		_ = png.Encode(buf, img)
+		placeholder = buf.Bytes()
+	})
+
+	return placeholderRSC{bytes.NewReader(placeholder)}
+}
@@ -0,0 +1,71 @@
+package filesystem
+
+import (
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbPool_Submit(t *testing.T) {
+	asserts := assert.New(t)
+	pool := NewThumbPool(1, 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// 占用唯一的 worker
+	running, err := pool.Submit("1", func() error {
+		close(started)
+		<-release
+		return nil
+	})
+	asserts.NoError(err)
+	<-started
+
+	// 排队
+	queued, err := pool.Submit("2", func() error { return errors.New("error") })
+	asserts.NoError(err)
+
+	// 重复的任务
+	duplicated, err := pool.Submit("2", func() error { return nil })
+	asserts.NoError(err)
+	asserts.Equal(queued, duplicated)
+
+	// 队列已满
+	_, err = pool.Submit("3", func() error { return nil })
+	asserts.Equal(ErrThumbQueueFull, err)
+
+	status := pool.Status()
+	asserts.Equal(1, status.Workers)
+	asserts.Equal(1, status.Capacity)
+	asserts.Equal(1, status.Queued)
+	asserts.EqualValues(1, status.Running)
+	asserts.EqualValues(1, status.Rejected)
+
+	close(release)
+	<-running
+	<-queued
+
+	status = pool.Status()
+	asserts.Equal(0, status.Queued)
+	asserts.EqualValues(0, status.Running)
+	asserts.EqualValues(1, status.Completed)
+	asserts.EqualValues(1, status.Failed)
+
+	// 完成后可再次加入
+	done, err := pool.Submit("2", func() error { return nil })
+	asserts.NoError(err)
+	<-done
+}
+
+func TestPlaceholderThumb(t *testing.T) {
+	asserts := assert.New(t)
+
+	rs := placeholderThumb()
+	defer rs.Close()
+	img, err := png.Decode(rs)
+	asserts.NoError(err)
+	asserts.Equal(1, img.Bounds().Dx())
+	asserts.Equal(1, img.Bounds().Dy())
+}
@@ -0,0 +1,168 @@
+package filesystem
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"path"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// thumbStorageDir 缩略图在存储策略中的存放目录
+const thumbStorageDir = "thumbs"
+
+// thumbStoragePolicy 获取用于存放缩略图的存储策略。未设置或设置为本机存储策略时返回 nil，
+// 缩略图与原文件一同保存在本机磁盘
+func thumbStoragePolicy() *model.Policy {
+	id := model.GetIntSetting("thumb_storage_policy", 0)
+	if id <= 0 {
+		return nil
+	}
+
+	policy, err := model.GetPolicyByID(uint(id))
+	if err != nil {
+		util.Log().Warning("无法获取缩略图存储策略 #%d，%s", id, err)
+		return nil
+	}
+
+	if policy.Type == "local" {
+		return nil
+	}
+
+	return &policy
+}
+
+// thumbStoragePath 获取缩略图在存储策略中的路径，按原文件的物理路径散列分布在子目录中
+func thumbStoragePath(sourceName string) string {
+	sum := sha1.Sum([]byte(sourceName))
+	name := hex.EncodeToString(sum[:])
+	ext := model.GetSettingByNameWithDefault("thumb_encode_method", "jpg")
+	return path.Join(thumbStorageDir, name[:2], name+"."+ext)
+}
+
+// newPolicyHandler 为给定的存储策略创建适配器
+func newPolicyHandler(policy *model.Policy) (driver.Handler, error) {
+	fs := &FileSystem{Policy: policy}
+	if err := fs.DispatchHandler(); err != nil {
+		return nil, err
+	}
+
+	return fs.Handler, nil
+}
+
+// putThumb 编码缩略图并上传至存储策略
+func putThumb(ctx context.Context, handler driver.Handler, savePath string, image *thumb.Thumb) error {
+	buf := &bytes.Buffer{}
+	mimeType, err := image.EncodeThumb(buf)
+	if err != nil {
+		return err
+	}
+
+	return handler.Put(ctx, &fsctx.FileStream{
+		File:     ioutil.NopCloser(buf),
+		Size:     uint64(buf.Len()),
+		Name:     path.Base(savePath),
+		MIMEType: mimeType,
+		SavePath: savePath,
+		Mode:     fsctx.Overwrite,
+	})
+}
+
+// storedThumb 获取保存在独立存储策略中的缩略图的存储策略 ID 及路径，
+// 缩略图保存在本机磁盘时返回的 ID 为 0
+func storedThumb(file *model.File) (uint, string) {
+	id, _ := strconv.ParseUint(file.MetadataSerialized[model.ThumbPolicyMetaKey], 10, 64)
+	thumbPath := file.MetadataSerialized[model.ThumbPathMetaKey]
+	if id == 0 || thumbPath == "" {
+		return 0, ""
+	}
+
+	return uint(id), thumbPath
+}
+
+// updateStoredThumb 在文件元数据中记录缩略图所在的存储策略，policy 为空时清除记录
+func updateStoredThumb(file *model.File, policy *model.Policy, thumbPath string) error {
+	if policy == nil {
+		if id, _ := storedThumb(file); id == 0 {
+			return nil
+		}
+
+		return file.UpdateMetadata(map[string]string{
+			model.ThumbPolicyMetaKey: "",
+			model.ThumbPathMetaKey:   "",
+		})
+	}
+
+	return file.UpdateMetadata(map[string]string{
+		model.ThumbPolicyMetaKey: strconv.FormatUint(uint64(policy.ID), 10),
+		model.ThumbPathMetaKey:   thumbPath,
+	})
+}
+
+// getStoredThumb 获取保存在独立存储策略中的缩略图的签名地址，客户端将被重定向至该地址
+func getStoredThumb(ctx context.Context, policyID uint, thumbPath string) (*response.ContentResponse, error) {
+	policy, err := model.GetPolicyByID(policyID)
+	if err != nil {
+		return nil, ErrObjectNotExist.WithError(err)
+	}
+
+	handler, err := newPolicyHandler(&policy)
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := model.GetIntSetting("preview_timeout", 60)
+	ctx = context.WithValue(ctx, fsctx.FileModelCtx, model.File{
+		Name:       path.Base(thumbPath),
+		SourceName: thumbPath,
+		PolicyID:   policy.ID,
+		Policy:     policy,
+	})
+	thumbURL, err := handler.Source(ctx, thumbPath, *model.GetSiteURL(), int64(ttl), false, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.ContentResponse{
+		Redirect: true,
+		URL:      thumbURL,
+		MaxAge:   ttl,
+	}, nil
+}
+
+// deleteStoredThumbs 删除文件保存在独立存储策略中的缩略图，失败时仅记录日志
+func deleteStoredThumbs(ctx context.Context, files []*model.File) {
+	groups := make(map[uint][]string)
+	for _, file := range files {
+		if id, thumbPath := storedThumb(file); id > 0 {
+			groups[id] = append(groups[id], thumbPath)
+		}
+	}
+
+	for id, paths := range groups {
+		policy, err := model.GetPolicyByID(id)
+		if err != nil {
+			util.Log().Warning("无法获取缩略图存储策略 #%d，%s", id, err)
+			continue
+		}
+
+		handler, err := newPolicyHandler(&policy)
+		if err != nil {
+			util.Log().Warning("无法初始化缩略图存储策略 [%s]，%s", policy.Name, err)
+			continue
+		}
+
+		if failed, err := handler.Delete(ctx, paths); err != nil {
+			util.Log().Warning("无法删除存储策略 [%s] 中的缩略图 %v，%s", policy.Name, failed, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package filesystem
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThumbStoragePath(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_thumb_encode_method", "png", 0)
+	defer cache.Deletes([]string{"thumb_encode_method"}, "setting_")
+
+	res := thumbStoragePath("uploads/1/a.jpg")
+	asserts.True(strings.HasPrefix(res, "thumbs/"))
+	asserts.True(strings.HasSuffix(res, ".png"))
+	asserts.Equal(res, thumbStoragePath("uploads/1/a.jpg"))
+	asserts.NotEqual(res, thumbStoragePath("uploads/1/b.jpg"))
+}
+
+func TestStoredThumb(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 保存在本机磁盘
+	{
+		id, thumbPath := storedThumb(&model.File{})
+		asserts.EqualValues(0, id)
+		asserts.Empty(thumbPath)
+	}
+
+	// 已清除记录
+	{
+		id, _ := storedThumb(&model.File{MetadataSerialized: map[string]string{
+			model.ThumbPolicyMetaKey: "",
+			model.ThumbPathMetaKey:   "",
+		}})
+		asserts.EqualValues(0, id)
+	}
+
+	// 保存在独立的存储策略中
+	{
+		id, thumbPath := storedThumb(&model.File{MetadataSerialized: map[string]string{
+			model.ThumbPolicyMetaKey: "5",
+			model.ThumbPathMetaKey:   "thumbs/ab/ab.jpg",
+		}})
+		asserts.EqualValues(5, id)
+		asserts.Equal("thumbs/ab/ab.jpg", thumbPath)
+	}
+}
+
+func TestGetStoredThumb(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_preview_timeout", "50", 0)
+	cache.Set("setting_siteURL", "http://localhost", 0)
+
+	// 存储策略不存在
+	{
+		cache.Deletes([]string{"4"}, "policy_")
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(gorm.ErrRecordNotFound)
+		_, err := getStoredThumb(context.Background(), 4, "thumbs/ab/ab.jpg")
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		cache.Set("policy_5", model.Policy{
+			Model:     gorm.Model{ID: 5},
+			Type:      "qiniu",
+			BaseURL:   "https://cdn.example.com",
+			AccessKey: "ak",
+			SecretKey: "sk",
+			IsPrivate: true,
+		}, 0)
+		defer cache.Deletes([]string{"5"}, "policy_")
+
+		res, err := getStoredThumb(context.Background(), 5, "thumbs/ab/ab.jpg")
+		asserts.NoError(err)
+		asserts.True(res.Redirect)
+		asserts.EqualValues(50, res.MaxAge)
+		asserts.True(strings.HasPrefix(res.URL, "https://cdn.example.com/thumbs/ab/ab.jpg?e="))
+		asserts.Contains(res.URL, "token=")
+	}
+}
+
+func TestFileSystem_GetThumb_Stored(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_preview_timeout", "50", 0)
+	cache.Set("setting_siteURL", "http://localhost", 0)
+	cache.Set("policy_5", model.Policy{
+		Model:   gorm.Model{ID: 5},
+		Type:    "qiniu",
+		BaseURL: "https://cdn.example.com",
+	}, 0)
+	defer cache.Deletes([]string{"5"}, "policy_")
+
+	fs := &FileSystem{User: &model.User{}}
+	fs.SetTargetFile(&[]model.File{{
+		PicInfo: "1,1",
+		Policy:  model.Policy{Model: gorm.Model{ID: 1}, Type: "mock"},
+		MetadataSerialized: map[string]string{
+			model.ThumbPolicyMetaKey: "5",
+			model.ThumbPathMetaKey:   "thumbs/ab/ab.jpg",
+		},
+	}})
+	res, err := fs.GetThumb(context.Background(), 1)
+	asserts.NoError(err)
+	asserts.True(res.Redirect)
+	asserts.Equal("https://cdn.example.com/thumbs/ab/ab.jpg", res.URL)
+}
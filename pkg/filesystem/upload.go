@@ -11,9 +11,11 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/gofrs/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 /* ================
@@ -25,11 +27,31 @@ const (
 	UploadSessionMetaKey     = "upload_session"
 	UploadSessionCtx         = "uploadSession"
 	UserCtx                  = "user"
+	APITokenCtx              = "apiToken"
 	UploadSessionCachePrefix = "callback_"
 )
 
 // Upload 上传文件
 func (fs *FileSystem) Upload(ctx context.Context, file *fsctx.FileStream) (err error) {
+	// 检查分享接收者的上传权限
+	if err = checkShareRole(ctx, model.ShareRoleUpload); err != nil {
+		request.BlackHole(file)
+		return err
+	}
+
+	// 写入的路径不能被锁定
+	err = fs.checkLocks(ctx, func() ([]string, error) {
+		// 更新已有文件时按文件 ID 获取路径
+		if originFile, ok := ctx.Value(fsctx.FileModelCtx).(model.File); ok {
+			return fs.objectPaths(nil, []uint{originFile.ID})
+		}
+		return fs.fullPaths(path.Join(file.Info().VirtualPath, file.Info().FileName))
+	})
+	if err != nil {
+		request.BlackHole(file)
+		return err
+	}
+
 	// 上传前的钩子
 	err = fs.Trigger(ctx, "BeforeUpload", file)
 	if err != nil {
@@ -54,7 +76,10 @@ func (fs *FileSystem) Upload(ctx context.Context, file *fsctx.FileStream) (err e
 		// 处理客户端未完成上传时，关闭连接
 		go fs.CancelUpload(ctx, savePath, file)
 
-		err = fs.Handler.Put(ctx, file)
+		putCtx, span := fs.traceDriver(ctx, "put")
+		span.SetAttributes(attribute.Int64("file.size", int64(file.Info().Size)))
+		err = fs.Handler.Put(putCtx, file)
+		tracing.End(span, err)
 		if err != nil {
 			fs.Trigger(ctx, "AfterUploadFailed", file)
 			return err
@@ -142,6 +167,8 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 
 	fs.Use("BeforeUpload", HookValidateFile)
 	fs.Use("BeforeUpload", HookValidateCapacity)
+	fs.Use("BeforeUpload", HookValidatePolicyCapacity)
+	fs.Use("BeforeUpload", HookValidateUploadTraffic)
 
 	// 验证文件规格
 	if err := fs.Upload(ctx, file); err != nil {
@@ -158,10 +185,23 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 		SavePath:       file.SavePath,
 		LastModified:   file.LastModified,
 		CallbackSecret: util.RandStringRunes(32),
+		Expires:        time.Now().Add(time.Duration(callBackSessionTTL) * time.Second).Unix(),
+	}
+
+	// 协商同时上传的分片数，不超过存储策略的限制
+	if requested, ok := ctx.Value(fsctx.ChunkConcurrencyCtx).(int); ok && requested > 1 && uploadSession.ChunkNum() > 1 {
+		if limit := fs.Policy.MaxChunkConcurrency(); limit < requested {
+			requested = limit
+		}
+		if requested > 1 {
+			uploadSession.Parallel = requested
+		}
 	}
 
 	// 获取上传凭证
-	credential, err := fs.Handler.Token(ctx, int64(callBackSessionTTL), uploadSession, file)
+	tokenCtx, span := fs.traceDriver(ctx, "token")
+	credential, err := fs.Handler.Token(tokenCtx, int64(callBackSessionTTL), uploadSession, file)
+	tracing.End(span, err)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +211,8 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 		fs.Use("AfterUpload", HookClearFileHeaderSize)
 	}
 	fs.Use("AfterUpload", GenericAfterUpload)
+	// 上传会话创建后即计入当日上传流量
+	fs.Use("AfterUpload", HookRecordUploadTraffic)
 	ctx = context.WithValue(ctx, fsctx.IgnoreDirectoryConflictCtx, true)
 	if err := fs.Upload(ctx, file); err != nil {
 		return nil, err
@@ -187,7 +229,8 @@ func (fs *FileSystem) CreateUploadSession(ctx context.Context, file *fsctx.FileS
 	}
 
 	// 补全上传凭证其他信息
-	credential.Expires = time.Now().Add(time.Duration(callBackSessionTTL) * time.Second).Unix()
+	credential.Expires = uploadSession.Expires
+	credential.Parallel = uploadSession.Parallel
 
 	return credential, nil
 }
@@ -208,17 +251,74 @@ func (fs *FileSystem) UploadFromStream(ctx context.Context, file *fsctx.FileStre
 	if fs.Hooks == nil {
 		fs.Use("BeforeUpload", HookValidateFile)
 		fs.Use("BeforeUpload", HookValidateCapacity)
+		fs.Use("BeforeUpload", HookValidatePolicyCapacity)
 		fs.Use("AfterUploadCanceled", HookDeleteTempFile)
 		fs.Use("AfterUpload", GenericAfterUpload)
+		fs.Use("AfterUpload", HookCheckBlockedHash)
 		fs.Use("AfterUpload", HookGenerateThumb)
+		fs.Use("AfterUpload", HookScanVirus)
+		fs.Use("AfterUpload", HookSubmitModeration)
 		fs.Use("AfterValidateFailed", HookDeleteTempFile)
 	}
 	fs.Lock.Unlock()
 
+	// 后台任务上传时限制读取速率
+	file.File = LimitReadCloser(ctx, file.File)
+
 	// 开始上传
 	return fs.Upload(ctx, file)
 }
 
+// UploadOrOverwrite 按路径上传文件，同名文件已存在时覆盖其内容，供 WebDAV 等按路径写入的接口使用
+func (fs *FileSystem) UploadOrOverwrite(ctx context.Context, file *fsctx.FileStream) error {
+	exist, originFile := fs.IsFileExist(path.Join(file.VirtualPath, file.Name))
+	if exist {
+		// 检查此文件是否有软链接
+		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
+		if err == nil && len(fileList) == 0 {
+			// 如果包含软连接，应重新生成新文件副本，并更新source_name
+			originFile.SourceName = fs.GenerateSavePath(ctx, file)
+			file.Mode &= ^fsctx.Overwrite
+			fs.Use("AfterUpload", HookUpdateSourceName)
+			fs.Use("AfterUploadCanceled", HookUpdateSourceName)
+			fs.Use("AfterValidateFailed", HookUpdateSourceName)
+		}
+
+		fs.Use("BeforeUpload", HookResetPolicy)
+		fs.Use("BeforeUpload", HookValidateFile)
+		fs.Use("BeforeUpload", HookValidateCapacityDiff)
+		fs.Use("BeforeUpload", HookValidateUploadTraffic)
+		fs.Use("AfterUploadCanceled", HookCleanFileContent)
+		fs.Use("AfterUploadCanceled", HookClearFileSize)
+		fs.Use("AfterUploadCanceled", HookCancelContext)
+		fs.Use("AfterUpload", GenericAfterUpdate)
+		fs.Use("AfterUpload", HookRecordUploadTraffic)
+		fs.Use("AfterUpload", HookCheckBlockedHash)
+		fs.Use("AfterUpload", HookScanVirus)
+		fs.Use("AfterUpload", HookSubmitModeration)
+		fs.Use("AfterValidateFailed", HookCleanFileContent)
+		fs.Use("AfterValidateFailed", HookClearFileSize)
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
+		file.Mode |= fsctx.Overwrite
+	} else {
+		fs.Use("BeforeUpload", HookValidateFile)
+		fs.Use("BeforeUpload", HookValidateCapacity)
+		fs.Use("BeforeUpload", HookValidatePolicyCapacity)
+		fs.Use("BeforeUpload", HookValidateUploadTraffic)
+		fs.Use("AfterUploadCanceled", HookDeleteTempFile)
+		fs.Use("AfterUploadCanceled", HookCancelContext)
+		fs.Use("AfterUpload", GenericAfterUpload)
+		fs.Use("AfterUpload", HookRecordUploadTraffic)
+		fs.Use("AfterUpload", HookCheckBlockedHash)
+		fs.Use("AfterUpload", HookGenerateThumb)
+		fs.Use("AfterUpload", HookScanVirus)
+		fs.Use("AfterUpload", HookSubmitModeration)
+		fs.Use("AfterValidateFailed", HookDeleteTempFile)
+	}
+
+	return fs.Upload(ctx, file)
+}
+
 // UploadFromPath 将本机已有文件上传到用户的文件系统
 func (fs *FileSystem) UploadFromPath(ctx context.Context, src, dst string, mode fsctx.WriteMode) error {
 	file, err := os.Open(util.RelativePath(src))
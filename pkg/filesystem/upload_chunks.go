@@ -0,0 +1,127 @@
+package filesystem
+
+import (
+	"context"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+	 并行分片上传
+   ================
+*/
+
+// UploadChunksCachePrefix 并行上传时已接收分片记录的缓存键前缀
+const UploadChunksCachePrefix = "upload_chunks_"
+
+// receivedChunks 已接收分片的位图，位序与 Redis 位图一致，缓存中的位图只包含到最后一个已设置的字节
+type receivedChunks []byte
+
+func (chunks receivedChunks) has(index int) bool {
+	if index/8 >= len(chunks) {
+		return false
+	}
+
+	return chunks[index/8]&(0x80>>uint(index%8)) != 0
+}
+
+// complete 是否已接收全部分片
+func (chunks receivedChunks) complete(session *serializer.UploadSession) bool {
+	for i := 0; i < session.ChunkNum(); i++ {
+		if !chunks.has(i) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// markChunk 在缓存中原子地设置分片的接收记录，返回记录此前的状态及设置后已接收的分片。
+// 记录随上传会话一同过期，多个节点同时接收同一会话的分片时也只有一个请求能设置成功
+func markChunk(session *serializer.UploadSession, index int, received bool) (bool, receivedChunks, error) {
+	ttl := 0
+	if session.Expires > 0 {
+		ttl = int(session.Expires - time.Now().Unix())
+		if ttl < 1 {
+			ttl = 1
+		}
+	}
+
+	return cache.SetBit(UploadChunksCachePrefix+session.Key, index, received, ttl)
+}
+
+// receiveChunk 记录已写入的分片，并将分片大小累加至占位文件。
+// 重复上传的分片不会重复记录，仅当本次记录使全部分片均已接收时返回 true
+func receiveChunk(session *serializer.UploadSession, index int, fileHeader fsctx.FileHeader) (bool, error) {
+	received, chunks, err := markChunk(session, index, true)
+	if err != nil || received {
+		return false, err
+	}
+
+	// 从机端没有占位文件
+	if placeholder, ok := fileHeader.Info().Model.(*model.File); ok && placeholder != nil {
+		if err := changePlaceholderSize(session, "+", index, fileHeader); err != nil {
+			unmarkChunk(session, index)
+			return false, err
+		}
+	}
+
+	return chunks.complete(session), nil
+}
+
+// releaseChunk 撤销分片的接收记录，并从占位文件中扣除分片大小
+func releaseChunk(session *serializer.UploadSession, index int, fileHeader fsctx.FileHeader) {
+	if placeholder, ok := fileHeader.Info().Model.(*model.File); ok && placeholder != nil {
+		if err := changePlaceholderSize(session, "-", index, fileHeader); err != nil {
+			util.Log().Warning("无法扣除分片 [%s/%d] 的大小, %s", session.Key, index, err)
+		}
+	}
+
+	unmarkChunk(session, index)
+}
+
+// unmarkChunk 清除分片的接收记录
+func unmarkChunk(session *serializer.UploadSession, index int) {
+	if _, _, err := markChunk(session, index, false); err != nil {
+		util.Log().Warning("无法撤销分片 [%s/%d] 的接收记录, %s", session.Key, index, err)
+	}
+}
+
+// changePlaceholderSize 按分片大小增减占位文件的大小
+func changePlaceholderSize(session *serializer.UploadSession, operator string, index int, fileHeader fsctx.FileHeader) error {
+	file, err := model.GetFilesByUploadSession(session.Key, session.UID)
+	if err != nil {
+		return err
+	}
+
+	if err := file.ChangeSize(operator, session.ChunkLength(index)); err != nil {
+		return err
+	}
+
+	fileHeader.SetModel(file)
+	return nil
+}
+
+// HookChunkReceived 并行上传的分片写入后记录已接收的分片，全部分片均已接收时
+// 触发 AfterChunksAssembled 钩子完成上传
+func HookChunkReceived(session *serializer.UploadSession, index int) Hook {
+	return func(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+		complete, err := receiveChunk(session, index, fileHeader)
+		if err != nil || !complete {
+			return err
+		}
+
+		if err := fs.Trigger(ctx, "AfterChunksAssembled", fileHeader); err != nil {
+			// 客户端重试此分片时可再次完成上传
+			releaseChunk(session, index, fileHeader)
+			return err
+		}
+
+		return nil
+	}
+}
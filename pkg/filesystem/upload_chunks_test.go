@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReceivedChunks(t *testing.T) {
+	a := assert.New(t)
+	session := &serializer.UploadSession{Size: 10}
+	session.Policy.OptionsSerialized.ChunkSize = 4
+
+	chunks := receivedChunks{0x20}
+	a.True(chunks.has(2))
+	a.False(chunks.has(0))
+	a.False(chunks.has(8))
+	a.False(chunks.complete(session))
+
+	chunks = receivedChunks{0xe0}
+	a.True(chunks.complete(session))
+}
+
+func TestHookChunkReceived(t *testing.T) {
+	a := assert.New(t)
+	session := &serializer.UploadSession{
+		Key:     "TestHookChunkReceived",
+		Size:    10,
+		Expires: time.Now().Add(time.Hour).Unix(),
+	}
+	session.Policy.OptionsSerialized.ChunkSize = 4
+	defer cache.Deletes([]string{session.Key}, UploadChunksCachePrefix)
+
+	assembled := 0
+	fs := &FileSystem{}
+	fs.Use("AfterChunksAssembled", func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+		assembled++
+		return nil
+	})
+
+	// 从机端乱序接收分片，重复的分片只记录一次
+	file := &fsctx.FileStream{}
+	for _, index := range []int{2, 0, 2} {
+		a.NoError(HookChunkReceived(session, index)(context.Background(), fs, file))
+	}
+	a.Equal(0, assembled)
+	a.NoError(HookChunkReceived(session, 1)(context.Background(), fs, file))
+	a.Equal(1, assembled)
+
+	// 全部接收后重复上传的分片不会再次完成上传
+	a.NoError(HookChunkReceived(session, 1)(context.Background(), fs, file))
+	a.Equal(1, assembled)
+}
+
+func TestHookChunkReceived_Failed(t *testing.T) {
+	a := assert.New(t)
+	session := &serializer.UploadSession{
+		Key:     "TestHookChunkReceived_Failed",
+		UID:     1,
+		Size:    10,
+		Expires: time.Now().Add(time.Hour).Unix(),
+	}
+	session.Policy.OptionsSerialized.ChunkSize = 4
+	defer cache.Deletes([]string{session.Key}, UploadChunksCachePrefix)
+
+	fs := &FileSystem{}
+	fs.Use("AfterChunksAssembled", func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
+		return errors.New("error")
+	})
+	file := &fsctx.FileStream{Model: &model.File{Model: gorm.Model{ID: 1}}}
+	received := func(index int) bool {
+		prev, _, _ := markChunk(session, index, true)
+		if !prev {
+			markChunk(session, index, false)
+		}
+		return prev
+	}
+
+	// 更新占位文件大小失败
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, session.Key).
+			WillReturnError(errors.New("error"))
+		a.Error(HookChunkReceived(session, 2)(context.Background(), fs, file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.False(received(2))
+	}
+
+	// 分片大小累加至占位文件
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, session.Key).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "size"}).AddRow(1, 1, 4))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)size(.+)").WithArgs(uint64(2), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(uint64(2), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.NoError(HookChunkReceived(session, 2)(context.Background(), fs, file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.EqualValues(6, file.Model.(*model.File).Size)
+	}
+
+	// 完成上传失败后撤销最后接收的分片并扣除其大小
+	{
+		markChunk(session, 1, true)
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, session.Key).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "size"}).AddRow(1, 1, 6))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)size(.+)+(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WithArgs(1, session.Key).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "size"}).AddRow(1, 1, 10))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)size(.+)-(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("UPDATE(.+)users(.+)").WithArgs(uint64(4), sqlmock.AnyArg(), 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		a.Error(HookChunkReceived(session, 0)(context.Background(), fs, file))
+		a.NoError(mock.ExpectationsWereMet())
+		a.False(received(0))
+		a.True(received(1))
+		a.EqualValues(6, file.Model.(*model.File).Size)
+	}
+}
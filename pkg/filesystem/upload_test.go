@@ -93,6 +93,7 @@ func TestFileSystem_Upload(t *testing.T) {
 		VirtualPath: "/",
 		Name:        "1.txt",
 	}
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	err := fs.Upload(ctx, file)
 	asserts.NoError(err)
 
@@ -123,6 +124,7 @@ func TestFileSystem_Upload(t *testing.T) {
 		Name:        "1.txt",
 		File:        ioutil.NopCloser(strings.NewReader("")),
 	}
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	err = fs.Upload(ctx, file)
 	asserts.NoError(err)
 
@@ -130,6 +132,7 @@ func TestFileSystem_Upload(t *testing.T) {
 	fs.Use("BeforeUpload", func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 		return errors.New("error")
 	})
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	err = fs.Upload(ctx, file)
 	asserts.Error(err)
 	fs.Hooks["BeforeUpload"] = nil
@@ -139,6 +142,7 @@ func TestFileSystem_Upload(t *testing.T) {
 	testHandler2 := new(FileHeaderMock)
 	testHandler2.On("Put", testMock.Anything, testMock.Anything).Return(errors.New("error"))
 	fs.Handler = testHandler2
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	err = fs.Upload(ctx, file)
 	asserts.Error(err)
 	testHandler2.AssertExpectations(t)
@@ -153,6 +157,7 @@ func TestFileSystem_Upload(t *testing.T) {
 	fs.Use("AfterValidateFailed", func(ctx context.Context, fs *FileSystem, file fsctx.FileHeader) error {
 		return errors.New("error")
 	})
+	mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	err = fs.Upload(ctx, file)
 	asserts.Error(err)
 	testHandler2.AssertExpectations(t)
@@ -175,6 +180,8 @@ func TestFileSystem_GetUploadToken(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Token", testMock.Anything, int64(10), testMock.Anything, testMock.Anything).Return(&serializer.UploadCredential{Credential: "test"}, nil)
 		fs.Handler = testHandler
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
@@ -182,6 +189,10 @@ func TestFileSystem_GetUploadToken(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		// 更新所在目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)file_num").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		res, err := fs.CreateUploadSession(ctx, &fsctx.FileStream{
 			Size:        0,
@@ -203,6 +214,7 @@ func TestFileSystem_GetUploadToken(t *testing.T) {
 		testHandler := new(FileHeaderMock)
 		testHandler.On("Token", testMock.Anything, int64(10), testMock.Anything, testMock.Anything).Return(&serializer.UploadCredential{}, errors.New("error"))
 		fs.Handler = testHandler
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		mock.ExpectQuery("SELECT(.+)").
 			WithArgs(1).
 			WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id"}).AddRow(1, 1))
@@ -210,6 +222,9 @@ func TestFileSystem_GetUploadToken(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectExec("UPDATE(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)file_num").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
 		_, err := fs.CreateUploadSession(ctx, &fsctx.FileStream{
 			Size:        0,
@@ -45,6 +45,13 @@ func (fs *FileSystem) ValidateLegalName(ctx context.Context, name string) bool {
 
 // ValidateFileSize 验证上传的文件大小是否超出限制
 func (fs *FileSystem) ValidateFileSize(ctx context.Context, size uint64) bool {
+	// 用户组限制与存储策略限制同时生效
+	if fs.User != nil {
+		if limit := fs.User.Group.OptionsSerialized.MaxFileSize; limit > 0 && size > limit {
+			return false
+		}
+	}
+
 	if fs.Policy.MaxSize == 0 {
 		return true
 	}
@@ -58,6 +65,17 @@ func (fs *FileSystem) ValidateCapacity(ctx context.Context, size uint64) bool {
 
 // ValidateExtension 验证文件扩展名
 func (fs *FileSystem) ValidateExtension(ctx context.Context, fileName string) bool {
+	// 用户组禁止的扩展名
+	if fs.User != nil && fs.User.Group.OptionsSerialized.DeniedExts != "" {
+		denied := util.SplitList(strings.ToLower(fs.User.Group.OptionsSerialized.DeniedExts))
+		for i := range denied {
+			denied[i] = strings.TrimPrefix(denied[i], ".")
+		}
+		if IsInExtensionList(denied, fileName) {
+			return false
+		}
+	}
+
 	// 不需要验证
 	if len(fs.Policy.OptionsSerialized.FileType) == 0 {
 		return true
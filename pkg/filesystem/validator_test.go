@@ -81,6 +81,13 @@ func TestFileSystem_ValidateFileSize(t *testing.T) {
 	// 无限制
 	fs.Policy.MaxSize = 0
 	asserts.True(fs.ValidateFileSize(ctx, 11))
+
+	// 用户组限制
+	fs.User.Group.OptionsSerialized.MaxFileSize = 8
+	asserts.True(fs.ValidateFileSize(ctx, 8))
+	asserts.False(fs.ValidateFileSize(ctx, 9))
+	fs.Policy.MaxSize = 5
+	asserts.False(fs.ValidateFileSize(ctx, 6))
 }
 
 func TestFileSystem_ValidateExtension(t *testing.T) {
@@ -110,3 +117,23 @@ func TestFileSystem_ValidateExtension(t *testing.T) {
 	asserts.True(fs.ValidateExtension(ctx, "1.png.jpG"))
 	asserts.False(fs.ValidateExtension(ctx, "1.png"))
 }
+
+func TestFileSystem_ValidateExtension_GroupDenied(t *testing.T) {
+	asserts := assert.New(t)
+	ctx := context.Background()
+	fs := FileSystem{
+		User:   &model.User{},
+		Policy: &model.Policy{},
+	}
+	fs.User.Group.OptionsSerialized.DeniedExts = "EXE, .bat"
+
+	asserts.False(fs.ValidateExtension(ctx, "setup.exe"))
+	asserts.False(fs.ValidateExtension(ctx, "run.BAT"))
+	asserts.True(fs.ValidateExtension(ctx, "readme.txt"))
+	asserts.True(fs.ValidateExtension(ctx, "exe"))
+
+	// 与存储策略允许的扩展名同时生效
+	fs.Policy.OptionsSerialized.FileType = []string{"exe", "txt"}
+	asserts.False(fs.ValidateExtension(ctx, "setup.exe"))
+	asserts.True(fs.ValidateExtension(ctx, "readme.txt"))
+}
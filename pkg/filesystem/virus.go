@@ -0,0 +1,97 @@
+package filesystem
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/clamav"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+/* ================
+     病毒扫描相关
+   ================
+*/
+
+// HookScanVirus 上传完成后异步扫描文件
+func HookScanVirus(ctx context.Context, fs *FileSystem, fileHeader fsctx.FileHeader) error {
+	if !model.IsTrueVal(model.GetSettingByName("clamav_enabled")) {
+		return nil
+	}
+
+	// 文件系统会在请求结束后回收，扫描使用文件及用户的副本
+	file := *fileHeader.Info().Model.(*model.File)
+	user := *fs.User
+	go func() {
+		if err := ScanFile(context.Background(), &user, &file); err != nil {
+			util.Log().Warning("无法扫描文件 [%s], %s", file.Name, err)
+		}
+	}()
+
+	return nil
+}
+
+// ScanFile 使用 ClamAV 扫描文件，检出病毒时按存储策略的设置隔离或删除文件，并通知文件所有者及管理员
+func ScanFile(ctx context.Context, user *model.User, file *model.File) error {
+	options := model.GetSettingByNames("clamav_address", "clamav_timeout", "clamav_max_size")
+	maxSize, _ := strconv.ParseUint(options["clamav_max_size"], 10, 64)
+	if maxSize > 0 && file.Size > maxSize {
+		util.Log().Debug("文件 [%s] 超过扫描大小限制，跳过扫描", file.Name)
+		return nil
+	}
+
+	timeout, _ := strconv.Atoi(options["clamav_timeout"])
+	client, err := clamav.NewClient(options["clamav_address"], time.Duration(timeout)*time.Second)
+	if err != nil {
+		return err
+	}
+
+	fs, err := NewFileSystem(user)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	if err := fs.resetPolicyToFirstFile(ctx); err != nil {
+		return err
+	}
+
+	action := fs.Policy.GetVirusAction()
+	if action == model.VirusActionNone {
+		return nil
+	}
+
+	rs, err := fs.Handler.Get(ctx, file.SourceName)
+	if err != nil {
+		return err
+	}
+	defer rs.Close()
+
+	result, err := client.Scan(ctx, rs)
+	if err != nil {
+		return err
+	}
+
+	if !result.Infected {
+		return nil
+	}
+
+	util.Log().Warning("用户 [%s] 上传的文件 [%s] 检出病毒 %s", user.Email, file.Name, result.Signature)
+	if action == model.VirusActionReject {
+		fs.CleanTargets()
+		err = fs.Delete(context.WithValue(ctx, fsctx.IgnoreLocksCtx, true), []uint{}, []uint{file.ID}, true)
+	} else {
+		err = file.UpdateMetadata(map[string]string{model.QuarantineMetaKey: result.Signature})
+	}
+
+	model.RecordAudit(nil, 0, model.AuditVirusDetected, model.AuditTarget("file", file.ID),
+		fmt.Sprintf("%s: %s/%s (%s)", action, user.Email, file.Name, result.Signature))
+	notify.VirusDetected(user, file.Name, result.Signature, action == model.VirusActionReject)
+	return err
+}
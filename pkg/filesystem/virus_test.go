@@ -0,0 +1,135 @@
+package filesystem
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookScanVirus(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	file := &fsctx.FileStream{Model: &model.File{}}
+
+	// 未开启
+	cache.Set("setting_clamav_enabled", "0", 0)
+	asserts.NoError(HookScanVirus(context.Background(), fs, file))
+	asserts.NoError(mock.ExpectationsWereMet())
+}
+
+func TestScanFile(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 模拟的 clamd，始终检出病毒
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	asserts.NoError(err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			r.ReadString(0)
+			for {
+				var size uint32
+				if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+					break
+				}
+				io.CopyN(ioutil.Discard, r, int64(size))
+			}
+			conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+			conn.Close()
+		}
+	}()
+
+	cache.Set("setting_clamav_address", "tcp://"+listener.Addr().String(), 0)
+	cache.Set("setting_clamav_timeout", "10", 0)
+	cache.Set("setting_clamav_action", "quarantine", 0)
+	cache.Set("policy_598", model.Policy{Model: gorm.Model{ID: 598}, Type: "local"}, 0)
+	defer cache.Deletes([]string{"598"}, "policy_")
+
+	physical, err := os.Create(util.RelativePath("TestScanFile.txt"))
+	asserts.NoError(err)
+	physical.WriteString("EICAR")
+	physical.Close()
+	defer os.Remove(util.RelativePath("TestScanFile.txt"))
+
+	user := &model.User{Policy: model.Policy{Type: "local"}}
+	user.ID = 2
+	user.OptionsSerialized.Notification = &model.NotificationPreference{
+		Routes: map[string][]string{model.NotifyVirusDetected: {model.NotifyChannelInbox}},
+	}
+	file := &model.File{Name: "virus.txt", SourceName: "TestScanFile.txt", PolicyID: 598, Size: 5}
+	file.ID = 1
+
+	// 超过大小限制，跳过
+	{
+		cache.Set("setting_clamav_max_size", "1", 0)
+		asserts.NoError(ScanFile(context.Background(), user, file))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.False(file.IsQuarantined())
+	}
+
+	// 检出病毒，隔离文件
+	{
+		cache.Set("setting_clamav_max_size", "0", 0)
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)files(.+)metadata(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)audit_logs(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("not found"))
+		asserts.NoError(ScanFile(context.Background(), user, file))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.True(file.IsQuarantined())
+		asserts.Equal("Eicar-Test-Signature", file.MetadataSerialized[model.QuarantineMetaKey])
+	}
+
+	// 存储策略不扫描
+	{
+		cache.Set("policy_598", model.Policy{
+			Model:             gorm.Model{ID: 598},
+			Type:              "local",
+			OptionsSerialized: model.PolicyOption{VirusAction: model.VirusActionNone},
+		}, 0)
+		asserts.NoError(ScanFile(context.Background(), user, &model.File{Name: "virus.txt", SourceName: "TestScanFile.txt", PolicyID: 598}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 无法连接 clamd
+	{
+		cache.Set("setting_clamav_address", "tcp://127.0.0.1:1", 0)
+		cache.Set("policy_598", model.Policy{Model: gorm.Model{ID: 598}, Type: "local"}, 0)
+		asserts.Error(ScanFile(context.Background(), user, &model.File{Name: "virus.txt", SourceName: "TestScanFile.txt", PolicyID: 598}))
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestFileSystem_GetContent_Quarantined(t *testing.T) {
+	asserts := assert.New(t)
+	fs := &FileSystem{User: &model.User{}}
+	fs.SetTargetFile(&[]model.File{{MetadataSerialized: map[string]string{model.QuarantineMetaKey: "Eicar-Test-Signature"}}})
+
+	_, err := fs.GetContent(context.Background(), 1)
+	asserts.Equal(ErrFileQuarantined, err)
+}
@@ -0,0 +1,81 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+)
+
+// idleTimeout 控制连接的空闲超时
+const idleTimeout = 5 * time.Minute
+
+// NewCloudreveServer 按照配置文件新建 FTP 服务
+func NewCloudreveServer() (*Server, error) {
+	settings := &Settings{
+		Listen:      conf.FTPConfig.Listen,
+		PublicHost:  conf.FTPConfig.PublicHost,
+		ForceTLS:    conf.FTPConfig.ForceTLS,
+		IdleTimeout: idleTimeout,
+	}
+
+	if ports := conf.FTPConfig.PassivePorts; ports != "" {
+		var err error
+		settings.PassivePortStart, settings.PassivePortEnd, err = parsePortRange(ports)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.FTPConfig.CertPath != "" {
+		cert, err := tls.LoadX509KeyPair(util.RelativePath(conf.FTPConfig.CertPath), util.RelativePath(conf.FTPConfig.KeyPath))
+		if err != nil {
+			return nil, err
+		}
+		settings.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}
+	} else if settings.ForceTLS {
+		return nil, errors.New("ForceTLS requires CertPath and KeyPath")
+	}
+
+	return NewServer(settings, &cloudreveDriver{}), nil
+}
+
+// parsePortRange 解析形如 30000-30100 的端口范围
+func parsePortRange(ports string) (int, int, error) {
+	bounds := strings.SplitN(ports, "-", 2)
+	start, err := strconv.Atoi(strings.TrimSpace(bounds[0]))
+	end := start
+	if err == nil && len(bounds) == 2 {
+		end, err = strconv.Atoi(strings.TrimSpace(bounds[1]))
+	}
+	if err != nil || start < 1 || end > 65535 || end < start {
+		return 0, 0, fmt.Errorf("invalid passive port range %q", ports)
+	}
+	return start, end, nil
+}
+
+// cloudreveDriver 使用 WebDAV 账户登录，用户名为邮箱，密码为 WebDAV 账户的密码
+type cloudreveDriver struct{}
+
+// Login 校验 WebDAV 账户，用户组须允许使用 FTP
+func (d *cloudreveDriver) Login(username, password string, addr net.Addr) (ClientDriver, error) {
+	ip, _, _ := net.SplitHostPort(addr.String())
+	session, err := vfs.LoginWebDAV(username, password, ip)
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.User.Group.OptionsSerialized.FTP {
+		return nil, ErrPermissionDenied
+	}
+
+	session.Account.Touch(ip)
+	return session, nil
+}
@@ -0,0 +1,512 @@
+package ftp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command 命令定义
+type command struct {
+	handler   func(s *session, arg string)
+	needArg   bool
+	needLogin bool
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		// 登录及会话
+		"USER": {handler: (*session).handleUSER, needArg: true},
+		"PASS": {handler: (*session).handlePASS},
+		"AUTH": {handler: (*session).handleAUTH, needArg: true},
+		"PBSZ": {handler: (*session).handlePBSZ, needArg: true},
+		"PROT": {handler: (*session).handlePROT, needArg: true},
+		"FEAT": {handler: (*session).handleFEAT},
+		"OPTS": {handler: (*session).handleOPTS, needArg: true},
+		"SYST": {handler: (*session).handleSYST},
+		"NOOP": {handler: (*session).handleNOOP},
+		"QUIT": {handler: (*session).handleQUIT},
+
+		// 传输参数
+		"TYPE": {handler: (*session).handleTYPE, needArg: true, needLogin: true},
+		"MODE": {handler: (*session).handleMODE, needArg: true, needLogin: true},
+		"STRU": {handler: (*session).handleSTRU, needArg: true, needLogin: true},
+		"PASV": {handler: (*session).handlePASV, needLogin: true},
+		"EPSV": {handler: (*session).handleEPSV, needLogin: true},
+		"PORT": {handler: (*session).handlePORT, needArg: true, needLogin: true},
+		"EPRT": {handler: (*session).handleEPRT, needArg: true, needLogin: true},
+		"REST": {handler: (*session).handleREST, needArg: true, needLogin: true},
+		"ALLO": {handler: (*session).handleALLO, needLogin: true},
+		"ABOR": {handler: (*session).handleABOR, needLogin: true},
+
+		// 目录
+		"PWD":  {handler: (*session).handlePWD, needLogin: true},
+		"XPWD": {handler: (*session).handlePWD, needLogin: true},
+		"CWD":  {handler: (*session).handleCWD, needArg: true, needLogin: true},
+		"XCWD": {handler: (*session).handleCWD, needArg: true, needLogin: true},
+		"CDUP": {handler: (*session).handleCDUP, needLogin: true},
+		"XCUP": {handler: (*session).handleCDUP, needLogin: true},
+		"MKD":  {handler: (*session).handleMKD, needArg: true, needLogin: true},
+		"XMKD": {handler: (*session).handleMKD, needArg: true, needLogin: true},
+		"RMD":  {handler: (*session).handleRMD, needArg: true, needLogin: true},
+		"XRMD": {handler: (*session).handleRMD, needArg: true, needLogin: true},
+		"LIST": {handler: (*session).handleLIST, needLogin: true},
+		"NLST": {handler: (*session).handleNLST, needLogin: true},
+		"MLSD": {handler: (*session).handleMLSD, needLogin: true},
+		"MLST": {handler: (*session).handleMLST, needLogin: true},
+
+		// 文件
+		"SIZE": {handler: (*session).handleSIZE, needArg: true, needLogin: true},
+		"MDTM": {handler: (*session).handleMDTM, needArg: true, needLogin: true},
+		"RETR": {handler: (*session).handleRETR, needArg: true, needLogin: true},
+		"STOR": {handler: (*session).handleSTOR, needArg: true, needLogin: true},
+		"DELE": {handler: (*session).handleDELE, needArg: true, needLogin: true},
+		"RNFR": {handler: (*session).handleRNFR, needArg: true, needLogin: true},
+		"RNTO": {handler: (*session).handleRNTO, needArg: true, needLogin: true},
+	}
+}
+
+func (s *session) handleUSER(arg string) {
+	if s.server.settings.ForceTLS && !s.tls {
+		s.reply(530, "TLS is required, use AUTH TLS first")
+		return
+	}
+	s.user = arg
+	s.driver = nil
+	s.reply(331, "Password required for "+singleLine(arg))
+}
+
+func (s *session) handlePASS(arg string) {
+	if s.user == "" {
+		s.reply(503, "Login with USER first")
+		return
+	}
+
+	driver, err := s.server.driver.Login(s.user, arg, s.conn.RemoteAddr())
+	if err != nil {
+		s.failures++
+		time.Sleep(loginFailureDelay)
+		s.reply(530, "Login incorrect")
+		if s.failures >= maxLoginFailures {
+			s.quit = true
+		}
+		return
+	}
+
+	s.driver = driver
+	s.reply(230, "User logged in")
+}
+
+func (s *session) handleAUTH(arg string) {
+	if s.server.settings.TLSConfig == nil {
+		s.reply(502, "TLS is not configured")
+		return
+	}
+	if s.tls {
+		s.reply(503, "TLS is already enabled")
+		return
+	}
+	switch strings.ToUpper(arg) {
+	case "TLS", "TLS-C", "SSL":
+	default:
+		s.reply(504, "Unsupported security mechanism")
+		return
+	}
+
+	s.reply(234, "AUTH command OK, starting TLS")
+	if err := s.startTLS(); err != nil {
+		s.quit = true
+	}
+}
+
+func (s *session) handlePBSZ(arg string) {
+	if !s.tls {
+		s.reply(503, "Use AUTH TLS first")
+		return
+	}
+	s.reply(200, "PBSZ=0")
+}
+
+func (s *session) handlePROT(arg string) {
+	switch strings.ToUpper(arg) {
+	case "C":
+		if s.server.settings.ForceTLS {
+			s.reply(534, "Data connection must be protected")
+			return
+		}
+		s.protData = false
+		s.reply(200, "Data protection level set to clear")
+	case "P":
+		if !s.tls {
+			s.reply(503, "Use AUTH TLS first")
+			return
+		}
+		s.protData = true
+		s.reply(200, "Data protection level set to private")
+	default:
+		s.reply(504, "Unsupported protection level")
+	}
+}
+
+func (s *session) handleFEAT(arg string) {
+	features := []string{"UTF8", "SIZE", "MDTM", "REST STREAM", "EPSV", "EPRT", "MLST type*;size*;modify*;"}
+	if s.server.settings.TLSConfig != nil {
+		features = append(features, "AUTH TLS", "PBSZ", "PROT")
+	}
+	s.replyLines(211, "Features:", features, "End")
+}
+
+func (s *session) handleOPTS(arg string) {
+	if strings.EqualFold(strings.TrimSpace(arg), "UTF8 ON") {
+		s.reply(200, "UTF8 mode enabled")
+		return
+	}
+	s.reply(501, "Option not understood")
+}
+
+func (s *session) handleSYST(arg string) {
+	s.reply(215, "UNIX Type: L8")
+}
+
+func (s *session) handleNOOP(arg string) {
+	s.reply(200, "OK")
+}
+
+func (s *session) handleQUIT(arg string) {
+	s.reply(221, "Goodbye")
+	s.quit = true
+}
+
+func (s *session) handleTYPE(arg string) {
+	// 只支持二进制传输，ASCII 模式同样按原样传输
+	fields := strings.Fields(strings.ToUpper(arg))
+	if len(fields) == 0 || (fields[0] != "A" && fields[0] != "I" && fields[0] != "L") {
+		s.reply(504, "Unsupported type")
+		return
+	}
+	s.reply(200, "Type set to "+fields[0])
+}
+
+func (s *session) handleMODE(arg string) {
+	if strings.ToUpper(arg) != "S" {
+		s.reply(504, "Only stream mode is supported")
+		return
+	}
+	s.reply(200, "Mode set to S")
+}
+
+func (s *session) handleSTRU(arg string) {
+	if strings.ToUpper(arg) != "F" {
+		s.reply(504, "Only file structure is supported")
+		return
+	}
+	s.reply(200, "Structure set to F")
+}
+
+func (s *session) handlePASV(arg string) {
+	ip := s.passiveHost()
+	if ip == nil {
+		s.reply(425, "Passive mode is not available over IPv6, use EPSV")
+		return
+	}
+
+	l, err := s.listenPassive()
+	if err != nil {
+		s.reply(425, "Can't open passive connection")
+		return
+	}
+	s.reply(227, fmt.Sprintf("Entering Passive Mode (%s)", formatPASV(ip, l.Addr().(*net.TCPAddr).Port)))
+}
+
+func (s *session) handleEPSV(arg string) {
+	if strings.EqualFold(arg, "ALL") {
+		s.reply(200, "EPSV ALL OK")
+		return
+	}
+
+	l, err := s.listenPassive()
+	if err != nil {
+		s.reply(425, "Can't open passive connection")
+		return
+	}
+	s.reply(229, fmt.Sprintf("Entering Extended Passive Mode (|||%d|)", l.Addr().(*net.TCPAddr).Port))
+}
+
+func (s *session) handlePORT(arg string) {
+	addr, err := parsePORT(arg)
+	if err != nil {
+		s.reply(501, "Invalid PORT command")
+		return
+	}
+	if !s.setActive(addr) {
+		s.reply(504, "Data connection must be made to the client address")
+		return
+	}
+	s.reply(200, "PORT command successful")
+}
+
+func (s *session) handleEPRT(arg string) {
+	addr, err := parseEPRT(arg)
+	if err != nil {
+		s.reply(522, "Invalid EPRT command, use (1,2)")
+		return
+	}
+	if !s.setActive(addr) {
+		s.reply(504, "Data connection must be made to the client address")
+		return
+	}
+	s.reply(200, "EPRT command successful")
+}
+
+func (s *session) handleREST(arg string) {
+	offset, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil || offset < 0 {
+		s.reply(501, "Invalid offset")
+		return
+	}
+	s.restOffset = offset
+	s.reply(350, fmt.Sprintf("Restarting at %d", offset))
+}
+
+func (s *session) handleALLO(arg string) {
+	s.reply(202, "No storage allocation necessary")
+}
+
+func (s *session) handleABOR(arg string) {
+	// 传输均在命令循环中同步完成，收到 ABOR 时已无进行中的传输
+	s.resetDataConn()
+	s.reply(226, "No transfer to abort")
+}
+
+func (s *session) handlePWD(arg string) {
+	s.reply(257, quotePath(s.cwd)+" is the current directory")
+}
+
+func (s *session) handleCWD(arg string) {
+	p := s.absPath(arg)
+	info, err := s.driver.Stat(p)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	if !info.IsDir() {
+		s.reply(550, "Not a directory")
+		return
+	}
+	s.cwd = p
+	s.reply(250, "Directory changed to "+singleLine(p))
+}
+
+func (s *session) handleCDUP(arg string) {
+	s.handleCWD("..")
+}
+
+func (s *session) handleMKD(arg string) {
+	p := s.absPath(arg)
+	if err := s.driver.MakeDir(p); err != nil {
+		s.replyError(err)
+		return
+	}
+	s.reply(257, quotePath(p)+" created")
+}
+
+func (s *session) handleRMD(arg string) {
+	p := s.absPath(arg)
+	if p == "/" {
+		s.replyError(ErrPermissionDenied)
+		return
+	}
+	if err := s.driver.RemoveDir(p); err != nil {
+		s.replyError(err)
+		return
+	}
+	s.reply(250, "Directory removed")
+}
+
+// listTarget 获取 LIST 等命令的目标，目标为文件时只列出此文件
+func (s *session) listTarget(arg string) (string, []FileInfo, error) {
+	p := s.absPath(listPath(arg))
+	info, err := s.driver.Stat(p)
+	if err != nil {
+		return p, nil, err
+	}
+	if !info.IsDir() {
+		return p, []FileInfo{info}, nil
+	}
+
+	entries, err := s.driver.List(p)
+	return p, entries, err
+}
+
+// writeLines 通过数据连接逐行发送
+func (s *session) writeLines(lines []string) {
+	s.transfer(func(conn net.Conn) error {
+		w := bufio.NewWriter(conn)
+		for _, line := range lines {
+			if _, err := w.WriteString(line + "\r\n"); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+}
+
+func (s *session) handleLIST(arg string) {
+	_, entries, err := s.listTarget(arg)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+
+	now := time.Now()
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, listLine(entry, now))
+	}
+	s.writeLines(lines)
+}
+
+func (s *session) handleNLST(arg string) {
+	_, entries, err := s.listTarget(arg)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, entry.GetName())
+	}
+	s.writeLines(lines)
+}
+
+func (s *session) handleMLSD(arg string) {
+	p := s.absPath(arg)
+	info, err := s.driver.Stat(p)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	if !info.IsDir() {
+		s.reply(501, "Not a directory")
+		return
+	}
+
+	entries, err := s.driver.List(p)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+
+	lines := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		lines = append(lines, mlsxLine(entry, entry.GetName()))
+	}
+	s.writeLines(lines)
+}
+
+func (s *session) handleMLST(arg string) {
+	p := s.absPath(arg)
+	info, err := s.driver.Stat(p)
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	s.replyLines(250, "Listing "+singleLine(p), []string{mlsxLine(info, p)}, "End")
+}
+
+func (s *session) handleSIZE(arg string) {
+	info, err := s.driver.Stat(s.absPath(arg))
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	if info.IsDir() {
+		s.reply(550, "Not a regular file")
+		return
+	}
+	s.reply(213, strconv.FormatUint(info.GetSize(), 10))
+}
+
+func (s *session) handleMDTM(arg string) {
+	info, err := s.driver.Stat(s.absPath(arg))
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	s.reply(213, info.ModTime().UTC().Format(timeValFormat))
+}
+
+func (s *session) handleRETR(arg string) {
+	offset := s.restOffset
+	s.restOffset = 0
+
+	r, err := s.driver.Open(s.absPath(arg))
+	if err != nil {
+		s.replyError(err)
+		return
+	}
+	defer r.Close()
+
+	if offset > 0 {
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			s.replyError(err)
+			return
+		}
+	}
+
+	s.transfer(func(conn net.Conn) error {
+		_, err := io.Copy(conn, r)
+		return err
+	})
+}
+
+func (s *session) handleSTOR(arg string) {
+	if s.restOffset != 0 {
+		s.restOffset = 0
+		s.reply(554, "Resuming uploads is not supported")
+		return
+	}
+
+	p := s.absPath(arg)
+	s.transfer(func(conn net.Conn) error {
+		return s.driver.Store(p, conn)
+	})
+}
+
+func (s *session) handleDELE(arg string) {
+	if err := s.driver.Remove(s.absPath(arg)); err != nil {
+		s.replyError(err)
+		return
+	}
+	s.reply(250, "File deleted")
+}
+
+func (s *session) handleRNFR(arg string) {
+	p := s.absPath(arg)
+	if _, err := s.driver.Stat(p); err != nil {
+		s.renameFrom = ""
+		s.replyError(err)
+		return
+	}
+	s.renameFrom = p
+	s.reply(350, "Ready for RNTO")
+}
+
+func (s *session) handleRNTO(arg string) {
+	if s.renameFrom == "" {
+		s.reply(503, "Use RNFR first")
+		return
+	}
+	if err := s.driver.Rename(s.renameFrom, s.absPath(arg)); err != nil {
+		s.replyError(err)
+		return
+	}
+	s.reply(250, "Rename successful")
+}
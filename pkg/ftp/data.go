@@ -0,0 +1,236 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	errNoDataConn    = errors.New("use PORT or PASV first")
+	errForeignPeer   = errors.New("data connection from foreign address")
+	errInvalidPort   = errors.New("invalid port specification")
+	errNoPassivePort = errors.New("no passive port available")
+)
+
+// listenPassive 在被动模式端口范围内监听数据连接
+func (s *session) listenPassive() (*net.TCPListener, error) {
+	s.resetDataConn()
+
+	host, _, _ := net.SplitHostPort(s.conn.LocalAddr().String())
+	start, end := s.server.settings.PassivePortStart, s.server.settings.PassivePortEnd
+
+	var (
+		l   net.Listener
+		err error
+	)
+	if start <= 0 || end < start {
+		l, err = net.Listen("tcp", net.JoinHostPort(host, "0"))
+	} else {
+		// 从随机位置开始尝试，减少并发会话间的冲突
+		count := end - start + 1
+		offset := rand.Intn(count)
+		err = errNoPassivePort
+		for i := 0; i < count; i++ {
+			port := start + (offset+i)%count
+			if l, err = net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(port))); err == nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.passive = l
+	s.mu.Unlock()
+	return l.(*net.TCPListener), nil
+}
+
+// resetDataConn 清除之前设置的数据连接方式
+func (s *session) resetDataConn() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.passive != nil {
+		s.passive.Close()
+		s.passive = nil
+	}
+	s.active = nil
+}
+
+// openDataConn 建立数据连接，PROT P 时在数据连接上启用 TLS
+func (s *session) openDataConn() (net.Conn, error) {
+	s.mu.Lock()
+	passive, active := s.passive, s.active
+	s.passive, s.active = nil, nil
+	s.mu.Unlock()
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch {
+	case passive != nil:
+		defer passive.Close()
+		passive.(*net.TCPListener).SetDeadline(time.Now().Add(dataConnTimeout))
+		if conn, err = passive.Accept(); err != nil {
+			return nil, err
+		}
+		// 只接受与控制连接来自同一地址的数据连接，防止被他人抢占
+		if !sameHost(conn.RemoteAddr(), s.conn.RemoteAddr()) {
+			conn.Close()
+			return nil, errForeignPeer
+		}
+	case active != nil:
+		if conn, err = net.DialTimeout("tcp", active.String(), dataConnTimeout); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errNoDataConn
+	}
+
+	if s.protData {
+		tlsConn := tls.Server(conn, s.server.settings.TLSConfig)
+		tlsConn.SetDeadline(time.Now().Add(dataConnTimeout))
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		tlsConn.SetDeadline(time.Time{})
+		conn = tlsConn
+	}
+
+	return conn, nil
+}
+
+// transfer 建立数据连接并执行传输，完成后回复传输结果
+func (s *session) transfer(fn func(conn net.Conn) error) {
+	s.reply(150, "Opening data connection")
+	conn, err := s.openDataConn()
+	if err != nil {
+		s.reply(425, "Can't open data connection: "+singleLine(err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	s.data = conn
+	s.mu.Unlock()
+
+	err = fn(conn)
+	conn.Close()
+
+	s.mu.Lock()
+	s.data = nil
+	s.mu.Unlock()
+
+	if err != nil {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			s.reply(426, "Connection closed; transfer aborted")
+			return
+		}
+		s.replyError(err)
+		return
+	}
+	s.reply(226, "Transfer complete")
+}
+
+// sameHost 两个地址的 IP 是否相同
+func sameHost(a, b net.Addr) bool {
+	hostA, _, errA := net.SplitHostPort(a.String())
+	hostB, _, errB := net.SplitHostPort(b.String())
+	return errA == nil && errB == nil && net.ParseIP(hostA).Equal(net.ParseIP(hostB))
+}
+
+// passiveHost 被动模式下告知客户端的 IPv4 地址
+func (s *session) passiveHost() net.IP {
+	if host := s.server.settings.PublicHost; host != "" {
+		if ip := net.ParseIP(host); ip != nil {
+			return ip.To4()
+		}
+		if ips, err := net.LookupIP(host); err == nil {
+			for _, ip := range ips {
+				if ip4 := ip.To4(); ip4 != nil {
+					return ip4
+				}
+			}
+		}
+		return nil
+	}
+
+	host, _, _ := net.SplitHostPort(s.conn.LocalAddr().String())
+	return net.ParseIP(host).To4()
+}
+
+// formatPASV 生成 PASV 响应中的地址，形如 h1,h2,h3,h4,p1,p2
+func formatPASV(ip net.IP, port int) string {
+	ip = ip.To4()
+	return fmt.Sprintf("%d,%d,%d,%d,%d,%d", ip[0], ip[1], ip[2], ip[3], port>>8, port&0xff)
+}
+
+// parsePORT 解析 PORT 命令的参数
+func parsePORT(arg string) (*net.TCPAddr, error) {
+	parts := strings.Split(arg, ",")
+	if len(parts) != 6 {
+		return nil, errInvalidPort
+	}
+
+	var nums [6]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 0 || n > 255 {
+			return nil, errInvalidPort
+		}
+		nums[i] = n
+	}
+
+	port := nums[4]<<8 | nums[5]
+	if port == 0 {
+		return nil, errInvalidPort
+	}
+	return &net.TCPAddr{
+		IP:   net.IPv4(byte(nums[0]), byte(nums[1]), byte(nums[2]), byte(nums[3])),
+		Port: port,
+	}, nil
+}
+
+// parseEPRT 解析 EPRT 命令的参数，形如 |1|132.235.1.2|6275|
+func parseEPRT(arg string) (*net.TCPAddr, error) {
+	if len(arg) < 2 {
+		return nil, errInvalidPort
+	}
+	parts := strings.Split(arg[1:len(arg)-1], arg[:1])
+	if len(parts) != 3 || arg[len(arg)-1] != arg[0] {
+		return nil, errInvalidPort
+	}
+
+	ip := net.ParseIP(parts[1])
+	if ip == nil || (parts[0] == "1") != (ip.To4() != nil) || (parts[0] != "1" && parts[0] != "2") {
+		return nil, errInvalidPort
+	}
+
+	port, err := strconv.Atoi(parts[2])
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, errInvalidPort
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// setActive 设置主动模式的地址，只允许连接控制连接的客户端地址，防止 FTP 跳板攻击
+func (s *session) setActive(addr *net.TCPAddr) bool {
+	if !sameHost(addr, s.conn.RemoteAddr()) || addr.Port < 1024 {
+		return false
+	}
+
+	s.resetDataConn()
+	s.mu.Lock()
+	s.active = addr
+	s.mu.Unlock()
+	return true
+}
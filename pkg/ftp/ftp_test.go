@@ -0,0 +1,406 @@
+package ftp
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memFile 内存中的文件或目录
+type memFile struct {
+	name    string
+	content []byte
+	dir     bool
+}
+
+func (f *memFile) GetName() string    { return f.name }
+func (f *memFile) GetSize() uint64    { return uint64(len(f.content)) }
+func (f *memFile) ModTime() time.Time { return time.Date(2021, 5, 1, 8, 30, 0, 0, time.UTC) }
+func (f *memFile) IsDir() bool        { return f.dir }
+
+// memDriver 内存中的文件系统
+type memDriver struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+}
+
+func newMemDriver() *memDriver {
+	return &memDriver{files: map[string]*memFile{"/": {name: "/", dir: true}}}
+}
+
+func (d *memDriver) Login(user, pass string, addr net.Addr) (ClientDriver, error) {
+	if user != "admin@cloudreve.org" || pass != "secret" {
+		return nil, ErrLoginFailed
+	}
+	return d, nil
+}
+
+func (d *memDriver) Stat(p string) (FileInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[p]; ok {
+		return f, nil
+	}
+	return nil, ErrNotExist
+}
+
+func (d *memDriver) List(p string) ([]FileInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var res []FileInfo
+	for name, f := range d.files {
+		if name != "/" && path.Dir(name) == p {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// memReader 读取内存中的文件
+type memReader struct {
+	*bytes.Reader
+}
+
+func (r memReader) Close() error { return nil }
+
+func (d *memDriver) Open(p string) (io.ReadSeekCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[p]
+	if !ok || f.dir {
+		return nil, ErrNotExist
+	}
+	return memReader{bytes.NewReader(f.content)}, nil
+}
+
+func (d *memDriver) Store(p string, r io.Reader) error {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files[p] = &memFile{name: path.Base(p), content: content}
+	return nil
+}
+
+func (d *memDriver) MakeDir(p string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.files[p]; ok {
+		return ErrExist
+	}
+	d.files[p] = &memFile{name: path.Base(p), dir: true}
+	return nil
+}
+
+func (d *memDriver) Remove(p string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[p]; !ok || f.dir {
+		return ErrNotExist
+	}
+	delete(d.files, p)
+	return nil
+}
+
+func (d *memDriver) RemoveDir(p string) error {
+	return ErrPermissionDenied
+}
+
+func (d *memDriver) Rename(from, to string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[from]
+	if !ok {
+		return ErrNotExist
+	}
+	delete(d.files, from)
+	f.name = path.Base(to)
+	d.files[to] = f
+	return nil
+}
+
+// testClient 测试用的 FTP 客户端
+type testClient struct {
+	t      *testing.T
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// cmd 发送命令并读取响应，返回响应码及最后一行
+func (c *testClient) cmd(format string, args ...interface{}) (int, string) {
+	if format != "" {
+		fmt.Fprintf(c.conn, format+"\r\n", args...)
+	}
+	for {
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			c.t.Fatal(err)
+		}
+		var code int
+		if len(line) >= 4 && line[3] == ' ' {
+			fmt.Sscanf(line[:3], "%d", &code)
+			return code, strings.TrimSpace(line[4:])
+		}
+	}
+}
+
+// pasv 进入被动模式并连接数据端口
+func (c *testClient) pasv() net.Conn {
+	code, msg := c.cmd("EPSV")
+	assert.Equal(c.t, 229, code)
+	var port int
+	fmt.Sscanf(msg[strings.Index(msg, "|||")+3:], "%d", &port)
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", port))
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return conn
+}
+
+func startTestServer(t *testing.T) (*Server, *testClient) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := NewServer(&Settings{IdleTimeout: time.Minute}, newMemDriver())
+	go server.Serve(l)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &testClient{t: t, conn: conn, reader: bufio.NewReader(conn)}
+	code, _ := client.cmd("")
+	assert.Equal(t, 220, code)
+	return server, client
+}
+
+func TestSession(t *testing.T) {
+	asserts := assert.New(t)
+	server, client := startTestServer(t)
+	defer server.Shutdown()
+
+	// 未登录
+	{
+		code, _ := client.cmd("PWD")
+		asserts.Equal(530, code)
+		code, _ = client.cmd("PASS secret")
+		asserts.Equal(503, code)
+		code, _ = client.cmd("UNKNOWN")
+		asserts.Equal(502, code)
+	}
+
+	// 登录失败
+	{
+		code, _ := client.cmd("USER admin@cloudreve.org")
+		asserts.Equal(331, code)
+		code, _ = client.cmd("PASS wrong")
+		asserts.Equal(530, code)
+	}
+
+	// 登录成功
+	{
+		client.cmd("USER admin@cloudreve.org")
+		code, _ := client.cmd("PASS secret")
+		asserts.Equal(230, code)
+		code, msg := client.cmd("PWD")
+		asserts.Equal(257, code)
+		asserts.Equal(`"/" is the current directory`, msg)
+	}
+
+	// 创建并进入目录
+	{
+		code, msg := client.cmd("MKD docs")
+		asserts.Equal(257, code)
+		asserts.Equal(`"/docs" created`, msg)
+		code, _ = client.cmd("MKD /docs")
+		asserts.Equal(550, code)
+		code, _ = client.cmd("CWD docs")
+		asserts.Equal(250, code)
+		code, _ = client.cmd("CWD /not_exist")
+		asserts.Equal(550, code)
+	}
+
+	// 上传
+	{
+		conn := client.pasv()
+		code, _ := client.cmd("STOR a.txt")
+		asserts.Equal(150, code)
+		conn.Write([]byte("hello world"))
+		conn.Close()
+		code, _ = client.cmd("")
+		asserts.Equal(226, code)
+
+		code, msg := client.cmd("SIZE /docs/a.txt")
+		asserts.Equal(213, code)
+		asserts.Equal("11", msg)
+		code, msg = client.cmd("MDTM a.txt")
+		asserts.Equal(213, code)
+		asserts.Equal("20210501083000", msg)
+	}
+
+	// 列出目录
+	{
+		conn := client.pasv()
+		code, _ := client.cmd("LIST -la")
+		asserts.Equal(150, code)
+		content, _ := ioutil.ReadAll(conn)
+		code, _ = client.cmd("")
+		asserts.Equal(226, code)
+		asserts.Contains(string(content), "-rw-r--r-- 1 ftp ftp           11 May  1  2021 a.txt\r\n")
+	}
+
+	// 断点续传下载
+	{
+		client.cmd("REST 6")
+		conn := client.pasv()
+		code, _ := client.cmd("RETR a.txt")
+		asserts.Equal(150, code)
+		content, _ := ioutil.ReadAll(conn)
+		code, _ = client.cmd("")
+		asserts.Equal(226, code)
+		asserts.Equal("world", string(content))
+	}
+
+	// 重命名
+	{
+		code, _ := client.cmd("RNTO b.txt")
+		asserts.Equal(503, code)
+		code, _ = client.cmd("RNFR a.txt")
+		asserts.Equal(350, code)
+		code, _ = client.cmd("RNTO /b.txt")
+		asserts.Equal(250, code)
+		code, _ = client.cmd("SIZE /b.txt")
+		asserts.Equal(213, code)
+	}
+
+	// 删除
+	{
+		code, _ := client.cmd("DELE ../b.txt")
+		asserts.Equal(250, code)
+		code, _ = client.cmd("DELE ../b.txt")
+		asserts.Equal(550, code)
+		code, _ = client.cmd("RMD /docs")
+		asserts.Equal(550, code)
+	}
+
+	// 不允许主动模式连接其他地址
+	{
+		code, _ := client.cmd("PORT 10,0,0,1,200,10")
+		asserts.Equal(504, code)
+		code, _ = client.cmd("PORT 127,0,0,1,0,21")
+		asserts.Equal(504, code)
+	}
+
+	// 未配置 TLS
+	{
+		code, _ := client.cmd("AUTH TLS")
+		asserts.Equal(502, code)
+	}
+
+	code, _ := client.cmd("QUIT")
+	asserts.Equal(221, code)
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	asserts := assert.New(t)
+	server, client := startTestServer(t)
+	asserts.NoError(server.Shutdown())
+
+	_, err := client.reader.ReadString('\n')
+	asserts.Error(err)
+	asserts.Equal(ErrServerClosed, server.ListenAndServe())
+}
+
+func TestParsePORT(t *testing.T) {
+	asserts := assert.New(t)
+
+	addr, err := parsePORT("192,168,1,2,7,138")
+	asserts.NoError(err)
+	asserts.Equal("192.168.1.2:1930", addr.String())
+
+	for _, arg := range []string{"192,168,1,2,7", "192,168,1,256,7,138", "a,b,c,d,e,f", "1,2,3,4,0,0"} {
+		_, err := parsePORT(arg)
+		asserts.Error(err, arg)
+	}
+}
+
+func TestParseEPRT(t *testing.T) {
+	asserts := assert.New(t)
+
+	addr, err := parseEPRT("|1|132.235.1.2|6275|")
+	asserts.NoError(err)
+	asserts.Equal("132.235.1.2:6275", addr.String())
+
+	addr, err = parseEPRT("|2|1080::8:800:200C:417A|5282|")
+	asserts.NoError(err)
+	asserts.Equal("[1080::8:800:200c:417a]:5282", addr.String())
+
+	for _, arg := range []string{"", "|1|132.235.1.2|6275", "|2|132.235.1.2|6275|", "|1|::1|6275|", "|3|132.235.1.2|6275|", "|1|132.235.1.2|70000|"} {
+		_, err := parseEPRT(arg)
+		asserts.Error(err, arg)
+	}
+}
+
+func TestFormatPASV(t *testing.T) {
+	assert.Equal(t, "10,0,0,1,117,48", formatPASV(net.ParseIP("10.0.0.1"), 30000))
+}
+
+func TestListPath(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal(".", listPath(""))
+	asserts.Equal(".", listPath("-la"))
+	asserts.Equal("my  docs", listPath("-l -a my  docs"))
+	asserts.Equal("/docs", listPath("/docs"))
+}
+
+func TestListLine(t *testing.T) {
+	asserts := assert.New(t)
+	file := &memFile{name: "a b.txt", content: []byte("hello")}
+	dir := &memFile{name: "docs", dir: true}
+
+	asserts.Equal("-rw-r--r-- 1 ftp ftp            5 May  1 08:30 a b.txt", listLine(file, time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+	asserts.Equal("-rw-r--r-- 1 ftp ftp            5 May  1  2021 a b.txt", listLine(file, time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)))
+	asserts.Equal("drwxr-xr-x 1 ftp ftp            0 May  1  2021 docs", listLine(dir, time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestMlsxLine(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("type=file;size=5;modify=20210501083000; a.txt", mlsxLine(&memFile{name: "a.txt", content: []byte("hello")}, "a.txt"))
+	asserts.Equal("type=dir;modify=20210501083000; /docs", mlsxLine(&memFile{name: "docs", dir: true}, "/docs"))
+}
+
+func TestQuotePath(t *testing.T) {
+	assert.Equal(t, `"/say ""hi"""`, quotePath(`/say "hi"`))
+}
+
+func TestParsePortRange(t *testing.T) {
+	asserts := assert.New(t)
+
+	start, end, err := parsePortRange("30000-30100")
+	asserts.NoError(err)
+	asserts.Equal(30000, start)
+	asserts.Equal(30100, end)
+
+	start, end, err = parsePortRange("30000")
+	asserts.NoError(err)
+	asserts.Equal(30000, start)
+	asserts.Equal(30000, end)
+
+	for _, ports := range []string{"a-b", "30100-30000", "0-10", "30000-70000"} {
+		_, _, err := parsePortRange(ports)
+		asserts.Error(err, ports)
+	}
+}
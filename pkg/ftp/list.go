@@ -0,0 +1,55 @@
+package ftp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// timeValFormat MDTM 及 MLST 使用的时间格式
+const timeValFormat = "20060102150405"
+
+// listPath 去除 LIST 参数中 ls 风格的选项，如 LIST -la
+func listPath(arg string) string {
+	arg = strings.TrimLeft(arg, " ")
+	for strings.HasPrefix(arg, "-") {
+		i := strings.IndexByte(arg, ' ')
+		if i < 0 {
+			return "."
+		}
+		arg = strings.TrimLeft(arg[i:], " ")
+	}
+	if arg == "" {
+		return "."
+	}
+	return arg
+}
+
+// listLine 生成 ls -l 风格的列表行，半年内修改的文件显示时间，否则显示年份
+func listLine(info FileInfo, now time.Time) string {
+	mode := "-rw-r--r--"
+	if info.IsDir() {
+		mode = "drwxr-xr-x"
+	}
+
+	modTime := info.ModTime()
+	stamp := modTime.Format("Jan _2 15:04")
+	if modTime.Before(now.AddDate(0, -6, 0)) || modTime.After(now.Add(time.Hour)) {
+		stamp = modTime.Format("Jan _2  2006")
+	}
+
+	return fmt.Sprintf("%s 1 ftp ftp %12d %s %s", mode, info.GetSize(), stamp, info.GetName())
+}
+
+// mlsxLine 生成 MLSD 及 MLST 使用的事实列表
+func mlsxLine(info FileInfo, name string) string {
+	if info.IsDir() {
+		return fmt.Sprintf("type=dir;modify=%s; %s", info.ModTime().UTC().Format(timeValFormat), name)
+	}
+	return fmt.Sprintf("type=file;size=%d;modify=%s; %s", info.GetSize(), info.ModTime().UTC().Format(timeValFormat), name)
+}
+
+// quotePath 为 PWD 及 MKD 响应中的路径添加引号，路径中的引号需重复
+func quotePath(p string) string {
+	return `"` + strings.ReplaceAll(singleLine(p), `"`, `""`) + `"`
+}
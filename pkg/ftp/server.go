@@ -0,0 +1,163 @@
+package ftp
+
+import (
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+)
+
+// ErrServerClosed 服务已关闭
+var ErrServerClosed = errors.New("ftp: server closed")
+
+// 驱动返回的错误，与按路径访问用户文件的会话使用相同的定义
+var (
+	ErrNotExist         = vfs.ErrNotExist
+	ErrExist            = vfs.ErrExist
+	ErrPermissionDenied = vfs.ErrPermissionDenied
+	ErrQuotaExceeded    = vfs.ErrQuotaExceeded
+	ErrLoginFailed      = vfs.ErrLoginFailed
+)
+
+// FileInfo 文件或目录的信息
+type FileInfo = vfs.FileInfo
+
+// Driver 处理登录的驱动
+type Driver interface {
+	// Login 校验用户名及密码，成功时返回此会话使用的客户端驱动
+	Login(user, pass string, addr net.Addr) (ClientDriver, error)
+}
+
+// ClientDriver 登录后的会话访问文件系统所用的驱动，路径均为以 / 开头的绝对路径
+type ClientDriver interface {
+	// Stat 获取文件或目录信息
+	Stat(path string) (FileInfo, error)
+	// List 列出目录下的文件及目录
+	List(path string) ([]FileInfo, error)
+	// Open 打开文件用于读取
+	Open(path string) (io.ReadSeekCloser, error)
+	// Store 读取 r 直至结束并写入文件，文件已存在时覆盖
+	Store(path string, r io.Reader) error
+	// MakeDir 创建目录
+	MakeDir(path string) error
+	// Remove 删除文件
+	Remove(path string) error
+	// RemoveDir 删除空目录
+	RemoveDir(path string) error
+	// Rename 重命名或移动文件及目录
+	Rename(from, to string) error
+}
+
+// Settings 服务配置
+type Settings struct {
+	// Listen 监听地址
+	Listen string
+	// PublicHost 被动模式下告知客户端的 IP，为空时使用控制连接的本地地址
+	PublicHost string
+	// PassivePortStart、PassivePortEnd 被动模式使用的端口范围，为 0 时随机选择
+	PassivePortStart int
+	PassivePortEnd   int
+	// TLSConfig 不为空时支持 AUTH TLS
+	TLSConfig *tls.Config
+	// ForceTLS 要求客户端在登录前启用 TLS
+	ForceTLS bool
+	// IdleTimeout 控制连接的空闲超时
+	IdleTimeout time.Duration
+}
+
+// Server FTP 服务
+type Server struct {
+	settings *Settings
+	driver   Driver
+
+	mu       sync.Mutex
+	listener net.Listener
+	sessions map[*session]struct{}
+	closed   bool
+}
+
+// NewServer 新建 FTP 服务
+func NewServer(settings *Settings, driver Driver) *Server {
+	return &Server{
+		settings: settings,
+		driver:   driver,
+		sessions: make(map[*session]struct{}),
+	}
+}
+
+// ListenAndServe 监听 Settings.Listen 并处理连接，Shutdown 后返回 ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.settings.Listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理 l 上的连接
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		sess := newSession(s, conn)
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.sessions[sess] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			sess.serve()
+			s.mu.Lock()
+			delete(s.sessions, sess)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Shutdown 停止监听并断开所有连接
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for sess := range s.sessions {
+		sess.close()
+	}
+	return err
+}
@@ -0,0 +1,202 @@
+package ftp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxLineLength 命令行的最大长度
+	maxLineLength = 4096
+	// maxLoginFailures 单个连接允许的登录失败次数
+	maxLoginFailures = 3
+	// loginFailureDelay 登录失败后的延迟，减缓密码爆破
+	loginFailureDelay = time.Second
+	// dataConnTimeout 建立数据连接的超时
+	dataConnTimeout = 30 * time.Second
+)
+
+// session 一个控制连接上的会话
+type session struct {
+	server *Server
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	closed bool
+
+	user     string
+	driver   ClientDriver
+	failures int
+	tls      bool
+	protData bool
+
+	cwd        string
+	restOffset int64
+	renameFrom string
+	passive    net.Listener
+	active     *net.TCPAddr
+	data       net.Conn
+	quit       bool
+}
+
+func newSession(server *Server, conn net.Conn) *session {
+	s := &session{server: server, cwd: "/"}
+	s.setConn(conn)
+	return s
+}
+
+// setConn 设置控制连接，启用 TLS 后使用加密的连接替换原连接
+func (s *session) setConn(conn net.Conn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conn = conn
+	s.reader = bufio.NewReaderSize(conn, maxLineLength)
+	s.writer = bufio.NewWriter(conn)
+}
+
+// close 断开控制连接及未使用的数据连接
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.conn.Close()
+	if s.passive != nil {
+		s.passive.Close()
+	}
+	if s.data != nil {
+		s.data.Close()
+	}
+}
+
+// serve 读取并处理命令，直至连接断开或客户端退出
+func (s *session) serve() {
+	defer s.close()
+
+	s.reply(220, "Cloudreve FTP server ready")
+	for !s.quit {
+		if timeout := s.server.settings.IdleTimeout; timeout > 0 {
+			s.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		line, err := s.reader.ReadSlice('\n')
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				s.reply(500, "Command line too long")
+			} else if timeoutErr, ok := err.(net.Error); ok && timeoutErr.Timeout() {
+				s.reply(421, "Idle timeout, closing control connection")
+			}
+			return
+		}
+
+		name, arg := parseLine(string(line))
+		if name == "" {
+			continue
+		}
+
+		cmd, ok := commands[name]
+		if !ok {
+			s.reply(502, "Command not implemented")
+			continue
+		}
+		if cmd.needArg && arg == "" {
+			s.reply(501, "Syntax error in parameters or arguments")
+			continue
+		}
+		if cmd.needLogin && s.driver == nil {
+			s.reply(530, "Please login with USER and PASS")
+			continue
+		}
+
+		cmd.handler(s, arg)
+
+		// RNTO 须紧随 RNFR
+		if name != "RNFR" {
+			s.renameFrom = ""
+		}
+	}
+}
+
+// parseLine 解析命令行，返回大写的命令及参数
+func parseLine(line string) (string, string) {
+	line = strings.TrimRight(line, "\r\n")
+	parts := strings.SplitN(line, " ", 2)
+	name := strings.ToUpper(strings.TrimSpace(parts[0]))
+	if len(parts) == 1 {
+		return name, ""
+	}
+	return name, parts[1]
+}
+
+// reply 回复单行响应
+func (s *session) reply(code int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.writer, "%d %s\r\n", code, message)
+	s.writer.Flush()
+}
+
+// replyLines 回复多行响应，中间行以空格开头
+func (s *session) replyLines(code int, first string, lines []string, last string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.writer, "%d-%s\r\n", code, first)
+	for _, line := range lines {
+		fmt.Fprintf(s.writer, " %s\r\n", line)
+	}
+	fmt.Fprintf(s.writer, "%d %s\r\n", code, last)
+	s.writer.Flush()
+}
+
+// replyError 按驱动返回的错误回复
+func (s *session) replyError(err error) {
+	switch {
+	case errors.Is(err, ErrNotExist):
+		s.reply(550, "No such file or directory")
+	case errors.Is(err, ErrExist):
+		s.reply(550, "File or directory already exists")
+	case errors.Is(err, ErrPermissionDenied):
+		s.reply(550, "Permission denied")
+	case errors.Is(err, ErrQuotaExceeded):
+		s.reply(552, "Insufficient storage space")
+	default:
+		s.reply(550, singleLine(err.Error()))
+	}
+}
+
+// singleLine 去除消息中的换行，避免破坏响应格式
+func singleLine(message string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(message)
+}
+
+// absPath 将客户端提供的路径转换为绝对路径，不会超出根目录
+func (s *session) absPath(p string) string {
+	if !strings.HasPrefix(p, "/") {
+		p = s.cwd + "/" + p
+	}
+	return path.Clean(p)
+}
+
+// startTLS 将控制连接升级为 TLS 连接
+func (s *session) startTLS() error {
+	conn := tls.Server(s.conn, s.server.settings.TLSConfig)
+	conn.SetDeadline(time.Now().Add(dataConnTimeout))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Time{})
+	s.setConn(conn)
+	s.tls = true
+	return nil
+}
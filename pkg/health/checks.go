@@ -0,0 +1,193 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/clamav"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gofrs/uuid"
+)
+
+// probeCacheKey 缓存探测使用的键
+const probeCacheKey = "health_probe"
+
+var (
+	// ErrProbeMismatch 探测读出的内容与写入不一致
+	ErrProbeMismatch = errors.New("probe content mismatch")
+	// ErrNodeOffline 节点心跳中断
+	ErrNodeOffline = errors.New("node is offline")
+	// ErrNodeNotLoaded 节点未加载至节点池
+	ErrNodeNotLoaded = errors.New("node is not loaded")
+)
+
+// Checks 列出当前站点需要执行的检查项。数据库与缓存为关键组件，
+// 存储策略、离线下载节点、邮件发送与病毒扫描异常时站点仍可部分提供服务
+func Checks() []Check {
+	checks := []Check{
+		{Name: "database", Critical: true, Probe: probeDatabase},
+		{Name: "cache", Critical: true, Probe: probeCache},
+	}
+
+	// 数据库不可用时无法列出其余组件
+	policies, err := model.GetPolicies()
+	if err != nil {
+		return checks
+	}
+
+	for i := range policies {
+		checks = append(checks, Check{
+			Name:  "policy:" + policies[i].Name,
+			Probe: probePolicy(&policies[i]),
+		})
+	}
+
+	nodes, _ := model.GetNodesByStatus(model.NodeActive)
+	for _, node := range nodes {
+		if node.Aria2Enabled {
+			checks = append(checks, Check{
+				Name:  "aria2:" + node.Name,
+				Probe: probeAria2(node.ID),
+			})
+		}
+	}
+
+	if model.GetSettingByName("smtpHost") != "" {
+		checks = append(checks, Check{Name: "mail", Probe: probeMail})
+	}
+
+	if model.IsTrueVal(model.GetSettingByName("clamav_enabled")) {
+		checks = append(checks, Check{Name: "clamav", Probe: probeClamAV})
+	}
+
+	return checks
+}
+
+// probeDatabase 检查数据库连接
+func probeDatabase(ctx context.Context) error {
+	return model.DB.DB().PingContext(ctx)
+}
+
+// probeCache 写入并读回随机值以检查缓存
+func probeCache(ctx context.Context) error {
+	value := uuid.Must(uuid.NewV4()).String()
+	if err := cache.Set(probeCacheKey, value, 60); err != nil {
+		return err
+	}
+
+	if res, ok := cache.Get(probeCacheKey); !ok || res != value {
+		return ErrProbeMismatch
+	}
+
+	return nil
+}
+
+// probePolicy 在存储策略中写入、读回并删除一个探测文件
+func probePolicy(policy *model.Policy) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		fs := &filesystem.FileSystem{Policy: policy}
+		if err := fs.DispatchHandler(); err != nil {
+			return err
+		}
+		if fs.Handler == nil {
+			return filesystem.ErrUnknownPolicyType
+		}
+
+		content := "cloudreve health probe " + uuid.Must(uuid.NewV4()).String()
+		savePath := path.Join(policy.GeneratePath(0, "/"), ".health_"+util.RandStringRunes(16))
+		err := fs.Handler.Put(ctx, &fsctx.FileStream{
+			File:     ioutil.NopCloser(strings.NewReader(content)),
+			Size:     uint64(len(content)),
+			Name:     path.Base(savePath),
+			SavePath: savePath,
+			Mode:     fsctx.Overwrite,
+		})
+		if err != nil {
+			return fmt.Errorf("write probe: %w", err)
+		}
+
+		// 探测文件总是需要删除，不受检查超时的影响
+		defer func() {
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			if _, err := fs.Handler.Delete(cleanupCtx, []string{savePath}); err != nil {
+				util.Log().Warning("无法删除存储策略 [%s] 的健康检查探测文件 %q, %s", policy.Name, savePath, err)
+			}
+		}()
+
+		rs, err := fs.Handler.Get(ctx, savePath)
+		if err != nil {
+			return fmt.Errorf("read probe: %w", err)
+		}
+		defer rs.Close()
+
+		res, err := ioutil.ReadAll(io.LimitReader(rs, int64(len(content))+1))
+		if err != nil {
+			return fmt.Errorf("read probe: %w", err)
+		}
+
+		if string(res) != content {
+			return ErrProbeMismatch
+		}
+
+		return nil
+	}
+}
+
+// probeAria2 检查离线下载节点，主机节点直接连接 aria2 RPC，从机节点检查心跳状态
+func probeAria2(id uint) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if cluster.Default == nil {
+			return ErrNodeNotLoaded
+		}
+
+		node := cluster.Default.GetNodeByID(id)
+		if node == nil {
+			return ErrNodeNotLoaded
+		}
+
+		if !node.IsMater() {
+			if !node.IsActive() {
+				return ErrNodeOffline
+			}
+			return nil
+		}
+
+		options := node.DBModel().Aria2OptionsSerialized
+		timeout := 10
+		if deadline, ok := ctx.Deadline(); ok {
+			timeout = int(time.Until(deadline).Seconds()) + 1
+		}
+
+		_, err := aria2.TestRPCConnection(options.Server, options.Token, timeout)
+		return err
+	}
+}
+
+// probeMail 连接并登录 SMTP 服务器
+func probeMail(ctx context.Context) error {
+	return email.Ping()
+}
+
+// probeClamAV 向 clamd 发送 PING 命令
+func probeClamAV(ctx context.Context) error {
+	client, err := clamav.NewClient(model.GetSettingByName("clamav_address"), 0)
+	if err != nil {
+		return err
+	}
+
+	return client.Ping(ctx)
+}
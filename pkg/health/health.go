@@ -0,0 +1,155 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// Status 健康状态
+type Status string
+
+const (
+	// StatusOK 所有组件正常
+	StatusOK Status = "ok"
+	// StatusDegraded 非关键组件异常，站点仍可提供服务
+	StatusDegraded Status = "degraded"
+	// StatusUnhealthy 关键组件异常，站点无法正常提供服务
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Check 健康检查项
+type Check struct {
+	// Name 组件名称
+	Name string
+	// Critical 是否为关键组件，关键组件异常时整体状态为 unhealthy，否则为 degraded
+	Critical bool
+	// Probe 检查函数，应在上下文结束时尽快返回
+	Probe func(ctx context.Context) error
+}
+
+// Result 单项检查结果
+type Result struct {
+	Name     string `json:"name"`
+	Status   Status `json:"status"`
+	Critical bool   `json:"critical"`
+	// Latency 检查耗时，单位为毫秒
+	Latency int64  `json:"latency"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Report 健康检查报告
+type Report struct {
+	Status    Status    `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+	Checks    []Result  `json:"checks"`
+}
+
+var (
+	lastReport *Report
+	lastLock   sync.Mutex
+)
+
+// Get 返回健康检查报告，health_check_ttl 秒内的重复请求复用上次的结果，
+// 避免负载均衡器频繁探测时反复读写存储策略
+func Get(ctx context.Context) *Report {
+	lastLock.Lock()
+	defer lastLock.Unlock()
+
+	ttl := time.Duration(model.GetIntSetting("health_check_ttl", 30)) * time.Second
+	if lastReport != nil && time.Since(lastReport.CheckedAt) < ttl {
+		return lastReport
+	}
+
+	timeout := time.Duration(model.GetIntSetting("health_check_timeout", 10)) * time.Second
+	lastReport = Run(ctx, Checks(), timeout)
+	return lastReport
+}
+
+// Run 并发执行所有检查项，每项检查的耗时不超过 timeout
+func Run(ctx context.Context, checks []Check, timeout time.Duration) *Report {
+	report := &Report{
+		Status:    StatusOK,
+		CheckedAt: time.Now(),
+		Checks:    make([]Result, len(checks)),
+	}
+
+	var wg sync.WaitGroup
+	for i := range checks {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			report.Checks[i] = runCheck(ctx, checks[i], timeout)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		if result.Status == StatusOK {
+			continue
+		}
+
+		if result.Status == StatusUnhealthy {
+			report.Status = StatusUnhealthy
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+
+	return report
+}
+
+// runCheck 执行单个检查项，检查函数超时未返回时视为失败
+func runCheck(ctx context.Context, check Check, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	res := Result{
+		Name:     check.Name,
+		Status:   StatusOK,
+		Critical: check.Critical,
+	}
+
+	start := time.Now()
+	errChan := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				errChan <- fmt.Errorf("panic: %v", r)
+			}
+		}()
+		errChan <- check.Probe(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errChan:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res.Latency = time.Since(start).Milliseconds()
+
+	if err != nil {
+		res.Error = err.Error()
+		res.Status = StatusDegraded
+		if check.Critical {
+			res.Status = StatusUnhealthy
+		}
+	}
+
+	return res
+}
+
+// Public 返回不含错误详情的报告副本，用于未授权的请求
+func (report *Report) Public() *Report {
+	res := *report
+	res.Checks = make([]Result, len(report.Checks))
+	for i, check := range report.Checks {
+		check.Error = ""
+		res.Checks[i] = check
+	}
+	return &res
+}
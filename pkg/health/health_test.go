@@ -0,0 +1,186 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func probeOK(ctx context.Context) error {
+	return nil
+}
+
+func probeErr(ctx context.Context) error {
+	return errors.New("error")
+}
+
+func TestRun(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 全部正常
+	{
+		report := Run(context.Background(), []Check{
+			{Name: "a", Critical: true, Probe: probeOK},
+			{Name: "b", Probe: probeOK},
+		}, time.Second)
+		asserts.Equal(StatusOK, report.Status)
+		asserts.Len(report.Checks, 2)
+		asserts.Equal("a", report.Checks[0].Name)
+		asserts.Equal(StatusOK, report.Checks[0].Status)
+		asserts.Empty(report.Checks[0].Error)
+	}
+
+	// 非关键组件异常
+	{
+		report := Run(context.Background(), []Check{
+			{Name: "a", Critical: true, Probe: probeOK},
+			{Name: "b", Probe: probeErr},
+		}, time.Second)
+		asserts.Equal(StatusDegraded, report.Status)
+		asserts.Equal(StatusDegraded, report.Checks[1].Status)
+		asserts.Equal("error", report.Checks[1].Error)
+	}
+
+	// 关键组件异常
+	{
+		report := Run(context.Background(), []Check{
+			{Name: "a", Critical: true, Probe: probeErr},
+			{Name: "b", Probe: probeErr},
+		}, time.Second)
+		asserts.Equal(StatusUnhealthy, report.Status)
+		asserts.Equal(StatusUnhealthy, report.Checks[0].Status)
+	}
+
+	// 超时、panic
+	{
+		report := Run(context.Background(), []Check{
+			{Name: "a", Probe: func(ctx context.Context) error {
+				time.Sleep(time.Second)
+				return nil
+			}},
+			{Name: "b", Probe: func(ctx context.Context) error {
+				panic("oops")
+			}},
+		}, 10*time.Millisecond)
+		asserts.Equal(StatusDegraded, report.Status)
+		asserts.Equal(context.DeadlineExceeded.Error(), report.Checks[0].Error)
+		asserts.Contains(report.Checks[1].Error, "oops")
+	}
+}
+
+func TestReport_Public(t *testing.T) {
+	asserts := assert.New(t)
+	report := &Report{
+		Status: StatusDegraded,
+		Checks: []Result{{Name: "a", Status: StatusDegraded, Error: "error"}},
+	}
+
+	res := report.Public()
+	asserts.Equal(StatusDegraded, res.Status)
+	asserts.Equal("a", res.Checks[0].Name)
+	asserts.Empty(res.Checks[0].Error)
+	asserts.Equal("error", report.Checks[0].Error)
+}
+
+func TestGet(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_health_check_ttl", "60", 0)
+	cache.Set("setting_health_check_timeout", "10", 0)
+	lastReport = &Report{Status: StatusDegraded, CheckedAt: time.Now()}
+
+	// 复用上次结果
+	asserts.Same(lastReport, Get(context.Background()))
+
+	// 结果已过期
+	lastReport.CheckedAt = time.Now().Add(-time.Minute)
+	mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(errors.New("error"))
+	report := Get(context.Background())
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.Equal(StatusOK, report.Status)
+	asserts.Len(report.Checks, 2)
+	lastReport = nil
+}
+
+func TestChecks(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_smtpHost", "smtp.cloudreve.org", 0)
+	defer cache.Set("setting_smtpHost", "", 0)
+	cache.Set("setting_clamav_enabled", "1", 0)
+	defer cache.Set("setting_clamav_enabled", "0", 0)
+
+	mock.ExpectQuery("SELECT(.+)policies(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "default").AddRow(2, "backup"))
+	mock.ExpectQuery("SELECT(.+)nodes(.+)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "aria2_enabled"}).AddRow(1, "master", true).AddRow(2, "slave", false))
+	checks := Checks()
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	var names []string
+	for _, check := range checks {
+		names = append(names, check.Name)
+	}
+	asserts.Equal([]string{"database", "cache", "policy:default", "policy:backup", "aria2:master", "mail", "clamav"}, names)
+	asserts.True(checks[0].Critical)
+	asserts.True(checks[1].Critical)
+	asserts.False(checks[2].Critical)
+}
+
+func TestProbeCache(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.NoError(probeCache(context.Background()))
+	res, ok := cache.Get(probeCacheKey)
+	asserts.True(ok)
+	asserts.NotEmpty(res)
+}
+
+func TestProbePolicy(t *testing.T) {
+	asserts := assert.New(t)
+	dir := t.TempDir()
+
+	// 本地存储策略
+	{
+		probe := probePolicy(&model.Policy{Type: "local", DirNameRule: filepath.ToSlash(dir) + "/probe"})
+		asserts.NoError(probe(context.Background()))
+		entries, err := os.ReadDir(filepath.Join(dir, "probe"))
+		asserts.NoError(err)
+		asserts.Len(entries, 0)
+	}
+
+	// 未知的存储策略
+	{
+		probe := probePolicy(&model.Policy{Type: "unknown"})
+		asserts.Error(probe(context.Background()))
+		probe = probePolicy(&model.Policy{Type: "mock"})
+		asserts.Error(probe(context.Background()))
+	}
+}
+
+func TestProbeAria2(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.ErrorIs(probeAria2(1)(context.Background()), ErrNodeNotLoaded)
+}
@@ -0,0 +1,235 @@
+package ldap
+
+import (
+	"bufio"
+	"errors"
+	"io"
+)
+
+// BER 标签类别
+const (
+	classUniversal   = 0x00
+	classApplication = 0x40
+	classContext     = 0x80
+)
+
+// 通用类型标签
+const (
+	tagBoolean     = 0x01
+	tagInteger     = 0x02
+	tagOctetString = 0x04
+	tagEnumerated  = 0x0a
+	tagSequence    = 0x10
+	tagSet         = 0x11
+)
+
+// 单个报文长度上限，防止恶意服务端耗尽内存
+const maxPacketSize = 16 << 20
+
+var errMalformedPacket = errors.New("malformed BER packet")
+
+// packet BER 编码的数据单元，构造类型的内容保存在 children 中
+type packet struct {
+	class       byte
+	constructed bool
+	tag         byte
+	value       []byte
+	children    []*packet
+}
+
+func newPrimitive(class, tag byte, value []byte) *packet {
+	return &packet{class: class, tag: tag, value: value}
+}
+
+func newConstructed(class, tag byte, children ...*packet) *packet {
+	return &packet{class: class, constructed: true, tag: tag, children: children}
+}
+
+func newSequence(children ...*packet) *packet {
+	return newConstructed(classUniversal, tagSequence, children...)
+}
+
+func newOctetString(s string) *packet {
+	return newPrimitive(classUniversal, tagOctetString, []byte(s))
+}
+
+func newBoolean(b bool) *packet {
+	if b {
+		return newPrimitive(classUniversal, tagBoolean, []byte{0xff})
+	}
+	return newPrimitive(classUniversal, tagBoolean, []byte{0x00})
+}
+
+func newInteger(i int64) *packet {
+	return newPrimitive(classUniversal, tagInteger, encodeInt(i))
+}
+
+func newEnumerated(i int64) *packet {
+	return newPrimitive(classUniversal, tagEnumerated, encodeInt(i))
+}
+
+// encodeInt 以最短的二进制补码形式编码整数
+func encodeInt(i int64) []byte {
+	n := 1
+	for v := i; v > 127 || v < -128; v >>= 8 {
+		n++
+	}
+
+	res := make([]byte, n)
+	for j := n - 1; j >= 0; j-- {
+		res[j] = byte(i)
+		i >>= 8
+	}
+	return res
+}
+
+// int 将基本类型的内容解码为整数
+func (p *packet) int() int64 {
+	if len(p.value) == 0 {
+		return 0
+	}
+
+	res := int64(int8(p.value[0]))
+	for _, b := range p.value[1:] {
+		res = res<<8 | int64(b)
+	}
+	return res
+}
+
+// string 将基本类型的内容解码为字符串
+func (p *packet) string() string {
+	return string(p.value)
+}
+
+// child 返回第 i 个子元素，不存在时返回空元素
+func (p *packet) child(i int) *packet {
+	if i < len(p.children) {
+		return p.children[i]
+	}
+	return &packet{}
+}
+
+// bytes 返回编码后的完整报文
+func (p *packet) bytes() []byte {
+	content := p.value
+	if p.constructed {
+		content = nil
+		for _, child := range p.children {
+			content = append(content, child.bytes()...)
+		}
+	}
+
+	identifier := p.class | p.tag
+	if p.constructed {
+		identifier |= 0x20
+	}
+
+	res := append([]byte{identifier}, encodeLength(len(content))...)
+	return append(res, content...)
+}
+
+func encodeLength(length int) []byte {
+	if length < 0x80 {
+		return []byte{byte(length)}
+	}
+
+	var res []byte
+	for ; length > 0; length >>= 8 {
+		res = append([]byte{byte(length)}, res...)
+	}
+	return append([]byte{0x80 | byte(len(res))}, res...)
+}
+
+// readPacket 从连接中读取一个完整报文
+func readPacket(r *bufio.Reader) (*packet, error) {
+	identifier, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	length, err := readLength(r)
+	if err != nil {
+		return nil, err
+	}
+
+	content := make([]byte, length)
+	if _, err := io.ReadFull(r, content); err != nil {
+		return nil, err
+	}
+
+	return decodePacket(identifier, content)
+}
+
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if first < 0x80 {
+		return int(first), nil
+	}
+
+	// 不支持不定长编码
+	n := int(first & 0x7f)
+	if n == 0 || n > 4 {
+		return 0, errMalformedPacket
+	}
+
+	length := 0
+	for i := 0; i < n; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+
+	if length > maxPacketSize {
+		return 0, errMalformedPacket
+	}
+	return length, nil
+}
+
+func decodePacket(identifier byte, content []byte) (*packet, error) {
+	p := &packet{
+		class:       identifier & 0xc0,
+		constructed: identifier&0x20 != 0,
+		tag:         identifier & 0x1f,
+	}
+	if !p.constructed {
+		p.value = content
+		return p, nil
+	}
+
+	for len(content) > 0 {
+		if len(content) < 2 {
+			return nil, errMalformedPacket
+		}
+
+		length, offset := int(content[1]), 2
+		if length >= 0x80 {
+			n := length & 0x7f
+			if n == 0 || n > 4 || len(content) < 2+n {
+				return nil, errMalformedPacket
+			}
+			length = 0
+			for _, b := range content[2 : 2+n] {
+				length = length<<8 | int(b)
+			}
+			offset += n
+		}
+
+		if length < 0 || len(content) < offset+length {
+			return nil, errMalformedPacket
+		}
+
+		child, err := decodePacket(content[0], content[offset:offset+length])
+		if err != nil {
+			return nil, err
+		}
+		p.children = append(p.children, child)
+		content = content[offset+length:]
+	}
+
+	return p, nil
+}
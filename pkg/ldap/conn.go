@@ -0,0 +1,252 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 协议操作标签
+const (
+	opBindRequest     = 0
+	opBindResponse    = 1
+	opUnbindRequest   = 2
+	opSearchRequest   = 3
+	opSearchEntry     = 4
+	opSearchDone      = 5
+	opSearchReference = 19
+)
+
+// 结果码
+const (
+	ResultSuccess            = 0
+	ResultInvalidCredentials = 49
+)
+
+var (
+	// ErrInvalidURL 无效的服务器地址
+	ErrInvalidURL = errors.New("invalid LDAP server URL, must be ldap:// or ldaps://")
+	// ErrUnexpectedResponse 服务端返回了无法识别的响应
+	ErrUnexpectedResponse = errors.New("unexpected LDAP response")
+)
+
+// Error 服务端返回的错误结果
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("LDAP result code %d: %s", e.Code, e.Message)
+}
+
+// IsInvalidCredentials 返回错误是否为凭据无效
+func IsInvalidCredentials(err error) bool {
+	var ldapErr *Error
+	return errors.As(err, &ldapErr) && ldapErr.Code == ResultInvalidCredentials
+}
+
+// Entry 搜索结果条目
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+}
+
+// Get 返回属性的第一个值，属性名不区分大小写
+func (entry *Entry) Get(name string) string {
+	if values := entry.GetAll(name); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// GetAll 返回属性的所有值，属性名不区分大小写
+func (entry *Entry) GetAll(name string) []string {
+	for attr, values := range entry.Attributes {
+		if strings.EqualFold(attr, name) {
+			return values
+		}
+	}
+	return nil
+}
+
+// Conn LDAP 连接，不支持并发使用
+type Conn struct {
+	conn    net.Conn
+	reader  *bufio.Reader
+	timeout time.Duration
+	msgID   int64
+}
+
+// Dial 连接 ldap:// 或 ldaps:// 地址指定的服务器，timeout 同时作为单次操作的超时时间
+func Dial(rawURL string, timeout time.Duration) (*Conn, error) {
+	serverURL, err := url.Parse(rawURL)
+	if err != nil || serverURL.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+
+	host := serverURL.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch strings.ToLower(serverURL.Scheme) {
+	case "ldap":
+		if serverURL.Port() == "" {
+			host = net.JoinHostPort(serverURL.Hostname(), "389")
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		if serverURL.Port() == "" {
+			host = net.JoinHostPort(serverURL.Hostname(), "636")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: serverURL.Hostname()})
+	default:
+		return nil, ErrInvalidURL
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(conn, timeout), nil
+}
+
+// NewConn 使用已建立的连接创建 LDAP 连接
+func NewConn(conn net.Conn, timeout time.Duration) *Conn {
+	return &Conn{conn: conn, reader: bufio.NewReader(conn), timeout: timeout}
+}
+
+// Close 发送解绑请求并关闭连接
+func (c *Conn) Close() error {
+	c.send(newPrimitive(classApplication, opUnbindRequest, nil))
+	return c.conn.Close()
+}
+
+// Bind 使用简单认证绑定给定 DN
+func (c *Conn) Bind(dn, password string) error {
+	req := newConstructed(classApplication, opBindRequest,
+		newInteger(3),
+		newOctetString(dn),
+		newPrimitive(classContext, 0, []byte(password)),
+	)
+
+	id, err := c.send(req)
+	if err != nil {
+		return err
+	}
+
+	op, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if op.tag != opBindResponse {
+		return ErrUnexpectedResponse
+	}
+	return resultError(op)
+}
+
+// Search 在 baseDN 子树下搜索满足过滤器的条目，最多返回 limit 条，0 表示不限制
+func (c *Conn) Search(baseDN, filter string, attributes []string, limit int) ([]*Entry, error) {
+	filterPacket, err := compileFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := newSequence()
+	for _, attr := range attributes {
+		attrs.children = append(attrs.children, newOctetString(attr))
+	}
+
+	req := newConstructed(classApplication, opSearchRequest,
+		newOctetString(baseDN),
+		newEnumerated(2), // wholeSubtree
+		newEnumerated(0), // neverDerefAliases
+		newInteger(int64(limit)),
+		newInteger(int64(c.timeout/time.Second)),
+		newBoolean(false),
+		filterPacket,
+		attrs,
+	)
+
+	id, err := c.send(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*Entry
+	for {
+		op, err := c.receive(id)
+		if err != nil {
+			return nil, err
+		}
+
+		switch op.tag {
+		case opSearchEntry:
+			entries = append(entries, parseEntry(op))
+		case opSearchReference:
+			// 忽略引用
+		case opSearchDone:
+			return entries, resultError(op)
+		default:
+			return nil, ErrUnexpectedResponse
+		}
+	}
+}
+
+func (c *Conn) send(op *packet) (int64, error) {
+	c.msgID++
+	msg := newSequence(newInteger(c.msgID), op)
+
+	if c.timeout > 0 {
+		c.conn.SetDeadline(time.Now().Add(c.timeout))
+	}
+	_, err := c.conn.Write(msg.bytes())
+	return c.msgID, err
+}
+
+// receive 读取给定消息ID的响应，返回其中的协议操作
+func (c *Conn) receive(id int64) (*packet, error) {
+	for {
+		msg, err := readPacket(c.reader)
+		if err != nil {
+			return nil, err
+		}
+
+		if !msg.constructed || len(msg.children) < 2 || msg.child(1).class != classApplication {
+			return nil, ErrUnexpectedResponse
+		}
+
+		// 跳过其他消息，如服务端主动发送的通知
+		if msg.child(0).int() != id {
+			continue
+		}
+		return msg.child(1), nil
+	}
+}
+
+// resultError 将 LDAPResult 转换为错误，成功时返回 nil
+func resultError(op *packet) error {
+	code := int(op.child(0).int())
+	if code == ResultSuccess {
+		return nil
+	}
+	return &Error{Code: code, Message: op.child(2).string()}
+}
+
+func parseEntry(op *packet) *Entry {
+	entry := &Entry{
+		DN:         op.child(0).string(),
+		Attributes: make(map[string][]string),
+	}
+
+	for _, attr := range op.child(1).children {
+		name := attr.child(0).string()
+		for _, value := range attr.child(1).children {
+			entry.Attributes[name] = append(entry.Attributes[name], value.string())
+		}
+	}
+	return entry
+}
@@ -0,0 +1,125 @@
+package ldap
+
+import (
+	"bufio"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeServer 模拟的目录服务器，按请求类型返回预设响应
+func fakeServer(t *testing.T, conn net.Conn, password string, entries []*packet) {
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := readPacket(reader)
+		if err != nil {
+			return
+		}
+
+		id := msg.child(0).int()
+		op := msg.child(1)
+		reply := func(op *packet) {
+			conn.Write(newSequence(newInteger(id), op).bytes())
+		}
+		result := func(tag byte, code int64) *packet {
+			return newConstructed(classApplication, tag, newEnumerated(code), newOctetString(""), newOctetString("message"))
+		}
+
+		switch op.tag {
+		case opBindRequest:
+			code := int64(ResultSuccess)
+			if op.child(2).string() != password {
+				code = ResultInvalidCredentials
+			}
+			reply(result(opBindResponse, code))
+		case opSearchRequest:
+			// 不相关的消息应被跳过
+			conn.Write(newSequence(newInteger(id+100), result(opSearchDone, 0)).bytes())
+			for _, entry := range entries {
+				reply(entry)
+			}
+			reply(newConstructed(classApplication, opSearchReference, newOctetString("ldap://other")))
+			reply(result(opSearchDone, 0))
+		case opUnbindRequest:
+			conn.Close()
+			return
+		}
+	}
+}
+
+func newEntry(dn string, attrs map[string][]string) *packet {
+	list := newSequence()
+	for name, values := range attrs {
+		set := newConstructed(classUniversal, tagSet)
+		for _, value := range values {
+			set.children = append(set.children, newOctetString(value))
+		}
+		list.children = append(list.children, newSequence(newOctetString(name), set))
+	}
+	return newConstructed(classApplication, opSearchEntry, newOctetString(dn), list)
+}
+
+func TestConn(t *testing.T) {
+	asserts := assert.New(t)
+	client, server := net.Pipe()
+	go fakeServer(t, server, "secret", []*packet{
+		newEntry("uid=john,dc=example,dc=com", map[string][]string{
+			"mail":     {"john@example.com"},
+			"memberOf": {"cn=a", "cn=b"},
+		}),
+	})
+
+	conn := NewConn(client, time.Second)
+
+	// 绑定
+	asserts.NoError(conn.Bind("cn=admin", "secret"))
+	err := conn.Bind("cn=admin", "wrong")
+	asserts.Error(err)
+	asserts.True(IsInvalidCredentials(err))
+
+	// 搜索
+	entries, err := conn.Search("dc=example,dc=com", "(uid=john)", []string{"mail", "memberOf"}, 0)
+	asserts.NoError(err)
+	asserts.Len(entries, 1)
+	asserts.Equal("uid=john,dc=example,dc=com", entries[0].DN)
+	asserts.Equal("john@example.com", entries[0].Get("MAIL"))
+	asserts.Equal([]string{"cn=a", "cn=b"}, entries[0].GetAll("memberof"))
+	asserts.Equal("", entries[0].Get("cn"))
+
+	// 无效过滤器
+	_, err = conn.Search("dc=example,dc=com", "uid=john", nil, 0)
+	asserts.Error(err)
+
+	asserts.NoError(conn.Close())
+}
+
+func TestPacket(t *testing.T) {
+	asserts := assert.New(t)
+
+	for _, i := range []int64{0, 1, 127, 128, 255, 256, 65536, -1, -129} {
+		asserts.Equal(i, newInteger(i).int(), i)
+	}
+
+	// 长内容使用长格式长度
+	long := newSequence(newOctetString(string(make([]byte, 300))), newBoolean(true))
+	data := long.bytes()
+	asserts.Equal([]byte{0x30, 0x82, 0x01, 0x33}, data[:4])
+	p, err := decodePacket(data[0], data[4:])
+	asserts.NoError(err)
+	asserts.Len(p.children, 2)
+	asserts.Len(p.child(0).value, 300)
+
+	// 内容被截断
+	_, err = decodePacket(0x30, []byte{0x04, 0x05, 0x01})
+	asserts.Error(err)
+}
+
+func TestDial(t *testing.T) {
+	asserts := assert.New(t)
+	_, err := Dial("http://example.com", time.Second)
+	asserts.Equal(ErrInvalidURL, err)
+	_, err = Dial("ldap://", time.Second)
+	asserts.Equal(ErrInvalidURL, err)
+}
@@ -0,0 +1,126 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// 过滤器标签
+const (
+	filterAnd      = 0
+	filterOr       = 1
+	filterNot      = 2
+	filterEquality = 3
+	filterPresent  = 7
+)
+
+// EscapeFilter 转义过滤器中的特殊字符，用于将用户输入拼接进过滤器
+func EscapeFilter(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// compileFilter 将字符串形式的过滤器编码为报文，支持与、或、非、相等及存在性判断
+func compileFilter(filter string) (*packet, error) {
+	p, rest, err := parseFilter(strings.TrimSpace(filter))
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("unexpected %q at end of filter", rest)
+	}
+	return p, nil
+}
+
+func parseFilter(s string) (*packet, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, s, fmt.Errorf("filter %q must start with '('", s)
+	}
+	s = s[1:]
+
+	switch {
+	case strings.HasPrefix(s, "&"), strings.HasPrefix(s, "|"):
+		tag := byte(filterAnd)
+		if s[0] == '|' {
+			tag = filterOr
+		}
+		set := newConstructed(classContext, tag)
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, rest, err
+			}
+			set.children = append(set.children, child)
+			s = rest
+		}
+		if len(set.children) == 0 || !strings.HasPrefix(s, ")") {
+			return nil, s, fmt.Errorf("invalid filter set")
+		}
+		return set, s[1:], nil
+	case strings.HasPrefix(s, "!"):
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, rest, err
+		}
+		if !strings.HasPrefix(rest, ")") {
+			return nil, rest, fmt.Errorf("invalid not filter")
+		}
+		return newConstructed(classContext, filterNot, child), rest[1:], nil
+	}
+
+	end := strings.Index(s, ")")
+	if end < 0 {
+		return nil, s, fmt.Errorf("unterminated filter")
+	}
+	item, rest := s[:end], s[end+1:]
+
+	eq := strings.Index(item, "=")
+	if eq <= 0 {
+		return nil, rest, fmt.Errorf("invalid filter item %q", item)
+	}
+	attr, value := item[:eq], item[eq+1:]
+
+	if value == "*" {
+		return newPrimitive(classContext, filterPresent, []byte(attr)), rest, nil
+	}
+	if strings.Contains(value, "*") {
+		return nil, rest, fmt.Errorf("substring filter is not supported")
+	}
+
+	decoded, err := unescapeFilter(value)
+	if err != nil {
+		return nil, rest, err
+	}
+	return newConstructed(classContext, filterEquality, newOctetString(attr), newOctetString(decoded)), rest, nil
+}
+
+// unescapeFilter 还原过滤器值中 \XX 形式的转义字符
+func unescapeFilter(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		c, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid escape in %q", s)
+		}
+		b.Write(c)
+		i += 2
+	}
+	return b.String(), nil
+}
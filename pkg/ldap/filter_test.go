@@ -0,0 +1,48 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEscapeFilter(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("john", EscapeFilter("john"))
+	asserts.Equal(`\2a\29\28uid=\5c`, EscapeFilter(`*)(uid=\`))
+}
+
+func TestCompileFilter(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 相等
+	{
+		p, err := compileFilter("(uid=john)")
+		asserts.NoError(err)
+		asserts.EqualValues(filterEquality, p.tag)
+		asserts.Equal("uid", p.child(0).string())
+		asserts.Equal("john", p.child(1).string())
+	}
+
+	// 组合及转义
+	{
+		p, err := compileFilter(`(&(objectClass=*)(!(uid=a\2ab))(|(mail=x)(mail=y)))`)
+		asserts.NoError(err)
+		asserts.EqualValues(filterAnd, p.tag)
+		asserts.Len(p.children, 3)
+		asserts.EqualValues(filterPresent, p.child(0).tag)
+		asserts.Equal("objectClass", p.child(0).string())
+		asserts.EqualValues(filterNot, p.child(1).tag)
+		asserts.Equal("a*b", p.child(1).child(0).child(1).string())
+		asserts.EqualValues(filterOr, p.child(2).tag)
+		asserts.Len(p.child(2).children, 2)
+	}
+
+	// 无效过滤器
+	{
+		for _, filter := range []string{"", "uid=john", "(uid=john", "(uid=jo*)", "(&)", "(uid=\\2)", "(uid=a)(uid=b)", "(=a)"} {
+			_, err := compileFilter(filter)
+			asserts.Error(err, filter)
+		}
+	}
+}
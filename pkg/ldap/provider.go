@@ -0,0 +1,232 @@
+package ldap
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+var (
+	// ErrUserNotFound 目录中不存在唯一匹配的用户
+	ErrUserNotFound = errors.New("user not found in LDAP directory")
+	// ErrInvalidCredentials 用户名或密码错误
+	ErrInvalidCredentials = errors.New("invalid LDAP credentials")
+	// ErrEmailMissing 目录中的用户没有邮箱属性，无法创建对应用户
+	ErrEmailMissing = errors.New("LDAP user has no email attribute")
+)
+
+// Config LDAP 认证设置
+type Config struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// 查找用户的过滤器，{username} 会被替换为转义后的登录名
+	UserFilter string
+	EmailAttr  string
+	NickAttr   string
+	GroupAttr  string
+	// 目录中的组 DN 与用户组ID的映射
+	GroupMap     map[string]uint
+	DefaultGroup uint
+	Timeout      time.Duration
+}
+
+// Enabled 返回是否启用了 LDAP 认证
+func Enabled() bool {
+	return model.IsTrueVal(model.GetSettingByName("ldap_enabled"))
+}
+
+// NewConfig 从站点设置读取 LDAP 认证设置
+func NewConfig() *Config {
+	options := model.GetSettingByNames(
+		"ldap_url",
+		"ldap_bind_dn",
+		"ldap_bind_password",
+		"ldap_base_dn",
+		"ldap_user_filter",
+		"ldap_email_attr",
+		"ldap_nick_attr",
+		"ldap_group_attr",
+		"ldap_group_map",
+	)
+
+	conf := &Config{
+		URL:          options["ldap_url"],
+		BindDN:       options["ldap_bind_dn"],
+		BindPassword: options["ldap_bind_password"],
+		BaseDN:       options["ldap_base_dn"],
+		UserFilter:   options["ldap_user_filter"],
+		EmailAttr:    options["ldap_email_attr"],
+		NickAttr:     options["ldap_nick_attr"],
+		GroupAttr:    options["ldap_group_attr"],
+		GroupMap:     make(map[string]uint),
+		DefaultGroup: uint(model.GetIntSetting("default_group", 2)),
+		Timeout:      time.Duration(model.GetIntSetting("ldap_timeout", 10)) * time.Second,
+	}
+
+	if options["ldap_group_map"] != "" {
+		if err := json.Unmarshal([]byte(options["ldap_group_map"]), &conf.GroupMap); err != nil {
+			util.Log().Warning("无法解析 LDAP 用户组映射, %s", err)
+		}
+	}
+
+	return conf
+}
+
+// Identity 目录中的用户信息
+type Identity struct {
+	Username string
+	DN       string
+	Email    string
+	Nick     string
+	Groups   []string
+}
+
+// Directory 已使用服务账户绑定的目录连接
+type Directory struct {
+	conf *Config
+	conn *Conn
+}
+
+// Open 连接目录服务器，并使用服务账户绑定，未设定服务账户时匿名绑定
+func (conf *Config) Open() (*Directory, error) {
+	conn, err := Dial(conf.URL, conf.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.BindDN != "" {
+		if err := conn.Bind(conf.BindDN, conf.BindPassword); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &Directory{conf: conf, conn: conn}, nil
+}
+
+// Close 关闭目录连接
+func (dir *Directory) Close() {
+	dir.conn.Close()
+}
+
+// Lookup 按登录名查找目录中的用户
+func (dir *Directory) Lookup(username string) (*Identity, error) {
+	filter := strings.ReplaceAll(dir.conf.UserFilter, "{username}", EscapeFilter(username))
+	attrs := []string{dir.conf.EmailAttr, dir.conf.NickAttr, dir.conf.GroupAttr}
+	entries, err := dir.conn.Search(dir.conf.BaseDN, filter, attrs, 2)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 {
+		return nil, ErrUserNotFound
+	}
+
+	entry := entries[0]
+	return &Identity{
+		Username: username,
+		DN:       entry.DN,
+		Email:    strings.ToLower(entry.Get(dir.conf.EmailAttr)),
+		Nick:     entry.Get(dir.conf.NickAttr),
+		Groups:   entry.GetAll(dir.conf.GroupAttr),
+	}, nil
+}
+
+// Authenticate 查找用户并以其 DN 和密码绑定，验证用户凭据
+func (conf *Config) Authenticate(username, password string) (*Identity, error) {
+	// 空密码会被服务端视为匿名绑定而通过
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	dir, err := conf.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer dir.Close()
+
+	identity, err := dir.Lookup(username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := dir.conn.Bind(identity.DN, password); err != nil {
+		if IsInvalidCredentials(err) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return identity, nil
+}
+
+// MapGroup 根据用户所属的目录组返回对应的用户组ID，无匹配时返回默认用户组
+func (conf *Config) MapGroup(groups []string) uint {
+	for _, group := range groups {
+		for dn, id := range conf.GroupMap {
+			if strings.EqualFold(strings.TrimSpace(dn), strings.TrimSpace(group)) {
+				return id
+			}
+		}
+	}
+	return conf.DefaultGroup
+}
+
+// Provision 为首次登录的目录用户创建对应的用户
+func (conf *Config) Provision(identity *Identity) (*model.User, error) {
+	if identity.Email == "" {
+		return nil, ErrEmailMissing
+	}
+
+	user := model.NewUser()
+	user.Email = identity.Email
+	user.Nick = identity.Nick
+	if user.Nick == "" {
+		user.Nick = identity.Username
+	}
+	user.Status = model.Active
+	user.GroupID = conf.MapGroup(identity.Groups)
+	user.LDAPUser = identity.Username
+	if err := model.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SyncUsers 从目录同步所有 LDAP 用户的昵称及用户组，目录中已不存在的用户保持不变
+func (conf *Config) SyncUsers() error {
+	users, err := model.ListLDAPUsers()
+	if err != nil || len(users) == 0 {
+		return err
+	}
+
+	dir, err := conf.Open()
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	for _, user := range users {
+		identity, err := dir.Lookup(user.LDAPUser)
+		if err != nil {
+			util.Log().Debug("无法在目录中找到用户 [%s], %s", user.LDAPUser, err)
+			continue
+		}
+
+		props := map[string]interface{}{"group_id": conf.MapGroup(identity.Groups)}
+		if identity.Nick != "" {
+			props["nick"] = identity.Nick
+		}
+		if err := model.DB.Model(&user).Updates(props).Error; err != nil {
+			util.Log().Warning("无法同步 LDAP 用户 [%s], %s", user.LDAPUser, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package ldap
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_MapGroup(t *testing.T) {
+	asserts := assert.New(t)
+	conf := &Config{
+		GroupMap:     map[string]uint{"CN=Admins,DC=example,DC=com": 1},
+		DefaultGroup: 2,
+	}
+
+	asserts.EqualValues(2, conf.MapGroup(nil))
+	asserts.EqualValues(2, conf.MapGroup([]string{"cn=users,dc=example,dc=com"}))
+	asserts.EqualValues(1, conf.MapGroup([]string{"cn=users,dc=example,dc=com", "cn=admins,dc=example,dc=com"}))
+}
+
+func TestConfig_Authenticate(t *testing.T) {
+	asserts := assert.New(t)
+	conf := &Config{URL: "invalid"}
+
+	// 空密码不允许匿名绑定
+	_, err := conf.Authenticate("john", "")
+	asserts.Equal(ErrInvalidCredentials, err)
+
+	// 地址无效
+	_, err = conf.Authenticate("john", "secret")
+	asserts.Equal(ErrInvalidURL, err)
+}
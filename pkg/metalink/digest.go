@@ -0,0 +1,87 @@
+package metalink
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/gob"
+	"errors"
+	"io"
+)
+
+const (
+	// minPieceLength 最小分块大小
+	minPieceLength = 256 << 10
+	// maxPieceLength 最大分块大小，多数 BT 客户端不支持更大的分块
+	maxPieceLength = 16 << 20
+	// maxPieces 分块数量的期望上限，超出时增大分块大小
+	maxPieces = 2048
+)
+
+// ErrSizeMismatch 读取的内容长度与文件大小不符
+var ErrSizeMismatch = errors.New("content size does not match file size")
+
+// Digest 文件内容的校验信息
+type Digest struct {
+	Size        uint64
+	SHA256      []byte
+	PieceLength int64
+	// Pieces 各分块 SHA1 摘要的拼接
+	Pieces []byte
+}
+
+func init() {
+	// 注册缓存用到的复杂结构
+	gob.Register(Digest{})
+}
+
+// PieceLength 根据文件大小选择分块大小，为 2 的幂次
+func PieceLength(size uint64) int64 {
+	length := int64(minPieceLength)
+	for length < maxPieceLength && size > uint64(length)*maxPieces {
+		length <<= 1
+	}
+	return length
+}
+
+// Compute 读取文件内容，计算整体的 SHA256 摘要及各分块的 SHA1 摘要
+func Compute(r io.Reader, size uint64) (*Digest, error) {
+	digest := &Digest{
+		Size:        size,
+		PieceLength: PieceLength(size),
+		Pieces:      make([]byte, 0, (size/uint64(PieceLength(size))+1)*sha1.Size),
+	}
+
+	whole := sha256.New()
+	var read uint64
+	for {
+		piece := sha1.New()
+		n, err := io.CopyN(io.MultiWriter(whole, piece), r, digest.PieceLength)
+		if n > 0 {
+			digest.Pieces = piece.Sum(digest.Pieces)
+			read += uint64(n)
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	if read != size {
+		return nil, ErrSizeMismatch
+	}
+
+	digest.SHA256 = whole.Sum(nil)
+	return digest, nil
+}
+
+// PieceCount 分块数量
+func (digest *Digest) PieceCount() int {
+	return len(digest.Pieces) / sha1.Size
+}
+
+// Piece 第 i 个分块的 SHA1 摘要
+func (digest *Digest) Piece(i int) []byte {
+	return digest.Pieces[i*sha1.Size : (i+1)*sha1.Size]
+}
@@ -0,0 +1,98 @@
+package metalink
+
+import (
+	"encoding/hex"
+	"encoding/xml"
+	"path"
+	"time"
+)
+
+// Descriptor 生成下载描述文件所需的信息
+type Descriptor struct {
+	// Name 文件名
+	Name string
+	// Digest 文件内容的校验信息
+	Digest *Digest
+	// URLs 下载地址，按优先级排列
+	URLs []string
+	// Published 文件的发布时间
+	Published time.Time
+	// Generator 生成者，如 Cloudreve/3.x
+	Generator string
+	// Comment 附加说明，如分享链接
+	Comment string
+}
+
+// fileName 去除文件名中的路径，避免下载器写入其他目录
+func (d *Descriptor) fileName() string {
+	return path.Base("/" + d.Name)
+}
+
+type metalinkHash struct {
+	Type  string `xml:"type,attr,omitempty"`
+	Value string `xml:",chardata"`
+}
+
+type metalinkPieces struct {
+	Length int64          `xml:"length,attr"`
+	Type   string         `xml:"type,attr"`
+	Hashes []metalinkHash `xml:"hash"`
+}
+
+type metalinkURL struct {
+	Priority int    `xml:"priority,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type metalinkFile struct {
+	Name        string          `xml:"name,attr"`
+	Description string          `xml:"description,omitempty"`
+	Size        uint64          `xml:"size"`
+	Hash        metalinkHash    `xml:"hash"`
+	Pieces      *metalinkPieces `xml:"pieces,omitempty"`
+	URLs        []metalinkURL   `xml:"url"`
+}
+
+type metalinkDoc struct {
+	XMLName   xml.Name     `xml:"urn:ietf:params:xml:ns:metalink metalink"`
+	Generator string       `xml:"generator,omitempty"`
+	Published string       `xml:"published"`
+	File      metalinkFile `xml:"file"`
+}
+
+// Metalink 生成 Metalink 4 (RFC 5854) 描述文件，包含整体及分块摘要，
+// 下载器可据此从多个地址断点续传并校验内容
+func Metalink(d *Descriptor) ([]byte, error) {
+	doc := metalinkDoc{
+		Generator: d.Generator,
+		Published: d.Published.UTC().Format(time.RFC3339),
+		File: metalinkFile{
+			Name:        d.fileName(),
+			Description: d.Comment,
+			Size:        d.Digest.Size,
+			Hash:        metalinkHash{Type: "sha-256", Value: hex.EncodeToString(d.Digest.SHA256)},
+		},
+	}
+
+	if count := d.Digest.PieceCount(); count > 0 {
+		doc.File.Pieces = &metalinkPieces{
+			Length: d.Digest.PieceLength,
+			Type:   "sha-1",
+			Hashes: make([]metalinkHash, count),
+		}
+		for i := 0; i < count; i++ {
+			doc.File.Pieces.Hashes[i].Value = hex.EncodeToString(d.Digest.Piece(i))
+		}
+	}
+
+	for i, u := range d.URLs {
+		doc.File.URLs = append(doc.File.URLs, metalinkURL{Priority: i + 1, Value: u})
+	}
+
+	res, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), res...), nil
+}
@@ -0,0 +1,144 @@
+package metalink
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("error")
+}
+
+func TestPieceLength(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.EqualValues(256<<10, PieceLength(0))
+	asserts.EqualValues(256<<10, PieceLength(512<<20))
+	asserts.EqualValues(512<<10, PieceLength(512<<20+1))
+	asserts.EqualValues(4<<20, PieceLength(8<<30))
+	asserts.EqualValues(16<<20, PieceLength(1<<40))
+}
+
+func TestCompute(t *testing.T) {
+	asserts := assert.New(t)
+	content := bytes.Repeat([]byte("0123456789"), 60<<10)
+
+	// 成功
+	{
+		digest, err := Compute(bytes.NewReader(content), uint64(len(content)))
+		asserts.NoError(err)
+		sum := sha256.Sum256(content)
+		asserts.Equal(sum[:], digest.SHA256)
+		asserts.EqualValues(256<<10, digest.PieceLength)
+		asserts.Equal(3, digest.PieceCount())
+
+		first := sha1.Sum(content[:256<<10])
+		last := sha1.Sum(content[512<<10:])
+		asserts.Equal(first[:], digest.Piece(0))
+		asserts.Equal(last[:], digest.Piece(2))
+	}
+
+	// 空文件
+	{
+		digest, err := Compute(bytes.NewReader(nil), 0)
+		asserts.NoError(err)
+		asserts.Equal(0, digest.PieceCount())
+		sum := sha256.Sum256(nil)
+		asserts.Equal(sum[:], digest.SHA256)
+	}
+
+	// 大小不符
+	{
+		_, err := Compute(bytes.NewReader(content), uint64(len(content))+1)
+		asserts.Equal(ErrSizeMismatch, err)
+	}
+
+	// 读取失败
+	{
+		_, err := Compute(errReader{}, 10)
+		asserts.Error(err)
+	}
+}
+
+func testDescriptor(t *testing.T) *Descriptor {
+	digest, err := Compute(strings.NewReader("hello world"), 11)
+	assert.NoError(t, err)
+	return &Descriptor{
+		Name:      "../dir/hello.txt",
+		Digest:    digest,
+		URLs:      []string{"http://a/hello", "http://b/hello"},
+		Published: time.Unix(1600000000, 0),
+		Generator: "Cloudreve/test",
+		Comment:   "http://a/s/key",
+	}
+}
+
+func TestMetalink(t *testing.T) {
+	asserts := assert.New(t)
+	d := testDescriptor(t)
+
+	res, err := Metalink(d)
+	asserts.NoError(err)
+	asserts.True(bytes.HasPrefix(res, []byte(xml.Header)))
+
+	var doc metalinkDoc
+	asserts.NoError(xml.Unmarshal(res, &doc))
+	asserts.Equal("urn:ietf:params:xml:ns:metalink", doc.XMLName.Space)
+	asserts.Equal("2020-09-13T12:26:40Z", doc.Published)
+	asserts.Equal("Cloudreve/test", doc.Generator)
+	asserts.Equal("hello.txt", doc.File.Name)
+	asserts.EqualValues(11, doc.File.Size)
+	asserts.Equal("sha-256", doc.File.Hash.Type)
+	asserts.Equal("b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", doc.File.Hash.Value)
+	asserts.Len(doc.File.Pieces.Hashes, 1)
+	asserts.Equal(hex.EncodeToString(d.Digest.Piece(0)), doc.File.Pieces.Hashes[0].Value)
+	asserts.Equal([]metalinkURL{{1, "http://a/hello"}, {2, "http://b/hello"}}, doc.File.URLs)
+}
+
+func TestTorrent(t *testing.T) {
+	asserts := assert.New(t)
+	d := testDescriptor(t)
+
+	res, err := Torrent(d)
+	asserts.NoError(err)
+
+	var info bytes.Buffer
+	asserts.NoError(bencode(&info, map[string]interface{}{
+		"length":       int64(11),
+		"name":         "hello.txt",
+		"piece length": int64(256 << 10),
+		"pieces":       d.Digest.Pieces,
+	}))
+
+	expected := "d7:comment14:http://a/s/key10:created by14:Cloudreve/test13:creation datei1600000000e" +
+		"4:info" + info.String() +
+		"8:url-listl14:http://a/hello14:http://b/helloee"
+	asserts.Equal(expected, string(res))
+}
+
+func TestBencode(t *testing.T) {
+	asserts := assert.New(t)
+
+	var buf bytes.Buffer
+	asserts.NoError(bencode(&buf, map[string]interface{}{
+		"b": []interface{}{int64(-1), "x"},
+		"a": []byte{0, 1},
+	}))
+	asserts.Equal("d1:a2:\x00\x011:bli-1e1:xee", buf.String())
+
+	buf.Reset()
+	asserts.Error(bencode(&buf, 1.5))
+	asserts.Error(bencode(&buf, []interface{}{1.5}))
+	asserts.Error(bencode(&buf, map[string]interface{}{"a": 1.5}))
+}
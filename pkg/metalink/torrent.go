@@ -0,0 +1,85 @@
+package metalink
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Torrent 生成单文件种子，不包含 Tracker，以下载地址作为 Web Seed (BEP 19)，
+// 供 BT 客户端校验分块并断点续传
+func Torrent(d *Descriptor) ([]byte, error) {
+	urls := make([]interface{}, len(d.URLs))
+	for i, u := range d.URLs {
+		urls[i] = u
+	}
+
+	torrent := map[string]interface{}{
+		"creation date": d.Published.Unix(),
+		"info": map[string]interface{}{
+			"name":         d.fileName(),
+			"length":       int64(d.Digest.Size),
+			"piece length": d.Digest.PieceLength,
+			"pieces":       d.Digest.Pieces,
+		},
+		"url-list": urls,
+	}
+	if d.Generator != "" {
+		torrent["created by"] = d.Generator
+	}
+	if d.Comment != "" {
+		torrent["comment"] = d.Comment
+	}
+
+	var buf bytes.Buffer
+	if err := bencode(&buf, torrent); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// bencode 按 BitTorrent 规范编码，字典的键按字节序排列
+func bencode(buf *bytes.Buffer, v interface{}) error {
+	switch value := v.(type) {
+	case string:
+		buf.WriteString(strconv.Itoa(len(value)))
+		buf.WriteByte(':')
+		buf.WriteString(value)
+	case []byte:
+		buf.WriteString(strconv.Itoa(len(value)))
+		buf.WriteByte(':')
+		buf.Write(value)
+	case int64:
+		buf.WriteByte('i')
+		buf.WriteString(strconv.FormatInt(value, 10))
+		buf.WriteByte('e')
+	case []interface{}:
+		buf.WriteByte('l')
+		for _, item := range value {
+			if err := bencode(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		buf.WriteByte('d')
+		for _, k := range keys {
+			bencode(buf, k)
+			if err := bencode(buf, value[k]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('e')
+	default:
+		return fmt.Errorf("bencode: unsupported type %T", v)
+	}
+
+	return nil
+}
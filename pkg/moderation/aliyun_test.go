@@ -0,0 +1,64 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliyun_Review(t *testing.T) {
+	asserts := assert.New(t)
+	pollInterval = time.Millisecond
+	polled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asserts.True(strings.HasPrefix(r.Header.Get("Authorization"), "acs ak:"))
+		switch r.URL.Path {
+		case "/green/image/scan":
+			w.Write([]byte(`{"code":200,"data":[{"code":200,"results":[
+				{"scene":"porn","suggestion":"review","label":"sexy","rate":80},
+				{"scene":"terrorism","suggestion":"pass","label":"normal","rate":99}]}]}`))
+		case "/green/video/asyncscan":
+			w.Write([]byte(`{"code":200,"data":[{"code":200,"taskId":"task"}]}`))
+		case "/green/video/results":
+			polled++
+			if polled < 2 {
+				w.Write([]byte(`{"code":200,"data":[{"code":280,"taskId":"task"}]}`))
+				return
+			}
+			w.Write([]byte(`{"code":200,"data":[{"code":200,"taskId":"task","results":[
+				{"scene":"porn","suggestion":"block","label":"porn","rate":99.5}]}]}`))
+		default:
+			w.Write([]byte(`{"code":400,"msg":"bad request"}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewAliyun("ak", "sk", "cn-shanghai", []string{"porn", "terrorism"})
+	provider.Endpoint = server.URL
+
+	// 图片
+	res, err := provider.Review(context.Background(), &Media{ID: "1", Kind: KindImage, URL: "http://example.com/a.jpg"})
+	asserts.NoError(err)
+	asserts.Equal(DecisionReview, res.Decision)
+	asserts.Equal("porn:sexy", res.Label)
+
+	// 视频
+	res, err = provider.Review(context.Background(), &Media{ID: "2", Kind: KindVideo, URL: "http://example.com/a.mp4"})
+	asserts.NoError(err)
+	asserts.Equal(DecisionBlock, res.Decision)
+	asserts.Equal(2, polled)
+
+	// 不支持的类型
+	_, err = provider.Review(context.Background(), &Media{Kind: "audio"})
+	asserts.Equal(ErrUnsupportedKind, err)
+
+	// 请求失败
+	provider.Endpoint = server.URL + "/invalid"
+	_, err = provider.Review(context.Background(), &Media{Kind: KindImage})
+	asserts.Error(err)
+}
@@ -0,0 +1,159 @@
+package moderation
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// running 是否正在处理等待审核的记录，避免定时任务重叠执行时重复审核
+var running int32
+
+// decisionStatus 审核结论对应的审核记录状态
+var decisionStatus = map[string]string{
+	DecisionPass:   model.ModerationPass,
+	DecisionReview: model.ModerationReview,
+	DecisionBlock:  model.ModerationBlock,
+}
+
+// NewProvider 根据站点设置创建审核服务
+func NewProvider() (Provider, error) {
+	options := model.GetSettingByNames(
+		"moderation_provider",
+		"moderation_aliyun_access_key", "moderation_aliyun_secret_key", "moderation_aliyun_region", "moderation_aliyun_scenes",
+		"moderation_tencent_secret_id", "moderation_tencent_secret_key", "moderation_tencent_bucket", "moderation_tencent_region",
+		"moderation_nsfw_endpoint", "moderation_nsfw_block_score", "moderation_nsfw_review_score",
+	)
+
+	switch options["moderation_provider"] {
+	case "aliyun":
+		return NewAliyun(
+			options["moderation_aliyun_access_key"],
+			options["moderation_aliyun_secret_key"],
+			options["moderation_aliyun_region"],
+			strings.Split(options["moderation_aliyun_scenes"], ","),
+		), nil
+	case "tencent":
+		return NewTencent(
+			options["moderation_tencent_secret_id"],
+			options["moderation_tencent_secret_key"],
+			options["moderation_tencent_bucket"],
+			options["moderation_tencent_region"],
+		), nil
+	case "nsfw":
+		blockScore, _ := strconv.ParseFloat(options["moderation_nsfw_block_score"], 64)
+		reviewScore, _ := strconv.ParseFloat(options["moderation_nsfw_review_score"], 64)
+		return &NSFW{
+			Endpoint:    options["moderation_nsfw_endpoint"],
+			BlockScore:  blockScore,
+			ReviewScore: reviewScore,
+			Client:      &http.Client{},
+		}, nil
+	}
+
+	return nil, ErrUnknownProvider
+}
+
+// ProcessPending 依次审核等待中的记录，由定时任务调用
+func ProcessPending() error {
+	if !model.IsTrueVal(model.GetSettingByName("moderation_enabled")) {
+		return nil
+	}
+
+	if !atomic.CompareAndSwapInt32(&running, 0, 1) {
+		return nil
+	}
+	defer atomic.StoreInt32(&running, 0)
+
+	provider, err := NewProvider()
+	if err != nil {
+		return err
+	}
+
+	pending, err := model.ListPendingModerations(model.GetIntSetting("moderation_batch_size", 20))
+	if err != nil {
+		return err
+	}
+
+	timeout := time.Duration(model.GetIntSetting("moderation_timeout", 600)) * time.Second
+	for i := range pending {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		Review(ctx, provider, &pending[i])
+		cancel()
+	}
+
+	return nil
+}
+
+// Review 审核单个文件并保存结论，违规或疑似违规时更新文件的分享并通知文件所有者
+func Review(ctx context.Context, provider Provider, moderation *model.Moderation) {
+	maxAttempts := model.GetIntSetting("moderation_max_attempts", 3)
+	result, err := review(ctx, provider, moderation)
+	if err != nil {
+		util.Log().Warning("无法审核文件 [%s], %s", moderation.FileName, err)
+		if err := moderation.Fail(err.Error(), maxAttempts); err != nil {
+			util.Log().Warning("无法更新审核记录, %s", err)
+		}
+		return
+	}
+
+	if err := moderation.Finish(provider.Name(), decisionStatus[result.Decision], result.Label, result.Score); err != nil {
+		util.Log().Warning("无法保存文件 [%s] 的审核结论, %s", moderation.FileName, err)
+		return
+	}
+
+	if moderation.Status == model.ModerationPass {
+		return
+	}
+
+	shareStatus := moderation.ShareStatus()
+	if err := model.UpdateSharesModerationByFile(moderation.FileID, shareStatus); err != nil {
+		util.Log().Warning("无法更新文件 [%s] 的分享状态, %s", moderation.FileName, err)
+	}
+
+	model.RecordAudit(nil, 0, model.AuditModeration, model.AuditTarget("file", moderation.FileID),
+		fmt.Sprintf("%s: %s (%s %.2f)", moderation.Status, moderation.FileName, result.Label, result.Score))
+	if owner, err := model.GetActiveUserByID(moderation.UserID); err == nil {
+		notify.ModerationFailed(&owner, moderation.FileName, result.Label, shareStatus == model.ModerationBlock)
+	}
+}
+
+// review 生成文件的临时地址并提交审核服务
+func review(ctx context.Context, provider Provider, moderation *model.Moderation) (*Result, error) {
+	files, err := model.GetFilesByIDs([]uint{moderation.FileID}, moderation.UserID)
+	if err != nil || len(files) == 0 {
+		return nil, filesystem.ErrObjectNotExist
+	}
+
+	user, err := model.GetUserByID(moderation.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	ttl := int64(model.GetIntSetting("moderation_url_ttl", 3600))
+	source, err := fs.SignURL(ctx, &files[0], ttl, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.Review(ctx, &Media{
+		ID:   strconv.FormatUint(uint64(moderation.ID), 10),
+		Kind: moderation.Kind,
+		URL:  source,
+	})
+}
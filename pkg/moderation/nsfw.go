@@ -0,0 +1,64 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// NSFW 本地部署的 NSFW 识别模型服务。请求正文为 {"url": 文件地址, "type": 媒体类型}，
+// 响应正文为 {"score": 0-1 之间的违规概率, "label": 违规类型}
+type NSFW struct {
+	Endpoint    string
+	BlockScore  float64 // 达到此分数（0-100）时判定违规
+	ReviewScore float64 // 达到此分数（0-100）时需人工复核
+	Client      *http.Client
+}
+
+// Name 审核服务名称
+func (n *NSFW) Name() string {
+	return "nsfw"
+}
+
+// Review 审核媒体文件
+func (n *NSFW) Review(ctx context.Context, media *Media) (*Result, error) {
+	payload, err := json.Marshal(map[string]string{"url": media.URL, "type": media.Kind})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", n.Endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("nsfw model returned unexpected status %d", resp.StatusCode)
+	}
+
+	var res struct {
+		Score float64 `json:"score"`
+		Label string  `json:"label"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return nil, err
+	}
+
+	result := &Result{Decision: DecisionPass, Label: res.Label, Score: res.Score * 100}
+	if result.Score >= n.BlockScore {
+		result.Decision = DecisionBlock
+	} else if result.Score >= n.ReviewScore {
+		result.Decision = DecisionReview
+	}
+
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNSFW_Review(t *testing.T) {
+	asserts := assert.New(t)
+	scores := map[string]float64{"pass.jpg": 0.1, "review.jpg": 0.7, "block.jpg": 0.95}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		score, ok := scores[req["url"]]
+		if !ok {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"score": score, "label": "porn"})
+	}))
+	defer server.Close()
+
+	provider := &NSFW{Endpoint: server.URL, BlockScore: 90, ReviewScore: 60, Client: server.Client()}
+	for url, decision := range map[string]string{
+		"pass.jpg":   DecisionPass,
+		"review.jpg": DecisionReview,
+		"block.jpg":  DecisionBlock,
+	} {
+		res, err := provider.Review(context.Background(), &Media{Kind: KindImage, URL: url})
+		asserts.NoError(err)
+		asserts.Equal(decision, res.Decision, url)
+		asserts.Equal("porn", res.Label)
+	}
+
+	// 服务出错
+	_, err := provider.Review(context.Background(), &Media{Kind: KindImage, URL: "error.jpg"})
+	asserts.Error(err)
+}
@@ -0,0 +1,79 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// 审核结论
+const (
+	// DecisionPass 通过
+	DecisionPass = "pass"
+	// DecisionReview 疑似违规，需要人工复核
+	DecisionReview = "review"
+	// DecisionBlock 违规
+	DecisionBlock = "block"
+)
+
+// 媒体类型
+const (
+	KindImage = "image"
+	KindVideo = "video"
+)
+
+var (
+	// ErrUnknownProvider 未知的审核服务
+	ErrUnknownProvider = errors.New("unknown moderation provider")
+	// ErrUnsupportedKind 审核服务不支持此媒体类型
+	ErrUnsupportedKind = errors.New("unsupported media kind")
+	// ErrEmptyResult 审核服务未返回结果
+	ErrEmptyResult = errors.New("moderation provider returned empty result")
+)
+
+// pollInterval 视频审核任务的结果轮询间隔
+var pollInterval = 5 * time.Second
+
+// Media 待审核的媒体文件
+type Media struct {
+	ID   string // 提交给审核服务的业务标识
+	Kind string // 媒体类型，image 或 video
+	URL  string // 供审核服务拉取文件的临时地址
+}
+
+// Result 审核结果
+type Result struct {
+	Decision string  // 审核结论
+	Label    string  // 违规类型
+	Score    float64 // 置信度，0-100
+}
+
+// Provider 内容审核服务
+type Provider interface {
+	// Name 审核服务名称
+	Name() string
+	// Review 审核媒体文件，视频等异步审核的任务会轮询至得出结论或 ctx 结束
+	Review(ctx context.Context, media *Media) (*Result, error)
+}
+
+// severity 审核结论的严重程度
+func severity(decision string) int {
+	switch decision {
+	case DecisionBlock:
+		return 2
+	case DecisionReview:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// wait 等待下一次轮询，ctx 结束时返回错误
+func wait(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(pollInterval):
+		return nil
+	}
+}
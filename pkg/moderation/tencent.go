@@ -0,0 +1,161 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/tencentyun/cos-go-sdk-v5"
+)
+
+// Tencent 腾讯云数据万象内容审核，需绑定一个存储桶
+type Tencent struct {
+	SecretID  string
+	SecretKey string
+	BucketURL string // 存储桶地址，如 https://examplebucket-1250000000.cos.ap-shanghai.myqcloud.com
+	CIURL     string // 数据万象地址，如 https://examplebucket-1250000000.ci.ap-shanghai.myqcloud.com
+	Client    *http.Client
+}
+
+// tencentImageResult 图片审核响应
+type tencentImageResult struct {
+	Result int     `xml:"Result"`
+	Label  string  `xml:"Label"`
+	Score  float64 `xml:"Score"`
+}
+
+// tencentVideoJob 视频审核任务
+type tencentVideoJob struct {
+	JobsDetail struct {
+		JobID   string  `xml:"JobId"`
+		State   string  `xml:"State"`
+		Code    string  `xml:"Code"`
+		Message string  `xml:"Message"`
+		Result  int     `xml:"Result"`
+		Label   string  `xml:"Label"`
+		Score   float64 `xml:"Score"`
+	} `xml:"JobsDetail"`
+}
+
+// tencentVideoRequest 提交视频审核任务的请求正文
+type tencentVideoRequest struct {
+	XMLName xml.Name `xml:"Request"`
+	Input   struct {
+		URL string `xml:"Url"`
+	} `xml:"Input"`
+	Conf struct {
+		DetectType string `xml:"DetectType"`
+	} `xml:"Conf"`
+}
+
+// NewTencent 创建腾讯云数据万象审核服务
+func NewTencent(secretID, secretKey, bucket, region string) *Tencent {
+	return &Tencent{
+		SecretID:  secretID,
+		SecretKey: secretKey,
+		BucketURL: fmt.Sprintf("https://%s.cos.%s.myqcloud.com", bucket, region),
+		CIURL:     fmt.Sprintf("https://%s.ci.%s.myqcloud.com", bucket, region),
+		Client:    &http.Client{},
+	}
+}
+
+// Name 审核服务名称
+func (t *Tencent) Name() string {
+	return "tencent"
+}
+
+// Review 审核媒体文件，图片同步检测，视频提交异步任务后轮询结果
+func (t *Tencent) Review(ctx context.Context, media *Media) (*Result, error) {
+	switch media.Kind {
+	case KindImage:
+		query := url.Values{
+			"ci-process": {"sensitive-content-recognition"},
+			"detect-url": {media.URL},
+		}
+		var res tencentImageResult
+		if err := t.request(ctx, "GET", t.BucketURL+"/?"+query.Encode(), nil, &res); err != nil {
+			return nil, err
+		}
+		return tencentResult(res.Result, res.Label, res.Score), nil
+	case KindVideo:
+		body := tencentVideoRequest{}
+		body.Input.URL = media.URL
+		body.Conf.DetectType = "Porn,Terrorism,Politics,Ads"
+		payload, err := xml.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		var job tencentVideoJob
+		if err := t.request(ctx, "POST", t.CIURL+"/video/auditing", payload, &job); err != nil {
+			return nil, err
+		}
+
+		jobID := job.JobsDetail.JobID
+		for job.JobsDetail.State != "Success" {
+			if job.JobsDetail.State == "Failed" {
+				return nil, fmt.Errorf("tencent ci auditing job failed: %s %s", job.JobsDetail.Code, job.JobsDetail.Message)
+			}
+
+			if err := wait(ctx); err != nil {
+				return nil, err
+			}
+
+			if err := t.request(ctx, "GET", t.CIURL+"/video/auditing/"+jobID, nil, &job); err != nil {
+				return nil, err
+			}
+		}
+
+		return tencentResult(job.JobsDetail.Result, job.JobsDetail.Label, job.JobsDetail.Score), nil
+	}
+
+	return nil, ErrUnsupportedKind
+}
+
+// tencentResult 转换审核结果，result 为 0 表示正常，1 表示违规，2 表示疑似
+func tencentResult(result int, label string, score float64) *Result {
+	decision := DecisionPass
+	switch result {
+	case 1:
+		decision = DecisionBlock
+	case 2:
+		decision = DecisionReview
+	}
+
+	return &Result{Decision: decision, Label: label, Score: score}
+}
+
+// request 发送签名后的请求并解析 XML 响应
+func (t *Tencent) request(ctx context.Context, method, target string, body []byte, res interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	cos.AddAuthorizationHeader(t.SecretID, t.SecretKey, "", req, cos.NewAuthTime(time.Hour))
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("tencent ci request failed with status %d: %s", resp.StatusCode, data)
+	}
+
+	return xml.Unmarshal(data, res)
+}
@@ -0,0 +1,57 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTencent_Review(t *testing.T) {
+	asserts := assert.New(t)
+	pollInterval = time.Millisecond
+	polled := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asserts.NotEmpty(r.Header.Get("Authorization"))
+		switch {
+		case r.URL.Query().Get("ci-process") == "sensitive-content-recognition":
+			asserts.Equal("http://example.com/a.jpg", r.URL.Query().Get("detect-url"))
+			w.Write([]byte(`<RecognitionResult><Result>2</Result><Label>Porn</Label><Score>75</Score></RecognitionResult>`))
+		case r.Method == "POST" && r.URL.Path == "/video/auditing":
+			w.Write([]byte(`<Response><JobsDetail><JobId>job</JobId><State>Submitted</State></JobsDetail></Response>`))
+		case r.URL.Path == "/video/auditing/job":
+			polled++
+			if polled < 2 {
+				w.Write([]byte(`<Response><JobsDetail><JobId>job</JobId><State>Auditing</State></JobsDetail></Response>`))
+				return
+			}
+			w.Write([]byte(`<Response><JobsDetail><JobId>job</JobId><State>Success</State><Result>1</Result><Label>Porn</Label><Score>99</Score></JobsDetail></Response>`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewTencent("id", "key", "bucket-1250000000", "ap-shanghai")
+	provider.BucketURL, provider.CIURL = server.URL, server.URL
+
+	// 图片
+	res, err := provider.Review(context.Background(), &Media{Kind: KindImage, URL: "http://example.com/a.jpg"})
+	asserts.NoError(err)
+	asserts.Equal(DecisionReview, res.Decision)
+	asserts.Equal("Porn", res.Label)
+
+	// 视频
+	res, err = provider.Review(context.Background(), &Media{Kind: KindVideo, URL: "http://example.com/a.mp4"})
+	asserts.NoError(err)
+	asserts.Equal(DecisionBlock, res.Decision)
+	asserts.Equal(2, polled)
+
+	// 请求失败
+	provider.CIURL = server.URL + "/invalid"
+	_, err = provider.Review(context.Background(), &Media{Kind: KindVideo})
+	asserts.Error(err)
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// ShareAccessed 分享被他人查看或下载时通知分享者，同一访客在 notify_share_interval 秒内只通知一次
+func ShareAccessed(c *gin.Context, share *model.Share, accessType int, fileName string) {
+	visitor := c.ClientIP()
+	if userCtx, ok := c.Get("user"); ok {
+		user := userCtx.(*model.User)
+		if user.ID == share.UserID {
+			return
+		}
+		if !user.IsAnonymous() {
+			visitor = fmt.Sprintf("%s (%s)", user.Nick, visitor)
+		}
+	}
+
+	if interval := model.GetIntSetting("notify_share_interval", 3600); interval > 0 {
+		key := fmt.Sprintf("notify_share_%d_%d_%s", share.ID, accessType, c.ClientIP())
+		if _, ok := cache.Get(key); ok {
+			return
+		}
+		cache.Set(key, true, interval)
+	}
+
+	owner := share.Creator()
+	if owner.ID == 0 {
+		return
+	}
+
+	action := "查看"
+	if accessType == model.ShareAccessDownload {
+		action = "下载"
+	}
+	content := fmt.Sprintf("%s 于 %s %s了您分享的「%s」", visitor, time.Now().Format("2006-01-02 15:04:05"), action, share.SourceName)
+	if fileName != "" && fileName != share.SourceName {
+		content += "，文件：" + fileName
+	}
+
+	Send(owner, &Message{
+		Event:   model.NotifyShareAccessed,
+		Title:   fmt.Sprintf("分享「%s」被%s", share.SourceName, action),
+		Content: content,
+	})
+}
+
+// QuotaWarning 已用容量由 before 增加至超过提醒阈值时通知用户，阈值为容量上限的百分比，设为 0 时不提醒
+func QuotaWarning(user *model.User, before uint64) {
+	capacity := user.MaxStorage()
+	if capacity == 0 || user.Storage <= before {
+		return
+	}
+
+	threshold := uint64(model.GetIntSetting("notify_quota_threshold", 90))
+	if threshold == 0 || before*100 >= capacity*threshold || user.Storage*100 < capacity*threshold {
+		return
+	}
+
+	Send(user, &Message{
+		Event: model.NotifyQuotaWarning,
+		Title: "存储空间即将用尽",
+		Content: fmt.Sprintf("您已使用 %s 存储空间，达到容量上限 %s 的 %d%%，请及时清理文件。",
+			util.FormatSize(user.Storage), util.FormatSize(capacity), user.Storage*100/capacity),
+	})
+}
+
+// VirusDetected 上传的文件检出病毒时通知文件所有者及站点管理员，removed 表示文件已被删除，否则已被隔离
+func VirusDetected(owner *model.User, fileName, signature string, removed bool) {
+	action := "隔离，无法下载或预览"
+	if removed {
+		action = "删除"
+	}
+
+	Send(owner, &Message{
+		Event:   model.NotifyVirusDetected,
+		Title:   fmt.Sprintf("文件「%s」检出病毒", fileName),
+		Content: fmt.Sprintf("您上传的文件「%s」检出病毒 %s，文件已被%s。", fileName, signature, action),
+	})
+
+	admin, err := model.GetActiveUserByID(1)
+	if err != nil || admin.ID == owner.ID {
+		return
+	}
+
+	Send(&admin, &Message{
+		Event:   model.NotifyVirusDetected,
+		Title:   fmt.Sprintf("用户 %s 上传的文件检出病毒", owner.Email),
+		Content: fmt.Sprintf("用户 %s 上传的文件「%s」检出病毒 %s，文件已被%s。", owner.Email, fileName, signature, action),
+	})
+}
+
+// CapacityAlert 存储策略剩余容量低于告警阈值时通知全部管理员，critical 表示低于严重阈值，
+// suspended 表示已暂停向此存储策略路由新上传
+func CapacityAlert(policyName string, free, total uint64, critical, suspended bool) {
+	admins, err := model.ListAdmins()
+	if err != nil {
+		util.Log().Warning("无法列取管理员, %s", err)
+		return
+	}
+
+	title := fmt.Sprintf("存储策略「%s」剩余容量不足", policyName)
+	if critical {
+		title = fmt.Sprintf("存储策略「%s」剩余容量严重不足", policyName)
+	}
+	content := fmt.Sprintf("存储策略「%s」剩余 %s，总容量 %s。", policyName, util.FormatSize(free), util.FormatSize(total))
+	if suspended {
+		content += "已暂停向此存储策略路由新上传，容量恢复后将自动恢复。"
+	}
+
+	for i := range admins {
+		Send(&admins[i], &Message{
+			Event:   model.NotifyCapacityAlert,
+			Title:   title,
+			Content: content,
+		})
+	}
+}
+
+// ModerationFailed 文件未通过内容审核时通知文件所有者，blocked 表示文件的分享已被屏蔽，否则等待人工复核
+func ModerationFailed(owner *model.User, fileName, label string, blocked bool) {
+	content := fmt.Sprintf("您的文件「%s」疑似包含违规内容（%s），相关分享正在等待管理员复核。", fileName, label)
+	if blocked {
+		content = fmt.Sprintf("您的文件「%s」未通过内容审核（%s），相关分享已被屏蔽。", fileName, label)
+	}
+
+	Send(owner, &Message{
+		Event:   model.NotifyModeration,
+		Title:   fmt.Sprintf("文件「%s」未通过内容审核", fileName),
+		Content: content,
+	})
+}
+
+// announcementBatchSize 投递公告时每批读取的用户数量
+const announcementBatchSize = 500
+
+// Announce 向公告面向的全部用户投递站内信，公告设置了发送邮件时按用户的通知偏好同时发送邮件，返回投递的用户数
+func Announce(announcement *model.Announcement) (int, error) {
+	msg := &Message{
+		Event:   model.NotifyAnnouncement,
+		Title:   announcement.Title,
+		Content: announcement.Content,
+	}
+	if !announcement.Email {
+		msg.Channels = []string{model.NotifyChannelInbox}
+	}
+
+	delivered := 0
+	err := announcement.EachRecipient(announcementBatchSize, func(users []model.User) error {
+		for i := range users {
+			Send(&users[i], msg)
+			delivered++
+		}
+		return nil
+	})
+	return delivered, err
+}
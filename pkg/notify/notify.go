@@ -0,0 +1,213 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sms"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// Message 待投递的通知
+type Message struct {
+	Event   string // 事件类型
+	Title   string // 标题
+	Content string // 纯文本内容
+
+	// Mail 生成即时通知邮件的标题及正文，未设置时使用通用通知邮件模板
+	Mail func() (string, string)
+	// Channels 限定投递的渠道，为空时不限制，仍需用户的通知偏好中包含此渠道
+	Channels []string
+}
+
+// allowed 通知是否可以通过给定渠道投递
+func (msg *Message) allowed(channel string) bool {
+	if len(msg.Channels) == 0 {
+		return true
+	}
+	for _, c := range msg.Channels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
+
+// webhookPayload Webhook 请求正文
+type webhookPayload struct {
+	Event   string `json:"event"`
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	User    string `json:"user"`
+	Time    int64  `json:"time"`
+}
+
+// Send 按用户的通知偏好投递通知，邮件及 Webhook 异步发送
+func Send(user *model.User, msg *Message) {
+	pref := user.OptionsSerialized.Notification
+	record := &model.Notification{
+		UserID:  user.ID,
+		Event:   msg.Event,
+		Title:   msg.Title,
+		Content: msg.Content,
+	}
+
+	for _, channel := range pref.Channels(msg.Event) {
+		if !msg.allowed(channel) {
+			continue
+		}
+
+		switch channel {
+		case model.NotifyChannelInbox:
+			record.Inbox = true
+		case model.NotifyChannelEmail:
+			if pref.IsDigest(msg.Event) {
+				record.Pending = true
+			} else {
+				sendEmail(user, msg)
+			}
+		case model.NotifyChannelWebhook:
+			if pref == nil || pref.Webhook == "" || !model.IsTrueVal(model.GetSettingByName("notify_webhook")) {
+				continue
+			}
+
+			payload := webhookPayload{
+				Event:   msg.Event,
+				Title:   msg.Title,
+				Content: msg.Content,
+				User:    hashid.HashID(user.ID, hashid.UserID),
+				Time:    time.Now().Unix(),
+			}
+			timeout := time.Duration(model.GetIntSetting("notify_webhook_timeout", 10)) * time.Second
+			go func(target, secret, address string) {
+				if err := sendWebhook(target, secret, &payload, timeout); err != nil {
+					util.Log().Warning("无法向用户 [%s] 的 Webhook 发送通知, %s", address, err)
+				}
+			}(pref.Webhook, pref.WebhookSecret, user.Email)
+		case model.NotifyChannelSMS:
+			// 仅重要事件通过短信通知
+			if user.Phone == "" || !util.ContainsString(model.NotifySMSEvents, msg.Event) || !sms.Enabled() {
+				continue
+			}
+
+			alert := sms.NewAlertMessage(msg.Title)
+			go func(phone, address string) {
+				if err := sms.Send(phone, alert); err != nil {
+					util.Log().Warning("无法向用户 [%s] 发送通知短信, %s", address, err)
+				}
+			}(user.Phone, user.Email)
+		}
+	}
+
+	if record.Inbox || record.Pending {
+		if err := record.Create(); err != nil {
+			util.Log().Warning("无法保存用户 [%s] 的通知, %s", user.Email, err)
+		}
+	}
+}
+
+// sendEmail 立即发送通知邮件
+func sendEmail(user *model.User, msg *Message) {
+	var title, body string
+	if msg.Mail != nil {
+		title, body = msg.Mail()
+	} else {
+		title, body = email.NewNotificationEmail(user.Nick, msg.Title, msg.Content)
+	}
+
+	address := user.Email
+	go func() {
+		if err := email.Send(address, title, body); err != nil {
+			util.Log().Warning("无法发送通知邮件至 %s, %s", address, err)
+		}
+	}()
+}
+
+// sendWebhook 以 JSON 格式向 Webhook 地址发送通知，设置了签名密钥时附带 HMAC-SHA256 签名
+func sendWebhook(target, secret string, payload *webhookPayload, timeout time.Duration) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	header := http.Header{
+		"Content-Type":      {"application/json"},
+		"X-Cloudreve-Event": {payload.Event},
+	}
+	if secret != "" {
+		header.Set("X-Cloudreve-Signature", "sha256="+Sign(secret, body))
+	}
+
+	// 请求头会写入客户端的默认设置，每次请求使用独立的客户端
+	resp := request.NewClient().Request(
+		"POST",
+		target,
+		bytes.NewReader(body),
+		request.WithHeader(header),
+		request.WithContentLength(int64(len(body))),
+		request.WithTimeout(timeout),
+	)
+	if resp.Err != nil {
+		return resp.Err
+	}
+	defer resp.Response.Body.Close()
+
+	if resp.Response.StatusCode < 200 || resp.Response.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned unexpected status %d", resp.Response.StatusCode)
+	}
+	return nil
+}
+
+// Sign 计算 Webhook 请求正文的签名
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendDigests 汇总发送等待中的邮件通知，每个用户一封邮件
+func SendDigests() {
+	notifications, err := model.ListPendingNotifications()
+	if err != nil {
+		util.Log().Warning("无法列取待汇总发送的通知, %s", err)
+		return
+	}
+
+	for start := 0; start < len(notifications); {
+		end := start + 1
+		for end < len(notifications) && notifications[end].UserID == notifications[start].UserID {
+			end++
+		}
+		sendDigest(notifications[start].UserID, notifications[start:end])
+		start = end
+	}
+}
+
+// sendDigest 向单个用户发送汇总邮件，发送失败的通知保留至下次重试，用户已停用时直接丢弃
+func sendDigest(uid uint, notifications []model.Notification) {
+	if user, err := model.GetActiveUserByID(uid); err == nil {
+		title, body := email.NewNotificationDigestEmail(user.Nick, notifications)
+		if err := email.Send(user.Email, title, body); err != nil {
+			util.Log().Warning("无法发送通知汇总邮件至 %s, %s", user.Email, err)
+			return
+		}
+	}
+
+	ids := make([]uint, 0, len(notifications))
+	for _, n := range notifications {
+		ids = append(ids, n.ID)
+	}
+	if err := model.FinishNotificationDigest(ids); err != nil {
+		util.Log().Warning("无法更新用户 [%d] 的通知状态, %s", uid, err)
+	}
+}
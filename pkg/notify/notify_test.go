@@ -0,0 +1,230 @@
+package notify
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func inboxUser(event string) *model.User {
+	user := &model.User{Model: gorm.Model{ID: 1}}
+	user.OptionsSerialized.Notification = &model.NotificationPreference{
+		Routes: map[string][]string{event: {model.NotifyChannelInbox}},
+	}
+	return user
+}
+
+func TestSend(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 不通知
+	{
+		user := &model.User{}
+		user.OptionsSerialized.Notification = &model.NotificationPreference{
+			Routes: map[string][]string{model.NotifyTaskFinished: {}},
+		}
+		Send(user, &Message{Event: model.NotifyTaskFinished, Title: "title"})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 站内信
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Send(inboxUser(model.NotifyTaskFinished), &Message{Event: model.NotifyTaskFinished, Title: "title"})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 汇总邮件
+	{
+		user := &model.User{}
+		user.OptionsSerialized.Notification = &model.NotificationPreference{
+			Routes: map[string][]string{model.NotifyTaskFinished: {model.NotifyChannelEmail}},
+			Digest: []string{model.NotifyTaskFinished},
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		Send(user, &Message{Event: model.NotifyTaskFinished, Title: "title"})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	asserts := assert.New(t)
+	payload := &webhookPayload{Event: model.NotifyLoginAlert, Title: "title", Time: 1}
+
+	// 成功，附带签名
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var received webhookPayload
+			asserts.NoError(json.Unmarshal(body, &received))
+			asserts.Equal("title", received.Title)
+			asserts.Equal(model.NotifyLoginAlert, r.Header.Get("X-Cloudreve-Event"))
+			asserts.Equal("sha256="+Sign("secret", body), r.Header.Get("X-Cloudreve-Signature"))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		asserts.NoError(sendWebhook(server.URL, "secret", payload, time.Second))
+	}
+
+	// 非正常状态码
+	{
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			asserts.Empty(r.Header.Get("X-Cloudreve-Signature"))
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		defer server.Close()
+		asserts.Error(sendWebhook(server.URL, "", payload, time.Second))
+	}
+}
+
+func TestSendDigests(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无法列取
+	{
+		mock.ExpectQuery("SELECT(.+)notifications(.+)").WillReturnError(errors.New("error"))
+		SendDigests()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 用户已停用，按用户丢弃通知
+	{
+		mock.ExpectQuery("SELECT(.+)notifications(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(1, 1).AddRow(2, 1).AddRow(3, 2))
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("not found"))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WithArgs(1, 2, false).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("UPDATE(.+)").WithArgs(false, 1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("not found"))
+		mock.ExpectBegin()
+		mock.ExpectExec("DELETE(.+)").WithArgs(3, false).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE(.+)").WithArgs(false, 3).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+		SendDigests()
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestQuotaWarning(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_notify_quota_threshold", "90", 0)
+	user := inboxUser(model.NotifyQuotaWarning)
+	user.Group.MaxStorage = 100
+
+	// 未超过阈值
+	{
+		user.Storage = 89
+		QuotaWarning(user, 80)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 此前已超过阈值
+	{
+		user.Storage = 95
+		QuotaWarning(user, 91)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 超过阈值
+	{
+		user.Storage = 90
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		QuotaWarning(user, 80)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未限制容量
+	{
+		user.Group.MaxStorage = 0
+		QuotaWarning(user, 0)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestCapacityAlert(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 读取管理员失败
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		CapacityAlert("default", 1, 100, true, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 通知全部管理员
+	{
+		options := `{"notification":{"routes":{"capacity_alert":["inbox"]}}}`
+		mock.ExpectQuery("SELECT(.+)users(.+)group_id = (.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "options"}).
+				AddRow(1, "a@example.com", options).AddRow(2, "b@example.com", options))
+		for i := 0; i < 2; i++ {
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+		}
+		CapacityAlert("default", 1, 100, true, true)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
+
+func TestAnnounce(t *testing.T) {
+	asserts := assert.New(t)
+	announcement := &model.Announcement{Title: "维护通知", Content: "content", GroupList: []uint{2}}
+
+	// 仅投递站内信
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)group_id in(.+)").
+			WithArgs(0, model.Active, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email"}).AddRow(1, "a@example.com").AddRow(2, "b@example.com"))
+		for i := 0; i < 2; i++ {
+			mock.ExpectBegin()
+			mock.ExpectExec("INSERT(.+)notifications(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+			mock.ExpectCommit()
+		}
+		delivered, err := Announce(announcement)
+		asserts.NoError(err)
+		asserts.Equal(2, delivered)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 读取用户失败
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		_, err := Announce(announcement)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
@@ -0,0 +1,140 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+var (
+	// ErrInvalidToken ID Token 格式错误或签名无效
+	ErrInvalidToken = errors.New("invalid ID token")
+	// ErrUnsupportedAlg 不支持的签名算法
+	ErrUnsupportedAlg = errors.New("unsupported ID token signing algorithm")
+)
+
+// jsonWebKey 身份提供方公布的公钥
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type keySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// publicKey 将公钥解析为 RSA 或 P-256 公钥
+func (key *jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch key.Kty {
+	case "RSA":
+		n, err := decodeBigInt(key.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(key.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if key.Crv != "P-256" {
+			return nil, ErrUnsupportedAlg
+		}
+		x, err := decodeBigInt(key.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(key.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	}
+	return nil, ErrUnsupportedAlg
+}
+
+// verifyJWT 使用给定公钥集验证 JWT 签名，返回解码后的载荷，仅支持 RS256 与 ES256
+func verifyJWT(token string, keys []jsonWebKey) ([]byte, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	verified := false
+	for i := range keys {
+		if header.Kid != "" && keys[i].Kid != header.Kid {
+			continue
+		}
+
+		pub, err := keys[i].publicKey()
+		if err != nil {
+			continue
+		}
+
+		switch header.Alg {
+		case "RS256":
+			if rsaKey, ok := pub.(*rsa.PublicKey); ok {
+				verified = rsa.VerifyPKCS1v15(rsaKey, crypto.SHA256, digest[:], sig) == nil
+			}
+		case "ES256":
+			if ecKey, ok := pub.(*ecdsa.PublicKey); ok && len(sig) == 64 {
+				r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+				verified = ecdsa.Verify(ecKey, digest[:], r, s)
+			}
+		default:
+			return nil, ErrUnsupportedAlg
+		}
+
+		if verified {
+			break
+		}
+	}
+
+	if !verified {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	return payload, nil
+}
@@ -0,0 +1,285 @@
+package oidc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// CallbackPath 身份提供方认证完成后的回调地址
+	CallbackPath   = "/api/v3/user/oidc/callback"
+	requestTimeout = time.Duration(10) * time.Second
+)
+
+var (
+	// ErrIssuerMismatch 发现文档或 ID Token 中的签发者与设定不符
+	ErrIssuerMismatch = errors.New("OIDC issuer mismatch")
+	// ErrNonceMismatch ID Token 中的 nonce 与登录会话不符
+	ErrNonceMismatch = errors.New("OIDC nonce mismatch")
+	// ErrTokenExpired ID Token 已过期
+	ErrTokenExpired = errors.New("ID token expired")
+)
+
+// Config OIDC 单点登录设置
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+	RedirectURL  string
+	// ID Token 中表示用户所属组的声明名称
+	GroupClaim   string
+	GroupMap     map[string]uint
+	DefaultGroup uint
+	// 是否为未关联的用户自动注册账号
+	Register bool
+
+	httpClient request.Client
+}
+
+// NewConfig 从站点设置读取 OIDC 单点登录设置
+func NewConfig() *Config {
+	options := model.GetSettingByNames(
+		"oidc_issuer",
+		"oidc_client_id",
+		"oidc_client_secret",
+		"oidc_scopes",
+		"oidc_group_claim",
+		"oidc_group_map",
+		"oidc_register",
+	)
+
+	callback, _ := url.Parse(CallbackPath)
+	conf := &Config{
+		Issuer:       strings.TrimSuffix(options["oidc_issuer"], "/"),
+		ClientID:     options["oidc_client_id"],
+		ClientSecret: options["oidc_client_secret"],
+		Scopes:       util.SplitList(options["oidc_scopes"]),
+		RedirectURL:  model.GetSiteURL().ResolveReference(callback).String(),
+		GroupClaim:   options["oidc_group_claim"],
+		GroupMap:     make(map[string]uint),
+		DefaultGroup: uint(model.GetIntSetting("default_group", 2)),
+		Register:     model.IsTrueVal(options["oidc_register"]),
+		httpClient:   request.NewClient(request.WithTimeout(requestTimeout)),
+	}
+
+	if options["oidc_group_map"] != "" {
+		if err := json.Unmarshal([]byte(options["oidc_group_map"]), &conf.GroupMap); err != nil {
+			util.Log().Warning("无法解析 OIDC 用户组映射, %s", err)
+		}
+	}
+
+	return conf
+}
+
+// Provider 通过发现文档获取的身份提供方接口地址
+type Provider struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Discover 读取身份提供方的发现文档
+func (conf *Config) Discover(ctx context.Context) (*Provider, error) {
+	var provider Provider
+	if err := conf.getJSON(ctx, conf.Issuer+"/.well-known/openid-configuration", &provider); err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSuffix(provider.Issuer, "/") != conf.Issuer {
+		return nil, ErrIssuerMismatch
+	}
+	return &provider, nil
+}
+
+// NewPKCE 生成 PKCE 校验码及 S256 方式的挑战码
+func NewPKCE() (string, string) {
+	verifier := util.RandSecureString(64)
+	sum := sha256.Sum256([]byte(verifier))
+	return verifier, base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL 返回身份提供方的认证页面地址
+func (conf *Config) AuthCodeURL(provider *Provider, state, nonce, challenge string) (string, error) {
+	base, err := url.Parse(provider.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := conf.Scopes
+	if !util.ContainsString(scopes, "openid") {
+		scopes = append([]string{"openid"}, scopes...)
+	}
+
+	queries := base.Query()
+	queries.Set("response_type", "code")
+	queries.Set("client_id", conf.ClientID)
+	queries.Set("redirect_uri", conf.RedirectURL)
+	queries.Set("scope", strings.Join(scopes, " "))
+	queries.Set("state", state)
+	queries.Set("nonce", nonce)
+	queries.Set("code_challenge", challenge)
+	queries.Set("code_challenge_method", "S256")
+	base.RawQuery = queries.Encode()
+	return base.String(), nil
+}
+
+// Exchange 使用授权码及 PKCE 校验码兑换 ID Token
+func (conf *Config) Exchange(ctx context.Context, provider *Provider, code, verifier string) (string, error) {
+	body := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {conf.RedirectURL},
+		"client_id":     {conf.ClientID},
+		"client_secret": {conf.ClientSecret},
+		"code_verifier": {verifier},
+	}.Encode()
+
+	resp, err := conf.httpClient.Request(
+		"POST",
+		provider.TokenEndpoint,
+		strings.NewReader(body),
+		request.WithContext(ctx),
+		request.WithContentLength(int64(len(body))),
+		request.WithHeader(http.Header{"Content-Type": {"application/x-www-form-urlencoded"}}),
+	).CheckHTTPResponse(200).GetResponse()
+	if err != nil {
+		return "", err
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal([]byte(resp), &token); err != nil {
+		return "", err
+	}
+	if token.IDToken == "" {
+		return "", ErrInvalidToken
+	}
+	return token.IDToken, nil
+}
+
+// Claims ID Token 中的声明
+type Claims struct {
+	Issuer            string       `json:"iss"`
+	Subject           string       `json:"sub"`
+	Audience          audience     `json:"aud"`
+	Expiry            int64        `json:"exp"`
+	Nonce             string       `json:"nonce"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	PreferredUsername string       `json:"preferred_username"`
+	Groups            []string     `json:"-"`
+}
+
+// audience aud 声明可以是字符串或字符串数组
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	return json.Unmarshal(data, (*[]string)(a))
+}
+
+// flexibleBool 部分身份提供方以字符串形式返回布尔值
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	*b = flexibleBool(strings.Trim(string(data), `"`) == "true")
+	return nil
+}
+
+// Verify 验证 ID Token 的签名、签发者、受众、有效期及 nonce，返回其中的声明
+func (conf *Config) Verify(ctx context.Context, provider *Provider, idToken, nonce string) (*Claims, error) {
+	var keys keySet
+	if err := conf.getJSON(ctx, provider.JWKSURI, &keys); err != nil {
+		return nil, err
+	}
+
+	payload, err := verifyJWT(idToken, keys.Keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != conf.Issuer:
+		return nil, ErrIssuerMismatch
+	case !util.ContainsString(claims.Audience, conf.ClientID):
+		return nil, ErrInvalidToken
+	case claims.Expiry < time.Now().Unix():
+		return nil, ErrTokenExpired
+	case claims.Nonce != nonce:
+		return nil, ErrNonceMismatch
+	case claims.Subject == "":
+		return nil, ErrInvalidToken
+	}
+
+	claims.Groups = groupsFromClaims(payload, conf.GroupClaim)
+	return &claims, nil
+}
+
+// groupsFromClaims 读取给定名称的组声明，值可以是字符串或字符串数组
+func groupsFromClaims(payload []byte, name string) []string {
+	if name == "" {
+		return nil
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil || raw[name] == nil {
+		return nil
+	}
+
+	var groups []string
+	if err := json.Unmarshal(raw[name], &groups); err == nil {
+		return groups
+	}
+	var group string
+	if err := json.Unmarshal(raw[name], &group); err == nil && group != "" {
+		return []string{group}
+	}
+	return nil
+}
+
+// MapGroup 根据用户所属的组返回对应的用户组ID，无匹配时返回默认用户组
+func (conf *Config) MapGroup(groups []string) uint {
+	for _, group := range groups {
+		if id, ok := conf.GroupMap[group]; ok {
+			return id
+		}
+	}
+	return conf.DefaultGroup
+}
+
+func (conf *Config) getJSON(ctx context.Context, target string, v interface{}) error {
+	resp, err := conf.httpClient.Request(
+		"GET",
+		target,
+		nil,
+		request.WithContext(ctx),
+	).CheckHTTPResponse(200).GetResponse()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(resp), v)
+}
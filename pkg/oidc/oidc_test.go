@@ -0,0 +1,186 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/stretchr/testify/assert"
+)
+
+func signJWT(key *rsa.PrivateKey, kid string, claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid})
+	payload, _ := json.Marshal(claims)
+	signing := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signing))
+	sig, _ := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	return signing + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// fakeProvider 模拟的身份提供方
+func fakeProvider(key *rsa.PrivateKey, idToken *string) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize?tenant=1",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	// 声明的签发者与请求的地址不符
+	mux.HandleFunc("/other/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "k1",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.PostForm.Get("code") != "code" || r.PostForm.Get("code_verifier") != "verifier" {
+			w.WriteHeader(400)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"id_token": *idToken})
+	})
+	return server
+}
+
+func TestConfig_Flow(t *testing.T) {
+	asserts := assert.New(t)
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	asserts.NoError(err)
+
+	var idToken string
+	server := fakeProvider(key, &idToken)
+	defer server.Close()
+
+	conf := &Config{
+		Issuer:      server.URL,
+		ClientID:    "client",
+		Scopes:      []string{"profile", "email"},
+		RedirectURL: "https://cloudreve.org" + CallbackPath,
+		GroupClaim:  "groups",
+		httpClient:  request.NewClient(),
+	}
+
+	// 发现
+	provider, err := conf.Discover(context.Background())
+	asserts.NoError(err)
+	asserts.Equal(server.URL+"/token", provider.TokenEndpoint)
+
+	// 认证地址
+	authURL, err := conf.AuthCodeURL(provider, "state", "nonce", "challenge")
+	asserts.NoError(err)
+	parsed, _ := url.Parse(authURL)
+	asserts.Equal("1", parsed.Query().Get("tenant"))
+	asserts.Equal("openid profile email", parsed.Query().Get("scope"))
+	asserts.Equal("S256", parsed.Query().Get("code_challenge_method"))
+
+	claims := map[string]interface{}{
+		"iss":            server.URL,
+		"sub":            "user-1",
+		"aud":            []string{"client", "other"},
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"nonce":          "nonce",
+		"email":          "John@Example.com",
+		"email_verified": "true",
+		"groups":         "admins",
+	}
+
+	// 兑换并验证
+	{
+		idToken = signJWT(key, "k1", claims)
+		token, err := conf.Exchange(context.Background(), provider, "code", "verifier")
+		asserts.NoError(err)
+		res, err := conf.Verify(context.Background(), provider, token, "nonce")
+		asserts.NoError(err)
+		asserts.Equal("user-1", res.Subject)
+		asserts.True(bool(res.EmailVerified))
+		asserts.Equal([]string{"admins"}, res.Groups)
+	}
+
+	// 授权码无效
+	{
+		_, err := conf.Exchange(context.Background(), provider, "wrong", "verifier")
+		asserts.Error(err)
+	}
+
+	// nonce、受众、有效期不符
+	{
+		_, err := conf.Verify(context.Background(), provider, signJWT(key, "k1", claims), "other")
+		asserts.Equal(ErrNonceMismatch, err)
+
+		claims["aud"] = "other"
+		_, err = conf.Verify(context.Background(), provider, signJWT(key, "k1", claims), "nonce")
+		asserts.Equal(ErrInvalidToken, err)
+		claims["aud"] = "client"
+
+		claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, err = conf.Verify(context.Background(), provider, signJWT(key, "k1", claims), "nonce")
+		asserts.Equal(ErrTokenExpired, err)
+	}
+
+	// 签名无效
+	{
+		other, _ := rsa.GenerateKey(rand.Reader, 2048)
+		_, err := conf.Verify(context.Background(), provider, signJWT(other, "k1", claims), "nonce")
+		asserts.Equal(ErrInvalidToken, err)
+		_, err = conf.Verify(context.Background(), provider, "a.b", "nonce")
+		asserts.Equal(ErrInvalidToken, err)
+	}
+
+	// 签发者不符
+	{
+		conf.Issuer = server.URL + "/other"
+		_, err := conf.Discover(context.Background())
+		asserts.Equal(ErrIssuerMismatch, err)
+	}
+}
+
+func TestVerifyJWT_Alg(t *testing.T) {
+	asserts := assert.New(t)
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte(`{}`))
+	_, err := verifyJWT(fmt.Sprintf("%s.%s.", header, payload), []jsonWebKey{{Kty: "RSA", N: "AQAB", E: "AQAB"}})
+	asserts.Equal(ErrUnsupportedAlg, err)
+}
+
+func TestConfig_MapGroup(t *testing.T) {
+	asserts := assert.New(t)
+	conf := &Config{GroupMap: map[string]uint{"admins": 1}, DefaultGroup: 2}
+	asserts.EqualValues(1, conf.MapGroup([]string{"users", "admins"}))
+	asserts.EqualValues(2, conf.MapGroup([]string{"users"}))
+	asserts.EqualValues(2, conf.MapGroup(nil))
+}
+
+func TestNewPKCE(t *testing.T) {
+	asserts := assert.New(t)
+	verifier, challenge := NewPKCE()
+	sum := sha256.Sum256([]byte(verifier))
+	asserts.Len(verifier, 64)
+	asserts.Equal(base64.RawURLEncoding.EncodeToString(sum[:]), challenge)
+}
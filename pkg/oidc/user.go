@@ -0,0 +1,59 @@
+package oidc
+
+import (
+	"errors"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+var (
+	// ErrEmailNotVerified 身份提供方未提供已验证的邮箱，无法关联或注册用户
+	ErrEmailNotVerified = errors.New("email address is not verified by the identity provider")
+	// ErrRegisterDisabled 没有可关联的用户，且未开启自动注册
+	ErrRegisterDisabled = errors.New("no linked account and registration via OIDC is disabled")
+)
+
+// ResolveUser 返回 ID Token 对应的用户。已关联的用户直接返回；邮箱已验证且与现有用户相同时，
+// 关联该用户；否则按设置自动注册，并根据组声明分配用户组
+func (conf *Config) ResolveUser(claims *Claims) (*model.User, error) {
+	if user, err := model.GetUserByOIDC(claims.Subject); err == nil {
+		return &user, nil
+	}
+
+	email := strings.ToLower(claims.Email)
+	if email == "" || !bool(claims.EmailVerified) {
+		return nil, ErrEmailNotVerified
+	}
+
+	if user, err := model.GetUserByEmail(email); err == nil {
+		if err := user.LinkOIDC(claims.Subject); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if !conf.Register {
+		return nil, ErrRegisterDisabled
+	}
+
+	user := model.NewUser()
+	user.Email = email
+	user.Nick = claims.Name
+	if user.Nick == "" {
+		user.Nick = claims.PreferredUsername
+	}
+	if user.Nick == "" {
+		user.Nick = strings.Split(email, "@")[0]
+	}
+	user.Status = model.Active
+	user.GroupID = conf.MapGroup(claims.Groups)
+	user.OIDC = claims.Subject
+	if err := model.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	// 重新读取以加载用户组等关联
+	created, err := model.GetUserByID(user.ID)
+	return &created, err
+}
@@ -0,0 +1,162 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// breachEndpoint 泄露密码查询接口，只需提交密码 SHA1 摘要的前五位
+	breachEndpoint = "https://api.pwnedpasswords.com/range/"
+	requestTimeout = time.Duration(5) * time.Second
+)
+
+// 字符类别
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+var (
+	// ErrBreached 密码出现在已泄露的密码库中
+	ErrBreached = errors.New("this password has appeared in a data breach, please choose another one")
+	// ErrReused 密码与近期使用过的密码重复
+	ErrReused = errors.New("this password has been used recently, please choose another one")
+)
+
+// Policy 密码策略
+type Policy struct {
+	// 最短长度
+	MinLength int
+	// 必须包含的字符类别
+	Classes []string
+	// 是否检查密码是否已泄露
+	BreachCheck bool
+	// 密码有效天数，0 表示不要求定期更换
+	MaxAgeDays int
+	// 不可与最近多少个密码重复，0 表示不限制
+	History int
+
+	endpoint   string
+	httpClient request.Client
+}
+
+// NewPolicy 从站点设置读取密码策略
+func NewPolicy() *Policy {
+	options := model.GetSettingByNames(
+		"password_min_length",
+		"password_classes",
+		"password_breach_check",
+		"password_max_age_days",
+		"password_history",
+	)
+
+	return &Policy{
+		MinLength:   model.GetIntSetting("password_min_length", 4),
+		Classes:     util.SplitList(strings.ToLower(options["password_classes"])),
+		BreachCheck: model.IsTrueVal(options["password_breach_check"]),
+		MaxAgeDays:  model.GetIntSetting("password_max_age_days", 0),
+		History:     model.GetIntSetting("password_history", 0),
+		endpoint:    breachEndpoint,
+		httpClient:  request.NewClient(request.WithTimeout(requestTimeout)),
+	}
+}
+
+// Validate 检查密码是否满足长度、字符类别要求，开启泄露检查时查询泄露密码库
+func (policy *Policy) Validate(password string) error {
+	if len([]rune(password)) < policy.MinLength {
+		return fmt.Errorf("password must be at least %d characters", policy.MinLength)
+	}
+
+	for _, class := range policy.Classes {
+		if !containsClass(password, class) {
+			return fmt.Errorf("password must contain %s characters", class)
+		}
+	}
+
+	if policy.BreachCheck {
+		breached, err := policy.Breached(password)
+		if err != nil {
+			// 查询失败时不阻止用户设置密码
+			util.Log().Warning("无法查询泄露密码库, %s", err)
+		} else if breached {
+			return ErrBreached
+		}
+	}
+
+	return nil
+}
+
+// Breached 使用 k-匿名方式查询密码是否已泄露，仅向查询接口提交摘要的前五位
+func (policy *Policy) Breached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	resp, err := policy.httpClient.Request(
+		"GET",
+		policy.endpoint+digest[:5],
+		nil,
+	).CheckHTTPResponse(200).GetResponse()
+	if err != nil {
+		return false, err
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(resp))
+	for scanner.Scan() {
+		parts := strings.SplitN(strings.TrimSpace(scanner.Text()), ":", 2)
+		if len(parts) == 2 && strings.EqualFold(parts[0], digest[5:]) && parts[1] != "0" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// Expired 返回用户的密码是否已超过有效期
+func (policy *Policy) Expired(user *model.User) bool {
+	if policy.MaxAgeDays <= 0 || user.LDAPUser != "" {
+		return false
+	}
+
+	changed := user.CreatedAt
+	if user.PasswordChangedAt != nil {
+		changed = *user.PasswordChangedAt
+	}
+	return time.Since(changed) > time.Duration(policy.MaxAgeDays)*24*time.Hour
+}
+
+// containsClass 返回密码是否包含给定类别的字符，未知类别视为满足
+func containsClass(password, class string) bool {
+	var match func(rune) bool
+	switch class {
+	case ClassLower:
+		match = unicode.IsLower
+	case ClassUpper:
+		match = unicode.IsUpper
+	case ClassDigit:
+		match = unicode.IsDigit
+	case ClassSymbol:
+		match = func(r rune) bool { return unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r) }
+	default:
+		return true
+	}
+
+	for _, r := range password {
+		if match(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package password
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBreachServer 模拟的泄露密码查询接口，返回包含给定密码的结果
+func fakeBreachServer(t *testing.T, breached string) *httptest.Server {
+	sum := sha1.Sum([]byte(breached))
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Len(t, strings.TrimPrefix(r.URL.Path, "/range/"), 5)
+		if strings.HasSuffix(r.URL.Path, digest[:5]) {
+			w.Write([]byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n" + digest[5:] + ":42\r\n"))
+			return
+		}
+		w.Write([]byte("0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n"))
+	}))
+}
+
+func testPolicy(endpoint string) *Policy {
+	return &Policy{
+		MinLength:  4,
+		endpoint:   endpoint + "/range/",
+		httpClient: request.NewClient(request.WithTimeout(time.Second)),
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	asserts := assert.New(t)
+	server := fakeBreachServer(t, "password123")
+	defer server.Close()
+	policy := testPolicy(server.URL)
+
+	// 长度不足
+	asserts.Error(policy.Validate("abc"))
+	asserts.NoError(policy.Validate("abcd"))
+
+	// 字符类别
+	{
+		policy.Classes = []string{ClassLower, ClassUpper, ClassDigit, ClassSymbol}
+		asserts.Error(policy.Validate("abcd"))
+		asserts.Error(policy.Validate("abcD1"))
+		asserts.NoError(policy.Validate("abcD1!"))
+		policy.Classes = nil
+	}
+
+	// 已泄露的密码
+	{
+		policy.BreachCheck = true
+		asserts.Equal(ErrBreached, policy.Validate("password123"))
+		asserts.NoError(policy.Validate("correct horse battery staple"))
+	}
+
+	// 查询失败时放行
+	{
+		policy.endpoint = "http://127.0.0.1:0/range/"
+		asserts.NoError(policy.Validate("password123"))
+	}
+}
+
+func TestPolicy_Breached(t *testing.T) {
+	asserts := assert.New(t)
+	server := fakeBreachServer(t, "hunter2")
+	defer server.Close()
+	policy := testPolicy(server.URL)
+
+	res, err := policy.Breached("hunter2")
+	asserts.NoError(err)
+	asserts.True(res)
+
+	res, err = policy.Breached("hunter3")
+	asserts.NoError(err)
+	asserts.False(res)
+}
+
+func TestPolicy_Expired(t *testing.T) {
+	asserts := assert.New(t)
+	policy := &Policy{}
+	user := &model.User{}
+	user.CreatedAt = time.Now().Add(-48 * time.Hour)
+
+	// 未设置有效期
+	asserts.False(policy.Expired(user))
+
+	// 以注册时间计算
+	policy.MaxAgeDays = 1
+	asserts.True(policy.Expired(user))
+
+	// 以修改密码时间计算
+	changed := time.Now()
+	user.PasswordChangedAt = &changed
+	asserts.False(policy.Expired(user))
+
+	// LDAP 用户不受限制
+	user.PasswordChangedAt = nil
+	user.LDAPUser = "user"
+	asserts.False(policy.Expired(user))
+}
@@ -2,6 +2,7 @@ package request
 
 import (
 	"context"
+	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"net/http"
 	"net/url"
@@ -75,6 +76,21 @@ func WithHeader(header http.Header) Option {
 	})
 }
 
+// WithRange 请求从 offset 开始的数据，offset 为 0 时请求完整数据
+func WithRange(offset int64) Option {
+	return optionFunc(func(o *options) {
+		if offset > 0 {
+			// 复制 Header，避免 Range 残留在共用的客户端设置中
+			header := o.header.Clone()
+			if header == nil {
+				header = http.Header{}
+			}
+			header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			o.header = header
+		}
+	})
+}
+
 // WithoutHeader 设置清除请求Header
 func WithoutHeader(header []string) Option {
 	return optionFunc(func(o *options) {
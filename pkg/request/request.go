@@ -10,11 +10,13 @@ import (
 	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -62,8 +64,8 @@ func (c *HTTPClient) Request(method, target string, body io.Reader, opts ...Opti
 		o.apply(&options)
 	}
 
-	// 创建请求客户端
-	client := &http.Client{Timeout: options.timeout}
+	// 创建请求客户端，启用链路追踪时记录请求并向对端传递链路
+	client := &http.Client{Timeout: options.timeout, Transport: tracing.NewTransport(nil)}
 
 	// size为0时将body设为nil
 	if options.contentLength == 0 {
@@ -255,6 +257,148 @@ func (instance NopRSCloser) Seek(offset int64, whence int) (int64, error) {
 
 }
 
+// rangeHeadSize 缓存的数据流头部大小，http.ServeContent 读取头部判断内容类型后会 seek 回开头
+const rangeHeadSize = 512
+
+// RangeRSCloser 实现完整 seeker 的响应数据流，seek 到其他位置后以 Range 请求重新获取，
+// 供 http.ServeContent 处理分段及断点续传下载
+type RangeRSCloser struct {
+	body       io.ReadCloser
+	reopen     func(offset int64) *Response
+	size       int64
+	offset     int64 // 当前读取位置
+	bodyOffset int64 // body 对应的位置
+	head       []byte
+}
+
+// GetRangeRSCloser 返回可 seek 的 RSCloser，reopen 用于请求从 offset 开始的数据
+func (resp *Response) GetRangeRSCloser(reopen func(offset int64) *Response) (*RangeRSCloser, error) {
+	if resp.Err != nil {
+		return nil, resp.Err
+	}
+
+	return &RangeRSCloser{
+		body:   resp.Response.Body,
+		reopen: reopen,
+		size:   resp.Response.ContentLength,
+	}, nil
+}
+
+// GetSourceRSCloser 以 GET 请求 source 返回的源地址，获取可 seek 的 RSCloser。seek 后以 Range 请求
+// 从对应位置重新获取，每次请求前都调用 source 重新签名以免源地址过期
+func GetSourceRSCloser(client Client, source func() (string, error), opts ...Option) (*RangeRSCloser, error) {
+	get := func(extra ...Option) *Response {
+		target, err := source()
+		if err != nil {
+			return &Response{Err: err}
+		}
+
+		return client.Request(
+			"GET",
+			target,
+			nil,
+			append(append([]Option{WithTimeout(time.Duration(0))}, opts...), extra...)...,
+		)
+	}
+
+	return get().CheckHTTPResponse(http.StatusOK).GetRangeRSCloser(func(offset int64) *Response {
+		return get(WithRange(offset))
+	})
+}
+
+// SetContentLength 设置数据流大小
+func (instance *RangeRSCloser) SetContentLength(size int64) {
+	instance.size = size
+}
+
+// Read 实现 RangeRSCloser reader
+func (instance *RangeRSCloser) Read(p []byte) (int, error) {
+	// 优先从已缓存的头部读取
+	if instance.offset < int64(len(instance.head)) {
+		n := copy(p, instance.head[instance.offset:])
+		instance.offset += int64(n)
+		return n, nil
+	}
+
+	if instance.body == nil || instance.bodyOffset != instance.offset {
+		if err := instance.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := instance.body.Read(p)
+
+	// 从开头顺序读取时缓存头部
+	if instance.bodyOffset == int64(len(instance.head)) && len(instance.head) < rangeHeadSize {
+		cached := n
+		if rest := rangeHeadSize - len(instance.head); cached > rest {
+			cached = rest
+		}
+		instance.head = append(instance.head, p[:cached]...)
+	}
+
+	instance.offset += int64(n)
+	instance.bodyOffset += int64(n)
+	return n, err
+}
+
+// open 关闭原有数据流，从当前位置重新请求
+func (instance *RangeRSCloser) open() error {
+	if instance.body != nil {
+		instance.body.Close()
+		instance.body = nil
+	}
+
+	if instance.size >= 0 && instance.offset >= instance.size {
+		return io.EOF
+	}
+
+	status := http.StatusPartialContent
+	if instance.offset == 0 {
+		status = http.StatusOK
+	}
+
+	resp := instance.reopen(instance.offset).CheckHTTPResponse(status)
+	if resp.Err != nil {
+		return resp.Err
+	}
+
+	instance.body = resp.Response.Body
+	instance.bodyOffset = instance.offset
+	return nil
+}
+
+// Seek 实现 RangeRSCloser seeker，仅记录位置，下次读取时再重新请求
+func (instance *RangeRSCloser) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += instance.offset
+	case io.SeekEnd:
+		if instance.size < 0 {
+			return 0, errors.New("未知的数据流大小")
+		}
+		offset += instance.size
+	default:
+		return 0, errors.New("无效的 whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("无效的偏移量")
+	}
+
+	instance.offset = offset
+	return offset, nil
+}
+
+// Close 实现 RangeRSCloser closer
+func (instance *RangeRSCloser) Close() error {
+	if instance.body == nil {
+		return nil
+	}
+	return instance.body.Close()
+}
+
 // BlackHole 将客户端发来的数据放入黑洞
 func BlackHole(r io.Reader) {
 	if !model.IsTrueVal(model.GetSettingByName("reset_after_upload_failed")) {
@@ -3,6 +3,7 @@ package request
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"io"
 	"io/ioutil"
@@ -231,6 +232,162 @@ func TestNopRSCloser_SetFirstFakeChunk(t *testing.T) {
 	asserts.EqualValues(20, rsc.status.Size)
 }
 
+func TestWithRange(t *testing.T) {
+	a := assert.New(t)
+	shared := newDefaultOption()
+
+	o := *shared
+	WithRange(0).apply(&o)
+	a.Empty(o.header.Get("Range"))
+
+	WithRange(10).apply(&o)
+	a.Equal("bytes=10-", o.header.Get("Range"))
+	a.Empty(shared.header.Get("Range"))
+}
+
+func TestResponse_GetRangeRSCloser(t *testing.T) {
+	a := assert.New(t)
+	content := strings.Repeat("0123456789", 100)
+	var offsets []int64
+	reopen := func(offset int64) *Response {
+		offsets = append(offsets, offset)
+		return &Response{Response: &http.Response{
+			StatusCode: http.StatusPartialContent,
+			Body:       ioutil.NopCloser(strings.NewReader(content[offset:])),
+		}}
+	}
+
+	// 直接返回错误
+	{
+		resp := Response{Err: errors.New("error")}
+		res, err := resp.GetRangeRSCloser(reopen)
+		a.Error(err)
+		a.Nil(res)
+	}
+
+	// 正常
+	{
+		resp := Response{
+			Response: &http.Response{ContentLength: -1, Body: ioutil.NopCloser(strings.NewReader(content))},
+		}
+		res, err := resp.GetRangeRSCloser(reopen)
+		a.NoError(err)
+		_, err = res.Seek(0, io.SeekEnd)
+		a.Error(err)
+		res.SetContentLength(int64(len(content)))
+
+		// 读取头部后回到开头，不重新请求
+		head := make([]byte, 512)
+		_, err = io.ReadFull(res, head)
+		a.NoError(err)
+		size, err := res.Seek(0, io.SeekEnd)
+		a.NoError(err)
+		a.EqualValues(1000, size)
+		_, err = res.Seek(0, io.SeekStart)
+		a.NoError(err)
+		all, err := ioutil.ReadAll(res)
+		a.NoError(err)
+		a.Equal(content, string(all))
+		a.Empty(offsets)
+
+		// seek 到其他位置后以 Range 请求重新获取
+		offset, err := res.Seek(995, io.SeekStart)
+		a.NoError(err)
+		a.EqualValues(995, offset)
+		all, err = ioutil.ReadAll(res)
+		a.NoError(err)
+		a.Equal("56789", string(all))
+		a.Equal([]int64{995}, offsets)
+
+		// 已缓存的头部
+		offset, err = res.Seek(-900, io.SeekCurrent)
+		a.NoError(err)
+		a.EqualValues(100, offset)
+		buf := make([]byte, 5)
+		_, err = io.ReadFull(res, buf)
+		a.NoError(err)
+		a.Equal("01234", string(buf))
+		a.Len(offsets, 1)
+
+		// 超出末尾
+		_, err = res.Seek(0, io.SeekEnd)
+		a.NoError(err)
+		_, err = res.Read(buf)
+		a.Equal(io.EOF, err)
+
+		// 无效的偏移量
+		_, err = res.Seek(-1, io.SeekStart)
+		a.Error(err)
+		a.NoError(res.Close())
+	}
+
+	// 重新请求失败
+	{
+		resp := Response{
+			Response: &http.Response{ContentLength: 1000, Body: ioutil.NopCloser(strings.NewReader(content))},
+		}
+		res, err := resp.GetRangeRSCloser(func(offset int64) *Response {
+			return &Response{Response: &http.Response{StatusCode: http.StatusOK}}
+		})
+		a.NoError(err)
+		_, err = res.Seek(600, io.SeekStart)
+		a.NoError(err)
+		_, err = res.Read(make([]byte, 10))
+		a.Error(err)
+	}
+}
+
+func TestGetSourceRSCloser(t *testing.T) {
+	a := assert.New(t)
+	content := strings.Repeat("0123456789", 100)
+
+	// 获取源地址失败
+	{
+		res, err := GetSourceRSCloser(&ClientMock{}, func() (string, error) {
+			return "", errors.New("error")
+		})
+		a.Error(err)
+		a.Nil(res)
+	}
+
+	// 正常，每次请求重新获取源地址
+	{
+		clientMock := &ClientMock{}
+		clientMock.On("Request", "GET", "http://source/1", nil, testMock.Anything).Return(&Response{
+			Response: &http.Response{
+				StatusCode:    http.StatusOK,
+				ContentLength: 1000,
+				Body:          ioutil.NopCloser(strings.NewReader(content)),
+			},
+		})
+		clientMock.On("Request", "GET", "http://source/2", nil, testMock.MatchedBy(func(opts []Option) bool {
+			var o options
+			for _, opt := range opts {
+				opt.apply(&o)
+			}
+			return o.header.Get("Range") == "bytes=995-"
+		})).Return(&Response{
+			Response: &http.Response{
+				StatusCode: http.StatusPartialContent,
+				Body:       ioutil.NopCloser(strings.NewReader(content[995:])),
+			},
+		})
+		signed := 0
+		res, err := GetSourceRSCloser(clientMock, func() (string, error) {
+			signed++
+			return fmt.Sprintf("http://source/%d", signed), nil
+		})
+		a.NoError(err)
+		_, err = res.Seek(995, io.SeekStart)
+		a.NoError(err)
+		all, err := ioutil.ReadAll(res)
+		a.NoError(err)
+		a.Equal("56789", string(all))
+		a.Equal(2, signed)
+		clientMock.AssertExpectations(t)
+	}
+}
+
 func TestBlackHole(t *testing.T) {
 	a := assert.New(t)
 	cache.Set("setting_reset_after_upload_failed", "true", 0)
@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// nodeServicePrefix 从机节点调用的接口，使用节点通信密钥签名认证，其余接口使用个人访问令牌认证
+	nodeServicePrefix = "/cloudreve.v1.NodeService/"
+	// NodeIDMetadata 从机节点 ID 所在的元数据
+	NodeIDMetadata = cluster.NodeRPCIDMetadata
+)
+
+// apiTokenReadMethods 只读令牌可调用的接口
+var apiTokenReadMethods = map[string]bool{
+	"/cloudreve.v1.FileService/List":     true,
+	"/cloudreve.v1.FileService/Stat":     true,
+	"/cloudreve.v1.FileService/Download": true,
+	"/cloudreve.v1.TaskService/Get":      true,
+	"/cloudreve.v1.TaskService/List":     true,
+	"/cloudreve.v1.TaskService/Watch":    true,
+}
+
+// apiTokenUploadMethods 上传令牌可调用的接口
+var apiTokenUploadMethods = map[string]bool{
+	"/cloudreve.v1.FileService/List":   true,
+	"/cloudreve.v1.FileService/Stat":   true,
+	"/cloudreve.v1.FileService/Upload": true,
+}
+
+// apiTokenFolderMethods 限定目录的令牌可调用的接口，均按路径定位对象
+var apiTokenFolderMethods = map[string]bool{
+	"/cloudreve.v1.FileService/List":            true,
+	"/cloudreve.v1.FileService/Stat":            true,
+	"/cloudreve.v1.FileService/CreateDirectory": true,
+	"/cloudreve.v1.FileService/Delete":          true,
+	"/cloudreve.v1.FileService/Move":            true,
+	"/cloudreve.v1.FileService/Rename":          true,
+	"/cloudreve.v1.FileService/Download":        true,
+	"/cloudreve.v1.FileService/Upload":          true,
+	"/cloudreve.v1.TaskService/Compress":        true,
+	"/cloudreve.v1.TaskService/Decompress":      true,
+}
+
+type ctxKey int
+
+const (
+	userCtx ctxKey = iota
+	apiTokenCtx
+	nodeCtx
+)
+
+// CurrentUser 获取调用者及其使用的个人访问令牌
+func CurrentUser(ctx context.Context) (*model.User, *model.APIToken) {
+	user, _ := ctx.Value(userCtx).(*model.User)
+	token, _ := ctx.Value(apiTokenCtx).(*model.APIToken)
+	return user, token
+}
+
+// CurrentNode 获取调用接口的从机节点
+func CurrentNode(ctx context.Context) cluster.Node {
+	node, _ := ctx.Value(nodeCtx).(cluster.Node)
+	return node
+}
+
+// UnaryAuth 认证一元调用
+func UnaryAuth(nodePool cluster.Pool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, nodePool, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuth 认证流式调用
+func StreamAuth(nodePool cluster.Pool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), nodePool, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authedStream 携带认证结果的流
+type authedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authedStream) Context() context.Context {
+	return s.ctx
+}
+
+// authenticate 按照调用的接口认证调用者，并将认证结果存入上下文
+func authenticate(ctx context.Context, nodePool cluster.Pool, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if strings.HasPrefix(method, nodeServicePrefix) {
+		node, err := authNode(md, nodePool, method)
+		if err != nil {
+			return nil, Error(err)
+		}
+		return context.WithValue(ctx, nodeCtx, node), nil
+	}
+
+	user, token, err := authAPIToken(md, peerIP(ctx), method)
+	if err != nil {
+		return nil, Error(err)
+	}
+	ctx = context.WithValue(ctx, userCtx, user)
+	return context.WithValue(ctx, apiTokenCtx, token), nil
+}
+
+// authAPIToken 使用个人访问令牌认证用户，并检查令牌能否调用当前接口
+func authAPIToken(md metadata.MD, ip, method string) (*model.User, *model.APIToken, error) {
+	raw := bearerToken(md)
+	if raw == "" {
+		return nil, nil, serializer.NewError(serializer.CodeCheckLogin, "API token is required", nil)
+	}
+
+	token, err := model.GetAPIToken(raw)
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeCredentialInvalid, "API token is invalid or expired", nil)
+	}
+
+	user, err := model.GetActiveUserByID(token.UserID)
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeCredentialInvalid, "API token is invalid or expired", nil)
+	}
+
+	if !user.Group.AllowIP(ip) {
+		return nil, nil, serializer.NewError(serializer.CodeIPNotAllowed, "", nil)
+	}
+
+	if !apiTokenAllowed(token, method) {
+		return nil, nil, serializer.NewError(serializer.CodeAPITokenScope, "API token is not allowed to perform this operation", nil)
+	}
+
+	token.Touch(ip)
+	return &user, token, nil
+}
+
+// apiTokenAllowed 检查令牌的权限范围及限定目录是否允许调用当前接口
+func apiTokenAllowed(token *model.APIToken, method string) bool {
+	switch token.Scope {
+	case model.APITokenScopeRead:
+		if !apiTokenReadMethods[method] {
+			return false
+		}
+	case model.APITokenScopeUpload:
+		if !apiTokenUploadMethods[method] {
+			return false
+		}
+	case model.APITokenScopeFull:
+	default:
+		return false
+	}
+
+	return token.Folder == "" || apiTokenFolderMethods[method]
+}
+
+// authNode 校验从机节点对调用的接口及节点 ID 的签名
+func authNode(md metadata.MD, nodePool cluster.Pool, method string) (cluster.Node, error) {
+	nodeID, err := strconv.ParseUint(firstMetadata(md, NodeIDMetadata), 10, 64)
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeParamErr, "未知的主机节点ID", err)
+	}
+
+	node := nodePool.GetNodeByID(uint(nodeID))
+	if node == nil {
+		return nil, serializer.NewError(serializer.CodeParamErr, "未知的主机节点ID", nil)
+	}
+
+	sign := bearerToken(md)
+	if sign == "" {
+		return nil, serializer.NewError(serializer.CodeCredentialInvalid, auth.ErrAuthHeaderMissing.Error(), nil)
+	}
+	if err := node.MasterAuthInstance().Check(cluster.NodeRPCSignContent(method, node.ID()), sign); err != nil {
+		return nil, serializer.NewError(serializer.CodeCredentialInvalid, err.Error(), err)
+	}
+
+	return node, nil
+}
+
+// bearerToken 读取元数据中的 Bearer 凭证
+func bearerToken(md metadata.MD) string {
+	header := firstMetadata(md, "authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// firstMetadata 读取元数据中的第一个值
+func firstMetadata(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// peerIP 调用者的 IP
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if ip, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+		return ip
+	}
+	return p.Addr.String()
+}
@@ -0,0 +1,149 @@
+package rpc
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// incoming 将客户端发出的元数据转换为服务端收到的上下文
+func incoming(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	return metadata.NewIncomingContext(context.Background(), md)
+}
+
+func TestBearerToken(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("", bearerToken(metadata.MD{}))
+	asserts.Equal("", bearerToken(metadata.Pairs("authorization", "Basic 123")))
+	asserts.Equal("cr_123", bearerToken(metadata.Pairs("authorization", "bearer cr_123 ")))
+}
+
+func TestAPITokenAllowed(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 完整权限
+	full := &model.APIToken{Scope: model.APITokenScopeFull}
+	asserts.True(apiTokenAllowed(full, "/cloudreve.v1.FileService/Delete"))
+	asserts.True(apiTokenAllowed(full, "/cloudreve.v1.TaskService/Compress"))
+	asserts.True(apiTokenAllowed(full, "/cloudreve.v1.TaskService/List"))
+
+	// 只读
+	read := &model.APIToken{Scope: model.APITokenScopeRead}
+	asserts.True(apiTokenAllowed(read, "/cloudreve.v1.FileService/Download"))
+	asserts.True(apiTokenAllowed(read, "/cloudreve.v1.TaskService/Watch"))
+	asserts.False(apiTokenAllowed(read, "/cloudreve.v1.FileService/Upload"))
+	asserts.False(apiTokenAllowed(read, "/cloudreve.v1.TaskService/Compress"))
+
+	// 仅上传
+	upload := &model.APIToken{Scope: model.APITokenScopeUpload}
+	asserts.True(apiTokenAllowed(upload, "/cloudreve.v1.FileService/Upload"))
+	asserts.True(apiTokenAllowed(upload, "/cloudreve.v1.FileService/List"))
+	asserts.False(apiTokenAllowed(upload, "/cloudreve.v1.FileService/Download"))
+	asserts.False(apiTokenAllowed(upload, "/cloudreve.v1.FileService/Delete"))
+
+	// 限定目录
+	folder := &model.APIToken{Scope: model.APITokenScopeFull, Folder: "/docs"}
+	asserts.True(apiTokenAllowed(folder, "/cloudreve.v1.FileService/Delete"))
+	asserts.True(apiTokenAllowed(folder, "/cloudreve.v1.TaskService/Compress"))
+	asserts.False(apiTokenAllowed(folder, "/cloudreve.v1.TaskService/List"))
+	asserts.False(apiTokenAllowed(folder, "/cloudreve.v1.TaskService/Get"))
+
+	// 未知的权限范围
+	asserts.False(apiTokenAllowed(&model.APIToken{Scope: "unknown"}, "/cloudreve.v1.FileService/List"))
+}
+
+func TestAuthenticate_APIToken(t *testing.T) {
+	asserts := assert.New(t)
+	np := &cluster.NodePool{}
+	np.Init()
+
+	// 未携带令牌
+	{
+		_, err := authenticate(context.Background(), np, "/cloudreve.v1.FileService/List")
+		asserts.Equal(codes.Unauthenticated, status.Code(err))
+	}
+
+	// 令牌格式错误
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer 123"))
+		_, err := authenticate(ctx, np, "/cloudreve.v1.FileService/List")
+		asserts.Equal(codes.Unauthenticated, status.Code(err))
+	}
+
+	// 未认证时上下文中没有用户
+	user, token := CurrentUser(context.Background())
+	asserts.Nil(user)
+	asserts.Nil(token)
+}
+
+func TestAuthenticate_Node(t *testing.T) {
+	asserts := assert.New(t)
+	np := &cluster.NodePool{}
+	np.Init()
+	np.Add(&model.Node{Model: gorm.Model{ID: 38}})
+	authInstance := auth.HMACAuth{SecretKey: []byte("")}
+	method := "/cloudreve.v1.NodeService/Ping"
+
+	// 节点 ID 无法解析
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(NodeIDMetadata, "unknown"))
+		_, err := authenticate(ctx, np, method)
+		asserts.Equal(codes.InvalidArgument, status.Code(err))
+	}
+
+	// 节点不存在
+	{
+		ctx := incoming(cluster.SignNodeRPC(context.Background(), authInstance, 39, method, 60))
+		_, err := authenticate(ctx, np, method)
+		asserts.Equal(codes.InvalidArgument, status.Code(err))
+	}
+
+	// 未签名
+	{
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(NodeIDMetadata, "38"))
+		_, err := authenticate(ctx, np, method)
+		asserts.Equal(codes.Unauthenticated, status.Code(err))
+	}
+
+	// 签名的接口不符
+	{
+		ctx := incoming(cluster.SignNodeRPC(context.Background(), authInstance, 38, "/cloudreve.v1.NodeService/GetOneDriveCredential", 60))
+		_, err := authenticate(ctx, np, method)
+		asserts.Equal(codes.Unauthenticated, status.Code(err))
+	}
+
+	// 签名已过期
+	{
+		ctx := incoming(cluster.SignNodeRPC(context.Background(), authInstance, 38, method, -10))
+		_, err := authenticate(ctx, np, method)
+		asserts.Equal(codes.Unauthenticated, status.Code(err))
+	}
+
+	// 成功
+	{
+		ctx := incoming(cluster.SignNodeRPC(context.Background(), authInstance, 38, method, 60))
+		ctx, err := authenticate(ctx, np, method)
+		asserts.NoError(err)
+		asserts.NotNil(CurrentNode(ctx))
+		asserts.EqualValues(38, CurrentNode(ctx).ID())
+	}
+}
+
+func TestPeerIP(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("", peerIP(context.Background()))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.2"), Port: 5212}})
+	asserts.Equal("192.168.1.2", peerIP(ctx))
+}
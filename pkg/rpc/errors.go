@@ -0,0 +1,92 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// appErrorCodes 业务错误码对应的 gRPC 状态码，未列出的客户端错误视为 FailedPrecondition，
+// 服务端错误视为 Internal
+var appErrorCodes = map[int]codes.Code{
+	serializer.CodeNotFound:             codes.NotFound,
+	serializer.CodeFileNotFound:         codes.NotFound,
+	serializer.CodeParentNotExist:       codes.NotFound,
+	serializer.CodePolicyNotExist:       codes.NotFound,
+	serializer.CodeObjectExist:          codes.AlreadyExists,
+	serializer.CodeConflict:             codes.Aborted,
+	serializer.CodeLocked:               codes.FailedPrecondition,
+	serializer.CodeCheckLogin:           codes.Unauthenticated,
+	serializer.CodeCredentialInvalid:    codes.Unauthenticated,
+	serializer.CodeNoPermissionErr:      codes.PermissionDenied,
+	serializer.CodeGroupNotAllowed:      codes.PermissionDenied,
+	serializer.CodeAPITokenScope:        codes.PermissionDenied,
+	serializer.CodeIPNotAllowed:         codes.PermissionDenied,
+	serializer.CodeParamErr:             codes.InvalidArgument,
+	serializer.CodeIllegalObjectName:    codes.InvalidArgument,
+	serializer.CodeFileTypeNotAllowed:   codes.InvalidArgument,
+	serializer.CodeInsufficientCapacity: codes.ResourceExhausted,
+	serializer.CodeFileTooLarge:         codes.ResourceExhausted,
+	serializer.CodeFeatureNotEnabled:    codes.FailedPrecondition,
+	serializer.CodeNodeOffline:          codes.Unavailable,
+}
+
+// vfsErrorCodes 按路径访问文件时的错误对应的 gRPC 状态码
+var vfsErrorCodes = map[error]codes.Code{
+	vfs.ErrNotExist:         codes.NotFound,
+	vfs.ErrExist:            codes.AlreadyExists,
+	vfs.ErrPermissionDenied: codes.PermissionDenied,
+	vfs.ErrQuotaExceeded:    codes.ResourceExhausted,
+	vfs.ErrDirNotEmpty:      codes.FailedPrecondition,
+	vfs.ErrLocked:           codes.FailedPrecondition,
+}
+
+// Error 将服务返回的错误转换为 gRPC 状态，状态信息中附带业务错误码以便与 HTTP 接口对照
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var appErr serializer.AppError
+	if errors.As(err, &appErr) {
+		msg := appErr.Msg
+		if msg == "" && appErr.RawError != nil {
+			msg = appErr.RawError.Error()
+		}
+		return status.Error(appErrorCode(appErr.Code), fmt.Sprintf("%s (code %d)", msg, appErr.Code))
+	}
+
+	for target, code := range vfsErrorCodes {
+		if errors.Is(err, target) {
+			return status.Error(code, err.Error())
+		}
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
+
+// ResponseError 将 HTTP 接口服务的响应转换为 gRPC 状态，成功时返回 nil
+func ResponseError(res serializer.Response) error {
+	if res.Code == 0 {
+		return nil
+	}
+	return Error(serializer.NewError(res.Code, res.Msg, nil))
+}
+
+// appErrorCode 业务错误码对应的 gRPC 状态码
+func appErrorCode(code int) codes.Code {
+	if c, ok := appErrorCodes[code]; ok {
+		return c
+	}
+	if code >= 50000 || code == serializer.CodeNotSet {
+		return codes.Internal
+	}
+	return codes.FailedPrecondition
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestError(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.NoError(Error(nil))
+
+	// 已是 gRPC 状态
+	{
+		err := status.Error(codes.Aborted, "aborted")
+		asserts.Equal(err, Error(err))
+	}
+
+	// 业务错误
+	{
+		testCases := []struct {
+			code     int
+			expected codes.Code
+		}{
+			{serializer.CodeNotFound, codes.NotFound},
+			{serializer.CodeParentNotExist, codes.NotFound},
+			{serializer.CodeObjectExist, codes.AlreadyExists},
+			{serializer.CodeCredentialInvalid, codes.Unauthenticated},
+			{serializer.CodeAPITokenScope, codes.PermissionDenied},
+			{serializer.CodeParamErr, codes.InvalidArgument},
+			{serializer.CodeInsufficientCapacity, codes.ResourceExhausted},
+			{serializer.CodeNodeOffline, codes.Unavailable},
+			{serializer.CodeDBError, codes.Internal},
+			{serializer.CodeUnsupportedArchiveType, codes.FailedPrecondition},
+			{serializer.CodeNotSet, codes.Internal},
+		}
+		for _, testCase := range testCases {
+			err := Error(serializer.NewError(testCase.code, "msg", nil))
+			asserts.Equal(testCase.expected, status.Code(err), testCase.code)
+			asserts.Equal(fmt.Sprintf("msg (code %d)", testCase.code), status.Convert(err).Message())
+		}
+	}
+
+	// 业务错误无提示信息时使用底层错误
+	{
+		err := Error(fmt.Errorf("wrapped: %w", serializer.NewError(serializer.CodeFileNotFound, "", errors.New("raw"))))
+		asserts.Equal(codes.NotFound, status.Code(err))
+		asserts.Equal(fmt.Sprintf("raw (code %d)", serializer.CodeFileNotFound), status.Convert(err).Message())
+	}
+
+	// 按路径访问文件时的错误
+	{
+		asserts.Equal(codes.NotFound, status.Code(Error(vfs.ErrNotExist)))
+		asserts.Equal(codes.AlreadyExists, status.Code(Error(vfs.ErrExist)))
+		asserts.Equal(codes.PermissionDenied, status.Code(Error(vfs.ErrPermissionDenied)))
+		asserts.Equal(codes.ResourceExhausted, status.Code(Error(vfs.ErrQuotaExceeded)))
+		asserts.Equal(codes.FailedPrecondition, status.Code(Error(vfs.ErrDirNotEmpty)))
+	}
+
+	// 其他错误
+	{
+		err := Error(errors.New("unknown"))
+		asserts.Equal(codes.Internal, status.Code(err))
+		asserts.Equal("unknown", status.Convert(err).Message())
+	}
+}
+
+func TestResponseError(t *testing.T) {
+	asserts := assert.New(t)
+
+	asserts.NoError(ResponseError(serializer.Response{}))
+
+	err := ResponseError(serializer.Err(serializer.CodePolicyNotExist, "policy not exist", nil))
+	asserts.Equal(codes.NotFound, status.Code(err))
+	asserts.Equal(fmt.Sprintf("policy not exist (code %d)", serializer.CodePolicyNotExist), status.Convert(err).Message())
+}
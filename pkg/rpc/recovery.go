@@ -0,0 +1,35 @@
+package rpc
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryRecovery 捕获一元调用处理中的 panic，避免服务退出
+func UnaryRecovery() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecovery 捕获流式调用处理中的 panic，避免服务退出
+func StreamRecovery() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(info.FullMethod, &err)
+		return handler(srv, ss)
+	}
+}
+
+// recoverPanic 记录 panic 并返回内部错误
+func recoverPanic(method string, err *error) {
+	if r := recover(); r != nil {
+		util.Log().Error("gRPC 接口 %s 处理时发生错误, %v\n%s", method, r, debug.Stack())
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
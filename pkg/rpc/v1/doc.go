@@ -0,0 +1,4 @@
+// Package rpcv1 第一版 gRPC 接口的协议定义，修改 .proto 文件后执行 go generate 重新生成代码
+package rpcv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative file.proto task.proto node.proto
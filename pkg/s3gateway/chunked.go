@@ -0,0 +1,137 @@
+package s3gateway
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxChunkSize aws-chunked 编码中单个分块的最大长度
+const maxChunkSize = 16 << 20
+
+// chunkSigner 按顺序校验各分块的签名，每个分块的签名依赖上一个分块的签名
+type chunkSigner struct {
+	key     []byte
+	date    string
+	scope   string
+	prevSig string
+}
+
+// verify 校验分块签名，通过后记录为下一分块的前序签名
+func (s *chunkSigner) verify(data []byte, signature string) bool {
+	stringToSign := strings.Join([]string{
+		signV4Algorithm + "-PAYLOAD",
+		s.date,
+		s.scope,
+		s.prevSig,
+		emptySHA256,
+		sha256Hex(data),
+	}, "\n")
+
+	expected := hex.EncodeToString(hmacSHA256(s.key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return false
+	}
+	s.prevSig = signature
+	return true
+}
+
+// chunkedReader 解码 aws-chunked 编码的请求体，signer 不为空时校验每个分块的签名
+type chunkedReader struct {
+	body   io.ReadCloser
+	r      *bufio.Reader
+	signer *chunkSigner
+	buf    []byte
+	off    int
+	err    error
+}
+
+func newChunkedReader(body io.ReadCloser, signer *chunkSigner) *chunkedReader {
+	return &chunkedReader{body: body, r: bufio.NewReader(body), signer: signer}
+}
+
+func (cr *chunkedReader) Read(p []byte) (int, error) {
+	for cr.off >= len(cr.buf) {
+		if cr.err != nil {
+			return 0, cr.err
+		}
+		cr.err = cr.readChunk()
+	}
+
+	n := copy(p, cr.buf[cr.off:])
+	cr.off += n
+	return n, nil
+}
+
+func (cr *chunkedReader) Close() error {
+	return cr.body.Close()
+}
+
+// readChunk 读取下一个分块，读取到结尾的空分块时返回 io.EOF
+func (cr *chunkedReader) readChunk() error {
+	line, err := cr.readLine()
+	if err != nil {
+		return err
+	}
+
+	// 分块头格式为 <十六进制长度>[;chunk-signature=<签名>]
+	sizeHex, signature := line, ""
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		sizeHex = line[:i]
+		if ext := line[i+1:]; strings.HasPrefix(ext, "chunk-signature=") {
+			signature = strings.TrimPrefix(ext, "chunk-signature=")
+		}
+	}
+
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return ErrIncompleteBody
+	}
+
+	if cap(cr.buf) < int(size) {
+		cr.buf = make([]byte, size)
+	}
+	cr.buf, cr.off = cr.buf[:size], 0
+	if _, err := io.ReadFull(cr.r, cr.buf); err != nil {
+		return ErrIncompleteBody
+	}
+
+	if cr.signer != nil && !cr.signer.verify(cr.buf, signature) {
+		cr.buf = cr.buf[:0]
+		return ErrSignatureDoesNotMatch
+	}
+
+	if size > 0 {
+		// 分块数据以 CRLF 结尾
+		if crlf, err := cr.readLine(); err != nil || crlf != "" {
+			return ErrIncompleteBody
+		}
+		return nil
+	}
+
+	// 最后一个分块之后可能附带校验和等尾部字段，以空行结束
+	for {
+		trailer, err := cr.readLine()
+		if err != nil {
+			return ErrIncompleteBody
+		}
+		if trailer == "" {
+			return io.EOF
+		}
+	}
+}
+
+// readLine 读取以 CRLF 结尾的一行，超出缓冲区长度的行视为非法
+func (cr *chunkedReader) readLine() (string, error) {
+	line, err := cr.r.ReadSlice('\n')
+	if err != nil {
+		if err == io.EOF || err == bufio.ErrBufferFull {
+			return "", ErrIncompleteBody
+		}
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(line), "\n"), "\r"), nil
+}
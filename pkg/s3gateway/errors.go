@@ -0,0 +1,85 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Error S3 接口错误，Code 与 AWS 定义的错误码一致，以便客户端正确处理
+type Error struct {
+	Code    string
+	Message string
+	Status  int
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+var (
+	ErrAccessDenied                 = &Error{"AccessDenied", "Access Denied", http.StatusForbidden}
+	ErrInvalidAccessKeyID           = &Error{"InvalidAccessKeyId", "The access key ID you provided does not exist in our records.", http.StatusForbidden}
+	ErrSignatureDoesNotMatch        = &Error{"SignatureDoesNotMatch", "The request signature we calculated does not match the signature you provided.", http.StatusForbidden}
+	ErrRequestTimeTooSkewed         = &Error{"RequestTimeTooSkewed", "The difference between the request time and the server's time is too large.", http.StatusForbidden}
+	ErrExpiredPresignRequest        = &Error{"AccessDenied", "Request has expired", http.StatusForbidden}
+	ErrAuthorizationHeaderMalformed = &Error{"AuthorizationHeaderMalformed", "The authorization header is malformed.", http.StatusBadRequest}
+	ErrAuthorizationQueryMalformed  = &Error{"AuthorizationQueryParametersError", "The authorization query parameters are malformed.", http.StatusBadRequest}
+	ErrMissingContentSHA256         = &Error{"InvalidRequest", "Missing required header for this request: x-amz-content-sha256", http.StatusBadRequest}
+	ErrContentSHA256Mismatch        = &Error{"XAmzContentSHA256Mismatch", "The provided 'x-amz-content-sha256' header does not match what was computed.", http.StatusBadRequest}
+	ErrIncompleteBody               = &Error{"IncompleteBody", "You did not provide the number of bytes specified by the Content-Length HTTP header.", http.StatusBadRequest}
+	ErrMissingContentLength         = &Error{"MissingContentLength", "You must provide the Content-Length HTTP header.", http.StatusLengthRequired}
+	ErrInvalidArgument              = &Error{"InvalidArgument", "Invalid argument.", http.StatusBadRequest}
+	ErrInvalidObjectName            = &Error{"InvalidArgument", "Object key is not a valid path.", http.StatusBadRequest}
+	ErrMalformedXML                 = &Error{"MalformedXML", "The XML you provided was not well-formed or did not validate against our published schema.", http.StatusBadRequest}
+	ErrEntityTooLarge               = &Error{"EntityTooLarge", "Your proposed upload exceeds the maximum allowed object size.", http.StatusBadRequest}
+	ErrQuotaExceeded                = &Error{"QuotaExceeded", "Your storage quota or upload traffic limit has been exceeded.", http.StatusForbidden}
+	ErrKeyConflict                  = &Error{"InvalidArgument", "An object or folder with the same name already exists.", http.StatusConflict}
+	ErrObjectLocked                 = &Error{"AccessDenied", "The object is locked by a WebDAV client.", http.StatusForbidden}
+	ErrInvalidPart                  = &Error{"InvalidPart", "One or more of the specified parts could not be found.", http.StatusBadRequest}
+	ErrInvalidPartOrder             = &Error{"InvalidPartOrder", "The list of parts was not in ascending order.", http.StatusBadRequest}
+	ErrNoSuchBucket                 = &Error{"NoSuchBucket", "The specified bucket does not exist.", http.StatusNotFound}
+	ErrNoSuchKey                    = &Error{"NoSuchKey", "The specified key does not exist.", http.StatusNotFound}
+	ErrNoSuchUpload                 = &Error{"NoSuchUpload", "The specified multipart upload does not exist.", http.StatusNotFound}
+	ErrNotImplemented               = &Error{"NotImplemented", "A header or query you provided implies functionality that is not implemented.", http.StatusNotImplemented}
+	ErrInternalError                = &Error{"InternalError", "We encountered an internal error. Please try again.", http.StatusInternalServerError}
+	ErrServiceUnavailable           = &Error{"ServiceUnavailable", "The service is under maintenance. Please try again later.", http.StatusServiceUnavailable}
+)
+
+// errorResponse 错误响应
+type errorResponse struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string
+	Message  string
+	Resource string
+}
+
+// WriteError 返回 S3 格式的错误响应，非 S3 错误视为内部错误
+func WriteError(c *gin.Context, err error) {
+	var s3Err *Error
+	if !errors.As(err, &s3Err) {
+		s3Err = ErrInternalError
+	}
+
+	// HEAD 请求不能携带响应正文
+	if c.Request.Method == http.MethodHead {
+		c.Status(s3Err.Status)
+		return
+	}
+
+	WriteXML(c, s3Err.Status, &errorResponse{
+		Code:     s3Err.Code,
+		Message:  s3Err.Message,
+		Resource: c.Request.URL.Path,
+	})
+}
+
+// WriteXML 以 XML 格式返回响应
+func WriteXML(c *gin.Context, status int, v interface{}) {
+	c.Header("Content-Type", "application/xml")
+	c.Status(status)
+	c.Writer.WriteString(xml.Header)
+	xml.NewEncoder(c.Writer).Encode(v)
+}
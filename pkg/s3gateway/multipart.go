@@ -0,0 +1,17 @@
+package s3gateway
+
+import (
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// MultipartTTL 未完成的分片上传的有效期
+const MultipartTTL = 7 * 24 * time.Hour
+
+// MultipartRoot 分片上传临时文件的存放目录
+func MultipartRoot() string {
+	return filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "s3_multipart")
+}
@@ -0,0 +1,142 @@
+package s3gateway
+
+import (
+	"encoding/xml"
+	"time"
+)
+
+// TimeFormat 响应中时间的格式
+const TimeFormat = "2006-01-02T15:04:05.000Z"
+
+// FormatTime 将时间格式化为 S3 响应使用的格式
+func FormatTime(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// Owner 存储桶或对象的所有者
+type Owner struct {
+	ID          string
+	DisplayName string
+}
+
+// Bucket 存储桶
+type Bucket struct {
+	Name         string
+	CreationDate string
+}
+
+// ListAllMyBucketsResult 存储桶列表
+type ListAllMyBucketsResult struct {
+	XMLName xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListAllMyBucketsResult"`
+	Owner   Owner
+	Buckets []Bucket `xml:"Buckets>Bucket"`
+}
+
+// LocationConstraint 存储桶所在区域
+type LocationConstraint struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ LocationConstraint"`
+	Location string   `xml:",chardata"`
+}
+
+// Object 对象
+type Object struct {
+	Key          string
+	LastModified string
+	ETag         string
+	Size         uint64
+	StorageClass string
+}
+
+// CommonPrefix 按分隔符归并的公共前缀
+type CommonPrefix struct {
+	Prefix string
+}
+
+// ListBucketResult 对象列表，同时用于 ListObjects 与 ListObjectsV2
+type ListBucketResult struct {
+	XMLName               xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListBucketResult"`
+	Name                  string
+	Prefix                string
+	Marker                string `xml:",omitempty"`
+	NextMarker            string `xml:",omitempty"`
+	ContinuationToken     string `xml:",omitempty"`
+	NextContinuationToken string `xml:",omitempty"`
+	StartAfter            string `xml:",omitempty"`
+	KeyCount              *int   `xml:",omitempty"`
+	MaxKeys               int
+	Delimiter             string `xml:",omitempty"`
+	EncodingType          string `xml:",omitempty"`
+	IsTruncated           bool
+	Contents              []Object
+	CommonPrefixes        []CommonPrefix
+}
+
+// InitiateMultipartUploadResult 创建分片上传的结果
+type InitiateMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ InitiateMultipartUploadResult"`
+	Bucket   string
+	Key      string
+	UploadID string `xml:"UploadId"`
+}
+
+// CompletedPart 完成分片上传时客户端提交的分片
+type CompletedPart struct {
+	PartNumber int
+	ETag       string
+}
+
+// CompleteMultipartUpload 完成分片上传的请求
+type CompleteMultipartUpload struct {
+	XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+	Parts   []CompletedPart `xml:"Part"`
+}
+
+// CompleteMultipartUploadResult 完成分片上传的结果
+type CompleteMultipartUploadResult struct {
+	XMLName  xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ CompleteMultipartUploadResult"`
+	Location string
+	Bucket   string
+	Key      string
+	ETag     string
+}
+
+// ListMultipartUploadsResult 进行中的分片上传列表
+type ListMultipartUploadsResult struct {
+	XMLName        xml.Name `xml:"http://s3.amazonaws.com/doc/2006-03-01/ ListMultipartUploadsResult"`
+	Bucket         string
+	KeyMarker      string
+	UploadIDMarker string `xml:"UploadIdMarker"`
+	MaxUploads     int
+	IsTruncated    bool
+}
+
+// ObjectIdentifier 批量删除请求中的对象
+type ObjectIdentifier struct {
+	Key string
+}
+
+// Delete 批量删除对象的请求
+type Delete struct {
+	XMLName xml.Name           `xml:"Delete"`
+	Quiet   bool               `xml:"Quiet"`
+	Objects []ObjectIdentifier `xml:"Object"`
+}
+
+// DeletedObject 已删除的对象
+type DeletedObject struct {
+	Key string
+}
+
+// DeleteError 删除失败的对象
+type DeleteError struct {
+	Key     string
+	Code    string
+	Message string
+}
+
+// DeleteResult 批量删除的结果
+type DeleteResult struct {
+	XMLName xml.Name        `xml:"http://s3.amazonaws.com/doc/2006-03-01/ DeleteResult"`
+	Deleted []DeletedObject `xml:"Deleted"`
+	Errors  []DeleteError   `xml:"Error"`
+}
@@ -0,0 +1,328 @@
+package s3gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signV4Algorithm = "AWS4-HMAC-SHA256"
+	// UnsignedPayload 请求体未参与签名
+	UnsignedPayload = "UNSIGNED-PAYLOAD"
+	// StreamingPayload 请求体以 aws-chunked 编码，每个分块单独签名
+	StreamingPayload = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	// StreamingUnsignedTrailer 请求体以 aws-chunked 编码，分块不签名，校验和附在结尾
+	StreamingUnsignedTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+
+	amzDateFormat   = "20060102T150405Z"
+	scopeDateFormat = "20060102"
+	emptySHA256     = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	// MaxClockSkew 请求时间与服务器时间允许的最大偏差
+	MaxClockSkew = 15 * time.Minute
+	// maxPresignExpires 预签名 URL 的最长有效期，单位为秒
+	maxPresignExpires = 7 * 24 * 3600
+)
+
+// Signature 请求携带的 AWS Signature Version 4 签名
+type Signature struct {
+	AccessKey     string
+	Date          time.Time
+	Region        string
+	SignedHeaders []string
+	Signature     string
+	PayloadHash   string
+	presigned     bool
+}
+
+// Parse 从 Authorization 头或预签名 URL 中解析签名，未携带签名时返回 ErrAccessDenied
+func Parse(r *http.Request) (*Signature, error) {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		return parseHeader(r, auth)
+	}
+	if r.URL.Query().Get("X-Amz-Algorithm") != "" {
+		return parsePresigned(r)
+	}
+	return nil, ErrAccessDenied
+}
+
+// parseHeader 解析 Authorization 头中的签名
+func parseHeader(r *http.Request, auth string) (*Signature, error) {
+	if !strings.HasPrefix(auth, signV4Algorithm+" ") {
+		return nil, ErrAuthorizationHeaderMalformed
+	}
+
+	var credential, signedHeaders string
+	sig := &Signature{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, signV4Algorithm+" "), ",") {
+		kv := strings.SplitN(strings.TrimSpace(field), "=", 2)
+		if len(kv) != 2 {
+			return nil, ErrAuthorizationHeaderMalformed
+		}
+		switch kv[0] {
+		case "Credential":
+			credential = kv[1]
+		case "SignedHeaders":
+			signedHeaders = kv[1]
+		case "Signature":
+			sig.Signature = kv[1]
+		}
+	}
+
+	date := r.Header.Get("X-Amz-Date")
+	if date == "" {
+		date = r.Header.Get("Date")
+	}
+	if err := sig.parseFields(credential, signedHeaders, date); err != nil {
+		return nil, ErrAuthorizationHeaderMalformed
+	}
+
+	if skew := time.Since(sig.Date); skew > MaxClockSkew || skew < -MaxClockSkew {
+		return nil, ErrRequestTimeTooSkewed
+	}
+
+	sig.PayloadHash = r.Header.Get("X-Amz-Content-Sha256")
+	if sig.PayloadHash == "" {
+		return nil, ErrMissingContentSHA256
+	}
+
+	return sig, nil
+}
+
+// parsePresigned 解析预签名 URL 中的签名
+func parsePresigned(r *http.Request) (*Signature, error) {
+	query := r.URL.Query()
+	if query.Get("X-Amz-Algorithm") != signV4Algorithm {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	sig := &Signature{Signature: query.Get("X-Amz-Signature"), presigned: true}
+	if err := sig.parseFields(query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"), query.Get("X-Amz-Date")); err != nil {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires <= 0 || expires > maxPresignExpires {
+		return nil, ErrAuthorizationQueryMalformed
+	}
+
+	now := time.Now()
+	if now.Before(sig.Date.Add(-MaxClockSkew)) {
+		return nil, ErrRequestTimeTooSkewed
+	}
+	if now.After(sig.Date.Add(time.Duration(expires) * time.Second)) {
+		return nil, ErrExpiredPresignRequest
+	}
+
+	sig.PayloadHash = query.Get("X-Amz-Content-Sha256")
+	if sig.PayloadHash == "" {
+		sig.PayloadHash = UnsignedPayload
+	}
+
+	return sig, nil
+}
+
+// parseFields 解析凭证范围、参与签名的请求头及请求时间
+func (sig *Signature) parseFields(credential, signedHeaders, date string) error {
+	// Credential 格式为 <AccessKey>/<日期>/<区域>/s3/aws4_request
+	scope := strings.Split(credential, "/")
+	if len(scope) != 5 || scope[0] == "" || scope[3] != "s3" || scope[4] != "aws4_request" {
+		return ErrInvalidArgument
+	}
+
+	t, err := time.Parse(amzDateFormat, date)
+	if err != nil {
+		if t, err = http.ParseTime(date); err != nil {
+			return err
+		}
+	}
+	if t.UTC().Format(scopeDateFormat) != scope[1] {
+		return ErrInvalidArgument
+	}
+
+	sig.SignedHeaders = strings.Split(signedHeaders, ";")
+	hasHost := false
+	for _, h := range sig.SignedHeaders {
+		if h == "host" {
+			hasHost = true
+		}
+	}
+	if !hasHost || sig.Signature == "" {
+		return ErrInvalidArgument
+	}
+
+	sig.AccessKey = scope[0]
+	sig.Region = scope[2]
+	sig.Date = t.UTC()
+	return nil
+}
+
+// scope 签名的凭证范围
+func (sig *Signature) scope() string {
+	return strings.Join([]string{sig.Date.Format(scopeDateFormat), sig.Region, "s3", "aws4_request"}, "/")
+}
+
+// Verify 使用签名密钥校验请求签名，校验通过后替换请求体，以便在读取时校验请求体的哈希或分块签名
+func (sig *Signature) Verify(r *http.Request, secret string) error {
+	key := signingKey(secret, sig.Date.Format(scopeDateFormat), sig.Region)
+	stringToSign := strings.Join([]string{
+		signV4Algorithm,
+		sig.Date.Format(amzDateFormat),
+		sig.scope(),
+		sha256Hex([]byte(sig.canonicalRequest(r))),
+	}, "\n")
+
+	expected := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	if !hmac.Equal([]byte(expected), []byte(sig.Signature)) {
+		return ErrSignatureDoesNotMatch
+	}
+
+	switch sig.PayloadHash {
+	case UnsignedPayload:
+	case StreamingPayload:
+		if sig.presigned {
+			return ErrInvalidArgument
+		}
+		r.Body = newChunkedReader(r.Body, &chunkSigner{
+			key:     key,
+			date:    sig.Date.Format(amzDateFormat),
+			scope:   sig.scope(),
+			prevSig: sig.Signature,
+		})
+	case StreamingUnsignedTrailer:
+		// 结尾附带的校验和由客户端自行保证，此处仅解码
+		r.Body = newChunkedReader(r.Body, nil)
+	default:
+		if _, err := hex.DecodeString(sig.PayloadHash); err != nil || len(sig.PayloadHash) != sha256.Size*2 {
+			return ErrContentSHA256Mismatch
+		}
+		r.Body = &hashReader{ReadCloser: r.Body, hash: sha256.New(), expected: sig.PayloadHash}
+	}
+
+	return nil
+}
+
+// canonicalRequest 构建规范请求
+func (sig *Signature) canonicalRequest(r *http.Request) string {
+	query := r.URL.Query()
+	if sig.presigned {
+		query.Del("X-Amz-Signature")
+	}
+
+	var headers bytes.Buffer
+	for _, name := range sig.SignedHeaders {
+		var values []string
+		switch name {
+		case "host":
+			values = []string{r.Host}
+		case "content-length":
+			values = r.Header.Values(name)
+			if len(values) == 0 {
+				values = []string{strconv.FormatInt(r.ContentLength, 10)}
+			}
+		default:
+			values = r.Header.Values(name)
+		}
+
+		for i := range values {
+			values[i] = strings.Join(strings.Fields(values[i]), " ")
+		}
+		headers.WriteString(name + ":" + strings.Join(values, ",") + "\n")
+	}
+
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+
+	return strings.Join([]string{
+		r.Method,
+		URIEncode(path, false),
+		canonicalQuery(query),
+		headers.String(),
+		strings.Join(sig.SignedHeaders, ";"),
+		sig.PayloadHash,
+	}, "\n")
+}
+
+// canonicalQuery 构建规范查询字符串
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		values := append([]string{}, query[k]...)
+		sort.Strings(values)
+		for _, v := range values {
+			pairs = append(pairs, URIEncode(k, true)+"="+URIEncode(v, true))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// URIEncode 按照 AWS 的规则对字符串进行百分号编码，仅保留非保留字符
+func URIEncode(s string, encodeSlash bool) string {
+	const hexChars = "0123456789ABCDEF"
+	var buf strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			buf.WriteByte(c)
+			continue
+		}
+		buf.WriteByte('%')
+		buf.WriteByte(hexChars[c>>4])
+		buf.WriteByte(hexChars[c&15])
+	}
+	return buf.String()
+}
+
+// signingKey 派生签名密钥
+func signingKey(secret, date, region string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), date)
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, "s3")
+	return hmacSHA256(key, "aws4_request")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// hashReader 读取完毕时校验请求体的 SHA256
+type hashReader struct {
+	io.ReadCloser
+	hash     hash.Hash
+	expected string
+}
+
+func (r *hashReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.hash.Write(p[:n])
+	if err == io.EOF && hex.EncodeToString(r.hash.Sum(nil)) != r.expected {
+		return n, ErrContentSHA256Mismatch
+	}
+	return n, err
+}
@@ -0,0 +1,120 @@
+package saml
+
+import (
+	"encoding/xml"
+	"sort"
+	"strings"
+)
+
+// canonicalize 按排他性 XML 规范化（不含注释）输出元素，exclude 中的元素不输出，
+// inclusive 为需按包含方式处理的命名空间前缀，#default 表示默认命名空间
+func canonicalize(el *element, exclude *element, inclusive []string) []byte {
+	var b strings.Builder
+	writeCanonical(&b, el, exclude, inclusive, map[string]string{})
+	return []byte(b.String())
+}
+
+func writeCanonical(b *strings.Builder, el *element, exclude *element, inclusive []string, rendered map[string]string) {
+	// 本元素需要输出的命名空间前缀
+	prefixes := map[string]bool{el.prefix: true}
+	var attrs []xml.Attr
+	for _, attr := range el.attrs {
+		if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+			continue
+		}
+		if attr.Name.Space != "" {
+			prefixes[attr.Name.Space] = true
+		}
+		attrs = append(attrs, attr)
+	}
+	for _, prefix := range inclusive {
+		if prefix == "#default" {
+			prefix = ""
+		}
+		if prefix == "" || el.lookupNamespace(prefix) != "" {
+			prefixes[prefix] = true
+		}
+	}
+
+	// 只输出与已输出祖先不同的声明
+	current := make(map[string]string, len(rendered))
+	for k, v := range rendered {
+		current[k] = v
+	}
+	var decls []string
+	for prefix := range prefixes {
+		if prefix == "xml" {
+			continue
+		}
+		uri := el.lookupNamespace(prefix)
+		if prev, ok := rendered[prefix]; (ok && prev == uri) || (!ok && prefix == "" && uri == "") {
+			continue
+		}
+		current[prefix] = uri
+		decls = append(decls, prefix)
+	}
+	sort.Strings(decls)
+
+	// 属性按命名空间及名称排序
+	sort.Slice(attrs, func(i, j int) bool {
+		nsI, nsJ := el.lookupNamespace(attrs[i].Name.Space), el.lookupNamespace(attrs[j].Name.Space)
+		if attrs[i].Name.Space == "" {
+			nsI = ""
+		}
+		if attrs[j].Name.Space == "" {
+			nsJ = ""
+		}
+		if nsI != nsJ {
+			return nsI < nsJ
+		}
+		return attrs[i].Name.Local < attrs[j].Name.Local
+	})
+
+	name := qualifiedName(el.prefix, el.local)
+	b.WriteString("<" + name)
+	for _, prefix := range decls {
+		if prefix == "" {
+			b.WriteString(` xmlns="`)
+		} else {
+			b.WriteString(" xmlns:" + prefix + `="`)
+		}
+		b.WriteString(escapeAttr(current[prefix]) + `"`)
+	}
+	for _, attr := range attrs {
+		b.WriteString(" " + qualifiedName(attr.Name.Space, attr.Name.Local) + `="` + escapeAttr(attr.Value) + `"`)
+	}
+	b.WriteString(">")
+
+	for _, child := range el.children {
+		switch c := child.(type) {
+		case string:
+			b.WriteString(escapeText(c))
+		case *element:
+			if c != exclude {
+				writeCanonical(b, c, exclude, inclusive, current)
+			}
+		}
+	}
+
+	b.WriteString("</" + name + ">")
+}
+
+func qualifiedName(prefix, local string) string {
+	if prefix == "" {
+		return local
+	}
+	return prefix + ":" + local
+}
+
+var (
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "\r", "&#xD;")
+	attrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", `"`, "&quot;", "\t", "&#x9;", "\n", "&#xA;", "\r", "&#xD;")
+)
+
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+func escapeAttr(s string) string {
+	return attrEscaper.Replace(s)
+}
@@ -0,0 +1,205 @@
+package saml
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// 支持的签名相关算法
+const (
+	algExcC14N   = "http://www.w3.org/2001/10/xml-exc-c14n#"
+	algEnveloped = "http://www.w3.org/2000/09/xmldsig#enveloped-signature"
+	algRSASHA256 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256"
+	algRSASHA512 = "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512"
+	algDigSHA256 = "http://www.w3.org/2001/04/xmlenc#sha256"
+	algDigSHA512 = "http://www.w3.org/2001/04/xmlenc#sha512"
+)
+
+var (
+	// ErrSignatureMissing 元素未签名
+	ErrSignatureMissing = errors.New("SAML signature is missing")
+	// ErrSignatureInvalid 签名无效或使用了不支持的算法
+	ErrSignatureInvalid = errors.New("SAML signature is invalid")
+)
+
+// hashByAlgorithm 返回算法标识对应的摘要算法
+func hashByAlgorithm(alg string) (crypto.Hash, bool) {
+	switch alg {
+	case algRSASHA256, algDigSHA256:
+		return crypto.SHA256, true
+	case algRSASHA512, algDigSHA512:
+		return crypto.SHA512, true
+	}
+	return 0, false
+}
+
+// inclusivePrefixes 读取规范化方法中的 InclusiveNamespaces 前缀列表
+func inclusivePrefixes(method *element) []string {
+	if inclusive := method.child(algExcC14N, "InclusiveNamespaces"); inclusive != nil {
+		return strings.Fields(inclusive.attr("PrefixList"))
+	}
+	return nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.StdEncoding.DecodeString(strings.Join(strings.Fields(s), ""))
+}
+
+// verifySignature 验证元素的封装签名。签名必须是元素的直接子元素，且只引用元素自身
+func verifySignature(el *element, cert *x509.Certificate) error {
+	signatures := el.childElements(nsDSig, "Signature")
+	if len(signatures) == 0 {
+		return ErrSignatureMissing
+	}
+	if len(signatures) != 1 {
+		return ErrSignatureInvalid
+	}
+	sig := signatures[0]
+
+	signedInfo := sig.child(nsDSig, "SignedInfo")
+	if signedInfo == nil {
+		return ErrSignatureInvalid
+	}
+
+	c14nMethod := signedInfo.child(nsDSig, "CanonicalizationMethod")
+	sigMethod := signedInfo.child(nsDSig, "SignatureMethod")
+	references := signedInfo.childElements(nsDSig, "Reference")
+	if c14nMethod == nil || c14nMethod.attr("Algorithm") != algExcC14N || sigMethod == nil || len(references) != 1 {
+		return ErrSignatureInvalid
+	}
+
+	// 引用必须指向被签名的元素
+	ref := references[0]
+	if id := el.attr("ID"); id == "" || ref.attr("URI") != "#"+id {
+		return ErrSignatureInvalid
+	}
+
+	var refInclusive []string
+	if transforms := ref.child(nsDSig, "Transforms"); transforms != nil {
+		for _, transform := range transforms.childElements(nsDSig, "Transform") {
+			switch transform.attr("Algorithm") {
+			case algEnveloped:
+			case algExcC14N:
+				refInclusive = inclusivePrefixes(transform)
+			default:
+				return ErrSignatureInvalid
+			}
+		}
+	}
+
+	// 校验摘要
+	digestMethod := ref.child(nsDSig, "DigestMethod")
+	digestValue := ref.child(nsDSig, "DigestValue")
+	if digestMethod == nil || digestValue == nil {
+		return ErrSignatureInvalid
+	}
+	digestAlg := digestMethod.attr("Algorithm")
+	digestHash, ok := hashByAlgorithm(digestAlg)
+	if !ok || (digestAlg != algDigSHA256 && digestAlg != algDigSHA512) {
+		return ErrSignatureInvalid
+	}
+	expected, err := decodeBase64(digestValue.text())
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	h := digestHash.New()
+	h.Write(canonicalize(el, sig, refInclusive))
+	if !equalBytes(h.Sum(nil), expected) {
+		return ErrSignatureInvalid
+	}
+
+	// 校验 SignedInfo 的签名
+	sigAlg := sigMethod.attr("Algorithm")
+	sigHash, ok := hashByAlgorithm(sigAlg)
+	if !ok || (sigAlg != algRSASHA256 && sigAlg != algRSASHA512) {
+		return ErrSignatureInvalid
+	}
+	sigValue := sig.child(nsDSig, "SignatureValue")
+	if sigValue == nil {
+		return ErrSignatureInvalid
+	}
+	signature, err := decodeBase64(sigValue.text())
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	h = sigHash.New()
+	h.Write(canonicalize(signedInfo, nil, inclusivePrefixes(c14nMethod)))
+	if err := rsa.VerifyPKCS1v15(pub, sigHash, h.Sum(nil), signature); err != nil {
+		return ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+// verifyRedirectSignature 验证 HTTP-Redirect 绑定中的查询签名，签名内容由原始编码的查询参数按固定顺序拼接而成
+func verifyRedirectSignature(rawQuery, param string, cert *x509.Certificate) error {
+	raw := make(map[string]string)
+	for _, pair := range strings.Split(rawQuery, "&") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		if _, ok := raw[kv[0]]; ok {
+			return ErrSignatureInvalid
+		}
+		raw[kv[0]] = kv[1]
+	}
+
+	if raw["Signature"] == "" || raw["SigAlg"] == "" || raw[param] == "" {
+		return ErrSignatureMissing
+	}
+	sigAlg, err := url.QueryUnescape(raw["SigAlg"])
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	hash, ok := hashByAlgorithm(sigAlg)
+	if !ok || (sigAlg != algRSASHA256 && sigAlg != algRSASHA512) {
+		return ErrSignatureInvalid
+	}
+	encoded, err := url.QueryUnescape(raw["Signature"])
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+	signature, err := decodeBase64(encoded)
+	if err != nil {
+		return ErrSignatureInvalid
+	}
+
+	signed := param + "=" + raw[param]
+	if relayState, ok := raw["RelayState"]; ok {
+		signed += "&RelayState=" + relayState
+	}
+	signed += "&SigAlg=" + raw["SigAlg"]
+
+	pub, ok := cert.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return ErrSignatureInvalid
+	}
+	h := hash.New()
+	h.Write([]byte(signed))
+	if err := rsa.VerifyPKCS1v15(pub, hash, h.Sum(nil), signature); err != nil {
+		return ErrSignatureInvalid
+	}
+	return nil
+}
+
+func equalBytes(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	var diff byte
+	for i := range a {
+		diff |= a[i] ^ b[i]
+	}
+	return diff == 0
+}
@@ -0,0 +1,534 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+const (
+	// MetadataPath 服务提供方元数据地址
+	MetadataPath = "/api/v3/user/saml/metadata"
+	// ACSPath 断言消费服务地址
+	ACSPath = "/api/v3/user/saml/acs"
+	// SLOPath 单点登出服务地址
+	SLOPath = "/api/v3/user/saml/slo"
+
+	bindingRedirect = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	bindingPOST     = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	statusSuccess   = "urn:oasis:names:tc:SAML:2.0:status:Success"
+	methodBearer    = "urn:oasis:names:tc:SAML:2.0:cm:bearer"
+
+	// 允许的时钟偏差
+	clockSkew = 3 * time.Minute
+	// 发起的请求的有效期，单位为秒
+	requestTTL = 600
+	// 收到的消息大小上限
+	maxMessageSize = 1 << 20
+)
+
+var (
+	// ErrInvalidResponse 响应格式错误或未通过校验
+	ErrInvalidResponse = errors.New("invalid SAML response")
+	// ErrStatusNotSuccess 身份提供方返回了失败状态
+	ErrStatusNotSuccess = errors.New("SAML response status is not success")
+	// ErrUnknownRequest 响应对应的请求不存在或已过期
+	ErrUnknownRequest = errors.New("SAML response does not match any pending request")
+	// ErrAssertionExpired 断言不在有效期内
+	ErrAssertionExpired = errors.New("SAML assertion is expired or not yet valid")
+	// ErrAudienceMismatch 断言的受众不包含本服务提供方
+	ErrAudienceMismatch = errors.New("SAML assertion audience mismatch")
+	// ErrAssertionReplayed 断言已被使用过
+	ErrAssertionReplayed = errors.New("SAML assertion has already been used")
+	// ErrEncryptedAssertion 不支持加密的断言
+	ErrEncryptedAssertion = errors.New("encrypted SAML assertions are not supported")
+	// ErrCertificateInvalid 身份提供方证书无效
+	ErrCertificateInvalid = errors.New("invalid identity provider certificate")
+)
+
+// Config SAML 服务提供方设置
+type Config struct {
+	// 身份提供方
+	IdPEntityID string
+	SSOURL      string
+	// 单点登出地址，为空时不启用单点登出
+	SLOURL      string
+	Certificate *x509.Certificate
+
+	// 服务提供方
+	EntityID    string
+	MetadataURL string
+	ACSURL      string
+	SLOCallback string
+
+	// 断言中表示邮箱、昵称及用户组的属性名称
+	EmailAttr    string
+	NickAttr     string
+	GroupAttr    string
+	GroupMap     map[string]uint
+	DefaultGroup uint
+	// 是否为未关联的用户自动注册账号
+	Register bool
+}
+
+// NewConfig 从站点设置读取 SAML 设置
+func NewConfig() (*Config, error) {
+	options := model.GetSettingByNames(
+		"saml_idp_entity_id",
+		"saml_idp_sso_url",
+		"saml_idp_slo_url",
+		"saml_idp_certificate",
+		"saml_sp_entity_id",
+		"saml_email_attr",
+		"saml_nick_attr",
+		"saml_group_attr",
+		"saml_group_map",
+		"saml_register",
+	)
+
+	siteURL := model.GetSiteURL()
+	resolve := func(p string) string {
+		u, _ := url.Parse(p)
+		return siteURL.ResolveReference(u).String()
+	}
+
+	conf := &Config{
+		IdPEntityID:  options["saml_idp_entity_id"],
+		SSOURL:       options["saml_idp_sso_url"],
+		SLOURL:       options["saml_idp_slo_url"],
+		EntityID:     options["saml_sp_entity_id"],
+		MetadataURL:  resolve(MetadataPath),
+		ACSURL:       resolve(ACSPath),
+		SLOCallback:  resolve(SLOPath),
+		EmailAttr:    options["saml_email_attr"],
+		NickAttr:     options["saml_nick_attr"],
+		GroupAttr:    options["saml_group_attr"],
+		GroupMap:     make(map[string]uint),
+		DefaultGroup: uint(model.GetIntSetting("default_group", 2)),
+		Register:     model.IsTrueVal(options["saml_register"]),
+	}
+	if conf.EntityID == "" {
+		conf.EntityID = conf.MetadataURL
+	}
+
+	if options["saml_group_map"] != "" {
+		if err := json.Unmarshal([]byte(options["saml_group_map"]), &conf.GroupMap); err != nil {
+			util.Log().Warning("无法解析 SAML 用户组映射, %s", err)
+		}
+	}
+
+	cert, err := ParseCertificate(options["saml_idp_certificate"])
+	if err != nil {
+		return nil, err
+	}
+	conf.Certificate = cert
+
+	return conf, nil
+}
+
+// ParseCertificate 解析 PEM 格式或仅包含 Base64 内容的 X.509 证书
+func ParseCertificate(raw string) (*x509.Certificate, error) {
+	raw = strings.TrimSpace(raw)
+	var der []byte
+	if block, _ := pem.Decode([]byte(raw)); block != nil {
+		der = block.Bytes
+	} else {
+		decoded, err := decodeBase64(raw)
+		if err != nil {
+			return nil, ErrCertificateInvalid
+		}
+		der = decoded
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, ErrCertificateInvalid
+	}
+	return cert, nil
+}
+
+// SLOEnabled 返回是否启用了单点登出
+func (conf *Config) SLOEnabled() bool {
+	return conf.SLOURL != ""
+}
+
+type metadataEndpoint struct {
+	Binding   string `xml:"Binding,attr"`
+	Location  string `xml:"Location,attr"`
+	Index     *int   `xml:"index,attr,omitempty"`
+	IsDefault *bool  `xml:"isDefault,attr,omitempty"`
+}
+
+type spMetadata struct {
+	XMLName  xml.Name `xml:"urn:oasis:names:tc:SAML:2.0:metadata EntityDescriptor"`
+	EntityID string   `xml:"entityID,attr"`
+	SP       struct {
+		AuthnRequestsSigned        bool               `xml:"AuthnRequestsSigned,attr"`
+		WantAssertionsSigned       bool               `xml:"WantAssertionsSigned,attr"`
+		ProtocolSupportEnumeration string             `xml:"protocolSupportEnumeration,attr"`
+		SingleLogoutService        []metadataEndpoint `xml:"SingleLogoutService"`
+		NameIDFormat               string             `xml:"NameIDFormat"`
+		AssertionConsumerService   []metadataEndpoint `xml:"AssertionConsumerService"`
+	} `xml:"SPSSODescriptor"`
+}
+
+// Metadata 返回服务提供方元数据
+func (conf *Config) Metadata() ([]byte, error) {
+	var metadata spMetadata
+	metadata.EntityID = conf.EntityID
+	metadata.SP.WantAssertionsSigned = true
+	metadata.SP.ProtocolSupportEnumeration = nsProtocol
+	metadata.SP.NameIDFormat = "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent"
+
+	index, isDefault := 0, true
+	metadata.SP.AssertionConsumerService = []metadataEndpoint{
+		{Binding: bindingPOST, Location: conf.ACSURL, Index: &index, IsDefault: &isDefault},
+	}
+	if conf.SLOEnabled() {
+		metadata.SP.SingleLogoutService = []metadataEndpoint{
+			{Binding: bindingRedirect, Location: conf.SLOCallback},
+		}
+	}
+
+	res, err := xml.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), res...), nil
+}
+
+// newID 生成消息ID，ID 须以字母或下划线开头
+func newID() string {
+	return "_" + util.RandSecureString(32)
+}
+
+func issueInstant(now time.Time) string {
+	return now.UTC().Format("2006-01-02T15:04:05Z")
+}
+
+// redirectURL 将消息以 HTTP-Redirect 绑定编码到目标地址
+func redirectURL(target, param string, message []byte, relayState string) (string, error) {
+	base, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	writer, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	writer.Write(message)
+	writer.Close()
+
+	queries := base.Query()
+	queries.Set(param, base64.StdEncoding.EncodeToString(buf.Bytes()))
+	if relayState != "" {
+		queries.Set("RelayState", relayState)
+	}
+	base.RawQuery = queries.Encode()
+	return base.String(), nil
+}
+
+// AuthnRequestURL 返回身份提供方的认证地址，发起的请求ID记录在缓存中，
+// 以便在断言消费服务中校验 InResponseTo
+func (conf *Config) AuthnRequestURL() (string, error) {
+	id := newID()
+	request := fmt.Sprintf(
+		`<samlp:AuthnRequest xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s" AssertionConsumerServiceURL="%s" ProtocolBinding="%s">`+
+			`<saml:Issuer>%s</saml:Issuer><samlp:NameIDPolicy AllowCreate="true"/></samlp:AuthnRequest>`,
+		nsProtocol, nsAssertion, id, issueInstant(time.Now()), escapeAttr(conf.SSOURL),
+		escapeAttr(conf.ACSURL), bindingPOST, escapeText(conf.EntityID),
+	)
+
+	target, err := redirectURL(conf.SSOURL, "SAMLRequest", []byte(request), "")
+	if err != nil {
+		return "", err
+	}
+
+	if err := cache.Set("saml_request_"+id, true, requestTTL); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// Assertion 通过校验的断言中的用户信息
+type Assertion struct {
+	ID           string
+	NameID       string
+	NameIDFormat string
+	SessionIndex string
+	Attributes   map[string][]string
+}
+
+// Attr 返回属性的第一个值
+func (assertion *Assertion) Attr(name string) string {
+	if values := assertion.Attributes[name]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// ParseResponse 解析并校验断言消费服务收到的响应，返回其中的断言
+func (conf *Config) ParseResponse(encoded string) (*Assertion, error) {
+	raw, err := decodeBase64(encoded)
+	if err != nil || len(raw) > maxMessageSize {
+		return nil, ErrInvalidResponse
+	}
+	root, err := parseXML(raw)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+
+	if !root.is(nsProtocol, "Response") || root.hasDuplicateIDs() {
+		return nil, ErrInvalidResponse
+	}
+	if dest := root.attr("Destination"); dest != "" && dest != conf.ACSURL {
+		return nil, ErrInvalidResponse
+	}
+	if issuer := root.child(nsAssertion, "Issuer"); issuer != nil && issuer.text() != conf.IdPEntityID {
+		return nil, ErrInvalidResponse
+	}
+	if err := checkStatus(root); err != nil {
+		return nil, err
+	}
+
+	// 只接受由本服务提供方发起的请求，每个请求只能使用一次
+	requestID := root.attr("InResponseTo")
+	if _, ok := cache.Get("saml_request_" + requestID); requestID == "" || !ok {
+		return nil, ErrUnknownRequest
+	}
+	cache.Deletes([]string{requestID}, "saml_request_")
+
+	if len(root.childElements(nsAssertion, "EncryptedAssertion")) > 0 {
+		return nil, ErrEncryptedAssertion
+	}
+	assertions := root.childElements(nsAssertion, "Assertion")
+	if len(assertions) != 1 {
+		return nil, ErrInvalidResponse
+	}
+	assertion := assertions[0]
+
+	// 响应或断言至少有一个带有有效签名
+	responseErr := verifySignature(root, conf.Certificate)
+	if responseErr != nil && responseErr != ErrSignatureMissing {
+		return nil, responseErr
+	}
+	assertionErr := verifySignature(assertion, conf.Certificate)
+	if assertionErr != nil && (assertionErr != ErrSignatureMissing || responseErr != nil) {
+		return nil, assertionErr
+	}
+
+	return conf.validateAssertion(assertion, requestID, time.Now())
+}
+
+func checkStatus(root *element) error {
+	status := root.child(nsProtocol, "Status")
+	if status == nil {
+		return ErrInvalidResponse
+	}
+	code := status.child(nsProtocol, "StatusCode")
+	if code == nil || code.attr("Value") != statusSuccess {
+		return ErrStatusNotSuccess
+	}
+	return nil
+}
+
+func parseTime(s string) (time.Time, bool) {
+	t, err := time.Parse(time.RFC3339Nano, s)
+	return t, err == nil
+}
+
+// validateAssertion 校验断言的签发者、有效期、受众及主体确认信息，并读取用户信息
+func (conf *Config) validateAssertion(el *element, requestID string, now time.Time) (*Assertion, error) {
+	if issuer := el.child(nsAssertion, "Issuer"); issuer == nil || issuer.text() != conf.IdPEntityID {
+		return nil, ErrInvalidResponse
+	}
+
+	expires := now.Add(time.Duration(requestTTL) * time.Second)
+	if conditions := el.child(nsAssertion, "Conditions"); conditions != nil {
+		if v := conditions.attr("NotBefore"); v != "" {
+			if t, ok := parseTime(v); !ok || now.Add(clockSkew).Before(t) {
+				return nil, ErrAssertionExpired
+			}
+		}
+		if v := conditions.attr("NotOnOrAfter"); v != "" {
+			t, ok := parseTime(v)
+			if !ok || !now.Add(-clockSkew).Before(t) {
+				return nil, ErrAssertionExpired
+			}
+			expires = t.Add(clockSkew)
+		}
+		for _, restriction := range conditions.childElements(nsAssertion, "AudienceRestriction") {
+			matched := false
+			for _, audience := range restriction.childElements(nsAssertion, "Audience") {
+				matched = matched || audience.text() == conf.EntityID
+			}
+			if !matched {
+				return nil, ErrAudienceMismatch
+			}
+		}
+	}
+
+	subject := el.child(nsAssertion, "Subject")
+	if subject == nil {
+		return nil, ErrInvalidResponse
+	}
+	nameID := subject.child(nsAssertion, "NameID")
+	if nameID == nil || nameID.text() == "" {
+		return nil, ErrInvalidResponse
+	}
+
+	// 至少有一个有效的 bearer 主体确认
+	confirmed := false
+	for _, confirmation := range subject.childElements(nsAssertion, "SubjectConfirmation") {
+		data := confirmation.child(nsAssertion, "SubjectConfirmationData")
+		if confirmation.attr("Method") != methodBearer || data == nil {
+			continue
+		}
+		notOnOrAfter, ok := parseTime(data.attr("NotOnOrAfter"))
+		if !ok || !now.Add(-clockSkew).Before(notOnOrAfter) {
+			continue
+		}
+		if data.attr("Recipient") != conf.ACSURL || data.attr("InResponseTo") != requestID {
+			continue
+		}
+		confirmed = true
+	}
+	if !confirmed {
+		return nil, ErrInvalidResponse
+	}
+
+	assertion := &Assertion{
+		ID:           el.attr("ID"),
+		NameID:       nameID.text(),
+		NameIDFormat: nameID.attr("Format"),
+		Attributes:   make(map[string][]string),
+	}
+	if statement := el.child(nsAssertion, "AuthnStatement"); statement != nil {
+		assertion.SessionIndex = statement.attr("SessionIndex")
+	}
+	for _, statement := range el.childElements(nsAssertion, "AttributeStatement") {
+		for _, attribute := range statement.childElements(nsAssertion, "Attribute") {
+			var values []string
+			for _, value := range attribute.childElements(nsAssertion, "AttributeValue") {
+				values = append(values, value.text())
+			}
+			for _, name := range []string{attribute.attr("Name"), attribute.attr("FriendlyName")} {
+				if name != "" {
+					assertion.Attributes[name] = append(assertion.Attributes[name], values...)
+				}
+			}
+		}
+	}
+
+	// 防止断言被重放
+	if _, ok := cache.Get("saml_assertion_" + assertion.ID); ok {
+		return nil, ErrAssertionReplayed
+	}
+	ttl := int(expires.Sub(now).Seconds()) + 1
+	if err := cache.Set("saml_assertion_"+assertion.ID, true, ttl); err != nil {
+		return nil, err
+	}
+
+	return assertion, nil
+}
+
+// MapGroup 根据断言中的用户组属性返回对应的用户组ID，无匹配时返回默认用户组
+func (conf *Config) MapGroup(assertion *Assertion) uint {
+	for _, group := range assertion.Attributes[conf.GroupAttr] {
+		if id, ok := conf.GroupMap[group]; ok {
+			return id
+		}
+	}
+	return conf.DefaultGroup
+}
+
+// LogoutRequestURL 返回向身份提供方发起单点登出的地址
+func (conf *Config) LogoutRequestURL(nameID, format, sessionIndex string) (string, error) {
+	formatAttr := ""
+	if format != "" {
+		formatAttr = fmt.Sprintf(` Format="%s"`, escapeAttr(format))
+	}
+	sessionElement := ""
+	if sessionIndex != "" {
+		sessionElement = "<samlp:SessionIndex>" + escapeText(sessionIndex) + "</samlp:SessionIndex>"
+	}
+
+	request := fmt.Sprintf(
+		`<samlp:LogoutRequest xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s">`+
+			`<saml:Issuer>%s</saml:Issuer><saml:NameID%s>%s</saml:NameID>%s</samlp:LogoutRequest>`,
+		nsProtocol, nsAssertion, newID(), issueInstant(time.Now()), escapeAttr(conf.SLOURL),
+		escapeText(conf.EntityID), formatAttr, escapeText(nameID), sessionElement,
+	)
+	return redirectURL(conf.SLOURL, "SAMLRequest", []byte(request), "")
+}
+
+// LogoutResponseURL 返回对身份提供方发起的登出请求的成功响应地址
+func (conf *Config) LogoutResponseURL(inResponseTo, relayState string) (string, error) {
+	response := fmt.Sprintf(
+		`<samlp:LogoutResponse xmlns:samlp="%s" xmlns:saml="%s" ID="%s" Version="2.0" IssueInstant="%s" Destination="%s" InResponseTo="%s">`+
+			`<saml:Issuer>%s</saml:Issuer><samlp:Status><samlp:StatusCode Value="%s"/></samlp:Status></samlp:LogoutResponse>`,
+		nsProtocol, nsAssertion, newID(), issueInstant(time.Now()), escapeAttr(conf.SLOURL),
+		escapeAttr(inResponseTo), escapeText(conf.EntityID), statusSuccess,
+	)
+	return redirectURL(conf.SLOURL, "SAMLResponse", []byte(response), relayState)
+}
+
+// ParseLogoutRequest 校验通过 HTTP-Redirect 绑定收到的登出请求，返回请求ID及用户标识
+func (conf *Config) ParseLogoutRequest(rawQuery string) (string, string, error) {
+	root, err := conf.parseRedirect(rawQuery, "SAMLRequest")
+	if err != nil {
+		return "", "", err
+	}
+	if !root.is(nsProtocol, "LogoutRequest") || root.attr("ID") == "" {
+		return "", "", ErrInvalidResponse
+	}
+	if issuer := root.child(nsAssertion, "Issuer"); issuer == nil || issuer.text() != conf.IdPEntityID {
+		return "", "", ErrInvalidResponse
+	}
+
+	nameID := root.child(nsAssertion, "NameID")
+	if nameID == nil {
+		return "", "", ErrInvalidResponse
+	}
+	return root.attr("ID"), nameID.text(), nil
+}
+
+// parseRedirect 校验 HTTP-Redirect 绑定的查询签名，并解码其中的消息
+func (conf *Config) parseRedirect(rawQuery, param string) (*element, error) {
+	if err := verifyRedirectSignature(rawQuery, param, conf.Certificate); err != nil {
+		return nil, err
+	}
+
+	queries, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	compressed, err := decodeBase64(queries.Get(param))
+	if err != nil {
+		return nil, ErrInvalidResponse
+	}
+	raw, err := ioutil.ReadAll(io.LimitReader(flate.NewReader(bytes.NewReader(compressed)), maxMessageSize+1))
+	if err != nil || len(raw) > maxMessageSize {
+		return nil, ErrInvalidResponse
+	}
+
+	root, err := parseXML(raw)
+	if err != nil || root.hasDuplicateIDs() {
+		return nil, ErrInvalidResponse
+	}
+	return root, nil
+}
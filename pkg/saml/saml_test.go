@@ -0,0 +1,285 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+type testIdP struct {
+	key  *rsa.PrivateKey
+	cert *x509.Certificate
+	conf *Config
+}
+
+func newTestIdP(t *testing.T) *testIdP {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := ParseCertificate(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testIdP{
+		key:  key,
+		cert: cert,
+		conf: &Config{
+			IdPEntityID:  "https://idp.example.com",
+			SSOURL:       "https://idp.example.com/sso",
+			SLOURL:       "https://idp.example.com/slo",
+			Certificate:  cert,
+			EntityID:     "https://cloudreve.org/api/v3/user/saml/metadata",
+			MetadataURL:  "https://cloudreve.org/api/v3/user/saml/metadata",
+			ACSURL:       "https://cloudreve.org/api/v3/user/saml/acs",
+			SLOCallback:  "https://cloudreve.org/api/v3/user/saml/slo",
+			EmailAttr:    "email",
+			GroupAttr:    "groups",
+			GroupMap:     map[string]uint{"admins": 1},
+			DefaultGroup: 2,
+		},
+	}
+}
+
+// sign 对给定ID的元素生成封装签名
+func (idp *testIdP) sign(t *testing.T, doc, id string) string {
+	root, err := parseXML([]byte(strings.Replace(doc, "{SIG}", "", 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var target *element
+	root.walk(func(e *element) {
+		if e.attr("ID") == id {
+			target = e
+		}
+	})
+	digest := sha256.Sum256(canonicalize(target, nil, nil))
+
+	signedInfo := fmt.Sprintf(
+		`<ds:SignedInfo xmlns:ds="%s"><ds:CanonicalizationMethod Algorithm="%s"></ds:CanonicalizationMethod>`+
+			`<ds:SignatureMethod Algorithm="%s"></ds:SignatureMethod><ds:Reference URI="#%s"><ds:Transforms>`+
+			`<ds:Transform Algorithm="%s"></ds:Transform><ds:Transform Algorithm="%s"></ds:Transform></ds:Transforms>`+
+			`<ds:DigestMethod Algorithm="%s"></ds:DigestMethod><ds:DigestValue>%s</ds:DigestValue></ds:Reference></ds:SignedInfo>`,
+		nsDSig, algExcC14N, algRSASHA256, id, algEnveloped, algExcC14N, algDigSHA256,
+		base64.StdEncoding.EncodeToString(digest[:]),
+	)
+	signedInfoEl, err := parseXML([]byte(signedInfo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hashed := sha256.Sum256(canonicalize(signedInfoEl, nil, nil))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature := fmt.Sprintf(`<ds:Signature xmlns:ds="%s">%s<ds:SignatureValue>%s</ds:SignatureValue></ds:Signature>`,
+		nsDSig, signedInfo, base64.StdEncoding.EncodeToString(sig))
+	return strings.Replace(doc, "{SIG}", signature, 1)
+}
+
+func (idp *testIdP) response(requestID, assertionID, audience string, notOnOrAfter time.Time) string {
+	return fmt.Sprintf(`<samlp:Response xmlns:samlp="%s" xmlns:saml="%s" ID="_resp" Version="2.0" Destination="%s" InResponseTo="%s">`+
+		`<saml:Issuer>%s</saml:Issuer><samlp:Status><samlp:StatusCode Value="%s"/></samlp:Status>`+
+		`<saml:Assertion ID="%s" Version="2.0"><saml:Issuer>%s</saml:Issuer>{SIG}`+
+		`<saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:2.0:nameid-format:persistent">user-1</saml:NameID>`+
+		`<saml:SubjectConfirmation Method="%s"><saml:SubjectConfirmationData Recipient="%s" InResponseTo="%s" NotOnOrAfter="%s"/></saml:SubjectConfirmation></saml:Subject>`+
+		`<saml:Conditions NotOnOrAfter="%s"><saml:AudienceRestriction><saml:Audience>%s</saml:Audience></saml:AudienceRestriction></saml:Conditions>`+
+		`<saml:AuthnStatement SessionIndex="session-1"/>`+
+		`<saml:AttributeStatement><saml:Attribute Name="email"><saml:AttributeValue>User@Example.com</saml:AttributeValue></saml:Attribute>`+
+		`<saml:Attribute Name="groups"><saml:AttributeValue>users</saml:AttributeValue><saml:AttributeValue>admins</saml:AttributeValue></saml:Attribute></saml:AttributeStatement>`+
+		`</saml:Assertion></samlp:Response>`,
+		nsProtocol, nsAssertion, idp.conf.ACSURL, requestID, idp.conf.IdPEntityID, statusSuccess,
+		assertionID, idp.conf.IdPEntityID, methodBearer, idp.conf.ACSURL, requestID,
+		notOnOrAfter.UTC().Format(time.RFC3339), notOnOrAfter.UTC().Format(time.RFC3339), audience,
+	)
+}
+
+func encode(doc string) string {
+	return base64.StdEncoding.EncodeToString([]byte(doc))
+}
+
+func TestCanonicalize(t *testing.T) {
+	asserts := assert.New(t)
+	doc := `<a:root xmlns:a="urn:a" xmlns:b="urn:b" xmlns:unused="urn:unused" z="1" b:y="2" a="3"><a:child>x &amp; y</a:child><b:child/></a:root>`
+	root, err := parseXML([]byte(doc))
+	asserts.NoError(err)
+
+	asserts.Equal(
+		`<a:root xmlns:a="urn:a" xmlns:b="urn:b" a="3" z="1" b:y="2"><a:child>x &amp; y</a:child><b:child></b:child></a:root>`,
+		string(canonicalize(root, nil, nil)),
+	)
+
+	// 子元素单独规范化时带上所需的命名空间声明
+	child := root.childElements("urn:b", "child")[0]
+	asserts.Equal(`<b:child xmlns:b="urn:b"></b:child>`, string(canonicalize(child, nil, nil)))
+
+	// 包含方式处理的前缀
+	asserts.Equal(`<b:child xmlns:b="urn:b" xmlns:unused="urn:unused"></b:child>`, string(canonicalize(child, nil, []string{"unused"})))
+
+	// 不允许 DTD
+	_, err = parseXML([]byte(`<!DOCTYPE a [<!ENTITY x "y">]><a>&x;</a>`))
+	asserts.Error(err)
+}
+
+func TestConfig_ParseResponse(t *testing.T) {
+	asserts := assert.New(t)
+	idp := newTestIdP(t)
+	expires := time.Now().Add(5 * time.Minute)
+
+	// 断言签名有效
+	{
+		cache.Set("saml_request__req1", true, 0)
+		doc := idp.sign(t, idp.response("_req1", "_a1", idp.conf.EntityID, expires), "_a1")
+		res, err := idp.conf.ParseResponse(encode(doc))
+		asserts.NoError(err)
+		asserts.Equal("user-1", res.NameID)
+		asserts.Equal("session-1", res.SessionIndex)
+		asserts.Equal("User@Example.com", res.Attr("email"))
+		asserts.EqualValues(1, idp.conf.MapGroup(res))
+
+		// 请求只能使用一次
+		_, err = idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrUnknownRequest, err)
+
+		// 断言不能重放
+		cache.Set("saml_request__req1", true, 0)
+		_, err = idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrAssertionReplayed, err)
+	}
+
+	// 签名后内容被篡改
+	{
+		cache.Set("saml_request__req2", true, 0)
+		doc := idp.sign(t, idp.response("_req2", "_a2", idp.conf.EntityID, expires), "_a2")
+		doc = strings.Replace(doc, "user-1", "admin", 1)
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrSignatureInvalid, err)
+	}
+
+	// 未签名
+	{
+		cache.Set("saml_request__req3", true, 0)
+		doc := strings.Replace(idp.response("_req3", "_a3", idp.conf.EntityID, expires), "{SIG}", "", 1)
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrSignatureMissing, err)
+	}
+
+	// 受众不符
+	{
+		cache.Set("saml_request__req4", true, 0)
+		doc := idp.sign(t, idp.response("_req4", "_a4", "https://other.example.com", expires), "_a4")
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrAudienceMismatch, err)
+	}
+
+	// 已过期
+	{
+		cache.Set("saml_request__req5", true, 0)
+		doc := idp.sign(t, idp.response("_req5", "_a5", idp.conf.EntityID, time.Now().Add(-time.Hour)), "_a5")
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrAssertionExpired, err)
+	}
+
+	// 签名包装：插入与已签名断言ID相同的元素
+	{
+		cache.Set("saml_request__req6", true, 0)
+		doc := idp.sign(t, idp.response("_req6", "_a6", idp.conf.EntityID, expires), "_a6")
+		doc = strings.Replace(doc, "<samlp:Status>", `<samlp:Extensions><saml:Assertion ID="_a6"/></samlp:Extensions><samlp:Status>`, 1)
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrInvalidResponse, err)
+	}
+
+	// 未发起的请求
+	{
+		doc := idp.sign(t, idp.response("_unknown", "_a7", idp.conf.EntityID, expires), "_a7")
+		_, err := idp.conf.ParseResponse(encode(doc))
+		asserts.Equal(ErrUnknownRequest, err)
+	}
+}
+
+func TestConfig_ParseLogoutRequest(t *testing.T) {
+	asserts := assert.New(t)
+	idp := newTestIdP(t)
+
+	request := fmt.Sprintf(`<samlp:LogoutRequest xmlns:samlp="%s" xmlns:saml="%s" ID="_logout" Version="2.0">`+
+		`<saml:Issuer>%s</saml:Issuer><saml:NameID>user-1</saml:NameID></samlp:LogoutRequest>`,
+		nsProtocol, nsAssertion, idp.conf.IdPEntityID)
+	var buf bytes.Buffer
+	writer, _ := flate.NewWriter(&buf, flate.DefaultCompression)
+	writer.Write([]byte(request))
+	writer.Close()
+
+	signed := "SAMLRequest=" + url.QueryEscape(base64.StdEncoding.EncodeToString(buf.Bytes())) +
+		"&RelayState=state&SigAlg=" + url.QueryEscape(algRSASHA256)
+	hashed := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, idp.key, crypto.SHA256, hashed[:])
+	asserts.NoError(err)
+	query := signed + "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(sig))
+
+	// 签名有效
+	{
+		id, nameID, err := idp.conf.ParseLogoutRequest(query)
+		asserts.NoError(err)
+		asserts.Equal("_logout", id)
+		asserts.Equal("user-1", nameID)
+	}
+
+	// RelayState 被篡改
+	{
+		_, _, err := idp.conf.ParseLogoutRequest(strings.Replace(query, "RelayState=state", "RelayState=other", 1))
+		asserts.Equal(ErrSignatureInvalid, err)
+	}
+
+	// 未签名
+	{
+		_, _, err := idp.conf.ParseLogoutRequest(signed)
+		asserts.Equal(ErrSignatureMissing, err)
+	}
+}
+
+func TestConfig_Metadata(t *testing.T) {
+	asserts := assert.New(t)
+	idp := newTestIdP(t)
+
+	metadata, err := idp.conf.Metadata()
+	asserts.NoError(err)
+	asserts.Contains(string(metadata), `entityID="https://cloudreve.org/api/v3/user/saml/metadata"`)
+	asserts.Contains(string(metadata), `Location="https://cloudreve.org/api/v3/user/saml/acs"`)
+	asserts.Contains(string(metadata), "SingleLogoutService")
+
+	idp.conf.SLOURL = ""
+	metadata, err = idp.conf.Metadata()
+	asserts.NoError(err)
+	asserts.NotContains(string(metadata), "SingleLogoutService")
+
+	target, err := idp.conf.AuthnRequestURL()
+	asserts.NoError(err)
+	asserts.True(strings.HasPrefix(target, "https://idp.example.com/sso?SAMLRequest="))
+}
@@ -0,0 +1,56 @@
+package saml
+
+import (
+	"errors"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+var (
+	// ErrEmailMissing 断言中没有邮箱属性，无法关联或注册用户
+	ErrEmailMissing = errors.New("email attribute is missing in SAML assertion")
+	// ErrRegisterDisabled 没有可关联的用户，且未开启自动注册
+	ErrRegisterDisabled = errors.New("no linked account and registration via SAML is disabled")
+)
+
+// ResolveUser 返回断言对应的用户。已关联的用户直接返回；邮箱与现有用户相同时，
+// 关联该用户；否则按设置自动注册，并根据用户组属性分配用户组
+func (conf *Config) ResolveUser(assertion *Assertion) (*model.User, error) {
+	if user, err := model.GetUserBySAML(assertion.NameID); err == nil {
+		return &user, nil
+	}
+
+	email := strings.ToLower(strings.TrimSpace(assertion.Attr(conf.EmailAttr)))
+	if email == "" {
+		return nil, ErrEmailMissing
+	}
+
+	if user, err := model.GetUserByEmail(email); err == nil {
+		if err := user.LinkSAML(assertion.NameID); err != nil {
+			return nil, err
+		}
+		return &user, nil
+	}
+
+	if !conf.Register {
+		return nil, ErrRegisterDisabled
+	}
+
+	user := model.NewUser()
+	user.Email = email
+	user.Nick = assertion.Attr(conf.NickAttr)
+	if user.Nick == "" {
+		user.Nick = strings.Split(email, "@")[0]
+	}
+	user.Status = model.Active
+	user.GroupID = conf.MapGroup(assertion)
+	user.SAML = assertion.NameID
+	if err := model.DB.Create(&user).Error; err != nil {
+		return nil, err
+	}
+
+	// 重新读取以加载用户组等关联
+	created, err := model.GetUserByID(user.ID)
+	return &created, err
+}
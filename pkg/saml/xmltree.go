@@ -0,0 +1,169 @@
+package saml
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+)
+
+// 命名空间
+const (
+	nsXML       = "http://www.w3.org/XML/1998/namespace"
+	nsDSig      = "http://www.w3.org/2000/09/xmldsig#"
+	nsAssertion = "urn:oasis:names:tc:SAML:2.0:assertion"
+	nsProtocol  = "urn:oasis:names:tc:SAML:2.0:protocol"
+)
+
+var errDirectiveNotAllowed = errors.New("XML directives are not allowed")
+
+// element 保留原始前缀及命名空间声明的 XML 元素，用于签名验证时的规范化
+type element struct {
+	prefix   string
+	local    string
+	attrs    []xml.Attr
+	children []interface{} // *element 或 string
+	parent   *element
+}
+
+// parseXML 解析 XML 文档，返回根元素。文档中不允许出现 DTD 等指令
+func parseXML(data []byte) (*element, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var root, current *element
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			el := &element{
+				prefix: t.Name.Space,
+				local:  t.Name.Local,
+				attrs:  append([]xml.Attr(nil), t.Attr...),
+				parent: current,
+			}
+			if current == nil {
+				if root != nil {
+					return nil, errors.New("multiple root elements")
+				}
+				root = el
+			} else {
+				current.children = append(current.children, el)
+			}
+			current = el
+		case xml.EndElement:
+			if current == nil || t.Name.Space != current.prefix || t.Name.Local != current.local {
+				return nil, errors.New("mismatched XML end element")
+			}
+			current = current.parent
+		case xml.CharData:
+			if current != nil {
+				current.children = append(current.children, string(t))
+			}
+		case xml.Directive:
+			return nil, errDirectiveNotAllowed
+		}
+	}
+
+	if root == nil || current != nil {
+		return nil, errors.New("incomplete XML document")
+	}
+	return root, nil
+}
+
+// lookupNamespace 返回在此元素处前缀绑定的命名空间，空前缀表示默认命名空间
+func (el *element) lookupNamespace(prefix string) string {
+	if prefix == "xml" {
+		return nsXML
+	}
+
+	for e := el; e != nil; e = e.parent {
+		for _, attr := range e.attrs {
+			if (prefix == "" && attr.Name.Space == "" && attr.Name.Local == "xmlns") ||
+				(prefix != "" && attr.Name.Space == "xmlns" && attr.Name.Local == prefix) {
+				return attr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// namespace 返回元素所在的命名空间
+func (el *element) namespace() string {
+	return el.lookupNamespace(el.prefix)
+}
+
+// is 返回元素是否为给定命名空间下的给定名称
+func (el *element) is(ns, local string) bool {
+	return el.local == local && el.namespace() == ns
+}
+
+// childElements 返回给定命名空间及名称的子元素
+func (el *element) childElements(ns, local string) []*element {
+	var res []*element
+	for _, child := range el.children {
+		if e, ok := child.(*element); ok && e.is(ns, local) {
+			res = append(res, e)
+		}
+	}
+	return res
+}
+
+// child 返回第一个给定命名空间及名称的子元素
+func (el *element) child(ns, local string) *element {
+	if children := el.childElements(ns, local); len(children) > 0 {
+		return children[0]
+	}
+	return nil
+}
+
+// attr 返回无前缀属性的值
+func (el *element) attr(name string) string {
+	for _, attr := range el.attrs {
+		if attr.Name.Space == "" && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// text 返回元素直接包含的文本
+func (el *element) text() string {
+	var b strings.Builder
+	for _, child := range el.children {
+		if s, ok := child.(string); ok {
+			b.WriteString(s)
+		}
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// walk 深度优先遍历元素及其所有子元素
+func (el *element) walk(fn func(*element)) {
+	fn(el)
+	for _, child := range el.children {
+		if e, ok := child.(*element); ok {
+			e.walk(fn)
+		}
+	}
+}
+
+// hasDuplicateIDs 返回文档中是否存在重复的 ID 属性，重复 ID 可被用于签名包装攻击
+func (el *element) hasDuplicateIDs() bool {
+	seen := make(map[string]bool)
+	duplicated := false
+	el.walk(func(e *element) {
+		if id := e.attr("ID"); id != "" {
+			duplicated = duplicated || seen[id]
+			seen[id] = true
+		}
+	})
+	return duplicated
+}
@@ -0,0 +1,36 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+type announcement struct {
+	ID          uint       `json:"id"`
+	Title       string     `json:"title"`
+	Content     string     `json:"content"`
+	Level       string     `json:"level"`
+	PublishDate time.Time  `json:"publish_date"`
+	ExpireDate  *time.Time `json:"expire_date"`
+}
+
+// BuildAnnouncementList 构建公告栏展示的公告列表响应
+func BuildAnnouncementList(announcements []model.Announcement) Response {
+	res := make([]announcement, 0, len(announcements))
+	for _, a := range announcements {
+		item := announcement{
+			ID:         a.ID,
+			Title:      a.Title,
+			Content:    a.Content,
+			Level:      a.Level,
+			ExpireDate: a.ExpiresAt,
+		}
+		if a.PublishedAt != nil {
+			item.PublishDate = *a.PublishedAt
+		}
+		res = append(res, item)
+	}
+
+	return Response{Data: res}
+}
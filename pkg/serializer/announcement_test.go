@@ -0,0 +1,23 @@
+package serializer
+
+import (
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildAnnouncementList(t *testing.T) {
+	asserts := assert.New(t)
+	now := time.Now()
+	announcements := []model.Announcement{{Title: "a", Level: model.AnnouncementWarning, PublishedAt: &now}}
+	announcements[0].ID = 1
+
+	res := BuildAnnouncementList(announcements)
+	list := res.Data.([]announcement)
+	asserts.Len(list, 1)
+	asserts.EqualValues(1, list[0].ID)
+	asserts.Equal(now, list[0].PublishDate)
+	asserts.Nil(list[0].ExpireDate)
+}
@@ -172,6 +172,58 @@ const (
 	CodeSlavePingMaster = 40060
 	// Cloudreve 版本不一致
 	CodeVersionMismatch = 40061
+	// 分享上传超出限制
+	CodeShareUploadLimitExceeded = 40062
+	// 分享密码尝试次数过多
+	CodeSharePasswordLocked = 40063
+	// 自定义分享链接已被使用
+	CodeShareSlugExisted = 40064
+	// 分享下载流量已用尽
+	CodeShareTrafficExhausted = 40065
+	// 联邦分享未启用或对方实例不受信任
+	CodeFederationNotAllowed = 40066
+	// 访客所在网络或国家/地区不允许访问分享
+	CodeShareAccessRestricted = 40067
+	// CodeTwoFactorRequired 用户组要求开启二步验证
+	CodeTwoFactorRequired = 40068
+	// CodeAPITokenScope 个人访问令牌的权限范围不允许此操作
+	CodeAPITokenScope = 40069
+	// CodeImpersonationRestricted 模拟用户期间无法进行此操作
+	CodeImpersonationRestricted = 40070
+	// CodeIPNotAllowed 用户组不允许从当前IP访问
+	CodeIPNotAllowed = 40071
+	// CodeLoginConfirmRequired 异常登录需通过邮件验证码确认
+	CodeLoginConfirmRequired = 40072
+	// CodePasswordPolicy 密码不符合密码策略
+	CodePasswordPolicy = 40073
+	// CodePasswordExpired 密码已过期，需修改密码
+	CodePasswordExpired = 40074
+	// CodeInvitationInvalid 邀请码无效或已过期
+	CodeInvitationInvalid = 40075
+	// CodeUploadTrafficExceeded 超出用户组每日上传流量限制
+	CodeUploadTrafficExceeded = 40076
+	// CodeSubAccountRestricted 子账户无法进行此操作
+	CodeSubAccountRestricted = 40077
+	// CodeEmailDomainNotAllowed 邮箱域名不在允许范围内
+	CodeEmailDomainNotAllowed = 40078
+	// CodePreconditionFailed 文件当前版本与请求的前置条件不符
+	CodePreconditionFailed = 40079
+	// CodeRateLimited 请求过于频繁
+	CodeRateLimited = 40080
+	// CodeFileQuarantined 文件因检出病毒已被隔离
+	CodeFileQuarantined = 40081
+	// CodeFileBlocked 文件内容命中禁止列表
+	CodeFileBlocked = 40082
+	// CodeMaintenance 站点维护中
+	CodeMaintenance = 40083
+	// CodeFailedSendSMS 短信发送失败
+	CodeFailedSendSMS = 40084
+	// CodePhoneExisted 手机号码已被使用
+	CodePhoneExisted = 40085
+	// CodePolicyCapacityExhausted 存储策略容量不足，暂停接收新上传
+	CodePolicyCapacityExhausted = 40086
+	// CodeLocked 资源已被 WebDAV 客户端锁定
+	CodeLocked = 40087
 	// CodeDBError 数据库操作失败
 	CodeDBError = 50001
 	// CodeEncryptError 加密失败
@@ -9,6 +9,7 @@ import (
 
 func init() {
 	gob.Register(ObjectProps{})
+	gob.Register([]Object{})
 }
 
 // ObjectProps 文件、目录对象的详细属性信息
@@ -29,6 +30,8 @@ type ObjectList struct {
 	Parent  string         `json:"parent,omitempty"`
 	Objects []Object       `json:"objects"`
 	Policy  *PolicySummary `json:"policy,omitempty"`
+	// NextCursor 分页列取时下一页的游标，已无更多内容时为空
+	NextCursor string `json:"next_cursor,omitempty"`
 }
 
 // Object 文件或者目录
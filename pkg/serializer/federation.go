@@ -0,0 +1,47 @@
+package serializer
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+)
+
+// FederationOffer 分享方实例向接收方实例提供联邦分享的请求正文
+type FederationOffer struct {
+	Provider string `json:"provider"`
+	Token    string `json:"token"`
+	Email    string `json:"email"`
+	Name     string `json:"name"`
+	Owner    string `json:"owner"`
+	IsDir    bool   `json:"is_dir"`
+}
+
+// remoteShareItem 远程分享列表条目
+type remoteShareItem struct {
+	ID         string    `json:"id"`
+	Provider   string    `json:"provider"`
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner"`
+	IsDir      bool      `json:"is_dir"`
+	Accepted   bool      `json:"accepted"`
+	CreateDate time.Time `json:"create_date"`
+}
+
+// BuildRemoteShareList 构建远程分享列表响应
+func BuildRemoteShareList(remotes []model.RemoteShare) Response {
+	res := make([]remoteShareItem, 0, len(remotes))
+	for _, remote := range remotes {
+		res = append(res, remoteShareItem{
+			ID:         hashid.HashID(remote.ID, hashid.RemoteShareID),
+			Provider:   remote.Provider,
+			Name:       remote.Name,
+			Owner:      remote.Owner,
+			IsDir:      remote.IsDir,
+			Accepted:   remote.Accepted,
+			CreateDate: remote.CreatedAt,
+		})
+	}
+
+	return Response{Data: res}
+}
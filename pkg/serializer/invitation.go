@@ -0,0 +1,53 @@
+package serializer
+
+import (
+	"net/url"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// Invitation 注册邀请码
+type Invitation struct {
+	ID        uint       `json:"id"`
+	Code      string     `json:"code"`
+	Link      string     `json:"link"`
+	CreatorID uint       `json:"creator_id"`
+	GroupID   uint       `json:"group_id"`
+	MaxUses   int        `json:"max_uses"`
+	Uses      int        `json:"uses"`
+	Available bool       `json:"available"`
+	ExpiredAt *time.Time `json:"expired_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// BuildInvitation 构建邀请码响应，附带可直接打开的注册链接
+func BuildInvitation(invitation *model.Invitation, base *url.URL) Invitation {
+	signup, _ := url.Parse("/signup")
+	link := base.ResolveReference(signup)
+	queries := link.Query()
+	queries.Set("invite", invitation.Code)
+	link.RawQuery = queries.Encode()
+
+	return Invitation{
+		ID:        invitation.ID,
+		Code:      invitation.Code,
+		Link:      link.String(),
+		CreatorID: invitation.CreatorID,
+		GroupID:   invitation.GroupID,
+		MaxUses:   invitation.MaxUses,
+		Uses:      invitation.Uses,
+		Available: invitation.Available(),
+		ExpiredAt: invitation.ExpiredAt,
+		CreatedAt: invitation.CreatedAt,
+	}
+}
+
+// BuildInvitations 构建邀请码列表响应
+func BuildInvitations(invitations []model.Invitation, base *url.URL) []Invitation {
+	res := make([]Invitation, 0, len(invitations))
+	for i := range invitations {
+		res = append(res, BuildInvitation(&invitations[i], base))
+	}
+	return res
+}
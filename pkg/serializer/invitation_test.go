@@ -0,0 +1,25 @@
+package serializer
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildInvitation(t *testing.T) {
+	asserts := assert.New(t)
+	base, _ := url.Parse("https://cloudreve.org/sub/")
+	past := time.Now().Add(-time.Hour)
+	invitation := &model.Invitation{Code: "abc", MaxUses: 1, ExpiredAt: &past}
+
+	res := BuildInvitation(invitation, base)
+	asserts.Equal("https://cloudreve.org/signup?invite=abc", res.Link)
+	asserts.False(res.Available)
+
+	list := BuildInvitations([]model.Invitation{{Code: "a"}, {Code: "b"}}, base)
+	asserts.Len(list, 2)
+	asserts.True(list[1].Available)
+}
@@ -2,6 +2,7 @@ package serializer
 
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"time"
 )
 
@@ -17,19 +18,27 @@ type SiteConfig struct {
 	HomepageViewMethod   string `json:"home_view_method"`
 	ShareViewMethod      string `json:"share_view_method"`
 	Authn                bool   `json:"authn"`
+	OIDC                 bool   `json:"oidc"`
+	SAML                 bool   `json:"saml"`
 	User                 User   `json:"user"`
 	ReCaptchaKey         string `json:"captcha_ReCaptchaKey"`
 	CaptchaType          string `json:"captcha_type"`
 	TCaptchaCaptchaAppId string `json:"tcaptcha_captcha_app_id"`
 	RegisterEnabled      bool   `json:"registerEnabled"`
+	RegisterInvite       bool   `json:"registerInvite"`
+	RegisterPhone        bool   `json:"registerPhone"`
+	Maintenance          bool   `json:"maintenance"`
+	MaintenanceMessage   string `json:"maintenanceMessage"`
 }
 
 type task struct {
+	ID         string    `json:"id"`
 	Status     int       `json:"status"`
 	Type       int       `json:"type"`
 	CreateDate time.Time `json:"create_date"`
 	Progress   int       `json:"progress"`
 	Error      string    `json:"error"`
+	HasReport  bool      `json:"has_report"`
 }
 
 // BuildTaskList 构建任务列表响应
@@ -37,11 +46,13 @@ func BuildTaskList(tasks []model.Task, total int) Response {
 	res := make([]task, 0, len(tasks))
 	for _, t := range tasks {
 		res = append(res, task{
+			ID:         hashid.HashID(t.ID, hashid.TaskID),
 			Status:     t.Status,
 			Type:       t.Type,
 			CreateDate: t.CreatedAt,
 			Progress:   t.Progress,
 			Error:      t.Error,
+			HasReport:  t.Report != "",
 		})
 	}
 
@@ -51,6 +62,36 @@ func BuildTaskList(tasks []model.Task, total int) Response {
 	}}
 }
 
+type notification struct {
+	ID         uint       `json:"id"`
+	Event      string     `json:"event"`
+	Title      string     `json:"title"`
+	Content    string     `json:"content"`
+	CreateDate time.Time  `json:"create_date"`
+	ReadAt     *time.Time `json:"read_at"`
+}
+
+// BuildNotificationList 构建站内信列表响应
+func BuildNotificationList(notifications []model.Notification, total, unread int) Response {
+	res := make([]notification, 0, len(notifications))
+	for _, n := range notifications {
+		res = append(res, notification{
+			ID:         n.ID,
+			Event:      n.Event,
+			Title:      n.Title,
+			Content:    n.Content,
+			CreateDate: n.CreatedAt,
+			ReadAt:     n.ReadAt,
+		})
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total":         total,
+		"unread":        unread,
+		"notifications": res,
+	}}
+}
+
 func checkSettingValue(setting map[string]string, key string) string {
 	if v, ok := setting[key]; ok {
 		return v
@@ -78,11 +119,17 @@ func BuildSiteConfig(settings map[string]string, user *model.User) Response {
 			HomepageViewMethod:   checkSettingValue(settings, "home_view_method"),
 			ShareViewMethod:      checkSettingValue(settings, "share_view_method"),
 			Authn:                model.IsTrueVal(checkSettingValue(settings, "authn_enabled")),
+			OIDC:                 model.IsTrueVal(checkSettingValue(settings, "oidc_enabled")),
+			SAML:                 model.IsTrueVal(checkSettingValue(settings, "saml_enabled")),
 			User:                 userRes,
 			ReCaptchaKey:         checkSettingValue(settings, "captcha_ReCaptchaKey"),
 			CaptchaType:          checkSettingValue(settings, "captcha_type"),
 			TCaptchaCaptchaAppId: checkSettingValue(settings, "captcha_TCaptcha_CaptchaAppId"),
 			RegisterEnabled:      model.IsTrueVal(checkSettingValue(settings, "register_enabled")),
+			RegisterInvite:       model.IsTrueVal(checkSettingValue(settings, "register_invite")),
+			RegisterPhone:        model.IsTrueVal(checkSettingValue(settings, "register_phone")),
+			Maintenance:          model.IsTrueVal(checkSettingValue(settings, "maintenance_enabled")),
+			MaintenanceMessage:   checkSettingValue(settings, "maintenance_message"),
 		}}
 	return res
 }
@@ -31,6 +31,11 @@ func TestBuildSiteConfig(t *testing.T) {
 		},
 	})
 	asserts.Len(res.Data.(SiteConfig).User.ID, 4)
+
+	// 维护模式
+	res = BuildSiteConfig(map[string]string{"maintenance_enabled": "1", "maintenance_message": "msg"}, nil)
+	asserts.True(res.Data.(SiteConfig).Maintenance)
+	asserts.Equal("msg", res.Data.(SiteConfig).MaintenanceMessage)
 }
 
 func TestBuildTaskList(t *testing.T) {
@@ -40,3 +45,13 @@ func TestBuildTaskList(t *testing.T) {
 	res := BuildTaskList(tasks, 1)
 	asserts.NotNil(res)
 }
+
+func TestBuildNotificationList(t *testing.T) {
+	asserts := assert.New(t)
+	notifications := []model.Notification{{Title: "title"}}
+
+	res := BuildNotificationList(notifications, 1, 1)
+	data := res.Data.(map[string]interface{})
+	asserts.Equal(1, data["unread"])
+	asserts.Equal("title", data["notifications"].([]notification)[0].Title)
+}
@@ -12,15 +12,31 @@ type Share struct {
 	Key        string        `json:"key"`
 	Locked     bool          `json:"locked"`
 	IsDir      bool          `json:"is_dir"`
+	Bundle     bool          `json:"bundle"`
 	CreateDate time.Time     `json:"create_date,omitempty"`
 	Downloads  int           `json:"downloads"`
 	Views      int           `json:"views"`
 	Expire     int64         `json:"expire"`
 	Preview    bool          `json:"preview"`
+	Download   bool          `json:"download"`
+	Upload     bool          `json:"upload"`
+	Roles      []string      `json:"roles,omitempty"`
 	Creator    *shareCreator `json:"creator,omitempty"`
 	Source     *shareSource  `json:"source,omitempty"`
 }
 
+// ShareMetadata 分享页面展示的自定义内容
+type ShareMetadata struct {
+	Description string       `json:"description"`
+	Readme      *ShareReadme `json:"readme"`
+}
+
+// ShareReadme 分享目录下的自述文件
+type ShareReadme struct {
+	Name    string `json:"name"`
+	Content string `json:"content"`
+}
+
 type shareCreator struct {
 	Key       string `json:"key"`
 	Nick      string `json:"nick"`
@@ -43,25 +59,60 @@ type myShareItem struct {
 	Views           int          `json:"views"`
 	Expire          int64        `json:"expire"`
 	Preview         bool         `json:"preview"`
+	Download        bool         `json:"download"`
+	Upload          bool         `json:"upload"`
+	ExpireAction    int          `json:"expire_action"`
+	Slug            string       `json:"slug,omitempty"`
+	Internal        bool         `json:"internal"`
+	TrafficLimit    uint64       `json:"traffic_limit"`
+	TrafficUsed     uint64       `json:"traffic_used"`
+	Watermark       bool         `json:"watermark"`
+	AllowedIPs      string       `json:"allowed_ips,omitempty"`
+	Countries       string       `json:"allowed_countries,omitempty"`
+	Bundle          bool         `json:"bundle"`
+	Moderation      string       `json:"moderation,omitempty"`
 	Source          *shareSource `json:"source,omitempty"`
 }
 
+// receivedShareItem 分享给我的内部分享列表条目
+type receivedShareItem struct {
+	Key        string        `json:"key"`
+	IsDir      bool          `json:"is_dir"`
+	CreateDate time.Time     `json:"create_date,omitempty"`
+	Creator    *shareCreator `json:"creator,omitempty"`
+	Source     *shareSource  `json:"source,omitempty"`
+}
+
 // BuildShareList 构建我的分享列表响应
 func BuildShareList(shares []model.Share, total int) Response {
 	res := make([]myShareItem, 0, total)
 	now := time.Now().Unix()
 	for i := 0; i < len(shares); i++ {
 		item := myShareItem{
-			Key:             hashid.HashID(shares[i].ID, hashid.ShareID),
+			Key:             shares[i].HashID(),
 			IsDir:           shares[i].IsDir,
 			Password:        shares[i].Password,
 			CreateDate:      shares[i].CreatedAt,
 			Downloads:       shares[i].Downloads,
 			Views:           shares[i].Views,
 			Preview:         shares[i].PreviewEnabled,
+			Download:        !shares[i].DownloadDisabled,
+			Upload:          shares[i].AllowUpload,
+			ExpireAction:    shares[i].ExpireAction,
+			Internal:        shares[i].Internal,
+			TrafficLimit:    shares[i].TrafficLimit,
+			TrafficUsed:     shares[i].TrafficUsed,
+			Watermark:       shares[i].Watermark,
+			AllowedIPs:      shares[i].AllowedIPs,
+			Countries:       shares[i].AllowedCountries,
+			Bundle:          shares[i].Bundle,
+			Moderation:      shares[i].ModerationStatus,
 			Expire:          -1,
 			RemainDownloads: shares[i].RemainDownloads,
 		}
+		if shares[i].Slug != nil {
+			item.Slug = *shares[i].Slug
+		}
 		if shares[i].Expires != nil {
 			item.Expire = shares[i].Expires.Unix() - now
 			if item.Expire == 0 {
@@ -77,6 +128,48 @@ func BuildShareList(shares []model.Share, total int) Response {
 			item.Source = &shareSource{
 				Name: shares[i].Folder.Name,
 			}
+		} else if shares[i].Bundle {
+			item.Source = &shareSource{
+				Name: shares[i].SourceName,
+			}
+		}
+
+		res = append(res, item)
+	}
+
+	return Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
+
+// BuildReceivedShareList 构建分享给我的内部分享列表响应
+func BuildReceivedShareList(shares []model.Share, total int) Response {
+	res := make([]receivedShareItem, 0, len(shares))
+	for i := 0; i < len(shares); i++ {
+		item := receivedShareItem{
+			Key:        shares[i].Key(),
+			IsDir:      shares[i].IsDir,
+			CreateDate: shares[i].CreatedAt,
+			Creator: &shareCreator{
+				Key:       hashid.HashID(shares[i].User.ID, hashid.UserID),
+				Nick:      shares[i].User.Nick,
+				GroupName: shares[i].User.Group.Name,
+			},
+		}
+		if shares[i].File.ID != 0 {
+			item.Source = &shareSource{
+				Name: shares[i].File.Name,
+				Size: shares[i].File.Size,
+			}
+		} else if shares[i].Folder.ID != 0 {
+			item.Source = &shareSource{
+				Name: shares[i].Folder.Name,
+			}
+		} else if shares[i].Bundle {
+			item.Source = &shareSource{
+				Name: shares[i].SourceName,
+			}
 		}
 
 		res = append(res, item)
@@ -92,7 +185,7 @@ func BuildShareList(shares []model.Share, total int) Response {
 func BuildShareResponse(share *model.Share, unlocked bool) Share {
 	creator := share.Creator()
 	resp := Share{
-		Key:    hashid.HashID(share.ID, hashid.ShareID),
+		Key:    share.HashID(),
 		Locked: !unlocked,
 		Creator: &shareCreator{
 			Key:       hashid.HashID(creator.ID, hashid.UserID),
@@ -108,15 +201,23 @@ func BuildShareResponse(share *model.Share, unlocked bool) Share {
 	}
 
 	resp.IsDir = share.IsDir
+	resp.Bundle = share.Bundle
 	resp.Downloads = share.Downloads
 	resp.Views = share.Views
 	resp.Preview = share.PreviewEnabled
+	resp.Download = !share.DownloadDisabled
+	resp.Upload = share.AllowUpload
 
 	if share.Expires != nil {
 		resp.Expire = share.Expires.Unix() - time.Now().Unix()
 	}
 
-	if share.IsDir {
+	if share.Bundle {
+		resp.Source = &shareSource{
+			Name: share.SourceName,
+			Size: 0,
+		}
+	} else if share.IsDir {
 		source := share.SourceFolder()
 		resp.Source = &shareSource{
 			Name: source.Name,
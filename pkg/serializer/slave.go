@@ -18,6 +18,11 @@ type ListRequest struct {
 	Recursive bool   `json:"recursive"`
 }
 
+// CapacityRequest 远程策略查询容量请求正文
+type CapacityRequest struct {
+	DirNameRule string `json:"dir_name_rule"`
+}
+
 // NodePingReq 从机节点Ping请求
 type NodePingReq struct {
 	SiteURL       string      `json:"site_url"`
@@ -25,6 +30,9 @@ type NodePingReq struct {
 	IsUpdate      bool        `json:"is_update"`
 	CredentialTTL int         `json:"credential_ttl"`
 	Node          *model.Node `json:"node"`
+	// RPCEndpoint 主机 gRPC 接口地址，为空时从机通过 HTTP 调用主机
+	RPCEndpoint string `json:"rpc_endpoint,omitempty"`
+	RPCTLS      bool   `json:"rpc_tls,omitempty"`
 }
 
 // NodePingResp 从机节点Ping响应
@@ -43,6 +51,8 @@ type SlaveTransferReq struct {
 	Src    string        `json:"src"`
 	Dst    string        `json:"dst"`
 	Policy *model.Policy `json:"policy"`
+	// 读取源文件的速率上限，字节/秒，0 表示不限制
+	IOLimit int64 `json:"io_limit,omitempty"`
 }
 
 // Hash 返回创建请求的唯一标识，保持创建请求幂等
@@ -0,0 +1,33 @@
+package serializer
+
+import "time"
+
+// FileChange 同步客户端拉取的文件变更，已删除的对象仅包含类型及 ID
+type FileChange struct {
+	Action string     `json:"action"`
+	Type   string     `json:"type"`
+	ID     string     `json:"id"`
+	Parent string     `json:"parent,omitempty"`
+	Name   string     `json:"name,omitempty"`
+	Path   string     `json:"path,omitempty"`
+	Size   uint64     `json:"size,omitempty"`
+	Hash   string     `json:"hash,omitempty"`
+	Date   *time.Time `json:"date,omitempty"`
+}
+
+// FileChangeList 游标之后的文件变更。Reset 为真时游标已失效，客户端需重新全量比对后从新游标继续
+type FileChangeList struct {
+	Cursor  string       `json:"cursor"`
+	HasMore bool         `json:"has_more"`
+	Reset   bool         `json:"reset"`
+	Changes []FileChange `json:"changes"`
+}
+
+// SyncFile 条件上传后文件的最新版本
+type SyncFile struct {
+	ID   string    `json:"id"`
+	Path string    `json:"path"`
+	Size uint64    `json:"size"`
+	Hash string    `json:"hash"`
+	Date time.Time `json:"date"`
+}
@@ -30,6 +30,7 @@ type UploadCredential struct {
 	KeyTime     string   `json:"keyTime,omitempty"` // COS用有效期
 	Policy      string   `json:"policy,omitempty"`
 	CompleteURL string   `json:"completeURL,omitempty"`
+	Parallel    int      `json:"parallel,omitempty"` // 允许同时上传的分片数，大于 1 时分片可乱序上传
 }
 
 // UploadSession 上传会话
@@ -47,6 +48,36 @@ type UploadSession struct {
 	UploadURL      string
 	UploadID       string
 	Credential     string
+	Parallel       int   // 协商的同时上传分片数，大于 1 时分片可乱序上传
+	Expires        int64 // 会话过期时间，Unix 时间戳
+}
+
+// ChunkNum 返回文件的分片数
+func (session *UploadSession) ChunkNum() int {
+	chunkSize := session.Policy.OptionsSerialized.ChunkSize
+	if chunkSize == 0 || session.Size <= chunkSize {
+		return 1
+	}
+
+	return int((session.Size + chunkSize - 1) / chunkSize)
+}
+
+// ChunkLength 返回第 index 个分片的长度
+func (session *UploadSession) ChunkLength(index int) uint64 {
+	chunkSize := session.Policy.OptionsSerialized.ChunkSize
+	if chunkSize == 0 {
+		return session.Size
+	}
+
+	start := uint64(index) * chunkSize
+	if start >= session.Size {
+		return 0
+	}
+	if session.Size-start < chunkSize {
+		return session.Size - start
+	}
+
+	return chunkSize
 }
 
 // UploadCallback 上传回调正文
@@ -0,0 +1,32 @@
+package serializer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadSession_Chunks(t *testing.T) {
+	a := assert.New(t)
+	session := &UploadSession{Size: 10}
+
+	// 未分片
+	a.Equal(1, session.ChunkNum())
+	a.EqualValues(10, session.ChunkLength(0))
+
+	// 最后一个分片不足分片大小
+	session.Policy.OptionsSerialized.ChunkSize = 4
+	a.Equal(3, session.ChunkNum())
+	a.EqualValues(4, session.ChunkLength(1))
+	a.EqualValues(2, session.ChunkLength(2))
+	a.EqualValues(0, session.ChunkLength(3))
+
+	// 恰好整除
+	session.Size = 8
+	a.Equal(2, session.ChunkNum())
+	a.EqualValues(4, session.ChunkLength(1))
+
+	// 空文件
+	session.Size = 0
+	a.Equal(1, session.ChunkNum())
+}
@@ -19,28 +19,35 @@ func CheckLogin() Response {
 
 // User 用户序列化器
 type User struct {
-	ID             string    `json:"id"`
-	Email          string    `json:"user_name"`
-	Nickname       string    `json:"nickname"`
-	Status         int       `json:"status"`
-	Avatar         string    `json:"avatar"`
-	CreatedAt      time.Time `json:"created_at"`
-	PreferredTheme string    `json:"preferred_theme"`
-	Anonymous      bool      `json:"anonymous"`
-	Group          group     `json:"group"`
-	Tags           []tag     `json:"tags"`
+	ID             string     `json:"id"`
+	Email          string     `json:"user_name"`
+	Nickname       string     `json:"nickname"`
+	Status         int        `json:"status"`
+	Avatar         string     `json:"avatar"`
+	CreatedAt      time.Time  `json:"created_at"`
+	PreferredTheme string     `json:"preferred_theme"`
+	Anonymous      bool       `json:"anonymous"`
+	Group          group      `json:"group"`
+	Tags           []tag      `json:"tags"`
+	Impersonated   bool       `json:"impersonated,omitempty"`
+	DeleteAfter    *time.Time `json:"delete_after,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`  // 访客账户的到期时间
+	SubAccount     string     `json:"sub_account,omitempty"` // 正在操作的子账户
 }
 
 type group struct {
-	ID                   uint   `json:"id"`
-	Name                 string `json:"name"`
-	AllowShare           bool   `json:"allowShare"`
-	AllowRemoteDownload  bool   `json:"allowRemoteDownload"`
-	AllowArchiveDownload bool   `json:"allowArchiveDownload"`
-	ShareDownload        bool   `json:"shareDownload"`
-	CompressEnabled      bool   `json:"compress"`
-	WebDAVEnabled        bool   `json:"webdav"`
-	SourceBatchSize      int    `json:"sourceBatch"`
+	ID                   uint       `json:"id"`
+	Name                 string     `json:"name"`
+	AllowShare           bool       `json:"allowShare"`
+	AllowRemoteDownload  bool       `json:"allowRemoteDownload"`
+	AllowArchiveDownload bool       `json:"allowArchiveDownload"`
+	ShareDownload        bool       `json:"shareDownload"`
+	CompressEnabled      bool       `json:"compress"`
+	WebDAVEnabled        bool       `json:"webdav"`
+	SourceBatchSize      int        `json:"sourceBatch"`
+	ShareSlug            bool       `json:"shareSlug"`
+	Require2FA           bool       `json:"require2FA"`
+	Expires              *time.Time `json:"expires,omitempty"` // 临时用户组的到期时间
 }
 
 type tag struct {
@@ -78,6 +85,85 @@ func BuildWebAuthnList(credentials []webauthn.Credential) []WebAuthnCredentials
 	return res
 }
 
+// AuthnCredential 验证器管理列表中的验证器
+type AuthnCredential struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	FingerPrint string     `json:"fingerprint"`
+	Passkey     bool       `json:"passkey"`
+	CreatedAt   *time.Time `json:"created_at"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+// BuildAuthnCredentialList 构建验证器管理列表
+func BuildAuthnCredentialList(authenticators []model.AuthnCredential) []AuthnCredential {
+	res := make([]AuthnCredential, 0, len(authenticators))
+	for _, v := range authenticators {
+		res = append(res, AuthnCredential{
+			ID:          model.AuthnCredentialID(v.ID),
+			Name:        v.Name,
+			FingerPrint: fmt.Sprintf("% X", v.Authenticator.AAGUID),
+			Passkey:     v.Passkey,
+			CreatedAt:   v.CreatedAt,
+			LastUsedAt:  v.LastUsedAt,
+		})
+	}
+
+	return res
+}
+
+// Device 登录设备及应用凭证
+type Device struct {
+	ID        uint       `json:"id"`
+	Type      string     `json:"type"`
+	Name      string     `json:"name"`
+	UserAgent string     `json:"user_agent,omitempty"`
+	IP        string     `json:"ip"`
+	CreatedAt time.Time  `json:"created_at"`
+	LastSeen  *time.Time `json:"last_seen"`
+	Current   bool       `json:"current,omitempty"`
+}
+
+// BuildDeviceList 构建登录设备列表，包括登录会话、WebDAV 账户及个人访问令牌
+func BuildDeviceList(sessions []model.UserSession, currentKey string, accounts []model.Webdav, tokens []model.APIToken) []Device {
+	res := make([]Device, 0, len(sessions)+len(accounts)+len(tokens))
+	for i := range sessions {
+		res = append(res, Device{
+			ID:        sessions[i].ID,
+			Type:      "session",
+			UserAgent: sessions[i].UserAgent,
+			IP:        sessions[i].IP,
+			CreatedAt: sessions[i].CreatedAt,
+			LastSeen:  &sessions[i].LastSeen,
+			Current:   currentKey != "" && sessions[i].SessionKey == currentKey,
+		})
+	}
+
+	for i := range accounts {
+		res = append(res, Device{
+			ID:        accounts[i].ID,
+			Type:      "webdav",
+			Name:      accounts[i].Name,
+			IP:        accounts[i].LastIP,
+			CreatedAt: accounts[i].CreatedAt,
+			LastSeen:  accounts[i].LastUsedAt,
+		})
+	}
+
+	for i := range tokens {
+		res = append(res, Device{
+			ID:        tokens[i].ID,
+			Type:      "token",
+			Name:      tokens[i].Name,
+			IP:        tokens[i].LastIP,
+			CreatedAt: tokens[i].CreatedAt,
+			LastSeen:  tokens[i].LastUsedAt,
+		})
+	}
+
+	return res
+}
+
 // BuildUser 序列化用户
 func BuildUser(user model.User) User {
 	tags, _ := model.GetTagsByUID(user.ID)
@@ -100,8 +186,44 @@ func BuildUser(user model.User) User {
 			CompressEnabled:      user.Group.OptionsSerialized.ArchiveTask,
 			WebDAVEnabled:        user.Group.WebDAVEnabled,
 			SourceBatchSize:      user.Group.OptionsSerialized.SourceBatchSize,
+			ShareSlug:            user.Group.OptionsSerialized.ShareSlug,
+			Require2FA:           user.Group.OptionsSerialized.Require2FA,
+			Expires:              user.GroupExpires,
 		},
-		Tags: buildTagRes(tags),
+		Tags:         buildTagRes(tags),
+		Impersonated: user.Impersonator != 0,
+		DeleteAfter:  user.DeleteAfter,
+		ExpiresAt:    user.ExpiresAt,
+		SubAccount:   subAccountName(user.SubAccount),
+	}
+}
+
+func subAccountName(sub *model.User) string {
+	if sub == nil {
+		return ""
+	}
+	return sub.Email
+}
+
+// SubAccount 子账户序列化器
+type SubAccount struct {
+	ID        uint      `json:"id"`
+	Email     string    `json:"user_name"`
+	Nickname  string    `json:"nickname"`
+	Folder    string    `json:"folder"`
+	Status    int       `json:"status"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BuildSubAccount 序列化子账户
+func BuildSubAccount(user model.User) SubAccount {
+	return SubAccount{
+		ID:        user.ID,
+		Email:     user.Email,
+		Nickname:  user.Nick,
+		Folder:    user.Folder,
+		Status:    user.Status,
+		CreatedAt: user.CreatedAt,
 	}
 }
 
@@ -114,7 +236,7 @@ func BuildUserResponse(user model.User) Response {
 
 // BuildUserStorageResponse 序列化用户存储概况响应
 func BuildUserStorageResponse(user model.User) Response {
-	total := user.Group.MaxStorage
+	total := user.MaxStorage()
 	storageResp := storage{
 		Used:  user.Storage,
 		Free:  total - user.Storage,
@@ -34,6 +34,17 @@ func TestBuildUser(t *testing.T) {
 	res := BuildUser(user)
 	asserts.NoError(mock.ExpectationsWereMet())
 	asserts.NotNil(res)
+	asserts.False(res.Impersonated)
+
+	user.Impersonator = 1
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	asserts.True(BuildUser(user).Impersonated)
+	asserts.NoError(mock.ExpectationsWereMet())
+
+	user.SubAccount = &model.User{Email: "family@cloudreve.org"}
+	mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	asserts.Equal("family@cloudreve.org", BuildUser(user).SubAccount)
+	asserts.NoError(mock.ExpectationsWereMet())
 
 }
 
@@ -114,3 +125,42 @@ func TestBuildWebAuthnList(t *testing.T) {
 	res := BuildWebAuthnList(credentials)
 	asserts.Len(res, 1)
 }
+
+func TestBuildAuthnCredentialList(t *testing.T) {
+	asserts := assert.New(t)
+	authenticators := []model.AuthnCredential{{
+		Credential: webauthn.Credential{ID: []byte("123")},
+		Name:       "YubiKey",
+		Passkey:    true,
+	}}
+	res := BuildAuthnCredentialList(authenticators)
+	asserts.Len(res, 1)
+	asserts.Equal("MTIz", res[0].ID)
+	asserts.Equal("YubiKey", res[0].Name)
+	asserts.True(res[0].Passkey)
+}
+
+func TestBuildDeviceList(t *testing.T) {
+	asserts := assert.New(t)
+	sessions := []model.UserSession{{SessionKey: "current", UserAgent: "Firefox"}, {SessionKey: "other"}}
+	sessions[0].ID = 1
+	sessions[1].ID = 2
+	accounts := []model.Webdav{{Name: "dav", LastIP: "127.0.0.1"}}
+	tokens := []model.APIToken{{Name: "script"}}
+
+	res := BuildDeviceList(sessions, "current", accounts, tokens)
+	asserts.Len(res, 4)
+	asserts.Equal("session", res[0].Type)
+	asserts.Equal("Firefox", res[0].UserAgent)
+	asserts.True(res[0].Current)
+	asserts.False(res[1].Current)
+	asserts.Equal("webdav", res[2].Type)
+	asserts.Equal("127.0.0.1", res[2].IP)
+	asserts.Nil(res[2].LastSeen)
+	asserts.Equal("token", res[3].Type)
+	asserts.Equal("script", res[3].Name)
+
+	// 无当前会话
+	res = BuildDeviceList(sessions, "", nil, nil)
+	asserts.False(res[0].Current)
+}
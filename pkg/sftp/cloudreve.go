@@ -0,0 +1,129 @@
+package sftp
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"golang.org/x/crypto/ssh"
+)
+
+// idleTimeout 连接的空闲超时
+const idleTimeout = 5 * time.Minute
+
+// NewCloudreveServer 按照配置文件新建 SFTP 服务
+func NewCloudreveServer() (*Server, error) {
+	hostKey, err := loadHostKey(util.RelativePath(conf.SFTPConfig.HostKeyPath))
+	if err != nil {
+		return nil, err
+	}
+
+	return NewServer(&Settings{
+		Listen:      conf.SFTPConfig.Listen,
+		HostKeys:    []ssh.Signer{hostKey},
+		TempFile:    vfs.TempFile,
+		IdleTimeout: idleTimeout,
+	}, &cloudreveDriver{}), nil
+}
+
+// loadHostKey 读取主机密钥，不存在时生成新的 Ed25519 密钥并保存
+func loadHostKey(keyPath string) (ssh.Signer, error) {
+	content, err := ioutil.ReadFile(keyPath)
+	if os.IsNotExist(err) {
+		util.Log().Info("SFTP 主机密钥不存在，生成新的密钥并保存至 %s", keyPath)
+		content, err = generateHostKey(keyPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.ParsePrivateKey(content)
+}
+
+// generateHostKey 生成 PKCS#8 PEM 格式的 Ed25519 密钥并保存
+func generateHostKey(keyPath string) ([]byte, error) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	content := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+
+	if err := os.MkdirAll(filepath.Dir(keyPath), 0700); err != nil {
+		return nil, err
+	}
+	if err := ioutil.WriteFile(keyPath, content, 0600); err != nil {
+		return nil, err
+	}
+	return content, nil
+}
+
+// cloudreveDriver 用户名为邮箱，可使用 WebDAV 账户的密码，或已添加的 SSH 公钥登录
+type cloudreveDriver struct{}
+
+// clientDriver 登录成功后访问用户文件，touch 记录所用账户或公钥的使用情况
+type clientDriver struct {
+	*vfs.Session
+	touch func(ip string)
+}
+
+// PasswordLogin 校验 WebDAV 账户，用户组须允许使用 SFTP
+func (d *cloudreveDriver) PasswordLogin(username, password string, addr net.Addr) (ClientDriver, error) {
+	session, err := vfs.LoginWebDAV(username, password, remoteIP(addr))
+	if err != nil {
+		return nil, err
+	}
+
+	if !session.User.Group.OptionsSerialized.SFTP {
+		return nil, ErrPermissionDenied
+	}
+
+	return &clientDriver{Session: session, touch: session.Account.Touch}, nil
+}
+
+// PublicKeyLogin 校验公钥是否属于用户名对应的用户，公钥登录可访问用户的全部文件
+func (d *cloudreveDriver) PublicKeyLogin(username string, key ssh.PublicKey, addr net.Addr) (ClientDriver, error) {
+	sshKey, err := model.GetSSHKeyByFingerprint(ssh.FingerprintSHA256(key))
+	if err != nil {
+		return nil, ErrLoginFailed
+	}
+
+	user, err := model.GetActiveUserByID(sshKey.UserID)
+	if err != nil || !strings.EqualFold(user.Email, username) {
+		return nil, ErrLoginFailed
+	}
+
+	if !user.Group.OptionsSerialized.SFTP || !user.Group.AllowIP(remoteIP(addr)) {
+		return nil, ErrPermissionDenied
+	}
+
+	return &clientDriver{Session: vfs.NewSession(&user), touch: sshKey.Touch}, nil
+}
+
+// LoggedIn 记录登录所用账户或公钥的使用时间及 IP
+func (d *cloudreveDriver) LoggedIn(driver ClientDriver, addr net.Addr) {
+	if client, ok := driver.(*clientDriver); ok {
+		client.touch(remoteIP(addr))
+	}
+}
+
+// remoteIP 客户端 IP
+func remoteIP(addr net.Addr) string {
+	ip, _, _ := net.SplitHostPort(addr.String())
+	return ip
+}
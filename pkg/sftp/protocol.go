@@ -0,0 +1,810 @@
+package sftp
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"time"
+)
+
+// 数据包类型，参见 draft-ietf-secsh-filexfer-02
+const (
+	fxpInit     = 1
+	fxpVersion  = 2
+	fxpOpen     = 3
+	fxpClose    = 4
+	fxpRead     = 5
+	fxpWrite    = 6
+	fxpLstat    = 7
+	fxpFstat    = 8
+	fxpSetstat  = 9
+	fxpFsetstat = 10
+	fxpOpendir  = 11
+	fxpReaddir  = 12
+	fxpRemove   = 13
+	fxpMkdir    = 14
+	fxpRmdir    = 15
+	fxpRealpath = 16
+	fxpStat     = 17
+	fxpRename   = 18
+	fxpStatus   = 101
+	fxpHandle   = 102
+	fxpData     = 103
+	fxpName     = 104
+	fxpAttrs    = 105
+	fxpExtended = 200
+)
+
+const (
+	protocolVersion = 3
+	// posixRenameExt 与 RENAME 相同，OpenSSH 客户端在服务端支持时优先使用
+	posixRenameExt = "posix-rename@openssh.com"
+	// maxPacketLength 单个数据包的最大长度
+	maxPacketLength = 256*1024 + 1024
+	// maxReadLength 单次读取返回的最大长度
+	maxReadLength = 64 * 1024
+	// maxHandles 单个会话同时打开的句柄数上限
+	maxHandles = 64
+	// readdirBatchSize 每次 READDIR 返回的条目数
+	readdirBatchSize = 100
+)
+
+// 状态码
+const (
+	fxOK               = 0
+	fxEOF              = 1
+	fxNoSuchFile       = 2
+	fxPermissionDenied = 3
+	fxFailure          = 4
+	fxBadMessage       = 5
+	fxOpUnsupported    = 8
+)
+
+// 文件属性标志
+const (
+	attrSize        = 0x00000001
+	attrUIDGID      = 0x00000002
+	attrPermissions = 0x00000004
+	attrACModTime   = 0x00000008
+	attrExtended    = 0x80000000
+)
+
+// 打开文件的标志
+const (
+	openRead   = 0x00000001
+	openWrite  = 0x00000002
+	openAppend = 0x00000004
+	openCreate = 0x00000008
+	openTrunc  = 0x00000010
+	openExcl   = 0x00000020
+)
+
+var (
+	errBadMessage  = errors.New("bad message")
+	errUnsupported = errors.New("operation unsupported")
+	errBadHandle   = errors.New("invalid handle")
+	errIsDir       = errors.New("is a directory")
+	errTooManyOpen = errors.New("too many open handles")
+)
+
+// packet 待解析的数据包内容
+type packet []byte
+
+func (p *packet) uint32() (uint32, error) {
+	if len(*p) < 4 {
+		return 0, errBadMessage
+	}
+	v := binary.BigEndian.Uint32(*p)
+	*p = (*p)[4:]
+	return v, nil
+}
+
+func (p *packet) uint64() (uint64, error) {
+	if len(*p) < 8 {
+		return 0, errBadMessage
+	}
+	v := binary.BigEndian.Uint64(*p)
+	*p = (*p)[8:]
+	return v, nil
+}
+
+func (p *packet) bytes() ([]byte, error) {
+	n, err := p.uint32()
+	if err != nil || uint32(len(*p)) < n {
+		return nil, errBadMessage
+	}
+	v := (*p)[:n]
+	*p = (*p)[n:]
+	return v, nil
+}
+
+func (p *packet) string() (string, error) {
+	v, err := p.bytes()
+	return string(v), err
+}
+
+// attrs 解析文件属性，只保留大小
+func (p *packet) attrs() (size uint64, hasSize bool, err error) {
+	flags, err := p.uint32()
+	if err != nil {
+		return 0, false, err
+	}
+	if flags&attrSize != 0 {
+		if size, err = p.uint64(); err != nil {
+			return 0, false, err
+		}
+	}
+	skip := 0
+	if flags&attrUIDGID != 0 {
+		skip += 8
+	}
+	if flags&attrPermissions != 0 {
+		skip += 4
+	}
+	if flags&attrACModTime != 0 {
+		skip += 8
+	}
+	if len(*p) < skip {
+		return 0, false, errBadMessage
+	}
+	*p = (*p)[skip:]
+	if flags&attrExtended != 0 {
+		count, err := p.uint32()
+		if err != nil {
+			return 0, false, err
+		}
+		for i := uint32(0); i < count*2; i++ {
+			if _, err := p.bytes(); err != nil {
+				return 0, false, err
+			}
+		}
+	}
+	return size, flags&attrSize != 0, nil
+}
+
+// buffer 待发送的数据包
+type buffer []byte
+
+func (b buffer) uint32(v uint32) buffer {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func (b buffer) uint64(v uint64) buffer {
+	return b.uint32(uint32(v >> 32)).uint32(uint32(v))
+}
+
+func (b buffer) string(s string) buffer {
+	return append(b.uint32(uint32(len(s))), s...)
+}
+
+func (b buffer) bytes(v []byte) buffer {
+	return append(b.uint32(uint32(len(v))), v...)
+}
+
+// attrs 写入文件属性
+func (b buffer) attrs(info FileInfo, readonly bool) buffer {
+	mtime := uint32(info.ModTime().Unix())
+	return b.uint32(attrSize | attrPermissions | attrACModTime).
+		uint64(info.GetSize()).
+		uint32(fileMode(info, readonly)).
+		uint32(mtime).
+		uint32(mtime)
+}
+
+// fileMode 文件权限，只读会话去掉写权限
+func fileMode(info FileInfo, readonly bool) uint32 {
+	if info.IsDir() {
+		if readonly {
+			return 040555
+		}
+		return 040755
+	}
+	if readonly {
+		return 0100444
+	}
+	return 0100644
+}
+
+// longName 类似 ls -l 的文件描述
+func longName(info FileInfo, readonly bool) string {
+	var perm string
+	switch {
+	case info.IsDir() && readonly:
+		perm = "dr-xr-xr-x"
+	case info.IsDir():
+		perm = "drwxr-xr-x"
+	case readonly:
+		perm = "-r--r--r--"
+	default:
+		perm = "-rw-r--r--"
+	}
+
+	layout := "Jan _2 15:04"
+	if time.Since(info.ModTime()) > 180*24*time.Hour {
+		layout = "Jan _2  2006"
+	}
+	return perm + " 1 cloudreve cloudreve " + strconv.FormatUint(info.GetSize(), 10) + " " +
+		info.ModTime().Format(layout) + " " + info.GetName()
+}
+
+// fileHandle 打开的文件。读取时直接读取存储策略中的文件，
+// 写入时先暂存至本机临时文件，关闭时再上传
+type fileHandle struct {
+	path   string
+	reader io.ReadSeekCloser
+	offset int64
+
+	tmp    *os.File
+	limit  uint64
+	append bool
+	dirty  bool
+}
+
+// dirHandle 打开的目录
+type dirHandle struct {
+	path    string
+	entries []FileInfo
+	listed  bool
+}
+
+// session SFTP 子系统会话
+type session struct {
+	rw       io.ReadWriter
+	driver   ClientDriver
+	settings *Settings
+
+	handles    map[string]interface{}
+	nextHandle uint64
+}
+
+func newSession(rw io.ReadWriter, driver ClientDriver, settings *Settings) *session {
+	return &session{
+		rw:       rw,
+		driver:   driver,
+		settings: settings,
+		handles:  make(map[string]interface{}),
+	}
+}
+
+// serve 逐个处理请求直至连接断开
+func (s *session) serve() {
+	defer s.closeAll()
+
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(s.rw, header); err != nil {
+			return
+		}
+		length := binary.BigEndian.Uint32(header)
+		if length < 1 || length > maxPacketLength {
+			return
+		}
+
+		data := make([]byte, length)
+		if _, err := io.ReadFull(s.rw, data); err != nil {
+			return
+		}
+
+		if err := s.handle(data[0], packet(data[1:])); err != nil {
+			return
+		}
+	}
+}
+
+// closeAll 关闭所有句柄，未关闭的上传视为中断，不会写入
+func (s *session) closeAll() {
+	for id, h := range s.handles {
+		if fh, ok := h.(*fileHandle); ok {
+			fh.dirty = false
+			fh.close(s.driver)
+		}
+		delete(s.handles, id)
+	}
+}
+
+// send 发送数据包
+func (s *session) send(typ byte, b buffer) error {
+	header := buffer(nil).uint32(uint32(len(b) + 1))
+	_, err := s.rw.Write(append(append(header, typ), b...))
+	return err
+}
+
+// sendStatus 发送状态
+func (s *session) sendStatus(id uint32, err error) error {
+	code, msg := uint32(fxOK), "OK"
+	switch {
+	case err == nil:
+	case errors.Is(err, io.EOF):
+		code, msg = fxEOF, "EOF"
+	case errors.Is(err, ErrNotExist):
+		code, msg = fxNoSuchFile, err.Error()
+	case errors.Is(err, ErrPermissionDenied):
+		code, msg = fxPermissionDenied, err.Error()
+	case errors.Is(err, errBadMessage):
+		code, msg = fxBadMessage, err.Error()
+	case errors.Is(err, errUnsupported):
+		code, msg = fxOpUnsupported, err.Error()
+	default:
+		code, msg = fxFailure, err.Error()
+	}
+
+	return s.send(fxpStatus, buffer(nil).uint32(id).uint32(code).string(msg).string(""))
+}
+
+// absPath 将客户端路径转换为以 / 开头的绝对路径，相对路径以根目录为起点
+func absPath(p string) string {
+	return path.Join("/", p)
+}
+
+// handle 处理一个请求，只有发送失败时返回错误
+func (s *session) handle(typ byte, p packet) error {
+	if typ == fxpInit {
+		return s.send(fxpVersion, buffer(nil).uint32(protocolVersion).string(posixRenameExt).string("1"))
+	}
+
+	id, err := p.uint32()
+	if err != nil {
+		return err
+	}
+
+	switch typ {
+	case fxpRealpath:
+		return s.handleRealpath(id, p)
+	case fxpStat, fxpLstat:
+		return s.handleStat(id, p)
+	case fxpFstat:
+		return s.handleFstat(id, p)
+	case fxpSetstat:
+		return s.handleSetstat(id, p)
+	case fxpFsetstat:
+		return s.handleFsetstat(id, p)
+	case fxpOpendir:
+		return s.handleOpendir(id, p)
+	case fxpReaddir:
+		return s.handleReaddir(id, p)
+	case fxpOpen:
+		return s.handleOpen(id, p)
+	case fxpRead:
+		return s.handleRead(id, p)
+	case fxpWrite:
+		return s.handleWrite(id, p)
+	case fxpClose:
+		return s.handleClose(id, p)
+	case fxpRemove:
+		return s.handlePath(id, p, s.driver.Remove)
+	case fxpMkdir:
+		return s.handlePath(id, p, s.driver.MakeDir)
+	case fxpRmdir:
+		return s.handlePath(id, p, s.driver.RemoveDir)
+	case fxpRename:
+		return s.handleRename(id, p)
+	case fxpExtended:
+		ext, err := p.string()
+		if err != nil {
+			return s.sendStatus(id, err)
+		}
+		if ext == posixRenameExt {
+			return s.handleRename(id, p)
+		}
+	}
+
+	return s.sendStatus(id, errUnsupported)
+}
+
+func (s *session) handleRealpath(id uint32, p packet) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	name = absPath(name)
+	return s.send(fxpName, buffer(nil).uint32(id).uint32(1).string(name).string(name).uint32(0))
+}
+
+func (s *session) handleStat(id uint32, p packet) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	info, err := s.driver.Stat(absPath(name))
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	return s.send(fxpAttrs, buffer(nil).uint32(id).attrs(info, s.driver.Readonly()))
+}
+
+// tempFileInfo 写入中的文件信息
+type tempFileInfo struct {
+	name string
+	os.FileInfo
+}
+
+func (i *tempFileInfo) GetName() string {
+	return i.name
+}
+
+func (i *tempFileInfo) GetSize() uint64 {
+	return uint64(i.Size())
+}
+
+func (s *session) handleFstat(id uint32, p packet) error {
+	h, err := s.fileHandle(&p)
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	var info FileInfo
+	if h.tmp != nil {
+		var stat os.FileInfo
+		if stat, err = h.tmp.Stat(); err == nil {
+			info = &tempFileInfo{name: path.Base(h.path), FileInfo: stat}
+		}
+	} else {
+		info, err = s.driver.Stat(h.path)
+	}
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	return s.send(fxpAttrs, buffer(nil).uint32(id).attrs(info, s.driver.Readonly()))
+}
+
+// handleSetstat 不支持修改权限及时间，客户端上传后通常会设置这些属性，直接忽略
+func (s *session) handleSetstat(id uint32, p packet) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	if _, _, err := p.attrs(); err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	_, err = s.driver.Stat(absPath(name))
+	return s.sendStatus(id, err)
+}
+
+// handleFsetstat 写入中的文件支持修改大小，其余属性忽略
+func (s *session) handleFsetstat(id uint32, p packet) error {
+	h, err := s.fileHandle(&p)
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	size, hasSize, err := p.attrs()
+	if err != nil || !hasSize {
+		return s.sendStatus(id, err)
+	}
+
+	if h.tmp == nil {
+		return s.sendStatus(id, ErrPermissionDenied)
+	}
+	if size > h.limit {
+		return s.sendStatus(id, ErrQuotaExceeded)
+	}
+	if err := h.tmp.Truncate(int64(size)); err != nil {
+		return s.sendStatus(id, err)
+	}
+	h.dirty = true
+	return s.sendStatus(id, nil)
+}
+
+func (s *session) handleOpendir(id uint32, p packet) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	name = absPath(name)
+	info, err := s.driver.Stat(name)
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	if !info.IsDir() {
+		return s.sendStatus(id, ErrNotExist)
+	}
+
+	return s.sendHandle(id, &dirHandle{path: name})
+}
+
+func (s *session) handleReaddir(id uint32, p packet) error {
+	handle, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	h, ok := s.handles[handle].(*dirHandle)
+	if !ok {
+		return s.sendStatus(id, errBadHandle)
+	}
+
+	if !h.listed {
+		if h.entries, err = s.driver.List(h.path); err != nil {
+			return s.sendStatus(id, err)
+		}
+		h.listed = true
+	}
+	if len(h.entries) == 0 {
+		return s.sendStatus(id, io.EOF)
+	}
+
+	batch := h.entries
+	if len(batch) > readdirBatchSize {
+		batch = batch[:readdirBatchSize]
+	}
+	h.entries = h.entries[len(batch):]
+
+	readonly := s.driver.Readonly()
+	b := buffer(nil).uint32(id).uint32(uint32(len(batch)))
+	for _, info := range batch {
+		b = b.string(info.GetName()).string(longName(info, readonly)).attrs(info, readonly)
+	}
+	return s.send(fxpName, b)
+}
+
+// sendHandle 记录句柄并告知客户端
+func (s *session) sendHandle(id uint32, h interface{}) error {
+	if len(s.handles) >= maxHandles {
+		if fh, ok := h.(*fileHandle); ok {
+			fh.close(s.driver)
+		}
+		return s.sendStatus(id, errTooManyOpen)
+	}
+
+	s.nextHandle++
+	handle := strconv.FormatUint(s.nextHandle, 10)
+	s.handles[handle] = h
+	return s.send(fxpHandle, buffer(nil).uint32(id).string(handle))
+}
+
+// fileHandle 读取并查找文件句柄
+func (s *session) fileHandle(p *packet) (*fileHandle, error) {
+	handle, err := p.string()
+	if err != nil {
+		return nil, err
+	}
+	h, ok := s.handles[handle].(*fileHandle)
+	if !ok {
+		return nil, errBadHandle
+	}
+	return h, nil
+}
+
+func (s *session) handleOpen(id uint32, p packet) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	flags, err := p.uint32()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	if _, _, err := p.attrs(); err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	name = absPath(name)
+	var h *fileHandle
+	if flags&openWrite == 0 {
+		h, err = s.openRead(name)
+	} else {
+		h, err = s.openWrite(name, flags)
+	}
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+
+	return s.sendHandle(id, h)
+}
+
+// openRead 打开文件用于读取
+func (s *session) openRead(name string) (*fileHandle, error) {
+	info, err := s.driver.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errIsDir
+	}
+
+	reader, err := s.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &fileHandle{path: name, reader: reader}, nil
+}
+
+// openWrite 打开文件用于写入，内容暂存至临时文件。未指定截断时保留原有内容，
+// 新建或截断的文件即使没有写入也会在关闭时保存
+func (s *session) openWrite(name string, flags uint32) (*fileHandle, error) {
+	if s.driver.Readonly() {
+		return nil, ErrPermissionDenied
+	}
+
+	info, err := s.driver.Stat(name)
+	exist := err == nil
+	switch {
+	case err != nil && !errors.Is(err, ErrNotExist):
+		return nil, err
+	case exist && info.IsDir():
+		return nil, errIsDir
+	case exist && flags&openExcl != 0 && flags&openCreate != 0:
+		return nil, ErrExist
+	case !exist && flags&openCreate == 0:
+		return nil, ErrNotExist
+	}
+
+	limit, err := s.driver.UploadLimit(name)
+	if err != nil {
+		return nil, err
+	}
+
+	tmp, err := s.settings.TempFile()
+	if err != nil {
+		return nil, err
+	}
+	h := &fileHandle{
+		path:   name,
+		tmp:    tmp,
+		limit:  limit,
+		append: flags&openAppend != 0,
+		dirty:  !exist || flags&openTrunc != 0,
+	}
+
+	if exist && flags&openTrunc == 0 {
+		if err := s.preload(h); err != nil {
+			h.close(s.driver)
+			return nil, err
+		}
+	}
+
+	return h, nil
+}
+
+// preload 将已存在的文件内容复制到临时文件，以便修改部分内容
+func (s *session) preload(h *fileHandle) error {
+	reader, err := s.driver.Open(h.path)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	_, err = io.Copy(h.tmp, reader)
+	return err
+}
+
+func (s *session) handleRead(id uint32, p packet) error {
+	h, err := s.fileHandle(&p)
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	offset, err := p.uint64()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	length, err := p.uint32()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	if length > maxReadLength {
+		length = maxReadLength
+	}
+
+	data := make([]byte, length)
+	var n int
+	if h.tmp != nil {
+		n, err = h.tmp.ReadAt(data, int64(offset))
+	} else {
+		n, err = h.read(data, int64(offset))
+	}
+	if n == 0 {
+		if err == nil || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = io.EOF
+		}
+		return s.sendStatus(id, err)
+	}
+
+	return s.send(fxpData, buffer(nil).uint32(id).bytes(data[:n]))
+}
+
+// read 从 offset 处读取，客户端通常顺序读取，仅在位置变化时重新定位
+func (h *fileHandle) read(data []byte, offset int64) (int, error) {
+	if offset != h.offset {
+		if _, err := h.reader.Seek(offset, io.SeekStart); err != nil {
+			return 0, err
+		}
+		h.offset = offset
+	}
+
+	n, err := io.ReadFull(h.reader, data)
+	h.offset += int64(n)
+	return n, err
+}
+
+func (s *session) handleWrite(id uint32, p packet) error {
+	h, err := s.fileHandle(&p)
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	offset, err := p.uint64()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	data, err := p.bytes()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	if h.tmp == nil {
+		return s.sendStatus(id, ErrPermissionDenied)
+	}
+
+	if h.append {
+		info, err := h.tmp.Stat()
+		if err != nil {
+			return s.sendStatus(id, err)
+		}
+		offset = uint64(info.Size())
+	}
+	if offset+uint64(len(data)) > h.limit {
+		return s.sendStatus(id, ErrQuotaExceeded)
+	}
+
+	if _, err := h.tmp.WriteAt(data, int64(offset)); err != nil {
+		return s.sendStatus(id, err)
+	}
+	h.dirty = true
+	return s.sendStatus(id, nil)
+}
+
+func (s *session) handleClose(id uint32, p packet) error {
+	handle, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	h, ok := s.handles[handle]
+	if !ok {
+		return s.sendStatus(id, errBadHandle)
+	}
+	delete(s.handles, handle)
+
+	if fh, ok := h.(*fileHandle); ok {
+		err = fh.close(s.driver)
+	}
+	return s.sendStatus(id, err)
+}
+
+// close 关闭文件，写入过的文件上传至存储策略，随后删除临时文件
+func (h *fileHandle) close(driver ClientDriver) error {
+	if h.reader != nil {
+		return h.reader.Close()
+	}
+
+	var err error
+	if h.dirty {
+		err = driver.StoreFile(h.path, h.tmp)
+	}
+	h.tmp.Close()
+	os.Remove(h.tmp.Name())
+	return err
+}
+
+// handlePath 处理只有一个路径参数的请求
+func (s *session) handlePath(id uint32, p packet, fn func(string) error) error {
+	name, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	return s.sendStatus(id, fn(absPath(name)))
+}
+
+func (s *session) handleRename(id uint32, p packet) error {
+	from, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	to, err := p.string()
+	if err != nil {
+		return s.sendStatus(id, err)
+	}
+	return s.sendStatus(id, s.driver.Rename(absPath(from), absPath(to)))
+}
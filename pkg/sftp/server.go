@@ -0,0 +1,283 @@
+package sftp
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	// loginFailureDelay 登录失败后的延迟，减缓密码爆破
+	loginFailureDelay = time.Second
+	// handshakeTimeout SSH 握手及认证的超时
+	handshakeTimeout = 30 * time.Second
+	// driverExtension 记录认证成功的驱动的扩展字段
+	driverExtension = "cloudreve-driver"
+)
+
+// ErrServerClosed 服务已关闭
+var ErrServerClosed = errors.New("sftp: server closed")
+
+// 驱动返回的错误，与按路径访问用户文件的会话使用相同的定义
+var (
+	ErrNotExist         = vfs.ErrNotExist
+	ErrExist            = vfs.ErrExist
+	ErrPermissionDenied = vfs.ErrPermissionDenied
+	ErrQuotaExceeded    = vfs.ErrQuotaExceeded
+	ErrLoginFailed      = vfs.ErrLoginFailed
+)
+
+// FileInfo 文件或目录的信息
+type FileInfo = vfs.FileInfo
+
+// Driver 处理登录的驱动
+type Driver interface {
+	// PasswordLogin 校验用户名及密码，成功时返回此会话使用的客户端驱动
+	PasswordLogin(user, pass string, addr net.Addr) (ClientDriver, error)
+	// PublicKeyLogin 校验用户名及公钥，客户端尚未证明持有私钥，不应记录登录
+	PublicKeyLogin(user string, key ssh.PublicKey, addr net.Addr) (ClientDriver, error)
+	// LoggedIn 认证完成后调用，driver 为认证成功的方式返回的客户端驱动
+	LoggedIn(driver ClientDriver, addr net.Addr)
+}
+
+// ClientDriver 登录后的会话访问文件系统所用的驱动，路径均为以 / 开头的绝对路径
+type ClientDriver interface {
+	// Readonly 会话是否只读
+	Readonly() bool
+	// Stat 获取文件或目录信息
+	Stat(path string) (FileInfo, error)
+	// List 列出目录下的文件及目录
+	List(path string) ([]FileInfo, error)
+	// Open 打开文件用于读取
+	Open(path string) (io.ReadSeekCloser, error)
+	// UploadLimit 写入 path 时允许的最大文件大小
+	UploadLimit(path string) (uint64, error)
+	// StoreFile 将本机暂存的文件写入 path，文件已存在时覆盖
+	StoreFile(path string, f *os.File) error
+	// MakeDir 创建目录
+	MakeDir(path string) error
+	// Remove 删除文件
+	Remove(path string) error
+	// RemoveDir 删除空目录
+	RemoveDir(path string) error
+	// Rename 重命名或移动文件及目录
+	Rename(from, to string) error
+}
+
+// Settings 服务配置
+type Settings struct {
+	// Listen 监听地址
+	Listen string
+	// HostKeys 主机密钥
+	HostKeys []ssh.Signer
+	// TempFile 创建暂存上传内容的临时文件，为空时使用系统临时目录
+	TempFile func() (*os.File, error)
+	// IdleTimeout 连接的空闲超时
+	IdleTimeout time.Duration
+}
+
+// Server SFTP 服务
+type Server struct {
+	settings *Settings
+	driver   Driver
+
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	closed   bool
+}
+
+// NewServer 新建 SFTP 服务
+func NewServer(settings *Settings, driver Driver) *Server {
+	if settings.TempFile == nil {
+		settings.TempFile = func() (*os.File, error) {
+			return ioutil.TempFile("", "sftp_")
+		}
+	}
+
+	return &Server{
+		settings: settings,
+		driver:   driver,
+		conns:    make(map[net.Conn]struct{}),
+	}
+}
+
+// ListenAndServe 监听 Settings.Listen 并处理连接，Shutdown 后返回 ErrServerClosed
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.settings.Listen)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve 处理 l 上的连接
+func (s *Server) Serve(l net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		l.Close()
+		return ErrServerClosed
+	}
+	s.listener = l
+	s.mu.Unlock()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return ErrServerClosed
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Temporary() {
+				time.Sleep(100 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			conn.Close()
+			return ErrServerClosed
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			s.handleConn(conn)
+			conn.Close()
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// Shutdown 停止监听并断开所有连接
+func (s *Server) Shutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		conn.Close()
+	}
+	return err
+}
+
+// idleConn 每次读写时延长超时时间的连接
+type idleConn struct {
+	net.Conn
+	timeout time.Duration
+}
+
+func (c *idleConn) Read(b []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Read(b)
+}
+
+func (c *idleConn) Write(b []byte) (int, error) {
+	c.Conn.SetDeadline(time.Now().Add(c.timeout))
+	return c.Conn.Write(b)
+}
+
+// handleConn 完成 SSH 握手及认证，并处理连接上的会话
+func (s *Server) handleConn(conn net.Conn) {
+	// 公钥认证时客户端可能先询问公钥是否可用，认证成功前不能确定使用的是哪个驱动，
+	// 因此暂存每次校验通过的驱动，认证完成后按扩展字段取出
+	pending := make(map[string]ClientDriver)
+	accept := func(driver ClientDriver, err error) (*ssh.Permissions, error) {
+		if err != nil {
+			time.Sleep(loginFailureDelay)
+			return nil, err
+		}
+		id := strconv.Itoa(len(pending))
+		pending[id] = driver
+		return &ssh.Permissions{Extensions: map[string]string{driverExtension: id}}, nil
+	}
+
+	config := &ssh.ServerConfig{
+		MaxAuthTries:  6,
+		ServerVersion: "SSH-2.0-Cloudreve",
+		PasswordCallback: func(meta ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			return accept(s.driver.PasswordLogin(meta.User(), string(password), meta.RemoteAddr()))
+		},
+		PublicKeyCallback: func(meta ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			return accept(s.driver.PublicKeyLogin(meta.User(), key, meta.RemoteAddr()))
+		},
+	}
+	for _, key := range s.settings.HostKeys {
+		config.AddHostKey(key)
+	}
+
+	if s.settings.IdleTimeout > 0 {
+		conn = &idleConn{Conn: conn, timeout: s.settings.IdleTimeout}
+	}
+
+	// 限制握手及认证的总时长
+	timer := time.AfterFunc(handshakeTimeout, func() { conn.Close() })
+	sconn, chans, reqs, err := ssh.NewServerConn(conn, config)
+	timer.Stop()
+	if err != nil {
+		return
+	}
+	defer sconn.Close()
+
+	driver, ok := pending[sconn.Permissions.Extensions[driverExtension]]
+	if !ok {
+		return
+	}
+	s.driver.LoggedIn(driver, sconn.RemoteAddr())
+
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "unknown channel type")
+			continue
+		}
+
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+		go s.handleSession(channel, requests, driver)
+	}
+}
+
+// handleSession 处理会话通道，只支持 sftp 子系统
+func (s *Server) handleSession(channel ssh.Channel, requests <-chan *ssh.Request, driver ClientDriver) {
+	defer channel.Close()
+
+	for req := range requests {
+		var payload struct{ Name string }
+		if req.Type != "subsystem" || ssh.Unmarshal(req.Payload, &payload) != nil || payload.Name != "sftp" {
+			req.Reply(false, nil)
+			continue
+		}
+
+		req.Reply(true, nil)
+		go ssh.DiscardRequests(requests)
+
+		newSession(channel, driver, s.settings).serve()
+		channel.SendRequest("exit-status", false, ssh.Marshal(struct{ Status uint32 }{0}))
+		return
+	}
+}
@@ -0,0 +1,439 @@
+package sftp
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+// memFile 内存中的文件或目录
+type memFile struct {
+	name    string
+	content []byte
+	dir     bool
+}
+
+func (f *memFile) GetName() string    { return f.name }
+func (f *memFile) GetSize() uint64    { return uint64(len(f.content)) }
+func (f *memFile) ModTime() time.Time { return time.Date(2021, 5, 1, 8, 30, 0, 0, time.UTC) }
+func (f *memFile) IsDir() bool        { return f.dir }
+
+// memDriver 内存中的文件系统
+type memDriver struct {
+	mu       sync.Mutex
+	files    map[string]*memFile
+	key      ssh.PublicKey
+	loggedIn int
+}
+
+func newMemDriver() *memDriver {
+	return &memDriver{files: map[string]*memFile{"/": {name: "/", dir: true}}}
+}
+
+func (d *memDriver) PasswordLogin(user, pass string, addr net.Addr) (ClientDriver, error) {
+	if user != "admin@cloudreve.org" || pass != "secret" {
+		return nil, ErrLoginFailed
+	}
+	return d, nil
+}
+
+func (d *memDriver) PublicKeyLogin(user string, key ssh.PublicKey, addr net.Addr) (ClientDriver, error) {
+	if user != "admin@cloudreve.org" || d.key == nil || !bytes.Equal(key.Marshal(), d.key.Marshal()) {
+		return nil, ErrLoginFailed
+	}
+	return d, nil
+}
+
+func (d *memDriver) LoggedIn(driver ClientDriver, addr net.Addr) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loggedIn++
+}
+
+func (d *memDriver) Readonly() bool {
+	return false
+}
+
+func (d *memDriver) Stat(p string) (FileInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[p]; ok {
+		return f, nil
+	}
+	return nil, ErrNotExist
+}
+
+func (d *memDriver) List(p string) ([]FileInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var res []FileInfo
+	for name, f := range d.files {
+		if name != "/" && path.Dir(name) == p {
+			res = append(res, f)
+		}
+	}
+	return res, nil
+}
+
+// memReader 读取内存中的文件
+type memReader struct {
+	*bytes.Reader
+}
+
+func (r memReader) Close() error { return nil }
+
+func (d *memDriver) Open(p string) (io.ReadSeekCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[p]
+	if !ok || f.dir {
+		return nil, ErrNotExist
+	}
+	return memReader{bytes.NewReader(f.content)}, nil
+}
+
+func (d *memDriver) UploadLimit(p string) (uint64, error) {
+	return 1024, nil
+}
+
+func (d *memDriver) StoreFile(p string, f *os.File) error {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	content, err := ioutil.ReadAll(f)
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.files[p] = &memFile{name: path.Base(p), content: content}
+	return nil
+}
+
+func (d *memDriver) MakeDir(p string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.files[p]; ok {
+		return ErrExist
+	}
+	d.files[p] = &memFile{name: path.Base(p), dir: true}
+	return nil
+}
+
+func (d *memDriver) Remove(p string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if f, ok := d.files[p]; !ok || f.dir {
+		return ErrNotExist
+	}
+	delete(d.files, p)
+	return nil
+}
+
+func (d *memDriver) RemoveDir(p string) error {
+	return ErrPermissionDenied
+}
+
+func (d *memDriver) Rename(from, to string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	f, ok := d.files[from]
+	if !ok {
+		return ErrNotExist
+	}
+	delete(d.files, from)
+	f.name = path.Base(to)
+	d.files[to] = f
+	return nil
+}
+
+// testClient 测试用的 SFTP 客户端，直接收发数据包
+type testClient struct {
+	t       *testing.T
+	client  *ssh.Client
+	session *ssh.Session
+	stdin   io.WriteCloser
+	stdout  io.Reader
+	id      uint32
+}
+
+// request 发送请求并返回响应的类型及内容，内容不含请求 ID
+func (c *testClient) request(typ byte, b buffer) (byte, packet) {
+	c.id++
+	b = append(buffer(nil).uint32(c.id), b...)
+	if typ == fxpInit {
+		b = b[4:]
+	}
+	_, err := c.stdin.Write(append(append(buffer(nil).uint32(uint32(len(b)+1)), typ), b...))
+	assert.NoError(c.t, err)
+
+	header := make([]byte, 4)
+	_, err = io.ReadFull(c.stdout, header)
+	assert.NoError(c.t, err)
+	data := make([]byte, binary.BigEndian.Uint32(header))
+	_, err = io.ReadFull(c.stdout, data)
+	assert.NoError(c.t, err)
+
+	p := packet(data[1:])
+	if data[0] != fxpVersion {
+		id, _ := p.uint32()
+		assert.Equal(c.t, c.id, id)
+	}
+	return data[0], p
+}
+
+// status 发送请求并返回状态码
+func (c *testClient) status(typ byte, b buffer) uint32 {
+	resType, p := c.request(typ, b)
+	assert.EqualValues(c.t, fxpStatus, resType)
+	code, _ := p.uint32()
+	return code
+}
+
+// handle 发送请求并返回句柄
+func (c *testClient) handle(typ byte, b buffer) string {
+	resType, p := c.request(typ, b)
+	assert.EqualValues(c.t, fxpHandle, resType)
+	handle, _ := p.string()
+	return handle
+}
+
+func newHostKey(t *testing.T) ssh.Signer {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.NoError(t, err)
+	return signer
+}
+
+func startTestServer(t *testing.T, driver *memDriver) (*Server, string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(&Settings{HostKeys: []ssh.Signer{newHostKey(t)}}, driver)
+	go server.Serve(l)
+	t.Cleanup(func() { server.Shutdown() })
+	return server, l.Addr().String()
+}
+
+func dial(t *testing.T, addr string, auth ssh.AuthMethod) (*ssh.Client, error) {
+	return ssh.Dial("tcp", addr, &ssh.ClientConfig{
+		User:            "admin@cloudreve.org",
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         5 * time.Second,
+	})
+}
+
+func newTestClient(t *testing.T, addr string) *testClient {
+	client, err := dial(t, addr, ssh.Password("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin, _ := session.StdinPipe()
+	stdout, _ := session.StdoutPipe()
+	if err := session.RequestSubsystem("sftp"); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testClient{t: t, client: client, session: session, stdin: stdin, stdout: stdout}
+	typ, p := c.request(fxpInit, buffer(nil).uint32(protocolVersion))
+	assert.EqualValues(t, fxpVersion, typ)
+	version, _ := p.uint32()
+	assert.EqualValues(t, protocolVersion, version)
+	return c
+}
+
+func TestSession(t *testing.T) {
+	asserts := assert.New(t)
+	driver := newMemDriver()
+	_, addr := startTestServer(t, driver)
+	c := newTestClient(t, addr)
+	defer c.client.Close()
+
+	// 相对路径以根目录为起点
+	typ, p := c.request(fxpRealpath, buffer(nil).string("."))
+	asserts.EqualValues(fxpName, typ)
+	count, _ := p.uint32()
+	name, _ := p.string()
+	asserts.EqualValues(1, count)
+	asserts.Equal("/", name)
+
+	asserts.EqualValues(fxOK, c.status(fxpMkdir, buffer(nil).string("docs").uint32(0)))
+	asserts.EqualValues(fxFailure, c.status(fxpMkdir, buffer(nil).string("/docs").uint32(0)))
+
+	// 写入文件，关闭后上传
+	handle := c.handle(fxpOpen, buffer(nil).string("/docs/a.txt").uint32(openWrite|openCreate|openTrunc).uint32(0))
+	asserts.EqualValues(fxOK, c.status(fxpWrite, buffer(nil).string(handle).uint64(0).bytes([]byte("hello"))))
+	asserts.EqualValues(fxOK, c.status(fxpWrite, buffer(nil).string(handle).uint64(5).bytes([]byte(" world"))))
+	_, err := driver.Stat("/docs/a.txt")
+	asserts.Equal(ErrNotExist, err)
+	asserts.EqualValues(fxOK, c.status(fxpClose, buffer(nil).string(handle)))
+	asserts.Equal("hello world", string(driver.files["/docs/a.txt"].content))
+
+	// 超出容量
+	handle = c.handle(fxpOpen, buffer(nil).string("/docs/big").uint32(openWrite|openCreate).uint32(0))
+	asserts.EqualValues(fxFailure, c.status(fxpWrite, buffer(nil).string(handle).uint64(1020).bytes([]byte("12345"))))
+	asserts.EqualValues(fxOK, c.status(fxpClose, buffer(nil).string(handle)))
+	asserts.Len(driver.files["/docs/big"].content, 0)
+
+	// 已存在时不能独占创建，不存在时须指定创建
+	asserts.EqualValues(fxFailure, c.status(fxpOpen, buffer(nil).string("/docs/a.txt").uint32(openWrite|openCreate|openExcl).uint32(0)))
+	asserts.EqualValues(fxNoSuchFile, c.status(fxpOpen, buffer(nil).string("/docs/b.txt").uint32(openWrite).uint32(0)))
+
+	// 追加时保留原有内容
+	handle = c.handle(fxpOpen, buffer(nil).string("/docs/a.txt").uint32(openWrite|openAppend).uint32(0))
+	asserts.EqualValues(fxOK, c.status(fxpWrite, buffer(nil).string(handle).uint64(0).bytes([]byte("!"))))
+	asserts.EqualValues(fxOK, c.status(fxpClose, buffer(nil).string(handle)))
+	asserts.Equal("hello world!", string(driver.files["/docs/a.txt"].content))
+
+	// 属性
+	typ, p = c.request(fxpStat, buffer(nil).string("/docs/a.txt"))
+	asserts.EqualValues(fxpAttrs, typ)
+	size, hasSize, err := p.attrs()
+	asserts.NoError(err)
+	asserts.True(hasSize)
+	asserts.EqualValues(12, size)
+	asserts.EqualValues(fxNoSuchFile, c.status(fxpLstat, buffer(nil).string("/missing")))
+
+	// 从任意位置读取
+	handle = c.handle(fxpOpen, buffer(nil).string("/docs/a.txt").uint32(openRead).uint32(0))
+	typ, p = c.request(fxpRead, buffer(nil).string(handle).uint64(6).uint32(5))
+	asserts.EqualValues(fxpData, typ)
+	data, _ := p.bytes()
+	asserts.Equal("world", string(data))
+	typ, p = c.request(fxpRead, buffer(nil).string(handle).uint64(0).uint32(100))
+	asserts.EqualValues(fxpData, typ)
+	data, _ = p.bytes()
+	asserts.Equal("hello world!", string(data))
+	asserts.EqualValues(fxEOF, c.status(fxpRead, buffer(nil).string(handle).uint64(12).uint32(100)))
+	asserts.EqualValues(fxPermissionDenied, c.status(fxpWrite, buffer(nil).string(handle).uint64(0).bytes([]byte("x"))))
+	asserts.EqualValues(fxOK, c.status(fxpClose, buffer(nil).string(handle)))
+	asserts.EqualValues(fxFailure, c.status(fxpClose, buffer(nil).string(handle)))
+
+	// 列出目录
+	asserts.EqualValues(fxNoSuchFile, c.status(fxpOpendir, buffer(nil).string("/docs/a.txt")))
+	handle = c.handle(fxpOpendir, buffer(nil).string("/docs"))
+	typ, p = c.request(fxpReaddir, buffer(nil).string(handle))
+	asserts.EqualValues(fxpName, typ)
+	count, _ = p.uint32()
+	asserts.EqualValues(2, count)
+	asserts.EqualValues(fxEOF, c.status(fxpReaddir, buffer(nil).string(handle)))
+	asserts.EqualValues(fxOK, c.status(fxpClose, buffer(nil).string(handle)))
+
+	// 重命名及删除
+	asserts.EqualValues(fxOK, c.status(fxpRename, buffer(nil).string("/docs/a.txt").string("/docs/c.txt")))
+	asserts.EqualValues(fxOK, c.status(fxpExtended, buffer(nil).string(posixRenameExt).string("/docs/c.txt").string("/c.txt")))
+	asserts.EqualValues(fxOK, c.status(fxpRemove, buffer(nil).string("/c.txt")))
+	asserts.EqualValues(fxNoSuchFile, c.status(fxpRemove, buffer(nil).string("/c.txt")))
+	asserts.EqualValues(fxPermissionDenied, c.status(fxpRmdir, buffer(nil).string("/docs")))
+
+	// 不支持的操作
+	asserts.EqualValues(fxOpUnsupported, c.status(19, buffer(nil).string("/docs")))
+	asserts.EqualValues(fxOpUnsupported, c.status(fxpExtended, buffer(nil).string("statvfs@openssh.com").string("/")))
+}
+
+func TestSession_AbortedUpload(t *testing.T) {
+	asserts := assert.New(t)
+	driver := newMemDriver()
+	server, addr := startTestServer(t, driver)
+	c := newTestClient(t, addr)
+
+	handle := c.handle(fxpOpen, buffer(nil).string("/a.txt").uint32(openWrite|openCreate).uint32(0))
+	asserts.EqualValues(fxOK, c.status(fxpWrite, buffer(nil).string(handle).uint64(0).bytes([]byte("partial"))))
+	c.client.Close()
+
+	// 连接断开后未关闭的上传不会写入
+	asserts.Eventually(func() bool {
+		server.mu.Lock()
+		defer server.mu.Unlock()
+		return len(server.conns) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err := driver.Stat("/a.txt")
+	asserts.Equal(ErrNotExist, err)
+}
+
+func TestServer_Auth(t *testing.T) {
+	asserts := assert.New(t)
+	driver := newMemDriver()
+	_, addr := startTestServer(t, driver)
+
+	// 密码错误
+	_, err := dial(t, addr, ssh.Password("wrong"))
+	asserts.Error(err)
+
+	// 公钥登录
+	signer := newHostKey(t)
+	driver.key = signer.PublicKey()
+	client, err := dial(t, addr, ssh.PublicKeys(signer))
+	asserts.NoError(err)
+	client.Close()
+
+	// 未添加的公钥
+	_, err = dial(t, addr, ssh.PublicKeys(newHostKey(t)))
+	asserts.Error(err)
+
+	asserts.Eventually(func() bool {
+		driver.mu.Lock()
+		defer driver.mu.Unlock()
+		return driver.loggedIn == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// 只支持 sftp 子系统
+	client, err = dial(t, addr, ssh.Password("secret"))
+	asserts.NoError(err)
+	defer client.Close()
+	session, err := client.NewSession()
+	asserts.NoError(err)
+	asserts.Error(session.Run("ls"))
+}
+
+func TestServer_Shutdown(t *testing.T) {
+	server := NewServer(&Settings{Listen: "127.0.0.1:0"}, newMemDriver())
+	assert.NoError(t, server.Shutdown())
+	assert.Equal(t, ErrServerClosed, server.ListenAndServe())
+}
+
+func TestPacket_Attrs(t *testing.T) {
+	asserts := assert.New(t)
+
+	p := packet(buffer(nil).uint32(attrSize | attrUIDGID | attrPermissions | attrACModTime | attrExtended).
+		uint64(42).uint32(0).uint32(0).uint32(0644).uint32(1).uint32(2).
+		uint32(1).string("name").string("value"))
+	size, hasSize, err := p.attrs()
+	asserts.NoError(err)
+	asserts.True(hasSize)
+	asserts.EqualValues(42, size)
+	asserts.Len(p, 0)
+
+	p = packet(buffer(nil).uint32(attrPermissions))
+	_, _, err = p.attrs()
+	asserts.Equal(errBadMessage, err)
+}
+
+func TestLongName(t *testing.T) {
+	file := &memFile{name: "a.txt", content: []byte("hello")}
+	dir := &memFile{name: "docs", dir: true}
+
+	assert.Equal(t, "-rw-r--r-- 1 cloudreve cloudreve 5 May  1  2021 a.txt", longName(file, false))
+	assert.Equal(t, "-r--r--r-- 1 cloudreve cloudreve 5 May  1  2021 a.txt", longName(file, true))
+	assert.Equal(t, "dr-xr-xr-x 1 cloudreve cloudreve 0 May  1  2021 docs", longName(dir, true))
+}
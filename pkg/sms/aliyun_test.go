@@ -0,0 +1,53 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAliyun_Send(t *testing.T) {
+	asserts := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asserts.NoError(r.ParseForm())
+		asserts.Equal("SendSms", r.PostForm.Get("Action"))
+		asserts.Equal("ak", r.PostForm.Get("AccessKeyId"))
+		asserts.NotEmpty(r.PostForm.Get("Signature"))
+		switch r.PostForm.Get("PhoneNumbers") {
+		case "13800138000":
+			asserts.Equal("Cloudreve", r.PostForm.Get("SignName"))
+			asserts.Equal("SMS_1", r.PostForm.Get("TemplateCode"))
+			asserts.JSONEq(`{"code":"123456","ttl":"5"}`, r.PostForm.Get("TemplateParam"))
+			w.Write([]byte(`{"Code":"OK","Message":"OK"}`))
+		case "85291234567":
+			w.Write([]byte(`{"Code":"isv.BUSINESS_LIMIT_CONTROL","Message":"limited"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	provider := NewAliyun("ak", "sk", "Cloudreve")
+	provider.Endpoint = server.URL
+	msg := &Message{Template: "SMS_1", Params: []Param{{"code", "123456"}, {"ttl", "5"}}}
+
+	// 中国大陆号码
+	asserts.NoError(provider.Send(context.Background(), "+8613800138000", msg))
+
+	// 服务商返回错误
+	asserts.Error(provider.Send(context.Background(), "+85291234567", msg))
+
+	// 无法解析的响应
+	asserts.Error(provider.Send(context.Background(), "+14155552671", msg))
+
+	// 未设置模板
+	asserts.Equal(ErrTemplateNotSet, provider.Send(context.Background(), "+8613800138000", &Message{}))
+}
+
+func TestAliyunEncode(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("a%20b%2A~%2F", aliyunEncode("a b*~/"))
+}
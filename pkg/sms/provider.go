@@ -0,0 +1,56 @@
+package sms
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrUnknownProvider 未知的短信服务
+	ErrUnknownProvider = errors.New("unknown sms provider")
+	// ErrInvalidPhone 无效的手机号码
+	ErrInvalidPhone = errors.New("invalid phone number")
+	// ErrTemplateNotSet 未设置短信模板
+	ErrTemplateNotSet = errors.New("sms template is not set")
+)
+
+// e164 E.164 格式的手机号码
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// Param 短信模板参数
+type Param struct {
+	Name  string
+	Value string
+}
+
+// Message 待发送的短信
+type Message struct {
+	Template string  // 服务商的模板 ID，阿里云及腾讯云使用
+	Params   []Param // 模板参数，按模板中出现的顺序排列
+	Text     string  // 完整的短信正文，Twilio 使用
+}
+
+// Provider 短信服务
+type Provider interface {
+	// Name 短信服务名称
+	Name() string
+	// Send 向 E.164 格式的手机号码发送短信
+	Send(ctx context.Context, phone string, msg *Message) error
+}
+
+// NormalizePhone 将手机号码转换为 E.164 格式，未带国家代码的 11 位号码视为中国大陆号码
+func NormalizePhone(phone string) (string, error) {
+	phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(phone)
+	if strings.HasPrefix(phone, "00") {
+		phone = "+" + phone[2:]
+	} else if !strings.HasPrefix(phone, "+") && len(phone) == 11 && phone[0] == '1' {
+		phone = "+86" + phone
+	}
+
+	if !e164.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
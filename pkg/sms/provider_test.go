@@ -0,0 +1,28 @@
+package sms
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	asserts := assert.New(t)
+	testCases := map[string]string{
+		"13800138000":       "+8613800138000",
+		"138-0013-8000":     "+8613800138000",
+		"+86 138 0013 8000": "+8613800138000",
+		"0085291234567":     "+85291234567",
+		"+1 (415) 555-2671": "+14155552671",
+	}
+	for input, expected := range testCases {
+		phone, err := NormalizePhone(input)
+		asserts.NoError(err, input)
+		asserts.Equal(expected, phone)
+	}
+
+	for _, input := range []string{"", "12345", "4155552671", "+0123456789", "+86abc13800138000"} {
+		_, err := NormalizePhone(input)
+		asserts.Equal(ErrInvalidPhone, err, input)
+	}
+}
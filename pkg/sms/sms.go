@@ -0,0 +1,105 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// sendTimeout 发送单条短信的超时时间
+const sendTimeout = 15 * time.Second
+
+// Enabled 站点是否配置了短信服务
+func Enabled() bool {
+	return model.GetSettingByName("sms_provider") != ""
+}
+
+// IsSupportedProvider 是否为支持的短信服务
+func IsSupportedProvider(name string) bool {
+	switch name {
+	case "aliyun", "tencent", "twilio":
+		return true
+	}
+	return false
+}
+
+// NewProvider 根据站点设置创建短信服务
+func NewProvider() (Provider, error) {
+	options := model.GetSettingByNames(
+		"sms_provider", "sms_access_key", "sms_secret_key", "sms_sign_name", "sms_app_id", "sms_region", "sms_from",
+	)
+
+	switch options["sms_provider"] {
+	case "aliyun":
+		return NewAliyun(options["sms_access_key"], options["sms_secret_key"], options["sms_sign_name"]), nil
+	case "tencent":
+		return NewTencent(
+			options["sms_access_key"],
+			options["sms_secret_key"],
+			options["sms_app_id"],
+			options["sms_sign_name"],
+			options["sms_region"],
+		), nil
+	case "twilio":
+		return NewTwilio(options["sms_access_key"], options["sms_secret_key"], options["sms_from"]), nil
+	}
+
+	return nil, ErrUnknownProvider
+}
+
+// Send 使用站点配置的短信服务发送短信
+func Send(phone string, msg *Message) error {
+	provider, err := NewProvider()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sendTimeout)
+	defer cancel()
+	if err := provider.Send(ctx, phone, msg); err != nil {
+		return fmt.Errorf("failed to send sms via %s: %w", provider.Name(), err)
+	}
+
+	util.Log().Debug("短信已发送至 %s", phone)
+	return nil
+}
+
+// newMessage 按模板设置构建短信，阿里云及腾讯云的模板为模板 ID，Twilio 的模板为短信正文，
+// 未设置时使用默认正文
+func newMessage(templateSetting, defaultText string, params []Param) *Message {
+	options := model.GetSettingByNames("siteName", templateSetting)
+	replace := map[string]string{"{siteName}": options["siteName"]}
+	for _, p := range params {
+		replace["{"+p.Name+"}"] = p.Value
+	}
+
+	text := options[templateSetting]
+	if text == "" {
+		text = defaultText
+	}
+
+	return &Message{
+		Template: options[templateSetting],
+		Params:   params,
+		Text:     util.Replace(replace, text),
+	}
+}
+
+// NewVerificationMessage 新建验证码短信，模板参数依次为验证码及有效分钟数
+func NewVerificationMessage(code string, ttl time.Duration) *Message {
+	return newMessage("sms_verify_template", "【{siteName}】您的验证码为 {code}，{ttl} 分钟内有效。", []Param{
+		{Name: "code", Value: code},
+		{Name: "ttl", Value: strconv.Itoa(int(ttl.Minutes()))},
+	})
+}
+
+// NewAlertMessage 新建重要事件提醒短信，模板参数为提醒标题
+func NewAlertMessage(title string) *Message {
+	return newMessage("sms_alert_template", "【{siteName}】{title}", []Param{
+		{Name: "title", Value: title},
+	})
+}
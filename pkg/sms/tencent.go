@@ -0,0 +1,163 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const tencentContentType = "application/json; charset=utf-8"
+
+// Tencent 腾讯云短信服务
+type Tencent struct {
+	SecretID  string
+	SecretKey string
+	AppID     string // 短信应用的 SdkAppId
+	SignName  string // 短信签名
+	Region    string
+	Endpoint  string
+	Client    *http.Client
+}
+
+// tencentRequest 发送短信的请求正文
+type tencentRequest struct {
+	PhoneNumberSet   []string `json:"PhoneNumberSet"`
+	SmsSdkAppID      string   `json:"SmsSdkAppId"`
+	SignName         string   `json:"SignName"`
+	TemplateID       string   `json:"TemplateId"`
+	TemplateParamSet []string `json:"TemplateParamSet"`
+}
+
+// tencentResponse 发送短信的响应
+type tencentResponse struct {
+	Response struct {
+		Error *struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"Error"`
+		SendStatusSet []struct {
+			Code    string `json:"Code"`
+			Message string `json:"Message"`
+		} `json:"SendStatusSet"`
+	} `json:"Response"`
+}
+
+// NewTencent 创建腾讯云短信服务
+func NewTencent(secretID, secretKey, appID, signName, region string) *Tencent {
+	if region == "" {
+		region = "ap-guangzhou"
+	}
+
+	return &Tencent{
+		SecretID:  secretID,
+		SecretKey: secretKey,
+		AppID:     appID,
+		SignName:  signName,
+		Region:    region,
+		Endpoint:  "https://sms.tencentcloudapi.com",
+		Client:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 短信服务名称
+func (t *Tencent) Name() string {
+	return "tencent"
+}
+
+// Send 发送模板短信
+func (t *Tencent) Send(ctx context.Context, phone string, msg *Message) error {
+	if msg.Template == "" {
+		return ErrTemplateNotSet
+	}
+
+	body := tencentRequest{
+		PhoneNumberSet:   []string{phone},
+		SmsSdkAppID:      t.AppID,
+		SignName:         t.SignName,
+		TemplateID:       msg.Template,
+		TemplateParamSet: make([]string, 0, len(msg.Params)),
+	}
+	for _, p := range msg.Params {
+		body.TemplateParamSet = append(body.TemplateParamSet, p.Value)
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.Endpoint+"/", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", tencentContentType)
+	req.Header.Set("X-TC-Action", "SendSms")
+	req.Header.Set("X-TC-Version", "2021-01-11")
+	req.Header.Set("X-TC-Region", t.Region)
+	req.Header.Set("X-TC-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("Authorization", t.authorization(req.URL.Host, payload, timestamp))
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res tencentResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("failed to decode tencent sms response (status %d): %w", resp.StatusCode, err)
+	}
+
+	if res.Response.Error != nil {
+		return fmt.Errorf("tencent sms request failed: %s %s", res.Response.Error.Code, res.Response.Error.Message)
+	}
+
+	for _, status := range res.Response.SendStatusSet {
+		if status.Code != "Ok" {
+			return fmt.Errorf("tencent sms request failed: %s %s", status.Code, status.Message)
+		}
+	}
+
+	return nil
+}
+
+// authorization 计算 TC3-HMAC-SHA256 签名
+func (t *Tencent) authorization(host string, payload []byte, timestamp int64) string {
+	date := time.Unix(timestamp, 0).UTC().Format("2006-01-02")
+	scope := date + "/sms/tc3_request"
+	signedHeaders := "content-type;host"
+
+	canonicalRequest := "POST\n/\n\n" +
+		"content-type:" + tencentContentType + "\nhost:" + host + "\n\n" +
+		signedHeaders + "\n" + sha256Hex(payload)
+	stringToSign := "TC3-HMAC-SHA256\n" + strconv.FormatInt(timestamp, 10) + "\n" + scope + "\n" +
+		sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("TC3"+t.SecretKey), date)
+	key = hmacSHA256(key, "sms")
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	return fmt.Sprintf("TC3-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		t.SecretID, scope, signedHeaders, signature)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
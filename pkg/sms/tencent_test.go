@@ -0,0 +1,50 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTencent_Send(t *testing.T) {
+	asserts := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		asserts.True(strings.HasPrefix(r.Header.Get("Authorization"), "TC3-HMAC-SHA256 Credential=id/"))
+		asserts.Contains(r.Header.Get("Authorization"), "/sms/tc3_request, SignedHeaders=content-type;host, Signature=")
+		asserts.Equal("SendSms", r.Header.Get("X-TC-Action"))
+		asserts.Equal("ap-guangzhou", r.Header.Get("X-TC-Region"))
+
+		var body tencentRequest
+		asserts.NoError(json.NewDecoder(r.Body).Decode(&body))
+		asserts.Equal("1400000000", body.SmsSdkAppID)
+		asserts.Equal("1", body.TemplateID)
+		asserts.Equal([]string{"123456", "5"}, body.TemplateParamSet)
+		switch body.PhoneNumberSet[0] {
+		case "+8613800138000":
+			w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"Ok","Message":"send success"}]}}`))
+		case "+8613800138001":
+			w.Write([]byte(`{"Response":{"SendStatusSet":[{"Code":"LimitExceeded.PhoneNumberDailyLimit","Message":"limited"}]}}`))
+		default:
+			w.Write([]byte(`{"Response":{"Error":{"Code":"AuthFailure.SignatureFailure","Message":"invalid"}}}`))
+		}
+	}))
+	defer server.Close()
+
+	provider := NewTencent("id", "key", "1400000000", "Cloudreve", "")
+	provider.Endpoint = server.URL
+	msg := &Message{Template: "1", Params: []Param{{"code", "123456"}, {"ttl", "5"}}}
+
+	// 成功
+	asserts.NoError(provider.Send(context.Background(), "+8613800138000", msg))
+
+	// 单个号码发送失败
+	asserts.Error(provider.Send(context.Background(), "+8613800138001", msg))
+
+	// 请求失败
+	asserts.Error(provider.Send(context.Background(), "+14155552671", msg))
+}
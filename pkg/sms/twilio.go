@@ -0,0 +1,83 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Twilio Twilio 短信服务
+type Twilio struct {
+	AccountSID string
+	AuthToken  string
+	From       string // 发送号码或 Messaging Service SID
+	Endpoint   string
+	Client     *http.Client
+}
+
+// twilioError Twilio 接口错误
+type twilioError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewTwilio 创建 Twilio 短信服务
+func NewTwilio(accountSID, authToken, from string) *Twilio {
+	return &Twilio{
+		AccountSID: accountSID,
+		AuthToken:  authToken,
+		From:       from,
+		Endpoint:   "https://api.twilio.com",
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Name 短信服务名称
+func (t *Twilio) Name() string {
+	return "twilio"
+}
+
+// Send 发送短信正文
+func (t *Twilio) Send(ctx context.Context, phone string, msg *Message) error {
+	if msg.Text == "" {
+		return ErrTemplateNotSet
+	}
+
+	form := url.Values{
+		"To":   {phone},
+		"Body": {msg.Text},
+	}
+	if strings.HasPrefix(t.From, "MG") {
+		form.Set("MessagingServiceSid", t.From)
+	} else {
+		form.Set("From", t.From)
+	}
+
+	target := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.Endpoint, url.PathEscape(t.AccountSID))
+	req, err := http.NewRequestWithContext(ctx, "POST", target, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.AccountSID, t.AuthToken)
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	var res twilioError
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("twilio request failed with status %d", resp.StatusCode)
+	}
+	return fmt.Errorf("twilio request failed: %d %s", res.Code, res.Message)
+}
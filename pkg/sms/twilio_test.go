@@ -0,0 +1,42 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTwilio_Send(t *testing.T) {
+	asserts := assert.New(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, password, ok := r.BasicAuth()
+		asserts.True(ok)
+		asserts.Equal("AC123", user)
+		asserts.Equal("token", password)
+		asserts.Equal("/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		asserts.NoError(r.ParseForm())
+		asserts.Equal("验证码 123456", r.PostForm.Get("Body"))
+
+		if r.PostForm.Get("To") == "+14155552671" {
+			asserts.Equal("+15005550006", r.PostForm.Get("From"))
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"sid":"SM123"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+	}))
+	defer server.Close()
+
+	provider := NewTwilio("AC123", "token", "+15005550006")
+	provider.Endpoint = server.URL
+	msg := &Message{Text: "验证码 123456"}
+
+	asserts.NoError(provider.Send(context.Background(), "+14155552671", msg))
+	asserts.Error(provider.Send(context.Background(), "+15005550001", msg))
+	asserts.Equal(ErrTemplateNotSet, provider.Send(context.Background(), "+14155552671", &Message{}))
+}
@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"errors"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// 用量报告周期
+const (
+	ReportWeekly  = "weekly"
+	ReportMonthly = "monthly"
+)
+
+// ErrUnknownPeriod 未知的报告周期
+var ErrUnknownPeriod = errors.New("unknown report period")
+
+// ReportRange 返回 now 所在周期的上一个完整周期 [from, to)，周报以周一为一周的开始
+func ReportRange(period string, now time.Time) (from, to time.Time, err error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch period {
+	case ReportWeekly:
+		to = today.AddDate(0, 0, -(int(today.Weekday())+6)%7)
+		return to.AddDate(0, 0, -7), to, nil
+	case ReportMonthly:
+		to = today.AddDate(0, 0, 1-today.Day())
+		return to.AddDate(0, -1, 0), to, nil
+	}
+
+	return from, to, ErrUnknownPeriod
+}
+
+// BuildReport 根据每日统计汇总 [from, to) 期间的站点用量报告。任务状态定义于任务包中，
+// 由调用方通过 failedStatus 给出视为失败的任务状态，为空时不统计失败任务
+func BuildReport(period string, from, to time.Time, failedStatus ...int) (*model.UsageReport, error) {
+	report := &model.UsageReport{Period: period, From: from, To: to}
+	first := from.Format(model.StatisticDateFormat)
+	last := to.AddDate(0, 0, -1).Format(model.StatisticDateFormat)
+
+	daily, err := model.ListStatistics(first, last)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, stat := range daily {
+		report.UploadTraffic += stat.UploadTraffic
+		report.DownloadTraffic += stat.DownloadTraffic
+		if stat.ActiveUsers > report.PeakActiveUsers {
+			report.PeakActiveUsers = stat.ActiveUsers
+		}
+	}
+
+	// 存储用量按周期开始前及结束时最近一次汇总的结果计算
+	before, err := model.GetStorageUsageAt(from.AddDate(0, 0, -1).Format(model.StatisticDateFormat))
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := model.GetStorageUsageAt(last)
+	if err != nil {
+		return nil, err
+	}
+
+	report.StorageBefore = before.TotalSize()
+	report.StorageAfter = after.TotalSize()
+	report.TotalUsers = after.TotalUsers()
+
+	if report.NewUsers, err = model.CountUsersCreated(from, to); err != nil {
+		return nil, err
+	}
+
+	limit := model.GetIntSetting("statistics_top_users", 10)
+	if report.TopDownloads, err = model.TopDownloadedShares(from, to, limit); err != nil {
+		return nil, err
+	}
+
+	if len(failedStatus) > 0 {
+		if report.FailedTasks, err = model.CountTasksByType(from, to, failedStatus...); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
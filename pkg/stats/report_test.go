@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportRange(t *testing.T) {
+	asserts := assert.New(t)
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+	}
+
+	testCases := []struct {
+		period   string
+		now      time.Time
+		from, to time.Time
+	}{
+		// 周一
+		{ReportWeekly, date(2022, 3, 7).Add(8 * time.Hour), date(2022, 2, 28), date(2022, 3, 7)},
+		// 周日
+		{ReportWeekly, date(2022, 3, 13).Add(23 * time.Hour), date(2022, 2, 28), date(2022, 3, 7)},
+		{ReportMonthly, date(2022, 3, 1).Add(8 * time.Hour), date(2022, 2, 1), date(2022, 3, 1)},
+		{ReportMonthly, date(2022, 1, 31), date(2021, 12, 1), date(2022, 1, 1)},
+	}
+
+	for _, tc := range testCases {
+		from, to, err := ReportRange(tc.period, tc.now)
+		asserts.NoError(err)
+		asserts.Equal(tc.from, from, tc.now)
+		asserts.Equal(tc.to, to, tc.now)
+	}
+
+	_, _, err := ReportRange("daily", time.Now())
+	asserts.Equal(ErrUnknownPeriod, err)
+}
+
+func TestBuildReport(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_statistics_top_users", "5", 0)
+	from := time.Date(2022, 2, 28, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(0, 0, 7)
+
+	// 无法列取每日统计
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-02-28", "2022-03-06").WillReturnError(errors.New("error"))
+		_, err := BuildReport(ReportWeekly, from, to)
+		asserts.Error(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-02-28", "2022-03-06").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "upload_traffic", "download_traffic", "active_users"}).
+				AddRow(1, 10, 20, 3).AddRow(2, 5, 5, 7))
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-02-27", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).AddRow(1, `{"policies":[{"id":1,"size":100}]}`))
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WithArgs("2022-03-06", "").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "storage"}).
+				AddRow(2, `{"policies":[{"id":1,"size":150}],"groups":[{"id":1,"users":4}]}`))
+		mock.ExpectQuery("SELECT count(.+)users(.+)").WithArgs(from, to).
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+		mock.ExpectQuery("SELECT(.+)share_access_logs(.+)LIMIT 5").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "downloads"}).AddRow(1, "a.txt", 9))
+		mock.ExpectQuery("SELECT type, count(.+)tasks(.+)").WithArgs(from, to, 2).
+			WillReturnRows(sqlmock.NewRows([]string{"type", "count"}).AddRow(1, 2))
+
+		report, err := BuildReport(ReportWeekly, from, to, 2)
+		asserts.NoError(err)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(&model.UsageReport{
+			Period:          ReportWeekly,
+			From:            from,
+			To:              to,
+			NewUsers:        2,
+			TotalUsers:      4,
+			UploadTraffic:   15,
+			DownloadTraffic: 25,
+			PeakActiveUsers: 7,
+			StorageBefore:   100,
+			StorageAfter:    150,
+			TopDownloads:    []model.ShareDownloads{{ID: 1, Name: "a.txt", Downloads: 9}},
+			FailedTasks:     []model.TaskCount{{Type: 1, Count: 2}},
+		}, report)
+	}
+}
@@ -0,0 +1,117 @@
+package stats
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 内存中累计、尚未写入数据库的流量及活跃用户
+var (
+	uploadTraffic   uint64
+	downloadTraffic uint64
+
+	activeUsers = make(map[uint]struct{})
+	activeLock  sync.Mutex
+)
+
+// AddUpload 记录上传流量
+func AddUpload(size uint64) {
+	atomic.AddUint64(&uploadTraffic, size)
+}
+
+// AddDownload 记录下载流量
+func AddDownload(size uint64) {
+	atomic.AddUint64(&downloadTraffic, size)
+}
+
+// Pending 返回尚未写入数据库的上传、下载流量
+func Pending() (upload, download uint64) {
+	return atomic.LoadUint64(&uploadTraffic), atomic.LoadUint64(&downloadTraffic)
+}
+
+// MarkActive 记录用户当日活跃
+func MarkActive(uid uint) {
+	activeLock.Lock()
+	activeUsers[uid] = struct{}{}
+	activeLock.Unlock()
+}
+
+// Flush 将内存中累计的流量及活跃用户写入当日的统计记录
+func Flush() error {
+	today := time.Now().Format(model.StatisticDateFormat)
+	stat, err := model.GetOrCreateStatistic(today)
+	if err != nil {
+		return err
+	}
+
+	// 写入失败时将流量加回，留待下次写入
+	upload := atomic.SwapUint64(&uploadTraffic, 0)
+	download := atomic.SwapUint64(&downloadTraffic, 0)
+	if upload > 0 || download > 0 {
+		if err := stat.AddTraffic(upload, download); err != nil {
+			AddUpload(upload)
+			AddDownload(download)
+			return err
+		}
+	}
+
+	activeLock.Lock()
+	ids := make([]uint, 0, len(activeUsers))
+	for uid := range activeUsers {
+		ids = append(ids, uid)
+	}
+	activeUsers = make(map[uint]struct{})
+	activeLock.Unlock()
+
+	if err := model.MarkUsersActive(ids, today); err != nil {
+		for _, uid := range ids {
+			MarkActive(uid)
+		}
+		return err
+	}
+
+	count, err := model.CountActiveUsers(today)
+	if err != nil {
+		return err
+	}
+	return stat.UpdateActiveUsers(count)
+}
+
+// Aggregate 写入累计的流量，并重新汇总各存储策略、用户组及用户的存储用量
+func Aggregate() error {
+	if err := Flush(); err != nil {
+		return err
+	}
+
+	policies, err := model.AggregatePolicyUsage()
+	if err != nil {
+		return err
+	}
+
+	groups, err := model.AggregateGroupUsage()
+	if err != nil {
+		return err
+	}
+
+	users, err := model.TopUsersByStorage(model.GetIntSetting("statistics_top_users", 10))
+	if err != nil {
+		return err
+	}
+
+	stat, err := model.GetOrCreateStatistic(time.Now().Format(model.StatisticDateFormat))
+	if err != nil {
+		return err
+	}
+
+	util.Log().Debug("已汇总 %d 个存储策略、%d 个用户组的存储用量", len(policies), len(groups))
+	return stat.UpdateStorage(model.StorageUsage{
+		Policies:  policies,
+		Groups:    groups,
+		TopUsers:  users,
+		UpdatedAt: time.Now(),
+	})
+}
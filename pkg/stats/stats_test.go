@@ -0,0 +1,159 @@
+package stats
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func reset() {
+	uploadTraffic = 0
+	downloadTraffic = 0
+	activeUsers = make(map[uint]struct{})
+}
+
+func TestRecord(t *testing.T) {
+	asserts := assert.New(t)
+	reset()
+
+	AddUpload(1)
+	AddUpload(2)
+	AddDownload(5)
+	upload, download := Pending()
+	asserts.EqualValues(3, upload)
+	asserts.EqualValues(5, download)
+
+	MarkActive(1)
+	MarkActive(1)
+	MarkActive(2)
+	asserts.Len(activeUsers, 2)
+}
+
+func TestFlush(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 无法获取统计记录
+	{
+		reset()
+		AddUpload(1)
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+		upload, _ := Pending()
+		asserts.EqualValues(1, upload)
+	}
+
+	// 写入流量失败，流量保留
+	{
+		reset()
+		AddUpload(1)
+		AddDownload(2)
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+		upload, download := Pending()
+		asserts.EqualValues(1, upload)
+		asserts.EqualValues(2, download)
+	}
+
+	// 写入活跃用户失败，活跃用户保留
+	{
+		reset()
+		MarkActive(1)
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)active_date(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(activeUsers, 1)
+	}
+
+	// 成功
+	{
+		reset()
+		AddUpload(1)
+		MarkActive(1)
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)upload_traffic(.+)").WithArgs(uint64(0), uint64(1), sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)users(.+)active_date(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT count(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)active_users(.+)").WithArgs(3, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(Flush())
+		asserts.NoError(mock.ExpectationsWereMet())
+		upload, download := Pending()
+		asserts.EqualValues(0, upload)
+		asserts.EqualValues(0, download)
+		asserts.Len(activeUsers, 0)
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	asserts := assert.New(t)
+	reset()
+	cache.Set("setting_statistics_top_users", "5", 0)
+
+	// 汇总失败
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT count(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)policies(.+)").WillReturnError(errors.New("error"))
+		asserts.Error(Aggregate())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("SELECT count(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		mock.ExpectQuery("SELECT(.+)policies(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "files", "size"}).AddRow(1, "default", 1, 10))
+		mock.ExpectQuery("SELECT(.+)groups(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "users", "size"}).AddRow(1, "admin", 1, 10))
+		mock.ExpectQuery("SELECT(.+)users(.+)LIMIT 5").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "size"}).AddRow(1, "a@cloudreve.org", 10))
+		mock.ExpectQuery("SELECT(.+)statistics(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)statistics(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		asserts.NoError(Aggregate())
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+}
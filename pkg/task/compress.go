@@ -1,7 +1,6 @@
 package task
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -19,15 +18,20 @@ type CompressTask struct {
 	TaskModel *model.Task
 	TaskProps CompressProps
 	Err       *JobError
+	jobContext
 
 	zipPath string
 }
 
 // CompressProps 压缩任务属性
 type CompressProps struct {
-	Dirs  []uint `json:"dirs"`
-	Files []uint `json:"files"`
-	Dst   string `json:"dst"`
+	Dirs      []uint `json:"dirs"`
+	Files     []uint `json:"files"`
+	Dst       string `json:"dst"`
+	Format    string `json:"format,omitempty"`    // 压缩格式，为空时为 zip
+	Encrypted bool   `json:"encrypted,omitempty"` // 是否设置了压缩包密码
+	// 压缩包密码，仅保存在内存中，不写入数据库
+	Password string `json:"-"`
 }
 
 // Props 获取任务属性
@@ -46,6 +50,11 @@ func (job *CompressTask) Creator() uint {
 	return job.User.ID
 }
 
+// Owner 获取任务所属用户
+func (job *CompressTask) Owner() *model.User {
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *CompressTask) Model() *model.Task {
 	return job.TaskModel
@@ -74,6 +83,11 @@ func (job *CompressTask) removeZipFile() {
 	}
 }
 
+// Cleanup 任务中止后删除临时压缩文件
+func (job *CompressTask) Cleanup() {
+	job.removeZipFile()
+}
+
 // SetErrorMsg 设定任务失败信息
 func (job *CompressTask) SetErrorMsg(msg string) {
 	job.SetError(&JobError{Msg: msg})
@@ -86,6 +100,12 @@ func (job *CompressTask) GetError() *JobError {
 
 // Do 开始执行任务
 func (job *CompressTask) Do() {
+	// 重启后恢复的任务已丢失密码，不能生成未加密的压缩包
+	if job.TaskProps.Encrypted && job.TaskProps.Password == "" {
+		job.SetErrorMsg("压缩包密码未保存，任务无法在重启后恢复")
+		return
+	}
+
 	// 创建文件系统
 	fs, err := filesystem.NewFileSystem(job.User)
 	if err != nil {
@@ -101,7 +121,7 @@ func (job *CompressTask) Do() {
 	zipFilePath := filepath.Join(
 		util.RelativePath(model.GetSettingByName("temp_path")),
 		saveFolder,
-		fmt.Sprintf("archive_%d.zip", time.Now().UnixNano()),
+		fmt.Sprintf("archive_%d%s", time.Now().UnixNano(), filesystem.ArchiveExt(job.TaskProps.Format)),
 	)
 	zipFile, err := util.CreatNestedFile(zipFilePath)
 	if err != nil {
@@ -113,8 +133,11 @@ func (job *CompressTask) Do() {
 	defer zipFile.Close()
 
 	// 开始压缩
-	ctx := context.Background()
-	err = fs.Compress(ctx, zipFile, job.TaskProps.Dirs, job.TaskProps.Files, false)
+	ctx := job.Context()
+	err = fs.CompressWithOption(ctx, zipFile, job.TaskProps.Dirs, job.TaskProps.Files, &filesystem.ArchiveOption{
+		Format:   job.TaskProps.Format,
+		Password: job.TaskProps.Password,
+	})
 	if err != nil {
 		job.SetErrorMsg(err.Error())
 		return
@@ -136,13 +159,16 @@ func (job *CompressTask) Do() {
 }
 
 // NewCompressTask 新建压缩任务
-func NewCompressTask(user *model.User, dst string, dirs, files []uint) (Job, error) {
+func NewCompressTask(user *model.User, dst string, dirs, files []uint, format, password string) (Job, error) {
 	newTask := &CompressTask{
 		User: user,
 		TaskProps: CompressProps{
-			Dirs:  dirs,
-			Files: files,
-			Dst:   dst,
+			Dirs:      dirs,
+			Files:     files,
+			Dst:       dst,
+			Format:    format,
+			Encrypted: password != "",
+			Password:  password,
 		},
 	}
 
@@ -166,6 +192,11 @@ func NewCompressTaskFromModel(task *model.Task) (Job, error) {
 		TaskModel: task,
 	}
 
+	// 清除旧版本以明文保存的压缩包密码
+	if RedactProps(task) {
+		task.SetProps(task.Props)
+	}
+
 	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
 	if err != nil {
 		return nil, err
@@ -173,3 +204,24 @@ func NewCompressTaskFromModel(task *model.Task) (Job, error) {
 
 	return newTask, nil
 }
+
+// RedactProps 移除旧版本写入压缩任务属性中的明文密码，并标记为已设置密码，返回是否有修改
+func RedactProps(task *model.Task) bool {
+	if task.Type != CompressTaskType {
+		return false
+	}
+
+	var props map[string]interface{}
+	if err := json.Unmarshal([]byte(task.Props), &props); err != nil {
+		return false
+	}
+	if _, ok := props["password"]; !ok {
+		return false
+	}
+
+	delete(props, "password")
+	props["encrypted"] = true
+	res, _ := json.Marshal(props)
+	task.Props = string(res)
+	return true
+}
@@ -19,6 +19,11 @@ func TestCompressTask_Props(t *testing.T) {
 	}
 	asserts.NotEmpty(task.Props())
 	asserts.Equal(CompressTaskType, task.Type())
+
+	// 密码不写入任务属性
+	task.TaskProps = CompressProps{Encrypted: true, Password: "secret"}
+	asserts.NotContains(task.Props(), "secret")
+	asserts.Contains(task.Props(), `"encrypted":true`)
 	asserts.EqualValues(0, task.Creator())
 	asserts.Nil(task.Model())
 }
@@ -130,12 +135,12 @@ func TestCompressTask_Do(t *testing.T) {
 		// 查找文件
 		mock.ExpectQuery("SELECT(.+)files").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
-		// 查找子文件
-		mock.ExpectQuery("SELECT(.+)files").
-			WillReturnRows(sqlmock.NewRows([]string{"id"}))
-		// 查找子目录
+		// 列取子目录
 		mock.ExpectQuery("SELECT(.+)folders").
 			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		// 列取子文件
+		mock.ExpectQuery("SELECT(.+)files").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
 		// 更新错误
 		mock.ExpectBegin()
 		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1,
@@ -156,7 +161,7 @@ func TestNewCompressTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		job, err := NewCompressTask(&model.User{}, "/", []uint{12}, []uint{})
+		job, err := NewCompressTask(&model.User{}, "/", []uint{12}, []uint{}, "", "")
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotNil(job)
 		asserts.NoError(err)
@@ -167,7 +172,7 @@ func TestNewCompressTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
-		job, err := NewCompressTask(&model.User{}, "/", []uint{12}, []uint{})
+		job, err := NewCompressTask(&model.User{}, "/", []uint{12}, []uint{}, "", "")
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(job)
 		asserts.Error(err)
@@ -186,6 +191,27 @@ func TestNewCompressTaskFromModel(t *testing.T) {
 		asserts.NotNil(job)
 	}
 
+	// 清除旧版本保存的明文密码，恢复后的任务直接失败
+	{
+		record := &model.Task{Model: gorm.Model{ID: 1}, Props: `{"dst":"/a.zip","password":"secret"}`}
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)props(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewCompressTaskFromModel(record)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.NotContains(record.Props, "secret")
+		asserts.True(job.(*CompressTask).TaskProps.Encrypted)
+
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job.Do()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(job.GetError())
+	}
+
 	// JSON解析失败
 	{
 		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
@@ -1,7 +1,6 @@
 package task
 
 import (
-	"context"
 	"encoding/json"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -14,15 +13,17 @@ type DecompressTask struct {
 	TaskModel *model.Task
 	TaskProps DecompressProps
 	Err       *JobError
+	jobContext
 
 	zipPath string
 }
 
 // DecompressProps 压缩任务属性
 type DecompressProps struct {
-	Src      string `json:"src"`
-	Dst      string `json:"dst"`
-	Encoding string `json:"encoding"`
+	Src      string   `json:"src"`
+	Dst      string   `json:"dst"`
+	Encoding string   `json:"encoding"`
+	Files    []string `json:"files,omitempty"` // 只解压指定条目或通配符匹配的条目
 }
 
 // Props 获取任务属性
@@ -41,6 +42,11 @@ func (job *DecompressTask) Creator() uint {
 	return job.User.ID
 }
 
+// Owner 获取任务所属用户
+func (job *DecompressTask) Owner() *model.User {
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *DecompressTask) Model() *model.Task {
 	return job.TaskModel
@@ -83,7 +89,8 @@ func (job *DecompressTask) Do() {
 
 	job.TaskModel.SetProgress(DecompressingProgress)
 
-	err = fs.Decompress(context.Background(), job.TaskProps.Src, job.TaskProps.Dst, job.TaskProps.Encoding)
+	err = fs.Decompress(job.Context(), job.TaskProps.Src, job.TaskProps.Dst, job.TaskProps.Encoding,
+		job.TaskProps.Files)
 	if err != nil {
 		job.SetErrorMsg("解压缩失败", err)
 		return
@@ -92,13 +99,14 @@ func (job *DecompressTask) Do() {
 }
 
 // NewDecompressTask 新建压缩任务
-func NewDecompressTask(user *model.User, src, dst, encoding string) (Job, error) {
+func NewDecompressTask(user *model.User, src, dst, encoding string, files []string) (Job, error) {
 	newTask := &DecompressTask{
 		User: user,
 		TaskProps: DecompressProps{
 			Src:      src,
 			Dst:      dst,
 			Encoding: encoding,
+			Files:    files,
 		},
 	}
 
@@ -99,7 +99,7 @@ func TestNewDecompressTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		job, err := NewDecompressTask(&model.User{}, "/", "/", "utf-8")
+		job, err := NewDecompressTask(&model.User{}, "/", "/", "utf-8", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotNil(job)
 		asserts.NoError(err)
@@ -110,7 +110,7 @@ func TestNewDecompressTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
-		job, err := NewDecompressTask(&model.User{}, "/", "/", "utf-8")
+		job, err := NewDecompressTask(&model.User{}, "/", "/", "utf-8", nil)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(job)
 		asserts.Error(err)
@@ -0,0 +1,51 @@
+package task
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 任务生命周期钩子名称
+const (
+	// HookOnStart 任务开始执行
+	HookOnStart = "OnStart"
+	// HookOnSuccess 任务执行成功
+	HookOnSuccess = "OnSuccess"
+	// HookOnFailure 任务执行失败、超时
+	HookOnFailure = "OnFailure"
+)
+
+// Hook 任务生命周期钩子
+type Hook func(job Job)
+
+var (
+	hooksLock sync.RWMutex
+	hooks     = make(map[string][]Hook)
+)
+
+// Use 注册任务生命周期钩子，对所有类型的任务生效
+func Use(name string, hook Hook) {
+	hooksLock.Lock()
+	defer hooksLock.Unlock()
+	hooks[name] = append(hooks[name], hook)
+}
+
+// Trigger 触发钩子，单个钩子出错不影响任务及其他钩子
+func Trigger(name string, job Job) {
+	hooksLock.RLock()
+	list := hooks[name]
+	hooksLock.RUnlock()
+
+	for _, hook := range list {
+		func() {
+			defer func() {
+				if err := recover(); err != nil {
+					util.Log().Warning("任务钩子 %s 执行出错，%s", name, fmt.Sprint(err))
+				}
+			}()
+			hook(job)
+		}()
+	}
+}
@@ -0,0 +1,48 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrigger(t *testing.T) {
+	asserts := assert.New(t)
+	defer func() {
+		hooks = make(map[string][]Hook)
+	}()
+
+	events := make([]string, 0)
+	record := func(name string) Hook {
+		return func(job Job) {
+			events = append(events, name)
+		}
+	}
+	Use(HookOnStart, record(HookOnStart))
+	Use(HookOnSuccess, func(job Job) {
+		panic("hook error")
+	})
+	Use(HookOnSuccess, record(HookOnSuccess))
+	Use(HookOnFailure, record(HookOnFailure))
+
+	worker := &GeneralWorker{}
+
+	// 成功，钩子出错不影响其他钩子
+	{
+		job := &MockJob{DoFunc: func() {}}
+		asserts.NotPanics(func() {
+			worker.Do(job)
+		})
+		asserts.Equal(Complete, job.Status)
+		asserts.Equal([]string{HookOnStart, HookOnSuccess}, events)
+	}
+
+	// 失败
+	{
+		events = events[:0]
+		job := &MockJob{DoFunc: func() {}, Err: &JobError{Msg: "error"}}
+		worker.Do(job)
+		asserts.Equal(Error, job.Status)
+		asserts.Equal([]string{HookOnStart, HookOnFailure}, events)
+	}
+}
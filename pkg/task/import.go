@@ -3,11 +3,14 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"path"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -17,6 +20,9 @@ type ImportTask struct {
 	TaskModel *model.Task
 	TaskProps ImportProps
 	Err       *JobError
+	jobContext
+
+	report Report
 }
 
 // ImportProps 导入任务属性
@@ -25,8 +31,15 @@ type ImportProps struct {
 	Src       string `json:"src"`          // 原始路径
 	Recursive bool   `json:"is_recursive"` // 是否递归导入
 	Dst       string `json:"dst"`          // 目的目录
+	Sync      bool   `json:"sync"`         // 增量同步，仅导入新增或有变化的对象
 }
 
+// errFileConflict 目标目录下已有来源不同的同名文件
+var errFileConflict = errors.New("file with the same name already exists")
+
+// importModifiedMetaKey 记录导入时外部对象修改时间的元数据键
+const importModifiedMetaKey = "import_modified"
+
 // Props 获取任务属性
 func (job *ImportTask) Props() string {
 	res, _ := json.Marshal(job.TaskProps)
@@ -76,7 +89,9 @@ func (job *ImportTask) GetError() *JobError {
 
 // Do 开始执行任务
 func (job *ImportTask) Do() {
-	ctx := context.Background()
+	// 导入由管理员发起，不受 WebDAV 锁限制
+	ctx := context.WithValue(job.Context(), fsctx.IgnoreLocksCtx, true)
+	defer job.report.Save(job.TaskModel)
 
 	// 查找存储策略
 	policy, err := model.GetPolicyByID(job.TaskProps.PolicyID)
@@ -106,7 +121,7 @@ func (job *ImportTask) Do() {
 
 	// 列取目录、对象
 	job.TaskModel.SetProgress(ListingProgress)
-	coxIgnoreConflict := context.WithValue(context.Background(), fsctx.IgnoreDirectoryConflictCtx,
+	coxIgnoreConflict := context.WithValue(ctx, fsctx.IgnoreDirectoryConflictCtx,
 		true)
 	objects, err := fs.Handler.List(ctx, job.TaskProps.Src, job.TaskProps.Recursive)
 	if err != nil {
@@ -127,6 +142,7 @@ func (job *ImportTask) Do() {
 			folder, err := fs.CreateDirectory(coxIgnoreConflict, virtualPath)
 			if err != nil {
 				util.Log().Warning("导入任务无法创建用户目录[%s], %s", virtualPath, err)
+				job.report.Add(object.RelativePath, ReportFailed, err)
 			} else if folder.ID > 0 {
 				pathCache[virtualPath] = folder
 			}
@@ -135,6 +151,11 @@ func (job *ImportTask) Do() {
 
 	// 插入文件记录到用户文件系统
 	for _, object := range objects {
+		if ctx.Err() != nil {
+			job.SetErrorMsg("任务已中止", ctx.Err())
+			return
+		}
+
 		if !object.IsDir {
 			// 创建文件信息
 			virtualPath := path.Dir(path.Join(job.TaskProps.Dst, object.RelativePath))
@@ -143,6 +164,9 @@ func (job *ImportTask) Do() {
 				VirtualPath: virtualPath,
 				Name:        object.Name,
 				SavePath:    object.Source,
+				Metadata: map[string]string{
+					importModifiedMetaKey: object.LastModify.UTC().Format(time.RFC3339),
+				},
 			}
 
 			// 查找父目录
@@ -150,21 +174,28 @@ func (job *ImportTask) Do() {
 			if parent, ok := pathCache[virtualPath]; ok {
 				parentFolder = parent
 			} else {
-				folder, err := fs.CreateDirectory(context.Background(), virtualPath)
+				folder, err := fs.CreateDirectory(ctx, virtualPath)
 				if err != nil {
 					util.Log().Warning("导入任务无法创建用户目录[%s], %s",
 						virtualPath, err)
+					job.report.Add(object.RelativePath, ReportFailed, err)
 					continue
 				}
 				parentFolder = folder
 
 			}
 
+			// 增量同步时处理已存在的文件
+			if job.TaskProps.Sync && job.syncExisted(parentFolder, &object, &fileHeader) {
+				continue
+			}
+
 			// 插入文件记录
-			_, err := fs.AddFile(context.Background(), parentFolder, &fileHeader)
+			_, err := fs.AddFile(ctx, parentFolder, &fileHeader)
 			if err != nil {
 				util.Log().Warning("导入任务无法创插入文件[%s], %s",
 					object.RelativePath, err)
+				job.report.Add(object.RelativePath, ReportFailed, err)
 				if err == filesystem.ErrInsufficientCapacity {
 					job.SetErrorMsg("容量不足", err)
 					return
@@ -175,8 +206,48 @@ func (job *ImportTask) Do() {
 	}
 }
 
+// syncExisted 增量同步时检查目标目录下是否已有同名文件，
+// 有则按大小和修改时间决定跳过或更新，返回 true 表示无需再插入新记录
+func (job *ImportTask) syncExisted(parent *model.Folder, object *response.Object, header *fsctx.FileStream) bool {
+	if parent.ID == 0 {
+		return false
+	}
+
+	existed, err := parent.GetChildFile(object.Name)
+	if err != nil {
+		return false
+	}
+
+	// 同名文件并非来自此外部对象，保留用户已有文件
+	if existed.PolicyID != job.TaskProps.PolicyID || existed.SourceName != object.Source {
+		util.Log().Debug("导入任务跳过已存在的同名文件[%s]", object.RelativePath)
+		job.report.Add(object.RelativePath, ReportSkipped, errFileConflict)
+		return true
+	}
+
+	modified := header.Metadata[importModifiedMetaKey]
+	if existed.Size == object.Size && existed.MetadataSerialized[importModifiedMetaKey] == modified {
+		return true
+	}
+
+	// 对象已变化，更新文件记录
+	if existed.Size != object.Size {
+		if err := existed.UpdateSize(object.Size); err != nil {
+			util.Log().Warning("导入任务无法更新文件[%s]大小, %s", object.RelativePath, err)
+			job.report.Add(object.RelativePath, ReportFailed, err)
+			return true
+		}
+	}
+
+	if err := existed.UpdateMetadata(header.Metadata); err != nil {
+		util.Log().Warning("导入任务无法更新文件[%s]元数据, %s", object.RelativePath, err)
+	}
+
+	return true
+}
+
 // NewImportTask 新建导入任务
-func NewImportTask(user, policy uint, src, dst string, recursive bool) (Job, error) {
+func NewImportTask(user, policy uint, src, dst string, recursive, sync bool) (Job, error) {
 	creator, err := model.GetActiveUserByID(user)
 	if err != nil {
 		return nil, err
@@ -189,6 +260,7 @@ func NewImportTask(user, policy uint, src, dst string, recursive bool) (Job, err
 			Recursive: recursive,
 			Src:       src,
 			Dst:       dst,
+			Sync:      sync,
 		},
 	}
 
@@ -181,10 +181,20 @@ func TestImportTask_Do(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)folders(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectCommit()
+		// 更新父目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)folders(.+)folder_num").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
 		// 插入文件记录
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)files(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
 		mock.ExpectExec("UPDATE(.+)users(.+)storage(.+)").WillReturnResult(sqlmock.NewResult(2, 1))
+		// 更新所在目录统计
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(2, 1).AddRow(1, nil))
+		mock.ExpectExec("UPDATE(.+)folders(.+)file_num").WillReturnResult(sqlmock.NewResult(1, 2))
 		mock.ExpectCommit()
 
 		task.Do()
@@ -204,7 +214,7 @@ func TestNewImportTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
 		mock.ExpectCommit()
-		job, err := NewImportTask(1, 1, "/", "/", false)
+		job, err := NewImportTask(1, 1, "/", "/", false, false)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.NotNil(job)
 		asserts.NoError(err)
@@ -216,7 +226,7 @@ func TestNewImportTask(t *testing.T) {
 		mock.ExpectBegin()
 		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
 		mock.ExpectRollback()
-		job, err := NewImportTask(1, 1, "/", "/", false)
+		job, err := NewImportTask(1, 1, "/", "/", false, false)
 		asserts.NoError(mock.ExpectationsWereMet())
 		asserts.Nil(job)
 		asserts.Error(err)
@@ -1,7 +1,11 @@
 package task
 
 import (
+	"context"
+	"time"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
@@ -15,6 +19,10 @@ const (
 	TransferTaskType
 	// ImportTaskType 导入任务
 	ImportTaskType
+	// TakeoutTaskType 导出个人数据任务
+	TakeoutTaskType
+	// StorageAuditTaskType 存储一致性检查任务
+	StorageAuditTaskType
 )
 
 // 任务状态
@@ -29,6 +37,8 @@ const (
 	Canceled
 	// Complete 完成
 	Complete
+	// TimedOut 执行超时
+	TimedOut
 )
 
 // 任务进度
@@ -61,6 +71,58 @@ type Job interface {
 	GetError() *JobError // 获取任务执行结果，返回nil表示成功完成执行
 }
 
+// ContextJob 可被超时中止的任务
+type ContextJob interface {
+	SetContext(ctx context.Context) // 设定任务执行使用的上下文，超时后上下文将被取消
+}
+
+// Cleaner 任务被中止后需要清理资源的任务
+type Cleaner interface {
+	Cleanup() // 清理任务已产生的临时资源
+}
+
+// jobContext 任务执行上下文
+type jobContext struct {
+	ctx context.Context
+}
+
+// SetContext 设定任务执行上下文
+func (c *jobContext) SetContext(ctx context.Context) {
+	c.ctx = ctx
+}
+
+// Context 获取任务执行上下文
+func (c *jobContext) Context() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// timeoutSettings 各类型任务最长执行时间的设置项
+var timeoutSettings = map[int]string{
+	CompressTaskType:     "compress_task_timeout",
+	DecompressTaskType:   "decompress_task_timeout",
+	TransferTaskType:     "transfer_task_timeout",
+	ImportTaskType:       "import_task_timeout",
+	TakeoutTaskType:      "takeout_task_timeout",
+	StorageAuditTaskType: "storage_audit_task_timeout",
+}
+
+// Timeout 获取给定类型任务的最长执行时间，0 表示不限制
+func Timeout(taskType int) time.Duration {
+	name, ok := timeoutSettings[taskType]
+	if !ok {
+		return 0
+	}
+	return time.Duration(model.GetIntSetting(name, 0)) * time.Second
+}
+
+// IOLimit 获取后台任务读写文件的速率上限，字节/秒，0 表示不限制
+func IOLimit() int64 {
+	return int64(model.GetIntSetting("task_io_limit", 0))
+}
+
 // JobError 任务失败信息
 type JobError struct {
 	Msg   string `json:"msg,omitempty"`
@@ -81,6 +143,15 @@ func Record(job Job) (*model.Task, error) {
 	return &record, err
 }
 
+// Trace 记录创建任务的请求 ID 及所在链路，任务执行时的日志及链路追踪据此与请求关联
+func Trace(job Job, ctx context.Context) Job {
+	if record := job.Model(); record != nil {
+		record.RequestID = util.RequestIDFromContext(ctx)
+		record.TraceParent = tracing.TraceParent(ctx)
+	}
+	return job
+}
+
 // Resume 从数据库中恢复未完成任务
 func Resume(p Pool) {
 	tasks := model.GetTasksByStatus(Queued, Processing)
@@ -113,6 +184,10 @@ func GetJobFromModel(task *model.Task) (Job, error) {
 		return NewTransferTaskFromModel(task)
 	case ImportTaskType:
 		return NewImportTaskFromModel(task)
+	case TakeoutTaskType:
+		return NewTakeoutTaskFromModel(task)
+	case StorageAuditTaskType:
+		return NewStorageAuditTaskFromModel(task)
 	default:
 		return nil, ErrUnknownTaskType
 	}
@@ -0,0 +1,54 @@
+package task
+
+import (
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+)
+
+// taskNames 通知中使用的任务类型名称，未列出的任务类型不发送通知
+var taskNames = map[int]string{
+	CompressTaskType:   "压缩文件",
+	DecompressTaskType: "解压缩文件",
+	TransferTaskType:   "中转文件",
+	ImportTaskType:     "导入外部目录",
+	TakeoutTaskType:    "导出个人数据",
+}
+
+// NotifySucceeded 任务执行成功后通知创建者，用作 HookOnSuccess 钩子
+func NotifySucceeded(job Job) {
+	notifyFinished(job, true)
+}
+
+// NotifyFailed 任务执行失败或超时后通知创建者，用作 HookOnFailure 钩子
+func NotifyFailed(job Job) {
+	notifyFinished(job, false)
+}
+
+func notifyFinished(job Job, succeeded bool) {
+	name, ok := taskNames[job.Type()]
+	if !ok {
+		return
+	}
+
+	user, err := model.GetActiveUserByID(job.Creator())
+	if err != nil {
+		return
+	}
+
+	msg := &notify.Message{
+		Event:   model.NotifyTaskFinished,
+		Title:   fmt.Sprintf("%s任务已完成", name),
+		Content: fmt.Sprintf("您创建的%s任务已执行完成。", name),
+	}
+	if !succeeded {
+		msg.Title = fmt.Sprintf("%s任务失败", name)
+		msg.Content = fmt.Sprintf("您创建的%s任务执行失败。", name)
+		if jobErr := job.GetError(); jobErr != nil {
+			msg.Content += "原因：" + jobErr.Msg
+		}
+	}
+
+	notify.Send(&user, msg)
+}
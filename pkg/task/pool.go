@@ -1,6 +1,10 @@
 package task
 
 import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
@@ -14,19 +18,172 @@ type Pool interface {
 	Submit(job Job)
 }
 
+// ResizablePool 可在运行时调整 Worker 数量的任务池
+type ResizablePool interface {
+	Resize(num int) error
+	Stats() PoolStats
+}
+
+// PausablePool 可暂停执行新任务的任务池，用于维护模式下排空任务
+type PausablePool interface {
+	SetPaused(paused bool)
+}
+
+// PoolStats 任务池运行状态
+type PoolStats struct {
+	// Worker 总数
+	Size int `json:"size"`
+	// 上限
+	Max int `json:"max"`
+	// 正在执行任务的 Worker 数
+	Busy int `json:"busy"`
+	// 等待 Worker 的任务数
+	Queued int `json:"queued"`
+	// 是否已暂停执行新任务
+	Paused bool `json:"paused"`
+}
+
+// maxPoolSize 任务池 Worker 数量上限
+const maxPoolSize = 1024
+
+// ErrInvalidPoolSize Worker 数量超出范围
+var ErrInvalidPoolSize = errors.New("invalid worker number")
+
 // AsyncPool 带有最大配额的任务池
 type AsyncPool struct {
 	// 容量
 	idleWorker chan int
+
+	// Worker 总数，及缩容后尚待回收的 Worker 数
+	sizeMu   sync.Mutex
+	size     int
+	retiring int
+
+	// 正在执行、等待 Worker 的任务数
+	busy   int32
+	queued int32
+
+	// 各用户正在执行的任务数，及是否暂停执行新任务
+	mu      sync.Mutex
+	cond    *sync.Cond
+	running map[uint]int
+	paused  bool
+}
+
+// userBoundJob 由用户发起，受用户组并发任务数限制的任务
+type userBoundJob interface {
+	Owner() *model.User
 }
 
 // Add 增加可用Worker数量
 func (pool *AsyncPool) Add(num int) {
+	pool.sizeMu.Lock()
+	pool.size += num
+	pool.sizeMu.Unlock()
+
 	for i := 0; i < num; i++ {
 		pool.idleWorker <- 1
 	}
 }
 
+// Resize 调整 Worker 总数，缩容时正在执行的任务不受影响，
+// 多出的 Worker 在任务结束后回收
+func (pool *AsyncPool) Resize(num int) error {
+	if num < 1 || num > cap(pool.idleWorker) {
+		return ErrInvalidPoolSize
+	}
+
+	pool.sizeMu.Lock()
+	defer pool.sizeMu.Unlock()
+
+	delta := num - pool.size
+	pool.size = num
+
+	// 扩容时先抵消尚未回收的 Worker
+	for delta > 0 && pool.retiring > 0 {
+		pool.retiring--
+		delta--
+	}
+	for ; delta > 0; delta-- {
+		pool.idleWorker <- 1
+	}
+
+	// 缩容时优先回收空闲 Worker
+	for delta < 0 {
+		select {
+		case <-pool.idleWorker:
+			delta++
+		default:
+			pool.retiring -= delta
+			delta = 0
+		}
+	}
+
+	util.Log().Info("任务队列 WorkerNum 调整为 %d", num)
+	return nil
+}
+
+// Stats 获取任务池运行状态
+func (pool *AsyncPool) Stats() PoolStats {
+	pool.mu.Lock()
+	paused := pool.paused
+	pool.mu.Unlock()
+
+	pool.sizeMu.Lock()
+	defer pool.sizeMu.Unlock()
+
+	return PoolStats{
+		Size:   pool.size,
+		Max:    cap(pool.idleWorker),
+		Busy:   int(atomic.LoadInt32(&pool.busy)),
+		Queued: int(atomic.LoadInt32(&pool.queued)),
+		Paused: paused,
+	}
+}
+
+// SetPaused 暂停或恢复执行新任务，暂停时正在执行的任务不受影响，
+// 新提交的任务在恢复前保持排队状态
+func (pool *AsyncPool) SetPaused(paused bool) {
+	pool.mu.Lock()
+	pool.initCond()
+	if pool.paused == paused {
+		pool.mu.Unlock()
+		return
+	}
+	pool.paused = paused
+	pool.mu.Unlock()
+	pool.cond.Broadcast()
+
+	if paused {
+		util.Log().Info("任务队列已暂停执行新任务")
+	} else {
+		util.Log().Info("任务队列已恢复执行新任务")
+	}
+}
+
+// initCond 初始化条件变量，调用方需持有 pool.mu
+func (pool *AsyncPool) initCond() {
+	if pool.cond == nil {
+		pool.cond = sync.NewCond(&pool.mu)
+		pool.running = make(map[uint]int)
+	}
+}
+
+// waitResumed 任务池暂停时阻塞直到恢复。中转任务用于完成已下载的离线下载，
+// 不受暂停影响，以便排空正在进行的传输
+func (pool *AsyncPool) waitResumed(job Job) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.initCond()
+	if !pool.paused || job.Type() == TransferTaskType {
+		return
+	}
+
+	for pool.paused {
+		pool.cond.Wait()
+	}
+}
+
 // ObtainWorker 阻塞直到获取新的Worker
 func (pool *AsyncPool) obtainWorker() Worker {
 	select {
@@ -36,32 +193,114 @@ func (pool *AsyncPool) obtainWorker() Worker {
 	}
 }
 
-// FreeWorker 添加空闲Worker
+// FreeWorker 添加空闲Worker，有待回收的 Worker 时直接回收
 func (pool *AsyncPool) freeWorker() {
-	pool.Add(1)
+	pool.sizeMu.Lock()
+	if pool.retiring > 0 {
+		pool.retiring--
+		pool.sizeMu.Unlock()
+		return
+	}
+	pool.sizeMu.Unlock()
+
+	pool.idleWorker <- 1
+}
+
+// acquireUserSlot 阻塞直到任务所属用户正在执行的任务数低于用户组限制，
+// 返回占用名额的用户ID，不受限制的任务返回 false
+func (pool *AsyncPool) acquireUserSlot(job Job) (uint, bool) {
+	owned, ok := job.(userBoundJob)
+	if !ok || owned.Owner() == nil {
+		return 0, false
+	}
+
+	user := owned.Owner()
+	limit := user.Group.OptionsSerialized.MaxParallelTask
+
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	pool.initCond()
+
+	for limit > 0 && pool.running[user.ID] >= limit {
+		pool.cond.Wait()
+	}
+
+	pool.running[user.ID]++
+	return user.ID, true
+}
+
+// releaseUserSlot 释放用户占用的任务名额
+func (pool *AsyncPool) releaseUserSlot(uid uint) {
+	pool.mu.Lock()
+	pool.running[uid]--
+	if pool.running[uid] <= 0 {
+		delete(pool.running, uid)
+	}
+	pool.mu.Unlock()
+	pool.cond.Broadcast()
 }
 
 // Submit 开始提交任务
 func (pool *AsyncPool) Submit(job Job) {
 	go func() {
+		// 暂停期间的任务计入排队数
+		atomic.AddInt32(&pool.queued, 1)
+		pool.waitResumed(job)
+		atomic.AddInt32(&pool.queued, -1)
+
+		// 先等待用户名额，避免排队中的任务占用 Worker
+		uid, limited := pool.acquireUserSlot(job)
+		if limited {
+			defer pool.releaseUserSlot(uid)
+		}
+
 		util.Log().Debug("等待获取Worker")
+		atomic.AddInt32(&pool.queued, 1)
 		worker := pool.obtainWorker()
+		atomic.AddInt32(&pool.queued, -1)
 		util.Log().Debug("获取到Worker")
+		atomic.AddInt32(&pool.busy, 1)
 		worker.Do(job)
+		atomic.AddInt32(&pool.busy, -1)
 		util.Log().Debug("释放Worker")
 		pool.freeWorker()
 	}()
 }
 
+// ApplySettings 按当前设置调整任务池 Worker 数量及暂停状态，用于设置变更后重新加载
+func ApplySettings() {
+	if pool, ok := TaskPoll.(ResizablePool); ok {
+		num := model.GetIntSetting("max_worker_num", 10)
+		if pool.Stats().Size != num {
+			if err := pool.Resize(num); err != nil {
+				util.Log().Warning("无法调整任务队列 WorkerNum 为 %d，%s", num, err)
+			}
+		}
+	}
+
+	if pool, ok := TaskPoll.(PausablePool); ok {
+		pool.SetPaused(model.IsTrueVal(model.GetSettingByName("maintenance_enabled")))
+	}
+}
+
 // Init 初始化任务池
 func Init() {
 	maxWorker := model.GetIntSetting("max_worker_num", 10)
+	poolCap := maxPoolSize
+	if maxWorker > poolCap {
+		poolCap = maxWorker
+	}
 	TaskPoll = &AsyncPool{
-		idleWorker: make(chan int, maxWorker),
+		idleWorker: make(chan int, poolCap),
 	}
 	TaskPoll.Add(maxWorker)
 	util.Log().Info("初始化任务队列，WorkerNum = %d", maxWorker)
 
+	// 维护模式下恢复的任务保持排队，待维护结束后执行
+	if model.IsTrueVal(model.GetSettingByName("maintenance_enabled")) {
+		TaskPoll.(PausablePool).SetPaused(true)
+	}
+
 	if conf.SystemConfig.Mode == "master" {
 		Resume(TaskPoll)
 	}
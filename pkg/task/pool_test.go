@@ -3,6 +3,7 @@ package task
 import (
 	"database/sql"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -29,6 +30,7 @@ func TestMain(m *testing.M) {
 func TestInit(t *testing.T) {
 	asserts := assert.New(t)
 	cache.Set("setting_max_worker_num", "10", 0)
+	cache.Set("setting_maintenance_enabled", "0", 0)
 	mock.ExpectQuery("SELECT(.+)").WithArgs(Queued, Processing).WillReturnRows(sqlmock.NewRows([]string{"type"}).AddRow(-1))
 	Init()
 	asserts.NoError(mock.ExpectationsWereMet())
@@ -50,3 +52,142 @@ func TestPool_Submit(t *testing.T) {
 		pool.Submit(job)
 	})
 }
+
+type MockOwnedJob struct {
+	MockJob
+	User *model.User
+}
+
+func (job *MockOwnedJob) Owner() *model.User {
+	return job.User
+}
+
+func TestPool_UserParallelLimit(t *testing.T) {
+	asserts := assert.New(t)
+	pool := &AsyncPool{
+		idleWorker: make(chan int, 2),
+	}
+	pool.Add(2)
+
+	user := &model.User{}
+	user.ID = 1
+	user.Group.OptionsSerialized.MaxParallelTask = 1
+
+	release := make(chan struct{})
+	started := make(chan int, 2)
+	newJob := func(i int) *MockOwnedJob {
+		return &MockOwnedJob{
+			User: user,
+			MockJob: MockJob{DoFunc: func() {
+				started <- i
+				<-release
+			}},
+		}
+	}
+
+	pool.Submit(newJob(1))
+	pool.Submit(newJob(2))
+
+	// 同一用户只能有一个任务在执行
+	<-started
+	select {
+	case <-started:
+		asserts.Fail("second job should be queued")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// 第一个任务完成后，排队的任务开始执行
+	release <- struct{}{}
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		asserts.Fail("queued job should be started")
+	}
+	release <- struct{}{}
+}
+
+func TestPool_Resize(t *testing.T) {
+	asserts := assert.New(t)
+	pool := &AsyncPool{
+		idleWorker: make(chan int, 4),
+	}
+	pool.Add(2)
+
+	// 超出范围
+	asserts.Equal(ErrInvalidPoolSize, pool.Resize(0))
+	asserts.Equal(ErrInvalidPoolSize, pool.Resize(5))
+
+	// 扩容
+	asserts.NoError(pool.Resize(4))
+	asserts.Len(pool.idleWorker, 4)
+	asserts.Equal(4, pool.Stats().Size)
+
+	// 缩容，Worker 均被占用时在释放后回收
+	for i := 0; i < 4; i++ {
+		<-pool.idleWorker
+	}
+	asserts.NoError(pool.Resize(1))
+	asserts.Equal(3, pool.retiring)
+	for i := 0; i < 4; i++ {
+		pool.freeWorker()
+	}
+	asserts.Len(pool.idleWorker, 1)
+	asserts.Equal(0, pool.retiring)
+
+	// 缩容后再扩容
+	<-pool.idleWorker
+	asserts.NoError(pool.Resize(3))
+	asserts.NoError(pool.Resize(2))
+	pool.freeWorker()
+	asserts.Len(pool.idleWorker, 2)
+	asserts.Equal(PoolStats{Size: 2, Max: 4}, pool.Stats())
+}
+
+type MockTypedJob struct {
+	MockJob
+	JobType int
+}
+
+func (job *MockTypedJob) Type() int {
+	return job.JobType
+}
+
+func TestPool_Paused(t *testing.T) {
+	asserts := assert.New(t)
+	pool := &AsyncPool{
+		idleWorker: make(chan int, 2),
+	}
+	pool.Add(2)
+	pool.SetPaused(true)
+	asserts.True(pool.Stats().Paused)
+
+	started := make(chan int, 2)
+	pool.Submit(&MockTypedJob{JobType: CompressTaskType, MockJob: MockJob{DoFunc: func() { started <- CompressTaskType }}})
+
+	// 暂停期间新任务保持排队
+	select {
+	case <-started:
+		asserts.Fail("job should be queued while paused")
+	case <-time.After(100 * time.Millisecond):
+	}
+	asserts.Equal(1, pool.Stats().Queued)
+
+	// 中转任务不受暂停影响
+	pool.Submit(&MockTypedJob{JobType: TransferTaskType, MockJob: MockJob{DoFunc: func() { started <- TransferTaskType }}})
+	select {
+	case i := <-started:
+		asserts.Equal(TransferTaskType, i)
+	case <-time.After(time.Second):
+		asserts.Fail("transfer job should be started")
+	}
+
+	// 恢复后排队的任务开始执行
+	pool.SetPaused(false)
+	select {
+	case i := <-started:
+		asserts.Equal(CompressTaskType, i)
+	case <-time.After(time.Second):
+		asserts.Fail("queued job should be started after resume")
+	}
+	asserts.False(pool.Stats().Paused)
+}
@@ -0,0 +1,89 @@
+package task
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 报告条目处理结果
+const (
+	// ReportSkipped 跳过
+	ReportSkipped = "skipped"
+	// ReportFailed 失败
+	ReportFailed = "failed"
+)
+
+// maxReportItems 单个报告最多记录的条目数
+const maxReportItems = 10000
+
+// ReportItem 批量任务中单个对象的处理结果
+type ReportItem struct {
+	Path   string `json:"path"`
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Report 批量任务逐项处理结果报告，只记录跳过和失败的对象
+type Report struct {
+	mu        sync.Mutex
+	Items     []ReportItem `json:"items"`
+	Truncated bool         `json:"truncated,omitempty"`
+}
+
+// Add 添加一条处理结果
+func (report *Report) Add(path, result string, err error) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if len(report.Items) >= maxReportItems {
+		report.Truncated = true
+		return
+	}
+
+	item := ReportItem{Path: path, Result: result}
+	if err != nil {
+		item.Reason = err.Error()
+	}
+	report.Items = append(report.Items, item)
+}
+
+// Save 将报告保存到任务记录，无条目时不保存
+func (report *Report) Save(task *model.Task) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+
+	if len(report.Items) == 0 || task == nil {
+		return
+	}
+
+	res, _ := json.Marshal(report)
+	if err := task.SetReport(string(res)); err != nil {
+		util.Log().Warning("无法保存任务报告，%s", err)
+	}
+}
+
+// ParseReport 解析任务记录中保存的报告
+func ParseReport(raw string) (*Report, error) {
+	report := &Report{}
+	if err := json.Unmarshal([]byte(raw), report); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// CSV 以 CSV 格式导出报告
+func (report *Report) CSV() []byte {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"path", "result", "reason"})
+	for _, item := range report.Items {
+		w.Write([]string{item.Path, item.Result, item.Reason})
+	}
+	w.Flush()
+	return buf.Bytes()
+}
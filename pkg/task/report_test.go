@@ -0,0 +1,57 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	asserts := assert.New(t)
+	report := &Report{}
+
+	// 无条目时不保存
+	{
+		report.Save(&model.Task{})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	report.Add("a.txt", ReportSkipped, nil)
+	report.Add("b,c.txt", ReportFailed, errors.New("error"))
+
+	// 保存
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)report(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		report.Save(&model.Task{})
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// CSV
+	{
+		asserts.Equal("path,result,reason\na.txt,skipped,\n\"b,c.txt\",failed,error\n", string(report.CSV()))
+	}
+
+	// 解析
+	{
+		res, err := ParseReport(`{"items":[{"path":"a.txt","result":"skipped"}]}`)
+		asserts.NoError(err)
+		asserts.Len(res.Items, 1)
+		_, err = ParseReport("?")
+		asserts.Error(err)
+	}
+
+	// 超出条目上限
+	{
+		report := &Report{}
+		for i := 0; i <= maxReportItems; i++ {
+			report.Add("a.txt", ReportFailed, nil)
+		}
+		asserts.Len(report.Items, maxReportItems)
+		asserts.True(report.Truncated)
+	}
+}
@@ -117,8 +117,10 @@ func (job *TransferTask) Do() {
 
 	size := fi.Size()
 
-	err = fs.Handler.Put(context.Background(), &fsctx.FileStream{
-		File:     file,
+	// 按主机的设置限制读取速率
+	ctx := filesystem.WithIOLimit(context.Background(), job.Req.IOLimit)
+	err = fs.Handler.Put(ctx, &fsctx.FileStream{
+		File:     filesystem.LimitReadCloser(ctx, file),
 		SavePath: job.Req.Dst,
 		Size:     uint64(size),
 	})
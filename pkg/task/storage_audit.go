@@ -0,0 +1,318 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// 存储一致性检查的报告条目类型
+const (
+	// ReportStorageMismatch 已用容量与文件记录不一致
+	ReportStorageMismatch = "storage_mismatch"
+	// ReportMissingObject 文件记录对应的物理对象不存在
+	ReportMissingObject = "missing_object"
+	// ReportSizeMismatch 文件记录与物理对象大小不一致
+	ReportSizeMismatch = "size_mismatch"
+	// ReportOrphanObject 物理对象没有对应的文件记录
+	ReportOrphanObject = "orphan_object"
+)
+
+// StorageAuditTask 重新计算用户已用容量，并检查文件记录与物理对象是否一致的任务
+type StorageAuditTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps StorageAuditProps
+	Err       *JobError
+	jobContext
+
+	report Report
+}
+
+// StorageAuditProps 存储一致性检查任务属性
+type StorageAuditProps struct {
+	Fix bool `json:"fix,omitempty"` // 是否修复发现的问题，物理对象缺失的文件记录将被删除，孤立的物理对象只报告不删除
+}
+
+// Props 获取任务属性
+func (job *StorageAuditTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务类型
+func (job *StorageAuditTask) Type() int {
+	return StorageAuditTaskType
+}
+
+// Creator 获取创建者ID
+func (job *StorageAuditTask) Creator() uint {
+	return job.User.ID
+}
+
+// Model 获取任务的数据库模型
+func (job *StorageAuditTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *StorageAuditTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *StorageAuditTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *StorageAuditTask) SetErrorMsg(msg string, err error) {
+	jobErr := &JobError{Msg: msg}
+	if err != nil {
+		jobErr.Error = err.Error()
+	}
+	job.SetError(jobErr)
+}
+
+// GetError 返回任务失败信息
+func (job *StorageAuditTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *StorageAuditTask) Do() {
+	ctx := job.Context()
+	defer job.report.Save(job.TaskModel)
+
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		job.SetErrorMsg(err.Error(), nil)
+		return
+	}
+	defer fs.Recycle()
+
+	job.TaskModel.SetProgress(ListingProgress)
+	var files []model.File
+	if err := model.ListUserRecords(job.User.ID, &files); err != nil {
+		job.SetErrorMsg("无法列取文件记录", err)
+		return
+	}
+
+	// 按存储策略及物理目录分组，逐个目录列取物理对象
+	groups := make(map[uint]map[string][]*model.File)
+	for i := range files {
+		// 上传中的文件尚无完整的物理对象
+		if files[i].UploadSessionID != nil {
+			continue
+		}
+
+		dirs, ok := groups[files[i].PolicyID]
+		if !ok {
+			dirs = make(map[string][]*model.File)
+			groups[files[i].PolicyID] = dirs
+		}
+		dir := path.Dir(files[i].SourceName)
+		dirs[dir] = append(dirs[dir], &files[i])
+	}
+
+	thumbSuffix := model.GetSettingByNameWithDefault("thumb_file_suffix", "._thumb")
+	var missing []uint
+	for policyID, dirs := range groups {
+		policy, err := model.GetPolicyByID(policyID)
+		if err != nil {
+			job.report.Add(fmt.Sprintf("policy #%d", policyID), ReportFailed, err)
+			continue
+		}
+
+		fs.Policy = &policy
+		if err := fs.DispatchHandler(); err != nil {
+			job.report.Add(policy.Name, ReportFailed, err)
+			continue
+		}
+
+		for dir, dirFiles := range dirs {
+			if ctx.Err() != nil {
+				job.SetErrorMsg("任务已中止", ctx.Err())
+				return
+			}
+
+			objects, err := fs.Handler.List(ctx, dir, false)
+			if err != nil {
+				util.Log().Warning("存储一致性检查无法列取目录[%s], %s", dir, err)
+				job.report.Add(dir, ReportFailed, err)
+				continue
+			}
+
+			result := auditDirectory(dir, dirFiles, objects, thumbSuffix)
+			for _, file := range result.missing {
+				job.report.Add(file.SourceName, ReportMissingObject, fmt.Errorf("file #%d (%s)", file.ID, file.Name))
+				missing = append(missing, file.ID)
+			}
+
+			for _, mismatch := range result.sizeMismatch {
+				file := mismatch.file
+				reason := fmt.Errorf("file #%d (%s) recorded %d bytes, actual %d bytes", file.ID, file.Name, file.Size, mismatch.actual)
+				if job.TaskProps.Fix {
+					if err := file.UpdateSize(mismatch.actual); err != nil {
+						job.report.Add(file.SourceName, ReportFailed, err)
+						continue
+					}
+					reason = fmt.Errorf("%s, fixed", reason)
+				}
+				job.report.Add(file.SourceName, ReportSizeMismatch, reason)
+			}
+
+			job.reportOrphans(policy.ID, result.orphans)
+		}
+	}
+
+	// 删除物理对象已丢失的文件记录，同时撤销相关分享
+	if job.TaskProps.Fix && len(missing) > 0 {
+		if err := fs.Delete(context.WithValue(ctx, fsctx.IgnoreLocksCtx, true), []uint{}, missing, true); err != nil {
+			job.report.Add("/", ReportFailed, err)
+		}
+	}
+
+	job.checkStorage()
+}
+
+// reportOrphans 报告没有任何文件记录引用的物理对象，其他用户的文件可能与当前用户共用物理目录
+func (job *StorageAuditTask) reportOrphans(policyID uint, candidates []string) {
+	if len(candidates) == 0 {
+		return
+	}
+
+	referenced, err := model.GetFilesBySourceNames(policyID, candidates)
+	if err != nil {
+		job.report.Add(path.Dir(candidates[0]), ReportFailed, err)
+		return
+	}
+
+	used := make(map[string]bool, len(referenced))
+	for _, file := range referenced {
+		used[file.SourceName] = true
+	}
+
+	for _, source := range candidates {
+		if !used[source] {
+			job.report.Add(source, ReportOrphanObject, nil)
+		}
+	}
+}
+
+// checkStorage 按文件记录重新计算已用容量
+func (job *StorageAuditTask) checkStorage() {
+	actual, err := model.GetUserUsedStorage(job.User.ID)
+	if err != nil {
+		job.report.Add("/", ReportFailed, err)
+		return
+	}
+
+	if actual == job.User.Storage {
+		return
+	}
+
+	reason := fmt.Errorf("recorded %d bytes, actual %d bytes", job.User.Storage, actual)
+	if job.TaskProps.Fix {
+		if err := job.User.SetStorage(actual); err != nil {
+			job.report.Add("/", ReportFailed, err)
+			return
+		}
+		reason = fmt.Errorf("%s, fixed", reason)
+	}
+	job.report.Add("/", ReportStorageMismatch, reason)
+}
+
+// sizeMismatch 大小不一致的文件及物理对象的实际大小
+type sizeMismatch struct {
+	file   *model.File
+	actual uint64
+}
+
+// directoryAudit 单个物理目录的检查结果
+type directoryAudit struct {
+	missing      []*model.File
+	sizeMismatch []sizeMismatch
+	orphans      []string // 当前用户的文件记录未引用的物理对象
+}
+
+// auditDirectory 比对物理目录下的文件记录与实际对象，缩略图不视为孤立对象
+func auditDirectory(dir string, files []*model.File, objects []response.Object, thumbSuffix string) directoryAudit {
+	var res directoryAudit
+	existed := make(map[string]*response.Object, len(objects))
+	for i := range objects {
+		if !objects[i].IsDir {
+			existed[path.Join(dir, objects[i].RelativePath)] = &objects[i]
+		}
+	}
+
+	referenced := make(map[string]bool, len(files))
+	for _, file := range files {
+		referenced[file.SourceName] = true
+		object, ok := existed[file.SourceName]
+		if !ok {
+			res.missing = append(res.missing, file)
+		} else if object.Size != file.Size {
+			res.sizeMismatch = append(res.sizeMismatch, sizeMismatch{file: file, actual: object.Size})
+		}
+	}
+
+	for source := range existed {
+		if referenced[source] || (thumbSuffix != "" && strings.HasSuffix(source, thumbSuffix)) {
+			continue
+		}
+		res.orphans = append(res.orphans, source)
+	}
+
+	return res
+}
+
+// NewStorageAuditTask 新建存储一致性检查任务
+func NewStorageAuditTask(uid uint, fix bool) (Job, error) {
+	user, err := model.GetUserByID(uid)
+	if err != nil {
+		return nil, err
+	}
+
+	newTask := &StorageAuditTask{
+		User:      &user,
+		TaskProps: StorageAuditProps{Fix: fix},
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewStorageAuditTaskFromModel 从数据库记录中恢复存储一致性检查任务
+func NewStorageAuditTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &StorageAuditTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
@@ -0,0 +1,136 @@
+package task
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStorageAuditTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &StorageAuditTask{
+		User: &model.User{},
+	}
+	asserts.Equal("{}", task.Props())
+	asserts.Equal(StorageAuditTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+
+	task.TaskProps.Fix = true
+	asserts.Equal(`{"fix":true}`, task.Props())
+}
+
+func TestAuditDirectory(t *testing.T) {
+	asserts := assert.New(t)
+	files := []*model.File{
+		{SourceName: "uploads/1/a.txt", Size: 10},
+		{SourceName: "uploads/1/b.txt", Size: 10},
+		{SourceName: "uploads/1/c.txt", Size: 10},
+	}
+	objects := []response.Object{
+		{RelativePath: "a.txt", Size: 10},
+		{RelativePath: "b.txt", Size: 5},
+		{RelativePath: "d.txt", Size: 1},
+		{RelativePath: "a.txt._thumb", Size: 1},
+		{RelativePath: "sub", IsDir: true},
+	}
+
+	res := auditDirectory("uploads/1", files, objects, "._thumb")
+	asserts.Len(res.missing, 1)
+	asserts.Equal("uploads/1/c.txt", res.missing[0].SourceName)
+	asserts.Len(res.sizeMismatch, 1)
+	asserts.Equal("uploads/1/b.txt", res.sizeMismatch[0].file.SourceName)
+	asserts.EqualValues(5, res.sizeMismatch[0].actual)
+	asserts.Equal([]string{"uploads/1/d.txt"}, res.orphans)
+}
+
+func TestStorageAuditTask_ReportOrphans(t *testing.T) {
+	asserts := assert.New(t)
+	task := &StorageAuditTask{User: &model.User{}}
+
+	// 被其他用户的文件引用的对象不视为孤立对象
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WithArgs(1, "a", "b").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "source_name"}).AddRow(1, "a"))
+		task.reportOrphans(1, []string{"a", "b"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(task.report.Items, 1)
+		asserts.Equal("b", task.report.Items[0].Path)
+		asserts.Equal(ReportOrphanObject, task.report.Items[0].Result)
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)files(.+)").WillReturnError(errors.New("error"))
+		task.reportOrphans(1, []string{"dir/c"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(task.report.Items, 2)
+		asserts.Equal(ReportFailed, task.report.Items[1].Result)
+	}
+}
+
+func TestStorageAuditTask_CheckStorage(t *testing.T) {
+	asserts := assert.New(t)
+	user := &model.User{Storage: 10}
+	user.ID = 1
+
+	// 一致
+	{
+		task := &StorageAuditTask{User: user}
+		mock.ExpectQuery("SELECT coalesce(.+)").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(10))
+		task.checkStorage()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Empty(task.report.Items)
+	}
+
+	// 不一致，只报告
+	{
+		task := &StorageAuditTask{User: user}
+		mock.ExpectQuery("SELECT coalesce(.+)").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(6))
+		task.checkStorage()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Len(task.report.Items, 1)
+		asserts.Equal(ReportStorageMismatch, task.report.Items[0].Result)
+		asserts.EqualValues(10, user.Storage)
+	}
+
+	// 不一致，修复
+	{
+		task := &StorageAuditTask{User: user, TaskProps: StorageAuditProps{Fix: true}}
+		mock.ExpectQuery("SELECT coalesce(.+)").WillReturnRows(sqlmock.NewRows([]string{"total"}).AddRow(6))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)storage(.+)").WithArgs(6, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		task.checkStorage()
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Contains(task.report.Items[0].Reason, "fixed")
+		asserts.EqualValues(6, user.Storage)
+	}
+}
+
+func TestNewStorageAuditTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 用户不存在
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnError(errors.New("error"))
+		job, err := NewStorageAuditTaskFromModel(&model.Task{UserID: 1})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)users(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewStorageAuditTaskFromModel(&model.Task{Model: gorm.Model{ID: 1}, UserID: 1, Props: `{"fix":true}`})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.True(job.(*StorageAuditTask).TaskProps.Fix)
+	}
+}
@@ -0,0 +1,231 @@
+package task
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// TakeoutTask 导出用户全部文件及元数据的任务
+type TakeoutTask struct {
+	User      *model.User
+	TaskModel *model.Task
+	TaskProps TakeoutProps
+	Err       *JobError
+	jobContext
+
+	archivePath string
+}
+
+// TakeoutProps 导出任务属性
+type TakeoutProps struct {
+	Archive string `json:"archive,omitempty"` // 生成的压缩包文件名，位于临时目录下
+}
+
+// TakeoutArchiveRoot 返回导出压缩包的存储目录
+func TakeoutArchiveRoot() string {
+	return filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "takeout")
+}
+
+// TakeoutArchivePath 返回导出压缩包的存储路径
+func TakeoutArchivePath(name string) string {
+	return filepath.Join(TakeoutArchiveRoot(), filepath.Base(name))
+}
+
+// Props 获取任务属性
+func (job *TakeoutTask) Props() string {
+	res, _ := json.Marshal(job.TaskProps)
+	return string(res)
+}
+
+// Type 获取任务类型
+func (job *TakeoutTask) Type() int {
+	return TakeoutTaskType
+}
+
+// Creator 获取创建者ID
+func (job *TakeoutTask) Creator() uint {
+	return job.User.ID
+}
+
+// Owner 获取任务所属用户
+func (job *TakeoutTask) Owner() *model.User {
+	return job.User
+}
+
+// Model 获取任务的数据库模型
+func (job *TakeoutTask) Model() *model.Task {
+	return job.TaskModel
+}
+
+// SetStatus 设定状态
+func (job *TakeoutTask) SetStatus(status int) {
+	job.TaskModel.SetStatus(status)
+}
+
+// SetError 设定任务失败信息
+func (job *TakeoutTask) SetError(err *JobError) {
+	job.Err = err
+	res, _ := json.Marshal(job.Err)
+	job.TaskModel.SetError(string(res))
+
+	// 删除未完成的压缩包
+	job.removeArchive()
+}
+
+func (job *TakeoutTask) removeArchive() {
+	if job.archivePath != "" {
+		if err := os.Remove(job.archivePath); err != nil {
+			util.Log().Warning("无法删除导出压缩包 %s , %s", job.archivePath, err)
+		}
+	}
+}
+
+// Cleanup 任务中止后删除未完成的压缩包
+func (job *TakeoutTask) Cleanup() {
+	job.removeArchive()
+}
+
+// SetErrorMsg 设定任务失败信息
+func (job *TakeoutTask) SetErrorMsg(msg string) {
+	job.SetError(&JobError{Msg: msg})
+}
+
+// GetError 返回任务失败信息
+func (job *TakeoutTask) GetError() *JobError {
+	return job.Err
+}
+
+// Do 开始执行任务
+func (job *TakeoutTask) Do() {
+	fs, err := filesystem.NewFileSystem(job.User)
+	if err != nil {
+		job.SetErrorMsg(err.Error())
+		return
+	}
+	defer fs.Recycle()
+
+	job.TaskModel.SetProgress(ListingProgress)
+	metadata, err := takeoutMetadata(job.User)
+	if err != nil {
+		job.SetError(&JobError{Msg: "Failed to export metadata", Error: err.Error()})
+		return
+	}
+
+	// 创建压缩包
+	job.TaskModel.SetProgress(CompressingProgress)
+	name := fmt.Sprintf("takeout_%d_%d.zip", job.User.ID, time.Now().UnixNano())
+	job.archivePath = TakeoutArchivePath(name)
+	archive, err := util.CreatNestedFile(job.archivePath)
+	if err != nil {
+		job.archivePath = ""
+		job.SetErrorMsg(err.Error())
+		return
+	}
+	defer archive.Close()
+
+	if err := fs.Takeout(job.Context(), archive, metadata); err != nil {
+		archive.Close()
+		job.SetErrorMsg(err.Error())
+		return
+	}
+
+	if err := archive.Close(); err != nil {
+		job.SetErrorMsg(err.Error())
+		return
+	}
+
+	job.TaskProps.Archive = name
+	job.TaskModel.SetProps(job.Props())
+}
+
+// takeoutMetadata 导出用户的个人资料、分享、任务及操作记录
+func takeoutMetadata(user *model.User) (map[string][]byte, error) {
+	var (
+		shares    []model.Share
+		tasks     []model.Task
+		downloads []model.Download
+		tags      []model.Tag
+		activity  []model.AuditLog
+	)
+
+	records := []struct {
+		name string
+		list interface{}
+	}{
+		{"shares.json", &shares},
+		{"tasks.json", &tasks},
+		{"downloads.json", &downloads},
+		{"tags.json", &tags},
+		{"activity.json", &activity},
+	}
+
+	metadata := make(map[string][]byte, len(records)+1)
+	for _, record := range records {
+		if err := model.ListUserRecords(user.ID, record.list); err != nil {
+			return nil, err
+		}
+
+		content, err := json.MarshalIndent(record.list, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		metadata["metadata/"+record.name] = content
+	}
+
+	profile, err := json.MarshalIndent(map[string]interface{}{
+		"id":          user.ID,
+		"email":       user.Email,
+		"nickname":    user.Nick,
+		"group":       user.Group.Name,
+		"storage":     user.Storage,
+		"created_at":  user.CreatedAt,
+		"exported_at": time.Now(),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	metadata["metadata/profile.json"] = profile
+
+	return metadata, nil
+}
+
+// NewTakeoutTask 新建导出任务
+func NewTakeoutTask(user *model.User) (Job, error) {
+	newTask := &TakeoutTask{
+		User: user,
+	}
+
+	record, err := Record(newTask)
+	if err != nil {
+		return nil, err
+	}
+	newTask.TaskModel = record
+
+	return newTask, nil
+}
+
+// NewTakeoutTaskFromModel 从数据库记录中恢复导出任务
+func NewTakeoutTaskFromModel(task *model.Task) (Job, error) {
+	user, err := model.GetActiveUserByID(task.UserID)
+	if err != nil {
+		return nil, err
+	}
+	newTask := &TakeoutTask{
+		User:      &user,
+		TaskModel: task,
+	}
+
+	err = json.Unmarshal([]byte(task.Props), &newTask.TaskProps)
+	if err != nil {
+		return nil, err
+	}
+
+	return newTask, nil
+}
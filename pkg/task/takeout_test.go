@@ -0,0 +1,132 @@
+package task
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTakeoutTask_Props(t *testing.T) {
+	asserts := assert.New(t)
+	task := &TakeoutTask{
+		User: &model.User{},
+	}
+	asserts.Equal("{}", task.Props())
+	asserts.Equal(TakeoutTaskType, task.Type())
+	asserts.EqualValues(0, task.Creator())
+	asserts.Nil(task.Model())
+
+	task.TaskProps.Archive = "takeout_1_1.zip"
+	asserts.Contains(task.Props(), "takeout_1_1.zip")
+}
+
+func TestTakeoutTask_SetError(t *testing.T) {
+	asserts := assert.New(t)
+	task := &TakeoutTask{
+		User: &model.User{},
+		TaskModel: &model.Task{
+			Model: gorm.Model{ID: 1},
+		},
+		archivePath: "test/TestTakeoutTask_SetError",
+	}
+	archive, _ := util.CreatNestedFile("test/TestTakeoutTask_SetError")
+	archive.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	task.SetErrorMsg("error")
+	asserts.NoError(mock.ExpectationsWereMet())
+	asserts.False(util.Exists("test/TestTakeoutTask_SetError"))
+	asserts.Equal("error", task.GetError().Msg)
+}
+
+func TestTakeoutArchivePath(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_temp_path", "test", 0)
+	asserts.Equal(filepath.Join(TakeoutArchiveRoot(), "takeout_1_1.zip"), TakeoutArchivePath("../../takeout_1_1.zip"))
+}
+
+func TestTakeoutMetadata(t *testing.T) {
+	asserts := assert.New(t)
+	user := &model.User{Email: "user@cloudreve.org"}
+	user.ID = 1
+
+	// 成功
+	{
+		for i := 0; i < 5; i++ {
+			mock.ExpectQuery("SELECT(.+)").WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		}
+		metadata, err := takeoutMetadata(user)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.Len(metadata, 6)
+		asserts.Contains(string(metadata["metadata/profile.json"]), "user@cloudreve.org")
+		asserts.Contains(metadata, "metadata/shares.json")
+	}
+
+	// 数据库错误
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnError(errors.New("error"))
+		metadata, err := takeoutMetadata(user)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(metadata)
+	}
+}
+
+func TestNewTakeoutTask(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+		job, err := NewTakeoutTask(&model.User{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NotNil(job)
+		asserts.NoError(err)
+	}
+
+	// 失败
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		job, err := NewTakeoutTask(&model.User{})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Nil(job)
+		asserts.Error(err)
+	}
+}
+
+func TestNewTakeoutTaskFromModel(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewTakeoutTaskFromModel(&model.Task{Props: "{}"})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.NoError(err)
+		asserts.NotNil(job)
+	}
+
+	// JSON解析失败
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		job, err := NewTakeoutTaskFromModel(&model.Task{Props: ""})
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Error(err)
+		asserts.Nil(job)
+	}
+}
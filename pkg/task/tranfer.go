@@ -1,12 +1,13 @@
 package task
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
@@ -21,8 +22,10 @@ type TransferTask struct {
 	TaskModel *model.Task
 	TaskProps TransferProps
 	Err       *JobError
+	jobContext
 
 	zipPath string
+	report  Report
 }
 
 // TransferProps 中转任务属性
@@ -35,6 +38,8 @@ type TransferProps struct {
 	TrimPath bool `json:"trim_path"`
 	// 负责处理中专任务的节点ID
 	NodeID uint `json:"node_id"`
+	// 已完成转存的原始文件，任务中断恢复后跳过这些文件
+	Finished map[string]bool `json:"finished,omitempty"`
 }
 
 // Props 获取任务属性
@@ -53,6 +58,11 @@ func (job *TransferTask) Creator() uint {
 	return job.User.ID
 }
 
+// Owner 获取任务所属用户
+func (job *TransferTask) Owner() *model.User {
+	return job.User
+}
+
 // Model 获取任务的数据库模型
 func (job *TransferTask) Model() *model.Task {
 	return job.TaskModel
@@ -89,54 +99,149 @@ func (job *TransferTask) GetError() *JobError {
 func (job *TransferTask) Do() {
 	defer job.Recycle()
 
-	// 创建文件系统
-	fs, err := filesystem.NewFileSystem(job.User)
+	// 检查能否创建文件系统
+	fs, err := job.newFileSystem()
 	if err != nil {
 		job.SetErrorMsg(err.Error(), nil)
 		return
 	}
+	fs.Recycle()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+
+	if job.TaskProps.Finished == nil {
+		job.TaskProps.Finished = make(map[string]bool)
+	}
+	successCount := len(job.TaskProps.Finished)
 
-	successCount := 0
+	// 同时转存多个文件
+	parallel := model.GetIntSetting("max_parallel_transfer", 4)
+	if parallel < 1 {
+		parallel = 1
+	}
+	worker := make(chan int, parallel)
+
+	// 跳过上次执行中已完成的文件，转存线程会写入 Finished，须在启动线程前确定待转存列表
+	pending := make([]string, 0, len(job.TaskProps.Src))
 	for _, file := range job.TaskProps.Src {
-		dst := path.Join(job.TaskProps.Dst, filepath.Base(file))
-		if job.TaskProps.TrimPath {
-			// 保留原始目录
-			trim := util.FormSlash(job.TaskProps.Parent)
-			src := util.FormSlash(file)
-			dst = path.Join(job.TaskProps.Dst, strings.TrimPrefix(src, trim))
+		if !job.TaskProps.Finished[file] {
+			pending = append(pending, file)
+		}
+	}
+
+	for _, file := range pending {
+		// 任务已被中止
+		if job.Context().Err() != nil {
+			break
 		}
 
-		if job.TaskProps.NodeID > 1 {
-			// 指定为从机中转
+		worker <- 1
+		wg.Add(1)
+		go func(file string) {
+			defer func() {
+				<-worker
+				wg.Done()
+			}()
 
-			// 获取从机节点
-			node := cluster.Default.GetNodeByID(job.TaskProps.NodeID)
-			if node == nil {
-				job.SetErrorMsg("从机节点不可用", nil)
+			// 各线程使用独立的文件系统，避免并发上传时互相切换存储策略及处理器
+			fs, err := job.newFileSystem()
+			if err == nil {
+				err = job.transferFile(fs, file)
+				fs.Recycle()
 			}
 
-			// 切换为从机节点处理上传
-			fs.SwitchToSlaveHandler(node)
-			err = fs.UploadFromStream(context.Background(), &fsctx.FileStream{
-				File:        nil,
-				Size:        job.TaskProps.SrcSizes[file],
-				Name:        path.Base(dst),
-				VirtualPath: path.Dir(dst),
-				Src:         file,
-			}, false)
-		} else {
-			// 主机节点中转
-			err = fs.UploadFromPath(context.Background(), file, dst, 0)
-		}
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				job.report.Add(file, ReportFailed, err)
+				job.SetErrorMsg("文件转存失败", err)
+			} else {
+				successCount++
+				job.TaskProps.Finished[file] = true
+				job.TaskModel.SetProps(job.Props())
+				job.TaskModel.SetProgress(successCount)
+			}
+		}(file)
+	}
 
-		if err != nil {
-			job.SetErrorMsg("文件转存失败", err)
-		} else {
-			successCount++
-			job.TaskModel.SetProgress(successCount)
+	wg.Wait()
+	job.report.Save(job.TaskModel)
+}
+
+// newFileSystem 创建用于转存的文件系统，指定从机中转时切换为从机节点处理上传
+func (job *TransferTask) newFileSystem() (*filesystem.FileSystem, error) {
+	user := *job.User
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.TaskProps.NodeID > 1 {
+		// 获取从机节点
+		node := cluster.Default.GetNodeByID(job.TaskProps.NodeID)
+		if node == nil {
+			fs.Recycle()
+			return nil, errors.New("从机节点不可用")
 		}
+
+		// 切换为从机节点处理上传
+		fs.SwitchToSlaveHandler(node)
+	}
+
+	return fs, nil
+}
+
+// transferFile 转存单个文件
+func (job *TransferTask) transferFile(fs *filesystem.FileSystem, file string) error {
+	dst := path.Join(job.TaskProps.Dst, filepath.Base(file))
+	if job.TaskProps.TrimPath {
+		// 保留原始目录
+		trim := util.FormSlash(job.TaskProps.Parent)
+		src := util.FormSlash(file)
+		dst = path.Join(job.TaskProps.Dst, strings.TrimPrefix(src, trim))
+	}
+
+	// 文件已在上次中断前上传完成，但未来得及记录状态
+	if exist, existed := fs.IsFileExist(dst); exist && existed.UploadSessionID == nil &&
+		existed.Size == job.srcSize(file) {
+		util.Log().Info("中转任务跳过已存在的文件 [%s]", dst)
+		return nil
+	}
+
+	if job.TaskProps.NodeID > 1 {
+		// 由从机节点上传
+		return fs.UploadFromStream(job.Context(), &fsctx.FileStream{
+			File:        nil,
+			Size:        job.TaskProps.SrcSizes[file],
+			Name:        path.Base(dst),
+			VirtualPath: path.Dir(dst),
+			Src:         file,
+		}, false)
+	}
+
+	// 主机节点中转
+	return fs.UploadFromPath(job.Context(), file, dst, 0)
+}
+
+// srcSize 获取原始文件大小
+func (job *TransferTask) srcSize(file string) uint64 {
+	if job.TaskProps.NodeID > 1 {
+		return job.TaskProps.SrcSizes[file]
 	}
 
+	fi, err := os.Stat(util.RelativePath(file))
+	if err != nil {
+		return 0
+	}
+	return uint64(fi.Size())
+}
+
+// Cleanup 任务中止后回收临时文件
+func (job *TransferTask) Cleanup() {
+	job.Recycle()
 }
 
 // Recycle 回收临时文件
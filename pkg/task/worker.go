@@ -1,10 +1,21 @@
 package task
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+// TimeoutGracePeriod 任务超时中止后等待其结束的宽限期
+var TimeoutGracePeriod = 30 * time.Second
+
 // Worker 处理任务的对象
 type Worker interface {
 	Do(Job) // 执行任务
@@ -14,17 +25,140 @@ type Worker interface {
 type GeneralWorker struct {
 }
 
+// logger 任务执行时使用的 Logger，附加任务 ID 及创建任务的请求 ID
+func logger(job Job) *util.Logger {
+	l := util.Log().Module("task")
+	if record := job.Model(); record != nil {
+		l = l.WithField("task", record.ID).WithRequestID(record.RequestID)
+	}
+	return l
+}
+
+// startSpan 开始记录任务执行的 Span，父 Span 为创建任务的请求或离线下载监控，
+// 返回的上下文同时附加创建任务的请求 ID
+func startSpan(job Job) (context.Context, trace.Span) {
+	ctx := context.Background()
+	var attrs []attribute.KeyValue
+	if record := job.Model(); record != nil {
+		ctx = tracing.Extract(ctx, record.TraceParent)
+		if record.RequestID != "" {
+			ctx = util.WithRequestID(ctx, record.RequestID)
+		}
+		attrs = append(attrs,
+			attribute.Int64("task.id", int64(record.ID)),
+			attribute.Int("task.type", record.Type),
+		)
+	}
+
+	return tracing.Start(ctx, "task.run", trace.WithAttributes(attrs...))
+}
+
 // Do 执行任务
 func (worker *GeneralWorker) Do(job Job) {
-	util.Log().Debug("开始执行任务")
+	logger(job).Debug("开始执行任务")
+	ctx, span := startSpan(job)
+	handedOff := false
+	defer func() {
+		if !handedOff {
+			endSpan(job, span)
+		}
+	}()
+
 	job.SetStatus(Processing)
+	Trigger(HookOnStart, job)
+
+	// 未设定超时时间的任务直接执行
+	ctxJob, ok := job.(ContextJob)
+	if !ok {
+		worker.finish(job, worker.run(job))
+		return
+	}
+
+	// 所有后台任务共同分摊读写速率上限
+	ctx = filesystem.WithIOLimit(ctx, IOLimit())
+
+	timeout := Timeout(job.Type())
+	if timeout <= 0 {
+		ctxJob.SetContext(ctx)
+		worker.finish(job, worker.run(job))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ctxJob.SetContext(ctx)
 
+	result := make(chan int, 1)
+	go func() {
+		result <- worker.run(job)
+	}()
+
+	select {
+	case status := <-result:
+		worker.finish(job, status)
+	case <-ctx.Done():
+		logger(job).Warning("任务执行超过 %s，已中止", timeout)
+
+		// 任务仍可能在写入自身状态，须等待其结束后再写入最终状态并清理。
+		// 超过宽限期仍未结束时先释放执行槽位，待任务结束后再处理
+		err := ctx.Err()
+		select {
+		case <-result:
+			worker.timedOut(job, err)
+		case <-time.After(TimeoutGracePeriod):
+			logger(job).Warning("任务中止后 %s 内仍未结束", TimeoutGracePeriod)
+			handedOff = true
+			go func() {
+				<-result
+				worker.timedOut(job, err)
+				endSpan(job, span)
+			}()
+		}
+	}
+}
+
+// endSpan 结束任务的 Span，任务出错时记录错误
+func endSpan(job Job, span trace.Span) {
+	if err := job.GetError(); err != nil {
+		tracing.End(span, errors.New(err.Msg))
+		return
+	}
+	span.End()
+}
+
+// timedOut 设定已结束的超时任务的最终状态并清理
+func (worker *GeneralWorker) timedOut(job Job, err error) {
+	job.SetError(&JobError{Msg: "任务执行超时", Error: err.Error()})
+	job.SetStatus(TimedOut)
+	Trigger(HookOnFailure, job)
+	worker.cleanup(job)
+}
+
+// cleanup 回收超时任务的临时资源
+func (worker *GeneralWorker) cleanup(job Job) {
+	if cleaner, ok := job.(Cleaner); ok {
+		cleaner.Cleanup()
+	}
+}
+
+// finish 设定任务最终状态并触发对应钩子
+func (worker *GeneralWorker) finish(job Job, status int) {
+	job.SetStatus(status)
+	if status == Complete {
+		Trigger(HookOnSuccess, job)
+	} else {
+		Trigger(HookOnFailure, job)
+	}
+}
+
+// run 执行任务，返回任务结束后应处于的状态
+func (worker *GeneralWorker) run(job Job) (status int) {
 	defer func() {
 		// 致命错误捕获
 		if err := recover(); err != nil {
-			util.Log().Debug("任务执行出错，%s", err)
+			logger(job).Debug("任务执行出错，%s", err)
 			job.SetError(&JobError{Msg: "致命错误", Error: fmt.Sprintf("%s", err)})
-			job.SetStatus(Error)
+			status = Error
 		}
 	}()
 
@@ -33,12 +167,11 @@ func (worker *GeneralWorker) Do(job Job) {
 
 	// 任务执行失败
 	if err := job.GetError(); err != nil {
-		util.Log().Debug("任务执行出错")
-		job.SetStatus(Error)
-		return
+		logger(job).Debug("任务执行出错")
+		return Error
 	}
 
-	util.Log().Debug("任务执行完成")
+	logger(job).Debug("任务执行完成")
 	// 执行完成
-	job.SetStatus(Complete)
+	return Complete
 }
@@ -1,9 +1,13 @@
 package task
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -26,7 +30,7 @@ func (job *MockJob) Props() string {
 }
 
 func (job *MockJob) Model() *model.Task {
-	panic("implement me")
+	return nil
 }
 
 func (job *MockJob) SetStatus(status int) {
@@ -79,3 +83,96 @@ func TestGeneralWorker_Do(t *testing.T) {
 	}
 
 }
+
+type MockContextJob struct {
+	MockJob
+	ctx       context.Context
+	cleaned   bool
+	onCleanup func()
+}
+
+func (job *MockContextJob) Type() int {
+	return CompressTaskType
+}
+
+func (job *MockContextJob) SetContext(ctx context.Context) {
+	job.ctx = ctx
+}
+
+func (job *MockContextJob) SetError(err *JobError) {
+	job.Err = err
+}
+
+func (job *MockContextJob) Cleanup() {
+	job.cleaned = true
+	if job.onCleanup != nil {
+		job.onCleanup()
+	}
+}
+
+func TestGeneralWorker_DoTimeout(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	cache.Set("setting_compress_task_timeout", "1", 0)
+	defer cache.Deletes([]string{"compress_task_timeout"}, "setting_")
+
+	// 超时
+	{
+		job := &MockContextJob{}
+		job.DoFunc = func() {
+			<-job.ctx.Done()
+			time.Sleep(100 * time.Millisecond)
+		}
+		worker.Do(job)
+		asserts.Equal(TimedOut, job.Status)
+		asserts.True(job.cleaned)
+		asserts.Equal("任务执行超时", job.Err.Msg)
+	}
+
+	// 超过宽限期仍未结束，先释放槽位，结束后再写入最终状态并清理
+	{
+		TimeoutGracePeriod = 50 * time.Millisecond
+		defer func() { TimeoutGracePeriod = 30 * time.Second }()
+
+		finished := make(chan struct{})
+		cleaned := make(chan bool, 1)
+		job := &MockContextJob{}
+		job.onCleanup = func() { cleaned <- true }
+		job.DoFunc = func() {
+			<-finished
+			// 中止后任务仍在写入自身状态
+			job.SetStatus(Error)
+		}
+		worker.Do(job)
+
+		close(finished)
+		asserts.True(<-cleaned)
+		asserts.Equal(TimedOut, job.Status)
+		asserts.Equal("任务执行超时", job.Err.Msg)
+	}
+
+	// 未超时
+	{
+		job := &MockContextJob{}
+		job.DoFunc = func() {}
+		worker.Do(job)
+		asserts.Equal(Complete, job.Status)
+		asserts.False(job.cleaned)
+	}
+}
+
+func TestGeneralWorker_DoIOLimit(t *testing.T) {
+	asserts := assert.New(t)
+	worker := &GeneralWorker{}
+	cache.Set("setting_task_io_limit", "1024", 0)
+	defer cache.Deletes([]string{"task_io_limit"}, "setting_")
+
+	var limit int64
+	job := &MockContextJob{}
+	job.DoFunc = func() {
+		limit = filesystem.IOLimit(job.ctx)
+	}
+	worker.Do(job)
+	asserts.Equal(Complete, job.Status)
+	asserts.EqualValues(1024, limit)
+}
@@ -77,15 +77,19 @@ func (image *Thumb) Save(path string) (err error) {
 		return err
 	}
 	defer out.Close()
+
+	_, err = image.EncodeThumb(out)
+	return err
+}
+
+// EncodeThumb 按缩略图的编码设置写入 w，返回对应的 MIME 类型
+func (image *Thumb) EncodeThumb(w io.Writer) (string, error) {
 	switch model.GetSettingByNameWithDefault("thumb_encode_method", "jpg") {
 	case "png":
-		err = png.Encode(out, image.src)
+		return "image/png", png.Encode(w, image.src)
 	default:
-		err = jpeg.Encode(out, image.src, &jpeg.Options{Quality: model.GetIntSetting("thumb_encode_quality", 85)})
+		return "image/jpeg", jpeg.Encode(w, image.src, &jpeg.Options{Quality: model.GetIntSetting("thumb_encode_quality", 85)})
 	}
-
-	return err
-
 }
 
 // Thumbnail will downscale provided image to max width and height preserving
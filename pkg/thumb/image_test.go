@@ -1,9 +1,11 @@
 package thumb
 
 import (
+	"bytes"
 	"fmt"
 	"image"
 	"image/jpeg"
+	"image/png"
 	"os"
 	"testing"
 
@@ -126,6 +128,23 @@ func TestThumb_Save(t *testing.T) {
 
 }
 
+func TestThumb_EncodeThumb(t *testing.T) {
+	asserts := assert.New(t)
+	file := CreateTestImage()
+	defer file.Close()
+	thumb, err := NewThumbFromFile(file, "123.jpg")
+	asserts.NoError(err)
+
+	cache.Set("setting_thumb_encode_method", "png", 0)
+	defer cache.Deletes([]string{"thumb_encode_method"}, "setting_")
+	buf := &bytes.Buffer{}
+	contentType, err := thumb.EncodeThumb(buf)
+	asserts.NoError(err)
+	asserts.Equal("image/png", contentType)
+	_, err = png.Decode(buf)
+	asserts.NoError(err)
+}
+
 func TestThumb_CreateAvatar(t *testing.T) {
 	asserts := assert.New(t)
 	file := CreateTestImage()
@@ -0,0 +1,75 @@
+package thumb
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// 水印颜色，半透明灰色
+var watermarkColor = color.NRGBA{R: 128, G: 128, B: 128, A: 96}
+
+// Watermark 在图像上平铺半透明水印文字，仅支持 ASCII 字符
+func (image *Thumb) Watermark(text string) {
+	image.src = Watermark(image.src, text)
+}
+
+// Encode 按原图像格式编码并写入 w，返回对应的 MIME 类型，gif 图像以 png 格式输出
+func (image *Thumb) Encode(w io.Writer) (string, error) {
+	switch image.ext {
+	case "jpg", "jpeg":
+		return "image/jpeg", jpeg.Encode(w, image.src, &jpeg.Options{Quality: 90})
+	default:
+		return "image/png", png.Encode(w, image.src)
+	}
+}
+
+// Watermark 返回平铺了水印文字的新图像，文字宽度约为图像宽度的三分之一，
+// 奇偶行错开排列
+func Watermark(img image.Image, text string) image.Image {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Rect, img, bounds.Min, draw.Src)
+
+	// 绘制文字蒙版
+	face := basicfont.Face7x13
+	drawer := &font.Drawer{Face: face}
+	width := drawer.MeasureString(text).Ceil()
+	height := face.Metrics().Height.Ceil()
+	if width == 0 {
+		return dst
+	}
+
+	mask := image.NewAlpha(image.Rect(0, 0, width, height))
+	drawer.Dst = mask
+	drawer.Src = image.Opaque
+	drawer.Dot = fixed.P(0, face.Metrics().Ascent.Ceil())
+	drawer.DrawString(text)
+
+	// 按图像尺寸放大蒙版
+	scale := float64(bounds.Dx()) / 3 / float64(width)
+	if scale < 1 {
+		scale = 1
+	}
+	scaled := image.NewAlpha(image.Rect(0, 0, int(float64(width)*scale), int(float64(height)*scale)))
+	draw.BiLinear.Scale(scaled, scaled.Rect, mask, mask.Rect, draw.Src, nil)
+
+	// 平铺水印
+	src := image.NewUniform(watermarkColor)
+	tileWidth, tileHeight := scaled.Rect.Dx(), scaled.Rect.Dy()
+	for row, y := 0, tileHeight; y < dst.Rect.Dy(); row, y = row+1, y+tileHeight*3 {
+		for x := -(row % 2) * tileWidth; x < dst.Rect.Dx(); x += tileWidth * 2 {
+			rect := image.Rect(x, y, x+tileWidth, y+tileHeight)
+			draw.DrawMask(dst, rect, src, image.Point{}, scaled, image.Point{}, draw.Over)
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,75 @@
+package thumb
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatermark(t *testing.T) {
+	asserts := assert.New(t)
+	src := image.NewRGBA(image.Rect(10, 10, 310, 210))
+	for x := 10; x < 310; x++ {
+		for y := 10; y < 210; y++ {
+			src.Set(x, y, color.White)
+		}
+	}
+
+	// 空文字
+	{
+		res := Watermark(src, "")
+		asserts.Equal(image.Rect(0, 0, 300, 200), res.Bounds())
+		asserts.Equal(color.RGBAModel.Convert(color.White), res.At(100, 100))
+	}
+
+	// 绘制文字
+	{
+		res := Watermark(src, "127.0.0.1")
+		asserts.Equal(image.Rect(0, 0, 300, 200), res.Bounds())
+
+		changed := 0
+		for x := 0; x < 300; x++ {
+			for y := 0; y < 200; y++ {
+				if res.At(x, y) != color.RGBAModel.Convert(color.White) {
+					changed++
+				}
+			}
+		}
+		asserts.NotZero(changed)
+		asserts.Less(changed, 300*200/2)
+	}
+}
+
+func TestThumb_Encode(t *testing.T) {
+	asserts := assert.New(t)
+	file := CreateTestImage()
+	defer file.Close()
+
+	// jpg
+	{
+		thumb, err := NewThumbFromFile(file, "123.jpg")
+		asserts.NoError(err)
+		thumb.Watermark("test")
+
+		var buf bytes.Buffer
+		contentType, err := thumb.Encode(&buf)
+		asserts.NoError(err)
+		asserts.Equal("image/jpeg", contentType)
+		asserts.NotZero(buf.Len())
+	}
+
+	// 其他格式
+	{
+		thumb := &Thumb{src: image.NewRGBA(image.Rect(0, 0, 10, 10)), ext: "gif"}
+		var buf bytes.Buffer
+		contentType, err := thumb.Encode(&buf)
+		asserts.NoError(err)
+		asserts.Equal("image/png", contentType)
+		_, err = png.Decode(&buf)
+		asserts.NoError(err)
+	}
+}
@@ -0,0 +1,80 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/jinzhu/gorm"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ContextKey 通过 db.Set 指定数据库操作所属链路的上下文，未指定时作为新链路记录
+	ContextKey = "tracing:context"
+	// spanKey 保存进行中的 Span 的实例设置名
+	spanKey = "tracing:span"
+)
+
+// RegisterCallbacks 为数据库的增删改查注册记录 Span 的回调
+func RegisterCallbacks(db *gorm.DB) {
+	callback := db.Callback()
+	operations := []struct {
+		name      string
+		processor func() *gorm.CallbackProcessor
+		target    string
+	}{
+		{"create", callback.Create, "gorm:create"},
+		{"query", callback.Query, "gorm:query"},
+		{"update", callback.Update, "gorm:update"},
+		{"delete", callback.Delete, "gorm:delete"},
+		{"row_query", callback.RowQuery, "gorm:row_query"},
+	}
+
+	// 每次注册需使用新的 CallbackProcessor
+	for _, op := range operations {
+		op.processor().Before(op.target).Register("tracing:before_"+op.name, beforeCallback(op.name))
+		op.processor().After(op.target).Register("tracing:after_"+op.name, afterCallback)
+	}
+}
+
+func beforeCallback(operation string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		ctx := context.Background()
+		if v, ok := scope.Get(ContextKey); ok {
+			if parent, ok := v.(context.Context); ok {
+				ctx = parent
+			}
+		}
+
+		_, span := Start(ctx, "db."+operation,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				semconv.DBSystemKey.String(scope.Dialect().GetName()),
+				semconv.DBOperationKey.String(operation),
+				semconv.DBSQLTableKey.String(scope.TableName()),
+			),
+		)
+		scope.InstanceSet(spanKey, span)
+	}
+}
+
+func afterCallback(scope *gorm.Scope) {
+	v, ok := scope.InstanceGet(spanKey)
+	if !ok {
+		return
+	}
+
+	span, ok := v.(trace.Span)
+	if !ok {
+		return
+	}
+
+	span.SetAttributes(semconv.DBStatementKey.String(scope.SQL))
+
+	// 未找到记录属于正常的查询结果
+	var err error
+	if scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error) {
+		err = scope.DB().Error
+	}
+	End(span, err)
+}
@@ -0,0 +1,150 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName 记录 Span 使用的 Tracer 名称
+const instrumentationName = "github.com/cloudreve/Cloudreve/v3"
+
+// traceParentHeader W3C Trace Context 的请求头
+const traceParentHeader = "traceparent"
+
+var (
+	provider   *sdktrace.TracerProvider
+	propagator = propagation.TraceContext{}
+)
+
+// Init 按配置初始化链路追踪，未设置接收端地址时不启用
+func Init() {
+	if conf.TracingConfig.Endpoint == "" {
+		return
+	}
+
+	opts := []otlptracehttp.Option{
+		otlptracehttp.WithEndpoint(conf.TracingConfig.Endpoint),
+	}
+	if conf.TracingConfig.URLPath != "" {
+		opts = append(opts, otlptracehttp.WithURLPath(conf.TracingConfig.URLPath))
+	}
+	if conf.TracingConfig.Insecure {
+		opts = append(opts, otlptracehttp.WithInsecure())
+	}
+
+	exporter, err := otlptracehttp.New(context.Background(), opts...)
+	if err != nil {
+		util.Log().Warning("无法初始化链路追踪导出器，%s", err)
+		return
+	}
+
+	res := resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceNameKey.String(conf.TracingConfig.ServiceName),
+		semconv.ServiceVersionKey.String(conf.BackendVersion),
+		attribute.String("cloudreve.mode", conf.SystemConfig.Mode),
+	)
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(conf.TracingConfig.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagator)
+
+	util.Log().Info("链路追踪已启用，导出至 %s", conf.TracingConfig.Endpoint)
+}
+
+// Enabled 是否启用了链路追踪
+func Enabled() bool {
+	return provider != nil
+}
+
+// Shutdown 导出尚未发送的 Span 并关闭链路追踪
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start 开始记录 Span，返回的上下文保留 ctx 中原有的值。
+// 对于 gin 的上下文，父 Span 从其对应的 HTTP 请求的上下文中获取
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	_, span := otel.Tracer(instrumentationName).Start(parentContext(ctx), name, opts...)
+	return trace.ContextWithSpan(ctx, span), span
+}
+
+// End 结束 Span，err 不为空时将 Span 标记为失败
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID 获取上下文中链路的 ID，未记录链路时返回空
+func TraceID(ctx context.Context) string {
+	sc := trace.SpanContextFromContext(parentContext(ctx))
+	if !sc.HasTraceID() {
+		return ""
+	}
+	return sc.TraceID().String()
+}
+
+// TraceParent 将上下文中的 Span 序列化为 traceparent，用于在异步任务、
+// 离线下载监控中延续链路，未记录链路时返回空
+func TraceParent(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	carrier := propagation.MapCarrier{}
+	propagator.Inject(parentContext(ctx), carrier)
+	return carrier.Get(traceParentHeader)
+}
+
+// Extract 将 traceparent 还原为上下文中的父 Span
+func Extract(ctx context.Context, traceParent string) context.Context {
+	if traceParent == "" {
+		return ctx
+	}
+	return propagator.Extract(ctx, propagation.MapCarrier{traceParentHeader: traceParent})
+}
+
+// ExtractHeader 从请求头中还原上游传递的父 Span
+func ExtractHeader(ctx context.Context, header http.Header) context.Context {
+	return propagator.Extract(ctx, propagation.HeaderCarrier(header))
+}
+
+// parentContext 获取包含父 Span 的上下文，gin 的上下文不会返回请求上下文中的值，
+// 需从其对应的 HTTP 请求中获取
+func parentContext(ctx context.Context) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+
+	if req, ok := ctx.Value(0).(*http.Request); ok && req != nil {
+		return req.Context()
+	}
+
+	return ctx
+}
@@ -0,0 +1,202 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useRecorder 启用记录至内存的链路追踪，测试结束后恢复
+func useRecorder(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider = nil
+		otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	})
+	return recorder
+}
+
+func TestDisabled(t *testing.T) {
+	asserts := assert.New(t)
+	provider = nil
+
+	ctx, span := Start(context.Background(), "test")
+	End(span, nil)
+	asserts.False(Enabled())
+	asserts.False(span.SpanContext().IsValid())
+	asserts.Empty(TraceParent(ctx))
+	asserts.Empty(TraceID(ctx))
+	asserts.NoError(Shutdown(context.Background()))
+}
+
+func TestTraceParent(t *testing.T) {
+	asserts := assert.New(t)
+	recorder := useRecorder(t)
+
+	// 序列化后在新的上下文中延续链路
+	{
+		ctx, parent := Start(context.Background(), "parent")
+		traceParent := TraceParent(ctx)
+		asserts.NotEmpty(traceParent)
+		asserts.Equal(parent.SpanContext().TraceID().String(), TraceID(ctx))
+
+		_, child := Start(Extract(context.Background(), traceParent), "child")
+		child.End()
+		parent.End()
+
+		ended := recorder.Ended()
+		asserts.Len(ended, 2)
+		asserts.Equal("child", ended[0].Name())
+		asserts.Equal(parent.SpanContext().SpanID(), ended[0].Parent().SpanID())
+		asserts.Equal(parent.SpanContext().TraceID(), ended[0].SpanContext().TraceID())
+	}
+
+	// 空 traceparent
+	{
+		ctx := context.Background()
+		asserts.Equal(ctx, Extract(ctx, ""))
+	}
+}
+
+func TestStart_GinContext(t *testing.T) {
+	asserts := assert.New(t)
+	recorder := useRecorder(t)
+
+	reqCtx, parent := Start(context.Background(), "request")
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil).WithContext(reqCtx)
+	c.Set("key", "value")
+
+	ctx, span := Start(c, "handler")
+	span.End()
+	parent.End()
+
+	asserts.Equal("value", ctx.Value("key"))
+	asserts.Equal(span.SpanContext(), trace.SpanContextFromContext(ctx))
+	asserts.Equal(parent.SpanContext().SpanID(), recorder.Ended()[0].Parent().SpanID())
+}
+
+func TestEnd(t *testing.T) {
+	asserts := assert.New(t)
+	recorder := useRecorder(t)
+
+	_, span := Start(context.Background(), "failed")
+	End(span, errors.New("error"))
+
+	ended := recorder.Ended()
+	asserts.Len(ended, 1)
+	asserts.Equal(codes.Error, ended[0].Status().Code)
+	asserts.Equal("error", ended[0].Status().Description)
+}
+
+func TestTransport(t *testing.T) {
+	asserts := assert.New(t)
+	var received string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: NewTransport(nil)}
+
+	// 未启用时不传递链路
+	{
+		resp, err := client.Get(server.URL + "/path?sign=secret")
+		asserts.NoError(err)
+		resp.Body.Close()
+		asserts.Empty(received)
+	}
+
+	// 启用后记录 Span 并传递链路
+	{
+		recorder := useRecorder(t)
+		ctx, parent := Start(context.Background(), "parent")
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+"/path?sign=secret", nil)
+		resp, err := client.Do(req)
+		asserts.NoError(err)
+		resp.Body.Close()
+		parent.End()
+
+		asserts.Empty(req.Header.Get("traceparent"))
+		ended := recorder.Ended()
+		asserts.Len(ended, 2)
+		asserts.Equal("HTTP GET", ended[0].Name())
+		asserts.Equal(parent.SpanContext().SpanID(), ended[0].Parent().SpanID())
+		asserts.Equal(codes.Error, ended[0].Status().Code)
+		asserts.Contains(ended[0].Attributes(), semconv.HTTPURLKey.String(server.URL+"/path"))
+		asserts.Contains(ended[0].Attributes(), semconv.HTTPStatusCodeKey.Int(http.StatusBadGateway))
+		asserts.Contains(received, ended[0].SpanContext().SpanID().String())
+	}
+}
+
+func TestRegisterCallbacks(t *testing.T) {
+	asserts := assert.New(t)
+	recorder := useRecorder(t)
+	mockDB, mock, _ := sqlmock.New()
+	db, _ := gorm.Open("mysql", mockDB)
+	defer db.Close()
+	RegisterCallbacks(db)
+
+	type File struct {
+		ID   uint
+		Name string
+	}
+
+	ctx, parent := Start(context.Background(), "parent")
+
+	// 查询成功
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "a"))
+		var files []File
+		asserts.NoError(db.Set(ContextKey, ctx).Find(&files).Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 未找到记录
+	{
+		mock.ExpectQuery("SELECT(.+)").WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+		var file File
+		asserts.Error(db.First(&file).Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	// 执行出错
+	{
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT(.+)").WillReturnError(errors.New("error"))
+		mock.ExpectRollback()
+		asserts.Error(db.Create(&File{Name: "b"}).Error)
+		asserts.NoError(mock.ExpectationsWereMet())
+	}
+
+	parent.End()
+	ended := recorder.Ended()
+	asserts.Len(ended, 4)
+
+	asserts.Equal("db.query", ended[0].Name())
+	asserts.Equal(parent.SpanContext().SpanID(), ended[0].Parent().SpanID())
+	asserts.Contains(ended[0].Attributes(), semconv.DBSQLTableKey.String("files"))
+	asserts.Equal(codes.Unset, ended[0].Status().Code)
+
+	asserts.Equal("db.query", ended[1].Name())
+	asserts.False(ended[1].Parent().IsValid())
+	asserts.Equal(codes.Unset, ended[1].Status().Code)
+
+	asserts.Equal("db.create", ended[2].Name())
+	asserts.Equal(codes.Error, ended[2].Status().Code)
+}
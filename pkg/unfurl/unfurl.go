@@ -0,0 +1,242 @@
+package unfurl
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// PlayerWidth 内嵌播放器的默认宽度
+	PlayerWidth = 640
+	// PlayerHeight 内嵌播放器的默认高度
+	PlayerHeight = 360
+)
+
+// videoTypes 可在浏览器中直接播放的视频
+var videoTypes = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".ogv":  "video/ogg",
+	".mov":  "video/quicktime",
+}
+
+// Enabled 是否为分享链接提供预览卡片
+func Enabled() bool {
+	return model.IsTrueVal(model.GetSettingByName("share_unfurl"))
+}
+
+// VideoType 返回可在浏览器中直接播放的视频的 MIME 类型
+func VideoType(name string) (string, bool) {
+	mime, ok := videoTypes[strings.ToLower(path.Ext(name))]
+	return mime, ok
+}
+
+// ShareKey 从分享页面路径 /s/{key}[/...] 中解析分享标识
+func ShareKey(p string) (string, bool) {
+	if !strings.HasPrefix(p, "/s/") {
+		return "", false
+	}
+
+	key := strings.SplitN(strings.TrimPrefix(p, "/s/"), "/", 2)[0]
+	return key, key != ""
+}
+
+// Card 分享链接的预览卡片
+type Card struct {
+	SiteName    string
+	Title       string
+	Description string
+	// URL 分享页面地址
+	URL string
+	// Type OpenGraph 对象类型
+	Type string
+	// Image 预览图像地址，无缩略图时为站点图标
+	Image string
+	// Thumb 预览图像是否为文件缩略图
+	Thumb bool
+	// Video 视频流地址
+	Video     string
+	VideoType string
+	// Player 内嵌播放页地址
+	Player string
+	// OEmbedURL oEmbed 接口地址
+	OEmbedURL string
+}
+
+// ForShare 生成分享的预览卡片，分享不存在或匿名访客无法访问时返回 nil。
+// 加密分享仅返回站点信息，不泄露分享内容
+func ForShare(c *gin.Context, key string) *Card {
+	share := model.GetShareByHashID(key)
+	if share == nil || !share.IsAvailable() || !share.IsAccessibleBy(model.NewAnonymousUser()) ||
+		share.TrafficExhausted() || !share.IsAccessibleFrom(c) {
+		return nil
+	}
+
+	siteURL := model.GetSiteURL()
+	resolve := func(p string) string {
+		u, err := url.Parse(p)
+		if err != nil {
+			return ""
+		}
+		return siteURL.ResolveReference(u).String()
+	}
+
+	options := model.GetSettingByNames("siteName", "pwa_large_icon")
+	card := &Card{
+		SiteName: options["siteName"],
+		URL:      resolve("/s/" + url.PathEscape(share.Key())),
+		Type:     "website",
+		Image:    resolve(options["pwa_large_icon"]),
+	}
+	card.OEmbedURL = resolve("/api/v3/share/oembed?url=" + url.QueryEscape(card.URL))
+
+	if share.Password != "" {
+		card.Title = options["siteName"]
+		card.Description = "此分享受密码保护"
+		return card
+	}
+
+	card.Title = share.SourceName
+	card.Description = share.Description
+	if share.IsDir || share.Bundle {
+		return card
+	}
+
+	file := share.SourceFile()
+	if card.Description == "" {
+		card.Description = util.FormatSize(file.Size)
+	}
+	if !share.PreviewEnabled {
+		return card
+	}
+
+	base := "/api/v3/share/card/" + url.PathEscape(share.Key())
+	// 添加水印的分享不提供原始缩略图
+	if file.PicInfo != "" && !share.Watermark {
+		card.Image = resolve(base + "/thumb")
+		card.Thumb = true
+	}
+	if mime, ok := VideoType(file.Name); ok {
+		card.Type = "video.other"
+		card.Video = resolve(base + "/stream")
+		card.VideoType = mime
+		card.Player = resolve(base + "/player")
+	}
+
+	return card
+}
+
+// twitterCard Twitter 卡片类型
+func (card *Card) twitterCard() string {
+	switch {
+	case card.Player != "":
+		return "player"
+	case card.Thumb:
+		return "summary_large_image"
+	default:
+		return "summary"
+	}
+}
+
+// MetaTags 生成插入页面 head 中的 OpenGraph、Twitter 卡片标签及 oEmbed 发现链接
+func (card *Card) MetaTags() string {
+	tags := [][2]string{
+		{"og:type", card.Type},
+		{"og:site_name", card.SiteName},
+		{"og:title", card.Title},
+		{"og:description", card.Description},
+		{"og:url", card.URL},
+		{"og:image", card.Image},
+		{"twitter:card", card.twitterCard()},
+		{"twitter:title", card.Title},
+		{"twitter:description", card.Description},
+		{"twitter:image", card.Image},
+	}
+
+	if card.Video != "" {
+		video := [][2]string{
+			{"og:video", card.Video},
+			{"og:video:type", card.VideoType},
+			{"og:video:width", fmt.Sprint(PlayerWidth)},
+			{"og:video:height", fmt.Sprint(PlayerHeight)},
+			{"twitter:player", card.Player},
+			{"twitter:player:width", fmt.Sprint(PlayerWidth)},
+			{"twitter:player:height", fmt.Sprint(PlayerHeight)},
+			{"twitter:player:stream", card.Video},
+			{"twitter:player:stream:content_type", card.VideoType},
+		}
+		if strings.HasPrefix(card.Video, "https://") {
+			video = append(video, [2]string{"og:video:secure_url", card.Video})
+		}
+		tags = append(tags, video...)
+	}
+
+	var b strings.Builder
+	for _, tag := range tags {
+		if tag[1] == "" {
+			continue
+		}
+
+		attr := "property"
+		if strings.HasPrefix(tag[0], "twitter:") {
+			attr = "name"
+		}
+		fmt.Fprintf(&b, "<meta %s=\"%s\" content=\"%s\">\n", attr, tag[0], html.EscapeString(tag[1]))
+	}
+
+	fmt.Fprintf(&b, "<link rel=\"alternate\" type=\"application/json+oembed\" href=\"%s\" title=\"%s\">\n",
+		html.EscapeString(card.OEmbedURL), html.EscapeString(card.Title))
+	return b.String()
+}
+
+// OEmbed oEmbed 响应
+type OEmbed struct {
+	Type         string `json:"type"`
+	Version      string `json:"version"`
+	Title        string `json:"title,omitempty"`
+	ProviderName string `json:"provider_name,omitempty"`
+	ProviderURL  string `json:"provider_url,omitempty"`
+	HTML         string `json:"html,omitempty"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+}
+
+// OEmbed 生成 oEmbed 响应，视频提供内嵌播放器，尺寸不超过 maxWidth 及 maxHeight，
+// 其他分享作为普通链接
+func (card *Card) OEmbed(maxWidth, maxHeight int) *OEmbed {
+	res := &OEmbed{
+		Type:         "link",
+		Version:      "1.0",
+		Title:        card.Title,
+		ProviderName: card.SiteName,
+		ProviderURL:  model.GetSiteURL().String(),
+	}
+	if card.Player == "" {
+		return res
+	}
+
+	// 按比例缩小播放器
+	width, height := PlayerWidth, PlayerHeight
+	if maxWidth > 0 && width > maxWidth {
+		width, height = maxWidth, maxWidth*PlayerHeight/PlayerWidth
+	}
+	if maxHeight > 0 && height > maxHeight {
+		width, height = maxHeight*PlayerWidth/PlayerHeight, maxHeight
+	}
+
+	res.Type = "video"
+	res.Width, res.Height = width, height
+	res.HTML = fmt.Sprintf(
+		"<iframe src=\"%s\" width=\"%d\" height=\"%d\" frameborder=\"0\" allowfullscreen></iframe>",
+		html.EscapeString(card.Player), width, height,
+	)
+	return res
+}
@@ -0,0 +1,124 @@
+package unfurl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShareKey(t *testing.T) {
+	asserts := assert.New(t)
+
+	key, ok := ShareKey("/s/abc.def")
+	asserts.True(ok)
+	asserts.Equal("abc.def", key)
+
+	key, ok = ShareKey("/s/abc/doc")
+	asserts.True(ok)
+	asserts.Equal("abc", key)
+
+	_, ok = ShareKey("/s/")
+	asserts.False(ok)
+	_, ok = ShareKey("/home")
+	asserts.False(ok)
+}
+
+func TestVideoType(t *testing.T) {
+	asserts := assert.New(t)
+
+	mime, ok := VideoType("a.MP4")
+	asserts.True(ok)
+	asserts.Equal("video/mp4", mime)
+
+	_, ok = VideoType("a.mkv")
+	asserts.False(ok)
+	_, ok = VideoType("mp4")
+	asserts.False(ok)
+}
+
+func TestCard_MetaTags(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 普通文件
+	{
+		card := &Card{
+			SiteName:  "Cloudreve",
+			Title:     `"a" & <b>.txt`,
+			URL:       "http://cloudreve.org/s/key",
+			Type:      "website",
+			Image:     "http://cloudreve.org/static/img/logo512.png",
+			OEmbedURL: "http://cloudreve.org/api/v3/share/oembed?url=a&b",
+		}
+		tags := card.MetaTags()
+		asserts.Contains(tags, `<meta property="og:title" content="&#34;a&#34; &amp; &lt;b&gt;.txt">`)
+		asserts.Contains(tags, `<meta name="twitter:card" content="summary">`)
+		asserts.Contains(tags, `href="http://cloudreve.org/api/v3/share/oembed?url=a&amp;b"`)
+		asserts.NotContains(tags, "og:description")
+		asserts.NotContains(tags, "og:video")
+	}
+
+	// 视频
+	{
+		card := &Card{
+			Title:     "a.mp4",
+			Type:      "video.other",
+			Image:     "https://cloudreve.org/api/v3/share/card/key/thumb",
+			Thumb:     true,
+			Video:     "https://cloudreve.org/api/v3/share/card/key/stream",
+			VideoType: "video/mp4",
+			Player:    "https://cloudreve.org/api/v3/share/card/key/player",
+		}
+		tags := card.MetaTags()
+		asserts.Contains(tags, `<meta name="twitter:card" content="player">`)
+		asserts.Contains(tags, `<meta property="og:video:type" content="video/mp4">`)
+		asserts.Contains(tags, `<meta property="og:video:secure_url" content="https://cloudreve.org/api/v3/share/card/key/stream">`)
+		asserts.Contains(tags, `<meta name="twitter:player" content="https://cloudreve.org/api/v3/share/card/key/player">`)
+		asserts.True(strings.HasSuffix(tags, "\n"))
+	}
+
+	// 图片缩略图
+	{
+		card := &Card{Thumb: true}
+		asserts.Equal("summary_large_image", card.twitterCard())
+	}
+}
+
+func TestCard_OEmbed(t *testing.T) {
+	asserts := assert.New(t)
+	cache.Set("setting_siteURL", "http://cloudreve.org", 0)
+
+	// 普通链接
+	{
+		card := &Card{SiteName: "Cloudreve", Title: "a.txt"}
+		res := card.OEmbed(0, 0)
+		asserts.Equal("link", res.Type)
+		asserts.Equal("1.0", res.Version)
+		asserts.Equal("Cloudreve", res.ProviderName)
+		asserts.Equal("http://cloudreve.org", res.ProviderURL)
+		asserts.Empty(res.HTML)
+	}
+
+	// 视频，默认尺寸
+	{
+		card := &Card{Title: "a.mp4", Player: "http://cloudreve.org/player?a&b"}
+		res := card.OEmbed(0, 0)
+		asserts.Equal("video", res.Type)
+		asserts.Equal(PlayerWidth, res.Width)
+		asserts.Equal(PlayerHeight, res.Height)
+		asserts.Contains(res.HTML, `src="http://cloudreve.org/player?a&amp;b"`)
+	}
+
+	// 视频，限制尺寸
+	{
+		card := &Card{Player: "http://cloudreve.org/player"}
+		res := card.OEmbed(320, 0)
+		asserts.Equal(320, res.Width)
+		asserts.Equal(180, res.Height)
+
+		res = card.OEmbed(0, 90)
+		asserts.Equal(160, res.Width)
+		asserts.Equal(90, res.Height)
+	}
+}
@@ -1,6 +1,9 @@
 package util
 
 import (
+	crand "crypto/rand"
+	"fmt"
+	"math/big"
 	"math/rand"
 	"regexp"
 	"strings"
@@ -22,6 +25,22 @@ func RandStringRunes(n int) string {
 	return string(b)
 }
 
+// RandSecureString 使用 crypto/rand 返回随机字符串，用于令牌、密钥等不可被猜测的场合
+func RandSecureString(n int) string {
+	const letters = "1234567890abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+	b := make([]byte, n)
+	max := big.NewInt(int64(len(letters)))
+	for i := range b {
+		idx, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			panic(fmt.Sprintf("failed to read random bytes: %s", err))
+		}
+		b[i] = letters[idx.Int64()]
+	}
+	return string(b)
+}
+
 // ContainsUint 返回list中是否包含
 func ContainsUint(s []uint, e uint) bool {
 	for _, a := range s {
@@ -106,3 +125,17 @@ func SliceDifference(slice1, slice2 []string) []string {
 	}
 	return nn
 }
+
+// FormatSize 将字节数转换为便于阅读的容量
+func FormatSize(size uint64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+	value := float64(size)
+	i := 0
+	for ; value >= 1024 && i < len(units)-1; i++ {
+		value /= 1024
+	}
+	if i == 0 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
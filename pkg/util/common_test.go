@@ -26,6 +26,13 @@ func TestRandStringRunes(t *testing.T) {
 	asserts.NotEqual(sameLenStr1, sameLenStr2)
 }
 
+func TestRandSecureString(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Len(RandSecureString(0), 0)
+	asserts.Regexp("^[0-9a-zA-Z]{32}$", RandSecureString(32))
+	asserts.NotEqual(RandSecureString(32), RandSecureString(32))
+}
+
 func TestContainsUint(t *testing.T) {
 	asserts := assert.New(t)
 	asserts.True(ContainsUint([]uint{0, 2, 3, 65, 4}, 65))
@@ -96,3 +103,11 @@ func TestSliceDifference(t *testing.T) {
 		asserts.Equal([]string{"1", "2", "3", "4"}, SliceDifference(s1, s2))
 	}
 }
+
+func TestFormatSize(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Equal("0 B", FormatSize(0))
+	asserts.Equal("1023 B", FormatSize(1023))
+	asserts.Equal("1.50 KB", FormatSize(1536))
+	asserts.Equal("2.00 GB", FormatSize(2<<30))
+}
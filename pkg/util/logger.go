@@ -1,10 +1,15 @@
 package util
 
 import (
+	"encoding/json"
 	"fmt"
-	"github.com/fatih/color"
+	"io"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+
+	"github.com/fatih/color"
 )
 
 const (
@@ -23,10 +28,39 @@ var Level = LevelDebug
 
 // Logger 日志
 type Logger struct {
-	level int
-	mu    sync.Mutex
+	level  int
+	module string
+	fields []logField
+}
+
+// logField 日志附加字段
+type logField struct {
+	key   string
+	value interface{}
 }
 
+// LogOptions 日志输出配置
+type LogOptions struct {
+	// Level 默认日志等级
+	Level string
+	// Format 输出格式，text 或 json
+	Format string
+	// Modules 各模块的日志等级，如 ftp=debug,aria2=warning
+	Modules string
+	// Output 日志输出，为空时输出至标准输出
+	Output io.Writer
+}
+
+// logOutput 所有 Logger 共享的输出
+type logOutput struct {
+	mu      sync.Mutex
+	w       io.Writer
+	json    bool
+	modules map[string]int
+}
+
+var output = &logOutput{}
+
 // 日志颜色
 var colors = map[string]func(a ...interface{}) string{
 	"Warning": color.New(color.FgYellow).Add(color.Bold).SprintFunc(),
@@ -45,28 +79,193 @@ var spaces = map[string]string{
 	"Debug":   "  ",
 }
 
+// parseLevel 解析日志等级，无法识别时返回 LevelError
+func parseLevel(level string) (int, bool) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "error":
+		return LevelError, true
+	case "warning":
+		return LevelWarning, true
+	case "info":
+		return LevelInformational, true
+	case "debug":
+		return LevelDebug, true
+	}
+	return LevelError, false
+}
+
+// parseModuleLevels 解析各模块的日志等级
+func parseModuleLevels(modules string) (map[string]int, error) {
+	res := make(map[string]int)
+	for _, item := range strings.Split(modules, ",") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+
+		pair := strings.SplitN(item, "=", 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf("invalid module log level %q", item)
+		}
+		level, ok := parseLevel(pair[1])
+		if !ok {
+			return nil, fmt.Errorf("invalid module log level %q", item)
+		}
+		res[strings.TrimSpace(pair[0])] = level
+	}
+	return res, nil
+}
+
+// ConfigureLogger 按配置重建全局 Logger 并设定输出格式及目标
+func ConfigureLogger(options LogOptions) error {
+	modules, err := parseModuleLevels(options.Modules)
+	if err != nil {
+		return err
+	}
+
+	output.mu.Lock()
+	output.w = options.Output
+	output.json = options.Format == "json"
+	output.modules = modules
+	output.mu.Unlock()
+
+	BuildLogger(options.Level)
+	return nil
+}
+
 // Println 打印
 func (ll *Logger) Println(prefix string, msg string) {
-	// TODO Release时去掉
-	// color.NoColor = false
+	now := time.Now()
 
-	c := color.New()
+	output.mu.Lock()
+	defer output.mu.Unlock()
 
-	ll.mu.Lock()
-	defer ll.mu.Unlock()
+	if output.json {
+		entry := map[string]interface{}{
+			"time":  now.Format(time.RFC3339Nano),
+			"level": strings.ToLower(prefix),
+			"msg":   msg,
+		}
+		if ll.module != "" {
+			entry["module"] = ll.module
+		}
+		for _, field := range ll.fields {
+			entry[field.key] = field.value
+		}
 
-	_, _ = c.Printf(
+		line, err := json.Marshal(entry)
+		if err != nil {
+			line, _ = json.Marshal(map[string]string{"time": entry["time"].(string), "level": "error", "msg": err.Error()})
+		}
+		ll.write(append(line, '\n'))
+		return
+	}
+
+	if ll.module != "" {
+		msg = "[" + ll.module + "] " + msg
+	}
+	msg += ll.formatFields()
+
+	// 输出至文件时不使用颜色
+	if output.w != nil {
+		ll.write([]byte(fmt.Sprintf("[%s]%s %s %s\n", prefix, spaces[prefix], now.Format("2006-01-02 15:04:05"), msg)))
+		return
+	}
+
+	_, _ = color.New().Printf(
 		"%s%s %s %s\n",
 		colors[prefix]("["+prefix+"]"),
 		spaces[prefix],
-		time.Now().Format("2006-01-02 15:04:05"),
+		now.Format("2006-01-02 15:04:05"),
 		msg,
 	)
 }
 
+// write 写入日志输出
+func (ll *Logger) write(line []byte) {
+	w := output.w
+	if w == nil {
+		w = color.Output
+	}
+	_, _ = w.Write(line)
+}
+
+// formatFields 以 key=value 格式输出附加字段
+func (ll *Logger) formatFields() string {
+	if len(ll.fields) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	for _, field := range ll.fields {
+		fmt.Fprintf(&b, " %s=%v", field.key, field.value)
+	}
+	return b.String()
+}
+
+// enabled 是否输出给定等级的日志，模块单独设定的等级优先
+func (ll *Logger) enabled(level int) bool {
+	max := ll.level
+	if ll.module != "" {
+		output.mu.Lock()
+		if moduleLevel, ok := output.modules[ll.module]; ok {
+			max = moduleLevel
+		}
+		output.mu.Unlock()
+	}
+	return level <= max
+}
+
+// Module 返回指定模块的 Logger，日志中附加模块名，并按模块设定的等级过滤
+func (ll *Logger) Module(name string) *Logger {
+	return &Logger{
+		level:  ll.level,
+		module: name,
+		fields: ll.fields,
+	}
+}
+
+// WithField 返回附加了字段的 Logger，同名字段将被覆盖
+func (ll *Logger) WithField(key string, value interface{}) *Logger {
+	fields := make([]logField, 0, len(ll.fields)+1)
+	for _, field := range ll.fields {
+		if field.key != key {
+			fields = append(fields, field)
+		}
+	}
+
+	return &Logger{
+		level:  ll.level,
+		module: ll.module,
+		fields: append(fields, logField{key: key, value: value}),
+	}
+}
+
+// WithFields 返回附加了多个字段的 Logger，字段按名称排序
+func (ll *Logger) WithFields(fields map[string]interface{}) *Logger {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	res := ll
+	for _, key := range keys {
+		res = res.WithField(key, fields[key])
+	}
+	return res
+}
+
+// WithRequestID 返回附加了请求 ID 的 Logger，ID 为空时返回自身
+func (ll *Logger) WithRequestID(id string) *Logger {
+	if id == "" {
+		return ll
+	}
+	return ll.WithField("request_id", id)
+}
+
 // Panic 极端错误
 func (ll *Logger) Panic(format string, v ...interface{}) {
-	if LevelError > ll.level {
+	if !ll.enabled(LevelError) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -76,7 +275,7 @@ func (ll *Logger) Panic(format string, v ...interface{}) {
 
 // Error 错误
 func (ll *Logger) Error(format string, v ...interface{}) {
-	if LevelError > ll.level {
+	if !ll.enabled(LevelError) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -85,7 +284,7 @@ func (ll *Logger) Error(format string, v ...interface{}) {
 
 // Warning 警告
 func (ll *Logger) Warning(format string, v ...interface{}) {
-	if LevelWarning > ll.level {
+	if !ll.enabled(LevelWarning) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -94,7 +293,7 @@ func (ll *Logger) Warning(format string, v ...interface{}) {
 
 // Info 信息
 func (ll *Logger) Info(format string, v ...interface{}) {
-	if LevelInformational > ll.level {
+	if !ll.enabled(LevelInformational) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -103,7 +302,7 @@ func (ll *Logger) Info(format string, v ...interface{}) {
 
 // Debug 校验
 func (ll *Logger) Debug(format string, v ...interface{}) {
-	if LevelDebug > ll.level {
+	if !ll.enabled(LevelDebug) {
 		return
 	}
 	msg := fmt.Sprintf(format, v...)
@@ -121,25 +320,23 @@ func (ll *Logger) Debug(format string, v ...interface{}) {
 
 // BuildLogger 构建logger
 func BuildLogger(level string) {
-	intLevel := LevelError
-	switch level {
-	case "error":
-		intLevel = LevelError
-	case "warning":
-		intLevel = LevelWarning
-	case "info":
-		intLevel = LevelInformational
-	case "debug":
-		intLevel = LevelDebug
-	}
+	intLevel, _ := parseLevel(level)
 	l := Logger{
 		level: intLevel,
 	}
+	globalLoggerMu.Lock()
 	GloablLogger = &l
+	globalLoggerMu.Unlock()
 }
 
+// globalLoggerMu 保护全局日志对象，首次使用时可能在多个协程中同时创建
+var globalLoggerMu sync.Mutex
+
 // Log 返回日志对象
 func Log() *Logger {
+	globalLoggerMu.Lock()
+	defer globalLoggerMu.Unlock()
+
 	if GloablLogger == nil {
 		l := Logger{
 			level: Level,
@@ -3,8 +3,12 @@
 package util
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestBuildLogger(t *testing.T) {
@@ -101,3 +105,51 @@ func TestLogger_Panic(t *testing.T) {
 		l.Error("123")
 	})
 }
+
+func TestConfigureLogger(t *testing.T) {
+	asserts := assert.New(t)
+	defer ConfigureLogger(LogOptions{Level: "debug"})
+
+	// 模块等级无效
+	{
+		asserts.Error(ConfigureLogger(LogOptions{Modules: "ftp"}))
+		asserts.Error(ConfigureLogger(LogOptions{Modules: "ftp=verbose"}))
+	}
+
+	// JSON 格式，附加字段
+	{
+		var buf bytes.Buffer
+		asserts.NoError(ConfigureLogger(LogOptions{Level: "info", Format: "json", Output: &buf}))
+		Log().Module("task").WithRequestID("req").WithFields(map[string]interface{}{"id": 1}).Info("hello %s", "world")
+		Log().Debug("hidden")
+
+		var entry map[string]interface{}
+		asserts.NoError(json.Unmarshal(buf.Bytes(), &entry))
+		asserts.Equal("info", entry["level"])
+		asserts.Equal("hello world", entry["msg"])
+		asserts.Equal("task", entry["module"])
+		asserts.Equal("req", entry["request_id"])
+		asserts.EqualValues(1, entry["id"])
+		asserts.Equal(1, strings.Count(buf.String(), "\n"))
+	}
+
+	// 文本格式，模块单独设定等级
+	{
+		var buf bytes.Buffer
+		asserts.NoError(ConfigureLogger(LogOptions{Level: "error", Modules: " ftp = debug ", Output: &buf}))
+		Log().Module("ftp").WithField("user", "a").Debug("login")
+		Log().Module("aria2").Debug("hidden")
+		asserts.Contains(buf.String(), "[ftp] login user=a")
+		asserts.NotContains(buf.String(), "hidden")
+	}
+}
+
+func TestLogger_WithField(t *testing.T) {
+	asserts := assert.New(t)
+	l := &Logger{level: LevelDebug}
+
+	l2 := l.WithField("a", 1).WithField("b", 2).WithField("a", 3)
+	asserts.Len(l.fields, 0)
+	asserts.Equal(" b=2 a=3", l2.formatFields())
+	asserts.Same(l, l.WithRequestID(""))
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// SplitList 将以逗号或空白分隔的列表拆分为切片，忽略空项
+func SplitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+// ParseIPRanges 解析以逗号或空白分隔的 CIDR 列表，单个IP地址视为仅包含自身的网段
+func ParseIPRanges(s string) ([]*net.IPNet, error) {
+	items := SplitList(s)
+	ranges := make([]*net.IPNet, 0, len(items))
+	for _, item := range items {
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP address %q", item)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			ranges = append(ranges, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, ipNet)
+	}
+
+	return ranges, nil
+}
+
+// IPInRanges 返回IP是否位于给定网段中的任意一个
+func IPInRanges(ip net.IP, ranges []*net.IPNet) bool {
+	for _, ipNet := range ranges {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseOrigins 解析以逗号或空白分隔的跨域来源列表，来源须为 * 或不含路径的 http(s) 地址
+func ParseOrigins(s string) ([]string, error) {
+	origins := SplitList(s)
+	for _, origin := range origins {
+		if origin == "*" {
+			continue
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" ||
+			strings.Trim(u.Path, "/") != "" || u.RawQuery != "" {
+			return nil, fmt.Errorf("invalid origin %q", origin)
+		}
+	}
+	return origins, nil
+}
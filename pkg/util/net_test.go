@@ -0,0 +1,64 @@
+package util
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitList(t *testing.T) {
+	asserts := assert.New(t)
+	asserts.Empty(SplitList(""))
+	asserts.Equal([]string{"CN", "US", "JP"}, SplitList("CN, US,,\nJP"))
+}
+
+func TestParseIPRanges(t *testing.T) {
+	asserts := assert.New(t)
+
+	// 空列表
+	{
+		ranges, err := ParseIPRanges(" ")
+		asserts.NoError(err)
+		asserts.Empty(ranges)
+	}
+
+	// 网段及单个地址
+	{
+		ranges, err := ParseIPRanges("10.0.0.0/8, 192.168.1.1,2001:db8::/32")
+		asserts.NoError(err)
+		asserts.Len(ranges, 3)
+		asserts.True(IPInRanges(net.ParseIP("10.1.2.3"), ranges))
+		asserts.True(IPInRanges(net.ParseIP("192.168.1.1"), ranges))
+		asserts.False(IPInRanges(net.ParseIP("192.168.1.2"), ranges))
+		asserts.True(IPInRanges(net.ParseIP("2001:db8::1"), ranges))
+		asserts.False(IPInRanges(net.ParseIP("2001:db9::1"), ranges))
+	}
+
+	// 无效格式
+	{
+		_, err := ParseIPRanges("10.0.0.0/33")
+		asserts.Error(err)
+		_, err = ParseIPRanges("localhost")
+		asserts.Error(err)
+	}
+}
+
+func TestParseOrigins(t *testing.T) {
+	asserts := assert.New(t)
+
+	origins, err := ParseOrigins("https://a.example.com, http://b.example.com:8080\n*")
+	asserts.NoError(err)
+	asserts.Equal([]string{"https://a.example.com", "http://b.example.com:8080", "*"}, origins)
+
+	origins, err = ParseOrigins("")
+	asserts.NoError(err)
+	asserts.Empty(origins)
+
+	_, err = ParseOrigins("a.example.com")
+	asserts.Error(err)
+	_, err = ParseOrigins("https://a.example.com/path")
+	asserts.Error(err)
+	_, err = ParseOrigins("ftp://a.example.com")
+	asserts.Error(err)
+}
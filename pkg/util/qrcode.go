@@ -0,0 +1,68 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// QRCodePNG 生成边长为 size 像素的 PNG 格式二维码
+func QRCodePNG(content string, size int) ([]byte, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	// 四周保留 4 个模块宽度的空白区
+	modules := code.Bounds().Dx()
+	inner := size * modules / (modules + 8)
+	code, err = barcode.Scale(code, inner, inner)
+	if err != nil {
+		return nil, err
+	}
+
+	canvas := image.NewGray(image.Rect(0, 0, size, size))
+	draw.Draw(canvas, canvas.Bounds(), image.White, image.Point{}, draw.Src)
+	offset := (size - inner) / 2
+	draw.Draw(canvas, image.Rect(offset, offset, offset+inner, offset+inner), code, image.Point{}, draw.Src)
+
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, canvas); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// QRCodeSVG 生成边长为 size 像素的 SVG 格式二维码
+func QRCodeSVG(content string, size int) ([]byte, error) {
+	code, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	// 四周保留 4 个模块宽度的空白区
+	modules := code.Bounds().Dx()
+	viewBox := modules + 8
+
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, viewBox, viewBox)
+	fmt.Fprintf(buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, viewBox, viewBox)
+	for y := 0; y < modules; y++ {
+		for x := 0; x < modules; x++ {
+			if code.At(x, y) == color.Black {
+				fmt.Fprintf(buf, "M%d %dh1v1h-1z", x+4, y+4)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
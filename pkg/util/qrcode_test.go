@@ -0,0 +1,35 @@
+package util
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQRCodePNG(t *testing.T) {
+	asserts := assert.New(t)
+
+	res, err := QRCodePNG("https://cloudreve.org/s/x9T4", 256)
+	asserts.NoError(err)
+	img, err := png.Decode(bytes.NewReader(res))
+	asserts.NoError(err)
+	asserts.Equal(256, img.Bounds().Dx())
+	asserts.Equal(256, img.Bounds().Dy())
+
+	// 尺寸过小
+	_, err = QRCodePNG("https://cloudreve.org/s/x9T4", 10)
+	asserts.Error(err)
+}
+
+func TestQRCodeSVG(t *testing.T) {
+	asserts := assert.New(t)
+
+	res, err := QRCodeSVG("https://cloudreve.org/s/x9T4", 256)
+	asserts.NoError(err)
+	asserts.True(strings.HasPrefix(string(res), "<svg"))
+	asserts.Contains(string(res), `width="256"`)
+	asserts.True(strings.HasSuffix(string(res), "</svg>"))
+}
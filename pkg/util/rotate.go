@@ -0,0 +1,142 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotateTimeFormat 轮转后文件名中的时间格式
+const rotateTimeFormat = "20060102-150405.000"
+
+// RotateWriter 按大小轮转的日志文件，轮转后的文件以时间为后缀保存在同一目录下
+type RotateWriter struct {
+	// Filename 日志文件路径
+	Filename string
+	// MaxSize 单个文件的最大字节数，0 表示不轮转
+	MaxSize int64
+	// MaxBackups 保留的轮转文件数量，0 表示不限制
+	MaxBackups int
+	// MaxAge 轮转文件的保留时间，0 表示不限制
+	MaxAge time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// Write 写入日志，超出大小限制时先轮转
+func (w *RotateWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > w.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Close 关闭日志文件
+func (w *RotateWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
+
+// open 以追加方式打开日志文件
+func (w *RotateWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.Filename), 0700); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.Filename, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// rotate 重命名当前文件并打开新文件，随后清理过期的轮转文件
+func (w *RotateWriter) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	backup := w.Filename + "." + time.Now().Format(rotateTimeFormat)
+	if err := os.Rename(w.Filename, backup); err != nil {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.cleanup()
+	return nil
+}
+
+// cleanup 删除超出数量或保留时间的轮转文件
+func (w *RotateWriter) cleanup() {
+	if w.MaxBackups <= 0 && w.MaxAge <= 0 {
+		return
+	}
+
+	prefix := filepath.Base(w.Filename) + "."
+	entries, err := os.ReadDir(filepath.Dir(w.Filename))
+	if err != nil {
+		return
+	}
+
+	var backups []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if _, err := time.Parse(rotateTimeFormat, strings.TrimPrefix(entry.Name(), prefix)); err == nil {
+			backups = append(backups, entry.Name())
+		}
+	}
+
+	// 时间后缀的字典序即时间顺序，新文件在前
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	for i, name := range backups {
+		expired := w.MaxBackups > 0 && i >= w.MaxBackups
+		if !expired && w.MaxAge > 0 {
+			created, _ := time.ParseInLocation(rotateTimeFormat, strings.TrimPrefix(name, prefix), time.Local)
+			expired = time.Since(created) > w.MaxAge
+		}
+		if expired {
+			os.Remove(filepath.Join(filepath.Dir(w.Filename), name))
+		}
+	}
+}
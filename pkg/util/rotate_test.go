@@ -0,0 +1,65 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotateWriter_Write(t *testing.T) {
+	asserts := assert.New(t)
+	dir := t.TempDir()
+	w := &RotateWriter{
+		Filename:   filepath.Join(dir, "logs", "cloudreve.log"),
+		MaxSize:    10,
+		MaxBackups: 1,
+	}
+	defer w.Close()
+
+	// 未超出大小
+	n, err := w.Write([]byte("12345"))
+	asserts.NoError(err)
+	asserts.Equal(5, n)
+	_, err = w.Write([]byte("67890"))
+	asserts.NoError(err)
+
+	// 超出大小，轮转
+	_, err = w.Write([]byte("abc"))
+	asserts.NoError(err)
+	content, err := os.ReadFile(w.Filename)
+	asserts.NoError(err)
+	asserts.Equal("abc", string(content))
+
+	// 再次轮转，仅保留一个备份
+	time.Sleep(2 * time.Millisecond)
+	_, err = w.Write([]byte("defghijk"))
+	asserts.NoError(err)
+	backups, _ := filepath.Glob(w.Filename + ".*")
+	asserts.Len(backups, 1)
+	content, _ = os.ReadFile(backups[0])
+	asserts.Equal("abc", string(content))
+}
+
+func TestRotateWriter_cleanup(t *testing.T) {
+	asserts := assert.New(t)
+	dir := t.TempDir()
+	w := &RotateWriter{
+		Filename: filepath.Join(dir, "cloudreve.log"),
+		MaxAge:   time.Hour,
+	}
+
+	expired := w.Filename + "." + time.Now().Add(-2*time.Hour).Format(rotateTimeFormat)
+	fresh := w.Filename + "." + time.Now().Format(rotateTimeFormat)
+	other := w.Filename + ".bak"
+	for _, name := range []string{expired, fresh, other} {
+		asserts.NoError(os.WriteFile(name, []byte("1"), 0644))
+	}
+
+	w.cleanup()
+	asserts.NoFileExists(expired)
+	asserts.FileExists(fresh)
+	asserts.FileExists(other)
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"context"
+	"net/http"
+)
+
+type traceCtxKey int
+
+// requestIDCtx 请求 ID
+const requestIDCtx traceCtxKey = iota
+
+// WithRequestID 将请求 ID 写入上下文
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDCtx, id)
+}
+
+// RequestIDFromContext 获取上下文中的请求 ID，
+// 对于 gin 的上下文，从其对应的 HTTP 请求的上下文中获取
+func RequestIDFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+
+	if id, ok := ctx.Value(requestIDCtx).(string); ok {
+		return id
+	}
+
+	if req, ok := ctx.Value(0).(*http.Request); ok && req != nil {
+		if id, ok := req.Context().Value(requestIDCtx).(string); ok {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// LogWithContext 返回附加了上下文中请求 ID 的 Logger
+func LogWithContext(ctx context.Context) *Logger {
+	return Log().WithRequestID(RequestIDFromContext(ctx))
+}
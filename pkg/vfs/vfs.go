@@ -0,0 +1,487 @@
+package vfs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+var (
+	// ErrNotExist 文件或目录不存在
+	ErrNotExist = errors.New("no such file or directory")
+	// ErrExist 文件或目录已存在
+	ErrExist = errors.New("file or directory already exists")
+	// ErrPermissionDenied 无权执行此操作
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrQuotaExceeded 容量不足
+	ErrQuotaExceeded = errors.New("insufficient storage space")
+	// ErrLoginFailed 用户名或密码错误
+	ErrLoginFailed = errors.New("login incorrect")
+	// ErrDirNotEmpty 目录不为空
+	ErrDirNotEmpty = errors.New("directory not empty")
+	// ErrLocked 资源已被 WebDAV 客户端锁定
+	ErrLocked = errors.New("resource is locked")
+)
+
+// FileInfo 文件或目录的信息，model.File 及 model.Folder 均实现了此接口
+type FileInfo interface {
+	GetName() string
+	GetSize() uint64
+	ModTime() time.Time
+	IsDir() bool
+}
+
+// Session 按路径访问用户文件的会话，供 FTP、SFTP 等协议前端使用。
+// 根目录、只读等限制与 WebDAV 账户相同，每次操作使用独立的文件系统，以便及时反映账户及分享的变更
+type Session struct {
+	User    *model.User
+	Account *model.Webdav
+}
+
+// NewSession 以用户根目录新建可读写的会话
+func NewSession(user *model.User) *Session {
+	return &Session{User: user, Account: &model.Webdav{Root: "/"}}
+}
+
+// NewTokenSession 以个人访问令牌限定的目录为根新建会话，只读令牌的会话只读
+func NewTokenSession(user *model.User, token *model.APIToken) *Session {
+	root := token.Folder
+	if root == "" {
+		root = "/"
+	}
+	return &Session{User: user, Account: &model.Webdav{
+		Root:     root,
+		Readonly: token.Scope == model.APITokenScopeRead,
+	}}
+}
+
+// LoginWebDAV 使用 WebDAV 账户登录，用户名为邮箱，密码为 WebDAV 账户的密码
+func LoginWebDAV(email, password, ip string) (*Session, error) {
+	user, err := model.GetActiveUserByEmail(email)
+	if err != nil {
+		return nil, ErrLoginFailed
+	}
+
+	account, err := model.GetWebdavByPassword(password, user.ID)
+	if err != nil || account.Expired() {
+		return nil, ErrLoginFailed
+	}
+
+	if !user.Group.AllowIP(ip) {
+		return nil, ErrPermissionDenied
+	}
+
+	// 根目录须可用
+	session := &Session{User: &user, Account: account}
+	fs, err := session.fs()
+	if err != nil {
+		return nil, err
+	}
+	fs.Recycle()
+
+	return session, nil
+}
+
+// fs 初始化以账户根目录为根的文件系统
+func (s *Session) fs() (*filesystem.FileSystem, error) {
+	fs, err := filesystem.NewFileSystemForWebDAV(s.User, s.Account)
+	if err != nil {
+		return nil, convertError(err)
+	}
+	return fs, nil
+}
+
+// FileSystem 初始化以会话根目录为根的文件系统，由调用方回收
+func (s *Session) FileSystem() (*filesystem.FileSystem, error) {
+	return s.fs()
+}
+
+// Readonly 会话是否只读
+func (s *Session) Readonly() bool {
+	return s.Account.Readonly
+}
+
+// writable 只读会话不允许写入
+func (s *Session) writable() error {
+	if s.Readonly() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// Stat 获取文件或目录信息
+func (s *Session) Stat(p string) (FileInfo, error) {
+	fs, err := s.fs()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	return stat(fs, p)
+}
+
+// stat 获取文件或目录信息，同名时优先返回目录
+func stat(fs *filesystem.FileSystem, p string) (FileInfo, error) {
+	if exist, folder := fs.IsPathExist(p); exist {
+		return folder, nil
+	}
+	if exist, file := fs.IsFileExist(p); exist {
+		return file, nil
+	}
+	return nil, ErrNotExist
+}
+
+// List 列出目录下的文件及目录
+func (s *Session) List(p string) ([]FileInfo, error) {
+	fs, err := s.fs()
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	exist, folder := fs.IsPathExist(p)
+	if !exist {
+		return nil, ErrNotExist
+	}
+
+	folders, err := folder.GetChildFolder()
+	if err != nil {
+		return nil, err
+	}
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]FileInfo, 0, len(folders)+len(files))
+	for i := range folders {
+		res = append(res, &folders[i])
+	}
+	for i := range files {
+		res = append(res, &files[i])
+	}
+	return res, nil
+}
+
+// fileReader 读取完成后回收文件系统
+type fileReader struct {
+	io.ReadSeekCloser
+	fs *filesystem.FileSystem
+}
+
+func (r *fileReader) Close() error {
+	err := r.ReadSeekCloser.Close()
+	r.fs.Recycle()
+	return err
+}
+
+// Open 打开文件用于读取
+func (s *Session) Open(p string) (io.ReadSeekCloser, error) {
+	fs, err := s.fs()
+	if err != nil {
+		return nil, err
+	}
+
+	exist, file := fs.IsFileExist(p)
+	if !exist {
+		fs.Recycle()
+		return nil, ErrNotExist
+	}
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(context.Background(), 0)
+	if err != nil {
+		fs.Recycle()
+		return nil, convertError(err)
+	}
+
+	return &fileReader{ReadSeekCloser: rs, fs: fs}, nil
+}
+
+// UploadLimit 写入 p 时允许的最大文件大小，覆盖时原文件的大小可被释放
+func (s *Session) UploadLimit(p string) (uint64, error) {
+	fs, err := s.fs()
+	if err != nil {
+		return 0, err
+	}
+	defer fs.Recycle()
+
+	limit := fs.User.GetRemainingCapacity()
+	if exist, file := fs.IsFileExist(p); exist {
+		limit += file.Size
+	}
+	if limit >= math.MaxInt64 {
+		limit = math.MaxInt64 - 1
+	}
+	return limit, nil
+}
+
+// TempFile 在临时目录中创建用于暂存上传内容的文件
+func TempFile() (*os.File, error) {
+	tempDir := filepath.Join(util.RelativePath(model.GetSettingByName("temp_path")), "vfs")
+	if err := os.MkdirAll(tempDir, 0700); err != nil {
+		return nil, err
+	}
+	return ioutil.TempFile(tempDir, "upload_")
+}
+
+// Store 写入文件，文件已存在时覆盖。上传前无法得知文件大小时，先暂存至本机临时目录，
+// 暂存的大小不超过剩余容量，再按照文件大小校验并上传至存储策略
+func (s *Session) Store(p string, r io.Reader) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	limit, err := s.UploadLimit(p)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := TempFile()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	size, err := io.Copy(tmp, io.LimitReader(r, int64(limit)+1))
+	if err != nil {
+		return err
+	}
+	if uint64(size) > limit {
+		return ErrQuotaExceeded
+	}
+
+	return s.StoreFile(p, tmp)
+}
+
+// StoreFile 将本机暂存的文件从头写入 p，文件已存在时覆盖
+func (s *Session) StoreFile(p string, f *os.File) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if exist, _ := fs.IsPathExist(p); exist {
+		return ErrExist
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	err = fs.UploadOrOverwrite(ctx, &fsctx.FileStream{
+		File:        ioutil.NopCloser(f),
+		Size:        uint64(info.Size()),
+		Name:        path.Base(p),
+		VirtualPath: path.Dir(p),
+	})
+	return convertError(err)
+}
+
+// MakeDir 创建目录
+func (s *Session) MakeDir(p string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if _, err := stat(fs, p); err == nil {
+		return ErrExist
+	}
+
+	_, err = fs.CreateDirectory(context.Background(), p)
+	return convertError(err)
+}
+
+// Remove 删除文件
+func (s *Session) Remove(p string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	exist, file := fs.IsFileExist(p)
+	if !exist {
+		return ErrNotExist
+	}
+
+	return convertError(fs.Delete(context.Background(), nil, []uint{file.ID}, false))
+}
+
+// RemoveDir 删除空目录
+func (s *Session) RemoveDir(p string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if p == "/" {
+		return ErrPermissionDenied
+	}
+	exist, folder := fs.IsPathExist(p)
+	if !exist {
+		return ErrNotExist
+	}
+
+	if files, err := folder.GetChildFiles(); err != nil || len(files) > 0 {
+		return dirNotEmpty(err)
+	}
+	if folders, err := folder.GetChildFolder(); err != nil || len(folders) > 0 {
+		return dirNotEmpty(err)
+	}
+
+	return convertError(fs.Delete(context.Background(), []uint{folder.ID}, nil, false))
+}
+
+// RemoveAll 删除文件或目录，目录中的内容一并删除
+func (s *Session) RemoveAll(p string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if p == "/" {
+		return ErrPermissionDenied
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	info, err := stat(fs, p)
+	if err != nil {
+		return err
+	}
+
+	switch obj := info.(type) {
+	case *model.Folder:
+		err = fs.Delete(context.Background(), []uint{obj.ID}, nil, false)
+	case *model.File:
+		err = fs.Delete(context.Background(), nil, []uint{obj.ID}, false)
+	}
+	return convertError(err)
+}
+
+// dirNotEmpty 查询子项出错时返回原错误，否则返回目录非空
+func dirNotEmpty(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrDirNotEmpty
+}
+
+// Rename 重命名或移动文件及目录，目标已存在时不覆盖
+func (s *Session) Rename(from, to string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if from == "/" || to == "/" {
+		return ErrPermissionDenied
+	}
+
+	fs, err := s.fs()
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	info, err := stat(fs, from)
+	if err != nil {
+		return err
+	}
+	if _, err := stat(fs, to); err == nil {
+		return ErrExist
+	}
+
+	var dirs, files []uint
+	switch obj := info.(type) {
+	case *model.Folder:
+		dirs = []uint{obj.ID}
+	case *model.File:
+		files = []uint{obj.ID}
+	}
+
+	ctx := context.Background()
+	if path.Dir(from) != path.Dir(to) {
+		if err := fs.Move(ctx, dirs, files, path.Dir(from), path.Dir(to)); err != nil {
+			return convertError(err)
+		}
+	}
+	if path.Base(from) != path.Base(to) {
+		fs.CleanTargets()
+		if err := fs.Rename(ctx, dirs, files, path.Base(to)); err != nil {
+			return convertError(err)
+		}
+	}
+
+	return nil
+}
+
+// convertError 将文件系统错误转换为本包定义的错误，其余错误只保留提示信息
+func convertError(err error) error {
+	var appErr serializer.AppError
+	if !errors.As(err, &appErr) {
+		return err
+	}
+
+	switch appErr.Code {
+	case serializer.CodeInsufficientCapacity, serializer.CodeUploadTrafficExceeded:
+		return ErrQuotaExceeded
+	case serializer.CodeNoPermissionErr:
+		return ErrPermissionDenied
+	case serializer.CodeParentNotExist, serializer.CodeNotFound:
+		return ErrNotExist
+	case serializer.CodeObjectExist:
+		return ErrExist
+	case serializer.CodeLocked:
+		return ErrLocked
+	}
+
+	if appErr.Msg != "" {
+		return errors.New(appErr.Msg)
+	}
+	return err
+}
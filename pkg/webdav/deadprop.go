@@ -0,0 +1,88 @@
+package webdav
+
+import (
+	"encoding/xml"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+)
+
+// maxDeadPropSize 单个自定义属性值的最大字节数
+const maxDeadPropSize = 64 << 10
+
+// deadPropKey 自定义属性所属的对象
+type deadPropKey struct {
+	isDir bool
+	id    uint
+}
+
+// deadPropObject 获取文件或目录对应的属性所属对象及其父目录，根目录的父目录为 nil
+func deadPropObject(fi FileInfo) (deadPropKey, *uint) {
+	switch obj := fi.(type) {
+	case *model.Folder:
+		return deadPropKey{isDir: true, id: obj.ID}, obj.ParentID
+	case *model.File:
+		return deadPropKey{id: obj.ID}, &obj.FolderID
+	}
+	return deadPropKey{}, nil
+}
+
+// deadPropLoader 在一次 PROPFIND 请求中加载对象的自定义属性，
+// 按父目录批量加载，列出目录时每个目录只需查询一次
+type deadPropLoader struct {
+	parents map[uint]bool
+	props   map[deadPropKey]map[xml.Name]Property
+}
+
+func newDeadPropLoader() *deadPropLoader {
+	return &deadPropLoader{
+		parents: make(map[uint]bool),
+		props:   make(map[deadPropKey]map[xml.Name]Property),
+	}
+}
+
+// add 缓存加载的属性
+func (l *deadPropLoader) add(props []model.WebdavProp) {
+	for _, prop := range props {
+		key := deadPropKey{isDir: prop.IsDir, id: prop.ObjectID}
+		if l.props[key] == nil {
+			l.props[key] = make(map[xml.Name]Property)
+		}
+
+		name := xml.Name{Space: prop.Space, Local: prop.Name}
+		l.props[key][name] = Property{
+			XMLName:  name,
+			Lang:     prop.Lang,
+			InnerXML: []byte(prop.Value),
+		}
+	}
+}
+
+// get 获取对象的自定义属性
+func (l *deadPropLoader) get(fi FileInfo) (map[xml.Name]Property, error) {
+	key, parent := deadPropObject(fi)
+	if key.id == 0 {
+		return nil, nil
+	}
+
+	if parent == nil {
+		if _, ok := l.props[key]; !ok {
+			props, err := model.GetWebdavProps(key.isDir, key.id)
+			if err != nil {
+				return nil, err
+			}
+			l.add(props)
+			if l.props[key] == nil {
+				l.props[key] = map[xml.Name]Property{}
+			}
+		}
+	} else if !l.parents[*parent] {
+		props, err := model.GetWebdavPropsByParent(*parent)
+		if err != nil {
+			return nil, err
+		}
+		l.add(props)
+		l.parents[*parent] = true
+	}
+
+	return l.props[key], nil
+}
@@ -94,6 +94,9 @@ func copyFiles(ctx context.Context, fs *filesystem.FileSystem, src FileInfo, dst
 	return http.StatusNoContent, nil
 }
 
+// walkPageSize 遍历目录时每页列取的子项数量
+const walkPageSize = 1000
+
 // walkFS traverses filesystem fs starting at name up to depth levels.
 //
 // Allowed values for depth are 0, 1 or infiniteDepth. For each visited node,
@@ -121,27 +124,37 @@ func walkFS(
 		depth = 0
 	}
 
-	dirs, _ := info.(*model.Folder).GetChildFolder()
-	files, _ := info.(*model.Folder).GetChildFiles()
-
-	for _, fileInfo := range files {
-		filename := path.Join(name, fileInfo.Name)
-		err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+	// 分页列取子项，避免大目录一次性载入全部记录
+	query := &model.ChildrenQuery{OrderBy: model.ChildOrderName, Limit: walkPageSize}
+	for {
+		dirs, files, next, err := info.(*model.Folder).ListChildren(query)
 		if err != nil {
-			if !fileInfo.IsDir() || err != filepath.SkipDir {
-				return err
+			return err
+		}
+
+		for _, fileInfo := range dirs {
+			filename := path.Join(name, fileInfo.Name)
+			err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+			if err != nil {
+				if !fileInfo.IsDir() || err != filepath.SkipDir {
+					return err
+				}
 			}
 		}
-	}
 
-	for _, fileInfo := range dirs {
-		filename := path.Join(name, fileInfo.Name)
-		err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
-		if err != nil {
-			if !fileInfo.IsDir() || err != filepath.SkipDir {
-				return err
+		for _, fileInfo := range files {
+			filename := path.Join(name, fileInfo.Name)
+			err = walkFS(ctx, fs, depth, filename, &fileInfo, walkFn)
+			if err != nil {
+				if !fileInfo.IsDir() || err != filepath.SkipDir {
+					return err
+				}
 			}
 		}
+
+		if next == nil {
+			return nil
+		}
+		query.Cursor = next
 	}
-	return nil
 }
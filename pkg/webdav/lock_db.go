@@ -0,0 +1,150 @@
+package webdav
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/gofrs/uuid"
+)
+
+// dbLS 保存在数据库中的锁，由各个节点共享，其他前端写入前同样会检查。
+// 锁以资源在所有者目录树中的完整路径保存，不同 WebDAV 账户根目录下的同一资源使用同一把锁
+type dbLS struct {
+	fs *filesystem.FileSystem
+	// 文件系统根目录的完整路径，首次使用时获取
+	base string
+}
+
+// NewDBLS 为文件系统创建保存在数据库中的 LockSystem
+func NewDBLS(fs *filesystem.FileSystem) LockSystem {
+	return &dbLS{fs: fs}
+}
+
+// fullPath 将相对文件系统根目录的路径转换为完整路径
+func (m *dbLS) fullPath(name string) (string, error) {
+	if m.base == "" {
+		base, err := m.fs.FullPath("/")
+		if err != nil {
+			return "", err
+		}
+		m.base = base
+	}
+
+	return path.Join(m.base, slashClean(name)), nil
+}
+
+// relPath 将完整路径转换为相对文件系统根目录的路径
+func (m *dbLS) relPath(name string) string {
+	switch {
+	case m.base == "/":
+		return name
+	case name == m.base:
+		return "/"
+	case strings.HasPrefix(name, m.base+"/"):
+		return strings.TrimPrefix(name, m.base)
+	}
+	return name
+}
+
+// Confirm 确认条件中的锁作用于 name0 及 name1。锁保存在数据库中，无需在请求期间持有
+func (m *dbLS) Confirm(now time.Time, name0, name1 string, conditions ...Condition) (func(), error) {
+	for _, name := range []string{name0, name1} {
+		if name == "" {
+			continue
+		}
+
+		full, err := m.fullPath(name)
+		if err != nil {
+			return nil, err
+		}
+		if !m.held(full, conditions) {
+			return nil, ErrConfirmationFailed
+		}
+	}
+
+	return func() {}, nil
+}
+
+// held 条件中是否有未过期且作用于 name 的锁
+func (m *dbLS) held(name string, conditions []Condition) bool {
+	for _, c := range conditions {
+		if c.Not || c.Token == "" {
+			continue
+		}
+
+		lock, err := model.GetFileLockByToken(m.fs.User.ID, c.Token)
+		if err == nil && lock.Covers(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Create 创建锁，有效期不超过 maxLockDuration，以免客户端异常退出后资源长期无法写入
+func (m *dbLS) Create(now time.Time, details LockDetails) (string, error) {
+	root, err := m.fullPath(details.Root)
+	if err != nil {
+		return "", err
+	}
+
+	duration := details.Duration
+	if duration < 0 || duration > maxLockDuration {
+		duration = maxLockDuration
+	}
+
+	lock := &model.FileLock{
+		Token:     "opaquelocktoken:" + uuid.Must(uuid.NewV4()).String(),
+		UserID:    m.fs.User.ID,
+		Root:      root,
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		ExpiresAt: now.Add(duration),
+	}
+	if err := model.CreateFileLock(lock); err != nil {
+		if err == model.ErrFileLocked {
+			return "", ErrLocked
+		}
+		return "", err
+	}
+
+	return lock.Token, nil
+}
+
+// Refresh 延长锁的有效期
+func (m *dbLS) Refresh(now time.Time, token string, duration time.Duration) (LockDetails, error) {
+	if _, err := m.fullPath("/"); err != nil {
+		return LockDetails{}, err
+	}
+
+	lock, err := model.GetFileLockByToken(m.fs.User.ID, token)
+	if err != nil {
+		return LockDetails{}, ErrNoSuchLock
+	}
+
+	if duration < 0 || duration > maxLockDuration {
+		duration = maxLockDuration
+	}
+	if err := lock.Refresh(now.Add(duration)); err != nil {
+		return LockDetails{}, err
+	}
+
+	return LockDetails{
+		Root:      m.relPath(lock.Root),
+		Duration:  duration,
+		OwnerXML:  lock.OwnerXML,
+		ZeroDepth: lock.ZeroDepth,
+	}, nil
+}
+
+// Unlock 释放锁
+func (m *dbLS) Unlock(now time.Time, token string) error {
+	lock, err := model.GetFileLockByToken(m.fs.User.ID, token)
+	if err != nil {
+		return ErrNoSuchLock
+	}
+
+	return lock.Delete()
+}
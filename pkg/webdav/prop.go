@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"time"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 )
 
@@ -153,8 +154,8 @@ var liveProps = map[xml.Name]struct {
 	},
 	{Space: "DAV:", Local: "getetag"}: {
 		findFn: findETag,
-		// findETag implements ETag as the concatenated hex values of a file's
-		// modification time and size. This is not a reliable synchronization
+		// findETag implements ETag as the recorded content checksum of a file, or
+		// a weak ETag of its version hash when no checksum is recorded. This is not a reliable synchronization
 		// mechanism for directories, so we do not advertise getetag for DAV
 		// collections.
 		dir: false,
@@ -175,10 +176,13 @@ var liveProps = map[xml.Name]struct {
 //
 // Each Propstat has a unique status and each property name will only be part
 // of one Propstat element.
-func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, pnames []xml.Name) ([]Propstat, error) {
+func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, dp *deadPropLoader, fi FileInfo, pnames []xml.Name) ([]Propstat, error) {
 	isDir := fi.IsDir()
 
-	var deadProps map[xml.Name]Property
+	deadProps, err := dp.get(fi)
+	if err != nil {
+		return nil, err
+	}
 
 	pstatOK := Propstat{Status: http.StatusOK}
 	pstatNotFound := Propstat{Status: http.StatusNotFound}
@@ -208,10 +212,13 @@ func props(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi Fil
 }
 
 // Propnames returns the property names defined for resource name.
-func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo) ([]xml.Name, error) {
+func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, dp *deadPropLoader, fi FileInfo) ([]xml.Name, error) {
 	isDir := fi.IsDir()
 
-	var deadProps map[xml.Name]Property
+	deadProps, err := dp.get(fi)
+	if err != nil {
+		return nil, err
+	}
 
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
@@ -219,6 +226,9 @@ func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi
 			pnames = append(pnames, pn)
 		}
 	}
+	for pn := range deadProps {
+		pnames = append(pnames, pn)
+	}
 	return pnames, nil
 }
 
@@ -230,8 +240,8 @@ func propnames(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi
 // returned if they are named in 'include'.
 //
 // See http://www.webdav.org/specs/rfc4918.html#METHOD_PROPFIND
-func allprop(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, info FileInfo, include []xml.Name) ([]Propstat, error) {
-	pnames, err := propnames(ctx, fs, ls, info)
+func allprop(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, dp *deadPropLoader, info FileInfo, include []xml.Name) ([]Propstat, error) {
+	pnames, err := propnames(ctx, fs, ls, dp, info)
 	if err != nil {
 		return nil, err
 	}
@@ -245,12 +255,12 @@ func allprop(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, info
 			pnames = append(pnames, pn)
 		}
 	}
-	return props(ctx, fs, ls, info, pnames)
+	return props(ctx, fs, ls, dp, info, pnames)
 }
 
-// Patch patches the properties of resource name. The return values are
+// Patch patches the properties of resource fi. The return values are
 // constrained in the same manner as DeadPropsHolder.Patch.
-func patch(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, name string, patches []Proppatch) ([]Propstat, error) {
+func patch(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, fi FileInfo, patches []Proppatch) ([]Propstat, error) {
 	conflict := false
 loop:
 	for _, patch := range patches {
@@ -281,8 +291,40 @@ loop:
 		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
+	// 自定义属性保存在数据库中，属性名或属性值过长时拒绝全部修改
+	pstatTooLarge := Propstat{Status: StatusInsufficientStorage}
+	pstatFailedDep := Propstat{Status: StatusFailedDependency}
+	deadPatches := make([]model.WebdavPropPatch, 0, len(patches))
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			if len(p.XMLName.Space) > model.WebdavPropNameMaxLength ||
+				len(p.XMLName.Local) > model.WebdavPropNameMaxLength ||
+				len(p.InnerXML) > maxDeadPropSize {
+				pstatTooLarge.Props = append(pstatTooLarge.Props, Property{XMLName: p.XMLName})
+			} else {
+				pstatFailedDep.Props = append(pstatFailedDep.Props, Property{XMLName: p.XMLName})
+			}
+
+			deadPatches = append(deadPatches, model.WebdavPropPatch{
+				Remove: patch.Remove,
+				Prop: model.WebdavProp{
+					Space: p.XMLName.Space,
+					Name:  p.XMLName.Local,
+					Lang:  p.Lang,
+					Value: string(p.InnerXML),
+				},
+			})
+		}
+	}
+	if len(pstatTooLarge.Props) > 0 {
+		return makePropstats(pstatTooLarge, pstatFailedDep), nil
+	}
+
+	key, _ := deadPropObject(fi)
+	if err := model.PatchWebdavProps(key.isDir, key.id, deadPatches); err != nil {
+		return nil, err
+	}
+
 	pstat := Propstat{Status: http.StatusOK}
 	for _, patch := range patches {
 		for _, p := range patch.Props {
@@ -405,6 +447,10 @@ type ETager interface {
 }
 
 func findETag(ctx context.Context, fs *filesystem.FileSystem, ls LockSystem, reqPath string, fi FileInfo) (string, error) {
+	// 文件使用与其他下载方式一致的实体标签
+	if file, ok := fi.(*model.File); ok {
+		return file.ETag(), nil
+	}
 	return fmt.Sprintf(`"%x%x"`, fi.ModTime().UnixNano(), fi.GetSize()), nil
 }
 
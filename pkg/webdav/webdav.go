@@ -14,7 +14,6 @@ import (
 	"path"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -26,12 +25,11 @@ import (
 type Handler struct {
 	// Prefix is the URL path prefix to strip from WebDAV resource paths.
 	Prefix string
-	// LockSystem is the lock management system.
-	LockSystem map[uint]LockSystem
+	// LockSystem 为请求的文件系统创建锁管理器
+	LockSystem func(fs *filesystem.FileSystem) LockSystem
 	// Logger is an optional error logger. If non-nil, it will be called
 	// for all HTTP requests.
 	Logger func(*http.Request, error)
-	Mutex  *sync.Mutex
 }
 
 func (h *Handler) stripPrefix(p string, uid uint) (string, int, error) {
@@ -62,18 +60,10 @@ func isPathExist(ctx context.Context, fs *filesystem.FileSystem, path string) (b
 
 func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) {
 	status, err := http.StatusBadRequest, errUnsupportedMethod
-	h.Mutex.Lock()
 	if h.LockSystem == nil {
-		h.Mutex.Unlock()
 		status, err = http.StatusInternalServerError, errNoLockSystem
 	} else {
-		// 检查并新建 LockSystem
-		ls, ok := h.LockSystem[fs.User.ID]
-		if !ok {
-			h.LockSystem[fs.User.ID] = NewMemLS()
-			ls = h.LockSystem[fs.User.ID]
-		}
-		h.Mutex.Unlock()
+		ls := h.LockSystem(fs)
 
 		switch r.Method {
 		case "OPTIONS":
@@ -81,13 +71,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 		case "GET", "HEAD", "POST":
 			status, err = h.handleGetHeadPost(w, r, fs)
 		case "DELETE":
-			status, err = h.handleDelete(w, r, fs)
+			status, err = h.handleDelete(w, r, fs, ls)
 		case "PUT":
-			status, err = h.handlePut(w, r, fs)
+			status, err = h.handlePut(w, r, fs, ls)
 		case "MKCOL":
-			status, err = h.handleMkcol(w, r, fs)
+			status, err = h.handleMkcol(w, r, fs, ls)
 		case "COPY", "MOVE":
-			status, err = h.handleCopyMove(w, r, fs)
+			status, err = h.handleCopyMove(w, r, fs, ls)
 		case "LOCK":
 			status, err = h.handleLock(w, r, fs, ls)
 		case "UNLOCK":
@@ -110,114 +100,118 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request, fs *filesyst
 	}
 }
 
-// OK
+// maxLockDuration 锁的最长有效期，客户端异常退出后资源最多在此期间内无法写入
+const maxLockDuration = time.Hour
+
+// lock 为 root 创建请求期间的临时锁，root 已被其他客户端锁定时返回 StatusLocked
 func (h *Handler) lock(now time.Time, root string, fs *filesystem.FileSystem, ls LockSystem) (token string, status int, err error) {
-	//token, err = ls.Create(now, LockDetails{
-	//	Root:      root,
-	//	Duration:  infiniteTimeout,
-	//	ZeroDepth: true,
-	//})
-	//if err != nil {
-	//	if err == ErrLocked {
-	//		return "", StatusLocked, err
-	//	}
-	//	return "", http.StatusInternalServerError, err
-	//}
+	token, err = ls.Create(now, LockDetails{
+		Root:      root,
+		Duration:  infiniteTimeout,
+		ZeroDepth: true,
+	})
+	if err != nil {
+		if err == ErrLocked {
+			return "", StatusLocked, err
+		}
+		return "", http.StatusInternalServerError, err
+	}
 
-	return fmt.Sprintf("%d", time.Now().Unix()), 0, nil
+	return token, 0, nil
 }
 
-// ok
-func (h *Handler) confirmLocks(r *http.Request, src, dst string, fs *filesystem.FileSystem) (release func(), status int, err error) {
-
-	//hdr := r.Header.Get("If")
-	//h.Mutex.Lock()
-	//ls,ok := h.LockSystem[fs.User.ID]
-	//h.Mutex.Unlock()
-	//if !ok{
-	//	return nil, http.StatusInternalServerError, errNoLockSystem
-	//}
-	//
-	//if hdr == "" {
-	//	// An empty If header means that the client hasn't previously created locks.
-	//	// Even if this client doesn't care about locks, we still need to check that
-	//	// the resources aren't locked by another client, so we create temporary
-	//	// locks that would conflict with another client's locks. These temporary
-	//	// locks are unlocked at the end of the HTTP request.
-	//	now, srcToken, dstToken := time.Now(), "", ""
-	//	if src != "" {
-	//		srcToken, status, err = h.lock(now, src, fs,ls)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	if dst != "" {
-	//		dstToken, status, err = h.lock(now, dst, fs,ls)
-	//		if err != nil {
-	//			if srcToken != "" {
-	//				ls.Unlock(now, srcToken)
-	//			}
-	//			return nil, status, err
-	//		}
-	//	}
-	//
-	//	return func() {
-	//		if dstToken != "" {
-	//			ls.Unlock(now, dstToken)
-	//		}
-	//		if srcToken != "" {
-	//			ls.Unlock(now, srcToken)
-	//		}
-	//	}, 0, nil
-	//}
-	//
-	//ih, ok := parseIfHeader(hdr)
-	//if !ok {
-	//	return nil, http.StatusBadRequest, errInvalidIfHeader
-	//}
-	//// ih is a disjunction (OR) of ifLists, so any ifList will do.
-	//for _, l := range ih.lists {
-	//	lsrc := l.resourceTag
-	//	if lsrc == "" {
-	//		lsrc = src
-	//	} else {
-	//		u, err := url.Parse(lsrc)
-	//		if err != nil {
-	//			continue
-	//		}
-	//		//if u.Host != r.Host {
-	//		//	continue
-	//		//}
-	//		lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
-	//		if err != nil {
-	//			return nil, status, err
-	//		}
-	//	}
-	//	release, err = ls.Confirm(
-	//		time.Now(),
-	//		lsrc,
-	//		dst,
-	//		l.conditions...,
-	//	)
-	//	if err == ErrConfirmationFailed {
-	//		continue
-	//	}
-	//	if err != nil {
-	//		return nil, http.StatusInternalServerError, err
-	//	}
-	//	return release, 0, nil
-	//}
-	//// Section 10.4.1 says that "If this header is evaluated and all state lists
-	//// fail, then the request must fail with a 412 (Precondition Failed) status."
-	//// We follow the spec even though the cond_put_corrupt_token test case from
-	//// the litmus test warns on seeing a 412 instead of a 423 (Locked).
-	//return nil, http.StatusPreconditionFailed, ErrLocked
+// tempLocks 为 src 和 dst 创建临时锁，返回的 release 用于在请求结束后释放
+func (h *Handler) tempLocks(src, dst string, fs *filesystem.FileSystem, ls LockSystem) (release func(), status int, err error) {
+	now, srcToken, dstToken := time.Now(), "", ""
+	if src != "" {
+		srcToken, status, err = h.lock(now, src, fs, ls)
+		if err != nil {
+			return nil, status, err
+		}
+	}
+	if dst != "" {
+		dstToken, status, err = h.lock(now, dst, fs, ls)
+		if err != nil {
+			if srcToken != "" {
+				ls.Unlock(now, srcToken)
+			}
+			return nil, status, err
+		}
+	}
 
 	return func() {
-
+		if dstToken != "" {
+			ls.Unlock(now, dstToken)
+		}
+		if srcToken != "" {
+			ls.Unlock(now, srcToken)
+		}
 	}, 0, nil
 }
 
+// confirmLocks 确认请求可以修改 src 和 dst，资源被锁定时须在 If 头中提供对应的锁 token
+func (h *Handler) confirmLocks(r *http.Request, src, dst string, fs *filesystem.FileSystem, ls LockSystem) (release func(), status int, err error) {
+	hdr := r.Header.Get("If")
+	if hdr == "" {
+		// An empty If header means that the client hasn't previously created locks.
+		// Even if this client doesn't care about locks, we still need to check that
+		// the resources aren't locked by another client, so we create temporary
+		// locks that would conflict with another client's locks. These temporary
+		// locks are unlocked at the end of the HTTP request.
+		return h.tempLocks(src, dst, fs, ls)
+	}
+
+	ih, ok := parseIfHeader(hdr)
+	if !ok {
+		return nil, http.StatusBadRequest, errInvalidIfHeader
+	}
+	// ih is a disjunction (OR) of ifLists, so any ifList will do.
+	for _, l := range ih.lists {
+		lsrc := l.resourceTag
+		if lsrc == "" {
+			lsrc = src
+		} else {
+			u, err := url.Parse(lsrc)
+			if err != nil {
+				continue
+			}
+			lsrc, status, err = h.stripPrefix(u.Path, fs.User.ID)
+			if err != nil {
+				return nil, status, err
+			}
+		}
+		release, err = ls.Confirm(
+			time.Now(),
+			lsrc,
+			dst,
+			l.conditions...,
+		)
+		if err == ErrConfirmationFailed {
+			continue
+		}
+		if err != nil {
+			return nil, http.StatusInternalServerError, err
+		}
+		return release, 0, nil
+	}
+
+	// Windows 及 Office 在服务端重启或锁过期后仍会附带失效的 token，
+	// 此时若资源未被锁定则按未附带 If 头处理，避免保存失败
+	release, status, err = h.tempLocks(src, dst, fs, ls)
+	if err == nil {
+		return release, 0, nil
+	}
+	if err != ErrLocked {
+		return nil, status, err
+	}
+
+	// Section 10.4.1 says that "If this header is evaluated and all state lists
+	// fail, then the request must fail with a 412 (Precondition Failed) status."
+	// We follow the spec even though the cond_put_corrupt_token test case from
+	// the litmus test warns on seeing a 412 instead of a 423 (Locked).
+	return nil, http.StatusPreconditionFailed, ErrLocked
+}
+
 //OK
 func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
@@ -285,7 +279,7 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request, fs *
 }
 
 // OK
-func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
@@ -293,13 +287,14 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 		return status, err
 	}
 
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
 	defer release()
 
-	ctx := r.Context()
+	// 锁已在上方确认，文件系统无需再次检查
+	ctx := context.WithValue(r.Context(), fsctx.IgnoreLocksCtx, true)
 
 	// 尝试作为文件删除
 	if ok, file := fs.IsFileExist(reqPath); ok {
@@ -321,12 +316,12 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request, fs *files
 }
 
 // OK
-func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -337,6 +332,7 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 	defer cancel()
 	ctx = context.WithValue(ctx, fsctx.HTTPCtx, r.Context())
 	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+	ctx = context.WithValue(ctx, fsctx.IgnoreLocksCtx, true)
 
 	fileSize, err := strconv.ParseUint(r.Header.Get("Content-Length"), 10, 64)
 	if err != nil {
@@ -352,46 +348,8 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 		VirtualPath: filePath,
 	}
 
-	// 判断文件是否已存在
-	exist, originFile := fs.IsFileExist(reqPath)
-	if exist {
-		// 已存在，为更新操作
-
-		// 检查此文件是否有软链接
-		fileList, err := model.RemoveFilesWithSoftLinks([]model.File{*originFile})
-		if err == nil && len(fileList) == 0 {
-			// 如果包含软连接，应重新生成新文件副本，并更新source_name
-			originFile.SourceName = fs.GenerateSavePath(ctx, &fileData)
-			fileData.Mode &= ^fsctx.Overwrite
-			fs.Use("AfterUpload", filesystem.HookUpdateSourceName)
-			fs.Use("AfterUploadCanceled", filesystem.HookUpdateSourceName)
-			fs.Use("AfterValidateFailed", filesystem.HookUpdateSourceName)
-		}
-
-		fs.Use("BeforeUpload", filesystem.HookResetPolicy)
-		fs.Use("BeforeUpload", filesystem.HookValidateFile)
-		fs.Use("BeforeUpload", filesystem.HookValidateCapacityDiff)
-		fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
-		fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
-		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
-		fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
-		fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
-		fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
-		ctx = context.WithValue(ctx, fsctx.FileModelCtx, *originFile)
-		fileData.Mode |= fsctx.Overwrite
-	} else {
-		// 给文件系统分配钩子
-		fs.Use("BeforeUpload", filesystem.HookValidateFile)
-		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
-		fs.Use("AfterUploadCanceled", filesystem.HookDeleteTempFile)
-		fs.Use("AfterUploadCanceled", filesystem.HookCancelContext)
-		fs.Use("AfterUpload", filesystem.GenericAfterUpload)
-		fs.Use("AfterUpload", filesystem.HookGenerateThumb)
-		fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
-	}
-
-	// 执行上传
-	err = fs.Upload(ctx, &fileData)
+	// 执行上传，文件已存在时为更新操作
+	err = fs.UploadOrOverwrite(ctx, &fileData)
 	if err != nil {
 		return http.StatusMethodNotAllowed, err
 	}
@@ -405,20 +363,20 @@ func (h *Handler) handlePut(w http.ResponseWriter, r *http.Request, fs *filesyst
 }
 
 // OK
-func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
 	defer release()
 
-	ctx := r.Context()
+	ctx := context.WithValue(r.Context(), fsctx.IgnoreLocksCtx, true)
 
 	if r.ContentLength > 0 {
 		return http.StatusUnsupportedMediaType, nil
@@ -431,7 +389,7 @@ func (h *Handler) handleMkcol(w http.ResponseWriter, r *http.Request, fs *filesy
 }
 
 // OK
-func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem) (status int, err error) {
+func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
 
 	hdr := r.Header.Get("Destination")
@@ -463,7 +421,8 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 		return http.StatusForbidden, errDestinationEqualsSource
 	}
 
-	ctx := r.Context()
+	// 锁由下方确认，文件系统无需再次检查
+	ctx := context.WithValue(r.Context(), fsctx.IgnoreLocksCtx, true)
 
 	isExist, target := isPathExist(ctx, fs, src)
 
@@ -477,7 +436,7 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 		// even though a COPY doesn't modify the source, if a concurrent
 		// operation modifies the source. However, the litmus test explicitly
 		// checks that COPYing a locked-by-another source is OK.
-		release, status, err := h.confirmLocks(r, "", dst, fs)
+		release, status, err := h.confirmLocks(r, "", dst, fs, ls)
 		if err != nil {
 			return status, err
 		}
@@ -499,7 +458,7 @@ func (h *Handler) handleCopyMove(w http.ResponseWriter, r *http.Request, fs *fil
 
 	// windows下，某些情况下（网盘根目录下）Office保存文件时附带的锁token只包含源文件，
 	// 此处暂时去除了对dst锁的检查
-	release, status, err := h.confirmLocks(r, src, "", fs)
+	release, status, err := h.confirmLocks(r, src, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -524,126 +483,105 @@ func (h *Handler) handleLock(w http.ResponseWriter, r *http.Request, fs *filesys
 	if err != nil {
 		return http.StatusBadRequest, err
 	}
+	if duration < 0 || duration > maxLockDuration {
+		duration = maxLockDuration
+	}
 
-	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+	li, status, err := readLockInfo(r.Body)
 	if err != nil {
 		return status, err
 	}
 
-	////ctx := r.Context()
-	//token, ld, now, created := "", LockDetails{}, time.Now(), false
-	//if li == (lockInfo{}) {
-	//	// An empty lockInfo means to refresh the lock.
-	//	ih, ok := parseIfHeader(r.Header.Get("If"))
-	//	if !ok {
-	//		return http.StatusBadRequest, errInvalidIfHeader
-	//	}
-	//	if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
-	//		token = ih.lists[0].conditions[0].Token
-	//	}
-	//	if token == "" {
-	//		return http.StatusBadRequest, errInvalidLockToken
-	//	}
-	//	ld, err = ls.Refresh(now, token, duration)
-	//	if err != nil {
-	//		if err == ErrNoSuchLock {
-	//			return http.StatusPreconditionFailed, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//
-	//} else {
-	//	// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
-	//	// then the request MUST act as if a "Depth:infinity" had been submitted."
-	//	depth := infiniteDepth
-	//	if hdr := r.Header.Get("Depth"); hdr != "" {
-	//		depth = parseDepth(hdr)
-	//		if depth != 0 && depth != infiniteDepth {
-	//			// Section 9.10.3 says that "Values other than 0 or infinity must not be
-	//			// used with the Depth header on a LOCK method".
-	//			return http.StatusBadRequest, errInvalidDepth
-	//		}
-	//	}
-	//	reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
-	//	if err != nil {
-	//		return status, err
-	//	}
-	//	ld = LockDetails{
-	//		Root:      reqPath,
-	//		Duration:  duration,
-	//		OwnerXML:  li.Owner.InnerXML,
-	//		ZeroDepth: depth == 0,
-	//	}
-	//	token, err = ls.Create(now, ld)
-	//	if err != nil {
-	//		if err == ErrLocked {
-	//			return StatusLocked, err
-	//		}
-	//		return http.StatusInternalServerError, err
-	//	}
-	//	defer func() {
-	//		if retErr != nil {
-	//			ls.Unlock(now, token)
-	//		}
-	//	}()
-	//
-	//	// Create the resource if it didn't previously exist.
-	//	//if _, err := h.FileSystem.Stat(ctx, reqPath); err != nil {
-	//	//	f, err := h.FileSystem.OpenFile(ctx, reqPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
-	//	//	if err != nil {
-	//	//		// TODO: detect missing intermediate dirs and return http.StatusConflict?
-	//	//		return http.StatusInternalServerError, err
-	//	//	}
-	//	//	f.Close()
-	//	//	created = true
-	//	//}
-	//
-	//	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//	// Lock-Token value is a Coded-URL. We add angle brackets.
-	//	w.Header().Set("Lock-Token", "<"+token+">")
-	//}
-	//
-	//w.Header().Set("Content-Type", "application/xml; charset=utf-8")
-	//if created {
-	//	// This is "w.WriteHeader(http.StatusCreated)" and not "return
-	//	// http.StatusCreated, nil" because we write our own (XML) response to w
-	//	// and Handler.ServeHTTP would otherwise write "Created".
-	//	w.WriteHeader(http.StatusCreated)
-	//}
+	token, ld, now := "", LockDetails{}, time.Now()
+	if li == (lockInfo{}) {
+		// An empty lockInfo means to refresh the lock.
+		ih, ok := parseIfHeader(r.Header.Get("If"))
+		if !ok {
+			return http.StatusBadRequest, errInvalidIfHeader
+		}
+		if len(ih.lists) == 1 && len(ih.lists[0].conditions) == 1 {
+			token = ih.lists[0].conditions[0].Token
+		}
+		if token == "" {
+			return http.StatusBadRequest, errInvalidLockToken
+		}
+		ld, err = ls.Refresh(now, token, duration)
+		if err != nil {
+			if err == ErrNoSuchLock {
+				return http.StatusPreconditionFailed, err
+			}
+			if err == ErrLocked {
+				return StatusLocked, err
+			}
+			return http.StatusInternalServerError, err
+		}
 
-	writeLockInfo(w, fmt.Sprintf("%d", time.Now().UnixNano()), LockDetails{
-		Duration: duration,
-		OwnerXML: fs.User.Email,
-		Root:     reqPath,
-	})
+	} else {
+		// Section 9.10.3 says that "If no Depth header is submitted on a LOCK request,
+		// then the request MUST act as if a "Depth:infinity" had been submitted."
+		depth := infiniteDepth
+		if hdr := r.Header.Get("Depth"); hdr != "" {
+			depth = parseDepth(hdr)
+			if depth != 0 && depth != infiniteDepth {
+				// Section 9.10.3 says that "Values other than 0 or infinity must not be
+				// used with the Depth header on a LOCK method".
+				return http.StatusBadRequest, errInvalidDepth
+			}
+		}
+		reqPath, status, err := h.stripPrefix(r.URL.Path, fs.User.ID)
+		if err != nil {
+			return status, err
+		}
+		ld = LockDetails{
+			Root:      reqPath,
+			Duration:  duration,
+			OwnerXML:  li.Owner.InnerXML,
+			ZeroDepth: depth == 0,
+		}
+		token, err = ls.Create(now, ld)
+		if err != nil {
+			if err == ErrLocked {
+				return StatusLocked, err
+			}
+			return http.StatusInternalServerError, err
+		}
+
+		// 不为不存在的路径创建空文件，客户端随后的 PUT 请求附带此 token 即可写入
+
+		// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+		// Lock-Token value is a Coded-URL. We add angle brackets.
+		w.Header().Set("Lock-Token", "<"+token+">")
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	writeLockInfo(w, token, ld)
 	return 0, nil
 }
 
 // OK
 func (h *Handler) handleUnlock(w http.ResponseWriter, r *http.Request, fs *filesystem.FileSystem, ls LockSystem) (status int, err error) {
 	defer fs.Recycle()
-	return http.StatusNoContent, err
 
-	//// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
-	//// Lock-Token value is a Coded-URL. We strip its angle brackets.
-	//t := r.Header.Get("Lock-Token")
-	//if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
-	//	return http.StatusBadRequest, errInvalidLockToken
-	//}
-	//t = t[1 : len(t)-1]
-	//
-	//switch err = ls.Unlock(time.Now(), t); err {
-	//case nil:
-	//	return http.StatusNoContent, err
-	//case ErrForbidden:
-	//	return http.StatusForbidden, err
-	//case ErrLocked:
-	//	return StatusLocked, err
-	//case ErrNoSuchLock:
-	//	return http.StatusConflict, err
-	//default:
-	//	return http.StatusInternalServerError, err
-	//}
+	// http://www.webdav.org/specs/rfc4918.html#HEADER_Lock-Token says that the
+	// Lock-Token value is a Coded-URL. We strip its angle brackets.
+	t := r.Header.Get("Lock-Token")
+	if len(t) < 2 || t[0] != '<' || t[len(t)-1] != '>' {
+		return http.StatusBadRequest, errInvalidLockToken
+	}
+	t = t[1 : len(t)-1]
+
+	switch err = ls.Unlock(time.Now(), t); err {
+	case nil:
+		return http.StatusNoContent, err
+	case ErrForbidden:
+		return http.StatusForbidden, err
+	case ErrLocked:
+		return StatusLocked, err
+	case ErrNoSuchLock:
+		return http.StatusConflict, err
+	default:
+		return http.StatusInternalServerError, err
+	}
 }
 
 // OK
@@ -673,6 +611,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 	}
 
 	mw := multistatusWriter{w: w}
+	dp := newDeadPropLoader()
 
 	walkFn := func(reqPath string, info FileInfo, err error) error {
 
@@ -681,7 +620,7 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 		}
 		var pstats []Propstat
 		if pf.Propname != nil {
-			pnames, err := propnames(ctx, fs, ls, info)
+			pnames, err := propnames(ctx, fs, ls, dp, info)
 			if err != nil {
 				return err
 			}
@@ -691,9 +630,9 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request, fs *fil
 			}
 			pstats = append(pstats, pstat)
 		} else if pf.Allprop != nil {
-			pstats, err = allprop(ctx, fs, ls, info, pf.Prop)
+			pstats, err = allprop(ctx, fs, ls, dp, info, pf.Prop)
 		} else {
-			pstats, err = props(ctx, fs, ls, info, pf.Prop)
+			pstats, err = props(ctx, fs, ls, dp, info, pf.Prop)
 		}
 		if err != nil {
 			return err
@@ -723,7 +662,7 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request, fs *fi
 	if err != nil {
 		return status, err
 	}
-	release, status, err := h.confirmLocks(r, reqPath, "", fs)
+	release, status, err := h.confirmLocks(r, reqPath, "", fs, ls)
 	if err != nil {
 		return status, err
 	}
@@ -731,14 +670,15 @@ func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request, fs *fi
 
 	ctx := r.Context()
 
-	if exist, _ := isPathExist(ctx, fs, reqPath); !exist {
+	exist, fi := isPathExist(ctx, fs, reqPath)
+	if !exist {
 		return http.StatusNotFound, nil
 	}
 	patches, status, err := readProppatch(r.Body)
 	if err != nil {
 		return status, err
 	}
-	pstats, err := patch(ctx, fs, ls, reqPath, patches)
+	pstats, err := patch(ctx, fs, ls, fi, patches)
 	if err != nil {
 		return http.StatusInternalServerError, err
 	}
@@ -25,6 +25,50 @@ func AdminSummary(c *gin.Context) {
 	}
 }
 
+// AdminTrafficStatistics 获取每日流量及活跃用户数
+func AdminTrafficStatistics(c *gin.Context) {
+	var service admin.TrafficStatisticsService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Traffic()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminStorageStatistics 获取存储用量汇总
+func AdminStorageStatistics(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.StorageStatistics()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRefreshStatistics 立即汇总站点统计
+func AdminRefreshStatistics(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.RefreshStatistics()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminThumbStatistics 获取缩略图任务池的运行状态
+func AdminThumbStatistics(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.ThumbStatistics()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminNews 获取社区新闻
 func AdminNews(c *gin.Context) {
 	tag := "announcements"
@@ -226,7 +270,7 @@ func AdminListGroup(c *gin.Context) {
 func AdminAddGroup(c *gin.Context) {
 	var service admin.AddGroupService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -237,7 +281,7 @@ func AdminAddGroup(c *gin.Context) {
 func AdminDeleteGroup(c *gin.Context) {
 	var service admin.GroupService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -270,7 +314,7 @@ func AdminListUser(c *gin.Context) {
 func AdminAddUser(c *gin.Context) {
 	var service admin.AddUserService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Add()
+		res := service.Add(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -292,7 +336,7 @@ func AdminGetUser(c *gin.Context) {
 func AdminDeleteUser(c *gin.Context) {
 	var service admin.UserBatchService
 	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Delete()
+		res := service.Delete(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
@@ -303,13 +347,95 @@ func AdminDeleteUser(c *gin.Context) {
 func AdminBanUser(c *gin.Context) {
 	var service admin.UserService
 	if err := c.ShouldBindUri(&service); err == nil {
-		res := service.Ban()
+		res := service.Ban(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminImpersonateUser 模拟用户
+func AdminImpersonateUser(c *gin.Context) {
+	var service admin.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Impersonate(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUpgradeUserGroup 临时调整用户组
+func AdminUpgradeUserGroup(c *gin.Context) {
+	var service admin.UserGroupUpgradeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Upgrade(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRevertUserGroup 提前结束临时用户组
+func AdminRevertUserGroup(c *gin.Context) {
+	var service admin.UserService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.RevertGroup(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminImportUsers 批量导入用户
+func AdminImportUsers(c *gin.Context) {
+	var service admin.UserImportService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Import(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminMergeUser 合并账户
+func AdminMergeUser(c *gin.Context) {
+	var service admin.UserMergeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Merge(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddGuest 创建访客账户
+func AdminAddGuest(c *gin.Context) {
+	var service admin.AddGuestService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRenewGuest 为访客账户续期
+func AdminRenewGuest(c *gin.Context) {
+	var service admin.GuestRenewService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Renew(c)
 		c.JSON(200, res)
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
 
+// AdminStopImpersonation 结束模拟用户
+func AdminStopImpersonation(c *gin.Context) {
+	c.JSON(200, admin.StopImpersonation(c, CurrentUser(c)))
+}
+
 // AdminListFile 列出文件
 func AdminListFile(c *gin.Context) {
 	var service admin.AdminListService
@@ -362,6 +488,17 @@ func AdminListShare(c *gin.Context) {
 	}
 }
 
+// AdminListShareLockLog 列出分享密码锁定记录
+func AdminListShareLockLog(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.ShareLockLogs()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminDeleteShare 批量删除分享
 func AdminDeleteShare(c *gin.Context) {
 	var service admin.ShareBatchService
@@ -373,6 +510,86 @@ func AdminDeleteShare(c *gin.Context) {
 	}
 }
 
+// AdminSearchShare 按所有者和文件名搜索分享
+func AdminSearchShare(c *gin.Context) {
+	var service admin.ShareSearchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Search()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRevokeShare 批量撤销符合条件的分享
+func AdminRevokeShare(c *gin.Context) {
+	var service admin.ShareFilterService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Revoke(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAuditLog 列出审计记录
+func AdminListAuditLog(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.AuditLogs()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminSearchAuditLog 按条件搜索审计记录
+func AdminSearchAuditLog(c *gin.Context) {
+	var service admin.AuditLogSearchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Search()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminExportAuditLog 导出审计记录为 CSV
+func AdminExportAuditLog(c *gin.Context) {
+	var service admin.AuditLogFilterService
+	if err := c.ShouldBindQuery(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	res := service.Export(c)
+	if res.Code != 0 || res.Data != nil {
+		c.JSON(200, res)
+	}
+}
+
+// AdminListFolderShare 列出目录子树下的分享
+func AdminListFolderShare(c *gin.Context) {
+	var service admin.ShareFolderService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRevokeFolderShare 撤销目录子树下的分享
+func AdminRevokeFolderShare(c *gin.Context) {
+	var service admin.ShareFolderService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Revoke(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminListDownload 列出离线下载任务
 func AdminListDownload(c *gin.Context) {
 	var service admin.AdminListService
@@ -428,6 +645,58 @@ func AdminCreateImportTask(c *gin.Context) {
 	}
 }
 
+// AdminCreateStorageAuditTask 新建存储一致性检查任务
+func AdminCreateStorageAuditTask(c *gin.Context) {
+	var service admin.StorageAuditService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminTaskReport 下载任务逐项处理结果报告
+func AdminTaskReport(c *gin.Context) {
+	var service admin.TaskReportService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	res := service.Download(c)
+	if res.Code != 0 || res.Data != nil {
+		c.JSON(200, res)
+	}
+}
+
+// AdminGetTaskPool 获取任务池运行状态
+func AdminGetTaskPool(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.TaskPoolStats()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminResizeTaskPool 调整任务池 Worker 数量
+func AdminResizeTaskPool(c *gin.Context) {
+	var service admin.TaskPoolService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Resize()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AdminListFolders 列出用户或外部文件系统目录
 func AdminListFolders(c *gin.Context) {
 	var service admin.ListFolderService
@@ -493,3 +762,236 @@ func AdminGetNode(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// AdminListOAuthClients 列出第三方应用
+func AdminListOAuthClients(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.OAuthClients()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddOAuthClient 新建或保存第三方应用
+func AdminAddOAuthClient(c *gin.Context) {
+	var service admin.AddOAuthClientService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminResetOAuthClientSecret 重置第三方应用密钥
+func AdminResetOAuthClientSecret(c *gin.Context) {
+	var service admin.OAuthClientService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.ResetSecret()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListInvitations 列出注册邀请码
+func AdminListInvitations(c *gin.Context) {
+	var service admin.AdminListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Invitations()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddInvitations 批量生成注册邀请码
+func AdminAddInvitations(c *gin.Context) {
+	var service admin.AddInvitationService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteInvitations 删除注册邀请码
+func AdminDeleteInvitations(c *gin.Context) {
+	var service admin.InvitationBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteOAuthClient 删除第三方应用
+func AdminDeleteOAuthClient(c *gin.Context) {
+	var service admin.OAuthClientService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListModeration 列出内容审核记录
+func AdminListModeration(c *gin.Context) {
+	var service admin.ModerationListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminResolveModeration 人工复核内容审核记录
+func AdminResolveModeration(c *gin.Context) {
+	var service admin.ModerationResolveService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Resolve(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListBlockedHash 列出禁止的文件摘要
+func AdminListBlockedHash(c *gin.Context) {
+	var service admin.BlockedHashListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddBlockedHash 添加禁止的文件摘要
+func AdminAddBlockedHash(c *gin.Context) {
+	var service admin.BlockedHashAddService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminImportBlockedHash 从外部列表导入禁止的文件摘要
+func AdminImportBlockedHash(c *gin.Context) {
+	var service admin.BlockedHashImportService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Import(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteBlockedHash 删除禁止的文件摘要
+func AdminDeleteBlockedHash(c *gin.Context) {
+	var service admin.BlockedHashBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminListAnnouncement 列出公告
+func AdminListAnnouncement(c *gin.Context) {
+	var service admin.AnnouncementListService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.List()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminAddAnnouncement 创建或保存公告
+func AdminAddAnnouncement(c *gin.Context) {
+	var service admin.AddAnnouncementService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Add(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminPublishAnnouncement 发布公告
+func AdminPublishAnnouncement(c *gin.Context) {
+	var service admin.AnnouncementService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Publish(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminDeleteAnnouncement 删除公告
+func AdminDeleteAnnouncement(c *gin.Context) {
+	var service admin.AnnouncementService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminGetMaintenance 获取维护模式状态
+func AdminGetMaintenance(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.MaintenanceStatus()
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminUpdateMaintenance 开启或关闭维护模式
+func AdminUpdateMaintenance(c *gin.Context) {
+	var service admin.MaintenanceService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminExportBackup 导出备份
+func AdminExportBackup(c *gin.Context) {
+	var service admin.BackupService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Export(c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// AdminRestoreBackup 从备份恢复
+func AdminRestoreBackup(c *gin.Context) {
+	var service admin.NoParamService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Restore(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
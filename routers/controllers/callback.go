@@ -120,3 +120,20 @@ func S3Callback(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// MailWebhook 邮件服务商投递通知回调
+func MailWebhook(c *gin.Context) {
+	var service callback.MailWebhookService
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(400, ErrorResponse(err))
+		return
+	}
+
+	res := service.Handle(c)
+	if res.Code != 0 {
+		c.JSON(400, res)
+		return
+	}
+
+	c.JSON(200, res)
+}
@@ -19,7 +19,12 @@ func CreateDirectory(c *gin.Context) {
 // ListDirectory 列出目录下内容
 func ListDirectory(c *gin.Context) {
 	var service explorer.DirectoryService
-	if err := c.ShouldBindUri(&service); err == nil {
+	if err := c.ShouldBindUri(&service); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	if err := c.ShouldBindQuery(&service); err == nil {
 		res := service.ListDirectory(c)
 		c.JSON(200, res)
 	} else {
@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/share"
+	"github.com/gin-gonic/gin"
+)
+
+// FederateShare 将分享提供给其他实例的用户
+func FederateShare(c *gin.Context) {
+	var service share.FederateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Federate(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ReceiveFederatedShare 接收其他实例提供的联邦分享
+func ReceiveFederatedShare(c *gin.Context) {
+	var service share.FederationOfferService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Receive(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetFederatedShare 供其他实例确认联邦分享
+func GetFederatedShare(c *gin.Context) {
+	var service share.FederatedShareService
+	res := service.Get(c)
+	c.JSON(200, res)
+}
+
+// ListRemoteShare 列出收到的远程分享
+func ListRemoteShare(c *gin.Context) {
+	var service share.RemoteShareListService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// AcceptRemoteShare 接受远程分享
+func AcceptRemoteShare(c *gin.Context) {
+	var service share.RemoteShareService
+	res := service.Accept(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// DeleteRemoteShare 拒绝或移除远程分享
+func DeleteRemoteShare(c *gin.Context) {
+	var service share.RemoteShareService
+	res := service.Delete(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// ListRemoteSharedFolder 列出远程分享目录下的对象
+func ListRemoteSharedFolder(c *gin.Context) {
+	var service share.RemoteShareService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.ListDirectory(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// GetRemoteShareDownload 获取远程分享文件的下载地址
+func GetRemoteShareDownload(c *gin.Context) {
+	var service share.RemoteShareService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Download(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -62,6 +62,17 @@ func Decompress(c *gin.Context) {
 	}
 }
 
+// ListArchive 列出压缩包内的条目
+func ListArchive(c *gin.Context) {
+	var service explorer.ArchiveListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.ListArchive(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // AnonymousGetContent 匿名获取文件资源
 func AnonymousGetContent(c *gin.Context) {
 	// 创建上下文
@@ -149,6 +160,11 @@ func Thumb(c *gin.Context) {
 		return
 	}
 
+	// 缩略图生成完成前返回的占位图不可缓存
+	if resp.Placeholder {
+		c.Header("Cache-Control", "no-store")
+	}
+
 	defer resp.Content.Close()
 	http.ServeContent(c.Writer, c.Request, "thumb."+model.GetSettingByNameWithDefault("thumb_encode_method", "jpg"), fs.FileTarget[0].UpdatedAt, resp.Content)
 
@@ -225,6 +241,34 @@ func CreateDownloadSession(c *gin.Context) {
 	}
 }
 
+// CreateOneTimeLink 创建一次性下载链接
+func CreateOneTimeLink(c *gin.Context) {
+	var service explorer.OneTimeLinkCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// OneTimeDownload 通过一次性链接下载文件
+func OneTimeDownload(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.OneTimeDownloadService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(ctx, c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // Download 文件下载
 func Download(c *gin.Context) {
 	// 创建上下文
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/graphql"
+	"github.com/gin-gonic/gin"
+)
+
+// GraphQL 执行只读的 GraphQL 查询
+func GraphQL(c *gin.Context) {
+	var service graphql.QueryService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Execute(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/oauth"
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthAuthorizeInfo 获取授权确认页面信息
+func OAuthAuthorizeInfo(c *gin.Context) {
+	var service oauth.AuthorizeService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Info(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// OAuthAuthorize 用户确认或拒绝授权
+func OAuthAuthorize(c *gin.Context) {
+	var service oauth.AuthorizeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Authorize(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// OAuthToken 令牌端点，响应格式遵循 RFC 6749
+func OAuthToken(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	var service oauth.TokenService
+	if err := c.ShouldBind(&service); err != nil {
+		c.JSON(400, oauth.Error{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	res, oauthErr := service.Token(c)
+	if oauthErr != nil {
+		c.JSON(oauthErr.Status, oauthErr)
+		return
+	}
+	c.JSON(200, res)
+}
+
+// OAuthRevoke 令牌撤销端点，响应格式遵循 RFC 7009
+func OAuthRevoke(c *gin.Context) {
+	var service oauth.RevokeService
+	if err := c.ShouldBind(&service); err != nil {
+		c.JSON(400, oauth.Error{Code: "invalid_request", Description: err.Error()})
+		return
+	}
+
+	if oauthErr := service.Revoke(c); oauthErr != nil {
+		c.JSON(oauthErr.Status, oauthErr)
+		return
+	}
+	c.Status(200)
+}
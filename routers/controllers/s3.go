@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/cloudreve/Cloudreve/v3/service/s3"
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// serveS3Bucket 绑定存储桶参数并执行操作，出错时返回 S3 格式的错误
+func serveS3Bucket(c *gin.Context, handler func(*s3.BucketService, *gin.Context) error) {
+	var service s3.BucketService
+	if err := c.ShouldBindUri(&service); err != nil {
+		s3gateway.WriteError(c, s3gateway.ErrInvalidArgument)
+		return
+	}
+	if err := handler(&service, c); err != nil {
+		s3gateway.WriteError(c, err)
+	}
+}
+
+// serveS3Object 绑定对象参数并执行操作，出错时返回 S3 格式的错误
+func serveS3Object(c *gin.Context, handler func(*s3.ObjectService, *gin.Context) error) {
+	var service s3.ObjectService
+	if err := c.ShouldBindUri(&service); err != nil {
+		s3gateway.WriteError(c, s3gateway.ErrInvalidArgument)
+		return
+	}
+	if err := handler(&service, c); err != nil {
+		s3gateway.WriteError(c, err)
+	}
+}
+
+// S3HeadBucket 检查存储桶
+func S3HeadBucket(c *gin.Context) {
+	serveS3Bucket(c, (*s3.BucketService).Head)
+}
+
+// S3CreateBucket 创建存储桶
+func S3CreateBucket(c *gin.Context) {
+	serveS3Bucket(c, (*s3.BucketService).Create)
+}
+
+// S3GetBucket 列出对象等存储桶上的读取操作
+func S3GetBucket(c *gin.Context) {
+	serveS3Bucket(c, (*s3.BucketService).Get)
+}
+
+// S3PostBucket 批量删除对象
+func S3PostBucket(c *gin.Context) {
+	serveS3Bucket(c, (*s3.BucketService).Post)
+}
+
+// 部分客户端请求存储桶时路径以 / 结尾，此时对象键为 /，按存储桶操作处理
+
+// S3HeadObject 获取对象元数据
+func S3HeadObject(c *gin.Context) {
+	if c.Param("key") == "/" {
+		S3HeadBucket(c)
+		return
+	}
+	serveS3Object(c, (*s3.ObjectService).Get)
+}
+
+// S3GetObject 下载对象
+func S3GetObject(c *gin.Context) {
+	if c.Param("key") == "/" {
+		S3GetBucket(c)
+		return
+	}
+	serveS3Object(c, (*s3.ObjectService).Get)
+}
+
+// S3PutObject 上传对象或分片
+func S3PutObject(c *gin.Context) {
+	if c.Param("key") == "/" {
+		S3CreateBucket(c)
+		return
+	}
+	serveS3Object(c, (*s3.ObjectService).Put)
+}
+
+// S3DeleteObject 删除对象或取消分片上传
+func S3DeleteObject(c *gin.Context) {
+	if c.Param("key") == "/" {
+		S3NotImplemented(c)
+		return
+	}
+	serveS3Object(c, (*s3.ObjectService).Delete)
+}
+
+// S3PostObject 创建或完成分片上传
+func S3PostObject(c *gin.Context) {
+	if c.Param("key") == "/" {
+		S3PostBucket(c)
+		return
+	}
+	serveS3Object(c, (*s3.ObjectService).Post)
+}
+
+// S3ListBuckets 列出存储桶
+func S3ListBuckets(c *gin.Context) {
+	if err := s3.ListBuckets(c); err != nil {
+		s3gateway.WriteError(c, err)
+	}
+}
+
+// S3NotImplemented 不支持的 S3 请求
+func S3NotImplemented(c *gin.Context) {
+	s3gateway.WriteError(c, s3gateway.ErrNotImplemented)
+}
+
+// ListS3Keys 列出 S3 访问密钥
+func ListS3Keys(c *gin.Context) {
+	var service setting.S3KeyListService
+	res := service.Keys(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// CreateS3Key 创建 S3 访问密钥
+func CreateS3Key(c *gin.Context) {
+	var service setting.S3KeyCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteS3Key 删除 S3 访问密钥
+func DeleteS3Key(c *gin.Context) {
+	var service setting.S3KeyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
@@ -0,0 +1,35 @@
+package controllers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
+	"github.com/gin-gonic/gin"
+)
+
+// ListSSHKeys 列出 SFTP 登录所用的 SSH 公钥
+func ListSSHKeys(c *gin.Context) {
+	var service setting.SSHKeyListService
+	res := service.Keys(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// CreateSSHKey 添加 SSH 公钥
+func CreateSSHKey(c *gin.Context) {
+	var service setting.SSHKeyCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteSSHKey 删除 SSH 公钥
+func DeleteSSHKey(c *gin.Context) {
+	var service setting.SSHKeyService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
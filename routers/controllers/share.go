@@ -45,6 +45,17 @@ func ListShare(c *gin.Context) {
 	}
 }
 
+// ListReceivedShare 列出分享给我的内部分享
+func ListReceivedShare(c *gin.Context) {
+	var service share.ShareListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Received(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // SearchShare 搜索分享
 func SearchShare(c *gin.Context) {
 	var service share.ShareListService
@@ -56,6 +67,86 @@ func SearchShare(c *gin.Context) {
 	}
 }
 
+// GetShareAnalytics 获取分享访问统计
+func GetShareAnalytics(c *gin.Context) {
+	var service share.AnalyticsService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Analytics(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// MailShare 通过邮件发送分享链接
+func MailShare(c *gin.Context) {
+	var service share.MailService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Send(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListShareMail 列出分享的邮件发送记录
+func ListShareMail(c *gin.Context) {
+	var service share.MailLogService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteSharedObject 删除分享目录下的对象
+func DeleteSharedObject(c *gin.Context) {
+	var service share.ObjectDeleteService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// RenameSharedObject 重命名分享目录下的对象
+func RenameSharedObject(c *gin.Context) {
+	var service share.ObjectRenameService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Rename(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateSharedDirectory 在分享目录下创建目录
+func CreateSharedDirectory(c *gin.Context) {
+	var service share.DirectoryCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListFolderShare 列出目录子树下的分享
+func ListFolderShare(c *gin.Context) {
+	var service share.FolderSharesService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// RevokeFolderShare 撤销目录子树下的分享
+func RevokeFolderShare(c *gin.Context) {
+	var service share.FolderSharesService
+	res := service.Revoke(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
 // UpdateShare 更新分享属性
 func UpdateShare(c *gin.Context) {
 	var service share.ShareUpdateService
@@ -162,6 +253,17 @@ func PreviewShareReadme(c *gin.Context) {
 	}
 }
 
+// CreateSharePreviewToken 签发分享预览令牌
+func CreateSharePreviewToken(c *gin.Context) {
+	var service share.PreviewTokenService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // GetShareDocPreview 创建分享Office文档预览地址
 func GetShareDocPreview(c *gin.Context) {
 	var service share.Service
@@ -173,6 +275,17 @@ func GetShareDocPreview(c *gin.Context) {
 	}
 }
 
+// GetShareMetadata 获取分享页面展示的描述及自述文件
+func GetShareMetadata(c *gin.Context) {
+	var service share.Service
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Metadata(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // ListSharedFolder 列出分享的目录下的对象
 func ListSharedFolder(c *gin.Context) {
 	var service share.Service
@@ -212,6 +325,93 @@ func ArchiveShare(c *gin.Context) {
 	}
 }
 
+// ShareUpload 访客向分享目录上传文件
+func ShareUpload(c *gin.Context) {
+	var service share.UploadService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Upload(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ShareQRCode 获取分享链接的二维码
+func ShareQRCode(c *gin.Context) {
+	var service share.QRCodeService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.QRCode(c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ExportShareMetalink 导出分享文件的 Metalink 或种子文件
+func ExportShareMetalink(c *gin.Context) {
+	var service share.MetalinkService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Export(c)
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ShareCardThumb 获取分享链接预览卡片的缩略图
+func ShareCardThumb(c *gin.Context) {
+	var service share.Service
+	res := service.CardThumb(c)
+	if res.Code >= 0 {
+		c.JSON(200, res)
+	}
+}
+
+// ShareCardStream 预览卡片中的视频流
+func ShareCardStream(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service share.Service
+	res := service.CardStream(ctx, c)
+	// 是否需要重定向
+	if res.Code == -301 {
+		c.Redirect(302, res.Data.(string))
+		return
+	}
+	// 是否有错误发生
+	if res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
+
+// ShareCardPlayer 预览卡片的内嵌播放页
+func ShareCardPlayer(c *gin.Context) {
+	var service share.Service
+	res := service.CardPlayer(c)
+	if res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
+
+// ShareOEmbed 获取分享链接的 oEmbed 信息
+func ShareOEmbed(c *gin.Context) {
+	var service share.OEmbedService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.OEmbed(c)
+		if res.Code >= 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // ShareThumb 获取分享目录下文件的缩略图
 func ShareThumb(c *gin.Context) {
 	var service share.Service
@@ -1,8 +1,13 @@
 package controllers
 
 import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/health"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -23,10 +28,16 @@ func SiteConfig(c *gin.Context) {
 		"home_view_method",
 		"share_view_method",
 		"authn_enabled",
+		"oidc_enabled",
+		"saml_enabled",
 		"captcha_ReCaptchaKey",
 		"captcha_type",
 		"captcha_TCaptcha_CaptchaAppId",
 		"register_enabled",
+		"register_invite",
+		"register_phone",
+		"maintenance_enabled",
+		"maintenance_message",
 	)
 
 	// 如果已登录，则同时返回用户信息和标签
@@ -39,6 +50,22 @@ func SiteConfig(c *gin.Context) {
 	c.JSON(200, serializer.BuildSiteConfig(siteConfig, nil))
 }
 
+// SiteAnnouncements 列出当前用户所在用户组可见的公告，未登录时使用游客用户组
+func SiteAnnouncements(c *gin.Context) {
+	user := CurrentUser(c)
+	if user == nil {
+		user = model.NewAnonymousUser()
+	}
+
+	announcements, err := model.ListActiveAnnouncements(user.Group.ID)
+	if err != nil {
+		c.JSON(200, serializer.DBErr("Failed to list announcements", err))
+		return
+	}
+
+	c.JSON(200, serializer.BuildAnnouncementList(announcements))
+}
+
 // Ping 状态检查页面
 func Ping(c *gin.Context) {
 	version := conf.BackendVersion
@@ -52,6 +79,24 @@ func Ping(c *gin.Context) {
 	})
 }
 
+// Healthz 组件健康检查，关键组件异常时返回 503；
+// 请求携带 health_check_token 时返回各组件的错误详情
+func Healthz(c *gin.Context) {
+	report := health.Get(context.Background())
+
+	token := model.GetSettingByName("health_check_token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(c.Query("token")), []byte(token)) != 1 {
+		report = report.Public()
+	}
+
+	status := http.StatusOK
+	if report.Status == health.StatusUnhealthy {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, report)
+}
+
 // Captcha 获取验证码
 func Captcha(c *gin.Context) {
 	options := model.GetSettingByNames(
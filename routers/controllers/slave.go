@@ -146,6 +146,17 @@ func SlaveList(c *gin.Context) {
 	}
 }
 
+// SlaveCapacity 从机查询存储目录的磁盘容量
+func SlaveCapacity(c *gin.Context) {
+	var service explorer.SlaveCapacityService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Capacity(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // SlaveHeartbeat 接受主机心跳包
 func SlaveHeartbeat(c *gin.Context) {
 	var service serializer.NodePingReq
@@ -0,0 +1,43 @@
+package controllers
+
+import (
+	"context"
+
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ListFileChanges 列出同步游标之后的文件变更
+func ListFileChanges(c *gin.Context) {
+	var service explorer.SyncChangesService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SyncDownload 按版本条件下载文件
+func SyncDownload(c *gin.Context) {
+	// 创建上下文
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var service explorer.SyncDownloadService
+	res := service.Download(ctx, c, CurrentUser(c))
+	if res.Code != 0 {
+		c.JSON(200, res)
+	}
+}
+
+// SyncUpload 按版本条件上传文件
+func SyncUpload(c *gin.Context) {
+	var service explorer.SyncUploadService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Upload(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
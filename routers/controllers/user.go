@@ -2,14 +2,17 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
+	"path"
+	"strconv"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/authn"
 	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/saml"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/cloudreve/Cloudreve/v3/service/setting"
 	"github.com/cloudreve/Cloudreve/v3/service/user"
 	"github.com/duo-labs/webauthn/webauthn"
 	"github.com/gin-gonic/gin"
@@ -69,90 +72,186 @@ func FinishLoginAuthn(c *gin.Context) {
 		return
 	}
 
-	_, err = instance.FinishLogin(expectedUser, sessionData, c.Request)
+	credential, err := instance.FinishLogin(expectedUser, sessionData, c.Request)
 
 	if err != nil {
 		c.JSON(200, serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err))
 		return
 	}
+	expectedUser.UpdateAuthnUsage(credential)
 
-	util.SetSession(c, map[string]interface{}{
-		"user_id": expectedUser.ID,
-	})
-	c.JSON(200, serializer.BuildUserResponse(expectedUser))
+	c.JSON(200, user.SignIn(c, &expectedUser))
 }
 
 // StartRegAuthn 开始注册WebAuthn信息
 func StartRegAuthn(c *gin.Context) {
-	currUser := CurrentUser(c)
-
-	instance, err := authn.NewAuthnInstance()
-	if err != nil {
-		c.JSON(200, serializer.Err(serializer.CodeInternalSetting, "无法初始化Authn", err))
-		return
+	var service user.AuthnRegisterService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Start(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
 	}
+}
 
-	options, sessionData, err := instance.BeginRegistration(currUser)
+// FinishRegAuthn 完成注册WebAuthn信息
+func FinishRegAuthn(c *gin.Context) {
+	var service user.AuthnRegisterService
+	res := service.Finish(c, CurrentUser(c))
+	c.JSON(200, res)
+}
 
-	if err != nil {
+// ListAuthn 列出已注册的验证器
+func ListAuthn(c *gin.Context) {
+	var service user.AuthnListService
+	res := service.List(c, CurrentUser(c))
+	c.JSON(200, res)
+}
+
+// RenameAuthn 重命名验证器
+func RenameAuthn(c *gin.Context) {
+	var service user.AuthnManageService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Rename(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
 		c.JSON(200, ErrorResponse(err))
-		return
 	}
+}
 
-	val, err := json.Marshal(sessionData)
-	if err != nil {
+// RevokeAuthn 撤销验证器
+func RevokeAuthn(c *gin.Context) {
+	var service user.AuthnManageService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Revoke(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
 		c.JSON(200, ErrorResponse(err))
-		return
 	}
+}
 
-	util.SetSession(c, map[string]interface{}{
-		"registration-session": val,
-	})
-	c.JSON(200, serializer.Response{Code: 0, Data: options})
+// StartPasskeyLogin 开始通行密钥登录
+func StartPasskeyLogin(c *gin.Context) {
+	var service user.PasskeyLoginService
+	res := service.Start(c)
+	c.JSON(200, res)
 }
 
-// FinishRegAuthn 完成注册WebAuthn信息
-func FinishRegAuthn(c *gin.Context) {
-	currUser := CurrentUser(c)
-	sessionDataJSON := util.GetSession(c, "registration-session").([]byte)
+// FinishPasskeyLogin 完成通行密钥登录
+func FinishPasskeyLogin(c *gin.Context) {
+	var service user.PasskeyLoginService
+	res := service.Finish(c)
+	c.JSON(200, res)
+}
 
-	var sessionData webauthn.SessionData
-	err := json.Unmarshal(sessionDataJSON, &sessionData)
+// StartAuthn2FA 开始使用验证器进行二步验证
+func StartAuthn2FA(c *gin.Context) {
+	var service user.AuthnSecondFactorService
+	res := service.Start(c)
+	c.JSON(200, res)
+}
 
-	instance, err := authn.NewAuthnInstance()
-	if err != nil {
-		c.JSON(200, serializer.Err(serializer.CodeInternalSetting, "无法初始化Authn", err))
+// FinishAuthn2FA 完成使用验证器的二步验证
+func FinishAuthn2FA(c *gin.Context) {
+	var service user.AuthnSecondFactorService
+	res := service.Finish(c)
+	c.JSON(200, res)
+}
+
+// UserLogin 用户登录
+func UserLogin(c *gin.Context) {
+	var service user.UserLoginService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Login(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// OIDCLogin 获取 OIDC 单点登录地址
+func OIDCLogin(c *gin.Context) {
+	var service user.OIDCLoginService
+	res := service.URL(c)
+	c.JSON(200, res)
+}
+
+// OIDCCallback OIDC 单点登录回调，完成后跳转回前端页面
+func OIDCCallback(c *gin.Context) {
+	var service user.OIDCCallbackService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		ssoRedirect(c, service.Login(c))
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ssoRedirect 单点登录完成后跳转回前端页面，失败或需要二步验证时回到登录页
+func ssoRedirect(c *gin.Context, res serializer.Response) {
+	redirect := model.GetSiteURL()
+	if res.Code == 0 {
+		redirect.Path = path.Join(redirect.Path, "/home")
+		c.Redirect(303, redirect.String())
 		return
 	}
 
-	credential, err := instance.FinishRegistration(currUser, sessionData, c.Request)
+	redirect.Path = path.Join(redirect.Path, "/login")
+	queries := redirect.Query()
+	queries.Add("code", strconv.Itoa(res.Code))
+	queries.Add("msg", res.Msg)
+	redirect.RawQuery = queries.Encode()
+	c.Redirect(303, redirect.String())
+}
 
+// SAMLMetadata 获取 SAML 服务提供方元数据
+func SAMLMetadata(c *gin.Context) {
+	conf, err := saml.NewConfig()
 	if err != nil {
-		c.JSON(200, ErrorResponse(err))
+		c.JSON(200, serializer.Err(serializer.CodeInternalSetting, "Invalid SAML settings", err))
 		return
 	}
 
-	err = currUser.RegisterAuthn(credential)
+	metadata, err := conf.Metadata()
 	if err != nil {
-		c.JSON(200, ErrorResponse(err))
+		c.JSON(200, serializer.Err(serializer.CodeInternalSetting, "Failed to create SAML metadata", err))
 		return
 	}
+	c.Data(200, "application/samlmetadata+xml", metadata)
+}
 
-	c.JSON(200, serializer.Response{
-		Code: 0,
-		Data: map[string]interface{}{
-			"id":          credential.ID,
-			"fingerprint": fmt.Sprintf("% X", credential.Authenticator.AAGUID),
-		},
-	})
+// SAMLLogin 获取 SAML 单点登录地址
+func SAMLLogin(c *gin.Context) {
+	var service user.SAMLLoginService
+	res := service.URL(c)
+	c.JSON(200, res)
 }
 
-// UserLogin 用户登录
-func UserLogin(c *gin.Context) {
-	var service user.UserLoginService
-	if err := c.ShouldBindJSON(&service); err == nil {
-		res := service.Login(c)
-		c.JSON(200, res)
+// SAMLACS SAML 断言消费服务，完成后跳转回前端页面
+func SAMLACS(c *gin.Context) {
+	var service user.SAMLACSService
+	if err := c.ShouldBind(&service); err == nil {
+		ssoRedirect(c, service.Login(c))
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// SAMLSLO SAML 单点登出服务
+func SAMLSLO(c *gin.Context) {
+	var service user.SAMLSLOService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		target, res := service.Logout(c)
+		if res.Code != 0 {
+			ssoRedirect(c, res)
+			return
+		}
+
+		if target == "" {
+			redirect := model.GetSiteURL()
+			redirect.Path = path.Join(redirect.Path, "/login")
+			target = redirect.String()
+		}
+		c.Redirect(303, target)
 	} else {
 		c.JSON(200, ErrorResponse(err))
 	}
@@ -180,6 +279,17 @@ func User2FALogin(c *gin.Context) {
 	}
 }
 
+// UserConfirmLogin 使用邮件验证码确认异常登录
+func UserConfirmLogin(c *gin.Context) {
+	var service user.LoginConfirmService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Confirm(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserSendReset 发送密码重设邮件
 func UserSendReset(c *gin.Context) {
 	var service user.UserResetEmailService
@@ -215,7 +325,19 @@ func UserActivate(c *gin.Context) {
 
 // UserSignOut 用户退出登录
 func UserSignOut(c *gin.Context) {
+	if key, ok := util.GetSession(c, "session_key").(string); ok {
+		model.DeleteUserSessionByKey(key)
+		util.DeleteSession(c, "session_key")
+	}
+	util.DeleteSession(c, "impersonate_id")
+	util.DeleteSession(c, "impersonate_until")
 	util.DeleteSession(c, "user_id")
+
+	// 通过 SAML 登录时，返回身份提供方的单点登出地址
+	if target := user.SAMLLogoutURL(c); target != "" {
+		c.JSON(200, serializer.Response{Data: target})
+		return
+	}
 	c.JSON(200, serializer.Response{})
 }
 
@@ -244,6 +366,43 @@ func UserTasks(c *gin.Context) {
 	}
 }
 
+// UserTaskReport 下载任务逐项处理结果报告
+func UserTaskReport(c *gin.Context) {
+	var service user.TaskReportService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.Download(c, CurrentUser(c), c.MustGet("object_id").(uint))
+		if res.Code != 0 || res.Data != nil {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserCreateTakeout 创建导出个人数据任务
+func UserCreateTakeout(c *gin.Context) {
+	var service user.TakeoutService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserTakeoutDownload 下载导出的个人数据
+func UserTakeoutDownload(c *gin.Context) {
+	var service user.TakeoutService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Download(c, CurrentUser(c), c.MustGet("object_id").(uint))
+		if res.Code != 0 {
+			c.JSON(200, res)
+		}
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserSetting 获取用户设定
 func UserSetting(c *gin.Context) {
 	var service user.SettingService
@@ -347,8 +506,18 @@ func UpdateOption(c *gin.Context) {
 			subService = &user.Enable2FA{}
 		case "authn":
 			subService = &user.DeleteWebAuthn{}
+		case "authn_2fa":
+			subService = &user.AuthnTwoFactor{}
 		case "theme":
 			subService = &user.ThemeChose{}
+		case "email":
+			subService = &user.EmailChange{}
+		case "notification":
+			subService = &user.NotificationPreferenceChange{}
+		case "dlna":
+			subService = &user.DLNAFolderChange{}
+		case "phone":
+			subService = &user.PhoneBind{}
 		default:
 			subService = &user.ChangerNick{}
 		}
@@ -367,6 +536,105 @@ func UpdateOption(c *gin.Context) {
 	}
 }
 
+// UserNotifications 列出站内信
+func UserNotifications(c *gin.Context) {
+	var service user.NotificationListService
+	if err := c.ShouldBindQuery(&service); err == nil {
+		res := service.List(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserReadNotifications 将站内信标为已读
+func UserReadNotifications(c *gin.Context) {
+	var service user.NotificationBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.MarkRead(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserDeleteNotifications 删除站内信
+func UserDeleteNotifications(c *gin.Context) {
+	var service user.NotificationBatchService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserConfirmEmailChange 验证新邮箱并完成更改
+func UserConfirmEmailChange(c *gin.Context) {
+	var service user.EmailChangeConfirmService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Confirm(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserSendPhoneCode 发送注册短信验证码
+func UserSendPhoneCode(c *gin.Context) {
+	var service user.PhoneCodeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Send(c)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserConfirmPhoneBind 验证并绑定手机号码
+func UserConfirmPhoneBind(c *gin.Context) {
+	var service user.PhoneBindConfirmService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Confirm(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserRegenerateRecoveryCodes 重新生成二步验证恢复码
+func UserRegenerateRecoveryCodes(c *gin.Context) {
+	var service user.RecoveryCodeService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Regenerate(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserScheduleDeletion 申请注销账户
+func UserScheduleDeletion(c *gin.Context) {
+	var service user.AccountDeletionService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Schedule(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserCancelDeletion 撤销注销申请
+func UserCancelDeletion(c *gin.Context) {
+	var service user.CancelDeletionService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Cancel(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
 // UserInit2FA 初始化二步验证
 func UserInit2FA(c *gin.Context) {
 	var service user.SettingService
@@ -377,3 +645,119 @@ func UserInit2FA(c *gin.Context) {
 		c.JSON(200, ErrorResponse(err))
 	}
 }
+
+// UserDevices 列出登录设备及应用凭证
+func UserDevices(c *gin.Context) {
+	var service setting.DeviceListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Devices(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserRevokeDevice 注销登录会话或撤销应用凭证
+func UserRevokeDevice(c *gin.Context) {
+	var service setting.DeviceService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Revoke(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UserSignOutOthers 注销其他全部登录会话
+func UserSignOutOthers(c *gin.Context) {
+	var service setting.SignOutOthersService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.SignOut(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListInvitations 列出注册邀请码
+func ListInvitations(c *gin.Context) {
+	var service setting.InvitationListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Invitations(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateInvitation 创建注册邀请码
+func CreateInvitation(c *gin.Context) {
+	var service setting.InvitationCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteInvitation 删除注册邀请码
+func DeleteInvitation(c *gin.Context) {
+	var service setting.InvitationService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// ListSubAccounts 列出子账户
+func ListSubAccounts(c *gin.Context) {
+	var service setting.SubAccountListService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.SubAccounts(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// CreateSubAccount 创建子账户
+func CreateSubAccount(c *gin.Context) {
+	var service setting.SubAccountCreateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Create(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// UpdateSubAccount 修改子账户
+func UpdateSubAccount(c *gin.Context) {
+	var target setting.SubAccountService
+	if err := c.ShouldBindUri(&target); err != nil {
+		c.JSON(200, ErrorResponse(err))
+		return
+	}
+
+	var service setting.SubAccountUpdateService
+	if err := c.ShouldBindJSON(&service); err == nil {
+		res := service.Update(c, CurrentUser(c), target.ID)
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
+
+// DeleteSubAccount 删除子账户
+func DeleteSubAccount(c *gin.Context) {
+	var service setting.SubAccountService
+	if err := c.ShouldBindUri(&service); err == nil {
+		res := service.Delete(c, CurrentUser(c))
+		c.JSON(200, res)
+	} else {
+		c.JSON(200, ErrorResponse(err))
+	}
+}
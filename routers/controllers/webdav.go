@@ -1,13 +1,14 @@
 package controllers
 
 import (
+	"net/http"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/pkg/webdav"
 	"github.com/cloudreve/Cloudreve/v3/service/setting"
 	"github.com/gin-gonic/gin"
-	"sync"
 )
 
 var handler *webdav.Handler
@@ -15,30 +16,34 @@ var handler *webdav.Handler
 func init() {
 	handler = &webdav.Handler{
 		Prefix:     "/dav",
-		LockSystem: make(map[uint]webdav.LockSystem),
-		Mutex:      &sync.Mutex{},
+		LockSystem: webdav.NewDBLS,
 	}
 }
 
 // ServeWebDAV 处理WebDAV相关请求
 func ServeWebDAV(c *gin.Context) {
-	fs, err := filesystem.NewFileSystemFromContext(c)
-	if err != nil {
-		util.Log().Warning("无法为WebDAV初始化文件系统，%s", err)
-		return
-	}
-
+	var (
+		fs  *filesystem.FileSystem
+		err error
+	)
 	if webdavCtx, ok := c.Get("webdav"); ok {
-		application := webdavCtx.(*model.Webdav)
+		fs, err = filesystem.NewFileSystemForWebDAV(CurrentUser(c), webdavCtx.(*model.Webdav))
+	} else {
+		fs, err = filesystem.NewFileSystemFromContext(c)
+	}
 
-		// 重定根目录
-		if application.Root != "/" {
-			if exist, root := fs.IsPathExist(application.Root); exist {
-				root.Position = ""
-				root.Name = "/"
-				fs.Root = root
-			}
-		}
+	switch err {
+	case nil:
+	case filesystem.ErrSharePermissionDenied:
+		c.Status(http.StatusForbidden)
+		return
+	case filesystem.ErrPathNotExist:
+		// 根目录不存在时不回退至上级目录
+		c.Status(http.StatusNotFound)
+		return
+	default:
+		util.Log().Warning("无法为WebDAV初始化文件系统，%s", err)
+		return
 	}
 
 	handler.ServeHTTP(c.Writer, c.Request, fs)
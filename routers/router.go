@@ -26,7 +26,9 @@ func InitRouter() *gin.Engine {
 
 // InitSlaveRouter 初始化从机模式路由
 func InitSlaveRouter() *gin.Engine {
-	r := gin.Default()
+	r := newEngine()
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
 	// 跨域相关
 	InitCORS(r)
 	v3 := r.Group("/api/v3/slave")
@@ -67,6 +69,8 @@ func InitSlaveRouter() *gin.Engine {
 		v3.POST("delete", controllers.SlaveDelete)
 		// 列出文件
 		v3.POST("list", controllers.SlaveList)
+		// 查询存储目录的磁盘容量
+		v3.POST("capacity", controllers.SlaveCapacity)
 
 		// 离线下载
 		aria2 := v3.Group("aria2")
@@ -93,6 +97,15 @@ func InitSlaveRouter() *gin.Engine {
 	return r
 }
 
+// newEngine 创建 gin 实例，仅信任配置中的反向代理转发的客户端 IP
+func newEngine() *gin.Engine {
+	r := gin.Default()
+	if err := r.SetTrustedProxies(conf.SystemConfig.TrustedProxies); err != nil {
+		util.Log().Panic("信任的反向代理地址无效, %s", err)
+	}
+	return r
+}
+
 // InitCORS 初始化跨域配置
 func InitCORS(router *gin.Engine) {
 	if conf.CORSConfig.AllowOrigins[0] != "UNSET" {
@@ -114,7 +127,12 @@ func InitCORS(router *gin.Engine) {
 
 // InitMasterRouter 初始化主机模式路由
 func InitMasterRouter() *gin.Engine {
-	r := gin.Default()
+	r := newEngine()
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
+	// 跨域及安全响应头，按站点设置在运行时生效
+	r.Use(middleware.CORS())
+	r.Use(middleware.SecurityHeaders())
 
 	/*
 		静态资源
@@ -122,6 +140,9 @@ func InitMasterRouter() *gin.Engine {
 	r.Use(gzip.Gzip(gzip.DefaultCompression, gzip.WithExcludedPaths([]string{"/api/"})))
 	r.Use(middleware.FrontendFileHandler())
 	r.GET("manifest.json", controllers.Manifest)
+	// 健康检查
+	r.GET("healthz", controllers.Healthz)
+	r.HEAD("healthz", controllers.Healthz)
 
 	v3 := r.Group("/api/v3")
 
@@ -129,14 +150,14 @@ func InitMasterRouter() *gin.Engine {
 		中间件
 	*/
 	v3.Use(middleware.Session(conf.SystemConfig.SessionSecret))
-	// 跨域相关
-	InitCORS(r)
 	// 测试模式加入Mock助手中间件
 	if gin.Mode() == gin.TestMode {
 		v3.Use(middleware.MockHelper())
 	}
 	// 用户会话
 	v3.Use(middleware.CurrentUser())
+	// 接口请求频率限制
+	v3.Use(middleware.RateLimit(middleware.RateLimitAPI))
 
 	// 禁止缓存
 	v3.Use(middleware.CacheControl())
@@ -154,25 +175,45 @@ func InitMasterRouter() *gin.Engine {
 			site.GET("captcha", controllers.Captcha)
 			// 站点全局配置
 			site.GET("config", middleware.CSRFInit(), controllers.SiteConfig)
+			// 当前用户可见的公告
+			site.GET("announcement", controllers.SiteAnnouncements)
 		}
 
 		// 用户相关路由
 		user := v3.Group("user")
 		{
 			// 用户登录
-			user.POST("session", middleware.CaptchaRequired("login_captcha"), controllers.UserLogin)
+			user.POST("session",
+				middleware.RateLimit(middleware.RateLimitAuth),
+				middleware.CaptchaRequired("login_captcha"),
+				controllers.UserLogin,
+			)
 			// 用户注册
 			user.POST("",
+				middleware.RateLimit(middleware.RateLimitAuth),
 				middleware.IsFunctionEnabled("register_enabled"),
 				middleware.CaptchaRequired("reg_captcha"),
 				controllers.UserRegister,
 			)
+			// 发送注册短信验证码
+			user.POST("phone/code",
+				middleware.RateLimit(middleware.RateLimitAuth),
+				middleware.IsFunctionEnabled("register_enabled"),
+				middleware.CaptchaRequired("reg_captcha"),
+				controllers.UserSendPhoneCode,
+			)
 			// 用二步验证户登录
-			user.POST("2fa", controllers.User2FALogin)
+			user.POST("2fa", middleware.RateLimit(middleware.RateLimitAuth), controllers.User2FALogin)
+			// 使用邮件验证码确认异常登录
+			user.POST("login/confirm", middleware.RateLimit(middleware.RateLimitAuth), controllers.UserConfirmLogin)
 			// 发送密码重设邮件
-			user.POST("reset", middleware.CaptchaRequired("forget_captcha"), controllers.UserSendReset)
+			user.POST("reset",
+				middleware.RateLimit(middleware.RateLimitAuth),
+				middleware.CaptchaRequired("forget_captcha"),
+				controllers.UserSendReset,
+			)
 			// 通过邮件里的链接重设密码
-			user.PATCH("reset", controllers.UserReset)
+			user.PATCH("reset", middleware.RateLimit(middleware.RateLimitAuth), controllers.UserReset)
 			// 邮件激活
 			user.GET("activate/:id",
 				middleware.SignRequired(auth.General),
@@ -186,9 +227,62 @@ func InitMasterRouter() *gin.Engine {
 			)
 			// WebAuthn登陆
 			user.POST("authn/finish/:username",
+				middleware.RateLimit(middleware.RateLimitAuth),
 				middleware.IsFunctionEnabled("authn_enabled"),
 				controllers.FinishLoginAuthn,
 			)
+			// 通行密钥登录初始化
+			user.GET("passkey",
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.StartPasskeyLogin,
+			)
+			// 通行密钥登录
+			user.POST("passkey",
+				middleware.RateLimit(middleware.RateLimitAuth),
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.FinishPasskeyLogin,
+			)
+			// 使用验证器进行二步验证初始化
+			user.GET("2fa/authn",
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.StartAuthn2FA,
+			)
+			// 使用验证器完成二步验证
+			user.POST("2fa/authn",
+				middleware.RateLimit(middleware.RateLimitAuth),
+				middleware.IsFunctionEnabled("authn_enabled"),
+				controllers.FinishAuthn2FA,
+			)
+			// 获取 OIDC 单点登录地址
+			user.GET("oidc",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.OIDCLogin,
+			)
+			// OIDC 单点登录回调
+			user.GET("oidc/callback",
+				middleware.IsFunctionEnabled("oidc_enabled"),
+				controllers.OIDCCallback,
+			)
+			// SAML 服务提供方元数据
+			user.GET("saml/metadata",
+				middleware.IsFunctionEnabled("saml_enabled"),
+				controllers.SAMLMetadata,
+			)
+			// 获取 SAML 单点登录地址
+			user.GET("saml",
+				middleware.IsFunctionEnabled("saml_enabled"),
+				controllers.SAMLLogin,
+			)
+			// SAML 断言消费服务
+			user.POST("saml/acs",
+				middleware.IsFunctionEnabled("saml_enabled"),
+				controllers.SAMLACS,
+			)
+			// SAML 单点登出
+			user.GET("saml/slo",
+				middleware.IsFunctionEnabled("saml_enabled"),
+				controllers.SAMLSLO,
+			)
 			// 获取用户主页展示用分享
 			user.GET("profile/:id",
 				middleware.HashID(hashid.UserID),
@@ -201,6 +295,15 @@ func InitMasterRouter() *gin.Engine {
 			)
 		}
 
+		// OAuth2 授权服务
+		oauth := v3.Group("oauth", middleware.IsFunctionEnabled("oauth_enabled"))
+		{
+			// 令牌端点
+			oauth.POST("token", controllers.OAuthToken)
+			// 令牌撤销端点
+			oauth.POST("revoke", controllers.OAuthRevoke)
+		}
+
 		// 需要携带签名验证的
 		sign := v3.Group("")
 		sign.Use(middleware.SignRequired(auth.General))
@@ -218,6 +321,9 @@ func InitMasterRouter() *gin.Engine {
 			}
 		}
 
+		// 一次性下载链接
+		v3.GET("file/once/:token", controllers.OneTimeDownload)
+
 		// 从机的 RPC 通信
 		slave := v3.Group("slave")
 		slave.Use(middleware.SlaveRPCSignRequired(cluster.Default))
@@ -296,6 +402,11 @@ func InitMasterRouter() *gin.Engine {
 				middleware.UseUploadSession("s3"),
 				controllers.S3Callback,
 			)
+			// 邮件服务商投递通知
+			callback.POST(
+				"mail/:provider/:token",
+				controllers.MailWebhook,
+			)
 		}
 
 		// 分享相关
@@ -305,20 +416,38 @@ func InitMasterRouter() *gin.Engine {
 			share.GET("info/:id", controllers.GetShare)
 			// 创建文件下载会话
 			share.PUT("download/:id",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
 				middleware.CheckShareUnlocked(),
+				middleware.ShareCanDownload(),
 				middleware.BeforeShareDownload(),
 				controllers.GetShareDownload,
 			)
+			// 导出 Metalink 或种子文件
+			share.GET("metalink/:id",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
+				middleware.CheckShareUnlocked(),
+				middleware.ShareCanDownload(),
+				middleware.BeforeShareDownload(),
+				controllers.ExportShareMetalink,
+			)
 			// 预览分享文件
 			share.GET("preview/:id",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
 				middleware.CSRFCheck(),
-				middleware.CheckShareUnlocked(),
+				middleware.CheckSharePreviewUnlocked(),
 				middleware.ShareCanPreview(),
 				middleware.BeforeShareDownload(),
 				controllers.PreviewShare,
 			)
+			// 签发短期预览令牌，供页面内媒体预览使用
+			share.PUT("token/:id",
+				middleware.CheckShareUnlocked(),
+				middleware.ShareCanPreview(),
+				controllers.CreateSharePreviewToken,
+			)
 			// 取得Office文档预览地址
 			share.GET("doc/:id",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
 				middleware.CheckShareUnlocked(),
 				middleware.ShareCanPreview(),
 				middleware.BeforeShareDownload(),
@@ -326,7 +455,9 @@ func InitMasterRouter() *gin.Engine {
 			)
 			// 获取文本文件内容
 			share.GET("content/:id",
-				middleware.CheckShareUnlocked(),
+				middleware.RateLimit(middleware.RateLimitShareDownload),
+				middleware.CheckSharePreviewUnlocked(),
+				middleware.ShareCanPreview(),
 				middleware.BeforeShareDownload(),
 				controllers.PreviewShareText,
 			)
@@ -342,31 +473,109 @@ func InitMasterRouter() *gin.Engine {
 			)
 			// 归档打包下载
 			share.POST("archive/:id",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
 				middleware.CheckShareUnlocked(),
+				middleware.ShareCanDownload(),
 				middleware.BeforeShareDownload(),
 				controllers.ArchiveShare,
 			)
+			// 访客上传文件至分享目录
+			share.PUT("upload/:id",
+				middleware.Maintenance(),
+				middleware.RateLimit(middleware.RateLimitUpload),
+				middleware.CheckShareUnlocked(),
+				controllers.ShareUpload,
+			)
+			// 删除分享目录下的对象
+			share.DELETE("object/:id",
+				middleware.CheckShareUnlocked(),
+				controllers.DeleteSharedObject,
+			)
+			// 重命名分享目录下的对象
+			share.PATCH("object/:id",
+				middleware.CheckShareUnlocked(),
+				controllers.RenameSharedObject,
+			)
+			// 在分享目录下创建目录
+			share.PUT("directory/:id",
+				middleware.CheckShareUnlocked(),
+				controllers.CreateSharedDirectory,
+			)
+			// 获取分享链接二维码
+			share.GET("qrcode/:id",
+				middleware.CheckShareUnlocked(),
+				controllers.ShareQRCode,
+			)
 			// 获取README文本文件内容
 			share.GET("readme/:id",
 				middleware.CheckShareUnlocked(),
 				controllers.PreviewShareReadme,
 			)
+			// 获取分享描述及自述文件
+			share.GET("metadata/:id",
+				middleware.CheckShareUnlocked(),
+				controllers.GetShareMetadata,
+			)
 			// 获取缩略图
 			share.GET("thumb/:id/:file",
-				middleware.CheckShareUnlocked(),
+				middleware.CheckSharePreviewUnlocked(),
 				middleware.ShareCanPreview(),
 				controllers.ShareThumb,
 			)
+			// 分享链接预览卡片的缩略图
+			share.GET("card/:id/thumb",
+				middleware.CheckSharePreviewUnlocked(),
+				middleware.ShareCanPreview(),
+				controllers.ShareCardThumb,
+			)
+			// 分享链接预览卡片的视频流
+			share.GET("card/:id/stream",
+				middleware.RateLimit(middleware.RateLimitShareDownload),
+				middleware.CheckSharePreviewUnlocked(),
+				middleware.ShareCanPreview(),
+				middleware.BeforeShareDownload(),
+				controllers.ShareCardStream,
+			)
+			// 分享链接预览卡片的内嵌播放页
+			share.GET("card/:id/player",
+				middleware.CheckSharePreviewUnlocked(),
+				middleware.ShareCanPreview(),
+				controllers.ShareCardPlayer,
+			)
 			// 搜索公共分享
 			v3.Group("share").GET("search", controllers.SearchShare)
+			// 分享链接的 oEmbed 信息
+			v3.Group("share").GET("oembed", controllers.ShareOEmbed)
+		}
+
+		// 联邦分享
+		federation := v3.Group("federation")
+		{
+			// 接收其他实例提供的分享
+			federation.POST("shares", controllers.ReceiveFederatedShare)
+
+			// 供其他实例访问的联邦分享
+			federated := federation.Group("shares/:token", middleware.FederatedShareAvailable())
+			{
+				// 确认联邦分享
+				federated.GET("", controllers.GetFederatedShare)
+				// 列出分享目录下的对象
+				federated.GET("list/*path", controllers.ListSharedFolder)
+				// 创建文件下载会话
+				federated.PUT("download",
+					middleware.ShareCanDownload(),
+					middleware.BeforeShareDownload(),
+					controllers.GetShareDownload,
+				)
+			}
 		}
 
 		// 需要登录保护的
 		auth := v3.Group("")
-		auth.Use(middleware.AuthRequired())
+		auth.Use(middleware.AuthRequired(), middleware.TwoFactorEnforced(), middleware.PasswordRotation())
 		{
 			// 管理
-			admin := auth.Group("admin", middleware.IsAdmin())
+			admin := auth.Group("admin", middleware.IsAdmin(), middleware.AuditAdminRequest())
 			{
 				// 获取站点概况
 				admin.GET("summary", controllers.AdminSummary)
@@ -383,6 +592,18 @@ func InitMasterRouter() *gin.Engine {
 				// 重新加载子服务
 				admin.POST("mailTest", controllers.AdminSendTestMail)
 
+				statistics := admin.Group("statistics")
+				{
+					// 每日流量及活跃用户数
+					statistics.GET("traffic", controllers.AdminTrafficStatistics)
+					// 存储用量汇总
+					statistics.GET("storage", controllers.AdminStorageStatistics)
+					// 立即汇总统计
+					statistics.POST("refresh", controllers.AdminRefreshStatistics)
+					// 缩略图任务池状态
+					statistics.GET("thumb", controllers.AdminThumbStatistics)
+				}
+
 				// 离线下载相关
 				aria2 := admin.Group("aria2")
 				{
@@ -438,6 +659,20 @@ func InitMasterRouter() *gin.Engine {
 					user.POST("delete", controllers.AdminDeleteUser)
 					// 封禁/解封用户
 					user.PATCH("ban/:id", controllers.AdminBanUser)
+					// 模拟用户
+					user.POST("impersonate/:id", controllers.AdminImpersonateUser)
+					// 临时调整用户组
+					user.POST("group", controllers.AdminUpgradeUserGroup)
+					// 提前结束临时用户组
+					user.DELETE("group/:id", controllers.AdminRevertUserGroup)
+					// 创建访客账户
+					user.POST("guest", controllers.AdminAddGuest)
+					// 批量导入用户
+					user.POST("import", controllers.AdminImportUsers)
+					// 合并账户
+					user.POST("merge", controllers.AdminMergeUser)
+					// 访客账户续期
+					user.PATCH("guest", controllers.AdminRenewGuest)
 				}
 
 				file := admin.Group("file")
@@ -459,8 +694,66 @@ func InitMasterRouter() *gin.Engine {
 					share.POST("list", controllers.AdminListShare)
 					// 删除
 					share.POST("delete", controllers.AdminDeleteShare)
+					// 列出密码锁定记录
+					share.POST("lock_logs", controllers.AdminListShareLockLog)
+					// 列出目录子树下的分享
+					share.POST("folder", controllers.AdminListFolderShare)
+					// 撤销目录子树下的分享
+					share.POST("folder/revoke", controllers.AdminRevokeFolderShare)
+					// 按所有者和文件名搜索分享
+					share.POST("search", controllers.AdminSearchShare)
+					// 批量撤销符合条件的分享
+					share.POST("revoke", controllers.AdminRevokeShare)
+				}
+
+				moderation := admin.Group("moderation")
+				{
+					// 列出审核记录
+					moderation.POST("list", controllers.AdminListModeration)
+					// 人工复核
+					moderation.POST("resolve", controllers.AdminResolveModeration)
 				}
 
+				blocklist := admin.Group("blocklist")
+				{
+					// 列出禁止的文件摘要
+					blocklist.POST("list", controllers.AdminListBlockedHash)
+					// 添加
+					blocklist.POST("", controllers.AdminAddBlockedHash)
+					// 从外部列表导入
+					blocklist.POST("import", controllers.AdminImportBlockedHash)
+					// 删除
+					blocklist.POST("delete", controllers.AdminDeleteBlockedHash)
+				}
+
+				announcement := admin.Group("announcement")
+				{
+					// 列出公告
+					announcement.POST("list", controllers.AdminListAnnouncement)
+					// 创建/保存公告
+					announcement.POST("", controllers.AdminAddAnnouncement)
+					// 发布公告
+					announcement.POST(":id/publish", controllers.AdminPublishAnnouncement)
+					// 删除公告
+					announcement.DELETE(":id", controllers.AdminDeleteAnnouncement)
+				}
+
+				// 获取维护模式状态
+				admin.GET("maintenance", controllers.AdminGetMaintenance)
+				// 开启或关闭维护模式
+				admin.PUT("maintenance", controllers.AdminUpdateMaintenance)
+				// 导出备份
+				admin.GET("backup", controllers.AdminExportBackup)
+				// 从备份恢复
+				admin.POST("restore", controllers.AdminRestoreBackup)
+
+				// 列出审计记录
+				admin.POST("audit/list", controllers.AdminListAuditLog)
+				// 按条件搜索审计记录
+				admin.POST("audit/search", controllers.AdminSearchAuditLog)
+				// 导出审计记录
+				admin.GET("audit/export", controllers.AdminExportAuditLog)
+
 				download := admin.Group("download")
 				{
 					// 列出任务
@@ -476,7 +769,37 @@ func InitMasterRouter() *gin.Engine {
 					// 删除
 					task.POST("delete", controllers.AdminDeleteTask)
 					// 新建文件导入任务
-					task.POST("import", controllers.AdminCreateImportTask)
+					task.POST("import", middleware.Idempotent(), controllers.AdminCreateImportTask)
+					// 新建存储一致性检查任务
+					task.POST("audit", middleware.Idempotent(), controllers.AdminCreateStorageAuditTask)
+					// 下载任务逐项处理结果报告
+					task.GET("report/:id", controllers.AdminTaskReport)
+					// 获取任务池状态
+					task.GET("pool", controllers.AdminGetTaskPool)
+					// 调整任务池 Worker 数量
+					task.PATCH("pool", controllers.AdminResizeTaskPool)
+				}
+
+				oauth := admin.Group("oauth")
+				{
+					// 列出第三方应用
+					oauth.POST("list", controllers.AdminListOAuthClients)
+					// 创建/保存第三方应用
+					oauth.POST("", controllers.AdminAddOAuthClient)
+					// 重置客户端密钥
+					oauth.PATCH(":id/secret", controllers.AdminResetOAuthClientSecret)
+					// 删除第三方应用
+					oauth.DELETE(":id", controllers.AdminDeleteOAuthClient)
+				}
+
+				invitation := admin.Group("invitation")
+				{
+					// 列出注册邀请码
+					invitation.POST("list", controllers.AdminListInvitations)
+					// 批量生成注册邀请码
+					invitation.POST("", controllers.AdminAddInvitations)
+					// 删除注册邀请码
+					invitation.POST("delete", controllers.AdminDeleteInvitations)
 				}
 
 				node := admin.Group("node")
@@ -497,6 +820,15 @@ func InitMasterRouter() *gin.Engine {
 
 			}
 
+			// OAuth2 授权确认
+			authorize := auth.Group("oauth/authorize", middleware.IsFunctionEnabled("oauth_enabled"))
+			{
+				// 获取授权确认页面信息
+				authorize.GET("", controllers.OAuthAuthorizeInfo)
+				// 确认或拒绝授权
+				authorize.POST("", controllers.OAuthAuthorize)
+			}
+
 			// 用户
 			user := auth.Group("user")
 			{
@@ -506,6 +838,8 @@ func InitMasterRouter() *gin.Engine {
 				user.GET("storage", controllers.UserStorage)
 				// 退出登录
 				user.DELETE("session", controllers.UserSignOut)
+				// 结束模拟用户
+				user.DELETE("impersonation", controllers.AdminStopImpersonation)
 
 				// WebAuthn 注册相关
 				authn := user.Group("authn",
@@ -513,6 +847,12 @@ func InitMasterRouter() *gin.Engine {
 				{
 					authn.PUT("", controllers.StartRegAuthn)
 					authn.PUT("finish", controllers.FinishRegAuthn)
+					// 列出已注册的验证器
+					authn.GET("", controllers.ListAuthn)
+					// 重命名验证器
+					authn.PATCH("", controllers.RenameAuthn)
+					// 撤销验证器
+					authn.DELETE("", controllers.RevokeAuthn)
 				}
 
 				// 用户设置
@@ -520,6 +860,8 @@ func InitMasterRouter() *gin.Engine {
 				{
 					// 任务队列
 					setting.GET("tasks", controllers.UserTasks)
+					// 下载任务处理结果报告
+					setting.GET("tasks/:id/report", middleware.HashID(hashid.TaskID), controllers.UserTaskReport)
 					// 获取当前用户设定
 					setting.GET("", controllers.UserSetting)
 					// 从文件上传头像
@@ -528,8 +870,79 @@ func InitMasterRouter() *gin.Engine {
 					setting.PUT("avatar", controllers.UseGravatar)
 					// 更改用户设定
 					setting.PATCH(":option", controllers.UpdateOption)
+					// 验证新邮箱并完成更改
+					setting.POST("email", controllers.UserConfirmEmailChange)
+					// 验证并绑定手机号码
+					setting.POST("phone", controllers.UserConfirmPhoneBind)
 					// 获得二步验证初始化信息
 					setting.GET("2fa", controllers.UserInit2FA)
+					// 重新生成二步验证恢复码
+					setting.POST("2fa/recovery", controllers.UserRegenerateRecoveryCodes)
+					// 申请注销账户
+					setting.POST("deletion", controllers.UserScheduleDeletion)
+					// 撤销注销申请
+					setting.DELETE("deletion", controllers.UserCancelDeletion)
+					// 导出个人数据
+					setting.POST("takeout", middleware.Maintenance(), controllers.UserCreateTakeout)
+					// 下载导出的个人数据
+					setting.GET("takeout/:id", middleware.HashID(hashid.TaskID), controllers.UserTakeoutDownload)
+				}
+
+				// 站内信
+				notification := user.Group("notification")
+				{
+					// 列出站内信
+					notification.GET("", controllers.UserNotifications)
+					// 标为已读
+					notification.PATCH("", controllers.UserReadNotifications)
+					// 删除站内信
+					notification.DELETE("", controllers.UserDeleteNotifications)
+				}
+
+				// 登录设备
+				devices := user.Group("devices")
+				{
+					// 列出登录会话及应用凭证
+					devices.GET("", controllers.UserDevices)
+					// 注销其他全部登录会话
+					devices.DELETE("", controllers.UserSignOutOthers)
+					// 注销登录会话或撤销应用凭证
+					devices.DELETE(":type/:id", controllers.UserRevokeDevice)
+				}
+
+				// 个人访问令牌
+				token := user.Group("token")
+				{
+					// 列出令牌
+					token.GET("", controllers.ListAPITokens)
+					// 创建令牌
+					token.POST("", controllers.CreateAPIToken)
+					// 撤销令牌
+					token.DELETE(":id", controllers.DeleteAPIToken)
+				}
+
+				// 子账户
+				subAccount := user.Group("subaccount")
+				{
+					// 列出子账户
+					subAccount.GET("", controllers.ListSubAccounts)
+					// 创建子账户
+					subAccount.POST("", controllers.CreateSubAccount)
+					// 修改子账户
+					subAccount.PATCH(":id", controllers.UpdateSubAccount)
+					// 删除子账户
+					subAccount.DELETE(":id", controllers.DeleteSubAccount)
+				}
+
+				// 注册邀请码
+				invitation := user.Group("invitation")
+				{
+					// 列出邀请码
+					invitation.GET("", controllers.ListInvitations)
+					// 创建邀请码
+					invitation.POST("", controllers.CreateInvitation)
+					// 删除邀请码
+					invitation.DELETE(":id", controllers.DeleteInvitation)
 				}
 			}
 
@@ -537,23 +950,25 @@ func InitMasterRouter() *gin.Engine {
 			file := auth.Group("file", middleware.HashID(hashid.FileID))
 			{
 				// 上传
-				upload := file.Group("upload")
+				upload := file.Group("upload", middleware.RateLimit(middleware.RateLimitUpload))
 				{
 					// 文件上传
 					upload.POST(":sessionId/:index", controllers.FileUpload)
 					// 创建上传会话
-					upload.PUT("", controllers.GetUploadSession)
+					upload.PUT("", middleware.Maintenance(), controllers.GetUploadSession)
 					// 删除给定上传会话
 					upload.DELETE(":sessionId", controllers.DeleteUploadSession)
 					// 删除全部上传会话
 					upload.DELETE("", controllers.DeleteAllUploadSession)
 				}
 				// 更新文件
-				file.PUT("update/:id", controllers.PutContent)
+				file.PUT("update/:id", middleware.Maintenance(), controllers.PutContent)
 				// 创建空白文件
-				file.POST("create", controllers.CreateFile)
+				file.POST("create", middleware.Maintenance(), controllers.CreateFile)
 				// 创建文件下载会话
 				file.PUT("download/:id", controllers.CreateDownloadSession)
+				// 创建一次性下载链接
+				file.POST("once/:id", controllers.CreateOneTimeLink)
 				// 预览文件
 				file.GET("preview/:id", controllers.Preview)
 				// 获取文本文件内容
@@ -567,9 +982,11 @@ func InitMasterRouter() *gin.Engine {
 				// 打包要下载的文件
 				file.POST("archive", controllers.Archive)
 				// 创建文件压缩任务
-				file.POST("compress", controllers.Compress)
+				file.POST("compress", middleware.Maintenance(), middleware.Idempotent(), controllers.Compress)
 				// 创建文件解压缩任务
-				file.POST("decompress", controllers.Decompress)
+				file.POST("decompress", middleware.Maintenance(), middleware.Idempotent(), controllers.Decompress)
+				// 列出压缩包内的条目
+				file.GET("decompress/list", controllers.ListArchive)
 				// 创建文件解压缩任务
 				file.GET("search/:type/:keywords", controllers.SearchFile)
 			}
@@ -578,9 +995,9 @@ func InitMasterRouter() *gin.Engine {
 			aria2 := auth.Group("aria2")
 			{
 				// 创建URL下载任务
-				aria2.POST("url", controllers.AddAria2URL)
+				aria2.POST("url", middleware.Maintenance(), middleware.Idempotent(), controllers.AddAria2URL)
 				// 创建种子下载任务
-				aria2.POST("torrent/:id", middleware.HashID(hashid.FileID), controllers.AddAria2Torrent)
+				aria2.POST("torrent/:id", middleware.HashID(hashid.FileID), middleware.Maintenance(), middleware.Idempotent(), controllers.AddAria2Torrent)
 				// 重新选择要下载的文件
 				aria2.PUT("select/:gid", controllers.SelectAria2File)
 				// 取消或删除下载任务
@@ -591,6 +1008,20 @@ func InitMasterRouter() *gin.Engine {
 				aria2.GET("finished", controllers.ListFinished)
 			}
 
+			// GraphQL 查询，一次请求获取目录、分享、任务等信息
+			auth.POST("graphql", controllers.GraphQL)
+
+			// 桌面同步客户端
+			sync := auth.Group("sync")
+			{
+				// 列出游标之后的文件变更
+				sync.GET("changes", controllers.ListFileChanges)
+				// 按版本条件下载文件
+				sync.GET("file/:id", middleware.HashID(hashid.FileID), controllers.SyncDownload)
+				// 按版本条件上传文件
+				sync.PUT("file", middleware.Maintenance(), controllers.SyncUpload)
+			}
+
 			// 目录
 			directory := auth.Group("directory")
 			{
@@ -632,6 +1063,47 @@ func InitMasterRouter() *gin.Engine {
 				share.DELETE(":id",
 					controllers.DeleteShare,
 				)
+				// 分享访问统计
+				share.GET("analytics/:id",
+					controllers.GetShareAnalytics,
+				)
+				// 列出分享给我的内部分享
+				share.GET("received", controllers.ListReceivedShare)
+				// 通过邮件发送分享链接
+				share.POST("mail/:id",
+					middleware.ShareAvailable(),
+					middleware.ShareCanReshare(),
+					controllers.MailShare,
+				)
+				// 分享邮件发送记录
+				share.GET("mail/:id",
+					controllers.ListShareMail,
+				)
+				// 列出目录子树下的分享
+				share.GET("folder/:id", controllers.ListFolderShare)
+				// 撤销目录子树下的分享
+				share.POST("folder/:id/revoke", controllers.RevokeFolderShare)
+				// 将分享提供给其他实例的用户
+				share.POST("federate/:id",
+					middleware.ShareAvailable(),
+					middleware.ShareOwner(),
+					controllers.FederateShare,
+				)
+			}
+
+			// 收到的远程分享
+			remote := auth.Group("federation/remote")
+			{
+				// 列出收到的远程分享
+				remote.GET("", controllers.ListRemoteShare)
+				// 接受远程分享
+				remote.PATCH(":id", controllers.AcceptRemoteShare)
+				// 拒绝或移除远程分享
+				remote.DELETE(":id", controllers.DeleteRemoteShare)
+				// 列出远程分享目录下的对象
+				remote.GET(":id/list/*path", controllers.ListRemoteSharedFolder)
+				// 获取远程分享文件的下载地址
+				remote.PUT(":id/download", controllers.GetRemoteShareDownload)
 			}
 
 			// 用户标签
@@ -656,6 +1128,28 @@ func InitMasterRouter() *gin.Engine {
 				webdav.DELETE("accounts/:id", controllers.DeleteWebDAVAccounts)
 			}
 
+			// S3 访问密钥管理
+			s3 := auth.Group("s3")
+			{
+				// 列出访问密钥
+				s3.GET("keys", controllers.ListS3Keys)
+				// 创建访问密钥
+				s3.POST("keys", controllers.CreateS3Key)
+				// 删除访问密钥
+				s3.DELETE("keys/:id", controllers.DeleteS3Key)
+			}
+
+			// SFTP 公钥管理
+			sftp := auth.Group("sftp")
+			{
+				// 列出公钥
+				sftp.GET("keys", controllers.ListSSHKeys)
+				// 添加公钥
+				sftp.POST("keys", controllers.CreateSSHKey)
+				// 删除公钥
+				sftp.DELETE("keys/:id", controllers.DeleteSSHKey)
+			}
+
 		}
 
 	}
@@ -665,10 +1159,37 @@ func InitMasterRouter() *gin.Engine {
 	return r
 }
 
+// InitS3Router 初始化 S3 兼容接口路由，使用单独的监听地址，以便客户端以路径形式访问存储桶
+func InitS3Router() *gin.Engine {
+	r := newEngine()
+	// 对象键中可能包含连续或结尾的 /，不能重定向
+	r.RedirectTrailingSlash = false
+	r.RedirectFixedPath = false
+
+	r.Use(middleware.RequestID())
+	r.Use(middleware.Tracing())
+	r.Use(middleware.S3Auth())
+	r.NoRoute(controllers.S3NotImplemented)
+
+	r.GET("/", controllers.S3ListBuckets)
+	r.HEAD("/:bucket", controllers.S3HeadBucket)
+	r.GET("/:bucket", controllers.S3GetBucket)
+	r.PUT("/:bucket", controllers.S3CreateBucket)
+	r.POST("/:bucket", controllers.S3PostBucket)
+	r.HEAD("/:bucket/*key", controllers.S3HeadObject)
+	r.GET("/:bucket/*key", controllers.S3GetObject)
+	r.PUT("/:bucket/*key", middleware.S3Maintenance(), controllers.S3PutObject)
+	r.POST("/:bucket/*key", middleware.S3Maintenance(), controllers.S3PostObject)
+	r.DELETE("/:bucket/*key", controllers.S3DeleteObject)
+	return r
+}
+
 // initWebDAV 初始化WebDAV相关路由
 func initWebDAV(group *gin.RouterGroup) {
 	{
 		group.Use(middleware.WebDAVAuth())
+		// 维护模式下拒绝上传新文件
+		group.Use(middleware.Maintenance("PUT"))
 
 		group.Any("/*path", controllers.ServeWebDAV)
 		group.Any("", controllers.ServeWebDAV)
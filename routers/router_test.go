@@ -4,9 +4,13 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
+	"github.com/cloudreve/Cloudreve/v3/middleware"
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/gorm"
 	"github.com/stretchr/testify/assert"
 )
@@ -249,3 +253,57 @@ func TestSiteConfigRoute(t *testing.T) {
 		},
 	}).UpdateColumn("name", "siteName")
 }
+
+func TestNewEngine_TrustedProxies(t *testing.T) {
+	asserts := assert.New(t)
+	defer func() { conf.SystemConfig.TrustedProxies = nil }()
+
+	// 仅允许 10.0.0.0/8 访问
+	user := &model.User{}
+	user.Group.OptionsSerialized.AllowedIPs = "10.0.0.0/8"
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		router := newEngine()
+		router.GET("/test", func(c *gin.Context) {
+			c.Set("user", user)
+		}, middleware.AuthRequired(), func(c *gin.Context) {
+			c.String(200, c.ClientIP())
+		})
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "10.0.0.1")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 默认不信任任何代理，伪造的请求头被忽略
+	{
+		conf.SystemConfig.TrustedProxies = nil
+		w := request("203.0.113.1:1234")
+		asserts.Contains(w.Body.String(), strconv.Itoa(serializer.CodeIPNotAllowed))
+		asserts.NotContains(w.Body.String(), "10.0.0.1")
+	}
+
+	// 来自不受信任的地址
+	{
+		conf.SystemConfig.TrustedProxies = []string{"192.168.1.1"}
+		w := request("203.0.113.1:1234")
+		asserts.Contains(w.Body.String(), strconv.Itoa(serializer.CodeIPNotAllowed))
+	}
+
+	// 来自受信任的代理
+	{
+		conf.SystemConfig.TrustedProxies = []string{"192.168.1.0/24"}
+		w := request("192.168.1.1:1234")
+		asserts.Equal("10.0.0.1", w.Body.String())
+	}
+
+	// 地址无效
+	{
+		conf.SystemConfig.TrustedProxies = []string{"invalid"}
+		asserts.Panics(func() {
+			newEngine()
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package routers
+
+import (
+	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/rpc"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	rpcservice "github.com/cloudreve/Cloudreve/v3/service/rpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// InitRPCServer 初始化 gRPC 接口，使用单独的监听地址。用户接口使用个人访问令牌认证，
+// 从机节点接口使用节点通信密钥签名认证
+func InitRPCServer() (*grpc.Server, error) {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(rpc.UnaryRecovery(), rpc.UnaryAuth(cluster.Default)),
+		grpc.ChainStreamInterceptor(rpc.StreamRecovery(), rpc.StreamAuth(cluster.Default)),
+	}
+
+	if conf.RPCConfig.CertPath != "" {
+		creds, err := credentials.NewServerTLSFromFile(conf.RPCConfig.CertPath, conf.RPCConfig.KeyPath)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	server := grpc.NewServer(opts...)
+	rpcv1.RegisterFileServiceServer(server, &rpcservice.FileServer{})
+	rpcv1.RegisterTaskServiceServer(server, &rpcservice.TaskServer{})
+	rpcv1.RegisterNodeServiceServer(server, &rpcservice.NodeServer{})
+	return server, nil
+}
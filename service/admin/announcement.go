@@ -0,0 +1,135 @@
+package admin
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// AnnouncementListService 分页列出公告的服务
+type AnnouncementListService struct {
+	Page     int `json:"page" binding:"min=1"`
+	PageSize int `json:"page_size" binding:"min=1,max=1000"`
+}
+
+// AddAnnouncementService 创建或保存公告的服务
+type AddAnnouncementService struct {
+	ID      uint   `json:"id"`
+	Title   string `json:"title" binding:"required,max=255"`
+	Content string `json:"content" binding:"required"`
+	Level   string `json:"level" binding:"required,eq=info|eq=warning|eq=critical"`
+	// 目标用户组，为空时面向全部用户
+	Groups []uint `json:"groups"`
+	Email  bool   `json:"email"`
+	// 过期时间，Unix 时间戳（秒），为 0 时不过期
+	ExpiresAt int64 `json:"expires_at" binding:"min=0"`
+	// 保存后立即发布
+	Publish bool `json:"publish"`
+}
+
+// AnnouncementService 公告ID服务
+type AnnouncementService struct {
+	ID uint `uri:"id" json:"id" binding:"required"`
+}
+
+// List 列出公告
+func (service *AnnouncementListService) List() serializer.Response {
+	announcements, total := model.ListAnnouncements(service.Page, service.PageSize)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": announcements,
+	}}
+}
+
+// Add 创建或保存公告，已发布的公告修改后不会重新投递
+func (service *AddAnnouncementService) Add(c *gin.Context) serializer.Response {
+	announcement := model.Announcement{AuthorID: operator(c)}
+	if service.ID > 0 {
+		var err error
+		if announcement, err = model.GetAnnouncementByID(service.ID); err != nil {
+			return serializer.Err(serializer.CodeNotFound, "Announcement not found", err)
+		}
+	}
+
+	announcement.Title = service.Title
+	announcement.Content = service.Content
+	announcement.Level = service.Level
+	announcement.GroupList = service.Groups
+	announcement.Email = service.Email
+	announcement.ExpiresAt = nil
+	if service.ExpiresAt > 0 {
+		expires := time.Unix(service.ExpiresAt, 0)
+		announcement.ExpiresAt = &expires
+	}
+
+	if err := announcement.Save(); err != nil {
+		return serializer.DBErr("Failed to save announcement", err)
+	}
+
+	if service.Publish && announcement.PublishedAt == nil {
+		if err := publish(c, &announcement); err != nil {
+			return serializer.DBErr("Failed to publish announcement", err)
+		}
+	}
+
+	return serializer.Response{Data: announcement}
+}
+
+// Publish 发布公告，并向目标用户投递站内信及邮件
+func (service *AnnouncementService) Publish(c *gin.Context) serializer.Response {
+	announcement, err := model.GetAnnouncementByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Announcement not found", err)
+	}
+
+	if announcement.PublishedAt != nil {
+		return serializer.ParamErr("Announcement is already published", nil)
+	}
+
+	if err := publish(c, &announcement); err != nil {
+		return serializer.DBErr("Failed to publish announcement", err)
+	}
+
+	return serializer.Response{Data: announcement}
+}
+
+// Delete 删除公告
+func (service *AnnouncementService) Delete(c *gin.Context) serializer.Response {
+	announcement, err := model.GetAnnouncementByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Announcement not found", err)
+	}
+
+	if err := model.DeleteAnnouncement(announcement.ID); err != nil {
+		return serializer.DBErr("Failed to delete announcement", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditAnnouncement, model.AuditTarget("announcement", announcement.ID),
+		"deleted: "+announcement.Title)
+	return serializer.Response{}
+}
+
+// publish 发布公告并在后台投递，投递的用户较多时耗时较长
+func publish(c *gin.Context, announcement *model.Announcement) error {
+	if err := announcement.Publish(); err != nil {
+		return err
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditAnnouncement, model.AuditTarget("announcement", announcement.ID),
+		"published: "+announcement.Title)
+
+	target := *announcement
+	go func() {
+		delivered, err := notify.Announce(&target)
+		if err != nil {
+			util.Log().Warning("投递公告 [%s] 时中断, %s", target.Title, err)
+		}
+		util.Log().Info("公告 [%s] 已投递给 %d 个用户", target.Title, delivered)
+	}()
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// auditExportMaxRows 单次导出的审计记录数量上限
+const auditExportMaxRows = 100000
+
+// AuditLogFilterService 审计记录筛选条件
+type AuditLogFilterService struct {
+	UserID     uint     `json:"user_id" form:"user_id"`
+	Actions    []string `json:"actions" form:"actions"`
+	Target     string   `json:"target" form:"target" binding:"max=255"`
+	TargetType string   `json:"target_type" form:"target_type" binding:"max=64"`
+	IP         string   `json:"ip" form:"ip" binding:"max=64"`
+	Keywords   string   `json:"keywords" form:"keywords" binding:"max=255"`
+	// 时间范围，Unix 时间戳（秒），为 0 时不限制
+	Start int64 `json:"start" form:"start" binding:"min=0"`
+	End   int64 `json:"end" form:"end" binding:"min=0"`
+}
+
+// AuditLogSearchService 分页搜索审计记录的服务
+type AuditLogSearchService struct {
+	AuditLogFilterService
+	Page     int `json:"page" binding:"min=1"`
+	PageSize int `json:"page_size" binding:"min=1,max=1000"`
+}
+
+// filter 构建数据库查询使用的筛选条件
+func (service *AuditLogFilterService) filter() *model.AuditLogFilter {
+	filter := &model.AuditLogFilter{
+		UserID:     service.UserID,
+		Actions:    service.Actions,
+		Target:     service.Target,
+		TargetType: service.TargetType,
+		IP:         service.IP,
+		Keywords:   service.Keywords,
+	}
+	if service.Start > 0 {
+		filter.Start = time.Unix(service.Start, 0)
+	}
+	if service.End > 0 {
+		filter.End = time.Unix(service.End, 0)
+	}
+	return filter
+}
+
+// Search 按条件分页列出审计记录
+func (service *AuditLogSearchService) Search() serializer.Response {
+	logs, total := model.ListAuditLogs(service.filter(), service.Page, service.PageSize)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": logs,
+	}}
+}
+
+// Export 将符合条件的审计记录以 CSV 格式导出
+func (service *AuditLogFilterService) Export(c *gin.Context) serializer.Response {
+	filter := service.filter()
+	w := csv.NewWriter(c.Writer)
+	started := false
+	exported := 0
+
+	// 读取到第一批记录后再写入响应头，以便在此之前出错时仍可返回错误信息
+	start := func() {
+		if started {
+			return
+		}
+		started = true
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"audit_%s.csv\"", time.Now().Format("20060102150405")))
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+		c.Status(200)
+		w.Write([]string{"id", "time", "user_id", "action", "target", "ip", "impersonator_id", "detail"})
+	}
+
+	err := model.EachAuditLog(filter, 1000, auditExportMaxRows, func(logs []model.AuditLog) error {
+		start()
+		for _, log := range logs {
+			w.Write([]string{
+				strconv.FormatUint(uint64(log.ID), 10),
+				log.CreatedAt.Format(time.RFC3339),
+				strconv.FormatUint(uint64(log.UserID), 10),
+				log.Action,
+				log.Target,
+				log.IP,
+				strconv.FormatUint(uint64(log.ImpersonatorID), 10),
+				log.Detail,
+			})
+		}
+		exported += len(logs)
+		w.Flush()
+		return w.Error()
+	})
+
+	if err != nil {
+		if !started {
+			return serializer.DBErr("Failed to list audit logs", err)
+		}
+		util.Log().Warning("导出审计记录时中断, %s", err)
+	}
+
+	start()
+	w.Flush()
+	model.RecordAudit(c, operator(c), model.AuditLogExport, "", fmt.Sprintf("exported %d records", exported))
+	return serializer.Response{}
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/backup"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// BackupService 导出备份的服务
+type BackupService struct {
+	Thumbnails bool `form:"thumbnails"`
+}
+
+// Export 导出数据库及设置，以 zip 文件直接下载
+func (service *BackupService) Export(c *gin.Context) serializer.Response {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"cloudreve_backup_%s.zip\"", time.Now().Format("20060102150405")))
+	c.Header("Content-Type", "application/zip")
+	manifest, err := backup.Export(c.Writer, backup.Options{Thumbnails: service.Thumbnails})
+	if err != nil {
+		// 响应已开始写入，无法返回错误。清单在最后写入，中断的备份文件无法被恢复
+		util.Log().Warning("导出备份失败，%s", err)
+		return serializer.Response{}
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditBackup, "",
+		fmt.Sprintf("exported %d tables, %d thumbnails", len(manifest.Tables), manifest.Thumbnails))
+	return serializer.Response{}
+}
+
+// Restore 从上传的备份文件恢复，仅允许恢复至尚未使用的新站点，恢复后需重新启动
+func (service *NoParamService) Restore(c *gin.Context) serializer.Response {
+	header, err := c.FormFile("file")
+	if err != nil {
+		return serializer.ParamErr("Backup file is required", err)
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return serializer.Err(serializer.CodeIOFailed, "Failed to read backup file", err)
+	}
+	defer file.Close()
+
+	manifest, err := backup.Restore(file, header.Size, backup.RestoreOptions{})
+	if err != nil {
+		if errors.Is(err, backup.ErrInvalidArchive) || errors.Is(err, backup.ErrIncompatibleVersion) ||
+			errors.Is(err, backup.ErrInstanceNotEmpty) {
+			return serializer.ParamErr(err.Error(), err)
+		}
+		return serializer.DBErr("Failed to restore backup", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditBackup, "",
+		fmt.Sprintf("restored backup of version %s created at %s", manifest.Version, manifest.CreatedAt.Format(time.RFC3339)))
+	return serializer.Response{Data: manifest}
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/request"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// blockedHashListMaxSize 导入外部列表时读取的最大字节数
+const blockedHashListMaxSize = 64 << 20
+
+// BlockedHashListService 分页列出禁止摘要的服务
+type BlockedHashListService struct {
+	Keywords string `json:"keywords" binding:"max=255"`
+	Page     int    `json:"page" binding:"min=1"`
+	PageSize int    `json:"page_size" binding:"min=1,max=1000"`
+}
+
+// BlockedHashAddService 手动添加禁止摘要的服务
+type BlockedHashAddService struct {
+	Hashes []string `json:"hashes" binding:"min=1,max=1000"`
+	Note   string   `json:"note" binding:"max=255"`
+}
+
+// BlockedHashImportService 从外部列表导入禁止摘要的服务，URL 与 Content 二选一
+type BlockedHashImportService struct {
+	URL     string `json:"url" binding:"omitempty,url"`
+	Content string `json:"content"`
+	Note    string `json:"note" binding:"max=255"`
+}
+
+// BlockedHashBatchService 批量删除禁止摘要的服务
+type BlockedHashBatchService struct {
+	ID []uint `json:"id" binding:"min=1"`
+}
+
+// List 列出禁止的摘要
+func (service *BlockedHashListService) List() serializer.Response {
+	hashes, total := model.ListBlockedHashes(service.Keywords, service.Page, service.PageSize)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": hashes,
+	}}
+}
+
+// Add 添加禁止的摘要
+func (service *BlockedHashAddService) Add(c *gin.Context) serializer.Response {
+	hashes := make([]string, 0, len(service.Hashes))
+	for _, raw := range service.Hashes {
+		hash, ok := model.NormalizeContentHash(raw)
+		if !ok {
+			return serializer.ParamErr("Invalid MD5, SHA1 or SHA256 hash: "+raw, nil)
+		}
+		hashes = append(hashes, hash)
+	}
+
+	added, err := model.AddBlockedHashes(hashes, "manual", service.Note)
+	if err != nil {
+		return serializer.DBErr("Failed to add blocked hashes", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditBlockedHash, "", fmt.Sprintf("added %d hashes manually", added))
+	return serializer.Response{Data: added}
+}
+
+// Import 从外部列表导入禁止的摘要，每行取第一列，无法识别的行会被忽略
+func (service *BlockedHashImportService) Import(c *gin.Context) serializer.Response {
+	var (
+		list   io.Reader
+		source = "import"
+	)
+
+	switch {
+	case service.URL != "":
+		resp := request.NewClient().Request("GET", service.URL, nil,
+			request.WithTimeout(time.Duration(60)*time.Second)).CheckHTTPResponse(200)
+		if resp.Err != nil {
+			return serializer.ParamErr("Failed to fetch hash list: "+resp.Err.Error(), resp.Err)
+		}
+		defer resp.Response.Body.Close()
+
+		list = io.LimitReader(resp.Response.Body, blockedHashListMaxSize)
+		source = service.URL
+	case service.Content != "":
+		list = strings.NewReader(service.Content)
+	default:
+		return serializer.ParamErr("Either url or content is required", nil)
+	}
+
+	hashes, err := model.ParseBlockedHashList(list)
+	if err != nil {
+		return serializer.ParamErr("Failed to read hash list: "+err.Error(), err)
+	}
+
+	added, err := model.AddBlockedHashes(hashes, source, service.Note)
+	if err != nil {
+		return serializer.DBErr("Failed to add blocked hashes", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditBlockedHash, "",
+		fmt.Sprintf("imported %d of %d hashes from %s", added, len(hashes), source))
+	return serializer.Response{Data: map[string]int{
+		"parsed": len(hashes),
+		"added":  added,
+	}}
+}
+
+// Delete 删除禁止的摘要
+func (service *BlockedHashBatchService) Delete(c *gin.Context) serializer.Response {
+	if err := model.DeleteBlockedHashes(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete blocked hashes", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditBlockedHash, "", fmt.Sprintf("deleted %d hashes", len(service.ID)))
+	return serializer.Response{}
+}
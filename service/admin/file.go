@@ -100,6 +100,8 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 		userFile[files[i].UserID] = append(userFile[files[i].UserID], files[i])
 	}
 
+	model.RecordObjectDelete(c, operator(c), nil, files)
+
 	// 异步执行删除
 	go func(files map[uint][]model.File) {
 		for uid, file := range files {
@@ -121,7 +123,7 @@ func (service *FileBatchService) Delete(c *gin.Context) serializer.Response {
 			}
 
 			// 执行删除
-			fs.Delete(context.Background(), []uint{}, ids, service.Force)
+			fs.Delete(context.WithValue(context.Background(), fsctx.IgnoreLocksCtx, true), []uint{}, ids, service.Force)
 			fs.Recycle()
 		}
 	}(userFile)
@@ -3,6 +3,8 @@ package admin
 import (
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
 	"strconv"
 )
 
@@ -27,7 +29,7 @@ func (service *GroupService) Get() serializer.Response {
 }
 
 // Delete 删除用户组
-func (service *GroupService) Delete() serializer.Response {
+func (service *GroupService) Delete(c *gin.Context) serializer.Response {
 	// 查找用户组
 	group, err := model.GetGroupByID(service.ID)
 	if err != nil {
@@ -48,13 +50,23 @@ func (service *GroupService) Delete() serializer.Response {
 		return serializer.Err(serializer.CodeGroupUsedByUser, strconv.Itoa(total), nil)
 	}
 
-	model.DB.Delete(&group)
+	if err := model.DB.Delete(&group).Error; err != nil {
+		return serializer.DBErr("Failed to delete group", err)
+	}
 
+	model.RecordAudit(c, operator(c), model.AuditGroupDelete, model.AuditTarget("group", group.ID), group.Name)
 	return serializer.Response{}
 }
 
 // Add 添加用户组
-func (service *AddGroupService) Add() serializer.Response {
+func (service *AddGroupService) Add(c *gin.Context) serializer.Response {
+	// 检查IP限制格式
+	for _, ranges := range []string{service.Group.OptionsSerialized.AllowedIPs, service.Group.OptionsSerialized.DeniedIPs} {
+		if _, err := util.ParseIPRanges(ranges); err != nil {
+			return serializer.ParamErr("Invalid IP range: "+err.Error(), err)
+		}
+	}
+
 	if service.Group.ID > 0 {
 		if err := model.DB.Save(&service.Group).Error; err != nil {
 			return serializer.DBErr("Failed to save group record", err)
@@ -65,6 +77,7 @@ func (service *AddGroupService) Add() serializer.Response {
 		}
 	}
 
+	model.RecordAudit(c, operator(c), model.AuditGroupChange, model.AuditTarget("group", service.Group.ID), service.Group.Name)
 	return serializer.Response{Data: service.Group.ID}
 }
 
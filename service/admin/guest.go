@@ -0,0 +1,98 @@
+package admin
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// AddGuestService 访客账户创建服务
+type AddGuestService struct {
+	UserName      string `json:"userName" binding:"required,email,max=100"`
+	Nick          string `json:"nick" binding:"max=50"`
+	Password      string `json:"password" binding:"required,min=4,max=64"`
+	GroupID       uint   `json:"group_id" binding:"required"`
+	Quota         uint64 `json:"quota"`                                  // 容量上限，为 0 时使用用户组的容量
+	Days          int    `json:"days" binding:"required,min=1,max=3650"` // 有效天数，到期后停用账户
+	PurgeOnExpiry bool   `json:"purge_on_expiry"`                        // 到期后是否删除其全部文件
+}
+
+// GuestRenewService 访客账户续期服务
+type GuestRenewService struct {
+	ID            uint   `json:"id" binding:"required"`
+	Quota         uint64 `json:"quota"`
+	Days          int    `json:"days" binding:"required,min=1,max=3650"`
+	PurgeOnExpiry bool   `json:"purge_on_expiry"`
+}
+
+// guestGroup 检查访客账户可使用的用户组，不能为管理员或游客用户组
+func guestGroup(id uint) (model.Group, serializer.Response) {
+	group, err := model.GetGroupByID(id)
+	if err != nil || group.ID == 1 || group.ID == 3 {
+		return group, serializer.Err(serializer.CodeGroupNotFound, "", err)
+	}
+	return group, serializer.Response{}
+}
+
+// Add 创建访客账户
+func (service *AddGuestService) Add(c *gin.Context) serializer.Response {
+	group, res := guestGroup(service.GroupID)
+	if res.Code != 0 {
+		return res
+	}
+
+	expires := time.Now().Add(time.Duration(service.Days) * 24 * time.Hour)
+	user := model.NewUser()
+	user.Email = service.UserName
+	user.Nick = service.Nick
+	if user.Nick == "" {
+		user.Nick = strings.Split(service.UserName, "@")[0]
+	}
+	user.SetPassword(service.Password)
+	user.Status = model.Active
+	user.GroupID = group.ID
+	user.Quota = service.Quota
+	user.ExpiresAt = &expires
+	user.PurgeOnExpiry = service.PurgeOnExpiry
+	if err := model.DB.Create(&user).Error; err != nil {
+		return serializer.Err(serializer.CodeEmailExisted, "Email already in use", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditGuestCreate, model.AuditTarget("user", user.ID),
+		fmt.Sprintf("%s until %s", user.Email, expires.Format(time.RFC3339)))
+	return serializer.Response{Data: user.ID}
+}
+
+// Renew 为访客账户续期并更新容量上限，已到期停用的账户将重新启用
+func (service *GuestRenewService) Renew(c *gin.Context) serializer.Response {
+	user, err := model.GetUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if user.ExpiresAt == nil {
+		return serializer.ParamErr("User is not a guest account", nil)
+	}
+
+	expires := time.Now().Add(time.Duration(service.Days) * 24 * time.Hour)
+	updates := map[string]interface{}{
+		"quota":           service.Quota,
+		"expires_at":      expires,
+		"purge_on_expiry": service.PurgeOnExpiry,
+	}
+	if user.ExpiresAt.Before(time.Now()) {
+		updates["status"] = model.Active
+	}
+
+	if err := model.DB.Model(&user).UpdateColumns(updates).Error; err != nil {
+		return serializer.DBErr("Failed to renew guest account", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditGuestRenew, model.AuditTarget("user", user.ID),
+		fmt.Sprintf("%s until %s", user.Email, expires.Format(time.RFC3339)))
+	return serializer.Response{Data: expires}
+}
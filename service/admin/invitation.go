@@ -0,0 +1,99 @@
+package admin
+
+import (
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// AddInvitationService 注册邀请码生成服务
+type AddInvitationService struct {
+	GroupID uint `json:"group_id"` // 受邀用户加入的用户组，为 0 时使用默认用户组
+	MaxUses int  `json:"max_uses" binding:"min=0"`
+	Expires int  `json:"expires" binding:"min=0"` // 有效天数，为 0 时永不过期
+	Count   int  `json:"count" binding:"required,min=1,max=100"`
+}
+
+// InvitationBatchService 注册邀请码批量操作服务
+type InvitationBatchService struct {
+	ID []uint `json:"id" binding:"min=1"`
+}
+
+// Add 批量生成注册邀请码
+func (service *AddInvitationService) Add(c *gin.Context) serializer.Response {
+	if service.GroupID != 0 {
+		// 不能邀请用户加入游客用户组
+		if _, err := model.GetGroupByID(service.GroupID); err != nil || service.GroupID == 3 {
+			return serializer.Err(serializer.CodeGroupNotFound, "", err)
+		}
+	}
+
+	var expires *time.Time
+	if service.Expires > 0 {
+		expiredAt := time.Now().Add(time.Duration(service.Expires) * 24 * time.Hour)
+		expires = &expiredAt
+	}
+
+	invitations := make([]model.Invitation, 0, service.Count)
+	for i := 0; i < service.Count; i++ {
+		invitation := model.Invitation{
+			CreatorID: operator(c),
+			GroupID:   service.GroupID,
+			MaxUses:   service.MaxUses,
+			ExpiredAt: expires,
+		}
+		if err := invitation.Create(); err != nil {
+			return serializer.DBErr("Failed to create invitation code", err)
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	return serializer.Response{Data: serializer.BuildInvitations(invitations, model.GetSiteURL())}
+}
+
+// Delete 删除注册邀请码
+func (service *InvitationBatchService) Delete(c *gin.Context) serializer.Response {
+	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Invitation{}).Error; err != nil {
+		return serializer.DBErr("Failed to delete invitation codes", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Invitations 列出注册邀请码
+func (service *AdminListService) Invitations() serializer.Response {
+	var res []model.Invitation
+	total := 0
+
+	tx := model.DB.Model(&model.Invitation{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " like '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": serializer.BuildInvitations(res, model.GetSiteURL()),
+	}}
+}
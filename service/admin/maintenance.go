@@ -0,0 +1,84 @@
+package admin
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/aria2/common"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceService 开启或关闭维护模式的服务
+type MaintenanceService struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message" binding:"max=1024"`
+}
+
+// maintenanceStatus 维护模式状态
+type maintenanceStatus struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+	// 任务池运行状态
+	Pool *task.PoolStats `json:"pool,omitempty"`
+	// 进行中的离线下载数
+	Downloads int `json:"downloads"`
+	// 正在执行的任务与离线下载均已结束，可以安全升级
+	Drained bool `json:"drained"`
+}
+
+// MaintenanceStatus 获取维护模式状态及任务排空进度
+func (service *NoParamService) MaintenanceStatus() serializer.Response {
+	return serializer.Response{Data: buildMaintenanceStatus()}
+}
+
+// Update 开启或关闭维护模式，开启后不再接受新的上传与任务，
+// 正在执行的任务及离线下载继续运行直至结束
+func (service *MaintenanceService) Update(c *gin.Context) serializer.Response {
+	enabled := "0"
+	if service.Enabled {
+		enabled = "1"
+	}
+
+	settings := map[string]string{"maintenance_enabled": enabled}
+	if service.Message != "" {
+		settings["maintenance_message"] = service.Message
+	}
+
+	for k, v := range settings {
+		if err := model.DB.Model(&model.Setting{}).Where("name = ?", k).Update("value", v).Error; err != nil {
+			return serializer.Err(serializer.CodeUpdateSetting, "Setting "+k+" failed to update", err)
+		}
+	}
+	// 任务池随设置变更暂停或恢复
+	model.NotifySettingChange("maintenance_enabled", "maintenance_message")
+
+	detail := "disabled"
+	if service.Enabled {
+		detail = "enabled"
+	}
+	model.RecordAudit(c, operator(c), model.AuditMaintenance, "", detail)
+
+	return serializer.Response{Data: buildMaintenanceStatus()}
+}
+
+func buildMaintenanceStatus() maintenanceStatus {
+	options := model.GetSettingByNames("maintenance_enabled", "maintenance_message")
+	status := maintenanceStatus{
+		Enabled: model.IsTrueVal(options["maintenance_enabled"]),
+		Message: options["maintenance_message"],
+	}
+
+	model.DB.Model(&model.Download{}).
+		Where("status in (?)", []int{common.Ready, common.Downloading, common.Paused}).
+		Count(&status.Downloads)
+
+	busy := 0
+	if pool, ok := task.TaskPoll.(task.ResizablePool); ok {
+		stats := pool.Stats()
+		status.Pool = &stats
+		busy = stats.Busy
+	}
+
+	status.Drained = status.Enabled && busy == 0 && status.Downloads == 0
+	return status
+}
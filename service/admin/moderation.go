@@ -0,0 +1,47 @@
+package admin
+
+import (
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// ModerationListService 分页列出审核记录的服务
+type ModerationListService struct {
+	Status   string `json:"status" binding:"omitempty,eq=pending|eq=pass|eq=review|eq=block|eq=error"`
+	Page     int    `json:"page" binding:"min=1"`
+	PageSize int    `json:"page_size" binding:"min=1,max=1000"`
+}
+
+// ModerationResolveService 人工复核审核记录的服务
+type ModerationResolveService struct {
+	ID     uint   `json:"id" binding:"required"`
+	Status string `json:"status" binding:"required,eq=pass|eq=block"`
+}
+
+// List 列出审核记录
+func (service *ModerationListService) List() serializer.Response {
+	moderations, total := model.ListModerations(service.Status, service.Page, service.PageSize)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": moderations,
+	}}
+}
+
+// Resolve 人工复核审核记录，并将结论应用到文件的分享上
+func (service *ModerationResolveService) Resolve(c *gin.Context) serializer.Response {
+	moderation, err := model.GetModerationByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Moderation record not found", err)
+	}
+
+	if err := moderation.Resolve(service.Status, operator(c)); err != nil {
+		return serializer.DBErr("Failed to update moderation record", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditModeration, model.AuditTarget("file", moderation.FileID),
+		fmt.Sprintf("resolved as %s: %s", service.Status, moderation.FileName))
+	return serializer.Response{Data: moderation}
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// AddOAuthClientService 第三方应用添加/保存服务
+type AddOAuthClientService struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name" binding:"required,min=1,max=255"`
+	Public       bool   `json:"public"`
+	Homepage     string `json:"homepage" binding:"max=255"`
+	RedirectURIs string `json:"redirect_uris" binding:"required,max=65535"`
+}
+
+// OAuthClientService 第三方应用管理服务
+type OAuthClientService struct {
+	ID uint `uri:"id" json:"id" binding:"required"`
+}
+
+// Add 添加或保存第三方应用，新建时返回客户端密钥
+func (service *AddOAuthClientService) Add() serializer.Response {
+	if service.ID > 0 {
+		client, err := model.GetOAuthClientByID(service.ID)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "Application not found", err)
+		}
+
+		client.Name = service.Name
+		client.Public = service.Public
+		client.Homepage = service.Homepage
+		client.RedirectURIs = service.RedirectURIs
+		if err := model.DB.Save(client).Error; err != nil {
+			return serializer.DBErr("Failed to save application", err)
+		}
+
+		return serializer.Response{Data: map[string]interface{}{"id": client.ID}}
+	}
+
+	client := &model.OAuthClient{
+		Name:         service.Name,
+		Public:       service.Public,
+		Homepage:     service.Homepage,
+		RedirectURIs: service.RedirectURIs,
+	}
+	secret, err := client.Create()
+	if err != nil {
+		return serializer.DBErr("Failed to create application", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"id":            client.ID,
+		"client_id":     client.ClientID,
+		"client_secret": secret,
+	}}
+}
+
+// ResetSecret 重置客户端密钥
+func (service *OAuthClientService) ResetSecret() serializer.Response {
+	client, err := model.GetOAuthClientByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Application not found", err)
+	}
+
+	secret, err := client.ResetSecret()
+	if err != nil {
+		return serializer.DBErr("Failed to reset client secret", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"client_id":     client.ClientID,
+		"client_secret": secret,
+	}}
+}
+
+// Delete 删除第三方应用，已签发的令牌一并撤销
+func (service *OAuthClientService) Delete() serializer.Response {
+	if err := model.DeleteOAuthClient(service.ID); err != nil {
+		return serializer.DBErr("Failed to delete application", err)
+	}
+
+	return serializer.Response{}
+}
+
+// OAuthClients 列出第三方应用
+func (service *AdminListService) OAuthClients() serializer.Response {
+	var res []model.OAuthClient
+	total := 0
+
+	tx := model.DB.Model(&model.OAuthClient{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	if len(service.Searches) > 0 {
+		search := ""
+		for k, v := range service.Searches {
+			search += k + " like '%" + v + "%' OR "
+		}
+		search = strings.TrimSuffix(search, " OR ")
+		tx = tx.Where(search)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
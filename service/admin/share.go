@@ -4,9 +4,9 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
-	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
 )
 
 // ShareBatchService 分享批量操作服务
@@ -14,14 +14,167 @@ type ShareBatchService struct {
 	ID []uint `json:"id" binding:"min=1"`
 }
 
+// ShareFilterService 按所有者和文件名筛选分享的服务
+type ShareFilterService struct {
+	// 所有者的邮箱或昵称
+	Owner    string `json:"owner" binding:"max=255"`
+	Keywords string `json:"keywords" binding:"max=255"`
+}
+
+// ShareSearchService 分页搜索分享的服务
+type ShareSearchService struct {
+	ShareFilterService
+	Page     int `json:"page" binding:"min=1"`
+	PageSize int `json:"page_size" binding:"min=1,max=1000"`
+}
+
+// operator 返回当前操作的管理员ID
+func operator(c *gin.Context) uint {
+	if user, ok := c.Get("user"); ok {
+		return user.(*model.User).ID
+	}
+	return 0
+}
+
+// revoke 撤销给定的分享并记录审计事件
+func revoke(c *gin.Context, shares []model.Share, reason string) error {
+	if len(shares) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(shares))
+	for _, share := range shares {
+		ids = append(ids, share.ID)
+	}
+
+	if err := model.DB.Where("id in (?)", ids).Delete(&model.Share{}).Error; err != nil {
+		return err
+	}
+
+	model.RecordShareRevoke(c, operator(c), shares, reason)
+	return nil
+}
+
 // Delete 删除文件
 func (service *ShareBatchService) Delete(c *gin.Context) serializer.Response {
-	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Share{}).Error; err != nil {
+	var shares []model.Share
+	if err := model.DB.Where("id in (?)", service.ID).Find(&shares).Error; err != nil {
+		return serializer.DBErr("Failed to list share records", err)
+	}
+
+	if err := revoke(c, shares, "deleted by admin"); err != nil {
 		return serializer.DBErr("Failed to delete share record", err)
 	}
 	return serializer.Response{}
 }
 
+// filter 构建筛选分享的查询
+func (service *ShareFilterService) filter() *gorm.DB {
+	tx := model.DB.Model(&model.Share{})
+	if service.Owner != "" {
+		owners := model.DB.Model(&model.User{}).Select("id").
+			Where("email = ? or nick = ?", service.Owner, service.Owner).QueryExpr()
+		tx = tx.Where("user_id in (?)", owners)
+	}
+
+	if service.Keywords != "" {
+		tx = tx.Where("source_name like ?", "%"+service.Keywords+"%")
+	}
+
+	return tx
+}
+
+// Search 按所有者和文件名搜索分享
+func (service *ShareSearchService) Search() serializer.Response {
+	var res []model.Share
+	total := 0
+
+	tx := service.filter()
+	tx.Count(&total)
+	tx.Order("id desc").Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	hashIDs := make(map[uint]string, len(res))
+	for _, share := range res {
+		hashIDs[share.ID] = share.HashID()
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+		"ids":   hashIDs,
+	}}
+}
+
+// Revoke 撤销所有符合条件的分享，至少需要一个筛选条件
+func (service *ShareFilterService) Revoke(c *gin.Context) serializer.Response {
+	if service.Owner == "" && service.Keywords == "" {
+		return serializer.ParamErr("At least one filter is required", nil)
+	}
+
+	var shares []model.Share
+	if err := service.filter().Find(&shares).Error; err != nil {
+		return serializer.DBErr("Failed to list share records", err)
+	}
+
+	if err := revoke(c, shares, "revoked by admin"); err != nil {
+		return serializer.DBErr("Failed to revoke shares", err)
+	}
+
+	return serializer.Response{Data: len(shares)}
+}
+
+// AuditLogs 列出审计记录
+func (service *AdminListService) AuditLogs() serializer.Response {
+	var res []model.AuditLog
+	total := 0
+
+	tx := model.DB.Model(&model.AuditLog{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
+
+// ShareLockLogs 列出分享密码锁定记录
+func (service *AdminListService) ShareLockLogs() serializer.Response {
+	var res []model.ShareLockLog
+	total := 0
+
+	tx := model.DB.Model(&model.ShareLockLog{})
+	if service.OrderBy != "" {
+		tx = tx.Order(service.OrderBy)
+	}
+
+	for k, v := range service.Conditions {
+		tx = tx.Where(k+" = ?", v)
+	}
+
+	// 计算总数用于分页
+	tx.Count(&total)
+
+	// 查询记录
+	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": res,
+	}}
+}
+
 // Shares 列出分享
 func (service *AdminListService) Shares() serializer.Response {
 	var res []model.Share
@@ -56,7 +209,7 @@ func (service *AdminListService) Shares() serializer.Response {
 	hashIDs := make(map[uint]string, len(res))
 	for _, file := range res {
 		users[file.UserID] = model.User{}
-		hashIDs[file.ID] = hashid.HashID(file.ID, hashid.ShareID)
+		hashIDs[file.ID] = file.HashID()
 	}
 
 	userIDs := make([]uint, 0, len(users))
@@ -78,3 +231,57 @@ func (service *AdminListService) Shares() serializer.Response {
 		"ids":   hashIDs,
 	}}
 }
+
+// ShareFolderService 目录子树下分享的管理服务
+type ShareFolderService struct {
+	ID uint `json:"id" binding:"required"`
+}
+
+// owner 获取目录的所有者ID
+func (service *ShareFolderService) owner() (uint, error) {
+	var folder model.Folder
+	if err := model.DB.First(&folder, service.ID).Error; err != nil {
+		return 0, err
+	}
+	return folder.OwnerID, nil
+}
+
+// List 列出目录子树下所有对象的分享
+func (service *ShareFolderService) List() serializer.Response {
+	owner, err := service.owner()
+	if err != nil {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	shares, err := model.ListSharesUnder(owner, []uint{service.ID}, nil)
+	if err != nil {
+		return serializer.DBErr("Failed to list shares", err)
+	}
+
+	hashIDs := make(map[uint]string, len(shares))
+	for _, share := range shares {
+		hashIDs[share.ID] = share.HashID()
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"total": len(shares),
+		"items": shares,
+		"ids":   hashIDs,
+	}}
+}
+
+// Revoke 撤销目录子树下所有对象的分享
+func (service *ShareFolderService) Revoke(c *gin.Context) serializer.Response {
+	owner, err := service.owner()
+	if err != nil {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	revoked, err := model.RevokeSharesUnder(owner, []uint{service.ID}, nil)
+	if err != nil {
+		return serializer.DBErr("Failed to revoke shares", err)
+	}
+
+	model.RecordShareRevoke(c, operator(c), revoked, "revoked with folder by admin")
+	return serializer.Response{Data: len(revoked)}
+}
@@ -2,6 +2,10 @@ package admin
 
 import (
 	"encoding/gob"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -9,6 +13,9 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sms"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 )
 
 func init() {
@@ -61,6 +68,10 @@ func (service *BatchSettingChangeService) Change() serializer.Response {
 	tx := model.DB.Begin()
 
 	for _, setting := range service.Options {
+		if err := validateSetting(setting.Key, setting.Value); err != nil {
+			tx.Rollback()
+			return serializer.ParamErr("Invalid value for setting "+setting.Key+": "+err.Error(), err)
+		}
 
 		if err := tx.Model(&model.Setting{}).Where("name = ?", setting.Key).Update("value", setting.Value).Error; err != nil {
 			cache.Deletes(cacheClean, "setting_")
@@ -75,11 +86,46 @@ func (service *BatchSettingChangeService) Change() serializer.Response {
 		return serializer.DBErr("Failed to update setting", err)
 	}
 
-	cache.Deletes(cacheClean, "setting_")
+	// 清除缓存，并重新初始化依赖这些设置的组件
+	model.NotifySettingChange(cacheClean...)
 
 	return serializer.Response{}
 }
 
+// validateSetting 校验运行时生效、取值无效会导致中间件失效的设置项
+func validateSetting(key, value string) error {
+	switch key {
+	case "cors_allow_origins":
+		_, err := util.ParseOrigins(value)
+		return err
+	case "security_hsts_max_age":
+		if maxAge, err := strconv.Atoi(value); err != nil || maxAge < 0 {
+			return errors.New("max-age must be a non-negative integer")
+		}
+	case "mail_driver":
+		if value != "smtp" && !email.IsSupportedDriver(value) {
+			return fmt.Errorf("mail driver must be one of smtp, %s", strings.Join(email.SupportedDrivers(), ", "))
+		}
+	case "sms_provider":
+		if value != "" && !sms.IsSupportedProvider(value) {
+			return errors.New("sms provider must be one of aliyun, tencent, twilio")
+		}
+	case "usage_report_period":
+		if value != "" && value != stats.ReportWeekly && value != stats.ReportMonthly {
+			return errors.New("usage report period must be empty, weekly or monthly")
+		}
+	case "capacity_warning_percent", "capacity_critical_percent":
+		if n, err := strconv.Atoi(value); err != nil || n < 0 || n > 100 {
+			return fmt.Errorf("%s must be an integer between 0 and 100", key)
+		}
+	case "mail_rate_limit", "mail_max_retries":
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf("%s must be a non-negative integer", key)
+		}
+	}
+	return nil
+}
+
 // Summary 获取站点统计概况
 func (service *NoParamService) Summary() serializer.Response {
 	// 获取版本信息
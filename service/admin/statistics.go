@@ -0,0 +1,85 @@
+package admin
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/stats"
+)
+
+// TrafficStatisticsService 流量及活跃用户统计查询服务
+type TrafficStatisticsService struct {
+	Days int `form:"days" binding:"omitempty,min=1,max=366"`
+}
+
+// dailyStatistic 单日的流量及活跃用户数
+type dailyStatistic struct {
+	Date            string `json:"date"`
+	UploadTraffic   uint64 `json:"upload"`
+	DownloadTraffic uint64 `json:"download"`
+	ActiveUsers     int    `json:"active_users"`
+}
+
+// Traffic 列出最近若干天每日的流量及活跃用户数，无记录的日期补零，
+// 当日流量包含尚未写入数据库的部分
+func (service *TrafficStatisticsService) Traffic() serializer.Response {
+	days := service.Days
+	if days == 0 {
+		days = 30
+	}
+
+	now := time.Now()
+	from := now.AddDate(0, 0, 1-days)
+	records, err := model.ListStatistics(from.Format(model.StatisticDateFormat), now.Format(model.StatisticDateFormat))
+	if err != nil {
+		return serializer.DBErr("Failed to list statistics", err)
+	}
+
+	recordMap := make(map[string]*model.Statistic, len(records))
+	for i := range records {
+		recordMap[records[i].Date] = &records[i]
+	}
+
+	res := make([]dailyStatistic, days)
+	for i := range res {
+		date := from.AddDate(0, 0, i).Format(model.StatisticDateFormat)
+		res[i].Date = date
+		if record, ok := recordMap[date]; ok {
+			res[i].UploadTraffic = record.UploadTraffic
+			res[i].DownloadTraffic = record.DownloadTraffic
+			res[i].ActiveUsers = record.ActiveUsers
+		}
+	}
+
+	upload, download := stats.Pending()
+	res[days-1].UploadTraffic += upload
+	res[days-1].DownloadTraffic += download
+
+	return serializer.Response{Data: res}
+}
+
+// StorageStatistics 获取最近一次汇总的各存储策略、用户组的用量及用量最多的用户
+func (service *NoParamService) StorageStatistics() serializer.Response {
+	usage, err := model.GetLatestStorageUsage()
+	if err != nil {
+		return serializer.DBErr("Failed to get storage statistics", err)
+	}
+
+	return serializer.Response{Data: usage}
+}
+
+// RefreshStatistics 立即汇总站点统计，不等待定时任务
+func (service *NoParamService) RefreshStatistics() serializer.Response {
+	if err := stats.Aggregate(); err != nil {
+		return serializer.DBErr("Failed to aggregate statistics", err)
+	}
+
+	return service.StorageStatistics()
+}
+
+// ThumbStatistics 获取缩略图任务池的运行状态
+func (service *NoParamService) ThumbStatistics() serializer.Response {
+	return serializer.Response{Data: filesystem.GetThumbPoolStatus()}
+}
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
@@ -21,19 +23,108 @@ type ImportTaskService struct {
 	Src       string `json:"src" binding:"required,min=1,max=65535"`
 	Dst       string `json:"dst" binding:"required,min=1,max=65535"`
 	Recursive bool   `json:"recursive"`
+	Sync      bool   `json:"sync"`
+}
+
+// StorageAuditService 存储一致性检查任务创建服务
+type StorageAuditService struct {
+	ID  []uint `json:"id" binding:"min=1"`
+	Fix bool   `json:"fix"` // 是否修复发现的问题
+}
+
+// TaskReportService 任务报告下载服务
+type TaskReportService struct {
+	ID     uint   `uri:"id" binding:"required"`
+	Format string `form:"format" binding:"omitempty,eq=csv|eq=json"`
+}
+
+// TaskPoolService 任务池调整服务
+type TaskPoolService struct {
+	Workers int `json:"workers" binding:"required,min=1,max=1024"`
+}
+
+// Resize 调整任务池 Worker 数量
+func (service *TaskPoolService) Resize() serializer.Response {
+	pool, ok := task.TaskPoll.(task.ResizablePool)
+	if !ok {
+		return serializer.Err(serializer.CodeInternalSetting, "Task pool is not resizable", nil)
+	}
+
+	if err := pool.Resize(service.Workers); err != nil {
+		return serializer.ParamErr("Invalid worker number", err)
+	}
+
+	// 保存设置，重启后继续生效
+	if err := model.DB.Model(&model.Setting{}).Where("name = ?", "max_worker_num").
+		Update("value", strconv.Itoa(service.Workers)).Error; err != nil {
+		return serializer.Err(serializer.CodeUpdateSetting, "Setting max_worker_num failed to update", err)
+	}
+	model.NotifySettingChange("max_worker_num")
+
+	return serializer.Response{Data: pool.Stats()}
+}
+
+// TaskPoolStats 获取任务池运行状态
+func (service *NoParamService) TaskPoolStats() serializer.Response {
+	pool, ok := task.TaskPoll.(task.ResizablePool)
+	if !ok {
+		return serializer.Err(serializer.CodeInternalSetting, "Task pool is not resizable", nil)
+	}
+
+	return serializer.Response{Data: pool.Stats()}
 }
 
 // Create 新建导入任务
 func (service *ImportTaskService) Create(c *gin.Context, user *model.User) serializer.Response {
 	// 创建任务
-	job, err := task.NewImportTask(service.UID, service.PolicyID, service.Src, service.Dst, service.Recursive)
+	job, err := task.NewImportTask(service.UID, service.PolicyID, service.Src, service.Dst, service.Recursive, service.Sync)
 	if err != nil {
 		return serializer.DBErr("Failed to create task record.", err)
 	}
-	task.TaskPoll.Submit(job)
+	task.TaskPoll.Submit(task.Trace(job, c))
 	return serializer.Response{}
 }
 
+// Create 为给定用户创建存储一致性检查任务
+func (service *StorageAuditService) Create(c *gin.Context) serializer.Response {
+	ids := make([]uint, 0, len(service.ID))
+	for _, uid := range service.ID {
+		job, err := task.NewStorageAuditTask(uid, service.Fix)
+		if err != nil {
+			return serializer.DBErr(fmt.Sprintf("Failed to create task for user %d", uid), err)
+		}
+		task.TaskPoll.Submit(task.Trace(job, c))
+		ids = append(ids, job.Model().ID)
+	}
+
+	return serializer.Response{Data: ids}
+}
+
+// Download 下载任务的逐项处理结果报告
+func (service *TaskReportService) Download(c *gin.Context) serializer.Response {
+	record, err := model.GetTasksByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Task not exist", err)
+	}
+
+	if record.Report == "" {
+		return serializer.Err(serializer.CodeNotFound, "Task has no report", nil)
+	}
+
+	report, err := task.ParseReport(record.Report)
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to parse task report", err)
+	}
+
+	if service.Format == "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"task_%d_report.csv\"", record.ID))
+		c.Data(200, "text/csv; charset=utf-8", report.CSV())
+		return serializer.Response{}
+	}
+
+	return serializer.Response{Data: report}
+}
+
 // Delete 删除任务
 func (service *TaskBatchService) Delete(c *gin.Context) serializer.Response {
 	if err := model.DB.Where("id in (?)", service.ID).Delete(&model.Download{}).Error; err != nil {
@@ -79,6 +170,11 @@ func (service *AdminListService) Tasks() serializer.Response {
 	// 查询记录
 	tx.Limit(service.PageSize).Offset((service.Page - 1) * service.PageSize).Find(&res)
 
+	// 不返回任务属性中的压缩包密码
+	for i := range res {
+		task.RedactProps(&res[i])
+	}
+
 	// 查询对应用户，同时计算HashID
 	users := make(map[uint]model.User)
 	for _, file := range res {
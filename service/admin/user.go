@@ -2,11 +2,16 @@ package admin
 
 import (
 	"context"
+	"fmt"
 	"strings"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
 )
 
 // AddUserService 用户添加服务
@@ -20,13 +25,20 @@ type UserService struct {
 	ID uint `uri:"id" json:"id" binding:"required"`
 }
 
+// UserGroupUpgradeService 临时调整用户组服务
+type UserGroupUpgradeService struct {
+	ID      uint `json:"id" binding:"required"`
+	GroupID uint `json:"group_id" binding:"required"`
+	Days    int  `json:"days" binding:"required,min=1,max=3650"` // 有效天数，到期后恢复为原用户组
+}
+
 // UserBatchService 用户批量操作服务
 type UserBatchService struct {
 	ID []uint `json:"id" binding:"min=1"`
 }
 
 // Ban 封禁/解封用户
-func (service *UserService) Ban() serializer.Response {
+func (service *UserService) Ban(c *gin.Context) serializer.Response {
 	user, err := model.GetUserByID(service.ID)
 	if err != nil {
 		return serializer.Err(serializer.CodeUserNotFound, "", err)
@@ -42,11 +54,13 @@ func (service *UserService) Ban() serializer.Response {
 		user.SetStatus(model.Active)
 	}
 
+	model.RecordAudit(c, operator(c), model.AuditUserPermission, model.AuditTarget("user", user.ID),
+		fmt.Sprintf("status -> %d", user.Status))
 	return serializer.Response{Data: user.Status}
 }
 
 // Delete 删除用户
-func (service *UserBatchService) Delete() serializer.Response {
+func (service *UserBatchService) Delete(c *gin.Context) serializer.Response {
 	for _, uid := range service.ID {
 		user, err := model.GetUserByID(uid)
 		if err != nil {
@@ -66,21 +80,14 @@ func (service *UserBatchService) Delete() serializer.Response {
 		if err != nil {
 			return serializer.Err(serializer.CodeInternalSetting, "User's root folder not exist", err)
 		}
-		fs.Delete(context.Background(), []uint{root.ID}, []uint{}, false)
-
-		// 删除相关任务
-		model.DB.Where("user_id = ?", uid).Delete(&model.Download{})
-		model.DB.Where("user_id = ?", uid).Delete(&model.Task{})
-
-		// 删除标签
-		model.DB.Where("user_id = ?", uid).Delete(&model.Tag{})
+		fs.Delete(context.WithValue(context.Background(), fsctx.IgnoreLocksCtx, true), []uint{root.ID}, []uint{}, false)
 
-		// 删除WebDAV账号
-		model.DB.Where("user_id = ?", uid).Delete(&model.Webdav{})
-
-		// 删除此用户
-		model.DB.Unscoped().Delete(user)
+		// 删除此用户及相关任务、标签、WebDAV账号等记录
+		if err := user.Purge(); err != nil {
+			return serializer.DBErr("Failed to delete user", err)
+		}
 
+		model.RecordAudit(c, operator(c), model.AuditUserDelete, model.AuditTarget("user", user.ID), user.Email)
 	}
 	return serializer.Response{}
 }
@@ -96,20 +103,31 @@ func (service *UserService) Get() serializer.Response {
 }
 
 // Add 添加用户
-func (service *AddUserService) Add() serializer.Response {
+func (service *AddUserService) Add(c *gin.Context) serializer.Response {
 	if service.User.ID > 0 {
 
 		user, _ := model.GetUserByID(service.User.ID)
+		previousGroup, previousStatus := user.GroupID, user.Status
 		if service.Password != "" {
 			user.SetPassword(service.Password)
 		}
 
 		// 只更新必要字段
 		user.Nick = service.User.Nick
-		user.Email = service.User.Email
-		user.GroupID = service.User.GroupID
+		if user.Email != service.User.Email {
+			// 新邮箱的投递状态未知
+			user.Email = service.User.Email
+			user.EmailStatus = model.EmailDeliverable
+		}
 		user.Status = service.User.Status
 
+		// 手动更换用户组后不再恢复临时调整前的用户组
+		if user.GroupID != service.User.GroupID {
+			user.GroupID = service.User.GroupID
+			user.PreviousGroupID = 0
+			user.GroupExpires = nil
+		}
+
 		// 检查愚蠢操作
 		if user.ID == 1 && user.GroupID != 1 {
 			return serializer.Err(serializer.CodeChangeGroupForDefaultUser, "", nil)
@@ -118,11 +136,19 @@ func (service *AddUserService) Add() serializer.Response {
 		if err := model.DB.Save(&user).Error; err != nil {
 			return serializer.DBErr("Failed to save user record", err)
 		}
+
+		if previousGroup != user.GroupID || previousStatus != user.Status {
+			model.RecordAudit(c, operator(c), model.AuditUserPermission, model.AuditTarget("user", user.ID),
+				fmt.Sprintf("group %d -> %d, status %d -> %d", previousGroup, user.GroupID, previousStatus, user.Status))
+		}
 	} else {
 		service.User.SetPassword(service.Password)
 		if err := model.DB.Create(&service.User).Error; err != nil {
 			return serializer.DBErr("Failed to create user record", err)
 		}
+
+		model.RecordAudit(c, operator(c), model.AuditUserPermission, model.AuditTarget("user", service.User.ID),
+			fmt.Sprintf("created in group %d with status %d", service.User.GroupID, service.User.Status))
 	}
 
 	return serializer.Response{Data: service.User.ID}
@@ -164,3 +190,86 @@ func (service *AdminListService) Users() serializer.Response {
 		"items": res,
 	}}
 }
+
+// impersonationTTL 模拟用户的有效期，单位秒
+const impersonationTTL = 3600
+
+// Impersonate 以指定用户身份访问站点，用于排查用户反馈的问题。不能模拟管理员
+func (service *UserService) Impersonate(c *gin.Context, admin *model.User) serializer.Response {
+	user, err := model.GetActiveUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if user.ID == admin.ID || user.IsAdmin() {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Cannot impersonate an administrator", nil)
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		"impersonate_id":    user.ID,
+		"impersonate_until": time.Now().Unix() + impersonationTTL,
+	})
+	model.RecordAudit(c, admin.ID, model.AuditImpersonateStart, model.AuditTarget("user", user.ID), user.Email)
+
+	return serializer.Response{}
+}
+
+// StopImpersonation 结束模拟用户，恢复为管理员本人
+func StopImpersonation(c *gin.Context, user *model.User) serializer.Response {
+	if user.Impersonator == 0 {
+		return serializer.ParamErr("Not impersonating", nil)
+	}
+
+	util.DeleteSession(c, "impersonate_id")
+	util.DeleteSession(c, "impersonate_until")
+	model.RecordAudit(c, user.Impersonator, model.AuditImpersonateStop, model.AuditTarget("user", user.ID), "")
+
+	return serializer.Response{}
+}
+
+// Upgrade 将用户临时调整到给定用户组，到期后由定时任务恢复
+func (service *UserGroupUpgradeService) Upgrade(c *gin.Context) serializer.Response {
+	user, err := model.GetUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if user.ID == 1 {
+		return serializer.Err(serializer.CodeChangeGroupForDefaultUser, "", nil)
+	}
+
+	// 不能调整到游客用户组
+	group, err := model.GetGroupByID(service.GroupID)
+	if err != nil || group.ID == 3 {
+		return serializer.Err(serializer.CodeGroupNotFound, "", err)
+	}
+
+	until := time.Now().Add(time.Duration(service.Days) * 24 * time.Hour)
+	if err := user.UpgradeGroup(group.ID, until); err != nil {
+		return serializer.DBErr("Failed to update user group", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditGroupUpgrade, model.AuditTarget("user", user.ID),
+		fmt.Sprintf("%s until %s", group.Name, until.Format(time.RFC3339)))
+	return serializer.Response{Data: user.GroupExpires}
+}
+
+// RevertGroup 提前结束临时用户组，恢复为原用户组
+func (service *UserService) RevertGroup(c *gin.Context) serializer.Response {
+	user, err := model.GetUserByID(service.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	if user.GroupExpires == nil {
+		return serializer.ParamErr("User is not in a temporary group", nil)
+	}
+
+	expired := user.Group.Name
+	if err := user.RevertGroup(); err != nil {
+		return serializer.DBErr("Failed to revert user group", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditGroupRevert, model.AuditTarget("user", user.ID), expired)
+	return serializer.Response{}
+}
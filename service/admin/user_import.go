@@ -0,0 +1,256 @@
+package admin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// userImportMaxRows 单次最多导入的用户数
+	userImportMaxRows = 1000
+	// userInviteTTL 设置密码邀请链接的有效期，单位秒
+	userInviteTTL = 7 * 86400
+)
+
+// UserImportRow 批量导入的单个用户
+type UserImportRow struct {
+	Email    string `json:"email"`
+	Nick     string `json:"nick"`
+	Group    string `json:"group"`    // 用户组ID或名称，为空时使用默认用户组
+	Quota    uint64 `json:"quota"`    // 容量上限，为 0 时使用用户组的容量
+	Password string `json:"password"` // 初始密码，为空时向用户发送设置密码的邀请邮件
+
+	invalid error // 解析 CSV 时发现的错误，在逐行校验时报告
+}
+
+// UserImportService 批量导入用户服务，可直接提交用户列表，或提交带表头的 CSV 文本，
+// 表头可包含 email、nick、group、quota、password 列
+type UserImportService struct {
+	Users []UserImportRow `json:"users"`
+	CSV   string          `json:"csv" binding:"max=4194304"`
+}
+
+// userImportResult 单个用户的导入结果，Row 为从 1 开始的序号，CSV 中的用户排在用户列表之后
+type userImportResult struct {
+	Row     int    `json:"row"`
+	Email   string `json:"email"`
+	ID      uint   `json:"id,omitempty"`
+	Invited bool   `json:"invited,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// parseCSV 解析 CSV 文本为待导入的用户
+func parseCSV(text string) ([]UserImportRow, error) {
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, errors.New(`CSV header must contain an "email" column`)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var rows []UserImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CSV: %w", err)
+		}
+
+		row := UserImportRow{
+			Email:    field(record, "email"),
+			Nick:     field(record, "nick"),
+			Group:    field(record, "group"),
+			Password: field(record, "password"),
+		}
+		if quota := field(record, "quota"); quota != "" {
+			if row.Quota, err = strconv.ParseUint(quota, 10, 64); err != nil {
+				row.invalid = fmt.Errorf("invalid quota %q", quota)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// Import 批量创建用户，逐行返回导入结果，单行失败不影响其他行
+func (service *UserImportService) Import(c *gin.Context) serializer.Response {
+	rows := service.Users
+	if service.CSV != "" {
+		parsed, err := parseCSV(service.CSV)
+		if err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+		rows = append(rows, parsed...)
+	}
+
+	if len(rows) == 0 {
+		return serializer.ParamErr("No users to import", nil)
+	}
+	if len(rows) > userImportMaxRows {
+		return serializer.ParamErr(fmt.Sprintf("At most %d users can be imported at once", userImportMaxRows), nil)
+	}
+
+	groups := make(map[string]*model.Group)
+	defaultGroup := strconv.Itoa(model.GetIntSetting("default_group", 2))
+	results := make([]userImportResult, 0, len(rows))
+	created := 0
+	for i, row := range rows {
+		result := userImportResult{Row: i + 1, Email: row.Email}
+		if err := importUser(&row, defaultGroup, groups, &result); err != nil {
+			result.Error = err.Error()
+		}
+		if result.ID > 0 {
+			created++
+		}
+		results = append(results, result)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditUserImport, "",
+		fmt.Sprintf("%d created, %d failed", created, len(rows)-created))
+	return serializer.Response{Data: map[string]interface{}{
+		"created": created,
+		"failed":  len(rows) - created,
+		"results": results,
+	}}
+}
+
+// importUser 创建单个用户，用户创建后才发生的错误同样返回，此时 result.ID 不为零
+func importUser(row *UserImportRow, defaultGroup string, groups map[string]*model.Group, result *userImportResult) error {
+	if row.invalid != nil {
+		return row.invalid
+	}
+
+	address, err := mail.ParseAddress(row.Email)
+	if err != nil || address.Address != row.Email || len(row.Email) > 100 {
+		return errors.New("invalid email address")
+	}
+
+	if row.Password != "" && (len(row.Password) < 4 || len(row.Password) > 64) {
+		return errors.New("password must be 4 to 64 characters")
+	}
+
+	if row.Group == "" {
+		row.Group = defaultGroup
+	}
+	group, err := resolveGroup(row.Group, groups)
+	if err != nil {
+		return err
+	}
+
+	user := model.NewUser()
+	user.Email = row.Email
+	user.Nick = row.Nick
+	if user.Nick == "" {
+		user.Nick = strings.Split(row.Email, "@")[0]
+	}
+	if len(user.Nick) > 50 {
+		return errors.New("nickname is too long")
+	}
+	user.Status = model.Active
+	user.GroupID = group.ID
+	user.Quota = row.Quota
+
+	// 未提供初始密码时使用随机密码，用户通过邀请邮件设置密码
+	password := row.Password
+	if password == "" {
+		password = util.RandSecureString(32)
+	}
+	user.SetPassword(password)
+
+	if err := model.DB.Create(&user).Error; err != nil {
+		if _, existed := model.GetUserByEmail(row.Email); existed == nil {
+			return errors.New("email already in use")
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	result.ID = user.ID
+
+	if row.Password == "" {
+		if err := sendAccountInvite(&user); err != nil {
+			return fmt.Errorf("user created, but failed to send invitation email: %w", err)
+		}
+		result.Invited = true
+	}
+
+	return nil
+}
+
+// resolveGroup 根据ID或名称查找用户组，不能为管理员或游客用户组
+func resolveGroup(key string, groups map[string]*model.Group) (*model.Group, error) {
+	if group, ok := groups[key]; ok {
+		if group == nil {
+			return nil, fmt.Errorf("group %q not found", key)
+		}
+		return group, nil
+	}
+
+	var (
+		group model.Group
+		err   error
+	)
+	if id, parseErr := strconv.ParseUint(key, 10, 32); parseErr == nil {
+		group, err = model.GetGroupByID(uint(id))
+	} else {
+		err = model.DB.Where("name = ?", key).First(&group).Error
+	}
+
+	if err != nil || group.ID == 1 || group.ID == 3 {
+		groups[key] = nil
+		return nil, fmt.Errorf("group %q not found", key)
+	}
+
+	groups[key] = &group
+	return &group, nil
+}
+
+// sendAccountInvite 向新用户发送设置密码的邀请邮件，链接复用密码重设流程
+func sendAccountInvite(user *model.User) error {
+	secret := util.RandSecureString(32)
+	if err := cache.Set(fmt.Sprintf("user_reset_%d", user.ID), secret, userInviteTTL); err != nil {
+		return err
+	}
+
+	controller, _ := url.Parse("/reset")
+	finalURL := model.GetSiteURL().ResolveReference(controller)
+	queries := finalURL.Query()
+	queries.Add("id", hashid.HashID(user.ID, hashid.UserID))
+	queries.Add("sign", secret)
+	finalURL.RawQuery = queries.Encode()
+
+	title, body := email.NewAccountInviteEmail(user.Nick, user.Email, finalURL.String())
+	return email.Send(user.Email, title, body)
+}
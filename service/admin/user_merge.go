@@ -0,0 +1,73 @@
+package admin
+
+import (
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// UserMergeService 账户合并服务
+type UserMergeService struct {
+	SourceID uint   `json:"source_id" binding:"required"`
+	TargetID uint   `json:"target_id" binding:"required"`
+	Path     string `json:"path" binding:"required,min=1,max=65535"` // 目标账户中存放原账户文件的父目录
+	Name     string `json:"name" binding:"required,min=1,max=255"`   // 原账户根目录合并后的目录名
+}
+
+// Merge 将原账户合并至目标账户，原账户合并后被停用
+func (service *UserMergeService) Merge(c *gin.Context) serializer.Response {
+	if service.SourceID == service.TargetID {
+		return serializer.ParamErr("Cannot merge an account into itself", nil)
+	}
+
+	source, err := model.GetUserByID(service.SourceID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "Source user not found", err)
+	}
+
+	target, err := model.GetActiveUserByID(service.TargetID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "Target user not found", err)
+	}
+
+	if source.ID == 1 {
+		return serializer.Err(serializer.CodeInvalidActionOnDefaultUser, "", nil)
+	}
+
+	// 子账户的可访问目录依赖母账户的目录结构，无法随合并转移
+	if source.IsSubAccount() || target.IsSubAccount() {
+		return serializer.ParamErr("Sub-accounts cannot be merged", nil)
+	}
+	if model.CountSubAccounts(source.ID) > 0 {
+		return serializer.ParamErr("Delete the sub-accounts of the source user before merging", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(&target)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if !fs.ValidateLegalName(c, service.Name) {
+		return serializer.Err(serializer.CodeIllegalObjectName, "", nil)
+	}
+
+	exist, parent := fs.IsPathExist(service.Path)
+	if !exist {
+		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+
+	if err := model.MergeUser(&source, &target, parent, service.Name); err != nil {
+		if err == model.ErrMergeFolderExisted {
+			return serializer.Err(serializer.CodeObjectExist, err.Error(), err)
+		}
+		return serializer.DBErr("Failed to merge accounts", err)
+	}
+
+	model.RecordAudit(c, operator(c), model.AuditAccountMerge, model.AuditTarget("user", source.ID),
+		fmt.Sprintf("%s -> %s", source.Email, target.Email))
+	return serializer.Response{Data: target.Storage}
+}
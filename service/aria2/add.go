@@ -9,6 +9,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/tracing"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
@@ -94,11 +95,12 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 
 	// 创建任务
 	task := &model.Download{
-		Status: common.Ready,
-		Type:   taskType,
-		Dst:    service.Dst,
-		UserID: fs.User.ID,
-		Source: service.URL,
+		Status:      common.Ready,
+		Type:        taskType,
+		Dst:         service.Dst,
+		UserID:      fs.User.ID,
+		Source:      service.URL,
+		TraceParent: tracing.TraceParent(c),
 	}
 
 	// 获取 Aria2 负载均衡器
@@ -124,14 +126,23 @@ func (service *AddURLService) Add(c *gin.Context, fs *filesystem.FileSystem, tas
 	}
 
 	// 创建任务监控
+	task.RequestID = util.RequestIDFromContext(c)
 	monitor.NewMonitor(task, cluster.Default, mq.GlobalMQ)
 
 	return serializer.Response{}
 }
 
+// traceSlaveCall 使从机处理离线下载调用时延续主机请求所在的链路
+func traceSlaveCall(c *gin.Context, service *serializer.SlaveAria2Call) {
+	if service.Task != nil {
+		service.Task.TraceParent = tracing.TraceParent(c)
+	}
+}
+
 // Add 从机创建新的链接离线下载任务
 func Add(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
+	traceSlaveCall(c, service)
 
 	// 创建任务
 	gid, err := caller.(common.Aria2).CreateTask(service.Task, service.GroupOptions)
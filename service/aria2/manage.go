@@ -106,6 +106,7 @@ func (service *SelectFileService) Select(c *gin.Context) serializer.Response {
 // SlaveStatus 从机查询离线任务状态
 func SlaveStatus(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
+	traceSlaveCall(c, service)
 
 	// 查询任务
 	status, err := caller.(common.Aria2).Status(service.Task)
@@ -120,6 +121,7 @@ func SlaveStatus(c *gin.Context, service *serializer.SlaveAria2Call) serializer.
 // SlaveCancel 取消从机离线下载任务
 func SlaveCancel(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
+	traceSlaveCall(c, service)
 
 	// 查询任务
 	err := caller.(common.Aria2).Cancel(service.Task)
@@ -134,6 +136,7 @@ func SlaveCancel(c *gin.Context, service *serializer.SlaveAria2Call) serializer.
 // SlaveSelect 从机选取离线下载任务文件
 func SlaveSelect(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
+	traceSlaveCall(c, service)
 
 	// 查询任务
 	err := caller.(common.Aria2).Select(service.Task, service.Files)
@@ -148,6 +151,7 @@ func SlaveSelect(c *gin.Context, service *serializer.SlaveAria2Call) serializer.
 // SlaveSelect 从机选取离线下载任务文件
 func SlaveDeleteTemp(c *gin.Context, service *serializer.SlaveAria2Call) serializer.Response {
 	caller, _ := c.Get("MasterAria2Instance")
+	traceSlaveCall(c, service)
 
 	// 查询任务
 	err := caller.(common.Aria2).DeleteTempFile(service.Task)
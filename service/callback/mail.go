@@ -0,0 +1,43 @@
+package callback
+
+import (
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// 投递通知请求体的最大长度
+const maxMailWebhookSize = 4 << 20
+
+// MailWebhookService 邮件服务商投递通知回调服务
+type MailWebhookService struct {
+	Provider string `uri:"provider" binding:"required"`
+	Token    string `uri:"token" binding:"required"`
+}
+
+// Handle 校验令牌后处理退信、投诉等投递通知
+func (service *MailWebhookService) Handle(c *gin.Context) serializer.Response {
+	token := model.GetSettingByName("mail_webhook_token")
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(service.Token)) != 1 {
+		return serializer.Err(serializer.CodeCredentialInvalid, "回调令牌无效", nil)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxMailWebhookSize))
+	if err != nil {
+		return serializer.ParamErr("无法读取请求正文", err)
+	}
+
+	if _, err := email.HandleWebhook(service.Provider, body); err != nil {
+		if err == email.ErrUnsupportedWebhook || err == email.ErrInvalidWebhook {
+			return serializer.ParamErr(err.Error(), nil)
+		}
+		return serializer.DBErr("无法更新邮箱状态", err)
+	}
+
+	return serializer.Response{}
+}
@@ -142,6 +142,9 @@ func ProcessCallback(service CallbackProcessService, c *gin.Context) serializer.
 	}
 
 	fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(callbackBody.PicInfo))
+	fs.Use("AfterUpload", filesystem.HookCheckBlockedHash)
+	fs.Use("AfterUpload", filesystem.HookScanVirus)
+	fs.Use("AfterUpload", filesystem.HookSubmitModeration)
 	fs.Use("AfterValidateFailed", filesystem.HookDeleteTempFile)
 	err = fs.Upload(context.Background(), &fileData)
 	if err != nil {
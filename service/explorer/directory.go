@@ -3,6 +3,7 @@ package explorer
 import (
 	"context"
 
+	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/gin-gonic/gin"
@@ -11,6 +12,14 @@ import (
 // DirectoryService 创建新目录服务
 type DirectoryService struct {
 	Path string `uri:"path" json:"path" binding:"required,min=1,max=65535"`
+
+	// 分页列取，PageSize 为 0 时一次列出全部内容
+	PageSize int    `form:"page_size" json:"-" binding:"omitempty,min=1,max=1000"`
+	Cursor   string `form:"cursor" json:"-"`
+	OrderBy  string `form:"order_by" json:"-" binding:"omitempty,eq=name|eq=size|eq=updated_at"`
+	Order    string `form:"order" json:"-" binding:"omitempty,eq=asc|eq=desc"`
+	Type     string `form:"type" json:"-" binding:"omitempty,eq=dir|eq=file"`
+	Keyword  string `form:"keyword" json:"-" binding:"max=255"`
 }
 
 // ListDirectory 列出目录内容
@@ -27,7 +36,20 @@ func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Respon
 	defer cancel()
 
 	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	var (
+		objects []serializer.Object
+		next    *model.ChildrenCursor
+	)
+	if service.PageSize > 0 {
+		query, err := service.childrenQuery()
+		if err != nil {
+			return serializer.ParamErr("Invalid cursor", err)
+		}
+
+		objects, next, err = fs.ListPage(ctx, service.Path, query)
+	} else {
+		objects, err = fs.List(ctx, service.Path, nil)
+	}
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -37,12 +59,45 @@ func (service *DirectoryService) ListDirectory(c *gin.Context) serializer.Respon
 		parentID = fs.DirTarget[0].ID
 	}
 
+	res := serializer.BuildObjectList(parentID, objects, fs.Policy)
+	if next != nil {
+		res.NextCursor = next.String()
+	}
+
 	return serializer.Response{
 		Code: 0,
-		Data: serializer.BuildObjectList(parentID, objects, fs.Policy),
+		Data: res,
 	}
 }
 
+// childrenQuery 根据分页参数构建查询条件，游标须与本次的排序条件一致
+func (service *DirectoryService) childrenQuery() (*model.ChildrenQuery, error) {
+	query := &model.ChildrenQuery{
+		OrderBy: service.OrderBy,
+		Desc:    service.Order == "desc",
+		Type:    service.Type,
+		Keyword: service.Keyword,
+		Limit:   service.PageSize,
+	}
+	if query.OrderBy == "" {
+		query.OrderBy = model.ChildOrderName
+	}
+
+	if service.Cursor != "" {
+		cursor, err := model.ParseChildrenCursor(service.Cursor)
+		if err != nil {
+			return nil, err
+		}
+
+		if cursor.OrderBy != query.OrderBy || cursor.Desc != query.Desc {
+			return nil, model.ErrInvalidChildrenCursor
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
+}
+
 // CreateDirectory 创建目录
 func (service *DirectoryService) CreateDirectory(c *gin.Context) serializer.Response {
 	// 创建文件系统
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"io/ioutil"
-	"net/http"
 	"net/url"
 	"path"
 	"strconv"
@@ -17,6 +16,7 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/gin-gonic/gin"
 )
@@ -156,7 +156,8 @@ func (service *FileAnonymousGetService) Download(ctx context.Context, c *gin.Con
 	}
 
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, service.Name, fs.FileTarget[0].UpdatedAt, rs)
+	c.Header("ETag", fs.FileTarget[0].ETag())
+	response.ServeContent(c.Writer, c.Request, service.Name, fs.FileTarget[0].UpdatedAt, rs)
 
 	return serializer.Response{
 		Code: 0,
@@ -295,7 +296,8 @@ func (service *DownloadService) Download(ctx context.Context, c *gin.Context) se
 	}
 
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
+	c.Header("ETag", fs.FileTarget[0].ETag())
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
 
 	return serializer.Response{
 		Code: 0,
@@ -354,7 +356,8 @@ func (service *FileIDService) PreviewContent(ctx context.Context, c *gin.Context
 		c.Header("Cache-Control", "no-cache")
 	}
 
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, resp.Content)
+	c.Header("ETag", fs.FileTarget[0].ETag())
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, resp.Content)
 
 	return serializer.Response{
 		Code: 0,
@@ -414,6 +417,9 @@ func (service *FileIDService) PutContent(ctx context.Context, c *gin.Context) se
 	fs.Use("AfterUploadCanceled", filesystem.HookCleanFileContent)
 	fs.Use("AfterUploadCanceled", filesystem.HookClearFileSize)
 	fs.Use("AfterUpload", filesystem.GenericAfterUpdate)
+	fs.Use("AfterUpload", filesystem.HookCheckBlockedHash)
+	fs.Use("AfterUpload", filesystem.HookScanVirus)
+	fs.Use("AfterUpload", filesystem.HookSubmitModeration)
 	fs.Use("AfterValidateFailed", filesystem.HookCleanFileContent)
 	fs.Use("AfterValidateFailed", filesystem.HookClearFileSize)
 
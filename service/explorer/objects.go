@@ -48,16 +48,25 @@ type ItemIDService struct {
 
 // ItemCompressService 文件压缩任务服务
 type ItemCompressService struct {
-	Src  ItemIDService `json:"src"`
-	Dst  string        `json:"dst" binding:"required,min=1,max=65535"`
-	Name string        `json:"name" binding:"required,min=1,max=255"`
+	Src      ItemIDService `json:"src"`
+	Dst      string        `json:"dst" binding:"required,min=1,max=65535"`
+	Name     string        `json:"name" binding:"required,min=1,max=255"`
+	Format   string        `json:"format" binding:"omitempty,oneof=zip tar.gz"`
+	Password string        `json:"password" binding:"max=255"`
 }
 
 // ItemDecompressService 文件解压缩任务服务
 type ItemDecompressService struct {
-	Src      string `json:"src"`
-	Dst      string `json:"dst" binding:"required,min=1,max=65535"`
-	Encoding string `json:"encoding"`
+	Src      string   `json:"src"`
+	Dst      string   `json:"dst" binding:"required,min=1,max=65535"`
+	Encoding string   `json:"encoding"`
+	Files    []string `json:"files" binding:"max=1000"`
+}
+
+// ArchiveListService 列出压缩包内容服务
+type ArchiveListService struct {
+	Src      string `form:"src" binding:"required,min=1,max=65535"`
+	Encoding string `form:"encoding"`
 }
 
 // ItemPropertyService 获取对象属性服务
@@ -106,26 +115,54 @@ func (service *ItemDecompressService) CreateDecompressTask(c *gin.Context) seria
 	}
 	defer fs.Recycle()
 
+	if _, err := service.Submit(c, fs); err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Submit 检查压缩包并提交解压缩任务，路径以 fs 的根目录解析，ctx 用于关联请求日志及链路
+func (service *ItemDecompressService) Submit(ctx context.Context, fs *filesystem.FileSystem) (*model.Task, error) {
 	// 检查用户组权限
 	if !fs.User.Group.OptionsSerialized.ArchiveTask {
-		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+		return nil, serializer.NewError(serializer.CodeGroupNotAllowed, "", nil)
 	}
 
 	// 存放目录是否存在
 	if exist, _ := fs.IsPathExist(service.Dst); !exist {
-		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		return nil, serializer.NewError(serializer.CodeParentNotExist, "", nil)
 	}
 
+	// 检查压缩包
+	if _, err := checkArchiveFile(fs, service.Src); err != nil {
+		return nil, err
+	}
+
+	// 创建任务
+	job, err := task.NewDecompressTask(fs.User, service.Src, service.Dst, service.Encoding, service.Files)
+	if err != nil {
+		return nil, serializer.NewError(serializer.CodeCreateTaskError, "", err)
+	}
+	// 任务执行时会修改记录，返回提交前的副本
+	record := *job.Model()
+	task.TaskPoll.Submit(task.Trace(job, ctx))
+
+	return &record, nil
+}
+
+// checkArchiveFile 检查待解压的压缩包是否存在、是否超出尺寸限制以及格式是否受支持
+func checkArchiveFile(fs *filesystem.FileSystem, src string) (*model.File, error) {
 	// 压缩包是否存在
-	exist, file := fs.IsFileExist(service.Src)
+	exist, file := fs.IsFileExist(src)
 	if !exist {
-		return serializer.Err(serializer.CodeFileNotFound, "", nil)
+		return nil, serializer.NewError(serializer.CodeFileNotFound, "", nil)
 	}
 
 	// 文件尺寸限制
 	if fs.User.Group.OptionsSerialized.DecompressSize != 0 && file.Size > fs.User.Group.
 		OptionsSerialized.DecompressSize {
-		return serializer.Err(serializer.CodeFileTooLarge, "", nil)
+		return nil, serializer.NewError(serializer.CodeFileTooLarge, "", nil)
 	}
 
 	// 支持的压缩格式后缀
@@ -140,18 +177,39 @@ func (service *ItemDecompressService) CreateDecompressTask(c *gin.Context) seria
 		}
 	}
 	if !matched {
-		return serializer.Err(serializer.CodeUnsupportedArchiveType, "", nil)
+		return nil, serializer.NewError(serializer.CodeUnsupportedArchiveType, "", nil)
 	}
 
-	// 创建任务
-	job, err := task.NewDecompressTask(fs.User, service.Src, service.Dst, service.Encoding)
+	return file, nil
+}
+
+// ListArchive 列出压缩包内的条目
+func (service *ArchiveListService) ListArchive(c *gin.Context) serializer.Response {
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystemFromContext(c)
 	if err != nil {
-		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
 	}
-	task.TaskPoll.Submit(job)
+	defer fs.Recycle()
 
-	return serializer.Response{}
+	// 检查用户组权限
+	if !fs.User.Group.OptionsSerialized.ArchiveTask {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	// 检查压缩包
+	file, err := checkArchiveFile(fs, service.Src)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+
+	fs.FileTarget = []model.File{*file}
+	entries, err := fs.ListArchive(c.Request.Context(), service.Src, service.Encoding)
+	if err != nil {
+		return serializer.Err(serializer.CodeUnsupportedArchiveType, "Failed to list archive", err)
+	}
 
+	return serializer.Response{Data: entries}
 }
 
 // CreateCompressTask 创建文件压缩任务
@@ -163,73 +221,82 @@ func (service *ItemCompressService) CreateCompressTask(c *gin.Context) serialize
 	}
 	defer fs.Recycle()
 
+	if _, err := service.Submit(c, fs); err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Submit 检查待压缩的文件及目标路径并提交压缩任务，ctx 用于关联请求日志及链路
+func (service *ItemCompressService) Submit(ctx context.Context, fs *filesystem.FileSystem) (*model.Task, error) {
 	// 检查用户组权限
 	if !fs.User.Group.OptionsSerialized.ArchiveTask {
-		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+		return nil, serializer.NewError(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	// 仅 zip 格式支持设置密码
+	if service.Password != "" && service.Format != "" && service.Format != filesystem.ArchiveFormatZip {
+		return nil, serializer.NewError(serializer.CodeParamErr, "Password is only supported by zip format", nil)
 	}
 
 	// 补齐压缩文件扩展名（如果没有）
-	if !strings.HasSuffix(service.Name, ".zip") {
-		service.Name += ".zip"
+	if ext := filesystem.ArchiveExt(service.Format); !strings.HasSuffix(service.Name, ext) {
+		service.Name += ext
 	}
 
 	// 存放目录是否存在，是否重名
 	if exist, _ := fs.IsPathExist(service.Dst); !exist {
-		return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		return nil, serializer.NewError(serializer.CodeParentNotExist, "", nil)
 	}
 	if exist, _ := fs.IsFileExist(path.Join(service.Dst, service.Name)); exist {
-		return serializer.ParamErr("File "+service.Name+" already exist", nil)
+		return nil, serializer.NewError(serializer.CodeParamErr, "File "+service.Name+" already exist", nil)
 	}
 
 	// 检查文件名合法性
 	if !fs.ValidateLegalName(context.Background(), service.Name) {
-		return serializer.Err(serializer.CodeIllegalObjectName, "", nil)
+		return nil, serializer.NewError(serializer.CodeIllegalObjectName, "", nil)
 	}
 	if !fs.ValidateExtension(context.Background(), service.Name) {
-		return serializer.Err(serializer.CodeFileTypeNotAllowed, "", nil)
-	}
-
-	// 递归列出待压缩子目录
-	folders, err := model.GetRecursiveChildFolder(service.Src.Raw().Dirs, fs.User.ID, true)
-	if err != nil {
-		return serializer.DBErr("Failed to list folders", err)
+		return nil, serializer.NewError(serializer.CodeFileTypeNotAllowed, "", nil)
 	}
 
-	// 列出所有待压缩文件
-	files, err := model.GetChildFilesOfFolders(&folders)
+	// 列出待压缩目录
+	folders, err := model.GetFoldersByIDs(service.Src.Raw().Dirs, fs.User.ID)
 	if err != nil {
-		return serializer.DBErr("Failed to list files", err)
+		return nil, serializer.NewError(serializer.CodeDBError, "Failed to list folders", err)
 	}
 
-	// 计算待压缩文件大小
+	// 根据目录的累计大小计算待压缩文件大小
 	var totalSize uint64
-	for i := 0; i < len(files); i++ {
-		totalSize += files[i].Size
+	for i := 0; i < len(folders); i++ {
+		totalSize += folders[i].Size
 	}
 
 	// 文件尺寸限制
 	if fs.User.Group.OptionsSerialized.CompressSize != 0 && totalSize > fs.User.Group.
 		OptionsSerialized.CompressSize {
-		return serializer.Err(serializer.CodeFileTooLarge, "", nil)
+		return nil, serializer.NewError(serializer.CodeFileTooLarge, "", nil)
 	}
 
 	// 按照平均压缩率计算用户空间是否足够
 	compressRatio := 0.4
 	spaceNeeded := uint64(math.Round(float64(totalSize) * compressRatio))
 	if fs.User.GetRemainingCapacity() < spaceNeeded {
-		return serializer.Err(serializer.CodeInsufficientCapacity, "", err)
+		return nil, serializer.NewError(serializer.CodeInsufficientCapacity, "", nil)
 	}
 
 	// 创建任务
 	job, err := task.NewCompressTask(fs.User, path.Join(service.Dst, service.Name), service.Src.Raw().Dirs,
-		service.Src.Raw().Items)
+		service.Src.Raw().Items, service.Format, service.Password)
 	if err != nil {
-		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+		return nil, serializer.NewError(serializer.CodeCreateTaskError, "", err)
 	}
-	task.TaskPoll.Submit(job)
-
-	return serializer.Response{}
+	// 任务执行时会修改记录，返回提交前的副本
+	record := *job.Model()
+	task.TaskPoll.Submit(task.Trace(job, ctx))
 
+	return &record, nil
 }
 
 // Archive 创建归档
@@ -272,13 +339,18 @@ func (service *ItemIDService) Delete(ctx context.Context, c *gin.Context) serial
 	}
 	defer fs.Recycle()
 
-	// 删除对象
+	// 删除前查找对象名称用于审计记录
 	items := service.Raw()
+	folders, _ := model.GetFoldersByIDs(items.Dirs, fs.User.ID)
+	files, _ := model.GetFilesByIDs(items.Items, fs.User.ID)
+
+	// 删除对象
 	err = fs.Delete(ctx, items.Dirs, items.Items, false)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
 
+	model.RecordObjectDelete(c, fs.User.ID, folders, files)
 	return serializer.Response{
 		Code: 0,
 	}
@@ -410,33 +482,10 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 		props.CreatedAt = folder[0].CreatedAt
 		props.UpdatedAt = folder[0].UpdatedAt
 
-		// 如果对象是目录, 先尝试返回缓存结果
-		if cacheRes, ok := cache.Get(fmt.Sprintf("folder_props_%d", res)); ok {
-			res := cacheRes.(serializer.ObjectProps)
-			res.CreatedAt = props.CreatedAt
-			res.UpdatedAt = props.UpdatedAt
-			return serializer.Response{Data: res}
-		}
-
-		// 统计子目录
-		childFolders, err := model.GetRecursiveChildFolder([]uint{folder[0].ID},
-			user.ID, true)
-		if err != nil {
-			return serializer.DBErr("Failed to list child folders", err)
-		}
-		props.ChildFolderNum = len(childFolders) - 1
-
-		// 统计子文件
-		files, err := model.GetChildFilesOfFolders(&childFolders)
-		if err != nil {
-			return serializer.DBErr("Failed to list child files", err)
-		}
-
-		// 统计子文件个数和大小
-		props.ChildFileNum = len(files)
-		for i := 0; i < len(files); i++ {
-			props.Size += files[i].Size
-		}
+		// 目录记录中已有累计统计
+		props.Size = folder[0].Size
+		props.ChildFileNum = int(folder[0].FileNum)
+		props.ChildFolderNum = int(folder[0].FolderNum)
 
 		// 查找父目录
 		if service.TraceRoot {
@@ -446,10 +495,6 @@ func (service *ItemPropertyService) GetProperty(ctx context.Context, c *gin.Cont
 
 			props.Path = folder[0].Position
 		}
-
-		// 如果列取对象是目录，则缓存结果
-		cache.Set(fmt.Sprintf("folder_props_%d", res), props,
-			model.GetIntSetting("folder_props_timeout", 300))
 	}
 
 	return serializer.Response{
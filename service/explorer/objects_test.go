@@ -0,0 +1,83 @@
+package explorer
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func newLockedUserContext() *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("user", &model.User{
+		Model:  gorm.Model{ID: 1},
+		Policy: model.Policy{Type: "mock"},
+	})
+	return c
+}
+
+func TestItemService_Locked(t *testing.T) {
+	asserts := assert.New(t)
+	lockRows := sqlmock.NewRows([]string{"id", "root", "zero_depth"}).AddRow(1, "/a/b.txt", true)
+
+	// 删除被 WebDAV 客户端锁定的文件
+	{
+		c := newLockedUserContext()
+		service := &ItemIDService{Source: &ItemService{Items: []uint{2}}}
+		// 查找对象名称用于审计记录
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(2, "b.txt", 3))
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).WillReturnRows(lockRows)
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "folder_id"}).AddRow(2, "b.txt", 3))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "a", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(1, "/", nil, 1))
+		res := service.Delete(context.Background(), c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(serializer.CodeLocked, res.Code)
+	}
+
+	// 重命名包含被锁定文件的目录
+	{
+		c := newLockedUserContext()
+		service := &ItemRenameService{
+			Src:     ItemIDService{Source: &ItemService{Dirs: []uint{3}}},
+			NewName: "c",
+		}
+		mock.ExpectQuery("SELECT(.+)file_locks(.+)").WithArgs(1, sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "root", "zero_depth"}).AddRow(1, "/a/b.txt", true))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(3, "a", 1, 1))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id", "owner_id"}).AddRow(1, "/", nil, 1))
+		res := service.Rename(context.Background(), c)
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(serializer.CodeLocked, res.Code)
+	}
+}
@@ -0,0 +1,95 @@
+package explorer
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OneTimeLinkCreateService 创建一次性下载链接服务
+type OneTimeLinkCreateService struct {
+	Expire int `json:"expire" binding:"min=0"`
+}
+
+// OneTimeDownloadService 一次性下载链接下载服务
+type OneTimeDownloadService struct {
+	Token string `uri:"token" binding:"required"`
+}
+
+// Create 为文件创建一次性下载链接，Expire 为 0 时链接不会过期
+func (service *OneTimeLinkCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	fileID, _ := c.Get("object_id")
+	files, err := model.GetFilesByIDs([]uint{fileID.(uint)}, user.ID)
+	if err != nil || len(files) == 0 {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+
+	token := &model.DownloadToken{
+		Token:  util.RandSecureString(32),
+		UserID: user.ID,
+		FileID: files[0].ID,
+	}
+	if service.Expire > 0 {
+		expires := time.Now().Add(time.Duration(service.Expire) * time.Second)
+		token.Expires = &expires
+	}
+
+	if err := token.Create(); err != nil {
+		return serializer.DBErr("Failed to create download token", err)
+	}
+
+	downloadPath, _ := url.Parse("/api/v3/file/once/" + token.Token)
+	return serializer.Response{
+		Data: model.GetSiteURL().ResolveReference(downloadPath).String(),
+	}
+}
+
+// Download 通过一次性链接下载文件，链接在被使用后即失效
+func (service *OneTimeDownloadService) Download(ctx context.Context, c *gin.Context) serializer.Response {
+	token, err := model.GetDownloadToken(service.Token)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Download link is invalid or has been used", nil)
+	}
+
+	// 占用凭证，并发请求中只有一个可以继续
+	if claimed, err := token.Claim(); err != nil || !claimed {
+		return serializer.Err(serializer.CodeNotFound, "Download link is invalid or has been used", err)
+	}
+
+	user, err := model.GetActiveUserByID(token.UserID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Download link is invalid or has been used", err)
+	}
+
+	fs, err := filesystem.NewFileSystem(&user)
+	if err != nil {
+		token.Release()
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
+	rs, err := fs.GetDownloadContent(ctx, token.FileID)
+	if err != nil {
+		token.Release()
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer rs.Close()
+
+	// 设置文件名
+	c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(fs.FileTarget[0].Name)+"\"")
+
+	// 发送文件
+	c.Header("ETag", fs.FileTarget[0].ETag())
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, fs.FileTarget[0].UpdatedAt, rs)
+
+	return serializer.Response{}
+}
@@ -9,7 +9,9 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/driver/local"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/task/slavetask"
@@ -87,7 +89,7 @@ func (service *SlaveDownloadService) ServeFile(ctx context.Context, c *gin.Conte
 	}
 
 	// 发送文件
-	http.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, time.Now(), rs)
+	response.ServeContent(c.Writer, c.Request, fs.FileTarget[0].Name, time.Now(), rs)
 
 	return serializer.Response{}
 }
@@ -139,6 +141,11 @@ func (service *SlaveFileService) Thumb(ctx context.Context, c *gin.Context) seri
 		return serializer.Err(serializer.CodeNotSet, "Failed to get thumb", err)
 	}
 
+	// 缩略图生成完成前返回的占位图不可缓存
+	if resp.Placeholder {
+		c.Header("Cache-Control", "no-store")
+	}
+
 	defer resp.Content.Close()
 	http.ServeContent(c.Writer, c.Request, "thumb.png", time.Now(), resp.Content)
 
@@ -189,3 +196,19 @@ func (service *SlaveCreateUploadSessionService) Create(ctx context.Context, c *g
 
 	return serializer.Response{}
 }
+
+// SlaveCapacityService 从机容量查询服务
+type SlaveCapacityService struct {
+	DirNameRule string `json:"dir_name_rule" binding:"max=65535"`
+}
+
+// Capacity 获取从机存储目录所在磁盘的总容量及剩余容量
+func (service *SlaveCapacityService) Capacity(c *gin.Context) serializer.Response {
+	res, err := local.DiskCapacity(local.StorageRoot(service.DirNameRule))
+	if err != nil {
+		return serializer.Err(serializer.CodeIOFailed, "Cannot get disk capacity", err)
+	}
+
+	capacity, _ := json.Marshal(res)
+	return serializer.Response{Data: string(capacity)}
+}
@@ -0,0 +1,449 @@
+package explorer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"github.com/gin-gonic/gin"
+)
+
+// fileChangeActions 变更类型在接口中的名称
+var fileChangeActions = map[int]string{
+	model.FileChangeCreated:  "created",
+	model.FileChangeModified: "modified",
+	model.FileChangeMoved:    "moved",
+	model.FileChangeDeleted:  "deleted",
+}
+
+// SyncChangesService 列出游标之后的文件变更
+type SyncChangesService struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=1000"`
+}
+
+// SyncDownloadService 按文件 ID 条件下载
+type SyncDownloadService struct {
+}
+
+// SyncUploadService 按路径条件上传，文件已存在时覆盖
+type SyncUploadService struct {
+	Path string `form:"path" binding:"required,min=1,max=65535"`
+}
+
+// syncCursor 由最后一条已拉取的变更记录 ID 及游标生成时间组成，后者用于判断游标是否已超出变更记录的保留期限
+func syncCursor(id uint, t time.Time) string {
+	return fmt.Sprintf("%d.%d", id, t.Unix())
+}
+
+// parseSyncCursor 解析游标
+func parseSyncCursor(cursor string) (uint, time.Time, error) {
+	parts := strings.SplitN(cursor, ".", 2)
+	if len(parts) != 2 {
+		return 0, time.Time{}, errors.New("malformed cursor")
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	ts, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	return uint(id), time.Unix(ts, 0), nil
+}
+
+// List 列出游标之后的变更。未提供游标或游标已失效时返回最新游标，客户端应先全量比对目录树，再从此游标开始增量同步
+func (service *SyncChangesService) List(c *gin.Context, user *model.User) serializer.Response {
+	limit := service.Limit
+	if limit == 0 {
+		limit = 200
+	}
+
+	var (
+		cursorID uint
+		reset    bool
+	)
+	if service.Cursor != "" {
+		id, cursorTime, err := parseSyncCursor(service.Cursor)
+		if err != nil {
+			return serializer.ParamErr("Invalid cursor", err)
+		}
+		cursorID = id
+
+		days := model.GetIntSetting("file_change_retention_days", 30)
+		reset = days > 0 && cursorTime.Before(time.Now().AddDate(0, 0, -days))
+	}
+
+	// 从最新的变更之后开始
+	if service.Cursor == "" || reset {
+		latest, err := model.GetLatestFileChangeID(user.ID)
+		if err != nil {
+			return serializer.DBErr("Failed to get latest file change", err)
+		}
+
+		return serializer.Response{Data: serializer.FileChangeList{
+			Cursor:  syncCursor(latest, time.Now()),
+			Reset:   reset,
+			Changes: []serializer.FileChange{},
+		}}
+	}
+
+	changes, err := model.ListFileChanges(user.ID, cursorID, limit+1)
+	if err != nil {
+		return serializer.DBErr("Failed to list file changes", err)
+	}
+
+	// 仍有未拉取的变更时，以最后一条变更的时间生成游标，避免剩余的变更在客户端拉取前被清理
+	res := serializer.FileChangeList{}
+	next := time.Now()
+	if len(changes) > limit {
+		changes = changes[:limit]
+		res.HasMore = true
+		next = changes[len(changes)-1].CreatedAt
+	}
+	if len(changes) > 0 {
+		cursorID = changes[len(changes)-1].ID
+	}
+	res.Cursor = syncCursor(cursorID, next)
+
+	res.Changes, err = buildFileChanges(user.ID, changes)
+	if err != nil {
+		return serializer.DBErr("Failed to load changed objects", err)
+	}
+
+	return serializer.Response{Data: res}
+}
+
+// fileChangeKey 变更的对象
+type fileChangeKey struct {
+	isDir bool
+	id    uint
+}
+
+// mergeFileChange 合并同一对象的两次变更，删除优先，新建次之
+func mergeFileChange(prev, next int) int {
+	switch {
+	case next == model.FileChangeDeleted || prev == model.FileChangeDeleted:
+		return next
+	case prev == model.FileChangeCreated || next == model.FileChangeCreated:
+		return model.FileChangeCreated
+	case prev == model.FileChangeMoved || next == model.FileChangeMoved:
+		return model.FileChangeMoved
+	}
+	return model.FileChangeModified
+}
+
+// buildFileChanges 按对象合并变更记录，并附带对象的最新状态。
+// 变更类型仅供参考，客户端应以哈希判断文件内容是否变化
+func buildFileChanges(uid uint, changes []model.FileChange) ([]serializer.FileChange, error) {
+	var (
+		keys    []fileChangeKey
+		actions = make(map[fileChangeKey]int, len(changes))
+		dirIDs  []uint
+		fileIDs []uint
+	)
+	for _, change := range changes {
+		key := fileChangeKey{isDir: change.IsDir, id: change.ObjectID}
+		if prev, ok := actions[key]; ok {
+			actions[key] = mergeFileChange(prev, change.Action)
+			continue
+		}
+
+		keys = append(keys, key)
+		actions[key] = change.Action
+	}
+
+	for _, key := range keys {
+		if actions[key] == model.FileChangeDeleted {
+			continue
+		}
+		if key.isDir {
+			dirIDs = append(dirIDs, key.id)
+		} else {
+			fileIDs = append(fileIDs, key.id)
+		}
+	}
+
+	resolver := &folderPathResolver{uid: uid, folders: make(map[uint]*model.Folder), paths: make(map[uint]string)}
+	files := make(map[uint]*model.File, len(fileIDs))
+	if len(dirIDs) > 0 {
+		folders, err := model.GetFoldersByIDs(dirIDs, uid)
+		if err != nil {
+			return nil, err
+		}
+		for i := range folders {
+			resolver.folders[folders[i].ID] = &folders[i]
+		}
+	}
+	if len(fileIDs) > 0 {
+		list, err := model.GetFilesByIDs(fileIDs, uid)
+		if err != nil {
+			return nil, err
+		}
+		for i := range list {
+			files[list[i].ID] = &list[i]
+		}
+	}
+
+	res := make([]serializer.FileChange, 0, len(keys))
+	for _, key := range keys {
+		change := serializer.FileChange{Action: fileChangeActions[actions[key]], Type: "file"}
+		if key.isDir {
+			change.Type = "dir"
+			change.ID = hashid.HashID(key.id, hashid.FolderID)
+		} else {
+			change.ID = hashid.HashID(key.id, hashid.FileID)
+		}
+
+		if actions[key] != model.FileChangeDeleted {
+			// 根目录及上传中的文件不作为变更返回
+			if key.isDir {
+				if folder, ok := resolver.folder(key.id); ok && folder.ParentID == nil {
+					continue
+				}
+			} else if file, ok := files[key.id]; ok && file.UploadSessionID != nil {
+				continue
+			}
+
+			// 对象已不存在时，其删除记录可能尚未拉取或记录失败，此处一并视为删除
+			var ok bool
+			if key.isDir {
+				ok = resolver.fill(&change, key.id)
+			} else {
+				ok = resolver.fillFile(&change, files[key.id])
+			}
+			if !ok {
+				change = serializer.FileChange{Action: fileChangeActions[model.FileChangeDeleted], Type: change.Type, ID: change.ID}
+			}
+		}
+
+		res = append(res, change)
+	}
+
+	return res, nil
+}
+
+// folderPathResolver 查找目录的完整路径，已查找过的目录会被缓存
+type folderPathResolver struct {
+	uid     uint
+	folders map[uint]*model.Folder
+	paths   map[uint]string
+}
+
+// folder 获取目录
+func (r *folderPathResolver) folder(id uint) (*model.Folder, bool) {
+	if folder, ok := r.folders[id]; ok {
+		return folder, true
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{id}, r.uid)
+	if err != nil || len(folders) == 0 {
+		return nil, false
+	}
+	r.folders[id] = &folders[0]
+	return &folders[0], true
+}
+
+// path 获取目录的完整路径
+func (r *folderPathResolver) path(id uint) (string, bool) {
+	if p, ok := r.paths[id]; ok {
+		return p, true
+	}
+
+	folder, ok := r.folder(id)
+	if !ok {
+		return "", false
+	}
+
+	p := "/"
+	if folder.ParentID != nil {
+		parent, ok := r.path(*folder.ParentID)
+		if !ok {
+			return "", false
+		}
+		p = path.Join(parent, folder.Name)
+	}
+
+	r.paths[id] = p
+	return p, true
+}
+
+// fill 填入目录的最新状态，目录不存在时返回假
+func (r *folderPathResolver) fill(change *serializer.FileChange, id uint) bool {
+	folder, ok := r.folder(id)
+	if !ok || folder.ParentID == nil {
+		return false
+	}
+
+	parent, ok := r.path(*folder.ParentID)
+	if !ok {
+		return false
+	}
+
+	change.Parent = hashid.HashID(*folder.ParentID, hashid.FolderID)
+	change.Name = folder.Name
+	change.Path = parent
+	change.Date = &folder.UpdatedAt
+	return true
+}
+
+// fillFile 填入文件的最新状态，文件不存在时返回假
+func (r *folderPathResolver) fillFile(change *serializer.FileChange, file *model.File) bool {
+	if file == nil {
+		return false
+	}
+
+	parent, ok := r.path(file.FolderID)
+	if !ok {
+		return false
+	}
+
+	change.Parent = hashid.HashID(file.FolderID, hashid.FolderID)
+	change.Name = file.Name
+	change.Path = parent
+	change.Size = file.Size
+	change.Hash = file.Hash()
+	change.Date = &file.UpdatedAt
+	return true
+}
+
+// etagMatches 判断条件请求头中是否包含给定文件的实体标签，文件不存在时任何标签均不匹配。
+// 未记录内容摘要的文件只有弱实体标签，同步接口以文件版本判断是否被修改，因此均使用弱比较
+func etagMatches(header string, file *model.File) bool {
+	if file == nil {
+		return false
+	}
+
+	etag := strings.TrimPrefix(file.ETag(), "W/")
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// preconditionFailed 检查 If-Match 条件，文件不存在或版本不符时返回真
+func preconditionFailed(c *gin.Context, file *model.File) bool {
+	header := c.GetHeader("If-Match")
+	return header != "" && !etagMatches(header, file)
+}
+
+// noneMatched 检查 If-None-Match 条件，文件存在且版本相符时返回真
+func noneMatched(c *gin.Context, file *model.File) bool {
+	header := c.GetHeader("If-None-Match")
+	return header != "" && etagMatches(header, file)
+}
+
+// Download 下载文件，支持以 If-Match、If-None-Match 请求头按版本条件下载
+func (service *SyncDownloadService) Download(ctx context.Context, c *gin.Context, user *model.User) serializer.Response {
+	objectID, _ := c.Get("object_id")
+	files, err := model.GetFilesByIDs([]uint{objectID.(uint)}, user.ID)
+	if err != nil || len(files) == 0 || files[0].UploadSessionID != nil {
+		return serializer.Err(serializer.CodeFileNotFound, "", err)
+	}
+	file := &files[0]
+
+	c.Header("ETag", file.ETag())
+	if preconditionFailed(c, file) {
+		return serializer.Err(serializer.CodePreconditionFailed, "File has been changed", nil)
+	}
+	if noneMatched(c, file) {
+		c.Status(http.StatusNotModified)
+		return serializer.Response{}
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fs.FileTarget = []model.File{*file}
+	ctx = context.WithValue(ctx, fsctx.GinCtx, c)
+	rs, err := fs.GetDownloadContent(ctx, 0)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer rs.Close()
+
+	response.ServeContent(c.Writer, c.Request, file.Name, file.UpdatedAt, rs)
+	return serializer.Response{}
+}
+
+// Upload 以请求体写入文件，支持以 If-Match 指定被覆盖文件的版本，或以 If-None-Match: * 要求文件不存在
+func (service *SyncUploadService) Upload(c *gin.Context, user *model.User) serializer.Response {
+	p := path.Clean("/" + service.Path)
+	if p == "/" {
+		return serializer.ParamErr("Invalid path", nil)
+	}
+
+	sess := vfs.NewSession(user)
+	info, err := sess.Stat(p)
+	if err != nil && err != vfs.ErrNotExist {
+		return syncUploadErr(err)
+	}
+
+	var origin *model.File
+	if info != nil {
+		if info.IsDir() {
+			return serializer.Err(serializer.CodeObjectExist, "A folder with the same name already exists", nil)
+		}
+		origin = info.(*model.File)
+	}
+
+	if preconditionFailed(c, origin) || noneMatched(c, origin) {
+		return serializer.Err(serializer.CodePreconditionFailed, "File has been changed", nil)
+	}
+
+	if err := sess.Store(p, c.Request.Body); err != nil {
+		return syncUploadErr(err)
+	}
+
+	info, err = sess.Stat(p)
+	if err != nil {
+		return syncUploadErr(err)
+	}
+
+	file := info.(*model.File)
+	c.Header("ETag", file.ETag())
+	return serializer.Response{Data: serializer.SyncFile{
+		ID:   hashid.HashID(file.ID, hashid.FileID),
+		Path: path.Dir(p),
+		Size: file.Size,
+		Hash: file.Hash(),
+		Date: file.UpdatedAt,
+	}}
+}
+
+// syncUploadErr 将按路径写入文件时的错误转换为响应
+func syncUploadErr(err error) serializer.Response {
+	switch err {
+	case vfs.ErrNotExist:
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	case vfs.ErrExist:
+		return serializer.Err(serializer.CodeObjectExist, "", err)
+	case vfs.ErrPermissionDenied:
+		return serializer.Err(serializer.CodeNoPermissionErr, "", err)
+	case vfs.ErrQuotaExceeded:
+		return serializer.Err(serializer.CodeInsufficientCapacity, "", err)
+	}
+	return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+}
@@ -26,6 +26,8 @@ type CreateUploadSessionService struct {
 	Name         string `json:"name" binding:"required"`
 	PolicyID     string `json:"policy_id" binding:"required"`
 	LastModified int64  `json:"last_modified"`
+	// Parallel 客户端希望同时上传的分片数，实际允许的数量在上传凭证中返回
+	Parallel int `json:"parallel" binding:"min=0"`
 }
 
 // Create 创建新的上传会话
@@ -56,6 +58,7 @@ func (service *CreateUploadSessionService) Create(ctx context.Context, c *gin.Co
 		lastModified := time.UnixMilli(service.LastModified)
 		file.LastModified = &lastModified
 	}
+	ctx = context.WithValue(ctx, fsctx.ChunkConcurrencyCtx, service.Parallel)
 	credential, err := fs.CreateUploadSession(ctx, file)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
@@ -107,18 +110,21 @@ func (service *UploadService) LocalUpload(ctx context.Context, c *gin.Context) s
 		return serializer.Err(serializer.CodePolicyNotExist, "", err)
 	}
 
-	expectedSizeStart := file.Size
-	actualSizeStart := uint64(service.Index) * uploadSession.Policy.OptionsSerialized.ChunkSize
-	if uploadSession.Policy.OptionsSerialized.ChunkSize == 0 && service.Index > 0 {
-		return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk index cannot be greater than 0", nil)
-	}
+	// 并行上传的分片可乱序到达，由 processChunkUpload 校验分片序号
+	if uploadSession.Parallel <= 1 {
+		expectedSizeStart := file.Size
+		actualSizeStart := uint64(service.Index) * uploadSession.Policy.OptionsSerialized.ChunkSize
+		if uploadSession.Policy.OptionsSerialized.ChunkSize == 0 && service.Index > 0 {
+			return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk index cannot be greater than 0", nil)
+		}
 
-	if expectedSizeStart < actualSizeStart {
-		return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk must be uploaded in order", nil)
-	}
+		if expectedSizeStart < actualSizeStart {
+			return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk must be uploaded in order", nil)
+		}
 
-	if expectedSizeStart > actualSizeStart {
-		util.Log().Info("Trying to overwrite chunk[%d] Start=%d", service.Index, actualSizeStart)
+		if expectedSizeStart > actualSizeStart {
+			util.Log().Info("Trying to overwrite chunk[%d] Start=%d", service.Index, actualSizeStart)
+		}
 	}
 
 	return processChunkUpload(ctx, c, fs, &uploadSession, service.Index, file, fsctx.Append)
@@ -151,6 +157,12 @@ func (service *UploadService) SlaveUpload(ctx context.Context, c *gin.Context) s
 }
 
 func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.FileSystem, session *serializer.UploadSession, index int, file *model.File, mode fsctx.WriteMode) serializer.Response {
+	// 并行上传时分片可乱序到达，按偏移写入，由最后接收的分片完成上传
+	parallel := session.Parallel > 1
+	if parallel && (index < 0 || index >= session.ChunkNum()) {
+		return serializer.Err(serializer.CodeInvalidChunkIndex, "Chunk index out of range", nil)
+	}
+
 	// 取得并校验文件大小是否符合分片要求
 	chunkSize := session.Policy.OptionsSerialized.ChunkSize
 	isLastChunk := session.Policy.OptionsSerialized.ChunkSize == 0 || uint64(index+1)*chunkSize >= session.Size
@@ -173,6 +185,11 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 		mode |= fsctx.Overwrite
 	}
 
+	// 并行上传的各分片均可能先于其他分片写入
+	if parallel {
+		mode = fsctx.Offset | fsctx.Overwrite
+	}
+
 	fileData := fsctx.FileStream{
 		MIMEType:     c.Request.Header.Get("Content-Type"),
 		File:         c.Request.Body,
@@ -187,22 +204,24 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 	}
 
 	// 给文件系统分配钩子
-	fs.Use("AfterUploadCanceled", filesystem.HookTruncateFileTo(fileData.AppendStart))
-	fs.Use("AfterValidateFailed", filesystem.HookTruncateFileTo(fileData.AppendStart))
-
-	if file != nil {
-		fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
-		fs.Use("AfterUpload", filesystem.HookChunkUploaded)
-		fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
-		if isLastChunk {
-			fs.Use("AfterUpload", filesystem.HookPopPlaceholderToFile(""))
-			fs.Use("AfterUpload", filesystem.HookGenerateThumb)
-			fs.Use("AfterUpload", filesystem.HookDeleteUploadSession(session.Key))
+	if parallel {
+		// 失败的分片由客户端重试覆盖，不截断文件以免破坏其他分片
+		if file != nil {
+			fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
 		}
+		fs.Use("AfterUpload", filesystem.HookChunkReceived(session, index))
+		useUploadFinishedHooks(fs, "AfterChunksAssembled", session, file)
 	} else {
+		fs.Use("AfterUploadCanceled", filesystem.HookTruncateFileTo(fileData.AppendStart))
+		fs.Use("AfterValidateFailed", filesystem.HookTruncateFileTo(fileData.AppendStart))
+
+		if file != nil {
+			fs.Use("BeforeUpload", filesystem.HookValidateCapacity)
+			fs.Use("AfterUpload", filesystem.HookChunkUploaded)
+			fs.Use("AfterValidateFailed", filesystem.HookChunkUploadFailed)
+		}
 		if isLastChunk {
-			fs.Use("AfterUpload", filesystem.SlaveAfterUpload(session))
-			fs.Use("AfterUpload", filesystem.HookDeleteUploadSession(session.Key))
+			useUploadFinishedHooks(fs, "AfterUpload", session, file)
 		}
 	}
 
@@ -216,6 +235,20 @@ func processChunkUpload(ctx context.Context, c *gin.Context, fs *filesystem.File
 	return serializer.Response{}
 }
 
+// useUploadFinishedHooks 注入全部分片上传完成后的钩子，file 为空时为从机端上传
+func useUploadFinishedHooks(fs *filesystem.FileSystem, name string, session *serializer.UploadSession, file *model.File) {
+	if file != nil {
+		fs.Use(name, filesystem.HookPopPlaceholderToFile(""))
+		fs.Use(name, filesystem.HookCheckBlockedHash)
+		fs.Use(name, filesystem.HookGenerateThumb)
+		fs.Use(name, filesystem.HookScanVirus)
+		fs.Use(name, filesystem.HookSubmitModeration)
+	} else {
+		fs.Use(name, filesystem.SlaveAfterUpload(session))
+	}
+	fs.Use(name, filesystem.HookDeleteUploadSession(session.Key))
+}
+
 // UploadSessionService 上传会话服务
 type UploadSessionService struct {
 	ID string `uri:"sessionId" binding:"required"`
@@ -0,0 +1,24 @@
+package graphql
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// QueryService GraphQL 查询服务
+type QueryService struct {
+	Query         string                 `json:"query" binding:"required,max=65535"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Execute 执行查询，返回标准的 GraphQL 响应
+func (service *QueryService) Execute(c *gin.Context) *graphql.Result {
+	return graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  service.Query,
+		VariableValues: service.Variables,
+		OperationName:  service.OperationName,
+		Context:        c,
+	})
+}
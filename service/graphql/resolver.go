@@ -0,0 +1,272 @@
+package graphql
+
+import (
+	"net/url"
+	"sync"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/aria2"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/cloudreve/Cloudreve/v3/service/user"
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+)
+
+// responseError 将服务返回的错误响应转换为 GraphQL 错误，错误码放在扩展字段中
+type responseError struct {
+	res serializer.Response
+}
+
+func (err responseError) Error() string {
+	if err.res.Msg == "" {
+		return err.res.Error
+	}
+	return err.res.Msg
+}
+
+// Extensions 返回附加在错误上的错误码
+func (err responseError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": err.res.Code}
+}
+
+// responseData 获取服务响应中的数据
+func responseData(res serializer.Response) (interface{}, error) {
+	if res.Code != 0 {
+		return nil, responseError{res}
+	}
+	return res.Data, nil
+}
+
+// ginContext 获取解析所在的请求上下文
+func ginContext(p graphql.ResolveParams) *gin.Context {
+	return p.Context.(*gin.Context)
+}
+
+// currentUser 获取当前用户
+func currentUser(p graphql.ResolveParams) *model.User {
+	userCtx, _ := ginContext(p).Get("user")
+	return userCtx.(*model.User)
+}
+
+// pageArgs 获取并检查分页参数
+func pageArgs(p graphql.ResolveParams, maxPageSize int) (int, int, error) {
+	page, _ := p.Args["page"].(int)
+	pageSize, _ := p.Args["pageSize"].(int)
+	if page < 1 || pageSize < 1 || pageSize > maxPageSize {
+		return 0, 0, responseError{serializer.ParamErr("Invalid page or page size", nil)}
+	}
+	return page, pageSize, nil
+}
+
+// resolveMe 当前用户
+func resolveMe(p graphql.ResolveParams) (interface{}, error) {
+	return serializer.BuildUser(*currentUser(p)), nil
+}
+
+// resolveStorage 当前用户的存储信息
+func resolveStorage(p graphql.ResolveParams) (interface{}, error) {
+	return responseData(serializer.BuildUserStorageResponse(*currentUser(p)))
+}
+
+// directory 目录内容
+type directory struct {
+	Parent  string
+	Objects []*object
+	Policy  *serializer.PolicySummary
+}
+
+// resolveDirectory 列出目录内容
+func resolveDirectory(p graphql.ResolveParams) (interface{}, error) {
+	service := explorer.DirectoryService{Path: p.Args["path"].(string)}
+	data, err := responseData(service.ListDirectory(ginContext(p)))
+	if err != nil {
+		return nil, err
+	}
+
+	list := data.(serializer.ObjectList)
+	res := &directory{
+		Parent:  list.Parent,
+		Objects: make([]*object, 0, len(list.Objects)),
+		Policy:  list.Policy,
+	}
+
+	// 同一目录下的对象共用分享索引，首次查询分享状态时一次性加载
+	index := &shareIndex{user: currentUser(p), objects: list.Objects}
+	for i := range list.Objects {
+		res.Objects = append(res.Objects, &object{Object: list.Objects[i], shares: index})
+	}
+
+	return res, nil
+}
+
+// object 目录下的文件或目录
+type object struct {
+	serializer.Object
+	shares *shareIndex
+}
+
+// Resolve 解析对象的字段，缩略图及分享状态需额外处理
+func (o *object) Resolve(p graphql.ResolveParams) (interface{}, error) {
+	switch p.Info.FieldName {
+	case "thumbnail":
+		// 仅已获取图像信息的文件可生成缩略图
+		if o.Type != "file" || o.Pic == "" {
+			return nil, nil
+		}
+		thumb := &url.URL{Path: "/api/v3/file/thumb/" + o.ID}
+		return model.GetSiteURL().ResolveReference(thumb).String(), nil
+	case "share":
+		return o.shares.get(o.Type == "dir", o.ID)
+	}
+
+	p.Source = o.Object
+	return graphql.DefaultResolveFn(p)
+}
+
+// shareKey 分享源对象的标识
+type shareKey struct {
+	isDir bool
+	id    string
+}
+
+// shareIndex 目录下各对象的分享
+type shareIndex struct {
+	user    *model.User
+	objects []serializer.Object
+
+	once   sync.Once
+	shares map[shareKey]*model.Share
+	err    error
+}
+
+// load 加载目录下所有对象的分享，同一对象有多个分享时保留最新的一个
+func (index *shareIndex) load() {
+	var dirs, files []uint
+	for _, obj := range index.objects {
+		if obj.Type == "dir" {
+			if id, err := hashid.DecodeHashID(obj.ID, hashid.FolderID); err == nil {
+				dirs = append(dirs, id)
+			}
+		} else if id, err := hashid.DecodeHashID(obj.ID, hashid.FileID); err == nil {
+			files = append(files, id)
+		}
+	}
+
+	shares, err := model.ListSharesBySources(index.user.ID, dirs, files)
+	if err != nil {
+		index.err = responseError{serializer.DBErr("Failed to list shares", err)}
+		return
+	}
+
+	index.shares = make(map[shareKey]*model.Share, len(shares))
+	for i := range shares {
+		key := shareKey{isDir: true, id: hashid.HashID(shares[i].SourceID, hashid.FolderID)}
+		if !shares[i].IsDir {
+			key = shareKey{id: hashid.HashID(shares[i].SourceID, hashid.FileID)}
+		}
+		if _, ok := index.shares[key]; !ok {
+			index.shares[key] = &shares[i]
+		}
+	}
+}
+
+// get 获取对象的分享，未分享时返回 nil
+func (index *shareIndex) get(isDir bool, id string) (interface{}, error) {
+	index.once.Do(index.load)
+	if index.err != nil {
+		return nil, index.err
+	}
+
+	if share, ok := index.shares[shareKey{isDir: isDir, id: id}]; ok {
+		return share, nil
+	}
+	return nil, nil
+}
+
+// resolveShares 列出我的分享
+func resolveShares(p graphql.ResolveParams) (interface{}, error) {
+	page, pageSize, err := pageArgs(p, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	order := p.Args["orderBy"].(string) + " " + p.Args["order"].(string)
+	shares, total := model.ListShares(currentUser(p).ID, page, pageSize, order, false)
+	items := make([]*model.Share, 0, len(shares))
+	for i := range shares {
+		items = append(items, &shares[i])
+	}
+
+	return map[string]interface{}{
+		"total": total,
+		"items": items,
+	}, nil
+}
+
+// resolveShareKey 分享的标识
+func resolveShareKey(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*model.Share).HashID(), nil
+}
+
+// resolveShareAvailable 分享是否可用
+func resolveShareAvailable(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*model.Share).IsAvailable(), nil
+}
+
+// resolveShareCreatedAt 分享的创建时间
+func resolveShareCreatedAt(p graphql.ResolveParams) (interface{}, error) {
+	return p.Source.(*model.Share).CreatedAt, nil
+}
+
+// resolveTasks 列出任务队列
+func resolveTasks(p graphql.ResolveParams) (interface{}, error) {
+	page, pageSize, err := pageArgs(p, 100)
+	if err != nil {
+		return nil, err
+	}
+
+	service := user.SettingListService{Page: page, PageSize: pageSize}
+	if taskType, ok := p.Args["type"].(int); ok {
+		service.Type = &taskType
+	}
+	if status, ok := p.Args["status"].(int); ok {
+		service.Status = &status
+	}
+
+	return responseData(service.ListTasks(ginContext(p), currentUser(p)))
+}
+
+// resolveDownloading 列出正在进行的离线下载
+func resolveDownloading(p graphql.ResolveParams) (interface{}, error) {
+	page, _ := p.Args["page"].(int)
+	if page < 0 {
+		return nil, responseError{serializer.ParamErr("Invalid page", nil)}
+	}
+
+	service := aria2.DownloadListService{Page: uint(page)}
+	return responseData(service.Downloading(ginContext(p), currentUser(p)))
+}
+
+// resolveFinishedDownloads 列出已结束的离线下载
+func resolveFinishedDownloads(p graphql.ResolveParams) (interface{}, error) {
+	page, _ := p.Args["page"].(int)
+	if page < 1 {
+		return nil, responseError{serializer.ParamErr("Invalid page", nil)}
+	}
+
+	service := aria2.DownloadListService{Page: uint(page)}
+	return responseData(service.Finished(ginContext(p), currentUser(p)))
+}
+
+// resolveDownloadGID 离线下载任务的 GID
+func resolveDownloadGID(p graphql.ResolveParams) (interface{}, error) {
+	switch download := p.Source.(type) {
+	case serializer.DownloadListResponse:
+		return download.Info.Gid, nil
+	case serializer.FinishedListResponse:
+		return download.GID, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,235 @@
+package graphql
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// 文件大小等可能超过 32 位整数范围的字段均使用 Float 类型
+
+var groupType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Group",
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var storageType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Storage",
+	Fields: graphql.Fields{
+		"used":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"free":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+	},
+})
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"email":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"nickname":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"createdAt": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"group":     &graphql.Field{Type: graphql.NewNonNull(groupType)},
+		"storage":   &graphql.Field{Type: graphql.NewNonNull(storageType), Resolve: resolveStorage},
+	},
+})
+
+var shareType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Share",
+	Fields: graphql.Fields{
+		"key":              &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveShareKey},
+		"slug":             &graphql.Field{Type: graphql.String},
+		"isDir":            &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"bundle":           &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"sourceName":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"password":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"internal":         &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"views":            &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"downloads":        &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"remainDownloads":  &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"previewEnabled":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"downloadDisabled": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"allowUpload":      &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"expires":          &graphql.Field{Type: graphql.DateTime},
+		"available":        &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean), Resolve: resolveShareAvailable},
+		"createdAt":        &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime), Resolve: resolveShareCreatedAt},
+	},
+})
+
+var shareListType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "ShareList",
+	Fields: graphql.Fields{
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"items": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(shareType)))},
+	},
+})
+
+var objectType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Object",
+	Fields: graphql.Fields{
+		"id":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"path":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"type":          &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"size":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"date":          &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"createDate":    &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+		"sourceEnabled": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"thumbnail": &graphql.Field{
+			Type:        graphql.String,
+			Description: "缩略图地址，无缩略图时为空",
+		},
+		"share": &graphql.Field{
+			Type:        shareType,
+			Description: "以此对象为源的分享，未分享时为空",
+		},
+	},
+})
+
+var policyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Policy",
+	Fields: graphql.Fields{
+		"id":       &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"name":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"type":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"maxSize":  &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"fileType": &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+	},
+})
+
+var directoryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Directory",
+	Fields: graphql.Fields{
+		"parent":  &graphql.Field{Type: graphql.String},
+		"objects": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(objectType)))},
+		"policy":  &graphql.Field{Type: policyType},
+	},
+})
+
+var taskType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Task",
+	Fields: graphql.Fields{
+		"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"type":       &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"status":     &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"progress":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"error":      &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"hasReport":  &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"createDate": &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var taskListType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "TaskList",
+	Fields: graphql.Fields{
+		"total": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"tasks": &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(taskType)))},
+	},
+})
+
+// downloadType 离线下载任务，进行中及已完成的任务各自只有部分字段
+var downloadType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Download",
+	Fields: graphql.Fields{
+		"gid":            &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveDownloadGID},
+		"name":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"status":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"dst":            &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"total":          &graphql.Field{Type: graphql.NewNonNull(graphql.Float)},
+		"downloaded":     &graphql.Field{Type: graphql.Float},
+		"speed":          &graphql.Field{Type: graphql.Int},
+		"updateInterval": &graphql.Field{Type: graphql.Int},
+		"error":          &graphql.Field{Type: graphql.String},
+		"taskStatus":     &graphql.Field{Type: graphql.Int},
+		"taskError":      &graphql.Field{Type: graphql.String},
+		"createTime":     &graphql.Field{Type: graphql.DateTime},
+		"updateTime":     &graphql.Field{Type: graphql.NewNonNull(graphql.DateTime)},
+	},
+})
+
+var shareOrderByType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "ShareOrderBy",
+	Values: graphql.EnumValueConfigMap{
+		"CREATED_AT": &graphql.EnumValueConfig{Value: "created_at"},
+		"DOWNLOADS":  &graphql.EnumValueConfig{Value: "downloads"},
+		"VIEWS":      &graphql.EnumValueConfig{Value: "views"},
+	},
+})
+
+var sortOrderType = graphql.NewEnum(graphql.EnumConfig{
+	Name: "SortOrder",
+	Values: graphql.EnumValueConfigMap{
+		"ASC":  &graphql.EnumValueConfig{Value: "ASC"},
+		"DESC": &graphql.EnumValueConfig{Value: "DESC"},
+	},
+})
+
+var queryType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"me": &graphql.Field{
+			Type:        graphql.NewNonNull(userType),
+			Description: "当前用户",
+			Resolve:     resolveMe,
+		},
+		"directory": &graphql.Field{
+			Type:        graphql.NewNonNull(directoryType),
+			Description: "列出目录内容",
+			Args: graphql.FieldConfigArgument{
+				"path": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+			},
+			Resolve: resolveDirectory,
+		},
+		"shares": &graphql.Field{
+			Type:        graphql.NewNonNull(shareListType),
+			Description: "列出我的分享",
+			Args: graphql.FieldConfigArgument{
+				"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 18},
+				"orderBy":  &graphql.ArgumentConfig{Type: shareOrderByType, DefaultValue: "created_at"},
+				"order":    &graphql.ArgumentConfig{Type: sortOrderType, DefaultValue: "DESC"},
+			},
+			Resolve: resolveShares,
+		},
+		"tasks": &graphql.Field{
+			Type:        graphql.NewNonNull(taskListType),
+			Description: "列出任务队列",
+			Args: graphql.FieldConfigArgument{
+				"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+				"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+				"type":     &graphql.ArgumentConfig{Type: graphql.Int},
+				"status":   &graphql.ArgumentConfig{Type: graphql.Int},
+			},
+			Resolve: resolveTasks,
+		},
+		"downloading": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(downloadType))),
+			Description: "列出正在进行的离线下载，page 为 0 时列出全部",
+			Args: graphql.FieldConfigArgument{
+				"page": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+			},
+			Resolve: resolveDownloading,
+		},
+		"finishedDownloads": &graphql.Field{
+			Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(downloadType))),
+			Description: "列出已结束的离线下载",
+			Args: graphql.FieldConfigArgument{
+				"page": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+			},
+			Resolve: resolveFinishedDownloads,
+		},
+	},
+})
+
+// schema 只读的 GraphQL 查询结构
+var schema graphql.Schema
+
+func init() {
+	var err error
+	schema, err = graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+	if err != nil {
+		panic(err)
+	}
+}
@@ -1,6 +1,7 @@
 package node
 
 import (
+	"context"
 	"encoding/gob"
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/cluster"
@@ -44,7 +45,7 @@ func (s *SlaveNotificationService) HandleSlaveNotificationPush(c *gin.Context) s
 }
 
 // Get 获取主机OneDrive策略的AccessToken
-func (s *OneDriveCredentialService) Get(c *gin.Context) serializer.Response {
+func (s *OneDriveCredentialService) Get(ctx context.Context) serializer.Response {
 	policy, err := model.GetPolicyByID(s.PolicyID)
 	if err != nil {
 		return serializer.Err(serializer.CodePolicyNotExist, "", err)
@@ -55,7 +56,7 @@ func (s *OneDriveCredentialService) Get(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeInternalSetting, "Cannot initialize OneDrive client", err)
 	}
 
-	if err := client.UpdateCredential(c, conf.SystemConfig.Mode == "slave"); err != nil {
+	if err := client.UpdateCredential(ctx, conf.SystemConfig.Mode == "slave"); err != nil {
 		return serializer.Err(serializer.CodeInternalSetting, "Cannot refresh OneDrive credential", err)
 	}
 
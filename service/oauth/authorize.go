@@ -0,0 +1,178 @@
+package oauth
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/gob"
+	"net/url"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// codeCachePrefix 授权码缓存前缀
+	codeCachePrefix = "oauth_code_"
+	// codeTTL 授权码有效期，单位秒
+	codeTTL = 600
+)
+
+func init() {
+	gob.Register(authorizationCode{})
+}
+
+// authorizationCode 已签发的授权码
+type authorizationCode struct {
+	ClientID            uint
+	UserID              uint
+	Scope               string
+	RedirectURI         string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// AuthorizeService 授权请求服务，用于展示授权确认页面及处理用户的确认结果
+type AuthorizeService struct {
+	ResponseType        string `form:"response_type" json:"response_type" binding:"required"`
+	ClientID            string `form:"client_id" json:"client_id" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri" binding:"required"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method"`
+	Approve             bool   `json:"approve"`
+}
+
+// randomCode 生成授权码
+func randomCode() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// validScope 检查并规范化申请的权限范围，未指定时为只读
+func validScope(scope string) (string, bool) {
+	switch scope {
+	case "":
+		return model.APITokenScopeRead, true
+	case model.APITokenScopeRead, model.APITokenScopeUpload, model.APITokenScopeFull:
+		return scope, true
+	}
+	return "", false
+}
+
+// client 查找应用并校验回调地址，校验通过前的错误不能重定向到回调地址
+func (service *AuthorizeService) client() (*model.OAuthClient, serializer.Response) {
+	client, err := model.GetOAuthClient(service.ClientID)
+	if err != nil {
+		return nil, serializer.Err(serializer.CodeNotFound, "Application not found", err)
+	}
+
+	if _, err := url.Parse(service.RedirectURI); err != nil || !client.AllowRedirect(service.RedirectURI) {
+		return nil, serializer.ParamErr("Redirect URI is not registered for this application", nil)
+	}
+
+	return client, serializer.Response{}
+}
+
+// redirect 拼接回调地址
+func (service *AuthorizeService) redirect(params url.Values) string {
+	target, _ := url.Parse(service.RedirectURI)
+	query := target.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if service.State != "" {
+		query.Set("state", service.State)
+	}
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+// check 校验授权请求，返回规范化后的权限范围，失败时返回应重定向至回调地址的错误
+func (service *AuthorizeService) check(client *model.OAuthClient) (string, string) {
+	if service.ResponseType != "code" {
+		return "", "unsupported_response_type"
+	}
+
+	scope, ok := validScope(service.Scope)
+	if !ok {
+		return "", "invalid_scope"
+	}
+
+	if service.CodeChallenge == "" && client.Public {
+		return "", "invalid_request"
+	}
+	if service.CodeChallenge != "" && service.CodeChallengeMethod != "S256" {
+		return "", "invalid_request"
+	}
+
+	return scope, ""
+}
+
+// Info 返回授权确认页面所需的应用信息
+func (service *AuthorizeService) Info(c *gin.Context, user *model.User) serializer.Response {
+	client, res := service.client()
+	if client == nil {
+		return res
+	}
+
+	scope, errCode := service.check(client)
+	if errCode != "" {
+		return serializer.Response{
+			Code: serializer.CodeParamErr,
+			Msg:  "Invalid authorization request: " + errCode,
+			Data: map[string]string{"redirect": service.redirect(url.Values{"error": {errCode}})},
+		}
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"name":         client.Name,
+		"homepage":     client.Homepage,
+		"scope":        scope,
+		"redirect_uri": service.RedirectURI,
+	}}
+}
+
+// Authorize 处理用户的确认结果，返回携带授权码或错误的回调地址
+func (service *AuthorizeService) Authorize(c *gin.Context, user *model.User) serializer.Response {
+	client, res := service.client()
+	if client == nil {
+		return res
+	}
+
+	scope, errCode := service.check(client)
+	if errCode == "" && !service.Approve {
+		errCode = "access_denied"
+	}
+	if errCode != "" {
+		return serializer.Response{Data: map[string]string{
+			"redirect": service.redirect(url.Values{"error": {errCode}}),
+		}}
+	}
+
+	code, err := randomCode()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate authorization code", err)
+	}
+
+	if err := cache.Set(codeCachePrefix+code, authorizationCode{
+		ClientID:            client.ID,
+		UserID:              user.ID,
+		Scope:               scope,
+		RedirectURI:         service.RedirectURI,
+		CodeChallenge:       service.CodeChallenge,
+		CodeChallengeMethod: service.CodeChallengeMethod,
+	}, codeTTL); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to save authorization code", err)
+	}
+
+	return serializer.Response{Data: map[string]string{
+		"redirect": service.redirect(url.Values{"code": {code}}),
+	}}
+}
@@ -0,0 +1,167 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/gin-gonic/gin"
+)
+
+// Error 令牌及撤销端点按 RFC 6749 返回的错误
+type Error struct {
+	Status      int    `json:"-"`
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func newError(status int, code, description string) *Error {
+	return &Error{Status: status, Code: code, Description: description}
+}
+
+// TokenResponse 令牌端点的成功响应
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	Scope        string `json:"scope"`
+}
+
+// TokenService 令牌端点服务
+type TokenService struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// RevokeService 令牌撤销端点服务
+type RevokeService struct {
+	Token        string `form:"token" binding:"required"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// authenticateClient 验证客户端身份，支持 HTTP Basic 认证及表单参数，公开客户端只需提供客户端ID
+func authenticateClient(c *gin.Context, clientID, clientSecret string) (*model.OAuthClient, *Error) {
+	if id, secret, ok := c.Request.BasicAuth(); ok {
+		clientID, clientSecret = id, secret
+	}
+
+	if clientID == "" {
+		return nil, newError(http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+	}
+
+	client, err := model.GetOAuthClient(clientID)
+	if err != nil || (!client.Public && !client.CheckSecret(clientSecret)) {
+		return nil, newError(http.StatusUnauthorized, "invalid_client", "Client authentication failed")
+	}
+
+	return client, nil
+}
+
+// verifyCodeChallenge 校验 PKCE 验证码
+func verifyCodeChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// Token 使用授权码或刷新令牌换取访问令牌
+func (service *TokenService) Token(c *gin.Context) (*TokenResponse, *Error) {
+	client, oauthErr := authenticateClient(c, service.ClientID, service.ClientSecret)
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	var token *model.APIToken
+	switch service.GrantType {
+	case "authorization_code":
+		token, oauthErr = service.exchangeCode(client)
+	case "refresh_token":
+		token, oauthErr = service.refresh(client)
+	default:
+		return nil, newError(http.StatusBadRequest, "unsupported_grant_type", "")
+	}
+	if oauthErr != nil {
+		return nil, oauthErr
+	}
+
+	ttl := model.GetIntSetting("oauth_token_ttl", 3600)
+	access, refresh, err := token.IssueOAuthToken(time.Duration(ttl) * time.Second)
+	if err != nil {
+		return nil, newError(http.StatusInternalServerError, "server_error", "Failed to issue token")
+	}
+
+	return &TokenResponse{
+		AccessToken:  access,
+		TokenType:    "Bearer",
+		ExpiresIn:    ttl,
+		RefreshToken: refresh,
+		Scope:        token.Scope,
+	}, nil
+}
+
+// exchangeCode 校验授权码，授权码只能使用一次
+func (service *TokenService) exchangeCode(client *model.OAuthClient) (*model.APIToken, *Error) {
+	raw, ok := cache.Get(codeCachePrefix + service.Code)
+	if service.Code == "" || !ok {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "Authorization code is invalid or expired")
+	}
+	_ = cache.Deletes([]string{service.Code}, codeCachePrefix)
+
+	code := raw.(authorizationCode)
+	if code.ClientID != client.ID || code.RedirectURI != service.RedirectURI {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "Authorization code is invalid or expired")
+	}
+	if code.CodeChallenge != "" && !verifyCodeChallenge(code.CodeChallenge, service.CodeVerifier) {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "Code verifier does not match")
+	}
+
+	if _, err := model.GetActiveUserByID(code.UserID); err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "User not found")
+	}
+
+	return &model.APIToken{
+		Name:     client.Name,
+		UserID:   code.UserID,
+		Scope:    code.Scope,
+		ClientID: client.ID,
+	}, nil
+}
+
+// refresh 校验刷新令牌，刷新后原令牌失效
+func (service *TokenService) refresh(client *model.OAuthClient) (*model.APIToken, *Error) {
+	token, err := model.GetOAuthTokenByRefresh(service.RefreshToken, client.ID)
+	if err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "Refresh token is invalid")
+	}
+
+	if _, err := model.GetActiveUserByID(token.UserID); err != nil {
+		return nil, newError(http.StatusBadRequest, "invalid_grant", "User not found")
+	}
+
+	return token, nil
+}
+
+// Revoke 撤销访问令牌或刷新令牌，两者会一同失效。令牌不存在时同样视为成功
+func (service *RevokeService) Revoke(c *gin.Context) *Error {
+	client, oauthErr := authenticateClient(c, service.ClientID, service.ClientSecret)
+	if oauthErr != nil {
+		return oauthErr
+	}
+
+	if token, err := model.GetOAuthToken(service.Token, client.ID); err == nil {
+		model.DeleteAPITokenByID(token.ID, token.UserID)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package rpc
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/rpc"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// session 以调用者的令牌限定的目录为根新建按路径访问文件的会话
+func session(ctx context.Context) *vfs.Session {
+	user, token := rpc.CurrentUser(ctx)
+	return vfs.NewTokenSession(user, token)
+}
+
+// cleanPath 将请求中的路径转换为以 / 开头的绝对路径
+func cleanPath(p string) string {
+	return path.Clean("/" + p)
+}
+
+// paramErr 请求参数错误
+func paramErr(msg string) error {
+	return rpc.Error(serializer.NewError(serializer.CodeParamErr, msg, nil))
+}
+
+// buildObject 构建文件或目录，parent 为所在目录的路径
+func buildObject(parent string, info vfs.FileInfo) *rpcv1.Object {
+	object := &rpcv1.Object{
+		Name:      info.GetName(),
+		Path:      parent,
+		Size:      info.GetSize(),
+		UpdatedAt: timestamppb.New(info.ModTime()),
+	}
+
+	switch obj := info.(type) {
+	case *model.Folder:
+		object.Id = hashid.HashID(obj.ID, hashid.FolderID)
+		object.Type = rpcv1.ObjectType_OBJECT_TYPE_DIRECTORY
+		object.CreatedAt = timestamppb.New(obj.CreatedAt)
+	case *model.File:
+		object.Id = hashid.HashID(obj.ID, hashid.FileID)
+		object.Type = rpcv1.ObjectType_OBJECT_TYPE_FILE
+		object.CreatedAt = timestamppb.New(obj.CreatedAt)
+	}
+
+	return object
+}
+
+// statObject 获取 p 对应的文件或目录
+func statObject(s *vfs.Session, p string) (*rpcv1.Object, error) {
+	info, err := s.Stat(p)
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+	return buildObject(path.Dir(p), info), nil
+}
+
+// buildTask 构建任务，任务类型、状态及进度的枚举值均比数据库中的值大 1，以 0 表示未指定
+func buildTask(t *model.Task) *rpcv1.Task {
+	return &rpcv1.Task{
+		Id:        hashid.HashID(t.ID, hashid.TaskID),
+		Type:      rpcv1.TaskType(t.Type + 1),
+		Status:    rpcv1.TaskStatus(t.Status + 1),
+		Progress:  rpcv1.TaskProgress(t.Progress + 1),
+		Error:     t.Error,
+		HasReport: t.Report != "",
+		CreatedAt: timestamppb.New(t.CreatedAt),
+		UpdatedAt: timestamppb.New(t.UpdatedAt),
+	}
+}
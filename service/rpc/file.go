@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cloudreve/Cloudreve/v3/pkg/rpc"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/vfs"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// downloadChunkSize 下载时每个消息携带的文件内容大小
+const downloadChunkSize = 64 << 10
+
+// errSizeMismatch 上传的内容与声明的大小不符
+var errSizeMismatch = status.Error(codes.InvalidArgument, "uploaded content does not match the declared size")
+
+// FileServer 按路径访问用户文件的服务
+type FileServer struct {
+	rpcv1.UnimplementedFileServiceServer
+}
+
+// List 列出目录下的文件及目录
+func (s *FileServer) List(ctx context.Context, req *rpcv1.ListRequest) (*rpcv1.ListResponse, error) {
+	dir := cleanPath(req.Path)
+	infos, err := session(ctx).List(dir)
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+
+	res := &rpcv1.ListResponse{Objects: make([]*rpcv1.Object, 0, len(infos))}
+	for _, info := range infos {
+		res.Objects = append(res.Objects, buildObject(dir, info))
+	}
+	return res, nil
+}
+
+// Stat 获取文件或目录信息
+func (s *FileServer) Stat(ctx context.Context, req *rpcv1.StatRequest) (*rpcv1.Object, error) {
+	return statObject(session(ctx), cleanPath(req.Path))
+}
+
+// CreateDirectory 创建目录
+func (s *FileServer) CreateDirectory(ctx context.Context, req *rpcv1.CreateDirectoryRequest) (*rpcv1.Object, error) {
+	p := cleanPath(req.Path)
+	sess := session(ctx)
+	if err := sess.MakeDir(p); err != nil {
+		return nil, rpc.Error(err)
+	}
+	return statObject(sess, p)
+}
+
+// Delete 删除文件及目录，未指定 recursive 时只能删除空目录
+func (s *FileServer) Delete(ctx context.Context, req *rpcv1.DeleteRequest) (*rpcv1.DeleteResponse, error) {
+	if len(req.Paths) == 0 {
+		return nil, paramErr("No paths to delete")
+	}
+
+	sess := session(ctx)
+	for _, raw := range req.Paths {
+		p := cleanPath(raw)
+		if req.Recursive {
+			if err := sess.RemoveAll(p); err != nil {
+				return nil, rpc.Error(err)
+			}
+			continue
+		}
+
+		info, err := sess.Stat(p)
+		if err != nil {
+			return nil, rpc.Error(err)
+		}
+		if info.IsDir() {
+			err = sess.RemoveDir(p)
+		} else {
+			err = sess.Remove(p)
+		}
+		if err != nil {
+			return nil, rpc.Error(err)
+		}
+	}
+
+	return &rpcv1.DeleteResponse{}, nil
+}
+
+// Move 将文件及目录移动至目标目录，目标目录中已存在同名对象时不覆盖
+func (s *FileServer) Move(ctx context.Context, req *rpcv1.MoveRequest) (*rpcv1.MoveResponse, error) {
+	if len(req.Paths) == 0 {
+		return nil, paramErr("No paths to move")
+	}
+
+	sess := session(ctx)
+	dst := cleanPath(req.Dst)
+	if info, err := sess.Stat(dst); err != nil || !info.IsDir() {
+		return nil, rpc.Error(serializer.NewError(serializer.CodeParentNotExist, "Destination folder not exist", err))
+	}
+
+	for _, raw := range req.Paths {
+		p := cleanPath(raw)
+		if err := sess.Rename(p, path.Join(dst, path.Base(p))); err != nil {
+			return nil, rpc.Error(err)
+		}
+	}
+
+	return &rpcv1.MoveResponse{}, nil
+}
+
+// Rename 重命名文件或目录
+func (s *FileServer) Rename(ctx context.Context, req *rpcv1.RenameRequest) (*rpcv1.Object, error) {
+	if req.NewName == "" || len(req.NewName) > 255 || strings.Contains(req.NewName, "/") {
+		return nil, paramErr("Invalid new name")
+	}
+
+	p := cleanPath(req.Path)
+	dst := path.Join(path.Dir(p), req.NewName)
+	sess := session(ctx)
+	if err := sess.Rename(p, dst); err != nil {
+		return nil, rpc.Error(err)
+	}
+	return statObject(sess, dst)
+}
+
+// Download 从 offset 处开始以分块的形式返回文件内容
+func (s *FileServer) Download(req *rpcv1.DownloadRequest, stream rpcv1.FileService_DownloadServer) error {
+	rs, err := session(stream.Context()).Open(cleanPath(req.Path))
+	if err != nil {
+		return rpc.Error(err)
+	}
+	defer rs.Close()
+
+	if req.Offset > 0 {
+		if _, err := rs.Seek(int64(req.Offset), io.SeekStart); err != nil {
+			return rpc.Error(err)
+		}
+	}
+
+	buf := make([]byte, downloadChunkSize)
+	for {
+		n, err := rs.Read(buf)
+		if n > 0 {
+			if err := stream.Send(&rpcv1.DownloadResponse{Chunk: buf[:n]}); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return rpc.Error(err)
+		}
+	}
+}
+
+// Upload 接收文件信息及内容并写入文件
+func (s *FileServer) Upload(stream rpcv1.FileService_UploadServer) error {
+	req, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	header := req.GetHeader()
+	if header == nil {
+		return paramErr("The first message must be the upload header")
+	}
+
+	p := cleanPath(header.Path)
+	sess := session(stream.Context())
+	if !header.Overwrite {
+		if _, err := sess.Stat(p); err == nil {
+			return rpc.Error(vfs.ErrExist)
+		}
+	}
+
+	// 尽早拒绝超出容量的上传
+	limit, err := sess.UploadLimit(p)
+	if err != nil {
+		return rpc.Error(err)
+	}
+	if header.Size > limit {
+		return rpc.Error(vfs.ErrQuotaExceeded)
+	}
+
+	if err := sess.Store(p, &uploadReader{stream: stream, remaining: header.Size}); err != nil {
+		return rpc.Error(err)
+	}
+
+	object, err := statObject(sess, p)
+	if err != nil {
+		return err
+	}
+	return stream.SendAndClose(object)
+}
+
+// uploadReader 读取上传流中的文件内容，内容与声明的大小不符时返回错误
+type uploadReader struct {
+	stream    rpcv1.FileService_UploadServer
+	buf       []byte
+	remaining uint64
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		req, err := r.stream.Recv()
+		if err == io.EOF {
+			if r.remaining > 0 {
+				return 0, errSizeMismatch
+			}
+			return 0, io.EOF
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if req.GetHeader() != nil {
+			return 0, paramErr("The upload header must only be sent once")
+		}
+		r.buf = req.GetChunk()
+		if uint64(len(r.buf)) > r.remaining {
+			return 0, errSizeMismatch
+		}
+		r.remaining -= uint64(len(r.buf))
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
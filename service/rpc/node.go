@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"io"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	aria2rpc "github.com/cloudreve/Cloudreve/v3/pkg/aria2/rpc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/mq"
+	"github.com/cloudreve/Cloudreve/v3/pkg/rpc"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/node"
+)
+
+// NodeServer 从机节点调用的主机服务
+type NodeServer struct {
+	rpcv1.UnimplementedNodeServiceServer
+}
+
+// Ping 返回主机信息
+func (s *NodeServer) Ping(ctx context.Context, req *rpcv1.PingRequest) (*rpcv1.PingResponse, error) {
+	version := conf.BackendVersion
+	if conf.IsPro == "true" {
+		version += "-pro"
+	}
+
+	return &rpcv1.PingResponse{
+		NodeId:  uint64(rpc.CurrentNode(ctx).ID()),
+		SiteId:  model.GetSettingByName("siteID"),
+		Version: version,
+	}, nil
+}
+
+// ReportDownloadEvents 将从机上报的离线下载事件转发到本机消息队列
+func (s *NodeServer) ReportDownloadEvents(stream rpcv1.NodeService_ReportDownloadEventsServer) error {
+	var accepted uint64
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&rpcv1.ReportDownloadEventsResponse{Accepted: accepted})
+		}
+		if err != nil {
+			return err
+		}
+
+		if event.Gid == "" {
+			return paramErr("Download event has no gid")
+		}
+
+		events := []aria2rpc.Event{{Gid: event.Gid}}
+		switch event.Type {
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_START:
+			mq.GlobalMQ.OnDownloadStart(events)
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_PAUSE:
+			mq.GlobalMQ.OnDownloadPause(events)
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_STOP:
+			mq.GlobalMQ.OnDownloadStop(events)
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_COMPLETE:
+			mq.GlobalMQ.OnDownloadComplete(events)
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_ERROR:
+			mq.GlobalMQ.OnDownloadError(events)
+		case rpcv1.DownloadEventType_DOWNLOAD_EVENT_TYPE_BT_COMPLETE:
+			mq.GlobalMQ.OnBtDownloadComplete(events)
+		default:
+			return paramErr("Unknown download event type")
+		}
+		accepted++
+	}
+}
+
+// GetOneDriveCredential 获取主机 OneDrive 存储策略的访问令牌
+func (s *NodeServer) GetOneDriveCredential(ctx context.Context, req *rpcv1.GetOneDriveCredentialRequest) (*rpcv1.OneDriveCredential, error) {
+	service := &node.OneDriveCredentialService{PolicyID: uint(req.PolicyId)}
+	res := service.Get(ctx)
+	if err := rpc.ResponseError(res); err != nil {
+		return nil, err
+	}
+
+	token, _ := res.Data.(string)
+	return &rpcv1.OneDriveCredential{AccessToken: token}, nil
+}
+
+// ReportTransferResult 将从机上报的中转任务结果转发到本机消息队列
+func (s *NodeServer) ReportTransferResult(ctx context.Context, req *rpcv1.TransferResult) (*rpcv1.ReportTransferResultResponse, error) {
+	if req.Hash == "" {
+		return nil, paramErr("Transfer result has no hash")
+	}
+
+	event := serializer.SlaveTransferFailed
+	if req.Success {
+		event = serializer.SlaveTransferSuccess
+	}
+
+	mq.GlobalMQ.Publish(req.Hash, mq.Message{
+		TriggeredBy: model.GetSettingByName("siteID"),
+		Event:       event,
+		Content:     serializer.SlaveTransferResult{Error: req.Error},
+	})
+	return &rpcv1.ReportTransferResultResponse{}, nil
+}
@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/rpc"
+	rpcv1 "github.com/cloudreve/Cloudreve/v3/pkg/rpc/v1"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+)
+
+// watchInterval 监听任务时查询任务状态的间隔
+const watchInterval = time.Second
+
+// TaskServer 异步任务服务
+type TaskServer struct {
+	rpcv1.UnimplementedTaskServiceServer
+}
+
+// Compress 创建压缩任务
+func (s *TaskServer) Compress(ctx context.Context, req *rpcv1.CompressRequest) (*rpcv1.Task, error) {
+	if len(req.Paths) == 0 {
+		return nil, paramErr("No paths to compress")
+	}
+	if req.Name == "" || len(req.Name) > 255 || len(req.Password) > 255 {
+		return nil, paramErr("Invalid archive name or password")
+	}
+	if req.Format != "" && req.Format != "zip" && req.Format != "tar.gz" {
+		return nil, paramErr("Unsupported archive format")
+	}
+
+	sess := session(ctx)
+	items := &explorer.ItemService{}
+	for _, p := range req.Paths {
+		info, err := sess.Stat(cleanPath(p))
+		if err != nil {
+			return nil, rpc.Error(err)
+		}
+		switch obj := info.(type) {
+		case *model.Folder:
+			items.Dirs = append(items.Dirs, obj.ID)
+		case *model.File:
+			items.Items = append(items.Items, obj.ID)
+		}
+	}
+
+	fs, err := sess.FileSystem()
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+	defer fs.Recycle()
+
+	service := &explorer.ItemCompressService{
+		Src:      explorer.ItemIDService{Source: items},
+		Dst:      cleanPath(req.Dst),
+		Name:     req.Name,
+		Format:   req.Format,
+		Password: req.Password,
+	}
+	record, err := service.Submit(ctx, fs)
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+	return buildTask(record), nil
+}
+
+// Decompress 创建解压缩任务
+func (s *TaskServer) Decompress(ctx context.Context, req *rpcv1.DecompressRequest) (*rpcv1.Task, error) {
+	if len(req.Files) > 1000 {
+		return nil, paramErr("Too many files to decompress")
+	}
+
+	fs, err := session(ctx).FileSystem()
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+	defer fs.Recycle()
+
+	service := &explorer.ItemDecompressService{
+		Src:      cleanPath(req.Src),
+		Dst:      cleanPath(req.Dst),
+		Encoding: req.Encoding,
+		Files:    req.Files,
+	}
+	record, err := service.Submit(ctx, fs)
+	if err != nil {
+		return nil, rpc.Error(err)
+	}
+	return buildTask(record), nil
+}
+
+// Get 获取调用者的任务
+func (s *TaskServer) Get(ctx context.Context, req *rpcv1.GetTaskRequest) (*rpcv1.Task, error) {
+	record, err := userTask(ctx, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	return buildTask(record), nil
+}
+
+// List 列出调用者的任务
+func (s *TaskServer) List(ctx context.Context, req *rpcv1.ListTasksRequest) (*rpcv1.ListTasksResponse, error) {
+	page, pageSize := int(req.Page), int(req.PageSize)
+	if page == 0 {
+		page = 1
+	}
+	if pageSize == 0 {
+		pageSize = 10
+	}
+	if page < 0 || pageSize < 0 || pageSize > 100 {
+		return nil, paramErr("Invalid page or page size")
+	}
+
+	conditions := make(map[string]interface{})
+	if req.Type != rpcv1.TaskType_TASK_TYPE_UNSPECIFIED {
+		conditions["type"] = int(req.Type) - 1
+	}
+	if req.Status != rpcv1.TaskStatus_TASK_STATUS_UNSPECIFIED {
+		conditions["status"] = int(req.Status) - 1
+	}
+
+	user, _ := rpc.CurrentUser(ctx)
+	tasks, total := model.ListTasks(user.ID, page, pageSize, "updated_at desc", conditions)
+	res := &rpcv1.ListTasksResponse{Tasks: make([]*rpcv1.Task, 0, len(tasks)), Total: int64(total)}
+	for i := range tasks {
+		res.Tasks = append(res.Tasks, buildTask(&tasks[i]))
+	}
+	return res, nil
+}
+
+// Watch 在任务状态、进度或错误信息变化时返回任务，任务结束后关闭
+func (s *TaskServer) Watch(req *rpcv1.WatchTaskRequest, stream rpcv1.TaskService_WatchServer) error {
+	ctx := stream.Context()
+	record, err := userTask(ctx, req.Id)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	var last *model.Task
+	for {
+		if last == nil || last.Status != record.Status || last.Progress != record.Progress || last.Error != record.Error {
+			if err := stream.Send(buildTask(record)); err != nil {
+				return err
+			}
+			last = record
+		}
+
+		if taskFinished(record.Status) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		if record, err = model.GetTasksByID(record.ID); err != nil {
+			return rpc.Error(serializer.NewError(serializer.CodeNotFound, "Task not exist", err))
+		}
+	}
+}
+
+// userTask 根据 HashID 获取调用者的任务
+func userTask(ctx context.Context, id string) (*model.Task, error) {
+	taskID, err := hashid.DecodeHashID(id, hashid.TaskID)
+	if err != nil {
+		return nil, rpc.Error(serializer.NewError(serializer.CodeNotFound, "Task not exist", err))
+	}
+
+	user, _ := rpc.CurrentUser(ctx)
+	record, err := model.GetTasksByID(taskID)
+	if err != nil || record.UserID != user.ID {
+		return nil, rpc.Error(serializer.NewError(serializer.CodeNotFound, "Task not exist", err))
+	}
+	return record, nil
+}
+
+// taskFinished 任务是否已结束
+func taskFinished(status int) bool {
+	switch status {
+	case task.Error, task.Canceled, task.Complete, task.TimedOut:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,338 @@
+package s3
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// maxListKeys 单次列取的对象数量上限
+const maxListKeys = 1000
+
+// listEntry 列取结果中的一项，folder 不为空时为目录
+type listEntry struct {
+	key    string
+	file   *model.File
+	folder *model.Folder
+}
+
+// ListBuckets 列出存储桶，每个访问密钥只对应一个存储桶
+func ListBuckets(c *gin.Context) error {
+	key := currentKey(c)
+	user, _ := c.Get("user")
+	owner := user.(*model.User)
+
+	s3gateway.WriteXML(c, http.StatusOK, &s3gateway.ListAllMyBucketsResult{
+		Owner: s3gateway.Owner{
+			ID:          hashid.HashID(owner.ID, hashid.UserID),
+			DisplayName: owner.Nick,
+		},
+		Buckets: []s3gateway.Bucket{{Name: key.Bucket, CreationDate: s3gateway.FormatTime(key.CreatedAt)}},
+	})
+	return nil
+}
+
+// Head 检查存储桶是否存在
+func (service *BucketService) Head(c *gin.Context) error {
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	fs.Recycle()
+
+	c.Status(http.StatusOK)
+	return nil
+}
+
+// Create 创建存储桶，只能“创建”访问密钥已对应的存储桶
+func (service *BucketService) Create(c *gin.Context) error {
+	if err := service.Head(c); err != nil {
+		if err == s3gateway.ErrNoSuchBucket {
+			return s3gateway.ErrAccessDenied
+		}
+		return err
+	}
+	return nil
+}
+
+// Get 处理存储桶上的 GET 请求，根据查询参数区分具体操作
+func (service *BucketService) Get(c *gin.Context) error {
+	if _, ok := c.GetQuery("location"); ok {
+		return service.Location(c)
+	}
+	if _, ok := c.GetQuery("uploads"); ok {
+		return service.ListMultipartUploads(c)
+	}
+	for _, sub := range []string{"acl", "cors", "lifecycle", "policy", "tagging", "versioning", "versions", "website"} {
+		if _, ok := c.GetQuery(sub); ok {
+			return s3gateway.ErrNotImplemented
+		}
+	}
+	return service.ListObjects(c)
+}
+
+// Location 获取存储桶所在区域，返回空值表示默认区域
+func (service *BucketService) Location(c *gin.Context) error {
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	fs.Recycle()
+
+	s3gateway.WriteXML(c, http.StatusOK, &s3gateway.LocationConstraint{})
+	return nil
+}
+
+// ListMultipartUploads 列出进行中的分片上传，分片上传不支持断点续传，始终返回空列表
+func (service *BucketService) ListMultipartUploads(c *gin.Context) error {
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	fs.Recycle()
+
+	s3gateway.WriteXML(c, http.StatusOK, &s3gateway.ListMultipartUploadsResult{
+		Bucket:     service.Bucket,
+		MaxUploads: maxListKeys,
+	})
+	return nil
+}
+
+// ListObjects 列出对象，支持 ListObjects 及 ListObjectsV2。分隔符只支持 /，此时只列取
+// 前缀所在目录的直接子项；不指定分隔符时递归列取，空目录以 / 结尾的键表示
+func (service *BucketService) ListObjects(c *gin.Context) error {
+	v2 := c.Query("list-type") == "2"
+	prefix := c.Query("prefix")
+	delimiter := c.Query("delimiter")
+	encodingType := c.Query("encoding-type")
+	if delimiter != "" && delimiter != "/" {
+		return s3gateway.ErrNotImplemented
+	}
+	if encodingType != "" && encodingType != "url" {
+		return s3gateway.ErrInvalidArgument
+	}
+
+	maxKeys := maxListKeys
+	if raw := c.Query("max-keys"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			return s3gateway.ErrInvalidArgument
+		}
+		if n < maxKeys {
+			maxKeys = n
+		}
+	}
+
+	// V2 的续取标识为上次返回的最后一个键
+	marker := c.Query("marker")
+	if v2 {
+		marker = c.Query("start-after")
+		if token := c.Query("continuation-token"); token != "" {
+			raw, err := base64.RawURLEncoding.DecodeString(token)
+			if err != nil {
+				return s3gateway.ErrInvalidArgument
+			}
+			marker = string(raw)
+		}
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	entries, err := listEntries(fs, prefix, delimiter == "/")
+	if err != nil {
+		return s3Error(err)
+	}
+
+	// 跳过续取标识之前的键
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key > marker })
+	entries = entries[start:]
+	truncated := len(entries) > maxKeys
+	if truncated {
+		entries = entries[:maxKeys]
+	}
+
+	encode := func(s string) string {
+		if encodingType == "url" {
+			return s3gateway.URIEncode(s, false)
+		}
+		return s
+	}
+
+	res := &s3gateway.ListBucketResult{
+		Name:           service.Bucket,
+		Prefix:         encode(prefix),
+		MaxKeys:        maxKeys,
+		Delimiter:      encode(delimiter),
+		EncodingType:   encodingType,
+		IsTruncated:    truncated,
+		Contents:       []s3gateway.Object{},
+		CommonPrefixes: []s3gateway.CommonPrefix{},
+	}
+
+	for _, entry := range entries {
+		if entry.folder != nil && delimiter == "/" {
+			res.CommonPrefixes = append(res.CommonPrefixes, s3gateway.CommonPrefix{Prefix: encode(entry.key)})
+			continue
+		}
+
+		object := s3gateway.Object{Key: encode(entry.key), StorageClass: "STANDARD"}
+		if entry.file != nil {
+			object.Size = entry.file.Size
+			object.LastModified = s3gateway.FormatTime(entry.file.UpdatedAt)
+			object.ETag = objectETag(entry.file.UpdatedAt, entry.file.Size)
+		} else {
+			object.LastModified = s3gateway.FormatTime(entry.folder.UpdatedAt)
+			object.ETag = objectETag(entry.folder.UpdatedAt, 0)
+		}
+		res.Contents = append(res.Contents, object)
+	}
+
+	if v2 {
+		count := len(entries)
+		res.KeyCount = &count
+		res.StartAfter = encode(c.Query("start-after"))
+		res.ContinuationToken = c.Query("continuation-token")
+		if truncated {
+			res.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(entries[len(entries)-1].key))
+		}
+	} else {
+		res.Marker = encode(marker)
+		if truncated {
+			res.NextMarker = encode(entries[len(entries)-1].key)
+		}
+	}
+
+	s3gateway.WriteXML(c, http.StatusOK, res)
+	return nil
+}
+
+// listEntries 列出键以 prefix 开头的对象并按键排序，shallow 为 true 时只列取前缀所在目录的直接子项
+func listEntries(fs *filesystem.FileSystem, prefix string, shallow bool) ([]listEntry, error) {
+	// 前缀中最后一个 / 及之前的部分为起始目录
+	base := prefix[:strings.LastIndex(prefix, "/")+1]
+	dirPath := "/"
+	if base != "" {
+		p, err := objectPath(base)
+		if err != nil {
+			return nil, nil
+		}
+		dirPath = p
+	}
+
+	exist, root := fs.IsPathExist(dirPath)
+	if !exist {
+		return nil, nil
+	}
+
+	var entries []listEntry
+	if shallow {
+		folders, err := root.GetChildFolder()
+		if err != nil {
+			return nil, err
+		}
+		files, err := root.GetChildFiles()
+		if err != nil {
+			return nil, err
+		}
+
+		for i := range folders {
+			entries = append(entries, listEntry{key: base + folders[i].Name + "/", folder: &folders[i]})
+		}
+		for i := range files {
+			entries = append(entries, listEntry{key: base + files[i].Name, file: &files[i]})
+		}
+	} else {
+		// 子目录按层级顺序返回，父目录的路径总是先于子目录确定
+		folders, err := model.GetRecursiveChildFolder([]uint{root.ID}, fs.User.ID, true)
+		if err != nil {
+			return nil, err
+		}
+		files, err := model.GetChildFilesOfFolders(&folders)
+		if err != nil {
+			return nil, err
+		}
+
+		paths := map[uint]string{root.ID: base}
+		nonEmpty := make(map[uint]bool)
+		for _, folder := range folders {
+			if folder.ID != root.ID && folder.ParentID != nil {
+				paths[folder.ID] = paths[*folder.ParentID] + folder.Name + "/"
+				nonEmpty[*folder.ParentID] = true
+			}
+		}
+
+		for i := range files {
+			nonEmpty[files[i].FolderID] = true
+			entries = append(entries, listEntry{key: paths[files[i].FolderID] + files[i].Name, file: &files[i]})
+		}
+
+		// 空目录以目录标记的形式列出，以便客户端同步空目录
+		for i := range folders {
+			if folders[i].ID != root.ID && !nonEmpty[folders[i].ID] {
+				entries = append(entries, listEntry{key: paths[folders[i].ID], folder: &folders[i]})
+			}
+		}
+	}
+
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.key, prefix) {
+			filtered = append(filtered, entry)
+		}
+	}
+	sort.Slice(filtered, func(i, j int) bool { return filtered[i].key < filtered[j].key })
+
+	return filtered, nil
+}
+
+// DeleteObjects 批量删除对象
+func (service *BucketService) DeleteObjects(c *gin.Context) error {
+	var req s3gateway.Delete
+	if err := xmlBody(c, &req); err != nil {
+		return err
+	}
+	if len(req.Objects) > maxListKeys {
+		return s3gateway.ErrMalformedXML
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	res := &s3gateway.DeleteResult{}
+	for _, object := range req.Objects {
+		if err := deleteObject(c, fs, object.Key); err != nil {
+			s3Err := s3Error(err).(*s3gateway.Error)
+			res.Errors = append(res.Errors, s3gateway.DeleteError{Key: object.Key, Code: s3Err.Code, Message: s3Err.Message})
+			continue
+		}
+		if !req.Quiet {
+			res.Deleted = append(res.Deleted, s3gateway.DeletedObject{Key: object.Key})
+		}
+	}
+
+	s3gateway.WriteXML(c, http.StatusOK, res)
+	return nil
+}
+
+// Post 处理存储桶上的 POST 请求，目前只支持批量删除
+func (service *BucketService) Post(c *gin.Context) error {
+	if _, ok := c.GetQuery("delete"); ok {
+		return service.DeleteObjects(c)
+	}
+	return s3gateway.ErrNotImplemented
+}
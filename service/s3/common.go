@@ -0,0 +1,145 @@
+package s3
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// BucketService 存储桶相关操作的服务
+type BucketService struct {
+	Bucket string `uri:"bucket" binding:"required"`
+}
+
+// ObjectService 对象相关操作的服务
+type ObjectService struct {
+	Bucket string `uri:"bucket" binding:"required"`
+	Key    string `uri:"key" binding:"required"`
+}
+
+// currentKey 当前请求使用的访问密钥
+func currentKey(c *gin.Context) *model.S3AccessKey {
+	if key, ok := c.Get("s3_key"); ok {
+		return key.(*model.S3AccessKey)
+	}
+	return nil
+}
+
+// openBucket 以访问密钥对应的目录为根初始化文件系统，每个访问密钥只能访问自己的存储桶
+func openBucket(c *gin.Context, bucket string) (*filesystem.FileSystem, error) {
+	key := currentKey(c)
+	if key == nil || key.Bucket != bucket {
+		return nil, s3gateway.ErrNoSuchBucket
+	}
+
+	fs, err := filesystem.NewFileSystemFromContext(c)
+	if err != nil {
+		return nil, err
+	}
+
+	// 目录已被删除时存储桶不可用，不回退至上级目录
+	if key.Root != "/" {
+		exist, root := fs.IsPathExist(key.Root)
+		if !exist {
+			fs.Recycle()
+			return nil, s3gateway.ErrNoSuchBucket
+		}
+		root.Position = ""
+		root.Name = "/"
+		fs.Root = root
+	}
+
+	return fs, nil
+}
+
+// key 去除路由参数开头的 /
+func (service *ObjectService) key() string {
+	return strings.TrimPrefix(service.Key, "/")
+}
+
+// isDir 以 / 结尾的键表示目录
+func (service *ObjectService) isDir() bool {
+	return strings.HasSuffix(service.Key, "/")
+}
+
+// objectPath 将对象键转换为存储桶内的路径，不允许出现空的或相对的路径段
+func objectPath(key string) (string, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(key, "/"), "/")
+	if name == "" {
+		return "", s3gateway.ErrInvalidObjectName
+	}
+
+	for _, segment := range strings.Split(name, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return "", s3gateway.ErrInvalidObjectName
+		}
+	}
+
+	return "/" + name, nil
+}
+
+// objectETag 生成对象的 ETag。文件不记录 MD5，以修改时间及大小生成，
+// 且格式与分片上传的 ETag 相同，客户端不会将其作为 MD5 校验
+func objectETag(updatedAt time.Time, size uint64) string {
+	return fmt.Sprintf(`"%x-%x"`, updatedAt.UnixNano(), size)
+}
+
+// s3Error 将文件系统错误转换为 S3 错误
+func s3Error(err error) error {
+	var s3Err *s3gateway.Error
+	if errors.As(err, &s3Err) {
+		return s3Err
+	}
+
+	var appErr serializer.AppError
+	if errors.As(err, &appErr) {
+		switch appErr.Code {
+		case serializer.CodeFileTooLarge:
+			return s3gateway.ErrEntityTooLarge
+		case serializer.CodeInsufficientCapacity, serializer.CodeUploadTrafficExceeded:
+			return s3gateway.ErrQuotaExceeded
+		case serializer.CodeIllegalObjectName, serializer.CodeFileTypeNotAllowed:
+			return s3gateway.ErrInvalidObjectName
+		case serializer.CodeObjectExist, serializer.CodeConflictUploadOngoing:
+			return s3gateway.ErrKeyConflict
+		case serializer.CodeNoPermissionErr:
+			return s3gateway.ErrAccessDenied
+		case serializer.CodeLocked:
+			return s3gateway.ErrObjectLocked
+		}
+
+		// 读取请求体时的签名或哈希校验错误
+		if errors.As(appErr.RawError, &s3Err) {
+			return s3Err
+		}
+	}
+
+	util.Log().Warning("S3 接口请求失败, %s", err)
+	return s3gateway.ErrInternalError
+}
+
+// maxXMLBodySize 请求正文中 XML 的最大长度
+const maxXMLBodySize = 2 << 20
+
+// xmlBody 解析请求正文中的 XML
+func xmlBody(c *gin.Context, v interface{}) error {
+	body, err := ioutil.ReadAll(io.LimitReader(c.Request.Body, maxXMLBodySize+1))
+	if err != nil {
+		return s3Error(err)
+	}
+	if len(body) > maxXMLBodySize || xml.Unmarshal(body, v) != nil {
+		return s3gateway.ErrMalformedXML
+	}
+	return nil
+}
@@ -0,0 +1,235 @@
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/gob"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/gin-gonic/gin"
+	"github.com/gofrs/uuid"
+)
+
+const (
+	// multipartCachePrefix 分片上传会话的缓存前缀
+	multipartCachePrefix = "s3_multipart_"
+	// maxPartNumber 分片编号上限
+	maxPartNumber = 10000
+)
+
+func init() {
+	gob.Register(multipartUpload{})
+}
+
+// multipartUpload 进行中的分片上传，分片暂存在本机的临时目录中，完成时合并上传
+type multipartUpload struct {
+	KeyID       uint
+	Bucket      string
+	Key         string
+	ContentType string
+}
+
+// partPath 分片文件的路径，分片的 MD5 保存在同名的 .etag 文件中
+func partPath(dir string, partNumber int) string {
+	return filepath.Join(dir, fmt.Sprintf("%05d", partNumber))
+}
+
+// CreateMultipartUpload 创建分片上传
+func (service *ObjectService) CreateMultipartUpload(c *gin.Context) error {
+	if service.isDir() {
+		return s3gateway.ErrInvalidObjectName
+	}
+	if _, err := objectPath(service.key()); err != nil {
+		return err
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	fs.Recycle()
+
+	id := uuid.Must(uuid.NewV4()).String()
+	if err := os.MkdirAll(filepath.Join(s3gateway.MultipartRoot(), id), 0700); err != nil {
+		return s3Error(err)
+	}
+
+	session := multipartUpload{
+		KeyID:       currentKey(c).ID,
+		Bucket:      service.Bucket,
+		Key:         service.key(),
+		ContentType: c.GetHeader("Content-Type"),
+	}
+	if err := cache.Set(multipartCachePrefix+id, session, int(s3gateway.MultipartTTL.Seconds())); err != nil {
+		return s3Error(err)
+	}
+
+	s3gateway.WriteXML(c, http.StatusOK, &s3gateway.InitiateMultipartUploadResult{
+		Bucket:   service.Bucket,
+		Key:      service.key(),
+		UploadID: id,
+	})
+	return nil
+}
+
+// multipartSession 获取当前请求对应的分片上传会话及临时目录
+func (service *ObjectService) multipartSession(c *gin.Context) (*multipartUpload, string, error) {
+	id := c.Query("uploadId")
+	raw, ok := cache.Get(multipartCachePrefix + id)
+	if !ok {
+		return nil, "", s3gateway.ErrNoSuchUpload
+	}
+
+	session := raw.(multipartUpload)
+	if session.KeyID != currentKey(c).ID || session.Bucket != service.Bucket || session.Key != service.key() {
+		return nil, "", s3gateway.ErrNoSuchUpload
+	}
+
+	return &session, filepath.Join(s3gateway.MultipartRoot(), id), nil
+}
+
+// UploadPart 上传分片，同一编号的分片重复上传时覆盖
+func (service *ObjectService) UploadPart(c *gin.Context) error {
+	partNumber, err := strconv.Atoi(c.Query("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		return s3gateway.ErrInvalidArgument
+	}
+
+	_, dir, err := service.multipartSession(c)
+	if err != nil {
+		return err
+	}
+
+	size, err := contentLength(c)
+	if err != nil {
+		return err
+	}
+
+	// 分片暂存在本机，提前拒绝超出剩余容量的分片
+	user, _ := c.Get("user")
+	if size > user.(*model.User).GetRemainingCapacity() {
+		return s3gateway.ErrQuotaExceeded
+	}
+
+	part := partPath(dir, partNumber)
+	f, err := os.Create(part)
+	if err != nil {
+		return s3Error(err)
+	}
+
+	hash := md5.New()
+	written, err := io.Copy(io.MultiWriter(f, hash), io.LimitReader(c.Request.Body, int64(size)))
+	f.Close()
+	if err == nil && uint64(written) != size {
+		err = s3gateway.ErrIncompleteBody
+	}
+	if err != nil {
+		os.Remove(part)
+		return s3Error(err)
+	}
+
+	etag := hex.EncodeToString(hash.Sum(nil))
+	if err := ioutil.WriteFile(part+".etag", []byte(etag), 0600); err != nil {
+		return s3Error(err)
+	}
+
+	c.Header("ETag", `"`+etag+`"`)
+	c.Status(http.StatusOK)
+	return nil
+}
+
+// CompleteMultipartUpload 按顺序合并分片并上传至存储策略
+func (service *ObjectService) CompleteMultipartUpload(c *gin.Context) error {
+	session, dir, err := service.multipartSession(c)
+	if err != nil {
+		return err
+	}
+
+	var req s3gateway.CompleteMultipartUpload
+	if err := xmlBody(c, &req); err != nil {
+		return err
+	}
+	if len(req.Parts) == 0 {
+		return s3gateway.ErrMalformedXML
+	}
+
+	readers := make([]io.Reader, 0, len(req.Parts))
+	var size uint64
+	prev := 0
+	for _, part := range req.Parts {
+		if part.PartNumber <= prev {
+			return s3gateway.ErrInvalidPartOrder
+		}
+		prev = part.PartNumber
+
+		// 校验分片是否存在且与客户端记录的一致
+		etag, err := ioutil.ReadFile(partPath(dir, part.PartNumber) + ".etag")
+		if err != nil || string(etag) != strings.Trim(part.ETag, `"`) {
+			return s3gateway.ErrInvalidPart
+		}
+
+		f, err := os.Open(partPath(dir, part.PartNumber))
+		if err != nil {
+			return s3gateway.ErrInvalidPart
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return s3Error(err)
+		}
+		size += uint64(info.Size())
+		readers = append(readers, f)
+	}
+
+	p, err := objectPath(session.Key)
+	if err != nil {
+		return err
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	file, err := upload(c, fs, p, io.MultiReader(readers...), size, session.ContentType)
+	if err != nil {
+		return s3Error(err)
+	}
+
+	os.RemoveAll(dir)
+	cache.Deletes([]string{c.Query("uploadId")}, multipartCachePrefix)
+
+	s3gateway.WriteXML(c, http.StatusOK, &s3gateway.CompleteMultipartUploadResult{
+		Location: "/" + service.Bucket + "/" + session.Key,
+		Bucket:   service.Bucket,
+		Key:      session.Key,
+		ETag:     objectETag(file.UpdatedAt, file.Size),
+	})
+	return nil
+}
+
+// AbortMultipartUpload 取消分片上传并删除已上传的分片
+func (service *ObjectService) AbortMultipartUpload(c *gin.Context) error {
+	_, dir, err := service.multipartSession(c)
+	if err != nil {
+		return err
+	}
+
+	os.RemoveAll(dir)
+	cache.Deletes([]string{c.Query("uploadId")}, multipartCachePrefix)
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
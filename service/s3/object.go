@@ -0,0 +1,238 @@
+package s3
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/s3gateway"
+	"github.com/gin-gonic/gin"
+)
+
+// emptyETag 空内容的 MD5，用于目录标记
+const emptyETag = `"d41d8cd98f00b204e9800998ecf8427e"`
+
+// Get 下载对象，HEAD 请求时只返回元数据
+func (service *ObjectService) Get(c *gin.Context) error {
+	if _, ok := c.GetQuery("uploadId"); ok {
+		return s3gateway.ErrNotImplemented
+	}
+
+	p, err := objectPath(service.key())
+	if err != nil {
+		return err
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	// 目录标记
+	if service.isDir() {
+		exist, folder := fs.IsPathExist(p)
+		if !exist {
+			return s3gateway.ErrNoSuchKey
+		}
+		c.Header("ETag", emptyETag)
+		c.Header("Last-Modified", folder.UpdatedAt.UTC().Format(http.TimeFormat))
+		c.Header("Content-Type", "application/x-directory")
+		c.Header("Content-Length", "0")
+		c.Status(http.StatusOK)
+		return nil
+	}
+
+	exist, file := fs.IsFileExist(p)
+	if !exist {
+		return s3gateway.ErrNoSuchKey
+	}
+	c.Header("ETag", objectETag(file.UpdatedAt, file.Size))
+	c.Header("Accept-Ranges", "bytes")
+
+	// HEAD 请求无需读取文件内容
+	if c.Request.Method == http.MethodHead {
+		c.Header("Last-Modified", file.UpdatedAt.UTC().Format(http.TimeFormat))
+		c.Header("Content-Length", strconv.FormatUint(file.Size, 10))
+		c.Status(http.StatusOK)
+		return nil
+	}
+
+	// 由服务端中转文件内容，不重定向至存储策略，以免客户端无法访问或校验签名
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetDownloadContent(c.Request.Context(), 0)
+	if err != nil {
+		if err == filesystem.ErrObjectNotExist {
+			return s3gateway.ErrNoSuchKey
+		}
+		return s3Error(err)
+	}
+	defer rs.Close()
+
+	response.ServeContent(c.Writer, c.Request, file.Name, file.UpdatedAt, rs)
+	return nil
+}
+
+// Put 上传对象，已存在时覆盖；以 / 结尾的键创建目录
+func (service *ObjectService) Put(c *gin.Context) error {
+	if c.GetHeader("X-Amz-Copy-Source") != "" {
+		return s3gateway.ErrNotImplemented
+	}
+	if _, ok := c.GetQuery("uploadId"); ok {
+		return service.UploadPart(c)
+	}
+
+	p, err := objectPath(service.key())
+	if err != nil {
+		return err
+	}
+
+	size, err := contentLength(c)
+	if err != nil {
+		return err
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if service.isDir() {
+		if size > 0 {
+			return s3gateway.ErrInvalidArgument
+		}
+		if _, err := fs.CreateDirectory(c.Request.Context(), p); err != nil {
+			return s3Error(err)
+		}
+		c.Header("ETag", emptyETag)
+		c.Status(http.StatusOK)
+		return nil
+	}
+
+	file, err := upload(c, fs, p, c.Request.Body, size, c.GetHeader("Content-Type"))
+	if err != nil {
+		return s3Error(err)
+	}
+
+	c.Header("ETag", objectETag(file.UpdatedAt, file.Size))
+	c.Status(http.StatusOK)
+	return nil
+}
+
+// Delete 删除对象，对象不存在时同样视为成功
+func (service *ObjectService) Delete(c *gin.Context) error {
+	if _, ok := c.GetQuery("uploadId"); ok {
+		return service.AbortMultipartUpload(c)
+	}
+
+	fs, err := openBucket(c, service.Bucket)
+	if err != nil {
+		return err
+	}
+	defer fs.Recycle()
+
+	if err := deleteObject(c, fs, service.Key); err != nil {
+		return s3Error(err)
+	}
+
+	c.Status(http.StatusNoContent)
+	return nil
+}
+
+// Post 处理对象上的 POST 请求，用于创建及完成分片上传
+func (service *ObjectService) Post(c *gin.Context) error {
+	if _, ok := c.GetQuery("uploads"); ok {
+		return service.CreateMultipartUpload(c)
+	}
+	if _, ok := c.GetQuery("uploadId"); ok {
+		return service.CompleteMultipartUpload(c)
+	}
+	return s3gateway.ErrNotImplemented
+}
+
+// contentLength 获取请求体长度，aws-chunked 编码时为解码后的长度
+func contentLength(c *gin.Context) (uint64, error) {
+	if decoded := c.GetHeader("X-Amz-Decoded-Content-Length"); decoded != "" {
+		size, err := strconv.ParseUint(decoded, 10, 64)
+		if err != nil {
+			return 0, s3gateway.ErrInvalidArgument
+		}
+		return size, nil
+	}
+
+	if c.Request.ContentLength < 0 {
+		return 0, s3gateway.ErrMissingContentLength
+	}
+	return uint64(c.Request.ContentLength), nil
+}
+
+// deleteObject 删除对象，对象不存在时视为成功。目录标记只在目录为空时删除目录，
+// 与 S3 中删除目录标记不影响其下对象的行为一致
+func deleteObject(c *gin.Context, fs *filesystem.FileSystem, key string) error {
+	p, err := objectPath(key)
+	if err != nil {
+		return err
+	}
+	fs.CleanTargets()
+
+	if key[len(key)-1] == '/' {
+		exist, folder := fs.IsPathExist(p)
+		if !exist {
+			return nil
+		}
+
+		if files, err := folder.GetChildFiles(); err != nil || len(files) > 0 {
+			return err
+		}
+		if folders, err := folder.GetChildFolder(); err != nil || len(folders) > 0 {
+			return err
+		}
+
+		if err := fs.Delete(c.Request.Context(), []uint{folder.ID}, nil, false); err != nil {
+			return err
+		}
+		model.RecordObjectDelete(c, fs.User.ID, []model.Folder{*folder}, nil)
+		return nil
+	}
+
+	exist, file := fs.IsFileExist(p)
+	if !exist {
+		return nil
+	}
+
+	if err := fs.Delete(c.Request.Context(), nil, []uint{file.ID}, false); err != nil {
+		return err
+	}
+	model.RecordObjectDelete(c, fs.User.ID, nil, []model.File{*file})
+	return nil
+}
+
+// upload 将内容上传至指定路径，父目录不存在时自动创建，文件已存在时覆盖
+func upload(c *gin.Context, fs *filesystem.FileSystem, filePath string, content io.Reader, size uint64, mimeType string) (*model.File, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.HTTPCtx, c.Request.Context())
+	ctx = context.WithValue(ctx, fsctx.CancelFuncCtx, cancel)
+
+	fileData := fsctx.FileStream{
+		MIMEType:    mimeType,
+		File:        ioutil.NopCloser(content),
+		Size:        size,
+		Name:        path.Base(filePath),
+		VirtualPath: path.Dir(filePath),
+	}
+
+	if err := fs.UploadOrOverwrite(ctx, &fileData); err != nil {
+		return nil, err
+	}
+
+	return fileData.Model.(*model.File), nil
+}
@@ -0,0 +1,54 @@
+package setting
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// DeviceListService 登录设备列表服务
+type DeviceListService struct {
+}
+
+// DeviceService 登录设备管理服务
+type DeviceService struct {
+	Type string `uri:"type" binding:"required,eq=session|eq=webdav|eq=token"`
+	ID   uint   `uri:"id" binding:"required,min=1"`
+}
+
+// SignOutOthersService 注销其他登录会话服务
+type SignOutOthersService struct {
+}
+
+// Devices 列出登录会话、WebDAV 账户及个人访问令牌
+func (service *DeviceListService) Devices(c *gin.Context, user *model.User) serializer.Response {
+	currentKey, _ := util.GetSession(c, "session_key").(string)
+	return serializer.Response{Data: serializer.BuildDeviceList(
+		model.ListUserSessions(user.ID),
+		currentKey,
+		model.ListWebDAVAccounts(user.ID),
+		model.ListAPITokens(user.ID),
+	)}
+}
+
+// Revoke 注销单个登录会话或撤销应用凭证
+func (service *DeviceService) Revoke(c *gin.Context, user *model.User) serializer.Response {
+	switch service.Type {
+	case "session":
+		model.DeleteUserSessionByID(service.ID, user.ID)
+	case "webdav":
+		model.DeleteWebDAVAccountByID(service.ID, user.ID)
+	case "token":
+		model.DeleteAPITokenByID(service.ID, user.ID)
+	}
+
+	return serializer.Response{}
+}
+
+// SignOut 注销当前会话以外的全部登录会话
+func (service *SignOutOthersService) SignOut(c *gin.Context, user *model.User) serializer.Response {
+	currentKey, _ := util.GetSession(c, "session_key").(string)
+	model.DeleteOtherUserSessions(user.ID, currentKey)
+	return serializer.Response{}
+}
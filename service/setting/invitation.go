@@ -0,0 +1,64 @@
+package setting
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// InvitationListService 注册邀请码列表服务
+type InvitationListService struct {
+}
+
+// InvitationService 注册邀请码管理服务
+type InvitationService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// InvitationCreateService 注册邀请码创建服务
+type InvitationCreateService struct {
+	MaxUses int `json:"max_uses" binding:"min=0,max=1000"`
+	Expires int `json:"expires" binding:"min=0,max=365"` // 有效天数，为 0 时永不过期
+}
+
+// Create 创建注册邀请码，受邀用户加入站点默认用户组
+func (service *InvitationCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if !user.Group.OptionsSerialized.Invite {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "Your group cannot create invitation codes", nil)
+	}
+
+	limit := user.Group.OptionsSerialized.InviteLimit
+	if limit > 0 && model.CountAvailableInvitations(user.ID) >= limit {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "You have reached the maximum number of invitation codes", nil)
+	}
+
+	invitation := model.Invitation{
+		CreatorID: user.ID,
+		MaxUses:   service.MaxUses,
+	}
+	if service.Expires > 0 {
+		expires := time.Now().Add(time.Duration(service.Expires) * 24 * time.Hour)
+		invitation.ExpiredAt = &expires
+	}
+
+	if err := invitation.Create(); err != nil {
+		return serializer.DBErr("Failed to create invitation code", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildInvitation(&invitation, model.GetSiteURL())}
+}
+
+// Delete 删除注册邀请码
+func (service *InvitationService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	model.DeleteInvitationByID(service.ID, user.ID)
+	return serializer.Response{}
+}
+
+// Invitations 列出用户创建的注册邀请码
+func (service *InvitationListService) Invitations(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"invitations": serializer.BuildInvitations(model.ListInvitations(user.ID), model.GetSiteURL()),
+	}}
+}
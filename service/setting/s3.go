@@ -0,0 +1,97 @@
+package setting
+
+import (
+	"regexp"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// s3BucketName 存储桶名称规则，与 S3 一致
+var s3BucketName = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// S3KeyListService S3 访问密钥列表服务
+type S3KeyListService struct {
+}
+
+// S3KeyService S3 访问密钥管理服务
+type S3KeyService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// S3KeyCreateService S3 访问密钥创建服务
+type S3KeyCreateService struct {
+	Name     string `json:"name" binding:"required,min=1,max=255"`
+	Bucket   string `json:"bucket" binding:"required"`
+	Path     string `json:"path" binding:"required,min=1,max=65535"` // 存储桶对应的目录
+	Readonly bool   `json:"readonly"`
+}
+
+// Create 创建访问密钥，签名密钥只在此时返回
+func (service *S3KeyCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if conf.S3Config.Listen == "" {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "S3 gateway is not enabled", nil)
+	}
+
+	if !user.Group.OptionsSerialized.S3Gateway {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	if !s3BucketName.MatchString(service.Bucket) {
+		return serializer.ParamErr("Invalid bucket name", nil)
+	}
+
+	// 目录须存在
+	if service.Path != "/" {
+		fs, err := filesystem.NewFileSystem(user)
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+
+		if exist, _ := fs.IsPathExist(service.Path); !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+	}
+
+	key := &model.S3AccessKey{
+		Name:     service.Name,
+		UserID:   user.ID,
+		Bucket:   service.Bucket,
+		Root:     service.Path,
+		Readonly: service.Readonly,
+	}
+	secret, err := key.Create()
+	if err != nil {
+		return serializer.DBErr("Failed to create access key", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":         key.ID,
+			"access_key": key.AccessKey,
+			"secret":     secret,
+			"bucket":     key.Bucket,
+			"path":       key.Root,
+			"readonly":   key.Readonly,
+			"created_at": key.CreatedAt,
+		},
+	}
+}
+
+// Delete 删除访问密钥
+func (service *S3KeyService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	model.DeleteS3AccessKeyByID(service.ID, user.ID)
+	return serializer.Response{}
+}
+
+// Keys 列出访问密钥
+func (service *S3KeyListService) Keys(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"keys":    model.ListS3AccessKeys(user.ID),
+		"enabled": conf.S3Config.Listen != "" && user.Group.OptionsSerialized.S3Gateway,
+	}}
+}
@@ -0,0 +1,74 @@
+package setting
+
+import (
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+)
+
+// SSHKeyListService SSH 公钥列表服务
+type SSHKeyListService struct {
+}
+
+// SSHKeyService SSH 公钥管理服务
+type SSHKeyService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// SSHKeyCreateService SSH 公钥添加服务
+type SSHKeyCreateService struct {
+	Name      string `json:"name" binding:"required,min=1,max=255"`
+	PublicKey string `json:"public_key" binding:"required,max=16384"` // authorized_keys 格式的公钥
+}
+
+// Create 添加用于 SFTP 登录的公钥
+func (service *SSHKeyCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if conf.SFTPConfig.Listen == "" {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "SFTP server is not enabled", nil)
+	}
+
+	if !user.Group.OptionsSerialized.SFTP {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(service.PublicKey))
+	if err != nil {
+		return serializer.ParamErr("Invalid public key", err)
+	}
+
+	// 同一公钥只能属于一个用户
+	fingerprint := ssh.FingerprintSHA256(publicKey)
+	if _, err := model.GetSSHKeyByFingerprint(fingerprint); err == nil {
+		return serializer.Err(serializer.CodeObjectExist, "Public key already exists", nil)
+	}
+
+	key := &model.SSHKey{
+		Name:        service.Name,
+		UserID:      user.ID,
+		PublicKey:   strings.TrimSpace(string(ssh.MarshalAuthorizedKey(publicKey))),
+		Fingerprint: fingerprint,
+	}
+	if _, err := key.Create(); err != nil {
+		return serializer.DBErr("Failed to add public key", err)
+	}
+
+	return serializer.Response{Data: key}
+}
+
+// Delete 删除公钥
+func (service *SSHKeyService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	model.DeleteSSHKeyByID(service.ID, user.ID)
+	return serializer.Response{}
+}
+
+// Keys 列出公钥
+func (service *SSHKeyListService) Keys(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"keys":    model.ListSSHKeys(user.ID),
+		"enabled": conf.SFTPConfig.Listen != "" && user.Group.OptionsSerialized.SFTP,
+	}}
+}
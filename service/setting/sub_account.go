@@ -0,0 +1,153 @@
+package setting
+
+import (
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// SubAccountListService 子账户列表服务
+type SubAccountListService struct {
+}
+
+// SubAccountService 子账户管理服务
+type SubAccountService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// SubAccountCreateService 子账户创建服务
+type SubAccountCreateService struct {
+	UserName string `json:"userName" binding:"required,email,max=100"`
+	Nick     string `json:"nick" binding:"max=50"`
+	Password string `json:"password" binding:"required,min=4,max=64"`
+	Folder   string `json:"folder" binding:"required,max=65535"`
+}
+
+// SubAccountUpdateService 子账户修改服务，字段为空时不修改
+type SubAccountUpdateService struct {
+	Password string `json:"password" binding:"omitempty,min=4,max=64"`
+	Folder   string `json:"folder" binding:"max=65535"`
+	Status   *int   `json:"status" binding:"omitempty,eq=0|eq=2"`
+}
+
+// checkSubAccountFolder 检查子账户可访问的目录是否存在，且不能为根目录
+func checkSubAccountFolder(user *model.User, folder string) (string, serializer.Response) {
+	folder = path.Clean("/" + folder)
+	if folder == "/" {
+		return "", serializer.ParamErr("Sub-accounts cannot access the root folder", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return "", serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	if exist, _ := fs.IsPathExist(folder); !exist {
+		return "", serializer.Err(serializer.CodeParentNotExist, "", nil)
+	}
+	return folder, serializer.Response{}
+}
+
+// Create 创建子账户，子账户只能访问给定的目录，占用母账户的容量
+func (service *SubAccountCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	limit := user.Group.OptionsSerialized.SubAccounts
+	if limit <= 0 {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "Your group cannot create sub-accounts", nil)
+	}
+	if model.CountSubAccounts(user.ID) >= limit {
+		return serializer.Err(serializer.CodeGroupNotAllowed, "You have reached the maximum number of sub-accounts", nil)
+	}
+
+	folder, res := checkSubAccountFolder(user, service.Folder)
+	if res.Code != 0 {
+		return res
+	}
+
+	if err := password.NewPolicy().Validate(service.Password); err != nil {
+		return serializer.Err(serializer.CodePasswordPolicy, err.Error(), err)
+	}
+
+	sub := model.NewUser()
+	sub.Email = service.UserName
+	sub.Nick = service.Nick
+	if sub.Nick == "" {
+		sub.Nick = strings.Split(service.UserName, "@")[0]
+	}
+	sub.SetPassword(service.Password)
+	sub.Status = model.Active
+	sub.GroupID = user.GroupID
+	sub.ParentID = user.ID
+	sub.Folder = folder
+	if err := model.DB.Create(&sub).Error; err != nil {
+		return serializer.Err(serializer.CodeEmailExisted, "Email already in use", err)
+	}
+
+	return serializer.Response{Data: serializer.BuildSubAccount(sub)}
+}
+
+// Update 修改子账户的密码、可访问目录或状态
+func (service *SubAccountUpdateService) Update(c *gin.Context, user *model.User, id uint) serializer.Response {
+	sub, err := model.GetSubAccount(id, user.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "", err)
+	}
+
+	updates := make(map[string]interface{})
+	if service.Folder != "" {
+		folder, res := checkSubAccountFolder(user, service.Folder)
+		if res.Code != 0 {
+			return res
+		}
+		updates["folder"] = folder
+	}
+
+	if service.Password != "" {
+		if err := password.NewPolicy().Validate(service.Password); err != nil {
+			return serializer.Err(serializer.CodePasswordPolicy, err.Error(), err)
+		}
+		sub.SetPassword(service.Password)
+		updates["password"] = sub.Password
+	}
+
+	if service.Status != nil {
+		updates["status"] = *service.Status
+	}
+
+	if err := sub.Update(updates); err != nil {
+		return serializer.DBErr("Failed to update sub-account", err)
+	}
+
+	// 修改密码或停用后注销子账户已登录的会话
+	if service.Password != "" || (service.Status != nil && *service.Status != model.Active) {
+		model.DeleteOtherUserSessions(sub.ID, "")
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 删除子账户
+func (service *SubAccountService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	if err := model.DeleteSubAccount(service.ID, user.ID); err != nil {
+		return serializer.DBErr("Failed to delete sub-account", err)
+	}
+	return serializer.Response{}
+}
+
+// SubAccounts 列出子账户
+func (service *SubAccountListService) SubAccounts(c *gin.Context, user *model.User) serializer.Response {
+	subs := model.ListSubAccounts(user.ID)
+	res := make([]serializer.SubAccount, 0, len(subs))
+	for _, sub := range subs {
+		res = append(res, serializer.BuildSubAccount(sub))
+	}
+
+	return serializer.Response{Data: map[string]interface{}{
+		"sub_accounts": res,
+	}}
+}
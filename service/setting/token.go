@@ -0,0 +1,81 @@
+package setting
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// APITokenListService 个人访问令牌列表服务
+type APITokenListService struct {
+}
+
+// APITokenService 个人访问令牌管理服务
+type APITokenService struct {
+	ID uint `uri:"id" binding:"required,min=1"`
+}
+
+// APITokenCreateService 个人访问令牌创建服务
+type APITokenCreateService struct {
+	Name    string `json:"name" binding:"required,min=1,max=255"`
+	Scope   string `json:"scope" binding:"required,eq=read|eq=upload|eq=full"`
+	Folder  string `json:"folder" binding:"max=65535"`
+	Expires int    `json:"expires" binding:"min=0,max=3650"` // 有效天数，为 0 时永不过期
+}
+
+// Create 创建个人访问令牌
+func (service *APITokenCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
+	token := model.APIToken{
+		Name:   service.Name,
+		UserID: user.ID,
+		Scope:  service.Scope,
+	}
+
+	// 限定的目录须存在
+	if service.Folder != "" && service.Folder != "/" {
+		fs, err := filesystem.NewFileSystem(user)
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+
+		if exist, _ := fs.IsPathExist(service.Folder); !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+		token.Folder = service.Folder
+	}
+
+	if service.Expires > 0 {
+		expires := time.Now().Add(time.Duration(service.Expires) * 24 * time.Hour)
+		token.ExpiredAt = &expires
+	}
+
+	raw, err := token.Create()
+	if err != nil {
+		return serializer.DBErr("Failed to create API token", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":         token.ID,
+			"token":      raw,
+			"created_at": token.CreatedAt,
+		},
+	}
+}
+
+// Delete 撤销个人访问令牌
+func (service *APITokenService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	model.DeleteAPITokenByID(service.ID, user.ID)
+	return serializer.Response{}
+}
+
+// Tokens 列出个人访问令牌
+func (service *APITokenListService) Tokens(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: map[string]interface{}{
+		"tokens": model.ListAPITokens(user.ID),
+	}}
+}
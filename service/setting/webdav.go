@@ -1,7 +1,10 @@
 package setting
 
 import (
+	"time"
+
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -18,8 +21,11 @@ type WebDAVAccountService struct {
 
 // WebDAVAccountCreateService WebDAV 账号创建服务
 type WebDAVAccountCreateService struct {
-	Path string `json:"path" binding:"required,min=1,max=65535"`
-	Name string `json:"name" binding:"required,min=1,max=255"`
+	Path     string `json:"path" binding:"required,min=1,max=65535"`
+	Name     string `json:"name" binding:"required,min=1,max=255"`
+	Readonly bool   `json:"readonly"`
+	Expires  int    `json:"expires" binding:"min=0,max=3650"` // 有效天数，为 0 时永不过期
+	Share    string `json:"share" binding:"max=255"`          // 挂载的分享标识，为空时挂载自己的文件，Path 为分享目录下的路径
 }
 
 // WebDAVMountCreateService WebDAV 挂载创建服务
@@ -32,9 +38,48 @@ type WebDAVMountCreateService struct {
 func (service *WebDAVAccountCreateService) Create(c *gin.Context, user *model.User) serializer.Response {
 	account := model.Webdav{
 		Name:     service.Name,
-		Password: util.RandStringRunes(32),
+		Password: util.RandSecureString(32),
 		UserID:   user.ID,
 		Root:     service.Path,
+		Readonly: service.Readonly,
+	}
+
+	if service.Expires > 0 {
+		expires := time.Now().Add(time.Duration(service.Expires) * 24 * time.Hour)
+		account.ExpiredAt = &expires
+	}
+
+	// 挂载分享时以分享目录为根，只能只读访问
+	owner := user
+	var shareRoot *model.Folder
+	if service.Share != "" {
+		share := model.GetShareByHashID(service.Share)
+		if share == nil {
+			return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+		}
+		if err := share.CanBeMountedBy(user); err != nil {
+			return serializer.Err(serializer.CodeNoPermissionErr, err.Error(), err)
+		}
+		account.ShareID = share.ID
+		account.Readonly = true
+		owner = share.Creator()
+		shareRoot = share.SourceFolder()
+		shareRoot.Position = ""
+		shareRoot.Name = "/"
+	}
+
+	// 根目录须存在
+	if service.Path != "/" {
+		fs, err := filesystem.NewFileSystem(owner)
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+
+		fs.Root = shareRoot
+		if exist, _ := fs.IsPathExist(service.Path); !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
 	}
 
 	if _, err := account.Create(); err != nil {
@@ -46,6 +91,9 @@ func (service *WebDAVAccountCreateService) Create(c *gin.Context, user *model.Us
 			"id":         account.ID,
 			"password":   account.Password,
 			"created_at": account.CreatedAt,
+			"readonly":   account.Readonly,
+			"share_id":   account.ShareID,
+			"expired_at": account.ExpiredAt,
 		},
 	}
 }
@@ -0,0 +1,36 @@
+package share
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// AnalyticsService 分享访问统计服务
+type AnalyticsService struct {
+	Page uint `form:"page" binding:"required,min=1"`
+}
+
+// Analytics 获取分享的访问统计及访问记录
+func (service *AnalyticsService) Analytics(c *gin.Context, user *model.User) serializer.Response {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	logs, total := model.ListShareAccessLogs(share.ID, int(service.Page), 50)
+	events := model.CountShareAccess(share.ID)
+
+	return serializer.Response{Data: map[string]interface{}{
+		"views":     share.Views,
+		"downloads": share.Downloads,
+		"recorded": map[string]int{
+			"views":     events[model.ShareAccessView],
+			"downloads": events[model.ShareAccessDownload],
+		},
+		"referrers": model.TopShareAccess(share.ID, "referrer", 10),
+		"countries": model.TopShareAccess(share.ID, "country", 10),
+		"total":     total,
+		"items":     logs,
+	}}
+}
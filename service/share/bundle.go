@@ -0,0 +1,135 @@
+package share
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+)
+
+// buildBundle 解析合集分享包含的文件和目录，返回其原始ID及用于搜索的名称。
+// 合集中顶层对象的名称不能重复，以便通过路径定位
+func buildBundle(user *model.User, items, dirs []string) ([]uint, []uint, string, error) {
+	raw := (&explorer.ItemIDService{Items: items, Dirs: dirs}).Raw()
+	if len(raw.Items) != len(items) || len(raw.Dirs) != len(dirs) {
+		return nil, nil, "", serializer.NewError(serializer.CodeNotFound, "", nil)
+	}
+
+	var (
+		files   []model.File
+		folders []model.Folder
+	)
+	if len(raw.Items) > 0 {
+		files, _ = model.GetFilesByIDs(raw.Items, user.ID)
+	}
+	if len(raw.Dirs) > 0 {
+		folders, _ = model.GetFoldersByIDs(raw.Dirs, user.ID)
+	}
+	if len(files) != len(raw.Items) || len(folders) != len(raw.Dirs) {
+		return nil, nil, "", serializer.NewError(serializer.CodeNotFound, "", nil)
+	}
+
+	names := make([]string, 0, len(files)+len(folders))
+	for _, folder := range folders {
+		names = append(names, folder.Name)
+	}
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+
+	existed := make(map[string]bool, len(names))
+	for _, name := range names {
+		if existed[name] {
+			return nil, nil, "", serializer.NewError(serializer.CodeParamErr, "Items in a bundle must have unique names: "+name, nil)
+		}
+		existed[name] = true
+	}
+
+	// 搜索字段长度有限，超出部分截断
+	sourceName := []rune(strings.Join(names, " "))
+	if len(sourceName) > 255 {
+		sourceName = sourceName[:255]
+	}
+
+	return raw.Items, raw.Dirs, string(sourceName), nil
+}
+
+// sharedSource 返回 Path 所在的分享源对象及其下的相对路径，
+// 合集分享会先定位到路径对应的顶层文件或目录
+func (service *Service) sharedSource(share *model.Share) (interface{}, string, bool) {
+	if !share.Bundle {
+		return share.Source(), service.Path, true
+	}
+
+	folder, rel, file := share.ResolveBundlePath(service.Path)
+	if file != nil {
+		return file, "", true
+	}
+	if folder != nil {
+		return folder, rel, true
+	}
+	return nil, "", false
+}
+
+// listBundle 列出合集分享中的对象，根目录下列出合集包含的文件和目录
+func (service *Service) listBundle(ctx context.Context, fs *filesystem.FileSystem, share *model.Share) ([]serializer.Object, error) {
+	if path.Clean(service.Path) == "/" {
+		files, folders := share.BundleSources()
+		return fs.ListItems(ctx, "/", files, folders), nil
+	}
+
+	folder, rel, _ := share.ResolveBundlePath(service.Path)
+	if folder == nil {
+		return nil, filesystem.ErrPathNotExist
+	}
+
+	// 以顶层目录为根目录列取，并还原对象在合集中的路径
+	fs.Root = folder
+	prefix := "/" + folder.Name
+	fs.Root.Name = "/"
+	return fs.List(ctx, rel, func(p string) string {
+		return path.Join(prefix, p)
+	})
+}
+
+// isBundleFile 返回文件是否为合集分享中的顶层文件
+func isBundleFile(share *model.Share, fileID uint) bool {
+	files, _ := share.BundleSources()
+	for _, file := range files {
+		if file.ID == fileID {
+			return true
+		}
+	}
+	return false
+}
+
+// bundleArchiveItems 筛选合集根目录下待打包对象的 HashID，
+// 仅保留合集中的对象，均未指定时返回合集中的全部对象
+func bundleArchiveItems(share *model.Share, items, dirs []string) ([]string, []string) {
+	all := len(items)+len(dirs) == 0
+	selected := make(map[string]bool, len(items)+len(dirs))
+	for _, id := range append(items, dirs...) {
+		selected[id] = true
+	}
+
+	files, folders := share.BundleSources()
+	resItems := make([]string, 0, len(files))
+	resDirs := make([]string, 0, len(folders))
+	for _, file := range files {
+		if id := hashid.HashID(file.ID, hashid.FileID); all || selected[id] {
+			resItems = append(resItems, id)
+		}
+	}
+	for _, folder := range folders {
+		if id := hashid.HashID(folder.ID, hashid.FolderID); all || selected[id] {
+			resDirs = append(resDirs, id)
+		}
+	}
+
+	return resItems, resDirs
+}
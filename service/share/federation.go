@@ -0,0 +1,257 @@
+package share
+
+import (
+	"context"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/federation"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FederateService 将分享提供给其他实例用户的服务
+type FederateService struct {
+	Instance string `json:"instance" binding:"required,url,max=255"`
+	Email    string `json:"email" binding:"required,email"`
+}
+
+// FederationOfferService 接收其他实例提供的联邦分享的服务
+type FederationOfferService struct {
+	Provider string `json:"provider" binding:"required,url,max=255"`
+	Token    string `json:"token" binding:"required,len=32"`
+	Email    string `json:"email" binding:"required,email"`
+	Name     string `json:"name" binding:"required,max=255"`
+	Owner    string `json:"owner" binding:"max=255"`
+	IsDir    bool   `json:"is_dir"`
+}
+
+// FederatedShareService 供接收方实例确认联邦分享的服务
+type FederatedShareService struct {
+}
+
+// RemoteShareListService 列出收到的远程分享的服务
+type RemoteShareListService struct {
+}
+
+// RemoteShareService 操作远程分享的服务，path 为远程分享目录下的路径
+type RemoteShareService struct {
+	Path string `form:"path" uri:"path" binding:"max=65535"`
+}
+
+// federationEnabled 返回是否已启用联邦分享
+func federationEnabled() bool {
+	return model.IsTrueVal(model.GetSettingByName("federation_enabled"))
+}
+
+// Federate 生成联邦分享令牌并提供给接收方实例
+func (service *FederateService) Federate(c *gin.Context) serializer.Response {
+	if !federationEnabled() {
+		return serializer.Err(serializer.CodeFederationNotAllowed, "联邦分享未启用", nil)
+	}
+
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	if share.Internal {
+		return serializer.Err(serializer.CodeNoPermissionErr, "内部分享无法提供给其他实例", nil)
+	}
+
+	if !federation.IsTrustedInstance(service.Instance) {
+		return serializer.Err(serializer.CodeFederationNotAllowed, "对方实例不受信任", nil)
+	}
+
+	client, err := federation.NewClient(service.Instance)
+	if err != nil {
+		return serializer.ParamErr("Invalid instance url", err)
+	}
+
+	federated := model.NewFederatedShare(share.ID, service.Instance, service.Email)
+	if _, err := federated.Create(); err != nil {
+		return serializer.DBErr("Failed to create federated share", err)
+	}
+
+	offer := &serializer.FederationOffer{
+		Provider: model.GetSiteURL().String(),
+		Token:    federated.Token,
+		Email:    service.Email,
+		Name:     share.SourceName,
+		Owner:    share.Creator().Nick,
+		IsDir:    share.IsDir || share.Bundle,
+	}
+	if err := client.Offer(context.Background(), offer); err != nil {
+		// 对方未接收时令牌不应继续有效
+		federated.Delete()
+		return serializer.Err(serializer.CodeNotSet, "对方实例未能接收分享", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Receive 为本实例中的接收者记录其他实例提供的联邦分享。请求无需登录，
+// 因此需先向受信任的分享方实例确认令牌有效，并以分享方返回的信息为准；
+// 接收者是否存在均返回相同的结果，避免被用于探测本实例的用户邮箱
+func (service *FederationOfferService) Receive(c *gin.Context) serializer.Response {
+	if !federationEnabled() {
+		return serializer.Err(serializer.CodeFederationNotAllowed, "联邦分享未启用", nil)
+	}
+
+	if !federation.IsTrustedInstance(service.Provider) {
+		return serializer.Err(serializer.CodeFederationNotAllowed, "对方实例不受信任", nil)
+	}
+
+	client, err := federation.NewClient(service.Provider)
+	if err != nil {
+		return serializer.ParamErr("Invalid provider url", err)
+	}
+
+	offer, err := client.Verify(context.Background(), service.Token)
+	if err != nil || !strings.EqualFold(offer.Email, service.Email) {
+		return serializer.Err(serializer.CodeFederationNotAllowed, "无法确认分享", err)
+	}
+
+	user, err := model.GetActiveUserByEmail(service.Email)
+	if err != nil {
+		return serializer.Response{}
+	}
+
+	remote := &model.RemoteShare{
+		UserID:   user.ID,
+		Provider: service.Provider,
+		Token:    service.Token,
+		Name:     offer.Name,
+		Owner:    offer.Owner,
+		IsDir:    offer.IsDir,
+	}
+	if _, err := remote.Create(); err != nil {
+		return serializer.DBErr("Failed to create remote share", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Get 返回路由参数中令牌对应的联邦分享信息，供接收方实例确认分享
+func (service *FederatedShareService) Get(c *gin.Context) serializer.Response {
+	federated, err := model.GetFederatedShareByToken(c.Param("token"))
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "分享不存在或已失效", err)
+	}
+
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	return serializer.Response{Data: serializer.FederationOffer{
+		Provider: model.GetSiteURL().String(),
+		Token:    federated.Token,
+		Email:    federated.Email,
+		Name:     share.SourceName,
+		Owner:    share.Creator().Nick,
+		IsDir:    share.IsDir || share.Bundle,
+	}}
+}
+
+// List 列出用户收到的远程分享
+func (service *RemoteShareListService) List(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.BuildRemoteShareList(model.ListRemoteShares(user.ID))
+}
+
+// remote 获取路由参数中当前用户的远程分享及其分享方实例客户端
+func (service *RemoteShareService) remote(c *gin.Context, user *model.User) (*model.RemoteShare, federation.Client, error) {
+	id, err := hashid.DecodeHashID(c.Param("id"), hashid.RemoteShareID)
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeNotFound, "远程分享不存在", err)
+	}
+
+	remote, err := model.GetRemoteShare(id, user.ID)
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeNotFound, "远程分享不存在", err)
+	}
+
+	client, err := federation.NewClient(remote.Provider)
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeNotSet, "无效的分享方实例", err)
+	}
+
+	return remote, client, nil
+}
+
+// accepted 获取已接受的远程分享
+func (service *RemoteShareService) accepted(c *gin.Context, user *model.User) (*model.RemoteShare, federation.Client, error) {
+	remote, client, err := service.remote(c, user)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !remote.Accepted {
+		return nil, nil, serializer.NewError(serializer.CodeNoPermissionErr, "尚未接受此远程分享", nil)
+	}
+
+	return remote, client, nil
+}
+
+// Accept 接受远程分享，目录分享会先向分享方实例确认其仍然有效
+func (service *RemoteShareService) Accept(c *gin.Context, user *model.User) serializer.Response {
+	remote, client, err := service.remote(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	if remote.IsDir {
+		if _, err := client.List(context.Background(), remote.Token, "/"); err != nil {
+			return serializer.Err(serializer.CodeNotSet, "无法访问远程分享", err)
+		}
+	}
+
+	if err := remote.Accept(); err != nil {
+		return serializer.DBErr("Failed to accept remote share", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Delete 拒绝或移除远程分享
+func (service *RemoteShareService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	remote, _, err := service.remote(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	if err := remote.Delete(); err != nil {
+		return serializer.DBErr("Failed to delete remote share", err)
+	}
+
+	return serializer.Response{}
+}
+
+// ListDirectory 列出远程分享目录下的对象
+func (service *RemoteShareService) ListDirectory(c *gin.Context, user *model.User) serializer.Response {
+	remote, client, err := service.accepted(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	if !remote.IsDir {
+		return serializer.ParamErr("This is not a shared folder", nil)
+	}
+
+	list, err := client.List(context.Background(), remote.Token, service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "无法访问远程分享", err)
+	}
+
+	return serializer.Response{Data: list}
+}
+
+// Download 获取远程分享中文件在分享方实例上的下载地址
+func (service *RemoteShareService) Download(c *gin.Context, user *model.User) serializer.Response {
+	remote, client, err := service.accepted(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "", err)
+	}
+
+	downloadURL, err := client.Download(context.Background(), remote.Token, service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "无法访问远程分享", err)
+	}
+
+	return serializer.Response{Data: downloadURL}
+}
@@ -0,0 +1,84 @@
+package share
+
+import (
+	"net/url"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// MailService 通过邮件发送分享链接的服务
+type MailService struct {
+	Emails       []string `json:"emails" binding:"required,min=1,max=20,dive,email"`
+	Message      string   `json:"message" binding:"max=1000"`
+	WithPassword bool     `json:"with_password"`
+}
+
+// MailLogService 列出分享邮件发送记录的服务
+type MailLogService struct {
+	Page uint `form:"page" binding:"required,min=1"`
+}
+
+// mailResult 单个收件人的发送结果
+type mailResult struct {
+	Email   string `json:"email"`
+	Success bool   `json:"success"`
+}
+
+// Send 以当前用户的名义将分享链接发送至给定邮箱，开启 WithPassword 时分享密码会通过另一封邮件单独发送
+func (service *MailService) Send(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	sharePath, _ := url.Parse("/s/" + share.Key())
+	shareURL := model.GetSiteURL().ResolveReference(sharePath).String()
+
+	title, body := email.NewShareEmail(user.Nick, share.SourceName, shareURL, service.Message)
+	var pwdTitle, pwdBody string
+	if service.WithPassword && share.Password != "" {
+		pwdTitle, pwdBody = email.NewSharePasswordEmail(user.Nick, share.SourceName, share.Password)
+	}
+
+	results := make([]mailResult, 0, len(service.Emails))
+	sent := 0
+	for _, to := range service.Emails {
+		err := email.Send(to, title, body)
+		if err == nil && pwdBody != "" {
+			err = email.Send(to, pwdTitle, pwdBody)
+		}
+		if err != nil {
+			util.Log().Warning("无法发送分享 [%d] 至 %s, %s", share.ID, to, err)
+		} else {
+			sent++
+		}
+
+		log := &model.ShareMailLog{ShareID: share.ID, Email: to, Success: err == nil}
+		log.Create()
+		results = append(results, mailResult{Email: to, Success: err == nil})
+	}
+
+	if sent == 0 {
+		return serializer.Err(serializer.CodeFailedSendEmail, "", nil)
+	}
+
+	return serializer.Response{Data: results}
+}
+
+// List 列出分享的邮件发送记录
+func (service *MailLogService) List(c *gin.Context, user *model.User) serializer.Response {
+	share := model.GetShareByHashID(c.Param("id"))
+	if share == nil || share.UserID != user.ID {
+		return serializer.Err(serializer.CodeShareLinkNotFound, "", nil)
+	}
+
+	logs, total := model.ListShareMailLogs(share.ID, int(service.Page), 50)
+	return serializer.Response{Data: map[string]interface{}{
+		"total": total,
+		"items": logs,
+	}}
+}
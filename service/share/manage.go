@@ -2,28 +2,58 @@ package share
 
 import (
 	"net/url"
+	"strings"
 	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 )
 
 // ShareCreateService 创建新分享服务
 type ShareCreateService struct {
-	SourceID        string `json:"id" binding:"required"`
-	IsDir           bool   `json:"is_dir"`
-	Password        string `json:"password" binding:"max=255"`
-	RemainDownloads int    `json:"downloads"`
-	Expire          int    `json:"expire"`
-	Preview         bool   `json:"preview"`
+	SourceID         string `json:"id" binding:"required_without_all=Items Dirs"`
+	IsDir            bool   `json:"is_dir"`
+	Password         string `json:"password" binding:"max=255"`
+	RemainDownloads  int    `json:"downloads"`
+	Expire           int    `json:"expire"`
+	Preview          bool   `json:"preview"`
+	DisableDownload  bool   `json:"disable_download"`
+	AllowUpload      bool   `json:"allow_upload"`
+	UploadSizeLimit  uint64 `json:"upload_size_limit"`
+	UploadCountLimit int    `json:"upload_count_limit" binding:"min=0"`
+	ExpireAction     int    `json:"expire_action" binding:"min=0,max=2"`
+	Slug             string `json:"slug" binding:"max=64"`
+	TrafficLimit     uint64 `json:"traffic_limit"`
+	Watermark        bool   `json:"watermark"`
+	WatermarkText    string `json:"watermark_text" binding:"max=255"`
+	Description      string `json:"description" binding:"max=2000"`
+	// 访问来源限制，分别为逗号分隔的 CIDR 网段及国家/地区代码
+	AllowedIPs       string `json:"allowed_ips" binding:"max=2000"`
+	AllowedCountries string `json:"allowed_countries" binding:"max=255"`
+	// 内部分享的接收者，非空时分享仅对接收者可见
+	Recipients []ShareRecipientParam `json:"recipients" binding:"dive"`
+	// 合集分享包含的文件和目录，非空时忽略 SourceID
+	Items []string `json:"items"`
+	Dirs  []string `json:"dirs"`
+}
+
+// ShareRecipientParam 内部分享接收者，Email 与 GroupID 二选一
+type ShareRecipientParam struct {
+	Email    string `json:"email" binding:"omitempty,email"`
+	GroupID  uint   `json:"group"`
+	Writable bool   `json:"writable"`
+	// 接收者的角色，可选 view、download、upload、edit、delete、reshare，
+	// 留空时可浏览、下载，并由 Writable 决定能否上传
+	Roles []string `json:"roles"`
 }
 
 // ShareUpdateService 分享更新服务
 type ShareUpdateService struct {
-	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled"`
-	Value string `json:"value" binding:"max=255"`
+	Prop  string `json:"prop" binding:"required,eq=password|eq=preview_enabled|eq=download_enabled|eq=slug|eq=description|eq=allowed_ips|eq=allowed_countries"`
+	Value string `json:"value" binding:"max=2000"`
 }
 
 // Delete 删除分享
@@ -37,15 +67,107 @@ func (service *Service) Delete(c *gin.Context, user *model.User) serializer.Resp
 		return serializer.DBErr("Failed to delete share record", err)
 	}
 
+	model.RecordShareRevoke(c, user.ID, []model.Share{*share}, "deleted by owner")
 	return serializer.Response{}
 }
 
+// checkSlug 检查用户是否可以使用给定的自定义分享链接
+func checkSlug(user *model.User, slug string) (string, error) {
+	if !user.Group.OptionsSerialized.ShareSlug {
+		return "", serializer.NewError(serializer.CodeGroupNotAllowed, "", nil)
+	}
+
+	slug, err := model.ValidateShareSlug(slug)
+	if err != nil {
+		return "", serializer.NewError(serializer.CodeParamErr, err.Error(), err)
+	}
+
+	if model.IsShareSlugExisted(slug) {
+		return "", serializer.NewError(serializer.CodeShareSlugExisted, "Slug already in use", nil)
+	}
+
+	return slug, nil
+}
+
+// normalizeCountries 将国家/地区代码列表统一为大写并以逗号分隔
+func normalizeCountries(countries string) string {
+	return strings.Join(util.SplitList(strings.ToUpper(countries)), ",")
+}
+
+// buildRecipients 将请求中的接收者转换为接收者记录
+func buildRecipients(params []ShareRecipientParam, isDir bool) ([]model.ShareRecipient, error) {
+	recipients := make([]model.ShareRecipient, 0, len(params))
+	for _, param := range params {
+		roles := model.ShareRoleView | model.ShareRoleDownload
+		if len(param.Roles) > 0 {
+			var err error
+			if roles, err = model.ParseShareRoles(param.Roles); err != nil {
+				return nil, serializer.NewError(serializer.CodeParamErr, err.Error(), err)
+			}
+		} else if param.Writable {
+			roles |= model.ShareRoleUpload
+		}
+
+		// 文件分享下不能修改目录内容
+		if !isDir {
+			roles &^= model.ShareRoleUpload | model.ShareRoleEdit | model.ShareRoleDelete
+		}
+
+		recipient := model.ShareRecipient{Roles: roles, Writable: roles&model.ShareRoleUpload != 0}
+		if param.Email != "" {
+			user, err := model.GetActiveUserByEmail(param.Email)
+			if err != nil {
+				return nil, serializer.NewError(serializer.CodeUserNotFound, "Recipient not found: "+param.Email, err)
+			}
+			recipient.UserID = user.ID
+		} else if param.GroupID != 0 {
+			if _, err := model.GetGroupByID(param.GroupID); err != nil {
+				return nil, serializer.NewError(serializer.CodeGroupNotFound, "", err)
+			}
+			recipient.GroupID = param.GroupID
+		} else {
+			return nil, serializer.NewError(serializer.CodeParamErr, "Recipient must be a user or a group", nil)
+		}
+
+		recipients = append(recipients, recipient)
+	}
+
+	return recipients, nil
+}
+
 // Update 更新分享属性
 func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
+	// 仅描述及IP限制允许较长的内容
+	if service.Prop != "description" && service.Prop != "allowed_ips" && len(service.Value) > 255 {
+		return serializer.ParamErr("Value is too long", nil)
+	}
+
 	switch service.Prop {
+	case "description":
+		err := share.Update(map[string]interface{}{"description": service.Value})
+		if err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+	case "allowed_ips":
+		if _, err := util.ParseIPRanges(service.Value); err != nil {
+			return serializer.ParamErr(err.Error(), err)
+		}
+		err := share.Update(map[string]interface{}{"allowed_ips": service.Value})
+		if err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+	case "allowed_countries":
+		value := normalizeCountries(service.Value)
+		err := share.Update(map[string]interface{}{"allowed_countries": value})
+		if err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+		return serializer.Response{
+			Data: value,
+		}
 	case "password":
 		err := share.Update(map[string]interface{}{"password": service.Value})
 		if err != nil {
@@ -60,6 +182,35 @@ func (service *ShareUpdateService) Update(c *gin.Context) serializer.Response {
 		return serializer.Response{
 			Data: value,
 		}
+	case "download_enabled":
+		value := service.Value == "true"
+		err := share.Update(map[string]interface{}{"download_disabled": !value})
+		if err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+		return serializer.Response{
+			Data: value,
+		}
+	case "slug":
+		// 留空表示恢复为随机Key
+		var value *string
+		if service.Value != "" {
+			userCtx, _ := c.Get("user")
+			user := userCtx.(*model.User)
+			slug, err := checkSlug(user, service.Value)
+			if err != nil {
+				return serializer.Err(serializer.CodeNotSet, "", err)
+			}
+			value = &slug
+		}
+
+		if err := share.Update(map[string]interface{}{"slug": value}); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+		share.Slug = value
+		return serializer.Response{
+			Data: share.Key(),
+		}
 	}
 	return serializer.Response{
 		Data: service.Value,
@@ -78,48 +229,106 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 
 	// 源对象真实ID
 	var (
-		sourceID   uint
-		sourceName string
-		err        error
+		sourceID      uint
+		sourceName    string
+		bundleFiles   []uint
+		bundleFolders []uint
+		err           error
 	)
-	if service.IsDir {
-		sourceID, err = hashid.DecodeHashID(service.SourceID, hashid.FolderID)
-	} else {
-		sourceID, err = hashid.DecodeHashID(service.SourceID, hashid.FileID)
-	}
-	if err != nil {
-		return serializer.Err(serializer.CodeNotFound, "", nil)
-	}
 
-	// 对象是否存在
-	exist := true
-	if service.IsDir {
-		folder, err := model.GetFoldersByIDs([]uint{sourceID}, user.ID)
-		if err != nil || len(folder) == 0 {
-			exist = false
-		} else {
-			sourceName = folder[0].Name
+	isBundle := len(service.Items)+len(service.Dirs) > 0
+	if isBundle {
+		service.IsDir = false
+		bundleFiles, bundleFolders, sourceName, err = buildBundle(user, service.Items, service.Dirs)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, "", err)
 		}
 	} else {
-		file, err := model.GetFilesByIDs([]uint{sourceID}, user.ID)
-		if err != nil || len(file) == 0 {
-			exist = false
+		if service.IsDir {
+			sourceID, err = hashid.DecodeHashID(service.SourceID, hashid.FolderID)
 		} else {
-			sourceName = file[0].Name
+			sourceID, err = hashid.DecodeHashID(service.SourceID, hashid.FileID)
+		}
+		if err != nil {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
+		}
+
+		// 对象是否存在
+		exist := true
+		if service.IsDir {
+			folder, err := model.GetFoldersByIDs([]uint{sourceID}, user.ID)
+			if err != nil || len(folder) == 0 {
+				exist = false
+			} else {
+				sourceName = folder[0].Name
+			}
+		} else {
+			file, err := model.GetFilesByIDs([]uint{sourceID}, user.ID)
+			if err != nil || len(file) == 0 {
+				exist = false
+			} else {
+				sourceName = file[0].Name
+			}
+		}
+		if !exist {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
 		}
 	}
-	if !exist {
-		return serializer.Err(serializer.CodeNotFound, "", nil)
+
+	// 仅目录分享可开启访客上传
+	if service.AllowUpload && !service.IsDir {
+		return serializer.ParamErr("Only shared folders can accept uploads", nil)
 	}
 
 	newShare := model.Share{
-		Password:        service.Password,
-		IsDir:           service.IsDir,
-		UserID:          user.ID,
-		SourceID:        sourceID,
-		RemainDownloads: -1,
-		PreviewEnabled:  service.Preview,
-		SourceName:      sourceName,
+		Password:         service.Password,
+		IsDir:            service.IsDir,
+		UserID:           user.ID,
+		SourceID:         sourceID,
+		RemainDownloads:  -1,
+		PreviewEnabled:   service.Preview,
+		DownloadDisabled: service.DisableDownload,
+		SourceName:       sourceName,
+		AllowUpload:      service.AllowUpload,
+		Watermark:        service.Watermark,
+		WatermarkText:    service.WatermarkText,
+		Description:      service.Description,
+		Bundle:           isBundle,
+		Secret:           util.RandSecureString(16),
+	}
+
+	// 下载流量上限，不能超过用户组限制
+	newShare.TrafficLimit = service.TrafficLimit
+	if groupLimit := user.Group.OptionsSerialized.ShareTraffic; groupLimit > 0 &&
+		(newShare.TrafficLimit == 0 || newShare.TrafficLimit > groupLimit) {
+		newShare.TrafficLimit = groupLimit
+	}
+
+	// 内部分享
+	recipients, err := buildRecipients(service.Recipients, service.IsDir)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+	newShare.Internal = len(recipients) > 0
+
+	// 访问来源限制
+	if _, err := util.ParseIPRanges(service.AllowedIPs); err != nil {
+		return serializer.ParamErr(err.Error(), err)
+	}
+	newShare.AllowedIPs = service.AllowedIPs
+	newShare.AllowedCountries = normalizeCountries(service.AllowedCountries)
+
+	if service.Slug != "" {
+		slug, err := checkSlug(user, service.Slug)
+		if err != nil {
+			return serializer.Err(serializer.CodeNotSet, "", err)
+		}
+		newShare.Slug = &slug
+	}
+
+	if service.AllowUpload {
+		newShare.UploadSizeLimit = service.UploadSizeLimit
+		newShare.UploadCountLimit = service.UploadCountLimit
 	}
 
 	// 如果开启了自动过期
@@ -127,19 +336,42 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 		expires := time.Now().Add(time.Duration(service.Expire) * time.Second)
 		newShare.RemainDownloads = service.RemainDownloads
 		newShare.Expires = &expires
+		newShare.ExpireAction = service.ExpireAction
 	}
 
 	// 创建分享
-	id, err := newShare.Create()
-	if err != nil {
+	if _, err := newShare.Create(); err != nil {
 		return serializer.DBErr("Failed to create share link record", err)
 	}
 
-	// 获取分享的唯一id
-	uid := hashid.HashID(id, hashid.ShareID)
+	if newShare.Internal {
+		if err := newShare.SetRecipients(recipients); err != nil {
+			newShare.Delete()
+			return serializer.DBErr("Failed to create share recipients", err)
+		}
+	}
+
+	if isBundle {
+		if err := newShare.SetBundleItems(bundleFiles, bundleFolders); err != nil {
+			newShare.Delete()
+			return serializer.DBErr("Failed to create bundle items", err)
+		}
+	}
+
+	model.RecordAudit(c, user.ID, model.AuditShareCreate, model.AuditTarget("share", newShare.ID), newShare.SourceName)
+
+	// 提交分享文件的内容审核，目录中的文件不在分享时审核
+	if model.IsModerationEnabled("share") {
+		if isBundle {
+			submitModeration(&newShare, bundleFiles)
+		} else if !newShare.IsDir {
+			submitModeration(&newShare, []uint{sourceID})
+		}
+	}
+
 	// 最终得到分享链接
 	siteURL := model.GetSiteURL()
-	sharePath, _ := url.Parse("/s/" + uid)
+	sharePath, _ := url.Parse("/s/" + newShare.Key())
 	shareURL := siteURL.ResolveReference(sharePath)
 
 	return serializer.Response{
@@ -148,3 +380,22 @@ func (service *ShareCreateService) Create(c *gin.Context) serializer.Response {
 	}
 
 }
+
+// submitModeration 提交分享中文件的内容审核
+func submitModeration(share *model.Share, fileIDs []uint) {
+	if len(fileIDs) == 0 {
+		return
+	}
+
+	files, err := model.GetFilesByIDs(fileIDs, share.UserID)
+	if err != nil {
+		util.Log().Warning("无法列取分享 [%d] 的文件, %s", share.ID, err)
+		return
+	}
+
+	for i := range files {
+		if err := model.SubmitModeration(&files[i], share.ID); err != nil {
+			util.Log().Warning("无法提交文件 [%s] 的内容审核, %s", files[i].Name, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package share
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// maxReadmeSize 分享页面渲染的自述文件大小上限，超出时不返回内容
+const maxReadmeSize = 256 << 10
+
+// Metadata 获取分享页面展示的描述及 Path 所指目录下的 README.md 内容
+func (service *Service) Metadata(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	res := serializer.ShareMetadata{Description: share.Description}
+	if !share.IsDir && !share.Bundle {
+		return serializer.Response{Data: res}
+	}
+
+	if service.Path == "" {
+		service.Path = "/"
+	}
+
+	readme, err := service.readme(share)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	res.Readme = readme
+
+	return serializer.Response{Data: res}
+}
+
+// readme 读取分享目录下的 README.md，不区分大小写，目录不存在或无自述文件时返回 nil
+func (service *Service) readme(share *model.Share) (*serializer.ShareReadme, error) {
+	source, rel, ok := service.sharedSource(share)
+	if !ok {
+		return nil, nil
+	}
+	root, isDir := source.(*model.Folder)
+	if !isDir {
+		return nil, nil
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	fs.Root = root
+	exist, folder := fs.IsPathExist(rel)
+	if !exist {
+		return nil, nil
+	}
+
+	files, err := folder.GetChildFiles()
+	if err != nil {
+		return nil, err
+	}
+
+	var file *model.File
+	for i := range files {
+		if strings.EqualFold(files[i].Name, "readme.md") {
+			file = &files[i]
+			break
+		}
+	}
+	if file == nil || file.Size > maxReadmeSize {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetContent(ctx, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	content, err := ioutil.ReadAll(rs)
+	if err != nil {
+		return nil, err
+	}
+
+	return &serializer.ShareReadme{Name: file.Name, Content: string(content)}, nil
+}
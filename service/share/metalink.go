@@ -0,0 +1,125 @@
+package share
+
+import (
+	"context"
+	"net/url"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/metalink"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// digestCacheTTL 文件校验信息的缓存时间，文件内容改变后缓存键随之改变
+const digestCacheTTL = 7 * 24 * 3600
+
+// MetalinkService 导出分享文件的下载描述文件服务
+type MetalinkService struct {
+	Service
+	Format string `form:"format" binding:"omitempty,eq=metalink|eq=torrent"`
+}
+
+// Export 生成 Path 所指分享文件的 Metalink 或以服务端为 Web Seed 的种子文件，
+// 其中的下载地址在 metalink_timeout 内有效
+func (service *MetalinkService) Export(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	file, ok := service.sharedFile(share)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
+	}
+
+	digest, err := fileDigest(c.Request.Context(), share, file)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	// 检查并扣除分享流量
+	if !share.CheckTraffic(file.Size) {
+		return serializer.Err(serializer.CodeShareTrafficExhausted, "", nil)
+	}
+	if err := share.ConsumeTraffic(file.Size); err != nil {
+		return serializer.DBErr("Failed to update share record", err)
+	}
+
+	fs, err := filesystem.NewFileSystem(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+	defer fs.Recycle()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	downloadURL, err := fs.GetDownloadURL(context.Background(), 0, "metalink_timeout")
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	sharePath, _ := url.Parse("/s/" + share.Key())
+	descriptor := &metalink.Descriptor{
+		Name:      file.Name,
+		Digest:    digest,
+		URLs:      []string{downloadURL},
+		Published: file.UpdatedAt,
+		Generator: "Cloudreve/" + conf.BackendVersion,
+		Comment:   model.GetSiteURL().ResolveReference(sharePath).String(),
+	}
+
+	var (
+		content     []byte
+		contentType string
+		ext         string
+	)
+	if service.Format == "torrent" {
+		content, err = metalink.Torrent(descriptor)
+		contentType = "application/x-bittorrent"
+		ext = ".torrent"
+	} else {
+		content, err = metalink.Metalink(descriptor)
+		contentType = "application/metalink4+xml"
+		ext = ".meta4"
+	}
+
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	c.Header("Content-Disposition", "attachment; filename=\""+url.PathEscape(file.Name+ext)+"\"")
+	c.Data(200, contentType, content)
+	return serializer.Response{}
+}
+
+// fileDigest 获取文件的校验信息，首次获取时读取全部内容计算，之后从缓存中读取
+func fileDigest(ctx context.Context, share *model.Share, file *model.File) (*metalink.Digest, error) {
+	key := "metalink_" + file.Hash()
+	if digest, ok := cache.Get(key); ok {
+		res := digest.(metalink.Digest)
+		return &res, nil
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, err
+	}
+	defer fs.Recycle()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetContent(ctx, file.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rs.Close()
+
+	digest, err := metalink.Compute(rs, file.Size)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Set(key, *digest, digestCacheTTL)
+	return digest, nil
+}
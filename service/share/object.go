@@ -0,0 +1,115 @@
+package share
+
+import (
+	"context"
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/service/explorer"
+	"github.com/gin-gonic/gin"
+)
+
+// ObjectDeleteService 删除分享目录下对象的服务
+type ObjectDeleteService struct {
+	Path string `json:"path" binding:"required,max=65535"`
+	explorer.ItemIDService
+}
+
+// ObjectRenameService 重命名分享目录下对象的服务
+type ObjectRenameService struct {
+	Path    string                 `json:"path" binding:"required,max=65535"`
+	Src     explorer.ItemIDService `json:"src"`
+	NewName string                 `json:"new_name" binding:"required,min=1,max=255"`
+}
+
+// DirectoryCreateService 在分享目录下创建目录的服务
+type DirectoryCreateService struct {
+	Path string `json:"path" binding:"required,min=1,max=65535"`
+}
+
+// sharedFileSystem 创建以分享目录为根目录的文件系统，操作范围限制在 dir 目录下，
+// 并在上下文中带上当前用户对分享的角色权限，由文件系统检查
+func sharedFileSystem(c *gin.Context, dir string) (*filesystem.FileSystem, context.Context, error) {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	if !share.IsDir || !path.IsAbs(dir) {
+		return nil, nil, serializer.NewError(serializer.CodeParamErr, "This is not a shared folder", nil)
+	}
+
+	// 文件归属于分享创建者
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, nil, serializer.NewError(serializer.CodeCreateFSError, "", err)
+	}
+
+	// 重设根目录
+	fs.Root = share.Source().(*model.Folder)
+	fs.Root.Name = "/"
+
+	exist, parent := fs.IsPathExist(dir)
+	if !exist {
+		fs.Recycle()
+		return nil, nil, filesystem.ErrPathNotExist
+	}
+
+	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
+	ctx = context.WithValue(ctx, fsctx.ShareRoleCtx, share.PermissionsOf(user))
+	return fs, ctx, nil
+}
+
+// Delete 删除分享目录下的对象，需要删除权限
+func (service *ObjectDeleteService) Delete(c *gin.Context) serializer.Response {
+	fs, ctx, err := sharedFileSystem(c, service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+	defer fs.Recycle()
+
+	items := service.Raw()
+	if err := fs.Delete(ctx, items.Dirs, items.Items, false); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Rename 重命名分享目录下的对象，需要编辑权限
+func (service *ObjectRenameService) Rename(c *gin.Context) serializer.Response {
+	if len(service.Src.Items)+len(service.Src.Dirs) > 1 {
+		return filesystem.ErrOneObjectOnly
+	}
+
+	fs, ctx, err := sharedFileSystem(c, service.Path)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+	defer fs.Recycle()
+
+	items := service.Src.Raw()
+	if err := fs.Rename(ctx, items.Dirs, items.Items, service.NewName); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// Create 在分享目录下创建目录，需要编辑权限
+func (service *DirectoryCreateService) Create(c *gin.Context) serializer.Response {
+	fs, ctx, err := sharedFileSystem(c, path.Dir(service.Path))
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "", err)
+	}
+	defer fs.Recycle()
+
+	if _, err := fs.CreateDirectory(ctx, service.Path); err != nil {
+		return serializer.Err(serializer.CodeCreateFolderFailed, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
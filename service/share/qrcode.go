@@ -0,0 +1,54 @@
+package share
+
+import (
+	"net/url"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// QRCodeService 分享链接二维码服务
+type QRCodeService struct {
+	Format       string `form:"format" binding:"omitempty,eq=png|eq=svg"`
+	Size         int    `form:"size" binding:"omitempty,min=64,max=1024"`
+	WithPassword bool   `form:"with_password"`
+}
+
+// QRCode 生成分享链接的二维码，可选将分享密码附加在链接中
+func (service *QRCodeService) QRCode(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	sharePath, _ := url.Parse("/s/" + share.Key())
+	if service.WithPassword && share.Password != "" {
+		sharePath.RawQuery = url.Values{"password": []string{share.Password}}.Encode()
+	}
+	shareURL := model.GetSiteURL().ResolveReference(sharePath).String()
+
+	if service.Size == 0 {
+		service.Size = 256
+	}
+
+	var (
+		content     []byte
+		contentType string
+		err         error
+	)
+	if service.Format == "svg" {
+		content, err = util.QRCodeSVG(shareURL, service.Size)
+		contentType = "image/svg+xml"
+	} else {
+		content, err = util.QRCodePNG(shareURL, service.Size)
+		contentType = "image/png"
+	}
+
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	c.Header("Cache-Control", "private, max-age=3600")
+	c.Data(200, contentType, content)
+	return serializer.Response{}
+}
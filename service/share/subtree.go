@@ -0,0 +1,63 @@
+package share
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// FolderSharesService 管理目录子树下分享的服务
+type FolderSharesService struct {
+}
+
+// folder 获取路由参数中当前用户的目录
+func (service *FolderSharesService) folder(c *gin.Context, user *model.User) (*model.Folder, error) {
+	folderID, err := hashid.DecodeHashID(c.Param("id"), hashid.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{folderID}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return nil, serializer.NewError(serializer.CodeParentNotExist, "", err)
+	}
+
+	return &folders[0], nil
+}
+
+// List 列出目录子树下所有对象的分享
+func (service *FolderSharesService) List(c *gin.Context, user *model.User) serializer.Response {
+	folder, err := service.folder(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	shares, err := model.ListSharesUnder(user.ID, []uint{folder.ID}, nil)
+	if err != nil {
+		return serializer.DBErr("Failed to list shares", err)
+	}
+
+	// 列出分享对应的文件
+	for i := 0; i < len(shares); i++ {
+		shares[i].Source()
+	}
+
+	return serializer.BuildShareList(shares, len(shares))
+}
+
+// Revoke 撤销目录子树下所有对象的分享
+func (service *FolderSharesService) Revoke(c *gin.Context, user *model.User) serializer.Response {
+	folder, err := service.folder(c, user)
+	if err != nil {
+		return serializer.Err(serializer.CodeParentNotExist, "", err)
+	}
+
+	revoked, err := model.RevokeSharesUnder(user.ID, []uint{folder.ID}, nil)
+	if err != nil {
+		return serializer.DBErr("Failed to revoke shares", err)
+	}
+
+	model.RecordShareRevoke(c, user.ID, revoked, "revoked with folder")
+	return serializer.Response{Data: len(revoked)}
+}
@@ -0,0 +1,26 @@
+package share
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// PreviewTokenService 签发分享预览令牌的服务
+type PreviewTokenService struct {
+	Path string `json:"path" binding:"max=65535"`
+}
+
+// Create 签发短期预览令牌，令牌仅在 Path 所指目录下有效，留空表示整个分享。
+// 页面内的媒体预览地址携带令牌即可访问，无需依赖分享密码解锁的会话
+func (service *PreviewTokenService) Create(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	ttl := model.GetIntSetting("share_preview_token_timeout", 1800)
+	return serializer.Response{Data: map[string]interface{}{
+		"token":   auth.SignSharePreview(auth.General, share, service.Path, int64(ttl)),
+		"expires": ttl,
+	}}
+}
@@ -0,0 +1,162 @@
+package share
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/unfurl"
+	"github.com/gin-gonic/gin"
+)
+
+// playerTemplate 内嵌播放页
+var playerTemplate = template.Must(template.New("player").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>html,body{margin:0;width:100%;height:100%;background:#000}video{width:100%;height:100%}</style>
+</head>
+<body>
+<video src="{{.Video}}" poster="{{.Poster}}" controls playsinline preload="metadata"></video>
+</body>
+</html>
+`))
+
+// OEmbedService 分享链接的 oEmbed 服务
+type OEmbedService struct {
+	URL       string `form:"url" binding:"required,max=65535"`
+	MaxWidth  int    `form:"maxwidth" binding:"min=0"`
+	MaxHeight int    `form:"maxheight" binding:"min=0"`
+	Format    string `form:"format" binding:"omitempty,eq=json|eq=xml"`
+}
+
+// cardFile 返回可生成预览卡片的单文件分享的源文件
+func cardFile(share *model.Share) (*model.File, bool) {
+	if !unfurl.Enabled() || share.IsDir || share.Bundle {
+		return nil, false
+	}
+
+	file := share.SourceFile()
+	return file, file.ID != 0
+}
+
+// CardThumb 输出单文件分享的缩略图，作为预览卡片的图像
+func (service *Service) CardThumb(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	file, ok := cardFile(share)
+	if !ok || share.Watermark {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	resp, err := fs.GetThumb(context.Background(), file.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "Failed to get thumb", err)
+	}
+
+	if resp.Redirect {
+		c.Header("Cache-Control", fmt.Sprintf("max-age=%d", resp.MaxAge))
+		c.Redirect(http.StatusMovedPermanently, resp.URL)
+		return serializer.Response{Code: -1}
+	}
+
+	// 缩略图生成完成前返回的占位图不可缓存
+	if resp.Placeholder {
+		c.Header("Cache-Control", "no-store")
+	}
+
+	defer resp.Content.Close()
+	http.ServeContent(c.Writer, c.Request, "thumb.png", file.UpdatedAt, resp.Content)
+
+	return serializer.Response{Code: -1}
+}
+
+// CardStream 预览卡片中的视频流，仅限浏览器可直接播放的视频
+func (service *Service) CardStream(ctx context.Context, c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+
+	file, ok := cardFile(share)
+	if !ok {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+	if _, ok := unfurl.VideoType(file.Name); !ok {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	service.Path = ""
+	return service.PreviewContent(ctx, c, false)
+}
+
+// CardPlayer 输出可被第三方页面内嵌的视频播放页
+func (service *Service) CardPlayer(c *gin.Context) serializer.Response {
+	if !unfurl.Enabled() {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	card := unfurl.ForShare(c, c.Param("id"))
+	if card == nil || card.Player == "" {
+		return serializer.Err(serializer.CodeNotFound, "", nil)
+	}
+
+	poster := ""
+	if card.Thumb {
+		poster = card.Image
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(http.StatusOK)
+	if err := playerTemplate.Execute(c.Writer, map[string]string{
+		"Title":  card.Title,
+		"Video":  card.Video,
+		"Poster": poster,
+	}); err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
+	}
+
+	return serializer.Response{}
+}
+
+// OEmbed 按 oEmbed 规范返回分享链接的嵌入信息，仅支持 JSON 格式
+func (service *OEmbedService) OEmbed(c *gin.Context) serializer.Response {
+	if service.Format == "xml" {
+		c.Status(http.StatusNotImplemented)
+		return serializer.Response{Code: -1}
+	}
+
+	u, err := url.Parse(service.URL)
+	if err != nil || !unfurl.Enabled() || !strings.EqualFold(u.Host, model.GetSiteURL().Host) {
+		c.Status(http.StatusNotFound)
+		return serializer.Response{Code: -1}
+	}
+
+	key, ok := unfurl.ShareKey(u.Path)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return serializer.Response{Code: -1}
+	}
+
+	card := unfurl.ForShare(c, key)
+	if card == nil {
+		c.Status(http.StatusNotFound)
+		return serializer.Response{Code: -1}
+	}
+
+	c.JSON(http.StatusOK, card.OEmbed(service.MaxWidth, service.MaxHeight))
+	return serializer.Response{Code: -1}
+}
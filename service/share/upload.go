@@ -0,0 +1,79 @@
+package share
+
+import (
+	"context"
+	"path"
+	"strconv"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// UploadService 访客向分享目录上传文件的服务
+type UploadService struct {
+	Path string `form:"path" binding:"required,max=65535"`
+	Name string `form:"name" binding:"required,max=255"`
+}
+
+// Upload 将请求体作为文件上传至分享目录下，文件归属于分享创建者
+func (service *UploadService) Upload(c *gin.Context) serializer.Response {
+	shareCtx, _ := c.Get("share")
+	share := shareCtx.(*model.Share)
+	userCtx, _ := c.Get("user")
+	user := userCtx.(*model.User)
+
+	if err := share.CanBeUploadedBy(user); err != nil {
+		return serializer.Err(serializer.CodeNoPermissionErr, err.Error(), nil)
+	}
+
+	if !path.IsAbs(service.Path) {
+		return serializer.ParamErr("Invalid path", nil)
+	}
+
+	// 取得文件大小
+	fileSize, err := strconv.ParseUint(c.Request.Header.Get("Content-Length"), 10, 64)
+	if err != nil {
+		return serializer.ParamErr("Invalid content-length value", err)
+	}
+
+	// 检查分享上传限制
+	if !share.CheckUploadQuota(fileSize) {
+		return serializer.Err(serializer.CodeShareUploadLimitExceeded, "Upload limit of this share exceeded", nil)
+	}
+
+	// 创建文件系统
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err)
+	}
+	defer fs.Recycle()
+
+	// 重设根目录
+	fs.Root = share.Source().(*model.Folder)
+	fs.Root.Name = "/"
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = context.WithValue(ctx, fsctx.ShareRoleCtx, share.PermissionsOf(user))
+
+	fileData := &fsctx.FileStream{
+		MIMEType:    c.Request.Header.Get("Content-Type"),
+		File:        c.Request.Body,
+		Size:        fileSize,
+		Name:        service.Name,
+		VirtualPath: service.Path,
+	}
+
+	if err := fs.UploadFromStream(ctx, fileData, true); err != nil {
+		return serializer.Err(serializer.CodeUploadFailed, err.Error(), err)
+	}
+
+	if err := share.Uploaded(fileSize); err != nil {
+		return serializer.DBErr("Failed to update share record", err)
+	}
+
+	return serializer.Response{}
+}
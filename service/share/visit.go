@@ -1,16 +1,21 @@
 package share
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"net/http"
 	"path"
+	"strings"
+	"time"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
 	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/fsctx"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/thumb"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/cloudreve/Cloudreve/v3/service/explorer"
 	"github.com/gin-gonic/gin"
@@ -114,6 +119,19 @@ func (service *ShareListService) List(c *gin.Context, user *model.User) serializ
 	return serializer.BuildShareList(shares, total)
 }
 
+// Received 列出分享给用户的内部分享
+func (service *ShareListService) Received(c *gin.Context, user *model.User) serializer.Response {
+	shares, total := model.ListReceivedShares(user, int(service.Page), 18, service.OrderBy+" "+
+		service.Order)
+	// 列出分享对应的文件及创建者
+	for i := 0; i < len(shares); i++ {
+		shares[i].Source()
+		shares[i].Creator()
+	}
+
+	return serializer.BuildReceivedShareList(shares, total)
+}
+
 // Get 获取分享内容
 func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
@@ -125,21 +143,40 @@ func (service *ShareGetService) Get(c *gin.Context) serializer.Response {
 		sessionKey := fmt.Sprintf("share_unlock_%d", share.ID)
 		unlocked = util.GetSession(c, sessionKey) != nil
 		if !unlocked && service.Password != "" {
+			// 尝试次数过多时拒绝解锁。ClientIP 仅采信受信任的反向代理转发的地址，
+			// 无法通过伪造请求头绕过限制
+			ip := c.ClientIP()
+			if share.IsPasswordLocked(ip) {
+				return serializer.Err(serializer.CodeSharePasswordLocked, "Too many failed attempts, please try again later", nil)
+			}
+
 			// 如果未解锁，且指定了密码，则尝试解锁
 			if service.Password == share.Password {
 				unlocked = true
+				share.PasswordSucceed(ip)
 				util.SetSession(c, map[string]interface{}{sessionKey: true})
+			} else if share.PasswordFailed(ip) {
+				return serializer.Err(serializer.CodeSharePasswordLocked, "Too many failed attempts, please try again later", nil)
 			}
 		}
 	}
 
 	if unlocked {
 		share.Viewed()
+		share.RecordAccess(c, model.ShareAccessView, "")
+		notify.ShareAccessed(c, share, model.ShareAccessView, "")
+	}
+
+	res := serializer.BuildShareResponse(share, unlocked)
+	if unlocked && share.Internal {
+		// 内部分享返回当前用户的角色，供前端决定可用的操作
+		userCtx, _ := c.Get("user")
+		res.Roles = model.ShareRoleNames(share.PermissionsOf(userCtx.(*model.User)))
 	}
 
 	return serializer.Response{
 		Code: 0,
-		Data: serializer.BuildShareResponse(share, unlocked),
+		Data: res,
 	}
 }
 
@@ -158,7 +195,11 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 	defer fs.Recycle()
 
 	// 重设文件系统处理目标为源文件
-	err = fs.SetTargetByInterface(share.Source())
+	source, rel, ok := service.sharedSource(share)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
+	}
+	err = fs.SetTargetByInterface(source)
 	if err != nil {
 		return serializer.Err(serializer.CodeFileNotFound, "", err)
 	}
@@ -166,16 +207,29 @@ func (service *Service) CreateDownloadSession(c *gin.Context) serializer.Respons
 	ctx := context.Background()
 
 	// 重设根目录
-	if share.IsDir {
+	if _, isDir := source.(*model.Folder); isDir {
 		fs.Root = &fs.DirTarget[0]
 
 		// 找到目标文件
-		err = fs.ResetFileIfNotExist(ctx, service.Path)
+		err = fs.ResetFileIfNotExist(ctx, rel)
 		if err != nil {
 			return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 		}
 	}
 
+	// 内容命中禁止列表的文件不可下载
+	if isBlocked(c, share, &fs.FileTarget[0]) {
+		return serializer.Err(serializer.CodeFileBlocked, "", nil)
+	}
+
+	// 检查并扣除分享流量
+	if !share.CheckTraffic(fs.FileTarget[0].Size) {
+		return serializer.Err(serializer.CodeShareTrafficExhausted, "", nil)
+	}
+	if err := share.ConsumeTraffic(fs.FileTarget[0].Size); err != nil {
+		return serializer.DBErr("Failed to update share record", err)
+	}
+
 	// 取得下载地址
 	downloadURL, err := fs.GetDownloadURL(ctx, 0, "download_timeout")
 	if err != nil {
@@ -194,30 +248,160 @@ func (service *Service) PreviewContent(ctx context.Context, c *gin.Context, isTe
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
+	// 检查并扣除分享流量
+	file, ok := service.sharedFile(share)
+	if ok {
+		if isBlocked(c, share, file) {
+			return serializer.Err(serializer.CodeFileBlocked, "", nil)
+		}
+		if !share.CheckTraffic(file.Size) {
+			return serializer.Err(serializer.CodeShareTrafficExhausted, "", nil)
+		}
+		if err := share.ConsumeTraffic(file.Size); err != nil {
+			return serializer.DBErr("Failed to update share record", err)
+		}
+	}
+
+	// 开启水印时由服务端输出带水印的图像
+	if share.Watermark && ok && !isText {
+		if res, handled := service.previewWithWatermark(ctx, c, share, file); handled {
+			return res
+		}
+	}
+
 	// 用于调下层service
-	if share.IsDir {
-		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
-		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
-	} else {
-		ctx = context.WithValue(ctx, fsctx.FileModelCtx, share.Source())
+	ctx, ok = service.sharedFileContext(ctx, share)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 	subService := explorer.FileIDService{}
 
 	return subService.PreviewContent(ctx, c, isText)
 }
 
+// isBlocked 检查分享的文件内容是否命中禁止列表，命中时记录审计事件
+func isBlocked(c *gin.Context, share *model.Share, file *model.File) bool {
+	blocked, err := model.MatchBlockedFile(file)
+	if err != nil {
+		util.Log().Warning("无法检查禁止列表, %s", err)
+	}
+	if blocked == nil {
+		return false
+	}
+
+	var uid uint
+	if user, ok := c.Get("user"); ok {
+		uid = user.(*model.User).ID
+	}
+	model.RecordAudit(c, uid, model.AuditBlockedHash, model.AuditTarget("share", share.ID),
+		fmt.Sprintf("share download rejected: %s (%s)", file.Name, blocked.Hash))
+	return true
+}
+
+// sharedFile 返回分享中 Path 指向的文件，文件不存在时返回 false
+func (service *Service) sharedFile(share *model.Share) (*model.File, bool) {
+	source, rel, ok := service.sharedSource(share)
+	if !ok {
+		return nil, false
+	}
+
+	folder, isDir := source.(*model.Folder)
+	if !isDir {
+		file := source.(*model.File)
+		return file, file.ID != 0
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return nil, false
+	}
+	defer fs.Recycle()
+
+	fs.Root = folder
+	exist, file := fs.IsFileExist(rel)
+	return file, exist
+}
+
+// sharedFileContext 将 Path 指向的分享对象写入上下文，供下层 explorer 服务使用
+func (service *Service) sharedFileContext(ctx context.Context, share *model.Share) (context.Context, bool) {
+	source, rel, ok := service.sharedSource(share)
+	if !ok {
+		return ctx, false
+	}
+
+	if folder, isDir := source.(*model.Folder); isDir {
+		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, folder)
+		ctx = context.WithValue(ctx, fsctx.PathCtx, rel)
+	} else {
+		ctx = context.WithValue(ctx, fsctx.FileModelCtx, source)
+	}
+	return ctx, true
+}
+
+// previewWithWatermark 输出添加了水印的预览图像，水印文字可使用 {ip} 与 {time} 占位符，
+// 文件不是图像时返回 false 交由常规预览处理
+func (service *Service) previewWithWatermark(ctx context.Context, c *gin.Context, share *model.Share, file *model.File) (serializer.Response, bool) {
+	switch strings.ToLower(path.Ext(file.Name)) {
+	case ".jpg", ".jpeg", ".png", ".gif":
+	case ".pdf":
+		return serializer.Err(serializer.CodeNoPermissionErr, "Watermarked preview is not supported for PDF files", nil), true
+	default:
+		return serializer.Response{}, false
+	}
+
+	fs, err := filesystem.NewFileSystem(share.Creator())
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateFSError, "", err), true
+	}
+	defer fs.Recycle()
+
+	fs.SetTargetFile(&[]model.File{*file})
+	rs, err := fs.GetContent(ctx, file.ID)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, err.Error(), err), true
+	}
+	defer rs.Close()
+
+	img, err := thumb.NewThumbFromFile(rs, file.Name)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "Failed to decode image", err), true
+	}
+
+	text := share.WatermarkText
+	if text == "" {
+		text = "{ip} {time}"
+	}
+	img.Watermark(util.Replace(map[string]string{
+		"{ip}":   c.ClientIP(),
+		"{time}": time.Now().Format("2006-01-02 15:04:05"),
+	}, text))
+
+	var buf bytes.Buffer
+	contentType, err := img.Encode(&buf)
+	if err != nil {
+		return serializer.Err(serializer.CodeNotSet, "Failed to encode image", err), true
+	}
+
+	// 水印包含访客信息，禁止缓存
+	c.Header("Cache-Control", "no-store")
+	c.Data(200, contentType, buf.Bytes())
+	return serializer.Response{}, true
+}
+
 // CreateDocPreviewSession 创建Office预览会话，返回预览地址
 func (service *Service) CreateDocPreviewSession(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
+	// 第三方文档预览无法添加水印
+	if share.Watermark {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Document preview is disabled for watermarked shares", nil)
+	}
+
 	// 用于调下层service
-	ctx := context.Background()
-	if share.IsDir {
-		ctx = context.WithValue(ctx, fsctx.FolderModelCtx, share.Source())
-		ctx = context.WithValue(ctx, fsctx.PathCtx, service.Path)
-	} else {
-		ctx = context.WithValue(ctx, fsctx.FileModelCtx, share.Source())
+	ctx, ok := service.sharedFileContext(context.Background(), share)
+	if !ok {
+		return serializer.Err(serializer.CodeFileNotFound, "", nil)
 	}
 	subService := explorer.FileIDService{}
 
@@ -229,7 +413,7 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
-	if !share.IsDir {
+	if !share.IsDir && !share.Bundle {
 		return serializer.ParamErr("This is not a shared folder", nil)
 	}
 
@@ -248,15 +432,19 @@ func (service *Service) List(c *gin.Context) serializer.Response {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
-	fs.Root.Name = "/"
-
 	// 分享Key上下文
-	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, share.HashID())
 
 	// 获取子项目
-	objects, err := fs.List(ctx, service.Path, nil)
+	var objects []serializer.Object
+	if share.Bundle {
+		objects, err = service.listBundle(ctx, fs, share)
+	} else {
+		// 重设根目录
+		fs.Root = share.Source().(*model.Folder)
+		fs.Root.Name = "/"
+		objects, err = fs.List(ctx, service.Path, nil)
+	}
 	if err != nil {
 		return serializer.Err(serializer.CodeNotSet, err.Error(), err)
 	}
@@ -272,7 +460,7 @@ func (service *Service) Thumb(c *gin.Context) serializer.Response {
 	shareCtx, _ := c.Get("share")
 	share := shareCtx.(*model.Share)
 
-	if !share.IsDir {
+	if !share.IsDir && !share.Bundle {
 		return serializer.ParamErr("This share has no thumb", nil)
 	}
 
@@ -283,23 +471,40 @@ func (service *Service) Thumb(c *gin.Context) serializer.Response {
 	}
 	defer fs.Recycle()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
-
-	// 找到缩略图的父目录
-	exist, parent := fs.IsPathExist(service.Path)
-	if !exist {
-		return serializer.Err(serializer.CodeParentNotExist, "", nil)
-	}
-
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
-
 	// 获取文件ID
 	fileID, err := hashid.DecodeHashID(c.Param("file"), hashid.FileID)
 	if err != nil {
 		return serializer.Err(serializer.CodeNotFound, "", err)
 	}
 
+	ctx := context.Background()
+	if share.Bundle && path.Clean(service.Path) == "/" {
+		// 合集根目录下的文件需属于该合集
+		if !isBundleFile(share, fileID) {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
+		}
+	} else {
+		// 重设根目录
+		parentPath := service.Path
+		if share.Bundle {
+			folder, rel, _ := share.ResolveBundlePath(service.Path)
+			if folder == nil {
+				return serializer.Err(serializer.CodeParentNotExist, "", nil)
+			}
+			fs.Root, parentPath = folder, rel
+		} else {
+			fs.Root = share.Source().(*model.Folder)
+		}
+
+		// 找到缩略图的父目录
+		exist, parent := fs.IsPathExist(parentPath)
+		if !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
+
+		ctx = context.WithValue(ctx, fsctx.LimitParentCtx, parent)
+	}
+
 	// 获取缩略图
 	resp, err := fs.GetThumb(ctx, uint(fileID))
 	if err != nil {
@@ -312,6 +517,11 @@ func (service *Service) Thumb(c *gin.Context) serializer.Response {
 		return serializer.Response{Code: -1}
 	}
 
+	// 缩略图生成完成前返回的占位图不可缓存
+	if resp.Placeholder {
+		c.Header("Cache-Control", "no-store")
+	}
+
 	defer resp.Content.Close()
 	http.ServeContent(c.Writer, c.Request, "thumb.png", fs.FileTarget[0].UpdatedAt, resp.Content)
 
@@ -331,7 +541,7 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeGroupNotAllowed, "", nil)
 	}
 
-	if !share.IsDir {
+	if !share.IsDir && !share.Bundle {
 		return serializer.ParamErr("This share cannot be batch downloaded", nil)
 	}
 
@@ -342,17 +552,35 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 	}
 	defer fs.Recycle()
 
-	// 重设根目录
-	fs.Root = share.Source().(*model.Folder)
+	ctx := context.Background()
+	if share.Bundle && path.Clean(service.Path) == "/" {
+		// 合集根目录下仅可打包合集中的对象，未指定时打包全部
+		service.Items, service.Dirs = bundleArchiveItems(share, service.Items, service.Dirs)
+		if len(service.Items)+len(service.Dirs) == 0 {
+			return serializer.Err(serializer.CodeNotFound, "", nil)
+		}
+	} else {
+		// 重设根目录
+		parentPath := service.Path
+		if share.Bundle {
+			folder, rel, _ := share.ResolveBundlePath(service.Path)
+			if folder == nil {
+				return serializer.Err(serializer.CodeParentNotExist, "", nil)
+			}
+			fs.Root, parentPath = folder, rel
+		} else {
+			fs.Root = share.Source().(*model.Folder)
+		}
 
-	// 找到要打包文件的父目录
-	exist, parent := fs.IsPathExist(service.Path)
-	if !exist {
-		return serializer.Err(serializer.CodeParentNotExist, "", nil)
-	}
+		// 找到要打包文件的父目录
+		exist, parent := fs.IsPathExist(parentPath)
+		if !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "", nil)
+		}
 
-	// 限制操作范围为父目录下
-	ctx := context.WithValue(context.Background(), fsctx.LimitParentCtx, parent)
+		// 限制操作范围为父目录下
+		ctx = context.WithValue(ctx, fsctx.LimitParentCtx, parent)
+	}
 
 	// 用于调下层service
 	tempUser := share.Creator()
@@ -364,7 +592,58 @@ func (service *ArchiveService) Archive(c *gin.Context) serializer.Response {
 		Items: service.Items,
 	}
 
-	return subService.Archive(ctx, c)
+	// 检查分享流量，打包大小为所选文件及目录下全部文件的大小之和
+	size, err := archiveSize(share.UserID, subService.Raw())
+	if err != nil {
+		return serializer.DBErr("Failed to list object records", err)
+	}
+	if !share.CheckTraffic(size) {
+		return serializer.Err(serializer.CodeShareTrafficExhausted, "", nil)
+	}
+
+	res := subService.Archive(ctx, c)
+	if res.Code != 0 {
+		return res
+	}
+
+	// 打包下载会话创建后扣除分享流量
+	if err := share.ConsumeTraffic(size); err != nil {
+		return serializer.DBErr("Failed to update share record", err)
+	}
+	return res
+}
+
+// archiveSize 计算打包下载的文件及目录下全部文件的大小之和
+func archiveSize(uid uint, items *explorer.ItemService) (uint64, error) {
+	var size uint64
+
+	if len(items.Items) > 0 {
+		files, err := model.GetFilesByIDs(items.Items, uid)
+		if err != nil {
+			return 0, err
+		}
+		for _, file := range files {
+			size += file.Size
+		}
+	}
+
+	if len(items.Dirs) > 0 {
+		folders, err := model.GetRecursiveChildFolder(items.Dirs, uid, true)
+		if err != nil {
+			return 0, err
+		}
+		if len(folders) > 0 {
+			files, err := model.GetChildFilesOfFolders(&folders)
+			if err != nil {
+				return 0, err
+			}
+			for _, file := range files {
+				size += file.Size
+			}
+		}
+	}
+
+	return size, nil
 }
 
 // SearchService 对分享的目录进行搜索
@@ -409,7 +688,7 @@ func (service *SearchService) Search(c *gin.Context) serializer.Response {
 	}
 
 	// 分享Key上下文
-	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, hashid.HashID(share.ID, hashid.ShareID))
+	ctx = context.WithValue(ctx, fsctx.ShareKeyCtx, share.HashID())
 
 	return service.SearchKeywords(c, fs, "%"+service.Keywords+"%")
 }
@@ -0,0 +1,102 @@
+package share
+
+import (
+	"database/sql"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+)
+
+var mock sqlmock.Sqlmock
+
+// TestMain 初始化数据库Mock
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+
+	var db *sql.DB
+	var err error
+	db, mock, err = sqlmock.New()
+	if err != nil {
+		panic("An error was not expected when opening a stub database connection")
+	}
+	model.DB, _ = gorm.Open("mysql", db)
+	defer db.Close()
+	m.Run()
+}
+
+func TestArchiveService_Archive_Traffic(t *testing.T) {
+	asserts := assert.New(t)
+	auth.General = auth.HMACAuth{SecretKey: []byte("test")}
+
+	newContext := func(share *model.Share) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		user := &model.User{Policy: model.Policy{Type: "mock"}}
+		user.Group.OptionsSerialized.ArchiveDownload = true
+		c.Set("user", user)
+		c.Set("share", share)
+		return c
+	}
+	newShare := func(limit, used uint64) *model.Share {
+		return &model.Share{
+			Model:        gorm.Model{ID: 1},
+			IsDir:        true,
+			UserID:       1,
+			User:         model.User{Model: gorm.Model{ID: 1}, Policy: model.Policy{Type: "mock"}},
+			Folder:       model.Folder{Model: gorm.Model{ID: 3}, OwnerID: 1},
+			TrafficLimit: limit,
+			TrafficUsed:  used,
+		}
+	}
+
+	// 打包大小超出剩余流量
+	{
+		share := newShare(10, 5)
+		service := &ArchiveService{Path: "/", Items: []string{hashid.HashID(2, hashid.FileID)}}
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(2, 8))
+		res := service.Archive(newContext(share))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(serializer.CodeShareTrafficExhausted, res.Code)
+		asserts.EqualValues(5, share.TrafficUsed)
+	}
+
+	// 目录下全部文件计入流量
+	{
+		share := newShare(10, 5)
+		service := &ArchiveService{Path: "/", Dirs: []string{hashid.HashID(4, hashid.FolderID)}}
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+		mock.ExpectQuery("SELECT(.+)folders(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(6, 3).AddRow(7, 4))
+		res := service.Archive(newContext(share))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(serializer.CodeShareTrafficExhausted, res.Code)
+	}
+
+	// 成功创建打包会话并扣除流量
+	{
+		share := newShare(10, 5)
+		service := &ArchiveService{Path: "/", Items: []string{hashid.HashID(2, hashid.FileID)}}
+		mock.ExpectQuery("SELECT(.+)files(.+)").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "size"}).AddRow(2, 5))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE(.+)shares(.+)traffic_used(.+)").WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+		res := service.Archive(newContext(share))
+		asserts.NoError(mock.ExpectationsWereMet())
+		asserts.Equal(0, res.Code)
+		asserts.EqualValues(10, share.TrafficUsed)
+	}
+}
@@ -0,0 +1,306 @@
+package user
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/authn"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/duo-labs/webauthn/protocol"
+	"github.com/duo-labs/webauthn/webauthn"
+	"github.com/gin-gonic/gin"
+	"github.com/jinzhu/gorm"
+)
+
+// AuthnRegisterService 注册验证器的服务
+type AuthnRegisterService struct {
+	Name    string `form:"name" binding:"max=50"`
+	Passkey bool   `form:"passkey"`
+}
+
+// AuthnListService 列出已注册验证器的服务
+type AuthnListService struct {
+}
+
+// AuthnManageService 管理已注册验证器的服务
+type AuthnManageService struct {
+	ID   string `json:"id" binding:"required"`
+	Name string `json:"name" binding:"max=50"`
+}
+
+// PasskeyLoginService 使用通行密钥免用户名登录的服务
+type PasskeyLoginService struct {
+}
+
+// AuthnSecondFactorService 使用验证器完成二步验证的服务
+type AuthnSecondFactorService struct {
+}
+
+// saveAuthnSession 将验证会话数据记录在会话中
+func saveAuthnSession(c *gin.Context, key string, sessionData *webauthn.SessionData, extra map[string]interface{}) error {
+	val, err := json.Marshal(sessionData)
+	if err != nil {
+		return err
+	}
+
+	session := map[string]interface{}{key: val}
+	for k, v := range extra {
+		session[k] = v
+	}
+	util.SetSession(c, session)
+	return nil
+}
+
+// loadAuthnSession 读取并清除会话中的验证会话数据
+func loadAuthnSession(c *gin.Context, key string) (webauthn.SessionData, bool) {
+	var sessionData webauthn.SessionData
+	val, ok := util.GetSession(c, key).([]byte)
+	if !ok {
+		return sessionData, false
+	}
+	util.DeleteSession(c, key)
+	return sessionData, json.Unmarshal(val, &sessionData) == nil
+}
+
+// Start 开始注册验证器，注册为通行密钥时要求验证器保存可发现凭证并验证用户身份
+func (service *AuthnRegisterService) Start(c *gin.Context, user *model.User) serializer.Response {
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	// 避免重复注册同一验证器
+	exclusions := make([]protocol.CredentialDescriptor, 0)
+	for _, credential := range user.WebAuthnCredentials() {
+		exclusions = append(exclusions, protocol.CredentialDescriptor{
+			Type:         protocol.PublicKeyCredentialType,
+			CredentialID: credential.ID,
+		})
+	}
+
+	opts := []webauthn.RegistrationOption{webauthn.WithExclusions(exclusions)}
+	if service.Passkey {
+		opts = append(opts,
+			webauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+				UserVerification: protocol.VerificationRequired,
+			}),
+			webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		)
+	}
+
+	options, sessionData, err := instance.BeginRegistration(user, opts...)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Cannot start registration", err)
+	}
+
+	if err := saveAuthnSession(c, "registration-session", sessionData, map[string]interface{}{
+		"registration-name":    service.Name,
+		"registration-passkey": service.Passkey,
+	}); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Cannot save registration session", err)
+	}
+
+	return serializer.Response{Data: options}
+}
+
+// Finish 完成注册验证器
+func (service *AuthnRegisterService) Finish(c *gin.Context, user *model.User) serializer.Response {
+	sessionData, ok := loadAuthnSession(c, "registration-session")
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Registration session not exist", nil)
+	}
+	name, _ := util.GetSession(c, "registration-name").(string)
+	passkey, _ := util.GetSession(c, "registration-passkey").(bool)
+	util.DeleteSession(c, "registration-name")
+	util.DeleteSession(c, "registration-passkey")
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	credential, err := instance.FinishRegistration(user, sessionData, c.Request)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+
+	if err := user.AddAuthn(model.AuthnCredential{
+		Credential: *credential,
+		Name:       name,
+		Passkey:    passkey,
+	}); err != nil {
+		return serializer.DBErr("Failed to save authenticator", err)
+	}
+
+	return serializer.Response{
+		Data: map[string]interface{}{
+			"id":          credential.ID,
+			"fingerprint": fmt.Sprintf("% X", credential.Authenticator.AAGUID),
+		},
+	}
+}
+
+// List 列出已注册的验证器
+func (service *AuthnListService) List(c *gin.Context, user *model.User) serializer.Response {
+	return serializer.Response{Data: serializer.BuildAuthnCredentialList(user.AuthnCredentials())}
+}
+
+// Rename 重命名验证器
+func (service *AuthnManageService) Rename(c *gin.Context, user *model.User) serializer.Response {
+	if err := user.RenameAuthn(service.ID, service.Name); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return serializer.Err(serializer.CodeNotFound, "Authenticator not found", nil)
+		}
+		return serializer.DBErr("Failed to rename authenticator", err)
+	}
+
+	return serializer.Response{}
+}
+
+// Revoke 撤销验证器
+func (service *AuthnManageService) Revoke(c *gin.Context, user *model.User) serializer.Response {
+	for _, authenticator := range user.AuthnCredentials() {
+		if model.AuthnCredentialID(authenticator.ID) == service.ID {
+			user.RemoveAuthn(service.ID)
+			return serializer.Response{}
+		}
+	}
+
+	return serializer.Err(serializer.CodeNotFound, "Authenticator not found", nil)
+}
+
+// Start 开始通行密钥登录，不限定凭证，由验证器选择可发现凭证
+func (service *PasskeyLoginService) Start(c *gin.Context) serializer.Response {
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	challenge, err := protocol.CreateChallenge()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot create challenge", err)
+	}
+
+	options := protocol.CredentialAssertion{
+		Response: protocol.PublicKeyCredentialRequestOptions{
+			Challenge:        challenge,
+			Timeout:          instance.Config.Timeout,
+			RelyingPartyID:   instance.Config.RPID,
+			UserVerification: protocol.VerificationRequired,
+		},
+	}
+	sessionData := &webauthn.SessionData{
+		Challenge:        base64.RawURLEncoding.EncodeToString(challenge),
+		UserVerification: protocol.VerificationRequired,
+	}
+
+	if err := saveAuthnSession(c, "passkey-session", sessionData, nil); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Cannot save login session", err)
+	}
+
+	return serializer.Response{Data: options}
+}
+
+// Finish 完成通行密钥登录，用户由验证器返回的用户句柄确定
+func (service *PasskeyLoginService) Finish(c *gin.Context) serializer.Response {
+	sessionData, ok := loadAuthnSession(c, "passkey-session")
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponse(c.Request)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+
+	expectedUser, err := model.GetActiveUserByWebAuthnID(parsed.Response.UserHandle)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+
+	sessionData.UserID = expectedUser.WebAuthnID()
+	credential, err := instance.ValidateLogin(expectedUser, sessionData, parsed)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+	expectedUser.UpdateAuthnUsage(credential)
+
+	// 通行密钥已验证用户身份，无需再进行二步验证
+	return SignIn(c, &expectedUser)
+}
+
+// Start 开始使用验证器进行二步验证
+func (service *AuthnSecondFactorService) Start(c *gin.Context) serializer.Response {
+	uid, ok := util.GetSession(c, "2fa_user_id").(uint)
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	expectedUser, err := model.GetActiveUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
+	}
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	options, sessionData, err := instance.BeginLogin(expectedUser)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Cannot start verification", err)
+	}
+
+	if err := saveAuthnSession(c, "2fa-authn-session", sessionData, nil); err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Cannot save login session", err)
+	}
+
+	return serializer.Response{Data: options}
+}
+
+// Finish 完成使用验证器的二步验证
+func (service *AuthnSecondFactorService) Finish(c *gin.Context) serializer.Response {
+	uid, ok := util.GetSession(c, "2fa_user_id").(uint)
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+	sessionData, ok := loadAuthnSession(c, "2fa-authn-session")
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	expectedUser, err := model.GetActiveUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
+	}
+
+	instance, err := authn.NewAuthnInstance()
+	if err != nil {
+		return serializer.Err(serializer.CodeInitializeAuthn, "Cannot initialize authn", err)
+	}
+
+	credential, err := instance.FinishLogin(expectedUser, sessionData, c.Request)
+	if err != nil {
+		return serializer.Err(serializer.CodeWebAuthnCredentialError, "Verification failed", err)
+	}
+	expectedUser.UpdateAuthnUsage(credential)
+
+	util.DeleteSession(c, "2fa_user_id")
+	return SignIn(c, &expectedUser)
+}
+
+// secondFactors 返回需要二步验证时用户可用的验证方式
+func secondFactors(user *model.User) map[string]bool {
+	return map[string]bool{
+		"totp":  user.TwoFactor != "",
+		"authn": user.CanUseAuthn(),
+	}
+}
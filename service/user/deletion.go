@@ -0,0 +1,66 @@
+package user
+
+import (
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// AccountDeletionService 申请注销账户服务
+type AccountDeletionService struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code"`
+}
+
+// CancelDeletionService 撤销注销申请服务
+type CancelDeletionService struct {
+}
+
+// Schedule 申请注销账户，账户下的分享立即失效，冷静期结束后清除全部文件及账户
+func (service *AccountDeletionService) Schedule(c *gin.Context, user *model.User) serializer.Response {
+	if user.ID == 1 {
+		return serializer.Err(serializer.CodeInvalidActionOnDefaultUser, "", nil)
+	}
+
+	if user.DeleteAfter != nil {
+		return serializer.ParamErr("Account deletion is already scheduled", nil)
+	}
+
+	if ok, _ := user.CheckPassword(service.Password); !ok {
+		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password", nil)
+	}
+
+	if user.TwoFactor != "" && !totp.Validate(service.Code, user.TwoFactor) && !user.UseRecoveryCode(service.Code) {
+		return serializer.Err(serializer.Code2FACodeErr, "2FA code not correct", nil)
+	}
+
+	days := model.GetIntSetting("account_deletion_grace_days", 7)
+	if days < 0 {
+		days = 0
+	}
+
+	if err := user.ScheduleDeletion(time.Duration(days) * 24 * time.Hour); err != nil {
+		return serializer.DBErr("Failed to schedule account deletion", err)
+	}
+
+	model.RecordAudit(c, user.ID, model.AuditAccountDeletionRequest, model.AuditTarget("user", user.ID),
+		user.DeleteAfter.Format(time.RFC3339))
+	return serializer.Response{Data: user.DeleteAfter}
+}
+
+// Cancel 在冷静期内撤销注销申请，账户下的分享恢复可用
+func (service *CancelDeletionService) Cancel(c *gin.Context, user *model.User) serializer.Response {
+	if user.DeleteAfter == nil {
+		return serializer.ParamErr("Account deletion is not scheduled", nil)
+	}
+
+	if err := user.CancelDeletion(); err != nil {
+		return serializer.DBErr("Failed to cancel account deletion", err)
+	}
+
+	model.RecordAudit(c, user.ID, model.AuditAccountDeletionCancel, model.AuditTarget("user", user.ID), "")
+	return serializer.Response{}
+}
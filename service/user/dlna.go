@@ -0,0 +1,70 @@
+package user
+
+import (
+	"path"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/conf"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+// DLNAFolderChange 更改在局域网中以 DLNA 发布的媒体目录，路径为空时停止发布
+type DLNAFolderChange struct {
+	Path string `json:"path" binding:"max=65535"`
+}
+
+// dlnaFolderPath 用户发布的媒体目录的路径，未发布或目录已删除时为空
+func dlnaFolderPath(user *model.User) string {
+	if user.OptionsSerialized.DLNAFolder == 0 {
+		return ""
+	}
+
+	folders, err := model.GetFoldersByIDs([]uint{user.OptionsSerialized.DLNAFolder}, user.ID)
+	if err != nil || len(folders) == 0 {
+		return ""
+	}
+
+	if err := folders[0].TraceRoot(); err != nil {
+		return ""
+	}
+	return path.Join(folders[0].Position, folders[0].Name)
+}
+
+// dlnaSetting 用户的 DLNA 设定，enabled 表示站点已启用 DLNA 且用户组允许发布
+func dlnaSetting(user *model.User) map[string]interface{} {
+	return map[string]interface{}{
+		"enabled": conf.DLNAConfig.Listen != "" && user.Group.OptionsSerialized.DLNA,
+		"path":    dlnaFolderPath(user),
+	}
+}
+
+// Update 更改发布的媒体目录
+func (service *DLNAFolderChange) Update(c *gin.Context, user *model.User) serializer.Response {
+	folderID := uint(0)
+	if service.Path != "" {
+		if !user.Group.OptionsSerialized.DLNA {
+			return serializer.Err(serializer.CodeGroupNotAllowed, "Your group has no permission to publish media over DLNA", nil)
+		}
+
+		fs, err := filesystem.NewFileSystem(user)
+		if err != nil {
+			return serializer.Err(serializer.CodeCreateFSError, "", err)
+		}
+		defer fs.Recycle()
+
+		exist, folder := fs.IsPathExist(service.Path)
+		if !exist {
+			return serializer.Err(serializer.CodeParentNotExist, "Folder not exist", nil)
+		}
+		folderID = folder.ID
+	}
+
+	user.OptionsSerialized.DLNAFolder = folderID
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update setting", err)
+	}
+
+	return serializer.Response{Data: dlnaSetting(user)}
+}
@@ -0,0 +1,133 @@
+package user
+
+import (
+	"crypto/subtle"
+	"encoding/gob"
+	"fmt"
+	"strings"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// emailChangeCachePrefix 更改邮箱验证码的缓存前缀
+	emailChangeCachePrefix = "email_change_"
+	// emailChangeTTL 更改邮箱验证码的有效期
+	emailChangeTTL = 600
+)
+
+func init() {
+	gob.Register(emailChange{})
+}
+
+// emailChange 待验证的邮箱更改请求
+type emailChange struct {
+	Email string
+	Code  string
+}
+
+// EmailChange 更改邮箱服务，新邮箱须通过验证码验证后才会生效
+type EmailChange struct {
+	Email    string `json:"email" binding:"required,email,max=100"`
+	Password string `json:"password" binding:"required,min=4,max=64"`
+}
+
+// EmailChangeConfirmService 验证新邮箱并完成更改的服务
+type EmailChangeConfirmService struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// checkNewEmail 检查新邮箱是否符合域名限制且未被使用
+func checkNewEmail(user *model.User, address string) serializer.Response {
+	if !model.EmailDomainAllowed(address) {
+		return serializer.Err(serializer.CodeEmailDomainNotAllowed, "Email domain is not allowed", nil)
+	}
+
+	if existed, err := model.GetUserByEmail(address); err == nil && existed.ID != user.ID {
+		return serializer.Err(serializer.CodeEmailExisted, "Email already in use", nil)
+	}
+
+	return serializer.Response{}
+}
+
+// Update 发送验证码至新邮箱
+func (service *EmailChange) Update(c *gin.Context, user *model.User) serializer.Response {
+	if user.LDAPUser != "" {
+		return serializer.Err(serializer.CodeNoPermissionErr, "Email of directory users cannot be changed", nil)
+	}
+
+	if ok, _ := user.CheckPassword(service.Password); !ok {
+		return serializer.Err(serializer.CodeCredentialInvalid, "Incorrect password", nil)
+	}
+
+	address := strings.TrimSpace(service.Email)
+	if strings.EqualFold(address, user.Email) {
+		return serializer.ParamErr("New email is the same as the current one", nil)
+	}
+
+	if res := checkNewEmail(user, address); res.Code != 0 {
+		return res
+	}
+
+	code, err := newLoginConfirmCode()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate verification code", err)
+	}
+
+	title, body := email.NewEmailChangeEmail(user.Nick, address, code)
+	if err := email.Send(address, title, body); err != nil {
+		return serializer.Err(serializer.CodeFailedSendEmail, "Failed to send verification email", err)
+	}
+
+	key := fmt.Sprintf("%d", user.ID)
+	cache.Set(emailChangeCachePrefix+key, emailChange{Email: address, Code: code}, emailChangeTTL)
+	cache.Deletes([]string{key}, emailChangeCachePrefix+"attempts_")
+	return serializer.Response{}
+}
+
+// Confirm 使用发往新邮箱的验证码完成更改
+func (service *EmailChangeConfirmService) Confirm(c *gin.Context, user *model.User) serializer.Response {
+	key := fmt.Sprintf("%d", user.ID)
+	pending, exist := cache.Get(emailChangeCachePrefix + key)
+	if !exist {
+		return serializer.Err(serializer.CodeNotFound, "Verification code expired", nil)
+	}
+
+	change := pending.(emailChange)
+	if subtle.ConstantTimeCompare([]byte(change.Code), []byte(strings.TrimSpace(service.Code))) != 1 {
+		attempts := 1
+		if v, ok := cache.Get(emailChangeCachePrefix + "attempts_" + key); ok {
+			attempts = v.(int) + 1
+		}
+
+		// 尝试次数过多时作废验证码
+		if attempts >= loginConfirmMaxAttempts {
+			cache.Deletes([]string{key, "attempts_" + key}, emailChangeCachePrefix)
+		} else {
+			cache.Set(emailChangeCachePrefix+"attempts_"+key, attempts, emailChangeTTL)
+		}
+		return serializer.Err(serializer.CodeCredentialInvalid, "Verification code not correct", nil)
+	}
+
+	cache.Deletes([]string{key, "attempts_" + key}, emailChangeCachePrefix)
+
+	// 验证期间限制或占用情况可能已变化
+	if res := checkNewEmail(user, change.Email); res.Code != 0 {
+		return res
+	}
+
+	previous := user.Email
+	if err := model.DB.Model(user).Updates(map[string]interface{}{
+		"email":        change.Email,
+		"email_status": model.EmailDeliverable,
+	}).Error; err != nil {
+		return serializer.Err(serializer.CodeEmailExisted, "Email already in use", err)
+	}
+
+	model.RecordAudit(c, user.ID, model.AuditEmailChange, model.AuditTarget("user", user.ID), previous+" -> "+change.Email)
+	return serializer.Response{Data: change.Email}
+}
@@ -0,0 +1,43 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+)
+
+// ldapLogin 通过 LDAP 验证用户凭据，local 为按邮箱找到的 LDAP 用户。
+// 目录用户首次登录时自动创建对应用户，并按所属目录组分配用户组
+func (service *UserLoginService) ldapLogin(local model.User) (model.User, error) {
+	username := service.UserName
+	if local.LDAPUser != "" {
+		username = local.LDAPUser
+	}
+
+	conf := ldap.NewConfig()
+	identity, err := conf.Authenticate(username, service.Password)
+	if err != nil {
+		if err != ldap.ErrInvalidCredentials && err != ldap.ErrUserNotFound {
+			util.Log().Warning("LDAP 认证失败, %s", err)
+		}
+		return local, err
+	}
+
+	if local.LDAPUser != "" {
+		return local, nil
+	}
+
+	user, err := model.GetUserByLDAP(identity.Username)
+	if err == nil {
+		return user, nil
+	}
+
+	created, err := conf.Provision(identity)
+	if err != nil {
+		util.Log().Warning("无法创建 LDAP 用户 [%s], %s", identity.Username, err)
+		return local, err
+	}
+
+	// 重新读取以加载用户组等关联
+	return model.GetUserByID(created.ID)
+}
@@ -6,6 +6,8 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/ldap"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
@@ -16,7 +18,8 @@ import (
 // UserLoginService 管理用户登录的服务
 type UserLoginService struct {
 	//TODO 细致调整验证规则
-	UserName string `form:"userName" json:"userName" binding:"required,email"`
+	// 启用 LDAP 认证时也可以是目录中的登录名
+	UserName string `form:"userName" json:"userName" binding:"required,max=100"`
 	Password string `form:"Password" json:"Password" binding:"required,min=4,max=64"`
 }
 
@@ -52,8 +55,12 @@ func (service *UserResetService) Reset(c *gin.Context) serializer.Response {
 		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
 	}
 
-	user.SetPassword(service.Password)
-	if err := user.Update(map[string]interface{}{"password": user.Password}); err != nil {
+	policy := password.NewPolicy()
+	if res := checkNewPassword(policy, &user, service.Password); res.Code != 0 {
+		return res
+	}
+
+	if err := user.ChangePassword(service.Password, policy.History); err != nil {
 		return serializer.DBErr("Failed to reset password", err)
 	}
 
@@ -95,6 +102,15 @@ func (service *UserResetEmailService) Reset(c *gin.Context) serializer.Response
 	return serializer.Response{}
 }
 
+// recordLoginFailure 记录登录失败的审计事件，操作者未经验证，记为匿名；user 为空时表示账户不存在
+func recordLoginFailure(c *gin.Context, user *model.User, email, reason string) {
+	target := ""
+	if user != nil {
+		target = model.AuditTarget("user", user.ID)
+	}
+	model.RecordAudit(c, 0, model.AuditLoginFailed, target, reason+": "+email)
+}
+
 // Login 二步验证继续登录
 func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 	if uid, ok := util.GetSession(c, "2fa_user_id").(uint); ok {
@@ -104,18 +120,19 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 			return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
 		}
 
-		// 验证二步验证代码
-		if !totp.Validate(service.Code, expectedUser.TwoFactor) {
+		// 验证二步验证代码，也可使用恢复码
+		if !totp.Validate(service.Code, expectedUser.TwoFactor) && !expectedUser.UseRecoveryCode(service.Code) {
+			recordLoginFailure(c, &expectedUser, expectedUser.Email, "wrong 2fa code")
 			return serializer.Err(serializer.Code2FACodeErr, "2FA code not correct", nil)
 		}
 
+		if service.Remember {
+			rememberDevice(c, &expectedUser)
+		}
+
 		//登陆成功，清空并设置session
 		util.DeleteSession(c, "2fa_user_id")
-		util.SetSession(c, map[string]interface{}{
-			"user_id": expectedUser.ID,
-		})
-
-		return serializer.BuildUserResponse(expectedUser)
+		return SignIn(c, &expectedUser)
 	}
 
 	return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
@@ -124,33 +141,42 @@ func (service *Enable2FA) Login(c *gin.Context) serializer.Response {
 // Login 用户登录函数
 func (service *UserLoginService) Login(c *gin.Context) serializer.Response {
 	expectedUser, err := model.GetUserByEmail(service.UserName)
-	// 一系列校验
-	if err != nil {
-		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", err)
-	}
-	if authOK, _ := expectedUser.CheckPassword(service.Password); !authOK {
-		return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", nil)
+	if (err != nil || expectedUser.LDAPUser != "") && ldap.Enabled() {
+		// 本地不存在的用户及 LDAP 用户通过目录验证
+		if expectedUser, err = service.ldapLogin(expectedUser); err != nil {
+			recordLoginFailure(c, nil, service.UserName, "ldap authentication failed")
+			return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", err)
+		}
+	} else {
+		// 一系列校验
+		if err != nil {
+			recordLoginFailure(c, nil, service.UserName, "user not found")
+			return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", err)
+		}
+		if authOK, _ := expectedUser.CheckPassword(service.Password); !authOK {
+			recordLoginFailure(c, &expectedUser, service.UserName, "wrong password")
+			return serializer.Err(serializer.CodeCredentialInvalid, "Wrong password or email address", nil)
+		}
 	}
 	if expectedUser.Status == model.Baned || expectedUser.Status == model.OveruseBaned {
 		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
 	}
+	if !expectedUser.Group.AllowIP(c.ClientIP()) {
+		return serializer.Err(serializer.CodeIPNotAllowed, "Login from your IP address is not allowed", nil)
+	}
 	if expectedUser.Status == model.NotActivicated {
 		return serializer.Err(serializer.CodeUserNotActivated, "This account is not activated", nil)
 	}
 
-	if expectedUser.TwoFactor != "" {
+	if needSecondFactor(c, &expectedUser) {
 		// 需要二步验证
 		util.SetSession(c, map[string]interface{}{
 			"2fa_user_id": expectedUser.ID,
 		})
-		return serializer.Response{Code: 203}
+		return serializer.Response{Code: 203, Data: secondFactors(&expectedUser)}
 	}
 
 	//登陆成功，清空并设置session
-	util.SetSession(c, map[string]interface{}{
-		"user_id": expectedUser.ID,
-	})
-
-	return serializer.BuildUserResponse(expectedUser)
+	return SignIn(c, &expectedUser)
 
 }
@@ -0,0 +1,174 @@
+package user
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/email"
+	"github.com/cloudreve/Cloudreve/v3/pkg/notify"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// loginConfirmCachePrefix 异常登录确认验证码的缓存前缀
+	loginConfirmCachePrefix = "login_confirm_"
+	// loginConfirmTTL 异常登录确认验证码的有效期
+	loginConfirmTTL = 600
+	// loginConfirmMaxAttempts 验证码最多可尝试的次数
+	loginConfirmMaxAttempts = 5
+)
+
+// 异常登录检测规则
+const (
+	loginRuleNewDevice  = "new_device"
+	loginRuleNewCountry = "new_country"
+)
+
+// LoginConfirmService 异常登录邮件确认服务
+type LoginConfirmService struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// SignIn 完成身份验证后登录用户，开启异常登录检测时，从新设备或新国家/地区登录会发送提醒邮件，
+// 开启邮件确认时需输入邮件中的验证码后才能完成登录
+func SignIn(c *gin.Context, user *model.User) serializer.Response {
+	options := model.GetSettingByNames("login_alert", "login_alert_rules", "login_alert_confirm")
+	if !model.IsTrueVal(options["login_alert"]) {
+		return signIn(c, user)
+	}
+
+	country := model.ClientCountry(c)
+	anomaly, err := model.DetectLoginAnomaly(user.ID, model.LoginFingerprint(c.Request.UserAgent()), country)
+	if err != nil {
+		util.Log().Warning("无法检测用户 [%s] 的异常登录, %s", user.Email, err)
+		return signIn(c, user)
+	}
+
+	reason := loginAnomalyReason(anomaly, util.SplitList(options["login_alert_rules"]))
+	if reason == "" {
+		return signIn(c, user)
+	}
+
+	info := &email.LoginInfo{
+		Reason:  reason,
+		IP:      c.ClientIP(),
+		Country: country,
+		Device:  c.Request.UserAgent(),
+		Time:    time.Now().Format("2006-01-02 15:04:05"),
+	}
+
+	// 无需确认时按用户的通知偏好发送提醒
+	if !model.IsTrueVal(options["login_alert_confirm"]) {
+		notify.Send(user, &notify.Message{
+			Event: model.NotifyLoginAlert,
+			Title: "新设备登录提醒",
+			Content: fmt.Sprintf("您的账户刚刚在%s登录。\n时间：%s\nIP：%s\n国家/地区：%s\n设备：%s",
+				reason, info.Time, info.IP, info.Country, info.Device),
+			Mail: func() (string, string) {
+				return email.NewLoginAlertEmail(user.Nick, info)
+			},
+		})
+		return signIn(c, user)
+	}
+
+	// 需要邮件确认
+	code, err := newLoginConfirmCode()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate confirmation code", err)
+	}
+
+	title, body := email.NewLoginConfirmEmail(user.Nick, code, info)
+	if err := email.Send(user.Email, title, body); err != nil {
+		return serializer.Err(serializer.CodeFailedSendEmail, "Failed to send confirmation email", err)
+	}
+
+	key := fmt.Sprintf("%d", user.ID)
+	cache.Set(loginConfirmCachePrefix+key, code, loginConfirmTTL)
+	cache.Deletes([]string{key}, loginConfirmCachePrefix+"attempts_")
+	util.SetSession(c, map[string]interface{}{
+		"login_confirm_user_id": user.ID,
+	})
+	return serializer.Err(serializer.CodeLoginConfirmRequired, "Please enter the confirmation code sent to your email", nil)
+}
+
+// signIn 记录登录设备并写入登录会话
+func signIn(c *gin.Context, user *model.User) serializer.Response {
+	if err := model.RecordLoginDevice(user.ID, c.Request.UserAgent(), model.ClientCountry(c), c.ClientIP()); err != nil {
+		util.Log().Warning("无法记录用户 [%s] 的登录设备, %s", user.Email, err)
+	}
+
+	model.RecordAudit(c, user.ID, model.AuditLogin, model.AuditTarget("user", user.ID), c.Request.UserAgent())
+	util.SetSession(c, map[string]interface{}{
+		"user_id": user.ID,
+	})
+	return serializer.BuildUserResponse(*user)
+}
+
+// loginAnomalyReason 根据启用的检测规则返回异常描述，无需提醒时返回空值
+func loginAnomalyReason(anomaly model.LoginAnomaly, rules []string) string {
+	reasons := make([]string, 0, 2)
+	if anomaly.NewDevice && util.ContainsString(rules, loginRuleNewDevice) {
+		reasons = append(reasons, "新设备上")
+	}
+	if anomaly.NewCountry && util.ContainsString(rules, loginRuleNewCountry) {
+		reasons = append(reasons, "新的国家/地区")
+	}
+	return strings.Join(reasons, "、")
+}
+
+// newLoginConfirmCode 生成六位数字验证码
+func newLoginConfirmCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// Confirm 使用邮件中的验证码完成登录
+func (service *LoginConfirmService) Confirm(c *gin.Context) serializer.Response {
+	uid, ok := util.GetSession(c, "login_confirm_user_id").(uint)
+	if !ok {
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Login session not exist", nil)
+	}
+
+	key := fmt.Sprintf("%d", uid)
+	code, exist := cache.Get(loginConfirmCachePrefix + key)
+	if !exist {
+		util.DeleteSession(c, "login_confirm_user_id")
+		return serializer.Err(serializer.CodeLoginSessionNotExist, "Confirmation code expired", nil)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.(string)), []byte(strings.TrimSpace(service.Code))) != 1 {
+		attempts := 1
+		if v, ok := cache.Get(loginConfirmCachePrefix + "attempts_" + key); ok {
+			attempts = v.(int) + 1
+		}
+
+		// 尝试次数过多时作废验证码
+		if attempts >= loginConfirmMaxAttempts {
+			cache.Deletes([]string{key, "attempts_" + key}, loginConfirmCachePrefix)
+			util.DeleteSession(c, "login_confirm_user_id")
+		} else {
+			cache.Set(loginConfirmCachePrefix+"attempts_"+key, attempts, loginConfirmTTL)
+		}
+		return serializer.Err(serializer.CodeCredentialInvalid, "Confirmation code not correct", nil)
+	}
+
+	user, err := model.GetActiveUserByID(uid)
+	if err != nil {
+		return serializer.Err(serializer.CodeUserNotFound, "User not found", nil)
+	}
+
+	cache.Deletes([]string{key, "attempts_" + key}, loginConfirmCachePrefix)
+	util.DeleteSession(c, "login_confirm_user_id")
+	return signIn(c, &user)
+}
@@ -0,0 +1,151 @@
+package user
+
+import (
+	"net/url"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sms"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// NotificationPreferenceChange 更改通知偏好
+type NotificationPreferenceChange struct {
+	Routes      map[string][]string `json:"routes" binding:"required"` // 各事件类型的通知渠道，渠道为空表示不通知
+	Digest      []string            `json:"digest"`                    // 邮件通知改为定期汇总发送的事件类型
+	Webhook     string              `json:"webhook" binding:"max=1024"`
+	ResetSecret bool                `json:"reset_secret"` // 重新生成 Webhook 签名密钥
+}
+
+// NotificationListService 站内信列表服务
+type NotificationListService struct {
+	Page     int  `form:"page" binding:"required,min=1"`
+	PageSize int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Unread   bool `form:"unread"`
+}
+
+// NotificationBatchService 站内信批量操作服务
+type NotificationBatchService struct {
+	ID []uint `json:"id"`
+}
+
+// notificationPreference 构建用户当前生效的通知偏好
+func notificationPreference(user *model.User) map[string]interface{} {
+	pref := user.OptionsSerialized.Notification
+	routes := make(map[string][]string, len(model.NotifyEvents))
+	for _, event := range model.NotifyEvents {
+		routes[event] = pref.Channels(event)
+	}
+
+	res := map[string]interface{}{
+		"routes":          routes,
+		"digest":          []string{},
+		"webhook_enabled": model.IsTrueVal(model.GetSettingByName("notify_webhook")),
+		"sms_enabled":     sms.Enabled(),
+		"sms_events":      model.NotifySMSEvents,
+	}
+	if pref != nil {
+		if pref.Digest != nil {
+			res["digest"] = pref.Digest
+		}
+		res["webhook"] = pref.Webhook
+		res["webhook_secret"] = pref.WebhookSecret
+	}
+	return res
+}
+
+// Update 更新通知偏好
+func (service *NotificationPreferenceChange) Update(c *gin.Context, user *model.User) serializer.Response {
+	webhookEnabled := model.IsTrueVal(model.GetSettingByName("notify_webhook"))
+	routes := make(map[string][]string, len(service.Routes))
+	for event, channels := range service.Routes {
+		if !util.ContainsString(model.NotifyEvents, event) {
+			return serializer.ParamErr("Unknown notification event: "+event, nil)
+		}
+
+		routes[event] = make([]string, 0, len(channels))
+		for _, channel := range channels {
+			if !util.ContainsString(model.NotifyChannels, channel) {
+				return serializer.ParamErr("Unknown notification channel: "+channel, nil)
+			}
+			if channel == model.NotifyChannelWebhook && (!webhookEnabled || service.Webhook == "") {
+				return serializer.ParamErr("Webhook is not available", nil)
+			}
+			if channel == model.NotifyChannelSMS &&
+				(!util.ContainsString(model.NotifySMSEvents, event) || user.Phone == "" || !sms.Enabled()) {
+				return serializer.ParamErr("SMS notification is not available for event: "+event, nil)
+			}
+			if !util.ContainsString(routes[event], channel) {
+				routes[event] = append(routes[event], channel)
+			}
+		}
+	}
+
+	for _, event := range service.Digest {
+		if !util.ContainsString(model.NotifyEvents, event) {
+			return serializer.ParamErr("Unknown notification event: "+event, nil)
+		}
+	}
+
+	if service.Webhook != "" {
+		target, err := url.Parse(service.Webhook)
+		if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+			return serializer.ParamErr("Invalid webhook URL", err)
+		}
+	}
+
+	pref := &model.NotificationPreference{
+		Routes:  routes,
+		Digest:  service.Digest,
+		Webhook: service.Webhook,
+	}
+
+	// 沿用原有的签名密钥，首次设置 Webhook 时生成
+	if previous := user.OptionsSerialized.Notification; previous != nil && !service.ResetSecret {
+		pref.WebhookSecret = previous.WebhookSecret
+	}
+	if pref.Webhook == "" {
+		pref.WebhookSecret = ""
+	} else if pref.WebhookSecret == "" {
+		pref.WebhookSecret = util.RandSecureString(32)
+	}
+
+	user.OptionsSerialized.Notification = pref
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update notification preference", err)
+	}
+
+	return serializer.Response{Data: notificationPreference(user)}
+}
+
+// List 列出站内信
+func (service *NotificationListService) List(c *gin.Context, user *model.User) serializer.Response {
+	pageSize := service.PageSize
+	if pageSize == 0 {
+		pageSize = 10
+	}
+
+	notifications, total := model.ListNotifications(user.ID, service.Unread, service.Page, pageSize)
+	return serializer.BuildNotificationList(notifications, total, model.CountUnreadNotifications(user.ID))
+}
+
+// MarkRead 将站内信标为已读，未指定ID时标记全部
+func (service *NotificationBatchService) MarkRead(c *gin.Context, user *model.User) serializer.Response {
+	if err := model.MarkNotificationsRead(user.ID, service.ID); err != nil {
+		return serializer.DBErr("Failed to update notifications", err)
+	}
+	return serializer.Response{}
+}
+
+// Delete 删除站内信
+func (service *NotificationBatchService) Delete(c *gin.Context, user *model.User) serializer.Response {
+	if len(service.ID) == 0 {
+		return serializer.ParamErr("No notification selected", nil)
+	}
+
+	if err := model.DeleteNotifications(user.ID, service.ID); err != nil {
+		return serializer.DBErr("Failed to delete notifications", err)
+	}
+	return serializer.Response{}
+}
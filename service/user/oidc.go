@@ -0,0 +1,96 @@
+package user
+
+import (
+	"context"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/oidc"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// OIDCLoginService 发起 OIDC 单点登录的服务
+type OIDCLoginService struct {
+}
+
+// OIDCCallbackService 处理身份提供方回调的服务
+type OIDCCallbackService struct {
+	Code  string `form:"code" binding:"required"`
+	State string `form:"state" binding:"required"`
+}
+
+// URL 返回身份提供方的认证页面地址，state、nonce 及 PKCE 校验码记录在会话中
+func (service *OIDCLoginService) URL(c *gin.Context) serializer.Response {
+	conf := oidc.NewConfig()
+	provider, err := conf.Discover(context.Background())
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to discover OIDC provider", err)
+	}
+
+	state, nonce := util.RandSecureString(32), util.RandSecureString(32)
+	verifier, challenge := oidc.NewPKCE()
+	authURL, err := conf.AuthCodeURL(provider, state, nonce, challenge)
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Invalid authorization endpoint", err)
+	}
+
+	util.SetSession(c, map[string]interface{}{
+		"oidc_state":    state,
+		"oidc_nonce":    nonce,
+		"oidc_verifier": verifier,
+	})
+	return serializer.Response{Data: authURL}
+}
+
+// Login 校验回调并登录 ID Token 对应的用户，首次登录时关联或注册用户
+func (service *OIDCCallbackService) Login(c *gin.Context) serializer.Response {
+	state, _ := util.GetSession(c, "oidc_state").(string)
+	nonce, _ := util.GetSession(c, "oidc_nonce").(string)
+	verifier, _ := util.GetSession(c, "oidc_verifier").(string)
+	util.DeleteSession(c, "oidc_state")
+	util.DeleteSession(c, "oidc_nonce")
+	util.DeleteSession(c, "oidc_verifier")
+	if state == "" || state != service.State {
+		return serializer.Err(serializer.CodeCredentialInvalid, "Invalid OIDC state", nil)
+	}
+
+	ctx := context.Background()
+	conf := oidc.NewConfig()
+	provider, err := conf.Discover(ctx)
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to discover OIDC provider", err)
+	}
+
+	idToken, err := conf.Exchange(ctx, provider, service.Code, verifier)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, "Failed to exchange authorization code", err)
+	}
+
+	claims, err := conf.Verify(ctx, provider, idToken, nonce)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, "Invalid ID token", err)
+	}
+
+	user, err := conf.ResolveUser(claims)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, err.Error(), err)
+	}
+
+	if user.Status == model.Baned || user.Status == model.OveruseBaned {
+		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
+	}
+	if user.Status == model.NotActivicated {
+		return serializer.Err(serializer.CodeUserNotActivated, "This account is not activated", nil)
+	}
+
+	if needSecondFactor(c, user) {
+		// 需要二步验证
+		util.SetSession(c, map[string]interface{}{
+			"2fa_user_id": user.ID,
+		})
+		return serializer.Response{Code: 203, Data: secondFactors(user)}
+	}
+
+	return SignIn(c, user)
+}
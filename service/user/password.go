@@ -0,0 +1,20 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+)
+
+// checkNewPassword 检查用户设定的新密码是否符合密码策略且未在近期使用过
+func checkNewPassword(policy *password.Policy, user *model.User, pw string) serializer.Response {
+	if err := policy.Validate(pw); err != nil {
+		return serializer.Err(serializer.CodePasswordPolicy, err.Error(), err)
+	}
+
+	if user.UsedPassword(pw, policy.History) {
+		return serializer.Err(serializer.CodePasswordPolicy, password.ErrReused.Error(), nil)
+	}
+
+	return serializer.Response{}
+}
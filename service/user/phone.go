@@ -0,0 +1,159 @@
+package user
+
+import (
+	"crypto/subtle"
+	"encoding/gob"
+	"fmt"
+	"strings"
+	"time"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/cache"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sms"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// phoneCodeCachePrefix 短信验证码的缓存前缀
+	phoneCodeCachePrefix = "phone_code_"
+	// phoneCodeTTL 短信验证码的有效期
+	phoneCodeTTL = 300
+	// phoneCodeCooldown 同一号码两次发送验证码的最小间隔
+	phoneCodeCooldown = 60
+)
+
+func init() {
+	gob.Register(phoneCode{})
+}
+
+// phoneCode 已发送的短信验证码
+type phoneCode struct {
+	Phone string
+	Code  string
+}
+
+// PhoneCodeService 发送注册验证码的服务
+type PhoneCodeService struct {
+	Phone string `json:"phone" binding:"required,max=32"`
+}
+
+// PhoneBind 绑定手机号码服务，号码为空时解除绑定，否则发送验证码至新号码
+type PhoneBind struct {
+	Phone string `json:"phone" binding:"max=32"`
+}
+
+// PhoneBindConfirmService 验证并绑定手机号码的服务
+type PhoneBindConfirmService struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// sendPhoneCode 向手机号码发送验证码，key 区分验证码的用途
+func sendPhoneCode(key, phone string) serializer.Response {
+	if !sms.Enabled() {
+		return serializer.Err(serializer.CodeFeatureNotEnabled, "SMS is not enabled", nil)
+	}
+
+	if _, ok := cache.Get(phoneCodeCachePrefix + "cooldown_" + phone); ok {
+		return serializer.Err(serializer.CodeRateLimited, "Verification code was sent recently, please try again later", nil)
+	}
+
+	code, err := newLoginConfirmCode()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to generate verification code", err)
+	}
+
+	if err := sms.Send(phone, sms.NewVerificationMessage(code, phoneCodeTTL*time.Second)); err != nil {
+		return serializer.Err(serializer.CodeFailedSendSMS, "Failed to send verification SMS", err)
+	}
+
+	cache.Set(phoneCodeCachePrefix+key, phoneCode{Phone: phone, Code: code}, phoneCodeTTL)
+	cache.Set(phoneCodeCachePrefix+"cooldown_"+phone, true, phoneCodeCooldown)
+	cache.Deletes([]string{"attempts_" + key}, phoneCodeCachePrefix)
+	return serializer.Response{}
+}
+
+// checkPhoneCode 校验验证码，通过后作废验证码并返回接收验证码的号码
+func checkPhoneCode(key, code string) (string, serializer.Response) {
+	pending, exist := cache.Get(phoneCodeCachePrefix + key)
+	if !exist {
+		return "", serializer.Err(serializer.CodeNotFound, "Verification code expired", nil)
+	}
+
+	expected := pending.(phoneCode)
+	if subtle.ConstantTimeCompare([]byte(expected.Code), []byte(strings.TrimSpace(code))) != 1 {
+		attempts := 1
+		if v, ok := cache.Get(phoneCodeCachePrefix + "attempts_" + key); ok {
+			attempts = v.(int) + 1
+		}
+
+		// 尝试次数过多时作废验证码
+		if attempts >= loginConfirmMaxAttempts {
+			cache.Deletes([]string{key, "attempts_" + key}, phoneCodeCachePrefix)
+		} else {
+			cache.Set(phoneCodeCachePrefix+"attempts_"+key, attempts, phoneCodeTTL)
+		}
+		return "", serializer.Err(serializer.CodeCredentialInvalid, "Verification code not correct", nil)
+	}
+
+	cache.Deletes([]string{key, "attempts_" + key}, phoneCodeCachePrefix)
+	return expected.Phone, serializer.Response{}
+}
+
+// Send 发送注册验证码
+func (service *PhoneCodeService) Send(c *gin.Context) serializer.Response {
+	phone, err := sms.NormalizePhone(service.Phone)
+	if err != nil {
+		return serializer.ParamErr("Invalid phone number", err)
+	}
+
+	if model.IsPhoneUsed(phone, 0) {
+		return serializer.Err(serializer.CodePhoneExisted, "Phone number already in use", nil)
+	}
+
+	return sendPhoneCode("register_"+phone, phone)
+}
+
+// Update 发送验证码至新号码，号码为空时解除绑定
+func (service *PhoneBind) Update(c *gin.Context, user *model.User) serializer.Response {
+	if service.Phone == "" {
+		if err := user.Update(map[string]interface{}{"phone": ""}); err != nil {
+			return serializer.DBErr("Failed to unbind phone number", err)
+		}
+		return serializer.Response{}
+	}
+
+	phone, err := sms.NormalizePhone(service.Phone)
+	if err != nil {
+		return serializer.ParamErr("Invalid phone number", err)
+	}
+
+	if phone == user.Phone {
+		return serializer.ParamErr("New phone number is the same as the current one", nil)
+	}
+
+	if model.IsPhoneUsed(phone, user.ID) {
+		return serializer.Err(serializer.CodePhoneExisted, "Phone number already in use", nil)
+	}
+
+	return sendPhoneCode(fmt.Sprintf("bind_%d", user.ID), phone)
+}
+
+// Confirm 使用发往新号码的验证码完成绑定
+func (service *PhoneBindConfirmService) Confirm(c *gin.Context, user *model.User) serializer.Response {
+	phone, res := checkPhoneCode(fmt.Sprintf("bind_%d", user.ID), service.Code)
+	if res.Code != 0 {
+		return res
+	}
+
+	// 验证期间号码可能已被占用
+	if model.IsPhoneUsed(phone, user.ID) {
+		return serializer.Err(serializer.CodePhoneExisted, "Phone number already in use", nil)
+	}
+
+	if err := user.Update(map[string]interface{}{"phone": phone}); err != nil {
+		return serializer.DBErr("Failed to bind phone number", err)
+	}
+
+	return serializer.Response{Data: phone}
+}
@@ -5,10 +5,13 @@ import (
 	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
 	"github.com/cloudreve/Cloudreve/v3/pkg/email"
 	"github.com/cloudreve/Cloudreve/v3/pkg/hashid"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/sms"
 	"github.com/gin-gonic/gin"
 	"net/url"
 	"strings"
+	"time"
 )
 
 // UserRegisterService 管理用户注册的服务
@@ -16,22 +19,75 @@ type UserRegisterService struct {
 	//TODO 细致调整验证规则
 	UserName string `form:"userName" json:"userName" binding:"required,email"`
 	Password string `form:"Password" json:"Password" binding:"required,min=4,max=64"`
+	// 邀请码，开启邀请注册时必填
+	InviteCode string `form:"inviteCode" json:"inviteCode" binding:"max=64"`
+	// 手机号码及短信验证码，开启手机验证时必填
+	Phone     string `form:"phone" json:"phone" binding:"max=32"`
+	PhoneCode string `form:"phoneCode" json:"phoneCode" binding:"max=16"`
 }
 
 // Register 新用户注册
 func (service *UserRegisterService) Register(c *gin.Context) serializer.Response {
 	// 相关设定
-	options := model.GetSettingByNames("email_active")
+	options := model.GetSettingByNames("email_active", "register_invite", "register_phone")
 
 	// 相关设定
 	isEmailRequired := model.IsTrueVal(options["email_active"])
 	defaultGroup := model.GetIntSetting("default_group", 2)
 
+	// 开启手机验证时须提供通过短信验证的手机号码
+	var phone string
+	if service.Phone != "" || model.IsTrueVal(options["register_phone"]) {
+		normalized, err := sms.NormalizePhone(service.Phone)
+		if err != nil {
+			return serializer.ParamErr("Invalid phone number", err)
+		}
+
+		if _, res := checkPhoneCode("register_"+normalized, service.PhoneCode); res.Code != 0 {
+			return res
+		}
+
+		if model.IsPhoneUsed(normalized, 0) {
+			return serializer.Err(serializer.CodePhoneExisted, "Phone number already in use", nil)
+		}
+		phone = normalized
+	}
+
+	// 开启邀请注册时须提供有效的邀请码，邀请码可指定受邀用户的用户组
+	var invitation *model.Invitation
+	if service.InviteCode != "" || model.IsTrueVal(options["register_invite"]) {
+		expected, err := model.GetInvitationByCode(service.InviteCode)
+		if err != nil || expected.Use() != nil {
+			return serializer.Err(serializer.CodeInvitationInvalid, "Invalid or expired invitation code", err)
+		}
+
+		invitation = expected
+		if invitation.GroupID != 0 {
+			defaultGroup = int(invitation.GroupID)
+		}
+	}
+
+	// 检查邮箱域名是否允许注册
+	if !model.EmailDomainAllowed(service.UserName) {
+		if invitation != nil {
+			invitation.Release()
+		}
+		return serializer.Err(serializer.CodeEmailDomainNotAllowed, "Email domain is not allowed", nil)
+	}
+
+	// 检查密码是否符合密码策略
+	if err := password.NewPolicy().Validate(service.Password); err != nil {
+		return serializer.Err(serializer.CodePasswordPolicy, err.Error(), err)
+	}
+
 	// 创建新的用户对象
 	user := model.NewUser()
 	user.Email = service.UserName
 	user.Nick = strings.Split(service.UserName, "@")[0]
+	user.Phone = phone
 	user.SetPassword(service.Password)
+	now := time.Now()
+	user.PasswordChangedAt = &now
 	user.Status = model.Active
 	if isEmailRequired {
 		user.Status = model.NotActivicated
@@ -40,6 +96,11 @@ func (service *UserRegisterService) Register(c *gin.Context) serializer.Response
 	userNotActivated := false
 	// 创建用户
 	if err := model.DB.Create(&user).Error; err != nil {
+		// 未创建新用户，归还邀请码的使用次数
+		if invitation != nil {
+			invitation.Release()
+		}
+
 		//检查已存在使用者是否尚未激活
 		expectedUser, err := model.GetUserByEmail(service.UserName)
 		if expectedUser.Status == model.NotActivicated {
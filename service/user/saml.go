@@ -0,0 +1,150 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/saml"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/util"
+	"github.com/gin-gonic/gin"
+)
+
+// SAMLLoginService 发起 SAML 单点登录的服务
+type SAMLLoginService struct {
+}
+
+// SAMLACSService 断言消费服务
+type SAMLACSService struct {
+	SAMLResponse string `form:"SAMLResponse" binding:"required"`
+}
+
+// SAMLSLOService 单点登出服务
+type SAMLSLOService struct {
+	SAMLRequest  string `form:"SAMLRequest"`
+	SAMLResponse string `form:"SAMLResponse"`
+	RelayState   string `form:"RelayState"`
+}
+
+// URL 返回身份提供方的认证地址
+func (service *SAMLLoginService) URL(c *gin.Context) serializer.Response {
+	conf, err := saml.NewConfig()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Invalid SAML settings", err)
+	}
+
+	target, err := conf.AuthnRequestURL()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to create SAML request", err)
+	}
+	return serializer.Response{Data: target}
+}
+
+// Login 校验身份提供方的响应并登录断言对应的用户，首次登录时关联或注册用户
+func (service *SAMLACSService) Login(c *gin.Context) serializer.Response {
+	conf, err := saml.NewConfig()
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Invalid SAML settings", err)
+	}
+
+	assertion, err := conf.ParseResponse(service.SAMLResponse)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, err.Error(), err)
+	}
+
+	user, err := conf.ResolveUser(assertion)
+	if err != nil {
+		return serializer.Err(serializer.CodeCredentialInvalid, err.Error(), err)
+	}
+
+	if user.Status == model.Baned || user.Status == model.OveruseBaned {
+		return serializer.Err(serializer.CodeUserBaned, "This account has been blocked", nil)
+	}
+	if user.Status == model.NotActivicated {
+		return serializer.Err(serializer.CodeUserNotActivated, "This account is not activated", nil)
+	}
+
+	// 记录身份提供方会话，用于单点登出
+	session := map[string]interface{}{
+		"saml_name_id":       assertion.NameID,
+		"saml_name_format":   assertion.NameIDFormat,
+		"saml_session_index": assertion.SessionIndex,
+	}
+
+	if needSecondFactor(c, user) {
+		// 需要二步验证
+		session["2fa_user_id"] = user.ID
+		util.SetSession(c, session)
+		return serializer.Response{Code: 203, Data: secondFactors(user)}
+	}
+
+	util.SetSession(c, session)
+	return SignIn(c, user)
+}
+
+// SAMLLogoutURL 清除当前会话中的 SAML 登录信息，启用单点登出时返回身份提供方的登出地址
+func SAMLLogoutURL(c *gin.Context) string {
+	nameID, _ := util.GetSession(c, "saml_name_id").(string)
+	format, _ := util.GetSession(c, "saml_name_format").(string)
+	sessionIndex, _ := util.GetSession(c, "saml_session_index").(string)
+	if nameID == "" {
+		return ""
+	}
+	clearSAMLSession(c)
+
+	if !model.IsTrueVal(model.GetSettingByName("saml_enabled")) {
+		return ""
+	}
+	conf, err := saml.NewConfig()
+	if err != nil || !conf.SLOEnabled() {
+		return ""
+	}
+
+	target, err := conf.LogoutRequestURL(nameID, format, sessionIndex)
+	if err != nil {
+		util.Log().Warning("无法创建 SAML 登出请求, %s", err)
+		return ""
+	}
+	return target
+}
+
+func clearSAMLSession(c *gin.Context) {
+	util.DeleteSession(c, "saml_name_id")
+	util.DeleteSession(c, "saml_name_format")
+	util.DeleteSession(c, "saml_session_index")
+}
+
+// Logout 处理身份提供方发来的登出请求或登出响应，返回需要跳转到的地址。
+// 收到登出请求时登出当前用户，并向身份提供方返回登出响应
+func (service *SAMLSLOService) Logout(c *gin.Context) (string, serializer.Response) {
+	if service.SAMLRequest == "" {
+		// 本服务发起的登出已完成
+		if service.SAMLResponse == "" {
+			return "", serializer.ParamErr("SAMLRequest or SAMLResponse is required", nil)
+		}
+		return "", serializer.Response{}
+	}
+
+	conf, err := saml.NewConfig()
+	if err != nil {
+		return "", serializer.Err(serializer.CodeInternalSetting, "Invalid SAML settings", err)
+	}
+	if !conf.SLOEnabled() {
+		return "", serializer.Err(serializer.CodeFeatureNotEnabled, "SAML single logout is not enabled", nil)
+	}
+
+	requestID, nameID, err := conf.ParseLogoutRequest(c.Request.URL.RawQuery)
+	if err != nil {
+		return "", serializer.Err(serializer.CodeCredentialInvalid, err.Error(), err)
+	}
+
+	// 只登出与请求中用户标识相同的会话
+	if current, _ := util.GetSession(c, "saml_name_id").(string); current == nameID {
+		util.DeleteSession(c, "user_id")
+		clearSAMLSession(c)
+	}
+
+	target, err := conf.LogoutResponseURL(requestID, service.RelayState)
+	if err != nil {
+		return "", serializer.Err(serializer.CodeInternalSetting, "Failed to create SAML logout response", err)
+	}
+	return target, serializer.Response{}
+}
@@ -10,7 +10,9 @@ import (
 	"strings"
 
 	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/password"
 	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
 	"github.com/cloudreve/Cloudreve/v3/pkg/util"
 	"github.com/gin-gonic/gin"
 	"github.com/pquerna/otp/totp"
@@ -20,9 +22,17 @@ import (
 type SettingService struct {
 }
 
+// TaskReportService 任务报告下载服务
+type TaskReportService struct {
+	Format string `form:"format" binding:"omitempty,eq=csv|eq=json"`
+}
+
 // SettingListService 通用设置列表服务
 type SettingListService struct {
-	Page int `form:"page" binding:"required,min=1"`
+	Page     int  `form:"page" binding:"required,min=1"`
+	PageSize int  `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Type     *int `form:"type"`
+	Status   *int `form:"status"`
 }
 
 // AvatarService 头像服务
@@ -32,7 +42,7 @@ type AvatarService struct {
 
 // SettingUpdateService 设定更改服务
 type SettingUpdateService struct {
-	Option string `uri:"option" binding:"required,eq=nick|eq=theme|eq=homepage|eq=vip|eq=qq|eq=policy|eq=password|eq=2fa|eq=authn"`
+	Option string `uri:"option" binding:"required,eq=nick|eq=theme|eq=homepage|eq=vip|eq=qq|eq=policy|eq=password|eq=2fa|eq=authn|eq=authn_2fa|eq=email|eq=notification|eq=dlna"`
 }
 
 // OptionsChangeHandler 属性更改接口
@@ -64,6 +74,8 @@ type PasswordChange struct {
 // Enable2FA 开启二步验证
 type Enable2FA struct {
 	Code string `json:"code" binding:"required"`
+	// 登录时记住此设备
+	Remember bool `json:"remember"`
 }
 
 // DeleteWebAuthn 删除WebAuthn凭证
@@ -71,6 +83,11 @@ type DeleteWebAuthn struct {
 	ID string `json:"id" binding:"required"`
 }
 
+// AuthnTwoFactor 使用验证器作为二步验证
+type AuthnTwoFactor struct {
+	Enabled bool `json:"status"`
+}
+
 // ThemeChose 主题选择
 type ThemeChose struct {
 	Theme string `json:"theme" binding:"required,hexcolor|rgb|rgba|hsl"`
@@ -92,6 +109,20 @@ func (service *DeleteWebAuthn) Update(c *gin.Context, user *model.User) serializ
 	return serializer.Response{}
 }
 
+// Update 更改是否使用验证器作为二步验证
+func (service *AuthnTwoFactor) Update(c *gin.Context, user *model.User) serializer.Response {
+	if service.Enabled && len(user.WebAuthnCredentials()) == 0 {
+		return serializer.ParamErr("No authenticator registered", nil)
+	}
+
+	user.OptionsSerialized.AuthnTwoFactor = service.Enabled
+	if err := user.UpdateOptions(); err != nil {
+		return serializer.DBErr("Failed to update setting", err)
+	}
+
+	return serializer.Response{}
+}
+
 // Update 更改二步验证设定
 func (service *Enable2FA) Update(c *gin.Context, user *model.User) serializer.Response {
 	if user.TwoFactor == "" {
@@ -109,6 +140,12 @@ func (service *Enable2FA) Update(c *gin.Context, user *model.User) serializer.Re
 			return serializer.DBErr("无法更新二步验证设定", err)
 		}
 
+		// 生成恢复码，仅在此时展示一次
+		codes, err := user.GenerateRecoveryCodes()
+		if err != nil {
+			return serializer.DBErr("无法生成恢复码", err)
+		}
+		return serializer.Response{Data: map[string]interface{}{"recovery_codes": codes}}
 	} else {
 		// 关闭2FA
 		if !totp.Validate(service.Code, user.TwoFactor) {
@@ -118,6 +155,9 @@ func (service *Enable2FA) Update(c *gin.Context, user *model.User) serializer.Re
 		if err := user.Update(map[string]interface{}{"two_factor": ""}); err != nil {
 			return serializer.DBErr("无法更新二步验证设定", err)
 		}
+		if err := user.ClearRecoveryCodes(); err != nil {
+			return serializer.DBErr("无法清除恢复码", err)
+		}
 	}
 
 	return serializer.Response{}
@@ -144,9 +184,14 @@ func (service *PasswordChange) Update(c *gin.Context, user *model.User) serializ
 		return serializer.Err(serializer.CodeParamErr, "原密码不正确", nil)
 	}
 
+	// 检查新密码是否符合密码策略
+	policy := password.NewPolicy()
+	if res := checkNewPassword(policy, user, service.New); res.Code != 0 {
+		return res
+	}
+
 	// 更改为新密码
-	user.SetPassword(service.New)
-	if err := user.Update(map[string]interface{}{"password": user.Password}); err != nil {
+	if err := user.ChangePassword(service.New, policy.History); err != nil {
 		return serializer.DBErr("密码更换失败", err)
 	}
 
@@ -237,10 +282,48 @@ func (service *AvatarService) Get(c *gin.Context) serializer.Response {
 
 // ListTasks 列出任务
 func (service *SettingListService) ListTasks(c *gin.Context, user *model.User) serializer.Response {
-	tasks, total := model.ListTasks(user.ID, service.Page, 10, "updated_at desc")
+	pageSize := service.PageSize
+	if pageSize == 0 {
+		pageSize = 10
+	}
+
+	conditions := make(map[string]interface{})
+	if service.Type != nil {
+		conditions["type"] = *service.Type
+	}
+	if service.Status != nil {
+		conditions["status"] = *service.Status
+	}
+
+	tasks, total := model.ListTasks(user.ID, service.Page, pageSize, "updated_at desc", conditions)
 	return serializer.BuildTaskList(tasks, total)
 }
 
+// Download 下载批量任务的逐项处理结果报告
+func (service *TaskReportService) Download(c *gin.Context, user *model.User, id uint) serializer.Response {
+	record, err := model.GetTasksByID(id)
+	if err != nil || record.UserID != user.ID {
+		return serializer.Err(serializer.CodeNotFound, "Task not exist", err)
+	}
+
+	if record.Report == "" {
+		return serializer.Err(serializer.CodeNotFound, "Task has no report", nil)
+	}
+
+	report, err := task.ParseReport(record.Report)
+	if err != nil {
+		return serializer.Err(serializer.CodeInternalSetting, "Failed to parse task report", err)
+	}
+
+	if service.Format == "csv" {
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"task_%d_report.csv\"", record.ID))
+		c.Data(200, "text/csv; charset=utf-8", report.CSV())
+		return serializer.Response{}
+	}
+
+	return serializer.Response{Data: report}
+}
+
 // Settings 获取用户设定
 func (service *SettingService) Settings(c *gin.Context, user *model.User) serializer.Response {
 	return serializer.Response{
@@ -248,9 +331,14 @@ func (service *SettingService) Settings(c *gin.Context, user *model.User) serial
 			"uid":          user.ID,
 			"homepage":     !user.OptionsSerialized.ProfileOff,
 			"two_factor":   user.TwoFactor != "",
+			"recovery":     user.RecoveryCodesLeft(), // 剩余恢复码数量
 			"prefer_theme": user.OptionsSerialized.PreferredTheme,
 			"themes":       model.GetSettingByName("themes"),
-			"authn":        serializer.BuildWebAuthnList(user.WebAuthnCredentials()),
+			"authn":        serializer.BuildAuthnCredentialList(user.AuthnCredentials()),
+			"authn_2fa":    user.OptionsSerialized.AuthnTwoFactor,
+			"notification": notificationPreference(user),
+			"phone":        user.Phone,
+			"dlna":         dlnaSetting(user),
 		},
 	}
 }
@@ -0,0 +1,61 @@
+package user
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/filesystem/response"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/cloudreve/Cloudreve/v3/pkg/task"
+	"github.com/gin-gonic/gin"
+)
+
+// TakeoutService 导出个人数据服务
+type TakeoutService struct {
+}
+
+// Create 创建导出个人数据任务，同一时间只能有一个进行中的导出任务
+func (service *TakeoutService) Create(c *gin.Context, user *model.User) serializer.Response {
+	if model.CountUserTasks(user.ID, task.TakeoutTaskType, task.Queued, task.Processing) > 0 {
+		return serializer.Err(serializer.CodeConflict, "An export task is already in progress", nil)
+	}
+
+	job, err := task.NewTakeoutTask(user)
+	if err != nil {
+		return serializer.Err(serializer.CodeCreateTaskError, "", err)
+	}
+	task.TaskPoll.Submit(task.Trace(job, c))
+
+	return serializer.Response{}
+}
+
+// Download 下载导出任务生成的压缩包
+func (service *TakeoutService) Download(c *gin.Context, user *model.User, id uint) serializer.Response {
+	record, err := model.GetTasksByID(id)
+	if err != nil || record.UserID != user.ID || record.Type != task.TakeoutTaskType {
+		return serializer.Err(serializer.CodeNotFound, "Task not exist", err)
+	}
+
+	var props task.TakeoutProps
+	if record.Status != task.Complete || json.Unmarshal([]byte(record.Props), &props) != nil || props.Archive == "" {
+		return serializer.Err(serializer.CodeNotFound, "Export archive is not ready", nil)
+	}
+
+	archive, err := os.Open(task.TakeoutArchivePath(props.Archive))
+	if err != nil {
+		return serializer.Err(serializer.CodeNotFound, "Export archive has expired", err)
+	}
+	defer archive.Close()
+
+	stat, err := archive.Stat()
+	if err != nil {
+		return serializer.Err(serializer.CodeIOFailed, "Failed to read export archive", err)
+	}
+
+	name := fmt.Sprintf("takeout_%s.zip", record.CreatedAt.Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", name))
+	response.ServeContent(c.Writer, c.Request, name, stat.ModTime(), archive)
+	return serializer.Response{}
+}
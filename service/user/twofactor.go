@@ -0,0 +1,57 @@
+package user
+
+import (
+	model "github.com/cloudreve/Cloudreve/v3/models"
+	"github.com/cloudreve/Cloudreve/v3/pkg/auth"
+	"github.com/cloudreve/Cloudreve/v3/pkg/serializer"
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+)
+
+// twoFactorRememberCookie 记住设备的免二步验证令牌
+const twoFactorRememberCookie = "cloudreve-2fa-remember"
+
+// RecoveryCodeService 重新生成二步验证恢复码的服务
+type RecoveryCodeService struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// needSecondFactor 返回登录时是否需要二步验证，已记住的设备可跳过
+func needSecondFactor(c *gin.Context, user *model.User) bool {
+	if !user.RequireSecondFactor() {
+		return false
+	}
+
+	token, err := c.Cookie(twoFactorRememberCookie)
+	return err != nil || auth.CheckTwoFactorRemember(auth.General, user, token) != nil
+}
+
+// rememberDevice 记住完成二步验证的设备，有效期内再次登录时无需二步验证
+func rememberDevice(c *gin.Context, user *model.User) {
+	days := model.GetIntSetting("2fa_remember_days", 30)
+	if days <= 0 {
+		return
+	}
+
+	ttl := days * 86400
+	token := auth.SignTwoFactorRemember(auth.General, user, int64(ttl))
+	c.SetCookie(twoFactorRememberCookie, token, ttl, "/", "", false, true)
+}
+
+// Regenerate 重新生成恢复码，原有恢复码全部失效
+func (service *RecoveryCodeService) Regenerate(c *gin.Context, user *model.User) serializer.Response {
+	if user.TwoFactor == "" {
+		return serializer.Err(serializer.CodeParamErr, "Two-factor authentication is not enabled", nil)
+	}
+
+	if !totp.Validate(service.Code, user.TwoFactor) {
+		return serializer.ParamErr("验证码不正确", nil)
+	}
+
+	codes, err := user.GenerateRecoveryCodes()
+	if err != nil {
+		return serializer.DBErr("Failed to generate recovery codes", err)
+	}
+
+	return serializer.Response{Data: map[string]interface{}{"recovery_codes": codes}}
+}